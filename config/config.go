@@ -1,9 +1,13 @@
 package config
 
 import (
-	"BackofficeGoService/internal/pkg/database"
+	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -15,8 +19,32 @@ type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	JWT      JWTConfig
+	Auth     AuthConfig
 	App      AppConfig
 	Logging  LoggingConfig
+	Cache    CacheConfig
+	Webhooks WebhookConfig
+	Outbound OutboundConfig
+	Partners PartnersConfig
+	Jobs     JobsConfig
+	Stream   StreamConfig
+	Socket   SocketConfig
+	GRPC     GRPCConfig
+	Storage  StorageConfig
+	Tasks    TasksConfig
+	API      APIConfig
+	Email    EmailConfig
+
+	RequestLog     RequestLogConfig
+	Messaging      MessagingConfig
+	AuditForwarder AuditForwarderConfig
+	WorkerPool     WorkerPoolConfig
+	Approvals      ApprovalsConfig
+	ReadOnly       ReadOnlyConfig
+	OIDC           OIDCConfig
+	Backup         BackupConfig
+	AuthWatch      AuthWatchConfig
+	GeoIP          GeoIPConfig
 }
 
 // ServerConfig holds server configuration
@@ -27,6 +55,31 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+	// DrainDelay is how long shutdown waits after failing readiness so load
+	// balancers stop routing new requests before connections are drained
+	DrainDelay time.Duration
+	// ReadinessCacheTTL is how long a readiness report is reused, so probes
+	// do not ping every database on each request; zero disables caching
+	ReadinessCacheTTL time.Duration
+	// ReadinessCheckTimeout bounds each readiness check, so one hung
+	// dependency cannot stall the probe; zero leaves checks unbounded
+	ReadinessCheckTimeout time.Duration
+
+	// SlowRequestThreshold is how long a request may take before it is
+	// logged and counted as slow; zero disables detection
+	SlowRequestThreshold time.Duration
+	// SlowRequestOverrides maps route prefixes to their own threshold
+	SlowRequestOverrides map[string]time.Duration
+
+	// MaxConcurrent is how many requests are handled at once before the
+	// rest queue or are shed with 503; zero disables the limit
+	MaxConcurrent int
+	// MaxConcurrentOverrides maps route prefixes to their own limit
+	MaxConcurrentOverrides map[string]int
+	// ConcurrencyQueue is how many requests may wait for a slot per limit
+	ConcurrencyQueue int
+	// ConcurrencyWait is how long a queued request waits before it is shed
+	ConcurrencyWait time.Duration
 }
 
 // DatabaseConfig holds database configuration
@@ -39,23 +92,83 @@ type DatabaseConfig struct {
 
 	// Multiple databases support
 	Databases map[string]DatabaseConnectionConfig `mapstructure:"databases"`
+
+	// Tenants maps tenant IDs to the database their requests are routed to
+	Tenants map[string]string `mapstructure:"tenants"`
+
+	// TenantHosts maps request hosts to the tenant they serve
+	TenantHosts map[string]string `mapstructure:"tenant_hosts"`
+
+	// AutoMigrate runs pending migrations on the primary and tenant databases at startup
+	AutoMigrate bool `mapstructure:"auto_migrate"`
+
+	// RetryInterval is how often optional databases that failed to connect are retried
+	RetryInterval time.Duration `mapstructure:"retry_interval"`
+
+	// QueryTimeout bounds every GORM statement on every database; zero disables it
+	QueryTimeout time.Duration `mapstructure:"query_timeout"`
+
+	// QueryAnnotations prefixes statements with a comment naming the route,
+	// method and request ID they were sent for. Off by default, as it makes
+	// the text of every statement unique to its request.
+	QueryAnnotations bool `mapstructure:"query_annotations"`
+
+	// QueryRetryAttempts is how often an idempotent operation runs at most
+	// when it fails with a transient error, such as a deadlock or a dropped
+	// connection during a failover; one disables retries
+	QueryRetryAttempts int `mapstructure:"query_retry_attempts"`
+	// QueryRetryBaseDelay is the jittered backoff before the first retry,
+	// doubled for each further one up to QueryRetryMaxDelay
+	QueryRetryBaseDelay time.Duration `mapstructure:"query_retry_base_delay"`
+	QueryRetryMaxDelay  time.Duration `mapstructure:"query_retry_max_delay"`
+
+	// DeepHealthCheck adds a readiness check writing to the primary
+	// database, which catches databases that answer pings but refuse writes
+	DeepHealthCheck bool `mapstructure:"deep_health_check"`
+	// DeepHealthCheckTimeout bounds each write
+	DeepHealthCheckTimeout time.Duration `mapstructure:"deep_health_check_timeout"`
+	// DeepHealthCheckFailures is how many writes in a row must fail before
+	// the check reports the database down, so one slow write does not flap it
+	DeepHealthCheckFailures int `mapstructure:"deep_health_check_failures"`
+
+	// RuntimeRegistration serves /admin/databases, which connects and removes
+	// named databases without a restart
+	RuntimeRegistration bool `mapstructure:"runtime_registration"`
+	// RegistrationTimeout bounds connecting a database registered at runtime
+	RegistrationTimeout time.Duration `mapstructure:"registration_timeout"`
+
+	// ReadReplica names the entry of databases serving the primary
+	// database's reads; empty sends every read to the primary
+	ReadReplica string `mapstructure:"read_replica"`
+	// ReadYourWritesWindow keeps a user's reads on the primary for this long
+	// after a request of theirs wrote; zero only covers the writing request
+	ReadYourWritesWindow time.Duration `mapstructure:"read_your_writes_window"`
 }
 
-// DatabaseConnectionConfig holds configuration for a single database connection
+// DatabaseConnectionConfig holds configuration for a single database
+// connection. The JSON form is accepted by POST /admin/databases, which
+// leaves the durations to the primary database's settings.
 type DatabaseConnectionConfig struct {
-	Driver          string        `mapstructure:"driver"` // postgresql, mysql, mongodb, sqlite
-	Host            string        `mapstructure:"host"`
-	Port            string        `mapstructure:"port"`
-	User            string        `mapstructure:"user"`
-	Password        string        `mapstructure:"password"`
-	DBName          string        `mapstructure:"dbname"`
-	SSLMode         string        `mapstructure:"sslmode"` // For PostgreSQL
-	Charset         string        `mapstructure:"charset"` // For MySQL
-	MaxOpenConns    int           `mapstructure:"max_open_conns"`
-	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
-	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
-	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
-	UseGorm         bool          `mapstructure:"use_gorm"`
+	Driver          string        `mapstructure:"driver" json:"driver"` // postgresql, mysql, mongodb, sqlite
+	Host            string        `mapstructure:"host" json:"host"`
+	Port            string        `mapstructure:"port" json:"port"`
+	User            string        `mapstructure:"user" json:"user"`
+	Password        string        `mapstructure:"password" json:"password"`
+	DBName          string        `mapstructure:"dbname" json:"dbname"`
+	SSLMode         string        `mapstructure:"sslmode" json:"sslmode"` // For PostgreSQL
+	Charset         string        `mapstructure:"charset" json:"charset"` // For MySQL
+	MaxOpenConns    int           `mapstructure:"max_open_conns" json:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns" json:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime" json:"-"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time" json:"-"`
+	UseGorm         bool          `mapstructure:"use_gorm" json:"use_gorm"`
+	// Required databases abort startup and fail readiness when down; optional
+	// ones are retried in the background and only mark the service degraded
+	Required bool `mapstructure:"required" json:"required"`
+	// BreakerFailures consecutive connection failures open the database's
+	// circuit breaker for BreakerCoolDown; zero disables the breaker
+	BreakerFailures int           `mapstructure:"breaker_failures" json:"breaker_failures"`
+	BreakerCoolDown time.Duration `mapstructure:"breaker_cooldown" json:"-"`
 }
 
 // JWTConfig holds JWT configuration
@@ -63,14 +176,109 @@ type JWTConfig struct {
 	Secret     string
 	Expiration time.Duration
 	Issuer     string
+	Audience   []string // Accepted audiences; tokens are issued for the first
+
+	ImpersonationExpiration time.Duration // Lifetime of tokens issued by impersonating a user
+	AllowAdminImpersonation bool          // Whether admins may impersonate other admins
+	RememberExpiration      time.Duration // Lifetime of tokens issued to "remember me" logins
+
+	// SecretFile holds the current and previous secret once the secret has
+	// been rotated, replacing Secret; it is read again every SecretFileRefresh
+	SecretFile        string
+	SecretFileRefresh time.Duration
+
+	// Tenants overrides the secret, issuer and audience of tenants' tokens
+	Tenants map[string]JWTTenantConfig
+}
+
+// JWTTenantConfig holds the token settings of one tenant. Issuer and
+// Audience default to those of JWTConfig; Secret is required.
+type JWTTenantConfig struct {
+	Secret   string   `mapstructure:"secret"`
+	Issuer   string   `mapstructure:"issuer"`
+	Audience []string `mapstructure:"audience"`
+}
+
+// Validate checks that every tenant override has its own secret
+func (c JWTConfig) Validate() error {
+	for tenant, override := range c.Tenants {
+		if tenant == "" {
+			return fmt.Errorf("invalid jwt.tenants config: empty tenant ID")
+		}
+		if override.Secret == "" {
+			return fmt.Errorf("invalid jwt.tenants config: tenant %s has no secret", tenant)
+		}
+	}
+	return nil
+}
+
+// AuthConfig holds the password policy
+type AuthConfig struct {
+	// PasswordMaxAge is how long a password lasts before login only issues a
+	// token for changing it; zero disables expiry
+	PasswordMaxAge time.Duration
+	// PasswordChangeExpiration is the lifetime of that restricted token
+	PasswordChangeExpiration time.Duration
+	// ProfileRequiredFields lists, by role, the profile fields users must
+	// fill in; the "*" entry applies to roles without one of their own
+	ProfileRequiredFields map[string][]string
+	// ProfileEnforce makes login only issue a token for completing the
+	// profile to users missing a required field
+	ProfileEnforce bool
+	// ProfileCompletionExpiration is the lifetime of that restricted token
+	ProfileCompletionExpiration time.Duration
+	// EmailChangeExpiration is how long the token confirming a new email
+	// address stays valid
+	EmailChangeExpiration time.Duration
+	// RequirePolicyAcceptance refuses API requests outside /auth and /me
+	// until the user accepted the current version of every policy
+	RequirePolicyAcceptance bool
+	// EmailPreserveLocalCase stops email addresses from being lowercased
+	// before the @; domains are lowercased regardless
+	EmailPreserveLocalCase bool
+	// EmailGmailNormalization ignores dots and +tags in Gmail addresses, so
+	// each Gmail account can only register once
+	EmailGmailNormalization bool
+	// LoginRateLimit is how many login and registration attempts each
+	// client IP may make per minute; zero disables the limit
+	LoginRateLimit int
+	// LoginRateLimitMode is "enforce" or "observe" for the login limit;
+	// empty follows API_RATE_LIMIT_MODE
+	LoginRateLimitMode string
+	// SecureAccountURL is the page the link in new sign-in emails opens,
+	// with the token in its token query parameter; the page posts the token
+	// to /auth/secure-account
+	SecureAccountURL string
+	// SecureAccountExpiration is how long that link works
+	SecureAccountExpiration time.Duration
+	// PasswordBreachCheck rejects new passwords found in breaches by the
+	// range API at PasswordBreachURL more than PasswordBreachThreshold times
+	PasswordBreachCheck     bool
+	PasswordBreachURL       string
+	PasswordBreachThreshold int
+	// PasswordBreachTimeout bounds each check, which the request waits for
+	PasswordBreachTimeout time.Duration
+	// PasswordBreachFailClosed refuses new passwords while the range API is
+	// unreachable; by default they are accepted with a warning
+	PasswordBreachFailClosed bool
+	// SessionIdleTimeout ends a login session that made no request for this
+	// long; zero disables it
+	SessionIdleTimeout time.Duration
+	// SessionAbsoluteLifetime ends a login session this long after the
+	// login, however often it is refreshed; zero disables it
+	SessionAbsoluteLifetime time.Duration
+	// SessionClockSkew is added to both limits so replicas whose clocks
+	// disagree do not end sessions early
+	SessionClockSkew time.Duration
 }
 
 // AppConfig holds application-level configuration
 type AppConfig struct {
 	Name        string
-	Version     string
+	Version     string // Overrides the build's version when set; see buildinfo
 	Environment string
 	Debug       bool
+	IDFormat    string // Format of new IDs: uuidv4, uuidv7 or ulid
 }
 
 // LoggingConfig holds logging configuration
@@ -84,6 +292,456 @@ type LoggingConfig struct {
 	MaxAge      int    // Maximum number of days to retain old log files
 	Compress    bool   // Whether to compress rotated log files
 	DailyRotate bool   // Enable daily rotation
+
+	// HTTPBodies logs JSON and form request and response bodies at debug
+	// level; it is ignored when APP_ENV is production
+	HTTPBodies    bool
+	HTTPBodyLimit int // Bytes of each body to log
+}
+
+// CacheConfig holds cache configuration
+type CacheConfig struct {
+	Driver string        // memory
+	Prefix string        // Key prefix applied to every cache entry
+	TTL    time.Duration // Default time-to-live for cached entries
+
+	// ResponseTTL is how long responses of cacheable GET routes are reused;
+	// zero disables the response cache
+	ResponseTTL time.Duration
+	// ResponseTTLOverrides maps route prefixes to their own TTL, zero
+	// disabling the cache for them
+	ResponseTTLOverrides map[string]time.Duration
+}
+
+// WebhookConfig holds outgoing webhook delivery configuration
+type WebhookConfig struct {
+	MaxAttempts    int           // Delivery attempts per event, including the first
+	InitialBackoff time.Duration // Delay before the first retry; doubles on each retry
+	Timeout        time.Duration // Per-attempt HTTP timeout
+	DisableAfter   int           // Consecutive failed deliveries before an endpoint is disabled
+	QueueSize      int           // Pending deliveries buffered before new events are dropped
+	Workers        int           // Concurrent delivery workers
+}
+
+// OutboundConfig configures the HTTP clients calling other services, such
+// as webhook endpoints and the email provider. Proxies are taken from
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+type OutboundConfig struct {
+	Timeout               time.Duration // Whole request, retries included
+	ConnectTimeout        time.Duration // Dialing and the TLS handshake
+	ResponseHeaderTimeout time.Duration // Wait for the response headers
+	MaxIdleConnsPerHost   int           // Idle connections kept per host
+	MaxConnsPerHost       int           // Connections per host, 0 for no limit
+	MaxRetries            int           // Retries of idempotent requests; webhooks retry on their own
+	RetryBackoff          time.Duration // Delay before the first retry; doubles on each retry
+}
+
+// PartnersConfig holds the partner API configuration
+type PartnersConfig struct {
+	SignatureWindow time.Duration // How far the X-Timestamp of a signed partner request may be from now
+}
+
+// JobsConfig holds background job configuration
+type JobsConfig struct {
+	Enabled                        bool          // Run the scheduler in this process
+	AuditRetention                 time.Duration // Age after which audit logs and login events are purged
+	AuditCleanupSchedule           string        // Cron schedule of the audit cleanup job
+	WebhookDeliveryRetention       time.Duration // Age after which webhook delivery attempts are purged
+	WebhookDeliveryCleanupSchedule string        // Cron schedule of the webhook delivery cleanup job
+	NotificationRetention          time.Duration // Age after which notifications are purged
+	NotificationCleanupSchedule    string        // Cron schedule of the notification cleanup job
+	SessionRetention               time.Duration // Time after expiry or revocation at which sessions are purged
+	SessionCleanupSchedule         string        // Cron schedule of the session cleanup job
+	TrustedDeviceRetention         time.Duration // Time since last use after which the session cleanup job forgets trusted devices
+	UserPurgeAfter                 time.Duration // Age of a soft delete after which the user is purged; 0 disables
+	UserPurgeSchedule              string        // Cron schedule of the user purge job
+	UserPurgeBatchSize             int           // Users purged per transaction
+	UserPurgeMaxPerRun             int           // Users purged per run at most
+	UserPurgeDryRun                bool          // Only log which users would be purged
+	RequestLogRetention            time.Duration // Age after which request log entries are purged
+	RequestLogCleanupSchedule      string        // Cron schedule of the request log cleanup job
+}
+
+// RequestLogConfig configures the request log, which keeps requests in the
+// database for support investigations
+type RequestLogConfig struct {
+	Enabled   bool
+	Methods   []string // Methods logged; reads are left out by default
+	Routes    []string // Route prefixes logged
+	BodyLimit int      // Bytes of each redacted request body kept
+
+	// SampleRate is the share of matching requests logged, from 0 to 1
+	SampleRate float64
+	// SampleRates maps route prefixes to their own sample rate; the
+	// longest matching prefix wins
+	SampleRates map[string]float64
+
+	BufferSize    int           // Entries waiting to be written before new ones are dropped
+	BatchSize     int           // Entries written per insert
+	FlushInterval time.Duration // How long entries wait for their batch to fill
+}
+
+// StorageConfig holds file storage configuration
+type StorageConfig struct {
+	Path          string        // Directory files are kept in
+	URLSecret     string        // Key signing download links; the JWT secret when empty
+	URLExpiration time.Duration // Lifetime of download links
+
+	// PreviousURLSecret is the key download links were signed with before
+	// URLSecret; its links are accepted for PreviousURLSecretGrace after start
+	PreviousURLSecret      string
+	PreviousURLSecretGrace time.Duration
+}
+
+// TasksConfig holds asynchronous task configuration
+type TasksConfig struct {
+	Workers         int           // Tasks run at the same time; more wait for a free worker
+	Retention       time.Duration // Age after which tasks and their results are purged
+	CleanupSchedule string        // Cron schedule of the task cleanup job
+	ExportBatchSize int           // Rows read per query by exports
+}
+
+// WorkerPoolConfig sizes the pool running work offloaded from requests,
+// such as password breach checks
+type WorkerPoolConfig struct {
+	Size       int    // Tasks run at the same time; 0 uses one per CPU
+	QueueDepth int    // Tasks that may wait for a worker
+	Mode       string // "reject" fails submissions while the queue is full, "block" waits for room
+}
+
+// ApprovalsConfig selects the changes a second admin must approve
+type ApprovalsConfig struct {
+	// ProtectedRoleChanges lists the role changes held for approval, as
+	// from->to with * for any role; empty applies every change at once
+	ProtectedRoleChanges []string
+	// TTL is how long an approval waits for a decision before it expires
+	TTL time.Duration
+}
+
+// ReadOnlyConfig configures the read-only mode operators turn on during
+// incidents
+type ReadOnlyConfig struct {
+	// RefreshInterval is how long an instance trusts the mode it last read
+	// from the cache store; zero reads the store on every check
+	RefreshInterval time.Duration
+}
+
+// OIDCConfig holds the OpenID Connect providers users can sign in with
+type OIDCConfig struct {
+	// Providers maps the slug in login URLs to a provider; they are only
+	// configurable in config.yaml, under oidc.providers
+	Providers map[string]OIDCProviderConfig
+	// StateTTL is how long a sign-in started may take to come back
+	StateTTL time.Duration
+	// DiscoveryCacheTTL is how long providers' discovery documents are reused
+	DiscoveryCacheTTL time.Duration
+	// ClockSkew is how far providers' clocks may differ when ID token times
+	// are checked
+	ClockSkew time.Duration
+}
+
+// BackupConfig configures the backups "backup create" uploads to storage
+type BackupConfig struct {
+	// Prefix is the storage key backups are kept under
+	Prefix string
+	// Gzip compresses backups as they are uploaded
+	Gzip bool
+
+	// Client programs dumps are made and restored with; a bare name is
+	// looked up in PATH
+	PgDumpPath    string
+	PsqlPath      string
+	MySQLDumpPath string
+	MySQLPath     string
+}
+
+// AuthWatchConfig configures the rules the authentication watch raises
+// alerts on. Each rule lists the mitigations applied when it fires:
+// "block_ip" and "force_password_reset".
+type AuthWatchConfig struct {
+	// ImpossibleTravel fires on a successful login further from the user's
+	// previous one than they could have travelled at MaxSpeed km/h, once
+	// both are at least MinDistance km apart. Logins more than Window
+	// apart are not compared.
+	ImpossibleTravel AuthWatchTravelRule
+	// CredentialStuffing fires when one IP address fails to log in as
+	// Threshold different emails within Window
+	CredentialStuffing AuthWatchRule
+	// FailureSpike fires when one email fails to log in Threshold times
+	// within Window
+	FailureSpike AuthWatchRule
+	// BlockDuration is how long block_ip refuses an address
+	BlockDuration time.Duration
+	// Locations places networks for impossible travel, as CIDR mapped to
+	// latitude and longitude; logins from elsewhere are placed by GeoIP,
+	// or not compared if it has no coordinates for them
+	Locations map[string][]string
+}
+
+// AuthWatchRule is a rule counting failed logins in a sliding window
+type AuthWatchRule struct {
+	Enabled     bool
+	Threshold   int
+	Window      time.Duration
+	Mitigations []string
+}
+
+// AuthWatchTravelRule is the impossible travel rule
+type AuthWatchTravelRule struct {
+	Enabled     bool
+	MaxSpeed    float64
+	MinDistance float64
+	Window      time.Duration
+	Mitigations []string
+}
+
+// GeoIPConfig selects how IP addresses are located for sessions, sign-in
+// alerts and the authentication watch
+type GeoIPConfig struct {
+	Driver string // none, maxmind or http
+	// MaxMindPath is a GeoLite2 or GeoIP2 City database, read again when it
+	// changes; ReloadInterval is how often it is checked, zero never
+	MaxMindPath    string
+	ReloadInterval time.Duration
+	// HTTPURL is an ipapi.co style provider, with {ip} replaced by the
+	// address; answers are cached for HTTPCacheTTL
+	HTTPURL      string
+	HTTPCacheTTL time.Duration
+	// Timeout bounds each lookup; a login never waits longer for one
+	Timeout time.Duration
+}
+
+// OIDCProviderConfig holds one OpenID Connect provider. Issuer, ClientID
+// and RedirectURL are required.
+type OIDCProviderConfig struct {
+	Name         string   `mapstructure:"name"`
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+
+	// Claims the user's attributes are read from; they default to email,
+	// given_name, family_name and groups. Dotted names reach into objects.
+	EmailClaim     string `mapstructure:"email_claim"`
+	FirstNameClaim string `mapstructure:"first_name_claim"`
+	LastNameClaim  string `mapstructure:"last_name_claim"`
+	GroupsClaim    string `mapstructure:"groups_claim"`
+
+	// Provision creates users signing in for the first time, with the role
+	// of their first group in GroupRoles, or DefaultRole
+	Provision   bool            `mapstructure:"provision"`
+	GroupRoles  []OIDCGroupRole `mapstructure:"group_roles"`
+	DefaultRole string          `mapstructure:"default_role"`
+	// LinkByEmail links the first sign-in to the existing user with the
+	// same address, when the provider verified it
+	LinkByEmail bool `mapstructure:"link_by_email"`
+	// Subjects link provider subjects to existing users explicitly
+	Subjects []OIDCSubject `mapstructure:"subjects"`
+}
+
+// OIDCGroupRole gives members of a provider group a role
+type OIDCGroupRole struct {
+	Group string `mapstructure:"group"`
+	Role  string `mapstructure:"role"`
+}
+
+// OIDCSubject links a provider subject to the user with an email address
+type OIDCSubject struct {
+	Subject string `mapstructure:"subject"`
+	Email   string `mapstructure:"email"`
+}
+
+// oidcSlug is what provider slugs may contain, as they are part of URLs
+var oidcSlug = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Validate checks that every provider can be used
+func (c OIDCConfig) Validate() error {
+	for slug, provider := range c.Providers {
+		if !oidcSlug.MatchString(slug) {
+			return fmt.Errorf("invalid oidc.providers config: slug %q must be lowercase letters, digits and dashes", slug)
+		}
+		if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			return fmt.Errorf("invalid oidc.providers config: provider %s needs issuer, client_id and redirect_url", slug)
+		}
+	}
+	return nil
+}
+
+// APIConfig holds settings shared by the API's endpoints
+type APIConfig struct {
+	DefaultPageSize  int // Page size of list endpoints when the request sends no limit
+	MaxPageSize      int // Largest limit a list request may ask for; larger ones are capped
+	AuditMaxPageSize int // MaxPageSize of audit trails, such as the user activity timeline
+
+	// Features turns groups of endpoints off; they are not registered at all.
+	// Set in config.yaml under api.features or with API_DISABLED_FEATURES.
+	Features APIFeatures `mapstructure:"features"`
+
+	// Quota limits how many requests each user makes per month
+	Quota QuotaConfig
+
+	// V1Deprecation announces the retirement of /api/v1 on its responses
+	V1Deprecation DeprecationConfig
+
+	// Confirmation asks for a token before destructive requests run
+	Confirmation ConfirmationConfig
+
+	// RateLimitMode is how rate limits without a mode of their own treat
+	// requests over the limit: "enforce" refuses them, "observe" only
+	// warns, logs and counts them
+	RateLimitMode string
+
+	// ProblemTypeBase prefixes error codes to form the type URI of the
+	// application/problem+json errors clients can ask for
+	ProblemTypeBase string
+}
+
+// ConfirmationConfig holds the two-step confirmation of destructive
+// requests, such as deleting, anonymizing or purging users
+type ConfirmationConfig struct {
+	Enabled bool          // Whether destructive requests need a confirmation token
+	TTL     time.Duration // How long a confirmation token stays valid
+	// ExemptScopes lists the scopes of API tokens that skip the
+	// confirmation, for automation credentials issued with an explicit scope
+	ExemptScopes []string
+}
+
+// DeprecationConfig holds when an API version was deprecated and when it
+// stops being served; zero times send no header
+type DeprecationConfig struct {
+	DeprecatedAt time.Time // Sent in the Deprecation header
+	Sunset       time.Time // Sent in the Sunset header
+}
+
+// QuotaConfig holds the monthly API request quotas of users
+type QuotaConfig struct {
+	Monthly       int           // Requests a user may make per calendar month (UTC); 0 for no limit unless the user has one
+	Sessions      bool          // Also count and limit requests made with interactive login session tokens
+	FlushInterval time.Duration // How often usage counters are written to usage_records for reports
+}
+
+// API features, each a group of endpoints that deployments can turn off
+const (
+	FeatureRegistration  = "registration"   // POST /auth/register
+	FeatureUserCreate    = "user_create"    // POST /users
+	FeatureUserDelete    = "user_delete"    // DELETE /users/:id
+	FeaturePasswordReset = "password_reset" // POST /users/:id/require-password-change
+	FeatureEmailChange   = "email_change"   // /me/email-change and its confirmation
+	FeatureImpersonation = "impersonation"  // /admin/impersonate
+	FeatureUserExport    = "user_export"    // /users/export
+	FeatureWebhooks      = "webhooks"       // /webhooks
+	FeatureOrganizations = "organizations"  // /organizations
+	FeaturePartners      = "partners"       // /partners and /admin/partners
+)
+
+// Features lists every API feature
+var Features = []string{
+	FeatureRegistration,
+	FeatureUserCreate,
+	FeatureUserDelete,
+	FeaturePasswordReset,
+	FeatureEmailChange,
+	FeatureImpersonation,
+	FeatureUserExport,
+	FeatureWebhooks,
+	FeatureOrganizations,
+	FeaturePartners,
+}
+
+// APIFeatures maps API features to whether they are on. Features missing
+// from the map are on, so the zero value enables everything.
+type APIFeatures map[string]bool
+
+// Enabled reports whether feature is on
+func (f APIFeatures) Enabled(feature string) bool {
+	enabled, ok := f[feature]
+	return enabled || !ok
+}
+
+// Effective returns every feature with whether it is on
+func (f APIFeatures) Effective() map[string]bool {
+	effective := make(map[string]bool, len(Features))
+	for _, feature := range Features {
+		effective[feature] = f.Enabled(feature)
+	}
+	return effective
+}
+
+// EmailConfig holds outgoing email configuration
+type EmailConfig struct {
+	Driver string // sendgrid, or log to write messages to the log instead of sending them; empty sends no email
+	From   string // Sender address
+
+	SendGridAPIKey string // API key of the sendgrid driver
+	SendGridURL    string // Mail send endpoint of the sendgrid driver
+}
+
+// MessagingConfig holds the domain event transport configuration
+type MessagingConfig struct {
+	Driver string // memory (in-process only) or nats
+	NATS   NATSConfig
+}
+
+// AuditForwarderConfig configures streaming audit entries to a SIEM
+type AuditForwarderConfig struct {
+	Driver string // Empty to keep audit entries in the database only, syslog or http
+
+	Address   string // host:port of the syslog receiver
+	TLS       bool   // Sends syslog over TLS rather than plain TCP
+	TLSCAFile string // CA bundle verifying the syslog receiver; the system roots when empty
+
+	URL   string // HTTPS endpoint entries are POSTed to
+	Token string // Bearer token of the HTTP endpoint
+
+	Timeout        time.Duration // Per-attempt timeout
+	QueueSize      int           // Entries buffered in memory before they are spilled
+	MaxAttempts    int           // Attempts per entry, including the first, before it is spilled
+	InitialBackoff time.Duration // Delay before the first retry; doubles on each retry
+	SpillPath      string        // File entries are kept in while the SIEM is unreachable
+	ReplayInterval time.Duration // How often spilled entries are retried
+}
+
+// NATSConfig holds NATS JetStream configuration
+type NATSConfig struct {
+	URLs            string        // Comma-separated server URLs
+	Name            string        // Connection name shown by the server
+	CredentialsFile string        // .creds file with the user JWT and NKey seed
+	TLSCAFile       string        // CA bundle verifying the servers
+	TLSCertFile     string        // Client certificate for mutual TLS
+	TLSKeyFile      string        // Key of the client certificate
+	ConnectTimeout  time.Duration // Timeout of the initial connection and stream setup
+	DrainTimeout    time.Duration // Time shutdown waits for in-flight messages
+
+	Stream          string        // JetStream stream holding the events
+	SubjectPrefix   string        // Events are published on <prefix>.<event type>
+	MaxAge          time.Duration // Age after which the stream drops events
+	DuplicateWindow time.Duration // Window in which events with the same ID are stored once
+
+	Consumer   string        // Durable consumer shared by every replica
+	MaxDeliver int           // Deliveries of an event before it is given up
+	AckWait    time.Duration // Time a handler has before the event is redelivered
+}
+
+// StreamConfig holds Server-Sent Events stream configuration
+type StreamConfig struct {
+	HeartbeatInterval time.Duration // Interval between heartbeat comments on idle streams
+	ClientBuffer      int           // Events buffered per stream before a slow client is evicted
+}
+
+// SocketConfig holds WebSocket configuration
+type SocketConfig struct {
+	SendQueue      int           // Messages queued per connection before a slow client is evicted
+	PingInterval   time.Duration // Interval between pings; a client silent for two intervals is dropped
+	WriteTimeout   time.Duration // Bound on writing one message
+	AllowedOrigins []string      // Origins allowed besides the API's own; "*" allows any
+}
+
+// GRPCConfig holds internal gRPC server configuration
+type GRPCConfig struct {
+	Enabled   bool   // Serve the internal gRPC API
+	Port      string // Port of the gRPC listener, separate from the HTTP port
+	AuthToken string // Shared token callers send as "authorization: Bearer <token>" metadata
 }
 
 // LoadConfig loads configuration from environment variables and config files
@@ -123,6 +781,16 @@ func LoadConfig() (*Config, error) {
 			ReadTimeout:  getDuration("SERVER_READ_TIMEOUT", 15*time.Second),
 			WriteTimeout: getDuration("SERVER_WRITE_TIMEOUT", 15*time.Second),
 			IdleTimeout:  getDuration("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			DrainDelay:   getDuration("SERVER_DRAIN_DELAY", 5*time.Second),
+
+			ReadinessCacheTTL:     getDuration("SERVER_READINESS_CACHE_TTL", 2*time.Second),
+			ReadinessCheckTimeout: getDuration("SERVER_READINESS_CHECK_TIMEOUT", 2*time.Second),
+
+			SlowRequestThreshold: getDuration("SERVER_SLOW_REQUEST_THRESHOLD", time.Second),
+
+			MaxConcurrent:    getInt("SERVER_MAX_CONCURRENT", 0),
+			ConcurrencyQueue: getInt("SERVER_CONCURRENCY_QUEUE", 50),
+			ConcurrencyWait:  getDuration("SERVER_CONCURRENCY_WAIT", 500*time.Millisecond),
 		},
 		Database: DatabaseConfig{
 			Primary: DatabaseConnectionConfig{
@@ -139,19 +807,72 @@ func LoadConfig() (*Config, error) {
 				ConnMaxLifetime: getDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
 				ConnMaxIdleTime: getDuration("DB_CONN_MAX_IDLE_TIME", 10*time.Minute),
 				UseGorm:         getBool("DB_USE_GORM", true),
+				BreakerFailures: getInt("DB_BREAKER_FAILURES", 5),
+				BreakerCoolDown: getDuration("DB_BREAKER_COOLDOWN", 30*time.Second),
 			},
-			Databases: make(map[string]DatabaseConnectionConfig),
+			Databases:     make(map[string]DatabaseConnectionConfig),
+			AutoMigrate:   getBool("DB_MIGRATE", false),
+			RetryInterval: getDuration("DB_RETRY_INTERVAL", 30*time.Second),
+			QueryTimeout:  getDuration("DB_QUERY_TIMEOUT", 30*time.Second),
+
+			QueryAnnotations: getBool("DB_QUERY_ANNOTATIONS", false),
+
+			QueryRetryAttempts:  getInt("DB_QUERY_RETRY_ATTEMPTS", 3),
+			QueryRetryBaseDelay: getDuration("DB_QUERY_RETRY_BASE_DELAY", 50*time.Millisecond),
+			QueryRetryMaxDelay:  getDuration("DB_QUERY_RETRY_MAX_DELAY", time.Second),
+
+			DeepHealthCheck:         getBool("DB_DEEP_HEALTH_CHECK", false),
+			DeepHealthCheckTimeout:  getDuration("DB_DEEP_HEALTH_CHECK_TIMEOUT", time.Second),
+			DeepHealthCheckFailures: getInt("DB_DEEP_HEALTH_CHECK_FAILURES", 3),
+
+			RuntimeRegistration: getBool("DB_RUNTIME_REGISTRATION", false),
+			RegistrationTimeout: getDuration("DB_REGISTRATION_TIMEOUT", 10*time.Second),
+
+			ReadReplica:          getString("DB_READ_REPLICA", ""),
+			ReadYourWritesWindow: getDuration("DB_READ_YOUR_WRITES_WINDOW", 0),
 		},
 		JWT: JWTConfig{
 			Secret:     getString("JWT_SECRET", "your-secret-key-change-in-production"),
 			Expiration: getDuration("JWT_EXPIRATION", 24*time.Hour),
 			Issuer:     getString("JWT_ISSUER", "backoffice-service"),
+			Audience:   getStringSlice("JWT_AUDIENCE", nil),
+
+			ImpersonationExpiration: getDuration("JWT_IMPERSONATION_EXPIRATION", 15*time.Minute),
+			AllowAdminImpersonation: getBool("JWT_ALLOW_ADMIN_IMPERSONATION", false),
+			RememberExpiration:      getDuration("JWT_REMEMBER_EXPIRATION", 30*24*time.Hour),
+
+			SecretFile:        getString("JWT_SECRET_FILE", ""),
+			SecretFileRefresh: getDuration("JWT_SECRET_FILE_REFRESH", 30*time.Second),
+		},
+		Auth: AuthConfig{
+			PasswordMaxAge:           getDuration("AUTH_PASSWORD_MAX_AGE", 0),
+			PasswordChangeExpiration: getDuration("AUTH_PASSWORD_CHANGE_EXPIRATION", 15*time.Minute),
+			EmailChangeExpiration:    getDuration("AUTH_EMAIL_CHANGE_EXPIRATION", 24*time.Hour),
+			RequirePolicyAcceptance:  getBool("AUTH_REQUIRE_POLICY_ACCEPTANCE", false),
+			EmailPreserveLocalCase:   getBool("AUTH_EMAIL_PRESERVE_LOCAL_CASE", false),
+			EmailGmailNormalization:  getBool("AUTH_EMAIL_GMAIL_NORMALIZATION", false),
+			LoginRateLimit:           getInt("AUTH_LOGIN_RATE_LIMIT", 0),
+			LoginRateLimitMode:       getString("AUTH_LOGIN_RATE_LIMIT_MODE", ""),
+			SecureAccountURL:         getString("AUTH_SECURE_ACCOUNT_URL", "http://localhost:3000/secure-account"),
+			SecureAccountExpiration:  getDuration("AUTH_SECURE_ACCOUNT_EXPIRATION", 7*24*time.Hour),
+			PasswordBreachCheck:      getBool("AUTH_PASSWORD_BREACH_CHECK", false),
+			PasswordBreachURL:        getString("AUTH_PASSWORD_BREACH_URL", "https://api.pwnedpasswords.com"),
+			PasswordBreachThreshold:  getInt("AUTH_PASSWORD_BREACH_THRESHOLD", 0),
+			PasswordBreachTimeout:    getDuration("AUTH_PASSWORD_BREACH_TIMEOUT", 2*time.Second),
+			PasswordBreachFailClosed: getBool("AUTH_PASSWORD_BREACH_FAIL_CLOSED", false),
+			SessionIdleTimeout:       getDuration("SESSION_IDLE_TIMEOUT", 0),
+			SessionAbsoluteLifetime:  getDuration("SESSION_ABSOLUTE_LIFETIME", 0),
+			SessionClockSkew:         getDuration("SESSION_CLOCK_SKEW", 30*time.Second),
+
+			ProfileEnforce:              getBool("AUTH_PROFILE_ENFORCE", false),
+			ProfileCompletionExpiration: getDuration("AUTH_PROFILE_COMPLETION_EXPIRATION", 30*time.Minute),
 		},
 		App: AppConfig{
 			Name:        getString("APP_NAME", "Backoffice Service"),
-			Version:     getString("APP_VERSION", "1.0.0"),
+			Version:     getString("APP_VERSION", ""),
 			Environment: getString("APP_ENV", "development"),
 			Debug:       getBool("APP_DEBUG", true),
+			IDFormat:    getString("APP_ID_FORMAT", "uuidv4"),
 		},
 		Logging: LoggingConfig{
 			Channel:     getString("LOG_CHANNEL", "stdout"),
@@ -163,52 +884,315 @@ func LoadConfig() (*Config, error) {
 			MaxAge:      getInt("LOG_MAX_AGE", 28),
 			Compress:    getBool("LOG_COMPRESS", true),
 			DailyRotate: getBool("LOG_DAILY_ROTATE", true),
+
+			HTTPBodies:    getBool("LOG_HTTP_BODIES", false),
+			HTTPBodyLimit: getInt("LOG_HTTP_BODY_LIMIT", 8192),
+		},
+		Cache: CacheConfig{
+			Driver: getString("CACHE_DRIVER", "memory"),
+			Prefix: getString("CACHE_PREFIX", "backoffice_cache"),
+			TTL:    time.Duration(getInt("CACHE_TTL", 3600)) * time.Second,
+
+			ResponseTTL: getDuration("CACHE_RESPONSE_TTL", 0),
+		},
+		Webhooks: WebhookConfig{
+			MaxAttempts:    getInt("WEBHOOK_MAX_ATTEMPTS", 5),
+			InitialBackoff: getDuration("WEBHOOK_INITIAL_BACKOFF", time.Second),
+			Timeout:        getDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+			DisableAfter:   getInt("WEBHOOK_DISABLE_AFTER", 10),
+			QueueSize:      getInt("WEBHOOK_QUEUE_SIZE", 1000),
+			Workers:        getInt("WEBHOOK_WORKERS", 4),
+		},
+		Outbound: OutboundConfig{
+			Timeout:               getDuration("HTTP_CLIENT_TIMEOUT", 30*time.Second),
+			ConnectTimeout:        getDuration("HTTP_CLIENT_CONNECT_TIMEOUT", 5*time.Second),
+			ResponseHeaderTimeout: getDuration("HTTP_CLIENT_RESPONSE_HEADER_TIMEOUT", 15*time.Second),
+			MaxIdleConnsPerHost:   getInt("HTTP_CLIENT_MAX_IDLE_CONNS_PER_HOST", 10),
+			MaxConnsPerHost:       getInt("HTTP_CLIENT_MAX_CONNS_PER_HOST", 0),
+			MaxRetries:            getInt("HTTP_CLIENT_MAX_RETRIES", 2),
+			RetryBackoff:          getDuration("HTTP_CLIENT_RETRY_BACKOFF", 200*time.Millisecond),
+		},
+		Partners: PartnersConfig{
+			SignatureWindow: getDuration("PARTNER_SIGNATURE_WINDOW", 5*time.Minute),
+		},
+		Jobs: JobsConfig{
+			Enabled:                        getBool("JOBS_ENABLED", true),
+			AuditRetention:                 getDuration("AUDIT_RETENTION", 90*24*time.Hour),
+			AuditCleanupSchedule:           getString("JOBS_AUDIT_CLEANUP_SCHEDULE", "0 3 * * *"),
+			WebhookDeliveryRetention:       getDuration("WEBHOOK_DELIVERY_RETENTION", 30*24*time.Hour),
+			WebhookDeliveryCleanupSchedule: getString("JOBS_WEBHOOK_DELIVERY_CLEANUP_SCHEDULE", "30 3 * * *"),
+			NotificationRetention:          getDuration("NOTIFICATION_RETENTION", 90*24*time.Hour),
+			NotificationCleanupSchedule:    getString("JOBS_NOTIFICATION_CLEANUP_SCHEDULE", "45 3 * * *"),
+			SessionRetention:               getDuration("SESSION_RETENTION", 7*24*time.Hour),
+			SessionCleanupSchedule:         getString("JOBS_SESSION_CLEANUP_SCHEDULE", "50 3 * * *"),
+			TrustedDeviceRetention:         getDuration("TRUSTED_DEVICE_RETENTION", 90*24*time.Hour),
+			UserPurgeAfter:                 getDuration("USERS_PURGE_AFTER", 90*24*time.Hour),
+			UserPurgeSchedule:              getString("JOBS_USER_PURGE_SCHEDULE", "15 4 * * *"),
+			UserPurgeBatchSize:             getInt("USERS_PURGE_BATCH_SIZE", 100),
+			UserPurgeMaxPerRun:             getInt("USERS_PURGE_MAX_PER_RUN", 1000),
+			UserPurgeDryRun:                getBool("USERS_PURGE_DRY_RUN", false),
+			RequestLogRetention:            getDuration("REQUEST_LOG_RETENTION", 30*24*time.Hour),
+			RequestLogCleanupSchedule:      getString("JOBS_REQUEST_LOG_CLEANUP_SCHEDULE", "55 3 * * *"),
+		},
+		RequestLog: RequestLogConfig{
+			Enabled:       getBool("REQUEST_LOG_ENABLED", false),
+			Methods:       getStringSlice("REQUEST_LOG_METHODS", []string{"POST", "PUT", "PATCH", "DELETE"}),
+			Routes:        getStringSlice("REQUEST_LOG_ROUTES", []string{"/api/"}),
+			BodyLimit:     getInt("REQUEST_LOG_BODY_LIMIT", 4096),
+			SampleRate:    getFloat("REQUEST_LOG_SAMPLE_RATE", 1),
+			BufferSize:    getInt("REQUEST_LOG_BUFFER_SIZE", 1000),
+			BatchSize:     getInt("REQUEST_LOG_BATCH_SIZE", 100),
+			FlushInterval: getDuration("REQUEST_LOG_FLUSH_INTERVAL", time.Second),
+		},
+		Stream: StreamConfig{
+			HeartbeatInterval: getDuration("SSE_HEARTBEAT_INTERVAL", 25*time.Second),
+			ClientBuffer:      getInt("SSE_CLIENT_BUFFER", 64),
+		},
+		Socket: SocketConfig{
+			SendQueue:      getInt("WS_SEND_QUEUE", 64),
+			PingInterval:   getDuration("WS_PING_INTERVAL", 30*time.Second),
+			WriteTimeout:   getDuration("WS_WRITE_TIMEOUT", 10*time.Second),
+			AllowedOrigins: getStringSlice("WS_ALLOWED_ORIGINS", nil),
+		},
+		GRPC: GRPCConfig{
+			Enabled:   getBool("GRPC_ENABLED", false),
+			Port:      getString("GRPC_PORT", "9090"),
+			AuthToken: getString("GRPC_AUTH_TOKEN", ""),
+		},
+		Storage: StorageConfig{
+			Path:          getString("STORAGE_PATH", "./storage/app"),
+			URLSecret:     getString("STORAGE_URL_SECRET", ""),
+			URLExpiration: getDuration("STORAGE_URL_EXPIRATION", 15*time.Minute),
+
+			PreviousURLSecret:      getString("STORAGE_URL_PREVIOUS_SECRET", ""),
+			PreviousURLSecretGrace: getDuration("STORAGE_URL_PREVIOUS_SECRET_GRACE", time.Hour),
+		},
+		Tasks: TasksConfig{
+			Workers:         getInt("TASKS_WORKERS", 2),
+			Retention:       getDuration("TASK_RETENTION", 7*24*time.Hour),
+			CleanupSchedule: getString("JOBS_TASK_CLEANUP_SCHEDULE", "0 4 * * *"),
+			ExportBatchSize: getInt("TASKS_EXPORT_BATCH_SIZE", 1000),
 		},
+		WorkerPool: WorkerPoolConfig{
+			Size:       getInt("WORKER_POOL_SIZE", 0),
+			QueueDepth: getInt("WORKER_POOL_QUEUE_DEPTH", 100),
+			Mode:       getString("WORKER_POOL_MODE", "reject"),
+		},
+		Approvals: ApprovalsConfig{
+			ProtectedRoleChanges: getStringSlice("APPROVALS_PROTECTED_ROLE_CHANGES", []string{"*->admin"}),
+			TTL:                  getDuration("APPROVALS_TTL", 72*time.Hour),
+		},
+		ReadOnly: ReadOnlyConfig{
+			RefreshInterval: getDuration("READ_ONLY_REFRESH_INTERVAL", 2*time.Second),
+		},
+		OIDC: OIDCConfig{
+			StateTTL:          getDuration("OIDC_STATE_TTL", 10*time.Minute),
+			DiscoveryCacheTTL: getDuration("OIDC_DISCOVERY_CACHE_TTL", time.Hour),
+			ClockSkew:         getDuration("OIDC_CLOCK_SKEW", time.Minute),
+		},
+		Backup: BackupConfig{
+			Prefix:        getString("BACKUP_PREFIX", "backups"),
+			Gzip:          getBool("BACKUP_GZIP", true),
+			PgDumpPath:    getString("BACKUP_PG_DUMP_PATH", "pg_dump"),
+			PsqlPath:      getString("BACKUP_PSQL_PATH", "psql"),
+			MySQLDumpPath: getString("BACKUP_MYSQLDUMP_PATH", "mysqldump"),
+			MySQLPath:     getString("BACKUP_MYSQL_PATH", "mysql"),
+		},
+		AuthWatch: AuthWatchConfig{
+			ImpossibleTravel: AuthWatchTravelRule{
+				Enabled:     getBool("AUTHWATCH_TRAVEL_ENABLED", true),
+				MaxSpeed:    getFloat("AUTHWATCH_TRAVEL_MAX_SPEED", 900),
+				MinDistance: getFloat("AUTHWATCH_TRAVEL_MIN_DISTANCE", 500),
+				Window:      getDuration("AUTHWATCH_TRAVEL_WINDOW", 24*time.Hour),
+				Mitigations: getStringSlice("AUTHWATCH_TRAVEL_MITIGATIONS", nil),
+			},
+			CredentialStuffing: AuthWatchRule{
+				Enabled:     getBool("AUTHWATCH_STUFFING_ENABLED", true),
+				Threshold:   getInt("AUTHWATCH_STUFFING_THRESHOLD", 20),
+				Window:      getDuration("AUTHWATCH_STUFFING_WINDOW", 10*time.Minute),
+				Mitigations: getStringSlice("AUTHWATCH_STUFFING_MITIGATIONS", nil),
+			},
+			FailureSpike: AuthWatchRule{
+				Enabled:     getBool("AUTHWATCH_SPIKE_ENABLED", true),
+				Threshold:   getInt("AUTHWATCH_SPIKE_THRESHOLD", 10),
+				Window:      getDuration("AUTHWATCH_SPIKE_WINDOW", 15*time.Minute),
+				Mitigations: getStringSlice("AUTHWATCH_SPIKE_MITIGATIONS", nil),
+			},
+			BlockDuration: getDuration("AUTHWATCH_BLOCK_DURATION", time.Hour),
+		},
+		GeoIP: GeoIPConfig{
+			Driver:         getString("GEOIP_DRIVER", "none"),
+			MaxMindPath:    getString("GEOIP_MAXMIND_PATH", "./storage/geoip/GeoLite2-City.mmdb"),
+			ReloadInterval: getDuration("GEOIP_RELOAD_INTERVAL", time.Minute),
+			HTTPURL:        getString("GEOIP_HTTP_URL", "https://ipapi.co/{ip}/json/"),
+			HTTPCacheTTL:   getDuration("GEOIP_HTTP_CACHE_TTL", 24*time.Hour),
+			Timeout:        getDuration("GEOIP_TIMEOUT", 200*time.Millisecond),
+		},
+		API: APIConfig{
+			DefaultPageSize:  getInt("API_DEFAULT_PAGE_SIZE", 10),
+			MaxPageSize:      getInt("API_MAX_PAGE_SIZE", 100),
+			AuditMaxPageSize: getInt("API_AUDIT_MAX_PAGE_SIZE", 500),
+			Quota: QuotaConfig{
+				Monthly:       getInt("API_QUOTA_MONTHLY", 0),
+				Sessions:      getBool("API_QUOTA_SESSIONS", false),
+				FlushInterval: getDuration("API_QUOTA_FLUSH_INTERVAL", time.Minute),
+			},
+			Confirmation: ConfirmationConfig{
+				Enabled:      getBool("API_CONFIRM_DESTRUCTIVE", true),
+				TTL:          getDuration("API_CONFIRM_TOKEN_TTL", 5*time.Minute),
+				ExemptScopes: getStringSlice("API_CONFIRM_EXEMPT_SCOPES", nil),
+			},
+			RateLimitMode:   getString("API_RATE_LIMIT_MODE", "enforce"),
+			ProblemTypeBase: getString("API_PROBLEM_TYPE_BASE", "/api/v1/error-codes#"),
+		},
+		Email: EmailConfig{
+			Driver: getString("EMAIL_DRIVER", ""),
+			From:   getString("EMAIL_FROM", "no-reply@example.com"),
+
+			SendGridAPIKey: getString("SENDGRID_API_KEY", ""),
+			SendGridURL:    getString("SENDGRID_URL", "https://api.sendgrid.com/v3/mail/send"),
+		},
+		Messaging: MessagingConfig{
+			Driver: getString("MESSAGING_DRIVER", "memory"),
+			NATS: NATSConfig{
+				URLs:            getString("NATS_URLS", "nats://127.0.0.1:4222"),
+				Name:            getString("NATS_CONNECTION_NAME", "backoffice-service"),
+				CredentialsFile: getString("NATS_CREDENTIALS_FILE", ""),
+				TLSCAFile:       getString("NATS_TLS_CA_FILE", ""),
+				TLSCertFile:     getString("NATS_TLS_CERT_FILE", ""),
+				TLSKeyFile:      getString("NATS_TLS_KEY_FILE", ""),
+				ConnectTimeout:  getDuration("NATS_CONNECT_TIMEOUT", 5*time.Second),
+				DrainTimeout:    getDuration("NATS_DRAIN_TIMEOUT", 10*time.Second),
+				Stream:          getString("NATS_STREAM", "BACKOFFICE_EVENTS"),
+				SubjectPrefix:   getString("NATS_SUBJECT_PREFIX", "backoffice.events"),
+				MaxAge:          getDuration("NATS_STREAM_MAX_AGE", 7*24*time.Hour),
+				DuplicateWindow: getDuration("NATS_DUPLICATE_WINDOW", 2*time.Minute),
+				Consumer:        getString("NATS_CONSUMER", "backoffice-service"),
+				MaxDeliver:      getInt("NATS_MAX_DELIVER", 5),
+				AckWait:         getDuration("NATS_ACK_WAIT", 30*time.Second),
+			},
+		},
+		AuditForwarder: AuditForwarderConfig{
+			Driver:         getString("AUDIT_FORWARDER_DRIVER", ""),
+			Address:        getString("AUDIT_FORWARDER_ADDRESS", ""),
+			TLS:            getBool("AUDIT_FORWARDER_TLS", true),
+			TLSCAFile:      getString("AUDIT_FORWARDER_TLS_CA_FILE", ""),
+			URL:            getString("AUDIT_FORWARDER_URL", ""),
+			Token:          getString("AUDIT_FORWARDER_TOKEN", ""),
+			Timeout:        getDuration("AUDIT_FORWARDER_TIMEOUT", 10*time.Second),
+			QueueSize:      getInt("AUDIT_FORWARDER_QUEUE_SIZE", 1000),
+			MaxAttempts:    getInt("AUDIT_FORWARDER_MAX_ATTEMPTS", 5),
+			InitialBackoff: getDuration("AUDIT_FORWARDER_INITIAL_BACKOFF", time.Second),
+			SpillPath:      getString("AUDIT_FORWARDER_SPILL_PATH", "./storage/audit/spill.jsonl"),
+			ReplayInterval: getDuration("AUDIT_FORWARDER_REPLAY_INTERVAL", 30*time.Second),
+		},
+	}
+
+	overrides, err := getDurationMap("SERVER_SLOW_REQUEST_OVERRIDES")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Server.SlowRequestOverrides = overrides
+
+	limits, err := getIntMap("SERVER_MAX_CONCURRENT_OVERRIDES")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Server.MaxConcurrentOverrides = limits
+
+	sampleRates, err := getFloatMap("REQUEST_LOG_SAMPLE_RATES")
+	if err != nil {
+		return nil, err
+	}
+	cfg.RequestLog.SampleRates = sampleRates
+
+	requiredFields, err := getListMap("AUTH_PROFILE_REQUIRED_FIELDS")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Auth.ProfileRequiredFields = requiredFields
+
+	locations, err := getListMap("AUTHWATCH_LOCATIONS")
+	if err != nil {
+		return nil, err
+	}
+	cfg.AuthWatch.Locations = locations
+
+	responseTTLs, err := getDurationMap("CACHE_RESPONSE_TTL_OVERRIDES")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Cache.ResponseTTLOverrides = responseTTLs
+
+	for key, mode := range map[string]string{
+		"API_RATE_LIMIT_MODE":        cfg.API.RateLimitMode,
+		"AUTH_LOGIN_RATE_LIMIT_MODE": cfg.Auth.LoginRateLimitMode,
+	} {
+		if mode != "" && mode != "enforce" && mode != "observe" {
+			return nil, fmt.Errorf("invalid %s %q: want enforce or observe", key, mode)
+		}
+	}
+
+	// Named databases are only configurable in config.yaml, under database.databases
+	if err := viper.UnmarshalKey("database.databases", &cfg.Database.Databases); err != nil {
+		return nil, fmt.Errorf("invalid database.databases config: %w", err)
+	}
+	if err := viper.UnmarshalKey("database.tenants", &cfg.Database.Tenants); err != nil {
+		return nil, fmt.Errorf("invalid database.tenants config: %w", err)
+	}
+	if err := viper.UnmarshalKey("database.tenant_hosts", &cfg.Database.TenantHosts); err != nil {
+		return nil, fmt.Errorf("invalid database.tenant_hosts config: %w", err)
+	}
+
+	// Tenant token settings are only configurable in config.yaml, under jwt.tenants
+	if err := viper.UnmarshalKey("jwt.tenants", &cfg.JWT.Tenants); err != nil {
+		return nil, fmt.Errorf("invalid jwt.tenants config: %w", err)
+	}
+	if err := cfg.JWT.Validate(); err != nil {
+		return nil, err
+	}
+
+	// OpenID Connect providers are only configurable in config.yaml, under oidc.providers
+	if err := viper.UnmarshalKey("oidc.providers", &cfg.OIDC.Providers); err != nil {
+		return nil, fmt.Errorf("invalid oidc.providers config: %w", err)
+	}
+	if err := cfg.OIDC.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cfg.API.V1Deprecation.DeprecatedAt, err = getTime("API_V1_DEPRECATED_AT"); err != nil {
+		return nil, err
+	}
+	if cfg.API.V1Deprecation.Sunset, err = getTime("API_V1_SUNSET"); err != nil {
+		return nil, err
+	}
+
+	features, err := loadAPIFeatures()
+	if err != nil {
+		return nil, err
 	}
+	cfg.API.Features = features
 
 	return cfg, nil
 }
 
-// GetDatabaseDriverConfig converts DatabaseConnectionConfig to appropriate driver config
-func (dbc *DatabaseConnectionConfig) GetDatabaseDriverConfig() (database.DriverType, interface{}, error) {
-	driverType := database.DriverType(dbc.Driver)
-
-	switch driverType {
-	case database.DriverPostgreSQL:
-		return driverType, &database.PostgresConfig{
-			Host:            dbc.Host,
-			Port:            dbc.Port,
-			User:            dbc.User,
-			Password:        dbc.Password,
-			DBName:          dbc.DBName,
-			SSLMode:         dbc.SSLMode,
-			MaxOpenConns:    dbc.MaxOpenConns,
-			MaxIdleConns:    dbc.MaxIdleConns,
-			ConnMaxLifetime: dbc.ConnMaxLifetime,
-			ConnMaxIdleTime: dbc.ConnMaxIdleTime,
-			UseGorm:         dbc.UseGorm,
-		}, nil
-
-	case database.DriverMySQL:
-		return driverType, &database.MySQLConfig{
-			Host:            dbc.Host,
-			Port:            dbc.Port,
-			User:            dbc.User,
-			Password:        dbc.Password,
-			DBName:          dbc.DBName,
-			Charset:         dbc.Charset,
-			ParseTime:       true,
-			Loc:             "Local",
-			MaxOpenConns:    dbc.MaxOpenConns,
-			MaxIdleConns:    dbc.MaxIdleConns,
-			ConnMaxLifetime: dbc.ConnMaxLifetime,
-			ConnMaxIdleTime: dbc.ConnMaxIdleTime,
-			UseGorm:         dbc.UseGorm,
-		}, nil
-
-	default:
-		return "", nil, database.ErrUnsupportedDriver
+// loadAPIFeatures reads api.features from config.yaml and turns off the
+// features API_DISABLED_FEATURES lists. Unknown features are an error, so a
+// misspelt one cannot leave endpoints exposed.
+func loadAPIFeatures() (APIFeatures, error) {
+	features := make(APIFeatures)
+	if err := viper.UnmarshalKey("api.features", &features); err != nil {
+		return nil, fmt.Errorf("invalid api.features config: %w", err)
 	}
+	for _, feature := range getStringSlice("API_DISABLED_FEATURES", nil) {
+		features[feature] = false
+	}
+	for feature := range features {
+		if !slices.Contains(Features, feature) {
+			return nil, fmt.Errorf("invalid api.features config: unknown feature %q", feature)
+		}
+	}
+	return features, nil
 }
 
 // Helper functions
@@ -248,3 +1232,123 @@ func getDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+// getFloat reads a number; unset is defaultValue, so an explicit 0 is kept
+func getFloat(key string, defaultValue float64) float64 {
+	if viper.IsSet(key) {
+		return viper.GetFloat64(key)
+	}
+	return defaultValue
+}
+
+// getStringSlice parses a comma-separated list, skipping empty entries
+func getStringSlice(key string, defaultValue []string) []string {
+	var values []string
+	for _, value := range strings.Split(viper.GetString(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
+
+// getTime parses an RFC 3339 time, such as "2025-01-01T00:00:00Z"; unset is
+// the zero time
+func getTime(key string) (time.Time, error) {
+	raw := strings.TrimSpace(viper.GetString(key))
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: expected an RFC 3339 time: %w", key, raw, err)
+	}
+	return t, nil
+}
+
+// getDurationMap parses "key=duration" pairs separated by commas, e.g.
+// "/api/v1/admin=10s,/api/v1/users=2s"
+func getDurationMap(key string) (map[string]time.Duration, error) {
+	values := make(map[string]time.Duration)
+	for _, pair := range strings.Split(viper.GetString(key), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s entry %q: expected key=duration", key, pair)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", key, pair, err)
+		}
+		values[strings.TrimSpace(name)] = duration
+	}
+	return values, nil
+}
+
+// getIntMap parses "key=integer" pairs separated by commas, e.g.
+// "/api/v1/users/export=2,/api/v1/admin=10"
+func getIntMap(key string) (map[string]int, error) {
+	values := make(map[string]int)
+	for _, pair := range strings.Split(viper.GetString(key), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s entry %q: expected key=integer", key, pair)
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", key, pair, err)
+		}
+		values[strings.TrimSpace(name)] = value
+	}
+	return values, nil
+}
+
+// getListMap parses "key=value value" pairs separated by commas, e.g.
+// "*=first_name last_name,admin=first_name last_name username"
+func getListMap(key string) (map[string][]string, error) {
+	values := make(map[string][]string)
+	for _, pair := range strings.Split(viper.GetString(key), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s entry %q: expected key=values", key, pair)
+		}
+		values[strings.TrimSpace(name)] = strings.Fields(raw)
+	}
+	return values, nil
+}
+
+// getFloatMap parses "key=number" pairs separated by commas, e.g.
+// "/api/v1/auth=0.1,/api/v1/admin=1"
+func getFloatMap(key string) (map[string]float64, error) {
+	values := make(map[string]float64)
+	for _, pair := range strings.Split(viper.GetString(key), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s entry %q: expected key=number", key, pair)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", key, pair, err)
+		}
+		values[strings.TrimSpace(name)] = value
+	}
+	return values, nil
+}
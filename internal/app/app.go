@@ -2,18 +2,75 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"BackofficeGoService/internal/app/controllers/admin"
 	"BackofficeGoService/internal/app/controllers/auth"
+	"BackofficeGoService/internal/app/controllers/feature"
+	"BackofficeGoService/internal/app/controllers/file"
+	"BackofficeGoService/internal/app/controllers/meta"
+	"BackofficeGoService/internal/app/controllers/notification"
+	"BackofficeGoService/internal/app/controllers/organization"
+	"BackofficeGoService/internal/app/controllers/partner"
+	"BackofficeGoService/internal/app/controllers/permission"
+	"BackofficeGoService/internal/app/controllers/product"
+	"BackofficeGoService/internal/app/controllers/stream"
+	"BackofficeGoService/internal/app/controllers/task"
 	"BackofficeGoService/internal/app/controllers/user"
+	"BackofficeGoService/internal/app/controllers/webhook"
+	"BackofficeGoService/internal/app/crud"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/database/migrations"
+	"BackofficeGoService/internal/grpcserver"
+	"BackofficeGoService/internal/infrastructure/email"
+	natsmessaging "BackofficeGoService/internal/infrastructure/messaging/nats"
+	"BackofficeGoService/internal/infrastructure/storage"
+	"BackofficeGoService/internal/pkg/audit"
+	"BackofficeGoService/internal/pkg/buildinfo"
+	"BackofficeGoService/internal/pkg/cache"
 	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/filetoken"
+	"BackofficeGoService/internal/pkg/geoip"
+	"BackofficeGoService/internal/pkg/health"
+	"BackofficeGoService/internal/pkg/httpclient"
+	"BackofficeGoService/internal/pkg/identifier"
+	"BackofficeGoService/internal/pkg/jobs"
+	"BackofficeGoService/internal/pkg/lifecycle"
 	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/pkg/ratelimit"
+	"BackofficeGoService/internal/pkg/readonly"
+	"BackofficeGoService/internal/pkg/requestctx"
+	"BackofficeGoService/internal/pkg/service"
+	"BackofficeGoService/internal/pkg/sse"
+	"BackofficeGoService/internal/pkg/workerpool"
+	"BackofficeGoService/internal/pkg/ws"
+	"BackofficeGoService/internal/routes"
 	"BackofficeGoService/internal/services"
+	"BackofficeGoService/internal/services/authwatch"
+	"BackofficeGoService/internal/services/featureflags"
 
 	"BackofficeGoService/config"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // Application represents the main application
@@ -21,49 +78,181 @@ type Application struct {
 	config    *config.Config
 	logger    logger.Logger
 	server    *http.Server
+	grpc      *grpcserver.Server
 	router    *gin.Engine
 	dbManager *database.Manager
+	cache     cache.Store
+	// readOnly freezes writes during incidents; it is kept in the cache
+	// store outside any tenant, so every replica sharing it follows
+	readOnly *readonly.Switch
+	events   *events.Bus
+	// responses caches the responses of heavy read endpoints; nil when
+	// CACHE_RESPONSE_TTL and its overrides are all zero
+	responses *services.ResponseCache
+	// messaging carries events between replicas when a broker is configured;
+	// eventConsumer hands the events it receives to the in-process bus
+	messaging     *natsmessaging.Client
+	eventConsumer *natsmessaging.Consumer
+	scheduler     *jobs.Scheduler
+	broker        *sse.Broker
+	hub           *ws.Hub
+	lifecycle     *lifecycle.Lifecycle
+	metrics       *metrics.Metrics
+	health        *health.Registry
+	build         buildinfo.Info
+	// workers runs work offloaded from requests, such as breach checks
+	workers *workerpool.Pool
 
 	// Services
-	authService *services.AuthService
-	userService *services.UserService
+	auditService *services.AuditService
+	authService  service.AuthService
+	userService  service.UserService
+	orgService   *services.OrganizationService
+
+	// users purges and exports users even when the user service of the
+	// APIs was replaced
+	users *services.UserService
+
+	permissionService *services.PermissionService
+	webhookService    *services.WebhookService
+	webhookDispatcher *services.WebhookDispatcher
+	partners          *services.PartnerService
+	featureFlags      *featureflags.Service
+	settingsService   *services.SettingsService
+	notifications     *services.NotificationService
+	sessions          *services.SessionService
+	devices           *services.TrustedDeviceService
+	signInAlerts      *services.SignInAlertService
+	authWatch         *authwatch.Watch
+	geo               geoip.Resolver
+	geoDB             *geoip.MaxMind
+	policies          *services.PolicyService
+	profiles          *services.ProfileService
+	quotas            *services.QuotaService
+	auditForwarder    *audit.Queue
+	files             *storage.LocalStore
+	links             *filetoken.Signer
+	tasks             *services.TaskService
+	approvals         *services.ApprovalService
+	// requestLogs keeps the requests REQUEST_LOG_ENABLED selects in the
+	// database; requestLogWriter is nil when it is disabled
+	requestLogs      *services.RequestLogService
+	requestLogWriter *services.RequestLogWriter
 
 	// Controllers
-	authController *auth.AuthController
-	userController *user.UserController
+	controllers routes.Controllers
+	// routeTable lists the registered routes once setupRoutes has run
+	routeTable route.Table
+
+	// Set by options
+	externalDB  bool
+	emailClient email.EmailClient
+	middleware  []gin.HandlerFunc
 }
 
-// New creates a new Application instance
-func New(cfg *config.Config, log logger.Logger) (*Application, error) {
+// New creates a new Application instance. Options replace the components it
+// would otherwise build.
+func New(cfg *config.Config, log logger.Logger, opts ...Option) (*Application, error) {
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
 
 	// Create router
 	router := gin.New()
-	router.Use(gin.Recovery())
-
-	// Add logging middleware
-	router.Use(ginLogger(log))
 
 	app := &Application{
 		config:    cfg,
 		logger:    log,
 		router:    router,
+		metrics:   metrics.New(),
 		dbManager: database.NewManager(),
+		health:    health.NewRegistry(cfg.Server.ReadinessCacheTTL, health.WithTimeout(cfg.Server.ReadinessCheckTimeout)),
+		lifecycle: lifecycle.New(cfg.Server.DrainDelay, log),
+		build:     BuildInfo(cfg),
 	}
+	app.metrics.SetBuildInfo(app.build)
 
-	// Initialize database connections
-	if err := app.initDatabase(); err != nil {
-		return nil, err
+	// Add logging middleware. Recovery runs inside it, so the request log
+	// sees the 500 a panic is turned into.
+	router.Use(ginLogger(log))
+	showStack := cfg.App.Debug && cfg.App.Environment != "production"
+	router.Use(middleware.Recovery(log, showStack, app.metrics.Panics))
+	if cfg.Logging.HTTPBodies {
+		if cfg.App.Environment == "production" {
+			log.Warn("LOG_HTTP_BODIES is ignored in production")
+		} else {
+			router.Use(middleware.BodyLogger(log, cfg.Logging.HTTPBodyLimit))
+		}
 	}
 
+	router.Use(middleware.SlowRequests(log, middleware.SlowRequestConfig{
+		Threshold: cfg.Server.SlowRequestThreshold,
+		Overrides: cfg.Server.SlowRequestOverrides,
+	}, app.metrics.SlowRequests))
+	router.Use(middleware.Locale())
+	middleware.SetProblemTypeBase(cfg.API.ProblemTypeBase)
+	// Probes, metrics and long-lived streams would hold slots indefinitely
+	router.Use(middleware.ConcurrencyLimit(middleware.ConcurrencyConfig{
+		Limit:     cfg.Server.MaxConcurrent,
+		Overrides: cfg.Server.MaxConcurrentOverrides,
+		Queue:     cfg.Server.ConcurrencyQueue,
+		Wait:      cfg.Server.ConcurrencyWait,
+		Exempt:    []string{"/health", "/ready", "/metrics", "/api/v1/events/stream", "/api/v1/ws"},
+	}, middleware.ConcurrencyMetrics{InFlight: app.metrics.InFlight, Shed: app.metrics.Shed}))
+	if cfg.RequestLog.Enabled {
+		app.requestLogWriter = services.NewRequestLogWriter(app.dbManager, services.RequestLogWriterConfig{
+			BufferSize:    cfg.RequestLog.BufferSize,
+			BatchSize:     cfg.RequestLog.BatchSize,
+			FlushInterval: cfg.RequestLog.FlushInterval,
+		}, log, services.WithRequestLogMetrics(app.metrics.Business))
+		router.Use(middleware.RequestLog(app.requestLogWriter, middleware.RequestLogConfig{
+			Methods:     cfg.RequestLog.Methods,
+			Routes:      cfg.RequestLog.Routes,
+			BodyLimit:   cfg.RequestLog.BodyLimit,
+			SampleRate:  cfg.RequestLog.SampleRate,
+			SampleRates: cfg.RequestLog.SampleRates,
+		}))
+	}
+	for _, opt := range opts {
+		opt(app)
+	}
+	router.Use(app.middleware...)
+	app.logConfiguration()
+
+	// Closers run in reverse order, so the logger outlives the databases
+	if closer, ok := log.(io.Closer); ok {
+		app.lifecycle.AddCloser("logger", closer)
+	}
+
+	// Initialize database connections; an injected manager is owned by the caller
+	if !app.externalDB {
+		app.lifecycle.AddCloser("databases", lifecycle.CloseFunc(app.dbManager.CloseAll))
+		if err := app.initDatabase(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Registered before every other worker, so it drains after the ones
+	// that may submit to it
+	app.workers = app.newWorkerPool()
+	app.lifecycle.AddWorker("worker pool", app.workers)
+
 	// Initialize dependencies (services, controllers)
 	if err := app.initDependencies(); err != nil {
 		return nil, err
 	}
 
 	// Setup routes
-	app.setupRoutes()
+	if err := app.setupRoutes(); err != nil {
+		return nil, err
+	}
+
+	// Internal gRPC API for other services
+	if cfg.GRPC.Enabled {
+		if cfg.GRPC.AuthToken == "" {
+			return nil, errors.New("GRPC_AUTH_TOKEN is required when GRPC_ENABLED is true")
+		}
+		app.grpc = grpcserver.NewServer(app.userService, app.authService, cfg.GRPC.AuthToken, log)
+	}
 
 	// Create HTTP server
 	app.server = &http.Server{
@@ -74,166 +263,907 @@ func New(cfg *config.Config, log logger.Logger) (*Application, error) {
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
+	// Open streams never go idle, so end them as soon as the server starts draining
+	app.server.RegisterOnShutdown(app.broker.Close)
+	// Sockets are hijacked from the HTTP server, so its shutdown leaves them open
+	app.lifecycle.AddServer("websocket", app.hub)
+
 	return app, nil
 }
 
-// initDatabase initializes database connections
+// initDatabase initializes database connections. The primary database and
+// named databases marked required abort startup when they cannot connect;
+// optional ones are retried in the background.
 func (app *Application) initDatabase() error {
 	ctx := context.Background()
 
 	// Initialize primary database
-	driverType, driverConfig, err := app.config.Database.Primary.GetDatabaseDriverConfig()
+	factory := database.NewFactory()
+	primaryDriver, err := factory.CreateFromConnectionConfig(connectionConfig(app.config.Database.Primary, app.applicationName()))
 	if err != nil {
 		return err
 	}
 
-	factory := database.NewFactory()
-	primaryDriver, err := factory.CreateDriver(driverType, driverConfig)
+	app.setQueryTimeout()
+	app.setQueryAnnotations()
+	app.setRetryPolicy()
+	app.setCircuitBreaker("primary", app.config.Database.Primary)
+	if err := app.dbManager.ConnectDriver(ctx, "primary", primaryDriver, database.ConnectPolicy{Required: true}); err != nil {
+		return err
+	}
+
+	app.logger.Info("Primary database connected", logger.Field{Key: "driver", Value: primaryDriver.Type()})
+
+	if app.config.Database.AutoMigrate {
+		if err := app.runMigrations(ctx, primaryDriver); err != nil {
+			return err
+		}
+	}
+
+	// Initialize additional databases if configured
+	for name, dbConfig := range app.config.Database.Databases {
+		if err := app.connectNamedDatabase(ctx, factory, name, dbConfig); err != nil {
+			if dbConfig.Required {
+				return err
+			}
+			app.logger.Warn("Optional database unavailable", logger.Field{Key: "database", Value: name}, logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+
+	if replica := app.config.Database.ReadReplica; replica != "" {
+		if err := app.dbManager.SetReplica(database.PrimaryDriver, replica); err != nil {
+			return fmt.Errorf("read replica: %w", err)
+		}
+		app.logger.Info("Read replica configured", logger.Field{Key: "database", Value: replica})
+	}
+
+	return app.registerTenants(ctx)
+}
+
+// registerTenants routes the configured tenants to their databases, which
+// are migrated like the primary one when auto_migrate is set, and serves
+// them on their hosts
+func (app *Application) registerTenants(ctx context.Context) error {
+	migrated := map[string]bool{database.PrimaryDriver: true}
+	for tenant, name := range app.config.Database.Tenants {
+		if err := app.dbManager.RegisterTenant(tenant, name); err != nil {
+			return fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+
+		if app.config.Database.AutoMigrate && !migrated[name] {
+			driver, err := app.dbManager.GetDriver(name)
+			if err != nil {
+				return fmt.Errorf("tenant %s: %w", tenant, err)
+			}
+			if err := app.runMigrations(ctx, driver); err != nil {
+				return fmt.Errorf("tenant %s: %w", tenant, err)
+			}
+			migrated[name] = true
+		}
+
+		app.logger.Info("Tenant registered", logger.Field{Key: "tenant", Value: tenant}, logger.Field{Key: "database", Value: name})
+	}
+
+	for host, tenant := range app.config.Database.TenantHosts {
+		if err := app.dbManager.RegisterTenantHost(host, tenant); err != nil {
+			return fmt.Errorf("tenant host %s: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// connectNamedDatabase connects one entry of database.databases. Connection
+// failures of optional databases are retried and not returned; configuration
+// errors are returned either way since retrying cannot fix them.
+func (app *Application) connectNamedDatabase(ctx context.Context, factory *database.Factory, name string, dbConfig config.DatabaseConnectionConfig) error {
+	driver, err := factory.CreateFromConnectionConfig(connectionConfig(dbConfig, app.applicationName()))
 	if err != nil {
 		return err
 	}
+	driverType := driver.Type()
+	app.setCircuitBreaker(name, dbConfig)
 
-	if err := app.dbManager.AddDriver("primary", primaryDriver); err != nil {
+	policy := database.ConnectPolicy{
+		Required:      dbConfig.Required,
+		RetryInterval: app.config.Database.RetryInterval,
+		OnRetry: func(name string, err error) {
+			if err != nil {
+				app.logger.Warn("Optional database still unavailable", logger.Field{Key: "database", Value: name}, logger.Field{Key: "error", Value: err.Error()})
+				return
+			}
+			app.logger.Info("Database connected", logger.Field{Key: "name", Value: name}, logger.Field{Key: "driver", Value: driverType})
+		},
+	}
+	if err := app.dbManager.ConnectDriver(ctx, name, driver, policy); err != nil {
 		return err
 	}
 
-	// Connect to primary database
-	if err := primaryDriver.Connect(ctx); err != nil {
+	if _, err := app.dbManager.GetDriver(name); err != nil {
+		app.logger.Warn("Optional database unavailable, retrying in the background",
+			logger.Field{Key: "database", Value: name},
+			logger.Field{Key: "retry_interval", Value: policy.RetryInterval.String()},
+		)
+		return nil
+	}
+
+	app.logger.Info("Database connected", logger.Field{Key: "name", Value: name}, logger.Field{Key: "driver", Value: driverType})
+	return nil
+}
+
+// initCache initializes the cache store
+func (app *Application) initCache() {
+	var store cache.Store
+	switch app.config.Cache.Driver {
+	case "memory", "":
+		store = cache.NewMemoryStore()
+	default:
+		app.logger.Warn("Unsupported cache driver, falling back to memory", logger.Field{Key: "driver", Value: app.config.Cache.Driver})
+		store = cache.NewMemoryStore()
+	}
+	shared := cache.WithPrefix(store, app.config.Cache.Prefix)
+	// Cached rows are kept apart per database so tenants never see each other's entries
+	app.cache = cache.WithContextPrefix(shared, databaseCachePrefix)
+
+	// Background writes made while read-only are refused by the databases
+	app.readOnly = readonly.New(shared, readonly.WithRefreshInterval(app.config.ReadOnly.RefreshInterval))
+	app.dbManager.SetWriteGuard(app.readOnly.CheckWrite)
+}
+
+// newResponseCache returns the response cache, or nil when no route
+// would use it
+func (app *Application) newResponseCache() *services.ResponseCache {
+	enabled := app.config.Cache.ResponseTTL > 0
+	for _, ttl := range app.config.Cache.ResponseTTLOverrides {
+		enabled = enabled || ttl > 0
+	}
+	if !enabled {
+		return nil
+	}
+	return services.NewResponseCache(app.cache, app.config.Cache.ResponseTTL, app.config.Cache.ResponseTTLOverrides, app.logger)
+}
+
+// newOutboundClient returns a client calling other services with the
+// HTTP_CLIENT settings, logging every request and timing it per host;
+// adjust, if not nil, changes the settings for one caller
+func (app *Application) newOutboundClient(adjust func(cfg *httpclient.Config)) *http.Client {
+	cfg := outboundClientConfig(app.config)
+	if adjust != nil {
+		adjust(&cfg)
+	}
+	return httpclient.New(cfg, httpclient.Logging(app.logger), httpclient.Latency(app.metrics.ObserveOutbound))
+}
+
+// newFileStore opens the store of task results, which are kept on disk and
+// downloaded through links newFileLinks signs
+func newFileStore(cfg *config.Config) (*storage.LocalStore, error) {
+	return storage.NewLocalStore(cfg.Storage.Path)
+}
+
+// newFileLinks returns the signer of download links, which uses the JWT
+// secret when STORAGE_URL_SECRET is unset. Links signed with the previous
+// secret keep working for its grace period after start.
+func newFileLinks(cfg *config.Config) (*filetoken.Signer, error) {
+	secret := cfg.Storage.URLSecret
+	if secret == "" {
+		secret = cfg.JWT.Secret
+	}
+	return filetoken.NewSigner("/api/v1/files", secret,
+		filetoken.WithPreviousSecret(cfg.Storage.PreviousURLSecret, time.Now().Add(cfg.Storage.PreviousURLSecretGrace)))
+}
+
+// outboundClientConfig is the configuration of the HTTP clients calling
+// other services
+func outboundClientConfig(cfg *config.Config) httpclient.Config {
+	out := cfg.Outbound
+	return httpclient.Config{
+		Timeout:               out.Timeout,
+		ConnectTimeout:        out.ConnectTimeout,
+		ResponseHeaderTimeout: out.ResponseHeaderTimeout,
+		MaxIdleConnsPerHost:   out.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       out.MaxConnsPerHost,
+		MaxRetries:            out.MaxRetries,
+		RetryBackoff:          out.RetryBackoff,
+		UserAgent:             httpclient.UserAgent(cfg.App.Name),
+	}
+}
+
+// newWorkerPool starts the pool sized by WORKER_POOL_SIZE
+func (app *Application) newWorkerPool() *workerpool.Pool {
+	cfg := app.config.WorkerPool
+	size := cfg.Size
+	if size <= 0 {
+		size = runtime.NumCPU()
+	}
+	return workerpool.New("default", workerpool.Config{
+		Size:       size,
+		QueueDepth: cfg.QueueDepth,
+		Mode:       workerpool.Mode(cfg.Mode),
+	}, app.logger, workerpool.WithMetrics(workerpool.Metrics{
+		Queued:   app.metrics.WorkerPoolQueued,
+		InFlight: app.metrics.WorkerPoolInFlight,
+		Rejected: app.metrics.WorkerPoolRejected,
+	}))
+}
+
+// newPasswordPolicy returns the policy screening new passwords, or nil
+// without AUTH_PASSWORD_BREACH_CHECK
+func (app *Application) newPasswordPolicy() services.PasswordPolicy {
+	cfg := app.config.Auth
+	if !cfg.PasswordBreachCheck {
+		return nil
+	}
+	// Registrations and password changes wait for the check
+	client := app.newOutboundClient(func(c *httpclient.Config) {
+		c.Timeout = cfg.PasswordBreachTimeout
+		c.MaxRetries = 0
+	})
+	return services.NewPwnedPasswords(client, cfg.PasswordBreachURL, cfg.PasswordBreachThreshold, cfg.PasswordBreachFailClosed, app.logger, services.WithBreachCheckPool(app.workers))
+}
+
+// initMessaging connects to the configured message broker. Without one,
+// events only reach subscribers in this process.
+func (app *Application) initMessaging() error {
+	switch app.config.Messaging.Driver {
+	case "memory", "":
+		return nil
+	case "nats":
+	default:
+		app.logger.Warn("Unsupported messaging driver, keeping events in-process", logger.Field{Key: "driver", Value: app.config.Messaging.Driver})
+		return nil
+	}
+
+	cfg := app.config.Messaging.NATS
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+
+	client, err := natsmessaging.Connect(ctx, cfg, app.logger)
+	if err != nil {
 		return err
 	}
+	app.messaging = client
+	// Closed after the workers, so the consumer has settled its events
+	app.lifecycle.AddCloser("nats", client)
 
-	app.logger.Info("Primary database connected", logger.Field{Key: "driver", Value: driverType})
+	// Events come back through the shared consumer, so each reaches the
+	// in-process subscribers of one replica
+	app.eventConsumer, err = natsmessaging.NewConsumer(ctx, client, func(ctx context.Context, event events.Event) error {
+		return app.events.Publish(ctx, event)
+	}, app.logger)
+	if err != nil {
+		return err
+	}
 
-	// Initialize additional databases if configured
-	for name, dbConfig := range app.config.Database.Databases {
-		driverType, driverConfig, err := dbConfig.GetDatabaseDriverConfig()
-		if err != nil {
-			app.logger.Warn("Failed to get driver config", logger.Field{Key: "database", Value: name}, logger.Field{Key: "error", Value: err.Error()})
-			continue
-		}
+	// Publishing fails while the broker is down, but the API keeps working
+	app.health.Register(health.NewCheck("nats", false, client.Health))
+	return nil
+}
 
-		driver, err := factory.CreateDriver(driverType, driverConfig)
-		if err != nil {
-			app.logger.Warn("Failed to create driver", logger.Field{Key: "database", Value: name}, logger.Field{Key: "error", Value: err.Error()})
-			continue
-		}
+// databaseCachePrefix namespaces cache keys of requests scoped to a tenant database
+func databaseCachePrefix(ctx context.Context) string {
+	if name := database.DriverName(ctx); name != database.PrimaryDriver {
+		return "db:" + name
+	}
+	return ""
+}
 
-		if err := app.dbManager.AddDriver(name, driver); err != nil {
-			app.logger.Warn("Failed to add driver", logger.Field{Key: "database", Value: name}, logger.Field{Key: "error", Value: err.Error()})
-			continue
+// initAuditForwarder builds the queue streaming audit entries to the SIEM
+// of AUDIT_FORWARDER_DRIVER. Without one they stay in the database only.
+func (app *Application) initAuditForwarder() error {
+	cfg := app.config.AuditForwarder
+
+	var forwarder audit.Forwarder
+	switch cfg.Driver {
+	case "":
+		return nil
+	case "syslog":
+		syslog := audit.SyslogConfig{
+			Address: cfg.Address,
+			AppName: app.config.App.Name,
+			Timeout: cfg.Timeout,
+		}
+		if cfg.TLS {
+			syslog.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+			if cfg.TLSCAFile != "" {
+				pem, err := os.ReadFile(cfg.TLSCAFile)
+				if err != nil {
+					return fmt.Errorf("failed to read AUDIT_FORWARDER_TLS_CA_FILE: %w", err)
+				}
+				syslog.TLS.RootCAs = x509.NewCertPool()
+				if !syslog.TLS.RootCAs.AppendCertsFromPEM(pem) {
+					return fmt.Errorf("no certificates in AUDIT_FORWARDER_TLS_CA_FILE %s", cfg.TLSCAFile)
+				}
+			}
 		}
+		forwarder = audit.NewSyslogForwarder(syslog)
+	case "http":
+		// The queue retries on its own
+		client := app.newOutboundClient(func(c *httpclient.Config) {
+			c.Timeout = cfg.Timeout
+			c.MaxRetries = 0
+		})
+		forwarder = audit.NewHTTPForwarder(client, cfg.URL, cfg.Token)
+	default:
+		app.logger.Warn("Unsupported audit forwarder driver, keeping audit entries in the database only", logger.Field{Key: "driver", Value: cfg.Driver})
+		return nil
+	}
 
-		if err := driver.Connect(ctx); err != nil {
-			app.logger.Warn("Failed to connect driver", logger.Field{Key: "database", Value: name}, logger.Field{Key: "error", Value: err.Error()})
-			continue
+	app.auditForwarder = audit.NewQueue(forwarder, audit.QueueConfig{
+		Size:           cfg.QueueSize,
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: cfg.InitialBackoff,
+		SpillPath:      cfg.SpillPath,
+		ReplayInterval: cfg.ReplayInterval,
+	}, app.logger, audit.WithMetrics(app.metrics.Business))
+	return nil
+}
+
+// initGeoIP builds the resolver of GEOIP_DRIVER locating logins. A
+// database that cannot be read fails startup; lookups failing later only
+// leave locations unknown.
+func (app *Application) initGeoIP() error {
+	cfg := app.config.GeoIP
+	switch cfg.Driver {
+	case "none", "":
+		app.geo = geoip.None{}
+	case "maxmind":
+		db, err := geoip.OpenMaxMind(cfg.MaxMindPath, app.logger)
+		if err != nil {
+			return fmt.Errorf("GEOIP_MAXMIND_PATH: %w", err)
 		}
+		app.geo, app.geoDB = db, db
+	case "http":
+		// Lookups are bounded by GEOIP_TIMEOUT, which leaves no time for retries
+		client := app.newOutboundClient(func(c *httpclient.Config) {
+			c.Timeout = cfg.Timeout
+			c.MaxRetries = 0
+		})
+		app.geo = geoip.NewHTTPResolver(client, cfg.HTTPURL, app.cache, cfg.HTTPCacheTTL)
+	default:
+		app.logger.Warn("Unsupported GeoIP driver, leaving locations unknown", logger.Field{Key: "driver", Value: cfg.Driver})
+		app.geo = geoip.None{}
+	}
+	return nil
+}
+
+// runMigrations applies pending migrations to the given database
+func (app *Application) runMigrations(ctx context.Context, driver database.Driver) error {
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		return err
+	}
 
-		app.logger.Info("Database connected", logger.Field{Key: "name", Value: name}, logger.Field{Key: "driver", Value: driverType})
+	applied, err := migrations.NewRunner(db).Up(ctx)
+	if err != nil {
+		return err
 	}
 
+	for _, id := range applied {
+		app.logger.Info("Migration applied", logger.Field{Key: "migration", Value: id})
+	}
 	return nil
 }
 
 // initDependencies initializes services and controllers
 func (app *Application) initDependencies() error {
+	// Initialize cache
+	app.initCache()
+
+	// In-process event stream for domain events
+	app.events = events.NewBus()
+	if err := app.initMessaging(); err != nil {
+		return err
+	}
+	var publisher events.Publisher = app.events
+	if app.messaging != nil {
+		publisher = app.messaging
+	}
+
+	// Realtime streams to connected backoffice clients
+	app.broker = sse.NewBroker(app.config.Stream.ClientBuffer, app.logger)
+	app.hub = ws.NewHub(ws.Options{
+		SendQueue:    app.config.Socket.SendQueue,
+		PingInterval: app.config.Socket.PingInterval,
+		WriteTimeout: app.config.Socket.WriteTimeout,
+	}, app.authorizeTopic, app.logger)
+
+	// Revocations must outlive the tokens they cover
+	revoker := services.NewTokenRevoker(app.cache, app.config.JWT.Expiration)
+	app.responses = app.newResponseCache()
+
 	// Initialize services
-	app.authService = services.NewAuthService(app.dbManager, app.config, app.logger)
-	app.userService = services.NewUserService(app.dbManager, app.logger)
+	if err := app.initAuditForwarder(); err != nil {
+		return err
+	}
+	if err := app.initGeoIP(); err != nil {
+		return err
+	}
+	var auditOpts []services.AuditOption
+	if app.auditForwarder != nil {
+		auditOpts = append(auditOpts, services.WithAuditForwarding(app.auditForwarder))
+	}
+	app.auditService = services.NewAuditService(app.dbManager, app.logger, auditOpts...)
+	app.permissionService = services.NewPermissionService(services.NewPermissionRepository(app.dbManager), app.cache, app.logger)
+	app.notifications = services.NewNotificationService(services.NewNotificationRepository(app.dbManager), realtimePublishers{app.broker, app.hub}, app.logger)
+	app.settingsService = services.NewSettingsService(services.NewSettingsRepository(app.dbManager), app.cache, app.auditService, app.logger, services.WithSettingsResponseCache(app.responses))
+	app.orgService = services.NewOrganizationService(services.NewOrganizationRepository(app.dbManager), app.logger)
+	sessionLimits := services.WithSessionLimits(app.config.Auth.SessionIdleTimeout, app.config.Auth.SessionAbsoluteLifetime, app.config.Auth.SessionClockSkew)
+	app.sessions = services.NewSessionService(services.NewSessionRepository(app.dbManager), revoker, app.cache, app.auditService, app.logger, sessionLimits)
+	app.devices = services.NewTrustedDeviceService(services.NewTrustedDeviceRepository(app.dbManager), app.sessions, app.auditService, app.logger)
+	app.quotas = services.NewQuotaService(services.NewUsageRepository(app.dbManager), app.cache, app.auditService, int64(app.config.API.Quota.Monthly), app.logger)
+	app.policies = services.NewPolicyService(services.NewPolicyAcceptanceRepository(app.dbManager), app.settingsService, app.auditService, app.logger, services.WithPolicyResponseCache(app.responses))
+	if app.emailClient == nil {
+		switch app.config.Email.Driver {
+		case "log":
+			app.emailClient = email.NewLogClient(app.logger)
+		case "sendgrid":
+			// Mail sends are not idempotent, so they are not retried
+			client := app.newOutboundClient(func(cfg *httpclient.Config) { cfg.MaxRetries = 0 })
+			app.emailClient = email.NewSendGridClient(client, app.config.Email.SendGridURL, app.config.Email.SendGridAPIKey, app.config.Email.From)
+		}
+	}
+	emailTemplates := services.NewEmailTemplates()
+	// Flag definitions are cached with a short TTL so edits reach every replica
+	app.featureFlags = featureflags.NewService(featureflags.NewRepository(app.dbManager), app.cache, app.logger)
+	app.signInAlerts = services.NewSignInAlertService(services.NewKnownSignInRepository(app.dbManager), app.emailClient, app.featureFlags, app.cache, app.config.Auth.SecureAccountURL, app.config.Auth.SecureAccountExpiration, app.logger, services.WithSignInAlertTemplates(emailTemplates), services.WithSignInAlertMetrics(app.metrics.Business))
+	emailChanges := services.NewEmailChangeService(services.NewEmailChangeRepository(app.dbManager), app.emailClient, app.cache, revoker, app.sessions, app.auditService, app.config.Auth.EmailChangeExpiration, app.logger, services.WithEmailChangeMetrics(app.metrics.Business), services.WithEmailChangeNormalization(services.EmailNormalization(app.config.Auth)), services.WithEmailChangeResponseCache(app.responses), services.WithEmailChangeTemplates(emailTemplates))
+	ids, err := identifier.NewGenerator(identifier.Format(app.config.App.IDFormat))
+	if err != nil {
+		return fmt.Errorf("APP_ID_FORMAT: %w", err)
+	}
+	passwords := app.newPasswordPolicy()
+	protectedRoles, err := services.ParseRoleTransitions(app.config.Approvals.ProtectedRoleChanges)
+	if err != nil {
+		return fmt.Errorf("APPROVALS_PROTECTED_ROLE_CHANGES: %w", err)
+	}
+	app.profiles, err = services.NewProfileService(app.dbManager, app.config.Auth.ProfileRequiredFields)
+	if err != nil {
+		return fmt.Errorf("AUTH_PROFILE_REQUIRED_FIELDS: %w", err)
+	}
+	app.approvals = services.NewApprovalService(services.NewApprovalRepository(app.dbManager), app.auditService, app.notifications, app.config.Approvals.TTL, app.logger)
+	authService := services.NewAuthService(app.dbManager, app.config, app.cache, revoker, app.auditService, app.orgService, app.logger, services.WithSessions(app.sessions), services.WithTrustedDevices(app.devices), services.WithPolicies(app.policies), services.WithAuthMetrics(app.metrics.Business), services.WithAuthResponseCache(app.responses), services.WithAuthIDGenerator(ids), services.WithSignInAlerts(app.signInAlerts), services.WithAuthPasswordPolicy(passwords), services.WithProfiles(app.profiles), services.WithLoginEvents(app.events), services.WithGeoIP(app.geo, app.config.GeoIP.Timeout))
+	app.authService = authService
+	app.users = services.NewUserService(app.dbManager, app.cache, revoker, app.auditService, publisher, app.logger, services.WithUserMetrics(app.metrics.Business), services.WithEmailNormalization(services.EmailNormalization(app.config.Auth)), services.WithUserResponseCache(app.responses), services.WithUserIDGenerator(ids), services.WithUserScopes(app.permissionService, services.DefaultUserScopes...), services.WithUserPasswordPolicy(passwords), services.WithRoleApprovals(app.approvals, protectedRoles))
+	if app.userService == nil {
+		app.userService = app.users
+	}
+	if err := app.initAuthWatch(); err != nil {
+		return err
+	}
+
+	files, err := newFileStore(app.config)
+	if err != nil {
+		return err
+	}
+	app.files = files
+	if app.links, err = newFileLinks(app.config); err != nil {
+		return err
+	}
 
-	// Initialize controllers
-	app.authController = auth.NewAuthController(app.authService)
-	app.userController = user.NewUserController(app.userService)
+	// Readiness checks every dependency; new infrastructure only registers
+	// its checker here
+	app.health.RegisterSource(app.dbManager.HealthChecks)
+	if app.config.Database.DeepHealthCheck {
+		// Only the writer takes writes, so tenant databases are not probed
+		instance, _ := os.Hostname()
+		app.health.Register(app.dbManager.WriteProbe(database.PrimaryDriver, database.WriteProbeConfig{
+			InstanceID: instance,
+			Timeout:    app.config.Database.DeepHealthCheckTimeout,
+			Failures:   app.config.Database.DeepHealthCheckFailures,
+		}))
+	}
+	app.health.Register(storage.NewHealthCheck(app.files, false))
+	if pinger, ok := app.emailClient.(email.Pinger); ok {
+		app.health.Register(email.NewHealthCheck(pinger, false))
+	}
+	app.tasks = services.NewTaskService(services.NewTaskRepository(app.dbManager), app.files, app.links, app.notifications, app.config.Tasks.Workers, app.config.Storage.URLExpiration, app.logger)
+	app.requestLogs = services.NewRequestLogService(app.dbManager, app.logger)
+
+	app.partners = services.NewPartnerService(services.NewPartnerRepository(app.dbManager), app.users, app.auditService, app.logger)
+	oidcService, err := services.NewOIDCService(app.config.OIDC, app.users, services.NewIdentityRepository(app.dbManager), authService, app.cache, app.auditService, app.logger,
+		services.WithOIDCHTTPClient(app.newOutboundClient(nil)))
+	if err != nil {
+		return fmt.Errorf("oidc.providers: %w", err)
+	}
+
+	// Deliver published events to webhooks in the background
+	webhookRepo := services.NewWebhookRepository(app.dbManager)
+	app.webhookService = services.NewWebhookService(webhookRepo, app.logger)
+	app.webhookDispatcher = services.NewWebhookDispatcher(webhookRepo, app.config.Webhooks, app.logger, services.WithWebhookMetrics(app.metrics.Business),
+		services.WithWebhookHTTPClient(app.newOutboundClient(func(cfg *httpclient.Config) {
+			cfg.Timeout = app.config.Webhooks.Timeout
+			cfg.MaxRetries = 0
+		})))
+	app.events.Subscribe(app.webhookDispatcher.HandleEvent)
+	app.events.Subscribe(app.publishSocketEvent)
+
+	// Initialize controllers. List endpoints share the configured page
+	// sizes; audit trails may be read in larger pages.
+	pages := pagination.Config{DefaultLimit: app.config.API.DefaultPageSize, MaxLimit: app.config.API.MaxPageSize}
+	savedFilters := services.NewSavedFilterService(services.NewSavedFilterRepository(app.dbManager), app.permissionService, app.logger)
+	loginLimit := route.RateLimit{Requests: app.config.Auth.LoginRateLimit, Window: time.Minute, Mode: ratelimit.Mode(app.config.Auth.LoginRateLimitMode)}
+	app.controllers = routes.Controllers{
+		Auth:          auth.NewAuthController(app.authService, auth.WithLoginRateLimit(loginLimit)),
+		EmailChange:   auth.NewEmailChangeController(emailChanges),
+		SecureAccount: auth.NewSecureAccountController(authService),
+		OIDC:          auth.NewOIDCController(oidcService, auth.WithOIDCRateLimit(loginLimit)),
+		User:          user.NewUserController(app.userService, pages, user.WithSavedFilters(savedFilters)),
+		Activity:      user.NewActivityController(services.NewActivityService(app.dbManager, app.logger), pages.WithMax(app.config.API.AuditMaxPageSize)),
+		Session:       user.NewSessionController(app.sessions, authService),
+		Device:        user.NewDeviceController(app.devices),
+		SavedFilter:   user.NewSavedFilterController(savedFilters),
+		Policy:        user.NewPolicyController(app.userService, app.policies, user.WithProfiles(app.profiles)),
+		Profile:       user.NewProfileController(app.userService, app.authService, app.profiles),
+		Usage:         user.NewUsageController(app.quotas),
+		Export:        user.NewExportController(app.users, app.tasks, app.files, app.config.Tasks.ExportBatchSize),
+		Organization:  organization.NewOrganizationController(app.orgService, pages),
+		Permission:    permission.NewPermissionController(app.permissionService),
+		Webhook:       webhook.NewWebhookController(app.webhookService, app.webhookDispatcher, pages),
+		Partner:       partner.NewPartnerController(app.partners),
+		Feature:       feature.NewFeatureController(app.featureFlags),
+		Settings:      admin.NewSettingsController(app.settingsService),
+		Meta:          meta.NewMetaController(app.build, app.config.API.Features.Effective()),
+		Tenant:        admin.NewTenantController(services.NewTenantService(app.dbManager, app.auditService, app.logger)),
+		Database:      admin.NewDatabaseController(services.NewDatabaseService(app.dbManager, app.connectRuntimeDatabase, app.config.Database.RegistrationTimeout, app.auditService, app.logger)),
+		Email:         admin.NewEmailController(services.NewEmailTemplateService(emailTemplates, app.emailClient, app.auditService, app.logger)),
+		Impersonation: admin.NewImpersonationController(authService),
+		Notification:  notification.NewNotificationController(app.notifications, pages.WithDefault(20)),
+		Stream:        stream.NewStreamController(app.broker, app.config.Stream.HeartbeatInterval),
+		Socket:        stream.NewSocketController(app.hub, app.config.Socket.AllowedOrigins),
+		Task:          task.NewTaskController(app.tasks),
+		File:          file.NewFileController(app.files, app.links, app.authService),
+		Routes:        admin.NewRoutesController(app.RouteTable),
+		RateLimit:     admin.NewRateLimitController(ratelimit.New(app.cache)),
+		Audit:         admin.NewAuditController(app.auditService),
+		RequestLog:    admin.NewRequestLogController(app.requestLogs, pages),
+		Approval:      admin.NewApprovalController(app.approvals, pages),
+		Alert:         admin.NewAlertController(app.authWatch, pages),
+		ReadOnly:      admin.NewReadOnlyController(services.NewReadOnlyService(app.readOnly, app.auditService, app.logger)),
+		JWTSecret:     admin.NewJWTSecretController(services.NewJWTSecretService(authService.Tokens(), app.config, app.auditService, app.logger)),
+		Product:       product.NewResource(app.dbManager, pages, crud.WithAudit(app.auditService), crud.WithResponseCache(app.responses), crud.WithLogger(app.logger)),
+	}
+
+	// Initialize background jobs
+	if err := app.initJobs(); err != nil {
+		return err
+	}
+	app.controllers.Jobs = admin.NewJobsController(app.scheduler, app.notifications)
+	jobsCfg := app.config.Jobs
+	app.controllers.Jobs.SetPreview(services.JobUserPurge, func(ctx context.Context) (*services.ConfirmationSummary, error) {
+		return services.PreviewUserPurge(ctx, app.users, jobsCfg.UserPurgeAfter, userPurgeOptions(jobsCfg))
+	})
 
 	return nil
 }
 
-// setupRoutes sets up all application routes
-func (app *Application) setupRoutes() {
-	// Health check
-	app.router.GET("/health", app.healthCheck)
-	app.router.GET("/ready", app.readinessCheck)
-
-	// API routes
-	api := app.router.Group("/api/v1")
-	{
-		// Auth routes
-		authGroup := api.Group("/auth")
-		{
-			authGroup.POST("/register", app.authController.Register)
-			authGroup.POST("/login", app.authController.Login)
-			authGroup.POST("/logout", app.authController.Logout)
-			authGroup.POST("/refresh", app.authController.RefreshToken)
+// initAuthWatch subscribes the authentication watch to login events. Its
+// blocks are enforced by the rate limiter, so block_ip needs logins to be
+// rate limited.
+func (app *Application) initAuthWatch() error {
+	cfg := app.config.AuthWatch
+	locator, err := authwatch.NewNetworkLocator(cfg.Locations)
+	if err != nil {
+		return fmt.Errorf("AUTHWATCH_LOCATIONS: %w", err)
+	}
+	for _, mitigations := range [][]string{cfg.ImpossibleTravel.Mitigations, cfg.CredentialStuffing.Mitigations, cfg.FailureSpike.Mitigations} {
+		if slices.Contains(mitigations, models.MitigationBlockIP) && app.config.Auth.LoginRateLimit <= 0 {
+			return fmt.Errorf("AUTHWATCH: %s needs AUTH_LOGIN_RATE_LIMIT, as blocks are enforced on rate-limited routes", models.MitigationBlockIP)
 		}
+	}
+
+	app.authWatch, err = authwatch.NewWatch(cfg, authwatch.NewRepository(app.dbManager), app.cache, ratelimit.New(app.cache), app.users, app.notifications, app.auditService, app.logger, authwatch.WithLocator(locator))
+	if err != nil {
+		return fmt.Errorf("AUTHWATCH: %w", err)
+	}
+	app.events.Subscribe(app.authWatch.HandleEvent)
+	return nil
+}
 
-		// User routes
-		usersGroup := api.Group("/users")
-		{
-			usersGroup.GET("", app.userController.ListUsers)
-			usersGroup.GET("/:id", app.userController.GetUser)
-			usersGroup.POST("", app.userController.CreateUser)
-			usersGroup.PUT("/:id", app.userController.UpdateUser)
-			usersGroup.DELETE("/:id", app.userController.DeleteUser)
+// initJobs registers the maintenance jobs; the scheduler is started by Start
+func (app *Application) initJobs() error {
+	var locker jobs.Locker
+	primaryDriver, err := app.dbManager.GetDriver("primary")
+	if err == nil {
+		locker, err = jobs.NewDBLocker(primaryDriver)
+	}
+	if err != nil {
+		app.logger.Warn("Database job locks unavailable, falling back to in-process locks", logger.Field{Key: "error", Value: err.Error()})
+		locker = jobs.NewMemoryLocker()
+	}
+
+	app.scheduler = jobs.NewScheduler(locker, app.logger)
+	app.scheduler.OnStatus(app.publishJobStatus)
+
+	cfg := app.config.Jobs
+	for _, job := range []jobs.Job{
+		services.NewAuditCleanupJob(app.auditService, cfg.AuditCleanupSchedule, cfg.AuditRetention, app.logger),
+		services.NewWebhookDeliveryCleanupJob(app.webhookService, cfg.WebhookDeliveryCleanupSchedule, cfg.WebhookDeliveryRetention, app.logger),
+		services.NewNotificationCleanupJob(app.notifications, cfg.NotificationCleanupSchedule, cfg.NotificationRetention, app.logger),
+		services.NewSessionCleanupJob(app.sessions, app.devices, cfg.SessionCleanupSchedule, cfg.SessionRetention, cfg.TrustedDeviceRetention, app.logger),
+		services.NewTaskCleanupJob(app.tasks, app.config.Tasks.CleanupSchedule, app.config.Tasks.Retention, app.logger),
+		services.NewRequestLogCleanupJob(app.requestLogs, cfg.RequestLogCleanupSchedule, cfg.RequestLogRetention, app.logger),
+		services.NewUserPurgeJob(app.users, cfg.UserPurgeSchedule, cfg.UserPurgeAfter, userPurgeOptions(cfg), app.metrics.UserPurge, app.logger),
+	} {
+		if err := app.scheduler.Register(job); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// userPurgeOptions are the options of the user purge job
+func userPurgeOptions(cfg config.JobsConfig) services.UserPurgeOptions {
+	return services.UserPurgeOptions{
+		BatchSize: cfg.UserPurgeBatchSize,
+		Limit:     cfg.UserPurgeMaxPerRun,
+		DryRun:    cfg.UserPurgeDryRun,
+	}
+}
+
+// publishJobStatus streams job status changes to users allowed to manage jobs
+func (app *Application) publishJobStatus(status jobs.Status) {
+	canManageJobs := func(client *sse.Client) bool {
+		allowed, err := app.permissionService.HasPermission(context.Background(), client.Role, models.PermissionJobsManage)
+		return err == nil && allowed
+	}
+	app.broker.PublishFunc(canManageJobs, "job.status", status)
+	// Socket clients were authorized when they subscribed
+	app.hub.PublishFunc(func(*ws.Client) bool { return true }, "job.status", status)
+}
+
+// publishSocketEvent sends a domain event to the sockets subscribed to it in
+// the tenant it happened in
+func (app *Application) publishSocketEvent(ctx context.Context, event events.Event) {
+	tenant := database.TenantID(ctx)
+	app.hub.PublishFunc(func(client *ws.Client) bool { return client.TenantID == tenant }, event.Type, event)
+}
+
+// authorizeTopic decides which socket topics a client may subscribe to: its
+// own notifications, job status with jobs.manage and user events with
+// users.manage
+func (app *Application) authorizeTopic(client *ws.Client, topic string) error {
+	var permission string
+	switch {
+	case topic == services.StreamEventNotification:
+		return nil
+	case topic == "job.status":
+		permission = models.PermissionJobsManage
+	case events.Known(topic):
+		permission = models.PermissionUsersManage
+	default:
+		return ws.ErrUnknownTopic
+	}
+
+	allowed, err := app.permissionService.HasPermission(context.Background(), client.Role, permission)
+	if err != nil || !allowed {
+		return ws.ErrForbiddenTopic
+	}
+	return nil
+}
+
+// realtimePublishers sends realtime events to both the SSE streams and the
+// sockets
+type realtimePublishers []services.RealtimePublisher
+
+// Publish sends the event through every publisher
+func (p realtimePublishers) Publish(userID, event string, data interface{}) {
+	for _, publisher := range p {
+		publisher.Publish(userID, event, data)
+	}
+}
+
+// setupRoutes sets up all application routes
+func (app *Application) setupRoutes() error {
+	deps := routes.Dependencies{
+		Tokens:        app.authService,
+		Permissions:   app.permissionService,
+		Databases:     app.dbManager,
+		Tenants:       app.dbManager,
+		Responses:     app.responses,
+		Features:      app.config.API.Features,
+		Quotas:        app.quotas,
+		QuotaSessions: app.config.API.Quota.Sessions,
+
+		Partners:               app.partners,
+		PartnerSignatureWindow: app.config.Partners.SignatureWindow,
+		PartnerReplays:         app.cache,
+
+		DatabaseRegistration: app.config.Database.RuntimeRegistration,
+		V1Deprecation:        app.config.API.V1Deprecation,
+		RateLimits:           app.cache,
+		RateLimitMode:        ratelimit.Mode(app.config.API.RateLimitMode),
+		RateLimitWouldBlock:  app.metrics.RateLimitWouldBlock,
+		ReadYourWrites:       app.cache,
+		ReadYourWritesWindow: app.config.Database.ReadYourWritesWindow,
+		ReadOnly:             app.readOnly,
+
+		// Health check
+		Probes: []route.Definition{
+			{Method: http.MethodGet, Path: "/health", Handler: app.healthCheck},
+			{Method: http.MethodGet, Path: "/ready", Handler: app.readinessCheck},
+			{Method: http.MethodGet, Path: "/metrics", Handler: gin.WrapH(app.metrics.Handler())},
+		},
+	}
+	if app.config.Auth.RequirePolicyAcceptance {
+		deps.Policies = app.policies
+	}
+	if confirm := app.config.API.Confirmation; confirm.Enabled {
+		deps.Confirmations = services.NewConfirmations(app.cache, confirm.TTL, confirm.ExemptScopes)
+	}
+
+	table, err := routes.SetupRoutes(app.router, &app.controllers, deps)
+	if err != nil {
+		return err
+	}
+	app.routeTable = table
+	return nil
+}
+
+// RouteTable lists the registered routes with their policies
+func (app *Application) RouteTable() route.Table {
+	return app.routeTable
 }
 
 // healthCheck handles health check requests
 func (app *Application) healthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"service": app.config.App.Name,
-		"version": app.config.App.Version,
-		"uptime":  time.Since(startTime).String(),
+		"status":     "healthy",
+		"service":    app.config.App.Name,
+		"version":    app.build.Version,
+		"commit":     app.build.Commit,
+		"build_date": app.build.BuildDate,
+		"uptime":     time.Since(startTime).String(),
+		"read_only":  app.readOnly.State(c.Request.Context()),
 	})
 }
 
-// readinessCheck handles readiness check requests
+// BuildInfo returns the running build, with APP_VERSION, when set, in place
+// of the version the binary was built with
+func BuildInfo(cfg *config.Config) buildinfo.Info {
+	info := buildinfo.Get()
+	if cfg.App.Version != "" {
+		info.Version = cfg.App.Version
+	}
+	return info
+}
+
+// readinessCheck handles readiness check requests. A critical check that
+// fails makes the service not ready; an informational one only marks it
+// degraded. ?exclude=a,b skips informational checks and ?verbose=true adds
+// each check's latency, error and last success.
 func (app *Application) readinessCheck(c *gin.Context) {
-	ctx := c.Request.Context()
-	healthResults := app.dbManager.Health(ctx)
+	// Not ready before Start has finished or once shutdown has begun
+	if !app.lifecycle.Ready() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "not ready",
+		})
+		return
+	}
 
-	allHealthy := true
-	for name, err := range healthResults {
-		if err != nil {
-			allHealthy = false
-			app.logger.Error("Database health check failed", logger.Field{Key: "database", Value: name}, logger.Field{Key: "error", Value: err.Error()})
+	var exclude []string
+	for _, value := range c.QueryArray("exclude") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				exclude = append(exclude, name)
+			}
+		}
+	}
+	report := app.health.Run(c.Request.Context(), exclude...)
+	if !report.Cached {
+		for name, result := range report.Checks {
+			if result.Status == health.StatusUp {
+				continue
+			}
+			fields := []logger.Field{{Key: "check", Value: name}, {Key: "error", Value: result.Error}}
+			if result.Critical {
+				app.logger.Error("Readiness check failed", fields...)
+			} else {
+				app.logger.Warn("Informational readiness check failed", fields...)
+			}
 		}
 	}
 
-	if allHealthy {
-		c.JSON(http.StatusOK, gin.H{
-			"status":    "ready",
-			"databases": healthResults,
-		})
-	} else {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"status":    "not ready",
-			"databases": healthResults,
-		})
+	code := http.StatusOK
+	if report.Status == health.StatusNotReady {
+		code = http.StatusServiceUnavailable
+	}
+	if verbose, _ := strconv.ParseBool(c.Query("verbose")); verbose {
+		c.JSON(code, report)
+		return
 	}
+
+	checks := make(map[string]gin.H, len(report.Checks))
+	for name, result := range report.Checks {
+		checks[name] = gin.H{"status": result.Status, "critical": result.Critical}
+	}
+	c.JSON(code, gin.H{
+		"status": report.Status,
+		"checks": checks,
+	})
 }
 
-// Start starts the HTTP server
+// Start opens the listeners, starts the background workers and serves HTTP
+// until the server is shut down. Everything started here is registered with
+// the lifecycle so Shutdown stops it in order.
 func (app *Application) Start() error {
+	lis, err := net.Listen("tcp", app.server.Addr)
+	if err != nil {
+		return err
+	}
+	app.lifecycle.AddServer("http", lifecycle.StopFunc(app.server.Shutdown))
+
+	if app.grpc != nil {
+		grpcLis, err := net.Listen("tcp", app.config.Server.Host+":"+app.config.GRPC.Port)
+		if err != nil {
+			lis.Close()
+			return err
+		}
+
+		app.logger.Info("Starting gRPC server", logger.Field{Key: "port", Value: app.config.GRPC.Port})
+		go func() {
+			if err := app.grpc.Serve(grpcLis); err != nil {
+				app.logger.Error("gRPC server stopped", logger.Field{Key: "error", Value: err.Error()})
+			}
+		}()
+		app.lifecycle.AddServer("grpc", app.grpc)
+	}
+
+	// Stopped last, so the entries audited by every other worker are
+	// forwarded or spilled
+	if app.auditForwarder != nil {
+		app.auditForwarder.Start()
+		app.lifecycle.AddWorker("audit forwarder", app.auditForwarder)
+	}
+
+	// Stopped after the other workers, so the usage of requests still
+	// draining is flushed
+	app.quotas.Start(app.config.API.Quota.FlushInterval)
+	app.lifecycle.AddWorker("usage flusher", app.quotas)
+
+	// Stopped after the HTTP server, so the requests it drained are written
+	if app.requestLogWriter != nil {
+		app.requestLogWriter.Start()
+		app.lifecycle.AddWorker("request log writer", app.requestLogWriter)
+	}
+
+	// Workers stop in reverse order: jobs first, then the webhook deliveries they may queue
+	app.webhookDispatcher.Start()
+	app.lifecycle.AddWorker("webhook dispatcher", app.webhookDispatcher)
+	if app.eventConsumer != nil {
+		if err := app.eventConsumer.Start(); err != nil {
+			lis.Close()
+			return err
+		}
+		// Stopped before the dispatcher it feeds
+		app.lifecycle.AddWorker("event consumer", app.eventConsumer)
+	}
+	if app.config.Jobs.Enabled {
+		app.scheduler.Start()
+	}
+	// Registered even when disabled so jobs started from the admin API are waited for
+	app.lifecycle.AddWorker("job scheduler", app.scheduler)
+	app.lifecycle.AddWorker("sign-in alerts", app.signInAlerts)
+	app.lifecycle.AddWorker("authentication watch", app.authWatch)
+	if app.geoDB != nil {
+		app.geoDB.Start(app.config.GeoIP.ReloadInterval)
+		app.lifecycle.AddWorker("GeoIP database", app.geoDB)
+	}
+	// Stopped first: running tasks are cancelled rather than waited for
+	app.lifecycle.AddWorker("task runner", app.tasks)
+
 	app.logger.Info("Starting server",
 		logger.Field{Key: "host", Value: app.config.Server.Host},
 		logger.Field{Key: "port", Value: app.config.Server.Port},
 		logger.Field{Key: "mode", Value: app.config.Server.Mode},
 	)
-	return app.server.ListenAndServe()
+	app.lifecycle.SetReady()
+	return app.server.Serve(lis)
 }
 
-// Shutdown gracefully shuts down the application
+// Shutdown fails readiness, waits for the drain delay, shuts the servers,
+// stops background workers and then closes the databases and the logger
 func (app *Application) Shutdown(ctx context.Context) error {
 	app.logger.Info("Shutting down server...")
-
-	// Close database connections
-	if err := app.dbManager.CloseAll(); err != nil {
-		app.logger.Error("Error closing database connections", logger.Field{Key: "error", Value: err.Error()})
-	}
-
-	// Shutdown HTTP server
-	return app.server.Shutdown(ctx)
+	return app.lifecycle.Shutdown(ctx)
 }
 
 // GetRouter returns the Gin router (useful for testing)
@@ -241,19 +1171,75 @@ func (app *Application) GetRouter() *gin.Engine {
 	return app.router
 }
 
+// GetMetrics returns the application's Prometheus collectors
+func (app *Application) GetMetrics() *metrics.Metrics {
+	return app.metrics
+}
+
+// GetRequestLogWriter returns the writer of the request log, nil when
+// REQUEST_LOG_ENABLED is off
+func (app *Application) GetRequestLogWriter() *services.RequestLogWriter {
+	return app.requestLogWriter
+}
+
+// GetSignInAlerts returns the service emailing users about new sign-ins
+func (app *Application) GetSignInAlerts() *services.SignInAlertService {
+	return app.signInAlerts
+}
+
+// GetAuthWatch returns the watch checking login events for attacks
+func (app *Application) GetAuthWatch() *authwatch.Watch {
+	return app.authWatch
+}
+
 // GetDBManager returns the database manager
 func (app *Application) GetDBManager() *database.Manager {
 	return app.dbManager
 }
 
+// GetEmailClient returns the email client set with WithEmailClient or
+// EMAIL_DRIVER, or nil
+func (app *Application) GetEmailClient() email.EmailClient {
+	return app.emailClient
+}
+
 var startTime = time.Now()
 
-// ginLogger creates a Gin middleware for logging
+// redactQuery hides access tokens passed in the query string, e.g. by EventSource clients
+func redactQuery(raw string) string {
+	if !strings.Contains(raw, middleware.StreamTokenParam) {
+		return raw
+	}
+	query, err := url.ParseQuery(raw)
+	if err != nil {
+		return ""
+	}
+	if query.Has(middleware.StreamTokenParam) {
+		query.Set(middleware.StreamTokenParam, "REDACTED")
+	}
+	return query.Encode()
+}
+
+// ginLogger creates a Gin middleware for logging. It gives every request an
+// ID, taken from X-Request-ID or generated and echoed back, and a logger
+// adding that ID to every entry, both stored with requestctx. The request
+// entry also names the trace of the request, when a span is active.
 func ginLogger(log logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		raw := c.Request.URL.RawQuery
+		raw := redactQuery(c.Request.URL.RawQuery)
+
+		requestID := c.GetHeader(middleware.RequestIDHeader)
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		c.Header(middleware.RequestIDHeader, requestID)
+		requestctx.Update(c, func(ctx context.Context) context.Context {
+			ctx = requestctx.WithRequestID(ctx, requestID)
+			ctx = requestctx.WithRoute(ctx, requestctx.Route{Method: c.Request.Method, Template: c.FullPath()})
+			return requestctx.WithLogger(ctx, log.With(logger.Field{Key: "request_id", Value: requestID}))
+		})
 
 		c.Next()
 
@@ -267,31 +1253,33 @@ func ginLogger(log logger.Logger) gin.HandlerFunc {
 			path = path + "?" + raw
 		}
 
+		fields := []logger.Field{
+			{Key: "status", Value: statusCode},
+			{Key: "latency", Value: latency},
+			{Key: "client_ip", Value: clientIP},
+			{Key: "method", Value: method},
+			{Key: "path", Value: path},
+		}
+		// Name the user, and the admin behind an impersonation token
+		if user, ok := requestctx.UserFrom(c); ok {
+			fields = append(fields, logger.Field{Key: "user_id", Value: user.ID})
+			if user.ImpersonatorID != "" {
+				fields = append(fields, logger.Field{Key: "impersonator_id", Value: user.ImpersonatorID})
+			}
+		}
+		requestLog := requestctx.LoggerOr(c, log)
+
 		if statusCode >= 500 {
-			log.Error("HTTP Request",
-				logger.Field{Key: "status", Value: statusCode},
-				logger.Field{Key: "latency", Value: latency},
-				logger.Field{Key: "client_ip", Value: clientIP},
-				logger.Field{Key: "method", Value: method},
-				logger.Field{Key: "path", Value: path},
+			fields = append(fields,
 				logger.Field{Key: "error", Value: errorMessage},
+				logger.Field{Key: "error_code", Value: c.GetString(middleware.ErrorCodeKey)},
 			)
+			requestLog.Error("HTTP Request", fields...)
 		} else if statusCode >= 400 {
-			log.Warn("HTTP Request",
-				logger.Field{Key: "status", Value: statusCode},
-				logger.Field{Key: "latency", Value: latency},
-				logger.Field{Key: "client_ip", Value: clientIP},
-				logger.Field{Key: "method", Value: method},
-				logger.Field{Key: "path", Value: path},
-			)
+			fields = append(fields, logger.Field{Key: "error_code", Value: c.GetString(middleware.ErrorCodeKey)})
+			requestLog.Warn("HTTP Request", fields...)
 		} else {
-			log.Info("HTTP Request",
-				logger.Field{Key: "status", Value: statusCode},
-				logger.Field{Key: "latency", Value: latency},
-				logger.Field{Key: "client_ip", Value: clientIP},
-				logger.Field{Key: "method", Value: method},
-				logger.Field{Key: "path", Value: path},
-			)
+			requestLog.Info("HTTP Request", fields...)
 		}
 	}
 }
@@ -0,0 +1,376 @@
+// Package apptest runs the complete Application for end-to-end tests.
+//
+// NewTestApp builds the application on a private in-memory SQLite database
+// with every migration applied, and serves its router from an
+// httptest.Server. Requests go through the real middleware, controllers and
+// services:
+//
+//	ta := apptest.NewTestApp(t)
+//	admin := ta.CreateUser(models.RoleAdmin)
+//	resp := ta.Request(http.MethodGet, "/api/v1/users", nil, admin.Token)
+//	if resp.StatusCode != http.StatusOK { ... }
+//
+// Logs are captured in ta.Logs instead of printed, and email is kept in
+// ta.Mail instead of sent. Everything is closed
+// through t.Cleanup. Background jobs are disabled; options can change any
+// part of the configuration before the application is built.
+package apptest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Option adjusts the test configuration before the application is built
+type Option func(cfg *config.Config)
+
+// TestApp is a running Application with helpers for making requests
+type TestApp struct {
+	App    *app.Application
+	Config *config.Config
+	Server *httptest.Server
+	Logs   *logger.CaptureLogger
+	Mail   *Mailbox
+
+	t testing.TB
+}
+
+// Mailbox is the email client of a TestApp; it keeps every message sent
+type Mailbox struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// Message is an email sent through a Mailbox
+type Message struct {
+	To, Subject, Body string
+}
+
+// Send implements email.EmailClient
+func (m *Mailbox) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, Message{To: to, Subject: subject, Body: body})
+	return nil
+}
+
+// Messages returns the messages sent to to, oldest first
+func (m *Mailbox) Messages(to string) []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var messages []Message
+	for _, message := range m.messages {
+		if message.To == to {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// User is a user created by CreateUser, with its password and an access token
+type User struct {
+	*models.User
+	Password string
+	Token    string
+}
+
+// Response is a fully read HTTP response
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// NewTestApp builds and serves an Application backed by in-memory SQLite
+func NewTestApp(t testing.TB, opts ...Option) *TestApp {
+	t.Helper()
+
+	cfg := Config()
+	cfg.Storage.Path = t.TempDir()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	logs := logger.NewCaptureLogger()
+	mail := &Mailbox{}
+	application, err := app.New(cfg, logs, app.WithEmailClient(mail))
+	if err != nil {
+		t.Fatalf("apptest: create application: %v", err)
+	}
+
+	server := httptest.NewServer(application.GetRouter())
+	t.Cleanup(func() {
+		server.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := application.Shutdown(ctx); err != nil {
+			t.Errorf("apptest: shutdown: %v", err)
+		}
+	})
+
+	return &TestApp{
+		App:    application,
+		Config: cfg,
+		Server: server,
+		Logs:   logs,
+		Mail:   mail,
+		t:      t,
+	}
+}
+
+// Config returns the configuration NewTestApp starts from
+func Config() *config.Config {
+	return &config.Config{
+		Server: config.ServerConfig{
+			Host:         "127.0.0.1",
+			Port:         "0",
+			Mode:         gin.TestMode,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+			IdleTimeout:  5 * time.Second,
+		},
+		Database: config.DatabaseConfig{
+			Primary: config.DatabaseConnectionConfig{
+				Driver:  string(database.DriverSQLite),
+				DBName:  ":memory:",
+				UseGorm: true,
+			},
+			AutoMigrate: true,
+		},
+		JWT: config.JWTConfig{
+			Secret:     "apptest-secret-key-that-is-long-enough",
+			Expiration: time.Hour,
+			Issuer:     "apptest",
+
+			ImpersonationExpiration: 15 * time.Minute,
+			RememberExpiration:      24 * time.Hour,
+		},
+		Auth: config.AuthConfig{
+			PasswordChangeExpiration: 15 * time.Minute,
+			EmailChangeExpiration:    time.Hour,
+			SecureAccountURL:         "http://localhost:3000/secure-account",
+			SecureAccountExpiration:  time.Hour,
+
+			ProfileCompletionExpiration: 30 * time.Minute,
+		},
+		App: config.AppConfig{
+			Name:        "Backoffice Service",
+			Version:     "test",
+			Environment: "test",
+		},
+		Cache: config.CacheConfig{
+			Driver: "memory",
+			Prefix: "apptest",
+			TTL:    time.Minute,
+		},
+		Webhooks: config.WebhookConfig{
+			MaxAttempts:    1,
+			InitialBackoff: time.Millisecond,
+			Timeout:        time.Second,
+			DisableAfter:   10,
+			QueueSize:      100,
+			Workers:        1,
+		},
+		Partners: config.PartnersConfig{
+			SignatureWindow: 5 * time.Minute,
+		},
+		Jobs: config.JobsConfig{
+			AuditRetention:                 time.Hour,
+			AuditCleanupSchedule:           "0 3 * * *",
+			WebhookDeliveryRetention:       time.Hour,
+			WebhookDeliveryCleanupSchedule: "30 3 * * *",
+			NotificationRetention:          time.Hour,
+			NotificationCleanupSchedule:    "45 3 * * *",
+			SessionRetention:               time.Hour,
+			SessionCleanupSchedule:         "50 3 * * *",
+			TrustedDeviceRetention:         time.Hour,
+			UserPurgeAfter:                 time.Hour,
+			UserPurgeSchedule:              "15 4 * * *",
+			UserPurgeBatchSize:             100,
+			UserPurgeMaxPerRun:             1000,
+			RequestLogRetention:            time.Hour,
+			RequestLogCleanupSchedule:      "55 3 * * *",
+		},
+		// Off unless a test enables it
+		RequestLog: config.RequestLogConfig{
+			Methods:       []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+			Routes:        []string{"/api/"},
+			BodyLimit:     4096,
+			SampleRate:    1,
+			BufferSize:    100,
+			BatchSize:     10,
+			FlushInterval: time.Hour,
+		},
+		Stream: config.StreamConfig{
+			HeartbeatInterval: 25 * time.Second,
+			ClientBuffer:      64,
+		},
+		// NewTestApp gives each app its own directory
+		Storage: config.StorageConfig{
+			Path:          filepath.Join(os.TempDir(), "apptest-storage"),
+			URLExpiration: 15 * time.Minute,
+		},
+		Tasks: config.TasksConfig{
+			Workers:         2,
+			Retention:       time.Hour,
+			CleanupSchedule: "0 4 * * *",
+			ExportBatchSize: 1000,
+		},
+		OIDC: config.OIDCConfig{
+			StateTTL:          10 * time.Minute,
+			DiscoveryCacheTTL: time.Hour,
+			ClockSkew:         time.Minute,
+		},
+		Backup: config.BackupConfig{
+			Prefix: "backups",
+			Gzip:   true,
+		},
+		Approvals: config.ApprovalsConfig{
+			ProtectedRoleChanges: []string{"*->admin"},
+			TTL:                  72 * time.Hour,
+		},
+		WorkerPool: config.WorkerPoolConfig{
+			Size:       2,
+			QueueDepth: 10,
+			Mode:       "reject",
+		},
+		API: config.APIConfig{
+			DefaultPageSize:  10,
+			MaxPageSize:      100,
+			AuditMaxPageSize: 500,
+		},
+	}
+}
+
+// DB returns a GORM handle on the application's database for seeding and assertions
+func (ta *TestApp) DB() *gorm.DB {
+	ta.t.Helper()
+
+	driver, err := ta.App.GetDBManager().GetDriver("primary")
+	if err != nil {
+		ta.t.Fatalf("apptest: %v", err)
+	}
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		ta.t.Fatalf("apptest: %v", err)
+	}
+	return db
+}
+
+// Request sends a request to the application. body, if not nil, is encoded
+// as JSON; token, if not empty, is sent as a bearer token.
+func (ta *TestApp) Request(method, path string, body interface{}, token string) *Response {
+	ta.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			ta.t.Fatalf("apptest: encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, ta.Server.URL+path, reader)
+	if err != nil {
+		ta.t.Fatalf("apptest: build request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := ta.Server.Client().Do(req)
+	if err != nil {
+		ta.t.Fatalf("apptest: %s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		ta.t.Fatalf("apptest: read response: %v", err)
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: data}
+}
+
+// CreateUser stores an active user with the given role and logs it in
+func (ta *TestApp) CreateUser(role models.UserRole) *User {
+	ta.t.Helper()
+
+	id := uuid.New()
+	password := "password-" + id.String()[:8]
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		ta.t.Fatalf("apptest: hash password: %v", err)
+	}
+
+	user := &models.User{
+		ID:        id,
+		Email:     id.String()[:8] + "@apptest.local",
+		Username:  "user-" + id.String()[:8],
+		Password:  hash,
+		FirstName: "Test",
+		LastName:  string(role),
+		Role:      role,
+		Active:    true,
+	}
+	if err := ta.DB().Create(user).Error; err != nil {
+		ta.t.Fatalf("apptest: create user: %v", err)
+	}
+	user.Password = ""
+
+	return &User{User: user, Password: password, Token: ta.Login(user.Email, password)}
+}
+
+// Login logs in through the API and returns the access token
+func (ta *TestApp) Login(email, password string) string {
+	ta.t.Helper()
+
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{
+		"email":    email,
+		"password": password,
+	}, "")
+	if resp.StatusCode != http.StatusOK {
+		ta.t.Fatalf("apptest: login %s: %d %s", email, resp.StatusCode, resp.Body)
+	}
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	resp.Decode(ta.t, &body)
+	if body.Token == "" {
+		ta.t.Fatalf("apptest: login %s returned no token: %s", email, resp.Body)
+	}
+	return body.Token
+}
+
+// Decode unmarshals the JSON body into v
+func (r *Response) Decode(t testing.TB, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("apptest: decode %q: %v", r.Body, err)
+	}
+}
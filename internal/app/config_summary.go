@@ -0,0 +1,59 @@
+package app
+
+import (
+	"sort"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+)
+
+// logConfiguration logs a summary of the resolved configuration before
+// anything connects, so a misdirected deployment shows in the first lines of
+// its log. Values come from a Redacted copy and never include secrets.
+func (app *Application) logConfiguration() {
+	cfg := app.config.Redacted()
+
+	names := make([]string, 0, len(cfg.Database.Databases))
+	for name := range cfg.Database.Databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	databases := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		databases = append(databases, databaseSummary(name, cfg.Database.Databases[name]))
+	}
+
+	app.logger.Info("Configuration",
+		logger.Field{Key: "address", Value: cfg.Server.Host + ":" + cfg.Server.Port},
+		logger.Field{Key: "mode", Value: cfg.Server.Mode},
+		logger.Field{Key: "environment", Value: cfg.App.Environment},
+		logger.Field{Key: "database", Value: databaseSummary("primary", cfg.Database.Primary)},
+		logger.Field{Key: "databases", Value: databases},
+		logger.Field{Key: "log_channel", Value: cfg.Logging.Channel},
+		logger.Field{Key: "log_level", Value: cfg.Logging.Level},
+		logger.Field{Key: "integrations", Value: map[string]interface{}{
+			"cache":     cfg.Cache.Driver,
+			"storage":   cfg.Storage.Path,
+			"email":     app.emailClient != nil,
+			"messaging": cfg.Messaging.Driver,
+			"nats":      cfg.Messaging.NATS.URLs,
+			"grpc":      cfg.GRPC.Enabled,
+			"geoip":     cfg.GeoIP.Driver,
+		}},
+		logger.Field{Key: "jwt_issuer", Value: cfg.JWT.Issuer},
+		logger.Field{Key: "jwt_audience", Value: cfg.JWT.Audience},
+		logger.Field{Key: "jwt_algorithm", Value: services.SigningMethod.Alg()},
+	)
+}
+
+// databaseSummary names where a redacted connection points
+func databaseSummary(name string, dbc config.DatabaseConnectionConfig) map[string]interface{} {
+	return map[string]interface{}{
+		"name":     name,
+		"driver":   dbc.Driver,
+		"host":     dbc.Host,
+		"port":     dbc.Port,
+		"database": dbc.DBName,
+	}
+}
@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services/authwatch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AlertController lists the alerts the authentication watch raised
+type AlertController struct {
+	watch *authwatch.Watch
+	pages pagination.Config
+}
+
+// NewAlertController creates a new alert controller
+func NewAlertController(watch *authwatch.Watch, pages pagination.Config) *AlertController {
+	return &AlertController{
+		watch: watch,
+		pages: pages,
+	}
+}
+
+// ListAlerts handles listing alerts
+// @Summary List alerts
+// @Description Suspicious login activity the authentication watch detected, with the mitigations it applied, newest first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param type query string false "Only alerts of this type: impossible_travel, credential_stuffing or failure_spike"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/alerts [get]
+func (ac *AlertController) ListAlerts(c *gin.Context) {
+	alertType := c.Query("type")
+	switch alertType {
+	case "", models.AlertTypeImpossibleTravel, models.AlertTypeCredentialStuffing, models.AlertTypeFailureSpike:
+	default:
+		appErr := errors.NewBadRequestError(i18n.SavedFilterInvalidValue, nil).
+			WithCode(errors.CodeInvalidFilter).
+			WithParams(errors.Params{"name": "type", "reason": "must be impossible_travel, credential_stuffing or failure_spike"})
+		middleware.RespondError(c, appErr)
+		return
+	}
+	params, appErr := pagination.ParseParams(c, ac.pages)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	alerts, total, err := ac.watch.List(c.Request.Context(), alertType, params.Limit, params.Offset())
+	if err != nil {
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.AlertListFailed, err))
+		return
+	}
+
+	meta := pagination.NewMeta(params, total)
+	c.Header("Link", meta.Links(c.Request.URL))
+	c.JSON(http.StatusOK, gin.H{
+		"data": alerts,
+		"meta": meta,
+	})
+}
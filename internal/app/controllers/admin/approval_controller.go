@@ -0,0 +1,231 @@
+package admin
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RejectApprovalRequest optionally says why an approval was rejected
+type RejectApprovalRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// ApprovalController lists and decides changes awaiting a second admin
+type ApprovalController struct {
+	approvals *services.ApprovalService
+	pages     pagination.Config
+}
+
+// NewApprovalController creates a new approval controller
+func NewApprovalController(approvals *services.ApprovalService, pages pagination.Config) *ApprovalController {
+	return &ApprovalController{
+		approvals: approvals,
+		pages:     pages,
+	}
+}
+
+// ListApprovals handles listing approvals
+// @Summary List approvals
+// @Description Changes held back for a second admin's approval, newest first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Only approvals in this status: pending, approved, rejected, cancelled or expired"
+// @Param subject_id query string false "Only approvals of changes to this user"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/approvals [get]
+func (ac *ApprovalController) ListApprovals(c *gin.Context) {
+	filter := services.ApprovalFilter{
+		Status:    models.ApprovalStatus(c.Query("status")),
+		SubjectID: c.Query("subject_id"),
+	}
+	if filter.Status != "" && !filter.Status.Valid() {
+		appErr := errors.NewBadRequestError(i18n.SavedFilterInvalidValue, nil).
+			WithCode(errors.CodeInvalidFilter).
+			WithParams(errors.Params{"name": "status", "reason": "must be pending, approved, rejected, cancelled or expired"})
+		middleware.RespondError(c, appErr)
+		return
+	}
+	params, appErr := pagination.ParseParams(c, ac.pages)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	result, err := ac.approvals.List(c.Request.Context(), filter, params.Limit, params.Offset())
+	if err != nil {
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.ApprovalListFailed, err))
+		return
+	}
+
+	meta := pagination.NewMeta(params, result.Total)
+	c.Header("Link", meta.Links(c.Request.URL))
+	c.JSON(http.StatusOK, gin.H{
+		"data": result.Items,
+		"meta": meta,
+	})
+}
+
+// GetApproval handles getting an approval
+// @Summary Get approval
+// @Description An approval with the change it holds back
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Approval ID"
+// @Success 200 {object} models.Approval
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/approvals/{id} [get]
+func (ac *ApprovalController) GetApproval(c *gin.Context) {
+	approval, err := ac.approvals.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		middleware.RespondError(c, approvalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": approval,
+	})
+}
+
+// Approve handles approving a change
+// @Summary Approve change
+// @Description Apply a pending change. Only an admin other than the one who requested it may approve it.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Approval ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/approvals/{id}/approve [post]
+func (ac *ApprovalController) Approve(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	approval, err := ac.approvals.Approve(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		middleware.RespondError(c, approvalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Change approved",
+		"data":    approval,
+	})
+}
+
+// Reject handles rejecting a change
+// @Summary Reject change
+// @Description Refuse a pending change. Only an admin other than the one who requested it may reject it.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Approval ID"
+// @Param reason body RejectApprovalRequest false "Why the change is refused"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/approvals/{id}/reject [post]
+func (ac *ApprovalController) Reject(c *gin.Context) {
+	req, ok := request.Bind[RejectApprovalRequest](c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	approval, err := ac.approvals.Reject(c.Request.Context(), c.Param("id"), claims.UserID, req.Reason)
+	if err != nil {
+		middleware.RespondError(c, approvalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Change rejected",
+		"data":    approval,
+	})
+}
+
+// Cancel handles withdrawing a change
+// @Summary Cancel change
+// @Description Withdraw a pending change. Only the admin who requested it may cancel it.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Approval ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/approvals/{id} [delete]
+func (ac *ApprovalController) Cancel(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	approval, err := ac.approvals.Cancel(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		middleware.RespondError(c, approvalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Change cancelled",
+		"data":    approval,
+	})
+}
+
+// approvalError reports why an approval could not be read or decided
+func approvalError(err error) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrApprovalNotFound):
+		return errors.NewNotFoundError(i18n.ApprovalNotFound, err).WithCode(errors.CodeApprovalNotFound)
+	case stderrors.Is(err, services.ErrSelfApproval):
+		return errors.NewForbiddenError(i18n.ApprovalSelf, err).WithCode(errors.CodeSelfApproval)
+	case stderrors.Is(err, services.ErrNotApprovalRequester):
+		return errors.NewForbiddenError(i18n.ApprovalNotRequester, err).WithCode(errors.CodeNotApprovalRequester)
+	case stderrors.Is(err, services.ErrApprovalNotPending):
+		return errors.NewConflictError(i18n.ApprovalNotPending, err).WithCode(errors.CodeApprovalNotPending)
+	case stderrors.Is(err, services.ErrApprovalExpired):
+		return errors.NewConflictError(i18n.ApprovalExpired, err).WithCode(errors.CodeApprovalExpired)
+	case stderrors.Is(err, services.ErrApprovalStale):
+		return errors.NewConflictError(i18n.ApprovalStale, err).WithCode(errors.CodeApprovalStale)
+	case stderrors.Is(err, services.ErrLastAdminDemotion):
+		return errors.NewConflictError(i18n.UserLastAdminDemotion, err).WithCode(errors.CodeLastAdminDemotion)
+	case stderrors.Is(err, services.ErrUserNotFound):
+		return errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+	default:
+		return errors.NewInternalServerError(i18n.ApprovalDecideFailed, err)
+	}
+}
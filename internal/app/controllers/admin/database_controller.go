@@ -0,0 +1,144 @@
+package admin
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DatabaseController handles connecting and removing named databases at runtime
+type DatabaseController struct {
+	databaseService *services.DatabaseService
+}
+
+// NewDatabaseController creates a new database controller
+func NewDatabaseController(databaseService *services.DatabaseService) *DatabaseController {
+	return &DatabaseController{
+		databaseService: databaseService,
+	}
+}
+
+// RegisterDatabaseRequest names a database and how to connect to it
+type RegisterDatabaseRequest struct {
+	Name string `json:"name" binding:"required"`
+	config.DatabaseConnectionConfig
+}
+
+// ListDatabases handles listing registered databases
+// @Summary List databases
+// @Description List every named database with its driver and health; connection settings are never included
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/databases [get]
+func (dc *DatabaseController) ListDatabases(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": dc.databaseService.ListDatabases(c.Request.Context()),
+	})
+}
+
+// RegisterDatabase handles connecting a named database
+// @Summary Register database
+// @Description Connect a database within DB_REGISTRATION_TIMEOUT and register it under the name until the next restart. Durations follow the primary database's settings.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param database body RegisterDatabaseRequest true "Name and connection settings"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/v1/admin/databases [post]
+func (dc *DatabaseController) RegisterDatabase(c *gin.Context) {
+	req, ok := request.Bind[RegisterDatabaseRequest](c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	info, err := dc.databaseService.RegisterDatabase(c.Request.Context(), claims.UserID, req.Name, req.DatabaseConnectionConfig)
+	if err != nil {
+		middleware.RespondError(c, databaseError(err, req.Name, i18n.DatabaseRegisterFailed))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Database registered successfully",
+		"data":    info,
+	})
+}
+
+// RemoveDatabase handles closing and removing a named database
+// @Summary Remove database
+// @Description Close a named database and stop routing to it. Requests already using it finish their statements first. The primary database and databases tenants are routed to cannot be removed.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param name path string true "Database name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/databases/{name} [delete]
+func (dc *DatabaseController) RemoveDatabase(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	name := c.Param("name")
+	if err := dc.databaseService.RemoveDatabase(c.Request.Context(), claims.UserID, name); err != nil {
+		middleware.RespondError(c, databaseError(err, name, i18n.DatabaseRemoveFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Database removed successfully",
+	})
+}
+
+// databaseError reports why a database could not be registered or removed.
+// Connection errors are not shown, as they may quote connection settings.
+func databaseError(err error, name, failedKey string) *errors.AppError {
+	params := errors.Params{"name": name}
+	switch {
+	case stderrors.Is(err, services.ErrInvalidDatabase):
+		params["reason"] = reason(err, services.ErrInvalidDatabase)
+		return errors.NewBadRequestError(i18n.DatabaseInvalid, err).WithCode(errors.CodeInvalidDatabase).WithParams(params)
+	case stderrors.Is(err, services.ErrDatabaseExists):
+		return errors.NewConflictError(i18n.DatabaseExists, err).WithCode(errors.CodeDatabaseExists).WithParams(params)
+	case stderrors.Is(err, services.ErrDatabaseNotFound):
+		return errors.NewNotFoundError(i18n.DatabaseNotFound, err).WithCode(errors.CodeDatabaseNotFound).WithParams(params)
+	case stderrors.Is(err, services.ErrDatabaseInUse):
+		params["reason"] = reason(err, services.ErrDatabaseInUse)
+		return errors.NewConflictError(i18n.DatabaseInUse, err).WithCode(errors.CodeDatabaseInUse).WithParams(params)
+	case stderrors.Is(err, services.ErrDatabaseConnectionFailed):
+		return errors.NewAppError(http.StatusBadGateway, i18n.DatabaseConnectionFailed, err).WithCode(errors.CodeDatabaseConnectionFailed).WithParams(params)
+	default:
+		return errors.NewInternalServerError(failedKey, err)
+	}
+}
+
+// reason returns what err adds to the sentinel it wraps
+func reason(err, sentinel error) string {
+	return strings.TrimPrefix(err.Error(), sentinel.Error()+": ")
+}
@@ -0,0 +1,148 @@
+package admin
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/infrastructure/email"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailController handles previewing email templates and sending test emails
+type EmailController struct {
+	emailService *services.EmailTemplateService
+}
+
+// NewEmailController creates a new email controller
+func NewEmailController(emailService *services.EmailTemplateService) *EmailController {
+	return &EmailController{
+		emailService: emailService,
+	}
+}
+
+// TestSendRequest names the recipient of a test email
+type TestSendRequest struct {
+	To string `json:"to" binding:"required,email"`
+}
+
+// ListTemplates handles listing email templates
+// @Summary List email templates
+// @Description List every email template with its formats and the sample data previews are rendered with
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/emails/templates [get]
+func (ec *EmailController) ListTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": ec.emailService.ListTemplates(),
+	})
+}
+
+// PreviewTemplate handles rendering an email template with its sample data
+// @Summary Preview email template
+// @Description Render the subject and body of an email template with its sample data
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param name path string true "Template name"
+// @Param format query string false "Body format, text (default) or html"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/admin/emails/templates/{name}/preview [get]
+func (ec *EmailController) PreviewTemplate(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	name := c.Param("name")
+	format := email.Format(c.DefaultQuery("format", string(email.FormatText)))
+	message, err := ec.emailService.Preview(c.Request.Context(), claims.UserID, name, format)
+	if err != nil {
+		middleware.RespondError(c, emailError(err, name, format, i18n.EmailPreviewFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": message,
+	})
+}
+
+// TestSendTemplate handles sending an email template to an operator
+// @Summary Send test email
+// @Description Render an email template as text with its sample data and send it through the configured email client, with "[Test]" before the subject
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param name path string true "Template name"
+// @Param request body TestSendRequest true "Recipient"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/admin/emails/templates/{name}/test-send [post]
+func (ec *EmailController) TestSendTemplate(c *gin.Context) {
+	req, ok := request.Bind[TestSendRequest](c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	name := c.Param("name")
+	message, err := ec.emailService.TestSend(c.Request.Context(), claims.UserID, name, req.To)
+	if err != nil {
+		middleware.RespondError(c, emailError(err, name, email.FormatText, i18n.EmailSendFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Test email sent",
+		"data":    message,
+	})
+}
+
+// emailError reports why a template could not be previewed or sent. Render
+// errors quote the template error, which only names template fields.
+func emailError(err error, name string, format email.Format, fallback string) *errors.AppError {
+	var renderErr *email.RenderError
+	switch {
+	case stderrors.Is(err, email.ErrTemplateNotFound):
+		return errors.NewNotFoundError(i18n.EmailTemplateNotFound, err).
+			WithCode(errors.CodeEmailTemplateNotFound).
+			WithParams(errors.Params{"name": name})
+	case stderrors.Is(err, email.ErrFormatUnsupported):
+		return errors.NewBadRequestError(i18n.EmailFormatUnsupported, err).
+			WithCode(errors.CodeEmailFormatUnsupported).
+			WithParams(errors.Params{"name": name, "format": string(format)})
+	case stderrors.As(err, &renderErr):
+		return errors.NewValidationError(i18n.EmailTemplateRenderFailed, err).
+			WithCode(errors.CodeEmailTemplateRenderFailed).
+			WithParams(errors.Params{"name": name, "error": renderErr.Err.Error()})
+	case stderrors.Is(err, services.ErrEmailUnavailable):
+		return errors.NewAppError(http.StatusServiceUnavailable, i18n.AuthEmailUnavailable, err).WithCode(errors.CodeEmailUnavailable)
+	case stderrors.Is(err, services.ErrEmailDeliveryFailed):
+		return errors.NewAppError(http.StatusBadGateway, i18n.EmailSendFailed, err).WithCode(errors.CodeEmailSendFailed)
+	default:
+		return errors.NewInternalServerError(fallback, err)
+	}
+}
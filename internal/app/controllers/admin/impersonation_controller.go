@@ -0,0 +1,107 @@
+package admin
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Impersonator issues and ends impersonation tokens
+type Impersonator interface {
+	Impersonate(ctx context.Context, impersonator *services.TokenClaims, userID string) (*services.AuthResult, error)
+	StopImpersonation(ctx context.Context, claims *services.TokenClaims) (*services.AuthResult, error)
+}
+
+// ImpersonationController handles admin impersonation HTTP requests
+type ImpersonationController struct {
+	impersonator Impersonator
+}
+
+// NewImpersonationController creates a new impersonation controller
+func NewImpersonationController(impersonator Impersonator) *ImpersonationController {
+	return &ImpersonationController{
+		impersonator: impersonator,
+	}
+}
+
+// Impersonate handles acting as another user
+// @Summary Impersonate user
+// @Description Issue a short-lived token for the user. The token cannot change passwords or roles, or impersonate again.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} services.AuthResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/impersonate/{id} [post]
+func (ic *ImpersonationController) Impersonate(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	result, err := ic.impersonator.Impersonate(c.Request.Context(), claims, c.Param("id"))
+	if err != nil {
+		middleware.RespondError(c, impersonationError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// StopImpersonation handles returning to the admin's own account
+// @Summary Stop impersonating
+// @Description Exchange an impersonation token for a token of the admin who issued it
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} services.AuthResult
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/impersonate/stop [post]
+func (ic *ImpersonationController) StopImpersonation(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	result, err := ic.impersonator.StopImpersonation(c.Request.Context(), claims)
+	if err != nil {
+		middleware.RespondError(c, impersonationError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// impersonationError reports why an impersonation could not start or stop
+func impersonationError(err error) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrImpersonationForbidden):
+		return errors.NewForbiddenError(i18n.AuthImpersonationForbidden, err).WithCode(errors.CodeImpersonationForbidden)
+	case stderrors.Is(err, services.ErrAdminImpersonationDisabled):
+		return errors.NewForbiddenError(i18n.AuthAdminImpersonationDisabled, err).WithCode(errors.CodeAdminImpersonationDisabled)
+	case stderrors.Is(err, services.ErrSelfImpersonation):
+		return errors.NewBadRequestError(i18n.AuthSelfImpersonation, err).WithCode(errors.CodeSelfImpersonation)
+	case stderrors.Is(err, services.ErrNotImpersonating):
+		return errors.NewBadRequestError(i18n.AuthNotImpersonating, err).WithCode(errors.CodeNotImpersonating)
+	case stderrors.Is(err, services.ErrUserNotFound):
+		return errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+	case stderrors.Is(err, services.ErrAccountDeactivated):
+		return errors.NewForbiddenError(i18n.AuthAccountDeactivated, err).WithCode(errors.CodeAccountDeactivated)
+	default:
+		return errors.NewInternalServerError(i18n.AuthImpersonationFailed, err)
+	}
+}
@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/jobs"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobPreview describes what running a destructive job would remove
+type JobPreview func(ctx context.Context) (*services.ConfirmationSummary, error)
+
+// JobsController exposes the background job scheduler
+type JobsController struct {
+	scheduler     *jobs.Scheduler
+	notifications *services.NotificationService
+	previews      map[string]JobPreview
+}
+
+// NewJobsController creates a new jobs controller
+func NewJobsController(scheduler *jobs.Scheduler, notifications *services.NotificationService) *JobsController {
+	return &JobsController{
+		scheduler:     scheduler,
+		notifications: notifications,
+		previews:      make(map[string]JobPreview),
+	}
+}
+
+// SetPreview marks the job name destructive: running it by hand needs a
+// confirmation, answered with what preview reports. A nil report means the
+// run would remove nothing.
+func (jc *JobsController) SetPreview(name string, preview JobPreview) {
+	jc.previews[name] = preview
+}
+
+// Confirmation is the confirmation rule of running jobs by hand, which
+// only asks to confirm runs of destructive jobs
+func (jc *JobsController) Confirmation() *middleware.ConfirmRule {
+	return &middleware.ConfirmRule{Summarize: jc.summarizeRun}
+}
+
+// summarizeRun describes what running the job named by :name would remove
+func (jc *JobsController) summarizeRun(c *gin.Context) (*services.ConfirmationSummary, *errors.AppError) {
+	preview, ok := jc.previews[c.Param("name")]
+	if !ok {
+		return nil, nil
+	}
+	summary, err := preview(c.Request.Context())
+	if err != nil {
+		return nil, errors.NewInternalServerError(i18n.ConfirmationFailed, err)
+	}
+	return summary, nil
+}
+
+// ListJobs handles listing background jobs and their last run
+// @Summary List background jobs
+// @Description List registered jobs with their schedule and last-run status
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/jobs [get]
+func (jc *JobsController) ListJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": jc.scheduler.Statuses(),
+	})
+}
+
+// RunJob handles triggering a background job immediately
+// @Summary Run job now
+// @Description Start a background job immediately; the caller is notified when it finishes. Destructive jobs such as user_purge are answered with 202 and a confirmation token first; repeat the request with X-Confirm-Token to run them.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param name path string true "Job name"
+// @Param X-Confirm-Token header string false "Token confirming a destructive job"
+// @Success 202 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/jobs/{name}/run [post]
+func (jc *JobsController) RunJob(c *gin.Context) {
+	name := c.Param("name")
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError("Authentication required", nil)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	// The request is long gone when the job finishes
+	notify := func(status jobs.Status, _ error) {
+		jc.notifications.NotifyJobFinished(context.Background(), claims.UserID, status)
+	}
+
+	if err := jc.scheduler.RunNowThen(name, notify); err != nil {
+		var appErr *errors.AppError
+		switch {
+		case stderrors.Is(err, jobs.ErrJobNotFound):
+			appErr = errors.NewNotFoundError("Job not found", err)
+		case stderrors.Is(err, jobs.ErrJobRunning):
+			appErr = errors.NewConflictError("Job is already running", err)
+		default:
+			appErr = errors.NewAppError(http.StatusServiceUnavailable, "Job scheduler is stopped", err)
+		}
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Job started",
+	})
+}
@@ -0,0 +1,63 @@
+package admin
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/jwtutil"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWTSecretController rotates the secret tokens are signed with
+type JWTSecretController struct {
+	secrets *services.JWTSecretService
+}
+
+// NewJWTSecretController creates a new JWT secret controller
+func NewJWTSecretController(secrets *services.JWTSecretService) *JWTSecretController {
+	return &JWTSecretController{
+		secrets: secrets,
+	}
+}
+
+// RotateSecret handles rotating the JWT secret
+// @Summary Rotate the JWT secret
+// @Description Generate a new secret in JWT_SECRET_FILE. Tokens signed with the replaced secret keep working until the longest-lived of them has expired. force=true rotates again during an earlier rotation's grace window, invalidating the tokens signed with the secret it would have kept.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param force query bool false "Rotate even while the previous secret is still accepted"
+// @Success 200 {object} services.SecretRotation
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/jwt/rotate-secret [post]
+func (jc *JWTSecretController) RotateSecret(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	rotation, err := jc.secrets.Rotate(c.Request.Context(), claims.UserID, c.Query("force") == "true")
+	switch {
+	case stderrors.Is(err, services.ErrJWTKeyFileUnset):
+		middleware.RespondError(c, errors.NewConflictError(i18n.JWTKeyFileUnset, err).WithCode(errors.CodeJWTKeyFileUnset))
+		return
+	case stderrors.Is(err, jwtutil.ErrRotationInProgress):
+		middleware.RespondError(c, errors.NewConflictError(i18n.JWTRotationInProgress, err).WithCode(errors.CodeJWTRotationInProgress))
+		return
+	case err != nil:
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.JWTRotationFailed, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": rotation,
+	})
+}
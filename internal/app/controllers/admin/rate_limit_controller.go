@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// offenderPages sizes the offender list, which has a single page
+var offenderPages = pagination.Config{DefaultLimit: 20, MaxLimit: 100}
+
+// RateLimitController reports the clients going over rate limits, so limits
+// can be observed before they are enforced
+type RateLimitController struct {
+	limiter *ratelimit.Limiter
+}
+
+// NewRateLimitController creates a rate limit controller reading limiter's store
+func NewRateLimitController(limiter *ratelimit.Limiter) *RateLimitController {
+	return &RateLimitController{
+		limiter: limiter,
+	}
+}
+
+// ListOffenders handles listing the clients over a rate limit
+// @Summary List rate limit offenders
+// @Description List the client IPs over a route's rate limit in its current window, the most requests over the limit first. Observed limits list the requests they would have refused.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "Number of offenders (default 20, max 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/ratelimit/offenders [get]
+func (rc *RateLimitController) ListOffenders(c *gin.Context) {
+	params, appErr := pagination.ParseParams(c, offenderPages)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	offenders := []ratelimit.Offender{}
+	if rc.limiter != nil {
+		var err error
+		if offenders, err = rc.limiter.Offenders(c.Request.Context(), params.Limit); err != nil {
+			middleware.RespondError(c, errors.NewInternalServerError(i18n.ErrorInternal, err))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": offenders,
+	})
+}
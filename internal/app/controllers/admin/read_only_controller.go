@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetReadOnlyRequest turns read-only mode on or off
+type SetReadOnlyRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Reason  string `json:"reason" binding:"max=500"`
+}
+
+// ReadOnlyController freezes and unfreezes writes
+type ReadOnlyController struct {
+	readOnly *services.ReadOnlyService
+}
+
+// NewReadOnlyController creates a new read-only controller
+func NewReadOnlyController(readOnly *services.ReadOnlyService) *ReadOnlyController {
+	return &ReadOnlyController{
+		readOnly: readOnly,
+	}
+}
+
+// GetReadOnly handles reading the read-only mode
+// @Summary Get read-only mode
+// @Description Whether writes are frozen, since when, by whom and why
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} readonly.State
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/read-only [get]
+func (rc *ReadOnlyController) GetReadOnly(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": rc.readOnly.State(c.Request.Context()),
+	})
+}
+
+// SetReadOnly handles turning the read-only mode on or off
+// @Summary Set read-only mode
+// @Description Freeze or unfreeze writes on every instance sharing the cache store. Reads keep working; this endpoint stays writable.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param state body SetReadOnlyRequest true "New state"
+// @Success 200 {object} readonly.State
+// @Failure 403 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/admin/read-only [put]
+func (rc *ReadOnlyController) SetReadOnly(c *gin.Context) {
+	req, ok := request.Bind[SetReadOnlyRequest](c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	state, err := rc.readOnly.Set(c.Request.Context(), claims.UserID, *req.Enabled, req.Reason)
+	if err != nil {
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.ReadOnlyUpdateFailed, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": state,
+	})
+}
@@ -0,0 +1,101 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLogController searches the database request log
+type RequestLogController struct {
+	requestLogs *services.RequestLogService
+	pages       pagination.Config
+}
+
+// NewRequestLogController creates a new request log controller
+func NewRequestLogController(requestLogs *services.RequestLogService, pages pagination.Config) *RequestLogController {
+	return &RequestLogController{
+		requestLogs: requestLogs,
+		pages:       pages,
+	}
+}
+
+// ListRequestLogs handles searching the request log
+// @Summary Search request log
+// @Description Logged API requests, newest first, with their redacted bodies. Admins of a tenant only see the tenant's requests.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param actor_id query string false "Only requests by this user"
+// @Param route query string false "Only requests to this route template, such as /api/v1/users/:id"
+// @Param method query string false "Only requests with this method"
+// @Param status query int false "Only requests answered with this status"
+// @Param from query string false "Only requests at or after this date (2024-01-31) or RFC 3339 time"
+// @Param to query string false "Only requests on or before this date, or before this RFC 3339 time"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/request-logs [get]
+func (rc *RequestLogController) ListRequestLogs(c *gin.Context) {
+	filter, appErr := requestLogFilter(c)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+	params, appErr := pagination.ParseParams(c, rc.pages)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	result, err := rc.requestLogs.List(c.Request.Context(), filter, params.Limit, params.Offset())
+	if err != nil {
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.RequestLogListFailed, err))
+		return
+	}
+
+	meta := pagination.NewMeta(params, result.Total)
+	c.Header("Link", meta.Links(c.Request.URL))
+	c.JSON(http.StatusOK, gin.H{
+		"data": result.Items,
+		"meta": meta,
+	})
+}
+
+// requestLogFilter parses the filters of a request log search. Requests
+// made for a tenant only see the tenant's entries.
+func requestLogFilter(c *gin.Context) (services.RequestLogFilter, *errors.AppError) {
+	filter := services.RequestLogFilter{
+		ActorID:  c.Query("actor_id"),
+		Route:    c.Query("route"),
+		Method:   strings.ToUpper(c.Query("method")),
+		TenantID: database.TenantID(c.Request.Context()),
+	}
+	if raw := c.Query("status"); raw != "" {
+		status, err := strconv.Atoi(raw)
+		if err != nil || status < 100 || status > 599 {
+			return filter, errors.NewBadRequestError(i18n.SavedFilterInvalidValue, err).
+				WithCode(errors.CodeInvalidFilter).
+				WithParams(errors.Params{"name": "status", "reason": "must be an HTTP status code"})
+		}
+		filter.Status = status
+	}
+
+	bounds, appErr := auditLogFilter(c.Query("from"), c.Query("to"))
+	if appErr != nil {
+		return filter, appErr
+	}
+	filter.From, filter.To = bounds.From, bounds.To
+	return filter, nil
+}
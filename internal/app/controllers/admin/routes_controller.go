@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Formats of the route table
+const (
+	RouteFormatJSON     = "json"
+	RouteFormatMarkdown = "markdown"
+)
+
+// RoutesController lists the registered routes with their policies, for
+// tooling such as API gateway configuration
+type RoutesController struct {
+	table func() route.Table
+}
+
+// NewRoutesController creates a routes controller serving the table table
+// returns, which is complete once every route is registered
+func NewRoutesController(table func() route.Table) *RoutesController {
+	return &RoutesController{
+		table: table,
+	}
+}
+
+// ListRoutes handles listing the registered routes
+// @Summary List routes
+// @Description List every registered route with its method, path template, handler, authentication, required roles and permission, rate limit and feature, ordered by path and method
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Produce text/markdown
+// @Param format query string false "json (default) or markdown"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/routes [get]
+func (rc *RoutesController) ListRoutes(c *gin.Context) {
+	table := rc.table()
+	switch format := c.DefaultQuery("format", RouteFormatJSON); format {
+	case RouteFormatJSON:
+		c.JSON(http.StatusOK, gin.H{
+			"data": table,
+		})
+	case RouteFormatMarkdown:
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(table.Markdown()))
+	default:
+		appErr := errors.NewBadRequestError(i18n.RouteInvalidFormat, nil).
+			WithCode(errors.CodeInvalidRouteFormat).
+			WithParams(errors.Params{"allowed": RouteFormatJSON + ", " + RouteFormatMarkdown})
+		middleware.RespondError(c, appErr)
+	}
+}
@@ -0,0 +1,91 @@
+package admin
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SettingsController handles application settings HTTP requests
+type SettingsController struct {
+	settingsService *services.SettingsService
+}
+
+// NewSettingsController creates a new settings controller
+func NewSettingsController(settingsService *services.SettingsService) *SettingsController {
+	return &SettingsController{
+		settingsService: settingsService,
+	}
+}
+
+// ListSettings handles listing application settings
+// @Summary List settings
+// @Description List every known setting with its type and current value
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/settings [get]
+func (sc *SettingsController) ListSettings(c *gin.Context) {
+	settings, err := sc.settingsService.ListSettings(c.Request.Context())
+	if err != nil {
+		appErr := errors.NewInternalServerError("Failed to fetch settings", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": settings,
+	})
+}
+
+// UpdateSettings handles changing application settings
+// @Summary Update settings
+// @Description Change one or more settings; unknown keys and type mismatches reject the whole request
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param settings body services.UpdateSettingsRequest true "Settings keyed by name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/admin/settings [put]
+func (sc *SettingsController) UpdateSettings(c *gin.Context) {
+	var req services.UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError("Authentication required", nil)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	settings, err := sc.settingsService.UpdateSettings(c.Request.Context(), claims.UserID, req.Settings)
+	if err != nil {
+		var appErr *errors.AppError
+		switch {
+		case stderrors.Is(err, services.ErrUnknownSetting), stderrors.Is(err, services.ErrSettingTypeMismatch):
+			appErr = errors.NewValidationError(err.Error(), err)
+		default:
+			appErr = errors.NewInternalServerError("Failed to update settings", err)
+		}
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": settings,
+	})
+}
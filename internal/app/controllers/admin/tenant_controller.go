@@ -0,0 +1,86 @@
+package admin
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantController handles tenant administration HTTP requests
+type TenantController struct {
+	tenantService *services.TenantService
+}
+
+// NewTenantController creates a new tenant controller
+func NewTenantController(tenantService *services.TenantService) *TenantController {
+	return &TenantController{
+		tenantService: tenantService,
+	}
+}
+
+// ListTenants handles listing registered tenants
+// @Summary List tenants
+// @Description List every tenant and the database its requests are routed to
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/tenants [get]
+func (tc *TenantController) ListTenants(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": tc.tenantService.ListTenants(),
+	})
+}
+
+// CreateTenant handles registering a tenant
+// @Summary Create tenant
+// @Description Route a new tenant to a connected database, or connect and migrate a new one for it
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param tenant body services.CreateTenantRequest true "Tenant and its database"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/tenants [post]
+func (tc *TenantController) CreateTenant(c *gin.Context) {
+	var req services.CreateTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError("Authentication required", nil)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	tenant, err := tc.tenantService.CreateTenant(c.Request.Context(), claims.UserID, &req)
+	if err != nil {
+		var appErr *errors.AppError
+		switch {
+		case stderrors.Is(err, services.ErrInvalidTenant), stderrors.Is(err, services.ErrTenantDatabaseNotFound):
+			appErr = errors.NewBadRequestError(err.Error(), err)
+		case stderrors.Is(err, services.ErrTenantExists):
+			appErr = errors.NewConflictError("Tenant already exists", err)
+		default:
+			appErr = errors.NewInternalServerError("Failed to create tenant", err)
+		}
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": tenant,
+	})
+}
@@ -1,9 +1,16 @@
 package auth
 
 import (
+	stderrors "errors"
 	"net/http"
 
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/app/route"
 	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/service"
 	"BackofficeGoService/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -11,29 +18,86 @@ import (
 
 // AuthController handles authentication-related HTTP requests
 type AuthController struct {
-	authService *services.AuthService
+	authService service.AuthService
+	loginLimit  route.RateLimit
+}
+
+// AuthControllerOption configures an AuthController
+type AuthControllerOption func(*AuthController)
+
+// WithLoginRateLimit limits the login and registration attempts of each
+// client IP
+func WithLoginRateLimit(limit route.RateLimit) AuthControllerOption {
+	return func(ac *AuthController) {
+		ac.loginLimit = limit
+	}
 }
 
 // NewAuthController creates a new auth controller
-func NewAuthController(authService *services.AuthService) *AuthController {
-	return &AuthController{
+func NewAuthController(authService service.AuthService, opts ...AuthControllerOption) *AuthController {
+	ac := &AuthController{
 		authService: authService,
 	}
+	for _, opt := range opts {
+		opt(ac)
+	}
+	return ac
+}
+
+// Routes lists the authentication routes
+func (ac *AuthController) Routes() []route.Definition {
+	return []route.Definition{
+		{Method: http.MethodPost, Path: "/auth/login", Handler: ac.Login, Policy: route.Policy{RateLimit: ac.loginLimit}},
+		{Method: http.MethodPost, Path: "/auth/logout", Handler: ac.Logout, Policy: route.Policy{Auth: route.Authenticated}},
+		{Method: http.MethodPost, Path: "/auth/refresh", Handler: ac.RefreshToken},
+		{
+			Method:  http.MethodPost,
+			Path:    "/auth/register",
+			Handler: ac.Register,
+			Policy:  route.Policy{RateLimit: ac.loginLimit},
+			Feature: config.FeatureRegistration,
+		},
+		{
+			// The only route accepting tokens restricted to changing the password
+			Method:      http.MethodPost,
+			Path:        "/auth/change-password",
+			Handler:     ac.ChangePassword,
+			Middlewares: []string{route.NotImpersonating},
+			Policy:      route.Policy{Auth: route.PasswordChange},
+		},
+	}
 }
 
 // LoginRequest represents the login request payload
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+
+	// RememberMe trusts the device and issues a token lasting JWT_REMEMBER_EXPIRATION
+	RememberMe bool `json:"remember_me"`
+	// DeviceID identifies the device to trust or recognize; a device
+	// trusted without one gets an identifier in the response
+	DeviceID string `json:"device_id" binding:"omitempty,min=16,max=128"`
 }
 
 // RegisterRequest represents the registration request payload
 type RegisterRequest struct {
 	Email     string `json:"email" binding:"required,email"`
 	Password  string `json:"password" binding:"required,min=6"`
-	FirstName string `json:"first_name" binding:"required"`
-	LastName  string `json:"last_name" binding:"required"`
-	Username  string `json:"username" binding:"required"`
+	FirstName string `json:"first_name" binding:"required,notblank"`
+	LastName  string `json:"last_name" binding:"required,notblank"`
+	Username  string `json:"username" binding:"required,notblank"`
+}
+
+// ChangePasswordRequest represents the change-password request payload
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// RefreshTokenRequest represents the token refresh request payload
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // LoginResponse represents the login response
@@ -51,20 +115,35 @@ type LoginResponse struct {
 // @Param request body RegisterRequest true "Registration data"
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
 // @Router /api/v1/auth/register [post]
 func (ac *AuthController) Register(c *gin.Context) {
-	var req RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		appErr := errors.NewValidationError("Invalid request data", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+	req, ok := request.Bind[RegisterRequest](c)
+	if !ok {
 		return
 	}
 
-	user, err := ac.authService.Register(c.Request.Context(), &req)
+	user, err := ac.authService.Register(c.Request.Context(), &services.CreateUserRequest{
+		Email:     req.Email,
+		Password:  req.Password,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Username:  req.Username,
+	})
 	if err != nil {
-		appErr := errors.NewInternalServerError("Failed to register user", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+		var appErr *errors.AppError
+		switch {
+		case stderrors.Is(err, services.ErrEmailTaken):
+			appErr = errors.NewConflictError(i18n.UserEmailTaken, err).WithCode(errors.CodeEmailAlreadyExists)
+		case stderrors.Is(err, services.ErrPasswordBreached), stderrors.Is(err, services.ErrPasswordCheckUnavailable):
+			appErr = passwordPolicyError(err)
+		default:
+			appErr = errors.NewInternalServerError(i18n.AuthRegisterFailed, err)
+		}
+		middleware.RespondError(c, appErr)
 		return
 	}
 
@@ -86,17 +165,26 @@ func (ac *AuthController) Register(c *gin.Context) {
 // @Failure 401 {object} map[string]interface{}
 // @Router /api/v1/auth/login [post]
 func (ac *AuthController) Login(c *gin.Context) {
-	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		appErr := errors.NewValidationError("Invalid request data", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+	req, ok := request.Bind[LoginRequest](c)
+	if !ok {
 		return
 	}
 
-	result, err := ac.authService.Login(c.Request.Context(), req.Email, req.Password)
+	client := services.ClientInfo{
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		DeviceID:   req.DeviceID,
+		RememberMe: req.RememberMe,
+	}
+	result, err := ac.authService.Login(c.Request.Context(), req.Email, req.Password, client)
 	if err != nil {
-		appErr := errors.NewUnauthorizedError("Invalid credentials", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+		if stderrors.Is(err, services.ErrAccountDeactivated) {
+			appErr := errors.NewForbiddenError(i18n.AuthAccountDeactivated, err).WithCode(errors.CodeAccountDeactivated)
+			middleware.RespondError(c, appErr)
+			return
+		}
+		appErr := errors.NewUnauthorizedError(i18n.AuthInvalidCredentials, err).WithCode(errors.CodeInvalidCredentials)
+		middleware.RespondError(c, appErr)
 		return
 	}
 
@@ -105,18 +193,23 @@ func (ac *AuthController) Login(c *gin.Context) {
 
 // Logout handles user logout
 // @Summary Logout user
-// @Description Logout user and invalidate token
+// @Description End the session of the token; its access and refresh tokens are refused from the next request on
 // @Tags auth
 // @Security BearerAuth
 // @Produce json
 // @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
 // @Router /api/v1/auth/logout [post]
 func (ac *AuthController) Logout(c *gin.Context) {
-	// Get token from header
-	token := c.GetHeader("Authorization")
-	if token != "" {
-		// Invalidate token (add to blacklist, etc.)
-		_ = ac.authService.Logout(c.Request.Context(), token)
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+	if err := ac.authService.Logout(c.Request.Context(), claims); err != nil {
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.SessionRevokeFailed, err))
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -135,22 +228,90 @@ func (ac *AuthController) Logout(c *gin.Context) {
 // @Failure 401 {object} map[string]interface{}
 // @Router /api/v1/auth/refresh [post]
 func (ac *AuthController) RefreshToken(c *gin.Context) {
-	var req struct {
-		RefreshToken string `json:"refresh_token" binding:"required"`
+	req, ok := request.Bind[RefreshTokenRequest](c)
+	if !ok {
+		return
+	}
+
+	result, err := ac.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if stderrors.Is(err, services.ErrPasswordChangeRequired) {
+			appErr := errors.NewForbiddenError(i18n.AuthPasswordChangeRequired, err).WithCode(errors.CodePasswordChangeRequired)
+			middleware.RespondError(c, appErr)
+			return
+		}
+		if stderrors.Is(err, services.ErrProfileIncomplete) {
+			appErr := errors.NewForbiddenError(i18n.AuthProfileIncomplete, err).WithCode(errors.CodeProfileIncomplete)
+			middleware.RespondError(c, appErr)
+			return
+		}
+		if stderrors.Is(err, services.ErrSessionExpired) {
+			appErr := errors.NewUnauthorizedError(i18n.AuthSessionExpired, err).WithCode(errors.CodeSessionExpired)
+			middleware.RespondError(c, appErr)
+			return
+		}
+		appErr := errors.NewUnauthorizedError(i18n.AuthInvalidRefreshToken, err).WithCode(errors.CodeTokenInvalid)
+		middleware.RespondError(c, appErr)
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		appErr := errors.NewValidationError("Invalid request data", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+	c.JSON(http.StatusOK, result)
+}
+
+// ChangePassword handles a user changing their own password
+// @Summary Change password
+// @Description Replace the current password and return an unrestricted token. Accepts the restricted token issued for expired passwords.
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/auth/change-password [post]
+func (ac *AuthController) ChangePassword(c *gin.Context) {
+	req, ok := request.Bind[ChangePasswordRequest](c)
+	if !ok {
 		return
 	}
 
-	result, err := ac.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	result, err := ac.authService.ChangePassword(c.Request.Context(), claims, req.CurrentPassword, req.NewPassword)
 	if err != nil {
-		appErr := errors.NewUnauthorizedError("Invalid refresh token", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+		var appErr *errors.AppError
+		switch {
+		case stderrors.Is(err, services.ErrInvalidCurrentPassword):
+			appErr = errors.NewBadRequestError(i18n.AuthInvalidCurrentPassword, err).WithCode(errors.CodeInvalidCurrentPassword)
+		case stderrors.Is(err, services.ErrPasswordReused):
+			appErr = errors.NewBadRequestError(i18n.AuthPasswordReused, err).WithCode(errors.CodePasswordReused)
+		case stderrors.Is(err, services.ErrPasswordBreached), stderrors.Is(err, services.ErrPasswordCheckUnavailable):
+			appErr = passwordPolicyError(err)
+		case stderrors.Is(err, services.ErrImpersonationForbidden):
+			appErr = errors.NewForbiddenError(i18n.AuthImpersonationForbidden, err).WithCode(errors.CodeImpersonationForbidden)
+		default:
+			appErr = errors.NewInternalServerError(i18n.AuthPasswordChangeFailed, err)
+		}
+		middleware.RespondError(c, appErr)
 		return
 	}
 
 	c.JSON(http.StatusOK, result)
 }
+
+// passwordPolicyError maps a password refused by the password policy
+func passwordPolicyError(err error) *errors.AppError {
+	if stderrors.Is(err, services.ErrPasswordCheckUnavailable) {
+		return errors.NewAppError(http.StatusServiceUnavailable, i18n.AuthPasswordCheckUnavailable, err).WithCode(errors.CodePasswordCheckUnavailable)
+	}
+	return errors.NewValidationError(i18n.AuthPasswordBreached, err).WithCode(errors.CodePasswordBreached)
+}
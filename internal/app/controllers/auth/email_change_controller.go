@@ -0,0 +1,126 @@
+package auth
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailChangeController handles users changing their email address
+type EmailChangeController struct {
+	emailChangeService *services.EmailChangeService
+}
+
+// NewEmailChangeController creates a new email change controller
+func NewEmailChangeController(emailChangeService *services.EmailChangeService) *EmailChangeController {
+	return &EmailChangeController{
+		emailChangeService: emailChangeService,
+	}
+}
+
+// EmailChangeRequest represents the email change request payload
+type EmailChangeRequest struct {
+	Password string `json:"password" binding:"required"`
+	NewEmail string `json:"new_email" binding:"required,email"`
+}
+
+// ConfirmEmailChangeRequest represents the email change confirmation payload
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// RequestEmailChange handles a user asking to change their email address
+// @Summary Request email change
+// @Description Mail a confirmation token to the new address and a notice to the current one. A newer request supersedes a pending one.
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body EmailChangeRequest true "Current password and new email"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/me/email-change [post]
+func (ec *EmailChangeController) RequestEmailChange(c *gin.Context) {
+	req, ok := request.Bind[EmailChangeRequest](c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	change, err := ec.emailChangeService.Request(c.Request.Context(), claims, req.Password, req.NewEmail)
+	if err != nil {
+		middleware.RespondError(c, emailChangeError(err))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Confirmation sent to the new email address",
+		"data":    change,
+	})
+}
+
+// ConfirmEmailChange handles the confirmation token coming back
+// @Summary Confirm email change
+// @Description Apply a requested email change. Every token and session of the user is revoked, so they log in again with the new address.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ConfirmEmailChangeRequest true "Token mailed to the new address"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/auth/confirm-email-change [post]
+func (ec *EmailChangeController) ConfirmEmailChange(c *gin.Context) {
+	req, ok := request.Bind[ConfirmEmailChangeRequest](c)
+	if !ok {
+		return
+	}
+
+	user, err := ec.emailChangeService.Confirm(c.Request.Context(), req.Token)
+	if err != nil {
+		middleware.RespondError(c, emailChangeError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email address changed",
+		"data":    user,
+	})
+}
+
+// emailChangeError reports why an email change could not be requested or confirmed
+func emailChangeError(err error) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrInvalidCurrentPassword):
+		return errors.NewBadRequestError(i18n.AuthInvalidCurrentPassword, err).WithCode(errors.CodeInvalidCurrentPassword)
+	case stderrors.Is(err, services.ErrEmailUnchanged):
+		return errors.NewBadRequestError(i18n.AuthEmailUnchanged, err).WithCode(errors.CodeEmailUnchanged)
+	case stderrors.Is(err, services.ErrEmailTaken):
+		return errors.NewConflictError(i18n.UserEmailTaken, err).WithCode(errors.CodeEmailAlreadyExists)
+	case stderrors.Is(err, services.ErrImpersonationForbidden):
+		return errors.NewForbiddenError(i18n.AuthImpersonationForbidden, err).WithCode(errors.CodeImpersonationForbidden)
+	case stderrors.Is(err, services.ErrEmailChangeInvalid), stderrors.Is(err, services.ErrUserNotFound):
+		return errors.NewBadRequestError(i18n.AuthEmailChangeTokenInvalid, err).WithCode(errors.CodeEmailChangeTokenInvalid)
+	case stderrors.Is(err, services.ErrEmailChangeExpired):
+		return errors.NewBadRequestError(i18n.AuthEmailChangeTokenExpired, err).WithCode(errors.CodeEmailChangeTokenExpired)
+	case stderrors.Is(err, services.ErrEmailUnavailable), stderrors.Is(err, services.ErrEmailDeliveryFailed):
+		return errors.NewAppError(http.StatusServiceUnavailable, i18n.AuthEmailUnavailable, err).WithCode(errors.CodeEmailUnavailable)
+	default:
+		return errors.NewInternalServerError(i18n.AuthEmailChangeFailed, err)
+	}
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/oidc"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCController handles signing in with OpenID Connect providers
+type OIDCController struct {
+	oidc          *services.OIDCService
+	callbackLimit route.RateLimit
+}
+
+// OIDCControllerOption configures an OIDCController
+type OIDCControllerOption func(*OIDCController)
+
+// WithOIDCRateLimit limits the sign-in callbacks of each client IP, as
+// logins are
+func WithOIDCRateLimit(limit route.RateLimit) OIDCControllerOption {
+	return func(oc *OIDCController) {
+		oc.callbackLimit = limit
+	}
+}
+
+// NewOIDCController creates a new OpenID Connect controller
+func NewOIDCController(oidc *services.OIDCService, opts ...OIDCControllerOption) *OIDCController {
+	oc := &OIDCController{
+		oidc: oidc,
+	}
+	for _, opt := range opts {
+		opt(oc)
+	}
+	return oc
+}
+
+// Routes lists the OpenID Connect sign-in routes
+func (oc *OIDCController) Routes() []route.Definition {
+	return []route.Definition{
+		{Method: http.MethodGet, Path: "/auth/oidc/providers", Handler: oc.ListProviders},
+		{Method: http.MethodGet, Path: "/auth/oidc/:provider/login", Handler: oc.Login},
+		{Method: http.MethodGet, Path: "/auth/oidc/:provider/callback", Handler: oc.Callback, Policy: route.Policy{RateLimit: oc.callbackLimit}},
+	}
+}
+
+// ListProviders handles listing the providers users can sign in with
+// @Summary List OpenID Connect providers
+// @Description List the providers users can sign in with, by the slug their login URL takes
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/oidc/providers [get]
+func (oc *OIDCController) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": oc.oidc.Providers(),
+	})
+}
+
+// Login handles starting a sign-in with a provider
+// @Summary Sign in with an OpenID Connect provider
+// @Description Redirect to the provider's sign-in page. The provider sends the user back to the callback, which must be reached within OIDC_STATE_TTL.
+// @Tags auth
+// @Param provider path string true "Provider slug"
+// @Success 302
+// @Failure 404 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/auth/oidc/{provider}/login [get]
+func (oc *OIDCController) Login(c *gin.Context) {
+	url, err := oc.oidc.Begin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		middleware.RespondError(c, oidcError(err))
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
+
+// Callback handles the provider sending the user back
+// @Summary Complete an OpenID Connect sign-in
+// @Description Redeem the authorization code the provider sent back and log in the user the provider account is linked to, linking or creating one as the provider is configured to. Responds like login.
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider slug"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State the sign-in was started with"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/auth/oidc/{provider}/callback [get]
+func (oc *OIDCController) Callback(c *gin.Context) {
+	// The user declined, or the provider refused them
+	if reason := c.Query("error"); reason != "" {
+		appErr := errors.NewUnauthorizedError(i18n.OIDCLoginFailed, stderrors.New(reason)).WithCode(errors.CodeOIDCLoginFailed)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	client := services.ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	result, err := oc.oidc.Complete(c.Request.Context(), c.Param("provider"), c.Query("code"), c.Query("state"), client)
+	if err != nil {
+		middleware.RespondError(c, oidcError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// oidcError maps a sign-in failure to its response
+func oidcError(err error) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrOIDCProviderNotFound):
+		return errors.NewNotFoundError(i18n.OIDCProviderNotFound, err).WithCode(errors.CodeOIDCProviderNotFound)
+	case stderrors.Is(err, services.ErrOIDCStateInvalid):
+		return errors.NewBadRequestError(i18n.OIDCStateInvalid, err).WithCode(errors.CodeOIDCStateInvalid)
+	case stderrors.Is(err, oidc.ErrUnavailable):
+		return errors.NewAppError(http.StatusServiceUnavailable, i18n.OIDCProviderUnavailable, err).WithCode(errors.CodeOIDCProviderUnavailable)
+	case stderrors.Is(err, oidc.ErrExchange), stderrors.Is(err, oidc.ErrInvalidIDToken):
+		return errors.NewUnauthorizedError(i18n.OIDCLoginFailed, err).WithCode(errors.CodeOIDCLoginFailed)
+	case stderrors.Is(err, services.ErrOIDCAccountExists), stderrors.Is(err, services.ErrIdentityLinked):
+		return errors.NewConflictError(i18n.OIDCAccountExists, err).WithCode(errors.CodeOIDCAccountExists)
+	case stderrors.Is(err, services.ErrOIDCNotProvisioned):
+		return errors.NewForbiddenError(i18n.OIDCNotProvisioned, err).WithCode(errors.CodeOIDCNotProvisioned)
+	case stderrors.Is(err, services.ErrAccountDeactivated):
+		return errors.NewForbiddenError(i18n.AuthAccountDeactivated, err).WithCode(errors.CodeAccountDeactivated)
+	default:
+		return errors.NewInternalServerError(i18n.OIDCSignInFailed, err)
+	}
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecureAccountController handles the links of new sign-in emails
+type SecureAccountController struct {
+	authService *services.AuthService
+}
+
+// NewSecureAccountController creates a new secure account controller
+func NewSecureAccountController(authService *services.AuthService) *SecureAccountController {
+	return &SecureAccountController{
+		authService: authService,
+	}
+}
+
+// SecureAccountRequest represents the secure account payload
+type SecureAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// SecureAccount handles a user disowning a new sign-in
+// @Summary Secure account after a new sign-in
+// @Description Redeem the token of a new sign-in email. Every token and session of the user is revoked, so whoever signed in is signed out; the user should then change their password.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body SecureAccountRequest true "Token from the new sign-in email"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/auth/secure-account [post]
+func (sc *SecureAccountController) SecureAccount(c *gin.Context) {
+	req, ok := request.Bind[SecureAccountRequest](c)
+	if !ok {
+		return
+	}
+
+	if err := sc.authService.SecureAccount(c.Request.Context(), req.Token); err != nil {
+		if stderrors.Is(err, services.ErrSecureAccountTokenInvalid) {
+			middleware.RespondError(c, errors.NewBadRequestError(i18n.AuthSecureAccountTokenInvalid, err).WithCode(errors.CodeSecureAccountTokenInvalid))
+			return
+		}
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.AuthSecureAccountFailed, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Signed out everywhere; change your password next",
+	})
+}
@@ -0,0 +1,208 @@
+package feature
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services/featureflags"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureController handles feature flag HTTP requests
+type FeatureController struct {
+	flags *featureflags.Service
+}
+
+// NewFeatureController creates a new feature flag controller
+func NewFeatureController(flags *featureflags.Service) *FeatureController {
+	return &FeatureController{
+		flags: flags,
+	}
+}
+
+// Routes lists the flag administration routes. Every route requires the
+// features.manage permission.
+func (fc *FeatureController) Routes() []route.Definition {
+	canManage := route.Policy{Auth: route.Authenticated, Permission: models.PermissionFeaturesManage}
+	return []route.Definition{
+		{Method: http.MethodGet, Path: "/admin/features", Handler: fc.ListFlags, Policy: canManage},
+		{Method: http.MethodPost, Path: "/admin/features", Handler: fc.CreateFlag, Policy: canManage},
+		{Method: http.MethodGet, Path: "/admin/features/:key", Handler: fc.GetFlag, Policy: canManage},
+		{Method: http.MethodPut, Path: "/admin/features/:key", Handler: fc.UpdateFlag, Policy: canManage},
+		{Method: http.MethodDelete, Path: "/admin/features/:key", Handler: fc.DeleteFlag, Policy: canManage},
+	}
+}
+
+// ListFlags handles listing feature flags
+// @Summary List feature flags
+// @Description List every feature flag definition
+// @Tags features
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/features [get]
+func (fc *FeatureController) ListFlags(c *gin.Context) {
+	flags, err := fc.flags.ListFlags(c.Request.Context())
+	if err != nil {
+		appErr := errors.NewInternalServerError("Failed to fetch feature flags", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": flags,
+	})
+}
+
+// CreateFlag handles creating a feature flag
+// @Summary Create feature flag
+// @Description Create a feature flag with optional role/user targeting and rollout percentage
+// @Tags features
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param flag body featureflags.CreateFlagRequest true "Flag data"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/admin/features [post]
+func (fc *FeatureController) CreateFlag(c *gin.Context) {
+	var req featureflags.CreateFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	flag, err := fc.flags.CreateFlag(c.Request.Context(), &req)
+	if err != nil {
+		appErr := flagError(err, "Failed to create feature flag")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": flag,
+	})
+}
+
+// GetFlag handles getting a feature flag by key
+// @Summary Get feature flag
+// @Description Get a feature flag definition by key
+// @Tags features
+// @Security BearerAuth
+// @Produce json
+// @Param key path string true "Flag key"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/features/{key} [get]
+func (fc *FeatureController) GetFlag(c *gin.Context) {
+	flag, err := fc.flags.GetFlag(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		appErr := flagError(err, "Failed to fetch feature flag")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": flag,
+	})
+}
+
+// UpdateFlag handles updating a feature flag
+// @Summary Update feature flag
+// @Description Partially update a feature flag; omitted targeting lists are left unchanged
+// @Tags features
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key path string true "Flag key"
+// @Param flag body featureflags.UpdateFlagRequest true "Flag data"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/admin/features/{key} [put]
+func (fc *FeatureController) UpdateFlag(c *gin.Context) {
+	var req featureflags.UpdateFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	flag, err := fc.flags.UpdateFlag(c.Request.Context(), c.Param("key"), &req)
+	if err != nil {
+		appErr := flagError(err, "Failed to update feature flag")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": flag,
+	})
+}
+
+// DeleteFlag handles deleting a feature flag
+// @Summary Delete feature flag
+// @Description Delete a feature flag; guarded routes become unavailable
+// @Tags features
+// @Security BearerAuth
+// @Produce json
+// @Param key path string true "Flag key"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/features/{key} [delete]
+func (fc *FeatureController) DeleteFlag(c *gin.Context) {
+	if err := fc.flags.DeleteFlag(c.Request.Context(), c.Param("key")); err != nil {
+		appErr := flagError(err, "Failed to delete feature flag")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Feature flag deleted successfully",
+	})
+}
+
+// MyFeatures handles evaluating every flag for the current user
+// @Summary Current user's features
+// @Description Evaluate every feature flag for the authenticated user
+// @Tags features
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me/features [get]
+func (fc *FeatureController) MyFeatures(c *gin.Context) {
+	evaluations, err := fc.flags.EvaluateAll(c.Request.Context(), middleware.FeatureUser(c))
+	if err != nil {
+		appErr := errors.NewInternalServerError("Failed to evaluate feature flags", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": evaluations,
+	})
+}
+
+// flagError maps feature flag service errors to HTTP errors
+func flagError(err error, fallback string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, featureflags.ErrFlagNotFound):
+		return errors.NewNotFoundError("Feature flag not found", err)
+	case stderrors.Is(err, featureflags.ErrFlagExists):
+		return errors.NewConflictError("Feature flag already exists", err)
+	case stderrors.Is(err, featureflags.ErrInvalidFlagKey), stderrors.Is(err, featureflags.ErrInvalidPercentage):
+		return errors.NewValidationError(err.Error(), err)
+	}
+	return errors.NewInternalServerError(fallback, err)
+}
@@ -0,0 +1,107 @@
+package file
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/infrastructure/storage"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/export"
+	"BackofficeGoService/internal/pkg/filetoken"
+	"BackofficeGoService/internal/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Files opens stored files
+type Files interface {
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// FileController serves files through signed download links
+type FileController struct {
+	files  Files
+	links  *filetoken.Signer
+	tokens middleware.TokenValidator
+}
+
+// NewFileController creates a new file controller. links verifies download
+// links; tokens authenticates the callers of links issued to one user.
+func NewFileController(files Files, links *filetoken.Signer, tokens middleware.TokenValidator) *FileController {
+	return &FileController{
+		files:  files,
+		links:  links,
+		tokens: tokens,
+	}
+}
+
+// Download handles a signed download link. The token names the file and its
+// expiry; links issued to anyone need no access token, links issued to a
+// user need that user's, from the Authorization header, the access_token
+// cookie or the access_token query parameter.
+// @Summary Download file
+// @Description Download a file, such as an export result, through a signed link
+// @Tags files
+// @Produce octet-stream
+// @Param token path string true "Signed file token"
+// @Success 200 {file} file
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/files/{token} [get]
+func (fc *FileController) Download(c *gin.Context) {
+	claims, err := fc.links.Verify(c.Param("token"))
+	if err != nil {
+		appErr := errors.NewForbiddenError(i18n.FileLinkInvalid, err).WithCode(errors.CodeDownloadLinkInvalid)
+		if stderrors.Is(err, filetoken.ErrExpired) {
+			appErr = errors.NewForbiddenError(i18n.FileLinkExpired, err).WithCode(errors.CodeDownloadLinkExpired)
+		}
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	if claims.UserID != filetoken.Anyone {
+		caller, appErr := middleware.AuthenticateStream(c, fc.tokens)
+		if appErr != nil {
+			middleware.RespondError(c, appErr)
+			return
+		}
+		if !claims.Allows(caller.UserID) {
+			middleware.RespondError(c, errors.NewForbiddenError(i18n.FileLinkForbidden, nil).WithCode(errors.CodeDownloadLinkForbidden))
+			return
+		}
+	}
+
+	f, err := fc.files.Open(c.Request.Context(), claims.Key)
+	if err != nil {
+		if stderrors.Is(err, storage.ErrNotFound) || stderrors.Is(err, storage.ErrInvalidKey) {
+			middleware.RespondError(c, errors.NewNotFoundError(i18n.FileNotFound, err).WithCode(errors.CodeFileNotFound))
+			return
+		}
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.FileDownloadFailed, err))
+		return
+	}
+	defer f.Close()
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(claims.Key)}))
+	c.Header("Cache-Control", "private, no-store")
+	c.DataFromReader(http.StatusOK, -1, contentType(claims.Key), f, nil)
+}
+
+// contentType returns the media type of a file from its extension
+func contentType(key string) string {
+	ext := path.Ext(key)
+	if format, err := export.ParseFormat(strings.TrimPrefix(ext, ".")); err == nil && ext != "" {
+		return format.ContentType()
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
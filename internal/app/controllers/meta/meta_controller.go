@@ -0,0 +1,52 @@
+package meta
+
+import (
+	"net/http"
+
+	"BackofficeGoService/internal/pkg/buildinfo"
+	"BackofficeGoService/internal/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetaController describes the API itself to clients
+type MetaController struct {
+	build    buildinfo.Info
+	features map[string]bool
+}
+
+// versionResponse is the running build and the API features it serves
+type versionResponse struct {
+	buildinfo.Info
+	Features map[string]bool `json:"features"`
+}
+
+// NewMetaController creates a meta controller reporting build as the running
+// build and features as the API features that are on
+func NewMetaController(build buildinfo.Info, features map[string]bool) *MetaController {
+	return &MetaController{build: build, features: features}
+}
+
+// ListErrorCodes handles listing the error codes the API can return
+// @Summary List error codes
+// @Description List every registered error code, so clients can keep their enums in sync
+// @Tags meta
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/error-codes [get]
+func (mc *MetaController) ListErrorCodes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": errors.Codes(),
+	})
+}
+
+// Version handles reporting the running build
+// @Summary Get version
+// @Description Report the version, commit, build date and Go version of the running build, and which API features are on
+// @Tags meta
+// @Produce json
+// @Success 200 {object} versionResponse
+// @Router /api/v1/version [get]
+func (mc *MetaController) Version(c *gin.Context) {
+	c.JSON(http.StatusOK, versionResponse{Info: mc.build, Features: mc.features})
+}
@@ -0,0 +1,148 @@
+package notification
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationController handles the current user's notifications
+type NotificationController struct {
+	notificationService *services.NotificationService
+	pages               pagination.Config
+}
+
+// NewNotificationController creates a new notification controller
+func NewNotificationController(notificationService *services.NotificationService, pages pagination.Config) *NotificationController {
+	return &NotificationController{
+		notificationService: notificationService,
+		pages:               pages,
+	}
+}
+
+// Routes lists the notification routes. Users only ever see their own
+// notifications, also while policies are pending.
+func (nc *NotificationController) Routes() []route.Definition {
+	account := route.Policy{Auth: route.Account}
+	return []route.Definition{
+		{Method: http.MethodGet, Path: "/me/notifications", Handler: nc.ListNotifications, Policy: account},
+		{Method: http.MethodPost, Path: "/me/notifications/read-all", Handler: nc.MarkAllRead, Policy: account},
+		{Method: http.MethodPost, Path: "/me/notifications/:id/read", Handler: nc.MarkRead, Policy: account},
+	}
+}
+
+// ListNotifications handles listing the current user's notifications
+// @Summary List notifications
+// @Description List the current user's notifications, newest first, with the unread count
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param unread query bool false "Only unread notifications"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me/notifications [get]
+func (nc *NotificationController) ListNotifications(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError("Authentication required", nil)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	params, appErr := pagination.ParseParams(c, nc.pages)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	result, unread, err := nc.notificationService.ListNotifications(c.Request.Context(), claims.UserID, unreadOnly, params.Limit, params.Offset())
+	if err != nil {
+		appErr := errors.NewInternalServerError("Failed to fetch notifications", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	meta := pagination.NewMeta(params, result.Total)
+	c.Header("Link", meta.Links(c.Request.URL))
+	c.JSON(http.StatusOK, gin.H{
+		"data":         result.Items,
+		"unread_count": unread,
+		"meta":         meta,
+	})
+}
+
+// MarkRead handles marking a notification as read
+// @Summary Mark notification read
+// @Description Mark one of the current user's notifications as read
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Notification ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/me/notifications/{id}/read [post]
+func (nc *NotificationController) MarkRead(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError("Authentication required", nil)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	if err := nc.notificationService.MarkRead(c.Request.Context(), claims.UserID, c.Param("id")); err != nil {
+		var appErr *errors.AppError
+		switch {
+		case stderrors.Is(err, services.ErrNotificationNotFound):
+			appErr = errors.NewNotFoundError("Notification not found", err)
+		default:
+			appErr = errors.NewInternalServerError("Failed to mark notification as read", err)
+		}
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notification marked as read",
+	})
+}
+
+// MarkAllRead handles marking every notification as read
+// @Summary Mark all notifications read
+// @Description Mark every unread notification of the current user as read
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me/notifications/read-all [post]
+func (nc *NotificationController) MarkAllRead(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError("Authentication required", nil)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	marked, err := nc.notificationService.MarkAllRead(c.Request.Context(), claims.UserID)
+	if err != nil {
+		appErr := errors.NewInternalServerError("Failed to mark notifications as read", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notifications marked as read",
+		"marked":  marked,
+	})
+}
@@ -0,0 +1,308 @@
+package organization
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrganizationController handles organization-related HTTP requests
+type OrganizationController struct {
+	orgService *services.OrganizationService
+	pages      pagination.Config
+}
+
+// NewOrganizationController creates a new organization controller
+func NewOrganizationController(orgService *services.OrganizationService, pages pagination.Config) *OrganizationController {
+	return &OrganizationController{
+		orgService: orgService,
+		pages:      pages,
+	}
+}
+
+// Routes lists the organization routes. Org-scoped reads are open to
+// members; mutations are admin-only.
+func (oc *OrganizationController) Routes() []route.Definition {
+	adminOnly := route.Policy{Auth: route.Authenticated, Roles: []models.UserRole{models.RoleAdmin}}
+	memberOnly := route.Policy{Auth: route.Authenticated}
+	organization := func(method, path string, handler gin.HandlerFunc, policy route.Policy, middlewares ...string) route.Definition {
+		return route.Definition{
+			Method:      method,
+			Path:        path,
+			Handler:     handler,
+			Middlewares: middlewares,
+			Policy:      policy,
+			Feature:     config.FeatureOrganizations,
+		}
+	}
+
+	return []route.Definition{
+		organization(http.MethodGet, "/organizations", oc.ListOrganizations, adminOnly),
+		organization(http.MethodPost, "/organizations", oc.CreateOrganization, adminOnly),
+		organization(http.MethodGet, "/organizations/:id", oc.GetOrganization, memberOnly, route.OrgMember),
+		organization(http.MethodPut, "/organizations/:id", oc.UpdateOrganization, adminOnly),
+		organization(http.MethodDelete, "/organizations/:id", oc.DeleteOrganization, adminOnly),
+
+		organization(http.MethodGet, "/organizations/:id/members", oc.ListMembers, memberOnly, route.OrgMember),
+		organization(http.MethodPost, "/organizations/:id/members", oc.AddMember, adminOnly),
+		organization(http.MethodDelete, "/organizations/:id/members/:userId", oc.RemoveMember, adminOnly),
+	}
+}
+
+// ListOrganizations handles listing organizations with pagination
+// @Summary List organizations
+// @Description Get a list of organizations with pagination (admin only)
+// @Tags organizations
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/organizations [get]
+func (oc *OrganizationController) ListOrganizations(c *gin.Context) {
+	params, appErr := pagination.ParseParams(c, oc.pages)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	result, err := oc.orgService.ListOrganizations(c.Request.Context(), params.Limit, params.Offset())
+	if err != nil {
+		appErr := errors.NewInternalServerError("Failed to fetch organizations", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	meta := pagination.NewMeta(params, result.Total)
+	c.Header("Link", meta.Links(c.Request.URL))
+	c.JSON(http.StatusOK, gin.H{
+		"data": result.Items,
+		"meta": meta,
+	})
+}
+
+// GetOrganization handles getting an organization by ID
+// @Summary Get organization by ID
+// @Description Get organization details (members or admin)
+// @Tags organizations
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/organizations/{id} [get]
+func (oc *OrganizationController) GetOrganization(c *gin.Context) {
+	org, err := oc.orgService.GetOrganization(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		appErr := organizationError(err, "Failed to fetch organization")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": org,
+	})
+}
+
+// CreateOrganization handles creating a new organization
+// @Summary Create organization
+// @Description Create a new organization (admin only)
+// @Tags organizations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param organization body services.CreateOrganizationRequest true "Organization data"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/organizations [post]
+func (oc *OrganizationController) CreateOrganization(c *gin.Context) {
+	var req services.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	org, err := oc.orgService.CreateOrganization(c.Request.Context(), &req)
+	if err != nil {
+		appErr := organizationError(err, "Failed to create organization")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": org,
+	})
+}
+
+// UpdateOrganization handles updating an organization
+// @Summary Update organization
+// @Description Partially update an organization (admin only)
+// @Tags organizations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param organization body services.UpdateOrganizationRequest true "Organization data"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/organizations/{id} [put]
+func (oc *OrganizationController) UpdateOrganization(c *gin.Context) {
+	var req services.UpdateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	org, err := oc.orgService.UpdateOrganization(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		appErr := organizationError(err, "Failed to update organization")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": org,
+	})
+}
+
+// DeleteOrganization handles deleting an organization
+// @Summary Delete organization
+// @Description Delete an organization; organizations with members require force=true (admin only)
+// @Tags organizations
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param force query bool false "Delete even if the organization has members"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/organizations/{id} [delete]
+func (oc *OrganizationController) DeleteOrganization(c *gin.Context) {
+	force := c.Query("force") == "true"
+
+	if err := oc.orgService.DeleteOrganization(c.Request.Context(), c.Param("id"), force); err != nil {
+		appErr := organizationError(err, "Failed to delete organization")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Organization deleted successfully",
+	})
+}
+
+// ListMembers handles listing the members of an organization
+// @Summary List organization members
+// @Description List the members of an organization (members or admin)
+// @Tags organizations
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/organizations/{id}/members [get]
+func (oc *OrganizationController) ListMembers(c *gin.Context) {
+	members, err := oc.orgService.ListMembers(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		appErr := organizationError(err, "Failed to fetch members")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": members,
+	})
+}
+
+// AddMember handles adding a user to an organization
+// @Summary Add organization member
+// @Description Add a user to an organization with a per-org role (admin only)
+// @Tags organizations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param member body services.AddMemberRequest true "Membership data"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/organizations/{id}/members [post]
+func (oc *OrganizationController) AddMember(c *gin.Context) {
+	var req services.AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	member, err := oc.orgService.AddMember(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		appErr := organizationError(err, "Failed to add member")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": member,
+	})
+}
+
+// RemoveMember handles removing a user from an organization
+// @Summary Remove organization member
+// @Description Remove a user from an organization (admin only)
+// @Tags organizations
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param userId path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/organizations/{id}/members/{userId} [delete]
+func (oc *OrganizationController) RemoveMember(c *gin.Context) {
+	if err := oc.orgService.RemoveMember(c.Request.Context(), c.Param("id"), c.Param("userId")); err != nil {
+		appErr := organizationError(err, "Failed to remove member")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member removed successfully",
+	})
+}
+
+// organizationError maps organization service errors to HTTP errors
+func organizationError(err error, fallback string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrOrganizationNotFound):
+		return errors.NewNotFoundError("Organization not found", err)
+	case stderrors.Is(err, services.ErrUserNotFound):
+		return errors.NewNotFoundError("User not found", err)
+	case stderrors.Is(err, services.ErrMembershipNotFound):
+		return errors.NewNotFoundError("Membership not found", err)
+	case stderrors.Is(err, services.ErrOrganizationHasMembers):
+		return errors.NewConflictError("Organization has members; use force=true to delete it", err)
+	case stderrors.Is(err, services.ErrMembershipExists):
+		return errors.NewConflictError("User is already a member of this organization", err)
+	case stderrors.Is(err, services.ErrInvalidOrganizationName):
+		return errors.NewValidationError("Organization name is required", err)
+	case stderrors.Is(err, services.ErrInvalidOrgRole):
+		return errors.NewValidationError("Invalid organization role", err)
+	}
+	return errors.NewInternalServerError(fallback, err)
+}
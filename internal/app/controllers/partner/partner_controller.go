@@ -0,0 +1,237 @@
+package partner
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PartnerController handles partner administration and the requests partners sign
+type PartnerController struct {
+	partnerService *services.PartnerService
+}
+
+// NewPartnerController creates a new partner controller
+func NewPartnerController(partnerService *services.PartnerService) *PartnerController {
+	return &PartnerController{
+		partnerService: partnerService,
+	}
+}
+
+// ListPartners handles listing partners
+// @Summary List partners
+// @Description List every partner; secrets are never included
+// @Tags partners
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/partners [get]
+func (pc *PartnerController) ListPartners(c *gin.Context) {
+	partners, err := pc.partnerService.ListPartners(c.Request.Context())
+	if err != nil {
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.PartnerFetchFailed, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": partners,
+	})
+}
+
+// CreatePartner handles registering a partner
+// @Summary Create partner
+// @Description Register a partner; the signing secret is generated unless given and only returned here
+// @Tags partners
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param partner body services.CreatePartnerRequest true "Partner data"
+// @Success 201 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/admin/partners [post]
+func (pc *PartnerController) CreatePartner(c *gin.Context) {
+	req, ok := request.Bind[services.CreatePartnerRequest](c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	partner, err := pc.partnerService.CreatePartner(c.Request.Context(), claims.UserID, &req)
+	if err != nil {
+		middleware.RespondError(c, partnerError(err, req.Name, i18n.PartnerCreateFailed))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":   partner,
+		"secret": partner.Secret,
+	})
+}
+
+// GetPartner handles getting a partner by ID
+// @Summary Get partner
+// @Description Get a partner by ID
+// @Tags partners
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Partner ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/partners/{id} [get]
+func (pc *PartnerController) GetPartner(c *gin.Context) {
+	partner, err := pc.partnerService.GetPartner(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		middleware.RespondError(c, partnerError(err, "", i18n.PartnerFetchFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": partner,
+	})
+}
+
+// UpdatePartner handles updating a partner
+// @Summary Update partner
+// @Description Rename, disable or re-enable a partner, or replace its signing secret
+// @Tags partners
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Partner ID"
+// @Param partner body services.UpdatePartnerRequest true "Partner data"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/admin/partners/{id} [put]
+func (pc *PartnerController) UpdatePartner(c *gin.Context) {
+	req, ok := request.Bind[services.UpdatePartnerRequest](c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	partner, err := pc.partnerService.UpdatePartner(c.Request.Context(), claims.UserID, c.Param("id"), &req)
+	if err != nil {
+		name := ""
+		if req.Name != nil {
+			name = *req.Name
+		}
+		middleware.RespondError(c, partnerError(err, name, i18n.PartnerUpdateFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": partner,
+	})
+}
+
+// DeletePartner handles deleting a partner
+// @Summary Delete partner
+// @Description Delete a partner. The users it provisioned are kept but unlinked from it.
+// @Tags partners
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Partner ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/partners/{id} [delete]
+func (pc *PartnerController) DeletePartner(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	if err := pc.partnerService.DeletePartner(c.Request.Context(), claims.UserID, c.Param("id")); err != nil {
+		middleware.RespondError(c, partnerError(err, "", i18n.PartnerDeleteFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Partner deleted successfully",
+	})
+}
+
+// ProvisionUser handles a partner creating or updating one of its users
+// @Summary Provision user
+// @Description Create the user the signing partner knows under external_id, or update it when the partner provisioned it before. Requests are signed with X-Partner-ID, X-Timestamp and X-Signature.
+// @Tags partners
+// @Accept json
+// @Produce json
+// @Param X-Partner-ID header string true "Partner ID"
+// @Param X-Timestamp header int true "Unix time in seconds"
+// @Param X-Signature header string true "sha256= and the hex HMAC-SHA256 of X-Timestamp and the body"
+// @Param user body services.ProvisionUserRequest true "User data"
+// @Success 200 {object} map[string]interface{}
+// @Success 201 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/partners/provision-user [post]
+func (pc *PartnerController) ProvisionUser(c *gin.Context) {
+	partner, ok := middleware.GetPartner(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.SignatureInvalid, nil).WithCode(errors.CodeSignatureInvalid)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	req, ok := request.Bind[services.ProvisionUserRequest](c)
+	if !ok {
+		return
+	}
+
+	user, created, err := pc.partnerService.ProvisionUser(c.Request.Context(), partner, &req)
+	if err != nil {
+		var appErr *errors.AppError
+		if stderrors.Is(err, services.ErrEmailTaken) {
+			appErr = errors.NewConflictError(i18n.UserEmailTaken, err).WithCode(errors.CodeEmailAlreadyExists)
+		} else {
+			appErr = errors.NewInternalServerError(i18n.PartnerProvisionFailed, err)
+		}
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{
+		"data": user,
+	})
+}
+
+// partnerError maps partner service errors to HTTP errors
+func partnerError(err error, name, failedKey string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrPartnerNotFound):
+		return errors.NewNotFoundError(i18n.PartnerNotFound, err).WithCode(errors.CodePartnerNotFound)
+	case stderrors.Is(err, services.ErrPartnerExists):
+		return errors.NewConflictError(i18n.PartnerExists, err).WithCode(errors.CodePartnerExists).WithParams(errors.Params{"name": name})
+	}
+	return errors.NewInternalServerError(failedKey, err)
+}
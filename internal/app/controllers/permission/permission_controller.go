@@ -0,0 +1,143 @@
+package permission
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionController handles permission administration HTTP requests
+type PermissionController struct {
+	permissionService *services.PermissionService
+}
+
+// NewPermissionController creates a new permission controller
+func NewPermissionController(permissionService *services.PermissionService) *PermissionController {
+	return &PermissionController{
+		permissionService: permissionService,
+	}
+}
+
+// Routes lists the permission administration routes
+func (pc *PermissionController) Routes() []route.Definition {
+	canManage := route.Policy{Auth: route.Authenticated, Permission: models.PermissionPermissionsManage}
+	return []route.Definition{
+		{Method: http.MethodGet, Path: "/permissions", Handler: pc.ListPermissions, Policy: canManage},
+		{Method: http.MethodGet, Path: "/roles/:role/permissions", Handler: pc.GetRolePermissions, Policy: canManage},
+		{
+			Method:      http.MethodPut,
+			Path:        "/roles/:role/permissions",
+			Handler:     pc.SetRolePermissions,
+			Middlewares: []string{route.NotImpersonating},
+			Policy:      canManage,
+		},
+	}
+}
+
+// ListPermissions handles listing every known permission
+// @Summary List permissions
+// @Description List every permission that can be granted to roles
+// @Tags permissions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/permissions [get]
+func (pc *PermissionController) ListPermissions(c *gin.Context) {
+	permissions, err := pc.permissionService.ListPermissions(c.Request.Context())
+	if err != nil {
+		appErr := errors.NewInternalServerError("Failed to fetch permissions", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": permissions,
+	})
+}
+
+// GetRolePermissions handles listing the permissions granted to a role
+// @Summary Get role permissions
+// @Description List the permissions granted to a role
+// @Tags permissions
+// @Security BearerAuth
+// @Produce json
+// @Param role path string true "Role"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/roles/{role}/permissions [get]
+func (pc *PermissionController) GetRolePermissions(c *gin.Context) {
+	role := models.UserRole(c.Param("role"))
+
+	permissions, err := pc.permissionService.RolePermissions(c.Request.Context(), role)
+	if err != nil {
+		appErr := permissionError(err, "Failed to fetch role permissions")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"role":        role,
+			"permissions": permissions,
+		},
+	})
+}
+
+// SetRolePermissions handles replacing the permissions granted to a role
+// @Summary Set role permissions
+// @Description Replace the permissions granted to a role; takes effect without re-login
+// @Tags permissions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param role path string true "Role"
+// @Param permissions body services.SetRolePermissionsRequest true "Permission names"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/roles/{role}/permissions [put]
+func (pc *PermissionController) SetRolePermissions(c *gin.Context) {
+	var req services.SetRolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	role := models.UserRole(c.Param("role"))
+
+	permissions, err := pc.permissionService.SetRolePermissions(c.Request.Context(), role, req.Permissions)
+	if err != nil {
+		appErr := permissionError(err, "Failed to update role permissions")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"role":        role,
+			"permissions": permissions,
+		},
+	})
+}
+
+// permissionError maps permission service errors to HTTP errors
+func permissionError(err error, fallback string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrInvalidRole):
+		return errors.NewNotFoundError("Role not found", err)
+	case stderrors.Is(err, services.ErrUnknownPermission):
+		return errors.NewValidationError(err.Error(), err)
+	}
+	return errors.NewInternalServerError(fallback, err)
+}
@@ -0,0 +1,82 @@
+// Package product serves the product catalog, the first resource built on
+// package crud
+package product
+
+import (
+	"BackofficeGoService/internal/app/crud"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// CreateProductRequest represents the payload for creating a product.
+// Products are active unless active is false.
+type CreateProductRequest struct {
+	Name   string `json:"name" binding:"required,notblank,max=200"`
+	SKU    string `json:"sku" binding:"required,notblank,max=64"`
+	Price  *int64 `json:"price" binding:"required,gte=0"`
+	Active *bool  `json:"active"`
+}
+
+// Apply sets the fields of a new product
+func (r CreateProductRequest) Apply(p *models.Product) {
+	p.Name = r.Name
+	p.SKU = r.SKU
+	p.Price = *r.Price
+	p.Active = r.Active == nil || *r.Active
+}
+
+// UpdateProductRequest represents a partial product update
+type UpdateProductRequest struct {
+	Name   *string `json:"name" binding:"omitempty,notblank,max=200"`
+	SKU    *string `json:"sku" binding:"omitempty,notblank,max=64"`
+	Price  *int64  `json:"price" binding:"omitempty,gte=0"`
+	Active *bool   `json:"active"`
+}
+
+// Apply sets the fields the update sends
+func (r UpdateProductRequest) Apply(p *models.Product) {
+	if r.Name != nil {
+		p.Name = *r.Name
+	}
+	if r.SKU != nil {
+		p.SKU = *r.SKU
+	}
+	if r.Price != nil {
+		p.Price = *r.Price
+	}
+	if r.Active != nil {
+		p.Active = *r.Active
+	}
+}
+
+// NewResource creates the product resource, served under /products
+func NewResource(db *database.Manager, pages pagination.Config, opts ...crud.Option) *crud.Resource[models.Product] {
+	return crud.New(db, crud.Config[models.Product]{
+		Path:       "/products",
+		EntityType: "product",
+		Permission: "products",
+		CacheGroup: services.ResponseGroupProducts,
+		New: func() *models.Product {
+			return &models.Product{ID: uuid.New()}
+		},
+		Create: crud.Body[models.Product, CreateProductRequest](),
+		Update: crud.Body[models.Product, UpdateProductRequest](),
+		Filters: map[string]crud.Filter{
+			"name":   crud.Contains("name"),
+			"sku":    crud.Equal("sku"),
+			"active": crud.Bool("active"),
+		},
+		Sorts:       []string{"name", "sku", "price", "created_at"},
+		DefaultSort: "name",
+		Unique:      []string{"sku"},
+		Pages:       pages,
+		NotFound:    crud.Message{Key: i18n.ProductNotFound, Code: errors.CodeProductNotFound},
+		Exists:      crud.Message{Key: i18n.ProductSKUTaken, Code: errors.CodeProductSKUTaken},
+	}, opts...)
+}
@@ -0,0 +1,81 @@
+package stream
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/ws"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// SocketController serves realtime WebSocket connections
+type SocketController struct {
+	hub      *ws.Hub
+	upgrader websocket.Upgrader
+}
+
+// NewSocketController creates a new socket controller. Browsers send
+// cookies with cross-site upgrades, so only the API's own origin and
+// allowedOrigins may connect; "*" allows any origin.
+func NewSocketController(hub *ws.Hub, allowedOrigins []string) *SocketController {
+	return &SocketController{
+		hub: hub,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: checkOrigin(allowedOrigins),
+		},
+	}
+}
+
+// Connect upgrades the request to a WebSocket connection
+// @Summary Realtime WebSocket
+// @Description Upgrade to a WebSocket carrying a JSON protocol: send {"type":"subscribe","topics":[...]} to receive {"type":"event"} messages for notifications, job status and user events. Browsers cannot set headers on the upgrade, so the token may be passed as the access_token cookie or query parameter.
+// @Tags events
+// @Security BearerAuth
+// @Param access_token query string false "Access token"
+// @Success 101 {string} string "switching protocols"
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/ws [get]
+func (sc *SocketController) Connect(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		middleware.AbortWithAppError(c, errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired))
+		return
+	}
+
+	// The upgrader answers failed handshakes itself
+	conn, err := sc.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	sc.hub.Serve(conn, claims.UserID, claims.Role, claims.TenantID)
+}
+
+// checkOrigin allows requests without an Origin header (non-browser
+// clients), from the API's own host, or from one of allowed
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		if strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		for _, candidate := range allowed {
+			if candidate == "*" || strings.EqualFold(strings.TrimSuffix(candidate, "/"), origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,100 @@
+package stream
+
+import (
+	"net/http"
+	"time"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/sse"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeTimeout bounds each write to a stream. The server's WriteTimeout is
+// lifted for streams, so this is what detects a client that stopped reading.
+const writeTimeout = 10 * time.Second
+
+// StreamController serves realtime event streams
+type StreamController struct {
+	broker    *sse.Broker
+	heartbeat time.Duration
+}
+
+// NewStreamController creates a new stream controller that sends a heartbeat
+// comment every heartbeat interval
+func NewStreamController(broker *sse.Broker, heartbeat time.Duration) *StreamController {
+	return &StreamController{
+		broker:    broker,
+		heartbeat: heartbeat,
+	}
+}
+
+// Stream handles a Server-Sent Events connection
+// @Summary Realtime event stream
+// @Description Stream notification and job status events as text/event-stream. EventSource cannot set headers, so the token may be passed as the access_token cookie or query parameter.
+// @Tags events
+// @Security BearerAuth
+// @Produce text/event-stream
+// @Param access_token query string false "Access token"
+// @Success 200 {string} string "event stream"
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/events/stream [get]
+func (sc *StreamController) Stream(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		middleware.AbortWithAppError(c, errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired))
+		return
+	}
+
+	// Errors mean the writer cannot take deadlines (e.g. in tests); the
+	// stream still works, it just keeps the server's WriteTimeout.
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	client := sc.broker.Subscribe(claims.UserID, claims.Role)
+	defer sc.broker.Unsubscribe(client)
+
+	write := func(fn func() error) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := fn(); err != nil {
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	}
+
+	// Send the headers right away so the client knows it is connected
+	if !write(func() error { return sse.WriteComment(c.Writer, "connected") }) {
+		return
+	}
+
+	ticker := time.NewTicker(sc.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case msg, ok := <-client.Messages():
+			// Closed when the client is evicted or the server shuts down
+			if !ok {
+				return
+			}
+			if !write(func() error { return sse.WriteMessage(c.Writer, msg) }) {
+				return
+			}
+		case <-ticker.C:
+			if !write(func() error { return sse.WriteComment(c.Writer, "heartbeat") }) {
+				return
+			}
+		}
+	}
+}
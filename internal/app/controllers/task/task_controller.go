@@ -0,0 +1,124 @@
+package task
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TaskController handles background task HTTP requests
+type TaskController struct {
+	taskService *services.TaskService
+}
+
+// NewTaskController creates a new task controller
+func NewTaskController(taskService *services.TaskService) *TaskController {
+	return &TaskController{
+		taskService: taskService,
+	}
+}
+
+// Routes lists the task routes. Only the user who started a task or an
+// admin sees it.
+func (tc *TaskController) Routes() []route.Definition {
+	authenticated := route.Policy{Auth: route.Authenticated}
+	return []route.Definition{
+		{Method: http.MethodGet, Path: "/tasks/:id", Handler: tc.GetTask, Policy: authenticated},
+		{Method: http.MethodDelete, Path: "/tasks/:id", Handler: tc.CancelTask, Policy: authenticated},
+	}
+}
+
+// GetTask handles polling a task
+// @Summary Get task
+// @Description Status and progress of a background task, with a short-lived result_url once it has succeeded (owner or admin)
+// @Tags tasks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Task ID"
+// @Success 200 {object} models.Task
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/tasks/{id} [get]
+func (tc *TaskController) GetTask(c *gin.Context) {
+	task, ok := tc.ownedTask(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": task,
+	})
+}
+
+// CancelTask handles cancelling a pending or running task
+// @Summary Cancel task
+// @Description Stop a pending or running task (owner or admin)
+// @Tags tasks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Task ID"
+// @Success 202 {object} models.Task
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/tasks/{id} [delete]
+func (tc *TaskController) CancelTask(c *gin.Context) {
+	if _, ok := tc.ownedTask(c); !ok {
+		return
+	}
+
+	task, err := tc.taskService.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		middleware.RespondError(c, taskError(err))
+		return
+	}
+
+	// A running task stops shortly after; poll it for the final status
+	c.JSON(http.StatusAccepted, gin.H{
+		"data": task,
+	})
+}
+
+// ownedTask loads the task in the path, responding with an error unless the
+// caller started it or is an admin
+func (tc *TaskController) ownedTask(c *gin.Context) (*models.Task, bool) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return nil, false
+	}
+
+	task, err := tc.taskService.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		middleware.RespondError(c, taskError(err))
+		return nil, false
+	}
+
+	if claims.Role != string(models.RoleAdmin) && claims.UserID != task.OwnerID.String() {
+		appErr := errors.NewForbiddenError(i18n.AuthInsufficientPermissions, nil).WithCode(errors.CodeInsufficientPermissions)
+		middleware.RespondError(c, appErr)
+		return nil, false
+	}
+	return task, true
+}
+
+// taskError reports why a task could not be loaded or cancelled
+func taskError(err error) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrTaskNotFound):
+		return errors.NewNotFoundError(i18n.TaskNotFound, err).WithCode(errors.CodeTaskNotFound)
+	case stderrors.Is(err, services.ErrTaskFinished):
+		return errors.NewConflictError(i18n.TaskFinished, err).WithCode(errors.CodeTaskFinished)
+	default:
+		return errors.NewInternalServerError(i18n.TaskLoadFailed, err)
+	}
+}
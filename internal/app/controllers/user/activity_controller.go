@@ -0,0 +1,118 @@
+package user
+
+import (
+	"net/http"
+	"time"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dateLayout is the day-only form accepted by the from and to filters
+const dateLayout = "2006-01-02"
+
+// ActivityController handles user activity timeline HTTP requests
+type ActivityController struct {
+	activityService *services.ActivityService
+	pages           pagination.Config
+}
+
+// NewActivityController creates a new activity controller
+func NewActivityController(activityService *services.ActivityService, pages pagination.Config) *ActivityController {
+	return &ActivityController{
+		activityService: activityService,
+		pages:           pages,
+	}
+}
+
+// ListActivity handles listing what happened to a user's account
+// @Summary List user activity
+// @Description Timeline of account changes and login attempts, newest first (admin or the user themselves)
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Param from query string false "Only events at or after this date (2024-01-31) or RFC 3339 time"
+// @Param to query string false "Only events on or before this date, or before this RFC 3339 time"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/activity [get]
+func (ac *ActivityController) ListActivity(c *gin.Context) {
+	id := canonicalID(c.Param("id"))
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	if claims.Role != string(models.RoleAdmin) && claims.UserID != id {
+		appErr := errors.NewForbiddenError(i18n.AuthInsufficientPermissions, nil).WithCode(errors.CodeInsufficientPermissions)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	var filter services.ActivityFilter
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		raw := c.Query(bound.name)
+		if raw == "" {
+			continue
+		}
+		t, err := parseDateFilter(raw, bound.name == "to")
+		if err != nil {
+			appErr := errors.NewBadRequestError(i18n.UserInvalidDateFilter, err).
+				WithCode(errors.CodeInvalidFilter).
+				WithParams(errors.Params{"name": bound.name})
+			middleware.RespondError(c, appErr)
+			return
+		}
+		*bound.value = t
+	}
+
+	params, appErr := pagination.ParseParams(c, ac.pages)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+	result, err := ac.activityService.ListForUser(c.Request.Context(), id, filter, params.Limit, params.Offset())
+	if err != nil {
+		appErr := errors.NewInternalServerError(i18n.UserActivityFailed, err)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	meta := pagination.NewMeta(params, result.Total)
+	c.Header("Link", meta.Links(c.Request.URL))
+	c.JSON(http.StatusOK, gin.H{
+		"data": result.Items,
+		"meta": meta,
+	})
+}
+
+// parseDateFilter parses an RFC 3339 time or a day. A day used as the upper
+// bound includes the whole day.
+func parseDateFilter(raw string, upper bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if upper {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
@@ -0,0 +1,94 @@
+package user
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceController handles listing and revoking trusted devices
+type DeviceController struct {
+	deviceService *services.TrustedDeviceService
+}
+
+// NewDeviceController creates a new device controller
+func NewDeviceController(deviceService *services.TrustedDeviceService) *DeviceController {
+	return &DeviceController{
+		deviceService: deviceService,
+	}
+}
+
+// ListMyDevices handles listing the current user's trusted devices
+// @Summary List my trusted devices
+// @Description Devices the current user logged in from with remember_me, most recently used first; current marks the one making the request
+// @Tags devices
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me/devices [get]
+func (dc *DeviceController) ListMyDevices(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	devices, err := dc.deviceService.List(c.Request.Context(), claims.UserID, claims.DeviceID)
+	if err != nil {
+		middleware.RespondError(c, deviceError(err, i18n.DeviceListFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": devices,
+	})
+}
+
+// RevokeMyDevice handles forgetting one of the current user's trusted devices
+// @Summary Revoke my trusted device
+// @Description Forget a trusted device and end its sessions; its tokens are refused from the next request on
+// @Tags devices
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Device ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/me/devices/{id} [delete]
+func (dc *DeviceController) RevokeMyDevice(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	if err := dc.deviceService.Revoke(c.Request.Context(), claims.UserID, claims.UserID, c.Param("id")); err != nil {
+		middleware.RespondError(c, deviceError(err, i18n.DeviceRevokeFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Device revoked",
+	})
+}
+
+// deviceError reports why trusted devices could not be listed or revoked,
+// using failedKey for unexpected errors
+func deviceError(err error, failedKey string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrDeviceNotFound):
+		return errors.NewNotFoundError(i18n.DeviceNotFound, err).WithCode(errors.CodeDeviceNotFound)
+	case stderrors.Is(err, services.ErrUserNotFound):
+		return errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+	default:
+		return errors.NewInternalServerError(failedKey, err)
+	}
+}
@@ -0,0 +1,145 @@
+package user
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/infrastructure/storage"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/export"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportController handles bulk user export HTTP requests
+type ExportController struct {
+	userService *services.UserService
+	taskService *services.TaskService
+	store       storage.Store
+	batchSize   int
+}
+
+// NewExportController creates a new export controller reading batchSize users per query
+func NewExportController(userService *services.UserService, taskService *services.TaskService, store storage.Store, batchSize int) *ExportController {
+	return &ExportController{
+		userService: userService,
+		taskService: taskService,
+		store:       store,
+		batchSize:   batchSize,
+	}
+}
+
+// ExportUsers handles exporting users as CSV or XLSX
+// @Summary Export users
+// @Description Export users as CSV or as an XLSX workbook with typed cells and a frozen header row. columns selects and orders the fields; active and sort narrow and order the users. With async=true the export runs as a task: poll it and download the result from its result_url.
+// @Tags users
+// @Security BearerAuth
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce json
+// @Param format query string false "File format" Enums(csv, xlsx) default(csv)
+// @Param columns query string false "Comma-separated columns, in order (default all): id, email, username, first_name, last_name, role, active, created_at"
+// @Param active query bool false "Only export users with this active flag"
+// @Param sort query string false "Sort field, prefixed with - for descending: id, email, username, created_at" default(id)
+// @Param async query bool false "Run the export in the background"
+// @Success 200 {string} string "CSV or XLSX file"
+// @Success 202 {object} models.Task
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/users/export [get]
+// @Router /api/v1/users/export [post]
+func (ec *ExportController) ExportUsers(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	opts, appErr := exportOptions(c)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	if async, _ := strconv.ParseBool(c.Query("async")); async {
+		task, err := ec.taskService.Submit(c.Request.Context(), claims.UserID, models.TaskTypeUserExport, services.UserExportTask(ec.userService, ec.store, opts, ec.batchSize))
+		if err != nil {
+			middleware.RespondError(c, errors.NewInternalServerError(i18n.TaskSubmitFailed, err))
+			return
+		}
+		c.Header("Location", "/api/v1/tasks/"+task.ID.String())
+		c.JSON(http.StatusAccepted, gin.H{
+			"data": task,
+		})
+		return
+	}
+
+	c.Header("Content-Type", opts.Format.ContentType())
+	c.Header("Content-Disposition", `attachment; filename="users.`+opts.Format.Extension()+`"`)
+	c.Status(http.StatusOK)
+	if err := ec.userService.Export(c.Request.Context(), c.Writer, opts, ec.batchSize, nil); err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			middleware.RespondError(c, errors.NewInternalServerError(i18n.UserExportFailed, err))
+			return
+		}
+		// Part of the file is already sent; record the failure for the request log
+		_ = c.Error(err)
+	}
+}
+
+// exportOptions reads the format, columns, filters and sort of an export,
+// shared by every format
+func exportOptions(c *gin.Context) (services.UserExportOptions, *errors.AppError) {
+	var opts services.UserExportOptions
+
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		formats := make([]string, len(export.Formats))
+		for i, f := range export.Formats {
+			formats[i] = string(f)
+		}
+		return opts, errors.NewBadRequestError(i18n.UserExportInvalidFormat, err).
+			WithCode(errors.CodeInvalidExportFormat).
+			WithParams(errors.Params{"allowed": strings.Join(formats, ", ")})
+	}
+	opts.Format = format
+
+	if raw := c.Query("columns"); raw != "" {
+		allowed := services.UserExportColumns()
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.TrimSpace(name)
+			if !slices.Contains(allowed, name) {
+				return opts, errors.NewBadRequestError(i18n.UserExportInvalidColumn, services.ErrInvalidExportColumn).
+					WithCode(errors.CodeInvalidExportColumn).
+					WithParams(errors.Params{"column": name, "allowed": strings.Join(allowed, ", ")})
+			}
+			opts.Columns = append(opts.Columns, name)
+		}
+	}
+
+	if raw := c.Query("active"); raw != "" {
+		active, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opts, errors.NewBadRequestError(i18n.UserInvalidActiveFilter, err).WithCode(errors.CodeInvalidFilter)
+		}
+		opts.Active = &active
+	}
+
+	if opts.Sort = c.Query("sort"); opts.Sort != "" {
+		if allowed := services.UserExportSorts(); !slices.Contains(allowed, strings.TrimPrefix(opts.Sort, "-")) {
+			return opts, errors.NewBadRequestError(i18n.UserExportInvalidSort, services.ErrInvalidExportSort).
+				WithCode(errors.CodeInvalidExportSort).
+				WithParams(errors.Params{"allowed": strings.Join(allowed, ", ")})
+		}
+	}
+	return opts, nil
+}
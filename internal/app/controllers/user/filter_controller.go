@@ -0,0 +1,244 @@
+package user
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SavedFilterController handles the list filters users save
+type SavedFilterController struct {
+	filterService *services.SavedFilterService
+}
+
+// NewSavedFilterController creates a new saved filter controller
+func NewSavedFilterController(filterService *services.SavedFilterService) *SavedFilterController {
+	return &SavedFilterController{
+		filterService: filterService,
+	}
+}
+
+// Routes lists the saved filter routes. Users manage their own filters;
+// the service decides who reads and changes shared ones.
+func (fc *SavedFilterController) Routes() []route.Definition {
+	authenticated := route.Policy{Auth: route.Authenticated}
+	return []route.Definition{
+		{Method: http.MethodGet, Path: "/me/filters", Handler: fc.ListFilters, Policy: authenticated},
+		{Method: http.MethodGet, Path: "/me/filters/shared", Handler: fc.ListSharedFilters, Policy: authenticated},
+		{Method: http.MethodPost, Path: "/me/filters", Handler: fc.CreateFilter, Policy: authenticated},
+		{Method: http.MethodPut, Path: "/me/filters/:id", Handler: fc.UpdateFilter, Policy: authenticated},
+		{Method: http.MethodDelete, Path: "/me/filters/:id", Handler: fc.DeleteFilter, Policy: authenticated},
+	}
+}
+
+// ListFilters handles listing the current user's saved filters
+// @Summary List my saved filters
+// @Description List the filters the current user saved, by name
+// @Tags filters
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me/filters [get]
+func (fc *SavedFilterController) ListFilters(c *gin.Context) {
+	principal, ok := filterPrincipal(c)
+	if !ok {
+		return
+	}
+
+	filters, err := fc.filterService.ListFilters(c.Request.Context(), principal)
+	if err != nil {
+		middleware.RespondError(c, savedFilterError(err, i18n.SavedFilterListFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": filters,
+	})
+}
+
+// ListSharedFilters handles listing the filters others shared
+// @Summary List shared filters
+// @Description List the filters other users shared for the resources the current user may list, such as users with users.view
+// @Tags filters
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me/filters/shared [get]
+func (fc *SavedFilterController) ListSharedFilters(c *gin.Context) {
+	principal, ok := filterPrincipal(c)
+	if !ok {
+		return
+	}
+
+	filters, err := fc.filterService.ListSharedFilters(c.Request.Context(), principal)
+	if err != nil {
+		middleware.RespondError(c, savedFilterError(err, i18n.SavedFilterListFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": filters,
+	})
+}
+
+// CreateFilter handles saving a filter
+// @Summary Save filter
+// @Description Save a list filter, e.g. {"name": "Inactive agents", "resource_type": "users", "filter": {"role": "user", "active": false}}. The filter is checked against the resource's filter schema; unknown fields are refused.
+// @Tags filters
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param filter body services.CreateSavedFilterRequest true "Filter"
+// @Success 201 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/me/filters [post]
+func (fc *SavedFilterController) CreateFilter(c *gin.Context) {
+	req, ok := request.Bind[services.CreateSavedFilterRequest](c)
+	if !ok {
+		return
+	}
+	principal, ok := filterPrincipal(c)
+	if !ok {
+		return
+	}
+
+	filter, err := fc.filterService.CreateFilter(c.Request.Context(), principal, &req)
+	if err != nil {
+		middleware.RespondError(c, savedFilterError(err, i18n.SavedFilterSaveFailed))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": filter,
+	})
+}
+
+// UpdateFilter handles changing a saved filter
+// @Summary Update saved filter
+// @Description Rename, replace or (un)share a saved filter. Shared filters can be changed by their owner or an admin.
+// @Tags filters
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Filter ID"
+// @Param filter body services.UpdateSavedFilterRequest true "Changes"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/me/filters/{id} [put]
+func (fc *SavedFilterController) UpdateFilter(c *gin.Context) {
+	req, ok := request.Bind[services.UpdateSavedFilterRequest](c)
+	if !ok {
+		return
+	}
+	principal, ok := filterPrincipal(c)
+	if !ok {
+		return
+	}
+
+	filter, err := fc.filterService.UpdateFilter(c.Request.Context(), principal, c.Param("id"), &req)
+	if err != nil {
+		middleware.RespondError(c, savedFilterError(err, i18n.SavedFilterSaveFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": filter,
+	})
+}
+
+// DeleteFilter handles deleting a saved filter
+// @Summary Delete saved filter
+// @Description Delete a saved filter. Shared filters can be deleted by their owner or an admin.
+// @Tags filters
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Filter ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/me/filters/{id} [delete]
+func (fc *SavedFilterController) DeleteFilter(c *gin.Context) {
+	principal, ok := filterPrincipal(c)
+	if !ok {
+		return
+	}
+
+	if err := fc.filterService.DeleteFilter(c.Request.Context(), principal, c.Param("id")); err != nil {
+		middleware.RespondError(c, savedFilterError(err, i18n.SavedFilterDeleteFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Filter deleted",
+	})
+}
+
+// filterPrincipal returns the authenticated caller, or responds 401
+func filterPrincipal(c *gin.Context) (services.Principal, bool) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return services.Principal{}, false
+	}
+	return services.Principal{ID: claims.UserID, Role: models.UserRole(claims.Role), TenantID: claims.TenantID}, true
+}
+
+// savedFilterError reports why a filter could not be saved, read or
+// applied, using failedKey for unexpected errors. Filters that do not
+// match their schema are unprocessable.
+func savedFilterError(err error, failedKey string) *errors.AppError {
+	var fieldErr *services.FilterFieldError
+	switch {
+	case stderrors.Is(err, services.ErrSavedFilterNotFound):
+		return errors.NewNotFoundError(i18n.SavedFilterNotFound, err).WithCode(errors.CodeSavedFilterNotFound)
+	case stderrors.Is(err, services.ErrSavedFilterForbidden):
+		return errors.NewForbiddenError(i18n.SavedFilterForbidden, err).WithCode(errors.CodeSavedFilterForbidden)
+	case stderrors.Is(err, services.ErrUnknownFilterResource):
+		return errors.NewValidationError(i18n.SavedFilterUnknownResource, err).
+			WithCode(errors.CodeUnknownFilterResource).
+			WithParams(errors.Params{"allowed": strings.Join(services.FilterResources(), ", ")})
+	case stderrors.Is(err, services.ErrFilterResourceMismatch):
+		return errors.NewValidationError(i18n.SavedFilterResourceMismatch, err).
+			WithCode(errors.CodeFilterResourceMismatch).
+			WithParams(errors.Params{"expected": models.FilterResourceUsers})
+	case stderrors.Is(err, services.ErrFilterSchemaTooNew):
+		return errors.NewValidationError(i18n.SavedFilterSchemaUnsupported, err).
+			WithCode(errors.CodeFilterSchemaUnsupported).
+			WithParams(errors.Params{"version": strconv.Itoa(services.UserFilterSchema.Version)})
+	case stderrors.As(err, &fieldErr):
+		return filterFieldError(fieldErr, http.StatusUnprocessableEntity)
+	case stderrors.Is(err, services.ErrUserNotFound):
+		return errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+	default:
+		return errors.NewInternalServerError(failedKey, err)
+	}
+}
+
+// filterFieldError reports a filter field that is unknown or has an
+// invalid value with status
+func filterFieldError(err *services.FilterFieldError, status int) *errors.AppError {
+	if stderrors.Is(err, services.ErrUnknownFilterField) {
+		return errors.NewAppError(status, i18n.SavedFilterUnknownField, err).
+			WithCode(errors.CodeUnknownFilterField).
+			WithParams(errors.Params{"name": err.Field, "allowed": strings.Join(err.Allowed, ", ")})
+	}
+	return errors.NewAppError(status, i18n.SavedFilterInvalidValue, err).
+		WithCode(errors.CodeInvalidFilter).
+		WithParams(errors.Params{"name": err.Field, "reason": err.Reason})
+}
@@ -0,0 +1,169 @@
+package user
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/service"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyController handles the current user's profile and policy acceptances
+type PolicyController struct {
+	userService   service.UserService
+	policyService *services.PolicyService
+	profiles      *services.ProfileService
+}
+
+// PolicyControllerOption configures a PolicyController
+type PolicyControllerOption func(*PolicyController)
+
+// WithProfiles makes GetMe report how complete the user's profile is
+func WithProfiles(profiles *services.ProfileService) PolicyControllerOption {
+	return func(pc *PolicyController) {
+		pc.profiles = profiles
+	}
+}
+
+// NewPolicyController creates a new policy controller
+func NewPolicyController(userService service.UserService, policyService *services.PolicyService, opts ...PolicyControllerOption) *PolicyController {
+	pc := &PolicyController{
+		userService:   userService,
+		policyService: policyService,
+	}
+	for _, opt := range opts {
+		opt(pc)
+	}
+	return pc
+}
+
+// AcceptPolicyRequest represents the policy acceptance payload
+type AcceptPolicyRequest struct {
+	Policy  string `json:"policy" binding:"required"`
+	Version string `json:"version" binding:"required"`
+}
+
+// GetMe handles fetching the current user
+// @Summary Get current user
+// @Description The current user with the policies whose current version they have yet to accept and, when profiles are evaluated, the required profile fields they left blank
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/me [get]
+func (pc *PolicyController) GetMe(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	user, err := pc.userService.GetUser(c.Request.Context(), claims.UserID)
+	if err != nil {
+		appErr := errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+		middleware.RespondError(c, appErr)
+		return
+	}
+	pending, err := pc.policyService.Pending(c.Request.Context(), claims.UserID)
+	if err != nil {
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.PolicyPendingFailed, err))
+		return
+	}
+
+	response := gin.H{
+		"data":             user,
+		"pending_policies": pending,
+	}
+	if pc.profiles != nil {
+		status := pc.profiles.Evaluate(user)
+		response["profile_complete"] = status.Complete
+		response["missing_profile_fields"] = status.Missing
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AcceptPolicy handles the current user accepting a policy version
+// @Summary Accept policy
+// @Description Record the current user accepting the current version of a policy. Accepting it again is a no-op.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body AcceptPolicyRequest true "Policy key and version"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/me/accept-policy [post]
+func (pc *PolicyController) AcceptPolicy(c *gin.Context) {
+	req, ok := request.Bind[AcceptPolicyRequest](c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	client := services.ClientInfo{IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	acceptance, err := pc.policyService.Accept(c.Request.Context(), claims.UserID, req.Policy, req.Version, client)
+	if err != nil {
+		middleware.RespondError(c, policyError(err, req))
+		return
+	}
+	pending, err := pc.policyService.Pending(c.Request.Context(), claims.UserID)
+	if err != nil {
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.PolicyPendingFailed, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":             acceptance,
+		"pending_policies": pending,
+	})
+}
+
+// PolicyReport handles reporting policy acceptance across users
+// @Summary Policy acceptance report
+// @Description How many active users accepted each version of every policy, and how many have yet to accept the current one (requires users.manage)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/policies [get]
+func (pc *PolicyController) PolicyReport(c *gin.Context) {
+	report, err := pc.policyService.Report(c.Request.Context())
+	if err != nil {
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.PolicyReportFailed, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": report,
+	})
+}
+
+// policyError reports why a policy could not be accepted
+func policyError(err error, req AcceptPolicyRequest) *errors.AppError {
+	params := errors.Params{"policy": req.Policy, "version": req.Version}
+	switch {
+	case stderrors.Is(err, services.ErrUnknownPolicy):
+		return errors.NewBadRequestError(i18n.PolicyUnknown, err).WithCode(errors.CodeUnknownPolicy).WithParams(params)
+	case stderrors.Is(err, services.ErrPolicyVersionOutdated):
+		return errors.NewConflictError(i18n.PolicyVersionOutdated, err).WithCode(errors.CodePolicyVersionOutdated).WithParams(params)
+	default:
+		return errors.NewInternalServerError(i18n.PolicyAcceptFailed, err)
+	}
+}
@@ -0,0 +1,117 @@
+package user
+
+import (
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/service"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProfileController handles users completing their profile and the
+// completeness report
+type ProfileController struct {
+	userService service.UserService
+	authService service.AuthService
+	profiles    *services.ProfileService
+}
+
+// NewProfileController creates a new profile controller
+func NewProfileController(userService service.UserService, authService service.AuthService, profiles *services.ProfileService) *ProfileController {
+	return &ProfileController{
+		userService: userService,
+		authService: authService,
+		profiles:    profiles,
+	}
+}
+
+// UpdateMe handles the current user updating their profile
+// @Summary Update my profile
+// @Description Update the current user's username, first and last name. Omitted fields are left unchanged. When the token was restricted to completing the profile and it now is complete, the response carries an unrestricted token for the same session.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param user body services.UpdateUserRequest true "Profile fields to change"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/me [put]
+func (pc *ProfileController) UpdateMe(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		appErr := errors.NewBadRequestError(i18n.RequestInvalidBody, err).WithCode(errors.CodeInvalidRequestBody)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	req, err := services.ParseUpdateProfileRequest(body)
+	if err != nil {
+		middleware.RespondError(c, updateRequestError(err))
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	if _, err := pc.userService.UpdateUser(c.Request.Context(), claims.UserID, req, claims.UserID); err != nil {
+		appErr := errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	// The restriction is lifted by the very request completing the profile
+	result, err := pc.authService.CompleteProfile(c.Request.Context(), claims)
+	if err != nil {
+		appErr := errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	response := gin.H{
+		"message": "Profile updated successfully",
+		"data":    result.User,
+	}
+	if result.ProfileStatus != nil {
+		response["profile_complete"] = result.Complete
+		response["missing_profile_fields"] = result.Missing
+	}
+	if result.ProfileCompletionRequired {
+		response["profile_completion_required"] = true
+	}
+	if result.Token != "" {
+		response["token"] = result.Token
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ProfileReport handles reporting profile completeness across users
+// @Summary Profile completeness report
+// @Description How many active users of each role completed their profile, and how many leave each required field blank (requires users.manage)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/profiles [get]
+func (pc *ProfileController) ProfileReport(c *gin.Context) {
+	report, err := pc.profiles.Report(c.Request.Context())
+	if err != nil {
+		middleware.RespondError(c, errors.NewInternalServerError(i18n.ProfileReportFailed, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": report,
+	})
+}
@@ -0,0 +1,228 @@
+package user
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionController handles listing and revoking login sessions
+type SessionController struct {
+	sessionService *services.SessionService
+	authService    *services.AuthService
+}
+
+// NewSessionController creates a new session controller
+func NewSessionController(sessionService *services.SessionService, authService *services.AuthService) *SessionController {
+	return &SessionController{
+		sessionService: sessionService,
+		authService:    authService,
+	}
+}
+
+// ListMySessions handles listing the current user's sessions
+// @Summary List my sessions
+// @Description Active sessions of the current user, most recently used first; current marks the one making the request
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me/sessions [get]
+func (sc *SessionController) ListMySessions(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+	sc.listSessions(c, claims.UserID, claims.SessionID)
+}
+
+// RevokeMySession handles signing the current user out of one session
+// @Summary Revoke my session
+// @Description End one of the current user's sessions; its tokens are refused from the next request on
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Param sid path string true "Session ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/me/sessions/{sid} [delete]
+func (sc *SessionController) RevokeMySession(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+	sc.revokeSession(c, claims.UserID, claims.UserID)
+}
+
+// RevokeMyOtherSessions handles signing the current user out everywhere else
+// @Summary Revoke my other sessions
+// @Description End every session of the current user except the one making the request
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me/sessions [delete]
+func (sc *SessionController) RevokeMyOtherSessions(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+	sc.revokeSessions(c, claims.UserID, claims.UserID, claims.SessionID)
+}
+
+// ListUserSessions handles listing a user's sessions
+// @Summary List user sessions
+// @Description Active sessions of a user, most recently used first
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/sessions [get]
+func (sc *SessionController) ListUserSessions(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+	sc.listSessions(c, c.Param("id"), claims.SessionID)
+}
+
+// RevokeUserSession handles signing a user out of one session
+// @Summary Revoke user session
+// @Description End one of a user's sessions; its tokens are refused from the next request on
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Param sid path string true "Session ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/sessions/{sid} [delete]
+func (sc *SessionController) RevokeUserSession(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+	sc.revokeSession(c, claims.UserID, c.Param("id"))
+}
+
+// RevokeUserSessions handles signing a user out of every session
+// @Summary Revoke user sessions
+// @Description End every session of a user
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/sessions [delete]
+func (sc *SessionController) RevokeUserSessions(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+	sc.revokeSessions(c, claims.UserID, c.Param("id"), "")
+}
+
+// ForceLogout handles signing a user out everywhere
+// @Summary Force logout
+// @Description Invalidate every token issued to a user so far and end all their sessions; their next login works as usual
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/force-logout [post]
+func (sc *SessionController) ForceLogout(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	if err := sc.authService.RevokeAllTokens(c.Request.Context(), c.Param("id"), claims.UserID); err != nil {
+		middleware.RespondError(c, sessionError(err, i18n.SessionForceLogoutFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User signed out everywhere",
+	})
+}
+
+// listSessions responds with the active sessions of userID
+func (sc *SessionController) listSessions(c *gin.Context, userID, currentSID string) {
+	sessions, err := sc.sessionService.List(c.Request.Context(), userID, currentSID)
+	if err != nil {
+		middleware.RespondError(c, sessionError(err, i18n.SessionListFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": sessions,
+	})
+}
+
+// revokeSession ends the session in the path, which must belong to userID
+func (sc *SessionController) revokeSession(c *gin.Context, actorID, userID string) {
+	if err := sc.sessionService.Revoke(c.Request.Context(), actorID, userID, c.Param("sid")); err != nil {
+		middleware.RespondError(c, sessionError(err, i18n.SessionRevokeFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked",
+	})
+}
+
+// revokeSessions ends every session of userID except keepSID
+func (sc *SessionController) revokeSessions(c *gin.Context, actorID, userID, keepSID string) {
+	revoked, err := sc.sessionService.RevokeOthers(c.Request.Context(), actorID, userID, keepSID)
+	if err != nil {
+		middleware.RespondError(c, sessionError(err, i18n.SessionRevokeFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sessions revoked",
+		"data":    gin.H{"revoked": revoked},
+	})
+}
+
+// sessionError reports why sessions could not be listed or revoked, using
+// failedKey for unexpected errors
+func sessionError(err error, failedKey string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrSessionNotFound):
+		return errors.NewNotFoundError(i18n.SessionNotFound, err).WithCode(errors.CodeSessionNotFound)
+	case stderrors.Is(err, services.ErrUserNotFound):
+		return errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+	default:
+		return errors.NewInternalServerError(failedKey, err)
+	}
+}
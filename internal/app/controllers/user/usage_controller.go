@@ -0,0 +1,152 @@
+package user
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsageController handles API usage reports and user quotas
+type UsageController struct {
+	quotaService *services.QuotaService
+}
+
+// NewUsageController creates a new usage controller
+func NewUsageController(quotaService *services.QuotaService) *UsageController {
+	return &UsageController{
+		quotaService: quotaService,
+	}
+}
+
+// UsageReportRequest selects the month of a usage report
+type UsageReportRequest struct {
+	Period string `form:"period"`
+}
+
+// SetQuotaRequest represents the quota override payload
+type SetQuotaRequest struct {
+	// Quota is the user's monthly request limit, 0 for none; null restores the default
+	Quota *int64 `json:"quota" binding:"omitempty,gte=0"`
+}
+
+// MyUsage handles fetching the current user's API usage
+// @Summary Get my API usage
+// @Description Requests the current user made this month, their quota and when it resets. limit and remaining are null without a limit.
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me/usage [get]
+func (uc *UsageController) MyUsage(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	usage, err := uc.quotaService.Usage(c.Request.Context(), claims.UserID)
+	if err != nil {
+		middleware.RespondError(c, usageError(err, i18n.UsageFetchFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": usage,
+	})
+}
+
+// UsageReport handles reporting API usage across users
+// @Summary API usage report
+// @Description Requests each user made in a month, busiest first (requires users.manage). Other instances' requests appear after their next flush.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param period query string false "Month such as 2024-06; the current month by default"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/usage [get]
+func (uc *UsageController) UsageReport(c *gin.Context) {
+	req, ok := request.Bind[UsageReportRequest](c)
+	if !ok {
+		return
+	}
+
+	report, err := uc.quotaService.Report(c.Request.Context(), req.Period)
+	if err != nil {
+		appErr := usageError(err, i18n.UsageReportFailed)
+		if stderrors.Is(err, services.ErrInvalidPeriod) {
+			appErr = appErr.WithParams(errors.Params{"period": req.Period})
+		}
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": report,
+	})
+}
+
+// SetQuota handles overriding a user's monthly API quota
+// @Summary Set user API quota
+// @Description Give the user their own monthly request limit, 0 for none, or restore API_QUOTA_MONTHLY with null (requires users.manage). Other instances apply it within a minute.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body SetQuotaRequest true "Quota"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/quota [put]
+func (uc *UsageController) SetQuota(c *gin.Context) {
+	req, ok := request.Bind[SetQuotaRequest](c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uc.quotaService.SetQuota(c.Request.Context(), claims.UserID, id, req.Quota); err != nil {
+		middleware.RespondError(c, usageError(err, i18n.QuotaUpdateFailed))
+		return
+	}
+	usage, err := uc.quotaService.Usage(c.Request.Context(), id)
+	if err != nil {
+		middleware.RespondError(c, usageError(err, i18n.UsageFetchFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Quota updated successfully",
+		"data":    usage,
+	})
+}
+
+// usageError reports why usage could not be read or a quota set
+func usageError(err error, failedKey string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrUserNotFound):
+		return errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+	case stderrors.Is(err, services.ErrInvalidPeriod):
+		return errors.NewBadRequestError(i18n.UsageInvalidPeriod, err).WithCode(errors.CodeInvalidPeriod)
+	default:
+		return errors.NewInternalServerError(failedKey, err)
+	}
+}
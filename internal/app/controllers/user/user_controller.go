@@ -1,10 +1,22 @@
 package user
 
 import (
+	stderrors "errors"
 	"net/http"
 	"strconv"
 
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/apiversion"
 	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/identifier"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/pkg/service"
+	"BackofficeGoService/internal/pkg/validator"
 	"BackofficeGoService/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -12,16 +24,114 @@ import (
 
 // UserController handles user-related HTTP requests
 type UserController struct {
-	userService *services.UserService
+	userService service.UserService
+	filters     *services.SavedFilterService
+	pages       pagination.Config
+	confirmUser middleware.ConfirmRule
+}
+
+// UserControllerOption configures a UserController
+type UserControllerOption func(*UserController)
+
+// WithSavedFilters lets user listings apply the filters saved in filters
+// through filter_id
+func WithSavedFilters(filters *services.SavedFilterService) UserControllerOption {
+	return func(uc *UserController) {
+		uc.filters = filters
+	}
 }
 
 // NewUserController creates a new user controller
-func NewUserController(userService *services.UserService) *UserController {
-	return &UserController{
+func NewUserController(userService service.UserService, pages pagination.Config, opts ...UserControllerOption) *UserController {
+	uc := &UserController{
 		userService: userService,
+		pages:       pages,
 	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	uc.confirmUser = middleware.ConfirmRule{Summarize: uc.summarizeUser}
+	return uc
 }
 
+// Routes lists the user management routes
+func (uc *UserController) Routes() []route.Definition {
+	authenticated := route.Policy{Auth: route.Authenticated}
+	withPermission := func(name string) route.Policy {
+		return route.Policy{Auth: route.Authenticated, Permission: name}
+	}
+	bothVersions := []apiversion.Version{apiversion.V1, apiversion.V2}
+
+	return []route.Definition{
+		{
+			Method:   http.MethodGet,
+			Path:     "/users",
+			Handler:  uc.ListUsers,
+			Policy:   route.Policy{Auth: route.Authenticated, Cache: &listCache},
+			Versions: bothVersions,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/users/search",
+			Handler:  uc.SearchUsers,
+			Policy:   route.Policy{Auth: route.Authenticated, Cache: &searchCache},
+			Versions: bothVersions,
+		},
+		{Method: http.MethodGet, Path: "/users/:id", Handler: uc.GetUser, Policy: authenticated, Versions: bothVersions},
+		{Method: http.MethodPut, Path: "/users/:id", Handler: uc.UpdateUser, Policy: withPermission(models.PermissionUsersUpdate)},
+		{Method: http.MethodPost, Path: "/users/:id/activate", Handler: uc.ActivateUser, Policy: withPermission(models.PermissionUsersManage)},
+		{Method: http.MethodPost, Path: "/users/:id/deactivate", Handler: uc.DeactivateUser, Policy: withPermission(models.PermissionUsersManage)},
+		{
+			Method:      http.MethodPut,
+			Path:        "/users/:id/role",
+			Handler:     uc.ChangeRole,
+			Middlewares: []string{route.NotImpersonating},
+			Policy:      withPermission(models.PermissionUsersManage),
+		},
+		{Method: http.MethodGet, Path: "/users/:id/export", Handler: uc.ExportUser, Policy: authenticated},
+		{
+			Method:  http.MethodPost,
+			Path:    "/users/:id/anonymize",
+			Handler: uc.AnonymizeUser,
+			Policy:  route.Policy{Auth: route.Authenticated, Permission: models.PermissionUsersManage, Confirm: &uc.confirmUser},
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/users",
+			Handler: uc.CreateUser,
+			Policy:  withPermission(models.PermissionUsersCreate),
+			Feature: config.FeatureUserCreate,
+		},
+		{
+			Method:  http.MethodDelete,
+			Path:    "/users/:id",
+			Handler: uc.DeleteUser,
+			Policy:  route.Policy{Auth: route.Authenticated, Permission: models.PermissionUsersDelete, Confirm: &uc.confirmUser},
+			Feature: config.FeatureUserDelete,
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/users/:id/require-password-change",
+			Handler: uc.RequirePasswordChange,
+			Policy:  withPermission(models.PermissionUsersManage),
+			Feature: config.FeaturePasswordReset,
+		},
+	}
+}
+
+// Response cache rules of the user listings
+var (
+	// Listings applying a saved filter are not cached, as the filter may change
+	listCache = middleware.CacheRule{
+		Groups: []string{services.ResponseGroupUsers},
+		Query:  append([]string{pagination.PageParam, pagination.LimitParam}, services.UserFilterSchema.Fields...),
+	}
+	searchCache = middleware.CacheRule{
+		Groups: []string{services.ResponseGroupUsers},
+		Query:  []string{"q", "active", pagination.PageParam, pagination.LimitParam, "include_anonymized"},
+	}
+)
+
 // GetUser handles getting a user by ID
 // @Summary Get user by ID
 // @Description Get user details by ID
@@ -33,67 +143,159 @@ func NewUserController(userService *services.UserService) *UserController {
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Router /api/v1/users/{id} [get]
+// @Router /api/v2/users/{id} [get]
 func (uc *UserController) GetUser(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		appErr := errors.NewBadRequestError("User ID is required", nil)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+		appErr := errors.NewBadRequestError(i18n.UserIDRequired, nil).WithCode(errors.CodeUserIDRequired)
+		middleware.RespondError(c, appErr)
 		return
 	}
 
 	user, err := uc.userService.GetUser(c.Request.Context(), id)
 	if err != nil {
-		appErr := errors.NewNotFoundError("User not found", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+		appErr := errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+		middleware.RespondError(c, appErr)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": user,
-	})
+	userResponses.Render(c, http.StatusOK, user)
 }
 
 // ListUsers handles listing users with pagination
 // @Summary List users
-// @Description Get a list of users with pagination
+// @Description Get a list of users with pagination. v1 nests the pagination under meta; v2 flattens it next to data. filter_id applies a saved filter; filter parameters sent alongside replace its fields, and sent empty clear them.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
+// @Param filter_id query string false "Saved filter to apply"
+// @Param role query string false "Only users with this role"
+// @Param active query bool false "Only users with this active flag"
+// @Param created_after query string false "Only users created at or after this date or RFC 3339 time"
+// @Param created_before query string false "Only users created before this time, or up to the end of this date"
+// @Param include_anonymized query bool false "Include anonymized users (admin only)"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
 // @Router /api/v1/users [get]
+// @Router /api/v2/users [get]
 func (uc *UserController) ListUsers(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
+	params, appErr := pagination.ParseParams(c, uc.pages)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
 	}
-	if limit < 1 {
-		limit = 10
+	filter, appErr := uc.listFilter(c)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
 	}
-	if limit > 100 {
-		limit = 100
+
+	result, err := uc.userService.ListUsers(c.Request.Context(), filter, params.Limit, params.Offset())
+	if err != nil {
+		appErr := errors.NewInternalServerError(i18n.UserListFailed, err)
+		middleware.RespondError(c, appErr)
+		return
 	}
 
-	offset := (page - 1) * limit
+	meta := pagination.NewMeta(params, result.Total)
+	c.Header("Link", meta.Links(c.Request.URL))
+	userPages.Render(c, http.StatusOK, page[*models.User]{items: result.Items, meta: meta})
+}
+
+// SearchUsers handles free-text user search
+// @Summary Search users
+// @Description Search users by email, username and names, most relevant first
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param q query string true "Search text"
+// @Param active query bool false "Only match users with this active flag"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param include_anonymized query bool false "Include anonymized users (admin only)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/users/search [get]
+// @Router /api/v2/users/search [get]
+func (uc *UserController) SearchUsers(c *gin.Context) {
+	params, appErr := pagination.ParseParams(c, uc.pages)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+	filter := services.SearchUsersFilter{
+		Query:             c.Query("q"),
+		IncludeAnonymized: includeAnonymized(c),
+	}
+	if raw := c.Query("active"); raw != "" {
+		active, err := strconv.ParseBool(raw)
+		if err != nil {
+			appErr := errors.NewBadRequestError(i18n.UserInvalidActiveFilter, err).WithCode(errors.CodeInvalidFilter)
+			middleware.RespondError(c, appErr)
+			return
+		}
+		filter.Active = &active
+	}
 
-	users, err := uc.userService.ListUsers(c.Request.Context(), limit, offset)
+	result, err := uc.userService.SearchUsers(c.Request.Context(), filter, params.Limit, params.Offset())
 	if err != nil {
-		appErr := errors.NewInternalServerError("Failed to fetch users", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+		if stderrors.Is(err, services.ErrEmptySearchQuery) {
+			appErr := errors.NewBadRequestError(i18n.UserSearchQueryRequired, err).WithCode(errors.CodeSearchQueryRequired)
+			middleware.RespondError(c, appErr)
+			return
+		}
+		appErr := errors.NewInternalServerError(i18n.UserSearchFailed, err)
+		middleware.RespondError(c, appErr)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": users,
-		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": len(users),
-		},
-	})
+	meta := pagination.NewMeta(params, result.Total)
+	c.Header("Link", meta.Links(c.Request.URL))
+	searchPages.Render(c, http.StatusOK, page[*services.UserSearchResult]{items: result.Items, meta: meta})
+}
+
+// listFilter reads the user listing filter: the saved filter_id, if any,
+// with the filter parameters of the request on top. Anonymized users are
+// only listed to admins.
+func (uc *UserController) listFilter(c *gin.Context) (services.ListUsersFilter, *errors.AppError) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return services.ListUsersFilter{}, errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+	}
+
+	var saved map[string]string
+	if id := c.Query("filter_id"); id != "" {
+		if uc.filters == nil {
+			return services.ListUsersFilter{}, savedFilterError(services.ErrSavedFilterNotFound, i18n.UserListFailed)
+		}
+		principal := services.Principal{ID: claims.UserID, Role: models.UserRole(claims.Role), TenantID: claims.TenantID}
+		var err error
+		if saved, err = uc.filters.FilterValues(c.Request.Context(), principal, id, models.FilterResourceUsers); err != nil {
+			return services.ListUsersFilter{}, savedFilterError(err, i18n.UserListFailed)
+		}
+	}
+
+	filter, err := services.ParseUserFilter(services.UserFilterSchema.Merge(saved, c.Request.URL.Query()))
+	if err != nil {
+		var fieldErr *services.FilterFieldError
+		if stderrors.As(err, &fieldErr) {
+			return filter, filterFieldError(fieldErr, http.StatusBadRequest)
+		}
+		return filter, errors.NewBadRequestError(i18n.UserListFailed, err).WithCode(errors.CodeInvalidFilter)
+	}
+	filter.IncludeAnonymized = filter.IncludeAnonymized && claims.Role == string(models.RoleAdmin)
+	return filter, nil
+}
+
+// includeAnonymized reports whether anonymized users were requested; they
+// are hidden unless an admin explicitly asks for them
+func includeAnonymized(c *gin.Context) bool {
+	claims, ok := middleware.GetClaims(c)
+	return ok && claims.Role == string(models.RoleAdmin) && c.Query("include_anonymized") == "true"
 }
 
 // CreateUser handles creating a new user
@@ -102,22 +304,38 @@ func (uc *UserController) ListUsers(c *gin.Context) {
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param user body map[string]interface{} true "User data"
+// @Param user body services.CreateUserRequest true "User data"
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
 // @Router /api/v1/users [post]
 func (uc *UserController) CreateUser(c *gin.Context) {
-	var req map[string]interface{}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		appErr := errors.NewValidationError("Invalid request data", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+	req, ok := request.Bind[services.CreateUserRequest](c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
 		return
 	}
 
-	user, err := uc.userService.CreateUser(c.Request.Context(), req)
+	user, err := uc.userService.CreateUser(c.Request.Context(), &req, claims.UserID)
 	if err != nil {
-		appErr := errors.NewInternalServerError("Failed to create user", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+		var appErr *errors.AppError
+		switch {
+		case stderrors.Is(err, services.ErrEmailTaken):
+			appErr = errors.NewConflictError(i18n.UserEmailTaken, err).WithCode(errors.CodeEmailAlreadyExists)
+		case stderrors.Is(err, services.ErrPasswordBreached), stderrors.Is(err, services.ErrPasswordCheckUnavailable):
+			appErr = passwordPolicyError(err)
+		default:
+			appErr = errors.NewInternalServerError(i18n.UserCreateFailed, err)
+		}
+		middleware.RespondError(c, appErr)
 		return
 	}
 
@@ -134,30 +352,57 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
-// @Param user body map[string]interface{} true "User data"
+// @Param user body services.UpdateUserRequest true "Fields to change; omitted fields are left unchanged"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
 // @Router /api/v1/users/{id} [put]
 func (uc *UserController) UpdateUser(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		appErr := errors.NewBadRequestError("User ID is required", nil)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+		appErr := errors.NewBadRequestError(i18n.UserIDRequired, nil).WithCode(errors.CodeUserIDRequired)
+		middleware.RespondError(c, appErr)
 		return
 	}
 
-	var req map[string]interface{}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		appErr := errors.NewValidationError("Invalid request data", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+	body, err := c.GetRawData()
+	if err != nil {
+		appErr := errors.NewBadRequestError(i18n.RequestInvalidBody, err).WithCode(errors.CodeInvalidRequestBody)
+		middleware.RespondError(c, appErr)
 		return
 	}
 
-	user, err := uc.userService.UpdateUser(c.Request.Context(), id, req)
+	req, err := services.ParseUpdateUserRequest(body)
 	if err != nil {
-		appErr := errors.NewNotFoundError("User not found", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+		middleware.RespondError(c, updateRequestError(err))
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	// Passwords cannot be changed through an impersonation token
+	if req.Password != nil && claims.Impersonating() {
+		appErr := errors.NewForbiddenError(i18n.AuthImpersonationForbidden, nil).WithCode(errors.CodeImpersonationForbidden)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	user, err := uc.userService.UpdateUser(c.Request.Context(), id, req, claims.UserID)
+	if err != nil {
+		if stderrors.Is(err, services.ErrPasswordBreached) || stderrors.Is(err, services.ErrPasswordCheckUnavailable) {
+			middleware.RespondError(c, passwordPolicyError(err))
+			return
+		}
+		appErr := errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+		middleware.RespondError(c, appErr)
 		return
 	}
 
@@ -169,27 +414,29 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 
 // DeleteUser handles deleting a user
 // @Summary Delete user
-// @Description Delete user by ID
+// @Description Delete user by ID. The first request is answered with 202 and a confirmation token; repeat it with X-Confirm-Token to delete.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
+// @Param X-Confirm-Token header string false "Token confirming the deletion"
 // @Success 200 {object} map[string]interface{}
+// @Success 202 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Router /api/v1/users/{id} [delete]
 func (uc *UserController) DeleteUser(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		appErr := errors.NewBadRequestError("User ID is required", nil)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+		appErr := errors.NewBadRequestError(i18n.UserIDRequired, nil).WithCode(errors.CodeUserIDRequired)
+		middleware.RespondError(c, appErr)
 		return
 	}
 
 	err := uc.userService.DeleteUser(c.Request.Context(), id)
 	if err != nil {
-		appErr := errors.NewNotFoundError("User not found", err)
-		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+		appErr := errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+		middleware.RespondError(c, appErr)
 		return
 	}
 
@@ -197,3 +444,314 @@ func (uc *UserController) DeleteUser(c *gin.Context) {
 		"message": "User deleted successfully",
 	})
 }
+
+// ActivateUser handles re-activating a user
+// @Summary Activate user
+// @Description Re-activate a deactivated user (admin only)
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/activate [post]
+func (uc *UserController) ActivateUser(c *gin.Context) {
+	uc.setActive(c, true)
+}
+
+// DeactivateUser handles deactivating a user
+// @Summary Deactivate user
+// @Description Deactivate a user and revoke their tokens (admin only)
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/deactivate [post]
+func (uc *UserController) DeactivateUser(c *gin.Context) {
+	uc.setActive(c, false)
+}
+
+// setActive applies an activation change on behalf of the authenticated admin
+func (uc *UserController) setActive(c *gin.Context, active bool) {
+	id := c.Param("id")
+	if id == "" {
+		appErr := errors.NewBadRequestError(i18n.UserIDRequired, nil).WithCode(errors.CodeUserIDRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	user, err := uc.userService.SetActive(c.Request.Context(), id, active, claims.UserID)
+	if err != nil {
+		var appErr *errors.AppError
+		switch {
+		case stderrors.Is(err, services.ErrSelfDeactivation):
+			appErr = errors.NewForbiddenError(i18n.UserSelfDeactivation, err).WithCode(errors.CodeSelfDeactivation)
+		case stderrors.Is(err, services.ErrLastActiveAdmin):
+			appErr = errors.NewConflictError(i18n.UserLastActiveAdmin, err).WithCode(errors.CodeLastActiveAdmin)
+		default:
+			appErr = errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+		}
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	message := "User activated successfully"
+	if !active {
+		message = "User deactivated successfully"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    newUserListItem(user),
+	})
+}
+
+// ChangeRoleRequest names the role to give a user
+type ChangeRoleRequest struct {
+	Role models.UserRole `json:"role" binding:"required"`
+}
+
+// ChangeRole handles changing a user's role
+// @Summary Change user role
+// @Description Give a user another role (admin only). Protected changes, such as granting admin, are not applied but answered with 202 and the approval another admin must give.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param role body ChangeRoleRequest true "New role"
+// @Success 200 {object} map[string]interface{}
+// @Success 202 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/role [put]
+func (uc *UserController) ChangeRole(c *gin.Context) {
+	req, ok := request.Bind[ChangeRoleRequest](c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	user, approval, err := uc.userService.ChangeRole(c.Request.Context(), c.Param("id"), req.Role, claims.UserID)
+	if err != nil {
+		var appErr *errors.AppError
+		switch {
+		case stderrors.Is(err, services.ErrInvalidRole):
+			appErr = errors.NewValidationError(i18n.UserInvalidRole, err).WithCode(errors.CodeInvalidRole)
+		case stderrors.Is(err, services.ErrUserNotFound):
+			appErr = errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+		case stderrors.Is(err, services.ErrLastAdminDemotion):
+			appErr = errors.NewConflictError(i18n.UserLastAdminDemotion, err).WithCode(errors.CodeLastAdminDemotion)
+		case stderrors.Is(err, services.ErrApprovalPending):
+			appErr = errors.NewConflictError(i18n.ApprovalPending, err).WithCode(errors.CodeApprovalPending)
+		case stderrors.Is(err, services.ErrApprovalStale):
+			appErr = errors.NewConflictError(i18n.ApprovalStale, err).WithCode(errors.CodeApprovalStale)
+		default:
+			appErr = errors.NewInternalServerError(i18n.UserRoleChangeFailed, err)
+		}
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	if approval != nil {
+		c.Header("Location", "/api/v1/admin/approvals/"+approval.ID.String())
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Role change awaits approval by another admin",
+			"data":    approval,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User role changed successfully",
+		"data":    newUserListItem(user),
+	})
+}
+
+// RequirePasswordChange handles forcing a user to change their password
+// @Summary Require password change
+// @Description Revoke the user's tokens and restrict their next login to changing the password (admin only)
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/require-password-change [post]
+func (uc *UserController) RequirePasswordChange(c *gin.Context) {
+	id := c.Param("id")
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	user, err := uc.userService.RequirePasswordChange(c.Request.Context(), id, claims.UserID)
+	if err != nil {
+		if stderrors.Is(err, services.ErrUserNotFound) {
+			appErr := errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+			middleware.RespondError(c, appErr)
+			return
+		}
+		appErr := errors.NewInternalServerError(i18n.UserRequirePasswordChangeFailed, err)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User must change their password at the next login",
+		"data":    newUserListItem(user),
+	})
+}
+
+// ExportUser handles the data-subject export of a user
+// @Summary Export user data
+// @Description Export the user record, login events and audit entries (admin or the user themselves)
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} services.UserDataExport
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/export [get]
+func (uc *UserController) ExportUser(c *gin.Context) {
+	id := canonicalID(c.Param("id"))
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	if claims.Role != string(models.RoleAdmin) && claims.UserID != id {
+		appErr := errors.NewForbiddenError(i18n.AuthInsufficientPermissions, nil).WithCode(errors.CodeInsufficientPermissions)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	export, err := uc.userService.ExportUserData(c.Request.Context(), id, claims.UserID)
+	if err != nil {
+		appErr := errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": export,
+	})
+}
+
+// AnonymizeUser handles irreversible anonymization of a user
+// @Summary Anonymize user
+// @Description Irreversibly scrub a user's personal data while keeping the record (admin only). The first request is answered with 202 and a confirmation token; repeat it with X-Confirm-Token to anonymize.
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Param X-Confirm-Token header string false "Token confirming the anonymization"
+// @Success 200 {object} map[string]interface{}
+// @Success 202 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/anonymize [post]
+func (uc *UserController) AnonymizeUser(c *gin.Context) {
+	id := c.Param("id")
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	user, err := uc.userService.AnonymizeUser(c.Request.Context(), id, claims.UserID)
+	if err != nil {
+		var appErr *errors.AppError
+		switch {
+		case stderrors.Is(err, services.ErrUserNotFound):
+			appErr = errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+		case stderrors.Is(err, services.ErrSelfDeactivation):
+			appErr = errors.NewForbiddenError(i18n.UserSelfDeactivation, err).WithCode(errors.CodeSelfDeactivation)
+		case stderrors.Is(err, services.ErrLastActiveAdmin):
+			appErr = errors.NewConflictError(i18n.UserLastActiveAdmin, err).WithCode(errors.CodeLastActiveAdmin)
+		default:
+			appErr = errors.NewInternalServerError(i18n.UserAnonymizeFailed, err)
+		}
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User anonymized successfully",
+		"data":    user,
+	})
+}
+
+// summarizeUser describes the user a destructive request names by :id
+func (uc *UserController) summarizeUser(c *gin.Context) (*services.ConfirmationSummary, *errors.AppError) {
+	user, err := uc.userService.GetUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		return nil, errors.NewNotFoundError(i18n.UserNotFound, err).WithCode(errors.CodeUserNotFound)
+	}
+	return &services.ConfirmationSummary{
+		Affected:  map[string]int64{"users": 1},
+		SampleIDs: []string{user.ID.String()},
+	}, nil
+}
+
+// canonicalID writes a user ID given in any supported format as the UUID
+// tokens carry, so it can be compared to the caller's ID
+func canonicalID(id string) string {
+	if parsed, err := identifier.Parse(id); err == nil {
+		return parsed.String()
+	}
+	return id
+}
+
+// updateRequestError reports why an update request body was refused
+func updateRequestError(err error) *errors.AppError {
+	var unknownErr *services.UnknownFieldsError
+	if stderrors.As(err, &unknownErr) {
+		appErr := errors.NewBadRequestError(i18n.ValidationFailed, err).WithCode(errors.CodeUnknownFields)
+		for _, field := range unknownErr.Fields {
+			appErr.WithDetails(errors.Detail{Field: field, Message: i18n.ValidationUnknownField, Params: errors.Params{"field": field}})
+		}
+		return appErr
+	}
+	if _, ok := validator.FieldErrors(err); ok {
+		return validator.NewAppError(err)
+	}
+	return errors.NewValidationError(i18n.RequestInvalidBody, err).WithCode(errors.CodeInvalidRequestBody)
+}
+
+// passwordPolicyError maps a password refused by the password policy
+func passwordPolicyError(err error) *errors.AppError {
+	if stderrors.Is(err, services.ErrPasswordCheckUnavailable) {
+		return errors.NewAppError(http.StatusServiceUnavailable, i18n.AuthPasswordCheckUnavailable, err).WithCode(errors.CodePasswordCheckUnavailable)
+	}
+	return errors.NewValidationError(i18n.AuthPasswordBreached, err).WithCode(errors.CodePasswordBreached)
+}
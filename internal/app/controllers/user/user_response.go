@@ -0,0 +1,146 @@
+package user
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/apimodel"
+	"BackofficeGoService/internal/pkg/apiversion"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// page is a page of a listing as a handler found it, before rendering
+type page[T any] struct {
+	items []T
+	meta  pagination.Meta
+}
+
+// userResponses renders a single user
+var userResponses = apiversion.Renderers[*models.User]{
+	apiversion.V1: func(u *models.User) any { return gin.H{"data": u} },
+	apiversion.V2: func(u *models.User) any { return gin.H{"data": newUserV2(u)} },
+}
+
+// userPages renders a page of GET /users
+var userPages = apiversion.Renderers[page[*models.User]]{
+	apiversion.V1: func(p page[*models.User]) any {
+		items := make([]userListItem, len(p.items))
+		for i, u := range p.items {
+			items[i] = newUserListItem(u)
+		}
+		return gin.H{"data": items, "meta": p.meta}
+	},
+	apiversion.V2: func(p page[*models.User]) any {
+		items := make([]userV2, len(p.items))
+		for i, u := range p.items {
+			items[i] = newUserV2(u)
+		}
+		return newPageV2(items, p.meta)
+	},
+}
+
+// searchPages renders a page of GET /users/search
+var searchPages = apiversion.Renderers[page[*services.UserSearchResult]]{
+	apiversion.V1: func(p page[*services.UserSearchResult]) any {
+		return gin.H{"data": p.items, "meta": p.meta}
+	},
+	apiversion.V2: func(p page[*services.UserSearchResult]) any {
+		items := make([]userSearchResultV2, len(p.items))
+		for i, r := range p.items {
+			items[i] = userSearchResultV2{userV2: newUserV2(r.User), Score: r.Score}
+		}
+		return newPageV2(items, p.meta)
+	},
+}
+
+// userListItem decorates a user with a display status for v1 list responses
+type userListItem struct {
+	*models.User
+	Status string `json:"status"`
+}
+
+// MarshalJSON implements json.Marshaler; the embedded user's own would
+// otherwise drop Status
+func (u userListItem) MarshalJSON() ([]byte, error) { return apimodel.Marshal(u) }
+
+// newUserListItem flags deactivated users in list responses
+func newUserListItem(u *models.User) userListItem {
+	status := "active"
+	if !u.Active {
+		status = "deactivated"
+	}
+	return userListItem{User: u, Status: status}
+}
+
+// userV2 is a user as v2 renders it. Fields are listed one by one, so
+// columns added to models.User stay out of v2 until they are added here.
+type userV2 struct {
+	ID         uuid.UUID       `json:"id"`
+	Email      string          `json:"email"`
+	Username   string          `json:"username"`
+	GivenName  string          `json:"given_name"`
+	FamilyName string          `json:"family_name"`
+	Role       models.UserRole `json:"role"`
+	// Status is active, deactivated or anonymized; it replaces v1's active flag
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (u userV2) MarshalJSON() ([]byte, error) { return apimodel.Marshal(u) }
+
+func newUserV2(u *models.User) userV2 {
+	status := "active"
+	switch {
+	case u.AnonymizedAt != nil:
+		status = "anonymized"
+	case !u.Active:
+		status = "deactivated"
+	}
+	return userV2{
+		ID:         u.ID,
+		Email:      u.Email,
+		Username:   u.Username,
+		GivenName:  u.FirstName,
+		FamilyName: u.LastName,
+		Role:       u.Role,
+		Status:     status,
+		CreatedAt:  u.CreatedAt,
+		UpdatedAt:  u.UpdatedAt,
+	}
+}
+
+// userSearchResultV2 is a search match as v2 renders it
+type userSearchResultV2 struct {
+	userV2
+	Score float64 `json:"score"`
+}
+
+// MarshalJSON implements json.Marshaler; the embedded user's own would
+// otherwise drop Score
+func (r userSearchResultV2) MarshalJSON() ([]byte, error) { return apimodel.Marshal(r) }
+
+// pageV2 is a page of a v2 listing, with the pagination next to the items
+// rather than nested under meta
+type pageV2[T any] struct {
+	Data       []T   `json:"data"`
+	Page       int   `json:"page"`
+	PerPage    int   `json:"per_page"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+func newPageV2[T any](items []T, meta pagination.Meta) pageV2[T] {
+	return pageV2[T]{
+		Data:       items,
+		Page:       meta.Page,
+		PerPage:    meta.Limit,
+		Total:      meta.Total,
+		TotalPages: meta.TotalPages,
+	}
+}
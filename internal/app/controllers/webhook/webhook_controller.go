@@ -0,0 +1,283 @@
+package webhook
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookController handles webhook administration HTTP requests
+type WebhookController struct {
+	webhookService *services.WebhookService
+	dispatcher     *services.WebhookDispatcher
+	pages          pagination.Config
+}
+
+// NewWebhookController creates a new webhook controller
+func NewWebhookController(webhookService *services.WebhookService, dispatcher *services.WebhookDispatcher, pages pagination.Config) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+		dispatcher:     dispatcher,
+		pages:          pages,
+	}
+}
+
+// Routes lists the webhook administration routes. Every route requires the
+// webhooks.manage permission.
+func (wc *WebhookController) Routes() []route.Definition {
+	canManage := route.Policy{Auth: route.Authenticated, Permission: models.PermissionWebhooksManage}
+	webhook := func(method, path string, handler gin.HandlerFunc) route.Definition {
+		return route.Definition{Method: method, Path: path, Handler: handler, Policy: canManage, Feature: config.FeatureWebhooks}
+	}
+
+	return []route.Definition{
+		webhook(http.MethodGet, "/webhooks", wc.ListWebhooks),
+		webhook(http.MethodPost, "/webhooks", wc.CreateWebhook),
+		webhook(http.MethodGet, "/webhooks/:id", wc.GetWebhook),
+		webhook(http.MethodPut, "/webhooks/:id", wc.UpdateWebhook),
+		webhook(http.MethodDelete, "/webhooks/:id", wc.DeleteWebhook),
+		webhook(http.MethodGet, "/webhooks/:id/deliveries", wc.ListDeliveries),
+		webhook(http.MethodPost, "/webhooks/:id/test", wc.SendTestEvent),
+	}
+}
+
+// ListWebhooks handles listing webhooks
+// @Summary List webhooks
+// @Description List every registered webhook endpoint
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/webhooks [get]
+func (wc *WebhookController) ListWebhooks(c *gin.Context) {
+	webhooks, err := wc.webhookService.ListWebhooks(c.Request.Context())
+	if err != nil {
+		appErr := errors.NewInternalServerError("Failed to fetch webhooks", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": webhooks,
+	})
+}
+
+// CreateWebhook handles registering a webhook
+// @Summary Create webhook
+// @Description Register a webhook endpoint; the signing secret is only returned here
+// @Tags webhooks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param webhook body services.CreateWebhookRequest true "Webhook data"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/webhooks [post]
+func (wc *WebhookController) CreateWebhook(c *gin.Context) {
+	var req services.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	webhook, err := wc.webhookService.CreateWebhook(c.Request.Context(), &req)
+	if err != nil {
+		appErr := webhookError(err, "Failed to create webhook")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":   webhook,
+		"secret": webhook.Secret,
+	})
+}
+
+// GetWebhook handles getting a webhook by ID
+// @Summary Get webhook
+// @Description Get a webhook endpoint by ID
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/webhooks/{id} [get]
+func (wc *WebhookController) GetWebhook(c *gin.Context) {
+	webhook, err := wc.webhookService.GetWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		appErr := webhookError(err, "Failed to fetch webhook")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": webhook,
+	})
+}
+
+// UpdateWebhook handles updating a webhook
+// @Summary Update webhook
+// @Description Partially update a webhook; enabling it clears its failure streak
+// @Tags webhooks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param webhook body services.UpdateWebhookRequest true "Webhook data"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/webhooks/{id} [put]
+func (wc *WebhookController) UpdateWebhook(c *gin.Context) {
+	var req services.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	webhook, err := wc.webhookService.UpdateWebhook(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		appErr := webhookError(err, "Failed to update webhook")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": webhook,
+	})
+}
+
+// DeleteWebhook handles deleting a webhook
+// @Summary Delete webhook
+// @Description Delete a webhook and its delivery history
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/webhooks/{id} [delete]
+func (wc *WebhookController) DeleteWebhook(c *gin.Context) {
+	if err := wc.webhookService.DeleteWebhook(c.Request.Context(), c.Param("id")); err != nil {
+		appErr := webhookError(err, "Failed to delete webhook")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook deleted successfully",
+	})
+}
+
+// ListDeliveries handles listing the delivery attempts of a webhook
+// @Summary List webhook deliveries
+// @Description List the delivery attempts of a webhook, newest first. Pages are read by cursor: follow meta.next_cursor or the Link header.
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param limit query int false "Items per page" default(100)
+// @Param cursor query string false "Cursor from the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func (wc *WebhookController) ListDeliveries(c *gin.Context) {
+	// Deliveries are read in bulk, a full page at a time
+	params, appErr := pagination.ParseParams(c, wc.pages.WithDefault(wc.pages.MaxLimit))
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+	var after *pagination.Cursor
+	if raw := c.Query(pagination.CursorParam); raw != "" {
+		cursor, err := pagination.ParseCursor(raw)
+		if err != nil {
+			appErr := errors.NewBadRequestError(i18n.RequestInvalidCursor, err).WithCode(errors.CodeInvalidCursor)
+			middleware.RespondError(c, appErr)
+			return
+		}
+		after = &cursor
+	}
+
+	deliveries, next, err := wc.webhookService.ListDeliveries(c.Request.Context(), c.Param("id"), after, params.Limit)
+	if err != nil {
+		appErr := webhookError(err, "Failed to fetch deliveries")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	meta := pagination.CursorMeta{Limit: params.Limit}
+	if next != nil {
+		meta.NextCursor = next.Encode()
+	}
+	c.Header("Link", meta.Links(c.Request.URL))
+	c.JSON(http.StatusOK, gin.H{
+		"data": deliveries,
+		"meta": meta,
+	})
+}
+
+// SendTestEvent handles sending a test event to a webhook
+// @Summary Send test event
+// @Description Make a single signed delivery of a webhook.test event and return the result
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/v1/webhooks/{id}/test [post]
+func (wc *WebhookController) SendTestEvent(c *gin.Context) {
+	webhook, err := wc.webhookService.GetWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		appErr := webhookError(err, "Failed to fetch webhook")
+		c.JSON(appErr.Status, gin.H{"error": appErr.Message})
+		return
+	}
+
+	delivery, err := wc.dispatcher.SendTestEvent(c.Request.Context(), webhook)
+	if err != nil {
+		status := http.StatusBadGateway
+		if delivery == nil {
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, gin.H{
+			"error": "Test delivery failed",
+			"data":  delivery,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": delivery,
+	})
+}
+
+// webhookError maps webhook service errors to HTTP errors
+func webhookError(err error, fallback string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrWebhookNotFound):
+		return errors.NewNotFoundError("Webhook not found", err)
+	case stderrors.Is(err, services.ErrInvalidWebhookURL), stderrors.Is(err, services.ErrUnknownEventType):
+		return errors.NewValidationError(err.Error(), err)
+	}
+	return errors.NewInternalServerError(fallback, err)
+}
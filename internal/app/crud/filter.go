@@ -0,0 +1,63 @@
+package crud
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Scope narrows a list query
+type Scope = func(db *gorm.DB) *gorm.DB
+
+// Filter reads the value of a filter query parameter. Its error says why the
+// value is invalid, such as "must be true or false".
+type Filter func(raw string) (Scope, error)
+
+// Equal matches entities whose column is the value
+func Equal(column string) Filter {
+	return func(raw string) (Scope, error) {
+		return where(clause.Eq{Column: clause.Column{Name: column}, Value: raw}), nil
+	}
+}
+
+// Bool matches entities whose boolean column is true or false
+func Bool(column string) Filter {
+	return func(raw string) (Scope, error) {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return where(clause.Eq{Column: clause.Column{Name: column}, Value: b}), nil
+	}
+}
+
+// Contains matches entities whose column contains every letter and digit run
+// of the value, ignoring case. Other characters, such as LIKE wildcards,
+// only separate the runs.
+func Contains(column string) Filter {
+	return func(raw string) (Scope, error) {
+		terms := strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(terms) == 0 {
+			return nil, errors.New("must contain letters or digits")
+		}
+		return func(db *gorm.DB) *gorm.DB {
+			for _, term := range terms {
+				db = db.Where("LOWER(?) LIKE ?", clause.Column{Name: column}, "%"+term+"%")
+			}
+			return db
+		}, nil
+	}
+}
+
+// where returns the scope adding a condition
+func where(condition clause.Expression) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(condition)
+	}
+}
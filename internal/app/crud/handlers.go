@@ -0,0 +1,210 @@
+package crud
+
+import (
+	stderrors "errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/pagination"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// Routes lists the resource's routes, each needing the permission of its
+// operation. Lists and gets are cached in CacheGroup.
+func (r *Resource[T]) Routes() []route.Definition {
+	var cache *middleware.CacheRule
+	if r.cfg.CacheGroup != "" {
+		query := append([]string{pagination.PageParam, pagination.LimitParam, SortParam}, slices.Sorted(maps.Keys(r.cfg.Filters))...)
+		cache = &middleware.CacheRule{Groups: []string{r.cfg.CacheGroup}, Query: query}
+	}
+	item := r.cfg.Path + "/:id"
+
+	definition := func(method, path string, op Operation, handler gin.HandlerFunc) route.Definition {
+		if custom, ok := r.cfg.Handlers[op]; ok {
+			handler = custom
+		}
+		policy := route.Policy{Auth: route.Authenticated, Permission: r.Permission(op)}
+		if method == http.MethodGet {
+			policy.Cache = cache
+		}
+		return route.Definition{Method: method, Path: path, Handler: handler, Policy: policy}
+	}
+	return []route.Definition{
+		definition(http.MethodGet, r.cfg.Path, List, r.HandleList),
+		definition(http.MethodPost, r.cfg.Path, Create, r.HandleCreate),
+		definition(http.MethodGet, item, Get, r.HandleGet),
+		definition(http.MethodPut, item, Update, r.HandleUpdate),
+		definition(http.MethodDelete, item, Delete, r.HandleDelete),
+	}
+}
+
+// HandleList responds with a page of entities, filtered by the Filters
+// sent and sorted by sort
+func (r *Resource[T]) HandleList(c *gin.Context) {
+	params, appErr := pagination.ParseParams(c, r.cfg.Pages)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+	query, appErr := r.ParseQuery(c)
+	if appErr != nil {
+		middleware.RespondError(c, appErr)
+		return
+	}
+	query.Limit, query.Offset = params.Limit, params.Offset()
+
+	result, err := r.List(c.Request.Context(), query)
+	if err != nil {
+		middleware.RespondError(c, r.failed(i18n.ResourceListFailed, err))
+		return
+	}
+
+	meta := pagination.NewMeta(params, result.Total)
+	c.Header("Link", meta.Links(c.Request.URL))
+	c.JSON(http.StatusOK, gin.H{
+		"data": result.Items,
+		"meta": meta,
+	})
+}
+
+// HandleGet responds with the entity with the id path parameter
+func (r *Resource[T]) HandleGet(c *gin.Context) {
+	entity, err := r.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		middleware.RespondError(c, r.Error(err, i18n.ResourceFetchFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": entity,
+	})
+}
+
+// HandleCreate creates an entity from the request body
+func (r *Resource[T]) HandleCreate(c *gin.Context) {
+	input, ok := r.cfg.Create(c)
+	if !ok {
+		return
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	entity, err := r.Create(c.Request.Context(), claims.UserID, input)
+	if err != nil {
+		middleware.RespondError(c, r.Error(err, i18n.ResourceCreateFailed))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": entity,
+	})
+}
+
+// HandleUpdate applies the request body to the entity with the id path
+// parameter
+func (r *Resource[T]) HandleUpdate(c *gin.Context) {
+	input, ok := r.cfg.Update(c)
+	if !ok {
+		return
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	entity, err := r.Update(c.Request.Context(), claims.UserID, c.Param("id"), input)
+	if err != nil {
+		middleware.RespondError(c, r.Error(err, i18n.ResourceUpdateFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": entity,
+	})
+}
+
+// HandleDelete deletes the entity with the id path parameter
+func (r *Resource[T]) HandleDelete(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+		middleware.RespondError(c, appErr)
+		return
+	}
+
+	if err := r.Delete(c.Request.Context(), claims.UserID, c.Param("id")); err != nil {
+		middleware.RespondError(c, r.Error(err, i18n.ResourceDeleteFailed))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": strings.ToUpper(r.cfg.EntityType[:1]) + r.cfg.EntityType[1:] + " deleted successfully",
+	})
+}
+
+// ParseQuery reads the filters and the order of a list request. Query
+// parameters that are not filters are ignored, like pagination's.
+func (r *Resource[T]) ParseQuery(c *gin.Context) (Query, *errors.AppError) {
+	var query Query
+	for _, name := range slices.Sorted(maps.Keys(r.cfg.Filters)) {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		scope, err := r.cfg.Filters[name](raw)
+		if err != nil {
+			return query, errors.NewBadRequestError(i18n.SavedFilterInvalidValue, err).
+				WithCode(errors.CodeInvalidFilter).
+				WithParams(errors.Params{"name": name, "reason": err.Error()})
+		}
+		query.Scopes = append(query.Scopes, scope)
+	}
+
+	sort := c.DefaultQuery(SortParam, r.cfg.DefaultSort)
+	if sort == "" {
+		return query, nil
+	}
+	column, desc := strings.CutPrefix(sort, "-")
+	if !slices.Contains(r.cfg.Sorts, column) {
+		return query, errors.NewBadRequestError(i18n.ResourceInvalidSort, nil).
+			WithCode(errors.CodeInvalidSort).
+			WithParams(errors.Params{"name": column, "allowed": strings.Join(r.cfg.Sorts, ", ")})
+	}
+	query.Order = clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: desc}
+	return query, nil
+}
+
+// Error maps an error of the data methods to its response; other errors
+// are reported as failedKey
+func (r *Resource[T]) Error(err error, failedKey string) *errors.AppError {
+	var exists *ExistsError
+	switch {
+	case stderrors.Is(err, ErrNotFound):
+		return errors.NewNotFoundError(r.cfg.NotFound.Key, err).WithCode(r.cfg.NotFound.Code)
+	case stderrors.As(err, &exists):
+		return errors.NewConflictError(r.cfg.Exists.Key, err).
+			WithCode(r.cfg.Exists.Code).
+			WithParams(errors.Params{"field": exists.Column, "value": fmt.Sprint(exists.Value)})
+	}
+	return r.failed(failedKey, err)
+}
+
+// failed reports an unexpected error as failedKey
+func (r *Resource[T]) failed(failedKey string, err error) *errors.AppError {
+	return errors.NewInternalServerError(failedKey, err).WithParams(errors.Params{"resource": r.cfg.EntityType})
+}
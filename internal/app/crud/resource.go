@@ -0,0 +1,203 @@
+// Package crud serves the list, get, create, update and delete routes of a
+// GORM model, so a new backoffice entity is declared rather than copied from
+// the user controller:
+//
+//	products := crud.New(db, crud.Config[models.Product]{
+//		Path:       "/products",
+//		EntityType: "product",
+//		Permission: "products",
+//		Create:     crud.Body[models.Product, CreateProductRequest](),
+//		Update:     crud.Body[models.Product, UpdateProductRequest](),
+//		...
+//	}, crud.WithAudit(audit), crud.WithResponseCache(responses))
+//	defs = append(defs, products.Routes()...)
+//
+// Lists are paginated like every other list, filtered by a whitelist of query
+// parameters and sorted by a whitelist of columns. Changes are audited as
+// <entity>.created, <entity>.updated and <entity>.deleted and purge the
+// resource's response cache group. Handlers replaces the generic handler of
+// an operation; the Handle methods and the data methods stay available to it.
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/schema"
+)
+
+// Operation is one of the routes a resource serves
+type Operation string
+
+// Operations of a resource
+const (
+	List   Operation = "list"
+	Get    Operation = "get"
+	Create Operation = "create"
+	Update Operation = "update"
+	Delete Operation = "delete"
+)
+
+// SortParam is the query parameter lists are sorted by: a column, or a
+// column prefixed with - for descending order
+const SortParam = "sort"
+
+// Input is a validated request body setting fields of a T. Create bodies are
+// applied to a new entity, update bodies to the stored one, so update
+// bodies use pointer fields and leave nil ones unchanged.
+type Input[T any] interface {
+	Apply(entity *T)
+}
+
+// Decoder binds and validates a request body. It responds to the client and
+// returns false when the body is invalid.
+type Decoder[T any] func(c *gin.Context) (Input[T], bool)
+
+// Body returns the decoder of the request type D, validated by its binding tags
+func Body[T any, D Input[T]]() Decoder[T] {
+	return func(c *gin.Context) (Input[T], bool) {
+		req, ok := request.Bind[D](c)
+		return req, ok
+	}
+}
+
+// Message is the message and code of an error specific to a resource
+type Message struct {
+	Key  string
+	Code errors.Code
+}
+
+// Config declares a resource
+type Config[T any] struct {
+	// Path is the collection's route, such as /products; entities are
+	// served under Path/:id
+	Path string
+	// EntityType names the entities in audit entries, such as product
+	EntityType string
+	// Permission prefixes the permissions of the routes: <prefix>.view
+	// lists and gets, <prefix>.create, <prefix>.update and <prefix>.delete
+	// change entities
+	Permission string
+	// CacheGroup is the response cache group of lists and gets, purged by
+	// every change; empty responses are not cached
+	CacheGroup string
+
+	// New returns an entity to create, with its ID set. Nil creates a zero T.
+	New func() *T
+	// Create and Update decode the request bodies, usually made by Body
+	Create Decoder[T]
+	Update Decoder[T]
+
+	// Filters are the query parameters lists may be filtered by
+	Filters map[string]Filter
+	// Sorts are the columns lists may be sorted by. DefaultSort orders lists
+	// sent without sort, such as -created_at.
+	Sorts       []string
+	DefaultSort string
+	// Unique lists the columns no two entities may share. They are checked
+	// before saving, so the client learns which value is taken.
+	Unique []string
+	// Pages sizes the pages of lists
+	Pages pagination.Config
+
+	// NotFound is returned for unknown IDs. Exists is returned for a taken
+	// Unique value, with the column as the field parameter and the value
+	// as the value parameter.
+	NotFound Message
+	Exists   Message
+
+	// Handlers replace the generic handlers of operations. The route and
+	// its policy stay the same.
+	Handlers map[Operation]gin.HandlerFunc
+}
+
+// Option configures a Resource
+type Option func(*hooks)
+
+// hooks are what a resource tells about its changes
+type hooks struct {
+	audit     services.AuditRecorder
+	responses *services.ResponseCache
+	logger    logger.Logger
+}
+
+// WithAudit records the changes of a resource in the audit log
+func WithAudit(audit services.AuditRecorder) Option {
+	return func(h *hooks) {
+		h.audit = audit
+	}
+}
+
+// WithResponseCache purges the resource's cached responses on changes
+func WithResponseCache(responses *services.ResponseCache) Option {
+	return func(h *hooks) {
+		h.responses = responses
+	}
+}
+
+// WithLogger logs the audit entries that could not be recorded
+func WithLogger(log logger.Logger) Option {
+	return func(h *hooks) {
+		h.logger = log
+	}
+}
+
+// Resource serves the CRUD routes of the GORM model T
+type Resource[T any] struct {
+	cfg    Config[T]
+	db     *database.Manager
+	schema *schema.Schema
+	hooks
+}
+
+// New creates the resource cfg declares on the database each request is
+// scoped to. It panics if T is not a GORM model with a primary key or a
+// Unique column is not one of its columns, as a misdeclared resource must
+// not start.
+func New[T any](db *database.Manager, cfg Config[T], opts ...Option) *Resource[T] {
+	s, err := schema.Parse(new(T), &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		panic(fmt.Sprintf("crud: %s: %v", cfg.Path, err))
+	}
+	if s.PrioritizedPrimaryField == nil {
+		panic(fmt.Sprintf("crud: %s: %s has no primary key", cfg.Path, s.Name))
+	}
+	for _, column := range cfg.Unique {
+		if s.LookUpField(column) == nil {
+			panic(fmt.Sprintf("crud: %s: %s has no column %s", cfg.Path, s.Name, column))
+		}
+	}
+	if cfg.New == nil {
+		cfg.New = func() *T { return new(T) }
+	}
+
+	r := &Resource[T]{cfg: cfg, db: db, schema: s}
+	for _, opt := range opts {
+		opt(&r.hooks)
+	}
+	return r
+}
+
+// Permission returns the permission of op
+func (r *Resource[T]) Permission(op Operation) string {
+	if op == List || op == Get {
+		return r.cfg.Permission + ".view"
+	}
+	return r.cfg.Permission + "." + string(op)
+}
+
+// id returns the primary key of entity
+func (r *Resource[T]) id(entity *T) string {
+	value, _ := r.schema.PrioritizedPrimaryField.ValueOf(context.Background(), reflect.ValueOf(entity).Elem())
+	return fmt.Sprint(value)
+}
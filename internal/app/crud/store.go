@@ -0,0 +1,223 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotFound is returned for an ID no entity has
+var ErrNotFound = errors.New("entity not found")
+
+// ErrExists is wrapped by the ExistsError of a taken Unique value
+var ErrExists = errors.New("entity exists")
+
+// ExistsError reports a Unique column whose value another entity has
+type ExistsError struct {
+	Column string
+	Value  interface{}
+}
+
+// Error implements the error interface
+func (e *ExistsError) Error() string {
+	return fmt.Sprintf("%v: %s %v", ErrExists, e.Column, e.Value)
+}
+
+// Unwrap returns ErrExists
+func (e *ExistsError) Unwrap() error {
+	return ErrExists
+}
+
+// Query selects a page of a list, usually read by ParseQuery
+type Query struct {
+	Scopes []Scope
+	Order  clause.OrderByColumn
+	Limit  int
+	Offset int
+}
+
+// List returns a page of the entities q selects and how many there are.
+// Entities in the same position of the order are ordered by ID, so pages do
+// not overlap.
+func (r *Resource[T]) List(ctx context.Context, q Query) (*services.ListResult[*T], error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	selected := func() *gorm.DB {
+		return db.Model(new(T)).Scopes(q.Scopes...)
+	}
+
+	result := &services.ListResult[*T]{Items: []*T{}}
+	if err := selected().Count(&result.Total).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	query := selected()
+	if q.Order.Column.Name != "" {
+		query = query.Order(q.Order)
+	}
+	err = query.Order(clause.OrderByColumn{Column: clause.Column{Name: r.schema.PrioritizedPrimaryField.DBName}}).
+		Limit(q.Limit).Offset(q.Offset).
+		Find(&result.Items).Error
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// Get returns the entity with the ID, or ErrNotFound
+func (r *Resource[T]) Get(ctx context.Context, id string) (*T, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(db, id)
+}
+
+// Create creates an entity from input on behalf of actorID. A taken Unique
+// value fails with an ExistsError.
+func (r *Resource[T]) Create(ctx context.Context, actorID string, input Input[T]) (*T, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := r.cfg.New()
+	input.Apply(entity)
+	if err := r.checkUnique(db, entity); err != nil {
+		return nil, err
+	}
+	if err := db.Create(entity).Error; err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", r.cfg.EntityType, err)
+	}
+
+	r.changed(ctx, actorID, "created", entity, nil)
+	return entity, nil
+}
+
+// Update applies input to the entity with the ID on behalf of actorID and
+// returns it. The audit entry names the changed fields; nothing is saved
+// or audited when input changes none.
+func (r *Resource[T]) Update(ctx context.Context, actorID, id string, input Input[T]) (*T, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	entity, err := r.find(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *entity
+	input.Apply(entity)
+	fields := r.changedFields(&before, entity)
+	if len(fields) == 0 {
+		return entity, nil
+	}
+	if err := r.checkUnique(db, entity); err != nil {
+		return nil, err
+	}
+	if err := db.Save(entity).Error; err != nil {
+		return nil, fmt.Errorf("failed to update %s: %w", r.cfg.EntityType, err)
+	}
+
+	r.changed(ctx, actorID, "updated", entity, map[string][]string{"fields": fields})
+	return entity, nil
+}
+
+// Delete deletes the entity with the ID on behalf of actorID
+func (r *Resource[T]) Delete(ctx context.Context, actorID, id string) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	entity, err := r.find(db, id)
+	if err != nil {
+		return err
+	}
+	if err := db.Delete(entity).Error; err != nil {
+		return fmt.Errorf("failed to delete %s: %w", r.cfg.EntityType, err)
+	}
+
+	r.changed(ctx, actorID, "deleted", entity, nil)
+	return nil
+}
+
+// find returns the entity with the ID, or ErrNotFound
+func (r *Resource[T]) find(db *gorm.DB, id string) (*T, error) {
+	entity := new(T)
+	err := db.Where(clause.Eq{Column: clause.Column{Name: r.schema.PrioritizedPrimaryField.DBName}, Value: id}).
+		First(entity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return entity, nil
+}
+
+// checkUnique fails with an ExistsError when another entity has one of
+// entity's Unique values
+func (r *Resource[T]) checkUnique(db *gorm.DB, entity *T) error {
+	value := reflect.ValueOf(entity).Elem()
+	primary := r.schema.PrioritizedPrimaryField.DBName
+	for _, column := range r.cfg.Unique {
+		v, _ := r.schema.LookUpField(column).ValueOf(db.Statement.Context, value)
+		var count int64
+		err := db.Model(new(T)).
+			Where(clause.Eq{Column: clause.Column{Name: column}, Value: v}).
+			Where(clause.Neq{Column: clause.Column{Name: primary}, Value: r.id(entity)}).
+			Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		if count > 0 {
+			return &ExistsError{Column: column, Value: v}
+		}
+	}
+	return nil
+}
+
+// changedFields lists the columns whose values differ between before and
+// after, leaving out the timestamps GORM maintains
+func (r *Resource[T]) changedFields(before, after *T) []string {
+	ctx := context.Background()
+	var fields []string
+	for _, field := range r.schema.Fields {
+		if field.DBName == "" || field.AutoCreateTime != 0 || field.AutoUpdateTime != 0 {
+			continue
+		}
+		old, _ := field.ValueOf(ctx, reflect.ValueOf(before).Elem())
+		updated, _ := field.ValueOf(ctx, reflect.ValueOf(after).Elem())
+		if !reflect.DeepEqual(old, updated) && !slices.Contains(fields, field.DBName) {
+			fields = append(fields, field.DBName)
+		}
+	}
+	return fields
+}
+
+// changed audits a change to entity as <entity>.<verb> and purges the
+// resource's cached responses
+func (r *Resource[T]) changed(ctx context.Context, actorID, verb string, entity *T, metadata interface{}) {
+	if r.cfg.CacheGroup != "" {
+		r.responses.Purge(ctx, r.cfg.CacheGroup)
+	}
+	if r.audit == nil {
+		return
+	}
+	id := r.id(entity)
+	action := r.cfg.EntityType + "." + verb
+	if err := r.audit.Record(ctx, actorID, action, r.cfg.EntityType, id, metadata); err != nil && r.logger != nil {
+		r.logger.Warn("Failed to audit "+r.cfg.EntityType+" change", logger.Field{Key: "entity_id", Value: id}, logger.Field{Key: "action", Value: action}, logger.Field{Key: "error", Value: err.Error()})
+	}
+}
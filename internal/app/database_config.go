@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+)
+
+// connectionConfig maps a configured connection onto the database package's
+// neutral ConnectionConfig. The mapping lives here so neither config nor
+// database has to import the other. applicationName names the connections
+// to PostgreSQL; see Application.applicationName.
+func connectionConfig(dbc config.DatabaseConnectionConfig, applicationName string) database.ConnectionConfig {
+	return database.ConnectionConfig{
+		Driver:          database.DriverType(dbc.Driver),
+		Host:            dbc.Host,
+		Port:            dbc.Port,
+		User:            dbc.User,
+		Password:        dbc.Password,
+		DBName:          dbc.DBName,
+		SSLMode:         dbc.SSLMode,
+		ApplicationName: applicationName,
+		Charset:         dbc.Charset,
+		MaxOpenConns:    dbc.MaxOpenConns,
+		MaxIdleConns:    dbc.MaxIdleConns,
+		ConnMaxLifetime: dbc.ConnMaxLifetime,
+		ConnMaxIdleTime: dbc.ConnMaxIdleTime,
+		UseGorm:         dbc.UseGorm,
+	}
+}
+
+// OpenDatabase connects one configured database outside an Application, for
+// commands that need a database but no server. The caller closes it.
+func OpenDatabase(ctx context.Context, dbc config.DatabaseConnectionConfig) (database.Driver, error) {
+	driver, err := database.NewFactory().CreateFromConnectionConfig(connectionConfig(dbc, ""))
+	if err != nil {
+		return nil, err
+	}
+	if err := driver.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return driver, nil
+}
+
+// connectRuntimeDatabase connects a database registered through
+// /admin/databases. Unlike configured ones, an optional database that fails
+// to connect is not retried, so the caller learns of the failure at once.
+// Durations the API does not take follow the primary database.
+func (app *Application) connectRuntimeDatabase(ctx context.Context, name string, dbc config.DatabaseConnectionConfig) error {
+	primary := app.config.Database.Primary
+	dbc.ConnMaxLifetime = primary.ConnMaxLifetime
+	dbc.ConnMaxIdleTime = primary.ConnMaxIdleTime
+	dbc.BreakerCoolDown = primary.BreakerCoolDown
+
+	driver, err := database.NewFactory().CreateFromConnectionConfig(connectionConfig(dbc, app.applicationName()))
+	if err != nil {
+		return fmt.Errorf("%w: %v", services.ErrInvalidDatabase, err)
+	}
+	if err := app.dbManager.ConnectDriver(ctx, name, driver, database.ConnectPolicy{Required: dbc.Required, NoRetry: true}); err != nil {
+		return err
+	}
+	app.setCircuitBreaker(name, dbc)
+	return nil
+}
+
+// applicationName is the service's name and the instance's hostname, so a
+// DBA can tell in pg_stat_activity which instance holds a connection
+func (app *Application) applicationName() string {
+	instance, _ := os.Hostname()
+	if instance == "" {
+		return app.config.App.Name
+	}
+	return app.config.App.Name + "-" + instance
+}
+
+// setQueryAnnotations prefixes every statement with the route and request
+// it was sent for when DB_QUERY_ANNOTATIONS is set
+func (app *Application) setQueryAnnotations() {
+	app.dbManager.SetQueryAnnotations(app.config.Database.QueryAnnotations)
+}
+
+// setQueryTimeout bounds the statements of every database by
+// DB_QUERY_TIMEOUT. Timeouts are logged with the query's name, never its SQL.
+func (app *Application) setQueryTimeout() {
+	app.dbManager.SetQueryTimeout(database.QueryTimeoutConfig{
+		Timeout: app.config.Database.QueryTimeout,
+		OnTimeout: func(name, query string, elapsed time.Duration) {
+			app.logger.Warn("Database query timed out",
+				logger.Field{Key: "database", Value: name},
+				logger.Field{Key: "query", Value: query},
+				logger.Field{Key: "duration_ms", Value: elapsed.Milliseconds()},
+			)
+		},
+	})
+}
+
+// setRetryPolicy lets repositories retry idempotent operations that failed
+// with a transient error. Retries are logged and counted by reason.
+func (app *Application) setRetryPolicy() {
+	app.dbManager.SetRetryPolicy(database.RetryPolicy{
+		MaxAttempts: app.config.Database.QueryRetryAttempts,
+		BaseDelay:   app.config.Database.QueryRetryBaseDelay,
+		MaxDelay:    app.config.Database.QueryRetryMaxDelay,
+		OnRetry: func(reason string, attempt int, err error) {
+			app.metrics.QueryRetries.WithLabelValues(reason).Inc()
+			app.logger.Warn("Retrying database operation",
+				logger.Field{Key: "reason", Value: reason},
+				logger.Field{Key: "attempt", Value: attempt},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+		},
+	})
+}
+
+// setCircuitBreaker guards the named database with a circuit breaker unless
+// BreakerFailures is zero. State changes are logged and exported as metrics.
+func (app *Application) setCircuitBreaker(name string, dbc config.DatabaseConnectionConfig) {
+	if dbc.BreakerFailures <= 0 {
+		return
+	}
+
+	state := app.metrics.CircuitState.WithLabelValues(name)
+	state.Set(float64(database.CircuitClosed))
+	app.dbManager.SetCircuitBreaker(name, database.NewCircuitBreaker(database.CircuitBreakerConfig{
+		FailureThreshold: dbc.BreakerFailures,
+		CoolDown:         dbc.BreakerCoolDown,
+		OnStateChange: func(from, to database.CircuitState) {
+			state.Set(float64(to))
+			fields := []logger.Field{
+				{Key: "database", Value: name},
+				{Key: "from", Value: from.String()},
+				{Key: "to", Value: to.String()},
+			}
+			if to == database.CircuitOpen {
+				app.logger.Error("Database circuit breaker opened", fields...)
+			} else {
+				app.logger.Info("Database circuit breaker state changed", fields...)
+			}
+		},
+	}))
+}
@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/requestctx"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClaimsKey is the gin context key holding the authenticated token claims
+const ClaimsKey = "auth.claims"
+
+// TokenValidator validates access tokens
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, token string) (*services.TokenClaims, error)
+}
+
+// SessionToucher is implemented by validators that track when the session
+// behind a token was last used
+type SessionToucher interface {
+	TouchSession(ctx context.Context, claims *services.TokenClaims)
+}
+
+// StreamTokenParam is the cookie and query parameter StreamAuth reads tokens from
+const StreamTokenParam = "access_token"
+
+// Auth requires a valid bearer token and stores its claims in the context.
+// Restricted tokens, such as those for changing the password, are refused.
+func Auth(validator TokenValidator) gin.HandlerFunc {
+	return ScopedAuth(validator, "")
+}
+
+// ScopedAuth is Auth for the routes of the task scope restricts tokens to,
+// which also accept tokens restricted to it. Tokens restricted to other
+// tasks are refused.
+func ScopedAuth(validator TokenValidator, scope string) gin.HandlerFunc {
+	return authenticate(validator, scope, func(c *gin.Context) string {
+		return bearerToken(c.GetHeader("Authorization"))
+	})
+}
+
+// PasswordChangeAuth is Auth for the change-password endpoint, which also
+// accepts the restricted tokens issued for expired or flagged passwords
+func PasswordChangeAuth(validator TokenValidator) gin.HandlerFunc {
+	return ScopedAuth(validator, services.ScopePasswordChange)
+}
+
+// ProfileCompletionAuth is Auth for the routes showing and completing the
+// current user's profile, which also accept the restricted tokens issued
+// for incomplete profiles
+func ProfileCompletionAuth(validator TokenValidator) gin.HandlerFunc {
+	return ScopedAuth(validator, services.ScopeProfileCompletion)
+}
+
+// StreamAuth is Auth for EventSource connections, which cannot set headers.
+// The token may also come from the access_token cookie or query parameter.
+func StreamAuth(validator TokenValidator) gin.HandlerFunc {
+	return authenticate(validator, "", streamToken)
+}
+
+// AuthenticateStream checks the token StreamAuth would read and stores its
+// claims like StreamAuth, for handlers that need a caller only for some
+// requests. It does not abort the request.
+func AuthenticateStream(c *gin.Context, validator TokenValidator) (*services.TokenClaims, *errors.AppError) {
+	return checkToken(c, validator, "", streamToken(c))
+}
+
+// streamToken reads the token from the Authorization header, the
+// access_token cookie or the access_token query parameter
+func streamToken(c *gin.Context) string {
+	if token := bearerToken(c.GetHeader("Authorization")); token != "" {
+		return token
+	}
+	if token, err := c.Cookie(StreamTokenParam); err == nil && token != "" {
+		return token
+	}
+	return c.Query(StreamTokenParam)
+}
+
+// authenticate validates the token returned by tokenFrom and stores its
+// claims. Restricted tokens pass only if restricted to scope.
+func authenticate(validator TokenValidator, scope string, tokenFrom func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, appErr := checkToken(c, validator, scope, tokenFrom(c)); appErr != nil {
+			AbortWithAppError(c, appErr)
+			return
+		}
+		c.Next()
+	}
+}
+
+// checkToken validates token and stores its claims and user in the context
+func checkToken(c *gin.Context, validator TokenValidator, scope string, token string) (*services.TokenClaims, *errors.AppError) {
+	if token == "" {
+		return nil, errors.NewUnauthorizedError(i18n.AuthTokenRequired, nil).WithCode(errors.CodeTokenRequired)
+	}
+
+	claims, err := validator.ValidateToken(c.Request.Context(), token)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+
+	// A token only works for the tenant it was issued for
+	if claims.TenantID != requestctx.TenantFrom(c) {
+		return nil, errors.NewUnauthorizedError(i18n.AuthInvalidToken, nil).WithCode(errors.CodeTokenInvalid)
+	}
+
+	if claims.Restricted() && claims.Scope != scope {
+		return nil, tokenError(services.ScopeError(claims.Scope))
+	}
+
+	if toucher, ok := validator.(SessionToucher); ok {
+		toucher.TouchSession(c.Request.Context(), claims)
+	}
+
+	c.Set(ClaimsKey, claims)
+	requestctx.Update(c, func(ctx context.Context) context.Context {
+		return requestctx.WithUser(ctx, requestctx.User{
+			ID:             claims.UserID,
+			Email:          claims.Email,
+			Role:           claims.Role,
+			ImpersonatorID: claims.ImpersonatorID,
+		})
+	})
+	return claims, nil
+}
+
+// tokenError reports why a token was rejected
+func tokenError(err error) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrAccountDeactivated):
+		return errors.NewUnauthorizedError(i18n.AuthAccountDeactivated, err).WithCode(errors.CodeAccountDeactivated)
+	case stderrors.Is(err, services.ErrTokenExpired):
+		return errors.NewUnauthorizedError(i18n.AuthInvalidToken, err).WithCode(errors.CodeTokenExpired)
+	case stderrors.Is(err, services.ErrSessionExpired):
+		return errors.NewUnauthorizedError(i18n.AuthSessionExpired, err).WithCode(errors.CodeSessionExpired)
+	case stderrors.Is(err, services.ErrTokenRevoked):
+		return errors.NewUnauthorizedError(i18n.AuthInvalidToken, err).WithCode(errors.CodeTokenRevoked)
+	case stderrors.Is(err, services.ErrPasswordChangeRequired):
+		return errors.NewForbiddenError(i18n.AuthPasswordChangeRequired, err).WithCode(errors.CodePasswordChangeRequired)
+	case stderrors.Is(err, services.ErrProfileIncomplete):
+		return errors.NewForbiddenError(i18n.AuthProfileIncomplete, err).WithCode(errors.CodeProfileIncomplete)
+	default:
+		return errors.NewUnauthorizedError(i18n.AuthInvalidToken, err).WithCode(errors.CodeTokenInvalid)
+	}
+}
+
+// RequireRole allows the request only if the authenticated user has one of roles.
+// It must be registered after Auth.
+func RequireRole(roles ...models.UserRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+			AbortWithAppError(c, appErr)
+			return
+		}
+
+		for _, role := range roles {
+			if claims.Role == string(role) {
+				c.Next()
+				return
+			}
+		}
+
+		appErr := errors.NewForbiddenError(i18n.AuthInsufficientPermissions, nil).WithCode(errors.CodeInsufficientPermissions)
+		AbortWithAppError(c, appErr)
+	}
+}
+
+// RequireOrgMember allows the request only if the authenticated user belongs to
+// the organization named by the route parameter param. Global admins always pass.
+// It must be registered after Auth.
+func RequireOrgMember(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+			AbortWithAppError(c, appErr)
+			return
+		}
+
+		if claims.Role == string(models.RoleAdmin) {
+			c.Next()
+			return
+		}
+
+		orgID := c.Param(param)
+		for _, id := range claims.OrgIDs {
+			if id == orgID {
+				c.Next()
+				return
+			}
+		}
+
+		appErr := errors.NewForbiddenError(i18n.AuthNotOrgMember, nil).WithCode(errors.CodeNotOrganizationMember)
+		AbortWithAppError(c, appErr)
+	}
+}
+
+// NotImpersonating rejects requests made with an impersonation token, for
+// routes such as password and role changes that an admin may not perform
+// as someone else. It must be registered after Auth.
+func NotImpersonating() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, ok := GetClaims(c); ok && claims.Impersonating() {
+			appErr := errors.NewForbiddenError(i18n.AuthImpersonationForbidden, nil).WithCode(errors.CodeImpersonationForbidden)
+			AbortWithAppError(c, appErr)
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetImpersonator returns the ID of the admin acting through an
+// impersonation token; GetClaims holds the impersonated user
+func GetImpersonator(c *gin.Context) (string, bool) {
+	claims, ok := GetClaims(c)
+	if !ok || !claims.Impersonating() {
+		return "", false
+	}
+	return claims.ImpersonatorID, true
+}
+
+// GetClaims returns the authenticated token claims, if any
+func GetClaims(c *gin.Context) (*services.TokenClaims, bool) {
+	value, exists := c.Get(ClaimsKey)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*services.TokenClaims)
+	return claims, ok
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(header string) string {
+	const prefix = "bearer "
+	if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return strings.TrimSpace(header[len(prefix):])
+	}
+	return ""
+}
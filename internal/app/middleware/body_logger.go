@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultBodyLogLimit is how much of each body BodyLogger keeps by default
+const DefaultBodyLogLimit = 8 << 10
+
+// TruncatedMarker is appended to logged bodies that exceeded the limit
+const TruncatedMarker = "...[truncated]"
+
+// RedactedValue replaces sensitive values in logged bodies
+const RedactedValue = "REDACTED"
+
+// sensitiveJSONValue matches string values of keys that look like credentials.
+// The closing quote is optional so values cut off by truncation are hidden too.
+var sensitiveJSONValue = regexp.MustCompile(`(?i)("[^"]*(?:password|token|secret|authorization)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+
+// BodyLogger logs JSON and form request and response bodies at debug level.
+// Each body is cut to maxBytes and credentials in it are redacted. Handlers
+// still receive the full request body and responses are written through
+// unchanged, so streaming keeps working.
+func BodyLogger(log logger.Logger, maxBytes int) gin.HandlerFunc {
+	if maxBytes <= 0 {
+		maxBytes = DefaultBodyLogLimit
+	}
+
+	return func(c *gin.Context) {
+		var requestBody string
+		if c.Request.Body != nil && loggableContentType(c.GetHeader("Content-Type")) {
+			requestBody = captureRequestBody(c, maxBytes)
+		}
+
+		writer := &bodyLogWriter{ResponseWriter: c.Writer, limit: maxBytes}
+		c.Writer = writer
+
+		c.Next()
+
+		responseBody := ""
+		if writer.capturing {
+			responseBody = formatBody(writer.Header().Get("Content-Type"), writer.buf.Bytes(), writer.truncated)
+		}
+		if requestBody == "" && responseBody == "" {
+			return
+		}
+
+		log.Debug("HTTP bodies",
+			logger.Field{Key: "method", Value: c.Request.Method},
+			logger.Field{Key: "path", Value: c.Request.URL.Path},
+			logger.Field{Key: "status", Value: writer.Status()},
+			logger.Field{Key: "request_body", Value: requestBody},
+			logger.Field{Key: "response_body", Value: responseBody},
+		)
+	}
+}
+
+// captureRequestBody reads up to limit bytes for the log and puts them back
+// in front of the rest of the body for the handlers
+func captureRequestBody(c *gin.Context, limit int) string {
+	body := c.Request.Body
+	prefix, err := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+	c.Request.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(prefix), body), Closer: body}
+	if err != nil {
+		return ""
+	}
+
+	truncated := len(prefix) > limit
+	if truncated {
+		prefix = prefix[:limit]
+	}
+	return formatBody(c.GetHeader("Content-Type"), prefix, truncated)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyLogWriter copies the first limit bytes of a loggable response
+type bodyLogWriter struct {
+	gin.ResponseWriter
+
+	limit     int
+	buf       bytes.Buffer
+	checked   bool
+	capturing bool
+	truncated bool
+}
+
+func (w *bodyLogWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *bodyLogWriter) capture(data []byte) {
+	// The content type is known once the handler starts writing
+	if !w.checked {
+		w.checked = true
+		w.capturing = loggableContentType(w.Header().Get("Content-Type"))
+	}
+	if !w.capturing || w.truncated {
+		return
+	}
+
+	if room := w.limit - w.buf.Len(); len(data) > room {
+		w.buf.Write(data[:room])
+		w.truncated = true
+		return
+	}
+	w.buf.Write(data)
+}
+
+// loggableContentType reports whether bodies of contentType may be logged.
+// Only JSON and URL-encoded forms are; multipart uploads never are.
+func loggableContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" ||
+		strings.HasSuffix(mediaType, "+json") ||
+		mediaType == "application/x-www-form-urlencoded"
+}
+
+// formatBody redacts credentials in body and marks it when truncated
+func formatBody(contentType string, body []byte, truncated bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var redacted string
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		redacted = redactForm(string(body))
+	} else {
+		redacted = RedactJSON(string(body))
+	}
+
+	if truncated {
+		redacted += TruncatedMarker
+	}
+	return redacted
+}
+
+// RedactJSON replaces the string values of password, token, secret and
+// authorization keys, at any depth, with RedactedValue. It works on
+// truncated documents too.
+func RedactJSON(body string) string {
+	return sensitiveJSONValue.ReplaceAllString(body, `${1}"`+RedactedValue+`"`)
+}
+
+// redactForm replaces the values of sensitive form fields
+func redactForm(body string) string {
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return ""
+	}
+	for key := range values {
+		if sensitiveKey(key) {
+			values.Set(key, RedactedValue)
+		}
+	}
+	return values.Encode()
+}
+
+// sensitiveKey reports whether a field name looks like it holds a credential
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"password", "token", "secret", "authorization"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	stderrors "errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultConcurrencyGroup labels the metrics of routes without an override
+const defaultConcurrencyGroup = "default"
+
+// errServerBusy is the cause of requests shed by ConcurrencyLimit
+var errServerBusy = stderrors.New("too many requests in flight")
+
+// ConcurrencyConfig sets how many requests are handled at once
+type ConcurrencyConfig struct {
+	// Limit applies to every route without an override; zero disables it
+	Limit int
+
+	// Overrides maps route template prefixes, such as "/api/v1/users/export",
+	// to their own limit, counted apart from Limit. The longest matching
+	// prefix wins; zero leaves the routes unlimited.
+	Overrides map[string]int
+
+	// Queue is how many requests may wait for each limit; the rest are shed
+	Queue int
+
+	// Wait is how long a queued request waits for a slot before it is shed
+	Wait time.Duration
+
+	// Exempt lists route templates that are never limited, such as health
+	// checks and long-lived streams
+	Exempt []string
+}
+
+// ConcurrencyMetrics are the collectors ConcurrencyLimit reports to, labelled
+// by group: the override prefix, or "default"
+type ConcurrencyMetrics struct {
+	InFlight *prometheus.GaugeVec
+	Shed     *prometheus.CounterVec
+}
+
+// concurrencyLimiter counts the requests of one group
+type concurrencyLimiter struct {
+	group  string
+	slots  chan struct{}
+	queue  int64
+	queued atomic.Int64
+}
+
+func newConcurrencyLimiter(group string, limit, queue int) *concurrencyLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &concurrencyLimiter{group: group, slots: make(chan struct{}, limit), queue: int64(queue)}
+}
+
+// acquire takes a slot, waiting up to wait in the queue if there is room in
+// it. It returns why the request is shed, or "" once it holds a slot.
+func (l *concurrencyLimiter) acquire(c *gin.Context, wait time.Duration) string {
+	select {
+	case l.slots <- struct{}{}:
+		return ""
+	default:
+	}
+
+	if l.queued.Add(1) > l.queue {
+		l.queued.Add(-1)
+		return "queue_full"
+	}
+	defer l.queued.Add(-1)
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return ""
+	case <-timer.C:
+		return "timeout"
+	case <-c.Request.Context().Done():
+		return "canceled"
+	}
+}
+
+func (l *concurrencyLimiter) release() {
+	<-l.slots
+}
+
+// ConcurrencyLimit caps the requests in flight per group of routes. Requests
+// over the cap wait in a short queue; when it is full or the wait runs out
+// they are shed with 503 SERVER_BUSY and a Retry-After header, so a spike
+// fails fast instead of saturating the database pool.
+func ConcurrencyLimit(cfg ConcurrencyConfig, m ConcurrencyMetrics) gin.HandlerFunc {
+	exempt := make(map[string]bool, len(cfg.Exempt))
+	for _, route := range cfg.Exempt {
+		exempt[route] = true
+	}
+	global := newConcurrencyLimiter(defaultConcurrencyGroup, cfg.Limit, cfg.Queue)
+	overrides := make(map[string]*concurrencyLimiter, len(cfg.Overrides))
+	for prefix, limit := range cfg.Overrides {
+		overrides[prefix] = newConcurrencyLimiter(prefix, limit, cfg.Queue)
+	}
+	retryAfter := strconv.Itoa(int(math.Max(1, math.Ceil(cfg.Wait.Seconds()))))
+
+	// limiterFor returns the limiter of a route template, nil if it is unlimited
+	limiterFor := func(route string) *concurrencyLimiter {
+		limiter, matched := global, -1
+		for prefix, override := range overrides {
+			if strings.HasPrefix(route, prefix) && len(prefix) > matched {
+				limiter, matched = override, len(prefix)
+			}
+		}
+		return limiter
+	}
+
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if exempt[route] {
+			c.Next()
+			return
+		}
+		limiter := limiterFor(route)
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		if reason := limiter.acquire(c, cfg.Wait); reason != "" {
+			m.Shed.WithLabelValues(limiter.group, reason).Inc()
+			c.Header("Retry-After", retryAfter)
+			appErr := errors.NewAppError(http.StatusServiceUnavailable, i18n.RequestServerBusy, errServerBusy).WithCode(errors.CodeServerBusy)
+			AbortWithAppError(c, appErr)
+			return
+		}
+		inFlight := m.InFlight.WithLabelValues(limiter.group)
+		inFlight.Inc()
+		defer func() {
+			inFlight.Dec()
+			limiter.release()
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bytes"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfirmTokenHeader carries the token confirming a destructive request
+const ConfirmTokenHeader = "X-Confirm-Token"
+
+// maxConfirmedBodyBytes bounds the body read to identify a request
+const maxConfirmedBodyBytes = 1 << 20
+
+// ConfirmRule describes a destructive route, run only once confirmed
+type ConfirmRule struct {
+	// Summarize describes what the request would affect. A nil summary
+	// means the request destroys nothing, and it runs unconfirmed.
+	Summarize func(c *gin.Context) (*services.ConfirmationSummary, *errors.AppError)
+}
+
+// Confirm runs destructive requests in two steps. Without X-Confirm-Token
+// the request is answered with 202, a summary of what it would affect and
+// a token; repeating the same request, with the same body, with the token
+// runs it. Tokens are single-use and bound to the caller. API tokens whose
+// scope confirmations exempts skip both steps; a nil confirmations turns
+// the check off. It must be registered after Auth.
+func Confirm(confirmations *services.Confirmations, rule ConfirmRule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if confirmations == nil || !ok || confirmations.Exempt(claims.APIScope) {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxConfirmedBodyBytes))
+		if err != nil {
+			AbortWithAppError(c, errors.NewBadRequestError(i18n.RequestInvalidBody, err))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		actor := strings.Join([]string{claims.UserID, claims.ImpersonatorID, claims.TenantID}, "|")
+		digest := services.ConfirmationDigest(c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, body)
+
+		if token := c.GetHeader(ConfirmTokenHeader); token != "" {
+			err := confirmations.Redeem(ctx, token, actor, digest)
+			switch {
+			case err == nil:
+				c.Next()
+			case stderrors.Is(err, services.ErrConfirmationInvalid):
+				AbortWithAppError(c, errors.NewConflictError(i18n.ConfirmationInvalid, err).WithCode(errors.CodeConfirmationInvalid))
+			case stderrors.Is(err, services.ErrConfirmationMismatch):
+				AbortWithAppError(c, errors.NewConflictError(i18n.ConfirmationMismatch, err).WithCode(errors.CodeConfirmationMismatch))
+			default:
+				AbortWithAppError(c, errors.NewInternalServerError(i18n.ConfirmationFailed, err))
+			}
+			return
+		}
+
+		summary, appErr := rule.Summarize(c)
+		if appErr != nil {
+			AbortWithAppError(c, appErr)
+			return
+		}
+		if summary == nil {
+			c.Next()
+			return
+		}
+		challenge, err := confirmations.Issue(ctx, actor, digest, summary)
+		if err != nil {
+			AbortWithAppError(c, errors.NewInternalServerError(i18n.ConfirmationFailed, err))
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusAccepted, gin.H{
+			"message": "Repeat the request with the " + ConfirmTokenHeader + " header to confirm it",
+			"data":    challenge,
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	stderrors "errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CircuitBreakers looks up the circuit breaker guarding a database
+type CircuitBreakers interface {
+	CircuitBreaker(name string) *database.CircuitBreaker
+}
+
+// DatabaseAvailable fails requests fast with 503 and a Retry-After header
+// while the named database's circuit breaker is open, instead of letting
+// every handler wait for the database to time out
+func DatabaseAvailable(breakers CircuitBreakers, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if breakers == nil {
+			c.Next()
+			return
+		}
+		breaker := breakers.CircuitBreaker(name)
+		if breaker == nil {
+			c.Next()
+			return
+		}
+
+		if retryAfter := breaker.RetryAfter(); retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			appErr := errors.NewAppError(http.StatusServiceUnavailable, "Service temporarily unavailable", database.ErrCircuitOpen)
+			c.AbortWithStatusJSON(appErr.Status, gin.H{"error": appErr.Message})
+			return
+		}
+		c.Next()
+	}
+}
+
+// DatabaseSession gives each request a database.Session, so its reads go
+// to the primary database rather than a read replica once it wrote. With a
+// hints store and a window, a user whose request wrote also reads from the
+// primary in their requests over the next window, on every instance
+// sharing the store. Without them, each request starts on the replica.
+func DatabaseSession(hints cache.Store, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sticky := func() bool {
+			if hints == nil || window <= 0 {
+				return false
+			}
+			claims, ok := GetClaims(c)
+			if !ok {
+				return false
+			}
+			_, err := hints.Get(c.Request.Context(), readYourWritesKey(claims.UserID))
+			return err == nil
+		}
+		session := database.NewSession(sticky)
+		c.Request = c.Request.WithContext(database.WithSession(c.Request.Context(), session))
+
+		c.Next()
+
+		if !session.Written() || hints == nil || window <= 0 {
+			return
+		}
+		if claims, ok := GetClaims(c); ok {
+			_ = hints.Set(c.Request.Context(), readYourWritesKey(claims.UserID), []byte("1"), window)
+		}
+	}
+}
+
+// readYourWritesKey is the hints key marking that the user wrote recently
+func readYourWritesKey(userID string) string {
+	return "read_your_writes:" + userID
+}
+
+// databaseError answers 501 OPERATION_NOT_SUPPORTED for errors caused by an
+// operation the database driver cannot perform, and 504 QUERY_TIMEOUT for
+// queries that ran out of time, whichever error the handler wrapped them in
+func databaseError(appErr *errors.AppError) *errors.AppError {
+	if appErr.Status != http.StatusNotImplemented && stderrors.Is(appErr.Err, database.ErrOperationNotSupported) {
+		return errors.NewNotSupportedError("", appErr.Err)
+	}
+	if appErr.Status != http.StatusGatewayTimeout && stderrors.Is(appErr.Err, database.ErrQueryTimeout) {
+		return errors.NewQueryTimeoutError("", appErr.Err)
+	}
+	return appErr
+}
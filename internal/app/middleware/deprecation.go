@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers announcing the retirement of an API version
+const (
+	DeprecationHeader = "Deprecation"
+	SunsetHeader      = "Sunset"
+)
+
+// Deprecation announces that the routes it guards are deprecated since
+// deprecatedAt, in the Deprecation header (RFC 9745), and stop being served
+// at sunset, in the Sunset header (RFC 8594). Each header is left out while
+// its time is zero, so routes are untouched until a retirement is planned.
+func Deprecation(deprecatedAt, sunset time.Time) gin.HandlerFunc {
+	var deprecation, sunsetDate string
+	if !deprecatedAt.IsZero() {
+		deprecation = "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	}
+	if !sunset.IsZero() {
+		sunsetDate = sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(c *gin.Context) {
+		// Set before the handler runs, so they reach error and cached responses too
+		if deprecation != "" {
+			c.Header(DeprecationHeader, deprecation)
+		}
+		if sunsetDate != "" {
+			c.Header(SunsetHeader, sunsetDate)
+		}
+
+		c.Next()
+	}
+}
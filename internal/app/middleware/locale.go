@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LangParam is the query parameter that overrides Accept-Language
+const LangParam = "lang"
+
+// Locale picks the locale messages are answered in from the lang query
+// parameter or the Accept-Language header and stores it in the request context
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		preferences := c.Query(LangParam)
+		if preferences == "" {
+			preferences = c.GetHeader("Accept-Language")
+		}
+
+		locale := i18n.Match(preferences)
+		c.Request = c.Request.WithContext(i18n.WithLocale(c.Request.Context(), locale))
+		c.Header("Content-Language", locale)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// ErrorCodeKey is the gin context key holding the code of the error a
+// request was answered with, for the request log
+const ErrorCodeKey = "error.code"
+
+// ErrorDetail is one field of a rendered validation error
+type ErrorDetail struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrorBody is the error envelope of a response:
+//
+//	{"error": {"code": "USER_NOT_FOUND", "message": "User not found"}}
+//
+// Code is the registered error code, which clients can branch on. Details
+// name the untranslated message key of each field as their code. Stack is
+// only set by Recovery in debug mode.
+type ErrorBody struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Details []ErrorDetail `json:"details,omitempty"`
+	Stack   []string      `json:"stack,omitempty"`
+}
+
+// RespondError writes appErr as an error envelope in the request's locale,
+// or as a problem document when the Accept header prefers one, and records
+// it for the request log
+func RespondError(c *gin.Context, appErr *errors.AppError) {
+	appErr = databaseError(appErr)
+	writeError(c, appErr.Status, renderError(c, appErr), false)
+}
+
+// AbortWithAppError is RespondError for middleware; later handlers are skipped
+func AbortWithAppError(c *gin.Context, appErr *errors.AppError) {
+	appErr = databaseError(appErr)
+	writeError(c, appErr.Status, renderError(c, appErr), true)
+}
+
+func renderError(c *gin.Context, appErr *errors.AppError) ErrorBody {
+	c.Set(ErrorCodeKey, string(appErr.Code))
+	_ = c.Error(appErr)
+
+	locale := i18n.Locale(c.Request.Context())
+	body := ErrorBody{
+		Code:    string(appErr.Code),
+		Message: i18n.Translate(locale, appErr.Message, appErr.Params),
+	}
+	for _, detail := range appErr.Details {
+		body.Details = append(body.Details, ErrorDetail{
+			Field:   detail.Field,
+			Code:    detail.Message,
+			Message: i18n.Translate(locale, detail.Message, detail.Params),
+		})
+	}
+	return body
+}
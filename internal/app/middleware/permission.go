@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionChecker resolves whether a role has been granted a permission
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, role, permission string) (bool, error)
+}
+
+// RequirePermission allows the request only if the authenticated user's role
+// has been granted permission. Lookup errors deny the request. It must be
+// registered after Auth.
+func RequirePermission(checker PermissionChecker, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			appErr := errors.NewUnauthorizedError(i18n.AuthRequired, nil).WithCode(errors.CodeAuthenticationRequired)
+			AbortWithAppError(c, appErr)
+			return
+		}
+
+		allowed, err := checker.HasPermission(c.Request.Context(), claims.Role, permission)
+		if err != nil {
+			appErr := errors.NewInternalServerError(i18n.AuthPermissionsFailed, err)
+			AbortWithAppError(c, appErr)
+			return
+		}
+		if !allowed {
+			appErr := errors.NewForbiddenError(i18n.AuthInsufficientPermissions, nil).WithCode(errors.CodeInsufficientPermissions)
+			AbortWithAppError(c, appErr)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyChecker lists the policies whose current version a user has yet to accept
+type PolicyChecker interface {
+	Pending(ctx context.Context, userID string) ([]services.PendingPolicy, error)
+}
+
+// PoliciesAccepted refuses requests with 403 POLICY_ACCEPTANCE_REQUIRED until
+// the authenticated user accepted the current version of every policy. A nil
+// checker lets every request through, and so do impersonation tokens, since
+// an admin cannot accept for the user. It must be registered after Auth.
+func PoliciesAccepted(checker PolicyChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if checker == nil || !ok || claims.Impersonating() {
+			c.Next()
+			return
+		}
+
+		pending, err := checker.Pending(c.Request.Context(), claims.UserID)
+		if err != nil {
+			AbortWithAppError(c, errors.NewInternalServerError(i18n.PolicyPendingFailed, err))
+			return
+		}
+		if len(pending) > 0 {
+			policies := make([]string, len(pending))
+			for i, p := range pending {
+				policies[i] = p.Policy
+			}
+			appErr := errors.NewForbiddenError(i18n.PolicyAcceptanceRequired, nil).
+				WithCode(errors.CodePolicyAcceptanceRequired).
+				WithParams(errors.Params{"policies": strings.Join(policies, ", ")})
+			AbortWithAppError(c, appErr)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/requestctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem documents
+const ProblemContentType = "application/problem+json"
+
+// DefaultProblemTypeBase prefixes the code of an error to form its problem
+// type URI, pointing into the error code catalogue
+const DefaultProblemTypeBase = "/api/v1/error-codes#"
+
+// Problem is an error rendered as an RFC 7807 problem document:
+//
+//	{"type": "/api/v1/error-codes#USER_NOT_FOUND", "title": "The user does not exist",
+//	 "status": 404, "detail": "User not found", "instance": "/api/v1/users/42",
+//	 "code": "USER_NOT_FOUND", "request_id": "..."}
+//
+// Title is the catalogue description of the code and Detail the translated
+// message. Code, RequestID, Details and Stack are extension members carrying
+// what the error envelope has.
+type Problem struct {
+	Type      string        `json:"type"`
+	Title     string        `json:"title"`
+	Status    int           `json:"status"`
+	Detail    string        `json:"detail,omitempty"`
+	Instance  string        `json:"instance,omitempty"`
+	Code      string        `json:"code"`
+	RequestID string        `json:"request_id,omitempty"`
+	Details   []ErrorDetail `json:"details,omitempty"`
+	Stack     []string      `json:"stack,omitempty"`
+}
+
+// problemTypes maps error codes to their problem type URI. Codes without
+// an entry get the base followed by the code.
+var problemTypes = struct {
+	mu   sync.RWMutex
+	base string
+	uris map[errors.Code]string
+}{base: DefaultProblemTypeBase, uris: map[errors.Code]string{}}
+
+// SetProblemTypeBase sets the prefix of derived problem type URIs, such as
+// https://docs.example.com/errors/. An empty base restores
+// DefaultProblemTypeBase.
+func SetProblemTypeBase(base string) {
+	if base == "" {
+		base = DefaultProblemTypeBase
+	}
+	problemTypes.mu.Lock()
+	defer problemTypes.mu.Unlock()
+	problemTypes.base = base
+}
+
+// RegisterProblemType gives code its own problem type URI instead of the
+// derived one
+func RegisterProblemType(code errors.Code, uri string) {
+	problemTypes.mu.Lock()
+	defer problemTypes.mu.Unlock()
+	problemTypes.uris[code] = uri
+}
+
+// ProblemType returns the problem type URI of code. Codes missing from the
+// catalogue are about:blank, the RFC's type for problems without one.
+func ProblemType(code errors.Code) string {
+	if !errors.Registered(code) {
+		return "about:blank"
+	}
+	problemTypes.mu.RLock()
+	defer problemTypes.mu.RUnlock()
+	if uri, ok := problemTypes.uris[code]; ok {
+		return uri
+	}
+	return problemTypes.base + string(code)
+}
+
+// newProblem turns a rendered error envelope into the equivalent problem
+// document
+func newProblem(c *gin.Context, status int, body ErrorBody) Problem {
+	code := errors.Code(body.Code)
+	title := errors.Describe(code)
+	if title == "" {
+		title = http.StatusText(status)
+	}
+	return Problem{
+		Type:      ProblemType(code),
+		Title:     title,
+		Status:    status,
+		Detail:    body.Message,
+		Instance:  c.Request.URL.Path,
+		Code:      body.Code,
+		RequestID: requestctx.RequestIDFrom(c),
+		Details:   body.Details,
+		Stack:     body.Stack,
+	}
+}
+
+// writeError writes body as the error envelope, or as a problem document
+// when the request prefers one, aborting later handlers if abort is set
+func writeError(c *gin.Context, status int, body ErrorBody, abort bool) {
+	c.Writer.Header().Add("Vary", "Accept")
+	var payload interface{} = gin.H{"error": body}
+	if prefersProblem(c.GetHeader("Accept")) {
+		// The JSON renderer keeps a content type that is already set
+		c.Header("Content-Type", ProblemContentType)
+		payload = newProblem(c, status, body)
+	}
+	if abort {
+		c.AbortWithStatusJSON(status, payload)
+		return
+	}
+	c.JSON(status, payload)
+}
+
+// prefersProblem reports whether the Accept header ranks problem documents
+// above plain JSON. On equal quality the more specific match wins, so
+// "application/problem+json, */*" asks for a problem document while a
+// missing header or "application/json" keeps the envelope.
+func prefersProblem(accept string) bool {
+	if accept == "" {
+		return false
+	}
+	problemQ, problemSpec := acceptQuality(accept, "application", "problem+json")
+	jsonQ, jsonSpec := acceptQuality(accept, "application", "json")
+	if problemQ <= 0 {
+		return false
+	}
+	return problemQ > jsonQ || (problemQ == jsonQ && problemSpec > jsonSpec)
+}
+
+// acceptQuality returns the quality the Accept header gives the media type
+// typ/subtype, taken from its most specific matching range, and how
+// specific that range is: 0 for */*, 1 for typ/* and 2 for an exact match.
+// A type no range matches has quality 0 and specificity -1.
+func acceptQuality(accept, typ, subtype string) (float64, int) {
+	quality, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		rangeType, rangeSubtype, _ := strings.Cut(mediaType, "/")
+		spec := -1
+		switch {
+		case rangeType == typ && rangeSubtype == subtype:
+			spec = 2
+		case rangeType == typ && rangeSubtype == "*":
+			spec = 1
+		case rangeType == "*" && rangeSubtype == "*":
+			spec = 0
+		}
+		if spec <= specificity {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+				q = parsed
+			}
+		}
+		quality, specificity = q, spec
+	}
+	return quality, specificity
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaCounter counts a user's requests against their monthly quota
+type QuotaCounter interface {
+	Consume(ctx context.Context, userID string) (*services.QuotaStatus, error)
+}
+
+// Quota counts each request against the authenticated user's monthly
+// quota and refuses it with 429 QUOTA_EXCEEDED once the quota is spent.
+// Limited users get X-Quota-Remaining and X-Quota-Reset (Unix seconds) on
+// every response. Tokens of interactive login sessions are exempt unless
+// sessions is true, and impersonation tokens always are, so an admin does
+// not spend the user's quota. A nil counter lets every request through. It
+// must be registered after Auth.
+func Quota(counter QuotaCounter, sessions bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if counter == nil || !ok || claims.Impersonating() || (claims.SessionID != "" && !sessions) {
+			c.Next()
+			return
+		}
+
+		status, err := counter.Consume(c.Request.Context(), claims.UserID)
+		if status != nil && status.Remaining != nil {
+			c.Header("X-Quota-Remaining", strconv.FormatInt(*status.Remaining, 10))
+			c.Header("X-Quota-Reset", strconv.FormatInt(status.ResetAt.Unix(), 10))
+		}
+		switch {
+		case stderrors.Is(err, services.ErrQuotaExceeded):
+			retryAfter := max(int64(time.Until(status.ResetAt).Seconds()), 1)
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			appErr := errors.NewAppError(http.StatusTooManyRequests, i18n.QuotaExceeded, err).
+				WithCode(errors.CodeQuotaExceeded).
+				WithParams(errors.Params{"limit": strconv.FormatInt(*status.Limit, 10), "reset": status.ResetAt.Format(time.RFC3339)})
+			AbortWithAppError(c, appErr)
+			return
+		case err != nil:
+			AbortWithAppError(c, errors.NewInternalServerError(i18n.QuotaCheckFailed, err))
+			return
+		}
+
+		c.Next()
+	}
+}
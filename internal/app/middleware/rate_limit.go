@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/ratelimit"
+	"BackofficeGoService/internal/pkg/requestctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Rate limit response headers
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+	RateLimitWarningHeader   = "X-RateLimit-Warning"
+)
+
+// RateLimitWouldThrottle is the warning of observed requests over the limit
+const RateLimitWouldThrottle = "would-throttle"
+
+// RateLimit allows each client IP limit.Requests per limit.Window on the
+// route, counted by limiter. Responses carry the X-RateLimit-Limit,
+// -Remaining and -Reset headers, the latter in seconds. Under
+// ratelimit.Enforce, further requests are refused with 429 RATE_LIMITED and
+// a Retry-After header until the window ends. Under ratelimit.Observe they
+// are let through with an X-RateLimit-Warning header, logged and counted in
+// wouldBlock by route template. Clients the limiter blocked are refused
+// the same way, in either mode, until their block ends. If the cache fails,
+// requests are let through. A nil limiter or a limit of zero lets every
+// request through.
+func RateLimit(limiter *ratelimit.Limiter, limit ratelimit.Limit, wouldBlock *prometheus.CounterVec) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil || limit.Requests <= 0 || limit.Window <= 0 {
+			c.Next()
+			return
+		}
+
+		if until, err := limiter.Blocked(c.Request.Context(), c.ClientIP()); err == nil && !until.IsZero() {
+			abortRateLimited(c, strconv.FormatInt(max(int64(time.Until(until).Seconds()), 1), 10))
+			return
+		}
+
+		usage, err := limiter.Take(c.Request.Context(), c.Request.Method+" "+c.FullPath(), c.ClientIP(), limit)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		reset := strconv.FormatInt(max(int64(time.Until(usage.Reset).Seconds()), 1), 10)
+		c.Header(RateLimitLimitHeader, strconv.Itoa(usage.Limit))
+		c.Header(RateLimitRemainingHeader, strconv.FormatInt(usage.Remaining(), 10))
+		c.Header(RateLimitResetHeader, reset)
+		if !usage.Exceeded() {
+			c.Next()
+			return
+		}
+
+		if limit.Mode == ratelimit.Observe {
+			c.Header(RateLimitWarningHeader, RateLimitWouldThrottle)
+			requestctx.LoggerFrom(c).Warn("Request over rate limit let through",
+				logger.Field{Key: "route", Value: c.FullPath()},
+				logger.Field{Key: "client_ip", Value: c.ClientIP()},
+				logger.Field{Key: "requests", Value: usage.Count},
+				logger.Field{Key: "limit", Value: usage.Limit},
+			)
+			if wouldBlock != nil {
+				wouldBlock.WithLabelValues(c.FullPath()).Inc()
+			}
+			c.Next()
+			return
+		}
+
+		abortRateLimited(c, reset)
+	}
+}
+
+// abortRateLimited refuses the request with 429 RATE_LIMITED, to be retried
+// after retry seconds
+func abortRateLimited(c *gin.Context, retry string) {
+	c.Header("Retry-After", retry)
+	appErr := errors.NewAppError(http.StatusTooManyRequests, i18n.RateLimited, nil).
+		WithCode(errors.CodeRateLimited).
+		WithParams(errors.Params{"retry": retry})
+	AbortWithAppError(c, appErr)
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/readonly"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadOnlyConfig configures the ReadOnly middleware
+type ReadOnlyConfig struct {
+	// Exempt lists route templates, such as /api/v1/auth/refresh, that keep
+	// accepting writes in read-only mode
+	Exempt []string
+}
+
+// ReadOnly answers 503 SERVICE_READ_ONLY to POST, PUT, PATCH and DELETE
+// requests while sw is on, except on exempt routes. Requests it lets
+// through may write to the database, which refuses other writes made while
+// read-only (readonly.Allow). A nil switch lets every request through.
+func ReadOnly(sw *readonly.Switch, cfg ReadOnlyConfig) gin.HandlerFunc {
+	exempt := make(map[string]bool, len(cfg.Exempt))
+	for _, route := range cfg.Exempt {
+		exempt[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if sw == nil {
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		if writes(c.Request.Method) && !exempt[c.FullPath()] && sw.Enabled(ctx) {
+			appErr := errors.NewAppError(http.StatusServiceUnavailable, i18n.ReadOnlyActive, readonly.ErrReadOnly).WithCode(errors.CodeServiceReadOnly)
+			AbortWithAppError(c, appErr)
+			return
+		}
+		c.Request = c.Request.WithContext(readonly.Allow(ctx))
+		c.Next()
+	}
+}
+
+// writes reports whether requests with method change state
+func writes(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
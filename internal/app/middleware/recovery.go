@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	stderrors "errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"syscall"
+
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/requestctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RequestIDHeader carries the ID a proxy or client assigned to the request
+const RequestIDHeader = "X-Request-ID"
+
+// Recovery turns a panic in a later handler into a 500 error response. The
+// panic and its stack are logged at error level and counted in counter,
+// labelled by route template. The stack is only added to the response when
+// showStack is set. Panics caused by the client going away are logged at warn
+// level without a stack, and nothing is written to the dead connection.
+func Recovery(log logger.Logger, showStack bool, counter *prometheus.CounterVec) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			err, ok := recovered.(error)
+			if !ok {
+				err = fmt.Errorf("%v", recovered)
+			}
+			requestID := requestctx.RequestIDFrom(c)
+			if requestID == "" {
+				requestID = c.GetHeader(RequestIDHeader)
+			}
+			fields := []logger.Field{
+				{Key: "request_id", Value: requestID},
+				{Key: "method", Value: c.Request.Method},
+				{Key: "path", Value: c.Request.URL.Path},
+				{Key: "error", Value: err.Error()},
+			}
+
+			if brokenPipe(err) {
+				log.Warn("Client connection lost", fields...)
+				_ = c.Error(err)
+				c.Abort()
+				return
+			}
+
+			route := c.FullPath()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			counter.WithLabelValues(route).Inc()
+
+			stack := debug.Stack()
+			log.Error("Panic recovered", append(fields, logger.Field{Key: "stack", Value: string(stack)})...)
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			appErr := errors.NewInternalServerError("", err)
+			body := renderError(c, appErr)
+			if showStack {
+				body.Stack = strings.Split(strings.TrimSpace(string(stack)), "\n")
+			}
+			writeError(c, appErr.Status, body, true)
+		}()
+
+		c.Next()
+	}
+}
+
+// brokenPipe reports whether err comes from writing to a closed connection
+func brokenPipe(err error) bool {
+	return stderrors.Is(err, syscall.EPIPE) || stderrors.Is(err, syscall.ECONNRESET)
+}
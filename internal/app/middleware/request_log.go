@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/requestctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestLogSink takes the entries of RequestLog without blocking, reporting
+// whether it kept them
+type RequestLogSink interface {
+	Enqueue(entry *models.RequestLog) bool
+}
+
+// RequestLogConfig selects the requests RequestLog records
+type RequestLogConfig struct {
+	// Methods lists the methods recorded, such as POST
+	Methods []string
+
+	// Routes lists route template prefixes, such as "/api/v1/admin"; requests
+	// matching no route are never recorded
+	Routes []string
+
+	// BodyLimit is how much of each request body is kept
+	BodyLimit int
+
+	// SampleRate is the share of selected requests recorded, from 0 to 1
+	SampleRate float64
+
+	// SampleRates maps route template prefixes to their own sample rate. The
+	// longest matching prefix wins.
+	SampleRates map[string]float64
+}
+
+// sampleRateFor returns the sample rate of a route template
+func (cfg RequestLogConfig) sampleRateFor(route string) float64 {
+	rate, matched := cfg.SampleRate, -1
+	for prefix, override := range cfg.SampleRates {
+		if strings.HasPrefix(route, prefix) && len(prefix) > matched {
+			rate, matched = override, len(prefix)
+		}
+	}
+	return rate
+}
+
+// selects reports whether a request to route with method is recorded,
+// sampling the ones cfg selects
+func (cfg RequestLogConfig) selects(method, route string) bool {
+	if route == "" || !slices.Contains(cfg.Methods, method) {
+		return false
+	}
+	if !slices.ContainsFunc(cfg.Routes, func(prefix string) bool { return strings.HasPrefix(route, prefix) }) {
+		return false
+	}
+	rate := cfg.sampleRateFor(route)
+	return rate >= 1 || (rate > 0 && rand.Float64() < rate)
+}
+
+// RequestLog hands the requests cfg selects to sink once they are answered:
+// the route, the actor, the status, the latency and the request body, cut to
+// BodyLimit with credentials redacted. Only JSON and form bodies are kept.
+func RequestLog(sink RequestLogSink, cfg RequestLogConfig) gin.HandlerFunc {
+	if cfg.BodyLimit <= 0 {
+		cfg.BodyLimit = DefaultBodyLogLimit
+	}
+
+	return func(c *gin.Context) {
+		if !cfg.selects(c.Request.Method, c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		var body string
+		if c.Request.Body != nil && loggableContentType(c.GetHeader("Content-Type")) {
+			body = captureRequestBody(c, cfg.BodyLimit)
+		}
+
+		c.Next()
+
+		entry := &models.RequestLog{
+			ID:          uuid.New(),
+			RequestID:   requestctx.RequestIDFrom(c),
+			TenantID:    database.TenantID(c),
+			Method:      c.Request.Method,
+			Route:       c.FullPath(),
+			Path:        c.Request.URL.Path,
+			ClientIP:    c.ClientIP(),
+			Status:      c.Writer.Status(),
+			LatencyMS:   time.Since(start).Milliseconds(),
+			RequestBody: body,
+			CreatedAt:   start,
+		}
+		if user, ok := requestctx.UserFrom(c); ok {
+			entry.ActorID, entry.ImpersonatorID = user.ID, user.ImpersonatorID
+		}
+		sink.Enqueue(entry)
+	}
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+	"strings"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheHeader tells whether a response came from the response cache: HIT,
+// MISS or BYPASS
+const CacheHeader = "X-Cache"
+
+// cachedHeaders are the response headers stored with a cached body; the
+// others, such as the request ID, belong to the request that made it
+var cachedHeaders = []string{"Content-Type", "Link"}
+
+// CacheRule describes what the responses of a cached route depend on
+type CacheRule struct {
+	// Groups are the response cache groups whose purge drops the responses
+	Groups []string
+	// Query lists the query parameters that change the response. Requests
+	// with any other parameter are not cached.
+	Query []string
+	// PerUser keeps responses apart per user, for routes showing the
+	// caller's own data
+	PerUser bool
+}
+
+// CacheResponses serves GET requests from responses, when the route has a
+// TTL, and caches 200 responses. Keys include the path, the Query
+// parameters, the locale and the tenant, and for authenticated requests the
+// role, so users never see responses rendered for another role. Admins can
+// skip the cache with Cache-Control: no-cache; the fresh response is cached.
+// It must be registered after Auth.
+func CacheResponses(responses *services.ResponseCache, rule CacheRule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ttl := responses.TTL(c.FullPath())
+		if c.Request.Method != http.MethodGet || ttl <= 0 || !rule.knownQuery(c) {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key, err := responses.Key(ctx, rule.Groups, cacheKeyParts(c, rule)...)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		if bypassCache(c) {
+			c.Header(CacheHeader, "BYPASS")
+		} else if cached, ok := responses.Get(ctx, key); ok {
+			for name, value := range cached.Header {
+				c.Header(name, value)
+			}
+			c.Header(CacheHeader, "HIT")
+			c.Data(cached.Status, cached.Header["Content-Type"], cached.Body)
+			c.Abort()
+			return
+		} else {
+			c.Header(CacheHeader, "MISS")
+		}
+
+		writer := &cacheWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.Status() != http.StatusOK {
+			return
+		}
+		cached := &services.CachedResponse{Status: http.StatusOK, Header: make(map[string]string), Body: writer.buf.Bytes()}
+		for _, name := range cachedHeaders {
+			if value := writer.Header().Get(name); value != "" {
+				cached.Header[name] = value
+			}
+		}
+		responses.Set(ctx, key, cached, ttl)
+	}
+}
+
+// knownQuery reports whether the request only has the rule's query parameters
+func (rule CacheRule) knownQuery(c *gin.Context) bool {
+	for name := range c.Request.URL.Query() {
+		if !slices.Contains(rule.Query, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheKeyParts lists what tells the responses of a cached route apart
+func cacheKeyParts(c *gin.Context, rule CacheRule) []string {
+	ctx := c.Request.Context()
+	parts := []string{
+		"path=" + c.Request.URL.Path,
+		"locale=" + i18n.Locale(ctx),
+		"tenant=" + database.TenantID(ctx),
+	}
+	query := c.Request.URL.Query()
+	for _, name := range rule.Query {
+		parts = append(parts, name+"="+strings.Join(query[name], ","))
+	}
+	if claims, ok := GetClaims(c); ok {
+		parts = append(parts, "role="+claims.Role)
+		if rule.PerUser {
+			parts = append(parts, "user="+claims.UserID)
+		}
+	}
+	return parts
+}
+
+// bypassCache reports whether an admin asked for a fresh response
+func bypassCache(c *gin.Context) bool {
+	if !strings.Contains(strings.ToLower(c.GetHeader("Cache-Control")), "no-cache") {
+		return false
+	}
+	claims, ok := GetClaims(c)
+	return ok && claims.Role == string(models.RoleAdmin)
+}
+
+// cacheWriter copies the response body so it can be cached
+type cacheWriter struct {
+	gin.ResponseWriter
+
+	buf bytes.Buffer
+}
+
+func (w *cacheWriter) Write(data []byte) (int, error) {
+	w.buf.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheWriter) WriteString(s string) (int, error) {
+	w.buf.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
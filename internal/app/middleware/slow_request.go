@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RequestStartHeader is set by proxies such as nginx to when they received the
+// request, as "t=<unix time>" in seconds, milliseconds or microseconds
+const RequestStartHeader = "X-Request-Start"
+
+// unmatchedRoute labels requests that matched no route, keeping metric labels bounded
+const unmatchedRoute = "unmatched"
+
+// SlowRequestConfig sets when a request counts as slow
+type SlowRequestConfig struct {
+	// Threshold applies to every route without an override
+	Threshold time.Duration
+
+	// Overrides maps route template prefixes, such as "/api/v1/admin", to their
+	// own threshold. The longest matching prefix wins.
+	Overrides map[string]time.Duration
+}
+
+// thresholdFor returns the threshold of a route template
+func (cfg SlowRequestConfig) thresholdFor(route string) time.Duration {
+	threshold, matched := cfg.Threshold, -1
+	for prefix, override := range cfg.Overrides {
+		if strings.HasPrefix(route, prefix) && len(prefix) > matched {
+			threshold, matched = override, len(prefix)
+		}
+	}
+	return threshold
+}
+
+// SlowRequests logs requests slower than their threshold at warn level and
+// counts them in counter, labelled by route template. Time spent queued in
+// front of the service is logged when a proxy sets RequestStartHeader.
+func SlowRequests(log logger.Logger, cfg SlowRequestConfig, counter *prometheus.CounterVec) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		latency := time.Since(start)
+		threshold := cfg.thresholdFor(route)
+		if threshold <= 0 || latency < threshold {
+			return
+		}
+
+		counter.WithLabelValues(route).Inc()
+
+		fields := []logger.Field{
+			{Key: "slow", Value: true},
+			{Key: "method", Value: c.Request.Method},
+			{Key: "route", Value: route},
+			{Key: "status", Value: c.Writer.Status()},
+			{Key: "latency", Value: latency},
+			{Key: "threshold", Value: threshold},
+		}
+		if received, ok := requestStart(c.GetHeader(RequestStartHeader)); ok && received.Before(start) {
+			fields = append(fields, logger.Field{Key: "queue_time", Value: start.Sub(received)})
+		}
+		log.Warn("Slow HTTP request", fields...)
+	}
+}
+
+// requestStart parses a RequestStartHeader value, guessing its unit from its size
+func requestStart(value string) (time.Time, bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "t=")
+	if value == "" {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}, false
+	}
+
+	switch {
+	case seconds > 1e15:
+		seconds /= 1e6
+	case seconds > 1e12:
+		seconds /= 1e3
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), true
+}
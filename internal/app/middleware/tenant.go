@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/jwtutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantHeader names the tenant a request is made for
+const TenantHeader = "X-Tenant-ID"
+
+// TenantClaim is the token claim holding the tenant a token was issued for
+const TenantClaim = jwtutil.TenantClaim
+
+// TenantResolver looks up the database a tenant's data lives in
+type TenantResolver interface {
+	TenantDatabase(tenant string) (string, bool)
+}
+
+// TenantHostResolver is implemented by resolvers that also serve tenants on
+// their own hosts
+type TenantHostResolver interface {
+	TenantForHost(host string) (string, bool)
+}
+
+// Tenant scopes the request to a tenant's database. The tenant comes from the
+// X-Tenant-ID header, then from the host the request was made to, and
+// otherwise from the tenant_id claim of the access token. Requests naming
+// none use the primary database; unknown tenants get 404. The claim is only
+// read here to route the request: Auth verifies the token against the
+// tenant's settings and rejects it unless it was issued for that tenant.
+func Tenant(resolver TenantResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if resolver == nil {
+			c.Next()
+			return
+		}
+
+		tenant := c.GetHeader(TenantHeader)
+		if hosts, ok := resolver.(TenantHostResolver); ok && tenant == "" {
+			tenant, _ = hosts.TenantForHost(c.Request.Host)
+		}
+		if tenant == "" {
+			tenant = jwtutil.UnverifiedTenant(requestToken(c))
+		}
+		if tenant == "" {
+			c.Next()
+			return
+		}
+
+		driverName, ok := resolver.TenantDatabase(tenant)
+		if !ok {
+			appErr := errors.NewNotFoundError("Tenant not found", nil)
+			c.AbortWithStatusJSON(appErr.Status, gin.H{"error": appErr.Message})
+			return
+		}
+
+		c.Request = c.Request.WithContext(database.WithTenant(c.Request.Context(), tenant, driverName))
+		c.Next()
+	}
+}
+
+// NoTenant allows the request only when it is not scoped to a tenant, for
+// operations that span every tenant
+func NoTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if database.TenantID(c.Request.Context()) != "" {
+			appErr := errors.NewForbiddenError("Not available to tenants", nil)
+			c.AbortWithStatusJSON(appErr.Status, gin.H{"error": appErr.Message})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestToken returns the token from wherever Auth or StreamAuth reads it
+func requestToken(c *gin.Context) string {
+	if token := bearerToken(c.GetHeader("Authorization")); token != "" {
+		return token
+	}
+	if token, err := c.Cookie(StreamTokenParam); err == nil && token != "" {
+		return token
+	}
+	return c.Query(StreamTokenParam)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout sets a deadline of timeout on the request's context, so database
+// queries and calls made with it give up in time. Handlers keep running
+// until they notice; one that ran out of time without writing a response
+// is answered 504 REQUEST_TIMEOUT. A zero timeout sets no deadline.
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !c.Writer.Written() && stderrors.Is(ctx.Err(), context.DeadlineExceeded) {
+			appErr := errors.NewAppError(http.StatusGatewayTimeout, i18n.RequestTimeout, ctx.Err()).
+				WithCode(errors.CodeRequestTimeout).
+				WithParams(errors.Params{"timeout": timeout.String()})
+			AbortWithAppError(c, appErr)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Alert types raised by the authentication watch
+const (
+	// AlertTypeImpossibleTravel is a login from further away than the
+	// user could have travelled since their last one
+	AlertTypeImpossibleTravel = "impossible_travel"
+	// AlertTypeCredentialStuffing is one IP address failing to log in as
+	// many different emails
+	AlertTypeCredentialStuffing = "credential_stuffing"
+	// AlertTypeFailureSpike is many failed logins to one account
+	AlertTypeFailureSpike = "failure_spike"
+)
+
+// Mitigations the authentication watch applies when an alert is raised
+const (
+	// MitigationBlockIP refuses the IP address on rate-limited routes for a while
+	MitigationBlockIP = "block_ip"
+	// MitigationForcePasswordReset signs the user out and makes them
+	// change their password at their next login
+	MitigationForcePasswordReset = "force_password_reset"
+)
+
+// Alert records suspicious login activity and the mitigations applied to
+// it. UserID is set when the activity concerns one known user.
+type Alert struct {
+	ID          uuid.UUID  `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	Type        string     `json:"type" db:"type" gorm:"size:50;not null;index"`
+	UserID      *uuid.UUID `json:"user_id,omitempty" db:"user_id" gorm:"type:varchar(36);index"`
+	Email       string     `json:"email,omitempty" db:"email" gorm:"size:255"`
+	IPAddress   string     `json:"ip_address,omitempty" db:"ip_address" gorm:"size:45"`
+	Details     JSON       `json:"details,omitempty" db:"details"`
+	Mitigations StringList `json:"mitigations" db:"mitigations"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at" gorm:"index"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "BackofficeGoService/internal/pkg/apimodel"
+
+// Models render through apimodel so timestamps come out in UTC with
+// millisecond precision whatever the database driver returned
+
+// MarshalJSON implements json.Marshaler
+func (u User) MarshalJSON() ([]byte, error) { return apimodel.Marshal(u) }
+
+// MarshalJSON implements json.Marshaler
+func (a AuditLog) MarshalJSON() ([]byte, error) { return apimodel.Marshal(a) }
+
+// MarshalJSON implements json.Marshaler
+func (e EmailChange) MarshalJSON() ([]byte, error) { return apimodel.Marshal(e) }
+
+// MarshalJSON implements json.Marshaler
+func (f FeatureFlag) MarshalJSON() ([]byte, error) { return apimodel.Marshal(f) }
+
+// MarshalJSON implements json.Marshaler
+func (e LoginEvent) MarshalJSON() ([]byte, error) { return apimodel.Marshal(e) }
+
+// MarshalJSON implements json.Marshaler
+func (n Notification) MarshalJSON() ([]byte, error) { return apimodel.Marshal(n) }
+
+// MarshalJSON implements json.Marshaler
+func (o Organization) MarshalJSON() ([]byte, error) { return apimodel.Marshal(o) }
+
+// MarshalJSON implements json.Marshaler
+func (m OrganizationMember) MarshalJSON() ([]byte, error) { return apimodel.Marshal(m) }
+
+// MarshalJSON implements json.Marshaler
+func (p Permission) MarshalJSON() ([]byte, error) { return apimodel.Marshal(p) }
+
+// MarshalJSON implements json.Marshaler
+func (a PolicyAcceptance) MarshalJSON() ([]byte, error) { return apimodel.Marshal(a) }
+
+// MarshalJSON implements json.Marshaler
+func (rp RolePermission) MarshalJSON() ([]byte, error) { return apimodel.Marshal(rp) }
+
+// MarshalJSON implements json.Marshaler
+func (s Session) MarshalJSON() ([]byte, error) { return apimodel.Marshal(s) }
+
+// MarshalJSON implements json.Marshaler
+func (s Setting) MarshalJSON() ([]byte, error) { return apimodel.Marshal(s) }
+
+// MarshalJSON implements json.Marshaler
+func (t Task) MarshalJSON() ([]byte, error) { return apimodel.Marshal(t) }
+
+// MarshalJSON implements json.Marshaler
+func (d TrustedDevice) MarshalJSON() ([]byte, error) { return apimodel.Marshal(d) }
+
+// MarshalJSON implements json.Marshaler
+func (r UsageRecord) MarshalJSON() ([]byte, error) { return apimodel.Marshal(r) }
+
+// MarshalJSON implements json.Marshaler
+func (w Webhook) MarshalJSON() ([]byte, error) { return apimodel.Marshal(w) }
+
+// MarshalJSON implements json.Marshaler
+func (d WebhookDelivery) MarshalJSON() ([]byte, error) { return apimodel.Marshal(d) }
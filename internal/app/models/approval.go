@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Approval actions
+const (
+	ApprovalActionRoleChange = "user.role_change"
+)
+
+// ApprovalStatus is where an approval is in its lifecycle
+type ApprovalStatus string
+
+const (
+	ApprovalStatusPending   ApprovalStatus = "pending"
+	ApprovalStatusApproved  ApprovalStatus = "approved"
+	ApprovalStatusRejected  ApprovalStatus = "rejected"
+	ApprovalStatusCancelled ApprovalStatus = "cancelled"
+	ApprovalStatusExpired   ApprovalStatus = "expired"
+)
+
+// Valid reports whether the status is a known approval status
+func (s ApprovalStatus) Valid() bool {
+	switch s {
+	case ApprovalStatusPending, ApprovalStatusApproved, ApprovalStatusRejected, ApprovalStatusCancelled, ApprovalStatusExpired:
+		return true
+	}
+	return false
+}
+
+// Approval is a change held back until an admin other than the one who
+// requested it approves it. Payload holds what the action changes, such as
+// a RoleChange.
+type Approval struct {
+	ID          uuid.UUID      `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	Action      string         `json:"action" db:"action" gorm:"size:100;not null;index:idx_pending_approvals_subject,priority:2"`
+	SubjectID   uuid.UUID      `json:"subject_id" db:"subject_id" gorm:"type:varchar(36);not null;index:idx_pending_approvals_subject,priority:1"`
+	Payload     JSON           `json:"payload" db:"payload"`
+	Status      ApprovalStatus `json:"status" db:"status" gorm:"size:20;not null;index"`
+	RequesterID uuid.UUID      `json:"requester_id" db:"requester_id" gorm:"type:varchar(36);not null;index"`
+	DeciderID   *uuid.UUID     `json:"decider_id,omitempty" db:"decider_id" gorm:"type:varchar(36)"`
+	Reason      string         `json:"reason,omitempty" db:"reason" gorm:"type:text"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at" gorm:"index"`
+	ExpiresAt   time.Time      `json:"expires_at" db:"expires_at"`
+	DecidedAt   *time.Time     `json:"decided_at,omitempty" db:"decided_at"`
+}
+
+// TableName names the table approvals are kept in
+func (Approval) TableName() string {
+	return "pending_approvals"
+}
+
+// RoleChange is the payload of a role change approval
+type RoleChange struct {
+	From UserRole `json:"from"`
+	To   UserRole `json:"to"`
+}
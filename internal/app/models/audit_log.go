@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions
+const (
+	AuditActionUserCreated                = "user.created"
+	AuditActionUserUpdated                = "user.updated"
+	AuditActionUserPasswordChanged        = "user.password_changed"
+	AuditActionUserEmailChanged           = "user.email_changed"
+	AuditActionUserPasswordChangeRequired = "user.password_change_required"
+	AuditActionUserRoleChanged            = "user.role_changed"
+	AuditActionUserActivated              = "user.activated"
+	AuditActionUserDeactivated            = "user.deactivated"
+	AuditActionUserAnonymized             = "user.anonymized"
+	AuditActionUserExported               = "user.exported"
+	AuditActionUserImpersonated           = "user.impersonated"
+	AuditActionImpersonationEnded         = "user.impersonation_ended"
+	AuditActionUserAPITokenIssued         = "user.api_token_issued"
+	AuditActionUserSessionsRevoked        = "user.sessions_revoked"
+	AuditActionUserForcedLogout           = "user.forced_logout"
+	AuditActionUserDeviceRevoked          = "user.device_revoked"
+	AuditActionUserPolicyAccepted         = "user.policy_accepted"
+	AuditActionUserQuotaChanged           = "user.quota_changed"
+	AuditActionSettingUpdated             = "setting.updated"
+	AuditActionTenantCreated              = "tenant.created"
+	AuditActionDatabaseRegistered         = "database.registered"
+	AuditActionDatabaseRemoved            = "database.removed"
+	AuditActionPartnerCreated             = "partner.created"
+	AuditActionPartnerUpdated             = "partner.updated"
+	AuditActionPartnerDeleted             = "partner.deleted"
+	AuditActionEmailPreviewed             = "email.previewed"
+	AuditActionEmailTestSent              = "email.test_sent"
+	AuditActionAuditExported              = "audit.exported"
+	AuditActionApprovalRequested          = "approval.requested"
+	AuditActionApprovalApproved           = "approval.approved"
+	AuditActionApprovalRejected           = "approval.rejected"
+	AuditActionApprovalCancelled          = "approval.cancelled"
+	AuditActionReadOnlyEnabled            = "read_only.enabled"
+	AuditActionReadOnlyDisabled           = "read_only.disabled"
+	AuditActionJWTSecretRotated           = "jwt.secret_rotated"
+	AuditActionUserIdentityLinked         = "user.identity_linked"
+	AuditActionBackupCreated              = "backup.created"
+	AuditActionBackupRestored             = "backup.restored"
+	AuditActionAlertRaised                = "alert.raised"
+	AuditActionIPBlocked                  = "ip.blocked"
+)
+
+// Actions login events are forwarded to a SIEM with; they are stored as
+// LoginEvents rather than audit logs
+const (
+	AuditActionLoginSucceeded = "login.succeeded"
+	AuditActionLoginFailed    = "login.failed"
+)
+
+// AuditChainLogs names the hash chain of the audit logs in an AuditChain
+const AuditChainLogs = "audit_logs"
+
+// AuditLog records a change made by an actor to an entity. Entries are
+// chained: EntryHash covers the entry and the PrevHash of the entry before
+// it in Sequence order, so changing or removing an entry breaks the chain.
+// Entries recorded before the chain was introduced have no Sequence.
+type AuditLog struct {
+	ID         uuid.UUID  `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	ActorID    *uuid.UUID `json:"actor_id,omitempty" db:"actor_id" gorm:"type:varchar(36);index"`
+	Action     string     `json:"action" db:"action" gorm:"size:100;not null;index"`
+	EntityType string     `json:"entity_type" db:"entity_type" gorm:"size:50;not null"`
+	EntityID   string     `json:"entity_id" db:"entity_id" gorm:"size:64;index"`
+	Metadata   JSON       `json:"metadata,omitempty" db:"metadata"`
+	IPAddress  string     `json:"ip_address,omitempty" db:"ip_address" gorm:"size:45"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at" gorm:"index"`
+
+	Sequence *int64 `json:"sequence,omitempty" db:"sequence" gorm:"uniqueIndex"`
+	// ActorDigest is the SHA-256 of the actor ID. The hash covers it rather
+	// than ActorID, which is cleared when the actor is purged.
+	ActorDigest string `json:"actor_digest,omitempty" db:"actor_digest" gorm:"size:64"`
+	PrevHash    string `json:"prev_hash,omitempty" db:"prev_hash" gorm:"size:64"`
+	EntryHash   string `json:"entry_hash,omitempty" db:"entry_hash" gorm:"size:64"`
+}
+
+// AuditChain is the head of a hash chain in a database: the sequence and
+// hash of its latest entry. Writers lock the row to append to the chain one
+// at a time.
+type AuditChain struct {
+	ID        string    `json:"id" db:"id" gorm:"size:50;primaryKey"`
+	Sequence  int64     `json:"sequence" db:"sequence" gorm:"not null;default:0"`
+	Hash      string    `json:"hash" db:"hash" gorm:"size:64"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
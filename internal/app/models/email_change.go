@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailChange is a pending change of a user's email address, applied once
+// the confirmation token sent to the new address comes back. A user has at
+// most one; requesting another replaces it.
+type EmailChange struct {
+	ID        uuid.UUID `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id" gorm:"type:varchar(36);not null;index"`
+	NewEmail  string    `json:"new_email" db:"new_email" gorm:"size:255;not null"`
+	TokenHash string    `json:"-" db:"token_hash" gorm:"size:64;not null;uniqueIndex"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+}
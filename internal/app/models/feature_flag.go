@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// FeatureFlag gates a feature behind an on/off switch, user/role targeting and
+// a deterministic percentage rollout
+type FeatureFlag struct {
+	Key               string     `json:"key" db:"key" gorm:"size:100;primaryKey"`
+	Description       string     `json:"description" db:"description" gorm:"size:500"`
+	Enabled           bool       `json:"enabled" db:"enabled" gorm:"not null;default:false"`
+	RolloutPercentage int        `json:"rollout_percentage" db:"rollout_percentage" gorm:"not null;default:0"`
+	AllowedRoles      StringList `json:"allowed_roles" db:"allowed_roles"`
+	AllowedUsers      StringList `json:"allowed_users" db:"allowed_users"`
+	Payload           JSON       `json:"payload,omitempty" db:"payload"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
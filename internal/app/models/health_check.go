@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// HealthCheck is the row a service instance rewrites on each deep readiness
+// check, proving the database still accepts writes
+type HealthCheck struct {
+	InstanceID string    `json:"instance_id" db:"instance_id" gorm:"size:255;primaryKey"`
+	CheckedAt  time.Time `json:"checked_at" db:"checked_at" gorm:"not null"`
+}
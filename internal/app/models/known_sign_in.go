@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// KnownSignIn is a device and network a user logged in from. The device is
+// the hash of the client's User-Agent and the network its address truncated
+// to a /24, or a /48 for IPv6, so logins from a new device or IP range can be
+// told apart from usual ones.
+type KnownSignIn struct {
+	ID          uuid.UUID `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_known_sign_ins_user_device_network"`
+	DeviceHash  string    `json:"-" db:"device_hash" gorm:"size:64;not null;uniqueIndex:idx_known_sign_ins_user_device_network"`
+	Network     string    `json:"network" db:"network" gorm:"size:64;not null;uniqueIndex:idx_known_sign_ins_user_device_network"`
+	FirstSeenAt time.Time `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginEvent records a login attempt
+type LoginEvent struct {
+	ID        uuid.UUID  `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	UserID    *uuid.UUID `json:"user_id,omitempty" db:"user_id" gorm:"type:varchar(36);index"`
+	Email     string     `json:"email" db:"email" gorm:"size:255"`
+	Success   bool       `json:"success" db:"success"`
+	Reason    string     `json:"reason,omitempty" db:"reason" gorm:"size:100"`
+	IPAddress string     `json:"ip_address,omitempty" db:"ip_address" gorm:"size:45"`
+	UserAgent string     `json:"user_agent,omitempty" db:"user_agent" gorm:"size:255"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at" gorm:"index"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification types
+const (
+	NotificationTypeJobFinished       = "job.finished"
+	NotificationTypeTaskFinished      = "task.finished"
+	NotificationTypeApprovalRequested = "approval.requested"
+	NotificationTypeApprovalDecided   = "approval.decided"
+	NotificationTypeSecurityAlert     = "security.alert"
+)
+
+// Notification is an in-app message shown to a backoffice user
+type Notification struct {
+	ID        uuid.UUID  `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id" gorm:"type:varchar(36);not null;index:idx_notifications_user_read,priority:1"`
+	Type      string     `json:"type" db:"type" gorm:"size:100;not null"`
+	Title     string     `json:"title" db:"title" gorm:"size:255;not null"`
+	Body      string     `json:"body,omitempty" db:"body" gorm:"type:text"`
+	Data      JSON       `json:"data,omitempty" db:"data"`
+	ReadAt    *time.Time `json:"read_at,omitempty" db:"read_at" gorm:"index:idx_notifications_user_read,priority:2"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at" gorm:"index"`
+}
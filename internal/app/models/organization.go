@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization groups backoffice users, e.g. a department
+type Organization struct {
+	ID          uuid.UUID `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	Name        string    `json:"name" db:"name" gorm:"size:255;not null"`
+	Description string    `json:"description" db:"description" gorm:"size:1000"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// OrgRole is a user's role within a single organization
+type OrgRole string
+
+const (
+	OrgRoleOwner  OrgRole = "owner"
+	OrgRoleAdmin  OrgRole = "admin"
+	OrgRoleMember OrgRole = "member"
+)
+
+// Valid reports whether the role is a known organization role
+func (r OrgRole) Valid() bool {
+	switch r {
+	case OrgRoleOwner, OrgRoleAdmin, OrgRoleMember:
+		return true
+	}
+	return false
+}
+
+// OrganizationMember links a user to an organization with a per-org role
+type OrganizationMember struct {
+	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id" gorm:"type:varchar(36);primaryKey"`
+	UserID         uuid.UUID `json:"user_id" db:"user_id" gorm:"type:varchar(36);primaryKey;index"`
+	Role           OrgRole   `json:"role" db:"role" gorm:"size:20;not null;default:member"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
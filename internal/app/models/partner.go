@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Partner is an external system calling the partner API. It signs every
+// request with its secret.
+type Partner struct {
+	ID        uuid.UUID `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	Name      string    `json:"name" db:"name" gorm:"size:100;not null;uniqueIndex"`
+	Secret    string    `json:"-" db:"secret" gorm:"size:255;not null"`
+	Enabled   bool      `json:"enabled" db:"enabled" gorm:"not null;default:true"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,114 @@
+package models
+
+import "time"
+
+// Permission names checked by middleware.RequirePermission
+const (
+	PermissionUsersView         = "users.view"
+	PermissionUsersCreate       = "users.create"
+	PermissionUsersUpdate       = "users.update"
+	PermissionUsersDelete       = "users.delete"
+	PermissionUsersManage       = "users.manage"
+	PermissionPermissionsManage = "permissions.manage"
+	PermissionWebhooksManage    = "webhooks.manage"
+	PermissionJobsManage        = "jobs.manage"
+	PermissionFeaturesManage    = "features.manage"
+	PermissionSettingsManage    = "settings.manage"
+	PermissionTenantsManage     = "tenants.manage"
+	PermissionUsersImpersonate  = "users.impersonate"
+	PermissionDatabasesManage   = "databases.manage"
+	PermissionPartnersManage    = "partners.manage"
+	PermissionEmailsManage      = "emails.manage"
+	PermissionRoutesView        = "routes.view"
+	PermissionAuditView         = "audit.view"
+	PermissionProductsView      = "products.view"
+	PermissionProductsCreate    = "products.create"
+	PermissionProductsUpdate    = "products.update"
+	PermissionProductsDelete    = "products.delete"
+	PermissionRequestLogsView   = "request_logs.view"
+	PermissionApprovalsManage   = "approvals.manage"
+)
+
+// Permission is a named capability that can be granted to roles
+type Permission struct {
+	Name        string    `json:"name" db:"name" gorm:"size:100;primaryKey"`
+	Description string    `json:"description" db:"description" gorm:"size:255"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// RolePermission grants a permission to every user with a role
+type RolePermission struct {
+	Role       UserRole  `json:"role" db:"role" gorm:"size:50;primaryKey"`
+	Permission string    `json:"permission" db:"permission" gorm:"size:100;primaryKey"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// DefaultPermissions lists the permissions seeded by the migrations
+var DefaultPermissions = []Permission{
+	{Name: PermissionUsersView, Description: "View users"},
+	{Name: PermissionUsersCreate, Description: "Create users"},
+	{Name: PermissionUsersUpdate, Description: "Update users"},
+	{Name: PermissionUsersDelete, Description: "Delete users"},
+	{Name: PermissionUsersManage, Description: "Activate, deactivate and anonymize users"},
+	{Name: PermissionPermissionsManage, Description: "Assign permissions to roles"},
+	{Name: PermissionWebhooksManage, Description: "Manage outgoing webhooks"},
+	{Name: PermissionJobsManage, Description: "View and trigger background jobs"},
+	{Name: PermissionFeaturesManage, Description: "Manage feature flags"},
+	{Name: PermissionSettingsManage, Description: "Edit application settings"},
+	{Name: PermissionTenantsManage, Description: "Register tenants and their databases"},
+	{Name: PermissionUsersImpersonate, Description: "Act as another user with a short-lived token"},
+	{Name: PermissionDatabasesManage, Description: "Connect and remove named databases at runtime"},
+	{Name: PermissionPartnersManage, Description: "Manage partners and their signing secrets"},
+	{Name: PermissionEmailsManage, Description: "Preview email templates and send test emails"},
+	{Name: PermissionRoutesView, Description: "List the registered routes and their policies"},
+	{Name: PermissionAuditView, Description: "Export the audit log and verify its hash chain"},
+	{Name: PermissionProductsView, Description: "View products"},
+	{Name: PermissionProductsCreate, Description: "Create products"},
+	{Name: PermissionProductsUpdate, Description: "Update products"},
+	{Name: PermissionProductsDelete, Description: "Delete products"},
+	{Name: PermissionRequestLogsView, Description: "Search the database request log"},
+	{Name: PermissionApprovalsManage, Description: "Approve or reject changes awaiting a second admin"},
+}
+
+// DefaultRolePermissions maps the built-in roles to their seeded permissions
+var DefaultRolePermissions = map[UserRole][]string{
+	RoleAdmin: {
+		PermissionUsersView,
+		PermissionUsersCreate,
+		PermissionUsersUpdate,
+		PermissionUsersDelete,
+		PermissionUsersManage,
+		PermissionPermissionsManage,
+		PermissionWebhooksManage,
+		PermissionJobsManage,
+		PermissionFeaturesManage,
+		PermissionSettingsManage,
+		PermissionTenantsManage,
+		PermissionUsersImpersonate,
+		PermissionDatabasesManage,
+		PermissionPartnersManage,
+		PermissionEmailsManage,
+		PermissionRoutesView,
+		PermissionAuditView,
+		PermissionProductsView,
+		PermissionProductsCreate,
+		PermissionProductsUpdate,
+		PermissionProductsDelete,
+		PermissionRequestLogsView,
+		PermissionApprovalsManage,
+	},
+	RoleUser: {
+		PermissionUsersView,
+		PermissionProductsView,
+	},
+	RoleGuest: {},
+}
+
+// Valid reports whether the role is a known user role
+func (r UserRole) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleUser, RoleGuest:
+		return true
+	}
+	return false
+}
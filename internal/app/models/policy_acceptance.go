@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PolicyAcceptance records a user accepting one version of a policy such as
+// the terms of service. The current version of each policy is the
+// policy_versions setting.
+type PolicyAcceptance struct {
+	ID         uuid.UUID `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_policy_acceptances_user_version"`
+	Policy     string    `json:"policy" db:"policy" gorm:"size:50;not null;uniqueIndex:idx_policy_acceptances_user_version;index:idx_policy_acceptances_version"`
+	Version    string    `json:"version" db:"version" gorm:"size:50;not null;uniqueIndex:idx_policy_acceptances_user_version;index:idx_policy_acceptances_version"`
+	AcceptedAt time.Time `json:"accepted_at" db:"accepted_at"`
+	IPAddress  string    `json:"ip_address,omitempty" db:"ip_address" gorm:"size:45"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Product is an item of the catalog. Price is in the smallest unit of the
+// currency, such as cents. Active has no column default, which GORM would
+// write instead of an explicit false.
+type Product struct {
+	ID        uuid.UUID `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	Name      string    `json:"name" db:"name" gorm:"size:200;not null"`
+	SKU       string    `json:"sku" db:"sku" gorm:"column:sku;size:64;not null;uniqueIndex"`
+	Price     int64     `json:"price" db:"price" gorm:"not null"`
+	Active    bool      `json:"active" db:"active" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
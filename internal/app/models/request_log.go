@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RequestLog is an API request kept for support investigations: who sent
+// what to which route, and how it was answered. Credentials in the body are
+// redacted and the body is cut to the configured size.
+type RequestLog struct {
+	ID             uuid.UUID `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	RequestID      string    `json:"request_id" db:"request_id" gorm:"size:64;index"`
+	TenantID       string    `json:"tenant_id,omitempty" db:"tenant_id" gorm:"size:100;index"`
+	ActorID        string    `json:"actor_id,omitempty" db:"actor_id" gorm:"size:36;index"`
+	ImpersonatorID string    `json:"impersonator_id,omitempty" db:"impersonator_id" gorm:"size:36"`
+	Method         string    `json:"method" db:"method" gorm:"size:10;not null"`
+	Route          string    `json:"route" db:"route" gorm:"size:255;not null;index"`
+	Path           string    `json:"path" db:"path" gorm:"size:2048;not null"`
+	ClientIP       string    `json:"client_ip" db:"client_ip" gorm:"size:45"`
+	Status         int       `json:"status" db:"status" gorm:"not null"`
+	LatencyMS      int64     `json:"latency_ms" db:"latency_ms" gorm:"not null"`
+	RequestBody    string    `json:"request_body,omitempty" db:"request_body" gorm:"type:text"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at" gorm:"not null;index"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Resources filters can be saved for
+const (
+	FilterResourceUsers = "users"
+)
+
+// SavedFilter is a named list filter a user stored to apply again later.
+// Filter holds the filter's fields as they were under SchemaVersion of the
+// resource's filter schema. Shared filters can be read by others allowed to
+// list the resource.
+type SavedFilter struct {
+	ID            uuid.UUID `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	OwnerID       uuid.UUID `json:"owner_id" db:"owner_id" gorm:"type:varchar(36);not null;index"`
+	Name          string    `json:"name" db:"name" gorm:"size:100;not null"`
+	ResourceType  string    `json:"resource_type" db:"resource_type" gorm:"size:50;not null;index"`
+	Filter        JSON      `json:"filter" db:"filter"`
+	SchemaVersion int       `json:"schema_version" db:"schema_version" gorm:"not null"`
+	Shared        bool      `json:"shared" db:"shared" gorm:"not null;default:false;index"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a login of a user on one client. Its ID is the sid claim of
+// every token issued for the login, including refreshed ones.
+type Session struct {
+	ID         uuid.UUID  `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id" gorm:"type:varchar(36);not null;index"`
+	IPAddress  string     `json:"ip_address,omitempty" db:"ip_address" gorm:"size:45"`
+	UserAgent  string     `json:"user_agent,omitempty" db:"user_agent" gorm:"size:255"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at" gorm:"index"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+
+	// DeviceID is the trusted device of a remember_me login; revoking the
+	// device ends the session
+	DeviceID *uuid.UUID `json:"device_id,omitempty" db:"device_id" gorm:"type:varchar(36);index"`
+
+	// Location is where the login came from as GeoIP placed it, such as
+	// "Berlin, DE"; empty when unknown
+	Location string `json:"location,omitempty" db:"location" gorm:"size:255"`
+
+	// Current marks the session of the token listing the sessions
+	Current bool `json:"current" gorm:"-"`
+}
+
+// Active reports whether tokens of the session are still accepted at now
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
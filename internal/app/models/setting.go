@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SettingType is the value type a setting is validated against
+type SettingType string
+
+const (
+	SettingTypeString SettingType = "string"
+	SettingTypeInt    SettingType = "int"
+	SettingTypeBool   SettingType = "bool"
+	SettingTypeJSON   SettingType = "json"
+)
+
+// Known setting keys
+const (
+	SettingSupportEmail  = "support_email"
+	SettingItemsPerPage  = "items_per_page"
+	SettingBannerMessage = "banner_message"
+
+	// SettingPolicyVersions maps each policy users must accept to its
+	// current version, e.g. {"tos": "2024-06"}
+	SettingPolicyVersions = "policy_versions"
+)
+
+// Setting is an application setting editable from the backoffice
+type Setting struct {
+	Key         string      `json:"key" db:"key" gorm:"size:100;primaryKey"`
+	Value       JSON        `json:"value" db:"value"`
+	Type        SettingType `json:"type" db:"type" gorm:"size:20;not null"`
+	Description string      `json:"description,omitempty" db:"-" gorm:"-"`
+	UpdatedBy   *uuid.UUID  `json:"updated_by,omitempty" db:"updated_by" gorm:"type:varchar(36)"`
+	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// SettingDefinition registers a known setting key with its type and default
+type SettingDefinition struct {
+	Key         string
+	Type        SettingType
+	Default     JSON
+	Description string
+}
+
+// DefaultSettings is the schema of known settings; the migrations seed their defaults
+var DefaultSettings = []SettingDefinition{
+	{Key: SettingSupportEmail, Type: SettingTypeString, Default: JSON(`""`), Description: "Address shown to users who need help"},
+	{Key: SettingItemsPerPage, Type: SettingTypeInt, Default: JSON(`20`), Description: "Default page size for list views"},
+	{Key: SettingBannerMessage, Type: SettingTypeString, Default: JSON(`""`), Description: "Message shown in the backoffice banner; empty hides it"},
+	{Key: SettingPolicyVersions, Type: SettingTypeJSON, Default: JSON(`{}`), Description: "Current version of each policy users must accept, e.g. {\"tos\": \"2024-06\"}"},
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Task types
+const (
+	TaskTypeUserExport = "users.export"
+)
+
+// TaskStatus is where a task is in its lifecycle
+type TaskStatus string
+
+const (
+	TaskStatusPending   TaskStatus = "pending"
+	TaskStatusRunning   TaskStatus = "running"
+	TaskStatusSucceeded TaskStatus = "succeeded"
+	TaskStatusFailed    TaskStatus = "failed"
+	TaskStatusCancelled TaskStatus = "cancelled"
+)
+
+// Finished reports whether the status is final
+func (s TaskStatus) Finished() bool {
+	switch s {
+	case TaskStatusSucceeded, TaskStatusFailed, TaskStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// Task is a long-running operation started by a user and run in the background
+type Task struct {
+	ID         uuid.UUID  `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	Type       string     `json:"type" db:"type" gorm:"size:100;not null"`
+	OwnerID    uuid.UUID  `json:"owner_id" db:"owner_id" gorm:"type:varchar(36);not null;index"`
+	Status     TaskStatus `json:"status" db:"status" gorm:"size:20;not null;index"`
+	Progress   int        `json:"progress" db:"progress" gorm:"not null;default:0"`
+	ResultKey  string     `json:"-" db:"result_key" gorm:"size:255"`
+	Error      string     `json:"error,omitempty" db:"error" gorm:"type:text"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at" gorm:"index"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+
+	// ResultURL downloads the result for a limited time, for the user who
+	// fetched the task only
+	ResultURL string `json:"result_url,omitempty" db:"-" gorm:"-"`
+}
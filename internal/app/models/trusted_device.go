@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TrustedDevice is a client a user logged in from with remember_me. The
+// client keeps the device identifier; only its hash is stored, unique per user.
+type TrustedDevice struct {
+	ID         uuid.UUID `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_trusted_devices_user_token"`
+	TokenHash  string    `json:"-" db:"token_hash" gorm:"size:64;not null;uniqueIndex:idx_trusted_devices_user_token"`
+	IPAddress  string    `json:"ip_address,omitempty" db:"ip_address" gorm:"size:45"`
+	UserAgent  string    `json:"user_agent,omitempty" db:"user_agent" gorm:"size:255"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at" db:"last_used_at" gorm:"index"`
+
+	// Current marks the device of the token listing the devices
+	Current bool `json:"current" gorm:"-"`
+}
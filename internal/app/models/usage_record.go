@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsageRecord is how many API requests a user made in one calendar month.
+// The live counters are kept in the cache and flushed here for reports.
+type UsageRecord struct {
+	ID        uuid.UUID `json:"-" db:"id" gorm:"type:varchar(36);primaryKey"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_usage_records_user_period"`
+	Period    string    `json:"period" db:"period" gorm:"size:7;not null;uniqueIndex:idx_usage_records_user_period;index"`
+	Requests  int64     `json:"requests" db:"requests" gorm:"not null;default:0"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
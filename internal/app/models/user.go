@@ -2,58 +2,78 @@
 
 // import "gorm.io/gorm"
 
-// type User struct {
-// 	gorm.Model
-// 	ID         uint   `gorm:"primaryKey"`
-// 	Name       string `json:"name"`
-// 	Email      string `json:"email" gorm:"unique"`
-// 	Password   string `json:"-"`
-// 	ProfilePic string `json:"profile_pic"`
-// }
+//	type User struct {
+//		gorm.Model
+//		ID         uint   `gorm:"primaryKey"`
+//		Name       string `json:"name"`
+//		Email      string `json:"email" gorm:"unique"`
+//		Password   string `json:"-"`
+//		ProfilePic string `json:"profile_pic"`
+//	}
 package models
 
 import (
-    "time"
-    "github.com/google/uuid"
+	"github.com/google/uuid"
+	"time"
 )
 
 type User struct {
-    ID        uuid.UUID `json:"id" db:"id"`
-    Email     string    `json:"email" db:"email"`
-    Username  string    `json:"username" db:"username"`
-    Password  string    `json:"-" db:"password"`
-    FirstName string    `json:"first_name" db:"first_name"`
-    LastName  string    `json:"last_name" db:"last_name"`
-    Role      UserRole  `json:"role" db:"role"`
-    Active    bool      `json:"active" db:"active"`
-    CreatedAt time.Time `json:"created_at" db:"created_at"`
-    UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
-    DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	ID           uuid.UUID  `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	Email        string     `json:"email" db:"email" gorm:"size:255;not null;uniqueIndex:idx_users_email_unique"`
+	Username     string     `json:"username" db:"username" gorm:"size:100"`
+	Password     string     `json:"-" db:"password" gorm:"size:255"`
+	FirstName    string     `json:"first_name" db:"first_name" gorm:"size:100"`
+	LastName     string     `json:"last_name" db:"last_name" gorm:"size:100"`
+	Role         UserRole   `json:"role" db:"role" gorm:"size:20;not null;default:user"`
+	Active       bool       `json:"active" db:"active" gorm:"not null;default:true"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at" db:"deleted_at"`
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty" db:"anonymized_at"`
+
+	// PasswordChangedAt is when the password was last set; the password
+	// expires AUTH_PASSWORD_MAX_AGE later
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty" db:"password_changed_at"`
+	// MustChangePassword forces a password change at the next login
+	MustChangePassword bool `json:"must_change_password" db:"must_change_password" gorm:"not null;default:false"`
+	// TokenVersion is embedded in the user's tokens; bumping it invalidates
+	// every token issued before
+	TokenVersion int `json:"-" db:"token_version" gorm:"not null;default:0"`
+	// APIQuota overrides API_QUOTA_MONTHLY for the user: requests allowed
+	// per calendar month, 0 for no limit. Nil uses the default.
+	APIQuota *int64 `json:"api_quota,omitempty" db:"api_quota"`
+	// PartnerID and ExternalID link a user provisioned by a partner to the
+	// partner's own ID for it; together they are unique
+	PartnerID  *uuid.UUID `json:"partner_id,omitempty" db:"partner_id" gorm:"type:varchar(36);uniqueIndex:idx_users_partner_external"`
+	ExternalID *string    `json:"external_id,omitempty" db:"external_id" gorm:"size:255;uniqueIndex:idx_users_partner_external"`
+	// TenantID is the tenant the user was created for; nil for users created
+	// outside any tenant or before users were tagged
+	TenantID *string `json:"tenant_id,omitempty" db:"tenant_id" gorm:"size:64;index"`
 }
 
 type UserRole string
 
 const (
-    RoleAdmin    UserRole = "admin"
-    RoleUser     UserRole = "user"
-    RoleGuest    UserRole = "guest"
+	RoleAdmin UserRole = "admin"
+	RoleUser  UserRole = "user"
+	RoleGuest UserRole = "guest"
 )
 
 // Value objects
 type Email struct {
-    Value string
+	Value string
 }
 
 func (e Email) Validate() error {
-    // Email validation logic
-    return nil
+	// Email validation logic
+	return nil
 }
 
 type Password struct {
-    Hash string
+	Hash string
 }
 
 func (p Password) Verify(plain string) bool {
-    // Password verification logic
-    return true
-}
\ No newline at end of file
+	// Password verification logic
+	return true
+}
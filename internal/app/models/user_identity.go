@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links a user to their account at an OpenID Connect provider.
+// Subject is the provider's stable ID for the account; together with the
+// provider's slug it is unique.
+type UserIdentity struct {
+	ID          uuid.UUID  `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	UserID      uuid.UUID  `json:"user_id" db:"user_id" gorm:"type:varchar(36);not null;index"`
+	Provider    string     `json:"provider" db:"provider" gorm:"size:64;not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject     string     `json:"subject" db:"subject" gorm:"size:255;not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email       string     `json:"email" db:"email" gorm:"size:255"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" db:"last_login_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEventAll subscribes a webhook to every event type
+const WebhookEventAll = "*"
+
+// Webhook is an external endpoint that receives signed event notifications
+type Webhook struct {
+	ID                  uuid.UUID  `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	URL                 string     `json:"url" db:"url" gorm:"size:2048;not null"`
+	Secret              string     `json:"-" db:"secret" gorm:"size:255;not null"`
+	Enabled             bool       `json:"enabled" db:"enabled" gorm:"not null;default:true"`
+	EventTypes          StringList `json:"event_types" db:"event_types"`
+	ConsecutiveFailures int        `json:"consecutive_failures" db:"consecutive_failures" gorm:"not null;default:0"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty" db:"disabled_at"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Subscribes reports whether the webhook wants events of eventType
+func (w *Webhook) Subscribes(eventType string) bool {
+	return w.EventTypes.Contains(WebhookEventAll) || w.EventTypes.Contains(eventType)
+}
+
+// WebhookDelivery records a single delivery attempt
+type WebhookDelivery struct {
+	ID         uuid.UUID `json:"id" db:"id" gorm:"type:varchar(36);primaryKey"`
+	WebhookID  uuid.UUID `json:"webhook_id" db:"webhook_id" gorm:"type:varchar(36);not null;index"`
+	EventID    string    `json:"event_id" db:"event_id" gorm:"size:36;not null;index"`
+	EventType  string    `json:"event_type" db:"event_type" gorm:"size:100;not null"`
+	Attempt    int       `json:"attempt" db:"attempt" gorm:"not null"`
+	StatusCode int       `json:"status_code" db:"status_code"`
+	Success    bool      `json:"success" db:"success" gorm:"not null"`
+	Error      string    `json:"error,omitempty" db:"error" gorm:"size:1000"`
+	DurationMs int64     `json:"duration_ms" db:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at" gorm:"index"`
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"BackofficeGoService/internal/infrastructure/email"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Option overrides a component New would otherwise build itself
+type Option func(app *Application)
+
+// WithDBManager uses m instead of connecting the configured databases.
+// m must already be connected and hold a "primary" driver; New neither
+// connects it nor runs migrations on it, and Shutdown does not close it.
+func WithDBManager(m *database.Manager) Option {
+	return func(app *Application) {
+		app.dbManager = m
+		app.externalDB = true
+	}
+}
+
+// WithUserService replaces the user service used by the HTTP and gRPC APIs
+func WithUserService(s service.UserService) Option {
+	return func(app *Application) {
+		app.userService = s
+	}
+}
+
+// WithEmailClient sets the client used to send email
+func WithEmailClient(c email.EmailClient) Option {
+	return func(app *Application) {
+		app.emailClient = c
+	}
+}
+
+// WithRouterMiddleware adds middleware to every route, after recovery and
+// request logging
+func WithRouterMiddleware(mw ...gin.HandlerFunc) Option {
+	return func(app *Application) {
+		app.middleware = append(app.middleware, mw...)
+	}
+}
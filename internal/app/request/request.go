@@ -0,0 +1,141 @@
+// Package request binds and validates request input for controllers, so
+// handlers no longer repeat the bind-then-respond boilerplate:
+//
+//	req, ok := request.Bind[LoginRequest](c)
+//	if !ok {
+//		return
+//	}
+package request
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Bind decodes the request into a T, which must be a struct, and validates
+// it with the shared validator. Fields are read from the query string by
+// their form tag, from a JSON body by their json tag and from path
+// parameters by their uri tag, in that order, so later sources win.
+//
+// When decoding or validation fails, Bind responds 422 with the failed
+// fields as details, aborts the chain and returns false.
+func Bind[T any](c *gin.Context) (T, bool) {
+	var req T
+	if err := decode(c, &req); err != nil {
+		middleware.AbortWithAppError(c, validator.NewAppError(err))
+		return req, false
+	}
+	if err := validator.Validate(&req); err != nil {
+		middleware.AbortWithAppError(c, validator.NewAppError(err))
+		return req, false
+	}
+	return req, true
+}
+
+// decode fills ptr from every source of the request without validating
+func decode(c *gin.Context, ptr any) error {
+	if query := c.Request.URL.Query(); len(query) > 0 {
+		if err := mapTagged(ptr, query, "form"); err != nil {
+			return err
+		}
+	}
+
+	if hasBody(c.Request) {
+		if err := json.NewDecoder(c.Request.Body).Decode(ptr); err != nil && !stderrors.Is(err, io.EOF) {
+			return err
+		}
+	}
+
+	if len(c.Params) > 0 {
+		params := make(map[string][]string, len(c.Params))
+		for _, param := range c.Params {
+			params[param.Key] = []string{param.Value}
+		}
+		if err := mapTagged(ptr, params, "uri"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasBody reports whether r may carry a body to decode
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+// mapTagged maps values onto the fields of ptr that declare their key with
+// tag. gin's mapping falls back to the Go field name for untagged fields;
+// dropping undeclared keys first keeps a query string from setting fields
+// meant only for the body.
+func mapTagged(ptr any, values map[string][]string, tag string) error {
+	keys := declaredKeys(reflect.TypeOf(ptr), tag)
+	declared := make(map[string][]string, len(values))
+	for key, value := range values {
+		if keys[key] {
+			declared[key] = value
+		}
+	}
+	if len(declared) == 0 {
+		return nil
+	}
+	return binding.MapFormWithTag(ptr, declared, tag)
+}
+
+// declaredKeys caches the keys a type declares per tag
+var declaredKeysCache sync.Map
+
+type declaredKeysKey struct {
+	t   reflect.Type
+	tag string
+}
+
+// declaredKeys returns the keys named by tag on the fields of t, including
+// those of nested and embedded structs
+func declaredKeys(t reflect.Type, tag string) map[string]bool {
+	cacheKey := declaredKeysKey{t: t, tag: tag}
+	if keys, ok := declaredKeysCache.Load(cacheKey); ok {
+		return keys.(map[string]bool)
+	}
+
+	keys := make(map[string]bool)
+	collectKeys(t, tag, keys, map[reflect.Type]bool{})
+	declaredKeysCache.Store(cacheKey, keys)
+	return keys
+}
+
+func collectKeys(t reflect.Type, tag string, keys map[string]bool, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			collectKeys(field.Type, tag, keys, seen)
+		default:
+			keys[name] = true
+		}
+	}
+}
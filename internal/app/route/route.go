@@ -0,0 +1,146 @@
+// Package route describes API routes declaratively. A controller lists its
+// routes with the policy guarding each, and the registrar in internal/routes
+// turns every policy into middleware, always in the same order, so no route
+// can check a permission before the token or cache a response before the
+// permission check:
+//
+//	func (uc *UserController) Routes() []route.Definition {
+//		return []route.Definition{
+//			{
+//				Method:  http.MethodPut,
+//				Path:    "/users/:id",
+//				Handler: uc.UpdateUser,
+//				Policy:  route.Policy{Auth: route.Authenticated, Permission: models.PermissionUsersUpdate},
+//			},
+//		}
+//	}
+//
+// Definitions are checked when they are registered; a misconfigured one,
+// such as a cached POST or an unknown permission, stops the server from
+// starting.
+package route
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/apiversion"
+	"BackofficeGoService/internal/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalid is wrapped by the errors of misconfigured definitions
+var ErrInvalid = errors.New("invalid route definition")
+
+// Auth is who may call a route
+type Auth int
+
+const (
+	// Public routes need no token
+	Public Auth = iota
+	// Authenticated routes need a bearer token, quota left this month and,
+	// when required, every current policy accepted
+	Authenticated
+	// PasswordChange routes also accept the restricted tokens issued for
+	// changing an expired password; quota and policies are not checked
+	PasswordChange
+	// ProfileCompletion routes also accept the restricted tokens issued for
+	// completing a profile; like Account routes they check quota but stay
+	// open to users with pending policies
+	ProfileCompletion
+	// Account routes need a bearer token and quota left but stay open to
+	// users with pending policies, so they can see and accept them
+	Account
+	// Stream routes take the token from the access_token cookie or query
+	// parameter too, since EventSource and WebSocket clients cannot set headers
+	Stream
+	// Signed routes are called by partners, who sign requests with their
+	// secret instead of sending a token
+	Signed
+)
+
+// authNames are the names of Auth values in route tables
+var authNames = map[Auth]string{
+	Public:            "public",
+	Authenticated:     "authenticated",
+	PasswordChange:    "password_change",
+	ProfileCompletion: "profile_completion",
+	Account:           "account",
+	Stream:            "stream",
+	Signed:            "signed",
+}
+
+// String returns the name of the auth, such as "authenticated"
+func (a Auth) String() string {
+	if name, ok := authNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("Auth(%d)", int(a))
+}
+
+// Names of the middleware a definition can add with Middlewares
+const (
+	// NotImpersonating refuses impersonation tokens
+	NotImpersonating = "not_impersonating"
+	// NoTenant refuses requests scoped to a tenant
+	NoTenant = "no_tenant"
+	// OrgMember refuses users outside the organization named by :id,
+	// unless they are admins
+	OrgMember = "org_member"
+)
+
+// Definition is one route of a controller
+type Definition struct {
+	Method string
+	// Path is relative to the group the definitions are registered on
+	Path    string
+	Handler gin.HandlerFunc
+	// Middlewares names middleware run after the policy's checks, in order
+	Middlewares []string
+	Policy      Policy
+
+	// Feature is the API feature the route belongs to; the route is left
+	// out while the feature is off. Empty routes are always served.
+	Feature string
+	// Versions lists the API versions serving the route; nil is v1 only
+	Versions []apiversion.Version
+}
+
+// Policy is what a request must pass before the handler runs
+type Policy struct {
+	Auth Auth
+	// Roles, if set, limits the route to users with one of the roles
+	Roles []models.UserRole
+	// Permission, if set, is checked server-side against the user's role
+	Permission string
+	// RateLimit caps the requests each client IP makes to the route
+	RateLimit RateLimit
+	// Timeout, if set, is the deadline of the request's context
+	Timeout time.Duration
+	// Cache, if set, serves the GET route from the response cache
+	Cache *middleware.CacheRule
+	// Confirm, if set, runs the destructive route only once the caller
+	// repeats the request with the confirmation token it was answered with
+	Confirm *middleware.ConfirmRule
+}
+
+// RateLimit allows Requests per Window; the zero value allows any number.
+// Limits without a Mode take the registrar's default.
+type RateLimit = ratelimit.Limit
+
+// Serves reports whether version serves the route
+func (d Definition) Serves(version apiversion.Version) bool {
+	if d.Versions == nil {
+		return version == apiversion.V1
+	}
+	for _, v := range d.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
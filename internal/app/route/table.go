@@ -0,0 +1,83 @@
+package route
+
+import (
+	"fmt"
+	"strings"
+
+	"BackofficeGoService/internal/pkg/ratelimit"
+)
+
+// Info describes a registered route for tooling, such as API gateway
+// configuration or a permission matrix. It is built from the route's
+// definition, so it lists exactly what the registrar enforces.
+type Info struct {
+	Method string `json:"method"`
+	// Path is the full path template, such as /api/v1/users/:id
+	Path string `json:"path"`
+	// Handler names the handler function as gin does, without the module
+	Handler    string         `json:"handler"`
+	Auth       string         `json:"auth"`
+	Roles      []string       `json:"roles,omitempty"`
+	Permission string         `json:"permission,omitempty"`
+	RateLimit  *RateLimitInfo `json:"rate_limit,omitempty"`
+	// Feature is the API feature the route is served under
+	Feature string `json:"feature,omitempty"`
+	// Confirm tells that the route asks for a confirmation token first
+	Confirm bool `json:"confirm,omitempty"`
+}
+
+// RateLimitInfo is a route's rate limit with the window spelled out
+type RateLimitInfo struct {
+	Requests int    `json:"requests"`
+	Window   string `json:"window"`
+	Mode     string `json:"mode"`
+}
+
+// Table lists registered routes, ordered by path and method
+type Table []Info
+
+// markdownColumns are the columns of Markdown tables
+var markdownColumns = []string{"Method", "Path", "Auth", "Roles", "Permission", "Rate limit", "Feature", "Confirm", "Handler"}
+
+// Markdown renders the table as a GitHub-flavored Markdown table
+func (t Table) Markdown() string {
+	var b strings.Builder
+	row := func(cells []string) {
+		b.WriteString("|")
+		for _, cell := range cells {
+			b.WriteString(" " + strings.ReplaceAll(cell, "|", `\|`) + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	row(markdownColumns)
+	separator := make([]string, len(markdownColumns))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	row(separator)
+	for _, info := range t {
+		var limit, confirm string
+		if info.RateLimit != nil {
+			limit = fmt.Sprintf("%d/%s", info.RateLimit.Requests, info.RateLimit.Window)
+			if info.RateLimit.Mode == string(ratelimit.Observe) {
+				limit += " (observed)"
+			}
+		}
+		if info.Confirm {
+			confirm = "yes"
+		}
+		row([]string{
+			info.Method,
+			"`" + info.Path + "`",
+			info.Auth,
+			strings.Join(info.Roles, ", "),
+			info.Permission,
+			limit,
+			info.Feature,
+			confirm,
+			"`" + info.Handler + "`",
+		})
+	}
+	return b.String()
+}
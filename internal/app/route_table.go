@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"os"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Routes returns the table of the routes New registers for cfg without
+// connecting to anything: the databases are replaced by an empty in-memory
+// SQLite one, and messaging and the gRPC API are left off.
+func Routes(cfg *config.Config) (route.Table, error) {
+	offline := *cfg
+	offline.Server.Mode = gin.ReleaseMode
+	offline.Server.DrainDelay = 0
+	offline.Messaging.Driver = "memory"
+	offline.GRPC.Enabled = false
+
+	// Task results would otherwise land in the configured storage directory
+	storagePath, err := os.MkdirTemp("", "backoffice-routes-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(storagePath)
+	offline.Storage.Path = storagePath
+
+	ctx := context.Background()
+	db := database.NewManager()
+	defer db.CloseAll()
+	driver, err := OpenDatabase(ctx, config.DatabaseConnectionConfig{
+		Driver:  string(database.DriverSQLite),
+		DBName:  ":memory:",
+		UseGorm: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.AddDriver(database.PrimaryDriver, driver); err != nil {
+		driver.Close()
+		return nil, err
+	}
+
+	application, err := New(&offline, logger.NewNopLogger(), WithDBManager(db))
+	if err != nil {
+		return nil, err
+	}
+	defer application.Shutdown(ctx)
+
+	return application.RouteTable(), nil
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/database/migrations"
+	"BackofficeGoService/internal/infrastructure/email"
+	natsmessaging "BackofficeGoService/internal/infrastructure/messaging/nats"
+	"BackofficeGoService/internal/infrastructure/storage"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/health"
+	"BackofficeGoService/internal/pkg/httpclient"
+	"BackofficeGoService/internal/pkg/jwtutil"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MinJWTSecretLength is the shortest secret the self-test accepts for
+// signing tokens: HS256 keys should be as long as the hash
+const MinJWTSecretLength = 32
+
+// SelfTestChecks returns the checks proving a process started with cfg can
+// serve: every configured database, the migrations of the primary one, the
+// file store, the email provider, the message broker and the token secrets.
+// Each check opens its own connections and closes them again, so nothing is
+// left running; critical checks are those the server cannot start without.
+func SelfTestChecks(cfg *config.Config, log logger.Logger) []health.Checker {
+	checks := []health.Checker{databaseCheck(database.PrimaryDriver, cfg.Database.Primary, true)}
+	names := make([]string, 0, len(cfg.Database.Databases))
+	for name := range cfg.Database.Databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		dbc := cfg.Database.Databases[name]
+		checks = append(checks, databaseCheck(name, dbc, dbc.Required))
+	}
+	checks = append(checks, migrationCheck(cfg.Database), fileStoreCheck(cfg))
+
+	if cfg.Email.Driver == "sendgrid" {
+		// Sends are not retried, so neither is the ping
+		out := outboundClientConfig(cfg)
+		out.MaxRetries = 0
+		client := email.NewSendGridClient(httpclient.New(out), cfg.Email.SendGridURL, cfg.Email.SendGridAPIKey, cfg.Email.From)
+		checks = append(checks, email.NewHealthCheck(client, false))
+	}
+	if cfg.Messaging.Driver == "nats" {
+		checks = append(checks, natsCheck(cfg.Messaging.NATS, log))
+	}
+	return append(checks, jwtCheck(cfg.JWT))
+}
+
+// databaseCheck connects the database, pings it and disconnects
+func databaseCheck(name string, dbc config.DatabaseConnectionConfig, critical bool) health.Checker {
+	return health.NewCheck("database:"+name, critical, func(ctx context.Context) error {
+		driver, err := OpenDatabase(ctx, dbc)
+		if err != nil {
+			return err
+		}
+		defer driver.Close()
+		return driver.Ping(ctx)
+	})
+}
+
+// migrationCheck fails while the primary database has pending migrations,
+// unless the server applies them itself on start
+func migrationCheck(cfg config.DatabaseConfig) health.Checker {
+	return health.NewCheck("migrations", true, func(ctx context.Context) error {
+		driver, err := OpenDatabase(ctx, cfg.Primary)
+		if err != nil {
+			return err
+		}
+		defer driver.Close()
+		db, err := database.OpenGorm(driver)
+		if err != nil {
+			return err
+		}
+
+		statuses, err := migrations.NewRunner(db).Status(ctx)
+		if err != nil {
+			return err
+		}
+		var pending []string
+		for _, s := range statuses {
+			if !s.Applied {
+				pending = append(pending, s.ID)
+			}
+		}
+		if len(pending) > 0 && !cfg.AutoMigrate {
+			return fmt.Errorf("%d pending migrations, starting with %s; run migrate up or set DB_MIGRATE", len(pending), pending[0])
+		}
+		return nil
+	})
+}
+
+// fileStoreCheck opens the file store and writes, reads and deletes a probe
+// object in it
+func fileStoreCheck(cfg *config.Config) health.Checker {
+	return health.NewCheck("storage", true, func(ctx context.Context) error {
+		files, err := newFileStore(cfg)
+		if err != nil {
+			return err
+		}
+		return storage.NewProbeCheck(files, true).Check(ctx)
+	})
+}
+
+// natsCheck connects to NATS, checks JetStream and disconnects. The server
+// does not start when the broker is unreachable.
+func natsCheck(cfg config.NATSConfig, log logger.Logger) health.Checker {
+	return health.NewCheck("nats", true, func(ctx context.Context) error {
+		client, err := natsmessaging.Connect(ctx, cfg, log)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		return client.Health(ctx)
+	})
+}
+
+// jwtCheck signs and verifies a token with the secret of the primary
+// database and of each tenant, which must be MinJWTSecretLength bytes long.
+// A JWT key file, once it exists, must be readable.
+func jwtCheck(cfg config.JWTConfig) health.Checker {
+	return health.NewCheck("jwt", true, func(ctx context.Context) error {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		if cfg.SecretFile != "" {
+			if _, err := jwtutil.LoadKeys(cfg.SecretFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		tenants := []string{""}
+		for tenant := range cfg.Tenants {
+			tenants = append(tenants, tenant)
+		}
+		sort.Strings(tenants)
+
+		tokens := services.NewTokenManager(cfg, time.Now)
+		var problems []string
+		for _, tenant := range tenants {
+			name := "default"
+			if tenant != "" {
+				name = "tenant " + tenant
+			}
+			if n := len(tokens.SettingsFor(tenant).Secret); n < MinJWTSecretLength {
+				problems = append(problems, fmt.Sprintf("%s secret is %d bytes, want at least %d", name, n, MinJWTSecretLength))
+				continue
+			}
+			token, err := tokens.GenerateFor(tenant, jwt.MapClaims{"sub": "selftest", "iat": time.Now().Unix(), "exp": time.Now().Add(time.Minute).Unix()})
+			if err == nil {
+				_, err = tokens.VerifyFor(tenant, token)
+			}
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s token does not verify: %v", name, err))
+			}
+		}
+		if len(problems) > 0 {
+			return errors.New(strings.Join(problems, "; "))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app"
+	"BackofficeGoService/internal/infrastructure/storage"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/spf13/cobra"
+)
+
+func newBackupCommand(e *env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up databases to storage and restore them",
+		Long: `Back up a database to STORAGE_PATH under BACKUP_PREFIX, dated and
+compressed unless BACKUP_GZIP is false. PostgreSQL and MySQL are dumped
+with pg_dump and mysqldump and restored with psql and mysql, found at the
+BACKUP_*_PATH settings; SQLite files are snapshotted while in use.`,
+		RunE: requireSubcommand,
+	}
+
+	var name string
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Back up a database",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withBackups(cmd.Context(), e.cfg, name, func(backups *services.BackupService, target services.BackupTarget) error {
+				backup, err := backups.Create(cmd.Context(), "", target)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Backed up %s to %s (%d bytes)\n", target.Name, backup.Key, backup.Size)
+				return nil
+			})
+		},
+	}
+	create.Flags().StringVar(&name, "database", database.PrimaryDriver, `database to back up: "primary" or a name from database.databases`)
+
+	var force bool
+	restore := &cobra.Command{
+		Use:   "restore KEY",
+		Short: "Restore a database from a backup",
+		Long: `Replace the contents of a database with a backup listed by "backup list".
+The database must be empty unless --force is given. Stop the service
+before restoring a SQLite database, whose file is replaced.`,
+		Example: `  backoffice-service backup restore backups/primary/20240601T020000.000Z.postgresql.gz`,
+		Args:    usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withBackups(cmd.Context(), e.cfg, name, func(backups *services.BackupService, target services.BackupTarget) error {
+				backup, err := backups.Restore(cmd.Context(), "", target, args[0], force)
+				if errors.Is(err, services.ErrBackupTargetNotEmpty) {
+					return fmt.Errorf("%w; use --force to replace its contents", err)
+				}
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Restored %s from %s\n", target.Name, backup.Key)
+				return nil
+			})
+		},
+	}
+	restore.Flags().StringVar(&name, "database", database.PrimaryDriver, `database to restore into: "primary" or a name from database.databases`)
+	restore.Flags().BoolVar(&force, "force", false, "restore even into a database that has tables")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the backups in storage, newest first",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backups, err := newBackupService(e.cfg)
+			if err != nil {
+				return err
+			}
+			listed, err := backups.List(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tDATABASE\tDRIVER\tSIZE\tCREATED AT")
+			for _, b := range listed {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", b.Key, b.Database, b.Driver, b.Size, b.CreatedAt.Format("2006-01-02 15:04:05"))
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.AddCommand(create, restore, list)
+	return cmd
+}
+
+// newBackupService builds the backup service on the configured storage.
+// Commands are not audited, having no actor.
+func newBackupService(cfg *config.Config) (*services.BackupService, error) {
+	store, err := storage.NewLocalStore(cfg.Storage.Path)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewBackupService(cfg.Backup, store, nil, logger.NewNopLogger()), nil
+}
+
+// withBackups connects the named database for the duration of run
+func withBackups(ctx context.Context, cfg *config.Config, name string, run func(backups *services.BackupService, target services.BackupTarget) error) error {
+	dbc, err := connection(cfg, name)
+	if err != nil {
+		return err
+	}
+	backups, err := newBackupService(cfg)
+	if err != nil {
+		return err
+	}
+
+	driver, err := app.OpenDatabase(ctx, dbc)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database %s: %w", name, err)
+	}
+	defer driver.Close()
+
+	db := driver.GetSQLDB()
+	if db == nil {
+		return fmt.Errorf("%w: %s", services.ErrBackupUnsupported, dbc.Driver)
+	}
+	return run(backups, services.BackupTarget{Name: name, Config: dbc, DB: db})
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"encoding/json"
+
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+func newConfigCommand(e *env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the resolved configuration",
+		RunE:  requireSubcommand,
+	}
+
+	var format string
+	show := &cobra.Command{
+		Use:   "show",
+		Short: "Print the resolved configuration with secrets redacted",
+		Long:  "Print the configuration as the server would load it from the environment and .env. Passwords, secrets, tokens and credentials in connection strings are redacted.",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dump := e.cfg.Redacted().Dump()
+			switch format {
+			case "yaml":
+				enc := yaml.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent(2)
+				if err := enc.Encode(dump); err != nil {
+					return err
+				}
+				return enc.Close()
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(dump)
+			default:
+				return usageErrorf("unknown format %q: use yaml or json", format)
+			}
+		},
+	}
+	show.Flags().StringVar(&format, "format", "yaml", "output format: yaml or json")
+
+	cmd.AddCommand(show)
+	return cmd
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+)
+
+// connection returns the configuration of the named database: "primary" or
+// an entry of database.databases
+func connection(cfg *config.Config, name string) (config.DatabaseConnectionConfig, error) {
+	if name == database.PrimaryDriver {
+		return cfg.Database.Primary, nil
+	}
+	if dbc, ok := cfg.Database.Databases[name]; ok {
+		return dbc, nil
+	}
+
+	names := []string{database.PrimaryDriver}
+	for configured := range cfg.Database.Databases {
+		names = append(names, configured)
+	}
+	sort.Strings(names[1:])
+	return config.DatabaseConnectionConfig{}, usageErrorf("unknown database %q; configured: %s", name, strings.Join(names, ", "))
+}
+
+// openUserService connects the primary database and builds the user
+// service on it. Its cache lives in this process only, and events are not
+// published since no server runs.
+func openUserService(ctx context.Context, cfg *config.Config) (*services.UserService, func(), error) {
+	users, _, closeDB, err := openAuthService(ctx, cfg)
+	return users, closeDB, err
+}
+
+// openAuthService is openUserService that also builds the auth service,
+// signing tokens with the JWT settings of cfg
+func openAuthService(ctx context.Context, cfg *config.Config) (*services.UserService, *services.AuthService, func(), error) {
+	driver, err := app.OpenDatabase(ctx, cfg.Database.Primary)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to connect to the database: %w", err)
+	}
+
+	db := database.NewManager()
+	if err := db.AddDriver(database.PrimaryDriver, driver); err != nil {
+		driver.Close()
+		return nil, nil, nil, err
+	}
+
+	store := cache.NewMemoryStore()
+	log := logger.NewNopLogger()
+	revoker := services.NewTokenRevoker(store, cfg.JWT.Expiration)
+	audit := services.NewAuditService(db, log)
+	users := services.NewUserService(db, store, revoker, audit, nil, log,
+		services.WithEmailNormalization(services.EmailNormalization(cfg.Auth)))
+	auth := services.NewAuthService(db, cfg, store, revoker, audit, nil, log)
+	return users, auth, func() { db.CloseAll() }, nil
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"BackofficeGoService/internal/pkg/jwtutil"
+	"BackofficeGoService/internal/services"
+
+	"github.com/spf13/cobra"
+)
+
+func newRotateJWTSecretCommand(e *env) *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "rotate-jwt-secret",
+		Short: "Sign new tokens with a new JWT secret, keeping existing ones valid",
+		Long: `Generate a new JWT secret in JWT_SECRET_FILE. The secret it replaces,
+JWT_SECRET on the first rotation, stays accepted until the longest-lived
+token signed with it has expired, so no session ends. Running instances
+pick up the file within JWT_SECRET_FILE_REFRESH. The secrets are never
+printed.`,
+		Example: `  JWT_SECRET_FILE=/run/secrets/jwt.json backoffice-service rotate-jwt-secret`,
+		Args:    usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := e.cfg.JWT
+			if cfg.SecretFile == "" {
+				return usageErrorf("JWT_SECRET_FILE is not set; the rotated secret must be kept in a file")
+			}
+
+			keys, err := jwtutil.RotateFile(cfg.SecretFile, cfg.Secret, time.Now(), services.MaxTokenLifetime(e.cfg), force)
+			if errors.Is(err, jwtutil.ErrRotationInProgress) {
+				return fmt.Errorf("%w; rotate again after its grace window ends, or use --force to invalidate the tokens signed with it", err)
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Rotated the JWT secret in %s; tokens signed with the previous secret are accepted until %s\n",
+				cfg.SecretFile, keys.PreviousUntil.Format(time.RFC3339))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "rotate even while the previous secret is still accepted")
+	return cmd
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app"
+	"BackofficeGoService/internal/database/migrations"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand(e *env) *cobra.Command {
+	var name string
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply, roll back or list database migrations",
+		RunE:  requireSubcommand,
+	}
+	cmd.PersistentFlags().StringVar(&name, "database", database.PrimaryDriver, `database to migrate: "primary" or a name from database.databases`)
+
+	up := &cobra.Command{
+		Use:   "up",
+		Short: "Apply pending migrations",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMigrations(cmd.Context(), e.cfg, name, func(runner *migrations.Runner) error {
+				applied, err := runner.Up(cmd.Context())
+				for _, id := range applied {
+					fmt.Fprintln(cmd.OutOrStdout(), "Applied", id)
+				}
+				if err != nil {
+					return err
+				}
+				if len(applied) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "Nothing to migrate")
+				}
+				return nil
+			})
+		},
+	}
+
+	var steps int
+	down := &cobra.Command{
+		Use:   "down",
+		Short: "Roll back the most recently applied migrations",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if steps < 1 {
+				return usageErrorf("--steps must be at least 1")
+			}
+			return withMigrations(cmd.Context(), e.cfg, name, func(runner *migrations.Runner) error {
+				reverted, err := runner.Down(cmd.Context(), steps)
+				for _, id := range reverted {
+					fmt.Fprintln(cmd.OutOrStdout(), "Rolled back", id)
+				}
+				if err != nil {
+					return err
+				}
+				if len(reverted) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "Nothing to roll back")
+				}
+				return nil
+			})
+		},
+	}
+	down.Flags().IntVar(&steps, "steps", 1, "number of migrations to roll back")
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "List migrations and whether they are applied",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMigrations(cmd.Context(), e.cfg, name, func(runner *migrations.Runner) error {
+				statuses, err := runner.Status(cmd.Context())
+				if err != nil {
+					return err
+				}
+
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "MIGRATION\tSTATUS\tAPPLIED AT")
+				for _, s := range statuses {
+					state, appliedAt := "pending", "-"
+					if s.Applied {
+						state = "applied"
+						if s.AppliedAt != nil {
+							appliedAt = s.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+						}
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\n", s.ID, state, appliedAt)
+				}
+				return w.Flush()
+			})
+		},
+	}
+
+	cmd.AddCommand(up, down, status)
+	return cmd
+}
+
+// withMigrations connects the named database for the duration of run
+func withMigrations(ctx context.Context, cfg *config.Config, name string, run func(runner *migrations.Runner) error) error {
+	dbc, err := connection(cfg, name)
+	if err != nil {
+		return err
+	}
+
+	driver, err := app.OpenDatabase(ctx, dbc)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database %s: %w", name, err)
+	}
+	defer driver.Close()
+
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		return err
+	}
+	return run(migrations.NewRunner(db))
+}
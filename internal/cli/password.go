@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// minPasswordLength matches the API's validation of new passwords
+const minPasswordLength = 6
+
+// readPassword reads a password for cmd. With fromStdin it is the first
+// line of stdin, for scripts; otherwise the user is prompted on the
+// terminal without echo, twice. The password is never written anywhere, and
+// errors do not include it.
+func readPassword(cmd *cobra.Command, fromStdin bool) (string, error) {
+	var password string
+	if fromStdin {
+		line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("failed to read the password from stdin: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+		if password == "" {
+			return "", usageErrorf("--password-stdin was given but stdin is empty")
+		}
+	} else {
+		in, ok := cmd.InOrStdin().(*os.File)
+		if !ok || !term.IsTerminal(int(in.Fd())) {
+			return "", usageErrorf("stdin is not a terminal; pipe the password in with --password-stdin")
+		}
+
+		first, err := prompt(cmd, in, "Password: ")
+		if err != nil {
+			return "", err
+		}
+		confirm, err := prompt(cmd, in, "Confirm password: ")
+		if err != nil {
+			return "", err
+		}
+		if first != confirm {
+			return "", errors.New("passwords do not match")
+		}
+		password = first
+	}
+
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	return password, nil
+}
+
+// prompt reads one line from the terminal in without echoing it
+func prompt(cmd *cobra.Command, in *os.File, label string) (string, error) {
+	fmt.Fprint(cmd.ErrOrStderr(), label)
+	password, err := term.ReadPassword(int(in.Fd()))
+	fmt.Fprintln(cmd.ErrOrStderr())
+	if err != nil {
+		return "", fmt.Errorf("failed to read the password: %w", err)
+	}
+	return string(password), nil
+}
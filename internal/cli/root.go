@@ -0,0 +1,159 @@
+// Package cli implements the service's command line. Every command loads
+// the configuration the same way and sets up only what it needs: serve runs
+// the application, while migrate, seed, user and backup connect to the
+// database alone.
+//
+//	backoffice-service serve
+//	backoffice-service migrate up
+//	backoffice-service config show --format json
+//	backoffice-service selftest --timeout 5s
+//	backoffice-service rotate-jwt-secret
+//	backoffice-service backup create
+//	echo "$PASSWORD" | backoffice-service user create --email a@example.com --role admin --password-stdin
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app"
+	"BackofficeGoService/internal/pkg/buildinfo"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by Execute
+const (
+	ExitOK    = 0
+	ExitError = 1
+	// ExitUsage is returned for unknown commands, flags and arguments
+	ExitUsage = 2
+)
+
+// Option configures the root command
+type Option func(e *env)
+
+// WithConfigLoader replaces config.LoadConfig, which reads the environment
+// and .env
+func WithConfigLoader(load func() (*config.Config, error)) Option {
+	return func(e *env) {
+		e.loadConfig = load
+	}
+}
+
+// env is shared by the commands of one root
+type env struct {
+	loadConfig func() (*config.Config, error)
+
+	// cfg is loaded before any command runs
+	cfg *config.Config
+}
+
+// NewRootCommand builds the command tree. Run without a command, it serves
+// like serve does, so existing deployments keep working.
+func NewRootCommand(opts ...Option) *cobra.Command {
+	e := &env{loadConfig: config.LoadConfig}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	serve := newServeCommand(e)
+	root := &cobra.Command{
+		Use:     "backoffice-service",
+		Short:   "Backoffice API service",
+		Version: buildinfo.Get().String(),
+		Args:    usageArgs(cobra.NoArgs),
+		RunE:    serve.RunE,
+
+		// Execute reports errors, with the usage hint only for usage errors
+		SilenceErrors: true,
+		SilenceUsage:  true,
+
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Name() == "help" {
+				return nil
+			}
+			cfg, err := e.loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load configuration: %w", err)
+			}
+			// APP_VERSION wins when set; otherwise report the version built in
+			cfg.App.Version = app.BuildInfo(cfg).Version
+			e.cfg = cfg
+			return nil
+		},
+	}
+	root.CompletionOptions.DisableDefaultCmd = true
+	root.SetVersionTemplate("backoffice-service {{.Version}}\n")
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &usageError{err: err}
+	})
+
+	root.AddCommand(
+		serve,
+		newMigrateCommand(e),
+		newSeedCommand(e),
+		newUserCommand(e),
+		newRoutesCommand(e),
+		newConfigCommand(e),
+		newSelfTestCommand(e),
+		newRotateJWTSecretCommand(e),
+		newBackupCommand(e),
+	)
+	return root
+}
+
+// Execute runs root and returns the process exit code. Errors go to the
+// command's stderr.
+func Execute(root *cobra.Command) int {
+	cmd, err := root.ExecuteC()
+	if err == nil {
+		return ExitOK
+	}
+
+	fmt.Fprintln(root.ErrOrStderr(), "Error:", err)
+	var usage *usageError
+	if errors.As(err, &usage) {
+		fmt.Fprintf(root.ErrOrStderr(), "Run '%s --help' for usage.\n", cmd.CommandPath())
+		return ExitUsage
+	}
+	return ExitError
+}
+
+// usageError is an error in how a command was invoked rather than in
+// running it
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// usageErrorf formats a usageError
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{err: fmt.Errorf(format, args...)}
+}
+
+// usageArgs marks the errors of an argument validator as usage errors
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return &usageError{err: err}
+		}
+		return nil
+	}
+}
+
+// requireSubcommand is the RunE of command groups, which do nothing on their own
+func requireSubcommand(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("unknown command %q for %q", args[0], cmd.CommandPath())
+	}
+	return usageErrorf("%s requires a subcommand", cmd.CommandPath())
+}
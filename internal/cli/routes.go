@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"BackofficeGoService/internal/app"
+
+	"github.com/spf13/cobra"
+)
+
+func newRoutesCommand(e *env) *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "routes",
+		Short: "Print the registered route table",
+		Long:  "Print every route the server registers. The table lists handlers; json and markdown add the policies: authentication, roles, permission, rate limit and feature. Nothing is connected: the routes are those of an application built on an empty in-memory database.",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" && format != "markdown" {
+				return usageErrorf("unknown format %q: use table, json or markdown", format)
+			}
+			routes, err := app.Routes(e.cfg)
+			if err != nil {
+				return fmt.Errorf("failed to build the routes: %w", err)
+			}
+
+			switch format {
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(routes)
+			case "markdown":
+				_, err := fmt.Fprint(cmd.OutOrStdout(), routes.Markdown())
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "METHOD\tPATH\tHANDLER")
+			for _, route := range routes {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", route.Method, route.Path, route.Handler)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "table", "output format: table, json or markdown")
+	return cmd
+}
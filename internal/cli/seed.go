@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/services"
+
+	"github.com/spf13/cobra"
+)
+
+// seedPassword is the password of every seeded user. Seeding is refused in
+// production, so it never guards real data.
+const seedPassword = "password"
+
+func newSeedCommand(e *env) *cobra.Command {
+	var (
+		count int
+		force bool
+	)
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Create development users",
+		Long: fmt.Sprintf(`Create admin@example.com and --users demo users (user1@example.com, ...)
+with the password %q. Users that already exist are skipped, so seeding
+twice is harmless. Roles, permissions and settings come with the
+migrations; run migrate up first.`, seedPassword),
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if e.cfg.App.Environment == "production" && !force {
+				return errors.New("refusing to seed a production database; pass --force to seed anyway")
+			}
+			if count < 0 {
+				return usageErrorf("--users must not be negative")
+			}
+
+			users, closeDB, err := openUserService(cmd.Context(), e.cfg)
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			seeds := []services.CreateUserRequest{{
+				Email:     "admin@example.com",
+				FirstName: "Admin",
+				Role:      models.RoleAdmin,
+			}}
+			for i := 1; i <= count; i++ {
+				seeds = append(seeds, services.CreateUserRequest{
+					Email:     fmt.Sprintf("user%d@example.com", i),
+					FirstName: "User",
+					LastName:  fmt.Sprint(i),
+					Role:      models.RoleUser,
+				})
+			}
+
+			created := 0
+			for _, seed := range seeds {
+				seed.Password = seedPassword
+				_, err := users.CreateUser(cmd.Context(), &seed, "")
+				if errors.Is(err, services.ErrEmailTaken) {
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("failed to seed %s: %w", seed.Email, err)
+				}
+				created++
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Seeded %d users (%d already existed)\n", created, len(seeds)-created)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&count, "users", 10, "number of demo users besides the admin")
+	cmd.Flags().BoolVar(&force, "force", false, "seed even when APP_ENV is production")
+	return cmd
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"BackofficeGoService/internal/app"
+	"BackofficeGoService/internal/pkg/health"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+func newSelfTestCommand(e *env) *cobra.Command {
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "selftest",
+		Short: "Check that every dependency the server needs can be used",
+		Long:  "Connect to each configured database and check the primary one's migrations, write, read and delete a probe file in storage, reach the email provider and the message broker, and sign and verify a token with each JWT secret. The checks are those of readiness where one exists. Exits non-zero when a required check fails; optional ones are reported but do not fail the command.",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if timeout <= 0 {
+				return usageErrorf("--timeout must be positive")
+			}
+
+			checks := app.SelfTestChecks(e.cfg, logger.NewNopLogger())
+			registry := health.NewRegistry(0, health.WithTimeout(timeout))
+			registry.Register(checks...)
+			report := registry.Run(cmd.Context())
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "CHECK\tRESULT\tREQUIRED\tDURATION\tERROR")
+			failed := 0
+			for _, check := range checks {
+				result := report.Checks[check.Name()]
+				outcome, message := "pass", "-"
+				if result.Status != health.StatusUp {
+					outcome, message = "fail", result.Error
+					if result.Critical {
+						failed++
+					}
+				}
+				required := "no"
+				if result.Critical {
+					required = "yes"
+				}
+				duration := time.Duration(result.LatencyMS * float64(time.Millisecond)).Round(time.Millisecond)
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", check.Name(), outcome, required, duration, message)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("self-test failed: %d required checks failed", failed)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "how long each check may take")
+	return cmd
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// shutdownTimeout is how long serve gives the application to stop
+const shutdownTimeout = 30 * time.Second
+
+func newServeCommand(e *env) *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the HTTP and gRPC APIs until interrupted",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serve(cmd.Context(), e.cfg)
+		},
+	}
+}
+
+// serve runs the application until SIGINT or SIGTERM, then shuts it down
+func serve(ctx context.Context, cfg *config.Config) error {
+	appLogger, err := newLogger(cfg)
+	if err != nil {
+		return err
+	}
+
+	build := app.BuildInfo(cfg)
+	appLogger.Info("Application starting",
+		logger.Field{Key: "version", Value: build.Version},
+		logger.Field{Key: "commit", Value: build.Commit},
+		logger.Field{Key: "build_date", Value: build.BuildDate},
+		logger.Field{Key: "go_version", Value: build.GoVersion},
+	)
+
+	application, err := app.New(cfg, appLogger)
+	if err != nil {
+		return fmt.Errorf("failed to create application: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- application.Start()
+	}()
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case err := <-served:
+		// The listener could not be opened, or the server failed
+		if !errors.Is(err, http.ErrServerClosed) {
+			serveErr = fmt.Errorf("failed to start server: %w", err)
+		}
+	}
+
+	// The context is used to inform the server it has 30 seconds to finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := application.Shutdown(shutdownCtx); err != nil {
+		appLogger.Error("Server forced to shutdown", logger.Field{Key: "error", Value: err.Error()})
+		if serveErr == nil {
+			serveErr = fmt.Errorf("server forced to shutdown: %w", err)
+		}
+	}
+	return serveErr
+}
+
+// newLogger creates the logger configured by LOG_CHANNEL. The application
+// closes it as the last step of Shutdown.
+func newLogger(cfg *config.Config) (logger.Logger, error) {
+	switch logger.LoggerType(cfg.Logging.Channel) {
+	case logger.LoggerTypeFile, logger.LoggerTypeStack:
+		fileConfig := logger.FileLoggerConfig{
+			LogPath:     cfg.Logging.LogPath,
+			LogFileName: cfg.Logging.LogFileName,
+			MaxSize:     cfg.Logging.MaxSize,
+			MaxBackups:  cfg.Logging.MaxBackups,
+			MaxAge:      cfg.Logging.MaxAge,
+			Compress:    cfg.Logging.Compress,
+			LocalTime:   true,
+			DailyRotate: cfg.Logging.DailyRotate,
+		}
+
+		appLogger, err := logger.NewLoggerFactory().CreateLogger(logger.LoggerType(cfg.Logging.Channel), fileConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create file logger: %w", err)
+		}
+		return appLogger, nil
+
+	default:
+		return logger.NewSimpleLogger(), nil
+	}
+}
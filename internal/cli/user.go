@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/services"
+
+	"github.com/spf13/cobra"
+)
+
+// roles lists the roles user create accepts
+var roles = map[models.UserRole]bool{
+	models.RoleAdmin: true,
+	models.RoleUser:  true,
+	models.RoleGuest: true,
+}
+
+func newUserCommand(e *env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage users without going through the API",
+		RunE:  requireSubcommand,
+	}
+	cmd.AddCommand(newUserCreateCommand(e), newUserResetPasswordCommand(e), newUserDuplicatesCommand(e), newUserNormalizeEmailsCommand(e), newUserIssueTokenCommand(e))
+	return cmd
+}
+
+func newUserCreateCommand(e *env) *cobra.Command {
+	var (
+		email     string
+		role      string
+		fromStdin bool
+	)
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a user, such as the first admin",
+		Long: `Create a user in the primary database. The password is read from stdin
+with --password-stdin, or prompted for without echo.`,
+		Example: `  echo "$ADMIN_PASSWORD" | backoffice-service user create --email admin@example.com --role admin --password-stdin`,
+		Args:    usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if email == "" {
+				return usageErrorf("--email is required")
+			}
+			if !roles[models.UserRole(role)] {
+				return usageErrorf("invalid role %q; use admin, user or guest", role)
+			}
+			password, err := readPassword(cmd, fromStdin)
+			if err != nil {
+				return err
+			}
+
+			users, closeDB, err := openUserService(cmd.Context(), e.cfg)
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			// No actor: the audit log records the change as made by the system
+			user, err := users.CreateUser(cmd.Context(), &services.CreateUserRequest{
+				Email:    email,
+				Password: password,
+				Role:     models.UserRole(role),
+			}, "")
+			if errors.Is(err, services.ErrEmailTaken) {
+				return fmt.Errorf("a user with email %s already exists", email)
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Created %s user %s (%s)\n", user.Role, user.Email, user.ID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&email, "email", "", "email address of the user (required)")
+	cmd.Flags().StringVar(&role, "role", string(models.RoleUser), "role of the user: admin, user or guest")
+	cmd.Flags().BoolVar(&fromStdin, "password-stdin", false, "read the password from the first line of stdin")
+	return cmd
+}
+
+func newUserResetPasswordCommand(e *env) *cobra.Command {
+	var (
+		email     string
+		fromStdin bool
+	)
+	cmd := &cobra.Command{
+		Use:   "reset-password",
+		Short: "Set a new password for a user",
+		Long: `Set a new password for the user with the given email. The password is
+read from stdin with --password-stdin, or prompted for without echo.`,
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if email == "" {
+				return usageErrorf("--email is required")
+			}
+			password, err := readPassword(cmd, fromStdin)
+			if err != nil {
+				return err
+			}
+
+			users, closeDB, err := openUserService(cmd.Context(), e.cfg)
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			user, err := users.GetUserByEmail(cmd.Context(), email)
+			if errors.Is(err, services.ErrUserNotFound) {
+				return fmt.Errorf("no user with email %s", email)
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := users.UpdateUser(cmd.Context(), user.ID.String(), &services.UpdateUserRequest{Password: &password}, ""); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Password reset for %s\n", user.Email)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&email, "email", "", "email address of the user (required)")
+	cmd.Flags().BoolVar(&fromStdin, "password-stdin", false, "read the password from the first line of stdin")
+	return cmd
+}
+
+func newUserDuplicatesCommand(e *env) *cobra.Command {
+	return &cobra.Command{
+		Use:   "duplicates",
+		Short: "List users whose email addresses collide once normalized",
+		Long: `List the users of the primary database whose email addresses are the same
+once normalized as configured, oldest first. Email addresses must be unique,
+so these have to be merged or renamed before migrating or running
+normalize-emails. Exits with 1 when there are any.`,
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			users, closeDB, err := openUserService(cmd.Context(), e.cfg)
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			duplicates, err := users.EmailDuplicates(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if len(duplicates) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No duplicate email addresses")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NORMALIZED\tUSER\tSTORED")
+			for _, duplicate := range duplicates {
+				for _, user := range duplicate.Users {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", duplicate.Email, user.ID, user.Email)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			return fmt.Errorf("%d email addresses belong to several users", len(duplicates))
+		},
+	}
+}
+
+func newUserNormalizeEmailsCommand(e *env) *cobra.Command {
+	return &cobra.Command{
+		Use:   "normalize-emails",
+		Short: "Rewrite stored email addresses in their normalized form",
+		Long: `Rewrite the email addresses of the primary database as the server now
+normalizes them, after AUTH_EMAIL_PRESERVE_LOCAL_CASE or
+AUTH_EMAIL_GMAIL_NORMALIZATION changed. Nothing is written while addresses
+collide; see duplicates.`,
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			users, closeDB, err := openUserService(cmd.Context(), e.cfg)
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			changed, err := users.NormalizeEmails(cmd.Context())
+			if errors.Is(err, services.ErrEmailDuplicates) {
+				return fmt.Errorf("%w; list them with duplicates", err)
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Normalized %d email addresses\n", changed)
+			return nil
+		},
+	}
+}
+
+func newUserIssueTokenCommand(e *env) *cobra.Command {
+	var (
+		email string
+		scope string
+		ttl   time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "issue-token",
+		Short: "Issue an API token for automation",
+		Long: `Issue an API token for the user with the given email, carrying an explicit
+scope. The token belongs to no session and cannot be refreshed; it lasts
+--ttl, JWT_EXPIRATION by default, or until the user's tokens are revoked.
+Destructive requests made with it skip the confirmation when its scope is
+listed in API_CONFIRM_EXEMPT_SCOPES. The token is printed to stdout.`,
+		Example: `  backoffice-service user issue-token --email ci@example.com --scope automation --ttl 12h`,
+		Args:    usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if email == "" || scope == "" {
+				return usageErrorf("--email and --scope are required")
+			}
+
+			users, auth, closeDB, err := openAuthService(cmd.Context(), e.cfg)
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			user, err := users.GetUserByEmail(cmd.Context(), email)
+			if errors.Is(err, services.ErrUserNotFound) {
+				return fmt.Errorf("no user with email %s", email)
+			}
+			if err != nil {
+				return err
+			}
+			result, err := auth.IssueAPIToken(cmd.Context(), user.ID.String(), scope, ttl)
+			if errors.Is(err, services.ErrInvalidAPIScope) || errors.Is(err, services.ErrAPITokenLifetime) {
+				return usageErrorf("%v", err)
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), result.Token)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&email, "email", "", "email address of the user (required)")
+	cmd.Flags().StringVar(&scope, "scope", "", "API scope of the token, such as automation (required)")
+	cmd.Flags().DurationVar(&ttl, "ttl", 0, "how long the token lasts; 0 uses JWT_EXPIRATION")
+	return cmd
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0001_create_users",
+		Up: func(tx *gorm.DB) error {
+			// Deployments that predate migrations already have the table
+			if tx.Migrator().HasTable(&models.User{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&models.User{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.User{})
+		},
+	})
+}
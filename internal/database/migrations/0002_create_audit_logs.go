@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0002_create_audit_logs",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&models.AuditLog{}); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateTable(&models.LoginEvent{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.LoginEvent{}, &models.AuditLog{})
+		},
+	})
+}
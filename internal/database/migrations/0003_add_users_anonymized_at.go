@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0003_add_users_anonymized_at",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&models.User{}, "AnonymizedAt") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&models.User{}, "AnonymizedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.User{}, "AnonymizedAt")
+		},
+	})
+}
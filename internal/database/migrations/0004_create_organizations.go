@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0004_create_organizations",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&models.Organization{}); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateTable(&models.OrganizationMember{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.OrganizationMember{}, &models.Organization{})
+		},
+	})
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0005_create_permissions",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&models.Permission{}); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateTable(&models.RolePermission{}); err != nil {
+				return err
+			}
+
+			// Seed defaults that mirror what the built-in roles could do before
+			now := time.Now()
+			permissions := make([]models.Permission, len(models.DefaultPermissions))
+			for i, p := range models.DefaultPermissions {
+				p.CreatedAt = now
+				permissions[i] = p
+			}
+			if err := tx.Create(&permissions).Error; err != nil {
+				return err
+			}
+
+			var grants []models.RolePermission
+			for role, names := range models.DefaultRolePermissions {
+				for _, name := range names {
+					grants = append(grants, models.RolePermission{Role: role, Permission: name, CreatedAt: now})
+				}
+			}
+			if len(grants) == 0 {
+				return nil
+			}
+			return tx.Create(&grants).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.RolePermission{}, &models.Permission{})
+		},
+	})
+}
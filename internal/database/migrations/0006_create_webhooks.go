@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0006_create_webhooks",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&models.Webhook{}); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateTable(&models.WebhookDelivery{}); err != nil {
+				return err
+			}
+
+			// 0005 seeds the current defaults on fresh databases, so only
+			// grant the new permission where it is missing
+			now := time.Now()
+			if err := tx.Where(models.Permission{Name: models.PermissionWebhooksManage}).
+				Attrs(models.Permission{Description: "Manage outgoing webhooks", CreatedAt: now}).
+				FirstOrCreate(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where(models.RolePermission{Role: models.RoleAdmin, Permission: models.PermissionWebhooksManage}).
+				Attrs(models.RolePermission{CreatedAt: now}).
+				FirstOrCreate(&models.RolePermission{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission = ?", models.PermissionWebhooksManage).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("name = ?", models.PermissionWebhooksManage).Delete(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.WebhookDelivery{}, &models.Webhook{})
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0007_add_jobs_permission",
+		Up: func(tx *gorm.DB) error {
+			now := time.Now()
+			if err := tx.Where(models.Permission{Name: models.PermissionJobsManage}).
+				Attrs(models.Permission{Description: "View and trigger background jobs", CreatedAt: now}).
+				FirstOrCreate(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where(models.RolePermission{Role: models.RoleAdmin, Permission: models.PermissionJobsManage}).
+				Attrs(models.RolePermission{CreatedAt: now}).
+				FirstOrCreate(&models.RolePermission{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission = ?", models.PermissionJobsManage).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("name = ?", models.PermissionJobsManage).Delete(&models.Permission{}).Error
+		},
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0008_create_feature_flags",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&models.FeatureFlag{}); err != nil {
+				return err
+			}
+
+			now := time.Now()
+			if err := tx.Where(models.Permission{Name: models.PermissionFeaturesManage}).
+				Attrs(models.Permission{Description: "Manage feature flags", CreatedAt: now}).
+				FirstOrCreate(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where(models.RolePermission{Role: models.RoleAdmin, Permission: models.PermissionFeaturesManage}).
+				Attrs(models.RolePermission{CreatedAt: now}).
+				FirstOrCreate(&models.RolePermission{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission = ?", models.PermissionFeaturesManage).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("name = ?", models.PermissionFeaturesManage).Delete(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.FeatureFlag{})
+		},
+	})
+}
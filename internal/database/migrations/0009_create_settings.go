@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0009_create_settings",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&models.Setting{}); err != nil {
+				return err
+			}
+
+			now := time.Now()
+			for _, def := range models.DefaultSettings {
+				if err := tx.Where(models.Setting{Key: def.Key}).
+					Attrs(models.Setting{Value: def.Default, Type: def.Type, UpdatedAt: now}).
+					FirstOrCreate(&models.Setting{}).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Where(models.Permission{Name: models.PermissionSettingsManage}).
+				Attrs(models.Permission{Description: "Edit application settings", CreatedAt: now}).
+				FirstOrCreate(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where(models.RolePermission{Role: models.RoleAdmin, Permission: models.PermissionSettingsManage}).
+				Attrs(models.RolePermission{CreatedAt: now}).
+				FirstOrCreate(&models.RolePermission{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission = ?", models.PermissionSettingsManage).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("name = ?", models.PermissionSettingsManage).Delete(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.Setting{})
+		},
+	})
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0010_create_notifications",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&models.Notification{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.Notification{})
+		},
+	})
+}
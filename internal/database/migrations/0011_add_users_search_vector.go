@@ -0,0 +1,34 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// usersSearchVector weights names and username above the email, whose
+// separators are turned into spaces so each part can be matched on its own
+const usersSearchVector = `setweight(to_tsvector('simple', coalesce(first_name, '') || ' ' || coalesce(last_name, '') || ' ' || coalesce(username, '')), 'A') ||
+	setweight(to_tsvector('simple', translate(coalesce(email, ''), '@.+_-', '     ')), 'B')`
+
+func init() {
+	register(Migration{
+		ID: "0011_add_users_search_vector",
+		Up: func(tx *gorm.DB) error {
+			// Full-text search is PostgreSQL only; other drivers search with LIKE
+			if tx.Dialector.Name() != "postgres" {
+				return nil
+			}
+			if err := tx.Exec(`ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector
+				GENERATED ALWAYS AS (` + usersSearchVector + `) STORED`).Error; err != nil {
+				return err
+			}
+			return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector)`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "postgres" {
+				return nil
+			}
+			if err := tx.Exec(`DROP INDEX IF EXISTS idx_users_search_vector`).Error; err != nil {
+				return err
+			}
+			return tx.Exec(`ALTER TABLE users DROP COLUMN IF EXISTS search_vector`).Error
+		},
+	})
+}
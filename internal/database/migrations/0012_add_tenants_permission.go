@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0012_add_tenants_permission",
+		Up: func(tx *gorm.DB) error {
+			now := time.Now()
+			if err := tx.Where(models.Permission{Name: models.PermissionTenantsManage}).
+				Attrs(models.Permission{Description: "Register tenants and their databases", CreatedAt: now}).
+				FirstOrCreate(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where(models.RolePermission{Role: models.RoleAdmin, Permission: models.PermissionTenantsManage}).
+				Attrs(models.RolePermission{CreatedAt: now}).
+				FirstOrCreate(&models.RolePermission{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission = ?", models.PermissionTenantsManage).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("name = ?", models.PermissionTenantsManage).Delete(&models.Permission{}).Error
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0013_add_impersonate_permission",
+		Up: func(tx *gorm.DB) error {
+			now := time.Now()
+			if err := tx.Where(models.Permission{Name: models.PermissionUsersImpersonate}).
+				Attrs(models.Permission{Description: "Act as another user with a short-lived token", CreatedAt: now}).
+				FirstOrCreate(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where(models.RolePermission{Role: models.RoleAdmin, Permission: models.PermissionUsersImpersonate}).
+				Attrs(models.RolePermission{CreatedAt: now}).
+				FirstOrCreate(&models.RolePermission{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission = ?", models.PermissionUsersImpersonate).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("name = ?", models.PermissionUsersImpersonate).Delete(&models.Permission{}).Error
+		},
+	})
+}
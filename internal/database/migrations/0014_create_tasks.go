@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0014_create_tasks",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&models.Task{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.Task{})
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0015_add_users_password_policy",
+		Up: func(tx *gorm.DB) error {
+			for _, field := range []string{"PasswordChangedAt", "MustChangePassword"} {
+				if tx.Migrator().HasColumn(&models.User{}, field) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&models.User{}, field); err != nil {
+					return err
+				}
+			}
+			// Existing passwords count from when their user was created
+			return tx.Model(&models.User{}).
+				Where("password_changed_at IS NULL AND password <> ''").
+				UpdateColumn("password_changed_at", gorm.Expr("created_at")).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, field := range []string{"MustChangePassword", "PasswordChangedAt"} {
+				if err := tx.Migrator().DropColumn(&models.User{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0017_create_email_changes",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&models.EmailChange{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.EmailChange{})
+		},
+	})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0018_create_trusted_devices",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&models.TrustedDevice{}); err != nil {
+				return err
+			}
+			if !tx.Migrator().HasColumn(&models.Session{}, "DeviceID") {
+				if err := tx.Migrator().AddColumn(&models.Session{}, "DeviceID"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasIndex(&models.Session{}, "DeviceID") {
+				return nil
+			}
+			return tx.Migrator().CreateIndex(&models.Session{}, "DeviceID")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&models.Session{}, "DeviceID"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.TrustedDevice{})
+		},
+	})
+}
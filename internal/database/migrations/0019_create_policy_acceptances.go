@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0019_create_policy_acceptances",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&models.PolicyAcceptance{}); err != nil {
+				return err
+			}
+			for _, def := range models.DefaultSettings {
+				if def.Key != models.SettingPolicyVersions {
+					continue
+				}
+				return tx.Where(models.Setting{Key: def.Key}).
+					Attrs(models.Setting{Value: def.Default, Type: def.Type, UpdatedAt: time.Now()}).
+					FirstOrCreate(&models.Setting{}).Error
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where(models.Setting{Key: models.SettingPolicyVersions}).Delete(&models.Setting{}).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.PolicyAcceptance{})
+		},
+	})
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0020_add_users_token_version",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&models.User{}, "TokenVersion") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&models.User{}, "TokenVersion")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.User{}, "TokenVersion")
+		},
+	})
+}
@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"fmt"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/emailaddr"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0021_add_users_email_unique",
+		Up: func(tx *gorm.DB) error {
+			// Addresses were stored as typed. They are normalized the default
+			// way; other normalizations are applied by "user normalize-emails".
+			var users []models.User
+			if err := tx.Select("id", "email").Order("created_at, id").Find(&users).Error; err != nil {
+				return err
+			}
+			owners := make(map[string]bool, len(users))
+			conflicts := 0
+			for _, user := range users {
+				email := emailaddr.Normalization{}.Normalize(user.Email)
+				if owners[email] {
+					conflicts++
+				}
+				owners[email] = true
+			}
+			if conflicts > 0 {
+				return fmt.Errorf(`%d users share an email address once it is lowercased; list them with "backoffice-service user duplicates" and resolve them first`, conflicts)
+			}
+			for _, user := range users {
+				email := emailaddr.Normalization{}.Normalize(user.Email)
+				if email == user.Email {
+					continue
+				}
+				if err := tx.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("email", email).Error; err != nil {
+					return err
+				}
+			}
+
+			if tx.Migrator().HasIndex(&models.User{}, "idx_users_email") {
+				if err := tx.Migrator().DropIndex(&models.User{}, "idx_users_email"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasIndex(&models.User{}, "idx_users_email_unique") {
+				return nil
+			}
+			return tx.Migrator().CreateIndex(&models.User{}, "idx_users_email_unique")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&models.User{}, "idx_users_email_unique"); err != nil {
+				return err
+			}
+			// Addresses stay normalized
+			return tx.Exec("CREATE INDEX idx_users_email ON users (email)").Error
+		},
+	})
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0022_create_health_checks",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasTable(&models.HealthCheck{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&models.HealthCheck{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.HealthCheck{})
+		},
+	})
+}
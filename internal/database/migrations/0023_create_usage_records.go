@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0023_create_usage_records",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasTable(&models.UsageRecord{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&models.UsageRecord{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.UsageRecord{})
+		},
+	})
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0024_add_users_api_quota",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&models.User{}, "APIQuota") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&models.User{}, "APIQuota")
+		},
+		Down: func(tx *gorm.DB) error {
+			// GORM rebuilds SQLite tables to drop a column, losing the
+			// users indexes; every supported database drops it in place
+			return tx.Exec("ALTER TABLE users DROP COLUMN api_quota").Error
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0025_add_databases_permission",
+		Up: func(tx *gorm.DB) error {
+			now := time.Now()
+			if err := tx.Where(models.Permission{Name: models.PermissionDatabasesManage}).
+				Attrs(models.Permission{Description: "Connect and remove named databases at runtime", CreatedAt: now}).
+				FirstOrCreate(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where(models.RolePermission{Role: models.RoleAdmin, Permission: models.PermissionDatabasesManage}).
+				Attrs(models.RolePermission{CreatedAt: now}).
+				FirstOrCreate(&models.RolePermission{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission = ?", models.PermissionDatabasesManage).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("name = ?", models.PermissionDatabasesManage).Delete(&models.Permission{}).Error
+		},
+	})
+}
@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0026_create_partners",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&models.Partner{}); err != nil {
+				return err
+			}
+			for _, field := range []string{"PartnerID", "ExternalID"} {
+				if tx.Migrator().HasColumn(&models.User{}, field) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&models.User{}, field); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasIndex(&models.User{}, "idx_users_partner_external") {
+				if err := tx.Migrator().CreateIndex(&models.User{}, "idx_users_partner_external"); err != nil {
+					return err
+				}
+			}
+
+			now := time.Now()
+			if err := tx.Where(models.Permission{Name: models.PermissionPartnersManage}).
+				Attrs(models.Permission{Description: "Manage partners and their signing secrets", CreatedAt: now}).
+				FirstOrCreate(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where(models.RolePermission{Role: models.RoleAdmin, Permission: models.PermissionPartnersManage}).
+				Attrs(models.RolePermission{CreatedAt: now}).
+				FirstOrCreate(&models.RolePermission{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission = ?", models.PermissionPartnersManage).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("name = ?", models.PermissionPartnersManage).Delete(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropIndex(&models.User{}, "idx_users_partner_external"); err != nil {
+				return err
+			}
+			// Dropped in place, as in 0024, so SQLite keeps the users indexes
+			if err := tx.Exec("ALTER TABLE users DROP COLUMN external_id").Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("ALTER TABLE users DROP COLUMN partner_id").Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.Partner{})
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0027_add_emails_permission",
+		Up: func(tx *gorm.DB) error {
+			now := time.Now()
+			if err := tx.Where(models.Permission{Name: models.PermissionEmailsManage}).
+				Attrs(models.Permission{Description: "Preview email templates and send test emails", CreatedAt: now}).
+				FirstOrCreate(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where(models.RolePermission{Role: models.RoleAdmin, Permission: models.PermissionEmailsManage}).
+				Attrs(models.RolePermission{CreatedAt: now}).
+				FirstOrCreate(&models.RolePermission{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission = ?", models.PermissionEmailsManage).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("name = ?", models.PermissionEmailsManage).Delete(&models.Permission{}).Error
+		},
+	})
+}
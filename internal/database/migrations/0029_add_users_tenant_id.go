@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0029_add_users_tenant_id",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&models.User{}, "TenantID") {
+				if err := tx.Migrator().AddColumn(&models.User{}, "TenantID"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasIndex(&models.User{}, "TenantID") {
+				return nil
+			}
+			return tx.Migrator().CreateIndex(&models.User{}, "TenantID")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&models.User{}, "TenantID"); err != nil {
+				return err
+			}
+			// GORM rebuilds SQLite tables to drop a column, losing the
+			// users indexes; every supported database drops it in place
+			return tx.Exec("ALTER TABLE users DROP COLUMN tenant_id").Error
+		},
+	})
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0030_create_known_sign_ins",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&models.KnownSignIn{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.KnownSignIn{})
+		},
+	})
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0031_create_saved_filters",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&models.SavedFilter{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.SavedFilter{})
+		},
+	})
+}
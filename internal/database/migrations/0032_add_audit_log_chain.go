@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0032_add_audit_log_chain",
+		Up: func(tx *gorm.DB) error {
+			// Entries recorded so far stay outside the chain, which starts
+			// with the next one
+			for _, field := range []string{"Sequence", "ActorDigest", "PrevHash", "EntryHash"} {
+				if tx.Migrator().HasColumn(&models.AuditLog{}, field) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&models.AuditLog{}, field); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasIndex(&models.AuditLog{}, "Sequence") {
+				if err := tx.Migrator().CreateIndex(&models.AuditLog{}, "Sequence"); err != nil {
+					return err
+				}
+			}
+
+			if !tx.Migrator().HasTable(&models.AuditChain{}) {
+				if err := tx.Migrator().CreateTable(&models.AuditChain{}); err != nil {
+					return err
+				}
+			}
+			now := time.Now()
+			if err := tx.Where(models.AuditChain{ID: models.AuditChainLogs}).
+				Attrs(models.AuditChain{UpdatedAt: now}).
+				FirstOrCreate(&models.AuditChain{}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Where(models.Permission{Name: models.PermissionAuditView}).
+				Attrs(models.Permission{Description: "Export the audit log and verify its hash chain", CreatedAt: now}).
+				FirstOrCreate(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where(models.RolePermission{Role: models.RoleAdmin, Permission: models.PermissionAuditView}).
+				Attrs(models.RolePermission{CreatedAt: now}).
+				FirstOrCreate(&models.RolePermission{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission = ?", models.PermissionAuditView).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("name = ?", models.PermissionAuditView).Delete(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropTable(&models.AuditChain{}); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropIndex(&models.AuditLog{}, "Sequence"); err != nil {
+				return err
+			}
+			// GORM rebuilds SQLite tables to drop a column, losing the
+			// audit_logs indexes; every supported database drops it in place
+			for _, column := range []string{"entry_hash", "prev_hash", "actor_digest", "sequence"} {
+				if err := tx.Exec("ALTER TABLE audit_logs DROP COLUMN " + column).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"slices"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+// productPermissions are the permissions of the product routes
+var productPermissions = []string{
+	models.PermissionProductsView,
+	models.PermissionProductsCreate,
+	models.PermissionProductsUpdate,
+	models.PermissionProductsDelete,
+}
+
+func init() {
+	register(Migration{
+		ID: "0033_create_products",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasTable(&models.Product{}) {
+				if err := tx.Migrator().CreateTable(&models.Product{}); err != nil {
+					return err
+				}
+			}
+
+			// Databases created before products get their permissions here;
+			// fresh ones already have them from 0005
+			now := time.Now()
+			for _, permission := range models.DefaultPermissions {
+				if !slices.Contains(productPermissions, permission.Name) {
+					continue
+				}
+				if err := tx.Where(models.Permission{Name: permission.Name}).
+					Attrs(models.Permission{Description: permission.Description, CreatedAt: now}).
+					FirstOrCreate(&models.Permission{}).Error; err != nil {
+					return err
+				}
+			}
+			for role, names := range models.DefaultRolePermissions {
+				for _, name := range names {
+					if !slices.Contains(productPermissions, name) {
+						continue
+					}
+					if err := tx.Where(models.RolePermission{Role: role, Permission: name}).
+						Attrs(models.RolePermission{CreatedAt: now}).
+						FirstOrCreate(&models.RolePermission{}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission IN ?", productPermissions).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("name IN ?", productPermissions).Delete(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.Product{})
+		},
+	})
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0034_create_request_logs",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasTable(&models.RequestLog{}) {
+				if err := tx.Migrator().CreateTable(&models.RequestLog{}); err != nil {
+					return err
+				}
+			}
+
+			now := time.Now()
+			if err := tx.Where(models.Permission{Name: models.PermissionRequestLogsView}).
+				Attrs(models.Permission{Description: "Search the database request log", CreatedAt: now}).
+				FirstOrCreate(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where(models.RolePermission{Role: models.RoleAdmin, Permission: models.PermissionRequestLogsView}).
+				Attrs(models.RolePermission{CreatedAt: now}).
+				FirstOrCreate(&models.RolePermission{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission = ?", models.PermissionRequestLogsView).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("name = ?", models.PermissionRequestLogsView).Delete(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.RequestLog{})
+		},
+	})
+}
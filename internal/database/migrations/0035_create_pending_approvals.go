@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0035_create_pending_approvals",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasTable(&models.Approval{}) {
+				if err := tx.Migrator().CreateTable(&models.Approval{}); err != nil {
+					return err
+				}
+			}
+
+			now := time.Now()
+			if err := tx.Where(models.Permission{Name: models.PermissionApprovalsManage}).
+				Attrs(models.Permission{Description: "Approve or reject changes awaiting a second admin", CreatedAt: now}).
+				FirstOrCreate(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where(models.RolePermission{Role: models.RoleAdmin, Permission: models.PermissionApprovalsManage}).
+				Attrs(models.RolePermission{CreatedAt: now}).
+				FirstOrCreate(&models.RolePermission{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("permission = ?", models.PermissionApprovalsManage).Delete(&models.RolePermission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("name = ?", models.PermissionApprovalsManage).Delete(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.Approval{})
+		},
+	})
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0036_create_user_identities",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&models.UserIdentity{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.UserIdentity{})
+		},
+	})
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0037_create_alerts",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&models.Alert{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.Alert{})
+		},
+	})
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"BackofficeGoService/internal/app/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		ID: "0038_add_sessions_location",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&models.Session{}, "Location") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&models.Session{}, "Location")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.Session{}, "Location")
+		},
+	})
+}
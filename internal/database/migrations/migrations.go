@@ -0,0 +1,179 @@
+// Package migrations holds the versioned schema migrations and the runner
+// that applies them. Migrations are written against GORM's Migrator so the
+// same definitions work on every supported SQL dialect.
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	// ID orders migrations and is recorded once applied, e.g. "0001_create_users"
+	ID string
+
+	// Up applies the change
+	Up func(tx *gorm.DB) error
+
+	// Down reverts the change
+	Down func(tx *gorm.DB) error
+}
+
+// registry holds every known migration
+var registry []Migration
+
+// register adds a migration to the registry; called from each migration file's init
+func register(m Migration) {
+	registry = append(registry, m)
+}
+
+// All returns every registered migration ordered by ID
+func All() []Migration {
+	all := make([]Migration, len(registry))
+	copy(all, registry)
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all
+}
+
+// schemaMigration records an applied migration
+type schemaMigration struct {
+	ID        string    `gorm:"size:255;primaryKey"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status describes whether a migration has been applied
+type Status struct {
+	ID        string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// Runner applies and reverts migrations
+type Runner struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewRunner creates a runner for every registered migration
+func NewRunner(db *gorm.DB) *Runner {
+	return &Runner{
+		db:         db,
+		migrations: All(),
+	}
+}
+
+// ensureTable creates the schema_migrations table if needed
+func (r *Runner) ensureTable(ctx context.Context) error {
+	db := r.db.WithContext(ctx)
+	if db.Migrator().HasTable(&schemaMigration{}) {
+		return nil
+	}
+	return db.Migrator().CreateTable(&schemaMigration{})
+}
+
+// applied returns the applied migrations keyed by ID
+func (r *Runner) applied(ctx context.Context) (map[string]schemaMigration, error) {
+	if err := r.ensureTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var rows []schemaMigration
+	if err := r.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	applied := make(map[string]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.ID] = row
+	}
+	return applied, nil
+}
+
+// Up applies every pending migration in order and returns the IDs applied
+func (r *Runner) Up(ctx context.Context) ([]string, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []string
+	for _, m := range r.migrations {
+		if _, ok := applied[m.ID]; ok {
+			continue
+		}
+
+		err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %s failed: %w", m.ID, err)
+		}
+		ran = append(ran, m.ID)
+	}
+
+	return ran, nil
+}
+
+// Down reverts the most recently applied migrations, up to steps of them
+func (r *Runner) Down(ctx context.Context, steps int) ([]string, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []string
+	for i := len(r.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := r.migrations[i]
+		if _, ok := applied[m.ID]; !ok {
+			continue
+		}
+		if m.Down == nil {
+			return reverted, fmt.Errorf("migration %s cannot be reverted", m.ID)
+		}
+
+		err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{ID: m.ID}).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %s failed: %w", m.ID, err)
+		}
+		reverted = append(reverted, m.ID)
+	}
+
+	return reverted, nil
+}
+
+// Status reports every migration and whether it has been applied
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		status := Status{ID: m.ID}
+		if row, ok := applied[m.ID]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,139 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"BackofficeGoService/internal/pkg/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethodPrefixes are reachable without a token so probes and grpcurl work
+var publicMethodPrefixes = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.",
+}
+
+// isPublicMethod reports whether fullMethod skips authentication
+func isPublicMethod(fullMethod string) bool {
+	for _, prefix := range publicMethodPrefixes {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize checks the bearer token in the call's metadata
+func authorize(ctx context.Context, fullMethod, authToken string) error {
+	if isPublicMethod(fullMethod) {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return status.Error(codes.Unauthenticated, "authorization metadata required")
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || authToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(authToken)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return nil
+}
+
+func unaryAuth(authToken string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, info.FullMethod, authToken); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamAuth(authToken string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(ss.Context(), info.FullMethod, authToken); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// recovered turns a panic into an Internal error so one bad call cannot take
+// the process down
+func recovered(log logger.Logger, fullMethod string, r interface{}) error {
+	log.Error("gRPC handler panicked",
+		logger.Field{Key: "method", Value: fullMethod},
+		logger.Field{Key: "panic", Value: r},
+		logger.Field{Key: "stack", Value: string(debug.Stack())},
+	)
+	return status.Error(codes.Internal, "internal error")
+}
+
+func unaryRecovery(log logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(log, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func streamRecovery(log logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(log, info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// logCall logs a finished call at a level matching its status code
+func logCall(log logger.Logger, fullMethod string, start time.Time, err error) {
+	code := status.Code(err)
+	fields := []logger.Field{
+		{Key: "method", Value: fullMethod},
+		{Key: "code", Value: code.String()},
+		{Key: "latency", Value: time.Since(start)},
+	}
+
+	switch code {
+	case codes.OK:
+		log.Info("gRPC request", fields...)
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		log.Error("gRPC request", append(fields, logger.Field{Key: "error", Value: err.Error()})...)
+	default:
+		log.Warn("gRPC request", fields...)
+	}
+}
+
+func unaryLogging(log logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(log, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func streamLogging(log logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(log, info.FullMethod, start, err)
+		return err
+	}
+}
@@ -0,0 +1,77 @@
+// Package grpcserver serves the internal gRPC API used by other backoffice
+// services. Handlers are thin adapters over the existing services.
+package grpcserver
+
+import (
+	"context"
+	"net"
+
+	userv1 "BackofficeGoService/api/gen/backoffice/user/v1"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server is the internal gRPC server with health checking and reflection
+type Server struct {
+	grpc   *grpc.Server
+	health *health.Server
+}
+
+// NewServer creates a gRPC server exposing the user API. Every call except
+// health checks and reflection must present authToken.
+func NewServer(users UserLookup, tokens TokenValidator, authToken string, log logger.Logger) *Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			unaryLogging(log),
+			unaryRecovery(log),
+			unaryAuth(authToken),
+		),
+		grpc.ChainStreamInterceptor(
+			streamLogging(log),
+			streamRecovery(log),
+			streamAuth(authToken),
+		),
+	)
+
+	userv1.RegisterUserServiceServer(server, newUserServer(users, tokens))
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(userv1.UserService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return &Server{
+		grpc:   server,
+		health: healthServer,
+	}
+}
+
+// Serve accepts connections on lis until the server is stopped
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpc.Serve(lis)
+}
+
+// Stop reports NOT_SERVING to health checks and waits for in-flight calls. If
+// ctx expires first, remaining calls are cancelled.
+func (s *Server) Stop(ctx context.Context) error {
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,121 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	userv1 "BackofficeGoService/api/gen/backoffice/user/v1"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxBatchSize caps GetUsersByIDs so one call cannot scan the users table
+const maxBatchSize = 100
+
+// UserLookup resolves users by ID
+type UserLookup interface {
+	GetUser(ctx context.Context, id string) (*models.User, error)
+	GetUsersByIDs(ctx context.Context, ids []string) ([]*models.User, error)
+}
+
+// TokenValidator validates user access tokens
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, token string) (*services.TokenClaims, error)
+}
+
+// userServer implements userv1.UserServiceServer over the user and auth services
+type userServer struct {
+	userv1.UnimplementedUserServiceServer
+
+	users  UserLookup
+	tokens TokenValidator
+}
+
+func newUserServer(users UserLookup, tokens TokenValidator) *userServer {
+	return &userServer{users: users, tokens: tokens}
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.GetUserResponse, error) {
+	if _, err := uuid.Parse(req.GetId()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "id must be a UUID")
+	}
+
+	user, err := s.users.GetUser(ctx, req.GetId())
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to fetch user")
+	}
+
+	return &userv1.GetUserResponse{User: toProtoUser(user)}, nil
+}
+
+func (s *userServer) GetUsersByIDs(ctx context.Context, req *userv1.GetUsersByIDsRequest) (*userv1.GetUsersByIDsResponse, error) {
+	ids := req.GetIds()
+	if len(ids) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d ids per call", maxBatchSize)
+	}
+
+	users, err := s.users.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch users")
+	}
+
+	found := make(map[string]bool, len(users))
+	resp := &userv1.GetUsersByIDsResponse{Users: make([]*userv1.User, 0, len(users))}
+	for _, user := range users {
+		found[user.ID.String()] = true
+		resp.Users = append(resp.Users, toProtoUser(user))
+	}
+	for _, id := range ids {
+		// Compare canonical forms so differently cased IDs still match
+		if parsed, err := uuid.Parse(id); err == nil && found[parsed.String()] {
+			continue
+		}
+		resp.MissingIds = append(resp.MissingIds, id)
+	}
+	return resp, nil
+}
+
+func (s *userServer) ValidateToken(ctx context.Context, req *userv1.ValidateTokenRequest) (*userv1.ValidateTokenResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	claims, err := s.tokens.ValidateToken(ctx, req.GetToken())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+	if claims.Restricted() {
+		return nil, status.Error(codes.PermissionDenied, "password change required")
+	}
+
+	return &userv1.ValidateTokenResponse{
+		UserId:    claims.UserID,
+		Email:     claims.Email,
+		Role:      claims.Role,
+		OrgIds:    claims.OrgIDs,
+		ExpiresAt: timestamppb.New(claims.ExpiresAt),
+	}, nil
+}
+
+// toProtoUser converts a user model to its wire form
+func toProtoUser(user *models.User) *userv1.User {
+	return &userv1.User{
+		Id:        user.ID.String(),
+		Email:     user.Email,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Role:      string(user.Role),
+		Active:    user.Active,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+		UpdatedAt: timestamppb.New(user.UpdatedAt),
+	}
+}
@@ -1,9 +1,16 @@
 package email
 
+import "context"
+
 // EmailClient interface for email operations
 type EmailClient interface {
 	Send(to, subject, body string) error
 }
 
-// TODO: Implement email client (SMTP, SendGrid, etc.)
+// Pinger is implemented by email clients that can check their connection
+// without sending mail, such as an SMTP NOOP
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
 
+// TODO: Implement email client (SMTP, SendGrid, etc.)
@@ -0,0 +1,8 @@
+package email
+
+import "BackofficeGoService/internal/pkg/health"
+
+// NewHealthCheck returns a readiness checker that pings client
+func NewHealthCheck(client Pinger, critical bool) health.Checker {
+	return health.NewCheck("email", critical, client.Ping)
+}
@@ -0,0 +1,24 @@
+package email
+
+import "BackofficeGoService/internal/pkg/logger"
+
+// LogClient is an EmailClient that logs messages instead of sending them,
+// for development and tests
+type LogClient struct {
+	logger logger.Logger
+}
+
+// NewLogClient creates an email client writing every message to log
+func NewLogClient(log logger.Logger) *LogClient {
+	return &LogClient{logger: log}
+}
+
+// Send logs the message at info level
+func (c *LogClient) Send(to, subject, body string) error {
+	c.logger.Info("Email not sent (log driver)",
+		logger.Field{Key: "to", Value: to},
+		logger.Field{Key: "subject", Value: subject},
+		logger.Field{Key: "body", Value: body},
+	)
+	return nil
+}
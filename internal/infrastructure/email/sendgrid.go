@@ -0,0 +1,102 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultSendGridURL is the SendGrid v3 mail send endpoint
+const DefaultSendGridURL = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridClient is an EmailClient sending plain text email through the
+// SendGrid v3 API
+type SendGridClient struct {
+	client *http.Client
+	url    string
+	apiKey string
+	from   string
+}
+
+// NewSendGridClient creates a client sending email from the from address
+// with apiKey. client should come from httpclient.New; an empty url is
+// DefaultSendGridURL.
+func NewSendGridClient(client *http.Client, url, apiKey, from string) *SendGridClient {
+	if url == "" {
+		url = DefaultSendGridURL
+	}
+	return &SendGridClient{client: client, url: url, apiKey: apiKey, from: from}
+}
+
+// sendGridAddress is an address in a SendGrid request
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+// sendGridPersonalization names the recipients of a message
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+// sendGridContent is a body of a message, in one content type
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// sendGridMessage is the body of a mail send request
+type sendGridMessage struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+// Send sends a plain text email to one recipient. SendGrid accepts the
+// message with 202; any other status is an error.
+func (c *SendGridClient) Send(to, subject, body string) error {
+	payload, err := json.Marshal(sendGridMessage{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: to}}}},
+		From:             sendGridAddress{Email: c.from},
+		Subject:          subject,
+		Content:          []sendGridContent{{Type: "text/plain", Value: body}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode email: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("sendgrid answered %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// Ping checks that the mail send endpoint answers, without sending mail.
+// Any HTTP response will do; only failing to reach the endpoint is an error.
+func (c *SendGridClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sendgrid unreachable: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
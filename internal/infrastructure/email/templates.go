@@ -0,0 +1,196 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"sync"
+	texttemplate "text/template"
+)
+
+// Template errors
+var (
+	ErrTemplateNotFound  = errors.New("email template not found")
+	ErrTemplateExists    = errors.New("email template already registered")
+	ErrFormatUnsupported = errors.New("email template has no body in this format")
+)
+
+// Format is a body format a template can be rendered in
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatHTML Format = "html"
+)
+
+// Template is an email the application sends. Subject and Text are
+// text/template sources and HTML an optional html/template source, all
+// executed with the same data. Referring to a field or key the data does
+// not have is a render error rather than an empty string.
+type Template struct {
+	Name        string
+	Description string
+	Subject     string
+	Text        string
+	HTML        string
+	// Sample is representative data of the type the template is executed
+	// with, used to preview it
+	Sample interface{}
+}
+
+// TemplateInfo describes a registered template
+type TemplateInfo struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Formats     []Format    `json:"formats"`
+	Sample      interface{} `json:"sample"`
+}
+
+// Message is a rendered template
+type Message struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	Format  Format `json:"format"`
+}
+
+// RenderError is returned when a template fails to execute with its data
+type RenderError struct {
+	Template string
+	Err      error
+}
+
+func (e *RenderError) Error() string {
+	return fmt.Sprintf("email template %s: %v", e.Template, e.Err)
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// Registry holds the email templates by name
+type Registry struct {
+	mu        sync.RWMutex
+	templates map[string]*parsedTemplate
+}
+
+// parsedTemplate is a registered template with its parsed sources
+type parsedTemplate struct {
+	Template
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// NewRegistry creates an empty template registry
+func NewRegistry() *Registry {
+	return &Registry{templates: make(map[string]*parsedTemplate)}
+}
+
+// Register parses t and adds it to the registry. It fails if a source does
+// not parse, if t has no name, text or sample, or if the name is taken.
+func (r *Registry) Register(t Template) error {
+	if t.Name == "" || t.Text == "" || t.Sample == nil {
+		return fmt.Errorf("email template %q needs a name, a text body and sample data", t.Name)
+	}
+
+	parsed := &parsedTemplate{Template: t}
+	var err error
+	if parsed.subject, err = texttemplate.New(t.Name + ".subject").Option("missingkey=error").Parse(t.Subject); err != nil {
+		return fmt.Errorf("email template %s: %w", t.Name, err)
+	}
+	if parsed.text, err = texttemplate.New(t.Name + ".text").Option("missingkey=error").Parse(t.Text); err != nil {
+		return fmt.Errorf("email template %s: %w", t.Name, err)
+	}
+	if t.HTML != "" {
+		if parsed.html, err = htmltemplate.New(t.Name + ".html").Option("missingkey=error").Parse(t.HTML); err != nil {
+			return fmt.Errorf("email template %s: %w", t.Name, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.templates[t.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrTemplateExists, t.Name)
+	}
+	r.templates[t.Name] = parsed
+	return nil
+}
+
+// MustRegister registers t, panicking if it is not valid; for templates
+// built into the application
+func (r *Registry) MustRegister(t Template) {
+	if err := r.Register(t); err != nil {
+		panic(err)
+	}
+}
+
+// Templates describes every registered template, ordered by name
+func (r *Registry) Templates() []TemplateInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]TemplateInfo, 0, len(r.templates))
+	for _, t := range r.templates {
+		infos = append(infos, t.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Template describes the template registered under name
+func (r *Registry) Template(name string) (TemplateInfo, error) {
+	t, err := r.lookup(name)
+	if err != nil {
+		return TemplateInfo{}, err
+	}
+	return t.info(), nil
+}
+
+// Render executes the template registered under name with data. It fails
+// with ErrTemplateNotFound, ErrFormatUnsupported or a *RenderError.
+func (r *Registry) Render(name string, format Format, data interface{}) (*Message, error) {
+	t, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var subject, body bytes.Buffer
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return nil, &RenderError{Template: name, Err: err}
+	}
+	switch {
+	case format == FormatText:
+		err = t.text.Execute(&body, data)
+	case format == FormatHTML && t.html != nil:
+		err = t.html.Execute(&body, data)
+	default:
+		return nil, fmt.Errorf("%w: %s has no %q body", ErrFormatUnsupported, name, format)
+	}
+	if err != nil {
+		return nil, &RenderError{Template: name, Err: err}
+	}
+	return &Message{Subject: subject.String(), Body: body.String(), Format: format}, nil
+}
+
+// lookup returns the template registered under name
+func (r *Registry) lookup(name string) (*parsedTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return t, nil
+}
+
+// info describes t
+func (t *parsedTemplate) info() TemplateInfo {
+	formats := []Format{FormatText}
+	if t.html != nil {
+		formats = append(formats, FormatHTML)
+	}
+	return TemplateInfo{Name: t.Name, Description: t.Description, Formats: formats, Sample: t.Sample}
+}
@@ -0,0 +1,155 @@
+// Package nats carries domain events over NATS JetStream. Events are
+// published to a stream that keeps them until a durable consumer shared by
+// every replica has handled them.
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/logger"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// EventTypeHeader names the event type on every published message
+const EventTypeHeader = "Event-Type"
+
+// Client is a JetStream connection that publishes events. It implements
+// events.Publisher.
+type Client struct {
+	conn   *natsgo.Conn
+	js     jetstream.JetStream
+	cfg    config.NATSConfig
+	logger logger.Logger
+	closed chan struct{}
+}
+
+// Connect connects to the servers in cfg and creates or updates the event
+// stream. ctx bounds the connection and the stream setup.
+func Connect(ctx context.Context, cfg config.NATSConfig, log logger.Logger) (*Client, error) {
+	closed := make(chan struct{})
+	opts := []natsgo.Option{
+		natsgo.Name(cfg.Name),
+		natsgo.Timeout(cfg.ConnectTimeout),
+		natsgo.DrainTimeout(cfg.DrainTimeout),
+		// Keep reconnecting; readiness reports the connection while it is down
+		natsgo.MaxReconnects(-1),
+		natsgo.DisconnectErrHandler(func(_ *natsgo.Conn, err error) {
+			if err != nil {
+				log.Warn("NATS disconnected", logger.Field{Key: "error", Value: err.Error()})
+			}
+		}),
+		natsgo.ReconnectHandler(func(conn *natsgo.Conn) {
+			log.Info("NATS reconnected", logger.Field{Key: "url", Value: conn.ConnectedUrlRedacted()})
+		}),
+		natsgo.ClosedHandler(func(*natsgo.Conn) { close(closed) }),
+	}
+	if cfg.CredentialsFile != "" {
+		opts = append(opts, natsgo.UserCredentials(cfg.CredentialsFile))
+	}
+	if cfg.TLSCAFile != "" {
+		opts = append(opts, natsgo.RootCAs(cfg.TLSCAFile))
+	}
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		opts = append(opts, natsgo.ClientCert(cfg.TLSCertFile, cfg.TLSKeyFile))
+	}
+
+	conn, err := natsgo.Connect(cfg.URLs, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("nats: connect: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats: jetstream: %w", err)
+	}
+
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       cfg.Stream,
+		Subjects:   []string{cfg.SubjectPrefix + ".>"},
+		Storage:    jetstream.FileStorage,
+		MaxAge:     cfg.MaxAge,
+		Duplicates: cfg.DuplicateWindow,
+	}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats: create stream %s: %w", cfg.Stream, err)
+	}
+
+	return &Client{
+		conn:   conn,
+		js:     js,
+		cfg:    cfg,
+		logger: log,
+		closed: closed,
+	}, nil
+}
+
+// Publish stores event in the stream. The event ID is sent as the message
+// ID, so the stream keeps a retried publish once within the duplicate window.
+func (c *Client) Publish(ctx context.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("nats: encode event: %w", err)
+	}
+
+	msg := natsgo.NewMsg(c.subject(event.Type))
+	msg.Data = data
+	msg.Header.Set(jetstream.MsgIDHeader, event.ID)
+	msg.Header.Set(EventTypeHeader, event.Type)
+
+	ack, err := c.js.PublishMsg(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("nats: publish %s: %w", event.Type, err)
+	}
+	if ack.Duplicate {
+		c.logger.Debug("Duplicate event dropped by NATS", logger.Field{Key: "event_id", Value: event.ID})
+	}
+	return nil
+}
+
+// Health reports whether the connection is up and JetStream answers
+func (c *Client) Health(ctx context.Context) error {
+	if !c.conn.IsConnected() {
+		return fmt.Errorf("nats: connection %s", strings.ToLower(c.conn.Status().String()))
+	}
+	if _, err := c.js.AccountInfo(ctx); err != nil {
+		return fmt.Errorf("nats: jetstream: %w", err)
+	}
+	return nil
+}
+
+// Close drains the connection: pending publishes are flushed and
+// subscriptions finish their messages before it closes, within the drain
+// timeout
+func (c *Client) Close() error {
+	if c.conn.IsClosed() {
+		return nil
+	}
+	if err := c.conn.Drain(); err != nil {
+		c.conn.Close()
+		return fmt.Errorf("nats: drain: %w", err)
+	}
+
+	timer := time.NewTimer(c.cfg.DrainTimeout + time.Second)
+	defer timer.Stop()
+	select {
+	case <-c.closed:
+		return nil
+	case <-timer.C:
+		c.conn.Close()
+		return errors.New("nats: drain timed out")
+	}
+}
+
+// subject returns the subject events of eventType are published on
+func (c *Client) subject(eventType string) string {
+	return c.cfg.SubjectPrefix + "." + eventType
+}
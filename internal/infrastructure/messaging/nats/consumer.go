@@ -0,0 +1,137 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Handler handles an event. An error has the event redelivered until it has
+// been delivered MaxDeliver times.
+type Handler func(ctx context.Context, event events.Event) error
+
+// Consumer runs a handler on the events of the stream through the durable
+// consumer in the config. Replicas sharing the consumer split the events, so
+// each is handled once.
+type Consumer struct {
+	client   *Client
+	consumer jetstream.Consumer
+	handler  Handler
+	logger   logger.Logger
+
+	// ctx is passed to handlers and cancelled when Stop gives up waiting
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	running jetstream.ConsumeContext
+}
+
+// NewConsumer creates or updates the durable consumer of client's stream
+func NewConsumer(ctx context.Context, client *Client, handler Handler, log logger.Logger) (*Consumer, error) {
+	cfg := client.cfg
+	consumer, err := client.js.CreateOrUpdateConsumer(ctx, cfg.Stream, jetstream.ConsumerConfig{
+		Durable:       cfg.Consumer,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       cfg.AckWait,
+		MaxDeliver:    cfg.MaxDeliver,
+		FilterSubject: cfg.SubjectPrefix + ".>",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("nats: create consumer %s: %w", cfg.Consumer, err)
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	return &Consumer{
+		client:   client,
+		consumer: consumer,
+		handler:  handler,
+		logger:   log,
+		ctx:      runCtx,
+		cancel:   cancel,
+	}, nil
+}
+
+// Start begins handling events in the background
+func (c *Consumer) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running != nil {
+		return nil
+	}
+
+	running, err := c.consumer.Consume(c.handle)
+	if err != nil {
+		return fmt.Errorf("nats: consume: %w", err)
+	}
+	c.running = running
+	return nil
+}
+
+// Stop drains the consumer: no more events are fetched and those already
+// fetched are handled. Handlers still running when ctx is done see their
+// context cancelled; their events are redelivered later.
+func (c *Consumer) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	running := c.running
+	c.mu.Unlock()
+	if running == nil {
+		c.cancel()
+		return nil
+	}
+
+	running.Drain()
+	select {
+	case <-running.Closed():
+		c.cancel()
+		return nil
+	case <-ctx.Done():
+		c.cancel()
+		running.Stop()
+		return ctx.Err()
+	}
+}
+
+// handle runs the handler on one message and settles it
+func (c *Consumer) handle(msg jetstream.Msg) {
+	var event events.Event
+	if err := json.Unmarshal(msg.Data(), &event); err != nil {
+		// Redelivering cannot fix a malformed message
+		c.logger.Error("Dropping malformed NATS event", logger.Field{Key: "subject", Value: msg.Subject()}, logger.Field{Key: "error", Value: err.Error()})
+		_ = msg.TermWithReason("malformed event")
+		return
+	}
+
+	err := c.handler(c.ctx, event)
+	if err == nil {
+		if err := msg.Ack(); err != nil {
+			c.logger.Warn("Failed to acknowledge NATS event", logger.Field{Key: "event_id", Value: event.ID}, logger.Field{Key: "error", Value: err.Error()})
+		}
+		return
+	}
+
+	fields := []logger.Field{
+		{Key: "event_id", Value: event.ID},
+		{Key: "event", Value: event.Type},
+		{Key: "error", Value: err.Error()},
+	}
+	if meta, metaErr := msg.Metadata(); metaErr == nil {
+		fields = append(fields, logger.Field{Key: "delivery", Value: meta.NumDelivered})
+		if c.client.cfg.MaxDeliver > 0 && meta.NumDelivered >= uint64(c.client.cfg.MaxDeliver) {
+			c.logger.Error("Giving up on NATS event", fields...)
+			_ = msg.TermWithReason(err.Error())
+			return
+		}
+	}
+	c.logger.Warn("NATS event handler failed, redelivering", fields...)
+	if err := msg.Nak(); err != nil && !errors.Is(err, jetstream.ErrMsgAlreadyAckd) {
+		c.logger.Warn("Failed to reject NATS event", logger.Field{Key: "event_id", Value: event.ID}, logger.Field{Key: "error", Value: err.Error()})
+	}
+}
@@ -1,11 +1,15 @@
 package redis
 
+import "context"
+
 // Client interface for Redis operations
 type Client interface {
 	Get(key string) (string, error)
 	Set(key string, value interface{}, expiration int) error
 	Delete(key string) error
+
+	// Ping checks that the server answers, for readiness checks
+	Ping(ctx context.Context) error
 }
 
 // TODO: Implement Redis client
-
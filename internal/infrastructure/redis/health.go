@@ -0,0 +1,9 @@
+package redis
+
+import "BackofficeGoService/internal/pkg/health"
+
+// NewHealthCheck returns a readiness checker that pings client. Redis only
+// backs caches, so it is usually registered as informational.
+func NewHealthCheck(client Client, critical bool) health.Checker {
+	return health.NewCheck("redis", critical, client.Ping)
+}
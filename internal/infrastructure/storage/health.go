@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"BackofficeGoService/internal/pkg/health"
+)
+
+// NewHealthCheck returns a readiness checker that pings store
+func NewHealthCheck(store Store, critical bool) health.Checker {
+	return health.NewCheck("storage", critical, store.Ping)
+}
+
+// probeKey is where NewProbeCheck writes its probe object
+const probeKey = ".probe/selftest"
+
+// NewProbeCheck returns a checker that writes a probe object to store, reads
+// it back and deletes it. Unlike NewHealthCheck it proves the store is
+// writable, so it suits one-off checks rather than every readiness probe.
+func NewProbeCheck(store Store, critical bool) health.Checker {
+	return health.NewCheck("storage", critical, func(ctx context.Context) error {
+		probe := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+		if err := store.Put(ctx, probeKey, bytes.NewReader(probe)); err != nil {
+			return fmt.Errorf("storage: write probe: %w", err)
+		}
+		defer store.Delete(context.WithoutCancel(ctx), probeKey)
+
+		r, err := store.Open(ctx, probeKey)
+		if err != nil {
+			return fmt.Errorf("storage: read probe: %w", err)
+		}
+		defer r.Close()
+		got, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("storage: read probe: %w", err)
+		}
+		if !bytes.Equal(got, probe) {
+			return errors.New("storage: probe read back differs from what was written")
+		}
+		if err := store.Delete(ctx, probeKey); err != nil {
+			return fmt.Errorf("storage: delete probe: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,40 @@
+// Package storage keeps files the application produces, such as export
+// results. Links to download them are minted by the filetoken package.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("file not found")
+	ErrInvalidKey = errors.New("invalid file key")
+)
+
+// Store keeps files under slash-separated keys
+type Store interface {
+	// Put stores everything read from r under key, replacing any file there
+	Put(ctx context.Context, key string, r io.Reader) error
+
+	// Open returns the file stored under key or ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the file stored under key; missing files are not an error
+	Delete(ctx context.Context, key string) error
+
+	// List returns the files whose key starts with prefix, in key order
+	List(ctx context.Context, prefix string) ([]Object, error)
+
+	// Ping checks that the store can be reached, for readiness checks
+	Ping(ctx context.Context) error
+}
+
+// Object describes a stored file
+type Object struct {
+	Key        string
+	Size       int64
+	ModifiedAt time.Time
+}
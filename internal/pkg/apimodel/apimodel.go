@@ -0,0 +1,328 @@
+// Package apimodel renders resources in the API's JSON format, whatever the
+// database driver handed back:
+//
+//   - timestamps are UTC RFC 3339 with millisecond precision, such as
+//     "2024-05-01T10:00:00.123Z"
+//   - unset nullable timestamps (*time.Time, sql.NullTime, gorm.DeletedAt)
+//     are null, or left out when tagged omitempty
+//   - UUIDs are lowercase strings, as uuid.UUID already marshals them
+//
+// Types opt in with a MarshalJSON that hands themselves to Marshal:
+//
+//	func (u User) MarshalJSON() ([]byte, error) {
+//		return apimodel.Marshal(u)
+//	}
+//
+// A type that embeds one of these must opt in too. Otherwise the embedded
+// MarshalJSON is promoted and encoding/json renders only the embedded value,
+// dropping the outer fields.
+package apimodel
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeFormat is the layout timestamps are rendered with, always in UTC
+const TimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Time is a timestamp in the API's format. It decodes any RFC 3339 time.
+type Time time.Time
+
+// FormatTime renders t in the API's format
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// MarshalJSON implements json.Marshaler
+func (t Time) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + FormatTime(time.Time(t)) + `"`), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler; null leaves t unchanged
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return fmt.Errorf("apimodel: invalid timestamp %q: %w", raw, err)
+	}
+	*t = Time(parsed)
+	return nil
+}
+
+// Marshal encodes v, usually a struct, like encoding/json does, honouring
+// the json tags of its fields and of the structs it embeds, but with
+// timestamps in the API's format. Values of other fields are encoded as
+// they would be on their own, through their own MarshalJSON when they have
+// one.
+func Marshal(v any) ([]byte, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return []byte("null"), nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return json.Marshal(v)
+	}
+
+	p := planFor(value.Type())
+	out := reflect.New(p.typ).Elem()
+	for i, field := range p.fields {
+		field.fill(out.Field(i), value)
+	}
+	return json.Marshal(out.Interface())
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	timePtrType  = reflect.TypeOf(&time.Time{})
+	nullTimeType = reflect.TypeOf(sql.NullTime{})
+	apiTimeType  = reflect.TypeOf(Time{})
+)
+
+// conversion is how a field's value is carried over
+type conversion int
+
+const (
+	keep      conversion = iota
+	toTime               // time.Time
+	toTimePtr            // *time.Time
+	fromNull             // sql.NullTime and types convertible to it, such as gorm.DeletedAt
+)
+
+// plan mirrors a struct type with timestamps replaced by Time
+type plan struct {
+	typ    reflect.Type
+	fields []fieldPlan
+}
+
+// fieldPlan is one field of the mirror
+type fieldPlan struct {
+	// index leads from the source struct to the field, through embedded structs
+	index []int
+	conv  conversion
+	// viaPointer is set when index passes an embedded pointer, which may be
+	// nil; the mirror field is then a pointer left nil in that case
+	viaPointer bool
+}
+
+// candidate is a field that may appear in the output under name
+type candidate struct {
+	name       string
+	tagged     bool
+	index      []int
+	field      reflect.StructField
+	options    string
+	viaPointer bool
+}
+
+var plans sync.Map
+
+// planFor returns the cached plan of t
+func planFor(t reflect.Type) *plan {
+	if p, ok := plans.Load(t); ok {
+		return p.(*plan)
+	}
+
+	var candidates []candidate
+	collect(t, nil, false, map[reflect.Type]bool{}, &candidates)
+	chosen := dominant(candidates)
+
+	p := &plan{fields: make([]fieldPlan, len(chosen))}
+	structFields := make([]reflect.StructField, len(chosen))
+	for i, c := range chosen {
+		typ, conv := mirrorType(c.field.Type)
+		options := c.options
+		if c.viaPointer {
+			typ = reflect.PointerTo(typ)
+			if !strings.Contains(options, "omitempty") {
+				options += ",omitempty"
+			}
+		}
+		structFields[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: typ,
+			Tag:  reflect.StructTag(fmt.Sprintf(`json:"%s%s"`, c.name, options)),
+		}
+		p.fields[i] = fieldPlan{index: c.index, conv: conv, viaPointer: c.viaPointer}
+	}
+	p.typ = reflect.StructOf(structFields)
+
+	actual, _ := plans.LoadOrStore(t, p)
+	return actual.(*plan)
+}
+
+// collect lists the fields encoding/json would consider for t, in order,
+// descending into embedded structs without a json name
+func collect(t reflect.Type, index []int, viaPointer bool, visiting map[reflect.Type]bool, out *[]candidate) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if options != "" {
+			options = "," + options
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			pointer := embedded.Kind() == reflect.Ptr
+			if pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				collect(embedded, fieldIndex, viaPointer || pointer, visiting, out)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		tagged := name != ""
+		if !tagged {
+			name = field.Name
+		}
+		*out = append(*out, candidate{
+			name:       name,
+			tagged:     tagged,
+			index:      fieldIndex,
+			field:      field,
+			options:    options,
+			viaPointer: viaPointer,
+		})
+	}
+}
+
+// dominant applies encoding/json's rules to fields sharing a name: the
+// shallowest wins, then the one with a json tag; otherwise all are dropped
+func dominant(candidates []candidate) []candidate {
+	byName := make(map[string][]candidate)
+	for _, c := range candidates {
+		byName[c.name] = append(byName[c.name], c)
+	}
+
+	var chosen []candidate
+	for _, c := range candidates {
+		rivals := byName[c.name]
+		if winner, ok := pick(rivals); ok && sameIndex(winner.index, c.index) {
+			chosen = append(chosen, c)
+		}
+	}
+	return chosen
+}
+
+func pick(rivals []candidate) (candidate, bool) {
+	depth := len(rivals[0].index)
+	for _, c := range rivals {
+		depth = min(depth, len(c.index))
+	}
+
+	var shallowest []candidate
+	for _, c := range rivals {
+		if len(c.index) == depth {
+			shallowest = append(shallowest, c)
+		}
+	}
+	if len(shallowest) == 1 {
+		return shallowest[0], true
+	}
+
+	var tagged []candidate
+	for _, c := range shallowest {
+		if c.tagged {
+			tagged = append(tagged, c)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return candidate{}, false
+}
+
+func sameIndex(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mirrorType returns the type a field has in the mirror
+func mirrorType(t reflect.Type) (reflect.Type, conversion) {
+	switch {
+	case t == timeType:
+		return apiTimeType, toTime
+	case t == timePtrType:
+		return reflect.PointerTo(apiTimeType), toTimePtr
+	case t.Kind() == reflect.Struct && t.ConvertibleTo(nullTimeType):
+		return reflect.PointerTo(apiTimeType), fromNull
+	default:
+		return t, keep
+	}
+}
+
+// fill copies the field from src into dst, converting timestamps
+func (f fieldPlan) fill(dst, src reflect.Value) {
+	value := src
+	for _, i := range f.index {
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return
+			}
+			value = value.Elem()
+		}
+		value = value.Field(i)
+	}
+
+	if f.viaPointer {
+		target := reflect.New(dst.Type().Elem())
+		dst.Set(target)
+		dst = target.Elem()
+	}
+
+	switch f.conv {
+	case toTime:
+		dst.Set(reflect.ValueOf(Time(value.Interface().(time.Time))))
+	case toTimePtr:
+		if !value.IsNil() {
+			t := Time(*value.Interface().(*time.Time))
+			dst.Set(reflect.ValueOf(&t))
+		}
+	case fromNull:
+		null := value.Convert(nullTimeType).Interface().(sql.NullTime)
+		if null.Valid {
+			t := Time(null.Time)
+			dst.Set(reflect.ValueOf(&t))
+		}
+	default:
+		dst.Set(value)
+	}
+}
@@ -0,0 +1,89 @@
+// Package apiversion lets one handler serve every version of the API. The
+// routes of a version are grouped under its prefix, and Use records the
+// version on their requests:
+//
+//	v2 := router.Group(apiversion.V2.Prefix(), apiversion.Use(apiversion.V2))
+//
+// Handlers run the same business logic whatever the version and only choose
+// how the result is rendered, through Renderers:
+//
+//	var userPages = apiversion.Renderers[userPage]{
+//		apiversion.V1: func(p userPage) any { return gin.H{"data": p.Users, "meta": p.Meta} },
+//		apiversion.V2: newUserPageV2,
+//	}
+//
+//	userPages.Render(c, http.StatusOK, page)
+//
+// A version without a renderer of its own uses the one of the newest older
+// version, so a version only lists the shapes it changes.
+package apiversion
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is a major version of the API
+type Version int
+
+// Supported versions
+const (
+	V1 Version = 1
+	V2 Version = 2
+)
+
+// Versions lists every supported version, oldest first
+var Versions = []Version{V1, V2}
+
+// String returns the version as written in paths, such as "v1"
+func (v Version) String() string {
+	return fmt.Sprintf("v%d", int(v))
+}
+
+// Prefix returns the path the routes of the version are grouped under
+func (v Version) Prefix() string {
+	return "/api/" + v.String()
+}
+
+// contextKey is the gin context key Use stores the version under
+const contextKey = "api_version"
+
+// Use records v as the version of the requests it handles
+func Use(v Version) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(contextKey, v)
+		c.Next()
+	}
+}
+
+// FromContext returns the version of the request; requests outside a
+// versioned group are V1, the version every route was first served as
+func FromContext(c *gin.Context) Version {
+	if value, ok := c.Get(contextKey); ok {
+		if v, ok := value.(Version); ok {
+			return v
+		}
+	}
+	return V1
+}
+
+// Renderers turns a handler's result into the response body of each version
+type Renderers[T any] map[Version]func(T) any
+
+// Render writes value as JSON with status, rendered for the version of the
+// request. It panics when no renderer covers the version, which is a
+// programming error caught by any test of the route.
+func (r Renderers[T]) Render(c *gin.Context, status int, value T) {
+	c.JSON(status, r.lookup(FromContext(c))(value))
+}
+
+// lookup returns the renderer of v or, failing that, of the newest older version
+func (r Renderers[T]) lookup(v Version) func(T) any {
+	for older := v; older >= V1; older-- {
+		if render, ok := r[older]; ok {
+			return render
+		}
+	}
+	panic("apiversion: no renderer for " + v.String())
+}
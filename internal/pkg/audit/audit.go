@@ -0,0 +1,32 @@
+// Package audit streams audit entries to an external SIEM. A Forwarder sends
+// one entry, over syslog or HTTPS; a Queue buffers entries between the
+// request path and its forwarder, retries them and spills them to disk while
+// the SIEM is unreachable, so an outage does not lose them.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Entry is an audit log or login event as sent to the SIEM
+type Entry struct {
+	ID         string          `json:"id"`
+	Action     string          `json:"action"`
+	ActorID    string          `json:"actor_id,omitempty"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id,omitempty"`
+	Metadata   json.RawMessage `json:"metadata,omitempty"`
+	IPAddress  string          `json:"ip_address,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Forwarder sends entries to a SIEM
+type Forwarder interface {
+	// Forward sends entry, returning an error if the SIEM may not have it
+	Forward(ctx context.Context, entry Entry) error
+
+	// Close releases the forwarder's connections
+	Close() error
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPForwarder POSTs each entry as JSON to a collector URL, authenticated
+// with a bearer token. Any 2xx response accepts the entry.
+type HTTPForwarder struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+// NewHTTPForwarder creates a forwarder posting to url with client, which
+// should not retry, as the Queue retries on its own. token may be empty
+// for collectors without authentication.
+func NewHTTPForwarder(client *http.Client, url, token string) *HTTPForwarder {
+	return &HTTPForwarder{
+		client: client,
+		url:    url,
+		token:  token,
+	}
+}
+
+// Forward posts entry
+func (f *HTTPForwarder) Forward(ctx context.Context, entry Entry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("audit collector unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit collector answered status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close closes the client's idle connections
+func (f *HTTPForwarder) Close() error {
+	f.client.CloseIdleConnections()
+	return nil
+}
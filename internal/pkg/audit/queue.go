@@ -0,0 +1,331 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+)
+
+// Defaults for the QueueConfig fields left zero
+const (
+	DefaultQueueSize      = 1000
+	DefaultMaxAttempts    = 5
+	DefaultInitialBackoff = time.Second
+	DefaultReplayInterval = 30 * time.Second
+)
+
+// replaySuffix marks the spill file being replayed
+const replaySuffix = ".replay"
+
+// replayBatch is how many entries a failed replay spills back at once
+const replayBatch = 500
+
+// QueueConfig configures a Queue. Zero fields take the defaults above.
+type QueueConfig struct {
+	Size           int           // Entries buffered in memory before they are spilled
+	MaxAttempts    int           // Attempts per entry, including the first, before it is spilled
+	InitialBackoff time.Duration // Delay before the first retry; doubles on each retry
+	SpillPath      string        // File entries are spilled to; without one they are dropped
+	ReplayInterval time.Duration // How often spilled entries are retried while nothing else succeeds
+}
+
+// Queue hands entries to a forwarder on a worker, so recording an entry
+// never waits for the SIEM. Entries are retried with exponential backoff;
+// those that still fail, arrive while the buffer is full or are pending at
+// Stop are appended to the spill file. The file is replayed at Start, after
+// the next successful forward and every ReplayInterval, and whatever fails
+// again is kept for later. Delivery is at least once: an entry may be sent
+// twice if the SIEM fails after receiving it, and spilled entries arrive
+// after newer ones.
+type Queue struct {
+	forwarder Forwarder
+	config    QueueConfig
+	metrics   *metrics.Business
+	logger    logger.Logger
+
+	queue   chan Entry
+	stopped atomic.Bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	// spillMu guards the spill file, spilled whether it may hold entries
+	spillMu sync.Mutex
+	spilled atomic.Bool
+}
+
+// QueueOption configures a Queue
+type QueueOption func(q *Queue)
+
+// WithMetrics counts the entries forwarded and failed
+func WithMetrics(m *metrics.Business) QueueOption {
+	return func(q *Queue) {
+		q.metrics = m
+	}
+}
+
+// NewQueue creates a queue for forwarder; call Start to begin forwarding
+func NewQueue(forwarder Forwarder, cfg QueueConfig, log logger.Logger, opts ...QueueOption) *Queue {
+	if cfg.Size < 1 {
+		cfg.Size = DefaultQueueSize
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = DefaultInitialBackoff
+	}
+	if cfg.ReplayInterval <= 0 {
+		cfg.ReplayInterval = DefaultReplayInterval
+	}
+
+	q := &Queue{
+		forwarder: forwarder,
+		config:    cfg,
+		logger:    log,
+		queue:     make(chan Entry, cfg.Size),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	// Entries spilled by an earlier run are replayed at Start
+	q.spilled.Store(cfg.SpillPath != "")
+	return q
+}
+
+// Start launches the worker
+func (q *Queue) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+	q.done = make(chan struct{})
+
+	go func() {
+		defer close(q.done)
+		ticker := time.NewTicker(q.config.ReplayInterval)
+		defer ticker.Stop()
+
+		q.replay(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				q.drain()
+				return
+			case entry := <-q.queue:
+				if q.forward(ctx, entry) && q.spilled.Load() {
+					q.replay(ctx)
+				}
+			case <-ticker.C:
+				q.replay(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops the worker, spilling the entries still queued, waits for it
+// to exit and closes the forwarder. Entries enqueued afterwards are spilled.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.stopped.Store(true)
+	if q.cancel == nil {
+		return q.forwarder.Close()
+	}
+	q.cancel()
+
+	select {
+	case <-q.done:
+		// Catches entries enqueued while the worker was exiting
+		q.drain()
+		return q.forwarder.Close()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Enqueue queues entry for forwarding without blocking; when the buffer is
+// full or the queue stopped, entry is spilled instead
+func (q *Queue) Enqueue(entry Entry) {
+	if !q.stopped.Load() {
+		select {
+		case q.queue <- entry:
+			return
+		default:
+		}
+	}
+	q.spill(entry)
+}
+
+// forward sends entry, retrying with backoff, and spills it if every
+// attempt fails. It reports whether entry was sent.
+func (q *Queue) forward(ctx context.Context, entry Entry) bool {
+	backoff := q.config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := q.forwarder.Forward(ctx, entry)
+		if err == nil {
+			q.metrics.AuditForwarded(true)
+			return true
+		}
+		if attempt >= q.config.MaxAttempts || ctx.Err() != nil {
+			q.logger.Warn("Failed to forward audit entry",
+				logger.Field{Key: "audit_id", Value: entry.ID},
+				logger.Field{Key: "attempts", Value: attempt},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	q.metrics.AuditForwarded(false)
+	q.spill(entry)
+	return false
+}
+
+// drain spills the entries left in the buffer
+func (q *Queue) drain() {
+	for {
+		select {
+		case entry := <-q.queue:
+			q.spill(entry)
+		default:
+			return
+		}
+	}
+}
+
+// spill appends entry to the spill file, or drops it without one
+func (q *Queue) spill(entry Entry) {
+	if err := q.appendSpill(entry); err != nil {
+		q.logger.Error("Dropping audit entry",
+			logger.Field{Key: "audit_id", Value: entry.ID},
+			logger.Field{Key: "action", Value: entry.Action},
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+	}
+}
+
+// appendSpill appends entries to the spill file as JSON lines
+func (q *Queue) appendSpill(entries ...Entry) error {
+	if q.config.SpillPath == "" {
+		return errors.New("no spill file configured")
+	}
+
+	q.spillMu.Lock()
+	defer q.spillMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(q.config.SpillPath), 0o700); err != nil {
+		return fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	file, err := os.OpenFile(q.config.SpillPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open spill file: %w", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit entry: %w", err)
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+	q.spilled.Store(true)
+	return nil
+}
+
+// replay forwards the spilled entries once each. The spill file is moved
+// aside first, so entries spilled meanwhile start a new one; on the first
+// failure the entries not yet sent are appended to it again.
+func (q *Queue) replay(ctx context.Context) {
+	if q.config.SpillPath == "" || !q.spilled.Load() {
+		return
+	}
+	replayPath := q.config.SpillPath + replaySuffix
+
+	q.spillMu.Lock()
+	// A replay file left by a crash is replayed first, and the spill file
+	// on the next replay
+	if _, err := os.Stat(replayPath); err != nil {
+		err = os.Rename(q.config.SpillPath, replayPath)
+		if errors.Is(err, os.ErrNotExist) {
+			q.spilled.Store(false)
+			q.spillMu.Unlock()
+			return
+		}
+		if err != nil {
+			q.spillMu.Unlock()
+			q.logger.Error("Failed to replay spilled audit entries", logger.Field{Key: "error", Value: err.Error()})
+			return
+		}
+		q.spilled.Store(false)
+	}
+	q.spillMu.Unlock()
+
+	if err := q.replayFile(ctx, replayPath); err != nil {
+		q.logger.Error("Failed to replay spilled audit entries", logger.Field{Key: "error", Value: err.Error()})
+		q.spilled.Store(true)
+		return
+	}
+	_ = os.Remove(replayPath)
+}
+
+// replayFile forwards the entries of path, spilling the rest on a failure
+func (q *Queue) replayFile(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var failed bool
+	var rest []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			q.logger.Warn("Skipping unreadable spilled audit entry", logger.Field{Key: "error", Value: err.Error()})
+			continue
+		}
+
+		if !failed {
+			if err := q.forwarder.Forward(ctx, entry); err == nil {
+				q.metrics.AuditForwarded(true)
+				continue
+			}
+			q.metrics.AuditForwarded(false)
+			failed = true
+		}
+		// Once the SIEM fails again the rest is spilled back in batches
+		if rest = append(rest, entry); len(rest) == replayBatch {
+			if err := q.appendSpill(rest...); err != nil {
+				return err
+			}
+			rest = rest[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return q.appendSpill(rest...)
+	}
+	return nil
+}
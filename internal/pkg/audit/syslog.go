@@ -0,0 +1,174 @@
+package audit
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog message fields, see RFC 5424
+const (
+	// syslogPriority is facility 13 (log audit) with severity 5 (notice)
+	syslogPriority = 13*8 + 5
+	// syslogSDID names the structured data element of entries; 32473 is the
+	// enterprise number reserved for documentation by RFC 5612
+	syslogSDID           = "audit@32473"
+	syslogNil            = "-"
+	syslogMaxAppName     = 48
+	syslogMaxMsgID       = 32
+	syslogMaxHostname    = 255
+	defaultSyslogTimeout = 10 * time.Second
+)
+
+// SyslogConfig configures a SyslogForwarder
+type SyslogConfig struct {
+	Address  string        // host:port of the syslog receiver
+	TLS      *tls.Config   // Sends over TLS (RFC 5425) when set, plain TCP otherwise
+	AppName  string        // APP-NAME of the messages
+	Hostname string        // HOSTNAME of the messages; os.Hostname when empty
+	Timeout  time.Duration // Dialing and writing a message; 10 seconds when zero
+}
+
+// SyslogForwarder sends entries as RFC 5424 messages over TCP or TLS, framed
+// by octet counting (RFC 6587). The message ID is the entry's action, the
+// structured data its identifiers and the message the entry as JSON. The
+// connection is dialed on first use and again after a failed write.
+type SyslogForwarder struct {
+	config SyslogConfig
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogForwarder creates a syslog forwarder; it connects on first use
+func NewSyslogForwarder(cfg SyslogConfig) *SyslogForwarder {
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultSyslogTimeout
+	}
+	return &SyslogForwarder{config: cfg}
+}
+
+// Forward writes entry as one syslog message
+func (f *SyslogForwarder) Forward(ctx context.Context, entry Entry) error {
+	message, err := f.format(entry)
+	if err != nil {
+		return err
+	}
+	frame := fmt.Sprintf("%d %s", len(message), message)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.conn == nil {
+		if f.conn, err = f.dial(ctx); err != nil {
+			return fmt.Errorf("syslog receiver unreachable: %w", err)
+		}
+	}
+
+	deadline := time.Now().Add(f.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = f.conn.SetWriteDeadline(deadline)
+	if _, err := f.conn.Write([]byte(frame)); err != nil {
+		f.conn.Close()
+		f.conn = nil
+		return fmt.Errorf("failed to write syslog message: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection
+func (f *SyslogForwarder) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.conn == nil {
+		return nil
+	}
+	err := f.conn.Close()
+	f.conn = nil
+	return err
+}
+
+// dial connects to the receiver
+func (f *SyslogForwarder) dial(ctx context.Context) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.config.Timeout)
+	defer cancel()
+
+	if f.config.TLS != nil {
+		dialer := &tls.Dialer{Config: f.config.TLS}
+		return dialer.DialContext(ctx, "tcp", f.config.Address)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", f.config.Address)
+}
+
+// format renders entry as an RFC 5424 message
+func (f *SyslogForwarder) format(entry Entry) (string, error) {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	var data strings.Builder
+	data.WriteString("[" + syslogSDID)
+	for _, param := range [][2]string{
+		{"id", entry.ID},
+		{"actor_id", entry.ActorID},
+		{"entity_type", entry.EntityType},
+		{"entity_id", entry.EntityID},
+		{"ip_address", entry.IPAddress},
+	} {
+		if param[1] != "" {
+			data.WriteString(" " + param[0] + `="` + escapeSDValue(param[1]) + `"`)
+		}
+	}
+	data.WriteString("]")
+
+	return fmt.Sprintf("<%d>1 %s %s %s %s %s %s %s",
+		syslogPriority,
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		headerField(f.config.Hostname, syslogMaxHostname),
+		headerField(f.config.AppName, syslogMaxAppName),
+		syslogNil,
+		headerField(entry.Action, syslogMaxMsgID),
+		data.String(),
+		body,
+	), nil
+}
+
+// headerField returns value as a header field of at most max printable
+// ASCII characters, or the nil value when it is empty
+func headerField(value string, max int) string {
+	field := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '-'
+		}
+		return r
+	}, value)
+	if len(field) > max {
+		field = field[:max]
+	}
+	if field == "" {
+		return syslogNil
+	}
+	return field
+}
+
+// sdEscaper escapes the characters RFC 5424 reserves in parameter values
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// escapeSDValue escapes a structured data parameter value
+func escapeSDValue(value string) string {
+	return sdEscaper.Replace(value)
+}
@@ -0,0 +1,101 @@
+// Package buildinfo reports which build of the service is running. Release
+// builds set the variables with -ldflags:
+//
+//	go build -ldflags "-X BackofficeGoService/internal/pkg/buildinfo.Version=v1.4.0 \
+//	  -X BackofficeGoService/internal/pkg/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X BackofficeGoService/internal/pkg/buildinfo.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/main.go
+//
+// Values left unset fall back to the VCS data the Go toolchain embeds in the
+// binary, then to defaults.
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags "-X BackofficeGoService/internal/pkg/buildinfo.<Name>=<value>"
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+// Defaults for values neither -ldflags nor the embedded build info provide
+const (
+	DefaultVersion = "dev"
+	Unknown        = "unknown"
+)
+
+// Info describes a build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+
+	// Modified is set when the binary was built from a tree with uncommitted changes
+	Modified bool `json:"modified,omitempty"`
+}
+
+// Get returns the running build's info
+func Get() Info {
+	bi, _ := debug.ReadBuildInfo()
+	return Resolve(Info{Version: Version, Commit: Commit, BuildDate: BuildDate}, bi)
+}
+
+// Resolve fills the fields of set that are empty from bi, which may be nil:
+// the main module's version, and the vcs.revision, vcs.time and vcs.modified
+// settings. Anything still empty gets DefaultVersion or Unknown.
+func Resolve(set Info, bi *debug.BuildInfo) Info {
+	info := set
+	if bi != nil {
+		// go run and go build of a checkout report (devel)
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		fromVCS := info.Commit == ""
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				// Only meaningful for the commit it describes
+				info.Modified = fromVCS && setting.Value == "true"
+			}
+		}
+		if info.GoVersion == "" {
+			info.GoVersion = bi.GoVersion
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = DefaultVersion
+	}
+	if info.Commit == "" {
+		info.Commit = Unknown
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = Unknown
+	}
+	if info.GoVersion == "" {
+		info.GoVersion = runtime.Version()
+	}
+	return info
+}
+
+// String formats the info for --version output
+func (i Info) String() string {
+	commit := i.Commit
+	if i.Modified {
+		commit += "-dirty"
+	}
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, commit, i.BuildDate, i.GoVersion)
+}
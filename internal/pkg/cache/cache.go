@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrCacheMiss is returned when a key is not present in the cache
+var ErrCacheMiss = errors.New("cache miss")
+
+// Store interface for cache operations
+type Store interface {
+	// Get returns the value stored under key or ErrCacheMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set stores a value under key; a zero ttl means no expiration
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete removes one or more keys
+	Delete(ctx context.Context, keys ...string) error
+
+	// Incr adds delta to the counter under key and returns its new value. A
+	// missing counter starts at zero and expires after ttl; zero means never.
+	// Counters are stored as decimal text, so Get reads them too.
+	Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// prefixedStore namespaces every key with a fixed prefix
+type prefixedStore struct {
+	prefix string
+	store  Store
+}
+
+// WithPrefix wraps a store so every key is prefixed with prefix
+func WithPrefix(store Store, prefix string) Store {
+	if prefix == "" {
+		return store
+	}
+	return &prefixedStore{prefix: prefix + ":", store: store}
+}
+
+func (p *prefixedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return p.store.Get(ctx, p.prefix+key)
+}
+
+func (p *prefixedStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return p.store.Set(ctx, p.prefix+key, value, ttl)
+}
+
+func (p *prefixedStore) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = p.prefix + key
+	}
+	return p.store.Delete(ctx, prefixed...)
+}
+
+func (p *prefixedStore) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return p.store.Incr(ctx, p.prefix+key, delta, ttl)
+}
+
+// contextPrefixedStore namespaces keys with a prefix taken from each call's context
+type contextPrefixedStore struct {
+	prefix func(ctx context.Context) string
+	store  Store
+}
+
+// WithContextPrefix wraps a store so every key is prefixed with prefix(ctx).
+// Calls for which prefix returns "" use the keys unchanged.
+func WithContextPrefix(store Store, prefix func(ctx context.Context) string) Store {
+	return &contextPrefixedStore{prefix: prefix, store: store}
+}
+
+func (p *contextPrefixedStore) key(ctx context.Context, key string) string {
+	if prefix := p.prefix(ctx); prefix != "" {
+		return prefix + ":" + key
+	}
+	return key
+}
+
+func (p *contextPrefixedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return p.store.Get(ctx, p.key(ctx, key))
+}
+
+func (p *contextPrefixedStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return p.store.Set(ctx, p.key(ctx, key), value, ttl)
+}
+
+func (p *contextPrefixedStore) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = p.key(ctx, key)
+	}
+	return p.store.Delete(ctx, prefixed...)
+}
+
+func (p *contextPrefixedStore) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return p.store.Incr(ctx, p.key(ctx, key), delta, ttl)
+}
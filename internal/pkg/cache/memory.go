@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/pkg/clock"
+)
+
+type memoryItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryStore is an in-process cache store
+type MemoryStore struct {
+	mu    sync.RWMutex
+	items map[string]memoryItem
+	clock clock.Clock
+}
+
+// MemoryOption configures a MemoryStore
+type MemoryOption func(m *MemoryStore)
+
+// WithMemoryClock sets the clock entries expire by
+func WithMemoryClock(c clock.Clock) MemoryOption {
+	return func(m *MemoryStore) {
+		m.clock = c
+	}
+}
+
+// NewMemoryStore creates a new in-memory cache store
+func NewMemoryStore(opts ...MemoryOption) *MemoryStore {
+	m := &MemoryStore{
+		items: make(map[string]memoryItem),
+		clock: clock.New(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Get returns the value stored under key or ErrCacheMiss
+func (m *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.RLock()
+	item, ok := m.items[key]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	if !item.expiresAt.IsZero() && m.clock.Now().After(item.expiresAt) {
+		m.mu.Lock()
+		delete(m.items, key)
+		m.mu.Unlock()
+		return nil, ErrCacheMiss
+	}
+	return item.value, nil
+}
+
+// Set stores a value under key; a zero ttl means no expiration
+func (m *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	item := memoryItem{value: value}
+	if ttl > 0 {
+		item.expiresAt = m.clock.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	m.items[key] = item
+	m.mu.Unlock()
+	return nil
+}
+
+// Delete removes one or more keys
+func (m *MemoryStore) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	for _, key := range keys {
+		delete(m.items, key)
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// Incr adds delta to the counter under key and returns its new value. A
+// missing or expired counter starts at zero and expires after ttl.
+func (m *MemoryStore) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[key]
+	if ok && !item.expiresAt.IsZero() && m.clock.Now().After(item.expiresAt) {
+		ok = false
+	}
+	var value int64
+	if ok {
+		var err error
+		if value, err = strconv.ParseInt(string(item.value), 10, 64); err != nil {
+			return 0, fmt.Errorf("cache value under %s is not a counter", key)
+		}
+	} else {
+		item = memoryItem{}
+		if ttl > 0 {
+			item.expiresAt = m.clock.Now().Add(ttl)
+		}
+	}
+	value += delta
+	item.value = []byte(strconv.FormatInt(value, 10))
+	m.items[key] = item
+	return value, nil
+}
@@ -0,0 +1,132 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when told to. Timers and tickers fire
+// during Advance once their deadline has been reached. It is safe for
+// concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	tickers []*fakeTicker
+}
+
+// fakeTimer is a pending After call
+type fakeTimer struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// fakeTicker is a ticker created by a Fake clock
+type fakeTicker struct {
+	clock    *Fake
+	interval time.Duration
+	next     time.Time
+	ch       chan time.Time
+}
+
+// NewFake creates a fake clock set to start
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+// Now returns the fake clock's current time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns a channel that receives the time once the clock has advanced by d
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- f.now
+		return ch
+	}
+	f.timers = append(f.timers, &fakeTimer{deadline: f.now.Add(d), ch: ch})
+	return ch
+}
+
+// NewTicker returns a ticker that ticks each time the clock passes another interval
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ticker := &fakeTicker{clock: f, interval: d, next: f.now.Add(d), ch: make(chan time.Time, 1)}
+	f.tickers = append(f.tickers, ticker)
+	return ticker
+}
+
+// Advance moves the clock forward by d and fires every timer and ticker that is due
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.set(f.now.Add(d))
+}
+
+// Set moves the clock to t, firing every timer and ticker that is due.
+// Moving backwards fires nothing.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.set(t)
+}
+
+// set moves the clock; the caller holds f.mu
+func (f *Fake) set(t time.Time) {
+	f.now = t
+
+	pending := f.timers[:0]
+	for _, timer := range f.timers {
+		if timer.deadline.After(t) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.ch <- t
+	}
+	f.timers = pending
+
+	for _, ticker := range f.tickers {
+		if ticker.next.After(t) {
+			continue
+		}
+		// Like time.Ticker, ticks are dropped rather than queued for slow receivers
+		select {
+		case ticker.ch <- t:
+		default:
+		}
+		for !ticker.next.After(t) {
+			ticker.next = ticker.next.Add(ticker.interval)
+		}
+	}
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.ch
+}
+
+// Stop stops the ticker; no more ticks are delivered
+func (t *fakeTicker) Stop() {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, ticker := range f.tickers {
+		if ticker == t {
+			f.tickers = append(f.tickers[:i], f.tickers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"BackofficeGoService/internal/pkg/requestctx"
+
+	"gorm.io/gorm"
+)
+
+// queryAnnotationCallback names the GORM callbacks SetQueryAnnotations
+// installs
+const queryAnnotationCallback = "query_annotation"
+
+// maxAnnotationValue caps each value of a query annotation, in bytes, so a
+// long route or request ID cannot bloat every statement sent for it
+const maxAnnotationValue = 96
+
+// SetQueryAnnotations turns on or off the comment naming the request that
+// is prepended to statements sent to the manager's databases, including
+// those registered later:
+//
+//	/* route=/api/v1/users method=GET req=0f8e... */ SELECT ...
+//
+// so a slow query seen in pg_stat_activity can be traced to its endpoint.
+// GORM statements are annotated on their own; raw SQL is annotated by
+// passing it through Annotate.
+func (m *Manager) SetQueryAnnotations(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.annotations.Store(enabled)
+	for _, driver := range m.drivers {
+		m.instrumentAnnotations(driver)
+	}
+}
+
+// Annotate prepends the comment naming ctx's request to query when query
+// annotations are on. Queries made outside a request are left alone.
+func (m *Manager) Annotate(ctx context.Context, query string) string {
+	if !m.annotations.Load() {
+		return query
+	}
+	if comment := QueryAnnotation(ctx); comment != "" {
+		return comment + " " + query
+	}
+	return query
+}
+
+// QueryAnnotation returns the SQL comment naming ctx's route, method and
+// request ID, or "" when ctx carries none of them. Values are cut to
+// maxAnnotationValue bytes and any character outside a conservative set is
+// replaced by an underscore, so a value can neither close the comment nor
+// carry bind parameter markers.
+func QueryAnnotation(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	var parts []string
+	if route, ok := requestctx.RouteFrom(ctx); ok {
+		if route.Template != "" {
+			parts = append(parts, "route="+annotationValue(route.Template))
+		}
+		if route.Method != "" {
+			parts = append(parts, "method="+annotationValue(route.Method))
+		}
+	}
+	if id := requestctx.RequestIDFrom(ctx); id != "" {
+		parts = append(parts, "req="+annotationValue(id))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "/* " + strings.Join(parts, " ") + " */"
+}
+
+// annotationValue makes value safe to place in a query annotation
+func annotationValue(value string) string {
+	if len(value) > maxAnnotationValue {
+		value = value[:maxAnnotationValue]
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("/:._-{}", r):
+			return r
+		}
+		return '_'
+	}, value)
+}
+
+// annotatedPool prepends a query annotation to the statements sent through
+// it
+type annotatedPool struct {
+	gorm.ConnPool
+	comment string
+}
+
+func (p annotatedPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.ConnPool.PrepareContext(ctx, p.comment+" "+query)
+}
+
+func (p annotatedPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.ConnPool.ExecContext(ctx, p.comment+" "+query, args...)
+}
+
+func (p annotatedPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.ConnPool.QueryContext(ctx, p.comment+" "+query, args...)
+}
+
+func (p annotatedPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.ConnPool.QueryRowContext(ctx, p.comment+" "+query, args...)
+}
+
+// instrumentAnnotations makes the driver's GORM statements carry the query
+// annotation while annotations are on. Drivers without an SQL connection
+// have nothing to annotate.
+func (m *Manager) instrumentAnnotations(driver Driver) {
+	if !m.annotations.Load() {
+		return
+	}
+	db, err := OpenGorm(driver)
+	if err != nil {
+		return
+	}
+
+	callbacks := db.Callback()
+	if callbacks.Query().Get(queryAnnotationCallback+":before") != nil {
+		return
+	}
+	before := func(tx *gorm.DB) {
+		if !m.annotations.Load() || tx.Statement.ConnPool == nil {
+			return
+		}
+		if _, ok := tx.Statement.ConnPool.(annotatedPool); ok {
+			return
+		}
+		comment := QueryAnnotation(tx.Statement.Context)
+		if comment == "" {
+			return
+		}
+		tx.InstanceSet(queryAnnotationCallback, tx.Statement.ConnPool)
+		tx.Statement.ConnPool = annotatedPool{ConnPool: tx.Statement.ConnPool, comment: comment}
+	}
+	after := func(tx *gorm.DB) {
+		if pool, ok := tx.InstanceGet(queryAnnotationCallback); ok {
+			tx.Statement.ConnPool = pool.(gorm.ConnPool)
+		}
+	}
+
+	// The pool is wrapped around the statement alone: the transaction GORM
+	// opens for a write is begun and committed through the unwrapped pool,
+	// and so are the statements for associations and preloads, which are
+	// annotated on their own. An unwrap registered only after the statement
+	// would be sorted last, past the commit.
+	for _, register := range []struct {
+		before, after interface {
+			Register(name string, fn func(*gorm.DB)) error
+		}
+	}{
+		{callbacks.Create().Before("gorm:create"), callbacks.Create().After("gorm:create").Before("gorm:save_after_associations")},
+		{callbacks.Query().Before("gorm:query"), callbacks.Query().After("gorm:query").Before("gorm:preload")},
+		{callbacks.Update().Before("gorm:update"), callbacks.Update().After("gorm:update").Before("gorm:save_after_associations")},
+		{callbacks.Delete().Before("gorm:delete"), callbacks.Delete().After("gorm:delete").Before("gorm:after_delete")},
+		{callbacks.Row().Before("gorm:row"), callbacks.Row().After("gorm:row")},
+		{callbacks.Raw().Before("gorm:raw"), callbacks.Raw().After("gorm:raw")},
+	} {
+		_ = register.before.Register(queryAnnotationCallback+":before", before)
+		_ = register.after.Register(queryAnnotationCallback+":after", after)
+	}
+}
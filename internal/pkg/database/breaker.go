@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/pkg/clock"
+
+	"gorm.io/gorm"
+)
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets every call through
+	CircuitClosed CircuitState = iota
+	// CircuitHalfOpen lets a single probe through after the cool-down
+	CircuitHalfOpen
+	// CircuitOpen fails every call with ErrCircuitOpen
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitBreakerConfig configures a CircuitBreaker
+type CircuitBreakerConfig struct {
+	// FailureThreshold consecutive failures open the breaker
+	FailureThreshold int
+
+	// CoolDown is how long the breaker stays open before a probe is allowed
+	CoolDown time.Duration
+
+	// Clock defaults to the real clock
+	Clock clock.Clock
+
+	// OnStateChange, if set, is called after every transition
+	OnStateChange func(from, to CircuitState)
+}
+
+// CircuitBreaker stops calls to a database that keeps failing. After
+// FailureThreshold consecutive failures it opens and calls fail fast with
+// ErrCircuitOpen. Once CoolDown has passed one probe is let through: its
+// success closes the breaker and its failure opens it again.
+type CircuitBreaker struct {
+	cfg CircuitBreakerConfig
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates a closed breaker
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 1
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.New()
+	}
+	return &CircuitBreaker{cfg: cfg}
+}
+
+// Allow reports whether a call may proceed. Callers that are allowed must
+// report the outcome with Record.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.cfg.Clock.Now().Sub(b.openedAt) < b.cfg.CoolDown {
+			return ErrCircuitOpen
+		}
+		b.transition(CircuitHalfOpen)
+		b.probing = true
+		return nil
+	case CircuitHalfOpen:
+		// Only one probe at a time; everyone else keeps failing fast
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record reports the outcome of an allowed call. Only errors for which
+// IsUnavailable is true count as failures; any other result shows the
+// database answered.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if !IsUnavailable(err) {
+		b.failures = 0
+		if b.state != CircuitClosed {
+			b.transition(CircuitClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		b.openedAt = b.cfg.Clock.Now()
+		if b.state != CircuitOpen {
+			b.transition(CircuitOpen)
+		}
+	}
+}
+
+// State returns the current state. An open breaker whose cool-down has passed
+// is still reported open until the next call probes it.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// RetryAfter returns how long until an open breaker lets a probe through, or
+// zero when calls are allowed now
+func (b *CircuitBreaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != CircuitOpen {
+		return 0
+	}
+	if remaining := b.cfg.CoolDown - b.cfg.Clock.Now().Sub(b.openedAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// transition changes state; b.mu must be held
+func (b *CircuitBreaker) transition(to CircuitState) {
+	from := b.state
+	b.state = to
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(from, to)
+	}
+}
+
+// IsUnavailable reports whether err means the database could not be reached,
+// as opposed to a query the database rejected
+func IsUnavailable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, ErrNotConnected)
+}
+
+// circuitCallback names the GORM callbacks a breaker installs
+const circuitCallback = "circuit_breaker"
+
+// instrumentGorm makes every GORM statement on db go through b
+func instrumentGorm(db *gorm.DB, b *CircuitBreaker) {
+	allow := func(tx *gorm.DB) {
+		if err := b.Allow(); err != nil {
+			tx.AddError(err)
+			return
+		}
+		tx.InstanceSet(circuitCallback, true)
+	}
+	record := func(tx *gorm.DB) {
+		if allowed, _ := tx.InstanceGet(circuitCallback); allowed == true {
+			b.Record(tx.Error)
+		}
+	}
+
+	callbacks := db.Callback()
+	if callbacks.Query().Get(circuitCallback+":before") != nil {
+		return
+	}
+	for _, register := range []struct {
+		before, after interface {
+			Register(name string, fn func(*gorm.DB)) error
+		}
+	}{
+		{callbacks.Create().Before("*"), callbacks.Create().After("*")},
+		{callbacks.Query().Before("*"), callbacks.Query().After("*")},
+		{callbacks.Update().Before("*"), callbacks.Update().After("*")},
+		{callbacks.Delete().Before("*"), callbacks.Delete().After("*")},
+		{callbacks.Row().Before("*"), callbacks.Row().After("*")},
+		{callbacks.Raw().Before("*"), callbacks.Raw().After("*")},
+	} {
+		_ = register.before.Register(circuitCallback+":before", allow)
+		_ = register.after.Register(circuitCallback+":after", record)
+	}
+}
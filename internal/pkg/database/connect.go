@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"BackofficeGoService/internal/pkg/health"
+)
+
+// DefaultRetryInterval is how often an optional database is retried when
+// ConnectPolicy.RetryInterval is not set
+const DefaultRetryInterval = 30 * time.Second
+
+// ConnectPolicy describes how ConnectDriver handles a connection failure
+type ConnectPolicy struct {
+	// Required databases return the connection error so startup can abort.
+	// Optional ones are retried in the background instead.
+	Required bool
+
+	// RetryInterval is the delay between background attempts
+	RetryInterval time.Duration
+
+	// OnRetry, if set, is called after every background attempt with its result
+	OnRetry func(name string, err error)
+
+	// NoRetry returns the connection error of an optional database too,
+	// registering nothing, for callers that can report it right away
+	NoRetry bool
+}
+
+// ConnectDriver connects driver and registers it under name. A driver is only
+// registered once Connect has succeeded, so GetDriver never returns a
+// half-connected driver. When an optional database fails, it is reported as
+// down by Health and retried until it connects or the manager is closed.
+func (m *Manager) ConnectDriver(ctx context.Context, name string, driver Driver, policy ConnectPolicy) error {
+	m.mu.Lock()
+	if _, exists := m.drivers[name]; exists {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrDriverExists, name)
+	}
+	if _, exists := m.pending[name]; exists {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrDriverExists, name)
+	}
+	m.optional[name] = !policy.Required
+	m.mu.Unlock()
+
+	err := connect(ctx, driver)
+	if err == nil {
+		// Another caller may have registered the name in the meantime
+		if err := m.AddDriver(name, driver); err != nil {
+			driver.Close()
+			return err
+		}
+		return nil
+	}
+	if policy.Required || policy.NoRetry {
+		return fmt.Errorf("failed to connect database %s: %w", name, err)
+	}
+
+	m.mu.Lock()
+	m.pending[name] = err
+	m.mu.Unlock()
+
+	interval := policy.RetryInterval
+	if interval <= 0 {
+		interval = DefaultRetryInterval
+	}
+	m.wg.Add(1)
+	go m.retry(name, driver, interval, policy.OnRetry)
+	return nil
+}
+
+// retry reconnects an optional database until it succeeds, the manager
+// closes or the database is removed
+func (m *Manager) retry(name string, driver Driver, interval time.Duration, onRetry func(string, error)) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := connect(ctx, driver)
+		cancel()
+
+		m.mu.Lock()
+		if _, retrying := m.pending[name]; !retrying {
+			m.mu.Unlock()
+			if err == nil {
+				driver.Close()
+			}
+			return
+		}
+		if err == nil {
+			delete(m.pending, name)
+			m.drivers[name] = driver
+			if breaker := m.breakers[name]; breaker != nil {
+				instrumentDriver(driver, breaker)
+			}
+		} else {
+			m.pending[name] = err
+		}
+		m.mu.Unlock()
+
+		if onRetry != nil {
+			onRetry(name, err)
+		}
+		if err == nil {
+			return
+		}
+	}
+}
+
+// connect connects driver, releasing whatever a failed attempt left open
+func connect(ctx context.Context, driver Driver) error {
+	if err := driver.Connect(ctx); err != nil {
+		driver.Close()
+		return err
+	}
+	return nil
+}
+
+// Readiness states reported by Manager.Readiness
+const (
+	StatusReady    = health.StatusReady
+	StatusDegraded = health.StatusDegraded
+	StatusNotReady = health.StatusNotReady
+)
+
+// DatabaseHealth is the health of one database
+type DatabaseHealth struct {
+	Status   string `json:"status"` // up or down
+	Required bool   `json:"required"`
+	Error    string `json:"error,omitempty"`
+	Circuit  string `json:"circuit,omitempty"` // breaker state, when one is set
+}
+
+// Readiness checks every database. The result is StatusNotReady if a required
+// database is down, StatusDegraded if only optional ones are, else StatusReady.
+func (m *Manager) Readiness(ctx context.Context) (string, map[string]DatabaseHealth) {
+	status := StatusReady
+	databases := make(map[string]DatabaseHealth)
+	for name, err := range m.Health(ctx) {
+		health := DatabaseHealth{Status: "up", Required: m.Required(name)}
+		if breaker := m.CircuitBreaker(name); breaker != nil {
+			health.Circuit = breaker.State().String()
+		}
+		if err == nil {
+			databases[name] = health
+			continue
+		}
+
+		if health.Required {
+			status = StatusNotReady
+		} else if status == StatusReady {
+			status = StatusDegraded
+		}
+		health.Status = "down"
+		health.Error = err.Error()
+		databases[name] = health
+	}
+	return status, databases
+}
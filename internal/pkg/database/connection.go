@@ -0,0 +1,112 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ConnectionConfig describes a database connection independently of the
+// driver. Each driver registers a ConfigBuilder that turns it into the
+// driver's own config type.
+type ConnectionConfig struct {
+	Driver          DriverType
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	DBName          string // database name, or the file path for sqlite
+	SSLMode         string // postgresql only
+	ApplicationName string // postgresql only; names the connections in pg_stat_activity
+	Charset         string // mysql only
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	UseGorm         bool
+}
+
+// ConfigBuilder builds the config CreateDriver expects for one driver type
+type ConfigBuilder func(cfg ConnectionConfig) interface{}
+
+var (
+	buildersMu     sync.RWMutex
+	configBuilders = map[DriverType]ConfigBuilder{
+		DriverPostgreSQL: postgresConfigFrom,
+		DriverMySQL:      mysqlConfigFrom,
+		DriverSQLite:     sqliteConfigFrom,
+	}
+)
+
+// RegisterConfigBuilder sets the builder used for driverType, replacing any
+// existing one
+func RegisterConfigBuilder(driverType DriverType, build ConfigBuilder) {
+	buildersMu.Lock()
+	defer buildersMu.Unlock()
+	configBuilders[driverType] = build
+}
+
+// DriverConfig translates cfg into its driver's config type
+func DriverConfig(cfg ConnectionConfig) (interface{}, error) {
+	buildersMu.RLock()
+	build, ok := configBuilders[cfg.Driver]
+	buildersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedDriver, cfg.Driver)
+	}
+	return build(cfg), nil
+}
+
+// CreateFromConnectionConfig creates the driver described by cfg
+func (f *Factory) CreateFromConnectionConfig(cfg ConnectionConfig) (Driver, error) {
+	driverConfig, err := DriverConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return f.CreateDriver(cfg.Driver, driverConfig)
+}
+
+func postgresConfigFrom(cfg ConnectionConfig) interface{} {
+	return &PostgresConfig{
+		Host:            cfg.Host,
+		Port:            cfg.Port,
+		User:            cfg.User,
+		Password:        cfg.Password,
+		DBName:          cfg.DBName,
+		SSLMode:         cfg.SSLMode,
+		ApplicationName: cfg.ApplicationName,
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		UseGorm:         cfg.UseGorm,
+	}
+}
+
+func mysqlConfigFrom(cfg ConnectionConfig) interface{} {
+	return &MySQLConfig{
+		Host:            cfg.Host,
+		Port:            cfg.Port,
+		User:            cfg.User,
+		Password:        cfg.Password,
+		DBName:          cfg.DBName,
+		Charset:         cfg.Charset,
+		ParseTime:       true,
+		Loc:             "Local",
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		UseGorm:         cfg.UseGorm,
+	}
+}
+
+func sqliteConfigFrom(cfg ConnectionConfig) interface{} {
+	// DBName is the database file, or ":memory:"
+	return &SQLiteConfig{
+		Path:         cfg.DBName,
+		MaxOpenConns: cfg.MaxOpenConns,
+		UseGorm:      cfg.UseGorm,
+	}
+}
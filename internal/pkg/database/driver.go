@@ -3,6 +3,10 @@ package database
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
 )
 
 // DriverType represents the type of database driver
@@ -33,7 +37,11 @@ type Driver interface {
 	GetSQLDB() *sql.DB
 	
 	// GetGormDB returns *gorm.DB for GORM operations (nil if not using GORM)
-	GetGormDB() interface{}
+	GetGormDB() *gorm.DB
+	
+	// Capabilities reports what the driver supports. Use SQLDB and
+	// NativeGorm rather than nil-checking GetSQLDB and GetGormDB.
+	Capabilities() DriverCapabilities
 	
 	// Type returns the driver type
 	Type() DriverType
@@ -42,6 +50,57 @@ type Driver interface {
 	Health(ctx context.Context) error
 }
 
+// DriverCapabilities describes what a driver supports, whether or not it is
+// connected yet
+type DriverCapabilities struct {
+	// SQL is set when GetSQLDB returns a *sql.DB once connected
+	SQL bool
+	// Gorm is set when the driver keeps its own GORM handle (use_gorm)
+	Gorm bool
+	// Transactions is set when the database supports transactions
+	Transactions bool
+}
+
+// String lists the capabilities, such as "sql,gorm,transactions", or "none"
+func (c DriverCapabilities) String() string {
+	var names []string
+	if c.SQL {
+		names = append(names, "sql")
+	}
+	if c.Gorm {
+		names = append(names, "gorm")
+	}
+	if c.Transactions {
+		names = append(names, "transactions")
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// SQLDB returns the driver's *sql.DB. It fails with ErrOperationNotSupported
+// for drivers without SQL and with ErrNotConnected before Connect.
+func SQLDB(driver Driver) (*sql.DB, error) {
+	if !driver.Capabilities().SQL {
+		return nil, fmt.Errorf("%w: %s has no SQL connection", ErrOperationNotSupported, driver.Type())
+	}
+	db := driver.GetSQLDB()
+	if db == nil {
+		return nil, ErrNotConnected
+	}
+	return db, nil
+}
+
+// NativeGorm returns the driver's own GORM handle, or nil when it keeps none.
+// Code that needs GORM either way uses OpenGorm.
+func NativeGorm(driver Driver) *gorm.DB {
+	if !driver.Capabilities().Gorm {
+		return nil
+	}
+	return driver.GetGormDB()
+}
+
 // Transaction interface for database transactions
 type Transaction interface {
 	Begin(ctx context.Context) (interface{}, error)
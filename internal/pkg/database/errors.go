@@ -6,6 +6,19 @@ var (
 	ErrUnsupportedDriver = errors.New("unsupported database driver")
 	ErrConnectionFailed  = errors.New("database connection failed")
 	ErrDriverNotFound    = errors.New("database driver not found")
+	ErrDriverExists      = errors.New("database driver already exists")
 	ErrNotConnected      = errors.New("database not connected")
+	ErrCircuitOpen       = errors.New("database circuit breaker is open")
+
+	// ErrQueryTimeout is returned for statements that outran the query
+	// timeout or the caller's deadline
+	ErrQueryTimeout = errors.New("database query timed out")
+
+	// ErrDriverInUse is returned when removing the primary database or one
+	// that tenants are routed to
+	ErrDriverInUse = errors.New("database driver is in use")
+
+	// ErrOperationNotSupported is returned for operations the driver cannot perform
+	ErrOperationNotSupported = errors.New("operation not supported by database driver")
 )
 
@@ -3,6 +3,11 @@ package database
 import (
 	"context"
 	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"BackofficeGoService/internal/pkg/health"
 )
 
 // Factory creates database drivers based on configuration
@@ -35,8 +40,11 @@ func (f *Factory) CreateDriver(driverType DriverType, config interface{}) (Drive
 		return nil, fmt.Errorf("mongodb driver not yet implemented")
 
 	case DriverSQLite:
-		// SQLite implementation would go here
-		return nil, fmt.Errorf("sqlite driver not yet implemented")
+		cfg, ok := config.(*SQLiteConfig)
+		if !ok {
+			return nil, fmt.Errorf("invalid sqlite config type")
+		}
+		return NewSQLiteDriver(cfg), nil
 
 	default:
 		return nil, fmt.Errorf("unsupported driver type: %s", driverType)
@@ -45,38 +53,157 @@ func (f *Factory) CreateDriver(driverType DriverType, config interface{}) (Drive
 
 // Manager manages multiple database connections
 type Manager struct {
-	drivers map[string]Driver
-	factory *Factory
+	mu       sync.RWMutex
+	drivers  map[string]Driver
+	optional map[string]bool
+	pending  map[string]error // optional databases still retrying, with their last error
+	breakers map[string]*CircuitBreaker
+	tenants  map[string]string // tenant ID to database name
+	hosts    map[string]string // request host to tenant ID
+	replicas map[string]string // database name to the name of its read replica
+	factory  *Factory
+
+	queryTimeout QueryTimeoutConfig
+	retryPolicy  RetryPolicy
+	writeGuard   atomic.Pointer[WriteGuard]
+	annotations  atomic.Bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
 }
 
 // NewManager creates a new database manager
 func NewManager() *Manager {
 	return &Manager{
-		drivers: make(map[string]Driver),
-		factory: NewFactory(),
+		drivers:  make(map[string]Driver),
+		optional: make(map[string]bool),
+		pending:  make(map[string]error),
+		breakers: make(map[string]*CircuitBreaker),
+		tenants:  make(map[string]string),
+		hosts:    make(map[string]string),
+		replicas: make(map[string]string),
+		factory:  NewFactory(),
+		done:     make(chan struct{}),
 	}
 }
 
 // AddDriver adds a database driver with a name
 func (m *Manager) AddDriver(name string, driver Driver) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if _, exists := m.drivers[name]; exists {
-		return fmt.Errorf("driver with name %s already exists", name)
+		return fmt.Errorf("%w: %s", ErrDriverExists, name)
 	}
 	m.drivers[name] = driver
+	if breaker := m.breakers[name]; breaker != nil {
+		instrumentDriver(driver, breaker)
+	}
+	instrumentQueryTimeout(name, driver, m.queryTimeout)
+	instrumentSession(driver)
+	m.instrumentWriteGuard(driver)
+	m.instrumentAnnotations(driver)
+	return nil
+}
+
+// RemoveDriver unregisters the named database and closes it, or stops
+// retrying it when it has not connected yet. New calls to GetDriver fail at
+// once. Requests already holding the driver finish the statements they have
+// started, while further ones fail as the connection is closed. The primary
+// database, databases tenants are routed to and read replicas in use cannot
+// be removed.
+func (m *Manager) RemoveDriver(name string) error {
+	m.mu.Lock()
+	if name == PrimaryDriver {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s is the primary database", ErrDriverInUse, name)
+	}
+	for tenant, driverName := range m.tenants {
+		if driverName == name {
+			m.mu.Unlock()
+			return fmt.Errorf("%w: tenant %s is routed to %s", ErrDriverInUse, tenant, name)
+		}
+	}
+	for database, replica := range m.replicas {
+		if replica == name {
+			m.mu.Unlock()
+			return fmt.Errorf("%w: %s is the read replica of %s", ErrDriverInUse, name, database)
+		}
+	}
+	driver, connected := m.drivers[name]
+	_, retrying := m.pending[name]
+	if !connected && !retrying {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrDriverNotFound, name)
+	}
+	delete(m.drivers, name)
+	delete(m.pending, name)
+	delete(m.optional, name)
+	delete(m.breakers, name)
+	delete(m.replicas, name)
+	m.mu.Unlock()
+
+	if !connected {
+		return nil
+	}
+	forgetGorm(driver)
+	if err := driver.Close(); err != nil {
+		return fmt.Errorf("failed to close driver %s: %w", name, err)
+	}
 	return nil
 }
 
-// GetDriver retrieves a driver by name
+// GetDriver retrieves a driver by name. It fails with ErrCircuitOpen while
+// the database's circuit breaker is open.
 func (m *Manager) GetDriver(name string) (Driver, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	driver, exists := m.drivers[name]
 	if !exists {
-		return nil, fmt.Errorf("driver with name %s not found", name)
+		return nil, fmt.Errorf("%w: %s", ErrDriverNotFound, name)
+	}
+	if breaker := m.breakers[name]; breaker != nil && breaker.RetryAfter() > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, name)
 	}
 	return driver, nil
 }
 
+// SetCircuitBreaker guards the named database with breaker. Health checks and
+// GORM statements report their outcome to it, and GetDriver fails fast while
+// it is open. Queries sent straight to GetSQLDB are not observed. The
+// database may be registered before or after the breaker is set.
+func (m *Manager) SetCircuitBreaker(name string, breaker *CircuitBreaker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.breakers[name] = breaker
+	if driver, exists := m.drivers[name]; exists {
+		instrumentDriver(driver, breaker)
+	}
+}
+
+// CircuitBreaker returns the breaker guarding the named database, or nil
+func (m *Manager) CircuitBreaker(name string) *CircuitBreaker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.breakers[name]
+}
+
+// instrumentDriver routes the driver's GORM statements through breaker.
+// Drivers without an SQL connection have nothing to instrument.
+func instrumentDriver(driver Driver, breaker *CircuitBreaker) {
+	if db, err := OpenGorm(driver); err == nil {
+		instrumentGorm(db, breaker)
+	}
+}
+
 // ConnectAll connects all registered drivers
 func (m *Manager) ConnectAll(ctx context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	for name, driver := range m.drivers {
 		if err := driver.Connect(ctx); err != nil {
 			return fmt.Errorf("failed to connect driver %s: %w", name, err)
@@ -85,8 +212,14 @@ func (m *Manager) ConnectAll(ctx context.Context) error {
 	return nil
 }
 
-// CloseAll closes all registered drivers
+// CloseAll stops background connection retries and closes all registered drivers
 func (m *Manager) CloseAll() error {
+	m.closeOnce.Do(func() { close(m.done) })
+	m.wg.Wait()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	var errs []error
 	for name, driver := range m.drivers {
 		if err := driver.Close(); err != nil {
@@ -99,12 +232,129 @@ func (m *Manager) CloseAll() error {
 	return nil
 }
 
-// Health checks the health of all drivers
+// Health checks the health of all drivers. Optional databases that have not
+// connected yet are reported with their last connection error.
 func (m *Manager) Health(ctx context.Context) map[string]error {
-	results := make(map[string]error)
-	for name, driver := range m.drivers {
-		results[name] = driver.Health(ctx)
+	names := m.databaseNames()
+	results := make(map[string]error, len(names))
+	for _, name := range names {
+		results[name] = m.checkHealth(ctx, name)
 	}
 	return results
 }
 
+// HealthChecks returns a readiness checker per database. Required databases
+// are critical; optional ones are informational.
+func (m *Manager) HealthChecks() []health.Checker {
+	names := m.databaseNames()
+	checkers := make([]health.Checker, 0, len(names))
+	for _, name := range names {
+		checkers = append(checkers, &databaseCheck{manager: m, name: name})
+	}
+	return checkers
+}
+
+// databaseCheck is the readiness checker of one database
+type databaseCheck struct {
+	manager *Manager
+	name    string
+}
+
+func (c *databaseCheck) Name() string                    { return c.name }
+func (c *databaseCheck) Check(ctx context.Context) error { return c.manager.checkHealth(ctx, c.name) }
+func (c *databaseCheck) Critical() bool                  { return c.manager.Required(c.name) }
+
+// Details reports the driver's capabilities and the state of the
+// database's circuit breaker
+func (c *databaseCheck) Details() map[string]string {
+	details := make(map[string]string)
+	if caps, ok := c.manager.Capabilities(c.name); ok {
+		details["capabilities"] = caps.String()
+	}
+	if breaker := c.manager.CircuitBreaker(c.name); breaker != nil {
+		details["circuit"] = breaker.State().String()
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// databaseNames returns the connected and still retrying databases, sorted
+func (m *Manager) databaseNames() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.drivers)+len(m.pending))
+	for name := range m.drivers {
+		names = append(names, name)
+	}
+	for name := range m.pending {
+		if _, connected := m.drivers[name]; !connected {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkHealth checks one database. A database still retrying reports its
+// last connection error.
+func (m *Manager) checkHealth(ctx context.Context, name string) error {
+	m.mu.RLock()
+	driver, connected := m.drivers[name]
+	breaker := m.breakers[name]
+	pendingErr := m.pending[name]
+	m.mu.RUnlock()
+
+	if !connected {
+		if pendingErr != nil {
+			return pendingErr
+		}
+		return fmt.Errorf("%w: %s", ErrDriverNotFound, name)
+	}
+	if breaker == nil {
+		return driver.Health(ctx)
+	}
+	// Health checks probe a half-open breaker like any other call
+	if err := breaker.Allow(); err != nil {
+		return err
+	}
+	err := driver.Health(ctx)
+	breaker.Record(err)
+	return err
+}
+
+// Capabilities reports what the named database's driver supports. It is
+// false for databases that are not registered, or still retrying.
+func (m *Manager) Capabilities(name string) (DriverCapabilities, bool) {
+	m.mu.RLock()
+	driver, exists := m.drivers[name]
+	m.mu.RUnlock()
+
+	if !exists {
+		return DriverCapabilities{}, false
+	}
+	return driver.Capabilities(), true
+}
+
+// DriverType reports the type of the named database's driver. It is false
+// for databases that are not registered, or still retrying.
+func (m *Manager) DriverType(name string) (DriverType, bool) {
+	m.mu.RLock()
+	driver, exists := m.drivers[name]
+	m.mu.RUnlock()
+
+	if !exists {
+		return "", false
+	}
+	return driver.Type(), true
+}
+
+// Required reports whether the service cannot work without the named database.
+// Databases added with AddDriver are required.
+func (m *Manager) Required(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !m.optional[name]
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var (
+	gormHandlesMu sync.Mutex
+	gormHandles   = make(map[*sql.DB]*gorm.DB)
+)
+
+// OpenGorm returns a GORM handle for the driver. Drivers with GORM enabled
+// return their own handle; otherwise a handle is opened once over the
+// driver's *sql.DB so GORM-only code (migrations, newer services) works
+// regardless of the use_gorm setting.
+func OpenGorm(driver Driver) (*gorm.DB, error) {
+	if gormDB := NativeGorm(driver); gormDB != nil {
+		return gormDB, nil
+	}
+
+	sqlDB, err := SQLDB(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	gormHandlesMu.Lock()
+	defer gormHandlesMu.Unlock()
+
+	if gormDB, ok := gormHandles[sqlDB]; ok {
+		return gormDB, nil
+	}
+
+	var dialector gorm.Dialector
+	switch driver.Type() {
+	case DriverPostgreSQL:
+		dialector = postgres.New(postgres.Config{Conn: sqlDB})
+	case DriverMySQL:
+		dialector = mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true})
+	case DriverSQLite:
+		dialector = sqlite.Dialector{Conn: sqlDB}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDriver, driver.Type())
+	}
+
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize gorm: %w", err)
+	}
+
+	gormHandles[sqlDB] = gormDB
+	return gormDB, nil
+}
+
+// GormFor returns a GORM handle on the database ctx is scoped to, bound to ctx
+func GormFor(ctx context.Context, manager *Manager) (*gorm.DB, error) {
+	driver, err := manager.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db, err := OpenGorm(driver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	return db.WithContext(ctx), nil
+}
+
+// forgetGorm drops the handle OpenGorm opened over the driver's *sql.DB, once
+// the driver is removed
+func forgetGorm(driver Driver) {
+	sqlDB, err := SQLDB(driver)
+	if err != nil {
+		return
+	}
+
+	gormHandlesMu.Lock()
+	defer gormHandlesMu.Unlock()
+	delete(gormHandles, sqlDB)
+}
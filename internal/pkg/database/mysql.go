@@ -141,10 +141,15 @@ func (d *MySQLDriver) GetSQLDB() *sql.DB {
 }
 
 // GetGormDB returns *gorm.DB if GORM is enabled
-func (d *MySQLDriver) GetGormDB() interface{} {
+func (d *MySQLDriver) GetGormDB() *gorm.DB {
 	return d.gormDB
 }
 
+// Capabilities reports SQL and transaction support, and GORM when enabled
+func (d *MySQLDriver) Capabilities() DriverCapabilities {
+	return DriverCapabilities{SQL: true, Gorm: d.config.UseGorm, Transactions: true}
+}
+
 // Type returns the driver type
 func (d *MySQLDriver) Type() DriverType {
 	return DriverMySQL
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/driver/postgres"
@@ -25,6 +26,7 @@ type PostgresConfig struct {
 	Password        string
 	DBName          string
 	SSLMode         string
+	ApplicationName string // reported as application_name; omitted when empty
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
@@ -66,6 +68,9 @@ func (d *PostgresDriver) Connect(ctx context.Context) error {
 		d.config.DBName,
 		d.config.SSLMode,
 	)
+	if name := postgresApplicationName(d.config.ApplicationName); name != "" {
+		dsn += " application_name=" + name
+	}
 
 	var err error
 	d.db, err = sql.Open("postgres", dsn)
@@ -127,10 +132,15 @@ func (d *PostgresDriver) GetSQLDB() *sql.DB {
 }
 
 // GetGormDB returns *gorm.DB if GORM is enabled
-func (d *PostgresDriver) GetGormDB() interface{} {
+func (d *PostgresDriver) GetGormDB() *gorm.DB {
 	return d.gormDB
 }
 
+// Capabilities reports SQL and transaction support, and GORM when enabled
+func (d *PostgresDriver) Capabilities() DriverCapabilities {
+	return DriverCapabilities{SQL: true, Gorm: d.config.UseGorm, Transactions: true}
+}
+
 // Type returns the driver type
 func (d *PostgresDriver) Type() DriverType {
 	return DriverPostgreSQL
@@ -141,3 +151,21 @@ func (d *PostgresDriver) Health(ctx context.Context) error {
 	return d.Ping(ctx)
 }
 
+// postgresApplicationName quotes name for a key/value connection string.
+// PostgreSQL keeps 63 bytes of printable ASCII, so the name is cut and
+// anything else replaced here, where it is still known what was sent.
+func postgresApplicationName(name string) string {
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return '?'
+		}
+		return r
+	}, name)
+	if name == "" {
+		return ""
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(name) + "'"
+}
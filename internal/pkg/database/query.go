@@ -0,0 +1,156 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsortable is returned when sorting by a column that was not declared sortable
+var ErrUnsortable = errors.New("column is not sortable")
+
+// Rebind rewrites the placeholders of query for driverType: "$1, $2, ..."
+// for PostgreSQL and "?" for MySQL and SQLite. Queries may be written with
+// either style, or both; each placeholder binds the next argument, so a
+// "$N" must be the Nth placeholder of the query. Placeholders inside quoted
+// literals and identifiers are left alone.
+//
+// Rebind panics on a "$N" out of position, which is a bug in the query
+// rather than a runtime condition.
+func Rebind(driverType DriverType, query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	scanPlaceholders(query, func(literal string, placeholder string) {
+		b.WriteString(literal)
+		if placeholder == "" {
+			return
+		}
+		n++
+		if placeholder != "?" && placeholder != "$"+strconv.Itoa(n) {
+			panic(fmt.Sprintf("database: placeholder %s is argument %d in %q", placeholder, n, query))
+		}
+		if driverType == DriverPostgreSQL {
+			b.WriteString("$" + strconv.Itoa(n))
+		} else {
+			b.WriteByte('?')
+		}
+	})
+	return b.String()
+}
+
+// countPlaceholders returns the number of placeholders in query
+func countPlaceholders(query string) int {
+	n := 0
+	scanPlaceholders(query, func(_ string, placeholder string) {
+		if placeholder != "" {
+			n++
+		}
+	})
+	return n
+}
+
+// scanPlaceholders calls fn with each run of text of query and the
+// placeholder following it, "" after the last run
+func scanPlaceholders(query string, fn func(literal, placeholder string)) {
+	start := 0
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; c {
+		case '\'', '"', '`':
+			// Skip to the closing quote; doubled quotes escape themselves
+			for i++; i < len(query); i++ {
+				if query[i] == c {
+					if i+1 < len(query) && query[i+1] == c {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case '?':
+			fn(query[start:i], "?")
+			start = i + 1
+		case '$':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if j > i+1 {
+				fn(query[start:i], query[i:j])
+				start = j
+				i = j - 1
+			}
+		}
+	}
+	fn(query[start:], "")
+}
+
+// WhereBuilder builds the WHERE and ORDER BY clauses of a raw query from
+// filters, keeping every value in arguments and every column sorted by in
+// a declared list, so no request input ends up in the SQL text:
+//
+//	where := database.NewWhereBuilder("created_at", "email")
+//	where.Where("anonymized_at IS NULL")
+//	where.Where("role = ?", role)
+//	if err := where.Sort(sortBy, true); err != nil { ... }
+//	clause, args := where.Clause()
+//	query := database.Rebind(driver.Type(), "SELECT ... FROM users "+clause+" "+where.Order()+" LIMIT ? OFFSET ?")
+//
+// Conditions are written with "?" placeholders; Rebind adapts them to the
+// driver once the query is complete.
+type WhereBuilder struct {
+	sortable   []string
+	conditions []string
+	args       []interface{}
+	order      []string
+}
+
+// NewWhereBuilder creates a builder that can sort by the sortable columns
+func NewWhereBuilder(sortable ...string) *WhereBuilder {
+	return &WhereBuilder{sortable: sortable}
+}
+
+// Where adds a condition, joined to the others with AND. It panics when
+// the number of placeholders in condition does not match args.
+func (w *WhereBuilder) Where(condition string, args ...interface{}) *WhereBuilder {
+	if n := countPlaceholders(condition); n != len(args) {
+		panic(fmt.Sprintf("database: %q has %d placeholders for %d arguments", condition, n, len(args)))
+	}
+	w.conditions = append(w.conditions, "("+condition+")")
+	w.args = append(w.args, args...)
+	return w
+}
+
+// Sort adds column to the ORDER BY clause. Columns not declared sortable
+// are refused with ErrUnsortable.
+func (w *WhereBuilder) Sort(column string, descending bool) error {
+	if !slices.Contains(w.sortable, column) {
+		return fmt.Errorf("%w: %q", ErrUnsortable, column)
+	}
+	if descending {
+		column += " DESC"
+	} else {
+		column += " ASC"
+	}
+	w.order = append(w.order, column)
+	return nil
+}
+
+// Clause returns "WHERE ..." with its arguments, or "" without conditions
+func (w *WhereBuilder) Clause() (string, []interface{}) {
+	if len(w.conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(w.conditions, " AND "), slices.Clone(w.args)
+}
+
+// Order returns "ORDER BY ...", or "" when nothing was sorted by
+func (w *WhereBuilder) Order() string {
+	if len(w.order) == 0 {
+		return ""
+	}
+	return "ORDER BY " + strings.Join(w.order, ", ")
+}
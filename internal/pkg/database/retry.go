@@ -0,0 +1,91 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// RetryPolicy says how idempotent operations are retried after a transient
+// error. Only operations that leave the same state however often they run
+// may be retried: reads, and writes that are marked idempotent.
+type RetryPolicy struct {
+	// MaxAttempts is how often an operation runs at most, the first attempt
+	// included; one or less disables retries
+	MaxAttempts int
+
+	// BaseDelay is the backoff before the first retry. It doubles with each
+	// retry up to MaxDelay, and a random part of it is waited.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// OnRetry, if set, is called before each retry with the transient
+	// error's reason, the attempt that failed and its error
+	OnRetry func(reason string, attempt int, err error)
+}
+
+// Transient error reasons, as reported by TransientReason
+const (
+	RetrySerialization = "serialization_failure"
+	RetryDeadlock      = "deadlock"
+	RetryStartingUp    = "starting_up"
+	RetryConnection    = "connection"
+)
+
+// postgresTransient maps the PostgreSQL SQLSTATE codes worth retrying to
+// their reason
+var postgresTransient = map[pq.ErrorCode]string{
+	"40001": RetrySerialization, // serialization_failure
+	"40P01": RetryDeadlock,      // deadlock_detected
+	"57P03": RetryStartingUp,    // cannot_connect_now: the database system is starting up
+	"08000": RetryConnection,    // connection_exception
+	"08003": RetryConnection,    // connection_does_not_exist
+	"08006": RetryConnection,    // connection_failure
+}
+
+// mysqlTransient maps the MySQL error numbers worth retrying to their reason
+var mysqlTransient = map[uint16]string{
+	1213: RetryDeadlock, // ER_LOCK_DEADLOCK
+}
+
+// SetRetryPolicy sets the policy repositories retry idempotent operations with
+func (m *Manager) SetRetryPolicy(policy RetryPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retryPolicy = policy
+}
+
+// RetryPolicy returns the policy set by SetRetryPolicy; by default nothing is
+// retried
+func (m *Manager) RetryPolicy() RetryPolicy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.retryPolicy
+}
+
+// TransientReason reports whether err is a transient database error, one a
+// retry of the same operation can absorb, and why. Errors the database gave
+// for the query itself, timeouts and an open circuit breaker are not.
+func TransientReason(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		reason, ok := postgresTransient[pqErr.Code]
+		return reason, ok
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		reason, ok := mysqlTransient[mysqlErr.Number]
+		return reason, ok
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNRESET) {
+		return RetryConnection, true
+	}
+	return "", false
+}
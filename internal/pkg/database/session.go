@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Session is the database state of one request. Once the request writes,
+// its later reads go to the database written to instead of a lagging read
+// replica, so a handler reads back what it just wrote.
+//
+// GORM creates, updates, deletes and Exec calls mark the session of their
+// statement's context as written on their own; code writing through the
+// raw *sql.DB calls MarkWritten.
+type Session struct {
+	mu      sync.Mutex
+	written bool
+	// sticky, if set, is asked once whether an earlier request wrote
+	// recently enough that this one should read from the primary too
+	sticky  func() bool
+	checked bool
+	primary bool
+}
+
+// NewSession creates a session that has not written. sticky may be nil.
+func NewSession(sticky func() bool) *Session {
+	return &Session{sticky: sticky}
+}
+
+// MarkWritten routes the session's later reads to the primary
+func (s *Session) MarkWritten() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.written = true
+}
+
+// Written reports whether the request wrote
+func (s *Session) Written() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.written
+}
+
+// ReadsPrimary reports whether reads go to the primary: once the request
+// wrote, or from the start when an earlier request wrote recently
+func (s *Session) ReadsPrimary() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.written && !s.checked && s.sticky != nil {
+		s.checked = true
+		s.primary = s.sticky()
+	}
+	return s.written || s.primary
+}
+
+// sessionKey holds the request's session
+type sessionKey struct{}
+
+// WithSession returns a context carrying session
+func WithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionFrom returns the session of ctx, or nil outside a request
+func SessionFrom(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionKey{}).(*Session)
+	return session
+}
+
+// MarkWritten marks the session of ctx written, if there is one
+func MarkWritten(ctx context.Context) {
+	if session := SessionFrom(ctx); session != nil {
+		session.MarkWritten()
+	}
+}
+
+// SetReplica sends the reads ReaderFor routes for the named database to
+// replica. Both must be registered; the replica may still be connecting.
+func (m *Manager) SetReplica(name, replica string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, n := range []string{name, replica} {
+		_, connected := m.drivers[n]
+		_, retrying := m.pending[n]
+		if !connected && !retrying {
+			return fmt.Errorf("%w: %s", ErrDriverNotFound, n)
+		}
+	}
+	if name == replica {
+		return fmt.Errorf("%w: %s cannot be its own replica", ErrDriverInUse, name)
+	}
+	m.replicas[name] = replica
+	return nil
+}
+
+// Replica returns the read replica of the named database
+func (m *Manager) Replica(name string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	replica, ok := m.replicas[name]
+	return replica, ok
+}
+
+// ReaderFor returns the driver reads of the database ctx is scoped to go
+// to: its read replica, unless the request's session has written. Outside
+// a request, or while the replica is unavailable, it is the database
+// itself, as DriverFor returns.
+func (m *Manager) ReaderFor(ctx context.Context) (Driver, error) {
+	name := DriverName(ctx)
+	if session := SessionFrom(ctx); session != nil && !session.ReadsPrimary() {
+		if replica, ok := m.Replica(name); ok {
+			if driver, err := m.GetDriver(replica); err == nil {
+				return driver, nil
+			}
+		}
+	}
+	return m.GetDriver(name)
+}
+
+// sessionCallback names the GORM callbacks marking sessions written
+const sessionCallback = "session"
+
+// instrumentSession marks the session of every write on the driver's GORM
+// handle as written. Drivers without an SQL connection have nothing to mark.
+func instrumentSession(driver Driver) {
+	db, err := OpenGorm(driver)
+	if err != nil {
+		return
+	}
+
+	callbacks := db.Callback()
+	if callbacks.Create().Get(sessionCallback+":after") != nil {
+		return
+	}
+	mark := func(tx *gorm.DB) {
+		if tx.Statement.Context != nil {
+			MarkWritten(tx.Statement.Context)
+		}
+	}
+	for _, processor := range []interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}{
+		callbacks.Create().After("*"),
+		callbacks.Update().After("*"),
+		callbacks.Delete().After("*"),
+		callbacks.Raw().After("*"),
+	} {
+		_ = processor.Register(sessionCallback+":after", mark)
+	}
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// SQLiteDriver implements the Driver interface for SQLite. It is pure Go, so
+// it needs no cgo, and is mainly meant for tests and local tooling.
+type SQLiteDriver struct {
+	config *SQLiteConfig
+	db     *sql.DB
+	gormDB *gorm.DB
+}
+
+// SQLiteConfig holds SQLite configuration
+type SQLiteConfig struct {
+	// Path is the database file, or ":memory:" for a private in-memory database
+	Path         string
+	MaxOpenConns int
+	UseGorm      bool
+}
+
+// NewSQLiteDriver creates a new SQLite driver instance
+func NewSQLiteDriver(cfg *SQLiteConfig) *SQLiteDriver {
+	if cfg.Path == "" {
+		cfg.Path = ":memory:"
+	}
+	// Every connection to ":memory:" opens a separate empty database
+	if cfg.Path == ":memory:" || cfg.MaxOpenConns == 0 {
+		cfg.MaxOpenConns = 1
+	}
+
+	return &SQLiteDriver{
+		config: cfg,
+	}
+}
+
+// Connect opens the database file
+func (d *SQLiteDriver) Connect(ctx context.Context) error {
+	var err error
+	d.db, err = sql.Open(sqlite.DriverName, d.config.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	d.db.SetMaxOpenConns(d.config.MaxOpenConns)
+
+	// Test connection
+	if err := d.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping sqlite: %w", err)
+	}
+
+	// Initialize GORM if requested
+	if d.config.UseGorm {
+		d.gormDB, err = gorm.Open(sqlite.Dialector{Conn: d.db}, &gorm.Config{})
+		if err != nil {
+			return fmt.Errorf("failed to initialize gorm: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the database connection
+func (d *SQLiteDriver) Close() error {
+	if d.db != nil {
+		return d.db.Close()
+	}
+	return nil
+}
+
+// Ping checks if the database connection is alive
+func (d *SQLiteDriver) Ping(ctx context.Context) error {
+	if d.db == nil {
+		return fmt.Errorf("database connection is not established")
+	}
+	return d.db.PingContext(ctx)
+}
+
+// GetDB returns the underlying database connection
+func (d *SQLiteDriver) GetDB() interface{} {
+	if d.config.UseGorm && d.gormDB != nil {
+		return d.gormDB
+	}
+	return d.db
+}
+
+// GetSQLDB returns *sql.DB
+func (d *SQLiteDriver) GetSQLDB() *sql.DB {
+	return d.db
+}
+
+// GetGormDB returns *gorm.DB if GORM is enabled
+func (d *SQLiteDriver) GetGormDB() *gorm.DB {
+	return d.gormDB
+}
+
+// Capabilities reports SQL and transaction support, and GORM when enabled
+func (d *SQLiteDriver) Capabilities() DriverCapabilities {
+	return DriverCapabilities{SQL: true, Gorm: d.config.UseGorm, Transactions: true}
+}
+
+// Type returns the driver type
+func (d *SQLiteDriver) Type() DriverType {
+	return DriverSQLite
+}
+
+// Health checks the health of the database connection
+func (d *SQLiteDriver) Health(ctx context.Context) error {
+	return d.Ping(ctx)
+}
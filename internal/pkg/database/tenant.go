@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"BackofficeGoService/internal/pkg/requestctx"
+)
+
+// PrimaryDriver is the database used when no tenant applies
+const PrimaryDriver = "primary"
+
+// tenantDriverKey holds the database of the tenant a request was resolved to
+type tenantDriverKey struct{}
+
+// WithTenant returns a context whose database calls go to driverName on
+// behalf of tenant id. The tenant is also set for requestctx.TenantFrom.
+func WithTenant(ctx context.Context, id, driverName string) context.Context {
+	return context.WithValue(requestctx.WithTenant(ctx, id), tenantDriverKey{}, driverName)
+}
+
+// TenantID returns the tenant set by WithTenant, or "" when none applies
+func TenantID(ctx context.Context) string {
+	return requestctx.TenantFrom(ctx)
+}
+
+// DriverName returns the database set by WithTenant, falling back to PrimaryDriver
+func DriverName(ctx context.Context) string {
+	if driverName, ok := ctx.Value(tenantDriverKey{}).(string); ok && driverName != "" {
+		return driverName
+	}
+	return PrimaryDriver
+}
+
+// DriverFor returns the driver of the database ctx is scoped to
+func (m *Manager) DriverFor(ctx context.Context) (Driver, error) {
+	return m.GetDriver(DriverName(ctx))
+}
+
+// RegisterTenant routes tenant to the named database, which must already be
+// registered. Registering a tenant again moves it to the new database.
+func (m *Manager) RegisterTenant(tenant, driverName string) error {
+	if tenant == "" {
+		return fmt.Errorf("tenant ID is required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.drivers[driverName]; !exists {
+		return fmt.Errorf("%w: %s", ErrDriverNotFound, driverName)
+	}
+	m.tenants[tenant] = driverName
+	return nil
+}
+
+// TenantDatabase returns the database name tenant is routed to
+func (m *Manager) TenantDatabase(tenant string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	driverName, ok := m.tenants[tenant]
+	return driverName, ok
+}
+
+// RegisterTenantHost serves tenant, which must already be registered, to
+// requests for host. The host is matched without its port and ignoring case.
+func (m *Manager) RegisterTenantHost(host, tenant string) error {
+	host = normalizeHost(host)
+	if host == "" {
+		return fmt.Errorf("host is required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.tenants[tenant]; !exists {
+		return fmt.Errorf("tenant %s is not registered", tenant)
+	}
+	m.hosts[host] = tenant
+	return nil
+}
+
+// TenantForHost returns the tenant served to requests for host, which may
+// include a port
+func (m *Manager) TenantForHost(host string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tenant, ok := m.hosts[normalizeHost(host)]
+	return tenant, ok
+}
+
+// normalizeHost strips the port from host and lower-cases it
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// Tenants returns every registered tenant and its database name
+func (m *Manager) Tenants() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tenants := make(map[string]string, len(m.tenants))
+	for tenant, driverName := range m.tenants {
+		tenants[tenant] = driverName
+	}
+	return tenants
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QueryTimeoutConfig bounds how long a single GORM statement may run
+type QueryTimeoutConfig struct {
+	// Timeout is the longest a statement may run; a shorter deadline on the
+	// caller's context still wins. Zero disables the limit.
+	Timeout time.Duration
+
+	// OnTimeout, if set, is called with the database and query names of
+	// every statement that ran out of time
+	OnTimeout func(database, query string, elapsed time.Duration)
+}
+
+// queryTimeoutCallback names the GORM callbacks SetQueryTimeout installs
+const queryTimeoutCallback = "query_timeout"
+
+// queryTimeout is the per-statement state kept between the callbacks
+type queryTimeout struct {
+	parent context.Context
+	cancel context.CancelFunc
+	start  time.Time
+}
+
+// SetQueryTimeout bounds every GORM statement sent to the manager's
+// databases by cfg, including those registered later. Statements that run
+// out of time fail with ErrQueryTimeout. Queries sent straight to GetSQLDB
+// are not bounded.
+func (m *Manager) SetQueryTimeout(cfg QueryTimeoutConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.queryTimeout = cfg
+	for name, driver := range m.drivers {
+		instrumentQueryTimeout(name, driver, cfg)
+	}
+}
+
+// QueryName names a statement for logs without its SQL text: the table and
+// the kind of statement, such as "users.query"
+func QueryName(tx *gorm.DB, kind string) string {
+	if tx.Statement == nil || tx.Statement.Table == "" {
+		return kind
+	}
+	return tx.Statement.Table + "." + kind
+}
+
+// instrumentQueryTimeout bounds the driver's GORM statements by cfg.
+// Drivers without an SQL connection have nothing to bound.
+func instrumentQueryTimeout(name string, driver Driver, cfg QueryTimeoutConfig) {
+	if cfg.Timeout <= 0 {
+		return
+	}
+	db, err := OpenGorm(driver)
+	if err != nil {
+		return
+	}
+
+	before := func(tx *gorm.DB) {
+		parent := tx.Statement.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, cancel := context.WithTimeout(parent, cfg.Timeout)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(queryTimeoutCallback, &queryTimeout{parent: parent, cancel: cancel, start: time.Now()})
+	}
+	after := func(kind string) func(tx *gorm.DB) {
+		return func(tx *gorm.DB) {
+			value, ok := tx.InstanceGet(queryTimeoutCallback)
+			if !ok {
+				return
+			}
+			state := value.(*queryTimeout)
+			timedOut := errors.Is(tx.Statement.Context.Err(), context.DeadlineExceeded)
+			// Rows are still read after the callbacks ran, so they keep the
+			// deadline, which releases the context once it passes
+			if kind != "row" {
+				state.cancel()
+			}
+			tx.Statement.Context = state.parent
+
+			// Callers that gave up are told so; only a deadline is a timeout
+			if tx.Error == nil || !timedOut || errors.Is(tx.Error, ErrQueryTimeout) {
+				return
+			}
+			query := QueryName(tx, kind)
+			tx.Error = fmt.Errorf("%w: %s: %w", ErrQueryTimeout, query, tx.Error)
+			if cfg.OnTimeout != nil {
+				cfg.OnTimeout(name, query, time.Since(state.start))
+			}
+		}
+	}
+
+	callbacks := db.Callback()
+	if callbacks.Query().Get(queryTimeoutCallback+":before") != nil {
+		return
+	}
+	for _, register := range []struct {
+		kind          string
+		before, after interface {
+			Register(name string, fn func(*gorm.DB)) error
+		}
+	}{
+		{"create", callbacks.Create().Before("*"), callbacks.Create().After("*")},
+		{"query", callbacks.Query().Before("*"), callbacks.Query().After("*")},
+		{"update", callbacks.Update().Before("*"), callbacks.Update().After("*")},
+		{"delete", callbacks.Delete().Before("*"), callbacks.Delete().After("*")},
+		{"row", callbacks.Row().Before("*"), callbacks.Row().After("*")},
+		{"raw", callbacks.Raw().Before("*"), callbacks.Raw().After("*")},
+	} {
+		_ = register.before.Register(queryTimeoutCallback+":before", before)
+		_ = register.after.Register(queryTimeoutCallback+":after", after(register.kind))
+	}
+}
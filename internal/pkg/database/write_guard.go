@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// WriteGuard decides whether a write made with ctx may run; a non-nil
+// error refuses it
+type WriteGuard func(ctx context.Context) error
+
+// writeGuardCallback names the GORM callbacks SetWriteGuard installs
+const writeGuardCallback = "write_guard"
+
+// unguarded is set on statements the write guard lets through, such as the
+// write probe's, which checks the database rather than changing data
+const unguarded = writeGuardCallback + ":skip"
+
+// SetWriteGuard consults guard before every GORM create, update, delete and
+// Exec on the manager's databases, including those registered later.
+// Refused statements fail with the guard's error and never reach the
+// database. Queries sent straight to GetSQLDB are not guarded.
+func (m *Manager) SetWriteGuard(guard WriteGuard) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.writeGuard.Store(&guard)
+	for _, driver := range m.drivers {
+		m.instrumentWriteGuard(driver)
+	}
+}
+
+// checkWrite runs the write guard, if one is set
+func (m *Manager) checkWrite(ctx context.Context) error {
+	guard := m.writeGuard.Load()
+	if guard == nil || *guard == nil {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return (*guard)(ctx)
+}
+
+// instrumentWriteGuard makes the driver's GORM writes consult the write
+// guard. Drivers without an SQL connection have nothing to guard.
+func (m *Manager) instrumentWriteGuard(driver Driver) {
+	if m.writeGuard.Load() == nil {
+		return
+	}
+	db, err := OpenGorm(driver)
+	if err != nil {
+		return
+	}
+
+	callbacks := db.Callback()
+	if callbacks.Create().Get(writeGuardCallback+":before") != nil {
+		return
+	}
+	check := func(tx *gorm.DB) {
+		if skip, _ := tx.Get(unguarded); skip == true {
+			return
+		}
+		if err := m.checkWrite(tx.Statement.Context); err != nil {
+			tx.AddError(err)
+		}
+	}
+	for _, processor := range []interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}{
+		callbacks.Create().Before("*"),
+		callbacks.Update().Before("*"),
+		callbacks.Delete().Before("*"),
+		callbacks.Raw().Before("*"),
+	} {
+		_ = processor.Register(writeGuardCallback+":before", check)
+	}
+}
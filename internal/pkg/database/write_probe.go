@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/pkg/health"
+)
+
+// HealthCheckTable holds one row per service instance, rewritten by its
+// write probe
+const HealthCheckTable = "health_checks"
+
+// WriteProbeConfig configures a database write probe
+type WriteProbeConfig struct {
+	// InstanceID keys the row this instance writes, so replicas do not
+	// contend for one row
+	InstanceID string
+	// Timeout bounds each write; zero leaves it to the readiness timeout
+	Timeout time.Duration
+	// Failures is how many writes in a row must fail before the probe
+	// reports the database down; below one, the first failure does
+	Failures int
+}
+
+// WriteProbe returns an informational checker, named after the database
+// with a ":write" suffix, that writes this instance's row of
+// HealthCheckTable. It catches databases that answer pings but refuse
+// writes, such as a read-only replica or a full disk. Databases without
+// SQL are skipped.
+func (m *Manager) WriteProbe(name string, cfg WriteProbeConfig) health.Checker {
+	return &writeProbe{manager: m, name: name, config: cfg}
+}
+
+// writeProbe is the checker WriteProbe returns
+type writeProbe struct {
+	manager *Manager
+	name    string
+	config  WriteProbeConfig
+
+	mu          sync.Mutex
+	failures    int
+	lastErr     error
+	lastSuccess time.Time
+	skipped     bool
+}
+
+func (p *writeProbe) Name() string   { return p.name + ":write" }
+func (p *writeProbe) Critical() bool { return false }
+
+// Check writes the row and reports an error once Failures writes in a row
+// have failed
+func (p *writeProbe) Check(ctx context.Context) error {
+	skipped, err := p.write(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.skipped = skipped
+	if err == nil {
+		p.failures, p.lastErr = 0, nil
+		if !skipped {
+			p.lastSuccess = time.Now().UTC()
+		}
+		return nil
+	}
+	p.failures++
+	p.lastErr = err
+	if p.failures < p.config.Failures {
+		return nil
+	}
+	return fmt.Errorf("%d writes in a row failed: %w", p.failures, err)
+}
+
+// write inserts or updates this instance's row. It reports whether the
+// database was skipped for having no SQL.
+func (p *writeProbe) write(ctx context.Context) (bool, error) {
+	driver, err := p.manager.GetDriver(p.name)
+	if err != nil {
+		return false, err
+	}
+	if !driver.Capabilities().SQL {
+		return true, nil
+	}
+	db, err := OpenGorm(driver)
+	if err != nil {
+		return false, err
+	}
+	if p.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.Timeout)
+		defer cancel()
+	}
+
+	db = db.WithContext(ctx).Set(unguarded, true)
+	now := time.Now().UTC()
+	result := db.Table(HealthCheckTable).Where("instance_id = ?", p.config.InstanceID).Update("checked_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return false, nil
+	}
+	row := map[string]interface{}{"instance_id": p.config.InstanceID, "checked_at": now}
+	return false, db.Table(HealthCheckTable).Create(row).Error
+}
+
+// Details reports the instance, the failures so far, the last error and
+// when a write last succeeded; the check stays up until Failures is reached
+func (p *writeProbe) Details() map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	details := map[string]string{
+		"instance":             p.config.InstanceID,
+		"consecutive_failures": strconv.Itoa(p.failures),
+	}
+	if p.skipped {
+		details["skipped"] = "no sql"
+	}
+	if p.lastErr != nil {
+		details["last_error"] = p.lastErr.Error()
+	}
+	if !p.lastSuccess.IsZero() {
+		details["last_write"] = p.lastSuccess.Format(time.RFC3339)
+	}
+	return details
+}
@@ -0,0 +1,59 @@
+// Package emailaddr normalizes email addresses so that the ways of typing
+// one address are stored, compared and looked up as one.
+//
+//	n := emailaddr.Normalization{Gmail: true}
+//	n.Normalize(" John.Smith+news@GoogleMail.com ") // "johnsmith@gmail.com"
+package emailaddr
+
+import "strings"
+
+// gmailDomains are the domains of Gmail addresses; the first is canonical
+var gmailDomains = []string{"gmail.com", "googlemail.com"}
+
+// Normalization selects how addresses are normalized. Surrounding spaces are
+// always trimmed and the domain lowercased; the zero value also lowercases
+// the local part before the @.
+type Normalization struct {
+	// PreserveLocalCase keeps the case of the local part, for mail servers
+	// that treat it as significant
+	PreserveLocalCase bool
+	// Gmail drops the dots and any +tag from the local part of Gmail
+	// addresses, which Gmail ignores when delivering
+	Gmail bool
+}
+
+// Normalize returns address in its normalized form. Strings without an @
+// are only trimmed and, unless PreserveLocalCase is set, lowercased.
+func (n Normalization) Normalize(address string) string {
+	address = strings.TrimSpace(address)
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		if n.PreserveLocalCase {
+			return address
+		}
+		return strings.ToLower(address)
+	}
+
+	local, domain := address[:at], strings.ToLower(address[at+1:])
+	if !n.PreserveLocalCase {
+		local = strings.ToLower(local)
+	}
+	if n.Gmail && isGmail(domain) {
+		local, _, _ = strings.Cut(local, "+")
+		local = strings.ReplaceAll(local, ".", "")
+		// Gmail ignores case as well
+		local = strings.ToLower(local)
+		domain = gmailDomains[0]
+	}
+	return local + "@" + domain
+}
+
+// isGmail reports whether domain receives Gmail addresses
+func isGmail(domain string) bool {
+	for _, d := range gmailDomains {
+		if domain == d {
+			return true
+		}
+	}
+	return false
+}
@@ -3,13 +3,33 @@ package errors
 import (
 	"fmt"
 	"net/http"
+
+	"BackofficeGoService/internal/pkg/i18n"
 )
 
-// AppError represents an application error
+// AppError represents an application error.
+//
+// Status is the HTTP status and Code the registered code clients branch on.
+// Message is a key of the i18n catalogue, translated into the request's
+// locale when the error is rendered. Handlers that have not been converted
+// yet pass literal text, which is rendered unchanged.
 type AppError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Err     error  `json:"-"`
+	Status  int      `json:"status"`
+	Code    Code     `json:"code"`
+	Message string   `json:"message"`
+	Params  Params   `json:"-"`
+	Details []Detail `json:"-"`
+	Err     error    `json:"-"`
+}
+
+// Params fill the {name} placeholders of a message
+type Params map[string]string
+
+// Detail describes what is wrong with one field of a request
+type Detail struct {
+	Field   string
+	Message string
+	Params  Params
 }
 
 // Error implements the error interface
@@ -25,15 +45,62 @@ func (e *AppError) Unwrap() error {
 	return e.Err
 }
 
-// NewAppError creates a new application error
-func NewAppError(code int, message string, err error) *AppError {
+// WithCode replaces the generic code for the status with a specific one
+func (e *AppError) WithCode(code Code) *AppError {
+	e.Code = code
+	return e
+}
+
+// WithParams sets the values of the message's placeholders
+func (e *AppError) WithParams(params Params) *AppError {
+	e.Params = params
+	return e
+}
+
+// WithDetails adds per-field details to the error
+func (e *AppError) WithDetails(details ...Detail) *AppError {
+	e.Details = append(e.Details, details...)
+	return e
+}
+
+// NewAppError creates a new application error with the generic code for
+// status. An empty message uses the generic message for the status.
+func NewAppError(status int, message string, err error) *AppError {
+	if message == "" {
+		message = defaultMessage(status)
+	}
 	return &AppError{
-		Code:    code,
+		Status:  status,
+		Code:    defaultCode(status),
 		Message: message,
 		Err:     err,
 	}
 }
 
+// defaultMessage returns the generic message key for a status code
+func defaultMessage(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return i18n.ErrorBadRequest
+	case http.StatusUnauthorized:
+		return i18n.ErrorUnauthorized
+	case http.StatusForbidden:
+		return i18n.ErrorForbidden
+	case http.StatusNotFound:
+		return i18n.ErrorNotFound
+	case http.StatusConflict:
+		return i18n.ErrorConflict
+	case http.StatusUnprocessableEntity:
+		return i18n.ErrorValidation
+	case http.StatusNotImplemented:
+		return i18n.ErrorNotSupported
+	case http.StatusGatewayTimeout:
+		return i18n.ErrorQueryTimeout
+	default:
+		return i18n.ErrorInternal
+	}
+}
+
 // Predefined error constructors
 func NewBadRequestError(message string, err error) *AppError {
 	return NewAppError(http.StatusBadRequest, message, err)
@@ -51,6 +118,10 @@ func NewNotFoundError(message string, err error) *AppError {
 	return NewAppError(http.StatusNotFound, message, err)
 }
 
+func NewConflictError(message string, err error) *AppError {
+	return NewAppError(http.StatusConflict, message, err)
+}
+
 func NewInternalServerError(message string, err error) *AppError {
 	return NewAppError(http.StatusInternalServerError, message, err)
 }
@@ -59,3 +130,10 @@ func NewValidationError(message string, err error) *AppError {
 	return NewAppError(http.StatusUnprocessableEntity, message, err)
 }
 
+func NewNotSupportedError(message string, err error) *AppError {
+	return NewAppError(http.StatusNotImplemented, message, err)
+}
+
+func NewQueryTimeoutError(message string, err error) *AppError {
+	return NewAppError(http.StatusGatewayTimeout, message, err)
+}
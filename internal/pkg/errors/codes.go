@@ -0,0 +1,287 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Code identifies an error for clients, e.g. USER_NOT_FOUND. Clients branch
+// on codes, so a registered code is never renamed or reused.
+type Code string
+
+// CodeInfo describes a registered code
+type CodeInfo struct {
+	Code        Code   `json:"code"`
+	Description string `json:"description"`
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[Code]string{}
+)
+
+// Register adds a code to the catalogue. It panics when the code is empty or
+// already registered, so duplicates are caught when the program starts.
+func Register(code, description string) Code {
+	if code == "" {
+		panic("errors: empty error code")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[Code(code)]; exists {
+		panic(fmt.Sprintf("errors: error code %s registered twice", code))
+	}
+	registry[Code(code)] = description
+	return Code(code)
+}
+
+// Registered reports whether code is in the catalogue
+func Registered(code Code) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, ok := registry[code]
+	return ok
+}
+
+// Describe returns the catalogue description of code, or "" when it is not
+// registered
+func Describe(code Code) string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[code]
+}
+
+// Codes returns every registered code, sorted
+func Codes() []CodeInfo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	codes := make([]CodeInfo, 0, len(registry))
+	for code, description := range registry {
+		codes = append(codes, CodeInfo{Code: code, Description: description})
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return codes
+}
+
+// Generic codes, used by the constructors until a specific code is set
+var (
+	CodeBadRequest         = Register("BAD_REQUEST", "The request is invalid")
+	CodeUnauthorized       = Register("UNAUTHORIZED", "Authentication failed")
+	CodeForbidden          = Register("FORBIDDEN", "The caller may not perform this operation")
+	CodeNotFound           = Register("NOT_FOUND", "The resource does not exist")
+	CodeConflict           = Register("CONFLICT", "The request conflicts with the current state")
+	CodeValidationFailed   = Register("VALIDATION_FAILED", "One or more fields failed validation; see details")
+	CodeInternal           = Register("INTERNAL_ERROR", "An unexpected server error")
+	CodeServiceUnavailable = Register("SERVICE_UNAVAILABLE", "A dependency is temporarily unavailable; retry later")
+	CodeNotSupported       = Register("OPERATION_NOT_SUPPORTED", "The configured database driver does not support the operation")
+	CodeQueryTimeout       = Register("QUERY_TIMEOUT", "A database query took too long; retry later or narrow the request")
+)
+
+// Request codes
+var (
+	CodeInvalidRequestBody = Register("INVALID_REQUEST_BODY", "The request body is not valid JSON for this endpoint")
+	CodeServerBusy         = Register("SERVER_BUSY", "The server is handling too many requests; retry after the Retry-After delay")
+	CodeServiceReadOnly    = Register("SERVICE_READ_ONLY", "Writes are frozen while the service is read-only; reads keep working, retry the change later")
+	CodeUnknownFields      = Register("UNKNOWN_FIELDS", "The request body contains fields the endpoint does not accept; see details")
+	CodeInvalidFilter      = Register("INVALID_FILTER", "A query filter has an invalid value")
+	CodeInvalidSort        = Register("INVALID_SORT", "The list cannot be sorted by the requested field; see the message for the allowed ones")
+	CodeInvalidCursor      = Register("INVALID_CURSOR", "The pagination cursor is malformed; start again from the first page")
+	CodeInvalidPagination  = Register("INVALID_PAGINATION", "The page or limit query parameter is not a positive integer")
+)
+
+// Authentication and authorization codes
+var (
+	CodeInvalidCredentials      = Register("INVALID_CREDENTIALS", "The email or password is wrong")
+	CodeAuthenticationRequired  = Register("AUTHENTICATION_REQUIRED", "The endpoint requires an authenticated user")
+	CodeTokenRequired           = Register("TOKEN_REQUIRED", "No access token was sent")
+	CodeTokenInvalid            = Register("TOKEN_INVALID", "The access token is malformed, has a bad signature or belongs to another tenant")
+	CodeTokenExpired            = Register("TOKEN_EXPIRED", "The access token has expired; refresh it or log in again")
+	CodeTokenRevoked            = Register("TOKEN_REVOKED", "The access token has been revoked")
+	CodeSessionExpired          = Register("SESSION_EXPIRED", "The login session was idle or open too long; log in again")
+	CodeAccountDeactivated      = Register("ACCOUNT_DEACTIVATED", "The user's account is deactivated")
+	CodeInsufficientPermissions = Register("INSUFFICIENT_PERMISSIONS", "The user's role lacks the required permission")
+	CodeNotOrganizationMember   = Register("NOT_ORGANIZATION_MEMBER", "The user does not belong to the organization")
+
+	CodeImpersonationForbidden     = Register("IMPERSONATION_FORBIDDEN", "The operation is not allowed with an impersonation token")
+	CodeAdminImpersonationDisabled = Register("ADMIN_IMPERSONATION_DISABLED", "Impersonating other admins is disabled on this server")
+	CodeSelfImpersonation          = Register("SELF_IMPERSONATION", "Users cannot impersonate themselves")
+	CodeNotImpersonating           = Register("NOT_IMPERSONATING", "The access token was not issued by impersonation")
+
+	CodePasswordChangeRequired = Register("PASSWORD_CHANGE_REQUIRED", "The password has expired or must be changed; the token only allows changing it")
+	CodeProfileIncomplete      = Register("PROFILE_INCOMPLETE", "The profile misses required fields; the token only allows viewing and completing it through /me")
+	CodeInvalidCurrentPassword = Register("INVALID_CURRENT_PASSWORD", "The current password is wrong")
+	CodePasswordReused         = Register("PASSWORD_REUSED", "The new password must differ from the current one")
+
+	CodePasswordBreached         = Register("PASSWORD_BREACHED", "The password appears in known data breaches; choose another")
+	CodePasswordCheckUnavailable = Register("PASSWORD_CHECK_UNAVAILABLE", "Passwords cannot be checked against known breaches right now; try again later")
+
+	CodeEmailUnchanged          = Register("EMAIL_UNCHANGED", "The new email address is the current one")
+	CodeEmailChangeTokenInvalid = Register("EMAIL_CHANGE_TOKEN_INVALID", "The email change token is unknown or was superseded by a newer request")
+	CodeEmailChangeTokenExpired = Register("EMAIL_CHANGE_TOKEN_EXPIRED", "The email change token has expired; request the change again")
+	CodeEmailUnavailable        = Register("EMAIL_UNAVAILABLE", "The server cannot send email, so the operation is unavailable")
+
+	CodeSecureAccountTokenInvalid = Register("SECURE_ACCOUNT_TOKEN_INVALID", "The secure account token from a new sign-in email is unknown, used or expired")
+
+	CodeJWTKeyFileUnset       = Register("JWT_KEY_FILE_UNSET", "JWT_SECRET_FILE is not set, so the JWT secret cannot be rotated")
+	CodeJWTRotationInProgress = Register("JWT_ROTATION_IN_PROGRESS", "Tokens signed with the previous JWT secret are still accepted; rotate again once its grace window ends, or force it")
+
+	CodeOIDCProviderNotFound    = Register("OIDC_PROVIDER_NOT_FOUND", "No OpenID Connect provider has the given slug")
+	CodeOIDCStateInvalid        = Register("OIDC_STATE_INVALID", "The sign-in state is unknown, used, expired or from another provider; sign in again")
+	CodeOIDCLoginFailed         = Register("OIDC_LOGIN_FAILED", "The provider refused the authorization code or sent an invalid ID token")
+	CodeOIDCAccountExists       = Register("OIDC_ACCOUNT_EXISTS", "A user with the provider account's email exists but is not linked to it")
+	CodeOIDCNotProvisioned      = Register("OIDC_NOT_PROVISIONED", "No user is linked to the provider account and the provider does not create users")
+	CodeOIDCProviderUnavailable = Register("OIDC_PROVIDER_UNAVAILABLE", "The OpenID Connect provider cannot be reached; try again later")
+)
+
+// User codes
+var (
+	CodeUserNotFound        = Register("USER_NOT_FOUND", "No user has the given ID")
+	CodeUserIDRequired      = Register("USER_ID_REQUIRED", "The user ID is missing")
+	CodeEmailAlreadyExists  = Register("EMAIL_ALREADY_EXISTS", "Another user already has this email")
+	CodeSearchQueryRequired = Register("SEARCH_QUERY_REQUIRED", "The search text q is missing")
+	CodeSelfDeactivation    = Register("SELF_DEACTIVATION", "Users cannot deactivate their own account")
+	CodeLastActiveAdmin     = Register("LAST_ACTIVE_ADMIN", "The last active admin cannot be deactivated")
+	CodeInvalidRole         = Register("INVALID_ROLE", "The role is not one of admin, user or guest")
+	CodeLastAdminDemotion   = Register("LAST_ADMIN_DEMOTION", "The last active admin cannot lose the admin role")
+
+	CodeInvalidExportFormat = Register("INVALID_EXPORT_FORMAT", "The export format is not supported; use csv or xlsx")
+	CodeInvalidExportColumn = Register("INVALID_EXPORT_COLUMN", "A requested export column does not exist; see the message for the allowed ones")
+	CodeInvalidExportSort   = Register("INVALID_EXPORT_SORT", "The export cannot be sorted by the requested field")
+)
+
+// Task and file codes
+var (
+	CodeTaskNotFound          = Register("TASK_NOT_FOUND", "No task has the given ID")
+	CodeTaskFinished          = Register("TASK_FINISHED", "The task has already finished and cannot be cancelled")
+	CodeFileNotFound          = Register("FILE_NOT_FOUND", "The file behind the download link no longer exists")
+	CodeDownloadLinkInvalid   = Register("DOWNLOAD_LINK_INVALID", "The download link's signature does not match")
+	CodeDownloadLinkExpired   = Register("DOWNLOAD_LINK_EXPIRED", "The download link has expired; fetch the task again for a new one")
+	CodeDownloadLinkForbidden = Register("DOWNLOAD_LINK_FORBIDDEN", "The download link was issued to another user; sign in as that user")
+)
+
+// Session codes
+var (
+	CodeSessionNotFound = Register("SESSION_NOT_FOUND", "The user has no active session with the given ID")
+	CodeDeviceNotFound  = Register("DEVICE_NOT_FOUND", "The user has no trusted device with the given ID")
+)
+
+// Policy codes
+var (
+	CodeUnknownPolicy            = Register("UNKNOWN_POLICY", "No policy has the given key")
+	CodePolicyVersionOutdated    = Register("POLICY_VERSION_OUTDATED", "The version is not the current version of the policy; fetch the pending policies again")
+	CodePolicyAcceptanceRequired = Register("POLICY_ACCEPTANCE_REQUIRED", "The current version of a policy must be accepted through /api/v1/me/accept-policy first")
+)
+
+// Quota and usage codes
+var (
+	CodeQuotaExceeded = Register("QUOTA_EXCEEDED", "The user's monthly API quota is spent; retry after the X-Quota-Reset time")
+	CodeInvalidPeriod = Register("INVALID_USAGE_PERIOD", "The usage period is not a month such as 2024-06")
+)
+
+// Route policy codes
+var (
+	CodeRateLimited    = Register("RATE_LIMITED", "Too many requests to the endpoint from the client; retry after the Retry-After seconds")
+	CodeRequestTimeout = Register("REQUEST_TIMEOUT", "The request took longer than the endpoint allows; retry later")
+)
+
+// Database codes
+var (
+	CodeInvalidDatabase          = Register("INVALID_DATABASE", "The database name or connection settings are not valid")
+	CodeDatabaseExists           = Register("DATABASE_EXISTS", "A database is already registered under the name")
+	CodeDatabaseNotFound         = Register("DATABASE_NOT_FOUND", "No database is registered under the name")
+	CodeDatabaseInUse            = Register("DATABASE_IN_USE", "The primary database and databases tenants are routed to cannot be removed")
+	CodeDatabaseConnectionFailed = Register("DATABASE_CONNECTION_FAILED", "The database could not be reached within DB_REGISTRATION_TIMEOUT; nothing was registered")
+)
+
+// Partner codes
+var (
+	CodePartnerNotFound   = Register("PARTNER_NOT_FOUND", "No partner has the given ID")
+	CodePartnerExists     = Register("PARTNER_EXISTS", "Another partner already has the name")
+	CodeSignatureInvalid  = Register("SIGNATURE_INVALID", "The request is not signed with the secret of an enabled partner, or X-Partner-ID, X-Timestamp or X-Signature is missing")
+	CodeSignatureExpired  = Register("SIGNATURE_EXPIRED", "X-Timestamp is further from the server time than PARTNER_SIGNATURE_WINDOW; sign the request again")
+	CodeSignatureReplayed = Register("SIGNATURE_REPLAYED", "The signed request was already received; sign a new one")
+)
+
+// Email template codes
+var (
+	CodeEmailTemplateNotFound     = Register("EMAIL_TEMPLATE_NOT_FOUND", "No email template is registered under the name")
+	CodeEmailFormatUnsupported    = Register("EMAIL_FORMAT_UNSUPPORTED", "The email template has no body in the requested format; use text or html")
+	CodeEmailTemplateRenderFailed = Register("EMAIL_TEMPLATE_RENDER_FAILED", "The email template failed to render with its sample data")
+	CodeEmailSendFailed           = Register("EMAIL_SEND_FAILED", "The email client failed to send the message")
+)
+
+// Route table codes
+var (
+	CodeInvalidRouteFormat = Register("INVALID_ROUTE_FORMAT", "The route table format is not supported; use json or markdown")
+)
+
+// Saved filter codes
+var (
+	CodeSavedFilterNotFound     = Register("SAVED_FILTER_NOT_FOUND", "No saved filter the user may read has the given ID")
+	CodeSavedFilterForbidden    = Register("SAVED_FILTER_FORBIDDEN", "Only the owner or an admin may change or delete a shared filter")
+	CodeUnknownFilterField      = Register("UNKNOWN_FILTER_FIELD", "The filter has a field the resource's filter schema does not know; see the message for the allowed ones")
+	CodeUnknownFilterResource   = Register("UNKNOWN_FILTER_RESOURCE", "Filters cannot be saved for the resource type")
+	CodeFilterResourceMismatch  = Register("FILTER_RESOURCE_MISMATCH", "The saved filter filters another resource than the listing")
+	CodeFilterSchemaUnsupported = Register("FILTER_SCHEMA_UNSUPPORTED", "The saved filter was stored under a newer filter schema than the server knows")
+)
+
+// Product codes
+var (
+	CodeProductNotFound = Register("PRODUCT_NOT_FOUND", "No product has the given ID")
+	CodeProductSKUTaken = Register("PRODUCT_SKU_TAKEN", "Another product already has the SKU")
+)
+
+// Approval codes
+var (
+	CodeApprovalNotFound     = Register("APPROVAL_NOT_FOUND", "No approval has the given ID")
+	CodeApprovalPending      = Register("APPROVAL_PENDING", "A change of the same kind to the user is already awaiting approval; cancel it first")
+	CodeApprovalNotPending   = Register("APPROVAL_NOT_PENDING", "The approval has already been approved, rejected or cancelled")
+	CodeApprovalExpired      = Register("APPROVAL_EXPIRED", "The approval expired before anyone decided it; request the change again")
+	CodeSelfApproval         = Register("SELF_APPROVAL", "Changes must be approved or rejected by an admin other than the requester")
+	CodeNotApprovalRequester = Register("NOT_APPROVAL_REQUESTER", "Only the admin who requested the change can cancel it")
+	CodeApprovalStale        = Register("APPROVAL_STALE", "The user changed since the approval was requested; request the change again")
+)
+
+// Confirmation codes
+var (
+	CodeConfirmationInvalid  = Register("CONFIRMATION_TOKEN_INVALID", "X-Confirm-Token is unknown, was already used or expired; send the request without it for a new token")
+	CodeConfirmationMismatch = Register("CONFIRMATION_TOKEN_MISMATCH", "X-Confirm-Token was issued to another user or for another request; repeat the exact request it was issued for")
+)
+
+// WebSocket protocol codes, sent in error messages on /api/v1/ws
+var (
+	CodeSocketMessageInvalid = Register("SOCKET_MESSAGE_INVALID", "The socket message is not valid JSON or has an unknown type")
+	CodeSocketTopicUnknown   = Register("SOCKET_TOPIC_UNKNOWN", "No events are published on the topic")
+	CodeSocketTopicForbidden = Register("SOCKET_TOPIC_FORBIDDEN", "The user may not subscribe to the topic")
+)
+
+// defaultCode returns the generic code for a status
+func defaultCode(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeValidationFailed
+	case http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	case http.StatusNotImplemented:
+		return CodeNotSupported
+	case http.StatusGatewayTimeout:
+		return CodeQueryTimeout
+	default:
+		return CodeInternal
+	}
+}
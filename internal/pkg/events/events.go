@@ -0,0 +1,149 @@
+// Package events provides the in-process domain event stream. Services
+// publish events after state changes; subscribers such as the webhook
+// dispatcher consume them without the publisher knowing about them.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/pkg/apimodel"
+	"BackofficeGoService/internal/pkg/geoip"
+
+	"github.com/google/uuid"
+)
+
+// User event types
+const (
+	UserCreated     = "user.created"
+	UserUpdated     = "user.updated"
+	UserDeleted     = "user.deleted"
+	UserActivated   = "user.activated"
+	UserDeactivated = "user.deactivated"
+	UserAnonymized  = "user.anonymized"
+
+	// WebhookTest is only sent by the webhook "send test event" endpoint
+	WebhookTest = "webhook.test"
+)
+
+// Login event types. They are only published in-process, for the
+// authentication watch, and are not offered to webhooks or sockets.
+const (
+	LoginSucceeded = "login.succeeded"
+	LoginFailed    = "login.failed"
+)
+
+// Types lists every event type published on the stream
+var Types = []string{
+	UserCreated,
+	UserUpdated,
+	UserDeleted,
+	UserActivated,
+	UserDeactivated,
+	UserAnonymized,
+}
+
+// Known reports whether eventType is published on the stream
+func Known(eventType string) bool {
+	for _, t := range Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a single domain event
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Login is the data of a login event. UserID is empty when the email
+// matched no user.
+type Login struct {
+	UserID    string `json:"user_id,omitempty"`
+	Email     string `json:"email"`
+	Success   bool   `json:"success"`
+	Reason    string `json:"reason,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+
+	// Location is where IPAddress is, on successful logins GeoIP placed
+	Location *geoip.Location `json:"location,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (e Event) MarshalJSON() ([]byte, error) { return apimodel.Marshal(e) }
+
+// New creates an event with a fresh ID
+func New(eventType string, data interface{}) Event {
+	return Event{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+// Handler consumes an event. Handlers run on the publisher's goroutine and
+// must hand slow work off (e.g. to a queue) instead of blocking.
+type Handler func(ctx context.Context, event Event)
+
+// Publisher publishes events to the stream
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Subscriber registers handlers on the stream
+type Subscriber interface {
+	// Subscribe registers handler and returns a function that removes it
+	Subscribe(handler Handler) func()
+}
+
+// Bus is an in-process Publisher and Subscriber
+type Bus struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[int]Handler
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+	return &Bus{
+		handlers: make(map[int]Handler),
+	}
+}
+
+// Publish delivers event to every subscribed handler
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers))
+	for _, h := range b.handlers {
+		handlers = append(handlers, h)
+	}
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(ctx, event)
+	}
+	return nil
+}
+
+// Subscribe registers handler and returns a function that removes it
+func (b *Bus) Subscribe(handler Handler) func() {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+	}
+}
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvFlushRows is how many rows the CSV writer buffers before flushing
+const csvFlushRows = 500
+
+// CSVWriter writes rows as CSV. Times are written in UTC as RFC 3339.
+type CSVWriter struct {
+	out     *csv.Writer
+	pending int
+}
+
+// NewCSVWriter returns a writer encoding CSV to w
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{out: csv.NewWriter(w)}
+}
+
+// WriteHeader writes the column names
+func (w *CSVWriter) WriteHeader(columns []Column) error {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return w.out.Write(names)
+}
+
+// WriteRow writes one record, flushing every csvFlushRows rows so large
+// exports reach the client as they are read
+func (w *CSVWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			record[i] = v
+		case bool:
+			record[i] = strconv.FormatBool(v)
+		case time.Time:
+			record[i] = v.UTC().Format(time.RFC3339)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	if err := w.out.Write(record); err != nil {
+		return err
+	}
+	if w.pending++; w.pending >= csvFlushRows {
+		w.pending = 0
+		w.out.Flush()
+		return w.out.Error()
+	}
+	return nil
+}
+
+// Flush writes the buffered rows
+func (w *CSVWriter) Flush() error {
+	w.out.Flush()
+	return w.out.Error()
+}
+
+// Close does nothing; CSV holds no resources
+func (w *CSVWriter) Close() error {
+	return nil
+}
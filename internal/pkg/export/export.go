@@ -0,0 +1,138 @@
+// Package export writes tabular data, such as bulk user exports, in the
+// formats clients download. A RowSource produces typed rows and a Writer
+// encodes them, so the query behind an export is shared by every format:
+//
+//	err := export.Write(ctx, w, export.FormatXLSX, source)
+package export
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Format is a file format an export can be written in
+type Format string
+
+// Supported formats
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+// Formats lists the supported formats
+var Formats = []Format{FormatCSV, FormatXLSX}
+
+// ErrUnknownFormat is returned for a format that is not supported
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// ParseFormat returns the format named s, case-insensitively. An empty s is CSV.
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return FormatCSV, nil
+	}
+	for _, format := range Formats {
+		if strings.EqualFold(s, string(format)) {
+			return format, nil
+		}
+	}
+	return "", ErrUnknownFormat
+}
+
+// ContentType returns the media type of files in the format
+func (f Format) ContentType() string {
+	if f == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Extension returns the file name extension of the format, without the dot
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// Kind is the type of a column's values
+type Kind int
+
+// Column kinds. Values are string, bool and time.Time respectively; nil is
+// an empty cell in any column.
+const (
+	KindString Kind = iota
+	KindBool
+	KindTime
+)
+
+// Column describes one column of an export
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+// RowSource produces the rows of an export
+type RowSource interface {
+	// Columns returns the columns of every row, in order
+	Columns() []Column
+
+	// Next returns the next row, one value per column, or io.EOF after the
+	// last row
+	Next(ctx context.Context) ([]interface{}, error)
+}
+
+// Writer encodes rows in one format
+type Writer interface {
+	// WriteHeader writes the header row; it is called once, before any row
+	WriteHeader(columns []Column) error
+
+	WriteRow(values []interface{}) error
+
+	// Flush finishes the file and writes whatever is still buffered
+	Flush() error
+
+	// Close releases the writer's resources. It does not close the
+	// underlying io.Writer and is safe to call after Flush.
+	Close() error
+}
+
+// NewWriter returns a writer encoding format to w
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatXLSX:
+		return NewXLSXWriter(w), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// Write copies every row of source to w, encoded as format. It stops when
+// ctx is cancelled.
+func Write(ctx context.Context, w io.Writer, format Format, source RowSource) error {
+	out, err := NewWriter(format, w)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := out.WriteHeader(source.Columns()); err != nil {
+		return err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row, err := source.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := out.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
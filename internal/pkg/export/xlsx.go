@@ -0,0 +1,104 @@
+package export
+
+import (
+	"io"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// XLSXSheet is the name of the worksheet an XLSX export is written to
+const XLSXSheet = "Sheet1"
+
+// xlsxDateFormat is the number format of time cells
+const xlsxDateFormat = "yyyy-mm-dd hh:mm:ss"
+
+// xlsxDateWidth fits a formatted time, so Excel does not show ####
+const xlsxDateWidth = 20
+
+// XLSXWriter writes rows to an Excel workbook with a bold, frozen header
+// row. Bool columns become boolean cells and time columns date cells in UTC.
+// Rows are streamed to a temporary file once they outgrow a small buffer,
+// so memory stays flat however many rows are written; the workbook reaches
+// the underlying writer on Flush.
+type XLSXWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+
+	dateStyle int
+	row       int
+}
+
+// NewXLSXWriter returns a writer encoding an XLSX workbook to w
+func NewXLSXWriter(w io.Writer) *XLSXWriter {
+	return &XLSXWriter{w: w, file: excelize.NewFile()}
+}
+
+// WriteHeader writes the bold header row and freezes it
+func (w *XLSXWriter) WriteHeader(columns []Column) error {
+	stream, err := w.file.NewStreamWriter(XLSXSheet)
+	if err != nil {
+		return err
+	}
+	headerStyle, err := w.file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	dateFormat := xlsxDateFormat
+	if w.dateStyle, err = w.file.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat}); err != nil {
+		return err
+	}
+
+	// Widths and panes can only be set before the first row
+	for i, column := range columns {
+		if column.Kind == KindTime {
+			if err := stream.SetColWidth(i+1, i+1, xlsxDateWidth); err != nil {
+				return err
+			}
+		}
+	}
+	if err := stream.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+
+	cells := make([]interface{}, len(columns))
+	for i, column := range columns {
+		cells[i] = excelize.Cell{StyleID: headerStyle, Value: column.Name}
+	}
+	w.stream = stream
+	w.row = 1
+	return stream.SetRow("A1", cells)
+}
+
+// WriteRow writes one row below the previous one
+func (w *XLSXWriter) WriteRow(values []interface{}) error {
+	cells := make([]interface{}, len(values))
+	for i, value := range values {
+		if t, ok := value.(time.Time); ok {
+			cells[i] = excelize.Cell{StyleID: w.dateStyle, Value: t.UTC()}
+			continue
+		}
+		cells[i] = value
+	}
+
+	w.row++
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+	return w.stream.SetRow(cell, cells)
+}
+
+// Flush completes the worksheet and writes the workbook
+func (w *XLSXWriter) Flush() error {
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	return w.file.Write(w.w)
+}
+
+// Close removes the temporary files holding streamed rows
+func (w *XLSXWriter) Close() error {
+	return w.file.Close()
+}
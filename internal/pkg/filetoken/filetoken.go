@@ -0,0 +1,167 @@
+// Package filetoken mints and verifies the tokens of download links. A token
+// names a stored object, who may fetch it and until when, and is signed with
+// HMAC-SHA256, so links are checked without a database lookup. Keys rotate by
+// keeping the previous secret for a grace period.
+package filetoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"BackofficeGoService/internal/pkg/clock"
+)
+
+// Anyone is the user of tokens any caller may use
+const Anyone = ""
+
+var (
+	// ErrInvalidToken is returned for tokens that are malformed or not signed
+	// with a current key
+	ErrInvalidToken = errors.New("invalid file token")
+	// ErrExpired is returned for tokens past their expiry
+	ErrExpired = errors.New("file token has expired")
+)
+
+// domain is mixed into every signature, so a secret shared with another use
+// never yields a valid file token
+const domain = "filetoken/v1\n"
+
+// Claims are what a token grants
+type Claims struct {
+	// Key is the storage key of the object
+	Key string `json:"k"`
+	// UserID is the only user the token works for, or Anyone
+	UserID string `json:"u,omitempty"`
+	// ExpiresAt is when the token stops working
+	ExpiresAt time.Time `json:"-"`
+}
+
+// Allows reports whether userID may use the token; userID is empty for
+// anonymous callers
+func (c Claims) Allows(userID string) bool {
+	return c.UserID == Anyone || c.UserID == userID
+}
+
+// payload is what is signed
+type payload struct {
+	Claims
+	Expires int64 `json:"e"`
+}
+
+// Signer mints and verifies tokens
+type Signer struct {
+	urlPrefix string
+	secret    []byte
+	previous  []byte
+	until     time.Time
+	clock     clock.Clock
+}
+
+// Option configures a Signer
+type Option func(*Signer)
+
+// WithPreviousSecret also accepts tokens signed with secret until the given
+// time, so links minted before a key rotation keep working
+func WithPreviousSecret(secret string, until time.Time) Option {
+	return func(s *Signer) {
+		if secret != "" {
+			s.previous, s.until = []byte(secret), until
+		}
+	}
+}
+
+// WithClock sets the clock tokens are minted and checked by
+func WithClock(c clock.Clock) Option {
+	return func(s *Signer) {
+		s.clock = c
+	}
+}
+
+// NewSigner creates a signer minting links under urlPrefix with secret
+func NewSigner(urlPrefix, secret string, opts ...Option) (*Signer, error) {
+	if secret == "" {
+		return nil, errors.New("filetoken: a signing secret is required")
+	}
+	s := &Signer{
+		urlPrefix: strings.TrimSuffix(urlPrefix, "/"),
+		secret:    []byte(secret),
+		clock:     clock.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Mint returns a token for key, usable by userID, or by Anyone, for ttl
+func (s *Signer) Mint(key, userID string, ttl time.Duration) (string, error) {
+	if key == "" {
+		return "", errors.New("filetoken: a key is required")
+	}
+	body, err := json.Marshal(payload{
+		Claims:  Claims{Key: key, UserID: userID},
+		Expires: s.clock.Now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(body)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sign(s.secret, encoded)), nil
+}
+
+// URL returns the download link of a token minted by Mint
+func (s *Signer) URL(key, userID string, ttl time.Duration) (string, error) {
+	token, err := s.Mint(key, userID, ttl)
+	if err != nil {
+		return "", err
+	}
+	return s.urlPrefix + "/" + token, nil
+}
+
+// Verify checks the signature and expiry of token and returns its claims
+func (s *Signer) Verify(token string) (Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !s.signed(encoded, mac) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil || p.Key == "" {
+		return Claims{}, ErrInvalidToken
+	}
+	p.ExpiresAt = time.Unix(p.Expires, 0)
+	if !s.clock.Now().Before(p.ExpiresAt) {
+		return p.Claims, ErrExpired
+	}
+	return p.Claims, nil
+}
+
+// signed reports whether mac signs encoded with the current secret or, during
+// its grace period, the previous one
+func (s *Signer) signed(encoded string, mac []byte) bool {
+	if hmac.Equal(mac, sign(s.secret, encoded)) {
+		return true
+	}
+	return s.previous != nil && s.clock.Now().Before(s.until) && hmac.Equal(mac, sign(s.previous, encoded))
+}
+
+// sign returns the HMAC-SHA256 of encoded under secret
+func sign(secret []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(domain))
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
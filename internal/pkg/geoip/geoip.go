@@ -0,0 +1,100 @@
+// Package geoip approximates where IP addresses are. Resolvers are
+// pluggable: a local MaxMind database, a remote HTTP provider, or None,
+// which knows no address. Callers on a request's path resolve through
+// ResolveWithin, so a slow resolver only costs them its timeout and a
+// failing one an unknown location.
+package geoip
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrInvalidIP is returned for addresses that do not parse
+var ErrInvalidIP = errors.New("invalid IP address")
+
+// Location is where an IP address approximately is. Country is an ISO
+// 3166-1 alpha-2 code. The zero Location is unknown, and coordinates of
+// exactly 0, 0 are taken as missing.
+type Location struct {
+	Country string  `json:"country,omitempty"`
+	City    string  `json:"city,omitempty"`
+	Lat     float64 `json:"lat,omitempty"`
+	Lon     float64 `json:"lon,omitempty"`
+}
+
+// Known reports whether anything is known of the location
+func (l Location) Known() bool {
+	return l.Country != "" || l.City != "" || l.HasCoordinates()
+}
+
+// HasCoordinates reports whether the location has a latitude and longitude
+func (l Location) HasCoordinates() bool {
+	return l.Lat != 0 || l.Lon != 0
+}
+
+// String formats the location as "Berlin, DE", the country alone without
+// a city, or "" when unknown
+func (l Location) String() string {
+	var parts []string
+	for _, part := range []string{l.City, l.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Resolver locates IP addresses. An address it has no location for
+// resolves to the zero Location without an error.
+type Resolver interface {
+	Resolve(ctx context.Context, ip string) (Location, error)
+}
+
+// None locates no address
+type None struct{}
+
+// Resolve returns the unknown location
+func (None) Resolve(ctx context.Context, ip string) (Location, error) {
+	return Location{}, nil
+}
+
+// ResolveWithin resolves ip with r, giving up after timeout. Errors and
+// timeouts resolve to the unknown location; a nil r or a timeout of zero
+// or less resolves nothing.
+func ResolveWithin(ctx context.Context, r Resolver, ip string, timeout time.Duration) Location {
+	if r == nil || timeout <= 0 || ip == "" {
+		return Location{}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Resolvers that ignore ctx are left to finish on their own
+	resolved := make(chan Location, 1)
+	go func() {
+		loc, err := r.Resolve(ctx, ip)
+		if err != nil {
+			loc = Location{}
+		}
+		resolved <- loc
+	}()
+
+	select {
+	case loc := <-resolved:
+		return loc
+	case <-ctx.Done():
+		return Location{}
+	}
+}
+
+// parseIP parses ip, failing with ErrInvalidIP
+func parseIP(ip string) (net.IP, error) {
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	if addr == nil {
+		return nil, ErrInvalidIP
+	}
+	return addr, nil
+}
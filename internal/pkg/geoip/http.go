@@ -0,0 +1,110 @@
+package geoip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"BackofficeGoService/internal/pkg/cache"
+)
+
+// httpResponse is the answer of an ipapi.co style provider
+type httpResponse struct {
+	Error       bool    `json:"error"`
+	CountryCode string  `json:"country_code"`
+	City        string  `json:"city"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+}
+
+// HTTPResolver resolves addresses with a remote provider answering
+// ipapi.co style JSON. Answers, unknown locations included, are cached so
+// an address is asked about once per cache lifetime; failed requests are
+// not cached.
+type HTTPResolver struct {
+	client *http.Client
+	url    string
+	store  cache.Store
+	ttl    time.Duration
+}
+
+// NewHTTPResolver creates a resolver requesting url, in which {ip} is
+// replaced by the address, such as https://ipapi.co/{ip}/json/, with
+// client. Answers are kept in store for ttl.
+func NewHTTPResolver(client *http.Client, url string, store cache.Store, ttl time.Duration) *HTTPResolver {
+	return &HTTPResolver{
+		client: client,
+		url:    url,
+		store:  cache.WithPrefix(store, "geoip"),
+		ttl:    ttl,
+	}
+}
+
+// Resolve returns the cached location of ip or asks the provider.
+// Private, loopback and unspecified addresses are unknown without asking.
+func (h *HTTPResolver) Resolve(ctx context.Context, ip string) (Location, error) {
+	addr, err := parseIP(ip)
+	if err != nil {
+		return Location{}, err
+	}
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsUnspecified() || addr.IsLinkLocalUnicast() {
+		return Location{}, nil
+	}
+	key := addr.String()
+
+	if data, err := h.store.Get(ctx, key); err == nil {
+		var loc Location
+		if json.Unmarshal(data, &loc) == nil {
+			return loc, nil
+		}
+	} else if !errors.Is(err, cache.ErrCacheMiss) {
+		return Location{}, fmt.Errorf("GeoIP cache unavailable: %w", err)
+	}
+
+	loc, err := h.lookup(ctx, key)
+	if err != nil {
+		return Location{}, err
+	}
+	if data, err := json.Marshal(loc); err == nil {
+		_ = h.store.Set(ctx, key, data, h.ttl)
+	}
+	return loc, nil
+}
+
+// lookup asks the provider where ip is
+func (h *HTTPResolver) lookup(ctx context.Context, ip string) (Location, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(h.url, "{ip}", ip), nil)
+	if err != nil {
+		return Location{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return Location{}, fmt.Errorf("GeoIP provider unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Location{}, fmt.Errorf("GeoIP provider answered status %d", resp.StatusCode)
+	}
+
+	var body httpResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err != nil {
+		return Location{}, fmt.Errorf("invalid GeoIP provider response: %w", err)
+	}
+	// Reserved and unlisted addresses are answered with an error flag
+	if body.Error {
+		return Location{}, nil
+	}
+	return Location{
+		Country: body.CountryCode,
+		City:    body.City,
+		Lat:     body.Latitude,
+		Lon:     body.Longitude,
+	}, nil
+}
@@ -0,0 +1,166 @@
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// maxMindRecord is what is read of a GeoLite2 or GeoIP2 City or Country
+// record; Country databases have no city or location
+type maxMindRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Location struct {
+		Latitude  float64 `maxminddb:"latitude"`
+		Longitude float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+// fileStamp tells versions of a file apart
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// MaxMind resolves addresses from a MaxMind database file, such as
+// GeoLite2-City.mmdb. The file is read into memory; once started, it is
+// read again when its size or modification time changes, so it can be
+// updated in place while the service runs.
+type MaxMind struct {
+	path   string
+	logger logger.Logger
+
+	mu     sync.RWMutex
+	reader *maxminddb.Reader
+	stamp  fileStamp
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// OpenMaxMind reads the database at path, failing if it cannot be read
+func OpenMaxMind(path string, log logger.Logger) (*MaxMind, error) {
+	m := &MaxMind{
+		path:   path,
+		logger: log,
+		stop:   make(chan struct{}),
+	}
+	if _, err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Start checks the file for changes every interval until Stop; zero or
+// less never does. A changed file that cannot be read is logged and the
+// database already read is kept.
+func (m *MaxMind) Start(interval time.Duration) {
+	if interval <= 0 || m.done != nil {
+		return
+	}
+	m.done = make(chan struct{})
+	go m.watch(interval)
+}
+
+// Reload reads the file again if it changed since it was last read,
+// reporting whether it did
+func (m *MaxMind) Reload() (bool, error) {
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	stamp := fileStamp{size: info.Size(), modTime: info.ModTime()}
+	m.mu.RLock()
+	unchanged := m.reader != nil && stamp == m.stamp
+	m.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read GeoIP database: %w", err)
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return false, fmt.Errorf("invalid GeoIP database %s: %w", m.path, err)
+	}
+
+	m.mu.Lock()
+	m.reader, m.stamp = reader, stamp
+	m.mu.Unlock()
+	return true, nil
+}
+
+// watch reloads the file every interval until Stop
+func (m *MaxMind) watch(interval time.Duration) {
+	defer close(m.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			reloaded, err := m.Reload()
+			if err != nil {
+				m.logger.Warn("Failed to reload GeoIP database", logger.Field{Key: "path", Value: m.path}, logger.Field{Key: "error", Value: err.Error()})
+				continue
+			}
+			if reloaded {
+				m.logger.Info("GeoIP database reloaded", logger.Field{Key: "path", Value: m.path})
+			}
+		}
+	}
+}
+
+// Resolve looks ip up in the database. Cities are named in English.
+func (m *MaxMind) Resolve(ctx context.Context, ip string) (Location, error) {
+	addr, err := parseIP(ip)
+	if err != nil {
+		return Location{}, err
+	}
+
+	m.mu.RLock()
+	reader := m.reader
+	m.mu.RUnlock()
+
+	var record maxMindRecord
+	if err := reader.Lookup(addr, &record); err != nil {
+		return Location{}, fmt.Errorf("GeoIP lookup failed: %w", err)
+	}
+	return Location{
+		Country: record.Country.ISOCode,
+		City:    record.City.Names["en"],
+		Lat:     record.Location.Latitude,
+		Lon:     record.Location.Longitude,
+	}, nil
+}
+
+// Stop stops checking the file for changes
+func (m *MaxMind) Stop(ctx context.Context) error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	if m.done == nil {
+		return nil
+	}
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
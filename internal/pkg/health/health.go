@@ -0,0 +1,295 @@
+// Package health runs the dependency checks behind the readiness endpoint.
+// Each dependency registers a Checker with the Registry; a check is critical,
+// meaning the service cannot work while it fails, or informational, meaning a
+// failure only degrades the service.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/pkg/apimodel"
+	"BackofficeGoService/internal/pkg/clock"
+)
+
+// Readiness states of a Report
+const (
+	StatusReady    = "ready"
+	StatusDegraded = "degraded"
+	StatusNotReady = "not ready"
+)
+
+// Check states of a Result
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Checker checks one dependency. Infrastructure packages provide adapters
+// for their clients, so a new dependency only needs to register one.
+type Checker interface {
+	// Name identifies the check in reports and in the exclude list
+	Name() string
+
+	// Check returns an error when the dependency cannot be used. It should be
+	// cheap, such as a ping, and must honour ctx.
+	Check(ctx context.Context) error
+
+	// Critical checks make the service not ready when they fail;
+	// informational ones only mark it degraded
+	Critical() bool
+}
+
+// Detailer is implemented by checkers that add facts about their dependency,
+// such as the state of its circuit breaker, to the result
+type Detailer interface {
+	Details() map[string]string
+}
+
+// NewCheck returns a checker that runs fn
+func NewCheck(name string, critical bool, fn func(ctx context.Context) error) Checker {
+	return &funcCheck{name: name, critical: critical, fn: fn}
+}
+
+// funcCheck adapts a function to Checker
+type funcCheck struct {
+	name     string
+	critical bool
+	fn       func(ctx context.Context) error
+}
+
+func (c *funcCheck) Name() string                    { return c.name }
+func (c *funcCheck) Check(ctx context.Context) error { return c.fn(ctx) }
+func (c *funcCheck) Critical() bool                  { return c.critical }
+
+// Source returns checkers whose set changes at runtime, such as the databases
+// of tenants registered while the service runs
+type Source func() []Checker
+
+// Result is the outcome of one check
+type Result struct {
+	Status      string            `json:"status"`
+	Critical    bool              `json:"critical"`
+	LatencyMS   float64           `json:"latency_ms"`
+	Error       string            `json:"error,omitempty"`
+	LastSuccess *time.Time        `json:"last_success,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) { return apimodel.Marshal(r) }
+
+// Report is the outcome of a run of every check
+type Report struct {
+	Status    string            `json:"status"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+
+	// Cached is set when the report was reused from an earlier run
+	Cached bool `json:"cached"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Report) MarshalJSON() ([]byte, error) { return apimodel.Marshal(r) }
+
+// Registry runs the registered checkers and caches their report
+type Registry struct {
+	ttl     time.Duration
+	timeout time.Duration
+	clock   clock.Clock
+
+	// mu is held while checks run, so concurrent callers share one run
+	mu          sync.Mutex
+	checkers    []Checker
+	sources     []Source
+	lastSuccess map[string]time.Time
+	cache       map[string]Report
+}
+
+// Option configures a Registry
+type Option func(*Registry)
+
+// WithClock sets the clock used for latencies and caching
+func WithClock(c clock.Clock) Option {
+	return func(r *Registry) {
+		r.clock = c
+	}
+}
+
+// WithTimeout bounds each check; a check still running after d is reported
+// down. Zero, the default, leaves checks bounded only by the caller's context.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Registry) {
+		r.timeout = d
+	}
+}
+
+// NewRegistry creates a registry whose reports are reused for ttl. A ttl of
+// zero runs the checks on every call.
+func NewRegistry(ttl time.Duration, opts ...Option) *Registry {
+	r := &Registry{
+		ttl:         ttl,
+		clock:       clock.New(),
+		lastSuccess: make(map[string]time.Time),
+		cache:       make(map[string]Report),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register adds checkers that run on every call
+func (r *Registry) Register(checkers ...Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers = append(r.checkers, checkers...)
+}
+
+// RegisterSource adds a source whose checkers are listed on every call
+func (r *Registry) RegisterSource(source Source) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = append(r.sources, source)
+}
+
+// Run runs every checker concurrently except the informational ones named in
+// exclude; critical checks cannot be skipped. The report is StatusNotReady if
+// a critical check failed, StatusDegraded if only informational ones did,
+// else StatusReady. A report younger than the ttl is reused for the same
+// exclude list.
+func (r *Registry) Run(ctx context.Context, exclude ...string) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := cacheKey(exclude)
+	now := r.clock.Now()
+	if report, ok := r.cache[key]; ok && now.Sub(report.CheckedAt) < r.ttl {
+		report.Cached = true
+		return report
+	}
+
+	skip := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		skip[name] = true
+	}
+	var checkers []Checker
+	for _, checker := range r.listCheckers() {
+		if checker.Critical() || !skip[checker.Name()] {
+			checkers = append(checkers, checker)
+		}
+	}
+
+	results := make([]Result, len(checkers))
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func(i int, checker Checker) {
+			defer wg.Done()
+			results[i] = r.run(ctx, checker)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Checks: make(map[string]Result, len(checkers)), CheckedAt: now}
+	for i, checker := range checkers {
+		name, result := checker.Name(), results[i]
+		if result.Status == StatusUp {
+			r.lastSuccess[name] = now
+		}
+		if last, ok := r.lastSuccess[name]; ok {
+			result.LastSuccess = &last
+		}
+		report.Checks[name] = result
+
+		switch {
+		case result.Status == StatusUp:
+		case result.Critical:
+			report.Status = StatusNotReady
+		case report.Status == StatusReady:
+			report.Status = StatusDegraded
+		}
+	}
+
+	if r.ttl > 0 {
+		r.prune(now)
+		r.cache[key] = report
+	}
+	return report
+}
+
+// listCheckers returns the registered checkers followed by the sources' checkers
+func (r *Registry) listCheckers() []Checker {
+	checkers := append([]Checker(nil), r.checkers...)
+	for _, source := range r.sources {
+		checkers = append(checkers, source()...)
+	}
+	return checkers
+}
+
+// run runs one checker within the timeout and times it. A checker that
+// ignores its context is abandoned at the timeout rather than holding up the
+// report.
+func (r *Registry) run(ctx context.Context, checker Checker) Result {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	start := r.clock.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- checker.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) && r.timeout > 0 {
+		err = fmt.Errorf("timed out after %s", r.timeout)
+	}
+
+	result := Result{
+		Status:    StatusUp,
+		Critical:  checker.Critical(),
+		LatencyMS: float64(r.clock.Now().Sub(start)) / float64(time.Millisecond),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	if detailer, ok := checker.(Detailer); ok {
+		result.Details = detailer.Details()
+	}
+	return result
+}
+
+// prune drops expired reports, so callers varying exclude cannot grow the cache
+func (r *Registry) prune(now time.Time) {
+	for key, report := range r.cache {
+		if now.Sub(report.CheckedAt) >= r.ttl {
+			delete(r.cache, key)
+		}
+	}
+}
+
+// cacheKey identifies an exclude list regardless of order and duplicates
+func cacheKey(exclude []string) string {
+	names := append([]string(nil), exclude...)
+	sort.Strings(names)
+	unique := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			unique = append(unique, name)
+		}
+	}
+	return strings.Join(unique, ",")
+}
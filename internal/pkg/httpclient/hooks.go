@@ -0,0 +1,56 @@
+package httpclient
+
+import (
+	"net/http"
+	"time"
+
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+// Logging logs the method, host, path, status and duration of every
+// attempt at debug level, and failed attempts at warn level. Query strings
+// and headers are left out, as they may carry credentials.
+func Logging(log logger.Logger) Hook {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			fields := []logger.Field{
+				{Key: "method", Value: req.Method},
+				{Key: "host", Value: req.URL.Host},
+				{Key: "path", Value: req.URL.Path},
+				{Key: "duration_ms", Value: time.Since(start).Milliseconds()},
+			}
+			if err != nil {
+				log.Warn("Outbound request failed", append(fields, logger.Field{Key: "error", Value: err.Error()})...)
+				return resp, err
+			}
+			log.Debug("Outbound request", append(fields, logger.Field{Key: "status", Value: resp.StatusCode})...)
+			return resp, nil
+		})
+	}
+}
+
+// LatencyObserver records the duration of an attempt to host. status is
+// 0 when no response was received.
+type LatencyObserver func(host string, status int, elapsed time.Duration)
+
+// Latency passes the host, status and duration of every attempt to
+// observe, such as metrics.Metrics.ObserveOutbound. The duration runs until
+// the response headers arrive.
+func Latency(observe LatencyObserver) Hook {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			status := 0
+			if err == nil {
+				status = resp.StatusCode
+			}
+			observe(req.URL.Host, status, time.Since(start))
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Package httpclient builds the http.Clients the service calls other
+// services with, such as webhook endpoints and email providers. Clients
+// time out, bound their connection pools, honour HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY, name the service in User-Agent and propagate the trace of
+// the calling request. Idempotent requests may be retried, and hooks see
+// every attempt, for logging and metrics.
+package httpclient
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"BackofficeGoService/internal/pkg/buildinfo"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Defaults for the Config fields left zero
+const (
+	DefaultTimeout               = 30 * time.Second
+	DefaultConnectTimeout        = 5 * time.Second
+	DefaultResponseHeaderTimeout = 15 * time.Second
+	DefaultMaxIdleConns          = 100
+	DefaultMaxIdleConnsPerHost   = 10
+	DefaultIdleConnTimeout       = 90 * time.Second
+	DefaultRetryBackoff          = 200 * time.Millisecond
+)
+
+// Config configures a client. Zero fields take the defaults above.
+type Config struct {
+	Timeout               time.Duration // Whole request, retries and reading the body included
+	ConnectTimeout        time.Duration // Dialing and the TLS handshake
+	ResponseHeaderTimeout time.Duration // Wait for the response headers once the request is written
+	MaxIdleConns          int           // Idle connections kept across all hosts
+	MaxIdleConnsPerHost   int           // Idle connections kept per host
+	MaxConnsPerHost       int           // Connections per host, 0 for no limit
+	IdleConnTimeout       time.Duration // Time an idle connection is kept
+
+	// MaxRetries is how many times an idempotent request is retried after a
+	// network error or a 429, 502, 503 or 504 response; 0 disables retries
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry; it doubles on each retry
+	RetryBackoff time.Duration
+
+	// UserAgent is sent with requests that set none; see UserAgent
+	UserAgent string
+}
+
+// withDefaults returns cfg with its zero fields set to the defaults
+func (cfg Config) withDefaults() Config {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.ConnectTimeout <= 0 {
+		cfg.ConnectTimeout = DefaultConnectTimeout
+	}
+	if cfg.ResponseHeaderTimeout <= 0 {
+		cfg.ResponseHeaderTimeout = DefaultResponseHeaderTimeout
+	}
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = DefaultMaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout <= 0 {
+		cfg.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = UserAgent("")
+	}
+	return cfg
+}
+
+// UserAgent returns "<name>/<version>" for the running build, spaces in
+// name replaced with dashes. An empty name stands for the service.
+func UserAgent(name string) string {
+	if name == "" {
+		name = "BackofficeGoService"
+	}
+	return strings.ReplaceAll(name, " ", "-") + "/" + buildinfo.Get().Version
+}
+
+// RoundTripperFunc is a function used as an http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Hook wraps the transport of a client, seeing every attempt of a request
+// and its outcome. Hooks must not read or close the response body.
+type Hook func(next http.RoundTripper) http.RoundTripper
+
+// New creates a client configured by cfg. Hooks run in order, the first
+// outermost, inside retries, so they see each attempt.
+func New(cfg Config, hooks ...Hook) *http.Client {
+	cfg = cfg.withDefaults()
+
+	dialer := &net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: 30 * time.Second}
+	var transport http.RoundTripper = &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.ConnectTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		ForceAttemptHTTP2:     true,
+	}
+	for i := len(hooks) - 1; i >= 0; i-- {
+		transport = hooks[i](transport)
+	}
+	if cfg.MaxRetries > 0 {
+		transport = &retryTransport{next: transport, retries: cfg.MaxRetries, backoff: cfg.RetryBackoff}
+	}
+	transport = headers(transport, cfg.UserAgent)
+
+	return &http.Client{Transport: transport, Timeout: cfg.Timeout}
+}
+
+// traceContext writes the W3C traceparent and tracestate headers
+var traceContext = propagation.TraceContext{}
+
+// headers sets the User-Agent of requests without one and, when the
+// request's context carries an OpenTelemetry span, the trace headers
+func headers(next http.RoundTripper, userAgent string) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		if req.Header.Get("User-Agent") == "" {
+			req.Header.Set("User-Agent", userAgent)
+		}
+		traceContext.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+		return next.RoundTrip(req)
+	})
+}
@@ -0,0 +1,153 @@
+// Package i18n translates API messages.
+//
+// Each locale has a catalogue in locales/<locale>.json, embedded in the
+// binary, that maps message keys to text. Text may contain {name}
+// placeholders that are filled from the parameters given to Translate:
+//
+//	i18n.Translate("de", i18n.ValidationRequired, map[string]string{"field": "email"})
+//
+// Keys missing from a catalogue fall back to English, and keys missing from
+// English are returned unchanged, so literal text passes through as is.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is used when a request asks for no supported locale
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var (
+	catalogs map[string]map[string]string
+	locales  []string
+	matcher  language.Matcher
+)
+
+func init() {
+	var err error
+	catalogs, err = loadCatalogs()
+	if err != nil {
+		panic(err)
+	}
+
+	// The default locale comes first so the matcher falls back to it
+	tags := []language.Tag{language.Make(DefaultLocale)}
+	locales = []string{DefaultLocale}
+	for locale := range catalogs {
+		if locale != DefaultLocale {
+			locales = append(locales, locale)
+		}
+	}
+	sort.Strings(locales[1:])
+	for _, locale := range locales[1:] {
+		tags = append(tags, language.Make(locale))
+	}
+	matcher = language.NewMatcher(tags)
+}
+
+// loadCatalogs reads every embedded catalogue
+func loadCatalogs() (map[string]map[string]string, error) {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("i18n: invalid catalogue %s: %w", entry.Name(), err)
+		}
+		loaded[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+	if _, ok := loaded[DefaultLocale]; !ok {
+		return nil, fmt.Errorf("i18n: missing %s catalogue", DefaultLocale)
+	}
+	return loaded, nil
+}
+
+// Locales returns the supported locales, DefaultLocale first
+func Locales() []string {
+	return append([]string(nil), locales...)
+}
+
+// Keys returns every key of the locale's catalogue, sorted
+func Keys(locale string) []string {
+	keys := make([]string, 0, len(catalogs[locale]))
+	for key := range catalogs[locale] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Has reports whether the locale's catalogue translates key
+func Has(locale, key string) bool {
+	_, ok := catalogs[locale][key]
+	return ok
+}
+
+// Translate returns the text for key in locale with its {name} placeholders
+// replaced by params
+func Translate(locale, key string, params map[string]string) string {
+	text, ok := catalogs[locale][key]
+	if !ok {
+		if text, ok = catalogs[DefaultLocale][key]; !ok {
+			return key
+		}
+	}
+	if len(params) == 0 {
+		return text
+	}
+
+	pairs := make([]string, 0, 2*len(params))
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Match returns the supported locale that best fits an Accept-Language
+// header or a single language tag, or DefaultLocale when none does
+func Match(preferences string) string {
+	tags, _, err := language.ParseAcceptLanguage(preferences)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return locales[index]
+}
+
+// localeContextKey holds the locale a request is answered in
+type localeContextKey struct{}
+
+// WithLocale returns a context whose messages are translated into locale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeContextKey{}, locale)
+}
+
+// Locale returns the locale set by WithLocale, or DefaultLocale
+func Locale(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeContextKey{}).(string); ok {
+		return locale
+	}
+	return DefaultLocale
+}
@@ -0,0 +1,310 @@
+package i18n
+
+// Generic error messages, used by the errors package constructors when no
+// more specific key is given
+const (
+	ErrorBadRequest   = "error.bad_request"
+	ErrorUnauthorized = "error.unauthorized"
+	ErrorForbidden    = "error.forbidden"
+	ErrorNotFound     = "error.not_found"
+	ErrorConflict     = "error.conflict"
+	ErrorInternal     = "error.internal"
+	ErrorValidation   = "error.validation"
+	ErrorNotSupported = "error.not_supported"
+	ErrorQueryTimeout = "error.query_timeout"
+)
+
+// Request and validation messages. Rule messages receive the {field} and
+// {param} placeholders.
+const (
+	RequestInvalidBody       = "request.invalid_body"
+	RequestInvalidCursor     = "request.invalid_cursor"
+	RequestInvalidPagination = "request.invalid_pagination"
+	RequestServerBusy        = "request.server_busy"
+	ValidationFailed         = "validation.failed"
+
+	ValidationRequired     = "validation.required"
+	ValidationEmail        = "validation.email"
+	ValidationMin          = "validation.min"
+	ValidationMax          = "validation.max"
+	ValidationLen          = "validation.len"
+	ValidationOneOf        = "validation.oneof"
+	ValidationURL          = "validation.url"
+	ValidationUUID         = "validation.uuid"
+	ValidationGTE          = "validation.gte"
+	ValidationLTE          = "validation.lte"
+	ValidationInvalid      = "validation.invalid"
+	ValidationUnknownField = "validation.unknown_field"
+	ValidationNotBlank     = "validation.not_blank"
+)
+
+// Authentication messages
+const (
+	AuthRequired                = "auth.required"
+	AuthTokenRequired           = "auth.token_required"
+	AuthInvalidToken            = "auth.invalid_token"
+	AuthSessionExpired          = "auth.session_expired"
+	AuthInvalidRefreshToken     = "auth.invalid_refresh_token"
+	AuthInvalidCredentials      = "auth.invalid_credentials"
+	AuthAccountDeactivated      = "auth.account_deactivated"
+	AuthInsufficientPermissions = "auth.insufficient_permissions"
+	AuthPermissionsFailed       = "auth.permissions_failed"
+	AuthNotOrgMember            = "auth.not_org_member"
+	AuthRegisterFailed          = "auth.register_failed"
+
+	AuthImpersonationForbidden     = "auth.impersonation_forbidden"
+	AuthAdminImpersonationDisabled = "auth.admin_impersonation_disabled"
+	AuthSelfImpersonation          = "auth.self_impersonation"
+	AuthNotImpersonating           = "auth.not_impersonating"
+	AuthImpersonationFailed        = "auth.impersonation_failed"
+
+	AuthPasswordChangeRequired = "auth.password_change_required"
+	AuthProfileIncomplete      = "auth.profile_incomplete"
+	AuthInvalidCurrentPassword = "auth.invalid_current_password"
+	AuthPasswordReused         = "auth.password_reused"
+	AuthPasswordChangeFailed   = "auth.password_change_failed"
+
+	AuthPasswordBreached         = "auth.password_breached"
+	AuthPasswordCheckUnavailable = "auth.password_check_unavailable"
+
+	AuthEmailUnchanged          = "auth.email_unchanged"
+	AuthEmailChangeTokenInvalid = "auth.email_change_token_invalid"
+	AuthEmailChangeTokenExpired = "auth.email_change_token_expired"
+	AuthEmailUnavailable        = "auth.email_unavailable"
+	AuthEmailChangeFailed       = "auth.email_change_failed"
+
+	AuthSecureAccountTokenInvalid = "auth.secure_account_token_invalid"
+	AuthSecureAccountFailed       = "auth.secure_account_failed"
+)
+
+// User messages
+const (
+	UserIDRequired          = "user.id_required"
+	UserNotFound            = "user.not_found"
+	UserEmailTaken          = "user.email_taken"
+	UserListFailed          = "user.list_failed"
+	UserCreateFailed        = "user.create_failed"
+	UserSearchFailed        = "user.search_failed"
+	UserSearchQueryRequired = "user.search_query_required"
+	UserInvalidActiveFilter = "user.invalid_active_filter"
+	UserSelfDeactivation    = "user.self_deactivation"
+	UserLastActiveAdmin     = "user.last_active_admin"
+	UserInvalidRole         = "user.invalid_role"
+	UserLastAdminDemotion   = "user.last_admin_demotion"
+	UserRoleChangeFailed    = "user.role_change_failed"
+	UserInvalidDateFilter   = "user.invalid_date_filter"
+	UserActivityFailed      = "user.activity_failed"
+	UserExportFailed        = "user.export_failed"
+	UserExportInvalidFormat = "user.export_invalid_format"
+	UserExportInvalidColumn = "user.export_invalid_column"
+	UserExportInvalidSort   = "user.export_invalid_sort"
+
+	UserRequirePasswordChangeFailed = "user.require_password_change_failed"
+	UserAnonymizeFailed             = "user.anonymize_failed"
+)
+
+// Task and file download messages
+const (
+	TaskNotFound       = "task.not_found"
+	TaskFinished       = "task.finished"
+	TaskLoadFailed     = "task.load_failed"
+	TaskSubmitFailed   = "task.submit_failed"
+	FileNotFound       = "file.not_found"
+	FileLinkInvalid    = "file.link_invalid"
+	FileLinkExpired    = "file.link_expired"
+	FileLinkForbidden  = "file.link_forbidden"
+	FileDownloadFailed = "file.download_failed"
+)
+
+// Session messages
+const (
+	SessionNotFound          = "session.not_found"
+	SessionListFailed        = "session.list_failed"
+	SessionRevokeFailed      = "session.revoke_failed"
+	SessionForceLogoutFailed = "session.force_logout_failed"
+
+	DeviceNotFound     = "device.not_found"
+	DeviceListFailed   = "device.list_failed"
+	DeviceRevokeFailed = "device.revoke_failed"
+)
+
+// Policy messages
+const (
+	PolicyUnknown            = "policy.unknown"
+	PolicyVersionOutdated    = "policy.version_outdated"
+	PolicyAcceptanceRequired = "policy.acceptance_required"
+	PolicyPendingFailed      = "policy.pending_failed"
+	PolicyAcceptFailed       = "policy.accept_failed"
+	PolicyReportFailed       = "policy.report_failed"
+)
+
+// Profile messages
+const (
+	ProfileReportFailed = "profile.report_failed"
+)
+
+// Quota and usage messages
+const (
+	QuotaExceeded      = "quota.exceeded"
+	QuotaCheckFailed   = "quota.check_failed"
+	QuotaUpdateFailed  = "quota.update_failed"
+	UsageInvalidPeriod = "usage.invalid_period"
+	UsageFetchFailed   = "usage.fetch_failed"
+	UsageReportFailed  = "usage.report_failed"
+)
+
+// Database messages
+const (
+	DatabaseInvalid          = "database.invalid"
+	DatabaseExists           = "database.exists"
+	DatabaseNotFound         = "database.not_found"
+	DatabaseInUse            = "database.in_use"
+	DatabaseConnectionFailed = "database.connection_failed"
+	DatabaseRegisterFailed   = "database.register_failed"
+	DatabaseRemoveFailed     = "database.remove_failed"
+)
+
+// Partner messages
+const (
+	PartnerNotFound        = "partner.not_found"
+	PartnerExists          = "partner.exists"
+	PartnerFetchFailed     = "partner.fetch_failed"
+	PartnerCreateFailed    = "partner.create_failed"
+	PartnerUpdateFailed    = "partner.update_failed"
+	PartnerDeleteFailed    = "partner.delete_failed"
+	PartnerProvisionFailed = "partner.provision_failed"
+	SignatureInvalid       = "signature.invalid"
+	SignatureExpired       = "signature.expired"
+	SignatureReplayed      = "signature.replayed"
+	SignatureCheckFailed   = "signature.check_failed"
+)
+
+// Email template messages
+const (
+	EmailTemplateNotFound     = "email.template_not_found"
+	EmailFormatUnsupported    = "email.format_unsupported"
+	EmailTemplateRenderFailed = "email.template_render_failed"
+	EmailSendFailed           = "email.send_failed"
+	EmailPreviewFailed        = "email.preview_failed"
+)
+
+// Route policy messages
+const (
+	RateLimited        = "route.rate_limited"
+	RequestTimeout     = "route.request_timeout"
+	RouteInvalidFormat = "route.invalid_format"
+)
+
+// Saved filter messages
+const (
+	SavedFilterNotFound          = "saved_filter.not_found"
+	SavedFilterForbidden         = "saved_filter.forbidden"
+	SavedFilterUnknownField      = "saved_filter.unknown_field"
+	SavedFilterInvalidValue      = "saved_filter.invalid_value"
+	SavedFilterUnknownResource   = "saved_filter.unknown_resource"
+	SavedFilterResourceMismatch  = "saved_filter.resource_mismatch"
+	SavedFilterSchemaUnsupported = "saved_filter.schema_unsupported"
+	SavedFilterListFailed        = "saved_filter.list_failed"
+	SavedFilterSaveFailed        = "saved_filter.save_failed"
+	SavedFilterDeleteFailed      = "saved_filter.delete_failed"
+)
+
+// Audit log messages
+const (
+	AuditInvalidRange = "audit.invalid_range"
+	AuditExportFailed = "audit.export_failed"
+	AuditVerifyFailed = "audit.verify_failed"
+)
+
+// Resource messages, shared by the resources built on package crud
+const (
+	ResourceInvalidSort  = "resource.invalid_sort"
+	ResourceListFailed   = "resource.list_failed"
+	ResourceFetchFailed  = "resource.fetch_failed"
+	ResourceCreateFailed = "resource.create_failed"
+	ResourceUpdateFailed = "resource.update_failed"
+	ResourceDeleteFailed = "resource.delete_failed"
+)
+
+// Product messages
+const (
+	ProductNotFound = "product.not_found"
+	ProductSKUTaken = "product.sku_taken"
+)
+
+// Request log messages
+const (
+	RequestLogListFailed = "request_log.list_failed"
+)
+
+// Approval messages
+const (
+	ApprovalNotFound     = "approval.not_found"
+	ApprovalPending      = "approval.pending"
+	ApprovalNotPending   = "approval.not_pending"
+	ApprovalExpired      = "approval.expired"
+	ApprovalSelf         = "approval.self"
+	ApprovalNotRequester = "approval.not_requester"
+	ApprovalStale        = "approval.stale"
+	ApprovalListFailed   = "approval.list_failed"
+	ApprovalDecideFailed = "approval.decide_failed"
+)
+
+// Read-only mode messages
+const (
+	ReadOnlyActive       = "read_only.active"
+	ReadOnlyUpdateFailed = "read_only.update_failed"
+)
+
+// JWT secret messages
+const (
+	JWTKeyFileUnset       = "jwt.key_file_unset"
+	JWTRotationInProgress = "jwt.rotation_in_progress"
+	JWTRotationFailed     = "jwt.rotation_failed"
+)
+
+// OpenID Connect messages
+const (
+	OIDCProviderNotFound    = "oidc.provider_not_found"
+	OIDCStateInvalid        = "oidc.state_invalid"
+	OIDCLoginFailed         = "oidc.login_failed"
+	OIDCAccountExists       = "oidc.account_exists"
+	OIDCNotProvisioned      = "oidc.not_provisioned"
+	OIDCProviderUnavailable = "oidc.provider_unavailable"
+	OIDCSignInFailed        = "oidc.sign_in_failed"
+)
+
+// Authentication watch messages
+const (
+	AlertListFailed = "alert.list_failed"
+)
+
+// Confirmation messages
+const (
+	ConfirmationInvalid  = "confirmation.invalid"
+	ConfirmationMismatch = "confirmation.mismatch"
+	ConfirmationFailed   = "confirmation.failed"
+)
+
+// ruleKeys maps validator rules to their messages
+var ruleKeys = map[string]string{
+	"required": ValidationRequired,
+	"email":    ValidationEmail,
+	"min":      ValidationMin,
+	"max":      ValidationMax,
+	"len":      ValidationLen,
+	"oneof":    ValidationOneOf,
+	"url":      ValidationURL,
+	"uuid":     ValidationUUID,
+	"gte":      ValidationGTE,
+	"lte":      ValidationLTE,
+}
+
+// RuleKey returns the message for a failed validator rule such as "email"
+// or "min", falling back to ValidationInvalid
+func RuleKey(rule string) string {
+	if key, ok := ruleKeys[rule]; ok {
+		return key
+	}
+	return ValidationInvalid
+}
@@ -0,0 +1,205 @@
+// Package identifier generates the IDs of new rows and parses IDs given by
+// clients.
+//
+// Every format is 128 bits and stored as a uuid.UUID, so the format of new
+// IDs can change without touching the schema, and existing IDs keep working
+// whichever format is configured:
+//
+//   - uuidv4 is random, as every ID issued before the setting existed
+//   - uuidv7 starts with a millisecond timestamp, so new rows sort by creation
+//     and land next to each other in a B-tree index
+//   - ulid is time-ordered like uuidv7 and can also be written as 26
+//     characters of Crockford base32; the API still returns it as a UUID
+package identifier
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"BackofficeGoService/internal/pkg/clock"
+
+	"github.com/google/uuid"
+)
+
+// Format names how new IDs are generated
+type Format string
+
+// Supported formats
+const (
+	FormatUUIDv4 Format = "uuidv4"
+	FormatUUIDv7 Format = "uuidv7"
+	FormatULID   Format = "ulid"
+)
+
+// Formats lists every supported format
+var Formats = []Format{FormatUUIDv4, FormatUUIDv7, FormatULID}
+
+var (
+	// ErrInvalid is returned by Parse for strings that are no ID
+	ErrInvalid = errors.New("invalid identifier")
+
+	// ErrUnknownFormat is returned by NewGenerator for unsupported formats
+	ErrUnknownFormat = errors.New("unknown identifier format")
+)
+
+// Generator creates the IDs of new rows
+type Generator interface {
+	New() uuid.UUID
+}
+
+// GeneratorFunc adapts a function to Generator
+type GeneratorFunc func() uuid.UUID
+
+// New calls f
+func (f GeneratorFunc) New() uuid.UUID { return f() }
+
+// Default generates random UUIDv4s, as IDs were generated before formats
+// could be configured
+var Default Generator = GeneratorFunc(uuid.New)
+
+// NewGenerator returns a generator of format; an empty format is uuidv4
+func NewGenerator(format Format) (Generator, error) {
+	switch format {
+	case FormatUUIDv4, "":
+		return Default, nil
+	case FormatUUIDv7:
+		return GeneratorFunc(func() uuid.UUID { return uuid.Must(uuid.NewV7()) }), nil
+	case FormatULID:
+		return NewULIDGenerator(clock.New()), nil
+	default:
+		return nil, fmt.Errorf("%w: %q, expected one of uuidv4, uuidv7 and ulid", ErrUnknownFormat, format)
+	}
+}
+
+// Parse reads an ID in any supported format: a UUID of any version, in the
+// forms uuid.Parse accepts, or a 26 character ULID
+func Parse(s string) (uuid.UUID, error) {
+	if len(s) == ulidLength {
+		return parseULID(s)
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, ErrInvalid
+	}
+	return id, nil
+}
+
+// Encode writes id in format. ULIDs are written in Crockford base32; the
+// other formats as canonical UUIDs.
+func Encode(id uuid.UUID, format Format) string {
+	if format == FormatULID {
+		return encodeULID(id)
+	}
+	return id.String()
+}
+
+// ulidLength is the length of a ULID written in Crockford base32
+const ulidLength = 26
+
+// crockford is the Crockford base32 alphabet ULIDs are written in
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// crockfordValues maps the characters of crockford, in either case, to
+// their value, and every other byte to 0xFF
+var crockfordValues = func() [256]byte {
+	var values [256]byte
+	for i := range values {
+		values[i] = 0xFF
+	}
+	for i, c := range crockford {
+		values[c] = byte(i)
+		values[strings.ToLower(string(c))[0]] = byte(i)
+	}
+	return values
+}()
+
+// ULIDGenerator generates ULIDs: a 48-bit millisecond timestamp followed by
+// 80 random bits. IDs generated within the same millisecond increment the
+// random bits of the previous one, so one generator's IDs strictly increase.
+type ULIDGenerator struct {
+	clock clock.Clock
+
+	mu     sync.Mutex
+	last   uuid.UUID
+	lastMS uint64
+}
+
+// NewULIDGenerator creates a ULID generator reading the time from c
+func NewULIDGenerator(c clock.Clock) *ULIDGenerator {
+	return &ULIDGenerator{clock: c}
+}
+
+// New returns the next ULID
+func (g *ULIDGenerator) New() uuid.UUID {
+	ms := uint64(g.clock.Now().UnixMilli())
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Within the same millisecond, or when the clock went back, count up
+	// from the previous ID; overflowing 80 bits starts a fresh random one
+	if ms <= g.lastMS && increment(g.last[6:]) {
+		return g.last
+	}
+
+	var id uuid.UUID
+	binary.BigEndian.PutUint16(id[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(fmt.Sprintf("identifier: read random bytes: %v", err))
+	}
+	g.last, g.lastMS = id, max(ms, g.lastMS)
+	return id
+}
+
+// increment adds one to the big-endian number b, reporting false when it overflowed
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeULID writes the 128 bits of id as 26 base32 characters, 5 bits
+// each; the first character holds the top 3 bits
+func encodeULID(id uuid.UUID) string {
+	hi := binary.BigEndian.Uint64(id[0:8])
+	lo := binary.BigEndian.Uint64(id[8:16])
+
+	var out [ulidLength]byte
+	for i := ulidLength - 1; i >= 0; i-- {
+		out[i] = crockford[lo&0x1F]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// parseULID reads a ULID written in Crockford base32, in either case
+func parseULID(s string) (uuid.UUID, error) {
+	var hi, lo uint64
+	for i := 0; i < ulidLength; i++ {
+		v := crockfordValues[s[i]]
+		if v == 0xFF {
+			return uuid.Nil, ErrInvalid
+		}
+		// The first character may only hold 3 bits
+		if i == 0 && v > 7 {
+			return uuid.Nil, ErrInvalid
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[0:8], hi)
+	binary.BigEndian.PutUint64(id[8:16], lo)
+	return id, nil
+}
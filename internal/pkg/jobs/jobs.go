@@ -0,0 +1,70 @@
+// Package jobs runs periodic maintenance jobs on cron-style schedules.
+// Each run is guarded by a Locker so that only one replica executes a job
+// at a time, and a job never overlaps with itself within a process.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"BackofficeGoService/internal/pkg/apimodel"
+)
+
+var (
+	ErrJobNotFound      = errors.New("job not found")
+	ErrJobRunning       = errors.New("job is already running")
+	ErrJobLocked        = errors.New("job is locked by another instance")
+	ErrDuplicateJob     = errors.New("job is already registered")
+	ErrSchedulerStopped = errors.New("scheduler is stopped")
+)
+
+// Run outcomes reported in Status.LastOutcome
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailed  = "failed"
+	OutcomeSkipped = "skipped"
+)
+
+// Job is a unit of periodic work
+type Job interface {
+	// Name uniquely identifies the job and its lock
+	Name() string
+
+	// Schedule is a standard 5-field cron expression or a descriptor such as "@every 1h"
+	Schedule() string
+
+	// Run performs the work; it should return promptly once ctx is cancelled
+	Run(ctx context.Context) error
+}
+
+// funcJob adapts a function to the Job interface
+type funcJob struct {
+	name     string
+	schedule string
+	run      func(ctx context.Context) error
+}
+
+// NewFunc creates a job from a function
+func NewFunc(name, schedule string, run func(ctx context.Context) error) Job {
+	return &funcJob{name: name, schedule: schedule, run: run}
+}
+
+func (j *funcJob) Name() string                  { return j.name }
+func (j *funcJob) Schedule() string              { return j.schedule }
+func (j *funcJob) Run(ctx context.Context) error { return j.run(ctx) }
+
+// Status describes a registered job and its most recent run
+type Status struct {
+	Name        string     `json:"name"`
+	Schedule    string     `json:"schedule"`
+	Running     bool       `json:"running"`
+	NextRunAt   *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	LastOutcome string     `json:"last_outcome,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	DurationMs  int64      `json:"last_duration_ms"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (s Status) MarshalJSON() ([]byte, error) { return apimodel.Marshal(s) }
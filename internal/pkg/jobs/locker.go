@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"BackofficeGoService/internal/pkg/database"
+)
+
+// Locker provides mutual exclusion for job runs across replicas
+type Locker interface {
+	// TryLock acquires key without waiting. When acquired is true the caller
+	// must call release once the run is over.
+	TryLock(ctx context.Context, key string) (release func(), acquired bool, err error)
+}
+
+// MemoryLocker is an in-process Locker, suitable for single-replica deployments and tests
+type MemoryLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+// NewMemoryLocker creates an in-process locker
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{held: make(map[string]bool)}
+}
+
+// TryLock acquires key if no other caller holds it
+func (l *MemoryLocker) TryLock(ctx context.Context, key string) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.held[key] {
+		return nil, false, nil
+	}
+	l.held[key] = true
+
+	return func() {
+		l.mu.Lock()
+		delete(l.held, key)
+		l.mu.Unlock()
+	}, true, nil
+}
+
+// dbLocker uses database session locks: advisory locks on PostgreSQL and
+// GET_LOCK on MySQL. Locks are tied to a dedicated connection and are freed
+// by the server if the process dies.
+type dbLocker struct {
+	db         *sql.DB
+	driverType database.DriverType
+}
+
+// NewDBLocker creates a Locker backed by the driver's database
+func NewDBLocker(driver database.Driver) (Locker, error) {
+	switch driver.Type() {
+	case database.DriverPostgreSQL, database.DriverMySQL:
+	default:
+		return nil, fmt.Errorf("%w: %s", database.ErrUnsupportedDriver, driver.Type())
+	}
+
+	db, err := database.SQLDB(driver)
+	if err != nil {
+		return nil, err
+	}
+	return &dbLocker{db: db, driverType: driver.Type()}, nil
+}
+
+// TryLock acquires a session lock named after key
+func (l *dbLocker) TryLock(ctx context.Context, key string) (func(), bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire lock connection: %w", err)
+	}
+
+	var (
+		acquired    bool
+		unlockQuery string
+		lockArg     interface{}
+	)
+
+	switch l.driverType {
+	case database.DriverPostgreSQL:
+		lockArg = advisoryLockID(key)
+		err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, lockArg).Scan(&acquired)
+		unlockQuery = `SELECT pg_advisory_unlock($1)`
+	default:
+		lockArg = key
+		var result sql.NullInt64
+		err = conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, 0)`, lockArg).Scan(&result)
+		acquired = result.Valid && result.Int64 == 1
+		unlockQuery = `SELECT RELEASE_LOCK(?)`
+	}
+
+	if err != nil || !acquired {
+		conn.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to acquire lock: %w", err)
+		}
+		return nil, false, nil
+	}
+
+	return func() {
+		_, _ = conn.ExecContext(context.Background(), unlockQuery, lockArg)
+		conn.Close()
+	}, true, nil
+}
+
+// advisoryLockID maps a lock key to a PostgreSQL advisory lock ID
+func advisoryLockID(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
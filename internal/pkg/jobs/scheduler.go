@@ -0,0 +1,306 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/robfig/cron/v3"
+)
+
+// tickInterval is how often the scheduler checks for due jobs
+const tickInterval = time.Second
+
+// entry is a registered job and its run state
+type entry struct {
+	job      Job
+	schedule cron.Schedule
+	next     time.Time
+	running  bool
+	status   Status
+}
+
+// Scheduler runs registered jobs on their schedules
+type Scheduler struct {
+	locker Locker
+	logger logger.Logger
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	stopped   bool
+	observers []func(Status)
+
+	// ctx is passed to running jobs and cancelled when Stop gives up waiting
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler that guards runs with locker
+func NewScheduler(locker Locker, log logger.Logger) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		locker:  locker,
+		logger:  log,
+		entries: make(map[string]*entry),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+}
+
+// Register adds a job; its schedule is validated immediately
+func (s *Scheduler) Register(job Job) error {
+	schedule, err := cron.ParseStandard(job.Schedule())
+	if err != nil {
+		return fmt.Errorf("invalid schedule for job %s: %w", job.Name(), err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[job.Name()]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateJob, job.Name())
+	}
+
+	next := schedule.Next(time.Now())
+	s.entries[job.Name()] = &entry{
+		job:      job,
+		schedule: schedule,
+		next:     next,
+		status:   Status{Name: job.Name(), Schedule: job.Schedule(), NextRunAt: &next},
+	}
+	return nil
+}
+
+// OnStatus registers fn to be called whenever a job starts or finishes.
+// fn runs on the job's goroutine and should not block.
+func (s *Scheduler) OnStatus(fn func(Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observers = append(s.observers, fn)
+}
+
+// notify passes status to every observer
+func (s *Scheduler) notify(status Status) {
+	s.mu.Lock()
+	observers := s.observers
+	s.mu.Unlock()
+
+	for _, fn := range observers {
+		fn(status)
+	}
+}
+
+// Start begins running jobs on their schedules
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.done:
+				return
+			case now := <-ticker.C:
+				s.runDue(now)
+			}
+		}
+	}()
+}
+
+// runDue starts every job whose next run time has passed
+func (s *Scheduler) runDue(now time.Time) {
+	s.mu.Lock()
+	var due []string
+	for name, e := range s.entries {
+		if !now.Before(e.next) {
+			e.next = e.schedule.Next(now)
+			next := e.next
+			e.status.NextRunAt = &next
+			due = append(due, name)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, name := range due {
+		s.runAsync(name, nil)
+	}
+}
+
+// RunNow starts a job immediately in the background
+func (s *Scheduler) RunNow(name string) error {
+	return s.RunNowThen(name, nil)
+}
+
+// RunNowThen starts a job immediately in the background and calls done with
+// the run's status once it finishes. done is not called if the run cannot start.
+func (s *Scheduler) RunNowThen(name string, done func(Status, error)) error {
+	s.mu.Lock()
+	e, ok := s.entries[name]
+	var running bool
+	if ok {
+		running = e.running
+	}
+	stopped := s.stopped
+	s.mu.Unlock()
+
+	switch {
+	case !ok:
+		return ErrJobNotFound
+	case stopped:
+		return ErrSchedulerStopped
+	case running:
+		return ErrJobRunning
+	}
+
+	s.runAsync(name, done)
+	return nil
+}
+
+// runAsync runs a job in a tracked goroutine so Stop can wait for it
+func (s *Scheduler) runAsync(name string, done func(Status, error)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		status, err := s.Run(name)
+		if done == nil {
+			return
+		}
+		// Lost a race with another trigger or with Stop; nothing ran
+		if errors.Is(err, ErrJobRunning) || errors.Is(err, ErrSchedulerStopped) {
+			return
+		}
+		done(status, err)
+	}()
+}
+
+// Run executes a job synchronously and returns its resulting status. A job
+// that is already running in this process returns ErrJobRunning; a job held
+// by another instance is recorded as skipped and returns ErrJobLocked.
+func (s *Scheduler) Run(name string) (Status, error) {
+	s.mu.Lock()
+	e, ok := s.entries[name]
+	if !ok {
+		s.mu.Unlock()
+		return Status{}, ErrJobNotFound
+	}
+	if s.stopped {
+		s.mu.Unlock()
+		return Status{}, ErrSchedulerStopped
+	}
+	if e.running {
+		status := e.status
+		s.mu.Unlock()
+		return status, ErrJobRunning
+	}
+	e.running = true
+	e.status.Running = true
+	started := e.status
+	s.mu.Unlock()
+
+	s.notify(started)
+
+	start := time.Now()
+	outcome, runErr := s.execute(e.job)
+	duration := time.Since(start)
+
+	s.mu.Lock()
+	e.running = false
+	e.status.Running = false
+	e.status.LastRunAt = &start
+	e.status.LastOutcome = outcome
+	e.status.DurationMs = duration.Milliseconds()
+	e.status.LastError = ""
+	if runErr != nil {
+		e.status.LastError = runErr.Error()
+	}
+	status := e.status
+	s.mu.Unlock()
+
+	s.notify(status)
+
+	fields := []logger.Field{
+		{Key: "job", Value: name},
+		{Key: "outcome", Value: outcome},
+		{Key: "duration_ms", Value: duration.Milliseconds()},
+	}
+	switch outcome {
+	case OutcomeFailed:
+		s.logger.Error("Job failed", append(fields, logger.Field{Key: "error", Value: runErr.Error()})...)
+	case OutcomeSkipped:
+		s.logger.Info("Job skipped", fields...)
+	default:
+		s.logger.Info("Job completed", fields...)
+	}
+
+	return status, runErr
+}
+
+// execute acquires the job's lock and runs it
+func (s *Scheduler) execute(job Job) (string, error) {
+	release, acquired, err := s.locker.TryLock(s.ctx, "jobs:"+job.Name())
+	if err != nil {
+		return OutcomeFailed, err
+	}
+	if !acquired {
+		return OutcomeSkipped, ErrJobLocked
+	}
+	defer release()
+
+	if err := job.Run(s.ctx); err != nil {
+		return OutcomeFailed, err
+	}
+	return OutcomeSuccess, nil
+}
+
+// Statuses returns the status of every registered job ordered by name
+func (s *Scheduler) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.entries))
+	for _, e := range s.entries {
+		statuses = append(statuses, e.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Stop stops scheduling new runs and waits for in-flight jobs. If ctx expires
+// first, running jobs are cancelled and ctx's error is returned.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
+	close(s.done)
+
+	finished := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,258 @@
+// Package jwtutil signs and verifies the service's access tokens. Each tenant
+// may have its own secret, issuer and audiences; tokens carry the tenant they
+// were issued for and only verify for that tenant. The default secret can be
+// rotated through a key file without invalidating the tokens signed before.
+package jwtutil
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TenantClaim is the claim holding the tenant a token was issued for
+const TenantClaim = "tenant_id"
+
+// SigningMethod signs every token
+var SigningMethod = jwt.SigningMethodHS256
+
+var (
+	// ErrTokenExpired is returned for tokens past their expiry
+	ErrTokenExpired = errors.New("token expired")
+	// ErrInvalidToken is returned for tokens that are malformed, wrongly
+	// signed or issued by or for someone else
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTenantMismatch is returned for tokens issued for another tenant
+	ErrTenantMismatch = errors.New("token issued for another tenant")
+)
+
+// Settings are what tokens are signed with and checked against
+type Settings struct {
+	Secret string
+	Issuer string
+	// Audience lists the accepted audiences. Tokens are issued for the first
+	// and accepted for any; without audiences the aud claim is neither set
+	// nor checked.
+	Audience []string
+}
+
+// reloadInterval is how often a token that fails to verify may make the
+// manager read its key file again
+const reloadInterval = time.Second
+
+// Manager issues and verifies tokens for the primary database and each
+// tenant. Tenants without their own settings use the defaults.
+type Manager struct {
+	defaults   Settings
+	tenants    map[string]Settings
+	now        func() time.Time
+	keyFile    string
+	refresh    time.Duration
+	onPrevious func()
+
+	mu     sync.Mutex
+	keys   Keys
+	loaded time.Time
+}
+
+// Option configures a Manager
+type Option func(*Manager)
+
+// WithKeyFile takes the default secrets from the key file at path, read
+// again every refresh so rotations made elsewhere are followed. Tokens that
+// do not verify make the file be read at once. Until the file exists, or
+// while it cannot be read, the last secrets known stand.
+func WithKeyFile(path string, refresh time.Duration) Option {
+	return func(m *Manager) {
+		m.keyFile, m.refresh = path, refresh
+	}
+}
+
+// OnPreviousSecret calls fn for every token verified with the previous
+// secret
+func OnPreviousSecret(fn func()) Option {
+	return func(m *Manager) {
+		m.onPrevious = fn
+	}
+}
+
+// NewManager creates a manager signing with defaults, or with the settings of
+// tenants for the tenants listed there. A tenant's empty issuer or audience
+// falls back to the defaults; its secret does not. now is the clock tokens
+// are issued and checked by.
+func NewManager(defaults Settings, tenants map[string]Settings, now func() time.Time, opts ...Option) *Manager {
+	m := &Manager{
+		defaults: defaults,
+		tenants:  make(map[string]Settings, len(tenants)),
+		now:      now,
+		keys:     Keys{Current: defaults.Secret},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	for tenant, settings := range tenants {
+		if settings.Issuer == "" {
+			settings.Issuer = defaults.Issuer
+		}
+		if len(settings.Audience) == 0 {
+			settings.Audience = defaults.Audience
+		}
+		m.tenants[tenant] = settings
+	}
+	return m
+}
+
+// SettingsFor returns the settings tokens of tenant are issued with; the
+// empty tenant is the primary database
+func (m *Manager) SettingsFor(tenant string) Settings {
+	if settings, ok := m.tenants[tenant]; ok {
+		return settings
+	}
+	settings := m.defaults
+	settings.Secret = m.Keys().Current
+	return settings
+}
+
+// Keys returns the default secrets, read from the key file when it is due
+func (m *Manager) Keys() Keys {
+	return m.loadKeys(false)
+}
+
+// SetKeys replaces the default secrets, such as after rotating the key file
+func (m *Manager) SetKeys(keys Keys) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.keys, m.loaded = keys, m.now()
+}
+
+// loadKeys reads the key file when the secrets are older than the refresh
+// interval, or, when stale is set, than reloadInterval
+func (m *Manager) loadKeys(stale bool) Keys {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.keyFile == "" {
+		return m.keys
+	}
+	age := m.now().Sub(m.loaded)
+	if m.loaded.IsZero() || age >= m.refresh || (stale && age >= reloadInterval) {
+		m.loaded = m.now()
+		if keys, err := LoadKeys(m.keyFile); err == nil {
+			m.keys = keys
+		}
+	}
+	return m.keys
+}
+
+// GenerateFor signs a token for tenant holding the user's claims, which
+// should include exp. The issuer, audience and tenant claims are set here.
+func (m *Manager) GenerateFor(tenant string, claims jwt.MapClaims) (string, error) {
+	settings := m.SettingsFor(tenant)
+
+	signed := make(jwt.MapClaims, len(claims)+3)
+	for name, value := range claims {
+		signed[name] = value
+	}
+	signed["iss"] = settings.Issuer
+	if len(settings.Audience) > 0 {
+		signed["aud"] = settings.Audience[0]
+	}
+	if tenant != "" {
+		signed[TenantClaim] = tenant
+	} else {
+		delete(signed, TenantClaim)
+	}
+
+	return jwt.NewWithClaims(SigningMethod, signed).SignedString([]byte(settings.Secret))
+}
+
+// VerifyFor checks token's signature, expiry, issuer and audience against
+// the settings of tenant, and that it was issued for tenant, returning its
+// claims. With the default settings, tokens signed with the previous secret
+// are accepted while it is.
+func (m *Manager) VerifyFor(tenant, token string) (jwt.MapClaims, error) {
+	if _, ok := m.tenants[tenant]; ok {
+		return m.verify(tenant, token, m.tenants[tenant])
+	}
+
+	keys := m.Keys()
+	claims, err := m.verifyWith(tenant, token, keys)
+	if errors.Is(err, ErrInvalidToken) && m.keyFile != "" {
+		// Another instance may have rotated the secret since the file was read
+		if reloaded := m.loadKeys(true); reloaded.Current != keys.Current {
+			claims, err = m.verifyWith(tenant, token, reloaded)
+		}
+	}
+	return claims, err
+}
+
+// verifyWith verifies token against the default settings with the current
+// secret of keys, then with the previous one while it is accepted
+func (m *Manager) verifyWith(tenant, token string, keys Keys) (jwt.MapClaims, error) {
+	settings := m.defaults
+	settings.Secret = keys.Current
+	claims, err := m.verify(tenant, token, settings)
+	if !errors.Is(err, ErrInvalidToken) || !keys.PreviousActive(m.now()) {
+		return claims, err
+	}
+
+	settings.Secret = keys.Previous
+	previous, previousErr := m.verify(tenant, token, settings)
+	if errors.Is(previousErr, ErrInvalidToken) {
+		return claims, err
+	}
+	if previousErr == nil && m.onPrevious != nil {
+		m.onPrevious()
+	}
+	return previous, previousErr
+}
+
+// verify checks token against settings
+func (m *Manager) verify(tenant, token string, settings Settings) (jwt.MapClaims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{SigningMethod.Alg()}),
+		jwt.WithIssuer(settings.Issuer),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(m.now),
+	}
+	if len(settings.Audience) > 0 {
+		opts = append(opts, jwt.WithAudience(slices.Clone(settings.Audience)...))
+	}
+
+	parsed, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) {
+		return []byte(settings.Secret), nil
+	}, opts...)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrTokenExpired
+	}
+	if err != nil || !parsed.Valid {
+		return nil, ErrInvalidToken
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	if issuedFor, _ := claims[TenantClaim].(string); issuedFor != tenant {
+		return nil, ErrTenantMismatch
+	}
+	return claims, nil
+}
+
+// UnverifiedTenant reads the tenant claim of token without verifying it, for
+// routing a request before its token can be checked
+func UnverifiedTenant(token string) string {
+	if token == "" {
+		return ""
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return ""
+	}
+	tenant, _ := claims[TenantClaim].(string)
+	return tenant
+}
@@ -0,0 +1,129 @@
+package jwtutil
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrRotationInProgress is returned when rotating again while the previous
+// secret is still accepted, which would invalidate the tokens signed with it
+var ErrRotationInProgress = errors.New("the previous JWT secret is still accepted")
+
+// secretBytes is the number of random bytes in a generated secret
+const secretBytes = 32
+
+// Keys are the secrets of the default settings. Tokens are signed with
+// Current; after a rotation, tokens signed with Previous are accepted until
+// PreviousUntil, when the longest-lived of them has expired.
+type Keys struct {
+	Current       string    `json:"current"`
+	Previous      string    `json:"previous,omitempty"`
+	PreviousUntil time.Time `json:"previous_until"`
+}
+
+// PreviousActive reports whether tokens signed with the previous secret are
+// accepted at now
+func (k Keys) PreviousActive(now time.Time) bool {
+	return k.Previous != "" && now.Before(k.PreviousUntil)
+}
+
+// Rotate makes secret current and accepts the current secret as the
+// previous one for grace after now
+func (k Keys) Rotate(secret string, now time.Time, grace time.Duration) Keys {
+	return Keys{
+		Current:       secret,
+		Previous:      k.Current,
+		PreviousUntil: now.Add(grace).UTC(),
+	}
+}
+
+// GenerateSecret returns a new random secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate a JWT secret: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// LoadKeys reads the key file at path. A missing file is reported as
+// fs.ErrNotExist.
+func LoadKeys(path string) (Keys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Keys{}, err
+	}
+	var keys Keys
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return Keys{}, fmt.Errorf("invalid JWT key file %s: %w", path, err)
+	}
+	if keys.Current == "" {
+		return Keys{}, fmt.Errorf("invalid JWT key file %s: no current secret", path)
+	}
+	return keys, nil
+}
+
+// SaveKeys writes keys to the key file at path, readable by its owner only.
+// The file is replaced atomically, so instances reading it never see half
+// of it.
+func SaveKeys(path string, keys Keys) error {
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write JWT key file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write JWT key file: %w", err)
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write JWT key file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write JWT key file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write JWT key file: %w", err)
+	}
+	return nil
+}
+
+// RotateFile generates a new secret in the key file at path, keeping the
+// secret it replaces as the previous one for grace. Without a file, current
+// is the secret being replaced. Unless force is set, it refuses with
+// ErrRotationInProgress while the previous secret is still accepted.
+func RotateFile(path, current string, now time.Time, grace time.Duration, force bool) (Keys, error) {
+	keys, err := LoadKeys(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		keys, err = Keys{Current: current}, nil
+	}
+	if err != nil {
+		return Keys{}, err
+	}
+	if keys.PreviousActive(now) && !force {
+		return Keys{}, ErrRotationInProgress
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return Keys{}, err
+	}
+	keys = keys.Rotate(secret, now, grace)
+	if err := SaveKeys(path, keys); err != nil {
+		return Keys{}, err
+	}
+	return keys, nil
+}
@@ -0,0 +1,145 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+// Stopper is a component that stops within the shutdown deadline
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// StopFunc adapts a function to a Stopper
+type StopFunc func(ctx context.Context) error
+
+// Stop calls f(ctx)
+func (f StopFunc) Stop(ctx context.Context) error {
+	return f(ctx)
+}
+
+// CloseFunc adapts a function to an io.Closer
+type CloseFunc func() error
+
+// Close calls f()
+func (f CloseFunc) Close() error {
+	return f()
+}
+
+type component struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Lifecycle tracks readiness and the components to stop on shutdown.
+// Shutdown withdraws readiness, waits for the drain delay, shuts the servers,
+// stops the workers and finally closes resources such as databases.
+type Lifecycle struct {
+	drainDelay time.Duration
+	logger     logger.Logger
+	ready      atomic.Bool
+
+	mu       sync.Mutex
+	servers  []component
+	workers  []component
+	closers  []component
+	shutdown bool
+}
+
+// New creates a lifecycle that waits drainDelay between failing readiness and
+// shutting the servers
+func New(drainDelay time.Duration, log logger.Logger) *Lifecycle {
+	return &Lifecycle{
+		drainDelay: drainDelay,
+		logger:     log,
+	}
+}
+
+// AddServer registers a server; servers are shut in registration order
+func (l *Lifecycle) AddServer(name string, s Stopper) {
+	l.add(&l.servers, name, s.Stop)
+}
+
+// AddWorker registers a background worker; workers are stopped in reverse
+// registration order, so a worker may depend on ones registered before it
+func (l *Lifecycle) AddWorker(name string, s Stopper) {
+	l.add(&l.workers, name, s.Stop)
+}
+
+// AddCloser registers a resource closed after every worker has stopped, in
+// reverse registration order
+func (l *Lifecycle) AddCloser(name string, c io.Closer) {
+	l.add(&l.closers, name, func(context.Context) error { return c.Close() })
+}
+
+func (l *Lifecycle) add(list *[]component, name string, stop func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*list = append(*list, component{name: name, stop: stop})
+}
+
+// SetReady marks the application ready to receive traffic
+func (l *Lifecycle) SetReady() {
+	l.ready.Store(true)
+}
+
+// Ready reports whether the application should receive traffic
+func (l *Lifecycle) Ready() bool {
+	return l.ready.Load()
+}
+
+// Shutdown runs the shutdown sequence once. Every component is stopped even
+// when an earlier one fails; the failures are returned joined.
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	if l.shutdown {
+		l.mu.Unlock()
+		return nil
+	}
+	l.shutdown = true
+	servers, workers, closers := l.servers, l.workers, l.closers
+	l.mu.Unlock()
+
+	// Fail readiness first so load balancers stop sending new requests
+	l.ready.Store(false)
+	if l.drainDelay > 0 {
+		l.logger.Info("Waiting for load balancers to drain", logger.Field{Key: "delay", Value: l.drainDelay.String()})
+		timer := time.NewTimer(l.drainDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	var errs []error
+	for _, c := range servers {
+		errs = append(errs, l.stop(ctx, c))
+	}
+	for i := len(workers) - 1; i >= 0; i-- {
+		errs = append(errs, l.stop(ctx, workers[i]))
+	}
+
+	l.logger.Info("Servers and workers stopped, closing resources")
+
+	// Closers run even past the deadline; they release resources rather than wait.
+	// The logger may be among them, so nothing is logged after a successful close.
+	for i := len(closers) - 1; i >= 0; i-- {
+		errs = append(errs, l.stop(ctx, closers[i]))
+	}
+	return errors.Join(errs...)
+}
+
+func (l *Lifecycle) stop(ctx context.Context, c component) error {
+	if err := c.stop(ctx); err != nil {
+		l.logger.Error("Error stopping component", logger.Field{Key: "component", Value: c.name}, logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+	return nil
+}
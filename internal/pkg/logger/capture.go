@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a captured entry
+type Level string
+
+const (
+	LevelDebug Level = "debug"
+	LevelInfo  Level = "info"
+	LevelWarn  Level = "warn"
+	LevelError Level = "error"
+	LevelFatal Level = "fatal"
+)
+
+// Entry is one captured log call
+type Entry struct {
+	Level   Level
+	Message string
+	Fields  []Field
+	Time    time.Time
+}
+
+// Field returns the value of the entry's field named key
+func (e Entry) Field(key string) (interface{}, bool) {
+	for _, field := range e.Fields {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+// CaptureLogger records entries in memory so tests can assert on them.
+// It is safe for concurrent use; Fatal records the entry without exiting.
+type CaptureLogger struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// NewCaptureLogger creates an empty capture logger
+func NewCaptureLogger() *CaptureLogger {
+	return &CaptureLogger{}
+}
+
+func (l *CaptureLogger) Debug(msg string, fields ...Field) {
+	l.record(LevelDebug, msg, fields)
+}
+
+func (l *CaptureLogger) Info(msg string, fields ...Field) {
+	l.record(LevelInfo, msg, fields)
+}
+
+func (l *CaptureLogger) Warn(msg string, fields ...Field) {
+	l.record(LevelWarn, msg, fields)
+}
+
+func (l *CaptureLogger) Error(msg string, fields ...Field) {
+	l.record(LevelError, msg, fields)
+}
+
+func (l *CaptureLogger) Fatal(msg string, fields ...Field) {
+	l.record(LevelFatal, msg, fields)
+}
+
+func (l *CaptureLogger) With(fields ...Field) Logger {
+	return withFields(l, fields)
+}
+
+func (l *CaptureLogger) record(level Level, msg string, fields []Field) {
+	entry := Entry{
+		Level:   level,
+		Message: msg,
+		Fields:  append([]Field(nil), fields...),
+		Time:    time.Now(),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+// Entries returns every captured entry in the order it was logged
+func (l *CaptureLogger) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Entry(nil), l.entries...)
+}
+
+// FilterByLevel returns the captured entries logged at level
+func (l *CaptureLogger) FilterByLevel(level Level) []Entry {
+	var matched []Entry
+	for _, entry := range l.Entries() {
+		if entry.Level == level {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// Contains reports whether any captured message contains msg
+func (l *CaptureLogger) Contains(msg string) bool {
+	for _, entry := range l.Entries() {
+		if strings.Contains(entry.Message, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// nopLogger discards every entry
+type nopLogger struct{}
+
+// NewNopLogger returns a logger that discards everything, for tests that only
+// need to satisfy a dependency. Fatal does not exit.
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+func (nopLogger) Debug(msg string, fields ...Field) {}
+func (nopLogger) Info(msg string, fields ...Field)  {}
+func (nopLogger) Warn(msg string, fields ...Field)  {}
+func (nopLogger) Error(msg string, fields ...Field) {}
+func (nopLogger) Fatal(msg string, fields ...Field) {}
+func (nopLogger) With(fields ...Field) Logger       { return nopLogger{} }
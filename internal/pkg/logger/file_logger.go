@@ -8,6 +8,8 @@ import (
 	"sync"
 	"time"
 
+	"BackofficeGoService/internal/pkg/clock"
+
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
@@ -31,34 +33,54 @@ type FileLogger struct {
 	warn       *log.Logger
 	error      *log.Logger
 	fatal      *log.Logger
-	currentDay int
+	logDate    string
 	mu         sync.Mutex
 	writer     *lumberjack.Logger
+	clock      clock.Clock
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// FileLoggerOption configures a FileLogger
+type FileLoggerOption func(fl *FileLogger)
+
+// WithFileLoggerClock sets the clock that decides when the daily file rotates
+func WithFileLoggerClock(c clock.Clock) FileLoggerOption {
+	return func(fl *FileLogger) {
+		fl.clock = c
+	}
 }
 
 // NewFileLogger creates a new file-based logger with daily rotation
-func NewFileLogger(config FileLoggerConfig) (Logger, error) {
+func NewFileLogger(config FileLoggerConfig, opts ...FileLoggerOption) (Logger, error) {
 	// Ensure log directory exists
 	if err := os.MkdirAll(config.LogPath, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create log directory: %w", err)
 	}
 
 	fl := &FileLogger{
-		config:     config,
-		currentDay: time.Now().Day(),
+		config: config,
+		clock:  clock.New(),
+		done:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(fl)
 	}
+	fl.logDate = fl.clock.Now().Format("2006-01-02")
 
 	// Initialize the log writer
 	if err := fl.initWriter(); err != nil {
 		return nil, err
 	}
 
-	// Create loggers for each level
-	fl.debug = log.New(fl.writer, "[DEBUG] ", log.LstdFlags|log.Lshortfile)
-	fl.info = log.New(fl.writer, "[INFO] ", log.LstdFlags|log.Lshortfile)
-	fl.warn = log.New(fl.writer, "[WARN] ", log.LstdFlags|log.Lshortfile)
-	fl.error = log.New(fl.writer, "[ERROR] ", log.LstdFlags|log.Lshortfile)
-	fl.fatal = log.New(fl.writer, "[FATAL] ", log.LstdFlags|log.Lshortfile)
+	// Create loggers for each level. They write through fileWriter so
+	// rotation only has to swap fl.writer.
+	out := fileWriter{fl}
+	fl.debug = log.New(out, "[DEBUG] ", log.LstdFlags|log.Lshortfile)
+	fl.info = log.New(out, "[INFO] ", log.LstdFlags|log.Lshortfile)
+	fl.warn = log.New(out, "[WARN] ", log.LstdFlags|log.Lshortfile)
+	fl.error = log.New(out, "[ERROR] ", log.LstdFlags|log.Lshortfile)
+	fl.fatal = log.New(out, "[FATAL] ", log.LstdFlags|log.Lshortfile)
 
 	// Start daily rotation check goroutine if enabled
 	if config.DailyRotate {
@@ -68,16 +90,13 @@ func NewFileLogger(config FileLoggerConfig) (Logger, error) {
 	return fl, nil
 }
 
-// initWriter initializes the log file writer
+// initWriter initializes the log file writer; the caller holds fl.mu or has
+// the logger to itself
 func (fl *FileLogger) initWriter() error {
-	fl.mu.Lock()
-	defer fl.mu.Unlock()
-
 	// Generate log file name with date if daily rotation is enabled
 	var logFileName string
 	if fl.config.DailyRotate {
-		dateStr := time.Now().Format("2006-01-02")
-		logFileName = fmt.Sprintf("%s-%s.log", fl.config.LogFileName, dateStr)
+		logFileName = fmt.Sprintf("%s-%s.log", fl.config.LogFileName, fl.logDate)
 	} else {
 		logFileName = fmt.Sprintf("%s.log", fl.config.LogFileName)
 	}
@@ -97,37 +116,52 @@ func (fl *FileLogger) initWriter() error {
 	return nil
 }
 
-// startDailyRotation checks daily and rotates log files if needed
-// Note: This is a background goroutine that monitors for day changes
+// startDailyRotation checks every hour whether the day has changed, so a
+// new file is started even when nothing is logged around midnight
 func (fl *FileLogger) startDailyRotation() {
-	ticker := time.NewTicker(1 * time.Hour) // Check every hour
+	ticker := fl.clock.NewTicker(1 * time.Hour)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		now := time.Now()
-		fl.mu.Lock()
-		currentDay := fl.currentDay
-		fl.mu.Unlock()
-
-		if now.Day() != currentDay {
-			// Day changed, rotation will happen on next log call
-			// This is handled in the log() method to avoid race conditions
+	for {
+		select {
+		case <-fl.done:
+			return
+		case now := <-ticker.C():
+			fl.rotateIfNeeded(now)
 		}
 	}
 }
 
-// rotateDaily rotates the log file to a new daily file
-func (fl *FileLogger) rotateDaily() error {
+// rotateIfNeeded switches to the file for now's date when the date has changed
+func (fl *FileLogger) rotateIfNeeded(now time.Time) {
+	date := now.Format("2006-01-02")
+
 	fl.mu.Lock()
 	defer fl.mu.Unlock()
 
+	if date == fl.logDate {
+		return
+	}
+
 	// Close current writer
 	if fl.writer != nil {
 		fl.writer.Close()
 	}
 
 	// Reinitialize with new date
-	return fl.initWriter()
+	fl.logDate = date
+	fl.initWriter()
+}
+
+// fileWriter writes to the FileLogger's current file
+type fileWriter struct {
+	fl *FileLogger
+}
+
+func (w fileWriter) Write(p []byte) (int, error) {
+	w.fl.mu.Lock()
+	defer w.fl.mu.Unlock()
+	return w.fl.writer.Write(p)
 }
 
 
@@ -152,6 +186,10 @@ func (fl *FileLogger) Fatal(msg string, fields ...Field) {
 	os.Exit(1)
 }
 
+func (fl *FileLogger) With(fields ...Field) Logger {
+	return withFields(fl, fields)
+}
+
 func (fl *FileLogger) log(logger *log.Logger, msg string, fields ...Field) {
 	if len(fields) > 0 {
 		msg += " | "
@@ -165,28 +203,16 @@ func (fl *FileLogger) log(logger *log.Logger, msg string, fields ...Field) {
 	
 	// Check if we need to rotate (for daily rotation)
 	if fl.config.DailyRotate {
-		now := time.Now()
-		fl.mu.Lock()
-		if now.Day() != fl.currentDay {
-			fl.currentDay = now.Day()
-			// Rotate to new file
-			if err := fl.rotateDaily(); err == nil {
-				// Recreate loggers with new writer
-				fl.debug = log.New(fl.writer, "[DEBUG] ", log.LstdFlags|log.Lshortfile)
-				fl.info = log.New(fl.writer, "[INFO] ", log.LstdFlags|log.Lshortfile)
-				fl.warn = log.New(fl.writer, "[WARN] ", log.LstdFlags|log.Lshortfile)
-				fl.error = log.New(fl.writer, "[ERROR] ", log.LstdFlags|log.Lshortfile)
-				fl.fatal = log.New(fl.writer, "[FATAL] ", log.LstdFlags|log.Lshortfile)
-			}
-		}
-		fl.mu.Unlock()
+		fl.rotateIfNeeded(fl.clock.Now())
 	}
 	
 	logger.Println(msg)
 }
 
-// Close closes the log file
+// Close stops the rotation check and closes the log file
 func (fl *FileLogger) Close() error {
+	fl.closeOnce.Do(func() { close(fl.done) })
+
 	fl.mu.Lock()
 	defer fl.mu.Unlock()
 	
@@ -13,6 +13,9 @@ type Logger interface {
 	Warn(msg string, fields ...Field)
 	Error(msg string, fields ...Field)
 	Fatal(msg string, fields ...Field)
+
+	// With returns a logger that adds fields to every entry it writes
+	With(fields ...Field) Logger
 }
 
 // Field represents a key-value pair for structured logging
@@ -62,6 +65,10 @@ func (l *SimpleLogger) Fatal(msg string, fields ...Field) {
 	os.Exit(1)
 }
 
+func (l *SimpleLogger) With(fields ...Field) Logger {
+	return withFields(l, fields)
+}
+
 func (l *SimpleLogger) log(logger *log.Logger, msg string, fields ...Field) {
 	if len(fields) > 0 {
 		msg += " | "
@@ -92,6 +92,10 @@ func (sl *StackLogger) Fatal(msg string, fields ...Field) {
 	sl.file.Fatal(msg, fields...)
 }
 
+func (sl *StackLogger) With(fields ...Field) Logger {
+	return withFields(sl, fields)
+}
+
 // Close closes the file logger
 func (sl *StackLogger) Close() error {
 	if closer, ok := sl.file.(interface{ Close() error }); ok {
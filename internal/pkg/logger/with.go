@@ -0,0 +1,45 @@
+package logger
+
+// fieldLogger adds a fixed set of fields to every entry written to its parent
+type fieldLogger struct {
+	parent Logger
+	fields []Field
+}
+
+// withFields wraps parent so every entry carries fields, ahead of the call's own
+func withFields(parent Logger, fields []Field) Logger {
+	if len(fields) == 0 {
+		return parent
+	}
+	return &fieldLogger{parent: parent, fields: append([]Field(nil), fields...)}
+}
+
+func (l *fieldLogger) merge(fields []Field) []Field {
+	merged := make([]Field, 0, len(l.fields)+len(fields))
+	merged = append(merged, l.fields...)
+	return append(merged, fields...)
+}
+
+func (l *fieldLogger) Debug(msg string, fields ...Field) {
+	l.parent.Debug(msg, l.merge(fields)...)
+}
+
+func (l *fieldLogger) Info(msg string, fields ...Field) {
+	l.parent.Info(msg, l.merge(fields)...)
+}
+
+func (l *fieldLogger) Warn(msg string, fields ...Field) {
+	l.parent.Warn(msg, l.merge(fields)...)
+}
+
+func (l *fieldLogger) Error(msg string, fields ...Field) {
+	l.parent.Error(msg, l.merge(fields)...)
+}
+
+func (l *fieldLogger) Fatal(msg string, fields ...Field) {
+	l.parent.Fatal(msg, l.merge(fields)...)
+}
+
+func (l *fieldLogger) With(fields ...Field) Logger {
+	return withFields(l.parent, l.merge(fields))
+}
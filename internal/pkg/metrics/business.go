@@ -0,0 +1,259 @@
+package metrics
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/pkg/events"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LoginResult is the result label of auth_logins_total
+type LoginResult string
+
+// Login results
+const (
+	LoginSuccess     LoginResult = "success"
+	LoginFailure     LoginResult = "failure"
+	LoginDeactivated LoginResult = "deactivated"
+)
+
+// PasswordChangeTrigger is the trigger label of auth_password_changes_total
+type PasswordChangeTrigger string
+
+// Password change triggers
+const (
+	// PasswordChangeVoluntary is a change the user chose to make
+	PasswordChangeVoluntary PasswordChangeTrigger = "voluntary"
+	// PasswordChangeRequired is a change forced by an admin or an expired password
+	PasswordChangeRequired PasswordChangeTrigger = "required"
+)
+
+// RequestLogResult is the result label of request_log_entries_total
+type RequestLogResult string
+
+// Request log results
+const (
+	// RequestLogWritten is an entry stored in the request log
+	RequestLogWritten RequestLogResult = "written"
+	// RequestLogDropped is an entry that arrived while the buffer was full
+	RequestLogDropped RequestLogResult = "dropped"
+	// RequestLogFailed is an entry whose insert failed
+	RequestLogFailed RequestLogResult = "failed"
+)
+
+// Delivery results, the result label of emails_sent_total, webhook_deliveries_total
+// and audit_forwarded_total
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+// otherEventType labels events whose type is not in events.Types
+const otherEventType = "other"
+
+// Business counts domain events for dashboards and alerts. Every label takes
+// one of a fixed set of values, so the series stay bounded. Its methods do
+// nothing on a nil *Business, which is what services get without metrics.
+type Business struct {
+	// Registrations counts users who registered themselves
+	Registrations prometheus.Counter
+
+	// Logins counts login attempts, by result
+	Logins *prometheus.CounterVec
+
+	// PasswordChanges counts passwords changed by their users, by trigger
+	PasswordChanges *prometheus.CounterVec
+
+	// PasswordResets counts password changes required by an admin
+	PasswordResets prometheus.Counter
+
+	// Emails counts emails sent, by result
+	Emails *prometheus.CounterVec
+
+	// WebhookDeliveries counts webhook deliveries after retries, by result
+	WebhookDeliveries *prometheus.CounterVec
+
+	// WebhookDuration observes each webhook delivery attempt
+	WebhookDuration prometheus.Histogram
+
+	// EventsPublished counts published events, by type
+	EventsPublished *prometheus.CounterVec
+
+	// AuditForwards counts audit entries sent to the SIEM, by result
+	AuditForwards *prometheus.CounterVec
+
+	// RequestLogs counts request log entries, by result
+	RequestLogs *prometheus.CounterVec
+
+	// PreviousSecretVerifications counts tokens verified with the JWT secret
+	// that was current before the last rotation
+	PreviousSecretVerifications prometheus.Counter
+}
+
+func newBusiness() *Business {
+	return &Business{
+		Registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "auth_registrations_total",
+			Help: "Users who registered themselves.",
+		}),
+		Logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_logins_total",
+			Help: "Login attempts by result: success, failure or deactivated.",
+		}, []string{"result"}),
+		PasswordChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_password_changes_total",
+			Help: "Passwords changed by their users, by trigger: voluntary or required.",
+		}, []string{"trigger"}),
+		PasswordResets: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "auth_password_resets_total",
+			Help: "Password changes required of users by an admin.",
+		}),
+		Emails: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "emails_sent_total",
+			Help: "Emails handed to the email client, by result: success or failure.",
+		}, []string{"result"}),
+		WebhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "webhook_deliveries_total",
+			Help: "Webhook deliveries after retries, by result: success or failure.",
+		}, []string{"result"}),
+		WebhookDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "webhook_delivery_duration_seconds",
+			Help:    "Duration of each webhook delivery attempt.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		EventsPublished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "events_published_total",
+			Help: "Events published to the event stream, by type.",
+		}, []string{"type"}),
+		AuditForwards: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "audit_forwarded_total",
+			Help: "Audit entries sent to the SIEM after retries, by result: success or failure. Failed entries are spilled and counted again when replayed.",
+		}, []string{"result"}),
+		RequestLogs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "request_log_entries_total",
+			Help: "Requests sampled into the database request log, by result: written, dropped while the buffer was full, or failed to insert.",
+		}, []string{"result"}),
+		PreviousSecretVerifications: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "auth_jwt_previous_secret_verifications_total",
+			Help: "Tokens verified with the previous JWT secret during a rotation's grace window. Once it stops increasing, no token in use depends on the previous secret.",
+		}),
+	}
+}
+
+// collectors returns the collectors to register
+func (b *Business) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		b.Registrations,
+		b.Logins,
+		b.PasswordChanges,
+		b.PasswordResets,
+		b.Emails,
+		b.WebhookDeliveries,
+		b.WebhookDuration,
+		b.EventsPublished,
+		b.AuditForwards,
+		b.RequestLogs,
+		b.PreviousSecretVerifications,
+	}
+}
+
+// Registered counts a self-registration
+func (b *Business) Registered() {
+	if b == nil {
+		return
+	}
+	b.Registrations.Inc()
+}
+
+// Login counts a login attempt
+func (b *Business) Login(result LoginResult) {
+	if b == nil {
+		return
+	}
+	b.Logins.WithLabelValues(string(result)).Inc()
+}
+
+// PasswordChanged counts a password changed by its user
+func (b *Business) PasswordChanged(trigger PasswordChangeTrigger) {
+	if b == nil {
+		return
+	}
+	b.PasswordChanges.WithLabelValues(string(trigger)).Inc()
+}
+
+// PasswordReset counts a password change required by an admin
+func (b *Business) PasswordReset() {
+	if b == nil {
+		return
+	}
+	b.PasswordResets.Inc()
+}
+
+// EmailSent counts an email send; err is the email client's result
+func (b *Business) EmailSent(err error) {
+	if b == nil {
+		return
+	}
+	b.Emails.WithLabelValues(result(err == nil)).Inc()
+}
+
+// WebhookAttempted observes the duration of one webhook delivery attempt
+func (b *Business) WebhookAttempted(duration time.Duration) {
+	if b == nil {
+		return
+	}
+	b.WebhookDuration.Observe(duration.Seconds())
+}
+
+// WebhookDelivered counts a webhook delivery once its retries are over
+func (b *Business) WebhookDelivered(success bool) {
+	if b == nil {
+		return
+	}
+	b.WebhookDeliveries.WithLabelValues(result(success)).Inc()
+}
+
+// EventPublished counts an event published to the event stream. Types
+// missing from events.Types are counted as "other".
+func (b *Business) EventPublished(eventType string) {
+	if b == nil {
+		return
+	}
+	if !events.Known(eventType) {
+		eventType = otherEventType
+	}
+	b.EventsPublished.WithLabelValues(eventType).Inc()
+}
+
+// AuditForwarded counts an audit entry sent to the SIEM, or failed to
+func (b *Business) AuditForwarded(success bool) {
+	if b == nil {
+		return
+	}
+	b.AuditForwards.WithLabelValues(result(success)).Inc()
+}
+
+// RequestLogged counts n request log entries with the same result
+func (b *Business) RequestLogged(result RequestLogResult, n int) {
+	if b == nil {
+		return
+	}
+	b.RequestLogs.WithLabelValues(string(result)).Add(float64(n))
+}
+
+// PreviousSecretVerified counts a token verified with the previous JWT secret
+func (b *Business) PreviousSecretVerified() {
+	if b == nil {
+		return
+	}
+	b.PreviousSecretVerifications.Inc()
+}
+
+// result returns the result label of a delivery
+func result(success bool) string {
+	if success {
+		return resultSuccess
+	}
+	return resultFailure
+}
@@ -0,0 +1,167 @@
+// Package metrics holds the application's Prometheus collectors. Each
+// Application owns its own registry, so several can run in one process.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"BackofficeGoService/internal/pkg/buildinfo"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics is a registry and the collectors registered on it
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	// SlowRequests counts requests slower than their threshold, by route template
+	SlowRequests *prometheus.CounterVec
+
+	// InFlight is the number of requests being handled, by concurrency group
+	InFlight *prometheus.GaugeVec
+
+	// Shed counts requests refused by the concurrency limit, by group and reason
+	Shed *prometheus.CounterVec
+
+	// Panics counts handler panics recovered into a 500, by route template
+	Panics *prometheus.CounterVec
+
+	// RateLimitWouldBlock counts requests over an observed rate limit that
+	// were let through, by route template
+	RateLimitWouldBlock *prometheus.CounterVec
+
+	// CircuitState is each database circuit breaker's state: 0 closed, 1 half-open, 2 open
+	CircuitState *prometheus.GaugeVec
+
+	// QueryRetries counts retries of idempotent database operations, by the
+	// reason of the transient error
+	QueryRetries *prometheus.CounterVec
+
+	// UserPurge counts rows removed by the user purge job, by table
+	UserPurge *prometheus.CounterVec
+
+	// Outbound observes requests the service makes to other services, by
+	// host and status class
+	Outbound *prometheus.HistogramVec
+
+	// WorkerPoolQueued is the number of tasks waiting for a worker, by pool
+	WorkerPoolQueued *prometheus.GaugeVec
+
+	// WorkerPoolInFlight is the number of tasks running, by pool
+	WorkerPoolInFlight *prometheus.GaugeVec
+
+	// WorkerPoolRejected counts tasks refused because the queue was full, by pool
+	WorkerPoolRejected *prometheus.CounterVec
+
+	// BuildInfo is always 1, labelled with the running build; see SetBuildInfo
+	BuildInfo *prometheus.GaugeVec
+
+	// Business counts registrations, logins, emails, webhook deliveries, events
+	// and audit entries forwarded
+	Business *Business
+}
+
+// New creates the collectors on a fresh registry
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		SlowRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_slow_requests_total",
+			Help: "HTTP requests that took longer than the slow request threshold.",
+		}, []string{"route"}),
+		InFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests being handled under the concurrency limit, by route group.",
+		}, []string{"group"}),
+		Shed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_shed_total",
+			Help: "HTTP requests refused with 503 because the concurrency limit and its queue were full.",
+		}, []string{"group", "reason"}),
+		Panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_panics_total",
+			Help: "HTTP requests whose handler panicked.",
+		}, []string{"route"}),
+		RateLimitWouldBlock: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ratelimit_would_block_total",
+			Help: "Requests over a rate limit in observe mode, which would have been refused with 429 under enforce.",
+		}, []string{"route"}),
+		CircuitState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "database_circuit_breaker_state",
+			Help: "Database circuit breaker state: 0 closed, 1 half-open, 2 open.",
+		}, []string{"database"}),
+		QueryRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "database_query_retries_total",
+			Help: "Retries of idempotent database operations after a transient error, by reason.",
+		}, []string{"reason"}),
+		UserPurge: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "user_purge_rows_total",
+			Help: "Rows removed by the user purge job; audit_logs counts entries whose actor was cleared.",
+		}, []string{"table"}),
+		Outbound: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_client_request_duration_seconds",
+			Help:    "Duration of outbound HTTP requests until their response headers, by host and status class; error when no response was received.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"host", "status"}),
+		WorkerPoolQueued: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "worker_pool_queued_tasks",
+			Help: "Tasks waiting for a worker of the pool.",
+		}, []string{"pool"}),
+		WorkerPoolInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "worker_pool_tasks_in_flight",
+			Help: "Tasks running on the pool's workers.",
+		}, []string{"pool"}),
+		WorkerPoolRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "worker_pool_rejected_tasks_total",
+			Help: "Tasks refused because the pool's queue was full.",
+		}, []string{"pool"}),
+		BuildInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "build_info",
+			Help: "Always 1, labelled with the version, commit, build date and Go version of the running build.",
+		}, []string{"version", "commit", "build_date", "go_version"}),
+		Business: newBusiness(),
+	}
+
+	m.Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		m.SlowRequests,
+		m.InFlight,
+		m.Shed,
+		m.Panics,
+		m.RateLimitWouldBlock,
+		m.CircuitState,
+		m.QueryRetries,
+		m.UserPurge,
+		m.Outbound,
+		m.WorkerPoolQueued,
+		m.WorkerPoolInFlight,
+		m.WorkerPoolRejected,
+		m.BuildInfo,
+	)
+	m.Registry.MustRegister(m.Business.collectors()...)
+	return m
+}
+
+// SetBuildInfo labels the build_info gauge with info, replacing any earlier build
+func (m *Metrics) SetBuildInfo(info buildinfo.Info) {
+	m.BuildInfo.Reset()
+	m.BuildInfo.WithLabelValues(info.Version, info.Commit, info.BuildDate, info.GoVersion).Set(1)
+}
+
+// ObserveOutbound observes an outbound request to host; status is 0 when
+// no response was received. It is an httpclient.LatencyObserver.
+func (m *Metrics) ObserveOutbound(host string, status int, elapsed time.Duration) {
+	class := "error"
+	if status > 0 {
+		class = strconv.Itoa(status/100) + "xx"
+	}
+	m.Outbound.WithLabelValues(host, class).Observe(elapsed.Seconds())
+}
+
+// Handler serves the registry in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+}
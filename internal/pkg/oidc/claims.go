@@ -0,0 +1,66 @@
+package oidc
+
+import (
+	"strings"
+)
+
+// Claims are the claims of a validated ID token
+type Claims map[string]interface{}
+
+// Subject returns the sub claim, the user's stable ID at the provider
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// String returns the string claim name, or "". Dotted names such as
+// realm_access.roles reach into nested objects.
+func (c Claims) String(name string) string {
+	s, _ := c.lookup(name).(string)
+	return s
+}
+
+// Strings returns the claim name as a list: a list of strings, or a single
+// string as a list of one
+func (c Claims) Strings(name string) []string {
+	switch v := c.lookup(name).(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Bool returns the boolean claim name. Some providers send booleans as
+// strings, which are read too.
+func (c Claims) Bool(name string) bool {
+	switch v := c.lookup(name).(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+// lookup returns the claim name, following dots into nested objects
+func (c Claims) lookup(name string) interface{} {
+	if v, ok := c[name]; ok {
+		return v
+	}
+	var current interface{} = map[string]interface{}(c)
+	for _, part := range strings.Split(name, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[part]
+	}
+	return current
+}
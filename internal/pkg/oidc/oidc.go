@@ -0,0 +1,369 @@
+// Package oidc signs users in with OpenID Connect providers such as Keycloak
+// or Azure AD. It reads a provider's discovery document, builds
+// authorization-code requests protected by PKCE, redeems their codes and
+// validates the ID tokens returned against the provider's published keys.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/pkg/clock"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrUnavailable is returned when the provider's discovery document or
+	// keys cannot be fetched
+	ErrUnavailable = errors.New("oidc: provider unavailable")
+	// ErrExchange is returned when the provider refuses to redeem a code
+	ErrExchange = errors.New("oidc: code exchange failed")
+	// ErrInvalidIDToken is returned for ID tokens that are malformed, wrongly
+	// signed, expired, or issued by or for someone else
+	ErrInvalidIDToken = errors.New("oidc: invalid ID token")
+)
+
+// DefaultCacheTTL is how long discovery documents are reused
+const DefaultCacheTTL = time.Hour
+
+// keyRefreshInterval is how often an unknown key ID may make the provider's
+// keys be fetched again
+const keyRefreshInterval = time.Minute
+
+// maxResponseSize bounds what is read from the provider
+const maxResponseSize = 1 << 20
+
+// signingMethods are the ID token algorithms accepted
+var signingMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// Config identifies the service to a provider
+type Config struct {
+	// Issuer is the provider's issuer URL; the discovery document is read
+	// from its /.well-known/openid-configuration
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback registered with the provider
+	RedirectURL string
+	// Scopes are requested besides openid
+	Scopes []string
+}
+
+// Discovery is the part of a provider's discovery document used here
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider signs users in with one OpenID Connect provider
+type Provider struct {
+	config   Config
+	client   *http.Client
+	clock    clock.Clock
+	cacheTTL time.Duration
+	skew     time.Duration
+
+	mu          sync.Mutex
+	discovery   *Discovery
+	discovered  time.Time
+	keys        map[string]interface{}
+	keysFetched time.Time
+}
+
+// Option configures a Provider
+type Option func(*Provider)
+
+// WithHTTPClient sets the client the provider is called with
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) {
+		p.client = c
+	}
+}
+
+// WithClock sets the clock ID tokens are checked by
+func WithClock(c clock.Clock) Option {
+	return func(p *Provider) {
+		p.clock = c
+	}
+}
+
+// WithCacheTTL sets how long the discovery document is reused
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(p *Provider) {
+		p.cacheTTL = ttl
+	}
+}
+
+// WithClockSkew tolerates clocks differing from the provider's by d when
+// checking the times of ID tokens
+func WithClockSkew(d time.Duration) Option {
+	return func(p *Provider) {
+		p.skew = d
+	}
+}
+
+// NewProvider creates a provider; nothing is fetched until it is used
+func NewProvider(cfg Config, opts ...Option) *Provider {
+	p := &Provider{
+		config:   cfg,
+		client:   http.DefaultClient,
+		clock:    clock.New(),
+		cacheTTL: DefaultCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Discover returns the provider's discovery document, fetched once per
+// cache TTL. While it cannot be fetched again, the last one read is used.
+func (p *Provider) Discover(ctx context.Context) (*Discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && p.clock.Now().Sub(p.discovered) < p.cacheTTL {
+		return p.discovery, nil
+	}
+
+	var doc Discovery
+	err := p.getJSON(ctx, strings.TrimSuffix(p.config.Issuer, "/")+"/.well-known/openid-configuration", &doc)
+	if err == nil && doc.Issuer != p.config.Issuer {
+		err = fmt.Errorf("%w: discovery document is for issuer %q", ErrUnavailable, doc.Issuer)
+	}
+	if err == nil && (doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "") {
+		err = fmt.Errorf("%w: discovery document lacks endpoints", ErrUnavailable)
+	}
+	if err != nil {
+		if p.discovery != nil {
+			return p.discovery, nil
+		}
+		return nil, err
+	}
+	p.discovery, p.discovered = &doc, p.clock.Now()
+	return p.discovery, nil
+}
+
+// AuthCodeURL returns the provider URL a user is sent to for signing in.
+// state and nonce come back in the callback and the ID token; verifier is
+// the PKCE code verifier Exchange needs, sent here only as its challenge.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	doc, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.scopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return doc.AuthorizationEndpoint + sep + query.Encode(), nil
+}
+
+// scopes returns openid and the configured scopes
+func (p *Provider) scopes() []string {
+	scopes := []string{"openid"}
+	for _, scope := range p.config.Scopes {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// Exchange redeems an authorization code with its PKCE verifier and returns
+// the raw ID token
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	doc, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {p.config.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	_ = json.Unmarshal(body, &token)
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return "", fmt.Errorf("%w: token endpoint answered %d", ErrUnavailable, resp.StatusCode)
+		}
+		return "", fmt.Errorf("%w: %s %s", ErrExchange, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("%w: no id_token in the response", ErrExchange)
+	}
+	return token.IDToken, nil
+}
+
+// Verify validates an ID token: its signature against the provider's keys,
+// its issuer and audience, its expiry and issue time within the clock
+// skew, and its nonce. It returns the token's claims.
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (Claims, error) {
+	doc, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyErr error
+	parsed, err := jwt.Parse(rawIDToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.key(ctx, doc.JWKSURI, kid)
+		keyErr = err
+		return key, err
+	},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(p.skew),
+		jwt.WithTimeFunc(p.clock.Now),
+	)
+	if errors.Is(keyErr, ErrUnavailable) {
+		return nil, keyErr
+	}
+	if err != nil || !parsed.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	claims := Claims(parsed.Claims.(jwt.MapClaims))
+	if claims.Subject() == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+	if claims.String("nonce") != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	// A token for several audiences must have been issued to this client
+	if aud := claims.Strings("aud"); len(aud) > 1 && claims.String("azp") != p.config.ClientID {
+		return nil, fmt.Errorf("%w: issued to %q", ErrInvalidIDToken, claims.String("azp"))
+	}
+	return claims, nil
+}
+
+// key returns the provider's key kid. Unknown key IDs make the keys be
+// fetched again, at most once per keyRefreshInterval, so rotated keys are
+// picked up. A token without a key ID is accepted when there is one key.
+func (p *Provider) key(ctx context.Context, jwksURI, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	if !p.keysFetched.IsZero() && p.clock.Now().Sub(p.keysFetched) < keyRefreshInterval {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidIDToken, kid)
+	}
+
+	var set jwks
+	if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, err
+	}
+	p.keys, p.keysFetched = set.publicKeys(), p.clock.Now()
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidIDToken, kid)
+}
+
+// lookup finds a fetched key
+func (p *Provider) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok && kid != ""
+}
+
+// getJSON fetches a document from the provider
+func (p *Provider) getJSON(ctx context.Context, rawURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s answered %d", ErrUnavailable, rawURL, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid response from %s: %v", ErrUnavailable, rawURL, err)
+	}
+	return nil
+}
+
+// Challenge returns the S256 PKCE challenge of verifier
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// RandomString returns a random URL-safe string, used for states, nonces
+// and PKCE verifiers
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("oidc: failed to generate a random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,241 @@
+// Package pagination reads the page a list request asks for and describes
+// the page in the response: a meta block for the body and an RFC 5988 Link
+// header pointing at the neighbouring pages. Links keep every other query
+// parameter of the request, so filters and sorting carry over.
+//
+// Offset lists use page and limit, sized by the controller's Config:
+//
+//	params, appErr := pagination.ParseParams(c, cfg)
+//	if appErr != nil {
+//		middleware.RespondError(c, appErr)
+//		return
+//	}
+//	result, err := svc.List(ctx, params.Limit, params.Offset())
+//	meta := pagination.NewMeta(params, result.Total)
+//	c.Header("Link", meta.Links(c.Request.URL))
+//	c.JSON(http.StatusOK, gin.H{"data": result.Items, "meta": meta})
+//
+// Cursor lists use limit and an opaque cursor, and only link to the next page.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Query parameters read and written by the package
+const (
+	PageParam   = "page"
+	LimitParam  = "limit"
+	CursorParam = "cursor"
+)
+
+// ErrInvalidCursor is returned by ParseCursor for a cursor it did not encode
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrInvalidParam is returned by Parse for a page or limit that is not a
+// positive integer
+var ErrInvalidParam = errors.New("not a positive integer")
+
+// Config sizes the pages of a list. Zero fields take their DefaultConfig value.
+type Config struct {
+	// DefaultLimit is the page size when the request sends no limit
+	DefaultLimit int
+	// MaxLimit caps the page size a client may ask for
+	MaxLimit int
+}
+
+// DefaultConfig is the page size of lists not configured otherwise
+var DefaultConfig = Config{DefaultLimit: 10, MaxLimit: 100}
+
+// WithDefault returns cfg with pages of limit items when none is asked for
+func (cfg Config) WithDefault(limit int) Config {
+	cfg.DefaultLimit = limit
+	return cfg
+}
+
+// WithMax returns cfg allowing pages of up to limit items, for lists such
+// as audit trails that are read in bulk
+func (cfg Config) WithMax(limit int) Config {
+	cfg.MaxLimit = limit
+	return cfg
+}
+
+// Params is the page a request asks for
+type Params struct {
+	Page  int
+	Limit int
+}
+
+// Parse reads page and limit from query. A missing page is 1 and a missing
+// limit is cfg.DefaultLimit; limits over cfg.MaxLimit are capped. Values
+// that are not positive integers fail with ErrInvalidParam.
+func Parse(query url.Values, cfg Config) (Params, error) {
+	if cfg.DefaultLimit <= 0 {
+		cfg.DefaultLimit = DefaultConfig.DefaultLimit
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = DefaultConfig.MaxLimit
+	}
+
+	page, err := positiveInt(query, PageParam, 1)
+	if err != nil {
+		return Params{}, err
+	}
+	limit, err := positiveInt(query, LimitParam, cfg.DefaultLimit)
+	if err != nil {
+		return Params{}, err
+	}
+	return Params{Page: page, Limit: min(limit, cfg.MaxLimit)}, nil
+}
+
+// ParseParams reads the page the request asks for like Parse. An invalid
+// page or limit is returned as a 400 INVALID_PAGINATION error naming the
+// parameter.
+func ParseParams(c *gin.Context, cfg Config) (Params, *apperrors.AppError) {
+	params, err := Parse(c.Request.URL.Query(), cfg)
+	if err != nil {
+		var paramErr *paramError
+		errors.As(err, &paramErr)
+		appErr := apperrors.NewBadRequestError(i18n.RequestInvalidPagination, err).
+			WithCode(apperrors.CodeInvalidPagination).
+			WithParams(apperrors.Params{"name": paramErr.name})
+		return Params{}, appErr
+	}
+	return params, nil
+}
+
+// Offset returns the number of items before the page
+func (p Params) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// Meta describes a page of an offset list
+type Meta struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// NewMeta describes the page p of a list of total items
+func NewMeta(p Params, total int64) Meta {
+	pages := 0
+	if p.Limit > 0 {
+		pages = int((total + int64(p.Limit) - 1) / int64(p.Limit))
+	}
+	return Meta{Page: p.Page, Limit: p.Limit, Total: total, TotalPages: pages}
+}
+
+// Links returns the Link header for the page: first and last always, prev
+// and next when there is such a page. An empty list has one, empty, page.
+func (m Meta) Links(u *url.URL) string {
+	last := max(m.TotalPages, 1)
+
+	links := []string{link(u, "first", map[string]string{PageParam: "1"}, m.Limit)}
+	if m.Page > 1 {
+		// A page past the end goes back to the last one
+		prev := min(m.Page-1, last)
+		links = append(links, link(u, "prev", map[string]string{PageParam: strconv.Itoa(prev)}, m.Limit))
+	}
+	if m.Page < m.TotalPages {
+		links = append(links, link(u, "next", map[string]string{PageParam: strconv.Itoa(m.Page + 1)}, m.Limit))
+	}
+	links = append(links, link(u, "last", map[string]string{PageParam: strconv.Itoa(last)}, m.Limit))
+	return strings.Join(links, ", ")
+}
+
+// CursorMeta describes a page of a cursor list. NextCursor is empty on the
+// last page.
+type CursorMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Links returns the Link header for the page: only next, and nothing on the
+// last page
+func (m CursorMeta) Links(u *url.URL) string {
+	if m.NextCursor == "" {
+		return ""
+	}
+	return link(u, "next", map[string]string{CursorParam: m.NextCursor}, m.Limit)
+}
+
+// Cursor is a position in a list ordered by time and then ID, newest first
+type Cursor struct {
+	Time time.Time
+	ID   string
+}
+
+// Encode returns the cursor as an opaque, URL-safe string
+func (c Cursor) Encode() string {
+	raw := c.Time.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a cursor returned by Encode
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	at, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{Time: t, ID: id}, nil
+}
+
+// link formats one Link header entry: u with params and limit replaced,
+// relative to the server so it works behind proxies
+func link(u *url.URL, rel string, params map[string]string, limit int) string {
+	query := u.Query()
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	query.Set(LimitParam, strconv.Itoa(limit))
+
+	target := url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: query.Encode()}
+	return "<" + target.String() + `>; rel="` + rel + `"`
+}
+
+// paramError is an ErrInvalidParam for one query parameter
+type paramError struct {
+	name string
+}
+
+func (e *paramError) Error() string {
+	return fmt.Sprintf("%s: %v", e.name, ErrInvalidParam)
+}
+
+func (e *paramError) Unwrap() error {
+	return ErrInvalidParam
+}
+
+// positiveInt parses the query parameter name, returning fallback when it
+// is missing
+func positiveInt(query url.Values, name string, fallback int) (int, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, &paramError{name: name}
+	}
+	return n, nil
+}
@@ -0,0 +1,273 @@
+// Package ratelimit counts requests in fixed windows in a cache store, so
+// every instance sharing the cache shares the limits, remembers the
+// clients that went over a limit until their window ends, and keeps the
+// clients blocked from every limited route for a while.
+package ratelimit
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/pkg/cache"
+)
+
+// Mode is what happens to requests over a limit
+type Mode string
+
+const (
+	// Enforce refuses requests over the limit
+	Enforce Mode = "enforce"
+	// Observe lets requests over the limit through and only reports them
+	Observe Mode = "observe"
+)
+
+// ParseMode returns the mode named s; the empty string is Enforce
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case "":
+		return Enforce, nil
+	case Enforce, Observe:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown rate limit mode %q, want %s or %s", s, Enforce, Observe)
+	}
+}
+
+// Limit allows Requests per Window; the zero value allows any number. An
+// empty Mode is left to whoever applies the limit.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+	Mode     Mode
+}
+
+// Enabled reports whether the limit is set
+func (l Limit) Enabled() bool {
+	return l.Requests > 0
+}
+
+// Usage is a client's use of a limit in the current window
+type Usage struct {
+	Limit int
+	Count int64
+	// Reset is when the window ends
+	Reset time.Time
+}
+
+// Exceeded reports whether the client went over the limit
+func (u Usage) Exceeded() bool {
+	return u.Count > int64(u.Limit)
+}
+
+// Remaining is how many more requests the window allows
+func (u Usage) Remaining() int64 {
+	return max(int64(u.Limit)-u.Count, 0)
+}
+
+// Offender is a client over a limit in the current window
+type Offender struct {
+	Route  string `json:"route"`
+	Client string `json:"client"`
+	Mode   Mode   `json:"mode"`
+	Limit  int    `json:"limit"`
+	// Requests counts every request of the window, OverLimit those that
+	// were refused or, when observed, would have been
+	Requests     int64     `json:"requests"`
+	OverLimit    int64     `json:"over_limit"`
+	WindowEndsAt time.Time `json:"window_ends_at"`
+
+	key string
+}
+
+// offenderEntry is an Offender as kept in the index
+type offenderEntry struct {
+	Key          string    `json:"key"`
+	Route        string    `json:"route"`
+	Client       string    `json:"client"`
+	Mode         Mode      `json:"mode"`
+	Limit        int       `json:"limit"`
+	WindowEndsAt time.Time `json:"window_ends_at"`
+}
+
+// Keys in the store
+const (
+	keyPrefix   = "rate_limit:"
+	offenderKey = "rate_limit_offenders"
+	blockPrefix = "rate_limit_block:"
+)
+
+// Limiter counts requests and tracks offenders in a store
+type Limiter struct {
+	store cache.Store
+	now   func() time.Time
+
+	// mu serializes this instance's updates of the offender index; updates
+	// racing on other instances may drop an offender until it is seen again
+	mu sync.Mutex
+}
+
+// New creates a limiter keeping its counters in store
+func New(store cache.Store) *Limiter {
+	return &Limiter{store: store, now: time.Now}
+}
+
+// Take counts a request of client to route against limit. The counter
+// does not depend on the limit's mode, so switching modes keeps counting
+// where the window is at.
+func (l *Limiter) Take(ctx context.Context, route, client string, limit Limit) (Usage, error) {
+	now := l.now()
+	start := now.Truncate(limit.Window)
+	usage := Usage{Limit: limit.Requests, Reset: start.Add(limit.Window)}
+
+	key := counterKey(route, client, start)
+	count, err := l.store.Incr(ctx, key, 1, limit.Window)
+	if err != nil {
+		return usage, err
+	}
+	usage.Count = count
+
+	// Each client is listed once per window, when it first goes over
+	if count == int64(limit.Requests)+1 {
+		if err := l.addOffender(ctx, offenderEntry{
+			Key:          key,
+			Route:        route,
+			Client:       client,
+			Mode:         limit.Mode,
+			Limit:        limit.Requests,
+			WindowEndsAt: usage.Reset,
+		}); err != nil {
+			return usage, err
+		}
+	}
+	return usage, nil
+}
+
+// Block refuses client on every route with a limit for d, whatever its
+// count, and returns when the block ends. Blocking a blocked client again
+// moves the end of its block.
+func (l *Limiter) Block(ctx context.Context, client string, d time.Duration) (time.Time, error) {
+	until := l.now().Add(d)
+	if d <= 0 {
+		return until, nil
+	}
+	return until, l.store.Set(ctx, blockPrefix+client, []byte(until.UTC().Format(time.RFC3339)), d)
+}
+
+// Blocked returns when the block of client ends, or the zero time if it is
+// not blocked
+func (l *Limiter) Blocked(ctx context.Context, client string) (time.Time, error) {
+	data, err := l.store.Get(ctx, blockPrefix+client)
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	until, err := time.Parse(time.RFC3339, string(data))
+	if err != nil || !until.After(l.now()) {
+		return time.Time{}, nil
+	}
+	return until, nil
+}
+
+// Offenders returns up to n clients over a limit in their current window,
+// the most requests over the limit first
+func (l *Limiter) Offenders(ctx context.Context, n int) ([]Offender, error) {
+	entries, err := l.offenders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	offenders := make([]Offender, 0, len(entries))
+	for _, entry := range entries {
+		data, err := l.store.Get(ctx, entry.Key)
+		if errors.Is(err, cache.ErrCacheMiss) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		count, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			continue
+		}
+		offenders = append(offenders, Offender{
+			Route:        entry.Route,
+			Client:       entry.Client,
+			Mode:         entry.Mode,
+			Limit:        entry.Limit,
+			Requests:     count,
+			OverLimit:    max(count-int64(entry.Limit), 0),
+			WindowEndsAt: entry.WindowEndsAt,
+			key:          entry.Key,
+		})
+	}
+
+	slices.SortFunc(offenders, func(a, b Offender) int {
+		if c := cmp.Compare(b.OverLimit, a.OverLimit); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.key, b.key)
+	})
+	if len(offenders) > n {
+		offenders = offenders[:n]
+	}
+	return offenders, nil
+}
+
+// addOffender adds entry to the index, dropping the entries whose window ended
+func (l *Limiter) addOffender(ctx context.Context, entry offenderEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := l.offenders(ctx)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, entry)
+
+	// The index lives as long as its last window
+	var ttl time.Duration
+	for _, e := range entries {
+		ttl = max(ttl, e.WindowEndsAt.Sub(l.now()))
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return l.store.Set(ctx, offenderKey, data, ttl)
+}
+
+// offenders reads the index without the entries whose window ended
+func (l *Limiter) offenders(ctx context.Context) ([]offenderEntry, error) {
+	data, err := l.store.Get(ctx, offenderKey)
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []offenderEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		// A corrupt index is rebuilt as clients go over their limits again
+		return nil, nil
+	}
+	now := l.now()
+	return slices.DeleteFunc(entries, func(e offenderEntry) bool {
+		return !e.WindowEndsAt.After(now)
+	}), nil
+}
+
+// counterKey is the counter of client's requests to route in the window
+// starting at start
+func counterKey(route, client string, start time.Time) string {
+	return keyPrefix + route + ":" + client + ":" + strconv.FormatInt(start.Unix(), 10)
+}
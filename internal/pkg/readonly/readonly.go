@@ -0,0 +1,160 @@
+// Package readonly freezes writes during incident response while reads keep
+// working. The switch is kept in a cache store, so every instance sharing
+// the store follows it within its refresh interval.
+package readonly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+)
+
+// ErrReadOnly is returned for writes refused while the switch is on
+var ErrReadOnly = errors.New("the service is read-only")
+
+// key is the cache key the state is stored under
+const key = "read_only"
+
+// DefaultRefreshInterval is how long an instance trusts the state it last
+// read from the store
+const DefaultRefreshInterval = 2 * time.Second
+
+// State is whether writes are frozen, and by whom
+type State struct {
+	Enabled bool       `json:"enabled"`
+	Reason  string     `json:"reason,omitempty"`
+	ActorID string     `json:"actor_id,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// Switch turns read-only mode on and off
+type Switch struct {
+	store   cache.Store
+	refresh time.Duration
+	clock   clock.Clock
+
+	mu      sync.Mutex
+	state   State
+	checked time.Time
+}
+
+// Option configures a Switch
+type Option func(*Switch)
+
+// WithRefreshInterval sets how long the state read from the store is
+// trusted; zero reads the store on every check
+func WithRefreshInterval(d time.Duration) Option {
+	return func(s *Switch) {
+		s.refresh = d
+	}
+}
+
+// WithClock sets the clock the refresh interval is measured by
+func WithClock(c clock.Clock) Option {
+	return func(s *Switch) {
+		s.clock = c
+	}
+}
+
+// New creates a switch kept in store. Without a store the state is local
+// to the instance.
+func New(store cache.Store, opts ...Option) *Switch {
+	s := &Switch{
+		store:   store,
+		refresh: DefaultRefreshInterval,
+		clock:   clock.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// State returns the current state. It is read from the store once the
+// last read is older than the refresh interval; while the store cannot be
+// read, the last known state stands.
+func (s *Switch) State(ctx context.Context) State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if s.store == nil || (!s.checked.IsZero() && now.Sub(s.checked) < s.refresh) {
+		return s.state
+	}
+	data, err := s.store.Get(context.WithoutCancel(ctx), key)
+	switch {
+	case errors.Is(err, cache.ErrCacheMiss):
+		s.state, s.checked = State{}, now
+	case err == nil:
+		var state State
+		if json.Unmarshal(data, &state) == nil {
+			s.state, s.checked = state, now
+		}
+	}
+	return s.state
+}
+
+// Enabled reports whether writes are frozen
+func (s *Switch) Enabled(ctx context.Context) bool {
+	return s.State(ctx).Enabled
+}
+
+// Set turns read-only mode on or off on behalf of actorID. Other instances
+// sharing the store follow within their refresh interval.
+func (s *Switch) Set(ctx context.Context, enabled bool, reason, actorID string) (State, error) {
+	state := State{Enabled: enabled}
+	if enabled {
+		since := s.clock.Now().UTC()
+		state.Reason, state.ActorID, state.Since = reason, actorID, &since
+	}
+
+	if s.store != nil {
+		var err error
+		if enabled {
+			var data []byte
+			if data, err = json.Marshal(state); err == nil {
+				err = s.store.Set(ctx, key, data, 0)
+			}
+		} else {
+			err = s.store.Delete(ctx, key)
+		}
+		if err != nil {
+			return State{}, err
+		}
+	}
+
+	s.mu.Lock()
+	s.state, s.checked = state, s.clock.Now()
+	s.mu.Unlock()
+	return state, nil
+}
+
+// allowedKey marks contexts whose writes go through in read-only mode
+type allowedKey struct{}
+
+// Allow lets writes made with the returned context through in read-only
+// mode. The API allows the requests it admitted; work started without it,
+// such as background jobs, is refused.
+func Allow(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allowedKey{}, true)
+}
+
+// Allowed reports whether writes made with ctx go through in read-only mode
+func Allowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(allowedKey{}).(bool)
+	return allowed
+}
+
+// CheckWrite returns ErrReadOnly for writes made with ctx while the switch
+// is on, unless ctx was allowed
+func (s *Switch) CheckWrite(ctx context.Context) error {
+	if Allowed(ctx) || !s.Enabled(ctx) {
+		return nil
+	}
+	return ErrReadOnly
+}
@@ -0,0 +1,27 @@
+package requestctx
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Update replaces the request's context with the one with returns. Values
+// set this way are seen by later handlers through the *gin.Context and by
+// services handed c.Request.Context():
+//
+//	requestctx.Update(c, func(ctx context.Context) context.Context {
+//		return requestctx.WithRequestID(ctx, id)
+//	})
+func Update(c *gin.Context, with func(ctx context.Context) context.Context) {
+	c.Request = c.Request.WithContext(with(c.Request.Context()))
+}
+
+// requestContext returns the request's context when ctx is a *gin.Context,
+// so getters see what Update stored whatever the engine's settings
+func requestContext(ctx context.Context) context.Context {
+	if c, ok := ctx.(*gin.Context); ok && c.Request != nil {
+		return c.Request.Context()
+	}
+	return ctx
+}
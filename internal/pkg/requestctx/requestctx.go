@@ -0,0 +1,127 @@
+// Package requestctx carries request-scoped values, such as the
+// authenticated user and the request ID, through a context.Context. Keys are
+// unexported types, so values cannot collide with other packages' or be set
+// with the wrong type:
+//
+//	ctx = requestctx.WithRequestID(ctx, id)
+//	id := requestctx.RequestIDFrom(ctx)
+//
+// Getters return a zero value when nothing was set. They also accept a
+// *gin.Context and read the request's context, which gin's own Value only
+// does when the engine has ContextWithFallback set; see Update for storing
+// values from middleware.
+package requestctx
+
+import (
+	"context"
+
+	"BackofficeGoService/internal/pkg/logger"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type (
+	userKey      struct{}
+	requestIDKey struct{}
+	tenantKey    struct{}
+	loggerKey    struct{}
+	routeKey     struct{}
+)
+
+// User is the authenticated user a request is made by
+type User struct {
+	ID    string
+	Email string
+	Role  string
+
+	// ImpersonatorID is the admin acting as the user, empty unless impersonating
+	ImpersonatorID string
+}
+
+// WithUser returns a context carrying the authenticated user
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFrom returns the user set by WithUser, reporting whether there was one
+func UserFrom(ctx context.Context) (User, bool) {
+	user, ok := requestContext(ctx).Value(userKey{}).(User)
+	return user, ok
+}
+
+// WithRequestID returns a context carrying the request's ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the ID set by WithRequestID, or ""
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := requestContext(ctx).Value(requestIDKey{}).(string)
+	return id
+}
+
+// Route is the endpoint a request was routed to: its method and the route
+// template, such as "/api/v1/users/:id", rather than the path requested
+type Route struct {
+	Method   string
+	Template string
+}
+
+// WithRoute returns a context carrying the request's route
+func WithRoute(ctx context.Context, route Route) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+// RouteFrom returns the route set by WithRoute, reporting whether there was one
+func RouteFrom(ctx context.Context) (Route, bool) {
+	route, ok := requestContext(ctx).Value(routeKey{}).(Route)
+	return route, ok
+}
+
+// WithTenant returns a context carrying the tenant the request is made for.
+// database.WithTenant sets it along with the tenant's database.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// TenantFrom returns the tenant set by WithTenant, or "" when none applies
+func TenantFrom(ctx context.Context) string {
+	id, _ := requestContext(ctx).Value(tenantKey{}).(string)
+	return id
+}
+
+// WithLogger returns a context carrying a logger for the request, usually
+// one that adds the request ID to every entry
+func WithLogger(ctx context.Context, log logger.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, log)
+}
+
+// LoggerFrom returns the logger set by WithLogger, or one that discards
+// everything, so callers never check for nil. Like LoggerOr, it adds the
+// active span's IDs.
+func LoggerFrom(ctx context.Context) logger.Logger {
+	return LoggerOr(ctx, logger.NewNopLogger())
+}
+
+// LoggerOr returns the logger set by WithLogger, or fallback. It suits code
+// with a logger of its own, such as a service, that should prefer the
+// request's when called on behalf of one.
+//
+// When ctx carries an OpenTelemetry span, every entry also gets its
+// trace_id and span_id, hex encoded as in W3C traceparent headers, so log
+// entries can be found from a trace. Without a span neither field is added.
+func LoggerOr(ctx context.Context, fallback logger.Logger) logger.Logger {
+	log := fallback
+	if l, ok := requestContext(ctx).Value(loggerKey{}).(logger.Logger); ok {
+		log = l
+	}
+
+	span := trace.SpanContextFromContext(requestContext(ctx))
+	if !span.IsValid() {
+		return log
+	}
+	return log.With(
+		logger.Field{Key: "trace_id", Value: span.TraceID().String()},
+		logger.Field{Key: "span_id", Value: span.SpanID().String()},
+	)
+}
@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/services"
 )
 
 // Service is the base interface for all services
@@ -13,43 +16,77 @@ type Service interface {
 // UserService defines the interface for user business logic
 type UserService interface {
 	Service
-	
-	// CreateUser creates a new user
-	CreateUser(ctx context.Context, req interface{}) (interface{}, error)
-	
+
+	// CreateUser creates a new user on behalf of actorID
+	CreateUser(ctx context.Context, req *services.CreateUserRequest, actorID string) (*models.User, error)
+
 	// GetUser retrieves a user by ID
-	GetUser(ctx context.Context, id string) (interface{}, error)
-	
+	GetUser(ctx context.Context, id string) (*models.User, error)
+
 	// GetUserByEmail retrieves a user by email
-	GetUserByEmail(ctx context.Context, email string) (interface{}, error)
-	
-	// UpdateUser updates an existing user
-	UpdateUser(ctx context.Context, id string, req interface{}) (interface{}, error)
-	
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+
+	// GetUsersByIDs retrieves several users at once, skipping unknown IDs
+	GetUsersByIDs(ctx context.Context, ids []string) ([]*models.User, error)
+
+	// UpdateUser applies a partial update to an existing user on behalf of actorID
+	UpdateUser(ctx context.Context, id string, req *services.UpdateUserRequest, actorID string) (*models.User, error)
+
 	// DeleteUser deletes a user by ID
 	DeleteUser(ctx context.Context, id string) error
-	
-	// ListUsers retrieves a list of users
-	ListUsers(ctx context.Context, limit, offset int) ([]interface{}, error)
+
+	// ListUsers retrieves a page of users
+	ListUsers(ctx context.Context, filter services.ListUsersFilter, limit, offset int) (*services.ListResult[*models.User], error)
+
+	// SearchUsers retrieves a page of users matching a free-text query, most relevant first
+	SearchUsers(ctx context.Context, filter services.SearchUsersFilter, limit, offset int) (*services.ListResult[*services.UserSearchResult], error)
+
+	// SetActive activates or deactivates a user on behalf of actorID
+	SetActive(ctx context.Context, id string, active bool, actorID string) (*models.User, error)
+
+	// ChangeRole gives a user a role on behalf of actorID, or returns the
+	// approval the change awaits
+	ChangeRole(ctx context.Context, id string, role models.UserRole, actorID string) (*models.User, *models.Approval, error)
+
+	// RequirePasswordChange forces a user to change their password at the next login
+	RequirePasswordChange(ctx context.Context, id string, actorID string) (*models.User, error)
+
+	// ExportUserData builds the data-subject export of a user
+	ExportUserData(ctx context.Context, id string, actorID string) (*services.UserDataExport, error)
+
+	// AnonymizeUser irreversibly scrubs a user's personal data
+	AnonymizeUser(ctx context.Context, id string, actorID string) (*models.User, error)
 }
 
 // AuthService defines the interface for authentication business logic
 type AuthService interface {
 	Service
-	
+
 	// Login authenticates a user and returns a token
-	Login(ctx context.Context, email, password string) (interface{}, error)
-	
+	Login(ctx context.Context, email, password string, client services.ClientInfo) (*services.AuthResult, error)
+
 	// Register registers a new user
-	Register(ctx context.Context, req interface{}) (interface{}, error)
-	
+	Register(ctx context.Context, req *services.CreateUserRequest) (*models.User, error)
+
 	// ValidateToken validates a JWT token
-	ValidateToken(ctx context.Context, token string) (interface{}, error)
-	
+	ValidateToken(ctx context.Context, token string) (*services.TokenClaims, error)
+
 	// RefreshToken refreshes an access token
-	RefreshToken(ctx context.Context, refreshToken string) (interface{}, error)
-	
-	// Logout logs out a user
-	Logout(ctx context.Context, token string) error
+	RefreshToken(ctx context.Context, refreshToken string) (*services.AuthResult, error)
+
+	// ChangePassword replaces the password of the token's user and returns an unrestricted token
+	ChangePassword(ctx context.Context, claims *services.TokenClaims, currentPassword, newPassword string) (*services.AuthResult, error)
+
+	// CompleteProfile reports how complete the token's user's profile is, with
+	// an unrestricted token once a profile the token was restricted to completing is
+	CompleteProfile(ctx context.Context, claims *services.TokenClaims) (*services.AuthResult, error)
+
+	// Logout ends the session of the token claims were read from
+	Logout(ctx context.Context, claims *services.TokenClaims) error
 }
 
+// The concrete services must keep satisfying the interfaces
+var (
+	_ UserService = (*services.UserService)(nil)
+	_ AuthService = (*services.AuthService)(nil)
+)
@@ -0,0 +1,532 @@
+// Package servicetest provides in-memory implementations of the service
+// interfaces for controller tests that should not need a database:
+//
+//	users := servicetest.NewUserService(existing)
+//	auth := servicetest.NewAuthService(users)
+//	token := auth.IssueToken(&services.TokenClaims{UserID: admin.ID.String(), Role: "admin"})
+//	controller := user.NewUserController(users, pagination.DefaultConfig)
+//
+// The fakes return the same sentinel errors as the real services. Setting
+// Err makes every call fail with it.
+package servicetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/service"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+var (
+	_ service.UserService = (*UserService)(nil)
+	_ service.AuthService = (*AuthService)(nil)
+)
+
+// UserService is an in-memory service.UserService. Users are listed in the
+// order they were added.
+type UserService struct {
+	// Err, when set, is returned by every method
+	Err error
+
+	mu    sync.Mutex
+	users []*models.User
+}
+
+// NewUserService creates a fake user service holding users
+func NewUserService(users ...*models.User) *UserService {
+	s := &UserService{}
+	for _, user := range users {
+		s.Add(user)
+	}
+	return s
+}
+
+// Add stores a copy of user, assigning an ID if it has none
+func (s *UserService) Add(user *models.User) *models.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *user
+	if stored.ID == uuid.Nil {
+		stored.ID = uuid.New()
+	}
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+		stored.UpdatedAt = stored.CreatedAt
+	}
+	s.users = append(s.users, &stored)
+	return copyUser(&stored)
+}
+
+func (s *UserService) Health(ctx context.Context) error {
+	return s.Err
+}
+
+func (s *UserService) CreateUser(ctx context.Context, req *services.CreateUserRequest, actorID string) (*models.User, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	if req.Email == "" {
+		return nil, errors.New("email is required")
+	}
+	if _, err := s.GetUserByEmail(ctx, req.Email); err == nil {
+		return nil, services.ErrEmailTaken
+	}
+
+	role := models.RoleUser
+	if req.Role != "" {
+		role = req.Role
+	}
+	return s.Add(&models.User{
+		Email:     req.Email,
+		Username:  req.Username,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Role:      role,
+		Active:    true,
+	}), nil
+}
+
+func (s *UserService) GetUser(ctx context.Context, id string) (*models.User, error) {
+	return s.find(func(u *models.User) bool { return u.ID.String() == id })
+}
+
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.find(func(u *models.User) bool { return u.Email == email })
+}
+
+func (s *UserService) GetUsersByIDs(ctx context.Context, ids []string) ([]*models.User, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []*models.User{}
+	for _, user := range s.users {
+		if wanted[user.ID.String()] {
+			users = append(users, copyUser(user))
+		}
+	}
+	return users, nil
+}
+
+func (s *UserService) UpdateUser(ctx context.Context, id string, req *services.UpdateUserRequest, actorID string) (*models.User, error) {
+	return s.update(id, func(user *models.User) error {
+		if req.Username != nil {
+			user.Username = *req.Username
+		}
+		if req.FirstName != nil {
+			user.FirstName = *req.FirstName
+		}
+		if req.LastName != nil {
+			user.LastName = *req.LastName
+		}
+		return nil
+	})
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, id string) error {
+	if s.Err != nil {
+		return s.Err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, user := range s.users {
+		if user.ID.String() == id {
+			s.users = append(s.users[:i], s.users[i+1:]...)
+			return nil
+		}
+	}
+	return services.ErrUserNotFound
+}
+
+func (s *UserService) ListUsers(ctx context.Context, filter services.ListUsersFilter, limit, offset int) (*services.ListResult[*models.User], error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := &services.ListResult[*models.User]{Items: []*models.User{}}
+	for _, user := range s.users {
+		if user.AnonymizedAt != nil && !filter.IncludeAnonymized {
+			continue
+		}
+		if (filter.Role != "" && user.Role != filter.Role) || (filter.Active != nil && user.Active != *filter.Active) {
+			continue
+		}
+		if (filter.CreatedAfter != nil && user.CreatedAt.Before(*filter.CreatedAfter)) || (filter.CreatedBefore != nil && !user.CreatedAt.Before(*filter.CreatedBefore)) {
+			continue
+		}
+		if result.Total >= int64(offset) && len(result.Items) < limit {
+			result.Items = append(result.Items, copyUser(user))
+		}
+		result.Total++
+	}
+	return result, nil
+}
+
+// SearchUsers matches users containing every whitespace-separated term in
+// their email, username or names. Every match scores 1.
+func (s *UserService) SearchUsers(ctx context.Context, filter services.SearchUsersFilter, limit, offset int) (*services.ListResult[*services.UserSearchResult], error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	terms := strings.Fields(strings.ToLower(filter.Query))
+	if len(terms) == 0 {
+		return nil, services.ErrEmptySearchQuery
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := &services.ListResult[*services.UserSearchResult]{Items: []*services.UserSearchResult{}}
+	for _, user := range s.users {
+		if user.AnonymizedAt != nil && !filter.IncludeAnonymized {
+			continue
+		}
+		if filter.Active != nil && user.Active != *filter.Active {
+			continue
+		}
+		text := strings.ToLower(strings.Join([]string{user.Email, user.Username, user.FirstName, user.LastName}, " "))
+		matched := true
+		for _, term := range terms {
+			matched = matched && strings.Contains(text, term)
+		}
+		if !matched {
+			continue
+		}
+		if result.Total >= int64(offset) && len(result.Items) < limit {
+			result.Items = append(result.Items, &services.UserSearchResult{User: copyUser(user), Score: 1})
+		}
+		result.Total++
+	}
+	return result, nil
+}
+
+// SetActive applies the same guards as the real service
+func (s *UserService) SetActive(ctx context.Context, id string, active bool, actorID string) (*models.User, error) {
+	return s.update(id, func(user *models.User) error {
+		if err := services.CheckActiveChange(actorID, user, active, s.activeAdmins()); err != nil {
+			return err
+		}
+		user.Active = active
+		return nil
+	})
+}
+
+// ChangeRole applies every change at once; approvals need the real service
+func (s *UserService) ChangeRole(ctx context.Context, id string, role models.UserRole, actorID string) (*models.User, *models.Approval, error) {
+	if !role.Valid() {
+		return nil, nil, services.ErrInvalidRole
+	}
+	user, err := s.update(id, func(user *models.User) error {
+		user.Role = role
+		return nil
+	})
+	return user, nil, err
+}
+
+func (s *UserService) RequirePasswordChange(ctx context.Context, id string, actorID string) (*models.User, error) {
+	return s.update(id, func(user *models.User) error {
+		user.MustChangePassword = true
+		return nil
+	})
+}
+
+func (s *UserService) ExportUserData(ctx context.Context, id string, actorID string) (*services.UserDataExport, error) {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &services.UserDataExport{
+		User:        user,
+		LoginEvents: []*models.LoginEvent{},
+		AuditLogs:   []*models.AuditLog{},
+		ExportedAt:  time.Now(),
+	}, nil
+}
+
+// AnonymizeUser applies the same guards as SetActive deactivating the user
+func (s *UserService) AnonymizeUser(ctx context.Context, id string, actorID string) (*models.User, error) {
+	return s.update(id, func(user *models.User) error {
+		if user.AnonymizedAt == nil {
+			if err := services.CheckActiveChange(actorID, user, false, s.activeAdmins()); err != nil {
+				return err
+			}
+		}
+		services.AnonymizeUserFields(user, time.Now())
+		return nil
+	})
+}
+
+// find returns a copy of the first user matching match
+func (s *UserService) find(match func(u *models.User) bool) (*models.User, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if match(user) {
+			return copyUser(user), nil
+		}
+	}
+	return nil, services.ErrUserNotFound
+}
+
+// update applies change to the stored user and returns a copy of the result
+func (s *UserService) update(id string, change func(user *models.User) error) (*models.User, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.ID.String() != id {
+			continue
+		}
+		updated := *user
+		if err := change(&updated); err != nil {
+			return nil, err
+		}
+		updated.UpdatedAt = time.Now()
+		*user = updated
+		return copyUser(user), nil
+	}
+	return nil, services.ErrUserNotFound
+}
+
+// activeAdmins counts active admins; the caller holds s.mu
+func (s *UserService) activeAdmins() int64 {
+	var count int64
+	for _, user := range s.users {
+		if user.Role == models.RoleAdmin && user.Active {
+			count++
+		}
+	}
+	return count
+}
+
+// copyUser returns a copy of user without its password
+func copyUser(user *models.User) *models.User {
+	c := *user
+	c.Password = ""
+	return &c
+}
+
+// AuthService is an in-memory service.AuthService. Tokens are opaque
+// strings mapped to their claims; passwords are stored in plain text.
+type AuthService struct {
+	// Err, when set, is returned by every method
+	Err error
+
+	users     *UserService
+	mu        sync.Mutex
+	tokens    map[string]*services.TokenClaims
+	passwords map[string]string
+	issued    int
+}
+
+// NewAuthService creates a fake auth service that logs in the users of users
+func NewAuthService(users *UserService) *AuthService {
+	return &AuthService{
+		users:     users,
+		tokens:    make(map[string]*services.TokenClaims),
+		passwords: make(map[string]string),
+	}
+}
+
+// SetPassword sets the password Login accepts for email
+func (s *AuthService) SetPassword(email, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.passwords[email] = password
+}
+
+// IssueToken returns a token that validates to claims
+func (s *AuthService) IssueToken(claims *services.TokenClaims) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.issued++
+	token := fmt.Sprintf("servicetest-token-%d", s.issued)
+	stored := *claims
+	if stored.IssuedAt.IsZero() {
+		stored.IssuedAt = time.Now()
+	}
+	s.tokens[token] = &stored
+	return token
+}
+
+func (s *AuthService) Health(ctx context.Context) error {
+	return s.Err
+}
+
+func (s *AuthService) Login(ctx context.Context, email, password string, client services.ClientInfo) (*services.AuthResult, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, errors.New("invalid credentials")
+	}
+
+	s.mu.Lock()
+	stored, ok := s.passwords[email]
+	s.mu.Unlock()
+	if !ok || stored != password {
+		return nil, errors.New("invalid credentials")
+	}
+	if !user.Active {
+		return nil, services.ErrAccountDeactivated
+	}
+
+	claims := &services.TokenClaims{UserID: user.ID.String(), Email: user.Email, Role: string(user.Role)}
+	if user.MustChangePassword {
+		claims.Scope = services.ScopePasswordChange
+	}
+	token := s.IssueToken(claims)
+	return &services.AuthResult{Token: token, User: user, PasswordExpired: claims.Restricted()}, nil
+}
+
+func (s *AuthService) Register(ctx context.Context, req *services.CreateUserRequest) (*models.User, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	if req.Email == "" || req.Password == "" {
+		return nil, errors.New("email and password are required")
+	}
+
+	user, err := s.users.CreateUser(ctx, req, "")
+	if err != nil {
+		return nil, err
+	}
+	s.SetPassword(req.Email, req.Password)
+	return user, nil
+}
+
+func (s *AuthService) ValidateToken(ctx context.Context, token string) (*services.TokenClaims, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	claims, ok := s.tokens[token]
+	if !ok {
+		return nil, services.ErrInvalidToken
+	}
+	copied := *claims
+	return &copied, nil
+}
+
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*services.AuthResult, error) {
+	claims, err := s.ValidateToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	claims.IssuedAt = time.Time{}
+	return &services.AuthResult{Token: s.IssueToken(claims)}, nil
+}
+
+// ChangePassword checks the stored password, clears any forced change and
+// issues an unrestricted token
+func (s *AuthService) ChangePassword(ctx context.Context, claims *services.TokenClaims, currentPassword, newPassword string) (*services.AuthResult, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	if claims.Impersonating() {
+		return nil, services.ErrImpersonationForbidden
+	}
+
+	user, err := s.users.GetUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	stored, ok := s.passwords[user.Email]
+	if !ok || stored != currentPassword {
+		s.mu.Unlock()
+		return nil, services.ErrInvalidCurrentPassword
+	}
+	if newPassword == currentPassword {
+		s.mu.Unlock()
+		return nil, services.ErrPasswordReused
+	}
+	s.passwords[user.Email] = newPassword
+	s.mu.Unlock()
+
+	user, err = s.users.update(user.ID.String(), func(user *models.User) error {
+		now := time.Now()
+		user.PasswordChangedAt = &now
+		user.MustChangePassword = false
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	token := s.IssueToken(&services.TokenClaims{UserID: user.ID.String(), Email: user.Email, Role: string(user.Role)})
+	return &services.AuthResult{Token: token, User: user}, nil
+}
+
+// CompleteProfile requires no profile fields, so tokens restricted to
+// completing the profile are exchanged for unrestricted ones
+func (s *AuthService) CompleteProfile(ctx context.Context, claims *services.TokenClaims) (*services.AuthResult, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	user, err := s.users.GetUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	result := &services.AuthResult{User: user, ProfileStatus: &services.ProfileStatus{Complete: true, Missing: []string{}}}
+	if claims.Scope == services.ScopeProfileCompletion {
+		result.Token = s.IssueToken(&services.TokenClaims{UserID: user.ID.String(), Email: user.Email, Role: string(user.Role)})
+	}
+	return result, nil
+}
+
+// Logout invalidates the tokens of claims' session, or the token claims
+// were issued for when it has none
+func (s *AuthService) Logout(ctx context.Context, claims *services.TokenClaims) error {
+	if s.Err != nil {
+		return s.Err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, stored := range s.tokens {
+		sameSession := claims.SessionID != "" && stored.SessionID == claims.SessionID
+		sameToken := claims.SessionID == "" && stored.UserID == claims.UserID && stored.IssuedAt.Equal(claims.IssuedAt)
+		if sameSession || sameToken {
+			delete(s.tokens, token)
+		}
+	}
+	return nil
+}
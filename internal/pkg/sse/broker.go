@@ -0,0 +1,185 @@
+// Package sse fans realtime events out to Server-Sent Events connections.
+package sse
+
+import (
+	"encoding/json"
+	"sync"
+
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+// Message is a single event sent to a client
+type Message struct {
+	Event string
+	Data  []byte
+}
+
+// Client is one open stream. Its channel is closed when the client is
+// unsubscribed, evicted for falling behind, or the broker shuts down.
+type Client struct {
+	UserID string
+	Role   string
+
+	messages chan Message
+}
+
+// Messages returns the client's pending messages
+func (c *Client) Messages() <-chan Message {
+	return c.messages
+}
+
+// Broker routes messages to the open streams of each user
+type Broker struct {
+	bufferSize int
+	logger     logger.Logger
+
+	mu      sync.Mutex
+	clients map[string]map[*Client]struct{}
+	closed  bool
+}
+
+// NewBroker creates a broker; each client buffers up to bufferSize messages
+// before it is considered too slow and evicted
+func NewBroker(bufferSize int, log logger.Logger) *Broker {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	return &Broker{
+		bufferSize: bufferSize,
+		logger:     log,
+		clients:    make(map[string]map[*Client]struct{}),
+	}
+}
+
+// Subscribe registers a new stream for a user. After Close the returned
+// client's channel is already closed.
+func (b *Broker) Subscribe(userID, role string) *Client {
+	client := &Client{
+		UserID:   userID,
+		Role:     role,
+		messages: make(chan Message, b.bufferSize),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(client.messages)
+		return client
+	}
+	if b.clients[userID] == nil {
+		b.clients[userID] = make(map[*Client]struct{})
+	}
+	b.clients[userID][client] = struct{}{}
+	return client
+}
+
+// Unsubscribe removes a stream; it is safe to call more than once
+func (b *Broker) Unsubscribe(client *Client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(client)
+}
+
+// remove drops a client and closes its channel. b.mu must be held.
+func (b *Broker) remove(client *Client) {
+	clients, ok := b.clients[client.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := clients[client]; !ok {
+		return
+	}
+
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(b.clients, client.UserID)
+	}
+	close(client.messages)
+}
+
+// Publish sends an event to every stream of userID
+func (b *Broker) Publish(userID, event string, data interface{}) {
+	b.PublishFunc(func(client *Client) bool { return client.UserID == userID }, event, data)
+}
+
+// PublishFunc sends an event to every stream for which match returns true.
+// match runs without the broker lock held, so it may do slow lookups.
+func (b *Broker) PublishFunc(match func(*Client) bool, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		b.logger.Error("Failed to encode stream event", logger.Field{Key: "event", Value: event}, logger.Field{Key: "error", Value: err.Error()})
+		return
+	}
+	msg := Message{Event: event, Data: payload}
+
+	var targets []*Client
+	for _, client := range b.snapshot() {
+		if match(client) {
+			targets = append(targets, client)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, client := range targets {
+		// Skip clients that went away while match was running
+		if _, ok := b.clients[client.UserID][client]; !ok {
+			continue
+		}
+		select {
+		case client.messages <- msg:
+		default:
+			// A client that cannot keep up is dropped rather than
+			// blocking delivery to everyone else; it can reconnect.
+			b.logger.Warn("Evicting slow stream client", logger.Field{Key: "user_id", Value: client.UserID}, logger.Field{Key: "event", Value: event})
+			b.remove(client)
+		}
+	}
+}
+
+// snapshot returns every open stream
+func (b *Broker) snapshot() []*Client {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var clients []*Client
+	for _, userClients := range b.clients {
+		for client := range userClients {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// ClientCount returns the number of open streams
+func (b *Broker) ClientCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := 0
+	for _, clients := range b.clients {
+		count += len(clients)
+	}
+	return count
+}
+
+// Close disconnects every stream and rejects new ones
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for _, clients := range b.clients {
+		for client := range clients {
+			b.remove(client)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package sse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// WriteMessage writes msg in text/event-stream framing. Each line of the
+// payload gets its own data field so embedded newlines survive.
+func WriteMessage(w io.Writer, msg Message) error {
+	var buf bytes.Buffer
+	if msg.Event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", msg.Event)
+	}
+	for _, line := range bytes.Split(msg.Data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteComment writes a comment line, used as a heartbeat to keep proxies
+// from closing idle streams
+func WriteComment(w io.Writer, comment string) error {
+	_, err := fmt.Fprintf(w, ": %s\n\n", comment)
+	return err
+}
@@ -0,0 +1,22 @@
+package validator
+
+import (
+	"strings"
+
+	"BackofficeGoService/internal/pkg/i18n"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// registerRules adds the service's own rules to the shared instance
+func registerRules() {
+	// Panics only on an empty rule name, so a failure is a programming error
+	if err := RegisterRule("notblank", notBlank, i18n.ValidationNotBlank); err != nil {
+		panic(err)
+	}
+}
+
+// notBlank fails strings made of whitespace only, which required lets through
+func notBlank(fl validator.FieldLevel) bool {
+	return strings.TrimSpace(fl.Field().String()) != ""
+}
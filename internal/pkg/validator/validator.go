@@ -1,13 +1,50 @@
 package validator
 
 import (
+	stderrors "errors"
+	"reflect"
+	"strings"
+
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 )
 
+// validate is the one instance behind Validate, Var and gin's request
+// binding, so rules registered with RegisterRule apply everywhere. Struct
+// rules are read from binding tags.
 var validate *validator.Validate
 
+// ruleMessages maps rules added with RegisterRule to their messages
+var ruleMessages = map[string]string{}
+
 func init() {
 	validate = validator.New()
+	validate.SetTagName("binding")
+	useJSONNames(validate)
+	registerRules()
+
+	binding.Validator = ginValidator{}
+}
+
+// useJSONNames makes v report fields by their json tag instead of the Go
+// name, or by their form or uri tag for fields bound from the query string
+// or path
+func useJSONNames(v *validator.Validate) {
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri"} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
 }
 
 // Validate validates a struct using the validator
@@ -15,8 +52,138 @@ func Validate(s interface{}) error {
 	return validate.Struct(s)
 }
 
-// GetValidator returns the validator instance
+// GetValidator returns the validator instance, which is also gin's binding
+// engine
 func GetValidator() *validator.Validate {
 	return validate
 }
 
+// RegisterRule adds a rule usable in binding tags and Var, reported with the
+// i18n message key when it fails. Rules are registered during startup,
+// before requests are served.
+func RegisterRule(rule string, fn validator.Func, message string) error {
+	if err := validate.RegisterValidation(rule, fn); err != nil {
+		return err
+	}
+	ruleMessages[rule] = message
+	return nil
+}
+
+// ruleKey returns the message for a failed rule
+func ruleKey(rule string) string {
+	if key, ok := ruleMessages[rule]; ok {
+		return key
+	}
+	return i18n.RuleKey(rule)
+}
+
+// ginValidator hands gin's request binding to the shared instance. Like
+// gin's own, it validates structs and each element of slices and skips
+// anything else.
+type ginValidator struct{}
+
+func (v ginValidator) ValidateStruct(obj any) error {
+	value := reflect.ValueOf(obj)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return validate.Struct(obj)
+	case reflect.Slice, reflect.Array:
+		var failed binding.SliceValidationError
+		for i := 0; i < value.Len(); i++ {
+			if err := v.ValidateStruct(value.Index(i).Interface()); err != nil {
+				failed = append(failed, err)
+			}
+		}
+		if len(failed) == 0 {
+			return nil
+		}
+		return failed
+	default:
+		return nil
+	}
+}
+
+func (ginValidator) Engine() any {
+	return validate
+}
+
+// FieldError is a validation rule one field failed, such as "email" or "min"
+type FieldError struct {
+	Field string
+	Rule  string
+	Param string
+}
+
+// Errors lists the rules a request failed
+type Errors []FieldError
+
+// Error implements the error interface with the English messages
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = i18n.Translate(i18n.DefaultLocale, ruleKey(fe.Rule), fe.params())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (fe FieldError) params() errors.Params {
+	return errors.Params{"field": fe.Field, "param": fe.Param}
+}
+
+// Var validates a single value against tag and reports failures under field
+func Var(field string, value interface{}, tag string) Errors {
+	failed, _ := FieldErrors(validate.Var(value, tag))
+	for i := range failed {
+		failed[i].Field = field
+	}
+	return failed
+}
+
+// FieldErrors returns the rule failures in err, which may come from Validate,
+// Var or request binding. ok is false when err is not a validation error.
+func FieldErrors(err error) (failed Errors, ok bool) {
+	if stderrors.As(err, &failed) {
+		return failed, true
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !stderrors.As(err, &validationErrs) {
+		return nil, false
+	}
+	for _, fe := range validationErrs {
+		failed = append(failed, FieldError{Field: fieldPath(fe), Rule: fe.Tag(), Param: fe.Param()})
+	}
+	return failed, true
+}
+
+// fieldPath names the field fe failed on by its JSON path, such as
+// "address.city" for a nested struct, without the top-level type's name
+func fieldPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
+
+// Details maps the rule failures in err onto translatable error details
+func Details(err error) []errors.Detail {
+	failed, _ := FieldErrors(err)
+	details := make([]errors.Detail, len(failed))
+	for i, fe := range failed {
+		details[i] = errors.Detail{Field: fe.Field, Message: ruleKey(fe.Rule), Params: fe.params()}
+	}
+	return details
+}
+
+// NewAppError reports a request that failed binding or validation, listing
+// the failed fields as details
+func NewAppError(err error) *errors.AppError {
+	return errors.NewValidationError(i18n.ValidationFailed, err).WithDetails(Details(err)...)
+}
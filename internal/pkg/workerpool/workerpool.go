@@ -0,0 +1,322 @@
+// Package workerpool runs tasks on a bounded set of goroutines, so work
+// offloaded from requests, such as calls to slow services or CPU-heavy
+// processing, cannot grow without limit:
+//
+//	pool := workerpool.New("default", workerpool.Config{Size: 4, QueueDepth: 100}, log)
+//	future, err := workerpool.Submit(ctx, pool, func(ctx context.Context) (int, error) {
+//		return lookup(ctx)
+//	})
+//	if err != nil {
+//		return err // ErrSaturated or ErrStopped
+//	}
+//	count, err := future.Wait(ctx)
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Mode is what Submit does while the queue is full
+type Mode string
+
+const (
+	// ModeReject fails the submission with ErrSaturated
+	ModeReject Mode = "reject"
+	// ModeBlock waits for room in the queue until the context ends
+	ModeBlock Mode = "block"
+)
+
+var (
+	// ErrSaturated is returned by Submit in ModeReject while the queue is full
+	ErrSaturated = errors.New("workerpool: queue is full")
+	// ErrStopped is returned by Submit once the pool is stopping
+	ErrStopped = errors.New("workerpool: pool is stopped")
+)
+
+// PanicError is the error of a task that panicked. The panic is contained
+// in the task; the worker carries on with the next one.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("workerpool: task panicked: %v", e.Value)
+}
+
+// Config sizes a pool
+type Config struct {
+	// Size is how many tasks run at once; at least one
+	Size int
+
+	// QueueDepth is how many submitted tasks may wait for a worker
+	QueueDepth int
+
+	// Mode is what Submit does while the queue is full; ModeReject by default
+	Mode Mode
+}
+
+// Metrics are the collectors a pool reports to, labelled by pool name
+type Metrics struct {
+	Queued   *prometheus.GaugeVec
+	InFlight *prometheus.GaugeVec
+	Rejected *prometheus.CounterVec
+}
+
+// Option configures a Pool
+type Option func(p *Pool)
+
+// WithMetrics reports the queue depth, the running tasks and the rejected
+// submissions to m
+func WithMetrics(m Metrics) Option {
+	return func(p *Pool) {
+		p.queuedGauge = m.Queued.WithLabelValues(p.name)
+		p.inFlightGauge = m.InFlight.WithLabelValues(p.name)
+		p.rejected = m.Rejected.WithLabelValues(p.name)
+	}
+}
+
+// job is a submitted task bound to its submitter's context. skip ends it
+// with err instead of running it.
+type job struct {
+	ctx  context.Context
+	run  func(ctx context.Context)
+	skip func(err error)
+}
+
+// Pool runs submitted tasks on a fixed number of workers, in submission
+// order. Its workers start with New; Stop drains them.
+type Pool struct {
+	name   string
+	mode   Mode
+	logger logger.Logger
+
+	// slots holds a token for every queued or running task, so the queue
+	// never takes more than QueueDepth tasks beyond those running
+	slots    chan struct{}
+	queue    chan job
+	stopping chan struct{}
+	stopOnce sync.Once
+	mu       sync.RWMutex
+	stopped  bool
+	wg       sync.WaitGroup
+
+	queued   atomic.Int64
+	inFlight atomic.Int64
+
+	queuedGauge   prometheus.Gauge
+	inFlightGauge prometheus.Gauge
+	rejected      prometheus.Counter
+}
+
+// New starts a pool of cfg.Size workers named name in logs and metrics
+func New(name string, cfg Config, log logger.Logger, opts ...Option) *Pool {
+	if cfg.Size < 1 {
+		cfg.Size = 1
+	}
+	if cfg.QueueDepth < 0 {
+		cfg.QueueDepth = 0
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = ModeReject
+	}
+
+	p := &Pool{
+		name:     name,
+		mode:     cfg.Mode,
+		logger:   log,
+		slots:    make(chan struct{}, cfg.Size+cfg.QueueDepth),
+		queue:    make(chan job, cfg.Size+cfg.QueueDepth),
+		stopping: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.wg.Add(cfg.Size)
+	for i := 0; i < cfg.Size; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Future is the pending result of a submitted task
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// Done is closed once the task has finished
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait returns the task's result once it has finished, or ctx's error if
+// ctx ends first. A task that panicked returns a *PanicError.
+func (f *Future[T]) Wait(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Submit queues task on p and returns its future. The task runs with ctx,
+// and is skipped with ctx's error if ctx ends while it waits. While the
+// queue is full Submit fails with ErrSaturated, or in ModeBlock waits for
+// room until ctx ends.
+func Submit[T any](ctx context.Context, p *Pool, task func(ctx context.Context) (T, error)) (*Future[T], error) {
+	future := &Future[T]{done: make(chan struct{})}
+	j := job{
+		ctx: ctx,
+		run: func(ctx context.Context) {
+			defer close(future.done)
+			future.value, future.err = runTask(ctx, task)
+			var panicked *PanicError
+			if errors.As(future.err, &panicked) {
+				p.logger.Error("Worker pool task panicked",
+					logger.Field{Key: "pool", Value: p.name},
+					logger.Field{Key: "error", Value: fmt.Sprint(panicked.Value)},
+					logger.Field{Key: "stack", Value: string(panicked.Stack)},
+				)
+			}
+		},
+		skip: func(err error) {
+			future.err = err
+			close(future.done)
+		},
+	}
+	if err := p.enqueue(ctx, j); err != nil {
+		return nil, err
+	}
+	return future, nil
+}
+
+// runTask runs task, turning a panic into a *PanicError
+func runTask[T any](ctx context.Context, task func(ctx context.Context) (T, error)) (value T, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = &PanicError{Value: recovered, Stack: debug.Stack()}
+		}
+	}()
+	return task(ctx)
+}
+
+// enqueue hands j to the workers once it holds a slot, waiting for one
+// only in ModeBlock
+func (p *Pool) enqueue(ctx context.Context, j job) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return ErrStopped
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		if p.mode != ModeBlock {
+			if p.rejected != nil {
+				p.rejected.Inc()
+			}
+			return ErrSaturated
+		}
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.stopping:
+			return ErrStopped
+		}
+	}
+
+	// Never blocks: the queue has room for every slot
+	p.setQueued(p.queued.Add(1))
+	p.queue <- j
+	return nil
+}
+
+// work runs queued jobs until the queue is closed and empty
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for j := range p.queue {
+		p.setQueued(p.queued.Add(-1))
+		p.setInFlight(p.inFlight.Add(1))
+		p.runJob(j)
+		p.setInFlight(p.inFlight.Add(-1))
+		<-p.slots
+	}
+}
+
+// runJob runs j unless its submitter has gone away
+func (p *Pool) runJob(j job) {
+	if err := j.ctx.Err(); err != nil {
+		j.skip(err)
+		return
+	}
+	j.run(j.ctx)
+}
+
+// Stop stops accepting tasks and waits for the queued and running ones to
+// finish, in the order they were submitted, or for ctx to end. Submitters
+// blocked on a full queue get ErrStopped.
+func (p *Pool) Stop(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		// Wakes blocked submitters, which hold the read lock
+		close(p.stopping)
+		p.mu.Lock()
+		p.stopped = true
+		close(p.queue)
+		p.mu.Unlock()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		p.logger.Warn("Worker pool did not drain in time",
+			logger.Field{Key: "pool", Value: p.name},
+			logger.Field{Key: "queued", Value: p.queued.Load()},
+			logger.Field{Key: "in_flight", Value: p.inFlight.Load()},
+		)
+		return ctx.Err()
+	}
+}
+
+// Queued returns how many tasks wait for a worker
+func (p *Pool) Queued() int {
+	return int(p.queued.Load())
+}
+
+// InFlight returns how many tasks are running
+func (p *Pool) InFlight() int {
+	return int(p.inFlight.Load())
+}
+
+func (p *Pool) setQueued(n int64) {
+	if p.queuedGauge != nil {
+		p.queuedGauge.Set(float64(n))
+	}
+}
+
+func (p *Pool) setInFlight(n int64) {
+	if p.inFlightGauge != nil {
+		p.inFlightGauge.Set(float64(n))
+	}
+}
@@ -0,0 +1,408 @@
+// Package ws fans realtime events out to WebSocket connections. Clients pick
+// the topics they receive with a small JSON protocol:
+//
+//	-> {"type":"subscribe","topics":["user.created"]}
+//	<- {"type":"subscribed","topics":["user.created"]}
+//	<- {"type":"event","event":"user.created","data":{...}}
+//	<- {"type":"error","code":"SOCKET_TOPIC_FORBIDDEN","error":"..."}
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/gorilla/websocket"
+)
+
+// Message types of the protocol
+const (
+	TypeSubscribe  = "subscribe"
+	TypeSubscribed = "subscribed"
+	TypeEvent      = "event"
+	TypeError      = "error"
+)
+
+// CloseSlowConsumer is the close code sent to a connection evicted for
+// falling behind; the client may reconnect
+const CloseSlowConsumer = websocket.CloseTryAgainLater
+
+// maxMessageSize bounds the messages clients send; they only send subscriptions
+const maxMessageSize = 4096
+
+var (
+	// ErrUnknownTopic is returned by an Authorizer for a topic that is never published
+	ErrUnknownTopic = stderrors.New("unknown topic")
+	// ErrForbiddenTopic is returned by an Authorizer for a topic the client may not receive
+	ErrForbiddenTopic = stderrors.New("topic not allowed")
+)
+
+// Message is one frame of the protocol, sent as a JSON text message
+type Message struct {
+	Type   string          `json:"type"`
+	Topics []string        `json:"topics,omitempty"`
+	Event  string          `json:"event,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Code   errors.Code     `json:"code,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Authorizer decides whether client may subscribe to topic. It returns
+// ErrUnknownTopic, ErrForbiddenTopic or nil.
+type Authorizer func(client *Client, topic string) error
+
+// Options tunes a Hub
+type Options struct {
+	SendQueue    int           // Messages queued per connection before it is evicted as too slow
+	PingInterval time.Duration // Interval between pings; a connection silent for two intervals is closed
+	WriteTimeout time.Duration // Bound on writing one message or control frame
+}
+
+// Client is one open connection
+type Client struct {
+	UserID   string
+	Role     string
+	TenantID string
+
+	conn *websocket.Conn
+	send chan []byte
+
+	// quit is closed with closeCode and closeText set when the connection
+	// should end; the writer then sends the close frame
+	quit      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+	// readerDone is closed when the peer has gone or sent its close frame
+	readerDone chan struct{}
+
+	mu     sync.RWMutex
+	topics map[string]struct{}
+}
+
+// Subscribed reports whether the client receives topic
+func (c *Client) Subscribed(topic string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.topics[topic]
+	return ok
+}
+
+// close asks the writer to end the connection with a close frame
+func (c *Client) close(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.quit)
+	})
+}
+
+// Hub routes events to the open connections of each user
+type Hub struct {
+	opts      Options
+	authorize Authorizer
+	logger    logger.Logger
+
+	mu      sync.Mutex
+	clients map[string]map[*Client]struct{}
+	closed  bool
+	serving sync.WaitGroup
+}
+
+// NewHub creates a hub. authorize may be nil, in which case every topic may
+// be subscribed to.
+func NewHub(opts Options, authorize Authorizer, log logger.Logger) *Hub {
+	if opts.SendQueue < 1 {
+		opts.SendQueue = 1
+	}
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = 10 * time.Second
+	}
+	return &Hub{
+		opts:      opts,
+		authorize: authorize,
+		logger:    log,
+		clients:   make(map[string]map[*Client]struct{}),
+	}
+}
+
+// Serve runs an upgraded connection until it closes. After Stop the
+// connection is closed with a going-away frame right away.
+func (h *Hub) Serve(conn *websocket.Conn, userID, role, tenantID string) {
+	client := &Client{
+		UserID:     userID,
+		Role:       role,
+		TenantID:   tenantID,
+		conn:       conn,
+		send:       make(chan []byte, h.opts.SendQueue),
+		quit:       make(chan struct{}),
+		readerDone: make(chan struct{}),
+		topics:     make(map[string]struct{}),
+	}
+
+	if !h.add(client) {
+		deadline := time.Now().Add(h.opts.WriteTimeout)
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), deadline)
+		_ = conn.Close()
+		return
+	}
+	defer h.serving.Done()
+	defer h.remove(client)
+
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		h.write(client)
+	}()
+	h.read(client)
+	close(client.readerDone)
+	<-writerDone
+}
+
+// add registers a client unless the hub has stopped
+func (h *Hub) add(client *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+	if h.clients[client.UserID] == nil {
+		h.clients[client.UserID] = make(map[*Client]struct{})
+	}
+	h.clients[client.UserID][client] = struct{}{}
+	h.serving.Add(1)
+	return true
+}
+
+// remove drops a client; it is safe to call more than once
+func (h *Hub) remove(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients := h.clients[client.UserID]
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(h.clients, client.UserID)
+	}
+}
+
+// read handles the client's messages until the connection fails or the
+// peer closes it. Pongs extend the read deadline.
+func (h *Hub) read(client *Client) {
+	conn := client.conn
+	conn.SetReadLimit(maxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * h.opts.PingInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * h.opts.PingInterval))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			h.reply(client, Message{Type: TypeError, Code: errors.CodeSocketMessageInvalid, Error: "message is not valid JSON"})
+			continue
+		}
+
+		switch msg.Type {
+		case TypeSubscribe:
+			h.subscribe(client, msg.Topics)
+		default:
+			h.reply(client, Message{Type: TypeError, Code: errors.CodeSocketMessageInvalid, Error: "unknown message type " + msg.Type})
+		}
+	}
+}
+
+// subscribe adds the topics client may receive and acknowledges them.
+// Topics it may not receive are reported as errors.
+func (h *Hub) subscribe(client *Client, topics []string) {
+	if len(topics) == 0 {
+		h.reply(client, Message{Type: TypeError, Code: errors.CodeSocketMessageInvalid, Error: "subscribe needs at least one topic"})
+		return
+	}
+
+	var accepted []string
+	for _, topic := range topics {
+		if h.authorize != nil {
+			if err := h.authorize(client, topic); err != nil {
+				code := errors.CodeSocketTopicForbidden
+				if stderrors.Is(err, ErrUnknownTopic) {
+					code = errors.CodeSocketTopicUnknown
+				}
+				h.reply(client, Message{Type: TypeError, Code: code, Error: topic + ": " + err.Error()})
+				continue
+			}
+		}
+		accepted = append(accepted, topic)
+	}
+	if len(accepted) == 0 {
+		return
+	}
+
+	client.mu.Lock()
+	for _, topic := range accepted {
+		client.topics[topic] = struct{}{}
+	}
+	client.mu.Unlock()
+	h.reply(client, Message{Type: TypeSubscribed, Topics: accepted})
+}
+
+// write sends queued messages and pings until the client is closed or the
+// peer goes away, then closes the connection
+func (h *Hub) write(client *Client) {
+	conn := client.conn
+	defer conn.Close()
+
+	ticker := time.NewTicker(h.opts.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case payload := <-client.send:
+			_ = conn.SetWriteDeadline(time.Now().Add(h.opts.WriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.opts.WriteTimeout)); err != nil {
+				return
+			}
+		case <-client.readerDone:
+			return
+		case <-client.quit:
+			frame := websocket.FormatCloseMessage(client.closeCode, client.closeText)
+			if err := conn.WriteControl(websocket.CloseMessage, frame, time.Now().Add(h.opts.WriteTimeout)); err != nil {
+				return
+			}
+			// Give the peer a moment to answer the close frame, so it is
+			// not lost to a reset
+			timer := time.NewTimer(h.opts.WriteTimeout)
+			defer timer.Stop()
+			select {
+			case <-client.readerDone:
+			case <-timer.C:
+			}
+			return
+		}
+	}
+}
+
+// reply queues a message for one client
+func (h *Hub) reply(client *Client, msg Message) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	h.enqueue(client, payload, msg.Type)
+}
+
+// enqueue queues payload without blocking. A client whose queue is full is
+// evicted rather than holding up delivery to everyone else.
+func (h *Hub) enqueue(client *Client, payload []byte, event string) {
+	select {
+	case client.send <- payload:
+	default:
+		h.logger.Warn("Evicting slow socket client", logger.Field{Key: "user_id", Value: client.UserID}, logger.Field{Key: "event", Value: event})
+		h.remove(client)
+		client.close(CloseSlowConsumer, "send queue full")
+	}
+}
+
+// Publish sends an event to every connection of userID subscribed to it
+func (h *Hub) Publish(userID, event string, data interface{}) {
+	h.PublishFunc(func(client *Client) bool { return client.UserID == userID }, event, data)
+}
+
+// PublishFunc sends an event to every connection subscribed to it for which
+// match returns true. match runs without the hub lock held, so it may do
+// slow lookups.
+func (h *Hub) PublishFunc(match func(*Client) bool, event string, data interface{}) {
+	var targets []*Client
+	for _, client := range h.snapshot() {
+		if client.Subscribed(event) && match(client) {
+			targets = append(targets, client)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		h.logger.Error("Failed to encode socket event", logger.Field{Key: "event", Value: event}, logger.Field{Key: "error", Value: err.Error()})
+		return
+	}
+	payload, err := json.Marshal(Message{Type: TypeEvent, Event: event, Data: encoded})
+	if err != nil {
+		return
+	}
+	for _, client := range targets {
+		h.enqueue(client, payload, event)
+	}
+}
+
+// snapshot returns every open connection
+func (h *Hub) snapshot() []*Client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var clients []*Client
+	for _, userClients := range h.clients {
+		for client := range userClients {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// ClientCount returns the number of open connections
+func (h *Hub) ClientCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count := 0
+	for _, clients := range h.clients {
+		count += len(clients)
+	}
+	return count
+}
+
+// Stop rejects new connections and closes the open ones with a going-away
+// frame. Connections still open when ctx is done are dropped.
+func (h *Hub) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+
+	clients := h.snapshot()
+	for _, client := range clients {
+		client.close(websocket.CloseGoingAway, "server shutting down")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.serving.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		for _, client := range clients {
+			_ = client.conn.Close()
+		}
+		return ctx.Err()
+	}
+}
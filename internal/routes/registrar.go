@@ -0,0 +1,295 @@
+package routes
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/apiversion"
+	"BackofficeGoService/internal/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Chain is the middleware a registered route runs before its handler, by name
+type Chain struct {
+	Method      string
+	Path        string
+	Middlewares []string
+}
+
+// String renders the chain as "GET /path: auth -> quota -> ... -> handler"
+func (c Chain) String() string {
+	return c.Method + " " + c.Path + ": " + strings.Join(append(slices.Clone(c.Middlewares), "handler"), " -> ")
+}
+
+// Registrar registers route definitions. Each policy becomes middleware in
+// this order, whatever order the definition lists things in:
+//
+//  1. rate limit, so floods are refused before any token is checked
+//  2. timeout, so the deadline covers everything after it
+//  3. authentication: the token or signature, then quota and accepted policies
+//  4. roles
+//  5. permission
+//  6. the definition's Middlewares, in the order listed
+//  7. confirmation of destructive requests, so only requests allowed to run
+//     are summarized or get a token
+//  8. response cache, so only requests allowed to see a response get it
+type Registrar struct {
+	deps    Dependencies
+	named   map[string]gin.HandlerFunc
+	limiter *ratelimit.Limiter
+	chains  []Chain
+	table   route.Table
+}
+
+// NewRegistrar creates a registrar building middleware from deps
+func NewRegistrar(deps Dependencies) *Registrar {
+	var limiter *ratelimit.Limiter
+	if deps.RateLimits != nil {
+		limiter = ratelimit.New(deps.RateLimits)
+	}
+	return &Registrar{
+		deps:    deps,
+		limiter: limiter,
+		named: map[string]gin.HandlerFunc{
+			route.NotImpersonating: middleware.NotImpersonating(),
+			route.NoTenant:         middleware.NoTenant(),
+			route.OrgMember:        middleware.RequireOrgMember("id"),
+		},
+	}
+}
+
+// Register registers the definitions version serves on group, leaving out
+// those of features that are off. Every definition is checked first, so a
+// misconfigured one registers nothing.
+func (r *Registrar) Register(group *gin.RouterGroup, version apiversion.Version, defs []route.Definition) error {
+	return r.register(group, defs, func(def route.Definition) bool {
+		return def.Serves(version)
+	})
+}
+
+// RegisterUnversioned registers defs on group whatever their Versions, for
+// routes outside the versioned API such as the health checks
+func (r *Registrar) RegisterUnversioned(group *gin.RouterGroup, defs []route.Definition) error {
+	return r.register(group, defs, func(route.Definition) bool {
+		return true
+	})
+}
+
+// register registers the definitions serves selects on group
+func (r *Registrar) register(group *gin.RouterGroup, defs []route.Definition, serves func(route.Definition) bool) error {
+	for _, def := range defs {
+		if err := r.validate(def); err != nil {
+			return err
+		}
+	}
+
+	for _, def := range defs {
+		if !serves(def) || (def.Feature != "" && !r.deps.Features.Enabled(def.Feature)) {
+			continue
+		}
+		if def.Policy.RateLimit.Mode == "" {
+			def.Policy.RateLimit.Mode = cmp.Or(r.deps.RateLimitMode, ratelimit.Enforce)
+		}
+		handlers, names := r.chain(def)
+		group.Handle(def.Method, def.Path, append(handlers, def.Handler)...)
+		fullPath := joinPaths(group.BasePath(), def.Path)
+		r.chains = append(r.chains, Chain{
+			Method:      def.Method,
+			Path:        fullPath,
+			Middlewares: names,
+		})
+		r.table = append(r.table, info(def, fullPath))
+	}
+	return nil
+}
+
+// Chains lists the routes registered so far with their middleware
+func (r *Registrar) Chains() []Chain {
+	return slices.Clone(r.chains)
+}
+
+// Table lists the routes registered so far with their policies, ordered
+// by path and method
+func (r *Registrar) Table() route.Table {
+	table := slices.Clone(r.table)
+	slices.SortFunc(table, func(a, b route.Info) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	return table
+}
+
+// info describes def registered at fullPath
+func info(def route.Definition, fullPath string) route.Info {
+	policy := def.Policy
+	info := route.Info{
+		Method:     def.Method,
+		Path:       fullPath,
+		Handler:    handlerName(def.Handler),
+		Auth:       policy.Auth.String(),
+		Permission: policy.Permission,
+		Feature:    def.Feature,
+		Confirm:    policy.Confirm != nil,
+	}
+	for _, role := range policy.Roles {
+		info.Roles = append(info.Roles, string(role))
+	}
+	if limit := policy.RateLimit; limit.Enabled() {
+		info.RateLimit = &route.RateLimitInfo{Requests: limit.Requests, Window: limit.Window.String(), Mode: string(limit.Mode)}
+	}
+	return info
+}
+
+// modulePrefix is trimmed from handler names
+const modulePrefix = "BackofficeGoService/"
+
+// handlerName names handler as gin's route table does, without the module
+// and the suffix of method values
+func handlerName(handler gin.HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	return strings.TrimSuffix(strings.TrimPrefix(name, modulePrefix), "-fm")
+}
+
+// chain returns the middleware of def with their names, in the fixed order
+func (r *Registrar) chain(def route.Definition) (gin.HandlersChain, []string) {
+	var handlers gin.HandlersChain
+	var names []string
+	add := func(name string, handler gin.HandlerFunc) {
+		handlers = append(handlers, handler)
+		names = append(names, name)
+	}
+
+	policy := def.Policy
+	if limit := policy.RateLimit; limit.Enabled() {
+		name := fmt.Sprintf("rate_limit(%d/%s)", limit.Requests, limit.Window)
+		if limit.Mode == ratelimit.Observe {
+			name = fmt.Sprintf("rate_limit(%d/%s, observe)", limit.Requests, limit.Window)
+		}
+		add(name, middleware.RateLimit(r.limiter, limit, r.deps.RateLimitWouldBlock))
+	}
+	if policy.Timeout > 0 {
+		add(fmt.Sprintf("timeout(%s)", policy.Timeout), middleware.Timeout(policy.Timeout))
+	}
+
+	switch policy.Auth {
+	case route.Authenticated:
+		add("auth", middleware.Auth(r.deps.Tokens))
+		add("quota", middleware.Quota(r.deps.Quotas, r.deps.QuotaSessions))
+		add("policies", middleware.PoliciesAccepted(r.deps.Policies))
+	case route.PasswordChange:
+		add("password_change_auth", middleware.PasswordChangeAuth(r.deps.Tokens))
+	case route.ProfileCompletion:
+		add("profile_completion_auth", middleware.ProfileCompletionAuth(r.deps.Tokens))
+		add("quota", middleware.Quota(r.deps.Quotas, r.deps.QuotaSessions))
+	case route.Account:
+		add("auth", middleware.Auth(r.deps.Tokens))
+		add("quota", middleware.Quota(r.deps.Quotas, r.deps.QuotaSessions))
+	case route.Stream:
+		add("stream_auth", middleware.StreamAuth(r.deps.Tokens))
+	case route.Signed:
+		add("signature", middleware.VerifySignature(r.deps.Partners, r.deps.PartnerSignatureWindow, r.deps.PartnerReplays))
+	}
+
+	if len(policy.Roles) > 0 {
+		roles := make([]string, len(policy.Roles))
+		for i, role := range policy.Roles {
+			roles[i] = string(role)
+		}
+		add("roles("+strings.Join(roles, "|")+")", middleware.RequireRole(policy.Roles...))
+	}
+	if policy.Permission != "" {
+		add("permission("+policy.Permission+")", middleware.RequirePermission(r.deps.Permissions, policy.Permission))
+	}
+	for _, name := range def.Middlewares {
+		add(name, r.named[name])
+	}
+	if policy.Confirm != nil {
+		add("confirm", middleware.Confirm(r.deps.Confirmations, *policy.Confirm))
+	}
+	if policy.Cache != nil {
+		add("cache("+strings.Join(policy.Cache.Groups, "|")+")", middleware.CacheResponses(r.deps.Responses, *policy.Cache))
+	}
+	return handlers, names
+}
+
+// validate refuses definitions the registrar cannot build a sound chain for
+func (r *Registrar) validate(def route.Definition) error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s %s: %s", route.ErrInvalid, def.Method, def.Path, fmt.Sprintf(format, args...))
+	}
+
+	policy := def.Policy
+	switch {
+	case !slices.Contains(methods, def.Method):
+		return invalid("unknown method")
+	case def.Handler == nil:
+		return invalid("no handler")
+	case policy.Auth < route.Public || policy.Auth > route.Signed:
+		return invalid("unknown auth %d", int(policy.Auth))
+	case (policy.Auth == route.Public || policy.Auth == route.Signed) && (len(policy.Roles) > 0 || policy.Permission != ""):
+		return invalid("roles and permissions need a route authenticating users")
+	case policy.Cache != nil && def.Method != http.MethodGet:
+		return invalid("only GET responses can be cached")
+	case policy.Confirm != nil && (def.Method == http.MethodGet || policy.Confirm.Summarize == nil):
+		return invalid("confirmation needs a request changing data and a summary of it")
+	case policy.Confirm != nil && policy.Auth != route.Authenticated && policy.Auth != route.Account:
+		return invalid("confirmation needs a route authenticating users with bearer tokens")
+	case policy.RateLimit.Requests < 0 || (policy.RateLimit.Enabled() && policy.RateLimit.Window <= 0):
+		return invalid("rate limit needs a positive number of requests per positive window")
+	case policy.RateLimit.Mode != "" && policy.RateLimit.Mode != ratelimit.Enforce && policy.RateLimit.Mode != ratelimit.Observe:
+		return invalid("unknown rate limit mode %q", policy.RateLimit.Mode)
+	case policy.Timeout < 0:
+		return invalid("negative timeout")
+	case def.Feature != "" && !slices.Contains(config.Features, def.Feature):
+		return invalid("unknown feature %q", def.Feature)
+	}
+	if policy.Permission != "" && !slices.ContainsFunc(models.DefaultPermissions, func(p models.Permission) bool {
+		return p.Name == policy.Permission
+	}) {
+		return invalid("unknown permission %q", policy.Permission)
+	}
+	for _, role := range policy.Roles {
+		if role != models.RoleAdmin && role != models.RoleUser && role != models.RoleGuest {
+			return invalid("unknown role %q", role)
+		}
+	}
+	for _, name := range def.Middlewares {
+		if _, ok := r.named[name]; !ok {
+			return invalid("unknown middleware %q", name)
+		}
+	}
+	for _, v := range def.Versions {
+		if !slices.Contains(apiversion.Versions, v) {
+			return invalid("unknown API version %d", int(v))
+		}
+	}
+	return nil
+}
+
+// methods are the HTTP methods routes can be defined for
+var methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// joinPaths joins a group's base path and a route path as gin does
+func joinPaths(base, relative string) string {
+	if relative == "" {
+		return base
+	}
+	joined := path.Join(base, relative)
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined
+}
@@ -1,46 +1,415 @@
 package routes
 
 import (
+	"net/http"
+	"slices"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/controllers/admin"
+	"BackofficeGoService/internal/app/controllers/auth"
+	"BackofficeGoService/internal/app/controllers/feature"
+	"BackofficeGoService/internal/app/controllers/file"
+	"BackofficeGoService/internal/app/controllers/meta"
+	"BackofficeGoService/internal/app/controllers/notification"
+	"BackofficeGoService/internal/app/controllers/organization"
+	"BackofficeGoService/internal/app/controllers/partner"
+	"BackofficeGoService/internal/app/controllers/permission"
+	"BackofficeGoService/internal/app/controllers/stream"
+	"BackofficeGoService/internal/app/controllers/task"
+	"BackofficeGoService/internal/app/controllers/user"
+	"BackofficeGoService/internal/app/controllers/webhook"
+	"BackofficeGoService/internal/app/crud"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/apiversion"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/ratelimit"
+	"BackofficeGoService/internal/pkg/readonly"
+	"BackofficeGoService/internal/services"
+
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-// SetupRoutes sets up all application routes
-// This function can be used to organize routes by domain/feature
-func SetupRoutes(router *gin.Engine) {
-	// Health check routes are handled in app.go
-	
+// Controllers holds the controllers the API routes dispatch to
+type Controllers struct {
+	Auth          *auth.AuthController
+	EmailChange   *auth.EmailChangeController
+	SecureAccount *auth.SecureAccountController
+	OIDC          *auth.OIDCController
+	User          *user.UserController
+	Activity      *user.ActivityController
+	Session       *user.SessionController
+	Device        *user.DeviceController
+	SavedFilter   *user.SavedFilterController
+	Policy        *user.PolicyController
+	Profile       *user.ProfileController
+	Usage         *user.UsageController
+	Export        *user.ExportController
+	Organization  *organization.OrganizationController
+	Permission    *permission.PermissionController
+	Webhook       *webhook.WebhookController
+	Partner       *partner.PartnerController
+	Jobs          *admin.JobsController
+	Feature       *feature.FeatureController
+	Settings      *admin.SettingsController
+	Tenant        *admin.TenantController
+	Database      *admin.DatabaseController
+	Email         *admin.EmailController
+	Routes        *admin.RoutesController
+	RateLimit     *admin.RateLimitController
+	Audit         *admin.AuditController
+	RequestLog    *admin.RequestLogController
+	Approval      *admin.ApprovalController
+	Alert         *admin.AlertController
+	ReadOnly      *admin.ReadOnlyController
+	JWTSecret     *admin.JWTSecretController
+	Impersonation *admin.ImpersonationController
+	Meta          *meta.MetaController
+	Notification  *notification.NotificationController
+	Stream        *stream.StreamController
+	Socket        *stream.SocketController
+	Task          *task.TaskController
+	File          *file.FileController
+	Product       *crud.Resource[models.Product]
+}
+
+// Dependencies holds what the routes need besides controllers
+type Dependencies struct {
+	// Tokens authenticates bearer and stream tokens
+	Tokens middleware.TokenValidator
+	// Permissions resolves the permissions granted to a role
+	Permissions middleware.PermissionChecker
+	// Databases, if set, rejects API requests while the primary database's
+	// circuit breaker is open
+	Databases middleware.CircuitBreakers
+	// Tenants, if set, routes requests naming a tenant to its database
+	Tenants middleware.TenantResolver
+	// Policies, if set, refuses requests outside /auth and /me until the
+	// user accepted every current policy
+	Policies middleware.PolicyChecker
+	// Responses, if set, caches the responses of heavy read endpoints
+	Responses *services.ResponseCache
+	// Features turns groups of endpoints off; nil registers them all
+	Features config.APIFeatures
+	// Quotas, if set, refuses requests once the user's monthly quota is
+	// spent. Login session tokens only count when QuotaSessions is set.
+	Quotas        middleware.QuotaCounter
+	QuotaSessions bool
+	// Partners verifies the signatures of partner requests, whose
+	// timestamps may be PartnerSignatureWindow from now. PartnerReplays, if
+	// set, remembers signatures to refuse requests sent twice.
+	Partners               middleware.PartnerLookup
+	PartnerSignatureWindow time.Duration
+	PartnerReplays         cache.Store
+	// DatabaseRegistration serves /admin/databases, which connects and
+	// removes named databases at runtime
+	DatabaseRegistration bool
+	// V1Deprecation, once set, announces the retirement of /api/v1 in the
+	// headers of its responses
+	V1Deprecation config.DeprecationConfig
+	// RateLimits, if set, counts the requests of routes with a rate limit.
+	// Limits without a mode take RateLimitMode, enforcing when it is empty.
+	// Requests observed over their limit are counted in RateLimitWouldBlock.
+	RateLimits          cache.Store
+	RateLimitMode       ratelimit.Mode
+	RateLimitWouldBlock *prometheus.CounterVec
+	// ReadYourWrites, if set, remembers for ReadYourWritesWindow which users
+	// wrote, so their later requests read from the primary database
+	ReadYourWrites       cache.Store
+	ReadYourWritesWindow time.Duration
+	// Confirmations, if set, issues the tokens confirming destructive
+	// requests; without it they run at once
+	Confirmations *services.Confirmations
+	// ReadOnly, if set, refuses writes outside readOnlyExempt while it is on
+	ReadOnly *readonly.Switch
+	// Probes are the health check and metrics routes, served at the root
+	// outside any API version
+	Probes []route.Definition
+}
+
+// SetupRoutes sets up all application routes and returns their table. It
+// fails if a route is misconfigured.
+func SetupRoutes(router *gin.Engine, c *Controllers, deps Dependencies) (route.Table, error) {
+	registrar := NewRegistrar(deps)
+	if err := registrar.RegisterUnversioned(&router.RouterGroup, deps.Probes); err != nil {
+		return nil, err
+	}
+
+	declared := slices.Concat(
+		metaRoutes(c),
+		c.Auth.Routes(),
+		c.OIDC.Routes(),
+		c.User.Routes(),
+		userRoutes(c),
+		c.Permission.Routes(),
+		adminRoutes(c, deps),
+		c.Feature.Routes(),
+		meRoutes(c),
+		c.SavedFilter.Routes(),
+		c.Notification.Routes(),
+		streamRoutes(c),
+		c.Task.Routes(),
+		emailChangeRoutes(c),
+		secureAccountRoutes(c),
+		impersonationRoutes(c),
+		userExportRoutes(c),
+		c.Webhook.Routes(),
+		c.Organization.Routes(),
+		partnerRoutes(c),
+		c.Product.Routes(),
+	)
+
 	// API v1 routes
-	api := router.Group("/api/v1")
-	{
-		// Auth routes
-		setupAuthRoutes(api)
-		
-		// User routes
-		setupUserRoutes(api)
-		
-		// Add more route groups here
-	}
-}
-
-// setupAuthRoutes sets up authentication routes
-func setupAuthRoutes(api *gin.RouterGroup) {
-	_ = api.Group("/auth")
-	// {
-	// 	auth.POST("/login", authHandler.Login)
-	// 	auth.POST("/register", authHandler.Register)
-	// 	auth.POST("/refresh", authHandler.RefreshToken)
-	// 	auth.POST("/logout", authHandler.Logout)
-	// }
-}
-
-// setupUserRoutes sets up user management routes
-func setupUserRoutes(api *gin.RouterGroup) {
-	_ = api.Group("/users")
-	// {
-	// 	users.GET("", userHandler.List)
-	// 	users.GET("/:id", userHandler.Get)
-	// 	users.POST("", userHandler.Create)
-	// 	users.PUT("/:id", userHandler.Update)
-	// 	users.DELETE("/:id", userHandler.Delete)
-	// }
+	api := router.Group(apiversion.V1.Prefix(),
+		apiversion.Use(apiversion.V1),
+		middleware.Deprecation(deps.V1Deprecation.DeprecatedAt, deps.V1Deprecation.Sunset),
+		middleware.ReadOnly(deps.ReadOnly, readOnlyExempt),
+		middleware.DatabaseSession(deps.ReadYourWrites, deps.ReadYourWritesWindow),
+		middleware.Tenant(deps.Tenants),
+		middleware.DatabaseAvailable(deps.Databases, "primary"),
+	)
+	if err := registrar.Register(api, apiversion.V1, declared); err != nil {
+		return nil, err
+	}
+
+	// API v2 routes run the v1 handlers, which render their responses in
+	// the v2 shapes for requests under /api/v2
+	v2 := router.Group(apiversion.V2.Prefix(),
+		apiversion.Use(apiversion.V2),
+		middleware.ReadOnly(deps.ReadOnly, readOnlyExempt),
+		middleware.DatabaseSession(deps.ReadYourWrites, deps.ReadYourWritesWindow),
+		middleware.Tenant(deps.Tenants),
+		middleware.DatabaseAvailable(deps.Databases, "primary"),
+	)
+	if err := registrar.Register(v2, apiversion.V2, declared); err != nil {
+		return nil, err
+	}
+	return registrar.Table(), nil
+}
+
+// readOnlyExempt lists the routes that keep accepting writes in read-only
+// mode: signing in and out, so operators can get a token, and the switch
+var readOnlyExempt = middleware.ReadOnlyConfig{Exempt: []string{
+	"/api/v1/auth/login",
+	"/api/v1/auth/refresh",
+	"/api/v1/auth/logout",
+	"/api/v1/admin/read-only",
+}}
+
+// Policies shared by the routes below
+var (
+	public        = route.Policy{}
+	authenticated = route.Policy{Auth: route.Authenticated}
+	account       = route.Policy{Auth: route.Account}
+)
+
+// withPermission is authenticated with a server-side permission check
+func withPermission(name string) route.Policy {
+	return route.Policy{Auth: route.Authenticated, Permission: name}
+}
+
+// metaRoutes lists the public routes describing the API
+func metaRoutes(c *Controllers) []route.Definition {
+	return []route.Definition{
+		// Error codes clients can branch on
+		{Method: http.MethodGet, Path: "/error-codes", Handler: c.Meta.ListErrorCodes, Policy: public},
+		// The running build and the features it serves
+		{Method: http.MethodGet, Path: "/version", Handler: c.Meta.Version, Policy: public},
+		// Signed download links carry their own authorization
+		{Method: http.MethodGet, Path: "/files/:token", Handler: c.File.Download, Policy: public},
+	}
+}
+
+// userRoutes lists the user routes of controllers besides UserController
+func userRoutes(c *Controllers) []route.Definition {
+	canManage := withPermission(models.PermissionUsersManage)
+	return []route.Definition{
+		{Method: http.MethodGet, Path: "/users/:id/activity", Handler: c.Activity.ListActivity, Policy: authenticated},
+		{Method: http.MethodGet, Path: "/users/:id/sessions", Handler: c.Session.ListUserSessions, Policy: canManage},
+		{Method: http.MethodDelete, Path: "/users/:id/sessions", Handler: c.Session.RevokeUserSessions, Policy: canManage},
+		{Method: http.MethodDelete, Path: "/users/:id/sessions/:sid", Handler: c.Session.RevokeUserSession, Policy: canManage},
+		{Method: http.MethodPost, Path: "/users/:id/force-logout", Handler: c.Session.ForceLogout, Policy: canManage},
+		{Method: http.MethodPut, Path: "/users/:id/quota", Handler: c.Usage.SetQuota, Policy: canManage},
+	}
+}
+
+// userExportRoutes lists exporting every user as a file
+func userExportRoutes(c *Controllers) []route.Definition {
+	canManage := withPermission(models.PermissionUsersManage)
+	return []route.Definition{
+		{Method: http.MethodGet, Path: "/users/export", Handler: c.Export.ExportUsers, Policy: canManage, Feature: config.FeatureUserExport},
+		{Method: http.MethodPost, Path: "/users/export", Handler: c.Export.ExportUsers, Policy: canManage, Feature: config.FeatureUserExport},
+	}
+}
+
+// adminRoutes lists the operator routes under /admin
+func adminRoutes(c *Controllers, deps Dependencies) []route.Definition {
+	canRunJobs := withPermission(models.PermissionJobsManage)
+	canManageSettings := withPermission(models.PermissionSettingsManage)
+	canManageTenants := withPermission(models.PermissionTenantsManage)
+	canManageUsers := withPermission(models.PermissionUsersManage)
+	canManageEmails := withPermission(models.PermissionEmailsManage)
+	canViewAudit := withPermission(models.PermissionAuditView)
+	canManageApprovals := withPermission(models.PermissionApprovalsManage)
+	notImpersonating := []string{route.NotImpersonating}
+	// Tenants are managed from outside any tenant
+	noTenant := []string{route.NoTenant}
+
+	defs := []route.Definition{
+		{Method: http.MethodGet, Path: "/admin/jobs", Handler: c.Jobs.ListJobs, Policy: canRunJobs},
+		{
+			Method:  http.MethodPost,
+			Path:    "/admin/jobs/:name/run",
+			Handler: c.Jobs.RunJob,
+			Policy:  route.Policy{Auth: route.Authenticated, Permission: models.PermissionJobsManage, Confirm: c.Jobs.Confirmation()},
+		},
+
+		{Method: http.MethodGet, Path: "/admin/settings", Handler: c.Settings.ListSettings, Policy: canManageSettings},
+		{Method: http.MethodPut, Path: "/admin/settings", Handler: c.Settings.UpdateSettings, Policy: canManageSettings},
+
+		{Method: http.MethodGet, Path: "/admin/tenants", Handler: c.Tenant.ListTenants, Middlewares: noTenant, Policy: canManageTenants},
+		{Method: http.MethodPost, Path: "/admin/tenants", Handler: c.Tenant.CreateTenant, Middlewares: noTenant, Policy: canManageTenants},
+
+		{
+			// The report counts users and reads the current versions from settings
+			Method:  http.MethodGet,
+			Path:    "/admin/policies",
+			Handler: c.Policy.PolicyReport,
+			Policy: route.Policy{Auth: route.Authenticated, Permission: models.PermissionUsersManage, Cache: &middleware.CacheRule{
+				Groups: []string{services.ResponseGroupUsers, services.ResponseGroupPolicies, services.ResponseGroupSettings},
+			}},
+		},
+		{Method: http.MethodGet, Path: "/admin/usage", Handler: c.Usage.UsageReport, Policy: canManageUsers},
+		{Method: http.MethodGet, Path: "/admin/profiles", Handler: c.Profile.ProfileReport, Policy: canManageUsers},
+
+		{Method: http.MethodGet, Path: "/admin/emails/templates", Handler: c.Email.ListTemplates, Policy: canManageEmails},
+		{Method: http.MethodGet, Path: "/admin/emails/templates/:name/preview", Handler: c.Email.PreviewTemplate, Policy: canManageEmails},
+		{Method: http.MethodPost, Path: "/admin/emails/templates/:name/test-send", Handler: c.Email.TestSendTemplate, Policy: canManageEmails},
+
+		{Method: http.MethodGet, Path: "/admin/routes", Handler: c.Routes.ListRoutes, Policy: withPermission(models.PermissionRoutesView)},
+		{Method: http.MethodGet, Path: "/admin/ratelimit/offenders", Handler: c.RateLimit.ListOffenders, Policy: withPermission(models.PermissionRoutesView)},
+
+		{Method: http.MethodGet, Path: "/admin/audit-logs/export", Handler: c.Audit.ExportAuditLogs, Policy: canViewAudit},
+		{Method: http.MethodPost, Path: "/admin/audit-logs/verify", Handler: c.Audit.VerifyAuditLogs, Policy: canViewAudit},
+		{Method: http.MethodGet, Path: "/admin/alerts", Handler: c.Alert.ListAlerts, Policy: canViewAudit},
+		{Method: http.MethodGet, Path: "/admin/request-logs", Handler: c.RequestLog.ListRequestLogs, Policy: withPermission(models.PermissionRequestLogsView)},
+
+		{Method: http.MethodGet, Path: "/admin/approvals", Handler: c.Approval.ListApprovals, Policy: canManageApprovals},
+		{Method: http.MethodGet, Path: "/admin/approvals/:id", Handler: c.Approval.GetApproval, Policy: canManageApprovals},
+		{Method: http.MethodPost, Path: "/admin/approvals/:id/approve", Handler: c.Approval.Approve, Middlewares: notImpersonating, Policy: canManageApprovals},
+		{Method: http.MethodPost, Path: "/admin/approvals/:id/reject", Handler: c.Approval.Reject, Middlewares: notImpersonating, Policy: canManageApprovals},
+		{Method: http.MethodDelete, Path: "/admin/approvals/:id", Handler: c.Approval.Cancel, Policy: canManageUsers},
+
+		{Method: http.MethodGet, Path: "/admin/read-only", Handler: c.ReadOnly.GetReadOnly, Policy: canManageSettings},
+		{Method: http.MethodPut, Path: "/admin/read-only", Handler: c.ReadOnly.SetReadOnly, Policy: canManageSettings},
+		{Method: http.MethodPost, Path: "/admin/jwt/rotate-secret", Handler: c.JWTSecret.RotateSecret, Middlewares: notImpersonating, Policy: canManageSettings},
+	}
+
+	// So are databases, where the deployment allows it
+	if deps.DatabaseRegistration {
+		canManageDatabases := withPermission(models.PermissionDatabasesManage)
+		defs = append(defs,
+			route.Definition{Method: http.MethodGet, Path: "/admin/databases", Handler: c.Database.ListDatabases, Middlewares: noTenant, Policy: canManageDatabases},
+			route.Definition{Method: http.MethodPost, Path: "/admin/databases", Handler: c.Database.RegisterDatabase, Middlewares: noTenant, Policy: canManageDatabases},
+			route.Definition{Method: http.MethodDelete, Path: "/admin/databases/:name", Handler: c.Database.RemoveDatabase, Middlewares: noTenant, Policy: canManageDatabases},
+		)
+	}
+	return defs
+}
+
+// meRoutes lists the current user's routes, which stay open to users with
+// pending policies, so they can see and accept them. Showing and updating
+// the profile stays open to users who must complete it.
+func meRoutes(c *Controllers) []route.Definition {
+	notImpersonating := []string{route.NotImpersonating}
+	profile := route.Policy{Auth: route.ProfileCompletion}
+	return []route.Definition{
+		{Method: http.MethodGet, Path: "/me", Handler: c.Policy.GetMe, Policy: profile},
+		{Method: http.MethodPut, Path: "/me", Handler: c.Profile.UpdateMe, Middlewares: notImpersonating, Policy: profile},
+		{Method: http.MethodPost, Path: "/me/accept-policy", Handler: c.Policy.AcceptPolicy, Middlewares: notImpersonating, Policy: account},
+		{Method: http.MethodGet, Path: "/me/features", Handler: c.Feature.MyFeatures, Policy: account},
+		{Method: http.MethodGet, Path: "/me/usage", Handler: c.Usage.MyUsage, Policy: account},
+
+		// Impersonators see the user's sessions but cannot end them
+		{Method: http.MethodGet, Path: "/me/sessions", Handler: c.Session.ListMySessions, Policy: account},
+		{Method: http.MethodDelete, Path: "/me/sessions", Handler: c.Session.RevokeMyOtherSessions, Middlewares: notImpersonating, Policy: account},
+		{Method: http.MethodDelete, Path: "/me/sessions/:sid", Handler: c.Session.RevokeMySession, Middlewares: notImpersonating, Policy: account},
+		{Method: http.MethodGet, Path: "/me/devices", Handler: c.Device.ListMyDevices, Policy: account},
+		{Method: http.MethodDelete, Path: "/me/devices/:id", Handler: c.Device.RevokeMyDevice, Middlewares: notImpersonating, Policy: account},
+	}
+}
+
+// streamRoutes lists the realtime event stream and socket
+func streamRoutes(c *Controllers) []route.Definition {
+	stream := route.Policy{Auth: route.Stream}
+	return []route.Definition{
+		{Method: http.MethodGet, Path: "/events/stream", Handler: c.Stream.Stream, Policy: stream},
+		{Method: http.MethodGet, Path: "/ws", Handler: c.Socket.Connect, Policy: stream},
+	}
+}
+
+// emailChangeRoutes lists changing one's email address
+func emailChangeRoutes(c *Controllers) []route.Definition {
+	return []route.Definition{
+		{
+			Method:      http.MethodPost,
+			Path:        "/me/email-change",
+			Handler:     c.EmailChange.RequestEmailChange,
+			Middlewares: []string{route.NotImpersonating},
+			Policy:      account,
+			Feature:     config.FeatureEmailChange,
+		},
+		{Method: http.MethodPost, Path: "/auth/confirm-email-change", Handler: c.EmailChange.ConfirmEmailChange, Policy: public, Feature: config.FeatureEmailChange},
+	}
+}
+
+// secureAccountRoutes lists the target of the links in new sign-in emails
+func secureAccountRoutes(c *Controllers) []route.Definition {
+	return []route.Definition{
+		{Method: http.MethodPost, Path: "/auth/secure-account", Handler: c.SecureAccount.SecureAccount, Policy: public},
+	}
+}
+
+// impersonationRoutes lists acting as another user
+func impersonationRoutes(c *Controllers) []route.Definition {
+	return []route.Definition{
+		{
+			// Stopping only needs the impersonation token, whoever it belongs to
+			Method:  http.MethodPost,
+			Path:    "/admin/impersonate/stop",
+			Handler: c.Impersonation.StopImpersonation,
+			Policy:  authenticated,
+			Feature: config.FeatureImpersonation,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/admin/impersonate/:id",
+			Handler:     c.Impersonation.Impersonate,
+			Middlewares: []string{route.NotImpersonating},
+			Policy:      withPermission(models.PermissionUsersImpersonate),
+			Feature:     config.FeatureImpersonation,
+		},
+	}
+}
+
+// partnerRoutes lists partner administration and the endpoints partners
+// call with signed requests instead of tokens
+func partnerRoutes(c *Controllers) []route.Definition {
+	canManage := withPermission(models.PermissionPartnersManage)
+	partner := func(method, path string, handler gin.HandlerFunc, policy route.Policy) route.Definition {
+		return route.Definition{Method: method, Path: path, Handler: handler, Policy: policy, Feature: config.FeaturePartners}
+	}
+
+	return []route.Definition{
+		partner(http.MethodGet, "/admin/partners", c.Partner.ListPartners, canManage),
+		partner(http.MethodPost, "/admin/partners", c.Partner.CreatePartner, canManage),
+		partner(http.MethodGet, "/admin/partners/:id", c.Partner.GetPartner, canManage),
+		partner(http.MethodPut, "/admin/partners/:id", c.Partner.UpdatePartner, canManage),
+		partner(http.MethodDelete, "/admin/partners/:id", c.Partner.DeletePartner, canManage),
+
+		partner(http.MethodPost, "/partners/provision-user", c.Partner.ProvisionUser, route.Policy{Auth: route.Signed}),
+	}
 }
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/apimodel"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// Activity entry types that are not audit actions
+const (
+	ActivityLoginSucceeded = "login.succeeded"
+	ActivityLoginFailed    = "login.failed"
+)
+
+// ActivityActor is who performed an activity
+type ActivityActor struct {
+	ID    string `json:"id"`
+	Email string `json:"email,omitempty"`
+}
+
+// ActivityEntry is one event of a user's timeline. Type is the audit action
+// or one of the login types; Summary renders the event for people.
+type ActivityEntry struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Summary    string            `json:"summary"`
+	Actor      *ActivityActor    `json:"actor,omitempty"`
+	Fields     []string          `json:"fields,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (e ActivityEntry) MarshalJSON() ([]byte, error) { return apimodel.Marshal(e) }
+
+// ActivityFilter narrows a timeline to a time range; zero bounds are open
+type ActivityFilter struct {
+	From time.Time
+	To   time.Time
+}
+
+// ActivityService renders the audit log and login events of a user into a
+// timeline of typed entries
+type ActivityService struct {
+	db     *database.Manager
+	logger logger.Logger
+}
+
+// NewActivityService creates a new activity service
+func NewActivityService(db *database.Manager, log logger.Logger) *ActivityService {
+	return &ActivityService{
+		db:     db,
+		logger: log,
+	}
+}
+
+// ListForUser returns a page of what happened to a user's account, newest
+// first: audit entries about the user and their login attempts
+func (s *ActivityService) ListForUser(ctx context.Context, userID string, filter ActivityFilter, limit, offset int) (*ListResult[*ActivityEntry], error) {
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db = db.WithContext(ctx)
+
+	inRange := func(query *gorm.DB) *gorm.DB {
+		if !filter.From.IsZero() {
+			query = query.Where("created_at >= ?", filter.From)
+		}
+		if !filter.To.IsZero() {
+			query = query.Where("created_at < ?", filter.To)
+		}
+		return query
+	}
+	audits := func() *gorm.DB {
+		return inRange(db.Model(&models.AuditLog{}).Where("entity_type = ? AND entity_id = ?", "user", userID))
+	}
+	logins := func() *gorm.DB {
+		return inRange(db.Model(&models.LoginEvent{}).Where("user_id = ?", userID))
+	}
+
+	var auditTotal, loginTotal int64
+	if err := audits().Count(&auditTotal).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if err := logins().Count(&loginTotal).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	// The page can only hold the newest offset+limit rows of either table
+	var auditLogs []*models.AuditLog
+	if err := audits().Order("created_at DESC").Limit(offset + limit).Find(&auditLogs).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	var loginEvents []*models.LoginEvent
+	if err := logins().Order("created_at DESC").Limit(offset + limit).Find(&loginEvents).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	entries := make([]*ActivityEntry, 0, len(auditLogs)+len(loginEvents))
+	for _, record := range auditLogs {
+		entries = append(entries, renderAuditLog(record))
+	}
+	for _, event := range loginEvents {
+		entries = append(entries, renderLoginEvent(event))
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].OccurredAt.Equal(entries[j].OccurredAt) {
+			return entries[i].OccurredAt.After(entries[j].OccurredAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+
+	if offset > len(entries) {
+		offset = len(entries)
+	}
+	page := entries[offset:min(offset+limit, len(entries))]
+	if err := s.resolveActors(db, page); err != nil {
+		return nil, err
+	}
+	return &ListResult[*ActivityEntry]{Items: page, Total: auditTotal + loginTotal}, nil
+}
+
+// resolveActors adds the actors' emails and names them in the summaries
+func (s *ActivityService) resolveActors(db *gorm.DB, entries []*ActivityEntry) error {
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Actor != nil {
+			ids = append(ids, entry.Actor.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var actors []*models.User
+	if err := db.Select("id", "email").Where("id IN ?", ids).Find(&actors).Error; err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	emails := make(map[string]string, len(actors))
+	for _, actor := range actors {
+		emails[actor.ID.String()] = actor.Email
+	}
+
+	for _, entry := range entries {
+		if entry.Actor == nil {
+			continue
+		}
+		entry.Actor.Email = emails[entry.Actor.ID]
+		name := entry.Actor.Email
+		if name == "" {
+			name = "a deleted user"
+		}
+		entry.Summary += " by " + name
+	}
+	return nil
+}
+
+// renderAuditLog turns an audit entry about a user into a timeline entry.
+// Actions done by the user themselves carry no actor. Only field names are
+// taken from the metadata, so values such as passwords never reach the
+// timeline.
+func renderAuditLog(record *models.AuditLog) *ActivityEntry {
+	entry := &ActivityEntry{
+		ID:         record.ID.String(),
+		Type:       record.Action,
+		OccurredAt: record.CreatedAt,
+	}
+	if record.ActorID != nil && record.ActorID.String() != record.EntityID {
+		entry.Actor = &ActivityActor{ID: record.ActorID.String()}
+	}
+
+	var metadata struct {
+		Fields     []string `json:"fields"`
+		From       string   `json:"from"`
+		To         string   `json:"to"`
+		SessionIDs []string `json:"session_ids"`
+		Policy     string   `json:"policy"`
+		Version    string   `json:"version"`
+	}
+	if len(record.Metadata) > 0 {
+		_ = json.Unmarshal(record.Metadata, &metadata)
+	}
+
+	switch record.Action {
+	case models.AuditActionUserCreated:
+		entry.Summary = "Account created"
+	case models.AuditActionUserUpdated:
+		for _, field := range metadata.Fields {
+			if field != "password" {
+				entry.Fields = append(entry.Fields, field)
+			}
+		}
+		entry.Summary = "Updated " + strings.Join(entry.Fields, ", ")
+	case models.AuditActionUserPasswordChanged:
+		entry.Summary = "Password changed"
+	case models.AuditActionUserEmailChanged:
+		entry.Summary = "Email changed"
+	case models.AuditActionUserRoleChanged:
+		entry.Details = map[string]string{"from": metadata.From, "to": metadata.To}
+		entry.Summary = fmt.Sprintf("Role changed from %s to %s", metadata.From, metadata.To)
+	case models.AuditActionUserActivated:
+		entry.Summary = "Account activated"
+	case models.AuditActionUserDeactivated:
+		entry.Summary = "Account deactivated"
+	case models.AuditActionUserAnonymized:
+		entry.Summary = "Account anonymized"
+	case models.AuditActionUserExported:
+		entry.Summary = "Personal data exported"
+	case models.AuditActionUserImpersonated:
+		entry.Summary = "Impersonated"
+	case models.AuditActionImpersonationEnded:
+		entry.Summary = "Impersonation ended"
+	case models.AuditActionUserAPITokenIssued:
+		entry.Summary = "API token issued"
+	case models.AuditActionUserSessionsRevoked:
+		if len(metadata.SessionIDs) == 1 {
+			entry.Summary = "Signed out of 1 session"
+		} else {
+			entry.Summary = fmt.Sprintf("Signed out of %d sessions", len(metadata.SessionIDs))
+		}
+	case models.AuditActionUserForcedLogout:
+		entry.Summary = "Signed out everywhere"
+	case models.AuditActionUserDeviceRevoked:
+		entry.Summary = "Trusted device revoked"
+	case models.AuditActionUserPolicyAccepted:
+		entry.Details = map[string]string{"policy": metadata.Policy, "version": metadata.Version}
+		entry.Summary = fmt.Sprintf("Accepted %s version %s", metadata.Policy, metadata.Version)
+	default:
+		entry.Summary = record.Action
+	}
+	return entry
+}
+
+// renderLoginEvent turns a login attempt into a timeline entry
+func renderLoginEvent(event *models.LoginEvent) *ActivityEntry {
+	entry := &ActivityEntry{
+		ID:         event.ID.String(),
+		Type:       ActivityLoginSucceeded,
+		Summary:    "Logged in",
+		OccurredAt: event.CreatedAt,
+		Details:    map[string]string{},
+	}
+	if !event.Success {
+		entry.Type = ActivityLoginFailed
+		entry.Summary = "Failed login attempt"
+		if event.Reason != "" {
+			entry.Summary += " (" + event.Reason + ")"
+			entry.Details["reason"] = event.Reason
+		}
+	}
+	if event.IPAddress != "" {
+		entry.Summary += " from " + event.IPAddress
+		entry.Details["ip_address"] = event.IPAddress
+	}
+	if event.UserAgent != "" {
+		entry.Details["user_agent"] = event.UserAgent
+	}
+	if len(entry.Details) == 0 {
+		entry.Details = nil
+	}
+	return entry
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ApprovalFilter selects approvals; zero fields match every approval
+type ApprovalFilter struct {
+	Status    models.ApprovalStatus
+	SubjectID string
+}
+
+// ApprovalRepository persists approvals
+type ApprovalRepository interface {
+	Create(ctx context.Context, approval *models.Approval) error
+
+	// Get returns the approval or ErrApprovalNotFound
+	Get(ctx context.Context, id uuid.UUID) (*models.Approval, error)
+
+	// FindPending returns the pending approval of action on subjectID, or
+	// nil when there is none
+	FindPending(ctx context.Context, action string, subjectID uuid.UUID) (*models.Approval, error)
+
+	// List returns a page of the approvals filter selects, newest first
+	List(ctx context.Context, filter ApprovalFilter, limit, offset int) (*ListResult[*models.Approval], error)
+
+	// Expire marks the pending approvals that expired by at as expired
+	Expire(ctx context.Context, at time.Time) (int64, error)
+
+	// Decide moves a pending approval to status and runs apply in the same
+	// transaction. It returns ErrApprovalNotPending if the approval was
+	// decided in the meantime; nothing is written when apply fails.
+	Decide(ctx context.Context, approval *models.Approval, apply func(tx *gorm.DB) error) error
+
+	// Approvers returns the IDs of the active admins
+	Approvers(ctx context.Context) ([]uuid.UUID, error)
+}
+
+// gormApprovalRepository implements ApprovalRepository on the database each call is scoped to
+type gormApprovalRepository struct {
+	db *database.Manager
+}
+
+// NewApprovalRepository creates a repository backed by the database each call is scoped to
+func NewApprovalRepository(db *database.Manager) ApprovalRepository {
+	return &gormApprovalRepository{db: db}
+}
+
+func (r *gormApprovalRepository) Create(ctx context.Context, approval *models.Approval) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(approval).Error
+}
+
+func (r *gormApprovalRepository) Get(ctx context.Context, id uuid.UUID) (*models.Approval, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var approval models.Approval
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("id = ?", id).First(&approval).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrApprovalNotFound
+		}
+		return nil, err
+	}
+	return &approval, nil
+}
+
+func (r *gormApprovalRepository) FindPending(ctx context.Context, action string, subjectID uuid.UUID) (*models.Approval, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var approvals []models.Approval
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("action = ? AND subject_id = ? AND status = ?", action, subjectID, models.ApprovalStatusPending).
+			Limit(1).Find(&approvals).Error
+	}); err != nil {
+		return nil, err
+	}
+	if len(approvals) == 0 {
+		return nil, nil
+	}
+	return &approvals[0], nil
+}
+
+func (r *gormApprovalRepository) List(ctx context.Context, filter ApprovalFilter, limit, offset int) (*ListResult[*models.Approval], error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	selected := func() *gorm.DB {
+		query := db.Model(&models.Approval{})
+		if filter.Status != "" {
+			query = query.Where("status = ?", filter.Status)
+		}
+		if filter.SubjectID != "" {
+			query = query.Where("subject_id = ?", filter.SubjectID)
+		}
+		return query
+	}
+
+	result := &ListResult[*models.Approval]{Items: []*models.Approval{}}
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		if err := selected().Count(&result.Total).Error; err != nil {
+			return err
+		}
+		return selected().Order("created_at DESC").Order("id").Limit(limit).Offset(offset).Find(&result.Items).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *gormApprovalRepository) Expire(ctx context.Context, at time.Time) (int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	var expired int64
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		result := db.Model(&models.Approval{}).
+			Where("status = ? AND expires_at <= ?", models.ApprovalStatusPending, at).
+			Updates(map[string]interface{}{"status": models.ApprovalStatusExpired, "decided_at": at})
+		expired = result.RowsAffected
+		return result.Error
+	})
+	return expired, err
+}
+
+func (r *gormApprovalRepository) Decide(ctx context.Context, approval *models.Approval, apply func(tx *gorm.DB) error) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Approval{}).
+			Where("id = ? AND status = ?", approval.ID, models.ApprovalStatusPending).
+			Updates(map[string]interface{}{
+				"status":     approval.Status,
+				"decider_id": approval.DeciderID,
+				"reason":     approval.Reason,
+				"decided_at": approval.DecidedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrApprovalNotPending
+		}
+		return apply(tx)
+	})
+}
+
+func (r *gormApprovalRepository) Approvers(ctx context.Context) ([]uuid.UUID, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uuid.UUID
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		ids = ids[:0]
+		return db.Model(&models.User{}).
+			Where("role = ? AND active = ? AND deleted_at IS NULL", models.RoleAdmin, true).
+			Order("created_at").Pluck("id", &ids).Error
+	})
+	return ids, err
+}
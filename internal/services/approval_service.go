@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultApprovalTTL is how long approvals wait when no TTL is configured
+const DefaultApprovalTTL = 72 * time.Hour
+
+// ApprovalTx is the transaction an approved change is applied in
+type ApprovalTx struct {
+	*gorm.DB
+	entries []*models.AuditLog
+}
+
+// Audit records an audit entry that commits or rolls back with the change
+func (tx *ApprovalTx) Audit(actorID, action, entityType, entityID string, metadata interface{}) error {
+	entry, err := newAuditEntry(actorID, action, entityType, entityID, metadata)
+	if err != nil {
+		return err
+	}
+	if err := appendAuditEntry(tx.DB, entry); err != nil {
+		return fmt.Errorf("failed to record audit log: %w", err)
+	}
+	tx.entries = append(tx.entries, entry)
+	return nil
+}
+
+// ApprovalHandler applies the changes of one approval action
+type ApprovalHandler interface {
+	// Apply makes the approved change within tx, which also marks the
+	// approval approved
+	Apply(ctx context.Context, tx *ApprovalTx, approval *models.Approval) error
+
+	// Applied runs once tx has committed, for side effects such as purging
+	// caches or revoking tokens
+	Applied(ctx context.Context, approval *models.Approval)
+}
+
+// ApprovalService holds changes back until an admin other than the one who
+// requested them approves them. Pending approvals expire after a TTL; the
+// requester may cancel them until then. Admins are notified of each request
+// and the requester of each decision.
+type ApprovalService struct {
+	repo          ApprovalRepository
+	audit         *AuditService
+	notifications *NotificationService
+	ttl           time.Duration
+	handlers      map[string]ApprovalHandler
+	logger        logger.Logger
+}
+
+// NewApprovalService creates an approval service whose approvals wait ttl
+// for a decision. notifications may be nil.
+func NewApprovalService(repo ApprovalRepository, audit *AuditService, notifications *NotificationService, ttl time.Duration, log logger.Logger) *ApprovalService {
+	if ttl <= 0 {
+		ttl = DefaultApprovalTTL
+	}
+	return &ApprovalService{
+		repo:          repo,
+		audit:         audit,
+		notifications: notifications,
+		ttl:           ttl,
+		handlers:      make(map[string]ApprovalHandler),
+		logger:        log,
+	}
+}
+
+// Handle makes handler apply the approvals of action. Call it while setting
+// up, before requests are served.
+func (s *ApprovalService) Handle(action string, handler ApprovalHandler) {
+	s.handlers[action] = handler
+}
+
+// Request records a pending approval of action on subjectID requested by
+// requesterID, with payload describing the change, and notifies the other
+// admins. It returns ErrApprovalPending while another one of the same action
+// on the subject is pending.
+func (s *ApprovalService) Request(ctx context.Context, action string, subjectID uuid.UUID, payload interface{}, requesterID string) (*models.Approval, error) {
+	if _, ok := s.handlers[action]; !ok {
+		return nil, fmt.Errorf("no handler for approval action %q", action)
+	}
+	requester, err := uuid.Parse(requesterID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	data, err := models.NewJSON(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode approval payload: %w", err)
+	}
+
+	now := time.Now()
+	if _, err := s.repo.Expire(ctx, now); err != nil {
+		return nil, fmt.Errorf("failed to expire approvals: %w", err)
+	}
+	pending, err := s.repo.FindPending(ctx, action, subjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up approvals: %w", err)
+	}
+	if pending != nil {
+		return nil, ErrApprovalPending
+	}
+
+	approval := &models.Approval{
+		ID:          uuid.New(),
+		Action:      action,
+		SubjectID:   subjectID,
+		Payload:     data,
+		Status:      models.ApprovalStatusPending,
+		RequesterID: requester,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	if err := s.repo.Create(ctx, approval); err != nil {
+		return nil, fmt.Errorf("failed to create approval: %w", err)
+	}
+
+	_ = s.audit.Record(ctx, requesterID, models.AuditActionApprovalRequested, "approval", approval.ID.String(), map[string]interface{}{
+		"action":     action,
+		"subject_id": subjectID.String(),
+		"payload":    data,
+	})
+	s.notifyApprovers(ctx, approval)
+	return approval, nil
+}
+
+// Get returns an approval, marking it expired once its TTL has passed
+func (s *ApprovalService) Get(ctx context.Context, id string) (*models.Approval, error) {
+	approvalID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrApprovalNotFound
+	}
+	if _, err := s.repo.Expire(ctx, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to expire approvals: %w", err)
+	}
+	return s.repo.Get(ctx, approvalID)
+}
+
+// List returns a page of the approvals filter selects, newest first
+func (s *ApprovalService) List(ctx context.Context, filter ApprovalFilter, limit, offset int) (*ListResult[*models.Approval], error) {
+	if _, err := s.repo.Expire(ctx, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to expire approvals: %w", err)
+	}
+	result, err := s.repo.List(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// Approve applies a pending approval on behalf of deciderID, who must not be
+// its requester. The change, the approval's new status and the audit entries
+// naming requester and approver are written in one transaction.
+func (s *ApprovalService) Approve(ctx context.Context, id, deciderID string) (*models.Approval, error) {
+	approval, err := s.decidable(ctx, id, deciderID)
+	if err != nil {
+		return nil, err
+	}
+	handler, ok := s.handlers[approval.Action]
+	if !ok {
+		return nil, fmt.Errorf("no handler for approval action %q", approval.Action)
+	}
+
+	tx, err := s.decide(ctx, approval, models.ApprovalStatusApproved, deciderID, "", func(tx *ApprovalTx) error {
+		if err := handler.Apply(ctx, tx, approval); err != nil {
+			return err
+		}
+		return tx.Audit(deciderID, models.AuditActionApprovalApproved, "approval", approval.ID.String(), map[string]interface{}{
+			"action":       approval.Action,
+			"subject_id":   approval.SubjectID.String(),
+			"requester_id": approval.RequesterID.String(),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.forwardEntries(tx.entries...)
+	handler.Applied(ctx, approval)
+	s.notifyRequester(ctx, approval)
+	s.logger.Info("Approval approved",
+		logger.Field{Key: "approval_id", Value: approval.ID.String()},
+		logger.Field{Key: "action", Value: approval.Action},
+		logger.Field{Key: "requester_id", Value: approval.RequesterID.String()},
+		logger.Field{Key: "approver_id", Value: deciderID},
+	)
+	return approval, nil
+}
+
+// Reject refuses a pending approval on behalf of deciderID, who must not be
+// its requester
+func (s *ApprovalService) Reject(ctx context.Context, id, deciderID, reason string) (*models.Approval, error) {
+	approval, err := s.decidable(ctx, id, deciderID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.decide(ctx, approval, models.ApprovalStatusRejected, deciderID, reason, func(tx *ApprovalTx) error {
+		return tx.Audit(deciderID, models.AuditActionApprovalRejected, "approval", approval.ID.String(), map[string]interface{}{
+			"action":       approval.Action,
+			"subject_id":   approval.SubjectID.String(),
+			"requester_id": approval.RequesterID.String(),
+			"reason":       reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.forwardEntries(tx.entries...)
+	s.notifyRequester(ctx, approval)
+	return approval, nil
+}
+
+// Cancel withdraws a pending approval on behalf of actorID, who must be its
+// requester
+func (s *ApprovalService) Cancel(ctx context.Context, id, actorID string) (*models.Approval, error) {
+	approval, err := s.pending(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if approval.RequesterID.String() != actorID {
+		return nil, ErrNotApprovalRequester
+	}
+
+	tx, err := s.decide(ctx, approval, models.ApprovalStatusCancelled, actorID, "", func(tx *ApprovalTx) error {
+		return tx.Audit(actorID, models.AuditActionApprovalCancelled, "approval", approval.ID.String(), map[string]interface{}{
+			"action":     approval.Action,
+			"subject_id": approval.SubjectID.String(),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.forwardEntries(tx.entries...)
+	return approval, nil
+}
+
+// pending returns the approval if it is still pending
+func (s *ApprovalService) pending(ctx context.Context, id string) (*models.Approval, error) {
+	approval, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch approval.Status {
+	case models.ApprovalStatusPending:
+		return approval, nil
+	case models.ApprovalStatusExpired:
+		return nil, ErrApprovalExpired
+	default:
+		return nil, ErrApprovalNotPending
+	}
+}
+
+// decidable returns the approval if it is pending and deciderID did not
+// request it
+func (s *ApprovalService) decidable(ctx context.Context, id, deciderID string) (*models.Approval, error) {
+	approval, err := s.pending(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if approval.RequesterID.String() == deciderID {
+		return nil, ErrSelfApproval
+	}
+	return approval, nil
+}
+
+// decide moves approval to status on behalf of deciderID and runs apply in
+// the same transaction
+func (s *ApprovalService) decide(ctx context.Context, approval *models.Approval, status models.ApprovalStatus, deciderID, reason string, apply func(tx *ApprovalTx) error) (*ApprovalTx, error) {
+	decider, err := uuid.Parse(deciderID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	now := time.Now()
+	approval.Status, approval.DeciderID, approval.Reason, approval.DecidedAt = status, &decider, reason, &now
+
+	var applied *ApprovalTx
+	err = s.repo.Decide(ctx, approval, func(tx *gorm.DB) error {
+		applied = &ApprovalTx{DB: tx}
+		return apply(applied)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// notifyApprovers tells every active admin but the requester about a new
+// approval. Failures are logged; the approval stands regardless.
+func (s *ApprovalService) notifyApprovers(ctx context.Context, approval *models.Approval) {
+	if s.notifications == nil {
+		return
+	}
+	approvers, err := s.repo.Approvers(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to list approvers", logger.Field{Key: "approval_id", Value: approval.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+		return
+	}
+
+	data, _ := models.NewJSON(map[string]interface{}{
+		"approval_id": approval.ID.String(),
+		"action":      approval.Action,
+		"subject_id":  approval.SubjectID.String(),
+		"payload":     approval.Payload,
+	})
+	for _, approver := range approvers {
+		if approver == approval.RequesterID {
+			continue
+		}
+		notification := &models.Notification{
+			Type:  models.NotificationTypeApprovalRequested,
+			Title: "A change awaits your approval",
+			Body:  fmt.Sprintf("%s requested by another admin; it expires at %s", approval.Action, approval.ExpiresAt.UTC().Format(time.RFC3339)),
+			Data:  data,
+		}
+		if err := s.notifications.Notify(ctx, approver.String(), notification); err != nil {
+			s.logger.Warn("Failed to notify approver", logger.Field{Key: "approval_id", Value: approval.ID.String()}, logger.Field{Key: "user_id", Value: approver.String()}, logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+}
+
+// notifyRequester tells the requester how their approval was decided
+func (s *ApprovalService) notifyRequester(ctx context.Context, approval *models.Approval) {
+	if s.notifications == nil {
+		return
+	}
+	data, _ := models.NewJSON(map[string]interface{}{
+		"approval_id": approval.ID.String(),
+		"action":      approval.Action,
+		"status":      approval.Status,
+	})
+	notification := &models.Notification{
+		Type:  models.NotificationTypeApprovalDecided,
+		Title: fmt.Sprintf("Your %s request was %s", approval.Action, approval.Status),
+		Body:  approval.Reason,
+		Data:  data,
+	}
+	if err := s.notifications.Notify(ctx, approval.RequesterID.String(), notification); err != nil {
+		s.logger.Warn("Failed to notify requester", logger.Field{Key: "approval_id", Value: approval.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+	}
+}
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// auditChainBatchSize is how many audit entries export and verification
+// read per query
+const auditChainBatchSize = 500
+
+// auditTimeLayout is how entry times are written in the hashed document.
+// Times are stored to the millisecond, which every supported database keeps.
+const auditTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// Reasons an audit chain is broken
+const (
+	// AuditBreakMissingEntry: the entry with the sequence is gone
+	AuditBreakMissingEntry = "missing_entry"
+	// AuditBreakPrevHash: the entry does not link to the one before it
+	AuditBreakPrevHash = "prev_hash_mismatch"
+	// AuditBreakActor: the actor no longer matches the digest hashed
+	AuditBreakActor = "actor_mismatch"
+	// AuditBreakEntryHash: the entry changed after it was recorded
+	AuditBreakEntryHash = "entry_hash_mismatch"
+	// AuditBreakHead: the latest entry is not the one the chain head names
+	AuditBreakHead = "head_mismatch"
+)
+
+// AuditLogFilter narrows audit entries to a time range; zero bounds are open
+type AuditLogFilter struct {
+	From time.Time
+	To   time.Time
+}
+
+// AuditChainBreak is the first place an audit chain fails verification
+type AuditChainBreak struct {
+	Sequence int64  `json:"sequence"`
+	EntryID  string `json:"entry_id,omitempty"`
+	Reason   string `json:"reason"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// AuditVerification reports a walk of the audit chain. Checked counts the
+// chained entries walked from FirstSequence to LastSequence.
+type AuditVerification struct {
+	Valid         bool             `json:"valid"`
+	Checked       int64            `json:"checked"`
+	FirstSequence int64            `json:"first_sequence,omitempty"`
+	LastSequence  int64            `json:"last_sequence,omitempty"`
+	Break         *AuditChainBreak `json:"break,omitempty"`
+}
+
+// auditDocument is what an entry's hash covers, with its fields in name
+// order
+type auditDocument struct {
+	Action      string          `json:"action"`
+	ActorDigest string          `json:"actor_digest"`
+	CreatedAt   string          `json:"created_at"`
+	EntityID    string          `json:"entity_id"`
+	EntityType  string          `json:"entity_type"`
+	ID          string          `json:"id"`
+	IPAddress   string          `json:"ip_address"`
+	Metadata    json.RawMessage `json:"metadata"`
+	PrevHash    string          `json:"prev_hash"`
+	Sequence    int64           `json:"sequence"`
+}
+
+// AuditEntryHash returns the hex SHA-256 of an entry's canonical JSON: the
+// document above without whitespace or HTML escaping, the time in UTC and
+// the metadata with its keys sorted at every level. The entry's PrevHash is
+// part of the document, which chains it to the entry before.
+func AuditEntryHash(entry *models.AuditLog) (string, error) {
+	doc := auditDocument{
+		Action:      entry.Action,
+		ActorDigest: entry.ActorDigest,
+		CreatedAt:   entry.CreatedAt.UTC().Format(auditTimeLayout),
+		EntityID:    entry.EntityID,
+		EntityType:  entry.EntityType,
+		ID:          entry.ID.String(),
+		IPAddress:   entry.IPAddress,
+		Metadata:    json.RawMessage("null"),
+		PrevHash:    entry.PrevHash,
+	}
+	if entry.Sequence != nil {
+		doc.Sequence = *entry.Sequence
+	}
+	if len(entry.Metadata) > 0 {
+		metadata, err := canonicalJSON(entry.Metadata)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode audit metadata: %w", err)
+		}
+		doc.Metadata = metadata
+	}
+
+	data, err := canonicalJSON(doc)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditActorDigest returns the hex SHA-256 of an actor ID, or "" for none
+func AuditActorDigest(actorID string) string {
+	if actorID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(actorID))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalJSON encodes v compactly without HTML escaping. Documents are
+// decoded first, so their object keys come out sorted and numbers as
+// written.
+func canonicalJSON(v interface{}) ([]byte, error) {
+	if raw, ok := v.(models.JSON); ok {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var decoded interface{}
+		if err := dec.Decode(&decoded); err != nil {
+			return nil, err
+		}
+		v = decoded
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// appendAuditEntry chains entry to the latest entry of the database and
+// inserts it. Incrementing the head first locks its row, so concurrent
+// writers append one at a time.
+func appendAuditEntry(tx *gorm.DB, entry *models.AuditLog) error {
+	head := tx.Model(&models.AuditChain{}).Where("id = ?", models.AuditChainLogs).
+		Updates(map[string]interface{}{"sequence": gorm.Expr("sequence + 1"), "updated_at": entry.CreatedAt})
+	if head.Error != nil {
+		return head.Error
+	}
+	if head.RowsAffected == 0 {
+		return fmt.Errorf("audit chain %q is missing; run the migrations", models.AuditChainLogs)
+	}
+
+	var chain models.AuditChain
+	if err := tx.Where("id = ?", models.AuditChainLogs).First(&chain).Error; err != nil {
+		return err
+	}
+	entry.Sequence = &chain.Sequence
+	entry.PrevHash = chain.Hash
+
+	hash, err := AuditEntryHash(entry)
+	if err != nil {
+		return err
+	}
+	entry.EntryHash = hash
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.AuditChain{}).Where("id = ?", models.AuditChainLogs).Update("hash", hash).Error
+}
+
+// Export writes the audit entries created in filter's range to w as
+// NDJSON, one entry per line with its sequence and hashes, and records the
+// export by actorID. Entries recorded before the chain come first, by time;
+// chained ones follow in sequence order.
+func (s *AuditService) Export(ctx context.Context, w io.Writer, filter AuditLogFilter, actorID string) (int64, error) {
+	written, err := s.export(ctx, w, filter)
+	if err != nil {
+		return written, err
+	}
+
+	metadata := map[string]interface{}{"entries": written}
+	if !filter.From.IsZero() {
+		metadata["from"] = filter.From.UTC()
+	}
+	if !filter.To.IsZero() {
+		metadata["to"] = filter.To.UTC()
+	}
+	if err := s.Record(ctx, actorID, models.AuditActionAuditExported, "audit_log", "", metadata); err != nil {
+		return written, err
+	}
+	return written, nil
+}
+
+// export writes the entries of an Export
+func (s *AuditService) export(ctx context.Context, w io.Writer, filter AuditLogFilter) (int64, error) {
+	db, err := database.GormFor(ctx, s.db)
+	if err != nil {
+		return 0, err
+	}
+	inRange := func() *gorm.DB {
+		query := db.Model(&models.AuditLog{})
+		if !filter.From.IsZero() {
+			query = query.Where("created_at >= ?", filter.From)
+		}
+		if !filter.To.IsZero() {
+			query = query.Where("created_at < ?", filter.To)
+		}
+		return query
+	}
+
+	var written int64
+	writeBatch := func(entries []*models.AuditLog) error {
+		for _, entry := range entries {
+			line, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return err
+			}
+			written++
+		}
+		return nil
+	}
+
+	// Unchained entries, paged by time and ID
+	var last *models.AuditLog
+	for {
+		query := inRange().Where("sequence IS NULL")
+		if last != nil {
+			query = query.Where("created_at > ? OR (created_at = ? AND id > ?)", last.CreatedAt, last.CreatedAt, last.ID)
+		}
+		var entries []*models.AuditLog
+		if err := query.Order("created_at ASC, id ASC").Limit(auditChainBatchSize).Find(&entries).Error; err != nil {
+			return written, fmt.Errorf("database error: %w", err)
+		}
+		if err := writeBatch(entries); err != nil {
+			return written, err
+		}
+		if len(entries) < auditChainBatchSize {
+			break
+		}
+		last = entries[len(entries)-1]
+	}
+
+	var after int64
+	for {
+		var entries []*models.AuditLog
+		if err := inRange().Where("sequence > ?", after).
+			Order("sequence ASC").Limit(auditChainBatchSize).Find(&entries).Error; err != nil {
+			return written, fmt.Errorf("database error: %w", err)
+		}
+		if err := writeBatch(entries); err != nil {
+			return written, err
+		}
+		if len(entries) < auditChainBatchSize {
+			return written, nil
+		}
+		after = *entries[len(entries)-1].Sequence
+	}
+}
+
+// VerifyChain walks the chained entries created in filter's range and
+// reports the first broken link. Each entry must follow the one before it
+// without a gap, link to its hash, match its actor digest and hash to its
+// EntryHash. The entry before the range is checked against too; when the
+// chain's oldest entries were purged, the oldest remaining one is trusted.
+// Walks reaching the latest entry check it against the chain head.
+func (s *AuditService) VerifyChain(ctx context.Context, filter AuditLogFilter) (*AuditVerification, error) {
+	db, err := database.GormFor(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var bounds struct {
+		FirstSequence *int64
+		LastSequence  *int64
+	}
+	query := db.Model(&models.AuditLog{}).Where("sequence IS NOT NULL")
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	if err := query.Select("MIN(sequence) AS first_sequence, MAX(sequence) AS last_sequence").Scan(&bounds).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	var head models.AuditChain
+	if err := db.Where("id = ?", models.AuditChainLogs).First(&head).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("audit chain %q is missing; run the migrations", models.AuditChainLogs)
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	result := &AuditVerification{Valid: true}
+	broken := func(b AuditChainBreak) (*AuditVerification, error) {
+		result.Valid = false
+		result.Break = &b
+		return result, nil
+	}
+
+	if bounds.FirstSequence == nil {
+		// Nothing chained in the range. A range open at the end still has
+		// to reach the latest entry the head counts.
+		if filter.To.IsZero() {
+			var newest int64
+			if err := db.Model(&models.AuditLog{}).Select("COALESCE(MAX(sequence), 0)").Scan(&newest).Error; err != nil {
+				return nil, fmt.Errorf("database error: %w", err)
+			}
+			if newest < head.Sequence {
+				return broken(AuditChainBreak{Sequence: newest + 1, Reason: AuditBreakMissingEntry})
+			}
+		}
+		return result, nil
+	}
+	first, last := *bounds.FirstSequence, *bounds.LastSequence
+	result.FirstSequence, result.LastSequence = first, last
+
+	// The hash the first entry has to link to, unless the chain starts
+	// there
+	expectedPrev, anchored := "", first == 1
+	if first > 1 {
+		var before []*models.AuditLog
+		if err := db.Where("sequence < ?", first).Order("sequence DESC").Limit(1).Find(&before).Error; err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		switch {
+		case len(before) == 0:
+			// The oldest entries were purged; trust the link of the oldest left
+		case *before[0].Sequence != first-1:
+			return broken(AuditChainBreak{Sequence: first - 1, Reason: AuditBreakMissingEntry})
+		default:
+			expectedPrev, anchored = before[0].EntryHash, true
+		}
+	}
+
+	next := first
+	for next <= last {
+		var entries []*models.AuditLog
+		if err := db.Where("sequence >= ? AND sequence <= ?", next, last).
+			Order("sequence ASC").Limit(auditChainBatchSize).Find(&entries).Error; err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		if len(entries) == 0 {
+			return broken(AuditChainBreak{Sequence: next, Reason: AuditBreakMissingEntry})
+		}
+
+		for _, entry := range entries {
+			if *entry.Sequence != next {
+				return broken(AuditChainBreak{Sequence: next, Reason: AuditBreakMissingEntry})
+			}
+			if anchored && entry.PrevHash != expectedPrev {
+				return broken(AuditChainBreak{Sequence: next, EntryID: entry.ID.String(), Reason: AuditBreakPrevHash, Expected: expectedPrev, Actual: entry.PrevHash})
+			}
+			if entry.ActorID != nil && AuditActorDigest(entry.ActorID.String()) != entry.ActorDigest {
+				return broken(AuditChainBreak{Sequence: next, EntryID: entry.ID.String(), Reason: AuditBreakActor})
+			}
+			hash, err := AuditEntryHash(entry)
+			if err != nil {
+				return broken(AuditChainBreak{Sequence: next, EntryID: entry.ID.String(), Reason: AuditBreakEntryHash, Expected: entry.EntryHash})
+			}
+			if hash != entry.EntryHash {
+				return broken(AuditChainBreak{Sequence: next, EntryID: entry.ID.String(), Reason: AuditBreakEntryHash, Expected: entry.EntryHash, Actual: hash})
+			}
+
+			expectedPrev, anchored = entry.EntryHash, true
+			result.Checked++
+			next++
+		}
+	}
+
+	// Entries removed from the end of the chain leave the head ahead of it
+	if filter.To.IsZero() || last >= head.Sequence {
+		switch {
+		case head.Sequence > last:
+			return broken(AuditChainBreak{Sequence: last + 1, Reason: AuditBreakMissingEntry})
+		case head.Sequence < last || head.Hash != expectedPrev:
+			return broken(AuditChainBreak{Sequence: last, Reason: AuditBreakHead, Expected: head.Hash, Actual: expectedPrev})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/audit"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/geoip"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ClientInfo describes the client that issued a request
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+
+	// DeviceID is the identifier of a trusted device the client presented
+	// at login, if any
+	DeviceID string
+	// RememberMe asks login to trust the device and issue a longer-lived token
+	RememberMe bool
+	// Location is where IPAddress is, which login resolves before it
+	// starts the session
+	Location geoip.Location
+}
+
+// AuditService records and queries audit logs and login events
+type AuditService struct {
+	db      *database.Manager
+	forward *audit.Queue
+	logger  logger.Logger
+}
+
+// AuditOption configures an AuditService
+type AuditOption func(s *AuditService)
+
+// WithAuditForwarding also queues every recorded entry and login event on
+// queue, which sends them to a SIEM
+func WithAuditForwarding(queue *audit.Queue) AuditOption {
+	return func(s *AuditService) {
+		s.forward = queue
+	}
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(db *database.Manager, log logger.Logger, opts ...AuditOption) *AuditService {
+	s := &AuditService{
+		db:     db,
+		logger: log,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Record writes an audit entry, chained to the latest entry of the
+// database. actorID may be empty for system actions.
+func (s *AuditService) Record(ctx context.Context, actorID, action, entityType, entityID string, metadata interface{}) error {
+	entry, err := newAuditEntry(actorID, action, entityType, entityID, metadata)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.GormFor(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return appendAuditEntry(tx, entry)
+	}); err != nil {
+		s.logger.Error("Failed to record audit log",
+			logger.Field{Key: "action", Value: action},
+			logger.Field{Key: "entity_id", Value: entityID},
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+		return fmt.Errorf("failed to record audit log: %w", err)
+	}
+
+	s.forwardEntries(entry)
+	return nil
+}
+
+// newAuditEntry builds an unchained audit entry. actorID may be empty for
+// system actions.
+func newAuditEntry(actorID, action, entityType, entityID string, metadata interface{}) (*models.AuditLog, error) {
+	entry := &models.AuditLog{
+		ID:         uuid.New(),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		CreatedAt:  time.Now().UTC().Truncate(time.Millisecond),
+	}
+
+	if actorID != "" {
+		if parsed, err := uuid.Parse(actorID); err == nil {
+			entry.ActorID = &parsed
+			entry.ActorDigest = AuditActorDigest(parsed.String())
+		}
+	}
+
+	if metadata != nil {
+		data, err := models.NewJSON(metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode audit metadata: %w", err)
+		}
+		entry.Metadata = data
+	}
+	return entry, nil
+}
+
+// forwardEntries hands committed entries to the audit forwarder, if any
+func (s *AuditService) forwardEntries(entries ...*models.AuditLog) {
+	if s.forward == nil {
+		return
+	}
+	for _, entry := range entries {
+		forwarded := audit.Entry{
+			ID:         entry.ID.String(),
+			Action:     entry.Action,
+			EntityType: entry.EntityType,
+			EntityID:   entry.EntityID,
+			Metadata:   []byte(entry.Metadata),
+			IPAddress:  entry.IPAddress,
+			CreatedAt:  entry.CreatedAt,
+		}
+		if entry.ActorID != nil {
+			forwarded.ActorID = entry.ActorID.String()
+		}
+		s.forward.Enqueue(forwarded)
+	}
+}
+
+// ListForUser returns audit entries where the user is the actor or the subject
+func (s *AuditService) ListForUser(ctx context.Context, userID string) ([]*models.AuditLog, error) {
+	db, err := database.GormFor(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*models.AuditLog
+	if err := db.Where("actor_id = ? OR (entity_type = ? AND entity_id = ?)", userID, "user", userID).
+		Order("created_at DESC").
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return entries, nil
+}
+
+// RecordLogin writes a login event. userID is nil when the email is unknown.
+func (s *AuditService) RecordLogin(ctx context.Context, userID *uuid.UUID, email string, success bool, reason string, client ClientInfo) error {
+	event := &models.LoginEvent{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Email:     email,
+		Success:   success,
+		Reason:    reason,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		CreatedAt: time.Now(),
+	}
+
+	db, err := database.GormFor(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	if err := db.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record login event: %w", err)
+	}
+
+	if s.forward != nil {
+		s.forward.Enqueue(loginAuditEntry(event))
+	}
+	return nil
+}
+
+// loginAuditEntry returns the entry a login event is forwarded as
+func loginAuditEntry(event *models.LoginEvent) audit.Entry {
+	entry := audit.Entry{
+		ID:         event.ID.String(),
+		Action:     models.AuditActionLoginFailed,
+		EntityType: "user",
+		IPAddress:  event.IPAddress,
+		CreatedAt:  event.CreatedAt,
+	}
+	if event.Success {
+		entry.Action = models.AuditActionLoginSucceeded
+	}
+	if event.UserID != nil {
+		entry.EntityID = event.UserID.String()
+	}
+	entry.Metadata, _ = json.Marshal(map[string]string{
+		"email":      event.Email,
+		"reason":     event.Reason,
+		"user_agent": event.UserAgent,
+	})
+	return entry
+}
+
+// ListLoginEvents returns the login events of a user, newest first
+func (s *AuditService) ListLoginEvents(ctx context.Context, userID string) ([]*models.LoginEvent, error) {
+	db, err := database.GormFor(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*models.LoginEvent
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return events, nil
+}
+
+// PurgeBefore deletes audit logs and login events created before cutoff and
+// returns the number of rows removed
+func (s *AuditService) PurgeBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	db, err := database.GormFor(ctx, s.db)
+	if err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, model := range []interface{}{&models.AuditLog{}, &models.LoginEvent{}} {
+		result := db.Where("created_at < ?", cutoff).Delete(model)
+		if result.Error != nil {
+			return purged, fmt.Errorf("failed to purge audit records: %w", result.Error)
+		}
+		purged += result.RowsAffected
+	}
+	return purged, nil
+}
+
+// Health checks if the service is healthy
+func (s *AuditService) Health(ctx context.Context) error {
+	return s.db.Health(ctx)["primary"]
+}
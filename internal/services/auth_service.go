@@ -5,11 +5,21 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
 	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/geoip"
+	"BackofficeGoService/internal/pkg/identifier"
+	"BackofficeGoService/internal/pkg/jwtutil"
 	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+	"BackofficeGoService/internal/pkg/requestctx"
 	"BackofficeGoService/internal/pkg/utils"
 
 	"BackofficeGoService/config"
@@ -19,26 +29,303 @@ import (
 	"gorm.io/gorm"
 )
 
+// activeStatusCacheTTL bounds how long a deactivation or forced logout can
+// take to reach token validation on other instances
+const activeStatusCacheTTL = time.Minute
+
+// Scopes of restricted tokens, which only the routes accepting their scope
+// take
+const (
+	// ScopePasswordChange is the scope of tokens issued to users who must
+	// change their password; they are only accepted by the change-password
+	// endpoint
+	ScopePasswordChange = "password_change"
+	// ScopeProfileCompletion is the scope of tokens issued to users who must
+	// complete their profile; they are only accepted by GET and PUT /me
+	ScopeProfileCompletion = "profile_completion"
+)
+
+// ScopeError is the error refusing a token restricted to scope on routes
+// that do not accept it
+func ScopeError(scope string) error {
+	switch scope {
+	case ScopePasswordChange:
+		return ErrPasswordChangeRequired
+	case ScopeProfileCompletion:
+		return ErrProfileIncomplete
+	default:
+		return ErrInvalidToken
+	}
+}
+
+// SigningMethod signs and verifies access and refresh tokens
+var SigningMethod = jwtutil.SigningMethod
+
 // AuthService handles authentication business logic
 type AuthService struct {
-	db     *database.Manager
-	config *config.Config
-	logger logger.Logger
+	db       *database.Manager
+	config   *config.Config
+	cache    cache.Store
+	revoker  *TokenRevoker
+	audit    *AuditService
+	orgs     *OrganizationService
+	sessions *SessionService
+	devices  *TrustedDeviceService
+	policies *PolicyService
+	profiles *ProfileService
+	alerts   *SignInAlertService
+	logins   events.Publisher
+	metrics  *metrics.Business
+	logger   logger.Logger
+	clock    clock.Clock
+	tokens   *jwtutil.Manager
+	// passwords, if set, screens the passwords users register or change to
+	passwords PasswordPolicy
+	// responses, if set, drops cached user listings on registration
+	responses *ResponseCache
+	ids       identifier.Generator
+	// geo, if set, locates logins, waiting at most geoTimeout
+	geo        geoip.Resolver
+	geoTimeout time.Duration
+}
+
+// AuthOption configures an AuthService
+type AuthOption func(s *AuthService)
+
+// WithAuthClock sets the clock used to issue and validate tokens
+func WithAuthClock(c clock.Clock) AuthOption {
+	return func(s *AuthService) {
+		s.clock = c
+	}
+}
+
+// WithAuthIDGenerator sets how the IDs of registered users are generated;
+// by default they are random UUIDv4s
+func WithAuthIDGenerator(ids identifier.Generator) AuthOption {
+	return func(s *AuthService) {
+		s.ids = ids
+	}
+}
+
+// WithSessions tracks every login as a session whose ID the tokens carry in
+// the sid claim, so that revoking the session revokes its tokens
+func WithSessions(sessions *SessionService) AuthOption {
+	return func(s *AuthService) {
+		s.sessions = sessions
+	}
+}
+
+// WithTrustedDevices lets remember_me logins trust the client's device.
+// Their tokens carry the device's ID in the did claim and last
+// JWT_REMEMBER_EXPIRATION.
+func WithTrustedDevices(devices *TrustedDeviceService) AuthOption {
+	return func(s *AuthService) {
+		s.devices = devices
+	}
+}
+
+// WithPolicies lists the policies the user has yet to accept in login responses
+func WithPolicies(policies *PolicyService) AuthOption {
+	return func(s *AuthService) {
+		s.policies = policies
+	}
+}
+
+// WithProfiles reports in login responses how complete the user's profile
+// is. With AUTH_PROFILE_ENFORCE, users missing a required field are only
+// issued a token for completing it.
+func WithProfiles(profiles *ProfileService) AuthOption {
+	return func(s *AuthService) {
+		s.profiles = profiles
+	}
+}
+
+// WithSignInAlerts emails users logging in from a new device or IP range,
+// and lets SecureAccount redeem the tokens the emails link with
+func WithSignInAlerts(alerts *SignInAlertService) AuthOption {
+	return func(s *AuthService) {
+		s.alerts = alerts
+	}
+}
+
+// WithLoginEvents publishes every login attempt to publisher as a
+// events.LoginSucceeded or events.LoginFailed event
+func WithLoginEvents(publisher events.Publisher) AuthOption {
+	return func(s *AuthService) {
+		s.logins = publisher
+	}
+}
+
+// WithGeoIP locates the address of every successful login with resolver,
+// for its session, sign-in alert and login event. A lookup slower than
+// timeout is abandoned and the location left unknown, so it never holds
+// up the login for longer.
+func WithGeoIP(resolver geoip.Resolver, timeout time.Duration) AuthOption {
+	return func(s *AuthService) {
+		s.geo = resolver
+		s.geoTimeout = timeout
+	}
+}
+
+// WithAuthPasswordPolicy screens the passwords users register with and
+// change to
+func WithAuthPasswordPolicy(policy PasswordPolicy) AuthOption {
+	return func(s *AuthService) {
+		s.passwords = policy
+	}
+}
+
+// WithAuthResponseCache purges the cached responses listing users when a
+// user registers or changes their password
+func WithAuthResponseCache(r *ResponseCache) AuthOption {
+	return func(s *AuthService) {
+		s.responses = r
+	}
+}
+
+// WithAuthMetrics counts registrations, logins and password changes
+func WithAuthMetrics(m *metrics.Business) AuthOption {
+	return func(s *AuthService) {
+		s.metrics = m
+	}
+}
+
+// TokenClaims holds the validated claims of an access token
+type TokenClaims struct {
+	UserID    string
+	Email     string
+	Role      string
+	OrgIDs    []string
+	TenantID  string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+
+	// ImpersonatorID is the admin acting as UserID, empty for the user's own tokens
+	ImpersonatorID string
+
+	// Scope restricts what the token may do; empty for unrestricted tokens
+	Scope string
+
+	// APIScope names what an API token issued with IssueAPIToken is for,
+	// such as "automation". It does not restrict the token; empty for
+	// tokens issued by logging in.
+	APIScope string
+
+	// SessionID is the login the token belongs to; impersonation tokens
+	// carry the impersonator's. Empty for tokens issued without sessions.
+	SessionID string
+
+	// DeviceID is the trusted device of a remember_me login; empty otherwise
+	DeviceID string
+
+	// TokenVersion is the user's token version when the token was issued, and
+	// ImpersonatorTokenVersion the impersonator's. Tokens of older versions
+	// are refused.
+	TokenVersion             int
+	ImpersonatorTokenVersion int
+}
+
+// Impersonating reports whether the token was issued by impersonating the user
+func (c *TokenClaims) Impersonating() bool {
+	return c.ImpersonatorID != ""
+}
+
+// Restricted reports whether the token may only be used for the task its
+// scope names, such as changing the password
+func (c *TokenClaims) Restricted() bool {
+	return c.Scope != ""
+}
+
+// AuthResult is the response to a successful login or token refresh
+type AuthResult struct {
+	Token string       `json:"token"`
+	User  *models.User `json:"user,omitempty"`
+
+	// PasswordExpired is set when Token is restricted to changing the password
+	PasswordExpired bool `json:"password_expired,omitempty"`
+	// ProfileCompletionRequired is set when Token is restricted to
+	// completing the profile
+	ProfileCompletionRequired bool `json:"profile_completion_required,omitempty"`
+	// ProfileStatus, when profiles are evaluated, lists the required fields
+	// the user left blank
+	*ProfileStatus
+
+	// TrustedDevice is set when the login presented the identifier of a
+	// device the user trusted before
+	TrustedDevice bool `json:"trusted_device,omitempty"`
+	// DeviceID is the identifier to present on later logins from a device
+	// just trusted with remember_me
+	DeviceID string `json:"device_id,omitempty"`
+
+	// PendingPolicies lists the policies whose current version the user has
+	// yet to accept; only logins report it
+	PendingPolicies []PendingPolicy `json:"pending_policies,omitempty"`
+}
+
+// PasswordExpired reports whether the user's password is older than maxAge
+// at now. Passwords never set count from the user's creation; a maxAge of
+// zero disables expiry.
+func PasswordExpired(user *models.User, maxAge time.Duration, now time.Time) bool {
+	if maxAge <= 0 {
+		return false
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return !now.Before(changedAt.Add(maxAge))
 }
 
 // NewAuthService creates a new auth service
-func NewAuthService(db *database.Manager, cfg *config.Config, log logger.Logger) *AuthService {
-	return &AuthService{
-		db:     db,
-		config: cfg,
-		logger: log,
+func NewAuthService(db *database.Manager, cfg *config.Config, store cache.Store, revoker *TokenRevoker, audit *AuditService, orgs *OrganizationService, log logger.Logger, opts ...AuthOption) *AuthService {
+	s := &AuthService{
+		db:      db,
+		config:  cfg,
+		cache:   store,
+		revoker: revoker,
+		audit:   audit,
+		orgs:    orgs,
+		logger:  log,
+		clock:   clock.New(),
+		ids:     identifier.Default,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.tokens = NewTokenManager(cfg.JWT, s.clock.Now, jwtutil.OnPreviousSecret(s.metrics.PreviousSecretVerified))
+	return s
+}
+
+// NewTokenManager creates the manager signing and verifying tokens with the
+// JWT settings of cfg and their tenant overrides, and with the secrets of
+// the JWT key file when one is set
+func NewTokenManager(cfg config.JWTConfig, now func() time.Time, opts ...jwtutil.Option) *jwtutil.Manager {
+	tenants := make(map[string]jwtutil.Settings, len(cfg.Tenants))
+	for tenant, override := range cfg.Tenants {
+		tenants[tenant] = jwtutil.Settings{Secret: override.Secret, Issuer: override.Issuer, Audience: override.Audience}
+	}
+	if cfg.SecretFile != "" {
+		opts = append(opts, jwtutil.WithKeyFile(cfg.SecretFile, cfg.SecretFileRefresh))
 	}
+	return jwtutil.NewManager(jwtutil.Settings{Secret: cfg.Secret, Issuer: cfg.Issuer, Audience: cfg.Audience}, tenants, now, opts...)
+}
+
+// Tokens returns the manager signing and verifying the service's tokens
+func (s *AuthService) Tokens() *jwtutil.Manager {
+	return s.tokens
+}
+
+// MaxTokenLifetime is the longest any token issued under cfg stays valid
+func MaxTokenLifetime(cfg *config.Config) time.Duration {
+	return max(cfg.JWT.Expiration, cfg.JWT.RememberExpiration, cfg.JWT.ImpersonationExpiration, cfg.Auth.PasswordChangeExpiration, cfg.Auth.ProfileCompletionExpiration)
 }
 
-// Login authenticates a user with email and password
-func (s *AuthService) Login(ctx context.Context, email, password string) (map[string]interface{}, error) {
-	// Get primary database
-	primaryDriver, err := s.db.GetDriver("primary")
+// Login authenticates a user with email and password and records the attempt
+func (s *AuthService) Login(ctx context.Context, email, password string, client ClientInfo) (*AuthResult, error) {
+	email = EmailNormalization(s.config.Auth).Normalize(email)
+
+	// Get the tenant's database, or the primary one
+	driver, err := s.db.DriverFor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("database connection error: %w", err)
 	}
@@ -46,27 +333,33 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (map[st
 	var user models.User
 
 	// Check if using GORM
-	if gormDB := primaryDriver.GetGormDB(); gormDB != nil {
-		db := gormDB.(*gorm.DB)
-		if err := db.WithContext(ctx).Where("email = ? AND active = ?", email, true).First(&user).Error; err != nil {
+	if db := database.NativeGorm(driver); db != nil {
+		if err := db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
+				s.recordLogin(ctx, nil, email, false, "unknown_email", client)
 				return nil, errors.New("invalid credentials")
 			}
 			return nil, fmt.Errorf("database error: %w", err)
 		}
 	} else {
 		// Use raw SQL
-		sqlDB := primaryDriver.GetSQLDB()
-		query := `SELECT id, email, username, password, first_name, last_name, role, active, created_at, updated_at 
-		          FROM users WHERE email = $1 AND active = $2`
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return nil, err
+		}
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), `SELECT id, email, username, password, first_name, last_name, role, active, created_at, updated_at, anonymized_at,
+		                 password_changed_at, must_change_password, token_version
+		          FROM users WHERE email = ?`))
 
-		err := sqlDB.QueryRowContext(ctx, query, email, true).Scan(
+		err = sqlDB.QueryRowContext(ctx, query, email).Scan(
 			&user.ID, &user.Email, &user.Username, &user.Password,
 			&user.FirstName, &user.LastName, &user.Role, &user.Active,
-			&user.CreatedAt, &user.UpdatedAt,
+			&user.CreatedAt, &user.UpdatedAt, &user.AnonymizedAt,
+			&user.PasswordChangedAt, &user.MustChangePassword, &user.TokenVersion,
 		)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
+				s.recordLogin(ctx, nil, email, false, "unknown_email", client)
 				return nil, errors.New("invalid credentials")
 			}
 			return nil, fmt.Errorf("database error: %w", err)
@@ -75,146 +368,949 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (map[st
 
 	// Verify password
 	if !utils.CheckPasswordHash(password, user.Password) {
+		s.recordLogin(ctx, &user.ID, email, false, "invalid_password", client)
 		return nil, errors.New("invalid credentials")
 	}
 
-	// Generate JWT token
-	token, err := s.generateToken(user.ID.String(), user.Email, string(user.Role))
+	// Deactivated accounts are only reported once the password is proven
+	if !user.Active {
+		s.recordLogin(ctx, &user.ID, email, false, "account_deactivated", client)
+		return nil, ErrAccountDeactivated
+	}
+
+	return s.issueLogin(ctx, &user, client, true)
+}
+
+// LoginAs logs in a user authenticated elsewhere, such as by an OpenID
+// Connect provider, as Login does once the password is proven. Deactivated
+// users are refused; password expiry does not apply.
+func (s *AuthService) LoginAs(ctx context.Context, user *models.User, client ClientInfo) (*AuthResult, error) {
+	if !user.Active {
+		s.recordLogin(ctx, &user.ID, user.Email, false, "account_deactivated", client)
+		return nil, ErrAccountDeactivated
+	}
+	return s.issueLogin(ctx, user, client, false)
+}
+
+// issueLogin issues the token of an authenticated user, starting a session
+// and trusting the device as the client asked. With password set, an
+// expired or flagged password restricts the token to changing it; an
+// incomplete profile restricts it to completing the profile when enforced.
+func (s *AuthService) issueLogin(ctx context.Context, user *models.User, client ClientInfo, password bool) (*AuthResult, error) {
+	orgIDs, err := s.userOrganizationIDs(ctx, user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// An expired or flagged password, though proven, only buys a token for
+	// changing it, which comes before completing the profile
+	result := &AuthResult{User: user}
+	result.PasswordExpired = password && (user.MustChangePassword || PasswordExpired(user, s.config.Auth.PasswordMaxAge, s.clock.Now()))
+	scope := ""
+	if result.PasswordExpired {
+		scope = ScopePasswordChange
+	}
+	if s.profiles != nil {
+		status := s.profiles.Evaluate(user)
+		result.ProfileStatus = &status
+		if scope == "" && s.profileScope(status) != "" {
+			scope = ScopeProfileCompletion
+			result.ProfileCompletionRequired = true
+		}
+	}
+	ttl := s.scopeTTL(scope, "")
+	// Restricted logins neither use nor trust devices
+	var device *models.TrustedDevice
+	if s.devices != nil && scope == "" {
+		if device, err = s.devices.Recognize(ctx, user.ID, client.DeviceID); err != nil {
+			return nil, err
+		}
+		result.TrustedDevice = device != nil
+		if client.RememberMe {
+			if device == nil {
+				device, result.DeviceID, err = s.devices.Trust(ctx, user.ID, client)
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				result.DeviceID = client.DeviceID
+			}
+			ttl = s.tokenTTL(device.ID.String())
+		} else {
+			device = nil
+		}
+	}
+	claims := s.tokenClaims(user.ID.String(), user.Email, string(user.Role), user.TokenVersion, orgIDs, ttl)
+	if scope != "" {
+		claims["scope"] = scope
+	}
+	deviceID := uuid.Nil
+	if device != nil {
+		deviceID = device.ID
+		claims["did"] = device.ID.String()
+	}
+	client.Location = geoip.ResolveWithin(ctx, s.geo, client.IPAddress, s.geoTimeout)
+	if s.sessions != nil {
+		session, err := s.sessions.Start(ctx, user.ID.String(), client, deviceID, s.clock.Now().Add(ttl))
+		if err != nil {
+			return nil, err
+		}
+		claims["sid"] = session.ID.String()
+	}
+	result.Token, err = s.signToken(ctx, claims)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
+	if s.policies != nil {
+		if result.PendingPolicies, err = s.policies.Pending(ctx, user.ID.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	s.recordLogin(ctx, &user.ID, user.Email, true, "", client)
+	if s.alerts != nil {
+		s.alerts.Observe(ctx, user, client)
+	}
+
 	// Remove password from response
 	user.Password = ""
 
-	return map[string]interface{}{
-		"token": token,
-		"user":  user,
-	}, nil
+	return result, nil
 }
 
 // Register registers a new user
-func (s *AuthService) Register(ctx context.Context, req interface{}) (*models.User, error) {
-	// Type assert request
-	registerReq, ok := req.(map[string]interface{})
-	if !ok {
-		// Try to get from struct if needed
-		return nil, errors.New("invalid request format")
+func (s *AuthService) Register(ctx context.Context, req *CreateUserRequest) (*models.User, error) {
+	if req.Email == "" || req.Password == "" {
+		return nil, errors.New("email and password are required")
 	}
 
-	email, _ := registerReq["email"].(string)
-	password, _ := registerReq["password"].(string)
-	firstName, _ := registerReq["first_name"].(string)
-	lastName, _ := registerReq["last_name"].(string)
-	username, _ := registerReq["username"].(string)
-
-	if email == "" || password == "" {
-		return nil, errors.New("email and password are required")
+	// Get the tenant's database, or the primary one
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
 	}
 
-	// Hash password
-	hashedPassword, err := utils.HashPassword(password)
+	email := EmailNormalization(s.config.Auth).Normalize(req.Email)
+	taken, err := emailTaken(ctx, s.db, driver, email)
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash password: %w", err)
+		return nil, err
+	}
+	if taken {
+		return nil, ErrEmailTaken
+	}
+	if err := checkPassword(ctx, s.passwords, req.Password); err != nil {
+		return nil, err
 	}
 
-	// Get primary database
-	primaryDriver, err := s.db.GetDriver("primary")
+	// Hash password
+	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		return nil, fmt.Errorf("database connection error: %w", err)
+		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
+	now := s.clock.Now()
 	user := models.User{
-		ID:        uuid.New(),
-		Email:     email,
-		Username:  username,
-		Password:  hashedPassword,
-		FirstName: firstName,
-		LastName:  lastName,
-		Role:      models.RoleUser,
-		Active:    true,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:                s.ids.New(),
+		Email:             email,
+		Username:          req.Username,
+		Password:          hashedPassword,
+		FirstName:         req.FirstName,
+		LastName:          req.LastName,
+		Role:              models.RoleUser,
+		Active:            true,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		PasswordChangedAt: &now,
 	}
 
 	// Check if using GORM
-	if gormDB := primaryDriver.GetGormDB(); gormDB != nil {
-		db := gormDB.(*gorm.DB)
+	if db := database.NativeGorm(driver); db != nil {
 		if err := db.WithContext(ctx).Create(&user).Error; err != nil {
 			return nil, fmt.Errorf("failed to create user: %w", err)
 		}
 	} else {
 		// Use raw SQL
-		sqlDB := primaryDriver.GetSQLDB()
-		query := `INSERT INTO users (id, email, username, password, first_name, last_name, role, active, created_at, updated_at)
-		          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return nil, err
+		}
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), `INSERT INTO users (id, email, username, password, first_name, last_name, role, active, created_at, updated_at, password_changed_at)
+		          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`))
 
-		_, err := sqlDB.ExecContext(ctx, query,
+		_, err = sqlDB.ExecContext(ctx, query,
 			user.ID, user.Email, user.Username, user.Password,
 			user.FirstName, user.LastName, user.Role, user.Active,
-			user.CreatedAt, user.UpdatedAt,
+			user.CreatedAt, user.UpdatedAt, user.PasswordChangedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create user: %w", err)
 		}
+		database.MarkWritten(ctx)
 	}
 
 	// Remove password from response
 	user.Password = ""
+	s.responses.Purge(ctx, ResponseGroupUsers)
+
+	// Self-registration: the new user is their own actor
+	if err := s.audit.Record(ctx, user.ID.String(), models.AuditActionUserCreated, "user", user.ID.String(), nil); err != nil {
+		s.log(ctx).Warn("Failed to audit registration", logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	s.metrics.Registered()
 
 	return &user, nil
 }
 
 // RefreshToken refreshes an access token
-func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (map[string]interface{}, error) {
-	// Parse and validate refresh token
-	claims, err := utils.VerifyToken(refreshToken)
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*AuthResult, error) {
+	// Parse and validate refresh token; revoked tokens and deactivated users are rejected
+	claims, err := s.ValidateToken(ctx, refreshToken)
 	if err != nil {
-		return nil, errors.New("invalid refresh token")
+		return nil, err
+	}
+	// Impersonation ends when its token expires
+	if claims.Impersonating() {
+		return nil, ErrImpersonationForbidden
+	}
+	// Restricted tokens end with the task they were issued for
+	if claims.Restricted() {
+		return nil, ScopeError(claims.Scope)
+	}
+	// API tokens end when they expire; another is issued in their place
+	if claims.APIScope != "" {
+		return nil, ErrInvalidToken
+	}
+	if claims.TenantID != database.TenantID(ctx) {
+		return nil, ErrInvalidToken
 	}
 
-	email, ok := (*claims)["email"].(string)
-	if !ok {
-		return nil, errors.New("invalid token claims")
+	// Memberships may have changed since the token was issued
+	orgIDs, err := s.userOrganizationIDs(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Generate new access token
-	userID, _ := (*claims)["user_id"].(string)
-	role, _ := (*claims)["role"].(string)
+	// Remembered tokens stop refreshing once their device is revoked
+	if claims.DeviceID != "" && s.devices != nil {
+		if err := s.devices.Touch(ctx, claims.DeviceID); err != nil {
+			return nil, err
+		}
+	}
+
+	// A revoked session cannot be refreshed, even before its tokens expire
+	if err := s.extendSession(ctx, claims.SessionID, s.tokenTTL(claims.DeviceID)); err != nil {
+		return nil, err
+	}
 
-	token, err := s.generateToken(userID, email, role)
+	// Generate new access token
+	token, err := s.generateToken(ctx, claims.UserID, claims.Email, claims.Role, claims.TokenVersion, orgIDs, claims.SessionID, claims.DeviceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
-	return map[string]interface{}{
-		"token": token,
-	}, nil
+	return &AuthResult{Token: token}, nil
+}
+
+// Logout ends the session of the token claims were read from, as revoking
+// it from the session list does, so its access and refresh tokens are
+// refused from the next request on. Impersonation tokens belong to the
+// impersonator's session, which ends the impersonation too. Tokens issued
+// without a session stay valid until they expire.
+func (s *AuthService) Logout(ctx context.Context, claims *TokenClaims) error {
+	if claims.SessionID == "" || s.sessions == nil {
+		return nil
+	}
+	owner := claims.UserID
+	if claims.Impersonating() {
+		owner = claims.ImpersonatorID
+	}
+	err := s.sessions.Revoke(ctx, owner, owner, claims.SessionID)
+	// The session ended since the token was checked
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	return err
 }
 
-// Logout logs out a user (can be extended to blacklist tokens)
-func (s *AuthService) Logout(ctx context.Context, token string) error {
-	// TODO: Implement token blacklisting if needed
-	// For now, just return success
+// RevokeAllTokens signs userID out everywhere on behalf of actorID. Bumping
+// the user's token version invalidates every access and refresh token issued
+// so far, including impersonations of and by the user, and every active
+// session is ended. Tokens issued afterwards are unaffected.
+func (s *AuthService) RevokeAllTokens(ctx context.Context, userID, actorID string) error {
+	id, err := identifier.Parse(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	userID = id.String()
+
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return fmt.Errorf("database connection error: %w", err)
+	}
+
+	var updated int64
+	if db := database.NativeGorm(driver); db != nil {
+		result := db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).
+			UpdateColumn("token_version", gorm.Expr("token_version + 1"))
+		if result.Error != nil {
+			return fmt.Errorf("failed to update user: %w", result.Error)
+		}
+		updated = result.RowsAffected
+	} else {
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return err
+		}
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), `UPDATE users SET token_version = token_version + 1 WHERE id = ?`))
+		result, err := sqlDB.ExecContext(ctx, query, userID)
+		if err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		database.MarkWritten(ctx)
+		if updated, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+	}
+	if updated == 0 {
+		return ErrUserNotFound
+	}
+	// The cached status holds the old version
+	_ = s.cache.Delete(ctx, userActiveCacheKey(userID))
+
+	if s.sessions != nil {
+		if _, err := s.sessions.RevokeOthers(ctx, actorID, userID, ""); err != nil {
+			return err
+		}
+	}
+
+	if err := s.audit.Record(ctx, actorID, models.AuditActionUserForcedLogout, "user", userID, nil); err != nil {
+		s.log(ctx).Warn("Failed to audit forced logout", logger.Field{Key: "user_id", Value: userID}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	return nil
+}
+
+// SecureAccount redeems the token of a new sign-in email, signing the user
+// it was sent to out everywhere as RevokeAllTokens does. An unknown, used or
+// expired token fails with ErrSecureAccountTokenInvalid.
+func (s *AuthService) SecureAccount(ctx context.Context, token string) error {
+	if s.alerts == nil {
+		return ErrSecureAccountTokenInvalid
+	}
+	userID, err := s.alerts.Redeem(ctx, token)
+	if err != nil {
+		return err
+	}
+	if err := s.RevokeAllTokens(ctx, userID, userID); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return ErrSecureAccountTokenInvalid
+		}
+		return err
+	}
 	return nil
 }
 
-// generateToken generates a JWT token
-func (s *AuthService) generateToken(userID, email, role string) (string, error) {
+// Impersonate issues a short-lived token for userID on behalf of the admin
+// impersonator. The token carries the impersonator_id claim, which the
+// routes that change passwords, roles or start impersonation reject.
+// Admins can only be impersonated if the configuration allows it.
+func (s *AuthService) Impersonate(ctx context.Context, impersonator *TokenClaims, userID string) (*AuthResult, error) {
+	if impersonator.Impersonating() {
+		return nil, ErrImpersonationForbidden
+	}
+	if impersonator.UserID == userID {
+		return nil, ErrSelfImpersonation
+	}
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		return nil, ErrAccountDeactivated
+	}
+	if user.Role == models.RoleAdmin && !s.config.JWT.AllowAdminImpersonation {
+		return nil, ErrAdminImpersonationDisabled
+	}
+
+	orgIDs, err := s.userOrganizationIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := s.config.JWT.ImpersonationExpiration
+	if ttl <= 0 || ttl > s.config.JWT.Expiration {
+		ttl = s.config.JWT.Expiration
+	}
+	claims := s.tokenClaims(userID, user.Email, string(user.Role), user.TokenVersion, orgIDs, ttl)
+	claims["impersonator_id"] = impersonator.UserID
+	// Signing the impersonator out everywhere ends the impersonation
+	claims["impersonator_token_version"] = impersonator.TokenVersion
+	// Revoking the impersonator's session ends the impersonation
+	if impersonator.SessionID != "" {
+		claims["sid"] = impersonator.SessionID
+	}
+	token, err := s.signToken(ctx, claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	// Impersonation that cannot be audited does not happen
+	metadata := map[string]interface{}{"expires_in": int(ttl.Seconds())}
+	if err := s.audit.Record(ctx, impersonator.UserID, models.AuditActionUserImpersonated, "user", userID, metadata); err != nil {
+		return nil, fmt.Errorf("failed to audit impersonation: %w", err)
+	}
+
+	user.Password = ""
+	return &AuthResult{Token: token, User: user}, nil
+}
+
+// IssueAPIToken issues userID a token for automation, with the API scope
+// scope and lasting ttl, or the configured token lifetime when ttl is not
+// positive. The token belongs to no session and cannot be refreshed; it
+// ends when it expires or the user's tokens are revoked. scope must not be
+// one of the scopes restricting tokens, and ttl must not exceed the longest
+// token lifetime, which rotated secrets are kept for.
+func (s *AuthService) IssueAPIToken(ctx context.Context, userID, scope string, ttl time.Duration) (*AuthResult, error) {
+	if scope == "" || scope == ScopePasswordChange || scope == ScopeProfileCompletion {
+		return nil, ErrInvalidAPIScope
+	}
+	if ttl <= 0 {
+		ttl = s.config.JWT.Expiration
+	}
+	if ttl > MaxTokenLifetime(s.config) {
+		return nil, ErrAPITokenLifetime
+	}
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		return nil, ErrAccountDeactivated
+	}
+
+	orgIDs, err := s.userOrganizationIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	claims := s.tokenClaims(userID, user.Email, string(user.Role), user.TokenVersion, orgIDs, ttl)
+	claims["api_scope"] = scope
+	token, err := s.signToken(ctx, claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	// API tokens that cannot be audited are not issued
+	metadata := map[string]interface{}{"scope": scope, "expires_in": int(ttl.Seconds())}
+	if err := s.audit.Record(ctx, "", models.AuditActionUserAPITokenIssued, "user", userID, metadata); err != nil {
+		return nil, fmt.Errorf("failed to audit API token: %w", err)
+	}
+
+	user.Password = ""
+	return &AuthResult{Token: token, User: user}, nil
+}
+
+// StopImpersonation ends the impersonation claims belong to and issues the
+// impersonator a token of their own
+func (s *AuthService) StopImpersonation(ctx context.Context, claims *TokenClaims) (*AuthResult, error) {
+	if !claims.Impersonating() {
+		return nil, ErrNotImpersonating
+	}
+
+	admin, err := s.findUser(ctx, claims.ImpersonatorID)
+	if err != nil {
+		return nil, err
+	}
+	if !admin.Active {
+		return nil, ErrAccountDeactivated
+	}
+
+	orgIDs, err := s.userOrganizationIDs(ctx, admin.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	// The impersonator goes back to the session they impersonated from
+	if err := s.extendSession(ctx, claims.SessionID, s.tokenTTL("")); err != nil {
+		return nil, err
+	}
+	token, err := s.generateToken(ctx, admin.ID.String(), admin.Email, string(admin.Role), admin.TokenVersion, orgIDs, claims.SessionID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	if err := s.audit.Record(ctx, admin.ID.String(), models.AuditActionImpersonationEnded, "user", claims.UserID, nil); err != nil {
+		s.log(ctx).Warn("Failed to audit end of impersonation", logger.Field{Key: "user_id", Value: claims.UserID}, logger.Field{Key: "error", Value: err.Error()})
+	}
+
+	admin.Password = ""
+	return &AuthResult{Token: token, User: admin}, nil
+}
+
+// ChangePassword replaces the password of the user claims belong to after
+// checking the current one, clears any forced change and returns an
+// unrestricted token, or one restricted to completing the profile when that
+// is enforced and the profile is incomplete. Impersonators cannot change
+// passwords.
+func (s *AuthService) ChangePassword(ctx context.Context, claims *TokenClaims, currentPassword, newPassword string) (*AuthResult, error) {
+	if claims.Impersonating() {
+		return nil, ErrImpersonationForbidden
+	}
+
+	user, err := s.findUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.CheckPasswordHash(currentPassword, user.Password) {
+		return nil, ErrInvalidCurrentPassword
+	}
+	if newPassword == currentPassword {
+		return nil, ErrPasswordReused
+	}
+	if err := checkPassword(ctx, s.passwords, newPassword); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+
+	now := s.clock.Now()
+	changes := map[string]interface{}{
+		"password":             hashedPassword,
+		"password_changed_at":  now,
+		"must_change_password": false,
+		"updated_at":           now,
+	}
+	if err := db.WithContext(ctx).Model(&models.User{}).Where("id = ?", user.ID).Updates(changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	// Cached copies of the user would still show the old password state
+	_ = s.cache.Delete(ctx, userCacheKeyByID(user.ID.String()), userCacheKeyByEmail(user.Email))
+	s.responses.Purge(ctx, ResponseGroupUsers)
+
+	if err := s.audit.Record(ctx, user.ID.String(), models.AuditActionUserPasswordChanged, "user", user.ID.String(), nil); err != nil {
+		s.log(ctx).Warn("Failed to audit password change", logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	trigger := metrics.PasswordChangeVoluntary
+	if claims.Scope == ScopePasswordChange {
+		trigger = metrics.PasswordChangeRequired
+	}
+	s.metrics.PasswordChanged(trigger)
+
+	orgIDs, err := s.userOrganizationIDs(ctx, user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	result := &AuthResult{User: user}
+	scope := ""
+	if s.profiles != nil {
+		status := s.profiles.Evaluate(user)
+		result.ProfileStatus = &status
+		scope = s.profileScope(status)
+		result.ProfileCompletionRequired = scope != ""
+	}
+	// The restricted login's session carries on with the new token
+	if err := s.extendSession(ctx, claims.SessionID, s.scopeTTL(scope, claims.DeviceID)); err != nil {
+		return nil, err
+	}
+	result.Token, err = s.scopedToken(ctx, user, orgIDs, scope, claims.SessionID, claims.DeviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	user.Password = ""
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = false
+	user.UpdatedAt = now
+	return result, nil
+}
+
+// CompleteProfile reports how complete the profile of the user claims
+// belong to is. When claims are restricted to completing the profile and it
+// now is complete, the result carries an unrestricted token for the same
+// session; otherwise it carries no token.
+func (s *AuthService) CompleteProfile(ctx context.Context, claims *TokenClaims) (*AuthResult, error) {
+	user, err := s.findUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = ""
+	result := &AuthResult{User: user}
+	if s.profiles == nil {
+		return result, nil
+	}
+	status := s.profiles.Evaluate(user)
+	result.ProfileStatus = &status
+	if claims.Scope != ScopeProfileCompletion {
+		return result, nil
+	}
+	if !status.Complete {
+		result.ProfileCompletionRequired = true
+		return result, nil
+	}
+
+	orgIDs, err := s.userOrganizationIDs(ctx, user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.extendSession(ctx, claims.SessionID, s.tokenTTL(claims.DeviceID)); err != nil {
+		return nil, err
+	}
+	result.Token, err = s.scopedToken(ctx, user, orgIDs, "", claims.SessionID, claims.DeviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	return result, nil
+}
+
+// profileScope returns the scope restricting the tokens of a user whose
+// profile has status: ScopeProfileCompletion while completing it is
+// enforced and it is incomplete, otherwise none
+func (s *AuthService) profileScope(status ProfileStatus) string {
+	if s.config.Auth.ProfileEnforce && !status.Complete {
+		return ScopeProfileCompletion
+	}
+	return ""
+}
+
+// scopeTTL returns the lifetime of tokens restricted to scope, or of
+// unrestricted tokens of the trusted device did when scope is empty
+func (s *AuthService) scopeTTL(scope, did string) time.Duration {
+	switch scope {
+	case ScopePasswordChange:
+		return s.config.Auth.PasswordChangeExpiration
+	case ScopeProfileCompletion:
+		return s.config.Auth.ProfileCompletionExpiration
+	default:
+		return s.tokenTTL(did)
+	}
+}
+
+// scopedToken issues a token of user restricted to scope, unless it is
+// empty, that belongs to the session sid and the trusted device did unless
+// they are empty
+func (s *AuthService) scopedToken(ctx context.Context, user *models.User, orgIDs []string, scope, sid, did string) (string, error) {
+	if scope == "" {
+		return s.generateToken(ctx, user.ID.String(), user.Email, string(user.Role), user.TokenVersion, orgIDs, sid, did)
+	}
+	claims := s.tokenClaims(user.ID.String(), user.Email, string(user.Role), user.TokenVersion, orgIDs, s.scopeTTL(scope, did))
+	claims["scope"] = scope
+	if sid != "" {
+		claims["sid"] = sid
+	}
+	if did != "" {
+		claims["did"] = did
+	}
+	return s.signToken(ctx, claims)
+}
+
+// findUser loads a user by ID
+func (s *AuthService) findUser(ctx context.Context, userID string) (*models.User, error) {
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+
+	var user models.User
+	if err := db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &user, nil
+}
+
+// generateToken generates a JWT token for the tenant ctx is scoped to,
+// belonging to the session sid and the trusted device did unless they are empty
+func (s *AuthService) generateToken(ctx context.Context, userID, email, role string, version int, orgIDs []string, sid, did string) (string, error) {
+	claims := s.tokenClaims(userID, email, role, version, orgIDs, s.tokenTTL(did))
+	if sid != "" {
+		claims["sid"] = sid
+	}
+	if did != "" {
+		claims["did"] = did
+	}
+	return s.signToken(ctx, claims)
+}
+
+// tokenTTL returns the lifetime of unrestricted tokens, which is longer for
+// remember_me logins on the trusted device did
+func (s *AuthService) tokenTTL(did string) time.Duration {
+	if did != "" && s.config.JWT.RememberExpiration > s.config.JWT.Expiration {
+		return s.config.JWT.RememberExpiration
+	}
+	return s.config.JWT.Expiration
+}
+
+// extendSession keeps the session sid alive for a newly issued token
+// lasting ttl. Tokens issued without a session have nothing to extend.
+func (s *AuthService) extendSession(ctx context.Context, sid string, ttl time.Duration) error {
+	if sid == "" || s.sessions == nil {
+		return nil
+	}
+	return s.sessions.Extend(ctx, sid, s.clock.Now().Add(ttl))
+}
+
+// TouchSession records that the session of claims was just used
+func (s *AuthService) TouchSession(ctx context.Context, claims *TokenClaims) {
+	if claims.SessionID != "" && s.sessions != nil {
+		s.sessions.Touch(ctx, claims.SessionID)
+	}
+}
+
+// tokenClaims builds the claims of a token valid for ttl. version is the
+// user's current token version.
+func (s *AuthService) tokenClaims(userID, email, role string, version int, orgIDs []string, ttl time.Duration) jwt.MapClaims {
+	if orgIDs == nil {
+		orgIDs = []string{}
+	}
+
+	now := s.clock.Now()
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"email":   email,
 		"role":    role,
-		"exp":     time.Now().Add(s.config.JWT.Expiration).Unix(),
-		"iat":     time.Now().Unix(),
-		"iss":     s.config.JWT.Issuer,
+		"org_ids": orgIDs,
+		"exp":     now.Add(ttl).Unix(),
+		"iat":     now.Unix(),
+
+		"token_version": version,
 	}
+	return claims
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.config.JWT.Secret))
+// signToken signs claims for the tenant ctx is scoped to, which also sets
+// the issuer, audience and tenant claims
+func (s *AuthService) signToken(ctx context.Context, claims jwt.MapClaims) (string, error) {
+	return s.tokens.GenerateFor(database.TenantID(ctx), claims)
+}
+
+// ValidateToken verifies a token's signature, issuer and audience against
+// the settings of the tenant ctx is scoped to, and that it was issued for
+// that tenant. It then rejects revoked tokens, tokens of sessions past their
+// idle timeout or lifetime, and tokens belonging to deactivated or deleted
+// users.
+func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*TokenClaims, error) {
+	mapClaims, err := s.tokens.VerifyFor(database.TenantID(ctx), tokenString)
+	if errors.Is(err, jwtutil.ErrTokenExpired) {
+		return nil, ErrTokenExpired
+	}
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &TokenClaims{}
+	claims.UserID, _ = mapClaims["user_id"].(string)
+	claims.Email, _ = mapClaims["email"].(string)
+	claims.Role, _ = mapClaims["role"].(string)
+	claims.TenantID, _ = mapClaims[jwtutil.TenantClaim].(string)
+	claims.ImpersonatorID, _ = mapClaims["impersonator_id"].(string)
+	claims.Scope, _ = mapClaims["scope"].(string)
+	claims.APIScope, _ = mapClaims["api_scope"].(string)
+	claims.SessionID, _ = mapClaims["sid"].(string)
+	claims.DeviceID, _ = mapClaims["did"].(string)
+	// Tokens issued before versions were introduced are version 0
+	if version, ok := mapClaims["token_version"].(float64); ok {
+		claims.TokenVersion = int(version)
+	}
+	if version, ok := mapClaims["impersonator_token_version"].(float64); ok {
+		claims.ImpersonatorTokenVersion = int(version)
+	}
+	if orgIDs, ok := mapClaims["org_ids"].([]interface{}); ok {
+		for _, id := range orgIDs {
+			if orgID, ok := id.(string); ok {
+				claims.OrgIDs = append(claims.OrgIDs, orgID)
+			}
+		}
+	}
+	if claims.UserID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	issuedAt, err := mapClaims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return nil, ErrInvalidToken
+	}
+	claims.IssuedAt = issuedAt.Time
+
+	if expiresAt, err := mapClaims.GetExpirationTime(); err == nil && expiresAt != nil {
+		claims.ExpiresAt = expiresAt.Time
+	}
+
+	// Forcing a password change revokes the user's tokens right before they
+	// log in for a restricted one, which only changes a password it is
+	// given, so password change tokens outlive revocations
+	if claims.Scope != ScopePasswordChange && s.revoker.IsRevoked(ctx, claims.UserID, claims.IssuedAt) {
+		return nil, ErrTokenRevoked
+	}
+	if claims.SessionID != "" && s.sessions != nil {
+		if s.sessions.IsRevoked(ctx, claims.SessionID) {
+			return nil, ErrTokenRevoked
+		}
+		// Idle and long-lived sessions end before their tokens do
+		if err := s.sessions.Check(ctx, claims.SessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	status, err := s.userStatus(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !status.active {
+		return nil, ErrAccountDeactivated
+	}
+	if claims.TokenVersion != status.tokenVersion {
+		return nil, ErrTokenRevoked
+	}
+
+	// Revoking or deactivating the impersonator ends their impersonations
+	if claims.Impersonating() {
+		if s.revoker.IsRevoked(ctx, claims.ImpersonatorID, claims.IssuedAt) {
+			return nil, ErrTokenRevoked
+		}
+		status, err := s.userStatus(ctx, claims.ImpersonatorID)
+		if err != nil {
+			return nil, err
+		}
+		if !status.active {
+			return nil, ErrAccountDeactivated
+		}
+		if claims.ImpersonatorTokenVersion != status.tokenVersion {
+			return nil, ErrTokenRevoked
+		}
+	}
+
+	return claims, nil
+}
+
+// userOrganizationIDs returns the organizations embedded in the org_ids claim
+func (s *AuthService) userOrganizationIDs(ctx context.Context, userID string) ([]string, error) {
+	if s.orgs == nil {
+		return nil, nil
+	}
+	orgIDs, err := s.orgs.ListUserOrganizationIDs(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load organizations: %w", err)
+	}
+	return orgIDs, nil
+}
+
+// userStatus is what token validation needs to know about a user
+type userStatus struct {
+	active       bool
+	tokenVersion int
+}
+
+// userStatus loads whether the user exists and is active and their token
+// version, using a short-lived cache
+func (s *AuthService) userStatus(ctx context.Context, userID string) (userStatus, error) {
+	key := userActiveCacheKey(userID)
+	if data, err := s.cache.Get(ctx, key); err == nil {
+		// Cached as "<1 or 0>:<token version>"
+		if active, version, ok := strings.Cut(string(data), ":"); ok {
+			if tokenVersion, err := strconv.Atoi(version); err == nil {
+				return userStatus{active: active == "1", tokenVersion: tokenVersion}, nil
+			}
+		}
+	}
+
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return userStatus{}, fmt.Errorf("database connection error: %w", err)
+	}
+
+	var status userStatus
+	if db := database.NativeGorm(driver); db != nil {
+		var user models.User
+		if err := db.WithContext(ctx).Select("active", "token_version").Where("id = ?", userID).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return userStatus{}, ErrInvalidToken
+			}
+			return userStatus{}, fmt.Errorf("database error: %w", err)
+		}
+		status = userStatus{active: user.Active, tokenVersion: user.TokenVersion}
+	} else {
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return userStatus{}, err
+		}
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), `SELECT active, token_version FROM users WHERE id = ?`))
+		if err := sqlDB.QueryRowContext(ctx, query, userID).Scan(&status.active, &status.tokenVersion); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return userStatus{}, ErrInvalidToken
+			}
+			return userStatus{}, fmt.Errorf("database error: %w", err)
+		}
+	}
+
+	active := "0"
+	if status.active {
+		active = "1"
+	}
+	_ = s.cache.Set(ctx, key, []byte(active+":"+strconv.Itoa(status.tokenVersion)), activeStatusCacheTTL)
+
+	return status, nil
+}
+
+// recordLogin writes and counts a login event; failures are logged but never
+// block the login
+func (s *AuthService) recordLogin(ctx context.Context, userID *uuid.UUID, email string, success bool, reason string, client ClientInfo) {
+	switch {
+	case success:
+		s.metrics.Login(metrics.LoginSuccess)
+	case reason == "account_deactivated":
+		s.metrics.Login(metrics.LoginDeactivated)
+	default:
+		s.metrics.Login(metrics.LoginFailure)
+	}
+	if err := s.audit.RecordLogin(ctx, userID, email, success, reason, client); err != nil {
+		s.log(ctx).Warn("Failed to record login event", logger.Field{Key: "email", Value: email}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	if s.logins == nil {
+		return
+	}
+
+	login := events.Login{Email: email, Success: success, Reason: reason, IPAddress: client.IPAddress, UserAgent: client.UserAgent}
+	if userID != nil {
+		login.UserID = userID.String()
+	}
+	if client.Location.Known() {
+		location := client.Location
+		login.Location = &location
+	}
+	eventType := events.LoginFailed
+	if success {
+		eventType = events.LoginSucceeded
+	}
+	if err := s.logins.Publish(ctx, events.New(eventType, login)); err != nil {
+		s.log(ctx).Warn("Failed to publish login event", logger.Field{Key: "email", Value: email}, logger.Field{Key: "error", Value: err.Error()})
+	}
 }
 
 // Health checks if the service is healthy
 func (s *AuthService) Health(ctx context.Context) error {
 	return s.db.Health(ctx)["primary"]
 }
+
+// log returns the logger of the request ctx belongs to, which adds its
+// request ID and trace, or the service's own outside requests
+func (s *AuthService) log(ctx context.Context) logger.Logger {
+	return requestctx.LoggerOr(ctx, s.logger)
+}
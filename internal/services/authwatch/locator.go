@@ -0,0 +1,95 @@
+package authwatch
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Location is a point on Earth in degrees
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Locator places the IP address of a login; ok is false when it is unknown
+type Locator interface {
+	Locate(ctx context.Context, ip string) (loc Location, ok bool, err error)
+}
+
+// NoLocator places no address, so impossible travel never fires
+type NoLocator struct{}
+
+// Locate returns no location
+func (NoLocator) Locate(ctx context.Context, ip string) (Location, bool, error) {
+	return Location{}, false, nil
+}
+
+// networkLocation is a network placed at a location
+type networkLocation struct {
+	network  *net.IPNet
+	location Location
+}
+
+// NetworkLocator places addresses by the network they are in, such as
+// offices and VPN exits
+type NetworkLocator struct {
+	networks []networkLocation
+}
+
+// NewNetworkLocator places the networks of locations, given as CIDR, at
+// the latitude and longitude they map to. The most specific network
+// containing an address places it.
+func NewNetworkLocator(locations map[string][]string) (*NetworkLocator, error) {
+	l := &NetworkLocator{}
+	for cidr, coordinates := range locations {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", cidr, err)
+		}
+		if len(coordinates) != 2 {
+			return nil, fmt.Errorf("invalid location of %s: expected latitude and longitude", cidr)
+		}
+		lat, err := strconv.ParseFloat(coordinates[0], 64)
+		if err != nil || math.Abs(lat) > 90 {
+			return nil, fmt.Errorf("invalid latitude of %s: %q", cidr, coordinates[0])
+		}
+		lon, err := strconv.ParseFloat(coordinates[1], 64)
+		if err != nil || math.Abs(lon) > 180 {
+			return nil, fmt.Errorf("invalid longitude of %s: %q", cidr, coordinates[1])
+		}
+		l.networks = append(l.networks, networkLocation{network: network, location: Location{Latitude: lat, Longitude: lon}})
+	}
+	return l, nil
+}
+
+// Locate returns the location of the most specific network containing ip
+func (l *NetworkLocator) Locate(ctx context.Context, ip string) (Location, bool, error) {
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	if addr == nil {
+		return Location{}, false, nil
+	}
+	best := -1
+	var found Location
+	for _, n := range l.networks {
+		if ones, _ := n.network.Mask.Size(); ones > best && n.network.Contains(addr) {
+			best, found = ones, n.location
+		}
+	}
+	return found, best >= 0, nil
+}
+
+// earthRadius is the mean radius of the Earth in km
+const earthRadius = 6371.0
+
+// Distance returns the great-circle distance between a and b in km
+func Distance(a, b Location) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
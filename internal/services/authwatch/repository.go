@@ -0,0 +1,77 @@
+package authwatch
+
+import (
+	"context"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Repository persists alerts and finds who to notify of them
+type Repository interface {
+	// Create stores an alert
+	Create(ctx context.Context, alert *models.Alert) error
+
+	// List returns a page of the alerts of alertType, or of every type when
+	// it is empty, newest first, with their total
+	List(ctx context.Context, alertType string, limit, offset int) ([]*models.Alert, int64, error)
+
+	// Admins returns the IDs of the active admins
+	Admins(ctx context.Context) ([]uuid.UUID, error)
+}
+
+// gormRepository implements Repository on the database each call is scoped to
+type gormRepository struct {
+	db *database.Manager
+}
+
+// NewRepository creates a repository backed by the database each call is scoped to
+func NewRepository(db *database.Manager) Repository {
+	return &gormRepository{db: db}
+}
+
+func (r *gormRepository) Create(ctx context.Context, alert *models.Alert) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(alert).Error
+}
+
+func (r *gormRepository) List(ctx context.Context, alertType string, limit, offset int) ([]*models.Alert, int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, 0, err
+	}
+	selected := func() *gorm.DB {
+		query := db.Model(&models.Alert{})
+		if alertType != "" {
+			query = query.Where("type = ?", alertType)
+		}
+		return query
+	}
+
+	var total int64
+	if err := selected().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	alerts := []*models.Alert{}
+	err = selected().Order("created_at DESC").Order("id").Limit(limit).Offset(offset).Find(&alerts).Error
+	return alerts, total, err
+}
+
+func (r *gormRepository) Admins(ctx context.Context) ([]uuid.UUID, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uuid.UUID
+	err = db.Model(&models.User{}).
+		Where("role = ? AND active = ? AND deleted_at IS NULL", models.RoleAdmin, true).
+		Order("created_at").Pluck("id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,511 @@
+// Package authwatch watches login events for signs of attack: impossible
+// travel, credential stuffing and spikes of failures on one account. Rules
+// count events in sliding windows kept in the cache store, so every
+// instance sharing the cache sees the same counts. A rule firing stores an
+// alert, notifies the active admins and applies the rule's mitigations.
+package authwatch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/ratelimit"
+	"BackofficeGoService/internal/pkg/requestctx"
+
+	"github.com/google/uuid"
+)
+
+// slots is how many counters a sliding window is kept in. An event leaves
+// its window up to a slot early.
+const slots = 10
+
+// Notifier delivers in-app notifications, as services.NotificationService does
+type Notifier interface {
+	Notify(ctx context.Context, userID string, notification *models.Notification) error
+}
+
+// PasswordResetter makes a user change their password at their next login,
+// as services.UserService does
+type PasswordResetter interface {
+	RequirePasswordChange(ctx context.Context, id string, actorID string) (*models.User, error)
+}
+
+// AuditRecorder records audit log entries, as services.AuditService does
+type AuditRecorder interface {
+	Record(ctx context.Context, actorID, action, entityType, entityID string, metadata interface{}) error
+}
+
+// Watch checks login events against the configured rules
+type Watch struct {
+	cfg           config.AuthWatchConfig
+	repo          Repository
+	store         cache.Store
+	limiter       *ratelimit.Limiter
+	users         PasswordResetter
+	notifications Notifier
+	audit         AuditRecorder
+	locator       Locator
+	logger        logger.Logger
+
+	wg sync.WaitGroup
+}
+
+// Option configures a Watch
+type Option func(w *Watch)
+
+// WithLocator sets where login addresses are placed for impossible travel
+func WithLocator(l Locator) Option {
+	return func(w *Watch) {
+		w.locator = l
+	}
+}
+
+// NewWatch creates a watch keeping its windows in store. block_ip blocks
+// addresses in limiter and force_password_reset goes through users; a rule
+// listing a mitigation without its dependency is an error, as is one
+// listing an unknown mitigation. notifications and audit may be nil.
+func NewWatch(cfg config.AuthWatchConfig, repo Repository, store cache.Store, limiter *ratelimit.Limiter, users PasswordResetter, notifications Notifier, audit AuditRecorder, log logger.Logger, opts ...Option) (*Watch, error) {
+	w := &Watch{
+		cfg:           cfg,
+		repo:          repo,
+		store:         cache.WithPrefix(store, "authwatch"),
+		limiter:       limiter,
+		users:         users,
+		notifications: notifications,
+		audit:         audit,
+		locator:       NoLocator{},
+		logger:        log,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	rules := map[string][]string{
+		models.AlertTypeImpossibleTravel:   cfg.ImpossibleTravel.Mitigations,
+		models.AlertTypeCredentialStuffing: cfg.CredentialStuffing.Mitigations,
+		models.AlertTypeFailureSpike:       cfg.FailureSpike.Mitigations,
+	}
+	for rule, mitigations := range rules {
+		for _, mitigation := range mitigations {
+			switch {
+			case mitigation == models.MitigationBlockIP && limiter == nil:
+				return nil, fmt.Errorf("%s: %s needs a rate limiter", rule, mitigation)
+			case mitigation == models.MitigationForcePasswordReset && users == nil:
+				return nil, fmt.Errorf("%s: %s needs the user service", rule, mitigation)
+			case mitigation != models.MitigationBlockIP && mitigation != models.MitigationForcePasswordReset:
+				return nil, fmt.Errorf("%s: unknown mitigation %q", rule, mitigation)
+			}
+		}
+	}
+	for rule, r := range map[string]config.AuthWatchRule{
+		models.AlertTypeCredentialStuffing: cfg.CredentialStuffing,
+		models.AlertTypeFailureSpike:       cfg.FailureSpike,
+	} {
+		if r.Enabled && (r.Threshold <= 0 || r.Window <= 0) {
+			return nil, fmt.Errorf("%s: threshold and window must be positive", rule)
+		}
+	}
+	if cfg.ImpossibleTravel.Enabled && (cfg.ImpossibleTravel.MaxSpeed <= 0 || cfg.ImpossibleTravel.Window <= 0) {
+		return nil, fmt.Errorf("%s: maximum speed and window must be positive", models.AlertTypeImpossibleTravel)
+	}
+	return w, nil
+}
+
+// HandleEvent checks login events in the background, so logins are not
+// slowed down; other events are ignored
+func (w *Watch) HandleEvent(ctx context.Context, event events.Event) {
+	login, ok := event.Data.(events.Login)
+	if !ok || (event.Type != events.LoginSucceeded && event.Type != events.LoginFailed) {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		if _, err := w.Check(ctx, login, event.OccurredAt); err != nil {
+			requestctx.LoggerOr(ctx, w.logger).Warn("Failed to check login",
+				logger.Field{Key: "email", Value: login.Email},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+		}
+	}()
+}
+
+// Stop waits for the login events handled so far to be checked
+func (w *Watch) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Check runs the enabled rules on a login made at at and returns the
+// alerts raised. Failed logins count towards credential stuffing and
+// failure spikes, successful ones are checked for impossible travel. A
+// rule fires when its count reaches the threshold, and again only once it
+// fell below it.
+func (w *Watch) Check(ctx context.Context, login events.Login, at time.Time) ([]*models.Alert, error) {
+	var alerts []*models.Alert
+	var errs []error
+	add := func(alert *models.Alert, err error) {
+		if alert != nil {
+			alerts = append(alerts, alert)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if login.Success {
+		if w.cfg.ImpossibleTravel.Enabled {
+			add(w.checkTravel(ctx, login, at))
+		}
+	} else {
+		if w.cfg.CredentialStuffing.Enabled && login.IPAddress != "" {
+			add(w.checkStuffing(ctx, login, at))
+		}
+		if w.cfg.FailureSpike.Enabled && login.Email != "" {
+			add(w.checkSpike(ctx, login, at))
+		}
+	}
+	return alerts, errors.Join(errs...)
+}
+
+// checkStuffing counts the different emails the login's address failed as
+func (w *Watch) checkStuffing(ctx context.Context, login events.Login, at time.Time) (*models.Alert, error) {
+	rule := w.cfg.CredentialStuffing
+	key := "stuffing:" + login.IPAddress
+
+	// Each email counts once per window
+	seen, err := w.store.Incr(ctx, key+":seen:"+hashEmail(login.Email), 1, rule.Window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count login failure: %w", err)
+	}
+	if seen > 1 {
+		return nil, nil
+	}
+	emails, err := w.slide(ctx, key, rule.Window, at)
+	if err != nil || emails != int64(rule.Threshold) {
+		return nil, err
+	}
+
+	return w.raise(ctx, &models.Alert{
+		Type:      models.AlertTypeCredentialStuffing,
+		IPAddress: login.IPAddress,
+	}, map[string]interface{}{
+		"emails": emails,
+		"window": rule.Window.String(),
+	}, rule.Mitigations, at)
+}
+
+// checkSpike counts the failed logins to the login's email
+func (w *Watch) checkSpike(ctx context.Context, login events.Login, at time.Time) (*models.Alert, error) {
+	rule := w.cfg.FailureSpike
+	failures, err := w.slide(ctx, "spike:"+hashEmail(login.Email), rule.Window, at)
+	if err != nil || failures != int64(rule.Threshold) {
+		return nil, err
+	}
+
+	return w.raise(ctx, &models.Alert{
+		Type:      models.AlertTypeFailureSpike,
+		UserID:    parseUserID(login.UserID),
+		Email:     login.Email,
+		IPAddress: login.IPAddress,
+	}, map[string]interface{}{
+		"failures": failures,
+		"window":   rule.Window.String(),
+	}, rule.Mitigations, at)
+}
+
+// lastLogin is where and when a user last logged in from a known location
+type lastLogin struct {
+	IPAddress string    `json:"ip_address"`
+	Location  Location  `json:"location"`
+	At        time.Time `json:"at"`
+}
+
+// checkTravel compares the login with the user's previous located one
+func (w *Watch) checkTravel(ctx context.Context, login events.Login, at time.Time) (*models.Alert, error) {
+	rule := w.cfg.ImpossibleTravel
+	if login.UserID == "" {
+		return nil, nil
+	}
+	location, ok, err := w.locator.Locate(ctx, login.IPAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate login: %w", err)
+	}
+	// Configured networks take precedence over GeoIP, which placed the
+	// login when it was made
+	if !ok && login.Location != nil && login.Location.HasCoordinates() {
+		location, ok = Location{Latitude: login.Location.Lat, Longitude: login.Location.Lon}, true
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	key := "travel:" + login.UserID
+	var previous *lastLogin
+	data, err := w.store.Get(ctx, key)
+	switch {
+	case err == nil:
+		previous = &lastLogin{}
+		if json.Unmarshal(data, previous) != nil {
+			previous = nil
+		}
+	case !errors.Is(err, cache.ErrCacheMiss):
+		return nil, fmt.Errorf("failed to read last login: %w", err)
+	}
+
+	data, err = json.Marshal(lastLogin{IPAddress: login.IPAddress, Location: location, At: at})
+	if err != nil {
+		return nil, err
+	}
+	if err := w.store.Set(ctx, key, data, rule.Window); err != nil {
+		return nil, fmt.Errorf("failed to store last login: %w", err)
+	}
+
+	if previous == nil || at.Before(previous.At) || at.Sub(previous.At) > rule.Window {
+		return nil, nil
+	}
+	distance := Distance(previous.Location, location)
+	elapsed := at.Sub(previous.At)
+	if distance < rule.MinDistance {
+		return nil, nil
+	}
+	speed := math.Inf(1)
+	if elapsed > 0 {
+		speed = distance / elapsed.Hours()
+	}
+	if speed <= rule.MaxSpeed {
+		return nil, nil
+	}
+
+	details := map[string]interface{}{
+		"previous_ip_address": previous.IPAddress,
+		"previous_at":         previous.At.UTC(),
+		"distance_km":         math.Round(distance),
+		"elapsed":             elapsed.String(),
+	}
+	if !math.IsInf(speed, 1) {
+		details["speed_kmh"] = math.Round(speed)
+	}
+	return w.raise(ctx, &models.Alert{
+		Type:      models.AlertTypeImpossibleTravel,
+		UserID:    parseUserID(login.UserID),
+		Email:     login.Email,
+		IPAddress: login.IPAddress,
+	}, details, rule.Mitigations, at)
+}
+
+// slide counts an event at at under key and returns how many it counted in
+// the window ending at at
+func (w *Watch) slide(ctx context.Context, key string, window time.Duration, at time.Time) (int64, error) {
+	slot := max(window/slots, time.Second)
+	start := at.Truncate(slot)
+	count, err := w.store.Incr(ctx, slotKey(key, start), 1, window+slot)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count login: %w", err)
+	}
+
+	for s := start.Add(-slot); s.After(at.Add(-window)); s = s.Add(-slot) {
+		data, err := w.store.Get(ctx, slotKey(key, s))
+		if errors.Is(err, cache.ErrCacheMiss) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read login count: %w", err)
+		}
+		if n, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+			count += n
+		}
+	}
+	return count, nil
+}
+
+// raise applies mitigations, stores alert with details and the mitigations
+// that succeeded, then audits it and notifies the admins. A mitigation
+// failing is logged; the alert is raised regardless.
+func (w *Watch) raise(ctx context.Context, alert *models.Alert, details map[string]interface{}, mitigations []string, at time.Time) (*models.Alert, error) {
+	alert.ID = uuid.New()
+	alert.CreatedAt = at
+	alert.Mitigations = models.StringList{}
+	for _, mitigation := range mitigations {
+		applied, err := w.mitigate(ctx, alert, mitigation)
+		if err != nil {
+			requestctx.LoggerOr(ctx, w.logger).Warn("Failed to mitigate alert",
+				logger.Field{Key: "alert_id", Value: alert.ID.String()},
+				logger.Field{Key: "mitigation", Value: mitigation},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+		}
+		if applied {
+			alert.Mitigations = append(alert.Mitigations, mitigation)
+		}
+	}
+
+	var err error
+	if alert.Details, err = models.NewJSON(details); err != nil {
+		return nil, err
+	}
+	if err := w.repo.Create(ctx, alert); err != nil {
+		return nil, fmt.Errorf("failed to store alert: %w", err)
+	}
+
+	requestctx.LoggerOr(ctx, w.logger).Warn("Suspicious login activity",
+		logger.Field{Key: "alert_id", Value: alert.ID.String()},
+		logger.Field{Key: "type", Value: alert.Type},
+		logger.Field{Key: "ip_address", Value: alert.IPAddress},
+		logger.Field{Key: "mitigations", Value: []string(alert.Mitigations)},
+	)
+	w.record(ctx, models.AuditActionAlertRaised, "alert", alert.ID.String(), map[string]interface{}{
+		"type":        alert.Type,
+		"mitigations": alert.Mitigations,
+	})
+	w.notify(ctx, alert)
+	return alert, nil
+}
+
+// mitigate applies mitigation to alert, reporting whether it applied;
+// blocks need an address and password resets a user
+func (w *Watch) mitigate(ctx context.Context, alert *models.Alert, mitigation string) (bool, error) {
+	switch mitigation {
+	case models.MitigationBlockIP:
+		if alert.IPAddress == "" {
+			return false, nil
+		}
+		until, err := w.limiter.Block(ctx, alert.IPAddress, w.cfg.BlockDuration)
+		if err != nil {
+			return false, err
+		}
+		w.record(ctx, models.AuditActionIPBlocked, "ip", alert.IPAddress, map[string]interface{}{
+			"alert_id": alert.ID.String(),
+			"until":    until.UTC(),
+		})
+		return true, nil
+	case models.MitigationForcePasswordReset:
+		if alert.UserID == nil {
+			return false, nil
+		}
+		if _, err := w.users.RequirePasswordChange(ctx, alert.UserID.String(), ""); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// record audits a system action; failures are logged
+func (w *Watch) record(ctx context.Context, action, entityType, entityID string, metadata interface{}) {
+	if w.audit == nil {
+		return
+	}
+	if err := w.audit.Record(ctx, "", action, entityType, entityID, metadata); err != nil {
+		requestctx.LoggerOr(ctx, w.logger).Warn("Failed to audit alert",
+			logger.Field{Key: "action", Value: action},
+			logger.Field{Key: "entity_id", Value: entityID},
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+	}
+}
+
+// alertTitles names the alert types in notifications
+var alertTitles = map[string]string{
+	models.AlertTypeImpossibleTravel:   "Impossible travel between logins",
+	models.AlertTypeCredentialStuffing: "Credential stuffing from one address",
+	models.AlertTypeFailureSpike:       "Spike of failed logins to one account",
+}
+
+// notify tells every active admin about alert; failures are logged
+func (w *Watch) notify(ctx context.Context, alert *models.Alert) {
+	if w.notifications == nil {
+		return
+	}
+	admins, err := w.repo.Admins(ctx)
+	if err != nil {
+		requestctx.LoggerOr(ctx, w.logger).Warn("Failed to list admins to alert", logger.Field{Key: "alert_id", Value: alert.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+		return
+	}
+
+	var subject []string
+	if alert.Email != "" {
+		subject = append(subject, "account "+alert.Email)
+	}
+	if alert.IPAddress != "" {
+		subject = append(subject, "address "+alert.IPAddress)
+	}
+	body := "Suspicious login activity on " + strings.Join(subject, " from ")
+	if len(alert.Mitigations) > 0 {
+		body += "; applied " + strings.Join(alert.Mitigations, ", ")
+	}
+
+	data, _ := models.NewJSON(map[string]interface{}{
+		"alert_id":    alert.ID.String(),
+		"type":        alert.Type,
+		"mitigations": alert.Mitigations,
+	})
+	for _, admin := range admins {
+		notification := &models.Notification{
+			Type:  models.NotificationTypeSecurityAlert,
+			Title: alertTitles[alert.Type],
+			Body:  body,
+			Data:  data,
+		}
+		if err := w.notifications.Notify(ctx, admin.String(), notification); err != nil {
+			requestctx.LoggerOr(ctx, w.logger).Warn("Failed to notify admin of alert", logger.Field{Key: "alert_id", Value: alert.ID.String()}, logger.Field{Key: "user_id", Value: admin.String()}, logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+}
+
+// List returns a page of the alerts of alertType, or of every type when it
+// is empty, newest first, with their total
+func (w *Watch) List(ctx context.Context, alertType string, limit, offset int) ([]*models.Alert, int64, error) {
+	alerts, total, err := w.repo.List(ctx, alertType, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	return alerts, total, nil
+}
+
+// slotKey is the counter of key's events in the slot starting at start
+func slotKey(key string, start time.Time) string {
+	return key + ":" + strconv.FormatInt(start.Unix(), 10)
+}
+
+// hashEmail returns the form emails are keyed by: the hash of the
+// lowercased address, which keeps them out of the cache
+func hashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:16])
+}
+
+// parseUserID returns the ID of a login's user, or nil for unknown emails
+func parseUserID(id string) *uuid.UUID {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
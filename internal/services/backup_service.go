@@ -0,0 +1,511 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/infrastructure/storage"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+// backupTimeLayout dates backup keys; keys of one database sort by time
+const backupTimeLayout = "20060102T150405.000Z"
+
+// maxBackupStderr caps the output of a failed dump or restore program
+// quoted in its error
+const maxBackupStderr = 512
+
+// Backup is a database backup kept in storage, under
+// <prefix>/<database>/<time>.<driver>[.gz]
+type Backup struct {
+	Key        string              `json:"key"`
+	Database   string              `json:"database"`
+	Driver     database.DriverType `json:"driver"`
+	Compressed bool                `json:"compressed"`
+	Size       int64               `json:"size"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// BackupTarget is a connected database to back up or restore into
+type BackupTarget struct {
+	// Name is "primary" or an entry of database.databases
+	Name   string
+	Config config.DatabaseConnectionConfig
+	DB     *sql.DB
+}
+
+// BackupService backs databases up to storage and restores them.
+// PostgreSQL and MySQL are dumped and restored by their client programs;
+// SQLite databases are snapshotted with VACUUM INTO, which is safe while
+// they are in use.
+type BackupService struct {
+	config config.BackupConfig
+	store  storage.Store
+	audit  AuditRecorder
+	logger logger.Logger
+	clock  clock.Clock
+}
+
+// BackupOption configures a BackupService
+type BackupOption func(s *BackupService)
+
+// WithBackupClock sets the clock backups are dated by
+func WithBackupClock(c clock.Clock) BackupOption {
+	return func(s *BackupService) {
+		s.clock = c
+	}
+}
+
+// NewBackupService creates a service keeping backups in store. Backups and
+// restores are recorded in audit unless it is nil.
+func NewBackupService(cfg config.BackupConfig, store storage.Store, audit AuditRecorder, log logger.Logger, opts ...BackupOption) *BackupService {
+	s := &BackupService{
+		config: cfg,
+		store:  store,
+		audit:  audit,
+		logger: log,
+		clock:  clock.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create backs target up on behalf of actorID, streaming the dump to
+// storage under a dated key. A failed dump leaves no backup behind.
+func (s *BackupService) Create(ctx context.Context, actorID string, target BackupTarget) (*Backup, error) {
+	driver := database.DriverType(target.Config.Driver)
+	if !backupSupported(driver) {
+		return nil, fmt.Errorf("%w: %s", ErrBackupUnsupported, driver)
+	}
+
+	backup := &Backup{
+		Database:   target.Name,
+		Driver:     driver,
+		Compressed: s.config.Gzip,
+		CreatedAt:  s.clock.Now().UTC().Truncate(time.Millisecond),
+	}
+	backup.Key = path.Join(s.config.Prefix, target.Name, backup.CreatedAt.Format(backupTimeLayout)+"."+string(driver))
+	if backup.Compressed {
+		backup.Key += ".gz"
+	}
+
+	pr, pw := io.Pipe()
+	counter := &countingWriter{w: pw}
+	dumped := make(chan error, 1)
+	go func() {
+		var w io.Writer = counter
+		var gz *gzip.Writer
+		if backup.Compressed {
+			gz = gzip.NewWriter(counter)
+			w = gz
+		}
+		err := s.dump(ctx, target, w)
+		if err == nil && gz != nil {
+			err = gz.Close()
+		}
+		pw.CloseWithError(err)
+		dumped <- err
+	}()
+
+	err := s.store.Put(ctx, backup.Key, pr)
+	// Stops the dump if the upload gave up first
+	pr.CloseWithError(errors.New("backup upload stopped"))
+	if dumpErr := <-dumped; dumpErr != nil {
+		return nil, fmt.Errorf("failed to dump database %s: %w", target.Name, dumpErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload backup: %w", err)
+	}
+	backup.Size = counter.n
+
+	s.logger.Info("Database backed up", logger.Field{Key: "actor_id", Value: actorID}, logger.Field{Key: "database", Value: target.Name},
+		logger.Field{Key: "key", Value: backup.Key}, logger.Field{Key: "size", Value: backup.Size})
+	s.record(ctx, actorID, models.AuditActionBackupCreated, backup)
+	return backup, nil
+}
+
+// Restore replaces the contents of target with the backup stored under key
+// on behalf of actorID. Unless force is set, it refuses with
+// ErrBackupTargetNotEmpty when target has any table. SQLite files are
+// replaced whole, so nothing else should have them open.
+func (s *BackupService) Restore(ctx context.Context, actorID string, target BackupTarget, key string, force bool) (*Backup, error) {
+	backup, ok := parseBackupKey(s.config.Prefix, key)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, key)
+	}
+	driver := database.DriverType(target.Config.Driver)
+	if backup.Driver != driver {
+		return nil, fmt.Errorf("%w: %s backup, %s database", ErrBackupDriverMismatch, backup.Driver, driver)
+	}
+
+	if !force {
+		tables, err := tableCount(ctx, driver, target.DB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect database %s: %w", target.Name, err)
+		}
+		if tables > 0 {
+			return nil, fmt.Errorf("%w: %s has %d tables", ErrBackupTargetNotEmpty, target.Name, tables)
+		}
+	}
+
+	file, err := s.store.Open(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer file.Close()
+
+	counter := &countingReader{r: file}
+	var r io.Reader = counter
+	if backup.Compressed {
+		gz, err := gzip.NewReader(counter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	if err := s.restore(ctx, target, r); err != nil {
+		return nil, fmt.Errorf("failed to restore database %s: %w", target.Name, err)
+	}
+	backup.Size = counter.n
+
+	s.logger.Warn("Database restored", logger.Field{Key: "actor_id", Value: actorID}, logger.Field{Key: "database", Value: target.Name},
+		logger.Field{Key: "key", Value: key}, logger.Field{Key: "forced", Value: force})
+	s.record(ctx, actorID, models.AuditActionBackupRestored, &backup)
+	return &backup, nil
+}
+
+// List returns the backups in storage, newest first
+func (s *BackupService) List(ctx context.Context) ([]Backup, error) {
+	objects, err := s.store.List(ctx, s.config.Prefix+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	backups := make([]Backup, 0, len(objects))
+	for _, object := range objects {
+		backup, ok := parseBackupKey(s.config.Prefix, object.Key)
+		if !ok {
+			continue
+		}
+		backup.Size = object.Size
+		backups = append(backups, backup)
+	}
+	sort.SliceStable(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// DumpCommand returns the program writing a dump of the database dbc
+// connects to on its stdout. The password is passed in the environment,
+// never on the command line.
+func (s *BackupService) DumpCommand(ctx context.Context, dbc config.DatabaseConnectionConfig) (*exec.Cmd, error) {
+	switch database.DriverType(dbc.Driver) {
+	case database.DriverPostgreSQL:
+		// Dropping objects first lets a forced restore replace a schema
+		args := append(postgresArgs(dbc), "--format=plain", "--no-owner", "--no-privileges", "--clean", "--if-exists")
+		return s.command(ctx, s.config.PgDumpPath, args, postgresEnv(dbc)), nil
+	case database.DriverMySQL:
+		args := append(mysqlArgs(dbc), "--single-transaction", "--routines", "--triggers", "--no-tablespaces", dbc.DBName)
+		return s.command(ctx, s.config.MySQLDumpPath, args, mysqlEnv(dbc)), nil
+	default:
+		return nil, fmt.Errorf("%w: %s has no dump program", ErrBackupUnsupported, dbc.Driver)
+	}
+}
+
+// RestoreCommand returns the program replaying a dump read from its stdin
+// into the database dbc connects to, stopping at the first error
+func (s *BackupService) RestoreCommand(ctx context.Context, dbc config.DatabaseConnectionConfig) (*exec.Cmd, error) {
+	switch database.DriverType(dbc.Driver) {
+	case database.DriverPostgreSQL:
+		args := append(postgresArgs(dbc), "--no-psqlrc", "--quiet", "--single-transaction", "--set=ON_ERROR_STOP=1")
+		return s.command(ctx, s.config.PsqlPath, args, postgresEnv(dbc)), nil
+	case database.DriverMySQL:
+		args := append(mysqlArgs(dbc), dbc.DBName)
+		return s.command(ctx, s.config.MySQLPath, args, mysqlEnv(dbc)), nil
+	default:
+		return nil, fmt.Errorf("%w: %s has no restore program", ErrBackupUnsupported, dbc.Driver)
+	}
+}
+
+// command builds the program name run with args and env added to the
+// service's environment
+func (s *BackupService) command(ctx context.Context, name string, args, env []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	return cmd
+}
+
+// dump writes a dump of target to w
+func (s *BackupService) dump(ctx context.Context, target BackupTarget, w io.Writer) error {
+	if database.DriverType(target.Config.Driver) == database.DriverSQLite {
+		return snapshotSQLite(ctx, target.DB, w)
+	}
+	cmd, err := s.DumpCommand(ctx, target.Config)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = w
+	return runBackupCommand(cmd)
+}
+
+// restore replays the dump read from r into target
+func (s *BackupService) restore(ctx context.Context, target BackupTarget, r io.Reader) error {
+	if database.DriverType(target.Config.Driver) == database.DriverSQLite {
+		return replaceSQLite(ctx, target.Config.DBName, r)
+	}
+	cmd, err := s.RestoreCommand(ctx, target.Config)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = r
+	return runBackupCommand(cmd)
+}
+
+// record audits a backup or restore, logging failures
+func (s *BackupService) record(ctx context.Context, actorID, action string, backup *Backup) {
+	if s.audit == nil {
+		return
+	}
+	metadata := map[string]interface{}{"database": backup.Database, "driver": backup.Driver, "size": backup.Size}
+	if err := s.audit.Record(ctx, actorID, action, "backup", backup.Key, metadata); err != nil {
+		s.logger.Warn("Failed to audit backup", logger.Field{Key: "action", Value: action}, logger.Field{Key: "error", Value: err.Error()})
+	}
+}
+
+// backupSupported reports whether databases of driver can be backed up
+func backupSupported(driver database.DriverType) bool {
+	switch driver {
+	case database.DriverPostgreSQL, database.DriverMySQL, database.DriverSQLite:
+		return true
+	}
+	return false
+}
+
+// parseBackupKey reads the database, driver, time and compression of the
+// backup stored under key, reporting false for keys backups are not kept at
+func parseBackupKey(prefix, key string) (Backup, bool) {
+	rest, ok := strings.CutPrefix(key, prefix+"/")
+	if !ok {
+		return Backup{}, false
+	}
+	name, file := path.Split(rest)
+	name = strings.TrimSuffix(name, "/")
+	if name == "" || strings.Contains(name, "/") {
+		return Backup{}, false
+	}
+	backup := Backup{Key: key, Database: name}
+	file, backup.Compressed = strings.CutSuffix(file, ".gz")
+	dot := strings.LastIndex(file, ".")
+	if dot < 0 {
+		return Backup{}, false
+	}
+	backup.Driver = database.DriverType(file[dot+1:])
+	created, err := time.Parse(backupTimeLayout, file[:dot])
+	if err != nil || !backupSupported(backup.Driver) {
+		return Backup{}, false
+	}
+	backup.CreatedAt = created
+	return backup, true
+}
+
+// postgresArgs are the connection flags of PostgreSQL's client programs
+func postgresArgs(dbc config.DatabaseConnectionConfig) []string {
+	var args []string
+	if dbc.Host != "" {
+		args = append(args, "--host="+dbc.Host)
+	}
+	if dbc.Port != "" {
+		args = append(args, "--port="+dbc.Port)
+	}
+	if dbc.User != "" {
+		args = append(args, "--username="+dbc.User)
+	}
+	return append(args, "--dbname="+dbc.DBName)
+}
+
+// postgresEnv passes the password and SSL mode to PostgreSQL's client programs
+func postgresEnv(dbc config.DatabaseConnectionConfig) []string {
+	env := []string{"PGPASSWORD=" + dbc.Password}
+	if dbc.SSLMode != "" {
+		env = append(env, "PGSSLMODE="+dbc.SSLMode)
+	}
+	return env
+}
+
+// mysqlArgs are the connection flags of MySQL's client programs
+func mysqlArgs(dbc config.DatabaseConnectionConfig) []string {
+	var args []string
+	if dbc.Host != "" {
+		args = append(args, "--host="+dbc.Host)
+	}
+	if dbc.Port != "" {
+		args = append(args, "--port="+dbc.Port)
+	}
+	if dbc.User != "" {
+		args = append(args, "--user="+dbc.User)
+	}
+	if dbc.Charset != "" {
+		args = append(args, "--default-character-set="+dbc.Charset)
+	}
+	return args
+}
+
+// mysqlEnv passes the password to MySQL's client programs
+func mysqlEnv(dbc config.DatabaseConnectionConfig) []string {
+	return []string{"MYSQL_PWD=" + dbc.Password}
+}
+
+// runBackupCommand runs cmd, quoting the end of what it wrote to stderr
+// when it fails
+func runBackupCommand(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		output := strings.TrimSpace(stderr.String())
+		if len(output) > maxBackupStderr {
+			output = "..." + output[len(output)-maxBackupStderr:]
+		}
+		if output == "" {
+			return fmt.Errorf("%s: %w", filepath.Base(cmd.Path), err)
+		}
+		return fmt.Errorf("%s: %w: %s", filepath.Base(cmd.Path), err, output)
+	}
+	return nil
+}
+
+// tableCount counts the tables of the database db is connected to
+func tableCount(ctx context.Context, driver database.DriverType, db *sql.DB) (int64, error) {
+	var query string
+	switch driver {
+	case database.DriverPostgreSQL:
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema NOT IN ('pg_catalog', 'information_schema')"
+	case database.DriverMySQL:
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE()"
+	case database.DriverSQLite:
+		query = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'"
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrBackupUnsupported, driver)
+	}
+	var count int64
+	if err := db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// snapshotSQLite writes a consistent copy of the SQLite database db is
+// connected to. VACUUM INTO copies it as of one transaction, so writers
+// carry on while it runs; the driver offers no sqlite3_backup.
+func snapshotSQLite(ctx context.Context, db *sql.DB, w io.Writer) error {
+	dir, err := os.MkdirTemp("", "backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	snapshot := filepath.Join(dir, "snapshot.db")
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", snapshot); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	f, err := os.Open(snapshot)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// replaceSQLite replaces the SQLite database file at name with the one
+// read from r, once it is checked to be a sound database. The file is
+// swapped in whole, so readers never see a partial database.
+func replaceSQLite(ctx context.Context, name string, r io.Reader) error {
+	if name == "" || name == ":memory:" {
+		return fmt.Errorf("%w: in-memory SQLite databases cannot be restored", ErrBackupUnsupported)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := checkSQLite(ctx, tmp.Name()); err != nil {
+		return err
+	}
+
+	// The journal of the replaced file must not be replayed into the new one
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(name + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// checkSQLite fails unless the file at name is a sound SQLite database
+func checkSQLite(ctx context.Context, name string) error {
+	driver := database.NewSQLiteDriver(&database.SQLiteConfig{Path: name})
+	if err := driver.Connect(ctx); err != nil {
+		return err
+	}
+	defer driver.Close()
+
+	var result string
+	if err := driver.GetSQLDB().QueryRowContext(ctx, "PRAGMA quick_check").Scan(&result); err != nil {
+		return fmt.Errorf("backup is not a SQLite database: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup failed its integrity check: %s", result)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"BackofficeGoService/internal/pkg/jobs"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Names of the built-in maintenance jobs
+const (
+	JobAuditCleanup           = "audit_cleanup"
+	JobWebhookDeliveryCleanup = "webhook_delivery_cleanup"
+	JobNotificationCleanup    = "notification_cleanup"
+	JobSessionCleanup         = "session_cleanup"
+	JobUserPurge              = "user_purge"
+	JobTaskCleanup            = "task_cleanup"
+	JobRequestLogCleanup      = "request_log_cleanup"
+)
+
+// NewAuditCleanupJob purges audit logs and login events older than retention
+func NewAuditCleanupJob(audit *AuditService, schedule string, retention time.Duration, log logger.Logger) jobs.Job {
+	return jobs.NewFunc(JobAuditCleanup, schedule, func(ctx context.Context) error {
+		purged, err := audit.PurgeBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		log.Info("Purged audit records", logger.Field{Key: "rows", Value: purged})
+		return nil
+	})
+}
+
+// NewWebhookDeliveryCleanupJob purges webhook delivery attempts older than retention
+func NewWebhookDeliveryCleanupJob(webhooks *WebhookService, schedule string, retention time.Duration, log logger.Logger) jobs.Job {
+	return jobs.NewFunc(JobWebhookDeliveryCleanup, schedule, func(ctx context.Context) error {
+		purged, err := webhooks.PurgeDeliveries(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		log.Info("Purged webhook deliveries", logger.Field{Key: "rows", Value: purged})
+		return nil
+	})
+}
+
+// NewNotificationCleanupJob purges notifications older than retention
+func NewNotificationCleanupJob(notifications *NotificationService, schedule string, retention time.Duration, log logger.Logger) jobs.Job {
+	return jobs.NewFunc(JobNotificationCleanup, schedule, func(ctx context.Context) error {
+		purged, err := notifications.PurgeBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		log.Info("Purged notifications", logger.Field{Key: "rows", Value: purged})
+		return nil
+	})
+}
+
+// NewSessionCleanupJob purges sessions that expired or were revoked more
+// than retention ago, and trusted devices unused for deviceRetention
+func NewSessionCleanupJob(sessions *SessionService, devices *TrustedDeviceService, schedule string, retention, deviceRetention time.Duration, log logger.Logger) jobs.Job {
+	return jobs.NewFunc(JobSessionCleanup, schedule, func(ctx context.Context) error {
+		purged, err := sessions.PurgeBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		log.Info("Purged sessions", logger.Field{Key: "rows", Value: purged})
+
+		purged, err = devices.PurgeUnusedBefore(ctx, time.Now().Add(-deviceRetention))
+		if err != nil {
+			return err
+		}
+		log.Info("Purged trusted devices", logger.Field{Key: "rows", Value: purged})
+		return nil
+	})
+}
+
+// NewTaskCleanupJob purges tasks and their results older than retention
+func NewTaskCleanupJob(tasks *TaskService, schedule string, retention time.Duration, log logger.Logger) jobs.Job {
+	return jobs.NewFunc(JobTaskCleanup, schedule, func(ctx context.Context) error {
+		purged, err := tasks.PurgeBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		log.Info("Purged tasks", logger.Field{Key: "rows", Value: purged})
+		return nil
+	})
+}
+
+// NewRequestLogCleanupJob purges request log entries older than retention
+func NewRequestLogCleanupJob(requestLogs *RequestLogService, schedule string, retention time.Duration, log logger.Logger) jobs.Job {
+	return jobs.NewFunc(JobRequestLogCleanup, schedule, func(ctx context.Context) error {
+		purged, err := requestLogs.PurgeBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		log.Info("Purged request log entries", logger.Field{Key: "rows", Value: purged})
+		return nil
+	})
+}
+
+// NewUserPurgeJob permanently removes users soft-deleted longer than after
+// ago and counts the removed rows on purged by table. A zero after disables
+// the purge; dry runs are logged but not counted.
+func NewUserPurgeJob(users *UserService, schedule string, after time.Duration, opts UserPurgeOptions, purged *prometheus.CounterVec, log logger.Logger) jobs.Job {
+	return jobs.NewFunc(JobUserPurge, schedule, func(ctx context.Context) error {
+		if after <= 0 {
+			return nil
+		}
+		result, err := users.PurgeDeleted(ctx, time.Now().Add(-after), opts)
+		if result != nil && !result.DryRun && purged != nil {
+			purged.WithLabelValues("users").Add(float64(result.Users))
+			purged.WithLabelValues("login_events").Add(float64(result.LoginEvents))
+			purged.WithLabelValues("notifications").Add(float64(result.Notifications))
+			purged.WithLabelValues("organization_members").Add(float64(result.Memberships))
+			purged.WithLabelValues("audit_logs").Add(float64(result.AuditEntries))
+		}
+		if err != nil {
+			return err
+		}
+
+		fields := []logger.Field{
+			{Key: "users", Value: result.Users},
+			{Key: "login_events", Value: result.LoginEvents},
+			{Key: "notifications", Value: result.Notifications},
+			{Key: "memberships", Value: result.Memberships},
+			{Key: "audit_entries", Value: result.AuditEntries},
+		}
+		if result.DryRun {
+			log.Info("Dry run: users would be purged", fields...)
+			return nil
+		}
+		log.Info("Purged deleted users", fields...)
+		return nil
+	})
+}
+
+// userPurgeSampleSize is how many user IDs a purge preview lists
+const userPurgeSampleSize = 10
+
+// PreviewUserPurge reports what a run of the user purge job configured with
+// after and opts would remove, or nil when it would remove nothing
+func PreviewUserPurge(ctx context.Context, users *UserService, after time.Duration, opts UserPurgeOptions) (*ConfirmationSummary, error) {
+	if after <= 0 || opts.DryRun {
+		return nil, nil
+	}
+	cutoff := time.Now().Add(-after)
+	opts.DryRun = true
+	result, err := users.PurgeDeleted(ctx, cutoff, opts)
+	if err != nil {
+		return nil, err
+	}
+	if result.Users == 0 {
+		return nil, nil
+	}
+	sample, err := users.PurgeCandidates(ctx, cutoff, userPurgeSampleSize)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfirmationSummary{
+		Affected: map[string]int64{
+			"users":         result.Users,
+			"login_events":  result.LoginEvents,
+			"notifications": result.Notifications,
+			"memberships":   result.Memberships,
+			"audit_entries": result.AuditEntries,
+		},
+		SampleIDs: sample,
+	}, nil
+}
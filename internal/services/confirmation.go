@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+)
+
+// DefaultConfirmationTTL is how long a confirmation token stays valid when
+// no TTL is configured
+const DefaultConfirmationTTL = 5 * time.Minute
+
+// ConfirmationSummary describes what a destructive request would affect
+type ConfirmationSummary struct {
+	// Affected counts the records affected, by kind, such as "users"
+	Affected map[string]int64 `json:"affected"`
+	// SampleIDs are IDs of some of the affected users or records
+	SampleIDs []string `json:"sample_ids,omitempty"`
+}
+
+// ConfirmationChallenge is the answer to an unconfirmed destructive request
+type ConfirmationChallenge struct {
+	Token     string               `json:"confirm_token"`
+	ExpiresAt time.Time            `json:"expires_at"`
+	Summary   *ConfirmationSummary `json:"summary"`
+}
+
+// pendingConfirmation is what the store keeps for an issued token
+type pendingConfirmation struct {
+	Actor  string `json:"actor"`
+	Digest string `json:"digest"`
+}
+
+// Confirmations issues the single-use tokens destructive requests are
+// repeated with to run. A token is bound to the user it was issued to and
+// to the digest of the exact request, and is stored hashed in the cache,
+// so it expires with the entry.
+type Confirmations struct {
+	cache        cache.Store
+	ttl          time.Duration
+	exemptScopes []string
+	clock        clock.Clock
+}
+
+// ConfirmationOption configures a Confirmations
+type ConfirmationOption func(s *Confirmations)
+
+// WithConfirmationClock sets the clock token expiries are computed with
+func WithConfirmationClock(c clock.Clock) ConfirmationOption {
+	return func(s *Confirmations) {
+		s.clock = c
+	}
+}
+
+// NewConfirmations creates a confirmation service storing tokens in store
+// for ttl. API tokens whose scope is one of exemptScopes skip confirmation.
+func NewConfirmations(store cache.Store, ttl time.Duration, exemptScopes []string, opts ...ConfirmationOption) *Confirmations {
+	if ttl <= 0 {
+		ttl = DefaultConfirmationTTL
+	}
+	s := &Confirmations{
+		cache:        cache.WithPrefix(store, "confirm:"),
+		ttl:          ttl,
+		exemptScopes: exemptScopes,
+		clock:        clock.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Exempt reports whether API tokens with the API scope scope run
+// destructive requests without confirmation, as automation credentials do
+func (s *Confirmations) Exempt(scope string) bool {
+	return scope != "" && slices.Contains(s.exemptScopes, scope)
+}
+
+// Issue stores a token for actor to confirm the request digest identifies
+func (s *Confirmations) Issue(ctx context.Context, actor, digest string, summary *ConfirmationSummary) (*ConfirmationChallenge, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	value, err := json.Marshal(pendingConfirmation{Actor: actor, Digest: digest})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.Set(ctx, confirmationKey(token), value, s.ttl); err != nil {
+		return nil, fmt.Errorf("failed to store confirmation token: %w", err)
+	}
+	return &ConfirmationChallenge{
+		Token:     token,
+		ExpiresAt: s.clock.Now().Add(s.ttl).UTC(),
+		Summary:   summary,
+	}, nil
+}
+
+// Redeem uses token to confirm the request digest identifies. It fails
+// with ErrConfirmationInvalid if the token is unknown, used or expired and
+// with ErrConfirmationMismatch if it was issued to another user or for
+// another request; a mismatched token stays valid for its own request.
+func (s *Confirmations) Redeem(ctx context.Context, token, actor, digest string) error {
+	key := confirmationKey(token)
+	value, err := s.cache.Get(ctx, key)
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return ErrConfirmationInvalid
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read confirmation token: %w", err)
+	}
+
+	var pending pendingConfirmation
+	if err := json.Unmarshal(value, &pending); err != nil {
+		return ErrConfirmationInvalid
+	}
+	if pending.Actor != actor || pending.Digest != digest {
+		return ErrConfirmationMismatch
+	}
+
+	// Claiming the token is atomic, so of two requests sent with it at once
+	// only one runs
+	claims, err := s.cache.Incr(ctx, key+":used", 1, s.ttl)
+	if err != nil {
+		return fmt.Errorf("failed to claim confirmation token: %w", err)
+	}
+	if claims > 1 {
+		return ErrConfirmationInvalid
+	}
+	_ = s.cache.Delete(ctx, key)
+	return nil
+}
+
+// ConfirmationDigest identifies a request by its method, path, query and
+// body, so a token confirms exactly the request it was issued for
+func ConfirmationDigest(method, path, query string, body []byte) string {
+	h := sha256.New()
+	for _, part := range []string{method, path, query} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// confirmationKey is the cache key of token, which is stored hashed
+func confirmationKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
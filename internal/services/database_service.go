@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+// databaseNamePattern restricts database names to what is safe in logs,
+// metric labels and readiness reports
+var databaseNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// DatabaseConnector creates the driver of a database registered at runtime,
+// connects it within ctx and registers it under name. It fails with
+// ErrInvalidDatabase when no driver can be built from cfg.
+type DatabaseConnector func(ctx context.Context, name string, cfg config.DatabaseConnectionConfig) error
+
+// DatabaseInfo is a registered database and its health. It never includes
+// connection settings.
+type DatabaseInfo struct {
+	Name   string `json:"name"`
+	Driver string `json:"driver,omitempty"` // empty while an optional database is still retrying
+	database.DatabaseHealth
+}
+
+// DatabaseService connects and removes named databases at runtime
+type DatabaseService struct {
+	db      *database.Manager
+	connect DatabaseConnector
+	timeout time.Duration
+	audit   AuditRecorder
+	logger  logger.Logger
+}
+
+// NewDatabaseService creates a database service. Connecting a database
+// through connect is bounded by timeout.
+func NewDatabaseService(db *database.Manager, connect DatabaseConnector, timeout time.Duration, audit AuditRecorder, log logger.Logger) *DatabaseService {
+	return &DatabaseService{
+		db:      db,
+		connect: connect,
+		timeout: timeout,
+		audit:   audit,
+		logger:  log,
+	}
+}
+
+// ListDatabases returns every database, connected or still retrying, with
+// its health, ordered by name
+func (s *DatabaseService) ListDatabases(ctx context.Context) []DatabaseInfo {
+	_, health := s.db.Readiness(ctx)
+	databases := make([]DatabaseInfo, 0, len(health))
+	for name, h := range health {
+		info := DatabaseInfo{Name: name, DatabaseHealth: h}
+		if driverType, ok := s.db.DriverType(name); ok {
+			info.Driver = string(driverType)
+		}
+		databases = append(databases, info)
+	}
+	sort.Slice(databases, func(i, j int) bool { return databases[i].Name < databases[j].Name })
+	return databases
+}
+
+// RegisterDatabase connects a database and registers it under name, where it
+// is health checked like the configured ones. Nothing is registered unless
+// it connects. Databases registered at runtime are not persisted.
+func (s *DatabaseService) RegisterDatabase(ctx context.Context, actorID, name string, cfg config.DatabaseConnectionConfig) (*DatabaseInfo, error) {
+	if !databaseNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: names are lower case letters, digits, _ and -", ErrInvalidDatabase)
+	}
+	if cfg.Driver == "" || cfg.DBName == "" {
+		return nil, fmt.Errorf("%w: driver and dbname are required", ErrInvalidDatabase)
+	}
+	if cfg.MaxOpenConns < 0 || cfg.MaxIdleConns < 0 || cfg.BreakerFailures < 0 {
+		return nil, fmt.Errorf("%w: limits cannot be negative", ErrInvalidDatabase)
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	err := s.connect(connectCtx, name, cfg)
+	cancel()
+	switch {
+	case errors.Is(err, database.ErrDriverExists):
+		return nil, ErrDatabaseExists
+	case errors.Is(err, ErrInvalidDatabase):
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseConnectionFailed, err)
+	}
+
+	s.logger.Info("Database registered", logger.Field{Key: "database", Value: name}, logger.Field{Key: "driver", Value: cfg.Driver})
+	s.record(ctx, actorID, models.AuditActionDatabaseRegistered, name, map[string]string{
+		"driver":   cfg.Driver,
+		"required": strconv.FormatBool(cfg.Required),
+	})
+
+	info := s.describe(ctx, name)
+	return &info, nil
+}
+
+// RemoveDatabase closes the named database and unregisters it. Requests
+// already using it finish their statements first. The primary database and
+// databases tenants are routed to are refused with ErrDatabaseInUse.
+func (s *DatabaseService) RemoveDatabase(ctx context.Context, actorID, name string) error {
+	driverType, _ := s.db.DriverType(name)
+	if err := s.db.RemoveDriver(name); err != nil {
+		switch {
+		case errors.Is(err, database.ErrDriverNotFound):
+			return ErrDatabaseNotFound
+		case errors.Is(err, database.ErrDriverInUse):
+			return fmt.Errorf("%w: %s", ErrDatabaseInUse, strings.TrimPrefix(err.Error(), database.ErrDriverInUse.Error()+": "))
+		}
+		// The driver is unregistered even when closing it failed
+		s.logger.Warn("Failed to close removed database", logger.Field{Key: "database", Value: name}, logger.Field{Key: "error", Value: err.Error()})
+	}
+
+	s.logger.Info("Database removed", logger.Field{Key: "database", Value: name})
+	s.record(ctx, actorID, models.AuditActionDatabaseRemoved, name, map[string]string{"driver": string(driverType)})
+	return nil
+}
+
+// describe reports one database's health
+func (s *DatabaseService) describe(ctx context.Context, name string) DatabaseInfo {
+	info := DatabaseInfo{Name: name, DatabaseHealth: database.DatabaseHealth{Status: "up", Required: s.db.Required(name)}}
+	if driverType, ok := s.db.DriverType(name); ok {
+		info.Driver = string(driverType)
+	}
+	if breaker := s.db.CircuitBreaker(name); breaker != nil {
+		info.Circuit = breaker.State().String()
+	}
+	for _, check := range s.db.HealthChecks() {
+		if check.Name() != name {
+			continue
+		}
+		if err := check.Check(ctx); err != nil {
+			info.Status = "down"
+			info.Error = err.Error()
+		}
+	}
+	return info
+}
+
+// record audits a change to a database; failures are only logged
+func (s *DatabaseService) record(ctx context.Context, actorID, action, name string, metadata map[string]string) {
+	if s.audit == nil {
+		return
+	}
+	if err := s.audit.Record(ctx, actorID, action, "database", name, metadata); err != nil {
+		s.logger.Warn("Failed to audit database change", logger.Field{Key: "database", Value: name}, logger.Field{Key: "action", Value: action}, logger.Field{Key: "error", Value: err.Error()})
+	}
+}
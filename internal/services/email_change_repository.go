@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EmailChangeRepository persists pending email changes and applies them
+type EmailChangeRepository interface {
+	// FindUser returns the user with id, password hash included, or ErrUserNotFound
+	FindUser(ctx context.Context, id uuid.UUID) (*models.User, error)
+
+	// EmailTaken reports whether a user other than exceptID has email
+	EmailTaken(ctx context.Context, email string, exceptID uuid.UUID) (bool, error)
+
+	// Replace stores change in place of any pending change of its user
+	Replace(ctx context.Context, change *models.EmailChange) error
+
+	// FindByTokenHash returns the change with tokenHash, or ErrEmailChangeInvalid
+	FindByTokenHash(ctx context.Context, tokenHash string) (*models.EmailChange, error)
+
+	// Apply sets the user's email to the change's address and deletes the
+	// user's pending changes in one transaction. It returns ErrEmailTaken if
+	// another user has the address by then.
+	Apply(ctx context.Context, change *models.EmailChange, at time.Time) error
+}
+
+// gormEmailChangeRepository implements EmailChangeRepository on the database each call is scoped to
+type gormEmailChangeRepository struct {
+	db *database.Manager
+}
+
+// NewEmailChangeRepository creates a repository backed by the database each call is scoped to
+func NewEmailChangeRepository(db *database.Manager) EmailChangeRepository {
+	return &gormEmailChangeRepository{db: db}
+}
+
+func (r *gormEmailChangeRepository) FindUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("id = ?", id).First(&user).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *gormEmailChangeRepository) EmailTaken(ctx context.Context, email string, exceptID uuid.UUID) (bool, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+	return emailTakenBy(db, email, exceptID)
+}
+
+func (r *gormEmailChangeRepository) Replace(ctx context.Context, change *models.EmailChange) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", change.UserID).Delete(&models.EmailChange{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+}
+
+func (r *gormEmailChangeRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.EmailChange, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var change models.EmailChange
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("token_hash = ?", tokenHash).First(&change).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmailChangeInvalid
+		}
+		return nil, err
+	}
+	return &change, nil
+}
+
+func (r *gormEmailChangeRepository) Apply(ctx context.Context, change *models.EmailChange, at time.Time) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		taken, err := emailTakenBy(tx, change.NewEmail, change.UserID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrEmailTaken
+		}
+
+		result := tx.Model(&models.User{}).Where("id = ?", change.UserID).
+			Updates(map[string]interface{}{"email": change.NewEmail, "updated_at": at})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		return tx.Where("user_id = ?", change.UserID).Delete(&models.EmailChange{}).Error
+	})
+}
+
+// emailTakenBy reports whether a user other than exceptID has email
+func emailTakenBy(db *gorm.DB, email string, exceptID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.User{}).Where("email = ? AND id <> ?", email, exceptID).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/infrastructure/email"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/emailaddr"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+	"BackofficeGoService/internal/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+// EmailChangeService changes users' email addresses once the new address
+// is proven to receive mail
+type EmailChangeService struct {
+	repo     EmailChangeRepository
+	mailer   email.EmailClient
+	cache    cache.Store
+	revoker  *TokenRevoker
+	sessions *SessionService
+	audit    *AuditService
+	ttl      time.Duration
+	metrics  *metrics.Business
+	logger   logger.Logger
+	clock    clock.Clock
+	emails   emailaddr.Normalization
+	// responses, if set, drops cached user listings on confirmed changes
+	responses *ResponseCache
+	templates *email.Registry
+}
+
+// EmailChangeOption configures an EmailChangeService
+type EmailChangeOption func(s *EmailChangeService)
+
+// WithEmailChangeClock sets the clock that timestamps and expires changes
+func WithEmailChangeClock(c clock.Clock) EmailChangeOption {
+	return func(s *EmailChangeService) {
+		s.clock = c
+	}
+}
+
+// WithEmailChangeMetrics counts the emails sent
+func WithEmailChangeMetrics(m *metrics.Business) EmailChangeOption {
+	return func(s *EmailChangeService) {
+		s.metrics = m
+	}
+}
+
+// WithEmailChangeNormalization sets how new email addresses are normalized;
+// it should match the user service's
+func WithEmailChangeNormalization(n emailaddr.Normalization) EmailChangeOption {
+	return func(s *EmailChangeService) {
+		s.emails = n
+	}
+}
+
+// WithEmailChangeTemplates sets the registry the emails are rendered from;
+// it must hold the templates NewEmailTemplates registers
+func WithEmailChangeTemplates(templates *email.Registry) EmailChangeOption {
+	return func(s *EmailChangeService) {
+		s.templates = templates
+	}
+}
+
+// WithEmailChangeResponseCache purges the cached responses listing users
+// when a change is confirmed
+func WithEmailChangeResponseCache(r *ResponseCache) EmailChangeOption {
+	return func(s *EmailChangeService) {
+		s.responses = r
+	}
+}
+
+// NewEmailChangeService creates a new email change service. Confirmation
+// tokens last ttl. Without a mailer every request fails with
+// ErrEmailUnavailable. sessions may be nil.
+func NewEmailChangeService(repo EmailChangeRepository, mailer email.EmailClient, store cache.Store, revoker *TokenRevoker, sessions *SessionService, audit *AuditService, ttl time.Duration, log logger.Logger, opts ...EmailChangeOption) *EmailChangeService {
+	s := &EmailChangeService{
+		repo:     repo,
+		mailer:   mailer,
+		cache:    store,
+		revoker:  revoker,
+		sessions: sessions,
+		audit:    audit,
+		ttl:      ttl,
+		logger:   log,
+		clock:    clock.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.templates == nil {
+		s.templates = NewEmailTemplates()
+	}
+	return s
+}
+
+// Request starts changing the email of the user claims belong to after
+// checking their password. A confirmation token is mailed to newEmail and
+// the current address is told about the change; an earlier pending change
+// is superseded. Impersonators cannot change emails.
+func (s *EmailChangeService) Request(ctx context.Context, claims *TokenClaims, password, newEmail string) (*models.EmailChange, error) {
+	if claims.Impersonating() {
+		return nil, ErrImpersonationForbidden
+	}
+	if s.mailer == nil {
+		return nil, ErrEmailUnavailable
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.repo.FindUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.CheckPasswordHash(password, user.Password) {
+		return nil, ErrInvalidCurrentPassword
+	}
+	newEmail = s.emails.Normalize(newEmail)
+	if newEmail == user.Email {
+		return nil, ErrEmailUnchanged
+	}
+	taken, err := s.repo.EmailTaken(ctx, newEmail, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if taken {
+		return nil, ErrEmailTaken
+	}
+
+	token, err := generateEmailChangeToken()
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	change := &models.EmailChange{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		NewEmail:  newEmail,
+		TokenHash: hashEmailChangeToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+	if err := s.repo.Replace(ctx, change); err != nil {
+		return nil, fmt.Errorf("failed to store email change: %w", err)
+	}
+
+	confirm := EmailChangeConfirmData{Token: token, ExpiresAt: change.ExpiresAt}
+	if err := s.send(newEmail, EmailTemplateEmailChangeConfirm, confirm); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEmailDeliveryFailed, err)
+	}
+
+	// The old address hears about the change in case the session was stolen
+	if err := s.send(user.Email, EmailTemplateEmailChangeNotice, EmailChangeNoticeData{NewEmail: newEmail}); err != nil {
+		s.logger.Warn("Failed to notify the current email address", logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+	}
+
+	return change, nil
+}
+
+// Confirm applies the change the token was mailed for. The address is
+// checked again since someone may have taken it since the request. The
+// user's tokens and sessions are revoked, so they log in again with the
+// new address.
+func (s *EmailChangeService) Confirm(ctx context.Context, token string) (*models.User, error) {
+	change, err := s.repo.FindByTokenHash(ctx, hashEmailChangeToken(token))
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	if !now.Before(change.ExpiresAt) {
+		return nil, ErrEmailChangeExpired
+	}
+
+	user, err := s.repo.FindUser(ctx, change.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Apply(ctx, change, now); err != nil {
+		if errors.Is(err, ErrEmailTaken) || errors.Is(err, ErrUserNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to apply email change: %w", err)
+	}
+
+	userID := user.ID.String()
+	_ = s.cache.Delete(ctx, userCacheKeyByID(userID), userCacheKeyByEmail(user.Email), userCacheKeyByEmail(change.NewEmail))
+	s.responses.Purge(ctx, ResponseGroupUsers)
+	if err := s.revoker.RevokeUserTokens(ctx, userID); err != nil {
+		s.logger.Warn("Failed to revoke tokens after email change", logger.Field{Key: "user_id", Value: userID}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	if s.sessions != nil {
+		if _, err := s.sessions.RevokeOthers(ctx, userID, userID, ""); err != nil {
+			s.logger.Warn("Failed to revoke sessions after email change", logger.Field{Key: "user_id", Value: userID}, logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+
+	if err := s.audit.Record(ctx, userID, models.AuditActionUserEmailChanged, "user", userID, nil); err != nil {
+		s.logger.Warn("Failed to audit email change", logger.Field{Key: "user_id", Value: userID}, logger.Field{Key: "error", Value: err.Error()})
+	}
+
+	user.Email = change.NewEmail
+	user.UpdatedAt = now
+	user.Password = ""
+	return user, nil
+}
+
+// generateEmailChangeToken returns a random hex-encoded confirmation token
+func generateEmailChangeToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashEmailChangeToken returns the form a confirmation token is stored in
+func hashEmailChangeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// send mails the template rendered as text with data to to and counts the attempt
+func (s *EmailChangeService) send(to, template string, data interface{}) error {
+	message, err := s.templates.Render(template, email.FormatText, data)
+	if err != nil {
+		return err
+	}
+	err = s.mailer.Send(to, message.Subject, message.Body)
+	s.metrics.EmailSent(err)
+	return err
+}
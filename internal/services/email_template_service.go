@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/infrastructure/email"
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+// testSubjectPrefix marks the subject of test emails
+const testSubjectPrefix = "[Test] "
+
+// EmailTemplateService lets operators see the email templates rendered
+// with their sample data and send them to themselves
+type EmailTemplateService struct {
+	templates *email.Registry
+	mailer    email.EmailClient
+	audit     AuditRecorder
+	logger    logger.Logger
+}
+
+// NewEmailTemplateService creates an email template service. Without a
+// mailer test sends fail with ErrEmailUnavailable.
+func NewEmailTemplateService(templates *email.Registry, mailer email.EmailClient, audit AuditRecorder, log logger.Logger) *EmailTemplateService {
+	return &EmailTemplateService{
+		templates: templates,
+		mailer:    mailer,
+		audit:     audit,
+		logger:    log,
+	}
+}
+
+// ListTemplates describes every registered template, ordered by name
+func (s *EmailTemplateService) ListTemplates() []email.TemplateInfo {
+	return s.templates.Templates()
+}
+
+// Preview renders the template name in format with its sample data
+func (s *EmailTemplateService) Preview(ctx context.Context, actorID, name string, format email.Format) (*email.Message, error) {
+	message, err := s.render(name, format)
+	if err != nil {
+		return nil, err
+	}
+	s.record(ctx, actorID, models.AuditActionEmailPreviewed, name, map[string]string{"format": string(format)})
+	return message, nil
+}
+
+// TestSend renders the template name as text with its sample data and
+// sends it to to through the configured email client. The subject is
+// prefixed with "[Test]".
+func (s *EmailTemplateService) TestSend(ctx context.Context, actorID, name, to string) (*email.Message, error) {
+	if s.mailer == nil {
+		return nil, ErrEmailUnavailable
+	}
+	message, err := s.render(name, email.FormatText)
+	if err != nil {
+		return nil, err
+	}
+	message.Subject = testSubjectPrefix + message.Subject
+	if err := s.mailer.Send(to, message.Subject, message.Body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEmailDeliveryFailed, err)
+	}
+	s.record(ctx, actorID, models.AuditActionEmailTestSent, name, map[string]string{"to": to})
+	return message, nil
+}
+
+// render renders the template name with its sample data
+func (s *EmailTemplateService) render(name string, format email.Format) (*email.Message, error) {
+	info, err := s.templates.Template(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.templates.Render(name, format, info.Sample)
+}
+
+// record audits an operator's use of a template; failures are only logged
+func (s *EmailTemplateService) record(ctx context.Context, actorID, action, name string, metadata map[string]string) {
+	if s.audit == nil {
+		return
+	}
+	if err := s.audit.Record(ctx, actorID, action, "email_template", name, metadata); err != nil {
+		s.logger.Warn("Failed to audit email template use", logger.Field{Key: "template", Value: name}, logger.Field{Key: "action", Value: action}, logger.Field{Key: "error", Value: err.Error()})
+	}
+}
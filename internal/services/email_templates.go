@@ -0,0 +1,89 @@
+package services
+
+import (
+	"time"
+
+	"BackofficeGoService/internal/infrastructure/email"
+)
+
+// Names of the email templates the services send
+const (
+	EmailTemplateEmailChangeConfirm = "email_change_confirm"
+	EmailTemplateEmailChangeNotice  = "email_change_notice"
+	EmailTemplateNewSignIn          = "new_sign_in"
+)
+
+// EmailChangeConfirmData is the data of the email_change_confirm template
+type EmailChangeConfirmData struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EmailChangeNoticeData is the data of the email_change_notice template
+type EmailChangeNoticeData struct {
+	NewEmail string `json:"new_email"`
+}
+
+// NewSignInData is the data of the new_sign_in template
+type NewSignInData struct {
+	Device    string    `json:"device"`
+	IPAddress string    `json:"ip_address"`
+	Location  string    `json:"location"`
+	Time      time.Time `json:"time"`
+	SecureURL string    `json:"secure_url"`
+}
+
+// NewEmailTemplates creates a registry holding every template the services send
+func NewEmailTemplates() *email.Registry {
+	templates := email.NewRegistry()
+	templates.MustRegister(email.Template{
+		Name:        EmailTemplateEmailChangeConfirm,
+		Description: "Sent to a new email address with the token confirming the change",
+		Subject:     "Confirm your new email address",
+		Text:        "Confirm your new email address with this token:\n\n{{.Token}}\n\nIt expires at {{.ExpiresAt.UTC.Format \"Mon, 02 Jan 2006 15:04:05 MST\"}}.",
+		HTML: `<p>Confirm your new email address with this token:</p>
+<p><code>{{.Token}}</code></p>
+<p>It expires at {{.ExpiresAt.UTC.Format "Mon, 02 Jan 2006 15:04:05 MST"}}.</p>`,
+		Sample: EmailChangeConfirmData{
+			Token:     "3f9a1c0e5b7d42a8b6e1f0c9d8a7b6e53f9a1c0e5b7d42a8b6e1f0c9d8a7b6e5",
+			ExpiresAt: time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC),
+		},
+	})
+	templates.MustRegister(email.Template{
+		Name:        EmailTemplateEmailChangeNotice,
+		Description: "Sent to the current email address when a change to another is requested",
+		Subject:     "Your email address is being changed",
+		Text:        "A change of your account's email address to {{.NewEmail}} was requested. If this was not you, change your password.",
+		HTML:        `<p>A change of your account's email address to <strong>{{.NewEmail}}</strong> was requested. If this was not you, change your password.</p>`,
+		Sample:      EmailChangeNoticeData{NewEmail: "new.address@example.com"},
+	})
+	templates.MustRegister(email.Template{
+		Name:        EmailTemplateNewSignIn,
+		Description: "Sent when a user logs in from a device or IP range they never used before",
+		Subject:     "New sign-in to your account",
+		Text: `Your account was signed in to from a new device or location.
+
+Device: {{.Device}}
+IP address: {{.IPAddress}}{{if .Location}} ({{.Location}}){{end}}
+Time: {{.Time.UTC.Format "Mon, 02 Jan 2006 15:04:05 MST"}}
+
+If this was you, there is nothing to do. Otherwise, secure your account to sign out everywhere, then change your password:
+
+{{.SecureURL}}`,
+		HTML: `<p>Your account was signed in to from a new device or location.</p>
+<ul>
+<li>Device: {{.Device}}</li>
+<li>IP address: {{.IPAddress}}{{if .Location}} ({{.Location}}){{end}}</li>
+<li>Time: {{.Time.UTC.Format "Mon, 02 Jan 2006 15:04:05 MST"}}</li>
+</ul>
+<p>If this was you, there is nothing to do. Otherwise, <a href="{{.SecureURL}}">secure your account</a> to sign out everywhere, then change your password.</p>`,
+		Sample: NewSignInData{
+			Device:    "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15",
+			IPAddress: "203.0.113.42",
+			Location:  "Berlin, Germany",
+			Time:      time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC),
+			SecureURL: "https://app.example.com/secure-account?token=3f9a1c0e5b7d42a8b6e1f0c9d8a7b6e5",
+		},
+	})
+	return templates
+}
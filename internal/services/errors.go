@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrTokenRevoked       = errors.New("token has been revoked")
+	ErrTokenExpired       = fmt.Errorf("%w: token has expired", ErrInvalidToken) // also matches ErrInvalidToken
+	ErrAccountDeactivated = errors.New("account is deactivated")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrSessionExpired     = errors.New("session has expired")
+	ErrDeviceNotFound     = errors.New("trusted device not found")
+	ErrSelfDeactivation   = errors.New("users cannot deactivate their own account")
+	ErrLastActiveAdmin    = errors.New("cannot deactivate the last active admin")
+	ErrLastAdminDemotion  = errors.New("cannot take the admin role from the last active admin")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrEmailUnchanged     = errors.New("new email is the current one")
+	ErrEmailDuplicates    = errors.New("several users share an email address once normalized")
+	ErrEmptySearchQuery   = errors.New("search query is required")
+
+	ErrInvalidExportColumn = errors.New("unknown export column")
+	ErrInvalidExportSort   = errors.New("unknown export sort field")
+
+	ErrImpersonationForbidden     = errors.New("not allowed while impersonating")
+	ErrAdminImpersonationDisabled = errors.New("impersonating admins is disabled")
+	ErrSelfImpersonation          = errors.New("users cannot impersonate themselves")
+	ErrNotImpersonating           = errors.New("token is not an impersonation token")
+
+	ErrInvalidAPIScope  = errors.New("API token scope is empty or restricts tokens")
+	ErrAPITokenLifetime = errors.New("API token lifetime exceeds the longest token lifetime")
+
+	ErrPasswordChangeRequired = errors.New("password must be changed")
+	ErrProfileIncomplete      = errors.New("profile must be completed")
+	ErrInvalidCurrentPassword = errors.New("current password is wrong")
+	ErrPasswordReused         = errors.New("new password must differ from the current one")
+
+	ErrPasswordBreached         = errors.New("password appears in known data breaches")
+	ErrPasswordCheckUnavailable = errors.New("password breach check is unavailable")
+
+	ErrEmailChangeInvalid  = errors.New("email change token is invalid")
+	ErrEmailChangeExpired  = errors.New("email change token has expired")
+	ErrEmailUnavailable    = errors.New("email delivery is not configured")
+	ErrEmailDeliveryFailed = errors.New("failed to send email")
+
+	ErrSecureAccountTokenInvalid = errors.New("secure account token is invalid, used or expired")
+
+	ErrConfirmationInvalid  = errors.New("confirmation token is unknown, used or expired")
+	ErrConfirmationMismatch = errors.New("confirmation token was issued for another request")
+
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrUnknownPermission = errors.New("unknown permission")
+
+	ErrApprovalNotFound     = errors.New("approval not found")
+	ErrApprovalPending      = errors.New("a change of the same kind is already awaiting approval")
+	ErrApprovalNotPending   = errors.New("approval has already been decided")
+	ErrApprovalExpired      = errors.New("approval has expired")
+	ErrSelfApproval         = errors.New("requesters cannot approve or reject their own changes")
+	ErrNotApprovalRequester = errors.New("only the requester can cancel an approval")
+	ErrApprovalStale        = errors.New("the subject changed since the approval was requested")
+
+	ErrOrganizationNotFound    = errors.New("organization not found")
+	ErrInvalidOrganizationName = errors.New("organization name is required")
+	ErrOrganizationHasMembers  = errors.New("organization still has members")
+	ErrMembershipExists        = errors.New("user is already a member of the organization")
+	ErrMembershipNotFound      = errors.New("membership not found")
+	ErrInvalidOrgRole          = errors.New("invalid organization role")
+
+	ErrWebhookNotFound   = errors.New("webhook not found")
+	ErrInvalidWebhookURL = errors.New("webhook URL must be an absolute http or https URL")
+	ErrUnknownEventType  = errors.New("unknown event type")
+	ErrWebhookQueueFull  = errors.New("webhook delivery queue is full")
+
+	ErrUnknownSetting      = errors.New("unknown setting")
+	ErrSettingTypeMismatch = errors.New("setting value does not match its type")
+
+	ErrUnknownPolicy         = errors.New("unknown policy")
+	ErrPolicyVersionOutdated = errors.New("policy version is not the current one")
+
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrInvalidNotification  = errors.New("notification type and title are required")
+
+	ErrTaskNotFound      = errors.New("task not found")
+	ErrTaskFinished      = errors.New("task has already finished")
+	ErrTaskRunnerStopped = errors.New("task runner is stopped")
+
+	ErrInvalidTenant          = errors.New("invalid tenant")
+	ErrTenantExists           = errors.New("tenant already exists")
+	ErrTenantDatabaseNotFound = errors.New("tenant database not found")
+
+	ErrInvalidDatabase          = errors.New("invalid database")
+	ErrDatabaseExists           = errors.New("database already exists")
+	ErrDatabaseNotFound         = errors.New("database not found")
+	ErrDatabaseInUse            = errors.New("database is in use")
+	ErrDatabaseConnectionFailed = errors.New("database connection failed")
+
+	ErrPartnerNotFound = errors.New("partner not found")
+	ErrPartnerExists   = errors.New("partner already exists")
+
+	ErrSavedFilterNotFound    = errors.New("saved filter not found")
+	ErrSavedFilterForbidden   = errors.New("only the owner or an admin may change a shared filter")
+	ErrUnknownFilterResource  = errors.New("filters cannot be saved for the resource")
+	ErrFilterResourceMismatch = errors.New("saved filter is for another resource")
+	ErrUnknownFilterField     = errors.New("unknown filter field")
+	ErrInvalidFilterValue     = errors.New("invalid filter value")
+	ErrFilterSchemaTooNew     = errors.New("saved filter uses a newer filter schema")
+
+	ErrJWTKeyFileUnset = errors.New("JWT_SECRET_FILE is not set")
+
+	ErrBackupNotFound       = errors.New("backup not found")
+	ErrBackupUnsupported    = errors.New("database driver cannot be backed up")
+	ErrBackupDriverMismatch = errors.New("backup was taken from another kind of database")
+	ErrBackupTargetNotEmpty = errors.New("database to restore into is not empty")
+
+	ErrOIDCProviderNotFound = errors.New("OpenID Connect provider not found")
+	ErrOIDCStateInvalid     = errors.New("sign-in state is invalid or expired")
+	ErrOIDCAccountExists    = errors.New("a user with the email already exists and is not linked to the provider")
+	ErrOIDCNotProvisioned   = errors.New("no user is linked to the provider account")
+	ErrIdentityLinked       = errors.New("provider account is already linked")
+
+	ErrQuotaExceeded = errors.New("monthly API quota exceeded")
+	ErrInvalidPeriod = errors.New("period must be a month such as 2024-06")
+)
@@ -0,0 +1,92 @@
+package featureflags
+
+import (
+	"context"
+	"errors"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository persists feature flags
+type Repository interface {
+	List(ctx context.Context) ([]*models.FeatureFlag, error)
+	Get(ctx context.Context, key string) (*models.FeatureFlag, error)
+	Create(ctx context.Context, flag *models.FeatureFlag) error
+	Update(ctx context.Context, flag *models.FeatureFlag) error
+	Delete(ctx context.Context, key string) error
+}
+
+// gormRepository implements Repository on the database each call is scoped to.
+// "key" is reserved in MySQL, so queries use struct conditions that GORM quotes.
+type gormRepository struct {
+	db *database.Manager
+}
+
+// NewRepository creates a repository backed by the database each call is scoped to
+func NewRepository(db *database.Manager) Repository {
+	return &gormRepository{db: db}
+}
+
+func (r *gormRepository) List(ctx context.Context) ([]*models.FeatureFlag, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags []*models.FeatureFlag
+	err = db.Order(clause.OrderByColumn{Column: clause.Column{Name: "key"}}).Find(&flags).Error
+	return flags, err
+}
+
+func (r *gormRepository) Get(ctx context.Context, key string) (*models.FeatureFlag, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var flag models.FeatureFlag
+	if err := db.Where(&models.FeatureFlag{Key: key}).First(&flag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFlagNotFound
+		}
+		return nil, err
+	}
+	return &flag, nil
+}
+
+func (r *gormRepository) Create(ctx context.Context, flag *models.FeatureFlag) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(flag).Error
+}
+
+func (r *gormRepository) Update(ctx context.Context, flag *models.FeatureFlag) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	// Save writes zero values too, e.g. enabled=false or an emptied targeting list
+	return db.Save(flag).Error
+}
+
+func (r *gormRepository) Delete(ctx context.Context, key string) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	result := db.Where(&models.FeatureFlag{Key: key}).Delete(&models.FeatureFlag{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFlagNotFound
+	}
+	return nil
+}
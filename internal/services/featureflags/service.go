@@ -0,0 +1,274 @@
+// Package featureflags evaluates feature flags for the current user and
+// manages their definitions. Evaluation is deterministic per user: a user
+// either always or never falls inside a flag's rollout percentage.
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+var (
+	ErrFlagNotFound      = errors.New("feature flag not found")
+	ErrFlagExists        = errors.New("feature flag already exists")
+	ErrInvalidFlagKey    = errors.New("feature flag key must be 1-100 lowercase letters, digits, dots, dashes or underscores")
+	ErrInvalidPercentage = errors.New("rollout percentage must be between 0 and 100")
+)
+
+// flagsCacheKey holds every flag definition. Edits delete it locally; other
+// replicas pick changes up when flagsCacheTTL expires.
+const (
+	flagsCacheKey = "featureflags:all"
+	flagsCacheTTL = 30 * time.Second
+)
+
+var flagKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,99}$`)
+
+// User is the subject a flag is evaluated for
+type User struct {
+	ID   string
+	Role string
+}
+
+// Evaluation is the result of evaluating a flag for a user
+type Evaluation struct {
+	Enabled bool        `json:"enabled"`
+	Payload models.JSON `json:"payload,omitempty"`
+}
+
+// CreateFlagRequest represents the payload for creating a flag
+type CreateFlagRequest struct {
+	Key               string      `json:"key" binding:"required"`
+	Description       string      `json:"description" binding:"max=500"`
+	Enabled           bool        `json:"enabled"`
+	RolloutPercentage int         `json:"rollout_percentage"`
+	AllowedRoles      []string    `json:"allowed_roles"`
+	AllowedUsers      []string    `json:"allowed_users"`
+	Payload           models.JSON `json:"payload"`
+}
+
+// UpdateFlagRequest represents a partial flag update
+type UpdateFlagRequest struct {
+	Description       *string     `json:"description" binding:"omitempty,max=500"`
+	Enabled           *bool       `json:"enabled"`
+	RolloutPercentage *int        `json:"rollout_percentage"`
+	AllowedRoles      []string    `json:"allowed_roles"`
+	AllowedUsers      []string    `json:"allowed_users"`
+	Payload           models.JSON `json:"payload"`
+}
+
+// Service manages and evaluates feature flags
+type Service struct {
+	repo   Repository
+	cache  cache.Store
+	logger logger.Logger
+}
+
+// NewService creates a new feature flag service
+func NewService(repo Repository, store cache.Store, log logger.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		cache:  store,
+		logger: log,
+	}
+}
+
+// Bucket maps a user to a stable bucket in [0, 100) for a flag. Hashing the
+// key with the user ID keeps rollouts of different flags independent.
+func Bucket(key, userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	h.Write([]byte{':'})
+	h.Write([]byte(userID))
+	return int(h.Sum32() % 100)
+}
+
+// Evaluate decides whether flag is on for user. Explicitly allowed users are
+// always in; otherwise the role must be allowed (when roles are listed) and
+// the user's bucket must fall inside the rollout percentage.
+func Evaluate(flag *models.FeatureFlag, user *User) bool {
+	if flag == nil || !flag.Enabled {
+		return false
+	}
+
+	if user != nil && user.ID != "" && flag.AllowedUsers.Contains(user.ID) {
+		return true
+	}
+
+	if len(flag.AllowedRoles) > 0 && (user == nil || !flag.AllowedRoles.Contains(user.Role)) {
+		return false
+	}
+
+	switch {
+	case flag.RolloutPercentage >= 100:
+		return true
+	case flag.RolloutPercentage <= 0 || user == nil || user.ID == "":
+		return false
+	}
+	return Bucket(flag.Key, user.ID) < flag.RolloutPercentage
+}
+
+// IsEnabled reports whether the flag is on for user. Unknown flags and lookup
+// errors evaluate to false.
+func (s *Service) IsEnabled(ctx context.Context, key string, user *User) bool {
+	flags, err := s.flags(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to load feature flags", logger.Field{Key: "flag", Value: key}, logger.Field{Key: "error", Value: err.Error()})
+		return false
+	}
+	return Evaluate(flags[key], user)
+}
+
+// EvaluateAll evaluates every flag for user; payloads are only included for enabled flags
+func (s *Service) EvaluateAll(ctx context.Context, user *User) (map[string]Evaluation, error) {
+	flags, err := s.flags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluations := make(map[string]Evaluation, len(flags))
+	for key, flag := range flags {
+		eval := Evaluation{Enabled: Evaluate(flag, user)}
+		if eval.Enabled {
+			eval.Payload = flag.Payload
+		}
+		evaluations[key] = eval
+	}
+	return evaluations, nil
+}
+
+// flags returns every flag keyed by flag key, read through the cache
+func (s *Service) flags(ctx context.Context) (map[string]*models.FeatureFlag, error) {
+	if data, err := s.cache.Get(ctx, flagsCacheKey); err == nil {
+		var flags map[string]*models.FeatureFlag
+		if err := json.Unmarshal(data, &flags); err == nil {
+			return flags, nil
+		}
+	}
+
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	flags := make(map[string]*models.FeatureFlag, len(list))
+	for _, flag := range list {
+		flags[flag.Key] = flag
+	}
+
+	if data, err := json.Marshal(flags); err == nil {
+		_ = s.cache.Set(ctx, flagsCacheKey, data, flagsCacheTTL)
+	}
+	return flags, nil
+}
+
+// invalidate drops the cached flag definitions after an edit
+func (s *Service) invalidate(ctx context.Context) {
+	if err := s.cache.Delete(ctx, flagsCacheKey); err != nil {
+		s.logger.Warn("Failed to invalidate feature flag cache", logger.Field{Key: "error", Value: err.Error()})
+	}
+}
+
+// ListFlags returns every flag definition
+func (s *Service) ListFlags(ctx context.Context) ([]*models.FeatureFlag, error) {
+	flags, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return flags, nil
+}
+
+// GetFlag returns a flag definition by key
+func (s *Service) GetFlag(ctx context.Context, key string) (*models.FeatureFlag, error) {
+	return s.repo.Get(ctx, key)
+}
+
+// CreateFlag creates a new flag
+func (s *Service) CreateFlag(ctx context.Context, req *CreateFlagRequest) (*models.FeatureFlag, error) {
+	if !flagKeyPattern.MatchString(req.Key) {
+		return nil, ErrInvalidFlagKey
+	}
+	if req.RolloutPercentage < 0 || req.RolloutPercentage > 100 {
+		return nil, ErrInvalidPercentage
+	}
+
+	if _, err := s.repo.Get(ctx, req.Key); err == nil {
+		return nil, ErrFlagExists
+	} else if !errors.Is(err, ErrFlagNotFound) {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	now := time.Now()
+	flag := &models.FeatureFlag{
+		Key:               req.Key,
+		Description:       req.Description,
+		Enabled:           req.Enabled,
+		RolloutPercentage: req.RolloutPercentage,
+		AllowedRoles:      models.StringList(req.AllowedRoles),
+		AllowedUsers:      models.StringList(req.AllowedUsers),
+		Payload:           req.Payload,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	if err := s.repo.Create(ctx, flag); err != nil {
+		return nil, fmt.Errorf("failed to create feature flag: %w", err)
+	}
+	s.invalidate(ctx)
+	return flag, nil
+}
+
+// UpdateFlag applies a partial update to a flag
+func (s *Service) UpdateFlag(ctx context.Context, key string, req *UpdateFlagRequest) (*models.FeatureFlag, error) {
+	flag, err := s.repo.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Description != nil {
+		flag.Description = *req.Description
+	}
+	if req.Enabled != nil {
+		flag.Enabled = *req.Enabled
+	}
+	if req.RolloutPercentage != nil {
+		if *req.RolloutPercentage < 0 || *req.RolloutPercentage > 100 {
+			return nil, ErrInvalidPercentage
+		}
+		flag.RolloutPercentage = *req.RolloutPercentage
+	}
+	if req.AllowedRoles != nil {
+		flag.AllowedRoles = models.StringList(req.AllowedRoles)
+	}
+	if req.AllowedUsers != nil {
+		flag.AllowedUsers = models.StringList(req.AllowedUsers)
+	}
+	if req.Payload != nil {
+		flag.Payload = req.Payload
+	}
+	flag.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ctx, flag); err != nil {
+		return nil, fmt.Errorf("failed to update feature flag: %w", err)
+	}
+	s.invalidate(ctx)
+	return flag, nil
+}
+
+// DeleteFlag deletes a flag
+func (s *Service) DeleteFlag(ctx context.Context, key string) error {
+	if err := s.repo.Delete(ctx, key); err != nil {
+		return err
+	}
+	s.invalidate(ctx)
+	return nil
+}
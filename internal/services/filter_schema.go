@@ -0,0 +1,249 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+)
+
+// FilterSchema describes the fields a list filter of a resource may hold.
+// Saved filters record the Version they were stored under. Upgrades[v]
+// rewrites the fields of a version v filter into version v+1, so that
+// renaming or dropping a field bumps Version and adds an upgrade rather
+// than breaking the filters users saved before.
+type FilterSchema struct {
+	Version int
+
+	// Fields name the filter fields, which are also the query parameters
+	// of the resource's listing
+	Fields []string
+
+	// Permission is needed to read the filters others shared
+	Permission string
+
+	// Check validates the values of a filter
+	Check func(values map[string]string) error
+
+	Upgrades map[int]func(values map[string]string) error
+}
+
+// FilterFieldError reports a filter field that is unknown or whose value
+// does not parse
+type FilterFieldError struct {
+	Field string
+	// Err is ErrUnknownFilterField or ErrInvalidFilterValue
+	Err error
+	// Reason says why the value is invalid
+	Reason string
+	// Allowed lists the known fields when Field is unknown
+	Allowed []string
+}
+
+// Error implements the error interface
+func (e *FilterFieldError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("%v: %s", e.Err, e.Field)
+	}
+	return fmt.Sprintf("%v: %s %s", e.Err, e.Field, e.Reason)
+}
+
+// Unwrap returns the sentinel error
+func (e *FilterFieldError) Unwrap() error {
+	return e.Err
+}
+
+// User filter fields, which GET /users also reads from the query string
+const (
+	UserFilterRole              = "role"
+	UserFilterActive            = "active"
+	UserFilterCreatedAfter      = "created_after"
+	UserFilterCreatedBefore     = "created_before"
+	UserFilterIncludeAnonymized = "include_anonymized"
+)
+
+// userFilterFields are the fields of UserFilterSchema
+var userFilterFields = []string{
+	UserFilterRole,
+	UserFilterActive,
+	UserFilterCreatedAfter,
+	UserFilterCreatedBefore,
+	UserFilterIncludeAnonymized,
+}
+
+// UserFilterSchema is the filter schema of the user listing
+var UserFilterSchema = FilterSchema{
+	Version:    1,
+	Fields:     userFilterFields,
+	Permission: models.PermissionUsersView,
+	Check: func(values map[string]string) error {
+		_, err := ParseUserFilter(values)
+		return err
+	},
+}
+
+// filterSchemas are the schemas of the resources filters can be saved for
+var filterSchemas = map[string]FilterSchema{
+	models.FilterResourceUsers: UserFilterSchema,
+}
+
+// FilterSchemaFor returns the filter schema of resource
+func FilterSchemaFor(resource string) (FilterSchema, error) {
+	schema, ok := filterSchemas[resource]
+	if !ok {
+		return FilterSchema{}, fmt.Errorf("%w: %s", ErrUnknownFilterResource, resource)
+	}
+	return schema, nil
+}
+
+// FilterResources lists the resources filters can be saved for, sorted
+func FilterResources() []string {
+	return slices.Sorted(maps.Keys(filterSchemas))
+}
+
+// Decode reads a filter document stored under version: a JSON object of
+// field names to strings, numbers or booleans, where null leaves a field
+// unset. It upgrades the values to the current version and checks them,
+// rejecting fields the schema no longer knows.
+func (s FilterSchema) Decode(doc []byte, version int) (map[string]string, error) {
+	if version > s.Version {
+		return nil, fmt.Errorf("%w: version %d, this server knows up to %d", ErrFilterSchemaTooNew, version, s.Version)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &raw); err != nil || raw == nil {
+		return nil, &FilterFieldError{Field: "filter", Err: ErrInvalidFilterValue, Reason: "must be a JSON object"}
+	}
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		var v interface{}
+		if err := json.Unmarshal(value, &v); err != nil {
+			return nil, &FilterFieldError{Field: name, Err: ErrInvalidFilterValue, Reason: err.Error()}
+		}
+		switch v := v.(type) {
+		case nil:
+		case string:
+			values[name] = v
+		case bool, float64:
+			values[name] = string(value)
+		default:
+			return nil, &FilterFieldError{Field: name, Err: ErrInvalidFilterValue, Reason: "must be a string, number or boolean"}
+		}
+	}
+
+	for v := version; v < s.Version; v++ {
+		upgrade, ok := s.Upgrades[v]
+		if !ok {
+			return nil, fmt.Errorf("no upgrade of filter schema version %d", v)
+		}
+		if err := upgrade(values); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.check(values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Merge returns saved with the schema's fields sent in query on top. A
+// field sent empty clears the saved value.
+func (s FilterSchema) Merge(saved map[string]string, query url.Values) map[string]string {
+	merged := maps.Clone(saved)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	for _, name := range s.Fields {
+		if _, ok := query[name]; !ok {
+			continue
+		}
+		if value := query.Get(name); value != "" {
+			merged[name] = value
+		} else {
+			delete(merged, name)
+		}
+	}
+	return merged
+}
+
+// check rejects unknown fields, the first in name order, then runs Check
+func (s FilterSchema) check(values map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if !slices.Contains(s.Fields, name) {
+			return &FilterFieldError{Field: name, Err: ErrUnknownFilterField, Allowed: s.Fields}
+		}
+	}
+	if s.Check == nil {
+		return nil
+	}
+	return s.Check(values)
+}
+
+// ParseUserFilter reads a user listing filter from the values of
+// UserFilterSchema's fields. Dates are RFC 3339 times or days such as
+// 2024-01-31; created_before includes the whole day.
+func ParseUserFilter(values map[string]string) (ListUsersFilter, error) {
+	var filter ListUsersFilter
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		raw := values[name]
+		invalid := func(reason string) error {
+			return &FilterFieldError{Field: name, Err: ErrInvalidFilterValue, Reason: reason}
+		}
+
+		switch name {
+		case UserFilterRole:
+			role := models.UserRole(raw)
+			if !role.Valid() {
+				return filter, invalid("must be one of admin, user or guest")
+			}
+			filter.Role = role
+		case UserFilterActive, UserFilterIncludeAnonymized:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return filter, invalid("must be true or false")
+			}
+			if name == UserFilterActive {
+				filter.Active = &b
+			} else {
+				filter.IncludeAnonymized = b
+			}
+		case UserFilterCreatedAfter, UserFilterCreatedBefore:
+			before := name == UserFilterCreatedBefore
+			t, err := ParseDateBound(raw, before)
+			if err != nil {
+				return filter, invalid("must be a date such as 2024-01-31 or an RFC 3339 time")
+			}
+			if before {
+				filter.CreatedBefore = &t
+			} else {
+				filter.CreatedAfter = &t
+			}
+		default:
+			return filter, &FilterFieldError{Field: name, Err: ErrUnknownFilterField, Allowed: userFilterFields}
+		}
+	}
+	return filter, nil
+}
+
+// ParseDateBound parses an RFC 3339 time or a day, which starts at
+// midnight UTC or, when upper, ends at the next one
+func ParseDateBound(raw string, upper bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", strings.TrimSpace(raw))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if upper {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
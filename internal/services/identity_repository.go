@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IdentityRepository persists the links between users and their accounts
+// at OpenID Connect providers
+type IdentityRepository interface {
+	// Find returns the identity of the provider's subject, or ErrUserNotFound
+	Find(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+
+	// Link stores the identity. It fails with ErrIdentityLinked when the
+	// provider's subject is already linked.
+	Link(ctx context.Context, identity *models.UserIdentity) error
+
+	// Unlink removes the identity
+	Unlink(ctx context.Context, id uuid.UUID) error
+
+	// Touch moves the identity's last_login_at to at
+	Touch(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// gormIdentityRepository implements IdentityRepository on the database each call is scoped to
+type gormIdentityRepository struct {
+	db *database.Manager
+}
+
+// NewIdentityRepository creates a repository backed by the database each call is scoped to
+func NewIdentityRepository(db *database.Manager) IdentityRepository {
+	return &gormIdentityRepository{db: db}
+}
+
+func (r *gormIdentityRepository) Find(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var identity models.UserIdentity
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *gormIdentityRepository) Link(ctx context.Context, identity *models.UserIdentity) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.UserIdentity{}).
+			Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrIdentityLinked
+		}
+		if identity.ID == uuid.Nil {
+			identity.ID = uuid.New()
+		}
+		return tx.Create(identity).Error
+	})
+}
+
+func (r *gormIdentityRepository) Unlink(ctx context.Context, id uuid.UUID) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Where("id = ?", id).Delete(&models.UserIdentity{}).Error
+}
+
+func (r *gormIdentityRepository) Touch(ctx context.Context, id uuid.UUID, at time.Time) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Model(&models.UserIdentity{}).Where("id = ?", id).Update("last_login_at", at).Error
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/jwtutil"
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+// SecretRotation reports a rotation of the JWT secret; the secrets
+// themselves are never returned
+type SecretRotation struct {
+	RotatedAt time.Time `json:"rotated_at"`
+	// PreviousValidUntil is when tokens signed with the replaced secret stop
+	// being accepted
+	PreviousValidUntil time.Time `json:"previous_valid_until"`
+}
+
+// JWTSecretService rotates the secret the service's tokens are signed with
+type JWTSecretService struct {
+	tokens *jwtutil.Manager
+	config *config.Config
+	audit  AuditRecorder
+	logger logger.Logger
+	clock  clock.Clock
+}
+
+// JWTSecretOption configures a JWTSecretService
+type JWTSecretOption func(s *JWTSecretService)
+
+// WithJWTSecretClock sets the clock the grace window of rotations starts by
+func WithJWTSecretClock(c clock.Clock) JWTSecretOption {
+	return func(s *JWTSecretService) {
+		s.clock = c
+	}
+}
+
+// NewJWTSecretService creates a service rotating the secret of tokens, kept
+// in the JWT key file of cfg
+func NewJWTSecretService(tokens *jwtutil.Manager, cfg *config.Config, audit AuditRecorder, log logger.Logger, opts ...JWTSecretOption) *JWTSecretService {
+	s := &JWTSecretService{
+		tokens: tokens,
+		config: cfg,
+		audit:  audit,
+		logger: log,
+		clock:  clock.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Rotate generates a new secret on behalf of actorID and stores it in the
+// key file. Tokens signed with the replaced secret are accepted until the
+// longest-lived of them has expired; other instances follow once they read
+// the file again. Unless force is set, it refuses with
+// jwtutil.ErrRotationInProgress while an earlier rotation's grace window
+// is open.
+func (s *JWTSecretService) Rotate(ctx context.Context, actorID string, force bool) (*SecretRotation, error) {
+	if s.config.JWT.SecretFile == "" {
+		return nil, ErrJWTKeyFileUnset
+	}
+
+	now := s.clock.Now().UTC()
+	keys, err := jwtutil.RotateFile(s.config.JWT.SecretFile, s.tokens.Keys().Current, now, MaxTokenLifetime(s.config), force)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate the JWT secret: %w", err)
+	}
+	s.tokens.SetKeys(keys)
+
+	rotation := &SecretRotation{RotatedAt: now, PreviousValidUntil: keys.PreviousUntil}
+	s.logger.Warn("JWT secret rotated", logger.Field{Key: "actor_id", Value: actorID}, logger.Field{Key: "previous_valid_until", Value: keys.PreviousUntil})
+	if s.audit != nil {
+		metadata := map[string]interface{}{"previous_valid_until": keys.PreviousUntil, "forced": force}
+		if err := s.audit.Record(ctx, actorID, models.AuditActionJWTSecretRotated, "jwt", "", metadata); err != nil {
+			s.logger.Warn("Failed to audit JWT secret rotation", logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+	return rotation, nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// KnownSignInRepository persists the devices and networks users logged in from
+type KnownSignInRepository interface {
+	// List returns the user's known sign-ins
+	List(ctx context.Context, userID uuid.UUID) ([]*models.KnownSignIn, error)
+
+	// Record stores the sign-in, or moves last_seen_at of the user's
+	// existing one with the same device and network to its LastSeenAt
+	Record(ctx context.Context, signIn *models.KnownSignIn) error
+}
+
+// gormKnownSignInRepository implements KnownSignInRepository on the database each call is scoped to
+type gormKnownSignInRepository struct {
+	db *database.Manager
+}
+
+// NewKnownSignInRepository creates a repository backed by the database each call is scoped to
+func NewKnownSignInRepository(db *database.Manager) KnownSignInRepository {
+	return &gormKnownSignInRepository{db: db}
+}
+
+func (r *gormKnownSignInRepository) List(ctx context.Context, userID uuid.UUID) ([]*models.KnownSignIn, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var signIns []*models.KnownSignIn
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("user_id = ?", userID).Find(&signIns).Error
+	})
+	return signIns, err
+}
+
+func (r *gormKnownSignInRepository) Record(ctx context.Context, signIn *models.KnownSignIn) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.KnownSignIn{}).
+			Where("user_id = ? AND device_hash = ? AND network = ?", signIn.UserID, signIn.DeviceHash, signIn.Network).
+			Update("last_seen_at", signIn.LastSeenAt)
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+		if signIn.ID == uuid.Nil {
+			signIn.ID = uuid.New()
+		}
+		return tx.Create(signIn).Error
+	})
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NotificationRepository persists in-app notifications
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	List(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]*models.Notification, error)
+	Count(ctx context.Context, userID uuid.UUID, unreadOnly bool) (int64, error)
+	CountUnread(ctx context.Context, userID uuid.UUID) (int64, error)
+
+	// MarkRead sets read_at on one of the user's notifications; already read
+	// notifications are left unchanged. Returns ErrNotificationNotFound if the
+	// user has no such notification.
+	MarkRead(ctx context.Context, userID, id uuid.UUID, at time.Time) error
+	MarkAllRead(ctx context.Context, userID uuid.UUID, at time.Time) (int64, error)
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// gormNotificationRepository implements NotificationRepository on the database each call is scoped to
+type gormNotificationRepository struct {
+	db *database.Manager
+}
+
+// NewNotificationRepository creates a repository backed by the database each call is scoped to
+func NewNotificationRepository(db *database.Manager) NotificationRepository {
+	return &gormNotificationRepository{db: db}
+}
+
+func (r *gormNotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(notification).Error
+}
+
+func (r *gormNotificationRepository) List(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]*models.Notification, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var notifications []*models.Notification
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		query := db.Where("user_id = ?", userID)
+		if unreadOnly {
+			query = query.Where("read_at IS NULL")
+		}
+		return query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&notifications).Error
+	})
+	return notifications, err
+}
+
+func (r *gormNotificationRepository) Count(ctx context.Context, userID uuid.UUID, unreadOnly bool) (int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		query := db.Model(&models.Notification{}).Where("user_id = ?", userID)
+		if unreadOnly {
+			query = query.Where("read_at IS NULL")
+		}
+		return query.Count(&count).Error
+	})
+	return count, err
+}
+
+func (r *gormNotificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	})
+	return count, err
+}
+
+func (r *gormNotificationRepository) MarkRead(ctx context.Context, userID, id uuid.UUID, at time.Time) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	var notification models.Notification
+	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotificationNotFound
+		}
+		return err
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	return db.Model(&notification).Update("read_at", at).Error
+}
+
+func (r *gormNotificationRepository) MarkAllRead(ctx context.Context, userID uuid.UUID, at time.Time) (int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	result := db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", at)
+	return result.RowsAffected, result.Error
+}
+
+func (r *gormNotificationRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	result := db.Where("created_at < ?", cutoff).Delete(&models.Notification{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/jobs"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// StreamEventNotification is the realtime event carrying a new notification
+const StreamEventNotification = "notification"
+
+// RealtimePublisher pushes events to a user's open realtime streams
+type RealtimePublisher interface {
+	Publish(userID, event string, data interface{})
+}
+
+// NotificationService delivers and tracks in-app notifications
+type NotificationService struct {
+	repo     NotificationRepository
+	realtime RealtimePublisher
+	logger   logger.Logger
+}
+
+// NewNotificationService creates a new notification service. realtime may be
+// nil, in which case notifications are only visible by listing them.
+func NewNotificationService(repo NotificationRepository, realtime RealtimePublisher, log logger.Logger) *NotificationService {
+	return &NotificationService{
+		repo:     repo,
+		realtime: realtime,
+		logger:   log,
+	}
+}
+
+// Notify stores notification for userID. Type and Title are required; the ID,
+// owner and creation time are set here.
+func (s *NotificationService) Notify(ctx context.Context, userID string, notification *models.Notification) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if notification.Type == "" || notification.Title == "" {
+		return ErrInvalidNotification
+	}
+
+	notification.ID = uuid.New()
+	notification.UserID = id
+	notification.ReadAt = nil
+	notification.CreatedAt = time.Now()
+
+	if err := s.repo.Create(ctx, notification); err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	if s.realtime != nil {
+		s.realtime.Publish(userID, StreamEventNotification, notification)
+	}
+	return nil
+}
+
+// ListNotifications returns a page of the user's notifications, newest
+// first, with the number of unread notifications
+func (s *NotificationService) ListNotifications(ctx context.Context, userID string, unreadOnly bool, limit, offset int) (*ListResult[*models.Notification], int64, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, 0, ErrUserNotFound
+	}
+
+	notifications, err := s.repo.List(ctx, id, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("database error: %w", err)
+	}
+	total, err := s.repo.Count(ctx, id, unreadOnly)
+	if err != nil {
+		return nil, 0, fmt.Errorf("database error: %w", err)
+	}
+	unread, err := s.repo.CountUnread(ctx, id)
+	if err != nil {
+		return nil, 0, fmt.Errorf("database error: %w", err)
+	}
+	return &ListResult[*models.Notification]{Items: notifications, Total: total}, unread, nil
+}
+
+// MarkRead marks one of the user's notifications as read
+func (s *NotificationService) MarkRead(ctx context.Context, userID, notificationID string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	id, err := uuid.Parse(notificationID)
+	if err != nil {
+		return ErrNotificationNotFound
+	}
+	return s.repo.MarkRead(ctx, uid, id, time.Now())
+}
+
+// MarkAllRead marks every unread notification of the user as read
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, ErrUserNotFound
+	}
+
+	marked, err := s.repo.MarkAllRead(ctx, id, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	return marked, nil
+}
+
+// PurgeBefore deletes notifications created before cutoff
+func (s *NotificationService) PurgeBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	purged, err := s.repo.DeleteBefore(ctx, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge notifications: %w", err)
+	}
+	return purged, nil
+}
+
+// NotifyJobFinished tells userID how a job they started finished. Failures are
+// logged rather than returned because the job has already completed.
+func (s *NotificationService) NotifyJobFinished(ctx context.Context, userID string, status jobs.Status) {
+	title := fmt.Sprintf("Job %s finished", status.Name)
+	switch status.LastOutcome {
+	case jobs.OutcomeFailed:
+		title = fmt.Sprintf("Job %s failed", status.Name)
+	case jobs.OutcomeSkipped:
+		title = fmt.Sprintf("Job %s was skipped because another instance is running it", status.Name)
+	}
+
+	data, _ := models.NewJSON(map[string]interface{}{
+		"job":         status.Name,
+		"outcome":     status.LastOutcome,
+		"duration_ms": status.DurationMs,
+	})
+	notification := &models.Notification{
+		Type:  models.NotificationTypeJobFinished,
+		Title: title,
+		Body:  status.LastError,
+		Data:  data,
+	}
+
+	if err := s.Notify(ctx, userID, notification); err != nil {
+		s.logger.Warn("Failed to notify job completion", logger.Field{Key: "job", Value: status.Name}, logger.Field{Key: "user_id", Value: userID}, logger.Field{Key: "error", Value: err.Error()})
+	}
+}
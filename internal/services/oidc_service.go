@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/oidc"
+)
+
+// OIDCProvider is a provider users can sign in with, as listed to clients
+type OIDCProvider struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// oidcProvider is a configured provider and the client talking to it
+type oidcProvider struct {
+	slug   string
+	config config.OIDCProviderConfig
+	client *oidc.Provider
+	// subjects maps explicitly linked subjects to their user's email
+	subjects map[string]string
+}
+
+// oidcState is what a sign-in started with, kept until its callback
+type oidcState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// OIDCService signs users in with OpenID Connect providers
+type OIDCService struct {
+	config     config.OIDCConfig
+	providers  map[string]*oidcProvider
+	users      *UserService
+	identities IdentityRepository
+	auth       *AuthService
+	states     cache.Store
+	audit      AuditRecorder
+	logger     logger.Logger
+	clock      clock.Clock
+	client     *http.Client
+}
+
+// OIDCOption configures an OIDCService
+type OIDCOption func(s *OIDCService)
+
+// WithOIDCHTTPClient sets the client the providers are called with
+func WithOIDCHTTPClient(c *http.Client) OIDCOption {
+	return func(s *OIDCService) {
+		s.client = c
+	}
+}
+
+// WithOIDCClock sets the clock ID tokens and linked identities are dated by
+func WithOIDCClock(c clock.Clock) OIDCOption {
+	return func(s *OIDCService) {
+		s.clock = c
+	}
+}
+
+// NewOIDCService creates a service signing users in with the providers of
+// cfg. It fails when a provider maps users to a role that does not exist.
+func NewOIDCService(cfg config.OIDCConfig, users *UserService, identities IdentityRepository, auth *AuthService, store cache.Store, audit AuditRecorder, log logger.Logger, opts ...OIDCOption) (*OIDCService, error) {
+	s := &OIDCService{
+		config:     cfg,
+		providers:  make(map[string]*oidcProvider, len(cfg.Providers)),
+		users:      users,
+		identities: identities,
+		auth:       auth,
+		states:     cache.WithPrefix(store, "oidc:state:"),
+		audit:      audit,
+		logger:     log,
+		clock:      clock.New(),
+		client:     http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	for slug, pc := range cfg.Providers {
+		if pc.DefaultRole != "" && !models.UserRole(pc.DefaultRole).Valid() {
+			return nil, fmt.Errorf("oidc provider %s: invalid default_role %q", slug, pc.DefaultRole)
+		}
+		for _, gr := range pc.GroupRoles {
+			if !models.UserRole(gr.Role).Valid() {
+				return nil, fmt.Errorf("oidc provider %s: invalid role %q for group %q", slug, gr.Role, gr.Group)
+			}
+		}
+		if pc.Name == "" {
+			pc.Name = slug
+		}
+		if pc.EmailClaim == "" {
+			pc.EmailClaim = "email"
+		}
+		if pc.FirstNameClaim == "" {
+			pc.FirstNameClaim = "given_name"
+		}
+		if pc.LastNameClaim == "" {
+			pc.LastNameClaim = "family_name"
+		}
+		if pc.GroupsClaim == "" {
+			pc.GroupsClaim = "groups"
+		}
+
+		p := &oidcProvider{
+			slug:   slug,
+			config: pc,
+			client: oidc.NewProvider(oidc.Config{
+				Issuer:       pc.Issuer,
+				ClientID:     pc.ClientID,
+				ClientSecret: pc.ClientSecret,
+				RedirectURL:  pc.RedirectURL,
+				Scopes:       pc.Scopes,
+			}, oidc.WithHTTPClient(s.client), oidc.WithClock(s.clock),
+				oidc.WithCacheTTL(cfg.DiscoveryCacheTTL), oidc.WithClockSkew(cfg.ClockSkew)),
+			subjects: make(map[string]string, len(pc.Subjects)),
+		}
+		for _, subject := range pc.Subjects {
+			p.subjects[subject.Subject] = subject.Email
+		}
+		s.providers[slug] = p
+	}
+	return s, nil
+}
+
+// Providers lists the configured providers by slug
+func (s *OIDCService) Providers() []OIDCProvider {
+	providers := make([]OIDCProvider, 0, len(s.providers))
+	for slug, p := range s.providers {
+		providers = append(providers, OIDCProvider{Slug: slug, Name: p.config.Name})
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Slug < providers[j].Slug })
+	return providers
+}
+
+// Begin starts a sign-in with the provider, returning the URL to send the
+// user to. The sign-in must complete within OIDC_STATE_TTL.
+func (s *OIDCService) Begin(ctx context.Context, slug string) (string, error) {
+	p, ok := s.providers[slug]
+	if !ok {
+		return "", ErrOIDCProviderNotFound
+	}
+
+	var values [3]string
+	for i := range values {
+		v, err := oidc.RandomString()
+		if err != nil {
+			return "", err
+		}
+		values[i] = v
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	url, err := p.client.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(oidcState{Provider: slug, Nonce: nonce, Verifier: verifier})
+	if err != nil {
+		return "", err
+	}
+	if err := s.states.Set(ctx, state, data, s.config.StateTTL); err != nil {
+		return "", fmt.Errorf("failed to store sign-in state: %w", err)
+	}
+	return url, nil
+}
+
+// Complete finishes the sign-in the provider redirected back with, logging
+// in the user its account is linked to. A state works once; an unknown,
+// used or expired one, or one from another provider, fails with
+// ErrOIDCStateInvalid. Provider and ID token failures wrap the errors of
+// package oidc.
+func (s *OIDCService) Complete(ctx context.Context, slug, code, state string, client ClientInfo) (*AuthResult, error) {
+	p, ok := s.providers[slug]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	started, err := s.redeemState(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if started.Provider != slug {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	rawIDToken, err := p.client.Exchange(ctx, code, started.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := p.client.Verify(ctx, rawIDToken, started.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolve(ctx, p, claims)
+	if err != nil {
+		return nil, err
+	}
+	return s.auth.LoginAs(ctx, user, client)
+}
+
+// redeemState returns and forgets what the sign-in with state started with
+func (s *OIDCService) redeemState(ctx context.Context, state string) (*oidcState, error) {
+	if state == "" {
+		return nil, ErrOIDCStateInvalid
+	}
+	value, err := s.states.Get(ctx, state)
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return nil, ErrOIDCStateInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sign-in state: %w", err)
+	}
+	if err := s.states.Delete(ctx, state); err != nil {
+		return nil, fmt.Errorf("failed to use sign-in state: %w", err)
+	}
+
+	var started oidcState
+	if err := json.Unmarshal(value, &started); err != nil {
+		return nil, ErrOIDCStateInvalid
+	}
+	return &started, nil
+}
+
+// resolve returns the user the provider's account is, in order: the user
+// already linked to it, the user its subject is configured for, the user
+// with its verified email when the provider links by email, or a new user
+// when the provider provisions. Roles are only set on provisioning; later
+// sign-ins leave them to the role change flow.
+func (s *OIDCService) resolve(ctx context.Context, p *oidcProvider, claims oidc.Claims) (*models.User, error) {
+	subject := claims.Subject()
+	email := claims.String(p.config.EmailClaim)
+
+	identity, err := s.identities.Find(ctx, p.slug, subject)
+	switch {
+	case err == nil:
+		user, err := s.users.GetUser(ctx, identity.UserID.String())
+		if err == nil {
+			if err := s.identities.Touch(ctx, identity.ID, s.clock.Now()); err != nil {
+				s.logger.Warn("Failed to record identity login", logger.Field{Key: "identity_id", Value: identity.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+			}
+			return user, nil
+		}
+		if !errors.Is(err, ErrUserNotFound) {
+			return nil, err
+		}
+		// The user was deleted; the account is resolved again as if new
+		if err := s.identities.Unlink(ctx, identity.ID); err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+	case !errors.Is(err, ErrUserNotFound):
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if linked, ok := p.subjects[subject]; ok {
+		user, err := s.users.GetUserByEmail(ctx, linked)
+		if err == nil {
+			return s.link(ctx, p, user, subject, email, "subject")
+		}
+		if !errors.Is(err, ErrUserNotFound) {
+			return nil, err
+		}
+	}
+
+	if email != "" {
+		user, err := s.users.GetUserByEmail(ctx, email)
+		switch {
+		case err == nil:
+			// An unverified address proves nothing about who owns the user
+			if p.config.LinkByEmail && claims.Bool("email_verified") {
+				return s.link(ctx, p, user, subject, email, "email")
+			}
+			return nil, ErrOIDCAccountExists
+		case !errors.Is(err, ErrUserNotFound):
+			return nil, err
+		}
+	}
+
+	if !p.config.Provision || email == "" {
+		return nil, ErrOIDCNotProvisioned
+	}
+	user, err := s.users.CreateUser(ctx, &CreateUserRequest{
+		Email:     email,
+		FirstName: claims.String(p.config.FirstNameClaim),
+		LastName:  claims.String(p.config.LastNameClaim),
+		Role:      p.role(claims.Strings(p.config.GroupsClaim)),
+	}, "")
+	if errors.Is(err, ErrEmailTaken) {
+		return nil, ErrOIDCAccountExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	linked, err := s.link(ctx, p, user, subject, email, "provisioned")
+	if err != nil {
+		// Another sign-in linked the account first; its user stays
+		if deleteErr := s.users.DeleteUser(ctx, user.ID.String()); deleteErr != nil {
+			s.logger.Warn("Failed to delete unlinked provisioned user", logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "error", Value: deleteErr.Error()})
+		}
+		return nil, err
+	}
+	return linked, nil
+}
+
+// link records that the provider's subject is user, how it was matched
+func (s *OIDCService) link(ctx context.Context, p *oidcProvider, user *models.User, subject, email, matchedBy string) (*models.User, error) {
+	now := s.clock.Now()
+	identity := &models.UserIdentity{
+		UserID:      user.ID,
+		Provider:    p.slug,
+		Subject:     subject,
+		Email:       email,
+		CreatedAt:   now,
+		LastLoginAt: &now,
+	}
+	if err := s.identities.Link(ctx, identity); err != nil {
+		if errors.Is(err, ErrIdentityLinked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if s.audit != nil {
+		metadata := map[string]string{"provider": p.slug, "matched_by": matchedBy}
+		if err := s.audit.Record(ctx, user.ID.String(), models.AuditActionUserIdentityLinked, "user", user.ID.String(), metadata); err != nil {
+			s.logger.Warn("Failed to audit identity link", logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+	s.logger.Info("OpenID Connect identity linked", logger.Field{Key: "provider", Value: p.slug}, logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "matched_by", Value: matchedBy})
+	return user, nil
+}
+
+// role returns the role of the first of the provider's group mappings the
+// groups match, or its default role
+func (p *oidcProvider) role(groups []string) models.UserRole {
+	for _, gr := range p.config.GroupRoles {
+		for _, group := range groups {
+			if group == gr.Group {
+				return models.UserRole(gr.Role)
+			}
+		}
+	}
+	return models.UserRole(p.config.DefaultRole)
+}
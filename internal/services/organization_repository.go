@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OrganizationRepository persists organizations and their memberships
+type OrganizationRepository interface {
+	Create(ctx context.Context, org *models.Organization) error
+	Get(ctx context.Context, id uuid.UUID) (*models.Organization, error)
+	List(ctx context.Context, limit, offset int) ([]*models.Organization, error)
+	Count(ctx context.Context) (int64, error)
+	Update(ctx context.Context, org *models.Organization) error
+
+	// Delete removes the organization together with all of its memberships
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	CountMembers(ctx context.Context, orgID uuid.UUID) (int64, error)
+	GetMember(ctx context.Context, orgID, userID uuid.UUID) (*models.OrganizationMember, error)
+	AddMember(ctx context.Context, member *models.OrganizationMember) error
+	RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error
+	ListMembers(ctx context.Context, orgID uuid.UUID) ([]*models.OrganizationMember, error)
+	ListUserOrganizationIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	UserExists(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+// gormOrganizationRepository implements OrganizationRepository on the database each call is scoped to
+type gormOrganizationRepository struct {
+	db *database.Manager
+}
+
+// NewOrganizationRepository creates a repository backed by the database each call is scoped to
+func NewOrganizationRepository(db *database.Manager) OrganizationRepository {
+	return &gormOrganizationRepository{db: db}
+}
+
+func (r *gormOrganizationRepository) Create(ctx context.Context, org *models.Organization) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(org).Error
+}
+
+func (r *gormOrganizationRepository) Get(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var org models.Organization
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("id = ?", id).First(&org).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *gormOrganizationRepository) List(ctx context.Context, limit, offset int) ([]*models.Organization, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var orgs []*models.Organization
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Order("name ASC").Limit(limit).Offset(offset).Find(&orgs).Error
+	})
+	return orgs, err
+}
+
+func (r *gormOrganizationRepository) Count(ctx context.Context) (int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Model(&models.Organization{}).Count(&count).Error
+	})
+	return count, err
+}
+
+func (r *gormOrganizationRepository) Update(ctx context.Context, org *models.Organization) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Model(&models.Organization{}).Where("id = ?", org.ID).Updates(map[string]interface{}{
+		"name":        org.Name,
+		"description": org.Description,
+		"updated_at":  org.UpdatedAt,
+	}).Error
+}
+
+func (r *gormOrganizationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", id).Delete(&models.OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&models.Organization{}).Error
+	})
+}
+
+func (r *gormOrganizationRepository) CountMembers(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Model(&models.OrganizationMember{}).Where("organization_id = ?", orgID).Count(&count).Error
+	})
+	return count, err
+}
+
+func (r *gormOrganizationRepository) GetMember(ctx context.Context, orgID, userID uuid.UUID) (*models.OrganizationMember, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var member models.OrganizationMember
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMembershipNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *gormOrganizationRepository) AddMember(ctx context.Context, member *models.OrganizationMember) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(member).Error
+}
+
+func (r *gormOrganizationRepository) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	result := db.Where("organization_id = ? AND user_id = ?", orgID, userID).Delete(&models.OrganizationMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMembershipNotFound
+	}
+	return nil
+}
+
+func (r *gormOrganizationRepository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]*models.OrganizationMember, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []*models.OrganizationMember
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("organization_id = ?", orgID).Order("created_at ASC").Find(&members).Error
+	})
+	return members, err
+}
+
+func (r *gormOrganizationRepository) ListUserOrganizationIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []*models.OrganizationMember
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Select("organization_id").Where("user_id = ?", userID).Find(&members).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(members))
+	for i, m := range members {
+		ids[i] = m.OrganizationID
+	}
+	return ids, nil
+}
+
+func (r *gormOrganizationRepository) UserExists(ctx context.Context, userID uuid.UUID) (bool, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Model(&models.User{}).Where("id = ?", userID).Count(&count).Error
+	})
+	return count > 0, err
+}
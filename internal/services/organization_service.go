@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// CreateOrganizationRequest represents the payload for creating an organization
+type CreateOrganizationRequest struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Description string `json:"description" binding:"max=1000"`
+}
+
+// UpdateOrganizationRequest represents a partial organization update
+type UpdateOrganizationRequest struct {
+	Name        *string `json:"name" binding:"omitempty,max=255"`
+	Description *string `json:"description" binding:"omitempty,max=1000"`
+}
+
+// AddMemberRequest represents the payload for adding a member to an organization
+type AddMemberRequest struct {
+	UserID string         `json:"user_id" binding:"required,uuid"`
+	Role   models.OrgRole `json:"role"`
+}
+
+// OrganizationService handles organization business logic
+type OrganizationService struct {
+	repo   OrganizationRepository
+	logger logger.Logger
+}
+
+// NewOrganizationService creates a new organization service
+func NewOrganizationService(repo OrganizationRepository, log logger.Logger) *OrganizationService {
+	return &OrganizationService{
+		repo:   repo,
+		logger: log,
+	}
+}
+
+// CreateOrganization creates a new organization
+func (s *OrganizationService) CreateOrganization(ctx context.Context, req *CreateOrganizationRequest) (*models.Organization, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrInvalidOrganizationName
+	}
+
+	now := time.Now()
+	org := &models.Organization{
+		ID:          uuid.New(),
+		Name:        name,
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.repo.Create(ctx, org); err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	return org, nil
+}
+
+// GetOrganization retrieves an organization by ID
+func (s *OrganizationService) GetOrganization(ctx context.Context, id string) (*models.Organization, error) {
+	orgID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrOrganizationNotFound
+	}
+	return s.repo.Get(ctx, orgID)
+}
+
+// ListOrganizations retrieves a page of organizations and the total count
+func (s *OrganizationService) ListOrganizations(ctx context.Context, limit, offset int) (*ListResult[*models.Organization], error) {
+	orgs, err := s.repo.List(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	total, err := s.repo.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &ListResult[*models.Organization]{Items: orgs, Total: total}, nil
+}
+
+// UpdateOrganization applies a partial update to an organization
+func (s *OrganizationService) UpdateOrganization(ctx context.Context, id string, req *UpdateOrganizationRequest) (*models.Organization, error) {
+	org, err := s.GetOrganization(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, ErrInvalidOrganizationName
+		}
+		org.Name = name
+	}
+	if req.Description != nil {
+		org.Description = *req.Description
+	}
+	org.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ctx, org); err != nil {
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+	return org, nil
+}
+
+// DeleteOrganization deletes an organization. Organizations that still have
+// members are only deleted when force is set, in which case memberships cascade.
+func (s *OrganizationService) DeleteOrganization(ctx context.Context, id string, force bool) error {
+	org, err := s.GetOrganization(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !force {
+		count, err := s.repo.CountMembers(ctx, org.ID)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		if count > 0 {
+			return ErrOrganizationHasMembers
+		}
+	}
+
+	if err := s.repo.Delete(ctx, org.ID); err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+
+	s.logger.Info("Organization deleted", logger.Field{Key: "organization_id", Value: org.ID.String()}, logger.Field{Key: "force", Value: force})
+	return nil
+}
+
+// AddMember adds a user to an organization; the role defaults to member
+func (s *OrganizationService) AddMember(ctx context.Context, orgID string, req *AddMemberRequest) (*models.OrganizationMember, error) {
+	org, err := s.GetOrganization(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.OrgRoleMember
+	}
+	if !role.Valid() {
+		return nil, ErrInvalidOrgRole
+	}
+
+	exists, err := s.repo.UserExists(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	if _, err := s.repo.GetMember(ctx, org.ID, userID); err == nil {
+		return nil, ErrMembershipExists
+	} else if !errors.Is(err, ErrMembershipNotFound) {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	member := &models.OrganizationMember{
+		OrganizationID: org.ID,
+		UserID:         userID,
+		Role:           role,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.repo.AddMember(ctx, member); err != nil {
+		return nil, fmt.Errorf("failed to add member: %w", err)
+	}
+	return member, nil
+}
+
+// RemoveMember removes a user from an organization
+func (s *OrganizationService) RemoveMember(ctx context.Context, orgID, userID string) error {
+	org, err := s.GetOrganization(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	memberID, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrMembershipNotFound
+	}
+
+	return s.repo.RemoveMember(ctx, org.ID, memberID)
+}
+
+// ListMembers lists the members of an organization
+func (s *OrganizationService) ListMembers(ctx context.Context, orgID string) ([]*models.OrganizationMember, error) {
+	org, err := s.GetOrganization(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.ListMembers(ctx, org.ID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return members, nil
+}
+
+// ListUserOrganizationIDs returns the IDs of every organization the user belongs to
+func (s *OrganizationService) ListUserOrganizationIDs(ctx context.Context, userID string) ([]string, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	orgIDs, err := s.repo.ListUserOrganizationIDs(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	ids := make([]string, len(orgIDs))
+	for i, orgID := range orgIDs {
+		ids[i] = orgID.String()
+	}
+	return ids, nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PartnerRepository persists partners and the users they provisioned
+type PartnerRepository interface {
+	Create(ctx context.Context, partner *models.Partner) error
+	Get(ctx context.Context, id uuid.UUID) (*models.Partner, error)
+	List(ctx context.Context) ([]*models.Partner, error)
+	Update(ctx context.Context, partner *models.Partner) error
+
+	// NameTaken reports whether a partner other than except is named name
+	NameTaken(ctx context.Context, name string, except uuid.UUID) (bool, error)
+
+	// Delete removes the partner and unlinks the users it provisioned
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	// FindUser returns the ID of the user the partner provisioned under
+	// externalID, or ErrUserNotFound
+	FindUser(ctx context.Context, partnerID uuid.UUID, externalID string) (uuid.UUID, error)
+
+	// LinkUser records that the partner provisioned userID under externalID.
+	// It fails when the partner already linked another user to externalID.
+	LinkUser(ctx context.Context, userID, partnerID uuid.UUID, externalID string) error
+}
+
+// gormPartnerRepository implements PartnerRepository on the database each call is scoped to
+type gormPartnerRepository struct {
+	db *database.Manager
+}
+
+// NewPartnerRepository creates a repository backed by the database each call is scoped to
+func NewPartnerRepository(db *database.Manager) PartnerRepository {
+	return &gormPartnerRepository{db: db}
+}
+
+func (r *gormPartnerRepository) Create(ctx context.Context, partner *models.Partner) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(partner).Error
+}
+
+func (r *gormPartnerRepository) Get(ctx context.Context, id uuid.UUID) (*models.Partner, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var partner models.Partner
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("id = ?", id).First(&partner).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPartnerNotFound
+		}
+		return nil, err
+	}
+	return &partner, nil
+}
+
+func (r *gormPartnerRepository) List(ctx context.Context) ([]*models.Partner, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var partners []*models.Partner
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Order("name ASC").Find(&partners).Error
+	})
+	return partners, err
+}
+
+func (r *gormPartnerRepository) Update(ctx context.Context, partner *models.Partner) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Model(&models.Partner{}).Where("id = ?", partner.ID).Updates(map[string]interface{}{
+		"name":       partner.Name,
+		"secret":     partner.Secret,
+		"enabled":    partner.Enabled,
+		"updated_at": partner.UpdatedAt,
+	}).Error
+}
+
+func (r *gormPartnerRepository) NameTaken(ctx context.Context, name string, except uuid.UUID) (bool, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Model(&models.Partner{}).Where("name = ? AND id <> ?", name, except).Count(&count).Error
+	})
+	return count > 0, err
+}
+
+func (r *gormPartnerRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("partner_id = ?", id).Updates(map[string]interface{}{
+			"partner_id":  nil,
+			"external_id": nil,
+		}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&models.Partner{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPartnerNotFound
+		}
+		return nil
+	})
+}
+
+func (r *gormPartnerRepository) FindUser(ctx context.Context, partnerID uuid.UUID, externalID string) (uuid.UUID, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var user models.User
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Select("id").Where("partner_id = ? AND external_id = ?", partnerID, externalID).First(&user).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, ErrUserNotFound
+		}
+		return uuid.Nil, err
+	}
+	return user.ID, nil
+}
+
+func (r *gormPartnerRepository) LinkUser(ctx context.Context, userID, partnerID uuid.UUID, externalID string) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"partner_id":  partnerID,
+		"external_id": externalID,
+	}).Error
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// SignPartnerRequest returns the X-Signature value of a partner request:
+// "sha256=" followed by the hex-encoded HMAC-SHA256 of the X-Timestamp value
+// and the body, in that order, keyed with the partner's secret
+func SignPartnerRequest(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyPartnerSignature reports, in constant time, whether signature is
+// valid for the timestamp, body and secret
+func VerifyPartnerSignature(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignPartnerRequest(secret, timestamp, body)), []byte(signature))
+}
+
+// CreatePartnerRequest represents the payload for registering a partner.
+// A secret is generated when none is supplied.
+type CreatePartnerRequest struct {
+	Name   string `json:"name" binding:"required,notblank,max=100"`
+	Secret string `json:"secret" binding:"omitempty,min=16,max=255"`
+}
+
+// UpdatePartnerRequest represents a partial partner update
+type UpdatePartnerRequest struct {
+	Name    *string `json:"name" binding:"omitempty,notblank,max=100"`
+	Secret  *string `json:"secret" binding:"omitempty,min=16,max=255"`
+	Enabled *bool   `json:"enabled"`
+}
+
+// ProvisionUserRequest is a user as a partner knows it. A nil field leaves
+// the user's value unchanged. The email is only used to create the user; it
+// changes through the confirmed email change flow like any other.
+type ProvisionUserRequest struct {
+	ExternalID string  `json:"external_id" binding:"required,notblank,max=255"`
+	Email      string  `json:"email" binding:"required,email"`
+	Username   *string `json:"username" binding:"omitempty,max=100"`
+	FirstName  *string `json:"first_name" binding:"omitempty,max=100"`
+	LastName   *string `json:"last_name" binding:"omitempty,max=100"`
+	Active     *bool   `json:"active"`
+}
+
+// PartnerService manages partners and provisions the users they send
+type PartnerService struct {
+	repo   PartnerRepository
+	users  *UserService
+	audit  AuditRecorder
+	logger logger.Logger
+}
+
+// NewPartnerService creates a new partner service
+func NewPartnerService(repo PartnerRepository, users *UserService, audit AuditRecorder, log logger.Logger) *PartnerService {
+	return &PartnerService{
+		repo:   repo,
+		users:  users,
+		audit:  audit,
+		logger: log,
+	}
+}
+
+// CreatePartner registers a partner on behalf of actorID. The returned
+// partner carries its secret, which is never shown again.
+func (s *PartnerService) CreatePartner(ctx context.Context, actorID string, req *CreatePartnerRequest) (*models.Partner, error) {
+	if taken, err := s.repo.NameTaken(ctx, req.Name, uuid.Nil); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	} else if taken {
+		return nil, ErrPartnerExists
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := generateSigningSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	now := time.Now()
+	partner := &models.Partner{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		Secret:    secret,
+		Enabled:   true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.Create(ctx, partner); err != nil {
+		return nil, fmt.Errorf("failed to create partner: %w", err)
+	}
+
+	s.record(ctx, actorID, models.AuditActionPartnerCreated, partner, nil)
+	return partner, nil
+}
+
+// GetPartner retrieves a partner by ID
+func (s *PartnerService) GetPartner(ctx context.Context, id string) (*models.Partner, error) {
+	partnerID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrPartnerNotFound
+	}
+	return s.repo.Get(ctx, partnerID)
+}
+
+// LookupPartner returns the partner signing a request, for
+// middleware.VerifySignature
+func (s *PartnerService) LookupPartner(ctx context.Context, id string) (*models.Partner, error) {
+	return s.GetPartner(ctx, id)
+}
+
+// ListPartners returns every partner, ordered by name
+func (s *PartnerService) ListPartners(ctx context.Context) ([]*models.Partner, error) {
+	partners, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return partners, nil
+}
+
+// UpdatePartner applies a partial update on behalf of actorID. Changed
+// fields are audited by name; the secret never is by value.
+func (s *PartnerService) UpdatePartner(ctx context.Context, actorID, id string, req *UpdatePartnerRequest) (*models.Partner, error) {
+	partner, err := s.GetPartner(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	if req.Name != nil && *req.Name != partner.Name {
+		if taken, err := s.repo.NameTaken(ctx, *req.Name, partner.ID); err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		} else if taken {
+			return nil, ErrPartnerExists
+		}
+		partner.Name = *req.Name
+		fields = append(fields, "name")
+	}
+	if req.Secret != nil {
+		partner.Secret = *req.Secret
+		fields = append(fields, "secret")
+	}
+	if req.Enabled != nil && *req.Enabled != partner.Enabled {
+		partner.Enabled = *req.Enabled
+		fields = append(fields, "enabled")
+	}
+	if len(fields) == 0 {
+		return partner, nil
+	}
+	partner.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ctx, partner); err != nil {
+		return nil, fmt.Errorf("failed to update partner: %w", err)
+	}
+	s.record(ctx, actorID, models.AuditActionPartnerUpdated, partner, map[string][]string{"fields": fields})
+	return partner, nil
+}
+
+// DeletePartner removes a partner on behalf of actorID. The users it
+// provisioned are kept but no longer linked to it.
+func (s *PartnerService) DeletePartner(ctx context.Context, actorID, id string) error {
+	partner, err := s.GetPartner(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, partner.ID); err != nil {
+		return err
+	}
+	s.record(ctx, actorID, models.AuditActionPartnerDeleted, partner, nil)
+	return nil
+}
+
+// ProvisionUser creates or updates the user partner knows under
+// req.ExternalID, reporting whether it was created. Sending the same
+// external ID again updates the same user, so retries are safe. A new user
+// whose email another user already has fails with ErrEmailTaken.
+func (s *PartnerService) ProvisionUser(ctx context.Context, partner *models.Partner, req *ProvisionUserRequest) (*models.User, bool, error) {
+	changes := &UpdateUserRequest{
+		Username:  req.Username,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	}
+
+	userID, err := s.repo.FindUser(ctx, partner.ID, req.ExternalID)
+	switch {
+	case err == nil:
+		user, err := s.users.UpdateUser(ctx, userID.String(), changes, "")
+		if err == nil && req.Active != nil && user.Active != *req.Active {
+			user, err = s.users.SetActive(ctx, userID.String(), *req.Active, "")
+		}
+		return user, false, err
+	case !errors.Is(err, ErrUserNotFound):
+		return nil, false, fmt.Errorf("database error: %w", err)
+	}
+
+	user, err := s.users.CreateUser(ctx, &CreateUserRequest{
+		Email:     req.Email,
+		Username:  deref(req.Username),
+		FirstName: deref(req.FirstName),
+		LastName:  deref(req.LastName),
+	}, "")
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.repo.LinkUser(ctx, user.ID, partner.ID, req.ExternalID); err != nil {
+		// Another request provisioned the external ID first; its user stays
+		if deleteErr := s.users.DeleteUser(ctx, user.ID.String()); deleteErr != nil {
+			s.logger.Warn("Failed to delete unlinked provisioned user", logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "error", Value: deleteErr.Error()})
+		}
+		return nil, false, fmt.Errorf("failed to link provisioned user: %w", err)
+	}
+	user.PartnerID = &partner.ID
+	user.ExternalID = &req.ExternalID
+
+	if req.Active != nil && !*req.Active {
+		if user, err = s.users.SetActive(ctx, user.ID.String(), false, ""); err != nil {
+			return nil, false, err
+		}
+	}
+
+	s.logger.Info("User provisioned", logger.Field{Key: "partner", Value: partner.Name}, logger.Field{Key: "user_id", Value: user.ID.String()})
+	return user, true, nil
+}
+
+// record audits a change to a partner; failures are only logged
+func (s *PartnerService) record(ctx context.Context, actorID, action string, partner *models.Partner, metadata interface{}) {
+	if s.audit == nil {
+		return
+	}
+	if err := s.audit.Record(ctx, actorID, action, "partner", partner.ID.String(), metadata); err != nil {
+		s.logger.Warn("Failed to audit partner change", logger.Field{Key: "partner_id", Value: partner.ID.String()}, logger.Field{Key: "action", Value: action}, logger.Field{Key: "error", Value: err.Error()})
+	}
+}
+
+// deref returns the string p points to, or "" for nil
+func deref(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
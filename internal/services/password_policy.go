@@ -0,0 +1,148 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/requestctx"
+	"BackofficeGoService/internal/pkg/workerpool"
+)
+
+// PasswordPolicy screens the passwords users choose, at registration, when
+// they change theirs and when an admin sets one. Check fails with
+// ErrPasswordBreached for a password that must not be used, or with
+// ErrPasswordCheckUnavailable when it cannot tell.
+type PasswordPolicy interface {
+	Check(ctx context.Context, password string) error
+}
+
+// checkPassword runs policy on password; a nil policy accepts everything
+func checkPassword(ctx context.Context, policy PasswordPolicy, password string) error {
+	if policy == nil {
+		return nil
+	}
+	return policy.Check(ctx, password)
+}
+
+// PwnedPasswords is a PasswordPolicy rejecting passwords found in breaches
+// by the Have I Been Pwned range API. Only the first five hex characters
+// of the password's SHA-1 leave the service (k-anonymity), and responses
+// are padded so their size does not give the rest away.
+type PwnedPasswords struct {
+	client     *http.Client
+	url        string
+	threshold  int
+	failClosed bool
+	logger     logger.Logger
+	pool       *workerpool.Pool
+}
+
+// PwnedPasswordsOption configures a PwnedPasswords
+type PwnedPasswordsOption func(p *PwnedPasswords)
+
+// WithBreachCheckPool runs the range API lookups on pool rather than the
+// request's goroutine. A lookup the saturated pool refuses counts as the
+// API being unreachable.
+func WithBreachCheckPool(pool *workerpool.Pool) PwnedPasswordsOption {
+	return func(p *PwnedPasswords) {
+		p.pool = pool
+	}
+}
+
+// NewPwnedPasswords creates a policy querying the range API at url, such as
+// https://api.pwnedpasswords.com, with client, which should time out
+// quickly as a request waits for it. Passwords seen in more than threshold
+// breaches are rejected. When the API cannot be reached passwords are
+// accepted with a warning, or refused if failClosed is set.
+func NewPwnedPasswords(client *http.Client, url string, threshold int, failClosed bool, log logger.Logger, opts ...PwnedPasswordsOption) *PwnedPasswords {
+	p := &PwnedPasswords{
+		client:     client,
+		url:        strings.TrimSuffix(url, "/"),
+		threshold:  threshold,
+		failClosed: failClosed,
+		logger:     log,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Check looks password up in the range of its hash prefix
+func (p *PwnedPasswords) Check(ctx context.Context, password string) error {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	count, err := p.offload(ctx, prefix, suffix)
+	if err != nil {
+		if p.failClosed {
+			return fmt.Errorf("%w: %v", ErrPasswordCheckUnavailable, err)
+		}
+		requestctx.LoggerOr(ctx, p.logger).Warn("Password breach check unavailable, accepting the password",
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+		return nil
+	}
+	if count > p.threshold {
+		return ErrPasswordBreached
+	}
+	return nil
+}
+
+// offload runs lookup on the pool, if there is one
+func (p *PwnedPasswords) offload(ctx context.Context, prefix, suffix string) (int, error) {
+	if p.pool == nil {
+		return p.lookup(ctx, prefix, suffix)
+	}
+	future, err := workerpool.Submit(ctx, p.pool, func(ctx context.Context) (int, error) {
+		return p.lookup(ctx, prefix, suffix)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("range API lookup not started: %w", err)
+	}
+	return future.Wait(ctx)
+}
+
+// lookup returns how often the hash with prefix and suffix was seen in breaches
+func (p *PwnedPasswords) lookup(ctx context.Context, prefix, suffix string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+"/range/"+prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("range API unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("range API answered status %d", resp.StatusCode)
+	}
+
+	// Each line is the rest of a hash and its count; padding lines count 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		rest, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(rest, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, fmt.Errorf("invalid range API count %q", count)
+		}
+		return n, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read range API response: %w", err)
+	}
+	return 0, nil
+}
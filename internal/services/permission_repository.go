@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// PermissionRepository persists permissions and their role grants
+type PermissionRepository interface {
+	ListPermissions(ctx context.Context) ([]*models.Permission, error)
+	ListRolePermissions(ctx context.Context, role models.UserRole) ([]string, error)
+
+	// SetRolePermissions replaces every permission granted to role
+	SetRolePermissions(ctx context.Context, role models.UserRole, permissions []string) error
+}
+
+// gormPermissionRepository implements PermissionRepository on the database each call is scoped to
+type gormPermissionRepository struct {
+	db *database.Manager
+}
+
+// NewPermissionRepository creates a repository backed by the database each call is scoped to
+func NewPermissionRepository(db *database.Manager) PermissionRepository {
+	return &gormPermissionRepository{db: db}
+}
+
+func (r *gormPermissionRepository) ListPermissions(ctx context.Context) ([]*models.Permission, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var permissions []*models.Permission
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Order("name ASC").Find(&permissions).Error
+	})
+	return permissions, err
+}
+
+func (r *gormPermissionRepository) ListRolePermissions(ctx context.Context, role models.UserRole) ([]string, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Model(&models.RolePermission{}).Where("role = ?", role).Order("permission ASC").Pluck("permission", &names).Error
+	})
+	return names, err
+}
+
+func (r *gormPermissionRepository) SetRolePermissions(ctx context.Context, role models.UserRole, permissions []string) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if len(permissions) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		grants := make([]models.RolePermission, len(permissions))
+		for i, name := range permissions {
+			grants[i] = models.RolePermission{Role: role, Permission: name, CreatedAt: now}
+		}
+		return tx.Create(&grants).Error
+	})
+}
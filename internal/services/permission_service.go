@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+// rolePermissionsCacheTTL bounds how long a permission change can take to apply
+// on another instance; changes made through this service apply immediately.
+const rolePermissionsCacheTTL = time.Minute
+
+// SetRolePermissionsRequest represents the payload for replacing a role's permissions
+type SetRolePermissionsRequest struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// PermissionService resolves role permissions server-side
+type PermissionService struct {
+	repo   PermissionRepository
+	cache  cache.Store
+	logger logger.Logger
+}
+
+// NewPermissionService creates a new permission service
+func NewPermissionService(repo PermissionRepository, store cache.Store, log logger.Logger) *PermissionService {
+	return &PermissionService{
+		repo:   repo,
+		cache:  store,
+		logger: log,
+	}
+}
+
+// rolePermissionsCacheKey returns the cache key for a role's permissions
+func rolePermissionsCacheKey(role models.UserRole) string {
+	return "permissions:role:" + string(role)
+}
+
+// ListPermissions returns every known permission
+func (s *PermissionService) ListPermissions(ctx context.Context) ([]*models.Permission, error) {
+	permissions, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return permissions, nil
+}
+
+// RolePermissions returns the permissions granted to role, using a short-lived cache
+func (s *PermissionService) RolePermissions(ctx context.Context, role models.UserRole) ([]string, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
+	key := rolePermissionsCacheKey(role)
+	if data, err := s.cache.Get(ctx, key); err == nil {
+		var names []string
+		if err := json.Unmarshal(data, &names); err == nil {
+			return names, nil
+		}
+	}
+
+	names, err := s.repo.ListRolePermissions(ctx, role)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if names == nil {
+		names = []string{}
+	}
+
+	if data, err := json.Marshal(names); err == nil {
+		if err := s.cache.Set(ctx, key, data, rolePermissionsCacheTTL); err != nil {
+			s.logger.Warn("Failed to cache role permissions", logger.Field{Key: "role", Value: string(role)}, logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+	return names, nil
+}
+
+// HasPermission reports whether role has been granted permission. Unknown
+// roles and permission names are never granted.
+func (s *PermissionService) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	if !models.UserRole(role).Valid() {
+		return false, nil
+	}
+
+	names, err := s.RolePermissions(ctx, models.UserRole(role))
+	if err != nil {
+		return false, err
+	}
+	for _, name := range names {
+		if name == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SetRolePermissions replaces the permissions granted to role and invalidates its cache
+func (s *PermissionService) SetRolePermissions(ctx context.Context, role models.UserRole, permissions []string) ([]string, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
+	known, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	knownNames := make(map[string]bool, len(known))
+	for _, p := range known {
+		knownNames[p.Name] = true
+	}
+
+	seen := make(map[string]bool, len(permissions))
+	names := make([]string, 0, len(permissions))
+	for _, name := range permissions {
+		if !knownNames[name] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPermission, name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if err := s.repo.SetRolePermissions(ctx, role, names); err != nil {
+		return nil, fmt.Errorf("failed to update role permissions: %w", err)
+	}
+
+	if err := s.cache.Delete(ctx, rolePermissionsCacheKey(role)); err != nil {
+		s.logger.Warn("Failed to invalidate role permissions cache", logger.Field{Key: "role", Value: string(role)}, logger.Field{Key: "error", Value: err.Error()})
+	}
+
+	s.logger.Info("Role permissions updated", logger.Field{Key: "role", Value: string(role)}, logger.Field{Key: "permissions", Value: names})
+	return names, nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PolicyVersionCount is how many users accepted one version of a policy
+type PolicyVersionCount struct {
+	Policy  string `json:"-"`
+	Version string `json:"version"`
+	Users   int64  `json:"users"`
+}
+
+// PolicyAcceptanceRepository persists the policy versions users accepted
+type PolicyAcceptanceRepository interface {
+	Create(ctx context.Context, acceptance *models.PolicyAcceptance) error
+
+	// Find returns the user's acceptance of the policy version, or
+	// errPolicyAcceptanceNotFound
+	Find(ctx context.Context, userID uuid.UUID, policy, version string) (*models.PolicyAcceptance, error)
+
+	// ListForUser returns every acceptance of the user, oldest first
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]*models.PolicyAcceptance, error)
+
+	// CountByVersion counts the active users who accepted each policy
+	// version, ordered by policy and version
+	CountByVersion(ctx context.Context) ([]PolicyVersionCount, error)
+
+	// CountActiveUsers counts the users policies apply to: active and not anonymized
+	CountActiveUsers(ctx context.Context) (int64, error)
+}
+
+// errPolicyAcceptanceNotFound is returned by Find when the user never
+// accepted the policy version
+var errPolicyAcceptanceNotFound = errors.New("policy acceptance not found")
+
+// gormPolicyAcceptanceRepository implements PolicyAcceptanceRepository on the database each call is scoped to
+type gormPolicyAcceptanceRepository struct {
+	db *database.Manager
+}
+
+// NewPolicyAcceptanceRepository creates a repository backed by the database each call is scoped to
+func NewPolicyAcceptanceRepository(db *database.Manager) PolicyAcceptanceRepository {
+	return &gormPolicyAcceptanceRepository{db: db}
+}
+
+func (r *gormPolicyAcceptanceRepository) Create(ctx context.Context, acceptance *models.PolicyAcceptance) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(acceptance).Error
+}
+
+func (r *gormPolicyAcceptanceRepository) Find(ctx context.Context, userID uuid.UUID, policy, version string) (*models.PolicyAcceptance, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var acceptance models.PolicyAcceptance
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("user_id = ? AND policy = ? AND version = ?", userID, policy, version).First(&acceptance).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errPolicyAcceptanceNotFound
+		}
+		return nil, err
+	}
+	return &acceptance, nil
+}
+
+func (r *gormPolicyAcceptanceRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]*models.PolicyAcceptance, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var acceptances []*models.PolicyAcceptance
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("user_id = ?", userID).Order("accepted_at").Find(&acceptances).Error
+	})
+	return acceptances, err
+}
+
+func (r *gormPolicyAcceptanceRepository) CountByVersion(ctx context.Context) ([]PolicyVersionCount, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []PolicyVersionCount
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Model(&models.PolicyAcceptance{}).
+			Select("policy_acceptances.policy, policy_acceptances.version, COUNT(DISTINCT policy_acceptances.user_id) AS users").
+			Joins("JOIN users ON users.id = policy_acceptances.user_id").
+			Where("users.active = ? AND users.anonymized_at IS NULL", true).
+			Group("policy_acceptances.policy, policy_acceptances.version").
+			Order("policy_acceptances.policy, policy_acceptances.version").
+			Scan(&counts).Error
+	})
+	return counts, err
+}
+
+func (r *gormPolicyAcceptanceRepository) CountActiveUsers(ctx context.Context) (int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Model(&models.User{}).Where("active = ? AND anonymized_at IS NULL", true).Count(&count).Error
+	})
+	return count, err
+}
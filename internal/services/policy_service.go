@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// PendingPolicy is a policy whose current version a user has not accepted
+type PendingPolicy struct {
+	Policy  string `json:"policy"`
+	Version string `json:"version"`
+}
+
+// PolicyStatus reports how far the users got with accepting one policy
+type PolicyStatus struct {
+	Policy string `json:"policy"`
+	// CurrentVersion is empty for policies no longer in the policy_versions setting
+	CurrentVersion string `json:"current_version"`
+	// Accepted and Pending count the users who did and did not accept the current version
+	Accepted int64 `json:"accepted"`
+	Pending  int64 `json:"pending"`
+	// Versions counts the users who accepted each version, old ones included
+	Versions []PolicyVersionCount `json:"versions"`
+}
+
+// PolicyReport is the acceptance status of every policy over the users
+// policies apply to
+type PolicyReport struct {
+	Users    int64          `json:"users"`
+	Policies []PolicyStatus `json:"policies"`
+}
+
+// PolicyService tracks which version of each policy users accepted. The
+// current versions come from the policy_versions setting, so bumping a
+// version there asks every user to accept it again.
+type PolicyService struct {
+	repo     PolicyAcceptanceRepository
+	settings *SettingsService
+	audit    *AuditService
+	logger   logger.Logger
+	clock    clock.Clock
+	// responses, if set, drops the cached acceptance report on acceptances
+	responses *ResponseCache
+}
+
+// PolicyOption configures a PolicyService
+type PolicyOption func(s *PolicyService)
+
+// WithPolicyClock sets the clock that timestamps acceptances
+func WithPolicyClock(c clock.Clock) PolicyOption {
+	return func(s *PolicyService) {
+		s.clock = c
+	}
+}
+
+// WithPolicyResponseCache purges the cached acceptance report whenever a
+// user accepts a policy
+func WithPolicyResponseCache(r *ResponseCache) PolicyOption {
+	return func(s *PolicyService) {
+		s.responses = r
+	}
+}
+
+// NewPolicyService creates a new policy service
+func NewPolicyService(repo PolicyAcceptanceRepository, settings *SettingsService, audit *AuditService, log logger.Logger, opts ...PolicyOption) *PolicyService {
+	s := &PolicyService{
+		repo:     repo,
+		settings: settings,
+		audit:    audit,
+		logger:   log,
+		clock:    clock.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CurrentVersions returns the current version of every policy
+func (s *PolicyService) CurrentVersions(ctx context.Context) (map[string]string, error) {
+	var versions map[string]string
+	if err := s.settings.GetJSON(ctx, models.SettingPolicyVersions, &versions); err != nil {
+		return nil, fmt.Errorf("failed to read policy versions: %w", err)
+	}
+	return versions, nil
+}
+
+// Pending returns the policies whose current version userID has not
+// accepted, ordered by policy
+func (s *PolicyService) Pending(ctx context.Context, userID string) ([]PendingPolicy, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	current, err := s.CurrentVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := []PendingPolicy{}
+	if len(current) == 0 {
+		return pending, nil
+	}
+
+	acceptances, err := s.repo.ListForUser(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	accepted := make(map[PendingPolicy]bool, len(acceptances))
+	for _, acceptance := range acceptances {
+		accepted[PendingPolicy{Policy: acceptance.Policy, Version: acceptance.Version}] = true
+	}
+
+	for policy, version := range current {
+		if p := (PendingPolicy{Policy: policy, Version: version}); !accepted[p] {
+			pending = append(pending, p)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Policy < pending[j].Policy })
+	return pending, nil
+}
+
+// Accept records userID accepting version of policy from client. Only the
+// current version can be accepted; accepting it again returns the first
+// acceptance.
+func (s *PolicyService) Accept(ctx context.Context, userID, policy, version string, client ClientInfo) (*models.PolicyAcceptance, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	current, err := s.CurrentVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	currentVersion, ok := current[policy]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPolicy, policy)
+	}
+	if version != currentVersion {
+		return nil, fmt.Errorf("%w: %s is at %s", ErrPolicyVersionOutdated, policy, currentVersion)
+	}
+
+	if existing, err := s.repo.Find(ctx, id, policy, version); err == nil {
+		return existing, nil
+	} else if !errors.Is(err, errPolicyAcceptanceNotFound) {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	acceptance := &models.PolicyAcceptance{
+		ID:         uuid.New(),
+		UserID:     id,
+		Policy:     policy,
+		Version:    version,
+		AcceptedAt: s.clock.Now(),
+		IPAddress:  client.IPAddress,
+	}
+	if err := s.repo.Create(ctx, acceptance); err != nil {
+		// A concurrent request may have recorded the same acceptance
+		if existing, findErr := s.repo.Find(ctx, id, policy, version); findErr == nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("failed to record policy acceptance: %w", err)
+	}
+	s.responses.Purge(ctx, ResponseGroupPolicies)
+
+	metadata := map[string]string{"policy": policy, "version": version}
+	if err := s.audit.Record(ctx, userID, models.AuditActionUserPolicyAccepted, "user", userID, metadata); err != nil {
+		s.logger.Warn("Failed to audit policy acceptance", logger.Field{Key: "user_id", Value: userID}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	return acceptance, nil
+}
+
+// Report returns how many users accepted each version of every policy,
+// current policies first in name order, then the ones no longer current
+func (s *PolicyService) Report(ctx context.Context) (*PolicyReport, error) {
+	current, err := s.CurrentVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.repo.CountActiveUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	counts, err := s.repo.CountByVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	statuses := make(map[string]*PolicyStatus, len(current))
+	for policy, version := range current {
+		statuses[policy] = &PolicyStatus{Policy: policy, CurrentVersion: version, Versions: []PolicyVersionCount{}}
+	}
+	for _, count := range counts {
+		status, ok := statuses[count.Policy]
+		if !ok {
+			status = &PolicyStatus{Policy: count.Policy, Versions: []PolicyVersionCount{}}
+			statuses[count.Policy] = status
+		}
+		status.Versions = append(status.Versions, count)
+		if count.Version == status.CurrentVersion {
+			status.Accepted = count.Users
+		}
+	}
+
+	report := &PolicyReport{Users: users, Policies: make([]PolicyStatus, 0, len(statuses))}
+	for _, status := range statuses {
+		if status.CurrentVersion != "" {
+			status.Pending = max(users-status.Accepted, 0)
+		}
+		report.Policies = append(report.Policies, *status)
+	}
+	sort.Slice(report.Policies, func(i, j int) bool {
+		a, b := report.Policies[i], report.Policies[j]
+		if (a.CurrentVersion == "") != (b.CurrentVersion == "") {
+			return a.CurrentVersion != ""
+		}
+		return a.Policy < b.Policy
+	})
+	return report, nil
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// ProfileFields are the fields of a profile that can be required, which
+// users fill in through PUT /me
+var ProfileFields = []string{"username", "first_name", "last_name"}
+
+// profileFieldAnyRole keys the required fields of roles without their own
+const profileFieldAnyRole = "*"
+
+// ProfileStatus is how complete a user's profile is
+type ProfileStatus struct {
+	Complete bool `json:"profile_complete"`
+	// Missing lists the required fields left blank, in ProfileFields order
+	Missing []string `json:"missing_profile_fields"`
+}
+
+// ProfileRoleReport is the profile completeness of the users of one role
+type ProfileRoleReport struct {
+	Role           models.UserRole `json:"role"`
+	RequiredFields []string        `json:"required_fields"`
+	Users          int64           `json:"users"`
+	Complete       int64           `json:"complete"`
+	// CompletionRate is Complete over Users; 1 for roles without users
+	CompletionRate float64 `json:"completion_rate"`
+	// Missing counts the users leaving each required field blank
+	Missing map[string]int64 `json:"missing"`
+}
+
+// ProfileReport is the profile completeness of the users requirements
+// apply to: active and not anonymized
+type ProfileReport struct {
+	Users          int64               `json:"users"`
+	Complete       int64               `json:"complete"`
+	CompletionRate float64             `json:"completion_rate"`
+	Roles          []ProfileRoleReport `json:"roles"`
+}
+
+// ProfileService evaluates profiles against the fields each role must fill
+// in, set by AUTH_PROFILE_REQUIRED_FIELDS
+type ProfileService struct {
+	db       *database.Manager
+	required map[string][]string
+}
+
+// NewProfileService creates a profile service requiring, by role, the
+// fields in required. The "*" entry applies to roles without one of their
+// own. It fails on unknown roles and fields.
+func NewProfileService(db *database.Manager, required map[string][]string) (*ProfileService, error) {
+	s := &ProfileService{db: db, required: make(map[string][]string, len(required))}
+	for role, fields := range required {
+		if role != profileFieldAnyRole && !models.UserRole(role).Valid() {
+			return nil, fmt.Errorf("profile required fields: invalid role %q", role)
+		}
+		for _, field := range fields {
+			if !slices.Contains(ProfileFields, field) {
+				return nil, fmt.Errorf("profile required fields: role %s: unknown field %q, want one of %s", role, field, strings.Join(ProfileFields, ", "))
+			}
+		}
+		// Kept in ProfileFields order, so missing fields are listed alike
+		s.required[role] = slices.DeleteFunc(slices.Clone(ProfileFields), func(field string) bool {
+			return !slices.Contains(fields, field)
+		})
+	}
+	return s, nil
+}
+
+// RequiredFields returns the fields users of role must fill in
+func (s *ProfileService) RequiredFields(role models.UserRole) []string {
+	if fields, ok := s.required[string(role)]; ok {
+		return fields
+	}
+	return s.required[profileFieldAnyRole]
+}
+
+// Evaluate reports which of the fields required of user's role it left
+// blank. Fields holding only spaces count as blank.
+func (s *ProfileService) Evaluate(user *models.User) ProfileStatus {
+	status := ProfileStatus{Missing: []string{}}
+	for _, field := range s.RequiredFields(user.Role) {
+		if strings.TrimSpace(profileField(user, field)) == "" {
+			status.Missing = append(status.Missing, field)
+		}
+	}
+	status.Complete = len(status.Missing) == 0
+	return status
+}
+
+// profileField returns the value of one of ProfileFields
+func profileField(user *models.User, field string) string {
+	switch field {
+	case "username":
+		return user.Username
+	case "first_name":
+		return user.FirstName
+	case "last_name":
+		return user.LastName
+	}
+	return ""
+}
+
+// Report returns how many users of each role completed their profile, in
+// role order
+func (s *ProfileService) Report(ctx context.Context) (*ProfileReport, error) {
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	users := func() *gorm.DB {
+		return db.WithContext(ctx).Model(&models.User{}).Where("active = ? AND anonymized_at IS NULL", true)
+	}
+
+	var counts []struct {
+		Role  models.UserRole
+		Users int64
+	}
+	if err := withRetry(ctx, s.db.RetryPolicy(), func() error {
+		return users().Select("role, COUNT(*) AS users").Group("role").Order("role").Scan(&counts).Error
+	}); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	report := &ProfileReport{Roles: make([]ProfileRoleReport, 0, len(counts))}
+	for _, count := range counts {
+		role := ProfileRoleReport{
+			Role:           count.Role,
+			RequiredFields: s.RequiredFields(count.Role),
+			Users:          count.Users,
+			Complete:       count.Users,
+			Missing:        make(map[string]int64),
+		}
+		if role.RequiredFields == nil {
+			role.RequiredFields = []string{}
+		}
+		// Field names come from ProfileFields, never from the request
+		var blanks []string
+		for _, field := range role.RequiredFields {
+			blank := fmt.Sprintf("TRIM(COALESCE(%s, '')) = ''", field)
+			var missing int64
+			if err := withRetry(ctx, s.db.RetryPolicy(), func() error {
+				return users().Where("role = ?", count.Role).Where(blank).Count(&missing).Error
+			}); err != nil {
+				return nil, fmt.Errorf("database error: %w", err)
+			}
+			role.Missing[field] = missing
+			blanks = append(blanks, blank)
+		}
+		if len(blanks) > 0 {
+			var incomplete int64
+			if err := withRetry(ctx, s.db.RetryPolicy(), func() error {
+				return users().Where("role = ?", count.Role).Where("(" + strings.Join(blanks, " OR ") + ")").Count(&incomplete).Error
+			}); err != nil {
+				return nil, fmt.Errorf("database error: %w", err)
+			}
+			role.Complete = count.Users - incomplete
+		}
+		role.CompletionRate = completionRate(role.Complete, role.Users)
+
+		report.Users += role.Users
+		report.Complete += role.Complete
+		report.Roles = append(report.Roles, role)
+	}
+	report.CompletionRate = completionRate(report.Complete, report.Users)
+	return report, nil
+}
+
+// completionRate is complete over users, or 1 when there are no users
+func completionRate(complete, users int64) float64 {
+	if users == 0 {
+		return 1
+	}
+	return float64(complete) / float64(users)
+}
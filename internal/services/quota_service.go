@@ -0,0 +1,379 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// UsagePeriodLayout formats the calendar months quotas are counted in
+const UsagePeriodLayout = "2006-01"
+
+// quotaLimitTTL is how long a user's resolved limit is cached. Other
+// instances see a changed quota after at most this long.
+const quotaLimitTTL = time.Minute
+
+// QuotaStatus is a user's API usage in one calendar month
+type QuotaStatus struct {
+	Period string `json:"period"`
+	// Limit and Remaining are null when the user has no limit
+	Limit     *int64    `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining *int64    `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// UsageReport is the API usage of every user in one calendar month
+type UsageReport struct {
+	Period string                `json:"period"`
+	Total  int64                 `json:"total"`
+	Users  []*models.UsageRecord `json:"users"`
+}
+
+// usageKey identifies the requests of one user in one month on one database
+type usageKey struct {
+	tenant string
+	driver string
+	userID uuid.UUID
+	period string
+}
+
+// QuotaService counts the API requests of each user per calendar month (UTC)
+// and refuses them once the user's quota is spent. Live counters are kept
+// in the cache so every instance shares them; the requests each instance
+// counted are written to usage_records by Flush for reports.
+type QuotaService struct {
+	repo   UsageRepository
+	cache  cache.Store
+	audit  *AuditService
+	logger logger.Logger
+	clock  clock.Clock
+	// monthly is the limit of users without their own; zero for none
+	monthly int64
+
+	// pending holds the requests counted since the last flush
+	mu      sync.Mutex
+	pending map[usageKey]int64
+	// seedMu keeps concurrent requests from seeding one counter twice
+	seedMu sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// QuotaOption configures a QuotaService
+type QuotaOption func(s *QuotaService)
+
+// WithQuotaClock sets the clock that picks the current month
+func WithQuotaClock(c clock.Clock) QuotaOption {
+	return func(s *QuotaService) {
+		s.clock = c
+	}
+}
+
+// NewQuotaService creates a quota service allowing users without their own
+// quota monthly requests per month; zero leaves them unlimited
+func NewQuotaService(repo UsageRepository, store cache.Store, audit *AuditService, monthly int64, log logger.Logger, opts ...QuotaOption) *QuotaService {
+	s := &QuotaService{
+		repo:    repo,
+		cache:   store,
+		audit:   audit,
+		logger:  log,
+		clock:   clock.New(),
+		monthly: monthly,
+		pending: make(map[usageKey]int64),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// UsagePeriod returns the month t falls in, such as "2024-06"
+func UsagePeriod(t time.Time) string {
+	return t.UTC().Format(UsagePeriodLayout)
+}
+
+// periodReset returns when the month after period starts
+func periodReset(period string) time.Time {
+	start, _ := time.Parse(UsagePeriodLayout, period)
+	return start.AddDate(0, 1, 0)
+}
+
+// counterKey is the cache key of the user's counter for period
+func counterKey(userID uuid.UUID, period string) string {
+	return "quota:" + userID.String() + ":" + period
+}
+
+// limitKey is the cache key of the user's resolved limit
+func limitKey(userID uuid.UUID) string {
+	return "quota:limit:" + userID.String()
+}
+
+// Consume counts one request of userID in the current month. Once the
+// quota is spent it returns ErrQuotaExceeded with the status, and the
+// request is not counted. When the counter cannot be reached the request
+// is let through uncounted and the status is nil, so a cache outage does not
+// take the API down.
+func (s *QuotaService) Consume(ctx context.Context, userID string) (*QuotaStatus, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	limit, err := s.Limit(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	period := UsagePeriod(s.clock.Now())
+	key := counterKey(id, period)
+	ttl := s.counterTTL(period)
+	if err := s.seed(ctx, key, s.keyFor(ctx, id, period), ttl); err != nil {
+		s.warnUncounted(userID, err)
+		return nil, nil
+	}
+	used, err := s.cache.Incr(ctx, key, 1, ttl)
+	if err != nil {
+		s.warnUncounted(userID, err)
+		return nil, nil
+	}
+	if limit > 0 && used > limit {
+		if _, err := s.cache.Incr(ctx, key, -1, ttl); err != nil {
+			s.warnUncounted(userID, err)
+		}
+		return s.status(period, limit, used-1), ErrQuotaExceeded
+	}
+
+	s.mu.Lock()
+	s.pending[s.keyFor(ctx, id, period)]++
+	s.mu.Unlock()
+	return s.status(period, limit, used), nil
+}
+
+// Usage returns the API usage of userID in the current month
+func (s *QuotaService) Usage(ctx context.Context, userID string) (*QuotaStatus, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	limit, err := s.Limit(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	period := UsagePeriod(s.clock.Now())
+	key := counterKey(id, period)
+	used, err := s.cache.Get(ctx, key)
+	if err == nil {
+		count, parseErr := strconv.ParseInt(string(used), 10, 64)
+		if parseErr == nil {
+			return s.status(period, limit, count), nil
+		}
+	}
+
+	// Without a counter, this instance's unflushed requests are added to
+	// the recorded ones
+	recorded, err := s.repo.Requests(ctx, id, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	s.mu.Lock()
+	recorded += s.pending[s.keyFor(ctx, id, period)]
+	s.mu.Unlock()
+	return s.status(period, limit, recorded), nil
+}
+
+// Report returns the recorded usage of every user in period, such as
+// "2024-06", or the current month when it is empty, busiest first. This
+// instance's requests are flushed first; other instances' show up after
+// their next flush.
+func (s *QuotaService) Report(ctx context.Context, period string) (*UsageReport, error) {
+	if period == "" {
+		period = UsagePeriod(s.clock.Now())
+	}
+	if _, err := time.Parse(UsagePeriodLayout, period); err != nil {
+		return nil, ErrInvalidPeriod
+	}
+	if err := s.Flush(ctx); err != nil {
+		s.logger.Warn("Failed to flush API usage", logger.Field{Key: "error", Value: err.Error()})
+	}
+
+	records, err := s.repo.ListPeriod(ctx, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	report := &UsageReport{Period: period, Users: records}
+	for _, record := range records {
+		report.Total += record.Requests
+	}
+	return report, nil
+}
+
+// Limit returns the monthly quota of userID: the user's own when set,
+// otherwise the default. Zero means no limit.
+func (s *QuotaService) Limit(ctx context.Context, userID string) (int64, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, ErrUserNotFound
+	}
+
+	key := limitKey(id)
+	if cached, err := s.cache.Get(ctx, key); err == nil {
+		if limit, err := strconv.ParseInt(string(cached), 10, 64); err == nil {
+			return limit, nil
+		}
+	}
+
+	quota, err := s.repo.UserQuota(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	limit := s.monthly
+	if quota != nil {
+		limit = *quota
+	}
+	if err := s.cache.Set(ctx, key, []byte(strconv.FormatInt(limit, 10)), quotaLimitTTL); err != nil {
+		s.logger.Warn("Failed to cache API quota", logger.Field{Key: "user_id", Value: userID}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	return limit, nil
+}
+
+// SetQuota gives userID their own monthly quota, zero for no limit, or
+// restores the default when quota is nil
+func (s *QuotaService) SetQuota(ctx context.Context, actorID, userID string, quota *int64) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.repo.SetUserQuota(ctx, id, quota); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to set quota: %w", err)
+	}
+	if err := s.cache.Delete(ctx, limitKey(id)); err != nil {
+		s.logger.Warn("Failed to drop cached API quota", logger.Field{Key: "user_id", Value: userID}, logger.Field{Key: "error", Value: err.Error()})
+	}
+
+	metadata := map[string]interface{}{"api_quota": quota}
+	if err := s.audit.Record(ctx, actorID, models.AuditActionUserQuotaChanged, "user", userID, metadata); err != nil {
+		s.logger.Warn("Failed to audit quota change", logger.Field{Key: "user_id", Value: userID}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	return nil
+}
+
+// Flush writes the requests counted since the last flush to usage_records.
+// Requests that could not be written are kept for the next flush.
+func (s *QuotaService) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[usageKey]int64)
+	s.mu.Unlock()
+
+	now := s.clock.Now().UTC()
+	var errs []error
+	for key, count := range pending {
+		scoped := database.WithTenant(ctx, key.tenant, key.driver)
+		if err := s.repo.AddRequests(scoped, key.userID, key.period, count, now); err != nil {
+			errs = append(errs, err)
+			s.mu.Lock()
+			s.pending[key] += count
+			s.mu.Unlock()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Start flushes the counted requests every interval until Stop
+func (s *QuotaService) Start(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := s.clock.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				if err := s.Flush(ctx); err != nil {
+					s.logger.Warn("Failed to flush API usage", logger.Field{Key: "error", Value: err.Error()})
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the flush loop and writes what it has not flushed yet
+func (s *QuotaService) Stop(ctx context.Context) error {
+	if s.cancel != nil {
+		s.cancel()
+		s.wg.Wait()
+	}
+	return s.Flush(ctx)
+}
+
+// seed starts a missing counter from the requests already recorded, so a
+// cache restart does not hand users a fresh quota
+func (s *QuotaService) seed(ctx context.Context, key string, usage usageKey, ttl time.Duration) error {
+	if _, err := s.cache.Get(ctx, key); !errors.Is(err, cache.ErrCacheMiss) {
+		return err
+	}
+
+	s.seedMu.Lock()
+	defer s.seedMu.Unlock()
+	if _, err := s.cache.Get(ctx, key); !errors.Is(err, cache.ErrCacheMiss) {
+		return err
+	}
+	recorded, err := s.repo.Requests(ctx, usage.userID, usage.period)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	recorded += s.pending[usage]
+	s.mu.Unlock()
+	_, err = s.cache.Incr(ctx, key, recorded, ttl)
+	return err
+}
+
+// keyFor returns the usage key of the user's requests in period on the
+// database ctx is scoped to
+func (s *QuotaService) keyFor(ctx context.Context, userID uuid.UUID, period string) usageKey {
+	return usageKey{tenant: database.TenantID(ctx), driver: database.DriverName(ctx), userID: userID, period: period}
+}
+
+// counterTTL keeps a counter until a day after its month ends
+func (s *QuotaService) counterTTL(period string) time.Duration {
+	return periodReset(period).Sub(s.clock.Now()) + 24*time.Hour
+}
+
+func (s *QuotaService) status(period string, limit, used int64) *QuotaStatus {
+	status := &QuotaStatus{Period: period, Used: used, ResetAt: periodReset(period)}
+	if limit > 0 {
+		remaining := max(limit-used, 0)
+		status.Limit, status.Remaining = &limit, &remaining
+	}
+	return status
+}
+
+func (s *QuotaService) warnUncounted(userID string, err error) {
+	s.logger.Warn("API request not counted against the quota", logger.Field{Key: "user_id", Value: userID}, logger.Field{Key: "error", Value: err.Error()})
+}
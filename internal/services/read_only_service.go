@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/readonly"
+)
+
+// ReadOnlyService lets operators freeze writes during incident response
+type ReadOnlyService struct {
+	sw     *readonly.Switch
+	audit  AuditRecorder
+	logger logger.Logger
+}
+
+// NewReadOnlyService creates a read-only service turning sw on and off
+func NewReadOnlyService(sw *readonly.Switch, audit AuditRecorder, log logger.Logger) *ReadOnlyService {
+	return &ReadOnlyService{
+		sw:     sw,
+		audit:  audit,
+		logger: log,
+	}
+}
+
+// State returns whether writes are frozen
+func (s *ReadOnlyService) State(ctx context.Context) readonly.State {
+	return s.sw.State(ctx)
+}
+
+// Set turns read-only mode on or off on behalf of actorID and records the
+// change in the audit log
+func (s *ReadOnlyService) Set(ctx context.Context, actorID string, enabled bool, reason string) (readonly.State, error) {
+	state, err := s.sw.Set(ctx, enabled, reason, actorID)
+	if err != nil {
+		return readonly.State{}, fmt.Errorf("failed to store read-only mode: %w", err)
+	}
+
+	action, message := models.AuditActionReadOnlyDisabled, "Read-only mode disabled"
+	if enabled {
+		action, message = models.AuditActionReadOnlyEnabled, "Read-only mode enabled"
+	}
+	s.logger.Warn(message, logger.Field{Key: "actor_id", Value: actorID}, logger.Field{Key: "reason", Value: reason})
+	if s.audit != nil {
+		if err := s.audit.Record(ctx, actorID, action, "read_only", "", map[string]string{"reason": reason}); err != nil {
+			s.logger.Warn("Failed to audit read-only mode change", logger.Field{Key: "action", Value: action}, logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+	return state, nil
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+
+	"gorm.io/gorm"
+)
+
+// Defaults for the RequestLogWriterConfig fields left zero
+const (
+	DefaultRequestLogBufferSize    = 1000
+	DefaultRequestLogBatchSize     = 100
+	DefaultRequestLogFlushInterval = time.Second
+)
+
+// RequestLogWriterConfig configures a RequestLogWriter. Zero fields take
+// the defaults above.
+type RequestLogWriterConfig struct {
+	BufferSize    int           // Entries waiting to be written before new ones are dropped
+	BatchSize     int           // Entries written per insert
+	FlushInterval time.Duration // How long entries wait for their batch to fill
+}
+
+// RequestLogWriter writes request log entries to the primary database on a
+// worker, in batches, so logging a request never waits for the database.
+// Entries that arrive while the buffer is full are dropped and counted in
+// request_log_entries_total: the request log is a support aid, and
+// handlers must not slow down when the database does.
+type RequestLogWriter struct {
+	db      *database.Manager
+	config  RequestLogWriterConfig
+	metrics *metrics.Business
+	logger  logger.Logger
+
+	queue   chan *models.RequestLog
+	stopped atomic.Bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// RequestLogWriterOption configures a RequestLogWriter
+type RequestLogWriterOption func(w *RequestLogWriter)
+
+// WithRequestLogMetrics counts the entries written, dropped and failed
+func WithRequestLogMetrics(m *metrics.Business) RequestLogWriterOption {
+	return func(w *RequestLogWriter) {
+		w.metrics = m
+	}
+}
+
+// NewRequestLogWriter creates a writer; call Start to begin writing
+func NewRequestLogWriter(db *database.Manager, cfg RequestLogWriterConfig, log logger.Logger, opts ...RequestLogWriterOption) *RequestLogWriter {
+	if cfg.BufferSize < 1 {
+		cfg.BufferSize = DefaultRequestLogBufferSize
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = DefaultRequestLogBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultRequestLogFlushInterval
+	}
+
+	w := &RequestLogWriter{
+		db:     db,
+		config: cfg,
+		logger: log,
+		queue:  make(chan *models.RequestLog, cfg.BufferSize),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Enqueue queues entry for writing without blocking. It reports false, and
+// counts entry as dropped, when the buffer is full or the writer stopped.
+func (w *RequestLogWriter) Enqueue(entry *models.RequestLog) bool {
+	if !w.stopped.Load() {
+		select {
+		case w.queue <- entry:
+			return true
+		default:
+		}
+	}
+	w.metrics.RequestLogged(metrics.RequestLogDropped, 1)
+	return false
+}
+
+// Start launches the worker, which writes a batch once it is full or has
+// waited FlushInterval
+func (w *RequestLogWriter) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.config.FlushInterval)
+		defer ticker.Stop()
+
+		batch := make([]*models.RequestLog, 0, w.config.BatchSize)
+		write := func() {
+			if len(batch) > 0 {
+				_ = w.write(context.Background(), batch)
+				batch = batch[:0]
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				write()
+				return
+			case entry := <-w.queue:
+				if batch = append(batch, entry); len(batch) == w.config.BatchSize {
+					write()
+				}
+			case <-ticker.C:
+				write()
+			}
+		}
+	}()
+}
+
+// Stop stops the worker and writes the entries still queued. Entries
+// enqueued afterwards are dropped.
+func (w *RequestLogWriter) Stop(ctx context.Context) error {
+	w.stopped.Store(true)
+	if w.cancel != nil {
+		w.cancel()
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return w.Flush(ctx)
+}
+
+// Flush writes the queued entries now
+func (w *RequestLogWriter) Flush(ctx context.Context) error {
+	var errs []error
+	for {
+		batch := make([]*models.RequestLog, 0, w.config.BatchSize)
+	fill:
+		for len(batch) < w.config.BatchSize {
+			select {
+			case entry := <-w.queue:
+				batch = append(batch, entry)
+			default:
+				break fill
+			}
+		}
+		if len(batch) == 0 {
+			return errors.Join(errs...)
+		}
+		if err := w.write(ctx, batch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+}
+
+// write inserts batch into the primary database; failed batches are
+// counted and logged, not retried
+func (w *RequestLogWriter) write(ctx context.Context, batch []*models.RequestLog) error {
+	err := func() error {
+		db, err := primaryDB(ctx, w.db)
+		if err != nil {
+			return err
+		}
+		return db.Create(&batch).Error
+	}()
+	if err != nil {
+		w.metrics.RequestLogged(metrics.RequestLogFailed, len(batch))
+		w.logger.Warn("Failed to write request log entries", logger.Field{Key: "entries", Value: len(batch)}, logger.Field{Key: "error", Value: err.Error()})
+		return fmt.Errorf("failed to write request log: %w", err)
+	}
+	w.metrics.RequestLogged(metrics.RequestLogWritten, len(batch))
+	return nil
+}
+
+// RequestLogFilter selects request log entries; zero fields match every entry
+type RequestLogFilter struct {
+	ActorID  string
+	Route    string // Route template, such as /api/v1/users/:id
+	Method   string
+	Status   int
+	TenantID string
+	From     time.Time // Entries at or after From
+	To       time.Time // Entries before To
+}
+
+// RequestLogService searches and purges the request log, which is kept in
+// the primary database for every tenant
+type RequestLogService struct {
+	db     *database.Manager
+	logger logger.Logger
+}
+
+// NewRequestLogService creates a new request log service
+func NewRequestLogService(db *database.Manager, log logger.Logger) *RequestLogService {
+	return &RequestLogService{
+		db:     db,
+		logger: log,
+	}
+}
+
+// List returns a page of the entries filter selects, newest first
+func (s *RequestLogService) List(ctx context.Context, filter RequestLogFilter, limit, offset int) (*ListResult[*models.RequestLog], error) {
+	db, err := primaryDB(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	selected := func() *gorm.DB {
+		query := db.Model(&models.RequestLog{})
+		for column, value := range map[string]string{
+			"actor_id":  filter.ActorID,
+			"route":     filter.Route,
+			"method":    filter.Method,
+			"tenant_id": filter.TenantID,
+		} {
+			if value != "" {
+				query = query.Where(column+" = ?", value)
+			}
+		}
+		if filter.Status != 0 {
+			query = query.Where("status = ?", filter.Status)
+		}
+		if !filter.From.IsZero() {
+			query = query.Where("created_at >= ?", filter.From)
+		}
+		if !filter.To.IsZero() {
+			query = query.Where("created_at < ?", filter.To)
+		}
+		return query
+	}
+
+	result := &ListResult[*models.RequestLog]{Items: []*models.RequestLog{}}
+	err = withRetry(ctx, s.db.RetryPolicy(), func() error {
+		if err := selected().Count(&result.Total).Error; err != nil {
+			return err
+		}
+		return selected().Order("created_at DESC").Order("id").Limit(limit).Offset(offset).Find(&result.Items).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// PurgeBefore deletes the entries created before cutoff
+func (s *RequestLogService) PurgeBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	db, err := primaryDB(ctx, s.db)
+	if err != nil {
+		return 0, err
+	}
+	result := db.Where("created_at < ?", cutoff).Delete(&models.RequestLog{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge request log: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// primaryDB returns a GORM handle on the primary database, whatever tenant
+// ctx is scoped to
+func primaryDB(ctx context.Context, db *database.Manager) (*gorm.DB, error) {
+	driver, err := db.GetDriver(database.PrimaryDriver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	gormDB, err := database.OpenGorm(driver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	return gormDB.WithContext(ctx), nil
+}
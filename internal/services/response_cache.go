@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// Response cache groups. A cached response belongs to the groups of the data
+// it shows; purging a group drops every response in it.
+const (
+	ResponseGroupUsers    = "users"
+	ResponseGroupPolicies = "policies"
+	ResponseGroupSettings = "settings"
+	ResponseGroupProducts = "products"
+)
+
+// CachedResponse is a response stored by the response cache
+type CachedResponse struct {
+	Status int               `json:"status"`
+	Header map[string]string `json:"header"`
+	Body   []byte            `json:"body"`
+}
+
+// ResponseCache stores the responses of read endpoints. Entries are keyed
+// with the current generation of each of their groups: purging a group
+// starts a new generation, so its entries are no longer found and expire
+// with their TTL. The store cannot delete keys by prefix.
+//
+// A nil *ResponseCache caches nothing, so services can purge unconditionally.
+type ResponseCache struct {
+	cache     cache.Store
+	ttl       time.Duration
+	overrides map[string]time.Duration
+	logger    logger.Logger
+}
+
+// NewResponseCache creates a response cache keeping responses for ttl, or
+// for the override with the longest prefix of their route template
+func NewResponseCache(store cache.Store, ttl time.Duration, overrides map[string]time.Duration, log logger.Logger) *ResponseCache {
+	return &ResponseCache{
+		cache:     store,
+		ttl:       ttl,
+		overrides: overrides,
+		logger:    log,
+	}
+}
+
+// TTL returns how long responses of a route template are kept; zero means
+// they are not cached
+func (r *ResponseCache) TTL(route string) time.Duration {
+	if r == nil {
+		return 0
+	}
+	ttl, matched := r.ttl, -1
+	for prefix, override := range r.overrides {
+		if strings.HasPrefix(route, prefix) && len(prefix) > matched {
+			ttl, matched = override, len(prefix)
+		}
+	}
+	return ttl
+}
+
+// Key returns the cache key of a response made of parts that belongs to
+// groups. It changes whenever one of the groups is purged.
+func (r *ResponseCache) Key(ctx context.Context, groups []string, parts ...string) (string, error) {
+	hash := sha256.New()
+	for _, group := range groups {
+		generation, err := r.generation(ctx, group)
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(group + "=" + generation + "\n"))
+	}
+	for _, part := range parts {
+		hash.Write([]byte(part + "\n"))
+	}
+	return "response:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Get returns the response stored under key
+func (r *ResponseCache) Get(ctx context.Context, key string) (*CachedResponse, bool) {
+	data, err := r.cache.Get(ctx, key)
+	if err != nil {
+		return nil, false
+	}
+	var response CachedResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, false
+	}
+	return &response, true
+}
+
+// Set stores response under key for ttl
+func (r *ResponseCache) Set(ctx context.Context, key string, response *CachedResponse, ttl time.Duration) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	if err := r.cache.Set(ctx, key, data, ttl); err != nil {
+		r.logger.Warn("Failed to cache response", logger.Field{Key: "error", Value: err.Error()})
+	}
+}
+
+// Purge drops the cached responses of groups. Failures are logged: the
+// entries then live until their TTL runs out.
+func (r *ResponseCache) Purge(ctx context.Context, groups ...string) {
+	if r == nil {
+		return
+	}
+	for _, group := range groups {
+		if err := r.cache.Set(ctx, responseGenerationKey(group), []byte(uuid.NewString()), 0); err != nil {
+			r.logger.Warn("Failed to purge cached responses", logger.Field{Key: "group", Value: group}, logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+}
+
+// generation returns the current generation of group, starting one if the
+// store has none, so an evicted generation can never bring back old entries
+func (r *ResponseCache) generation(ctx context.Context, group string) (string, error) {
+	data, err := r.cache.Get(ctx, responseGenerationKey(group))
+	if err == nil {
+		return string(data), nil
+	}
+	if !errors.Is(err, cache.ErrCacheMiss) {
+		return "", err
+	}
+	generation := uuid.NewString()
+	if err := r.cache.Set(ctx, responseGenerationKey(group), []byte(generation), 0); err != nil {
+		return "", err
+	}
+	return generation, nil
+}
+
+// responseGenerationKey returns the cache key of a group's generation
+func responseGenerationKey(group string) string {
+	return "response:generation:" + group
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"BackofficeGoService/internal/pkg/database"
+)
+
+// withRetry runs fn and runs it again under policy while it fails with a
+// transient database error, waiting a jittered, doubling backoff between
+// attempts. It returns the last error, or the context's once it is done.
+//
+// Only wrap operations that are safe to repeat: reads, and writes that leave
+// the same state however often they run, which say so where they call it.
+// A create or an increment must never be retried, and neither must a single
+// statement inside a transaction: wrap the whole transaction instead.
+func withRetry(ctx context.Context, policy database.RetryPolicy, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= policy.MaxAttempts {
+			return err
+		}
+		reason, ok := database.TransientReason(err)
+		if !ok {
+			return err
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(reason, attempt, err)
+		}
+
+		timer := time.NewTimer(retryDelay(policy, attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay returns the wait after the given failed attempt: half the
+// doubled backoff plus a random part of the other half, so callers that
+// failed together do not retry together
+func retryDelay(policy database.RetryPolicy, attempt int) time.Duration {
+	delay := policy.BaseDelay
+	for i := 1; i < attempt && (policy.MaxDelay <= 0 || delay < policy.MaxDelay); i++ {
+		delay *= 2
+	}
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SavedFilterRepository persists the list filters users saved
+type SavedFilterRepository interface {
+	Create(ctx context.Context, filter *models.SavedFilter) error
+	Get(ctx context.Context, id uuid.UUID) (*models.SavedFilter, error)
+
+	// ListOwned returns the owner's filters, by name
+	ListOwned(ctx context.Context, ownerID uuid.UUID) ([]*models.SavedFilter, error)
+
+	// ListShared returns the filters of resources others shared, by name
+	ListShared(ctx context.Context, resources []string, excludeOwner uuid.UUID) ([]*models.SavedFilter, error)
+
+	Update(ctx context.Context, filter *models.SavedFilter) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// gormSavedFilterRepository implements SavedFilterRepository on the database each call is scoped to
+type gormSavedFilterRepository struct {
+	db *database.Manager
+}
+
+// NewSavedFilterRepository creates a repository backed by the database each call is scoped to
+func NewSavedFilterRepository(db *database.Manager) SavedFilterRepository {
+	return &gormSavedFilterRepository{db: db}
+}
+
+func (r *gormSavedFilterRepository) Create(ctx context.Context, filter *models.SavedFilter) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(filter).Error
+}
+
+func (r *gormSavedFilterRepository) Get(ctx context.Context, id uuid.UUID) (*models.SavedFilter, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var filter models.SavedFilter
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("id = ?", id).First(&filter).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSavedFilterNotFound
+		}
+		return nil, err
+	}
+	return &filter, nil
+}
+
+func (r *gormSavedFilterRepository) ListOwned(ctx context.Context, ownerID uuid.UUID) ([]*models.SavedFilter, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []*models.SavedFilter{}
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("owner_id = ?", ownerID).Order("name ASC, created_at ASC").Find(&filters).Error
+	})
+	return filters, err
+}
+
+func (r *gormSavedFilterRepository) ListShared(ctx context.Context, resources []string, excludeOwner uuid.UUID) ([]*models.SavedFilter, error) {
+	filters := []*models.SavedFilter{}
+	if len(resources) == 0 {
+		return filters, nil
+	}
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("shared = ? AND resource_type IN ? AND owner_id <> ?", true, resources, excludeOwner).
+			Order("name ASC, created_at ASC").Find(&filters).Error
+	})
+	return filters, err
+}
+
+func (r *gormSavedFilterRepository) Update(ctx context.Context, filter *models.SavedFilter) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Model(&models.SavedFilter{}).Where("id = ?", filter.ID).Updates(map[string]interface{}{
+		"name":           filter.Name,
+		"filter":         filter.Filter,
+		"schema_version": filter.SchemaVersion,
+		"shared":         filter.Shared,
+		"updated_at":     filter.UpdatedAt,
+	}).Error
+}
+
+func (r *gormSavedFilterRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	result := db.Where("id = ?", id).Delete(&models.SavedFilter{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSavedFilterNotFound
+	}
+	return nil
+}
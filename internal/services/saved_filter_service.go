@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/requestctx"
+
+	"github.com/google/uuid"
+)
+
+// CreateSavedFilterRequest represents the payload for saving a filter
+type CreateSavedFilterRequest struct {
+	Name         string          `json:"name" binding:"required,max=100"`
+	ResourceType string          `json:"resource_type" binding:"required"`
+	Filter       json.RawMessage `json:"filter" binding:"required"`
+	Shared       bool            `json:"shared"`
+}
+
+// UpdateSavedFilterRequest represents a partial saved filter update
+type UpdateSavedFilterRequest struct {
+	Name   *string         `json:"name" binding:"omitempty,min=1,max=100"`
+	Filter json.RawMessage `json:"filter"`
+	Shared *bool           `json:"shared"`
+}
+
+// SavedFilterService manages the list filters users save. Owners see and
+// change their filters; shared filters can be read by those allowed to
+// list the resource and changed by their owner or an admin.
+type SavedFilterService struct {
+	repo        SavedFilterRepository
+	permissions RolePermissionLookup
+	logger      logger.Logger
+}
+
+// NewSavedFilterService creates a saved filter service resolving the
+// permissions of readers with permissions
+func NewSavedFilterService(repo SavedFilterRepository, permissions RolePermissionLookup, log logger.Logger) *SavedFilterService {
+	return &SavedFilterService{
+		repo:        repo,
+		permissions: permissions,
+		logger:      log,
+	}
+}
+
+// ListFilters returns the principal's filters
+func (s *SavedFilterService) ListFilters(ctx context.Context, principal Principal) ([]*models.SavedFilter, error) {
+	ownerID, err := uuid.Parse(principal.ID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	filters, err := s.repo.ListOwned(ctx, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return filters, nil
+}
+
+// ListSharedFilters returns the filters others shared for the resources
+// the principal may list
+func (s *SavedFilterService) ListSharedFilters(ctx context.Context, principal Principal) ([]*models.SavedFilter, error) {
+	ownerID, err := uuid.Parse(principal.ID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if err := s.resolvePermissions(ctx, &principal); err != nil {
+		return nil, err
+	}
+
+	var resources []string
+	for _, resource := range FilterResources() {
+		if schema, _ := FilterSchemaFor(resource); principal.Can(schema.Permission) {
+			resources = append(resources, resource)
+		}
+	}
+	filters, err := s.repo.ListShared(ctx, resources, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return filters, nil
+}
+
+// CreateFilter saves a filter owned by the principal under the current
+// version of the resource's schema
+func (s *SavedFilterService) CreateFilter(ctx context.Context, principal Principal, req *CreateSavedFilterRequest) (*models.SavedFilter, error) {
+	ownerID, err := uuid.Parse(principal.ID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	schema, err := FilterSchemaFor(req.ResourceType)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := schema.Decode(req.Filter, schema.Version); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := &models.SavedFilter{
+		ID:            uuid.New(),
+		OwnerID:       ownerID,
+		Name:          req.Name,
+		ResourceType:  req.ResourceType,
+		Filter:        models.JSON(req.Filter),
+		SchemaVersion: schema.Version,
+		Shared:        req.Shared,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.repo.Create(ctx, filter); err != nil {
+		return nil, fmt.Errorf("failed to save filter: %w", err)
+	}
+	return filter, nil
+}
+
+// UpdateFilter applies a partial update. A new filter is saved under the
+// current schema version.
+func (s *SavedFilterService) UpdateFilter(ctx context.Context, principal Principal, id string, req *UpdateSavedFilterRequest) (*models.SavedFilter, error) {
+	filter, err := s.editable(ctx, principal, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		filter.Name = *req.Name
+	}
+	if req.Filter != nil {
+		schema, err := FilterSchemaFor(filter.ResourceType)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := schema.Decode(req.Filter, schema.Version); err != nil {
+			return nil, err
+		}
+		filter.Filter = models.JSON(req.Filter)
+		filter.SchemaVersion = schema.Version
+	}
+	if req.Shared != nil {
+		filter.Shared = *req.Shared
+	}
+	filter.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ctx, filter); err != nil {
+		return nil, fmt.Errorf("failed to update filter: %w", err)
+	}
+	return filter, nil
+}
+
+// DeleteFilter removes a filter
+func (s *SavedFilterService) DeleteFilter(ctx context.Context, principal Principal, id string) error {
+	filter, err := s.editable(ctx, principal, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, filter.ID); err != nil {
+		return err
+	}
+
+	requestctx.LoggerOr(ctx, s.logger).Info("Saved filter deleted",
+		logger.Field{Key: "filter_id", Value: filter.ID.String()},
+		logger.Field{Key: "owner_id", Value: filter.OwnerID.String()},
+		logger.Field{Key: "deleted_by", Value: principal.ID},
+	)
+	return nil
+}
+
+// FilterValues loads the filter the principal may read for resource,
+// upgraded to the current schema version and checked against it
+func (s *SavedFilterService) FilterValues(ctx context.Context, principal Principal, id, resource string) (map[string]string, error) {
+	filter, err := s.readable(ctx, principal, id)
+	if err != nil {
+		return nil, err
+	}
+	if filter.ResourceType != resource {
+		return nil, fmt.Errorf("%w: %s", ErrFilterResourceMismatch, filter.ResourceType)
+	}
+	schema, err := FilterSchemaFor(filter.ResourceType)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Decode(filter.Filter, filter.SchemaVersion)
+}
+
+// readable returns the filter if the principal owns it, or it is shared
+// and the principal may list its resource. Others' private filters are
+// reported not found.
+func (s *SavedFilterService) readable(ctx context.Context, principal Principal, id string) (*models.SavedFilter, error) {
+	filter, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if filter.OwnerID.String() == principal.ID {
+		return filter, nil
+	}
+	if !filter.Shared {
+		return nil, ErrSavedFilterNotFound
+	}
+
+	if err := s.resolvePermissions(ctx, &principal); err != nil {
+		return nil, err
+	}
+	if schema, err := FilterSchemaFor(filter.ResourceType); err != nil || !principal.Can(schema.Permission) {
+		return nil, ErrSavedFilterNotFound
+	}
+	return filter, nil
+}
+
+// editable returns the filter if the principal owns it, or it is shared
+// and the principal is an admin
+func (s *SavedFilterService) editable(ctx context.Context, principal Principal, id string) (*models.SavedFilter, error) {
+	filter, err := s.readable(ctx, principal, id)
+	if err != nil {
+		return nil, err
+	}
+	if filter.OwnerID.String() != principal.ID && principal.Role != models.RoleAdmin {
+		return nil, ErrSavedFilterForbidden
+	}
+	return filter, nil
+}
+
+// get loads a filter by ID
+func (s *SavedFilterService) get(ctx context.Context, id string) (*models.SavedFilter, error) {
+	filterID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrSavedFilterNotFound
+	}
+	return s.repo.Get(ctx, filterID)
+}
+
+// resolvePermissions fills in the permissions of the principal's role
+func (s *SavedFilterService) resolvePermissions(ctx context.Context, principal *Principal) error {
+	if principal.Permissions != nil || !principal.Role.Valid() {
+		return nil
+	}
+	permissions, err := s.permissions.RolePermissions(ctx, principal.Role)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the caller's permissions: %w", err)
+	}
+	principal.Permissions = permissions
+	return nil
+}
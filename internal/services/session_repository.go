@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SessionRepository persists login sessions
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+
+	// Get returns the session with id, or ErrSessionNotFound
+	Get(ctx context.Context, id uuid.UUID) (*models.Session, error)
+
+	// ListActive returns the user's sessions that are neither revoked nor
+	// expired at now, most recently used first
+	ListActive(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Session, error)
+
+	// Revoke sets revoked_at on the given active sessions of the user and
+	// returns the IDs it revoked. A nil ids revokes every active session
+	// except keep.
+	Revoke(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, keep uuid.UUID, at time.Time) ([]uuid.UUID, error)
+
+	// RevokeDevice sets revoked_at on the user's active sessions started
+	// from the trusted device and returns the IDs it revoked
+	RevokeDevice(ctx context.Context, userID, deviceID uuid.UUID, at time.Time) ([]uuid.UUID, error)
+
+	Touch(ctx context.Context, id uuid.UUID, at time.Time) error
+
+	// Extend moves the expiry of an active session, reporting false if the
+	// session is missing, revoked or expired at now
+	Extend(ctx context.Context, id uuid.UUID, expiresAt, now time.Time) (bool, error)
+
+	// Expire ends an active session at, as if it had expired then
+	Expire(ctx context.Context, id uuid.UUID, at time.Time) error
+
+	// DeleteBefore deletes sessions that expired or were revoked before cutoff
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// gormSessionRepository implements SessionRepository on the database each call is scoped to
+type gormSessionRepository struct {
+	db *database.Manager
+}
+
+// NewSessionRepository creates a repository backed by the database each call is scoped to
+func NewSessionRepository(db *database.Manager) SessionRepository {
+	return &gormSessionRepository{db: db}
+}
+
+func (r *gormSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(session).Error
+}
+
+func (r *gormSessionRepository) Get(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var session models.Session
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("id = ?", id).First(&session).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *gormSessionRepository) ListActive(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Session, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*models.Session
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+			Order("last_seen_at DESC").
+			Find(&sessions).Error
+	})
+	return sessions, err
+}
+
+func (r *gormSessionRepository) Revoke(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, keep uuid.UUID, at time.Time) ([]uuid.UUID, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, at)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	} else if keep != uuid.Nil {
+		query = query.Where("id <> ?", keep)
+	}
+
+	var revoked []uuid.UUID
+	if err := query.Pluck("id", &revoked).Error; err != nil {
+		return nil, err
+	}
+	if len(revoked) == 0 {
+		return nil, nil
+	}
+	err = db.Model(&models.Session{}).Where("id IN ?", revoked).Update("revoked_at", at).Error
+	return revoked, err
+}
+
+func (r *gormSessionRepository) RevokeDevice(ctx context.Context, userID, deviceID uuid.UUID, at time.Time) ([]uuid.UUID, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked []uuid.UUID
+	err = db.Model(&models.Session{}).
+		Where("user_id = ? AND device_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, deviceID, at).
+		Pluck("id", &revoked).Error
+	if err != nil || len(revoked) == 0 {
+		return nil, err
+	}
+	err = db.Model(&models.Session{}).Where("id IN ?", revoked).Update("revoked_at", at).Error
+	return revoked, err
+}
+
+func (r *gormSessionRepository) Touch(ctx context.Context, id uuid.UUID, at time.Time) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	// Setting the same time again changes nothing, so the write is retried
+	return withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Model(&models.Session{}).Where("id = ?", id).Update("last_seen_at", at).Error
+	})
+}
+
+func (r *gormSessionRepository) Extend(ctx context.Context, id uuid.UUID, expiresAt, now time.Time) (bool, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+
+	result := db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", id, now).
+		Updates(map[string]interface{}{"expires_at": expiresAt, "last_seen_at": now})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormSessionRepository) Expire(ctx context.Context, id uuid.UUID, at time.Time) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	// Sessions that ended already keep their end, so the write is retried
+	return withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL AND expires_at > ?", id, at).
+			Update("expires_at", at).Error
+	})
+}
+
+func (r *gormSessionRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	result := db.Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
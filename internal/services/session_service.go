@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// sessionTouchInterval bounds how often a session's last_seen_at is written
+const sessionTouchInterval = time.Minute
+
+// SessionService tracks the logins of users and revokes them
+type SessionService struct {
+	repo    SessionRepository
+	revoker *TokenRevoker
+	cache   cache.Store
+	audit   *AuditService
+	logger  logger.Logger
+	clock   clock.Clock
+
+	// idleTimeout and absoluteLifetime end sessions unused for that long or
+	// started that long ago, give or take clockSkew; zero disables them
+	idleTimeout      time.Duration
+	absoluteLifetime time.Duration
+	clockSkew        time.Duration
+}
+
+// SessionOption configures a SessionService
+type SessionOption func(s *SessionService)
+
+// WithSessionClock sets the clock that timestamps sessions. It should be the
+// clock the AuthService issues tokens with.
+func WithSessionClock(c clock.Clock) SessionOption {
+	return func(s *SessionService) {
+		s.clock = c
+	}
+}
+
+// WithSessionLimits ends sessions that made no request for idle or were
+// started more than absolute ago. skew is added to both, so replicas whose
+// clocks disagree do not end sessions early. Zero disables a limit.
+func WithSessionLimits(idle, absolute, skew time.Duration) SessionOption {
+	return func(s *SessionService) {
+		s.idleTimeout = idle
+		s.absoluteLifetime = absolute
+		s.clockSkew = skew
+	}
+}
+
+// NewSessionService creates a new session service. Revoked sessions are
+// recorded with revoker so their tokens are refused right away.
+func NewSessionService(repo SessionRepository, revoker *TokenRevoker, store cache.Store, audit *AuditService, log logger.Logger, opts ...SessionOption) *SessionService {
+	s := &SessionService{
+		repo:    repo,
+		revoker: revoker,
+		cache:   store,
+		audit:   audit,
+		logger:  log,
+		clock:   clock.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Start records a new session for userID on the client, valid until
+// expiresAt. deviceID is the trusted device of a remember_me login, or
+// uuid.Nil.
+func (s *SessionService) Start(ctx context.Context, userID string, client ClientInfo, deviceID uuid.UUID, expiresAt time.Time) (*models.Session, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	now := s.clock.Now()
+	session := &models.Session{
+		ID:         uuid.New(),
+		UserID:     id,
+		IPAddress:  client.IPAddress,
+		UserAgent:  client.UserAgent,
+		Location:   client.Location.String(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+	if deviceID != uuid.Nil {
+		session.DeviceID = &deviceID
+	}
+	if err := s.repo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return session, nil
+}
+
+// List returns the active sessions of userID, most recently used first,
+// marking the one with ID currentSID
+func (s *SessionService) List(ctx context.Context, userID, currentSID string) ([]*models.Session, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	sessions, err := s.repo.ListActive(ctx, id, s.clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	for _, session := range sessions {
+		session.Current = session.ID.String() == currentSID
+	}
+	return sessions, nil
+}
+
+// Revoke ends the session sid of userID on behalf of actorID. Its tokens are
+// refused from the next request on and it can no longer be refreshed.
+func (s *SessionService) Revoke(ctx context.Context, actorID, userID, sid string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	id, err := uuid.Parse(sid)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	revoked, err := s.repo.Revoke(ctx, uid, []uuid.UUID{id}, uuid.Nil, s.clock.Now())
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if len(revoked) == 0 {
+		return ErrSessionNotFound
+	}
+	return s.revoked(ctx, actorID, userID, revoked)
+}
+
+// RevokeOthers ends every active session of userID except keepSID on behalf
+// of actorID and returns how many it ended. An empty keepSID ends them all.
+func (s *SessionService) RevokeOthers(ctx context.Context, actorID, userID, keepSID string) (int, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, ErrUserNotFound
+	}
+	// A token without a session keeps nothing
+	keep, _ := uuid.Parse(keepSID)
+
+	revoked, err := s.repo.Revoke(ctx, uid, nil, keep, s.clock.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if len(revoked) == 0 {
+		return 0, nil
+	}
+	return len(revoked), s.revoked(ctx, actorID, userID, revoked)
+}
+
+// RevokeDevice ends every active session of userID started from the trusted
+// device on behalf of actorID and returns how many it ended
+func (s *SessionService) RevokeDevice(ctx context.Context, actorID, userID string, deviceID uuid.UUID) (int, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, ErrUserNotFound
+	}
+
+	revoked, err := s.repo.RevokeDevice(ctx, uid, deviceID, s.clock.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if len(revoked) == 0 {
+		return 0, nil
+	}
+	return len(revoked), s.revoked(ctx, actorID, userID, revoked)
+}
+
+// revoked refuses the tokens of the revoked sessions and audits the revocation
+func (s *SessionService) revoked(ctx context.Context, actorID, userID string, ids []uuid.UUID) error {
+	sessionIDs := make([]string, len(ids))
+	for i, id := range ids {
+		sessionIDs[i] = id.String()
+		if err := s.revoker.RevokeSession(ctx, sessionIDs[i]); err != nil {
+			return fmt.Errorf("failed to revoke session tokens: %w", err)
+		}
+	}
+
+	metadata := map[string]interface{}{"session_ids": sessionIDs}
+	if err := s.audit.Record(ctx, actorID, models.AuditActionUserSessionsRevoked, "user", userID, metadata); err != nil {
+		s.logger.Warn("Failed to audit session revocation", logger.Field{Key: "user_id", Value: userID}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	return nil
+}
+
+// IsRevoked reports whether the session sid has been revoked
+func (s *SessionService) IsRevoked(ctx context.Context, sid string) bool {
+	return s.revoker.IsSessionRevoked(ctx, sid)
+}
+
+// Check returns ErrSessionExpired once the session sid has been idle or
+// alive longer than the limits allow, marking it expired, and ErrTokenRevoked
+// for sessions that no longer exist. Without limits every session passes.
+func (s *SessionService) Check(ctx context.Context, sid string) error {
+	if s.idleTimeout <= 0 && s.absoluteLifetime <= 0 {
+		return nil
+	}
+	id, err := uuid.Parse(sid)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	times, err := s.sessionTimes(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+	var reason string
+	switch {
+	case s.absoluteLifetime > 0 && now.Sub(times.createdAt) > s.absoluteLifetime+s.clockSkew:
+		reason = "absolute_lifetime"
+	case s.idleTimeout > 0 && now.Sub(times.lastSeenAt) > s.idleTimeout+s.clockSkew:
+		reason = "idle_timeout"
+	default:
+		return nil
+	}
+
+	if err := s.repo.Expire(ctx, id, now); err != nil {
+		s.logger.Warn("Failed to expire session", logger.Field{Key: "session_id", Value: sid}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	_ = s.cache.Delete(ctx, sessionTimesCacheKey(sid))
+	s.logger.Info("Session expired", logger.Field{Key: "session_id", Value: sid}, logger.Field{Key: "reason", Value: reason})
+	return ErrSessionExpired
+}
+
+// sessionTimes is what Check needs to know about a session
+type sessionTimes struct {
+	createdAt  time.Time
+	lastSeenAt time.Time
+}
+
+// sessionTimes loads when the session id started and was last seen, using a
+// cache entry that lives until the next touch
+func (s *SessionService) sessionTimes(ctx context.Context, id uuid.UUID) (sessionTimes, error) {
+	key := sessionTimesCacheKey(id.String())
+	if data, err := s.cache.Get(ctx, key); err == nil {
+		// Cached as "<created_at>:<last_seen_at>" in Unix nanoseconds
+		if created, seen, ok := strings.Cut(string(data), ":"); ok {
+			createdAt, err1 := strconv.ParseInt(created, 10, 64)
+			lastSeenAt, err2 := strconv.ParseInt(seen, 10, 64)
+			if err1 == nil && err2 == nil {
+				return sessionTimes{createdAt: time.Unix(0, createdAt), lastSeenAt: time.Unix(0, lastSeenAt)}, nil
+			}
+		}
+	}
+
+	session, err := s.repo.Get(ctx, id)
+	if errors.Is(err, ErrSessionNotFound) {
+		return sessionTimes{}, ErrTokenRevoked
+	}
+	if err != nil {
+		return sessionTimes{}, fmt.Errorf("failed to load session: %w", err)
+	}
+	if session.RevokedAt != nil {
+		return sessionTimes{}, ErrTokenRevoked
+	}
+	// Marked expired earlier, possibly by another replica
+	if !session.Active(s.clock.Now()) {
+		return sessionTimes{}, ErrSessionExpired
+	}
+
+	times := sessionTimes{createdAt: session.CreatedAt, lastSeenAt: session.LastSeenAt}
+	data := strconv.FormatInt(times.createdAt.UnixNano(), 10) + ":" + strconv.FormatInt(times.lastSeenAt.UnixNano(), 10)
+	_ = s.cache.Set(ctx, key, []byte(data), s.touchInterval())
+	return times, nil
+}
+
+// Touch records that the session sid was just used. Writes are throttled to
+// one per sessionTouchInterval, or a tenth of the idle timeout when that is
+// shorter; failures are logged and otherwise ignored.
+func (s *SessionService) Touch(ctx context.Context, sid string) {
+	id, err := uuid.Parse(sid)
+	if err != nil {
+		return
+	}
+
+	key := sessionSeenCacheKey(sid)
+	if _, err := s.cache.Get(ctx, key); err == nil {
+		return
+	}
+	_ = s.cache.Set(ctx, key, []byte("1"), s.touchInterval())
+
+	if err := s.repo.Touch(ctx, id, s.clock.Now()); err != nil {
+		s.logger.Warn("Failed to touch session", logger.Field{Key: "session_id", Value: sid}, logger.Field{Key: "error", Value: err.Error()})
+		return
+	}
+	_ = s.cache.Delete(ctx, sessionTimesCacheKey(sid))
+}
+
+// touchInterval returns how often last_seen_at is written. Idle sessions
+// are judged by it, so it stays well below the idle timeout.
+func (s *SessionService) touchInterval() time.Duration {
+	if s.idleTimeout > 0 && s.idleTimeout/10 < sessionTouchInterval {
+		return max(s.idleTimeout/10, time.Second)
+	}
+	return sessionTouchInterval
+}
+
+// Extend keeps the session sid alive until expiresAt for a refreshed token.
+// Revoked and expired sessions cannot be extended and return ErrTokenRevoked.
+func (s *SessionService) Extend(ctx context.Context, sid string, expiresAt time.Time) error {
+	id, err := uuid.Parse(sid)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	extended, err := s.repo.Extend(ctx, id, expiresAt, s.clock.Now())
+	if err != nil {
+		return fmt.Errorf("failed to extend session: %w", err)
+	}
+	if !extended {
+		return ErrTokenRevoked
+	}
+	_ = s.cache.Delete(ctx, sessionTimesCacheKey(sid))
+	return nil
+}
+
+// PurgeBefore deletes sessions that expired or were revoked before cutoff
+func (s *SessionService) PurgeBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	purged, err := s.repo.DeleteBefore(ctx, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge sessions: %w", err)
+	}
+	return purged, nil
+}
+
+// sessionSeenCacheKey returns the cache key throttling a session's touches
+func sessionSeenCacheKey(sid string) string {
+	return "auth:session_seen:" + sid
+}
+
+// sessionTimesCacheKey returns the cache key holding when a session started
+// and was last seen
+func sessionTimesCacheKey(sid string) string {
+	return "auth:session_times:" + sid
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// SettingsRepository persists application settings
+type SettingsRepository interface {
+	List(ctx context.Context) ([]*models.Setting, error)
+
+	// SaveAll upserts every setting in a single transaction
+	SaveAll(ctx context.Context, settings []*models.Setting) error
+}
+
+// gormSettingsRepository implements SettingsRepository on the database each call is scoped to
+type gormSettingsRepository struct {
+	db *database.Manager
+}
+
+// NewSettingsRepository creates a repository backed by the database each call is scoped to
+func NewSettingsRepository(db *database.Manager) SettingsRepository {
+	return &gormSettingsRepository{db: db}
+}
+
+func (r *gormSettingsRepository) List(ctx context.Context) ([]*models.Setting, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []*models.Setting
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Find(&settings).Error
+	})
+	return settings, err
+}
+
+func (r *gormSettingsRepository) SaveAll(ctx context.Context, settings []*models.Setting) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, setting := range settings {
+			if err := tx.Save(setting).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
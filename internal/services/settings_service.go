@@ -0,0 +1,290 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// settingsCacheKey holds every stored setting. Writes delete it locally; other
+// instances pick changes up when settingsCacheTTL expires.
+const (
+	settingsCacheKey = "settings:all"
+	settingsCacheTTL = time.Minute
+)
+
+// AuditRecorder records audit log entries
+type AuditRecorder interface {
+	Record(ctx context.Context, actorID, action, entityType, entityID string, metadata interface{}) error
+}
+
+// UpdateSettingsRequest represents the payload for changing settings
+type UpdateSettingsRequest struct {
+	Settings map[string]json.RawMessage `json:"settings" binding:"required"`
+}
+
+// SettingsService provides typed access to application settings
+type SettingsService struct {
+	repo    SettingsRepository
+	cache   cache.Store
+	audit   AuditRecorder
+	logger  logger.Logger
+	schema  map[string]models.SettingDefinition
+	ordered []models.SettingDefinition
+	// responses, if set, drops cached responses built from settings
+	responses *ResponseCache
+}
+
+// SettingsOption configures a SettingsService
+type SettingsOption func(s *SettingsService)
+
+// WithSettingsResponseCache purges the cached responses that depend on
+// settings, such as the policy report, whenever a setting changes
+func WithSettingsResponseCache(r *ResponseCache) SettingsOption {
+	return func(s *SettingsService) {
+		s.responses = r
+	}
+}
+
+// NewSettingsService creates a settings service over the known settings in models.DefaultSettings
+func NewSettingsService(repo SettingsRepository, store cache.Store, audit AuditRecorder, log logger.Logger, opts ...SettingsOption) *SettingsService {
+	schema := make(map[string]models.SettingDefinition, len(models.DefaultSettings))
+	for _, def := range models.DefaultSettings {
+		schema[def.Key] = def
+	}
+	s := &SettingsService{
+		repo:    repo,
+		cache:   store,
+		audit:   audit,
+		logger:  log,
+		schema:  schema,
+		ordered: models.DefaultSettings,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// GetString returns a string setting
+func (s *SettingsService) GetString(ctx context.Context, key string) (string, error) {
+	var v string
+	err := s.get(ctx, key, models.SettingTypeString, &v)
+	return v, err
+}
+
+// GetInt returns an int setting
+func (s *SettingsService) GetInt(ctx context.Context, key string) (int, error) {
+	var v int
+	err := s.get(ctx, key, models.SettingTypeInt, &v)
+	return v, err
+}
+
+// GetBool returns a bool setting
+func (s *SettingsService) GetBool(ctx context.Context, key string) (bool, error) {
+	var v bool
+	err := s.get(ctx, key, models.SettingTypeBool, &v)
+	return v, err
+}
+
+// GetJSON decodes a json setting into dest
+func (s *SettingsService) GetJSON(ctx context.Context, key string, dest interface{}) error {
+	return s.get(ctx, key, models.SettingTypeJSON, dest)
+}
+
+// get decodes the current value of key, falling back to its default when it
+// has never been stored
+func (s *SettingsService) get(ctx context.Context, key string, want models.SettingType, dest interface{}) error {
+	def, ok := s.schema[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+	if def.Type != want {
+		return fmt.Errorf("%w: %s is %s", ErrSettingTypeMismatch, key, def.Type)
+	}
+
+	stored, err := s.stored(ctx)
+	if err != nil {
+		return err
+	}
+
+	value := def.Default
+	if setting, ok := stored[key]; ok && len(setting.Value) > 0 {
+		value = setting.Value
+	}
+	if err := json.Unmarshal(value, dest); err != nil {
+		return fmt.Errorf("%w: %s", ErrSettingTypeMismatch, key)
+	}
+	return nil
+}
+
+// stored returns the persisted settings keyed by setting key, read through the cache
+func (s *SettingsService) stored(ctx context.Context) (map[string]*models.Setting, error) {
+	if data, err := s.cache.Get(ctx, settingsCacheKey); err == nil {
+		var settings map[string]*models.Setting
+		if err := json.Unmarshal(data, &settings); err == nil {
+			return settings, nil
+		}
+	}
+
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	settings := make(map[string]*models.Setting, len(list))
+	for _, setting := range list {
+		settings[setting.Key] = setting
+	}
+
+	if data, err := json.Marshal(settings); err == nil {
+		if err := s.cache.Set(ctx, settingsCacheKey, data, settingsCacheTTL); err != nil {
+			s.logger.Warn("Failed to cache settings", logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+	return settings, nil
+}
+
+// ListSettings returns every known setting with its current value
+func (s *SettingsService) ListSettings(ctx context.Context) ([]*models.Setting, error) {
+	stored, err := s.stored(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make([]*models.Setting, len(s.ordered))
+	for i, def := range s.ordered {
+		setting := &models.Setting{Key: def.Key, Value: def.Default, Type: def.Type}
+		if current, ok := stored[def.Key]; ok {
+			setting.Value = current.Value
+			setting.UpdatedBy = current.UpdatedBy
+			setting.UpdatedAt = current.UpdatedAt
+		}
+		setting.Description = def.Description
+		settings[i] = setting
+	}
+	return settings, nil
+}
+
+// UpdateSettings validates and stores the given values. Either every value is
+// stored or none is; each changed key is audit-logged with its old and new value.
+func (s *SettingsService) UpdateSettings(ctx context.Context, actorID string, values map[string]json.RawMessage) ([]*models.Setting, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	normalized := make(map[string]models.JSON, len(values))
+	for _, key := range keys {
+		def, ok := s.schema[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+		}
+		value, ok := normalizeSettingValue(def.Type, values[key])
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be %s", ErrSettingTypeMismatch, key, def.Type)
+		}
+		normalized[key] = value
+	}
+
+	// Read around the cache so the audit trail records the true previous value
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	previous := make(map[string]models.JSON, len(list))
+	for _, setting := range list {
+		previous[setting.Key] = setting.Value
+	}
+
+	var updatedBy *uuid.UUID
+	if parsed, err := uuid.Parse(actorID); err == nil {
+		updatedBy = &parsed
+	}
+
+	now := time.Now()
+	var changed []*models.Setting
+	for _, key := range keys {
+		old, ok := previous[key]
+		if !ok {
+			old = s.schema[key].Default
+		}
+		if bytes.Equal(old, normalized[key]) {
+			continue
+		}
+		changed = append(changed, &models.Setting{
+			Key:       key,
+			Value:     normalized[key],
+			Type:      s.schema[key].Type,
+			UpdatedBy: updatedBy,
+			UpdatedAt: now,
+		})
+	}
+
+	if len(changed) > 0 {
+		if err := s.repo.SaveAll(ctx, changed); err != nil {
+			return nil, fmt.Errorf("failed to update settings: %w", err)
+		}
+		if err := s.cache.Delete(ctx, settingsCacheKey); err != nil {
+			s.logger.Warn("Failed to invalidate settings cache", logger.Field{Key: "error", Value: err.Error()})
+		}
+		s.responses.Purge(ctx, ResponseGroupSettings)
+
+		for _, setting := range changed {
+			old, ok := previous[setting.Key]
+			if !ok {
+				old = s.schema[setting.Key].Default
+			}
+			metadata := map[string]models.JSON{"old": old, "new": setting.Value}
+			if err := s.audit.Record(ctx, actorID, models.AuditActionSettingUpdated, "setting", setting.Key, metadata); err != nil {
+				s.logger.Warn("Failed to audit setting change", logger.Field{Key: "setting", Value: setting.Key}, logger.Field{Key: "error", Value: err.Error()})
+			}
+		}
+	}
+
+	return s.ListSettings(ctx)
+}
+
+// normalizeSettingValue checks raw against t and returns it compacted
+func normalizeSettingValue(t models.SettingType, raw json.RawMessage) (models.JSON, bool) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return nil, false
+	}
+	value := buf.Bytes()
+
+	if t != models.SettingTypeJSON && bytes.Equal(value, []byte("null")) {
+		return nil, false
+	}
+
+	var err error
+	switch t {
+	case models.SettingTypeString:
+		var v string
+		err = json.Unmarshal(value, &v)
+	case models.SettingTypeInt:
+		// Rejects fractions and exponents as well as non-numbers
+		var v int64
+		err = json.Unmarshal(value, &v)
+	case models.SettingTypeBool:
+		var v bool
+		err = json.Unmarshal(value, &v)
+	case models.SettingTypeJSON:
+	default:
+		return nil, false
+	}
+	if err != nil {
+		return nil, false
+	}
+	return models.JSON(value), true
+}
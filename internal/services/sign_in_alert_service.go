@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/infrastructure/email"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+	"BackofficeGoService/internal/pkg/requestctx"
+	"BackofficeGoService/internal/services/featureflags"
+
+	"github.com/google/uuid"
+)
+
+// SignInAlertsFlag is the feature flag turning new sign-in emails on, per user
+const SignInAlertsFlag = "sign_in_alerts"
+
+// DefaultSecureAccountLinkTTL is how long the link in a new sign-in email
+// works when no TTL is configured
+const DefaultSecureAccountLinkTTL = 7 * 24 * time.Hour
+
+// FlagChecker evaluates feature flags for a user, as featureflags.Service does
+type FlagChecker interface {
+	IsEnabled(ctx context.Context, key string, user *featureflags.User) bool
+}
+
+// SignInAlertService emails users when they log in from a device or IP
+// range they never logged in from before. Logins are checked in the
+// background, so they are not slowed down; the first login of a user has
+// nothing to compare against and only records the device and network.
+// The email links to a page redeeming a token with AuthService.SecureAccount,
+// which signs the user out everywhere.
+type SignInAlertService struct {
+	repo      KnownSignInRepository
+	mailer    email.EmailClient
+	flags     FlagChecker
+	cache     cache.Store
+	secureURL string
+	linkTTL   time.Duration
+	templates *email.Registry
+	metrics   *metrics.Business
+	logger    logger.Logger
+	clock     clock.Clock
+
+	wg sync.WaitGroup
+}
+
+// SignInAlertOption configures a SignInAlertService
+type SignInAlertOption func(s *SignInAlertService)
+
+// WithSignInAlertTemplates sets the registry the email is rendered from; it
+// must hold the templates NewEmailTemplates registers
+func WithSignInAlertTemplates(templates *email.Registry) SignInAlertOption {
+	return func(s *SignInAlertService) {
+		s.templates = templates
+	}
+}
+
+// WithSignInAlertMetrics counts the emails sent
+func WithSignInAlertMetrics(m *metrics.Business) SignInAlertOption {
+	return func(s *SignInAlertService) {
+		s.metrics = m
+	}
+}
+
+// WithSignInAlertClock sets the clock that timestamps sign-ins
+func WithSignInAlertClock(c clock.Clock) SignInAlertOption {
+	return func(s *SignInAlertService) {
+		s.clock = c
+	}
+}
+
+// NewSignInAlertService creates a new sign-in alert service. Logins are
+// checked for users flags enables SignInAlertsFlag for; a nil flags checks
+// none. The emailed link is secureURL with the token in its token query
+// parameter, valid for linkTTL. Without a mailer sign-ins are recorded but
+// no email is sent.
+func NewSignInAlertService(repo KnownSignInRepository, mailer email.EmailClient, flags FlagChecker, store cache.Store, secureURL string, linkTTL time.Duration, log logger.Logger, opts ...SignInAlertOption) *SignInAlertService {
+	if linkTTL <= 0 {
+		linkTTL = DefaultSecureAccountLinkTTL
+	}
+	s := &SignInAlertService{
+		repo:      repo,
+		mailer:    mailer,
+		flags:     flags,
+		cache:     cache.WithPrefix(store, "signin:secure:"),
+		secureURL: secureURL,
+		linkTTL:   linkTTL,
+		logger:    log,
+		clock:     clock.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.templates == nil {
+		s.templates = NewEmailTemplates()
+	}
+	return s
+}
+
+// Observe checks a successful login of user from client in the background
+func (s *SignInAlertService) Observe(ctx context.Context, user *models.User, client ClientInfo) {
+	signedIn := *user
+	at := s.clock.Now()
+	ctx = context.WithoutCancel(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.check(ctx, &signedIn, client, at); err != nil {
+			requestctx.LoggerOr(ctx, s.logger).Warn("Failed to check sign-in",
+				logger.Field{Key: "user_id", Value: signedIn.ID.String()},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+		}
+	}()
+}
+
+// Stop waits for the logins observed so far to be checked
+func (s *SignInAlertService) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// check records the device and network of a login and emails the user if
+// either is new to them
+func (s *SignInAlertService) check(ctx context.Context, user *models.User, client ClientInfo, at time.Time) error {
+	if s.flags == nil || !s.flags.IsEnabled(ctx, SignInAlertsFlag, &featureflags.User{ID: user.ID.String(), Role: string(user.Role)}) {
+		return nil
+	}
+
+	known, err := s.repo.List(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load known sign-ins: %w", err)
+	}
+	device := hashSignInDevice(client.UserAgent)
+	network := SignInNetwork(client.IPAddress)
+	newDevice, newNetwork := true, true
+	for _, signIn := range known {
+		newDevice = newDevice && signIn.DeviceHash != device
+		newNetwork = newNetwork && signIn.Network != network
+	}
+
+	signIn := &models.KnownSignIn{UserID: user.ID, DeviceHash: device, Network: network, FirstSeenAt: at, LastSeenAt: at}
+	if err := s.repo.Record(ctx, signIn); err != nil {
+		return fmt.Errorf("failed to record sign-in: %w", err)
+	}
+
+	if len(known) == 0 || (!newDevice && !newNetwork) || s.mailer == nil {
+		return nil
+	}
+	return s.alert(ctx, user, client, at)
+}
+
+// alert emails user about the login with a link securing the account
+func (s *SignInAlertService) alert(ctx context.Context, user *models.User, client ClientInfo, at time.Time) error {
+	token, err := generateSecureAccountToken()
+	if err != nil {
+		return err
+	}
+	if err := s.cache.Set(ctx, hashSecureAccountToken(token), []byte(user.ID.String()), s.linkTTL); err != nil {
+		return fmt.Errorf("failed to store secure account token: %w", err)
+	}
+	link, err := url.Parse(s.secureURL)
+	if err != nil {
+		return fmt.Errorf("invalid secure account URL: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	message, err := s.templates.Render(EmailTemplateNewSignIn, email.FormatText, NewSignInData{
+		Device:    client.UserAgent,
+		IPAddress: client.IPAddress,
+		Location:  client.Location.String(),
+		Time:      at,
+		SecureURL: link.String(),
+	})
+	if err != nil {
+		return err
+	}
+	err = s.mailer.Send(user.Email, message.Subject, message.Body)
+	s.metrics.EmailSent(err)
+	return err
+}
+
+// Redeem uses a token from a new sign-in email, returning the ID of the
+// user it was sent to. Tokens work once; an unknown, used or expired one
+// fails with ErrSecureAccountTokenInvalid.
+func (s *SignInAlertService) Redeem(ctx context.Context, token string) (string, error) {
+	key := hashSecureAccountToken(token)
+	value, err := s.cache.Get(ctx, key)
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return "", ErrSecureAccountTokenInvalid
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secure account token: %w", err)
+	}
+	if err := s.cache.Delete(ctx, key); err != nil {
+		return "", fmt.Errorf("failed to use secure account token: %w", err)
+	}
+	if _, err := uuid.Parse(string(value)); err != nil {
+		return "", ErrSecureAccountTokenInvalid
+	}
+	return string(value), nil
+}
+
+// SignInNetwork returns the range a login's IP address is compared by: its
+// /24 for IPv4 and its /48 for IPv6. Addresses that do not parse are kept
+// as they are.
+func SignInNetwork(ip string) string {
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	if addr == nil {
+		return ip
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: addr.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// hashSignInDevice returns the form a login's device is compared and stored
+// in: the hash of its User-Agent
+func hashSignInDevice(userAgent string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(userAgent)))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateSecureAccountToken returns a random hex-encoded token
+func generateSecureAccountToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secure account token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashSecureAccountToken returns the form a secure account token is stored in
+func hashSecureAccountToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TaskRepository persists asynchronous tasks
+type TaskRepository interface {
+	Create(ctx context.Context, task *models.Task) error
+
+	// Get returns the task or ErrTaskNotFound
+	Get(ctx context.Context, id uuid.UUID) (*models.Task, error)
+
+	// Update sets fields on a task that has not finished yet and reports
+	// whether it did, so a finished task is never changed again
+	Update(ctx context.Context, id uuid.UUID, fields map[string]interface{}) (bool, error)
+
+	// ListBefore returns the tasks last updated before cutoff
+	ListBefore(ctx context.Context, cutoff time.Time) ([]*models.Task, error)
+	Delete(ctx context.Context, ids []uuid.UUID) (int64, error)
+}
+
+// gormTaskRepository implements TaskRepository on the database each call is scoped to
+type gormTaskRepository struct {
+	db *database.Manager
+}
+
+// NewTaskRepository creates a repository backed by the database each call is scoped to
+func NewTaskRepository(db *database.Manager) TaskRepository {
+	return &gormTaskRepository{db: db}
+}
+
+func (r *gormTaskRepository) Create(ctx context.Context, task *models.Task) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(task).Error
+}
+
+func (r *gormTaskRepository) Get(ctx context.Context, id uuid.UUID) (*models.Task, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var task models.Task
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("id = ?", id).First(&task).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (r *gormTaskRepository) Update(ctx context.Context, id uuid.UUID, fields map[string]interface{}) (bool, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+
+	fields["updated_at"] = time.Now()
+	result := db.Model(&models.Task{}).
+		Where("id = ? AND status IN ?", id, []models.TaskStatus{models.TaskStatusPending, models.TaskStatusRunning}).
+		Updates(fields)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormTaskRepository) ListBefore(ctx context.Context, cutoff time.Time) ([]*models.Task, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []*models.Task
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("updated_at < ?", cutoff).Find(&tasks).Error
+	})
+	return tasks, err
+}
+
+func (r *gormTaskRepository) Delete(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	result := db.Where("id IN ?", ids).Delete(&models.Task{})
+	return result.RowsAffected, result.Error
+}
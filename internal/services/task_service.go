@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/infrastructure/storage"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/filetoken"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/requestctx"
+
+	"github.com/google/uuid"
+)
+
+// TaskFunc does the work of a task. It reports progress through run and
+// returns the storage key of its result, if any. It must return once ctx is
+// cancelled.
+type TaskFunc func(ctx context.Context, run *TaskRun) (resultKey string, err error)
+
+// TaskRun is the task a TaskFunc is running
+type TaskRun struct {
+	ID uuid.UUID
+
+	mu       sync.Mutex
+	progress int
+	save     func(percent int)
+}
+
+// Progress records how much of the task is done, from 0 to 100. Only
+// changes are saved, so it can be called for every row.
+func (r *TaskRun) Progress(percent int) {
+	percent = max(0, min(percent, 100))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if percent == r.progress {
+		return
+	}
+	r.progress = percent
+	r.save(percent)
+}
+
+// TaskService runs long operations in the background and tracks them in the
+// tasks table, so clients can poll their progress and download the result
+type TaskService struct {
+	repo          TaskRepository
+	store         storage.Store
+	links         *filetoken.Signer
+	notifications *NotificationService
+	logger        logger.Logger
+	urlTTL        time.Duration
+
+	slots   chan struct{}
+	mu      sync.Mutex
+	running map[uuid.UUID]context.CancelFunc
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// NewTaskService creates a task service running at most workers tasks at a
+// time. Result links are minted by links and valid for urlTTL. notifications
+// may be nil.
+func NewTaskService(repo TaskRepository, store storage.Store, links *filetoken.Signer, notifications *NotificationService, workers int, urlTTL time.Duration, log logger.Logger) *TaskService {
+	if workers < 1 {
+		workers = 1
+	}
+	return &TaskService{
+		repo:          repo,
+		store:         store,
+		links:         links,
+		notifications: notifications,
+		logger:        log,
+		urlTTL:        urlTTL,
+		slots:         make(chan struct{}, workers),
+		running:       make(map[uuid.UUID]context.CancelFunc),
+	}
+}
+
+// Submit records a pending task owned by ownerID and runs fn in the
+// background, in the tenant ctx is scoped to. The task waits for a free
+// worker; the owner is notified when it finishes.
+func (s *TaskService) Submit(ctx context.Context, ownerID, taskType string, fn TaskFunc) (*models.Task, error) {
+	owner, err := uuid.Parse(ownerID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	now := time.Now()
+	task := &models.Task{
+		ID:        uuid.New(),
+		Type:      taskType,
+		OwnerID:   owner,
+		Status:    models.TaskStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// The task outlives the request but keeps its tenant
+	runCtx := database.WithTenant(context.Background(), database.TenantID(ctx), database.DriverName(ctx))
+	runCtx, cancel := context.WithCancel(runCtx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		cancel()
+		return nil, ErrTaskRunnerStopped
+	}
+	if err := s.repo.Create(ctx, task); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+	s.running[task.ID] = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.forget(task.ID)
+		s.run(runCtx, *task, fn)
+	}()
+	return task, nil
+}
+
+// Get returns a task with a fresh download link for its result. The link
+// only works for the user ctx belongs to, or the owner when it has none.
+func (s *TaskService) Get(ctx context.Context, id string) (*models.Task, error) {
+	taskID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrTaskNotFound
+	}
+	task, err := s.repo.Get(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.Status == models.TaskStatusSucceeded && task.ResultKey != "" {
+		userID := task.OwnerID.String()
+		if user, ok := requestctx.UserFrom(ctx); ok {
+			userID = user.ID
+		}
+		url, err := s.links.URL(task.ResultKey, userID, s.urlTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign result link: %w", err)
+		}
+		task.ResultURL = url
+	}
+	return task, nil
+}
+
+// Cancel stops a pending or running task. The worker sees its context
+// cancelled and the task ends as cancelled. Tasks running on another
+// instance are marked cancelled; their result is discarded.
+func (s *TaskService) Cancel(ctx context.Context, id string) (*models.Task, error) {
+	task, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status.Finished() {
+		return task, ErrTaskFinished
+	}
+
+	s.mu.Lock()
+	cancel, local := s.running[task.ID]
+	s.mu.Unlock()
+	if local {
+		cancel()
+		return task, nil
+	}
+
+	if _, err := s.finish(ctx, task.ID, models.TaskStatusCancelled, "", ""); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, id)
+}
+
+// PurgeBefore deletes tasks last updated before cutoff with their results.
+// Tasks still running here are left alone.
+func (s *TaskService) PurgeBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	tasks, err := s.repo.ListBefore(ctx, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	ids := make([]uuid.UUID, 0, len(tasks))
+	for _, task := range tasks {
+		s.mu.Lock()
+		_, local := s.running[task.ID]
+		s.mu.Unlock()
+		if local {
+			continue
+		}
+		if task.ResultKey != "" {
+			if err := s.store.Delete(ctx, task.ResultKey); err != nil {
+				s.logger.Warn("Failed to delete task result", logger.Field{Key: "task_id", Value: task.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+				continue
+			}
+		}
+		ids = append(ids, task.ID)
+	}
+
+	purged, err := s.repo.Delete(ctx, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge tasks: %w", err)
+	}
+	return purged, nil
+}
+
+// Stop cancels every task and waits for the workers to exit
+func (s *TaskService) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	for _, cancel := range s.running {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run waits for a worker, runs fn and records how it ended
+func (s *TaskService) run(ctx context.Context, task models.Task, fn TaskFunc) {
+	// Bookkeeping must still reach the database once the task is cancelled
+	store := context.WithoutCancel(ctx)
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		s.complete(store, task, models.TaskStatusCancelled, "", "")
+		return
+	}
+
+	if _, err := s.repo.Update(store, task.ID, map[string]interface{}{"status": models.TaskStatusRunning}); err != nil {
+		s.logger.Warn("Failed to mark task running", logger.Field{Key: "task_id", Value: task.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+	}
+
+	run := &TaskRun{ID: task.ID}
+	run.save = func(percent int) {
+		if _, err := s.repo.Update(store, task.ID, map[string]interface{}{"progress": percent}); err != nil {
+			s.logger.Warn("Failed to save task progress", logger.Field{Key: "task_id", Value: task.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+
+	resultKey, err := fn(ctx, run)
+	switch {
+	case ctx.Err() != nil:
+		if resultKey != "" {
+			_ = s.store.Delete(store, resultKey)
+		}
+		s.complete(store, task, models.TaskStatusCancelled, "", "")
+	case err != nil:
+		s.logger.Error("Task failed", logger.Field{Key: "task_id", Value: task.ID.String()}, logger.Field{Key: "type", Value: task.Type}, logger.Field{Key: "error", Value: err.Error()})
+		s.complete(store, task, models.TaskStatusFailed, "", err.Error())
+	default:
+		s.complete(store, task, models.TaskStatusSucceeded, resultKey, "")
+	}
+}
+
+// complete records the end of a task and notifies its owner
+func (s *TaskService) complete(ctx context.Context, task models.Task, status models.TaskStatus, resultKey, errMsg string) {
+	// Once the status is final, the task is no longer running here
+	s.forget(task.ID)
+
+	updated, err := s.finish(ctx, task.ID, status, resultKey, errMsg)
+	if err != nil {
+		s.logger.Error("Failed to record task result", logger.Field{Key: "task_id", Value: task.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+		return
+	}
+	// Cancelled elsewhere in the meantime; the owner already knows
+	if !updated {
+		if resultKey != "" {
+			_ = s.store.Delete(ctx, resultKey)
+		}
+		return
+	}
+	s.notifyFinished(ctx, task, status, errMsg)
+}
+
+// finish moves an unfinished task to a final status
+func (s *TaskService) finish(ctx context.Context, id uuid.UUID, status models.TaskStatus, resultKey, errMsg string) (bool, error) {
+	fields := map[string]interface{}{
+		"status":      status,
+		"result_key":  resultKey,
+		"error":       errMsg,
+		"finished_at": time.Now(),
+	}
+	if status == models.TaskStatusSucceeded {
+		fields["progress"] = 100
+	}
+	updated, err := s.repo.Update(ctx, id, fields)
+	if err != nil {
+		return false, fmt.Errorf("failed to update task: %w", err)
+	}
+	return updated, nil
+}
+
+// notifyFinished tells the owner how their task ended
+func (s *TaskService) notifyFinished(ctx context.Context, task models.Task, status models.TaskStatus, errMsg string) {
+	if s.notifications == nil {
+		return
+	}
+
+	title := fmt.Sprintf("Task %s finished", task.Type)
+	switch status {
+	case models.TaskStatusFailed:
+		title = fmt.Sprintf("Task %s failed", task.Type)
+	case models.TaskStatusCancelled:
+		title = fmt.Sprintf("Task %s was cancelled", task.Type)
+	}
+	data, _ := models.NewJSON(map[string]interface{}{
+		"task_id": task.ID.String(),
+		"type":    task.Type,
+		"status":  status,
+	})
+	notification := &models.Notification{
+		Type:  models.NotificationTypeTaskFinished,
+		Title: title,
+		Body:  errMsg,
+		Data:  data,
+	}
+
+	if err := s.notifications.Notify(ctx, task.OwnerID.String(), notification); err != nil {
+		s.logger.Warn("Failed to notify task completion", logger.Field{Key: "task_id", Value: task.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+	}
+}
+
+// forget drops a task that is no longer running here
+func (s *TaskService) forget(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.running[id]; ok {
+		cancel()
+		delete(s.running, id)
+	}
+}
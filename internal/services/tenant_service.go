@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/database/migrations"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// tenantIDPattern restricts tenant IDs to what is safe in headers, cache keys
+// and database names
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Tenant is a tenant and the database its data lives in
+type Tenant struct {
+	ID       string `json:"id"`
+	Database string `json:"database"`
+}
+
+// TenantConnection describes a database connected for a new tenant
+type TenantConnection struct {
+	Driver   string `json:"driver" binding:"required"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	DBName   string `json:"dbname" binding:"required"`
+	SSLMode  string `json:"sslmode"`
+}
+
+// CreateTenantRequest registers a tenant. The tenant either shares an already
+// connected database, named by Database, or gets a new one from Connection.
+type CreateTenantRequest struct {
+	ID         string            `json:"id" binding:"required"`
+	Database   string            `json:"database"`
+	Connection *TenantConnection `json:"connection"`
+}
+
+// TenantService registers tenants and connects their databases at runtime
+type TenantService struct {
+	db      *database.Manager
+	factory *database.Factory
+	audit   AuditRecorder
+	logger  logger.Logger
+}
+
+// NewTenantService creates a new tenant service
+func NewTenantService(db *database.Manager, audit AuditRecorder, log logger.Logger) *TenantService {
+	return &TenantService{
+		db:      db,
+		factory: database.NewFactory(),
+		audit:   audit,
+		logger:  log,
+	}
+}
+
+// ListTenants returns every registered tenant ordered by ID
+func (s *TenantService) ListTenants() []Tenant {
+	registered := s.db.Tenants()
+	tenants := make([]Tenant, 0, len(registered))
+	for id, driverName := range registered {
+		tenants = append(tenants, Tenant{ID: id, Database: driverName})
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants
+}
+
+// CreateTenant registers a tenant. A new connection is opened, migrated and
+// registered as "tenant_<id>" before any request is routed to it.
+func (s *TenantService) CreateTenant(ctx context.Context, actorID string, req *CreateTenantRequest) (*Tenant, error) {
+	if !tenantIDPattern.MatchString(req.ID) {
+		return nil, ErrInvalidTenant
+	}
+	if (req.Database == "") == (req.Connection == nil) {
+		return nil, fmt.Errorf("%w: exactly one of database and connection is required", ErrInvalidTenant)
+	}
+	if _, exists := s.db.TenantDatabase(req.ID); exists {
+		return nil, ErrTenantExists
+	}
+
+	driverName := req.Database
+	if req.Connection != nil {
+		driverName = "tenant_" + req.ID
+		if err := s.connect(ctx, driverName, req.Connection); err != nil {
+			return nil, err
+		}
+	} else if _, err := s.db.GetDriver(driverName); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTenantDatabaseNotFound, driverName)
+	}
+
+	if err := s.db.RegisterTenant(req.ID, driverName); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Tenant registered", logger.Field{Key: "tenant", Value: req.ID}, logger.Field{Key: "database", Value: driverName})
+	if s.audit != nil {
+		if err := s.audit.Record(ctx, actorID, models.AuditActionTenantCreated, "tenant", req.ID, map[string]string{"database": driverName}); err != nil {
+			s.logger.Warn("Failed to audit tenant creation", logger.Field{Key: "tenant", Value: req.ID}, logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+
+	return &Tenant{ID: req.ID, Database: driverName}, nil
+}
+
+// connect opens the tenant's database and applies every migration before
+// registering it, so a failed attempt leaves nothing behind
+func (s *TenantService) connect(ctx context.Context, driverName string, conn *TenantConnection) error {
+	driver, err := s.factory.CreateFromConnectionConfig(database.ConnectionConfig{
+		Driver:   database.DriverType(conn.Driver),
+		Host:     conn.Host,
+		Port:     conn.Port,
+		User:     conn.User,
+		Password: conn.Password,
+		DBName:   conn.DBName,
+		SSLMode:  conn.SSLMode,
+		UseGorm:  true,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTenant, err)
+	}
+
+	if _, err := s.db.GetDriver(driverName); err == nil {
+		return ErrTenantExists
+	}
+	if err := driver.Connect(ctx); err != nil {
+		driver.Close()
+		return fmt.Errorf("failed to connect tenant database: %w", err)
+	}
+
+	gormDB, err := database.OpenGorm(driver)
+	if err == nil {
+		err = s.migrate(ctx, gormDB, driverName)
+	}
+	if err == nil {
+		err = s.db.AddDriver(driverName, driver)
+	}
+	if err != nil {
+		driver.Close()
+		return err
+	}
+	return nil
+}
+
+// migrate applies every pending migration to a tenant's database
+func (s *TenantService) migrate(ctx context.Context, gormDB *gorm.DB, driverName string) error {
+	applied, err := migrations.NewRunner(gormDB).Up(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to migrate tenant database: %w", err)
+	}
+	for _, id := range applied {
+		s.logger.Info("Migration applied", logger.Field{Key: "database", Value: driverName}, logger.Field{Key: "migration", Value: id})
+	}
+	return nil
+}
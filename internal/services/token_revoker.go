@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+)
+
+// TokenRevoker tracks per-user and per-session token revocation.
+// Revoking a user invalidates every access and refresh token issued up to
+// that moment; tokens issued afterwards are unaffected. Revoking a session
+// invalidates every token carrying its sid.
+type TokenRevoker struct {
+	cache cache.Store
+	ttl   time.Duration
+	clock clock.Clock
+}
+
+// TokenRevokerOption configures a TokenRevoker
+type TokenRevokerOption func(r *TokenRevoker)
+
+// WithRevokerClock sets the clock that timestamps revocations. It should be
+// the clock the AuthService issues tokens with.
+func WithRevokerClock(c clock.Clock) TokenRevokerOption {
+	return func(r *TokenRevoker) {
+		r.clock = c
+	}
+}
+
+// NewTokenRevoker creates a token revoker. ttl should be at least the
+// longest token lifetime so a revocation outlives every token it covers.
+func NewTokenRevoker(store cache.Store, ttl time.Duration, opts ...TokenRevokerOption) *TokenRevoker {
+	r := &TokenRevoker{
+		cache: store,
+		ttl:   ttl,
+		clock: clock.New(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// RevokeUserTokens invalidates every token issued to the user so far
+func (r *TokenRevoker) RevokeUserTokens(ctx context.Context, userID string) error {
+	revokedAt := strconv.FormatInt(r.clock.Now().Unix(), 10)
+	return r.cache.Set(ctx, revokedTokensCacheKey(userID), []byte(revokedAt), r.ttl)
+}
+
+// IsRevoked reports whether a token issued at issuedAt has been revoked
+func (r *TokenRevoker) IsRevoked(ctx context.Context, userID string, issuedAt time.Time) bool {
+	data, err := r.cache.Get(ctx, revokedTokensCacheKey(userID))
+	if err != nil {
+		return false
+	}
+
+	revokedAt, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return false
+	}
+
+	// iat has second precision, so a token issued in the revocation second is revoked too
+	return issuedAt.Unix() <= revokedAt
+}
+
+// RevokeSession invalidates every token issued for the session sid
+func (r *TokenRevoker) RevokeSession(ctx context.Context, sid string) error {
+	revokedAt := strconv.FormatInt(r.clock.Now().Unix(), 10)
+	return r.cache.Set(ctx, revokedSessionCacheKey(sid), []byte(revokedAt), r.ttl)
+}
+
+// IsSessionRevoked reports whether the session sid has been revoked
+func (r *TokenRevoker) IsSessionRevoked(ctx context.Context, sid string) bool {
+	_, err := r.cache.Get(ctx, revokedSessionCacheKey(sid))
+	return err == nil
+}
+
+// revokedTokensCacheKey returns the cache key holding a user's revocation time
+func revokedTokensCacheKey(userID string) string {
+	return "auth:revoked:" + userID
+}
+
+// revokedSessionCacheKey returns the cache key marking a session as revoked
+func revokedSessionCacheKey(sid string) string {
+	return "auth:revoked_session:" + sid
+}
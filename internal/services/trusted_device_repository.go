@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TrustedDeviceRepository persists the devices users asked to be remembered on
+type TrustedDeviceRepository interface {
+	Create(ctx context.Context, device *models.TrustedDevice) error
+
+	// Find returns the user's device with tokenHash, or ErrDeviceNotFound
+	Find(ctx context.Context, userID uuid.UUID, tokenHash string) (*models.TrustedDevice, error)
+
+	// List returns the user's devices, most recently used first
+	List(ctx context.Context, userID uuid.UUID) ([]*models.TrustedDevice, error)
+
+	// Touch sets last_used_at, reporting false if the device is gone
+	Touch(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+
+	// Delete removes the user's device, reporting false if it has none with id
+	Delete(ctx context.Context, userID, id uuid.UUID) (bool, error)
+
+	// DeleteUnusedBefore deletes devices last used before cutoff
+	DeleteUnusedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// gormTrustedDeviceRepository implements TrustedDeviceRepository on the database each call is scoped to
+type gormTrustedDeviceRepository struct {
+	db *database.Manager
+}
+
+// NewTrustedDeviceRepository creates a repository backed by the database each call is scoped to
+func NewTrustedDeviceRepository(db *database.Manager) TrustedDeviceRepository {
+	return &gormTrustedDeviceRepository{db: db}
+}
+
+func (r *gormTrustedDeviceRepository) Create(ctx context.Context, device *models.TrustedDevice) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(device).Error
+}
+
+func (r *gormTrustedDeviceRepository) Find(ctx context.Context, userID uuid.UUID, tokenHash string) (*models.TrustedDevice, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var device models.TrustedDevice
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("user_id = ? AND token_hash = ?", userID, tokenHash).First(&device).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *gormTrustedDeviceRepository) List(ctx context.Context, userID uuid.UUID) ([]*models.TrustedDevice, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []*models.TrustedDevice
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("user_id = ?", userID).Order("last_used_at DESC").Find(&devices).Error
+	})
+	return devices, err
+}
+
+func (r *gormTrustedDeviceRepository) Touch(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+
+	result := db.Model(&models.TrustedDevice{}).Where("id = ?", id).Update("last_used_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormTrustedDeviceRepository) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+
+	result := db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.TrustedDevice{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormTrustedDeviceRepository) DeleteUnusedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	result := db.Where("last_used_at < ?", cutoff).Delete(&models.TrustedDevice{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// TrustedDeviceService remembers the devices users log in from with
+// remember_me. Logins presenting a remembered device identifier are reported
+// as coming from a trusted device.
+type TrustedDeviceService struct {
+	repo     TrustedDeviceRepository
+	sessions *SessionService
+	audit    *AuditService
+	logger   logger.Logger
+	clock    clock.Clock
+}
+
+// TrustedDeviceOption configures a TrustedDeviceService
+type TrustedDeviceOption func(s *TrustedDeviceService)
+
+// WithTrustedDeviceClock sets the clock that timestamps device use
+func WithTrustedDeviceClock(c clock.Clock) TrustedDeviceOption {
+	return func(s *TrustedDeviceService) {
+		s.clock = c
+	}
+}
+
+// NewTrustedDeviceService creates a new trusted device service. Revoking a
+// device ends its sessions through sessions, which may be nil.
+func NewTrustedDeviceService(repo TrustedDeviceRepository, sessions *SessionService, audit *AuditService, log logger.Logger, opts ...TrustedDeviceOption) *TrustedDeviceService {
+	s := &TrustedDeviceService{
+		repo:     repo,
+		sessions: sessions,
+		audit:    audit,
+		logger:   log,
+		clock:    clock.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Recognize returns the trusted device of userID that identifier belongs to
+// and records its use, or nil if the user never trusted it
+func (s *TrustedDeviceService) Recognize(ctx context.Context, userID uuid.UUID, identifier string) (*models.TrustedDevice, error) {
+	if identifier == "" {
+		return nil, nil
+	}
+
+	device, err := s.repo.Find(ctx, userID, hashDeviceIdentifier(identifier))
+	if errors.Is(err, ErrDeviceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	device.LastUsedAt = s.clock.Now()
+	if _, err := s.repo.Touch(ctx, device.ID, device.LastUsedAt); err != nil {
+		return nil, fmt.Errorf("failed to touch trusted device: %w", err)
+	}
+	return device, nil
+}
+
+// Trust remembers the client as a device of userID and returns it with the
+// identifier the client presents on later logins: client.DeviceID, or a
+// random one if the client did not supply any
+func (s *TrustedDeviceService) Trust(ctx context.Context, userID uuid.UUID, client ClientInfo) (*models.TrustedDevice, string, error) {
+	identifier := client.DeviceID
+	if identifier == "" {
+		var err error
+		if identifier, err = generateDeviceIdentifier(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	now := s.clock.Now()
+	device := &models.TrustedDevice{
+		ID:         uuid.New(),
+		UserID:     userID,
+		TokenHash:  hashDeviceIdentifier(identifier),
+		IPAddress:  client.IPAddress,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+	if err := s.repo.Create(ctx, device); err != nil {
+		return nil, "", fmt.Errorf("failed to trust device: %w", err)
+	}
+	return device, identifier, nil
+}
+
+// List returns the trusted devices of userID, most recently used first,
+// marking the one with ID currentID
+func (s *TrustedDeviceService) List(ctx context.Context, userID, currentID string) ([]*models.TrustedDevice, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	devices, err := s.repo.List(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	for _, device := range devices {
+		device.Current = device.ID.String() == currentID
+	}
+	return devices, nil
+}
+
+// Touch records that a remembered token of the device was refreshed. A
+// revoked or purged device returns ErrTokenRevoked.
+func (s *TrustedDeviceService) Touch(ctx context.Context, deviceID string) error {
+	id, err := uuid.Parse(deviceID)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	touched, err := s.repo.Touch(ctx, id, s.clock.Now())
+	if err != nil {
+		return fmt.Errorf("failed to touch trusted device: %w", err)
+	}
+	if !touched {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// Revoke forgets the trusted device of userID on behalf of actorID and ends
+// the sessions started from it, so its tokens can no longer be used or
+// refreshed
+func (s *TrustedDeviceService) Revoke(ctx context.Context, actorID, userID, deviceID string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrDeviceNotFound
+	}
+	id, err := uuid.Parse(deviceID)
+	if err != nil {
+		return ErrDeviceNotFound
+	}
+
+	deleted, err := s.repo.Delete(ctx, uid, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke trusted device: %w", err)
+	}
+	if !deleted {
+		return ErrDeviceNotFound
+	}
+
+	if s.sessions != nil {
+		if _, err := s.sessions.RevokeDevice(ctx, actorID, userID, id); err != nil {
+			return err
+		}
+	}
+
+	metadata := map[string]interface{}{"device_id": deviceID}
+	if err := s.audit.Record(ctx, actorID, models.AuditActionUserDeviceRevoked, "user", userID, metadata); err != nil {
+		s.logger.Warn("Failed to audit trusted device revocation", logger.Field{Key: "user_id", Value: userID}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	return nil
+}
+
+// PurgeUnusedBefore deletes trusted devices last used before cutoff
+func (s *TrustedDeviceService) PurgeUnusedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	purged, err := s.repo.DeleteUnusedBefore(ctx, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trusted devices: %w", err)
+	}
+	return purged, nil
+}
+
+// generateDeviceIdentifier returns a random hex-encoded device identifier
+func generateDeviceIdentifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate device identifier: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashDeviceIdentifier returns the form a device identifier is stored in
+func hashDeviceIdentifier(identifier string) string {
+	sum := sha256.Sum256([]byte(identifier))
+	return hex.EncodeToString(sum[:])
+}
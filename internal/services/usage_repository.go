@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsageRepository persists monthly API usage and the users' own quotas
+type UsageRepository interface {
+	// AddRequests adds count requests to the user's record of the period,
+	// creating it if needed
+	AddRequests(ctx context.Context, userID uuid.UUID, period string, count int64, at time.Time) error
+
+	// Requests returns the requests recorded for the user in the period,
+	// zero when there is no record
+	Requests(ctx context.Context, userID uuid.UUID, period string) (int64, error)
+
+	// ListPeriod returns every record of the period, busiest users first
+	ListPeriod(ctx context.Context, period string) ([]*models.UsageRecord, error)
+
+	// UserQuota returns the user's own quota, nil when the default applies,
+	// or ErrUserNotFound
+	UserQuota(ctx context.Context, userID uuid.UUID) (*int64, error)
+
+	// SetUserQuota sets the user's own quota; nil restores the default
+	SetUserQuota(ctx context.Context, userID uuid.UUID, quota *int64) error
+}
+
+// gormUsageRepository implements UsageRepository on the database each call is scoped to
+type gormUsageRepository struct {
+	db *database.Manager
+}
+
+// NewUsageRepository creates a repository backed by the database each call is scoped to
+func NewUsageRepository(db *database.Manager) UsageRepository {
+	return &gormUsageRepository{db: db}
+}
+
+// AddRequests is an increment, so it is never retried
+func (r *gormUsageRepository) AddRequests(ctx context.Context, userID uuid.UUID, period string, count int64, at time.Time) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	record := &models.UsageRecord{ID: uuid.New(), UserID: userID, Period: period, Requests: count, UpdatedAt: at}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "period"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"requests":   gorm.Expr("usage_records.requests + ?", count),
+			"updated_at": at,
+		}),
+	}).Create(record).Error
+}
+
+func (r *gormUsageRepository) Requests(ctx context.Context, userID uuid.UUID, period string) (int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	var record models.UsageRecord
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("user_id = ? AND period = ?", userID, period).First(&record).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return record.Requests, err
+}
+
+func (r *gormUsageRepository) ListPeriod(ctx context.Context, period string) ([]*models.UsageRecord, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*models.UsageRecord
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("period = ?", period).Order("requests DESC, user_id").Find(&records).Error
+	})
+	return records, err
+}
+
+func (r *gormUsageRepository) UserQuota(ctx context.Context, userID uuid.UUID) (*int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Select("id", "api_quota").Where("id = ?", userID).First(&user).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user.APIQuota, nil
+}
+
+// SetUserQuota leaves the same state however often it runs, so it is retried
+func (r *gormUsageRepository) SetUserQuota(ctx context.Context, userID uuid.UUID, quota *int64) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	var result *gorm.DB
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		result = db.Model(&models.User{}).Where("id = ?", userID).Update("api_quota", quota)
+		return result.Error
+	})
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/emailaddr"
+
+	"gorm.io/gorm"
+)
+
+// EmailNormalization returns the email normalization cfg selects
+func EmailNormalization(cfg config.AuthConfig) emailaddr.Normalization {
+	return emailaddr.Normalization{
+		PreserveLocalCase: cfg.EmailPreserveLocalCase,
+		Gmail:             cfg.EmailGmailNormalization,
+	}
+}
+
+// EmailDuplicate is a normalized email address shared by several users
+type EmailDuplicate struct {
+	Email string
+	// Users hold the ID and the email address as stored, oldest first
+	Users []*models.User
+}
+
+// EmailDuplicates lists the email addresses several users share once
+// normalized, ordered by address. They have to be resolved before
+// NormalizeEmails can run.
+func (s *UserService) EmailDuplicates(ctx context.Context) ([]EmailDuplicate, error) {
+	users, err := s.loadEmails(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.emailDuplicates(users), nil
+}
+
+// NormalizeEmails rewrites every stored email address to its normalized form
+// and returns how many changed. Nothing is written if the normalized
+// addresses collide; ErrEmailDuplicates is returned instead.
+func (s *UserService) NormalizeEmails(ctx context.Context) (int, error) {
+	users, err := s.loadEmails(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if duplicates := s.emailDuplicates(users); len(duplicates) > 0 {
+		return 0, fmt.Errorf("%w: %d addresses", ErrEmailDuplicates, len(duplicates))
+	}
+
+	db, err := s.gormDB(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var changed []*models.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, user := range users {
+			normalized := s.emails.Normalize(user.Email)
+			if normalized == user.Email {
+				continue
+			}
+			if err := tx.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("email", normalized).Error; err != nil {
+				return fmt.Errorf("failed to update user: %w", err)
+			}
+			changed = append(changed, user)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, user := range changed {
+		s.invalidateUserCache(ctx, user)
+	}
+	return len(changed), nil
+}
+
+// loadEmails loads the ID and email address of every user, oldest first
+func (s *UserService) loadEmails(ctx context.Context) ([]*models.User, error) {
+	db, err := s.gormDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var users []*models.User
+	if err := db.Select("id", "email", "created_at").Order("created_at, id").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return users, nil
+}
+
+// emailDuplicates groups users whose addresses normalize to the same one
+func (s *UserService) emailDuplicates(users []*models.User) []EmailDuplicate {
+	groups := make(map[string][]*models.User)
+	for _, user := range users {
+		normalized := s.emails.Normalize(user.Email)
+		groups[normalized] = append(groups[normalized], user)
+	}
+
+	var duplicates []EmailDuplicate
+	for email, group := range groups {
+		if len(group) > 1 {
+			duplicates = append(duplicates, EmailDuplicate{Email: email, Users: group})
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i].Email < duplicates[j].Email })
+	return duplicates
+}
+
+// gormDB opens the tenant's database, or the primary one, with GORM
+func (s *UserService) gormDB(ctx context.Context) (*gorm.DB, error) {
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	return db.WithContext(ctx), nil
+}
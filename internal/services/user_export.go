@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/infrastructure/storage"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/export"
+
+	"gorm.io/gorm"
+)
+
+// DefaultUserExportBatchSize is how many users Export reads per query
+// when no batch size is given
+const DefaultUserExportBatchSize = 1000
+
+// userExportColumns are the columns a user export may include, in their
+// default order, with how each reads its value from a user
+var userExportColumns = []struct {
+	export.Column
+	value func(u *models.User) interface{}
+}{
+	{export.Column{Name: "id", Kind: export.KindString}, func(u *models.User) interface{} { return u.ID.String() }},
+	{export.Column{Name: "email", Kind: export.KindString}, func(u *models.User) interface{} { return u.Email }},
+	{export.Column{Name: "username", Kind: export.KindString}, func(u *models.User) interface{} { return u.Username }},
+	{export.Column{Name: "first_name", Kind: export.KindString}, func(u *models.User) interface{} { return u.FirstName }},
+	{export.Column{Name: "last_name", Kind: export.KindString}, func(u *models.User) interface{} { return u.LastName }},
+	{export.Column{Name: "role", Kind: export.KindString}, func(u *models.User) interface{} { return string(u.Role) }},
+	{export.Column{Name: "active", Kind: export.KindBool}, func(u *models.User) interface{} { return u.Active }},
+	{export.Column{Name: "created_at", Kind: export.KindTime}, func(u *models.User) interface{} { return u.CreatedAt }},
+}
+
+// userExportSorts are the orders a user export accepts, each with the value
+// of a user that keyset pagination continues after
+var userExportSorts = map[string]func(u *models.User) interface{}{
+	"id":         func(u *models.User) interface{} { return u.ID.String() },
+	"email":      func(u *models.User) interface{} { return u.Email },
+	"username":   func(u *models.User) interface{} { return u.Username },
+	"created_at": func(u *models.User) interface{} { return u.CreatedAt },
+}
+
+// UserExportColumns returns the names of the columns a user export may
+// include, in their default order
+func UserExportColumns() []string {
+	names := make([]string, len(userExportColumns))
+	for i, column := range userExportColumns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// UserExportSorts returns the fields a user export may be sorted by
+func UserExportSorts() []string {
+	names := make([]string, 0, len(userExportSorts))
+	for name := range userExportSorts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UserExportOptions selects the users, columns and format of an export. The
+// zero value exports every column of every user as CSV, ordered by ID.
+type UserExportOptions struct {
+	Format export.Format
+
+	// Columns lists the columns to include, in order; empty means all
+	Columns []string
+
+	// Active, when set, only exports users with that active flag
+	Active *bool
+
+	// Sort is a field of UserExportSorts, prefixed with "-" to sort descending
+	Sort string
+}
+
+// Validate checks the columns and sort against the allowed ones
+func (o UserExportOptions) Validate() error {
+	for _, name := range o.Columns {
+		if !slices.Contains(UserExportColumns(), name) {
+			return fmt.Errorf("%w: %s", ErrInvalidExportColumn, name)
+		}
+	}
+	if field := strings.TrimPrefix(o.Sort, "-"); o.Sort != "" && userExportSorts[field] == nil {
+		return fmt.Errorf("%w: %s", ErrInvalidExportSort, o.Sort)
+	}
+	return nil
+}
+
+// Export writes every user that is neither deleted nor anonymized and
+// matches opts to w, reading batchSize users at a time. progress, if set, is
+// called with the percentage written after each batch. It stops when ctx is
+// cancelled.
+func (s *UserService) Export(ctx context.Context, w io.Writer, opts UserExportOptions, batchSize int, progress func(percent int)) error {
+	format := opts.Format
+	if format == "" {
+		format = export.FormatCSV
+	}
+	source, err := s.NewUserExportSource(ctx, opts, batchSize, progress)
+	if err != nil {
+		return err
+	}
+	return export.Write(ctx, w, format, source)
+}
+
+// NewUserExportSource returns the rows of a user export; see Export
+func (s *UserService) NewUserExportSource(ctx context.Context, opts UserExportOptions, batchSize int, progress func(percent int)) (export.RowSource, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultUserExportBatchSize
+	}
+
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	query := db.WithContext(ctx).Model(&models.User{}).Where("deleted_at IS NULL AND anonymized_at IS NULL")
+	if opts.Active != nil {
+		query = query.Where("active = ?", *opts.Active)
+	}
+
+	source := &userExportSource{
+		query:     query,
+		batchSize: batchSize,
+		sort:      "id",
+		progress:  progress,
+	}
+	if opts.Sort != "" {
+		source.sort = strings.TrimPrefix(opts.Sort, "-")
+		source.descending = strings.HasPrefix(opts.Sort, "-")
+	}
+
+	names := opts.Columns
+	if len(names) == 0 {
+		names = UserExportColumns()
+	}
+	for _, name := range names {
+		for _, column := range userExportColumns {
+			if column.Name == name {
+				source.columns = append(source.columns, column.Column)
+				source.values = append(source.values, column.value)
+			}
+		}
+	}
+
+	if progress != nil {
+		if err := query.Session(&gorm.Session{}).Count(&source.total).Error; err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+	}
+	return source, nil
+}
+
+// userExportSource reads users in batches for an export. It pages by the
+// sort field and then ID rather than by offset, so rows added meanwhile do
+// not shift batches.
+type userExportSource struct {
+	query      *gorm.DB
+	batchSize  int
+	sort       string
+	descending bool
+	columns    []export.Column
+	values     []func(u *models.User) interface{}
+
+	batch []*models.User
+	next  int
+	last  *models.User
+	done  bool
+
+	total    int64
+	written  int64
+	progress func(percent int)
+}
+
+// Columns returns the selected columns
+func (s *userExportSource) Columns() []export.Column {
+	return s.columns
+}
+
+// Next returns the next user's row, reading the next batch when the current
+// one is used up
+func (s *userExportSource) Next(ctx context.Context) ([]interface{}, error) {
+	for s.next >= len(s.batch) {
+		if n := len(s.batch); n > 0 {
+			s.written += int64(n)
+			if s.progress != nil && s.total > 0 {
+				s.progress(int(min(s.written, s.total) * 100 / s.total))
+			}
+			s.last, s.batch = s.batch[n-1], nil
+		}
+		if s.done {
+			return nil, io.EOF
+		}
+		if err := s.read(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	user := s.batch[s.next]
+	s.next++
+	row := make([]interface{}, len(s.values))
+	for i, value := range s.values {
+		row[i] = value(user)
+	}
+	return row, nil
+}
+
+// read fetches the batch after the last user read
+func (s *userExportSource) read(ctx context.Context) error {
+	direction, cmp := "ASC", ">"
+	if s.descending {
+		direction, cmp = "DESC", "<"
+	}
+
+	query := s.query.Session(&gorm.Session{}).WithContext(ctx)
+	if last := s.last; last != nil {
+		if s.sort == "id" {
+			query = query.Where("id "+cmp+" ?", last.ID.String())
+		} else {
+			value := userExportSorts[s.sort](last)
+			query = query.Where("("+s.sort+" "+cmp+" ? OR ("+s.sort+" = ? AND id "+cmp+" ?))", value, value, last.ID.String())
+		}
+	}
+	if s.sort != "id" {
+		query = query.Order(s.sort + " " + direction)
+	}
+
+	var batch []*models.User
+	if err := query.Order("id " + direction).Limit(s.batchSize).Find(&batch).Error; err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	s.batch, s.next = batch, 0
+	s.done = len(batch) < s.batchSize
+	return nil
+}
+
+// UserExportTask returns a task that exports the users matching opts to store
+func UserExportTask(users *UserService, store storage.Store, opts UserExportOptions, batchSize int) TaskFunc {
+	if opts.Format == "" {
+		opts.Format = export.FormatCSV
+	}
+	return func(ctx context.Context, run *TaskRun) (string, error) {
+		key := "exports/users-" + run.ID.String() + "." + opts.Format.Extension()
+
+		// Stream into the store while the export is written
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(users.Export(ctx, pw, opts, batchSize, run.Progress))
+		}()
+
+		if err := store.Put(ctx, key, pr); err != nil {
+			pr.CloseWithError(err)
+			return "", fmt.Errorf("failed to export users: %w", err)
+		}
+		return key, nil
+	}
+}
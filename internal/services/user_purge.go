@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// DefaultUserPurgeBatchSize is how many users PurgeDeleted removes per
+// transaction when UserPurgeOptions.BatchSize is not set
+const DefaultUserPurgeBatchSize = 100
+
+// UserPurgeOptions controls a purge of soft-deleted users
+type UserPurgeOptions struct {
+	// BatchSize is the number of users removed per transaction
+	BatchSize int
+
+	// Limit caps the users removed per run; zero removes every eligible user
+	Limit int
+
+	// DryRun only counts and logs what would be removed
+	DryRun bool
+}
+
+// UserPurgeResult counts what a purge removed, or would remove in a dry run
+type UserPurgeResult struct {
+	Users         int64 `json:"users"`
+	LoginEvents   int64 `json:"login_events"`
+	Notifications int64 `json:"notifications"`
+	Memberships   int64 `json:"memberships"`
+
+	// AuditEntries are audit logs the users acted in; they are kept with the
+	// actor cleared, so the change stays on record without naming anyone
+	AuditEntries int64 `json:"audit_entries"`
+
+	DryRun bool `json:"dry_run"`
+}
+
+// PurgeDeleted permanently removes users soft-deleted before cutoff with
+// their login events, notifications and organization memberships. Audit
+// entries they made are kept but no longer point at them. Each batch is
+// removed in one transaction; the tokens of removed users are revoked.
+func (s *UserService) PurgeDeleted(ctx context.Context, cutoff time.Time, opts UserPurgeOptions) (*UserPurgeResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultUserPurgeBatchSize
+	}
+
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db = db.WithContext(ctx)
+
+	result := &UserPurgeResult{DryRun: opts.DryRun}
+	if opts.DryRun {
+		ids, err := purgeCandidates(db, cutoff, opts.Limit)
+		if err != nil {
+			return nil, err
+		}
+		if err := countPurge(db, ids, result); err != nil {
+			return nil, err
+		}
+		if len(ids) > 0 {
+			s.log(ctx).Info("Users that would be purged", logger.Field{Key: "user_ids", Value: ids})
+		}
+		return result, nil
+	}
+
+	for opts.Limit <= 0 || int(result.Users) < opts.Limit {
+		size := opts.BatchSize
+		if opts.Limit > 0 {
+			size = min(size, opts.Limit-int(result.Users))
+		}
+		ids, err := purgeCandidates(db, cutoff, size)
+		if err != nil {
+			return result, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return purgeUsers(tx, ids, result)
+		}); err != nil {
+			return result, fmt.Errorf("failed to purge users: %w", err)
+		}
+
+		for _, id := range ids {
+			if s.revoker != nil {
+				if err := s.revoker.RevokeUserTokens(ctx, id); err != nil {
+					s.log(ctx).Warn("Failed to revoke tokens of purged user", logger.Field{Key: "user_id", Value: id}, logger.Field{Key: "error", Value: err.Error()})
+				}
+			}
+			_ = s.cache.Delete(ctx, userCacheKeyByID(id))
+			_ = s.cache.Delete(ctx, userActiveCacheKey(id))
+		}
+		s.responses.Purge(ctx, ResponseGroupUsers)
+	}
+	return result, nil
+}
+
+// PurgeCandidates returns the IDs of up to limit users PurgeDeleted would
+// remove with cutoff, longest deleted first
+func (s *UserService) PurgeCandidates(ctx context.Context, cutoff time.Time, limit int) ([]string, error) {
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	return purgeCandidates(db.WithContext(ctx), cutoff, limit)
+}
+
+// purgeCandidates returns up to limit users soft-deleted before cutoff,
+// longest deleted first; a limit of zero returns all of them
+func purgeCandidates(db *gorm.DB, cutoff time.Time, limit int) ([]string, error) {
+	query := db.Model(&models.User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("deleted_at, id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var ids []string
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return ids, nil
+}
+
+// countPurge adds the rows purging ids would touch to result
+func countPurge(db *gorm.DB, ids []string, result *UserPurgeResult) error {
+	result.Users += int64(len(ids))
+	if len(ids) == 0 {
+		return nil
+	}
+
+	for _, count := range []struct {
+		model interface{}
+		where string
+		n     *int64
+	}{
+		{&models.LoginEvent{}, "user_id IN ?", &result.LoginEvents},
+		{&models.Notification{}, "user_id IN ?", &result.Notifications},
+		{&models.OrganizationMember{}, "user_id IN ?", &result.Memberships},
+		{&models.AuditLog{}, "actor_id IN ?", &result.AuditEntries},
+	} {
+		var n int64
+		if err := db.Model(count.model).Where(count.where, ids).Count(&n).Error; err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		*count.n += n
+	}
+	return nil
+}
+
+// purgeUsers removes ids and their related rows, adding the counts to result
+func purgeUsers(tx *gorm.DB, ids []string, result *UserPurgeResult) error {
+	for _, del := range []struct {
+		model interface{}
+		n     *int64
+	}{
+		{&models.LoginEvent{}, &result.LoginEvents},
+		{&models.Notification{}, &result.Notifications},
+		{&models.OrganizationMember{}, &result.Memberships},
+	} {
+		res := tx.Where("user_id IN ?", ids).Delete(del.model)
+		if res.Error != nil {
+			return res.Error
+		}
+		*del.n += res.RowsAffected
+	}
+
+	res := tx.Model(&models.AuditLog{}).Where("actor_id IN ?", ids).Update("actor_id", nil)
+	if res.Error != nil {
+		return res.Error
+	}
+	result.AuditEntries += res.RowsAffected
+
+	res = tx.Where("id IN ?", ids).Delete(&models.User{})
+	if res.Error != nil {
+		return res.Error
+	}
+	result.Users += res.RowsAffected
+	return nil
+}
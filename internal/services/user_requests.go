@@ -0,0 +1,144 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/validator"
+)
+
+// CreateUserRequest holds the fields of a new user. Email is required; an
+// empty password creates a user that cannot log in until one is set.
+type CreateUserRequest struct {
+	Email     string `json:"email"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+
+	// Role defaults to models.RoleUser. It is never bound from a request
+	// body; only in-process callers such as the CLI set it.
+	Role models.UserRole `json:"-"`
+}
+
+// UpdateUserRequest represents a partial user update.
+// A nil field means "leave unchanged"; a non-nil field is applied as-is,
+// so an empty string clears the value. The email is not among the fields;
+// it only changes through the confirmed EmailChangeService flow, and the
+// active flag only through UserService.SetActive, which guards it.
+type UpdateUserRequest struct {
+	Username  *string `json:"username"`
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+	Password  *string `json:"password"`
+}
+
+// updateUserFields lists the JSON keys accepted by UpdateUserRequest
+var updateUserFields = map[string]bool{
+	"username":   true,
+	"first_name": true,
+	"last_name":  true,
+	"password":   true,
+}
+
+// updateProfileFields lists the JSON keys users may change on their own
+// profile through PUT /me
+var updateProfileFields = map[string]bool{
+	"username":   true,
+	"first_name": true,
+	"last_name":  true,
+}
+
+// UnknownFieldsError is returned when a request contains unrecognised keys
+type UnknownFieldsError struct {
+	Fields []string
+}
+
+// Error implements the error interface
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("unknown fields: %s", strings.Join(e.Fields, ", "))
+}
+
+// ParseUpdateUserRequest decodes a raw JSON body into an UpdateUserRequest,
+// rejecting unknown keys and validating every provided value
+func ParseUpdateUserRequest(body []byte) (*UpdateUserRequest, error) {
+	return parseUpdateRequest(body, updateUserFields)
+}
+
+// ParseUpdateProfileRequest decodes the body of PUT /me like
+// ParseUpdateUserRequest, accepting only the profile fields
+func ParseUpdateProfileRequest(body []byte) (*UpdateUserRequest, error) {
+	return parseUpdateRequest(body, updateProfileFields)
+}
+
+// parseUpdateRequest decodes body, rejecting keys outside fields
+func parseUpdateRequest(body []byte, fields map[string]bool) (*UpdateUserRequest, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	var unknown []string
+	for key := range raw {
+		if !fields[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, &UnknownFieldsError{Fields: unknown}
+	}
+
+	// JSON null decodes to a nil pointer and is treated like an omitted key
+	var req UpdateUserRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("invalid field value: %w", err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+// Validate validates every provided field. Failures are reported as
+// validator.Errors.
+func (r *UpdateUserRequest) Validate() error {
+	var failed validator.Errors
+	if r.Password != nil {
+		failed = append(failed, validator.Var("password", *r.Password, "required,min=6")...)
+	}
+
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// IsEmpty reports whether the request changes nothing
+func (r *UpdateUserRequest) IsEmpty() bool {
+	return len(r.Changes()) == 0
+}
+
+// Changes returns the provided fields keyed by column name.
+// The password is returned as given; callers must hash it before persisting.
+func (r *UpdateUserRequest) Changes() map[string]interface{} {
+	changes := make(map[string]interface{})
+	if r.Username != nil {
+		changes["username"] = *r.Username
+	}
+	if r.FirstName != nil {
+		changes["first_name"] = *r.FirstName
+	}
+	if r.LastName != nil {
+		changes["last_name"] = *r.LastName
+	}
+	if r.Password != nil {
+		changes["password"] = *r.Password
+	}
+	return changes
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// AnyRole matches every role in a RoleTransition
+const AnyRole models.UserRole = "*"
+
+// RoleTransition is a change from one role to another; either may be AnyRole
+type RoleTransition struct {
+	From models.UserRole
+	To   models.UserRole
+}
+
+// Matches reports whether changing from one role to another is the transition
+func (t RoleTransition) Matches(from, to models.UserRole) bool {
+	return (t.From == AnyRole || t.From == from) && (t.To == AnyRole || t.To == to)
+}
+
+// ParseRoleTransitions parses transitions written as from->to, such as
+// "*->admin" or "guest->user"
+func ParseRoleTransitions(specs []string) ([]RoleTransition, error) {
+	transitions := make([]RoleTransition, 0, len(specs))
+	for _, spec := range specs {
+		from, to, ok := strings.Cut(spec, "->")
+		t := RoleTransition{From: models.UserRole(strings.TrimSpace(from)), To: models.UserRole(strings.TrimSpace(to))}
+		if !ok || !(t.From == AnyRole || t.From.Valid()) || !(t.To == AnyRole || t.To.Valid()) {
+			return nil, fmt.Errorf("invalid role transition %q: want from->to with admin, user, guest or *", spec)
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, nil
+}
+
+// WithRoleApprovals holds back the role changes matching protected until an
+// admin other than the requester approves them through approvals
+func WithRoleApprovals(approvals *ApprovalService, protected []RoleTransition) UserOption {
+	return func(s *UserService) {
+		s.approvals, s.protectedRoles = approvals, protected
+		approvals.Handle(models.ApprovalActionRoleChange, roleChangeHandler{users: s})
+	}
+}
+
+// ChangeRole gives a user role on behalf of actorID. Protected transitions
+// are not applied but return the pending approval that will apply them;
+// others are applied at once. Either way the last active admin keeps the
+// admin role.
+func (s *UserService) ChangeRole(ctx context.Context, id string, role models.UserRole, actorID string) (*models.User, *models.Approval, error) {
+	if !role.Valid() {
+		return nil, nil, ErrInvalidRole
+	}
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Role == role {
+		return user, nil, nil
+	}
+	if user.Role == models.RoleAdmin && user.Active {
+		admins, err := s.countActiveAdmins(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if admins <= 1 {
+			return nil, nil, ErrLastAdminDemotion
+		}
+	}
+
+	change := models.RoleChange{From: user.Role, To: role}
+	if s.protectsRoleChange(change) {
+		approval, err := s.approvals.Request(ctx, models.ApprovalActionRoleChange, user.ID, change, actorID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return user, approval, nil
+	}
+
+	db, err := s.gormDB(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := setRole(db, user.ID.String(), change, s.clock.Now()); err != nil {
+		return nil, nil, err
+	}
+	s.auditUser(ctx, actorID, models.AuditActionUserRoleChanged, user, change)
+	user.Role = role
+	s.roleChanged(ctx, user, change)
+	return user, nil, nil
+}
+
+// protectsRoleChange reports whether change needs a second admin's approval
+func (s *UserService) protectsRoleChange(change models.RoleChange) bool {
+	if s.approvals == nil {
+		return false
+	}
+	for _, t := range s.protectedRoles {
+		if t.Matches(change.From, change.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// setRole moves a user from change.From to change.To at now, returning
+// ErrApprovalStale if the user no longer has change.From
+func setRole(db *gorm.DB, userID string, change models.RoleChange, now time.Time) error {
+	result := db.Model(&models.User{}).Where("id = ? AND role = ?", userID, change.From).
+		Updates(map[string]interface{}{"role": change.To, "updated_at": now})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrApprovalStale
+	}
+	return nil
+}
+
+// roleChanged revokes the user's tokens, which carry the old role, and
+// drops the cached user once a role change has committed
+func (s *UserService) roleChanged(ctx context.Context, user *models.User, change models.RoleChange) {
+	if err := s.revoker.RevokeUserTokens(ctx, user.ID.String()); err != nil {
+		s.log(ctx).Warn("Failed to revoke tokens after a role change", logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	s.invalidateUserCache(ctx, user)
+	s.log(ctx).Info("User role changed",
+		logger.Field{Key: "user_id", Value: user.ID.String()},
+		logger.Field{Key: "from", Value: string(change.From)},
+		logger.Field{Key: "to", Value: string(change.To)},
+	)
+	s.publish(ctx, events.UserUpdated, user)
+}
+
+// roleChangeHandler applies approved role changes
+type roleChangeHandler struct {
+	users *UserService
+}
+
+func (h roleChangeHandler) Apply(ctx context.Context, tx *ApprovalTx, approval *models.Approval) error {
+	var change models.RoleChange
+	if err := json.Unmarshal(approval.Payload, &change); err != nil {
+		return fmt.Errorf("invalid role change payload: %w", err)
+	}
+	// Admins may have been demoted since the change was requested, so the
+	// last active admin is checked again where the change commits
+	if change.From == models.RoleAdmin {
+		var others int64
+		if err := tx.DB.Model(&models.User{}).
+			Where("role = ? AND active = ? AND id <> ?", models.RoleAdmin, true, approval.SubjectID).
+			Count(&others).Error; err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		if others == 0 {
+			return ErrLastAdminDemotion
+		}
+	}
+	if err := setRole(tx.DB, approval.SubjectID.String(), change, h.users.clock.Now()); err != nil {
+		return err
+	}
+	return tx.Audit(approval.DeciderID.String(), models.AuditActionUserRoleChanged, "user", approval.SubjectID.String(), map[string]interface{}{
+		"from":         change.From,
+		"to":           change.To,
+		"approval_id":  approval.ID.String(),
+		"requester_id": approval.RequesterID.String(),
+		"approver_id":  approval.DeciderID.String(),
+	})
+}
+
+func (h roleChangeHandler) Applied(ctx context.Context, approval *models.Approval) {
+	var change models.RoleChange
+	_ = json.Unmarshal(approval.Payload, &change)
+	user, err := h.users.GetUser(ctx, approval.SubjectID.String())
+	if err != nil {
+		return
+	}
+	// The user may have been cached with the old role
+	user.Role = change.To
+	h.users.roleChanged(ctx, user, change)
+}
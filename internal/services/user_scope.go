@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/requestctx"
+)
+
+// Principal is the authenticated caller users are looked up for
+type Principal struct {
+	ID       string
+	Role     models.UserRole
+	TenantID string
+	// Permissions are those granted to Role
+	Permissions []string
+}
+
+// Can reports whether the principal was granted permission
+func (p Principal) Can(permission string) bool {
+	return slices.Contains(p.Permissions, permission)
+}
+
+// UserFilter is a condition users must meet to be seen. Where is SQL on the
+// users table with "?" placeholders for Args; Match applies the same
+// condition to a user already loaded, such as one served from the cache.
+type UserFilter struct {
+	Where string
+	Args  []interface{}
+	Match func(user *models.User) bool
+}
+
+// ScopeFunc returns the filter principal is held to, reporting false when
+// it does not restrict the principal. Scope functions only depend on the
+// role, its permissions and the tenant, which the response cache keys user
+// listings by.
+type ScopeFunc func(principal Principal) (UserFilter, bool)
+
+// HideAdminsWithout hides admin users from principals lacking permission,
+// so support agents only see the users they support
+func HideAdminsWithout(permission string) ScopeFunc {
+	return func(p Principal) (UserFilter, bool) {
+		if p.Can(permission) {
+			return UserFilter{}, false
+		}
+		return UserFilter{
+			Where: "role <> ?",
+			Args:  []interface{}{models.RoleAdmin},
+			Match: func(user *models.User) bool {
+				return user.Role != models.RoleAdmin
+			},
+		}, true
+	}
+}
+
+// OwnTenant limits principals acting for a tenant to the tenant's users.
+// Users created before users were tagged with their tenant belong to
+// whichever tenant owns their database, so they stay visible.
+func OwnTenant() ScopeFunc {
+	return func(p Principal) (UserFilter, bool) {
+		if p.TenantID == "" {
+			return UserFilter{}, false
+		}
+		return UserFilter{
+			Where: "tenant_id = ? OR tenant_id IS NULL",
+			Args:  []interface{}{p.TenantID},
+			Match: func(user *models.User) bool {
+				return user.TenantID == nil || *user.TenantID == p.TenantID
+			},
+		}, true
+	}
+}
+
+// DefaultUserScopes are the scopes of the user API: only those who may
+// manage users see admins, and tenants see their own users
+var DefaultUserScopes = []ScopeFunc{
+	HideAdminsWithout(models.PermissionUsersManage),
+	OwnTenant(),
+}
+
+// UserScope is the filters a principal is held to, all of which a user
+// must meet. The nil scope sees every user.
+type UserScope []UserFilter
+
+// ScopeFor composes the filters scopes hold principal to. Admins acting
+// outside any tenant bypass every scope.
+func ScopeFor(principal Principal, scopes ...ScopeFunc) UserScope {
+	if principal.Role == models.RoleAdmin && principal.TenantID == "" {
+		return nil
+	}
+	var scope UserScope
+	for _, fn := range scopes {
+		if filter, ok := fn(principal); ok {
+			scope = append(scope, filter)
+		}
+	}
+	return scope
+}
+
+// Where joins the filters into one SQL condition, or returns "" for the
+// nil scope
+func (s UserScope) Where() (string, []interface{}) {
+	if len(s) == 0 {
+		return "", nil
+	}
+	conditions := make([]string, len(s))
+	var args []interface{}
+	for i, filter := range s {
+		conditions[i] = "(" + filter.Where + ")"
+		args = append(args, filter.Args...)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// Allows reports whether user meets every filter
+func (s UserScope) Allows(user *models.User) bool {
+	for _, filter := range s {
+		if !filter.Match(user) {
+			return false
+		}
+	}
+	return true
+}
+
+// RolePermissionLookup resolves the permissions granted to a role
+type RolePermissionLookup interface {
+	RolePermissions(ctx context.Context, role models.UserRole) ([]string, error)
+}
+
+// WithUserScopes holds the callers of GetUser, ListUsers and SearchUsers
+// to scopes, resolving their permissions with permissions. Calls made
+// outside an authenticated request are not scoped.
+func WithUserScopes(permissions RolePermissionLookup, scopes ...ScopeFunc) UserOption {
+	return func(s *UserService) {
+		s.permissions = permissions
+		s.scopes = scopes
+	}
+}
+
+// scope returns the scope of the authenticated caller of ctx
+func (s *UserService) scope(ctx context.Context) (UserScope, error) {
+	user, ok := requestctx.UserFrom(ctx)
+	if len(s.scopes) == 0 || !ok {
+		return nil, nil
+	}
+
+	principal := Principal{
+		ID:       user.ID,
+		Role:     models.UserRole(user.Role),
+		TenantID: requestctx.TenantFrom(ctx),
+	}
+	if principal.Role.Valid() {
+		permissions, err := s.permissions.RolePermissions(ctx, principal.Role)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the caller's permissions: %w", err)
+		}
+		principal.Permissions = permissions
+	}
+	return ScopeFor(principal, s.scopes...), nil
+}
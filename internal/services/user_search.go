@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/apimodel"
+	"BackofficeGoService/internal/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// SearchUsersFilter describes a user search
+type SearchUsersFilter struct {
+	// Query is free text matched against email, username and names
+	Query string
+
+	// Active, when set, only matches users with that active flag
+	Active *bool
+
+	// IncludeAnonymized includes anonymized users, which are hidden by default
+	IncludeAnonymized bool
+}
+
+// UserSearchResult is a matched user with its relevance; higher scores rank first
+type UserSearchResult struct {
+	*models.User
+	Score float64 `json:"score"`
+}
+
+// MarshalJSON implements json.Marshaler; the embedded user's own would
+// otherwise drop Score
+func (r UserSearchResult) MarshalJSON() ([]byte, error) { return apimodel.Marshal(r) }
+
+// userSearchRow is what a search query scans into
+type userSearchRow struct {
+	models.User `gorm:"embedded"`
+	Score       float64
+}
+
+// SearchUsers finds users matching filter.Query, most relevant first. PostgreSQL
+// uses the users.search_vector full-text index; other drivers fall back to
+// case-insensitive substring matching.
+func (s *UserService) SearchUsers(ctx context.Context, filter SearchUsersFilter, limit, offset int) (*ListResult[*UserSearchResult], error) {
+	terms := searchTerms(filter.Query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+
+	scope, err := s.scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.WithContext(ctx).Model(&models.User{})
+	if scoped, args := scope.Where(); scoped != "" {
+		query = query.Where(scoped, args...)
+	}
+	query = query.Where("deleted_at IS NULL")
+	if !filter.IncludeAnonymized {
+		query = query.Where("anonymized_at IS NULL")
+	}
+	if filter.Active != nil {
+		query = query.Where("active = ?", *filter.Active)
+	}
+
+	var (
+		score     string
+		scoreArgs []interface{}
+	)
+	if driver.Type() == database.DriverPostgreSQL {
+		query, score, scoreArgs = fullTextUserSearch(query, terms)
+	} else {
+		query, score, scoreArgs = likeUserSearch(query, terms)
+	}
+
+	result := &ListResult[*UserSearchResult]{Items: []*UserSearchResult{}}
+	if err := query.Count(&result.Total).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	var rows []userSearchRow
+	if err := query.Select("users.*, "+score+" AS score", scoreArgs...).
+		Order("score DESC, created_at DESC").Limit(limit).Offset(offset).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	for i := range rows {
+		rows[i].Password = ""
+		result.Items = append(result.Items, &UserSearchResult{User: &rows[i].User, Score: rows[i].Score})
+	}
+	return result, nil
+}
+
+// searchTerms lowercases q and splits it into letter and digit runs, which
+// also keeps tsquery operators and LIKE wildcards out of the queries
+func searchTerms(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// fullTextUserSearch matches every term as a prefix against the search vector.
+// It returns the narrowed query and a ts_rank score expression with its arguments.
+func fullTextUserSearch(query *gorm.DB, terms []string) (*gorm.DB, string, []interface{}) {
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	tsquery := strings.Join(prefixes, " & ")
+
+	query = query.Where("search_vector @@ to_tsquery('simple', ?)", tsquery)
+	return query, "ts_rank(search_vector, to_tsquery('simple', ?))", []interface{}{tsquery}
+}
+
+// userSearchColumns are the columns the substring fallback searches
+var userSearchColumns = []string{"first_name", "last_name", "username", "email"}
+
+// likeUserSearch requires every term to appear in some column. Each term adds
+// 1 to the score when a column starts with it and 0.5 when it only appears
+// inside one; the total is divided by the number of terms.
+func likeUserSearch(query *gorm.DB, terms []string) (*gorm.DB, string, []interface{}) {
+	var (
+		scores    []string
+		scoreArgs []interface{}
+	)
+	for _, term := range terms {
+		contains := make([]interface{}, len(userSearchColumns))
+		prefix := make([]interface{}, len(userSearchColumns))
+		for i := range userSearchColumns {
+			contains[i] = "%" + term + "%"
+			prefix[i] = term + "%"
+		}
+
+		query = query.Where(anyColumnLike(), contains...)
+		scores = append(scores, "CASE WHEN "+anyColumnLike()+" THEN 1.0 ELSE 0.5 END")
+		scoreArgs = append(scoreArgs, prefix...)
+	}
+
+	score := fmt.Sprintf("(%s) / %d.0", strings.Join(scores, " + "), len(terms))
+	return query, score, scoreArgs
+}
+
+// anyColumnLike matches one LIKE pattern per search column
+func anyColumnLike() string {
+	likes := make([]string, len(userSearchColumns))
+	for i, column := range userSearchColumns {
+		likes[i] = "LOWER(" + column + ") LIKE ?"
+	}
+	return "(" + strings.Join(likes, " OR ") + ")"
+}
@@ -3,69 +3,244 @@ package services
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/apimodel"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
 	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/emailaddr"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/identifier"
 	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+	"BackofficeGoService/internal/pkg/requestctx"
 	"BackofficeGoService/internal/pkg/utils"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// userCacheTTL is how long user records stay cached
+const userCacheTTL = 5 * time.Minute
+
 // UserService handles user business logic
 type UserService struct {
-	db     *database.Manager
-	logger logger.Logger
+	db      *database.Manager
+	cache   cache.Store
+	revoker *TokenRevoker
+	audit   *AuditService
+	events  events.Publisher
+	metrics *metrics.Business
+	logger  logger.Logger
+	emails  emailaddr.Normalization
+	// responses, if set, drops cached user listings when users change
+	responses *ResponseCache
+	ids       identifier.Generator
+	// scopes, if set, restrict the users callers see; permissions resolves
+	// the callers' permissions
+	scopes      []ScopeFunc
+	permissions RolePermissionLookup
+	// passwords, if set, screens the passwords users are created with or
+	// set to
+	passwords PasswordPolicy
+	// approvals, if set, holds back the role changes in protectedRoles until
+	// a second admin approves them
+	approvals      *ApprovalService
+	protectedRoles []RoleTransition
+	clock          clock.Clock
+}
+
+// UserOption configures a UserService
+type UserOption func(s *UserService)
+
+// WithUserIDGenerator sets how the IDs of created users are generated; by
+// default they are random UUIDv4s
+func WithUserIDGenerator(ids identifier.Generator) UserOption {
+	return func(s *UserService) {
+		s.ids = ids
+	}
+}
+
+// WithEmailNormalization sets how email addresses are normalized before
+// they are stored or looked up; by default they are trimmed and lowercased
+func WithEmailNormalization(n emailaddr.Normalization) UserOption {
+	return func(s *UserService) {
+		s.emails = n
+	}
+}
+
+// WithUserResponseCache purges the cached responses listing users whenever
+// a user is created, changed or deleted
+func WithUserResponseCache(r *ResponseCache) UserOption {
+	return func(s *UserService) {
+		s.responses = r
+	}
+}
+
+// WithUserMetrics counts published events and required password changes
+func WithUserMetrics(m *metrics.Business) UserOption {
+	return func(s *UserService) {
+		s.metrics = m
+	}
+}
+
+// WithUserPasswordPolicy screens the passwords users are created with or
+// set to
+func WithUserPasswordPolicy(policy PasswordPolicy) UserOption {
+	return func(s *UserService) {
+		s.passwords = policy
+	}
+}
+
+// WithUserClock sets the clock role changes are stamped by
+func WithUserClock(c clock.Clock) UserOption {
+	return func(s *UserService) {
+		s.clock = c
+	}
+}
+
+// ListUsersFilter narrows a user listing
+type ListUsersFilter struct {
+	// Role, when set, only lists users with that role
+	Role models.UserRole
+
+	// Active, when set, only lists users with that active flag
+	Active *bool
+
+	// CreatedAfter and CreatedBefore, when set, bound the creation time,
+	// the former inclusive
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// IncludeAnonymized includes anonymized users, which are hidden by default
+	IncludeAnonymized bool
 }
 
+// conditions returns the filter as SQL conditions on the users table with
+// their arguments
+func (f ListUsersFilter) conditions() ([]string, [][]interface{}) {
+	var (
+		conditions []string
+		args       [][]interface{}
+	)
+	add := func(condition string, arg ...interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+	if f.Role != "" {
+		add("role = ?", f.Role)
+	}
+	if f.Active != nil {
+		add("active = ?", *f.Active)
+	}
+	if f.CreatedAfter != nil {
+		add("created_at >= ?", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		add("created_at < ?", *f.CreatedBefore)
+	}
+	if !f.IncludeAnonymized {
+		add("anonymized_at IS NULL")
+	}
+	return conditions, args
+}
+
+// ListResult is one page of a listing with the total number of matches
+type ListResult[T any] struct {
+	Items []T   `json:"items"`
+	Total int64 `json:"total"`
+}
+
+// UserDataExport is the data-subject export bundle for a single user
+type UserDataExport struct {
+	User        *models.User         `json:"user"`
+	LoginEvents []*models.LoginEvent `json:"login_events"`
+	AuditLogs   []*models.AuditLog   `json:"audit_logs"`
+	ExportedAt  time.Time            `json:"exported_at"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (e UserDataExport) MarshalJSON() ([]byte, error) { return apimodel.Marshal(e) }
+
 // NewUserService creates a new user service
-func NewUserService(db *database.Manager, log logger.Logger) *UserService {
-	return &UserService{
-		db:     db,
-		logger: log,
+func NewUserService(db *database.Manager, store cache.Store, revoker *TokenRevoker, audit *AuditService, publisher events.Publisher, log logger.Logger, opts ...UserOption) *UserService {
+	s := &UserService{
+		db:      db,
+		cache:   store,
+		revoker: revoker,
+		audit:   audit,
+		events:  publisher,
+		logger:  log,
+		ids:     identifier.Default,
+		clock:   clock.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-// GetUser retrieves a user by ID
+// GetUser retrieves a user by ID. Users outside the caller's scope are
+// reported as ErrUserNotFound, so callers cannot tell they exist.
 func (s *UserService) GetUser(ctx context.Context, id string) (*models.User, error) {
-	// Get primary database
-	primaryDriver, err := s.db.GetDriver("primary")
+	// Read from the tenant's database, or the primary one, or their replica
+	driver, err := s.db.ReaderFor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("database connection error: %w", err)
 	}
 
 	var user models.User
-	userID, err := uuid.Parse(id)
+	userID, err := identifier.Parse(id)
+	if err != nil {
+		// A malformed ID names no user
+		return nil, fmt.Errorf("%w: invalid user ID format", ErrUserNotFound)
+	}
+
+	scope, err := s.scope(ctx)
 	if err != nil {
-		return nil, errors.New("invalid user ID format")
+		return nil, err
+	}
+	if cached := s.getCachedUser(ctx, userCacheKeyByID(userID.String())); cached != nil {
+		if !scope.Allows(cached) {
+			return nil, ErrUserNotFound
+		}
+		return cached, nil
 	}
 
 	// Check if using GORM
-	if gormDB := primaryDriver.GetGormDB(); gormDB != nil {
-		db := gormDB.(*gorm.DB)
-		if err := db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+	if db := database.NativeGorm(driver); db != nil {
+		if err := withRetry(ctx, s.db.RetryPolicy(), func() error {
+			return db.WithContext(ctx).Where("id = ?", userID).First(&user).Error
+		}); err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return nil, errors.New("user not found")
+				return nil, ErrUserNotFound
 			}
 			return nil, fmt.Errorf("database error: %w", err)
 		}
 	} else {
 		// Use raw SQL
-		sqlDB := primaryDriver.GetSQLDB()
-		query := `SELECT id, email, username, password, first_name, last_name, role, active, created_at, updated_at 
-		          FROM users WHERE id = $1`
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return nil, err
+		}
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), `SELECT id, email, username, password, first_name, last_name, role, active, created_at, updated_at, anonymized_at, tenant_id 
+		          FROM users WHERE id = ?`))
 
-		err := sqlDB.QueryRowContext(ctx, query, userID).Scan(
+		err = sqlDB.QueryRowContext(ctx, query, userID).Scan(
 			&user.ID, &user.Email, &user.Username, &user.Password,
 			&user.FirstName, &user.LastName, &user.Role, &user.Active,
-			&user.CreatedAt, &user.UpdatedAt,
+			&user.CreatedAt, &user.UpdatedAt, &user.AnonymizedAt, &user.TenantID,
 		)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
-				return nil, errors.New("user not found")
+				return nil, ErrUserNotFound
 			}
 			return nil, fmt.Errorf("database error: %w", err)
 		}
@@ -73,195 +248,398 @@ func (s *UserService) GetUser(ctx context.Context, id string) (*models.User, err
 
 	// Remove password from response
 	user.Password = ""
+	s.cacheUser(ctx, &user)
+	if !scope.Allows(&user) {
+		return nil, ErrUserNotFound
+	}
 	return &user, nil
 }
 
-// CreateUser creates a new user
-func (s *UserService) CreateUser(ctx context.Context, req interface{}) (*models.User, error) {
-	reqMap, ok := req.(map[string]interface{})
-	if !ok {
-		return nil, errors.New("invalid request format")
+// GetUserByEmail retrieves a user by email address, however it is typed
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	email = s.emails.Normalize(email)
+	driver, err := s.db.ReaderFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+
+	if cached := s.getCachedUser(ctx, userCacheKeyByEmail(email)); cached != nil {
+		return cached, nil
+	}
+
+	var user models.User
+	if db := database.NativeGorm(driver); db != nil {
+		if err := withRetry(ctx, s.db.RetryPolicy(), func() error {
+			return db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+		}); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrUserNotFound
+			}
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+	} else {
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return nil, err
+		}
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), `SELECT id, email, username, password, first_name, last_name, role, active, created_at, updated_at, anonymized_at 
+		          FROM users WHERE email = ?`))
+
+		err = sqlDB.QueryRowContext(ctx, query, email).Scan(
+			&user.ID, &user.Email, &user.Username, &user.Password,
+			&user.FirstName, &user.LastName, &user.Role, &user.Active,
+			&user.CreatedAt, &user.UpdatedAt, &user.AnonymizedAt,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrUserNotFound
+			}
+			return nil, fmt.Errorf("database error: %w", err)
+		}
 	}
 
-	email, _ := reqMap["email"].(string)
-	username, _ := reqMap["username"].(string)
-	firstName, _ := reqMap["first_name"].(string)
-	lastName, _ := reqMap["last_name"].(string)
-	password, _ := reqMap["password"].(string)
+	user.Password = ""
+	s.cacheUser(ctx, &user)
+	return &user, nil
+}
 
-	if email == "" {
+// CreateUser creates a new user on behalf of actorID
+func (s *UserService) CreateUser(ctx context.Context, req *CreateUserRequest, actorID string) (*models.User, error) {
+	if req.Email == "" {
 		return nil, errors.New("email is required")
 	}
 
-	// Get primary database
-	primaryDriver, err := s.db.GetDriver("primary")
+	// Get the tenant's database, or the primary one
+	driver, err := s.db.DriverFor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("database connection error: %w", err)
 	}
 
+	email := s.emails.Normalize(req.Email)
+	taken, err := emailTaken(ctx, s.db, driver, email)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrEmailTaken
+	}
+
 	user := models.User{
-		ID:        uuid.New(),
+		ID:        s.ids.New(),
 		Email:     email,
-		Username:  username,
-		FirstName: firstName,
-		LastName:  lastName,
+		Username:  req.Username,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
 		Role:      models.RoleUser,
 		Active:    true,
 	}
+	if req.Role != "" {
+		user.Role = req.Role
+	}
+	if tenant := database.TenantID(ctx); tenant != "" {
+		user.TenantID = &tenant
+	}
 
-	if password != "" {
-		hashedPassword, err := utils.HashPassword(password)
+	if req.Password != "" {
+		if err := checkPassword(ctx, s.passwords, req.Password); err != nil {
+			return nil, err
+		}
+		hashedPassword, err := utils.HashPassword(req.Password)
 		if err != nil {
 			return nil, fmt.Errorf("failed to hash password: %w", err)
 		}
+		now := time.Now()
 		user.Password = hashedPassword
+		user.PasswordChangedAt = &now
 	}
 
 	// Check if using GORM
-	if gormDB := primaryDriver.GetGormDB(); gormDB != nil {
-		db := gormDB.(*gorm.DB)
+	if db := database.NativeGorm(driver); db != nil {
 		if err := db.WithContext(ctx).Create(&user).Error; err != nil {
 			return nil, fmt.Errorf("failed to create user: %w", err)
 		}
 	} else {
 		// Use raw SQL
-		sqlDB := primaryDriver.GetSQLDB()
-		query := `INSERT INTO users (id, email, username, password, first_name, last_name, role, active, created_at, updated_at)
-		          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())`
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return nil, err
+		}
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), `INSERT INTO users (id, email, username, password, first_name, last_name, role, active, password_changed_at, tenant_id, created_at, updated_at)
+		          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`))
 
-		_, err := sqlDB.ExecContext(ctx, query,
+		_, err = sqlDB.ExecContext(ctx, query,
 			user.ID, user.Email, user.Username, user.Password,
 			user.FirstName, user.LastName, user.Role, user.Active,
+			user.PasswordChangedAt, user.TenantID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create user: %w", err)
 		}
+		database.MarkWritten(ctx)
 	}
 
 	user.Password = ""
+	s.responses.Purge(ctx, ResponseGroupUsers)
+	s.auditUser(ctx, actorID, models.AuditActionUserCreated, &user, nil)
+	s.publish(ctx, events.UserCreated, &user)
 	return &user, nil
 }
 
-// UpdateUser updates an existing user
-func (s *UserService) UpdateUser(ctx context.Context, id string, req interface{}) (*models.User, error) {
-	user, err := s.GetUser(ctx, id)
+// UpdateUser applies a partial update to an existing user on behalf of
+// actorID. Only the fields present in req are written; an empty request is a
+// no-op. Changed fields are audited by name, never by value.
+func (s *UserService) UpdateUser(ctx context.Context, id string, req *UpdateUserRequest, actorID string) (*models.User, error) {
+	user, changed, err := s.updateUser(ctx, id, req.Changes())
 	if err != nil {
 		return nil, err
 	}
 
-	reqMap, ok := req.(map[string]interface{})
-	if !ok {
-		return nil, errors.New("invalid request format")
-	}
-
-	// Update fields
-	if email, ok := reqMap["email"].(string); ok && email != "" {
-		user.Email = email
+	var fields []string
+	for _, column := range changed {
+		if column != "password" {
+			fields = append(fields, column)
+			continue
+		}
+		s.auditUser(ctx, actorID, models.AuditActionUserPasswordChanged, user, nil)
 	}
-	if username, ok := reqMap["username"].(string); ok && username != "" {
-		user.Username = username
+	if len(fields) > 0 {
+		s.auditUser(ctx, actorID, models.AuditActionUserUpdated, user, map[string][]string{"fields": fields})
 	}
-	if firstName, ok := reqMap["first_name"].(string); ok && firstName != "" {
-		user.FirstName = firstName
+	return user, nil
+}
+
+// updateUser writes changes, keyed by column, and returns the updated user
+// with the columns whose value changed, sorted. A password counts as changed
+// whenever one is given.
+func (s *UserService) updateUser(ctx context.Context, id string, changes map[string]interface{}) (*models.User, []string, error) {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, nil, err
 	}
-	if lastName, ok := reqMap["last_name"].(string); ok && lastName != "" {
-		user.LastName = lastName
+
+	if len(changes) == 0 {
+		return user, nil, nil
 	}
-	if password, ok := reqMap["password"].(string); ok && password != "" {
+	changed := changedColumns(user, changes)
+
+	if password, ok := changes["password"].(string); ok {
+		if err := checkPassword(ctx, s.passwords, password); err != nil {
+			return nil, nil, err
+		}
 		hashedPassword, err := utils.HashPassword(password)
 		if err != nil {
-			return nil, fmt.Errorf("failed to hash password: %w", err)
+			return nil, nil, fmt.Errorf("failed to hash password: %w", err)
 		}
-		user.Password = hashedPassword
+		changes["password"] = hashedPassword
 	}
 
-	// Get primary database
-	primaryDriver, err := s.db.GetDriver("primary")
+	// Get the tenant's database, or the primary one
+	driver, err := s.db.DriverFor(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("database connection error: %w", err)
+		return nil, nil, fmt.Errorf("database connection error: %w", err)
+	}
+
+	now := time.Now()
+	if _, ok := changes["password"]; ok {
+		changes["password_changed_at"] = now
 	}
 
 	// Check if using GORM
-	if gormDB := primaryDriver.GetGormDB(); gormDB != nil {
-		db := gormDB.(*gorm.DB)
-		if err := db.WithContext(ctx).Save(&user).Error; err != nil {
-			return nil, fmt.Errorf("failed to update user: %w", err)
+	if db := database.NativeGorm(driver); db != nil {
+		changes["updated_at"] = now
+		if err := db.WithContext(ctx).Model(&models.User{}).Where("id = ?", user.ID).Updates(changes).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to update user: %w", err)
 		}
+		delete(changes, "updated_at")
 	} else {
-		// Use raw SQL
-		sqlDB := primaryDriver.GetSQLDB()
-		query := `UPDATE users SET email = $1, username = $2, first_name = $3, last_name = $4, 
-		          password = $5, updated_at = NOW() WHERE id = $6`
-
-		_, err := sqlDB.ExecContext(ctx, query,
-			user.Email, user.Username, user.FirstName, user.LastName,
-			user.Password, user.ID,
-		)
+		// Use raw SQL with a SET clause built from the changed columns only
+		sqlDB, err := database.SQLDB(driver)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update user: %w", err)
+			return nil, nil, err
+		}
+
+		columns := make([]string, 0, len(changes))
+		for column := range changes {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+
+		setClauses := make([]string, 0, len(columns)+1)
+		args := make([]interface{}, 0, len(columns)+1)
+		for _, column := range columns {
+			setClauses = append(setClauses, column+" = ?")
+			args = append(args, changes[column])
 		}
+		setClauses = append(setClauses, "updated_at = CURRENT_TIMESTAMP")
+		args = append(args, user.ID)
+
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), "UPDATE users SET "+strings.Join(setClauses, ", ")+" WHERE id = ?"))
+		if _, err := sqlDB.ExecContext(ctx, query, args...); err != nil {
+			return nil, nil, fmt.Errorf("failed to update user: %w", err)
+		}
+		database.MarkWritten(ctx)
 	}
 
+	s.invalidateUserCache(ctx, user)
+	if _, ok := changes["active"]; ok {
+		_ = s.cache.Delete(ctx, userActiveCacheKey(user.ID.String()))
+	}
+
+	applyUserChanges(user, changes)
+	user.UpdatedAt = now
 	user.Password = ""
-	return user, nil
+
+	s.cacheUser(ctx, user)
+	s.publish(ctx, events.UserUpdated, user)
+	return user, changed, nil
+}
+
+// applyUserChanges copies persisted column changes onto the in-memory user
+func applyUserChanges(user *models.User, changes map[string]interface{}) {
+	for column, value := range changes {
+		switch column {
+		case "username":
+			user.Username = value.(string)
+		case "first_name":
+			user.FirstName = value.(string)
+		case "last_name":
+			user.LastName = value.(string)
+		case "active":
+			user.Active = value.(bool)
+		case "password_changed_at":
+			changedAt := value.(time.Time)
+			user.PasswordChangedAt = &changedAt
+		}
+	}
+}
+
+// changedColumns returns the columns in changes whose value differs from user,
+// sorted
+func changedColumns(user *models.User, changes map[string]interface{}) []string {
+	current := map[string]interface{}{
+		"username":   user.Username,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"active":     user.Active,
+	}
+
+	var changed []string
+	for column, value := range changes {
+		if old, ok := current[column]; !ok || old != value {
+			changed = append(changed, column)
+		}
+	}
+	sort.Strings(changed)
+	return changed
 }
 
 // DeleteUser deletes a user by ID
 func (s *UserService) DeleteUser(ctx context.Context, id string) error {
-	userID, err := uuid.Parse(id)
+	userID, err := identifier.Parse(id)
 	if err != nil {
 		return errors.New("invalid user ID format")
 	}
 
-	// Get primary database
-	primaryDriver, err := s.db.GetDriver("primary")
+	// Get the tenant's database, or the primary one
+	driver, err := s.db.DriverFor(ctx)
 	if err != nil {
 		return fmt.Errorf("database connection error: %w", err)
 	}
 
 	// Check if using GORM
-	if gormDB := primaryDriver.GetGormDB(); gormDB != nil {
-		db := gormDB.(*gorm.DB)
+	if db := database.NativeGorm(driver); db != nil {
 		if err := db.WithContext(ctx).Delete(&models.User{}, userID).Error; err != nil {
 			return fmt.Errorf("failed to delete user: %w", err)
 		}
 	} else {
 		// Use raw SQL
-		sqlDB := primaryDriver.GetSQLDB()
-		query := `DELETE FROM users WHERE id = $1`
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return err
+		}
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), `DELETE FROM users WHERE id = ?`))
 
-		_, err := sqlDB.ExecContext(ctx, query, userID)
+		_, err = sqlDB.ExecContext(ctx, query, userID)
 		if err != nil {
 			return fmt.Errorf("failed to delete user: %w", err)
 		}
+		database.MarkWritten(ctx)
+	}
+
+	if user := s.getCachedUser(ctx, userCacheKeyByID(userID.String())); user != nil {
+		s.invalidateUserCache(ctx, user)
+	} else {
+		_ = s.cache.Delete(ctx, userCacheKeyByID(userID.String()))
 	}
+	// Tokens are checked against the cached active flag, so drop it too
+	_ = s.cache.Delete(ctx, userActiveCacheKey(userID.String()))
 
+	s.publish(ctx, events.UserDeleted, map[string]string{"id": userID.String()})
 	return nil
 }
 
-// ListUsers retrieves a list of users with pagination
-func (s *UserService) ListUsers(ctx context.Context, limit, offset int) ([]*models.User, error) {
-	// Get primary database
-	primaryDriver, err := s.db.GetDriver("primary")
+// ListUsers retrieves a page of the users in the caller's scope and the
+// total number of them matching filter
+func (s *UserService) ListUsers(ctx context.Context, filter ListUsersFilter, limit, offset int) (*ListResult[*models.User], error) {
+	// Read from the tenant's database, or the primary one, or their replica
+	driver, err := s.db.ReaderFor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("database connection error: %w", err)
 	}
+	scope, err := s.scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scoped, scopeArgs := scope.Where()
+	conditions, conditionArgs := filter.conditions()
 
-	var users []*models.User
+	result := &ListResult[*models.User]{Items: []*models.User{}}
 
 	// Check if using GORM
-	if gormDB := primaryDriver.GetGormDB(); gormDB != nil {
-		db := gormDB.(*gorm.DB)
-		if err := db.WithContext(ctx).Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+	if db := database.NativeGorm(driver); db != nil {
+		err := withRetry(ctx, s.db.RetryPolicy(), func() error {
+			query := db.WithContext(ctx).Model(&models.User{})
+			if scoped != "" {
+				query = query.Where(scoped, scopeArgs...)
+			}
+			for i, condition := range conditions {
+				query = query.Where(condition, conditionArgs[i]...)
+			}
+			if err := query.Count(&result.Total).Error; err != nil {
+				return err
+			}
+			return query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&result.Items).Error
+		})
+		if err != nil {
 			return nil, fmt.Errorf("database error: %w", err)
 		}
 	} else {
 		// Use raw SQL
-		sqlDB := primaryDriver.GetSQLDB()
-		query := `SELECT id, email, username, password, first_name, last_name, role, active, created_at, updated_at 
-		          FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return nil, err
+		}
+		where := database.NewWhereBuilder("created_at")
+		if scoped != "" {
+			where.Where(scoped, scopeArgs...)
+		}
+		for i, condition := range conditions {
+			where.Where(condition, conditionArgs[i]...)
+		}
+		if err := where.Sort("created_at", true); err != nil {
+			return nil, err
+		}
+		clause, args := where.Clause()
+
+		count := s.db.Annotate(ctx, database.Rebind(driver.Type(), "SELECT COUNT(*) FROM users "+clause))
+		if err := sqlDB.QueryRowContext(ctx, count, args...).Scan(&result.Total); err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), `SELECT id, email, username, password, first_name, last_name, role, active, created_at, updated_at, anonymized_at 
+		          FROM users `+clause+` `+where.Order()+` LIMIT ? OFFSET ?`))
 
-		rows, err := sqlDB.QueryContext(ctx, query, limit, offset)
+		rows, err := sqlDB.QueryContext(ctx, query, append(args, limit, offset)...)
 		if err != nil {
 			return nil, fmt.Errorf("database error: %w", err)
 		}
@@ -272,24 +650,432 @@ func (s *UserService) ListUsers(ctx context.Context, limit, offset int) ([]*mode
 			if err := rows.Scan(
 				&user.ID, &user.Email, &user.Username, &user.Password,
 				&user.FirstName, &user.LastName, &user.Role, &user.Active,
-				&user.CreatedAt, &user.UpdatedAt,
+				&user.CreatedAt, &user.UpdatedAt, &user.AnonymizedAt,
 			); err != nil {
 				return nil, fmt.Errorf("failed to scan user: %w", err)
 			}
-			user.Password = ""
-			users = append(users, &user)
+			result.Items = append(result.Items, &user)
 		}
 	}
 
 	// Remove passwords from response
-	for _, user := range users {
+	for _, user := range result.Items {
 		user.Password = ""
 	}
 
+	return result, nil
+}
+
+// GetUsersByIDs retrieves the users with the given IDs in a single query.
+// Malformed and unknown IDs are skipped; callers compare the result to their input.
+func (s *UserService) GetUsersByIDs(ctx context.Context, ids []string) ([]*models.User, error) {
+	userIDs := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if parsed, err := identifier.Parse(id); err == nil {
+			userIDs = append(userIDs, parsed)
+		}
+	}
+	if len(userIDs) == 0 {
+		return []*models.User{}, nil
+	}
+
+	driver, err := s.db.ReaderFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+
+	var users []*models.User
+	if err := withRetry(ctx, s.db.RetryPolicy(), func() error {
+		return db.WithContext(ctx).Where("id IN ?", userIDs).Find(&users).Error
+	}); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	// Remove passwords from response
+	for _, user := range users {
+		user.Password = ""
+	}
 	return users, nil
 }
 
+// SetActive activates or deactivates a user on behalf of actorID.
+// Deactivation revokes every token previously issued to the user.
+func (s *UserService) SetActive(ctx context.Context, id string, active bool, actorID string) (*models.User, error) {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkActiveChange(ctx, actorID, user, active); err != nil {
+		return nil, err
+	}
+
+	if user.Active != active {
+		user, _, err = s.updateUser(ctx, id, map[string]interface{}{"active": active})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !active {
+		if err := s.revoker.RevokeUserTokens(ctx, user.ID.String()); err != nil {
+			return nil, fmt.Errorf("failed to revoke tokens: %w", err)
+		}
+	}
+
+	_ = s.cache.Delete(ctx, userActiveCacheKey(user.ID.String()))
+
+	action, eventType := models.AuditActionUserActivated, events.UserActivated
+	if !active {
+		action, eventType = models.AuditActionUserDeactivated, events.UserDeactivated
+	}
+	if err := s.audit.Record(ctx, actorID, action, "user", user.ID.String(), nil); err != nil {
+		s.log(ctx).Warn("Failed to audit active status change", logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+	}
+
+	s.log(ctx).Info("User active status changed",
+		logger.Field{Key: "user_id", Value: user.ID.String()},
+		logger.Field{Key: "active", Value: active},
+		logger.Field{Key: "actor_id", Value: actorID},
+	)
+
+	s.publish(ctx, eventType, user)
+	return user, nil
+}
+
+// RequirePasswordChange flags a user to change their password on behalf of
+// actorID. Every token issued to the user so far is revoked, and until the
+// password is changed logins only return a token restricted to changing it.
+func (s *UserService) RequirePasswordChange(ctx context.Context, id string, actorID string) (*models.User, error) {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+
+	now := time.Now()
+	if db := database.NativeGorm(driver); db != nil {
+		changes := map[string]interface{}{"must_change_password": true, "updated_at": now}
+		if err := db.WithContext(ctx).Model(&models.User{}).Where("id = ?", user.ID).Updates(changes).Error; err != nil {
+			return nil, fmt.Errorf("failed to update user: %w", err)
+		}
+	} else {
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return nil, err
+		}
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), `UPDATE users SET must_change_password = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`))
+		if _, err := sqlDB.ExecContext(ctx, query, true, user.ID); err != nil {
+			return nil, fmt.Errorf("failed to update user: %w", err)
+		}
+		database.MarkWritten(ctx)
+	}
+
+	s.invalidateUserCache(ctx, user)
+	if err := s.revoker.RevokeUserTokens(ctx, user.ID.String()); err != nil {
+		return nil, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	user.MustChangePassword = true
+	user.UpdatedAt = now
+	s.auditUser(ctx, actorID, models.AuditActionUserPasswordChangeRequired, user, nil)
+	s.metrics.PasswordReset()
+	s.publish(ctx, events.UserUpdated, user)
+	return user, nil
+}
+
+// ExportUserData returns the data-subject export bundle for a user
+func (s *UserService) ExportUserData(ctx context.Context, id string, actorID string) (*UserDataExport, error) {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	loginEvents, err := s.audit.ListLoginEvents(ctx, user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	auditLogs, err := s.audit.ListForUser(ctx, user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.audit.Record(ctx, actorID, models.AuditActionUserExported, "user", user.ID.String(), nil); err != nil {
+		s.log(ctx).Warn("Failed to audit user export", logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+	}
+
+	return &UserDataExport{
+		User:        user,
+		LoginEvents: loginEvents,
+		AuditLogs:   auditLogs,
+		ExportedAt:  time.Now(),
+	}, nil
+}
+
+// AnonymizeUser irreversibly scrubs a user's personal data while keeping the
+// row so foreign keys stay intact. Anonymizing an already anonymized user is a no-op.
+func (s *UserService) AnonymizeUser(ctx context.Context, id string, actorID string) (*models.User, error) {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.AnonymizedAt != nil {
+		return user, nil
+	}
+
+	// Anonymizing deactivates the user for good, so the same guards apply
+	if err := s.checkActiveChange(ctx, actorID, user, false); err != nil {
+		return nil, err
+	}
+
+	// Capture the old cache keys before the email is replaced
+	s.invalidateUserCache(ctx, user)
+
+	changes := AnonymizeUserFields(user, time.Now())
+
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+
+	if db := database.NativeGorm(driver); db != nil {
+		changes["updated_at"] = time.Now()
+		if err := db.WithContext(ctx).Model(&models.User{}).Where("id = ?", user.ID).Updates(changes).Error; err != nil {
+			return nil, fmt.Errorf("failed to anonymize user: %w", err)
+		}
+	} else {
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return nil, err
+		}
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), `UPDATE users SET email = ?, username = ?, first_name = ?, last_name = ?, 
+		          password = ?, active = ?, anonymized_at = ?, partner_id = NULL, external_id = NULL,
+		          updated_at = CURRENT_TIMESTAMP WHERE id = ?`))
+		if _, err := sqlDB.ExecContext(ctx, query,
+			changes["email"], changes["username"], changes["first_name"], changes["last_name"],
+			changes["password"], changes["active"], changes["anonymized_at"], user.ID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to anonymize user: %w", err)
+		}
+		database.MarkWritten(ctx)
+	}
+
+	if err := s.revoker.RevokeUserTokens(ctx, user.ID.String()); err != nil {
+		return nil, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+	_ = s.cache.Delete(ctx, userActiveCacheKey(user.ID.String()))
+
+	if err := s.audit.Record(ctx, actorID, models.AuditActionUserAnonymized, "user", user.ID.String(), nil); err != nil {
+		s.log(ctx).Warn("Failed to audit anonymization", logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+	}
+
+	s.log(ctx).Info("User anonymized", logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "actor_id", Value: actorID})
+
+	s.publish(ctx, events.UserAnonymized, map[string]string{"id": user.ID.String()})
+	return user, nil
+}
+
+// AnonymizeUserFields scrubs the personal data on user in place and returns
+// the changed columns. The tombstone email is derived from the user ID, so
+// applying it again yields the same values. The user is unlinked from the
+// partner that provisioned it, which provisions a new user next time.
+func AnonymizeUserFields(user *models.User, at time.Time) map[string]interface{} {
+	if user.AnonymizedAt != nil {
+		at = *user.AnonymizedAt
+	}
+
+	user.Email = fmt.Sprintf("anonymized+%s@anonymized.invalid", user.ID)
+	user.Username = ""
+	user.FirstName = ""
+	user.LastName = ""
+	user.Password = ""
+	user.Active = false
+	user.AnonymizedAt = &at
+	user.PartnerID = nil
+	user.ExternalID = nil
+
+	return map[string]interface{}{
+		"email":         user.Email,
+		"username":      user.Username,
+		"first_name":    user.FirstName,
+		"last_name":     user.LastName,
+		"password":      user.Password,
+		"active":        user.Active,
+		"anonymized_at": at,
+		"partner_id":    nil,
+		"external_id":   nil,
+	}
+}
+
+// CheckActiveChange enforces the deactivation guards: nobody may deactivate
+// themselves, and the last active admin may not be deactivated.
+// activeAdmins is only consulted when deactivating an active admin.
+func CheckActiveChange(actorID string, target *models.User, active bool, activeAdmins int64) error {
+	if active {
+		return nil
+	}
+	if actorID == target.ID.String() {
+		return ErrSelfDeactivation
+	}
+	if target.Role == models.RoleAdmin && target.Active && activeAdmins <= 1 {
+		return ErrLastActiveAdmin
+	}
+	return nil
+}
+
+// checkActiveChange runs CheckActiveChange for actorID setting user's active
+// flag, counting the active admins only when the guard needs them
+func (s *UserService) checkActiveChange(ctx context.Context, actorID string, user *models.User, active bool) error {
+	var activeAdmins int64
+	if !active && user.Role == models.RoleAdmin && user.Active {
+		var err error
+		if activeAdmins, err = s.countActiveAdmins(ctx); err != nil {
+			return err
+		}
+	}
+	return CheckActiveChange(actorID, user, active, activeAdmins)
+}
+
+// publish emits a user event; publishing failures never fail the operation
+func (s *UserService) publish(ctx context.Context, eventType string, data interface{}) {
+	if s.events == nil {
+		return
+	}
+	if err := s.events.Publish(ctx, events.New(eventType, data)); err != nil {
+		s.log(ctx).Warn("Failed to publish event", logger.Field{Key: "event", Value: eventType}, logger.Field{Key: "error", Value: err.Error()})
+		return
+	}
+	s.metrics.EventPublished(eventType)
+}
+
+// auditUser records an action on user; failures are logged but never fail the change
+func (s *UserService) auditUser(ctx context.Context, actorID, action string, user *models.User, metadata interface{}) {
+	if s.audit == nil {
+		return
+	}
+	if err := s.audit.Record(ctx, actorID, action, "user", user.ID.String(), metadata); err != nil {
+		s.log(ctx).Warn("Failed to audit user change", logger.Field{Key: "action", Value: action}, logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+	}
+}
+
+// countActiveAdmins returns the number of active admin users
+func (s *UserService) countActiveAdmins(ctx context.Context) (int64, error) {
+	driver, err := s.db.DriverFor(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("database connection error: %w", err)
+	}
+
+	var count int64
+	if db := database.NativeGorm(driver); db != nil {
+		if err := db.WithContext(ctx).Model(&models.User{}).
+			Where("role = ? AND active = ?", models.RoleAdmin, true).
+			Count(&count).Error; err != nil {
+			return 0, fmt.Errorf("database error: %w", err)
+		}
+	} else {
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return 0, err
+		}
+		query := s.db.Annotate(ctx, database.Rebind(driver.Type(), `SELECT COUNT(*) FROM users WHERE role = ? AND active = ?`))
+		if err := sqlDB.QueryRowContext(ctx, query, models.RoleAdmin, true).Scan(&count); err != nil {
+			return 0, fmt.Errorf("database error: %w", err)
+		}
+	}
+
+	return count, nil
+}
+
+// emailTaken reports whether a user with the given normalized email already exists
+func emailTaken(ctx context.Context, db *database.Manager, driver database.Driver, email string) (bool, error) {
+	var count int64
+	if gormDB := database.NativeGorm(driver); gormDB != nil {
+		if err := gormDB.WithContext(ctx).Model(&models.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+			return false, fmt.Errorf("database error: %w", err)
+		}
+	} else {
+		sqlDB, err := database.SQLDB(driver)
+		if err != nil {
+			return false, err
+		}
+		query := db.Annotate(ctx, database.Rebind(driver.Type(), `SELECT COUNT(*) FROM users WHERE email = ?`))
+		if err := sqlDB.QueryRowContext(ctx, query, email).Scan(&count); err != nil {
+			return false, fmt.Errorf("database error: %w", err)
+		}
+	}
+
+	return count > 0, nil
+}
+
 // Health checks if the service is healthy
 func (s *UserService) Health(ctx context.Context) error {
 	return s.db.Health(ctx)["primary"]
 }
+
+// log returns the logger of the request ctx belongs to, which adds its
+// request ID and trace, or the service's own outside requests
+func (s *UserService) log(ctx context.Context) logger.Logger {
+	return requestctx.LoggerOr(ctx, s.logger)
+}
+
+// userCacheKeyByID returns the cache key for a user looked up by ID
+func userCacheKeyByID(id string) string {
+	return "user:id:" + id
+}
+
+// userActiveCacheKey returns the cache key for a user's active flag and token version
+func userActiveCacheKey(id string) string {
+	return "user:active:" + id
+}
+
+// userCacheKeyByEmail returns the cache key for a user looked up by email
+func userCacheKeyByEmail(email string) string {
+	return "user:email:" + email
+}
+
+// getCachedUser returns the cached user stored under key, or nil on a miss
+func (s *UserService) getCachedUser(ctx context.Context, key string) *models.User {
+	data, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return nil
+	}
+
+	var user models.User
+	if err := json.Unmarshal(data, &user); err != nil {
+		return nil
+	}
+	return &user
+}
+
+// cacheUser stores a user under both its ID and email keys
+func (s *UserService) cacheUser(ctx context.Context, user *models.User) {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return
+	}
+
+	for _, key := range []string{userCacheKeyByID(user.ID.String()), userCacheKeyByEmail(user.Email)} {
+		if err := s.cache.Set(ctx, key, data, userCacheTTL); err != nil {
+			s.log(ctx).Warn("Failed to cache user", logger.Field{Key: "key", Value: key}, logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+}
+
+// invalidateUserCache removes every cache entry for a user, and the cached
+// responses listing users
+func (s *UserService) invalidateUserCache(ctx context.Context, user *models.User) {
+	if err := s.cache.Delete(ctx, userCacheKeyByID(user.ID.String()), userCacheKeyByEmail(user.Email)); err != nil {
+		s.log(ctx).Warn("Failed to invalidate user cache", logger.Field{Key: "user_id", Value: user.ID.String()}, logger.Field{Key: "error", Value: err.Error()})
+	}
+	s.responses.Purge(ctx, ResponseGroupUsers)
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/httpclient"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+
+	"BackofficeGoService/config"
+
+	"github.com/google/uuid"
+)
+
+// Headers sent with every webhook delivery
+const (
+	WebhookSignatureHeader = "X-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookEventIDHeader   = "X-Webhook-Event-ID"
+	WebhookEventTypeHeader = "X-Webhook-Event-Type"
+)
+
+// SignWebhookPayload returns the X-Signature value for body: "sha256=" followed
+// by the hex-encoded HMAC-SHA256 of the body keyed with the endpoint's secret
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether signature is valid for body and secret
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhookPayload(secret, body)), []byte(signature))
+}
+
+// WebhookDispatcher delivers events from the event stream to subscribed webhooks
+type WebhookDispatcher struct {
+	repo    WebhookRepository
+	config  config.WebhookConfig
+	client  *http.Client
+	metrics *metrics.Business
+	logger  logger.Logger
+
+	queue  chan events.Event
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// WebhookDispatcherOption configures a WebhookDispatcher
+type WebhookDispatcherOption func(d *WebhookDispatcher)
+
+// WithWebhookMetrics counts deliveries and times their attempts
+func WithWebhookMetrics(m *metrics.Business) WebhookDispatcherOption {
+	return func(d *WebhookDispatcher) {
+		d.metrics = m
+	}
+}
+
+// WithWebhookHTTPClient delivers with client, which should come from
+// httpclient.New without retries, as deliveries retry on their own
+func WithWebhookHTTPClient(client *http.Client) WebhookDispatcherOption {
+	return func(d *WebhookDispatcher) {
+		d.client = client
+	}
+}
+
+// NewWebhookDispatcher creates a dispatcher; call Start to begin delivering
+func NewWebhookDispatcher(repo WebhookRepository, cfg config.WebhookConfig, log logger.Logger, opts ...WebhookDispatcherOption) *WebhookDispatcher {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize < 1 {
+		cfg.QueueSize = 1
+	}
+
+	d := &WebhookDispatcher{
+		repo:   repo,
+		config: cfg,
+		client: httpclient.New(httpclient.Config{Timeout: cfg.Timeout}),
+		logger: log,
+		queue:  make(chan events.Event, cfg.QueueSize),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Start launches the delivery workers
+func (d *WebhookDispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	for i := 0; i < d.config.Workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-d.queue:
+					d.dispatch(ctx, event)
+				}
+			}
+		}()
+	}
+}
+
+// Stop stops the workers, abandoning pending retries, and waits for them to exit
+func (d *WebhookDispatcher) Stop(ctx context.Context) error {
+	if d.cancel == nil {
+		return nil
+	}
+	d.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HandleEvent queues an event for delivery; it is registered as an events.Handler
+// and never blocks the publisher
+func (d *WebhookDispatcher) HandleEvent(ctx context.Context, event events.Event) {
+	select {
+	case d.queue <- event:
+	default:
+		d.logger.Warn("Dropping webhook event",
+			logger.Field{Key: "event_id", Value: event.ID},
+			logger.Field{Key: "event_type", Value: event.Type},
+			logger.Field{Key: "error", Value: ErrWebhookQueueFull.Error()},
+		)
+	}
+}
+
+// dispatch delivers event to every enabled webhook subscribed to its type
+func (d *WebhookDispatcher) dispatch(ctx context.Context, event events.Event) {
+	webhooks, err := d.repo.ListEnabled(ctx)
+	if err != nil {
+		d.logger.Error("Failed to load webhooks", logger.Field{Key: "error", Value: err.Error()})
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event.Type) {
+			continue
+		}
+		if _, err := d.Deliver(ctx, webhook, event); err != nil {
+			d.logger.Warn("Webhook delivery failed",
+				logger.Field{Key: "webhook_id", Value: webhook.ID.String()},
+				logger.Field{Key: "event_id", Value: event.ID},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+		}
+	}
+}
+
+// Deliver sends event to webhook, retrying network errors and 5xx responses
+// with exponential backoff. Every attempt is recorded. A delivery that still
+// fails counts towards the endpoint's failure streak, which disables it once
+// it reaches the configured threshold; a successful delivery resets the streak.
+func (d *WebhookDispatcher) Deliver(ctx context.Context, webhook *models.Webhook, event events.Event) (*models.WebhookDelivery, error) {
+	delivery, err := d.deliver(ctx, webhook, event, d.config.MaxAttempts)
+
+	if err == nil {
+		if webhook.ConsecutiveFailures > 0 {
+			webhook.ConsecutiveFailures = 0
+			d.saveDeliveryState(ctx, webhook)
+		}
+		return delivery, nil
+	}
+
+	webhook.ConsecutiveFailures++
+	if d.config.DisableAfter > 0 && webhook.ConsecutiveFailures >= d.config.DisableAfter && webhook.Enabled {
+		now := time.Now()
+		webhook.Enabled = false
+		webhook.DisabledAt = &now
+		d.logger.Warn("Webhook disabled after consecutive failures",
+			logger.Field{Key: "webhook_id", Value: webhook.ID.String()},
+			logger.Field{Key: "failures", Value: webhook.ConsecutiveFailures},
+		)
+	}
+	d.saveDeliveryState(ctx, webhook)
+
+	return delivery, err
+}
+
+// SendTestEvent makes a single delivery attempt of a test event. It does not
+// retry and does not affect the endpoint's failure streak.
+func (d *WebhookDispatcher) SendTestEvent(ctx context.Context, webhook *models.Webhook) (*models.WebhookDelivery, error) {
+	event := events.New(events.WebhookTest, map[string]string{"webhook_id": webhook.ID.String()})
+	return d.deliver(ctx, webhook, event, 1)
+}
+
+// deliver makes up to maxAttempts attempts and returns the last recorded attempt
+func (d *WebhookDispatcher) deliver(ctx context.Context, webhook *models.Webhook, event events.Event, maxAttempts int) (*models.WebhookDelivery, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	backoff := d.config.InitialBackoff
+	var delivery *models.WebhookDelivery
+	for attempt := 1; ; attempt++ {
+		var retryable bool
+		delivery, retryable = d.attempt(ctx, webhook, event, body, attempt)
+		if delivery.Success {
+			d.metrics.WebhookDelivered(true)
+			return delivery, nil
+		}
+		if !retryable || attempt >= maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			d.metrics.WebhookDelivered(false)
+			return delivery, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	d.metrics.WebhookDelivered(false)
+	return delivery, fmt.Errorf("webhook delivery failed after %d attempt(s): %s", delivery.Attempt, delivery.Error)
+}
+
+// attempt makes one signed POST and records it. It reports whether a failure is retryable.
+func (d *WebhookDispatcher) attempt(ctx context.Context, webhook *models.Webhook, event events.Event, body []byte, attempt int) (*models.WebhookDelivery, bool) {
+	delivery := &models.WebhookDelivery{
+		ID:        uuid.New(),
+		WebhookID: webhook.ID,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Attempt:   attempt,
+		CreatedAt: time.Now(),
+	}
+
+	retryable := false
+	start := time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, body))
+		req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+		req.Header.Set(WebhookEventIDHeader, event.ID)
+		req.Header.Set(WebhookEventTypeHeader, event.Type)
+
+		resp, err := d.client.Do(req)
+		if err != nil {
+			delivery.Error = err.Error()
+			retryable = true
+		} else {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+
+			delivery.StatusCode = resp.StatusCode
+			switch {
+			case resp.StatusCode >= 200 && resp.StatusCode < 300:
+				delivery.Success = true
+			case resp.StatusCode >= 500:
+				delivery.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+				retryable = true
+			default:
+				delivery.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+			}
+		}
+	}
+	elapsed := time.Since(start)
+	delivery.DurationMs = elapsed.Milliseconds()
+	d.metrics.WebhookAttempted(elapsed)
+
+	if err := d.repo.CreateDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		d.logger.Error("Failed to record webhook delivery",
+			logger.Field{Key: "webhook_id", Value: webhook.ID.String()},
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+	}
+
+	return delivery, retryable
+}
+
+// saveDeliveryState persists the failure streak and enabled state
+func (d *WebhookDispatcher) saveDeliveryState(ctx context.Context, webhook *models.Webhook) {
+	if err := d.repo.UpdateDeliveryState(context.WithoutCancel(ctx), webhook); err != nil {
+		d.logger.Error("Failed to update webhook state",
+			logger.Field{Key: "webhook_id", Value: webhook.ID.String()},
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+	}
+}
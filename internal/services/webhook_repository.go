@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/pagination"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookRepository persists webhooks and their delivery attempts
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	Get(ctx context.Context, id uuid.UUID) (*models.Webhook, error)
+	List(ctx context.Context) ([]*models.Webhook, error)
+	ListEnabled(ctx context.Context) ([]*models.Webhook, error)
+	Update(ctx context.Context, webhook *models.Webhook) error
+
+	// UpdateDeliveryState writes only the failure counter and enabled state
+	UpdateDeliveryState(ctx context.Context, webhook *models.Webhook) error
+
+	// Delete removes the webhook together with its delivery history
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, webhookID uuid.UUID, after *pagination.Cursor, limit int) ([]*models.WebhookDelivery, error)
+
+	// DeleteDeliveriesBefore removes delivery attempts older than cutoff
+	DeleteDeliveriesBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// gormWebhookRepository implements WebhookRepository on the database each call is scoped to
+type gormWebhookRepository struct {
+	db *database.Manager
+}
+
+// NewWebhookRepository creates a repository backed by the database each call is scoped to
+func NewWebhookRepository(db *database.Manager) WebhookRepository {
+	return &gormWebhookRepository{db: db}
+}
+
+func (r *gormWebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(webhook).Error
+}
+
+func (r *gormWebhookRepository) Get(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var webhook models.Webhook
+	if err := withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("id = ?", id).First(&webhook).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *gormWebhookRepository) List(ctx context.Context) ([]*models.Webhook, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var webhooks []*models.Webhook
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Order("created_at ASC").Find(&webhooks).Error
+	})
+	return webhooks, err
+}
+
+func (r *gormWebhookRepository) ListEnabled(ctx context.Context) ([]*models.Webhook, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var webhooks []*models.Webhook
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		return db.Where("enabled = ?", true).Find(&webhooks).Error
+	})
+	return webhooks, err
+}
+
+func (r *gormWebhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Model(&models.Webhook{}).Where("id = ?", webhook.ID).Updates(map[string]interface{}{
+		"url":                  webhook.URL,
+		"secret":               webhook.Secret,
+		"enabled":              webhook.Enabled,
+		"event_types":          webhook.EventTypes,
+		"consecutive_failures": webhook.ConsecutiveFailures,
+		"disabled_at":          webhook.DisabledAt,
+		"updated_at":           webhook.UpdatedAt,
+	}).Error
+}
+
+func (r *gormWebhookRepository) UpdateDeliveryState(ctx context.Context, webhook *models.Webhook) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Model(&models.Webhook{}).Where("id = ?", webhook.ID).Updates(map[string]interface{}{
+		"enabled":              webhook.Enabled,
+		"consecutive_failures": webhook.ConsecutiveFailures,
+		"disabled_at":          webhook.DisabledAt,
+	}).Error
+}
+
+func (r *gormWebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&models.Webhook{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		return nil
+	})
+}
+
+func (r *gormWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Create(delivery).Error
+}
+
+func (r *gormWebhookRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, after *pagination.Cursor, limit int) ([]*models.WebhookDelivery, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []*models.WebhookDelivery
+	err = withRetry(ctx, r.db.RetryPolicy(), func() error {
+		query := db.Where("webhook_id = ?", webhookID)
+		if after != nil {
+			query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", after.Time, after.Time, after.ID)
+		}
+		return query.Order("created_at DESC").Order("id DESC").Limit(limit).Find(&deliveries).Error
+	})
+	return deliveries, err
+}
+
+func (r *gormWebhookRepository) DeleteDeliveriesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	db, err := database.GormFor(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	result := db.Where("created_at < ?", cutoff).Delete(&models.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
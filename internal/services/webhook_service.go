@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/pagination"
+
+	"github.com/google/uuid"
+)
+
+// CreateWebhookRequest represents the payload for registering a webhook.
+// A secret is generated when none is supplied.
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required"`
+	Secret     string   `json:"secret" binding:"omitempty,min=16"`
+	EventTypes []string `json:"event_types" binding:"required,min=1"`
+}
+
+// UpdateWebhookRequest represents a partial webhook update
+type UpdateWebhookRequest struct {
+	URL        *string  `json:"url"`
+	Secret     *string  `json:"secret" binding:"omitempty,min=16"`
+	Enabled    *bool    `json:"enabled"`
+	EventTypes []string `json:"event_types"`
+}
+
+// WebhookService manages webhook endpoints
+type WebhookService struct {
+	repo   WebhookRepository
+	logger logger.Logger
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(repo WebhookRepository, log logger.Logger) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		logger: log,
+	}
+}
+
+// CreateWebhook registers a new webhook endpoint
+func (s *WebhookService) CreateWebhook(ctx context.Context, req *CreateWebhookRequest) (*models.Webhook, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := generateSigningSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	now := time.Now()
+	webhook := &models.Webhook{
+		ID:         uuid.New(),
+		URL:        req.URL,
+		Secret:     secret,
+		Enabled:    true,
+		EventTypes: models.StringList(req.EventTypes),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err := s.repo.Create(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// GetWebhook retrieves a webhook by ID
+func (s *WebhookService) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	webhookID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	return s.repo.Get(ctx, webhookID)
+}
+
+// ListWebhooks returns every webhook
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	webhooks, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook applies a partial update. Re-enabling an endpoint clears its failure streak.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id string, req *UpdateWebhookRequest) (*models.Webhook, error) {
+	webhook, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *req.URL
+	}
+	if req.Secret != nil {
+		webhook.Secret = *req.Secret
+	}
+	if req.EventTypes != nil {
+		if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+			return nil, err
+		}
+		webhook.EventTypes = models.StringList(req.EventTypes)
+	}
+	if req.Enabled != nil {
+		if *req.Enabled && !webhook.Enabled {
+			webhook.ConsecutiveFailures = 0
+			webhook.DisabledAt = nil
+		}
+		webhook.Enabled = *req.Enabled
+	}
+	webhook.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery history
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) error {
+	webhookID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrWebhookNotFound
+	}
+	return s.repo.Delete(ctx, webhookID)
+}
+
+// ListDeliveries returns up to limit delivery attempts of a webhook, newest
+// first, starting after the given cursor when it is not nil. next points
+// after the last attempt returned and is nil on the last page.
+func (s *WebhookService) ListDeliveries(ctx context.Context, id string, after *pagination.Cursor, limit int) (deliveries []*models.WebhookDelivery, next *pagination.Cursor, err error) {
+	webhook, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// One extra row tells whether there is a next page
+	deliveries, err = s.repo.ListDeliveries(ctx, webhook.ID, after, limit+1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("database error: %w", err)
+	}
+	if len(deliveries) <= limit {
+		return deliveries, nil, nil
+	}
+	deliveries = deliveries[:limit]
+	last := deliveries[limit-1]
+	return deliveries, &pagination.Cursor{Time: last.CreatedAt, ID: last.ID.String()}, nil
+}
+
+// PurgeDeliveries deletes delivery attempts older than cutoff
+func (s *WebhookService) PurgeDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	purged, err := s.repo.DeleteDeliveriesBefore(ctx, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge webhook deliveries: %w", err)
+	}
+	return purged, nil
+}
+
+// validateWebhookURL requires an absolute http(s) URL
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+// validateWebhookEventTypes accepts known event types and the "*" wildcard
+func validateWebhookEventTypes(eventTypes []string) error {
+	if len(eventTypes) == 0 {
+		return fmt.Errorf("%w: at least one event type is required", ErrUnknownEventType)
+	}
+	for _, t := range eventTypes {
+		if t != models.WebhookEventAll && !events.Known(t) {
+			return fmt.Errorf("%w: %s", ErrUnknownEventType, t)
+		}
+	}
+	return nil
+}
+
+// generateSigningSecret returns a random hex-encoded signing secret for
+// webhooks and partners
+func generateSigningSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate signing secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// activityPage is the body of GET /users/:id/activity
+type activityPage struct {
+	Data []services.ActivityEntry `json:"data"`
+	Meta struct {
+		Total int64 `json:"total"`
+	} `json:"meta"`
+}
+
+// seedActivity replaces a user's history with one event of every kind, an hour apart
+func seedActivity(t *testing.T, ta *apptest.TestApp, user, admin *apptest.User) time.Time {
+	t.Helper()
+
+	db := ta.DB()
+	if err := db.Where("user_id = ?", user.ID).Delete(&models.LoginEvent{}).Error; err != nil {
+		t.Fatalf("clear login events: %v", err)
+	}
+
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return start.Add(time.Duration(hours) * time.Hour) }
+	audit := func(hours int, actor *apptest.User, action string, metadata interface{}) {
+		entry := &models.AuditLog{ID: uuid.New(), Action: action, EntityType: "user", EntityID: user.ID.String(), CreatedAt: at(hours)}
+		entry.ActorID = &actor.ID
+		if metadata != nil {
+			data, err := models.NewJSON(metadata)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry.Metadata = data
+		}
+		if err := db.Create(entry).Error; err != nil {
+			t.Fatalf("seed %s: %v", action, err)
+		}
+	}
+	login := func(hours int, success bool, reason string) {
+		event := &models.LoginEvent{ID: uuid.New(), UserID: &user.ID, Email: user.Email, Success: success, Reason: reason, IPAddress: "10.0.0.1", CreatedAt: at(hours)}
+		if err := db.Create(event).Error; err != nil {
+			t.Fatalf("seed login: %v", err)
+		}
+	}
+
+	audit(0, user, models.AuditActionUserCreated, nil)
+	login(1, true, "")
+	// Values in the metadata must never be shown, even when they were recorded
+	audit(2, admin, models.AuditActionUserUpdated, map[string]interface{}{"fields": []string{"email", "first_name", "password"}, "password": "hunter2"})
+	audit(3, admin, models.AuditActionUserRoleChanged, map[string]string{"from": "user", "to": "admin"})
+	audit(4, user, models.AuditActionUserPasswordChanged, nil)
+	login(5, false, "invalid_password")
+	audit(6, admin, models.AuditActionUserDeactivated, nil)
+	return start
+}
+
+// TestUserActivityTimeline tests the ordering and rendering of each event type
+func TestUserActivityTimeline(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+	seedActivity(t, ta, user, admin)
+
+	resp := ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String()+"/activity?limit=20", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	if strings.Contains(string(resp.Body), "hunter2") {
+		t.Fatalf("activity leaks a password: %s", resp.Body)
+	}
+	var page activityPage
+	resp.Decode(t, &page)
+
+	want := []struct {
+		typ, summary string
+		actor        bool
+	}{
+		{models.AuditActionUserDeactivated, "Account deactivated by " + admin.Email, true},
+		{services.ActivityLoginFailed, "Failed login attempt (invalid_password) from 10.0.0.1", false},
+		{models.AuditActionUserPasswordChanged, "Password changed", false},
+		{models.AuditActionUserRoleChanged, "Role changed from user to admin by " + admin.Email, true},
+		{models.AuditActionUserUpdated, "Updated email, first_name by " + admin.Email, true},
+		{services.ActivityLoginSucceeded, "Logged in from 10.0.0.1", false},
+		{models.AuditActionUserCreated, "Account created", false},
+	}
+	if page.Meta.Total != int64(len(want)) || len(page.Data) != len(want) {
+		t.Fatalf("expected %d entries, got %d of %d", len(want), len(page.Data), page.Meta.Total)
+	}
+	for i, w := range want {
+		entry := page.Data[i]
+		if entry.Type != w.typ || entry.Summary != w.summary || (entry.Actor != nil) != w.actor {
+			t.Errorf("entry %d: expected %s %q, got %s %q (actor %+v)", i, w.typ, w.summary, entry.Type, entry.Summary, entry.Actor)
+		}
+	}
+	if fields := page.Data[4].Fields; !reflect.DeepEqual(fields, []string{"email", "first_name"}) {
+		t.Errorf("expected the changed fields without password, got %v", fields)
+	}
+	if details := page.Data[3].Details; details["from"] != "user" || details["to"] != "admin" {
+		t.Errorf("unexpected role change details %v", details)
+	}
+}
+
+// TestUserActivityPagination tests paging across both sources and date filtering
+func TestUserActivityPagination(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+	seedActivity(t, ta, user, admin)
+	path := "/api/v1/users/" + user.ID.String() + "/activity"
+
+	types := func(query string) ([]string, int64) {
+		t.Helper()
+		resp := ta.Request(http.MethodGet, path+query, nil, user.Token)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", query, resp.StatusCode, resp.Body)
+		}
+		var page activityPage
+		resp.Decode(t, &page)
+		var types []string
+		for _, entry := range page.Data {
+			types = append(types, entry.Type)
+		}
+		return types, page.Meta.Total
+	}
+
+	got, total := types("?page=2&limit=3")
+	want := []string{models.AuditActionUserRoleChanged, models.AuditActionUserUpdated, services.ActivityLoginSucceeded}
+	if !reflect.DeepEqual(got, want) || total != 7 {
+		t.Errorf("expected page 2 to be %v of 7, got %v of %d", want, got, total)
+	}
+
+	got, total = types("?from=2024-03-01T11:00:00Z&to=2024-03-01T14:00:00Z")
+	want = []string{models.AuditActionUserPasswordChanged, models.AuditActionUserRoleChanged, models.AuditActionUserUpdated}
+	if !reflect.DeepEqual(got, want) || total != 3 {
+		t.Errorf("expected %v in range, got %v of %d", want, got, total)
+	}
+
+	if _, total := types("?to=2024-02-29"); total != 0 {
+		t.Errorf("expected nothing before the account existed, got %d", total)
+	}
+	if _, total := types("?from=2024-03-01&to=2024-03-01"); total != 7 {
+		t.Errorf("expected a day filter to include the whole day, got %d", total)
+	}
+
+	if resp := ta.Request(http.MethodGet, path+"?from=yesterday", nil, user.Token); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid date, got %d", resp.StatusCode)
+	}
+	other := ta.CreateUser(models.RoleUser)
+	if resp := ta.Request(http.MethodGet, path, nil, other.Token); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for another user, got %d", resp.StatusCode)
+	}
+}
+
+// TestUserChangesAreAudited tests that changes made through the API show up in the timeline
+func TestUserChangesAreAudited(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/users", map[string]string{
+		"email": "ann@example.com", "password": "secret123", "first_name": "Ann", "last_name": "Lee",
+	}, admin.Token)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: %d %s", resp.StatusCode, resp.Body)
+	}
+	var created struct {
+		Data models.User `json:"data"`
+	}
+	resp.Decode(t, &created)
+	userPath := "/api/v1/users/" + created.Data.ID.String()
+
+	// Unchanged values are not reported as changes
+	if resp := ta.Request(http.MethodPut, userPath, map[string]interface{}{"first_name": "Anne", "last_name": "Lee", "password": "new-secret"}, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("update: %d %s", resp.StatusCode, resp.Body)
+	}
+	if resp := ta.Request(http.MethodPost, userPath+"/deactivate", nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("deactivate: %d %s", resp.StatusCode, resp.Body)
+	}
+
+	resp = ta.Request(http.MethodGet, userPath+"/activity", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("activity: %d %s", resp.StatusCode, resp.Body)
+	}
+	if strings.Contains(string(resp.Body), "new-secret") {
+		t.Fatalf("activity leaks the password: %s", resp.Body)
+	}
+	var page activityPage
+	resp.Decode(t, &page)
+
+	byType := map[string]services.ActivityEntry{}
+	for _, entry := range page.Data {
+		byType[entry.Type] = entry
+	}
+	for _, typ := range []string{models.AuditActionUserCreated, models.AuditActionUserUpdated, models.AuditActionUserPasswordChanged, models.AuditActionUserDeactivated} {
+		entry, ok := byType[typ]
+		if !ok {
+			t.Errorf("expected a %s entry, got %+v", typ, page.Data)
+			continue
+		}
+		if entry.Actor == nil || entry.Actor.ID != admin.ID.String() {
+			t.Errorf("expected %s to name the admin, got %+v", typ, entry.Actor)
+		}
+	}
+	if fields := byType[models.AuditActionUserUpdated].Fields; !reflect.DeepEqual(fields, []string{"first_name"}) {
+		t.Errorf("expected only first_name changed, got %v", fields)
+	}
+	if page.Meta.Total != 4 {
+		t.Errorf("expected 4 entries, got %d", page.Meta.Total)
+	}
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+)
+
+// featureRoutes are the routes each API feature registers
+var featureRoutes = map[string][]route{
+	config.FeatureRegistration:  {{"POST", "/api/v1/auth/register"}},
+	config.FeatureUserCreate:    {{"POST", "/api/v1/users"}},
+	config.FeatureUserDelete:    {{"DELETE", "/api/v1/users/:id"}},
+	config.FeaturePasswordReset: {{"POST", "/api/v1/users/:id/require-password-change"}},
+	config.FeatureEmailChange:   {{"POST", "/api/v1/me/email-change"}, {"POST", "/api/v1/auth/confirm-email-change"}},
+	config.FeatureImpersonation: {{"POST", "/api/v1/admin/impersonate/:id"}, {"POST", "/api/v1/admin/impersonate/stop"}},
+	config.FeatureUserExport:    {{"GET", "/api/v1/users/export"}, {"POST", "/api/v1/users/export"}},
+	config.FeatureWebhooks: {
+		{"GET", "/api/v1/webhooks"}, {"POST", "/api/v1/webhooks"}, {"GET", "/api/v1/webhooks/:id"}, {"PUT", "/api/v1/webhooks/:id"},
+		{"DELETE", "/api/v1/webhooks/:id"}, {"GET", "/api/v1/webhooks/:id/deliveries"}, {"POST", "/api/v1/webhooks/:id/test"},
+	},
+	config.FeatureOrganizations: {
+		{"GET", "/api/v1/organizations"}, {"POST", "/api/v1/organizations"}, {"GET", "/api/v1/organizations/:id"},
+		{"PUT", "/api/v1/organizations/:id"}, {"DELETE", "/api/v1/organizations/:id"}, {"GET", "/api/v1/organizations/:id/members"},
+		{"POST", "/api/v1/organizations/:id/members"}, {"DELETE", "/api/v1/organizations/:id/members/:userId"},
+	},
+	config.FeaturePartners: {
+		{"GET", "/api/v1/admin/partners"}, {"POST", "/api/v1/admin/partners"}, {"GET", "/api/v1/admin/partners/:id"},
+		{"PUT", "/api/v1/admin/partners/:id"}, {"DELETE", "/api/v1/admin/partners/:id"}, {"POST", "/api/v1/partners/provision-user"},
+	},
+}
+
+// TestAPIFeatureRoutes tests that turned off features register none of
+// their routes, and that the version endpoint reports them
+func TestAPIFeatureRoutes(t *testing.T) {
+	allOff := make(config.APIFeatures)
+	for _, feature := range config.Features {
+		allOff[feature] = false
+	}
+
+	tests := map[string]config.APIFeatures{
+		"defaults":     nil,
+		"idp synced":   {config.FeatureRegistration: false, config.FeatureUserDelete: false, config.FeaturePasswordReset: true},
+		"all disabled": allOff,
+	}
+	for name, features := range tests {
+		t.Run(name, func(t *testing.T) {
+			ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+				cfg.API.Features = features
+			})
+
+			want := make(map[route]bool)
+			for _, r := range expectedRoutes {
+				want[r] = true
+			}
+			for feature, routes := range featureRoutes {
+				for _, r := range routes {
+					if !want[r] {
+						t.Fatalf("%s: %s %s is not an expected route", feature, r.method, r.path)
+					}
+					if !features.Enabled(feature) {
+						delete(want, r)
+					}
+				}
+			}
+			registered := make(map[route]bool)
+			for _, r := range ta.App.GetRouter().Routes() {
+				registered[route{r.Method, r.Path}] = true
+				if !want[route{r.Method, r.Path}] {
+					t.Errorf("unexpected route %s %s", r.Method, r.Path)
+				}
+			}
+			for r := range want {
+				if !registered[r] {
+					t.Errorf("missing route %s %s", r.method, r.path)
+				}
+			}
+
+			var version struct {
+				Version  string          `json:"version"`
+				Features map[string]bool `json:"features"`
+			}
+			ta.Request(http.MethodGet, "/api/v1/version", nil, "").Decode(t, &version)
+			if version.Version == "" || len(version.Features) != len(config.Features) {
+				t.Fatalf("expected the build and every feature, got %+v", version)
+			}
+			for _, feature := range config.Features {
+				if version.Features[feature] != features.Enabled(feature) {
+					t.Errorf("%s: expected %v, got %v", feature, features.Enabled(feature), version.Features[feature])
+				}
+			}
+		})
+	}
+}
+
+// TestAPIFeaturesDisabledEndpoints tests that turned off endpoints answer
+// 404 while the rest of their group works
+func TestAPIFeaturesDisabledEndpoints(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.API.Features = config.APIFeatures{config.FeatureRegistration: false, config.FeatureUserDelete: false}
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"email": "new@example.com", "password": "secret123", "first_name": "New", "last_name": "User", "username": "new",
+	}, "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("register: expected 404, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	if resp := ta.Request(http.MethodDelete, "/api/v1/users/"+user.ID.String(), nil, admin.Token); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("delete: expected 404, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	ta.Login(user.Email, user.Password)
+	if resp := ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String(), nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("get: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+}
+
+// TestAPIFeaturesConfig tests turning features off with API_DISABLED_FEATURES
+func TestAPIFeaturesConfig(t *testing.T) {
+	t.Setenv("API_DISABLED_FEATURES", "registration, user_delete")
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, feature := range config.Features {
+		want := feature != config.FeatureRegistration && feature != config.FeatureUserDelete
+		if cfg.API.Features.Enabled(feature) != want {
+			t.Errorf("%s: expected enabled %v", feature, want)
+		}
+	}
+
+	t.Setenv("API_DISABLED_FEATURES", "registraton")
+	if _, err := config.LoadConfig(); err == nil {
+		t.Error("expected an error for an unknown feature")
+	}
+}
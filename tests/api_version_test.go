@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+)
+
+// TestAPIVersionsServeUsers tests that v1 and v2 serve the same users side
+// by side, each in its own shape
+func TestAPIVersionsServeUsers(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	member := ta.CreateUser(models.RoleUser)
+	for i := 0; i < 2; i++ {
+		ta.CreateUser(models.RoleUser)
+	}
+	if err := ta.DB().Model(&models.User{}).Where("id = ?", member.ID).
+		Updates(map[string]any{"first_name": "Ann", "last_name": "Lee", "active": false}).Error; err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	t.Run("list", func(t *testing.T) {
+		var v1 struct {
+			Data []map[string]json.RawMessage `json:"data"`
+			Meta struct {
+				Page       int   `json:"page"`
+				Limit      int   `json:"limit"`
+				Total      int64 `json:"total"`
+				TotalPages int   `json:"total_pages"`
+			} `json:"meta"`
+		}
+		resp := ta.Request(http.MethodGet, "/api/v1/users?limit=3", nil, admin.Token)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("v1: %d %s", resp.StatusCode, resp.Body)
+		}
+		resp.Decode(t, &v1)
+		if v1.Meta.Page != 1 || v1.Meta.Limit != 3 || v1.Meta.Total != 4 || v1.Meta.TotalPages != 2 || len(v1.Data) != 3 {
+			t.Errorf("v1: unexpected page %+v with %d users", v1.Meta, len(v1.Data))
+		}
+		for _, field := range []string{"first_name", "last_name", "active", "status"} {
+			if _, ok := v1.Data[0][field]; !ok {
+				t.Errorf("v1: expected %s in %v", field, v1.Data[0])
+			}
+		}
+
+		var v2 map[string]json.RawMessage
+		resp = ta.Request(http.MethodGet, "/api/v2/users?limit=3", nil, admin.Token)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("v2: %d %s", resp.StatusCode, resp.Body)
+		}
+		resp.Decode(t, &v2)
+		for field, want := range map[string]string{"page": "1", "per_page": "3", "total": "4", "total_pages": "2"} {
+			if got := string(v2[field]); got != want {
+				t.Errorf("v2: expected %s %s, got %s", field, want, got)
+			}
+		}
+		if _, ok := v2["meta"]; ok {
+			t.Errorf("v2: expected no meta block, got %s", resp.Body)
+		}
+		var items []map[string]json.RawMessage
+		if err := json.Unmarshal(v2["data"], &items); err != nil || len(items) != 3 {
+			t.Fatalf("v2: expected 3 users, got %s (%v)", v2["data"], err)
+		}
+		for _, field := range []string{"first_name", "last_name", "active", "deleted_at", "must_change_password"} {
+			if _, ok := items[0][field]; ok {
+				t.Errorf("v2: unexpected %s in %v", field, items[0])
+			}
+		}
+		for _, field := range []string{"id", "email", "given_name", "family_name", "role", "status", "created_at"} {
+			if _, ok := items[0][field]; !ok {
+				t.Errorf("v2: expected %s in %v", field, items[0])
+			}
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		var v1 struct {
+			Data struct {
+				FirstName string `json:"first_name"`
+				Active    bool   `json:"active"`
+			} `json:"data"`
+		}
+		resp := ta.Request(http.MethodGet, "/api/v1/users/"+member.ID.String(), nil, admin.Token)
+		resp.Decode(t, &v1)
+		if resp.StatusCode != http.StatusOK || v1.Data.FirstName != "Ann" || v1.Data.Active {
+			t.Errorf("v1: unexpected %d %s", resp.StatusCode, resp.Body)
+		}
+
+		var v2 struct {
+			Data struct {
+				GivenName  string `json:"given_name"`
+				FamilyName string `json:"family_name"`
+				Status     string `json:"status"`
+			} `json:"data"`
+		}
+		resp = ta.Request(http.MethodGet, "/api/v2/users/"+member.ID.String(), nil, admin.Token)
+		resp.Decode(t, &v2)
+		if resp.StatusCode != http.StatusOK || v2.Data.GivenName != "Ann" || v2.Data.FamilyName != "Lee" || v2.Data.Status != "deactivated" {
+			t.Errorf("v2: unexpected %d %s", resp.StatusCode, resp.Body)
+		}
+
+		// Errors keep their shape in every version
+		expectErrorCode(t, ta.Request(http.MethodGet, "/api/v2/users/missing", nil, admin.Token), http.StatusNotFound, errors.CodeUserNotFound)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		var v1 struct {
+			Data []struct {
+				FirstName string  `json:"first_name"`
+				Score     float64 `json:"score"`
+			} `json:"data"`
+			Meta struct {
+				Total int64 `json:"total"`
+			} `json:"meta"`
+		}
+		resp := ta.Request(http.MethodGet, "/api/v1/users/search?q=Ann", nil, admin.Token)
+		resp.Decode(t, &v1)
+		if resp.StatusCode != http.StatusOK || v1.Meta.Total != 1 || len(v1.Data) != 1 || v1.Data[0].FirstName != "Ann" || v1.Data[0].Score <= 0 {
+			t.Errorf("v1: unexpected %d %s", resp.StatusCode, resp.Body)
+		}
+
+		var v2 struct {
+			Data []struct {
+				GivenName string  `json:"given_name"`
+				Score     float64 `json:"score"`
+			} `json:"data"`
+			Total int64 `json:"total"`
+		}
+		resp = ta.Request(http.MethodGet, "/api/v2/users/search?q=Ann", nil, admin.Token)
+		resp.Decode(t, &v2)
+		if resp.StatusCode != http.StatusOK || v2.Total != 1 || len(v2.Data) != 1 || v2.Data[0].GivenName != "Ann" || v2.Data[0].Score <= 0 {
+			t.Errorf("v2: unexpected %d %s", resp.StatusCode, resp.Body)
+		}
+	})
+}
+
+// TestV1Deprecation tests the Deprecation and Sunset headers of v1
+// responses, which are only sent once configured and never on v2
+func TestV1Deprecation(t *testing.T) {
+	t.Run("unset", func(t *testing.T) {
+		ta := apptest.NewTestApp(t)
+		admin := ta.CreateUser(models.RoleAdmin)
+
+		resp := ta.Request(http.MethodGet, "/api/v1/users", nil, admin.Token)
+		if resp.Header.Get("Deprecation") != "" || resp.Header.Get("Sunset") != "" {
+			t.Errorf("expected no deprecation headers, got %v", resp.Header)
+		}
+	})
+
+	t.Run("set", func(t *testing.T) {
+		ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+			cfg.API.V1Deprecation = config.DeprecationConfig{
+				DeprecatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				Sunset:       time.Date(2025, 7, 1, 0, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+			}
+		})
+		admin := ta.CreateUser(models.RoleAdmin)
+
+		for _, resp := range []*apptest.Response{
+			ta.Request(http.MethodGet, "/api/v1/users", nil, admin.Token),
+			ta.Request(http.MethodGet, "/api/v1/users", nil, ""),
+		} {
+			if got := resp.Header.Get("Deprecation"); got != "@1735689600" {
+				t.Errorf("expected Deprecation @1735689600, got %q", got)
+			}
+			if got := resp.Header.Get("Sunset"); got != "Mon, 30 Jun 2025 22:00:00 GMT" {
+				t.Errorf("expected Sunset Mon, 30 Jun 2025 22:00:00 GMT, got %q", got)
+			}
+		}
+
+		resp := ta.Request(http.MethodGet, "/api/v2/users", nil, admin.Token)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Deprecation") != "" || resp.Header.Get("Sunset") != "" {
+			t.Errorf("expected v2 without deprecation headers, got %d %v", resp.StatusCode, resp.Header)
+		}
+	})
+}
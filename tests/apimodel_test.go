@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/apimodel"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// apiTimestamp matches a timestamp in the API's format
+var apiTimestamp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}Z$`)
+
+// apimodelBase is embedded in apimodelRecord
+type apimodelBase struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Shadowed  string    `json:"name"`
+}
+
+// apimodelRecord covers the field kinds apimodel converts
+type apimodelRecord struct {
+	apimodelBase
+	Name      string         `json:"name"`
+	UpdatedAt *time.Time     `json:"updated_at"`
+	ReadAt    *time.Time     `json:"read_at,omitempty"`
+	Removed   gorm.DeletedAt `json:"removed_at"`
+	Seen      sql.NullTime   `json:"seen_at"`
+	Secret    string         `json:"-"`
+	Untagged  int
+	*apimodelExtra
+}
+
+// apimodelExtra is embedded by pointer in apimodelRecord
+type apimodelExtra struct {
+	Note string `json:"note"`
+}
+
+func (r apimodelRecord) MarshalJSON() ([]byte, error) { return apimodel.Marshal(r) }
+
+// TestAPIModelTimestamps tests the rendering of every kind of timestamp
+func TestAPIModelTimestamps(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+	created := time.Date(2024, 5, 1, 12, 0, 0, 123456789, berlin)
+
+	record := apimodelRecord{
+		apimodelBase: apimodelBase{ID: uuid.MustParse("0B1C52E8-6C2E-4A52-8B2A-3F7F3C2D7A10"), CreatedAt: created, Shadowed: "hidden"},
+		Name:         "record",
+		Removed:      gorm.DeletedAt{Time: created, Valid: true},
+		Secret:       "secret",
+		Untagged:     7,
+	}
+	got, err := json.Marshal(record)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"id":"0b1c52e8-6c2e-4a52-8b2a-3f7f3c2d7a10","created_at":"2024-05-01T10:00:00.123Z","name":"record",` +
+		`"updated_at":null,"removed_at":"2024-05-01T10:00:00.123Z","seen_at":null,"Untagged":7}`
+	if string(got) != want {
+		t.Errorf("unexpected JSON\n got: %s\nwant: %s", got, want)
+	}
+
+	record.apimodelExtra = &apimodelExtra{Note: "extra"}
+	record.ReadAt = &created
+	record.Seen = sql.NullTime{Time: created, Valid: true}
+	got, _ = json.Marshal(&record)
+	for _, fragment := range []string{`"read_at":"2024-05-01T10:00:00.123Z"`, `"seen_at":"2024-05-01T10:00:00.123Z"`, `"note":"extra"`} {
+		if !bytes.Contains(got, []byte(fragment)) {
+			t.Errorf("expected %s in %s", fragment, got)
+		}
+	}
+}
+
+// TestAPIModelRoundTrip tests that rendered resources decode back to the
+// same values, to the millisecond
+func TestAPIModelRoundTrip(t *testing.T) {
+	local := time.FixedZone("EST", -5*60*60)
+	deleted := time.Date(2024, 1, 31, 23, 59, 59, 999999999, local)
+	user := models.User{
+		ID:        uuid.New(),
+		Email:     "round@example.com",
+		Role:      models.RoleAdmin,
+		CreatedAt: time.Date(2023, 12, 31, 20, 0, 0, 1500000, local),
+		UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		DeletedAt: &deleted,
+	}
+
+	data, err := json.Marshal(&user)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded models.User
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded.ID != user.ID || decoded.Email != user.Email || decoded.Role != user.Role {
+		t.Errorf("unexpected user %+v", decoded)
+	}
+	for name, pair := range map[string][2]time.Time{
+		"created_at": {user.CreatedAt, decoded.CreatedAt},
+		"updated_at": {user.UpdatedAt, decoded.UpdatedAt},
+		"deleted_at": {*user.DeletedAt, *decoded.DeletedAt},
+	} {
+		if want := pair[0].Truncate(time.Millisecond); !pair[1].Equal(want) || pair[1].Location() != time.UTC {
+			t.Errorf("%s: expected %s in UTC, got %s", name, want.UTC(), pair[1])
+		}
+	}
+
+	again, _ := json.Marshal(&decoded)
+	if !bytes.Equal(data, again) {
+		t.Errorf("expected a stable encoding\nfirst:  %s\nsecond: %s", data, again)
+	}
+
+	var ts apimodel.Time
+	if err := json.Unmarshal([]byte(`"2024-05-01T12:00:00.5+02:00"`), &ts); err != nil || apimodel.FormatTime(time.Time(ts)) != "2024-05-01T10:00:00.500Z" {
+		t.Errorf("expected any RFC 3339 time to decode, got %v %v", time.Time(ts), err)
+	}
+	if err := json.Unmarshal([]byte(`"yesterday"`), &ts); err == nil {
+		t.Error("expected an invalid timestamp to fail")
+	}
+}
+
+// TestAPIModelEmbeddingTypes tests that types embedding a model keep their
+// own fields
+func TestAPIModelEmbeddingTypes(t *testing.T) {
+	result := services.UserSearchResult{User: &models.User{ID: uuid.New()}, Score: 0.5}
+	data, _ := json.Marshal(result)
+	var decoded map[string]any
+	json.Unmarshal(data, &decoded)
+	if decoded["score"] != 0.5 || decoded["id"] != result.ID.String() || !apiTimestamp.MatchString(decoded["created_at"].(string)) {
+		t.Errorf("unexpected search result %s", data)
+	}
+}
+
+// TestUserJSONContract pins the exact shape of a user in API responses
+func TestUserJSONContract(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	zone := time.FixedZone("IST", 5*60*60+30*60)
+	user := &models.User{
+		ID:        uuid.MustParse("5f1d7c62-3a1e-4f7b-9c2d-8e4a6b0c1d2e"),
+		Email:     "contract@example.com",
+		Username:  "contract",
+		Password:  "not-a-real-hash",
+		FirstName: "Con",
+		LastName:  "Tract",
+		Role:      models.RoleUser,
+		Active:    true,
+		CreatedAt: time.Date(2024, 3, 10, 9, 15, 30, 987654321, zone),
+		UpdatedAt: time.Date(2024, 3, 11, 5, 30, 0, 0, zone),
+	}
+	if err := ta.DB().Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	resp := ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String(), nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	resp.Decode(t, &envelope)
+
+	want := `{"id":"5f1d7c62-3a1e-4f7b-9c2d-8e4a6b0c1d2e","email":"contract@example.com","username":"contract",` +
+		`"first_name":"Con","last_name":"Tract","role":"user","active":true,` +
+		`"created_at":"2024-03-10T03:45:30.987Z","updated_at":"2024-03-11T00:00:00.000Z","deleted_at":null,` +
+		`"must_change_password":false}`
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, envelope.Data); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if compact.String() != want {
+		t.Errorf("user JSON changed shape\n got: %s\nwant: %s", compact.String(), want)
+	}
+}
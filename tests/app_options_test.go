@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/database/migrations"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/service/servicetest"
+	"BackofficeGoService/internal/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// newOptionsApp builds an application from cfg with opts and shuts it down after the test
+func newOptionsApp(t *testing.T, cfg *config.Config, opts ...app.Option) *app.Application {
+	t.Helper()
+	application, err := app.New(cfg, logger.NewNopLogger(), opts...)
+	if err != nil {
+		t.Fatalf("create application: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		application.Shutdown(ctx)
+	})
+	return application
+}
+
+func serve(application *app.Application, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	application.GetRouter().ServeHTTP(w, req)
+	return w
+}
+
+// seedUser stores an active user with password "secret123" through the manager's GORM handle
+func seedUser(t *testing.T, driver *databasetest.MockDriver, email string, role models.UserRole) *models.User {
+	t.Helper()
+	hash, err := utils.HashPassword("secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{ID: uuid.New(), Email: email, Password: hash, Role: role, Active: true}
+	if err := driver.GormDB().Create(user).Error; err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+	return user
+}
+
+// TestAppWithDBManager tests that an injected manager replaces the configured databases
+func TestAppWithDBManager(t *testing.T) {
+	db, driver := databasetest.NewManager(t, databasetest.WithSQLite())
+	if _, err := migrations.NewRunner(driver.GormDB()).Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	seedUser(t, driver, "ann@example.com", models.RoleUser)
+
+	// Nothing listens here, so connecting the configured database would fail New
+	cfg := apptest.Config()
+	cfg.Database.Primary = config.DatabaseConnectionConfig{Driver: "postgresql", Host: "127.0.0.1", Port: "1", DBName: "none"}
+
+	application := newOptionsApp(t, cfg, app.WithDBManager(db))
+	if application.GetDBManager() != db {
+		t.Fatal("expected the injected manager")
+	}
+
+	w := serve(application, http.MethodPost, "/api/v1/auth/login", "", `{"email":"ann@example.com","password":"secret123"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected login against the injected database, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestAppWithUserService tests that the user routes are served by an injected user service
+func TestAppWithUserService(t *testing.T) {
+	db, driver := databasetest.NewManager(t, databasetest.WithSQLite())
+	if _, err := migrations.NewRunner(driver.GormDB()).Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	seedUser(t, driver, "admin@example.com", models.RoleAdmin)
+
+	// This user only exists in the fake
+	fake := servicetest.NewUserService()
+	ghost := fake.Add(&models.User{Email: "ghost@example.com", Role: models.RoleUser, Active: true})
+
+	application := newOptionsApp(t, apptest.Config(), app.WithDBManager(db), app.WithUserService(fake))
+
+	w := serve(application, http.MethodPost, "/api/v1/auth/login", "", `{"email":"admin@example.com","password":"secret123"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", w.Code, w.Body.String())
+	}
+	token := strings.Split(strings.Split(w.Body.String(), `"token":"`)[1], `"`)[0]
+
+	w = serve(application, http.MethodGet, "/api/v1/users/"+ghost.ID.String(), token, "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "ghost@example.com") {
+		t.Fatalf("expected the fake's user, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+type recordingEmailClient struct {
+	sent []string
+}
+
+func (c *recordingEmailClient) Send(to, subject, body string) error {
+	c.sent = append(c.sent, to)
+	return nil
+}
+
+// TestAppWithEmailClientAndMiddleware tests the email client and router middleware options
+func TestAppWithEmailClientAndMiddleware(t *testing.T) {
+	mailer := &recordingEmailClient{}
+	var seen []string
+	record := func(c *gin.Context) {
+		seen = append(seen, c.Request.URL.Path)
+		c.Header("X-Injected", "yes")
+		c.Next()
+	}
+
+	application := newOptionsApp(t, apptest.Config(), app.WithEmailClient(mailer), app.WithRouterMiddleware(record))
+	if application.GetEmailClient() != mailer {
+		t.Error("expected the injected email client")
+	}
+
+	w := serve(application, http.MethodGet, "/health", "", "")
+	if w.Header().Get("X-Injected") != "yes" || len(seen) != 1 || seen[0] != "/health" {
+		t.Errorf("expected the middleware to run, got header %q and paths %v", w.Header().Get("X-Injected"), seen)
+	}
+
+	// Unmatched routes go through the middleware too
+	serve(application, http.MethodGet, "/missing", "", "")
+	if len(seen) != 2 {
+		t.Errorf("expected the middleware on every request, got %v", seen)
+	}
+}
+
+// TestAppWithoutOptions tests that New still connects and migrates the configured database
+func TestAppWithoutOptions(t *testing.T) {
+	application := newOptionsApp(t, apptest.Config())
+	if _, err := application.GetDBManager().GetDriver("primary"); err != nil {
+		t.Fatalf("expected the configured primary database: %v", err)
+	}
+	if application.GetEmailClient() != nil {
+		t.Error("expected no email client by default")
+	}
+}
@@ -0,0 +1,317 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+)
+
+// approvalEnvelope is the body of responses carrying an approval
+type approvalEnvelope struct {
+	Data models.Approval `json:"data"`
+}
+
+// requestPromotion asks for user to become an admin on behalf of requester
+// and returns the approval holding the change back
+func requestPromotion(t *testing.T, ta *apptest.TestApp, requester, user *apptest.User) models.Approval {
+	t.Helper()
+
+	resp := ta.Request(http.MethodPut, "/api/v1/users/"+user.ID.String()+"/role", map[string]string{"role": "admin"}, requester.Token)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected the promotion to await approval, got %d %s", resp.StatusCode, resp.Body)
+	}
+	var body approvalEnvelope
+	resp.Decode(t, &body)
+	if body.Data.Status != models.ApprovalStatusPending || body.Data.SubjectID != user.ID || body.Data.RequesterID != requester.ID {
+		t.Fatalf("unexpected approval %+v", body.Data)
+	}
+	if got := resp.Header.Get("Location"); got != "/api/v1/admin/approvals/"+body.Data.ID.String() {
+		t.Errorf("unexpected Location %q", got)
+	}
+	return body.Data
+}
+
+// userRole reads a user's role from the database
+func userRole(t *testing.T, ta *apptest.TestApp, user *apptest.User) models.UserRole {
+	t.Helper()
+
+	var stored models.User
+	if err := ta.DB().Where("id = ?", user.ID).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	return stored.Role
+}
+
+// TestRoleChangeApproval tests that a promotion to admin waits for a second
+// admin, who applies it, and that both admins are audited
+func TestRoleChangeApproval(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	requester := ta.CreateUser(models.RoleAdmin)
+	approver := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	approval := requestPromotion(t, ta, requester, user)
+	if got := userRole(t, ta, user); got != models.RoleUser {
+		t.Fatalf("expected the role to be held back, got %s", got)
+	}
+	if n := countRows(t, ta.DB(), &models.Notification{}, "user_id = ? AND type = ?", approver.ID, models.NotificationTypeApprovalRequested); n != 1 {
+		t.Errorf("expected the other admin to be notified, got %d notifications", n)
+	}
+	if n := countRows(t, ta.DB(), &models.Notification{}, "user_id = ? AND type = ?", requester.ID, models.NotificationTypeApprovalRequested); n != 0 {
+		t.Errorf("expected the requester not to be notified of their own request, got %d notifications", n)
+	}
+	expectErrorCode(t, ta.Request(http.MethodPut, "/api/v1/users/"+user.ID.String()+"/role", map[string]string{"role": "admin"}, approver.Token),
+		http.StatusConflict, errors.CodeApprovalPending)
+
+	resp := ta.Request(http.MethodGet, "/api/v1/admin/approvals?status=pending&subject_id="+user.ID.String(), nil, approver.Token)
+	var list struct {
+		Data []models.Approval `json:"data"`
+	}
+	resp.Decode(t, &list)
+	if resp.StatusCode != http.StatusOK || len(list.Data) != 1 || list.Data[0].ID != approval.ID {
+		t.Fatalf("expected the approval to be listed, got %d %s", resp.StatusCode, resp.Body)
+	}
+	expectErrorCode(t, ta.Request(http.MethodGet, "/api/v1/admin/approvals?status=unknown", nil, approver.Token), http.StatusBadRequest, errors.CodeInvalidFilter)
+	expectErrorCode(t, ta.Request(http.MethodGet, "/api/v1/admin/approvals", nil, user.Token), http.StatusForbidden, errors.CodeInsufficientPermissions)
+
+	resp = ta.Request(http.MethodPost, "/api/v1/admin/approvals/"+approval.ID.String()+"/approve", nil, approver.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the approval to succeed, got %d %s", resp.StatusCode, resp.Body)
+	}
+	var decided approvalEnvelope
+	resp.Decode(t, &decided)
+	if decided.Data.Status != models.ApprovalStatusApproved || decided.Data.DeciderID == nil || *decided.Data.DeciderID != approver.ID {
+		t.Errorf("unexpected approval %+v", decided.Data)
+	}
+	if got := userRole(t, ta, user); got != models.RoleAdmin {
+		t.Errorf("expected the user to be an admin, got %s", got)
+	}
+
+	if n := auditCount(t, ta, models.AuditActionApprovalRequested, requester, approval.ID.String()); n != 1 {
+		t.Errorf("expected the request to be audited, got %d", n)
+	}
+	if n := auditCount(t, ta, models.AuditActionApprovalApproved, approver, approval.ID.String()); n != 1 {
+		t.Errorf("expected the approval to be audited, got %d", n)
+	}
+	var entry models.AuditLog
+	if err := ta.DB().Where("action = ? AND entity_id = ?", models.AuditActionUserRoleChanged, user.ID.String()).First(&entry).Error; err != nil {
+		t.Fatalf("expected the role change to be audited: %v", err)
+	}
+	var metadata map[string]string
+	if err := json.Unmarshal(entry.Metadata, &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata["requester_id"] != requester.ID.String() || metadata["approver_id"] != approver.ID.String() || metadata["to"] != "admin" {
+		t.Errorf("expected the role change to name both admins, got %s", entry.Metadata)
+	}
+	if n := countRows(t, ta.DB(), &models.Notification{}, "user_id = ? AND type = ?", requester.ID, models.NotificationTypeApprovalDecided); n != 1 {
+		t.Errorf("expected the requester to be told of the decision, got %d notifications", n)
+	}
+
+	// The user's token carries the old role
+	if resp := ta.Request(http.MethodGet, "/api/v1/me", nil, user.Token); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the user's token to be revoked, got %d", resp.StatusCode)
+	}
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/admin/approvals/"+approval.ID.String()+"/approve", nil, approver.Token),
+		http.StatusConflict, errors.CodeApprovalNotPending)
+}
+
+// TestRoleChangeRejection tests that a rejected promotion is never applied
+func TestRoleChangeRejection(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	requester := ta.CreateUser(models.RoleAdmin)
+	approver := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	approval := requestPromotion(t, ta, requester, user)
+	resp := ta.Request(http.MethodPost, "/api/v1/admin/approvals/"+approval.ID.String()+"/reject", map[string]string{"reason": "not needed"}, approver.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the rejection to succeed, got %d %s", resp.StatusCode, resp.Body)
+	}
+	var decided approvalEnvelope
+	resp.Decode(t, &decided)
+	if decided.Data.Status != models.ApprovalStatusRejected || decided.Data.Reason != "not needed" {
+		t.Errorf("unexpected approval %+v", decided.Data)
+	}
+	if got := userRole(t, ta, user); got != models.RoleUser {
+		t.Errorf("expected the role to be unchanged, got %s", got)
+	}
+	if n := auditCount(t, ta, models.AuditActionApprovalRejected, approver, approval.ID.String()); n != 1 {
+		t.Errorf("expected the rejection to be audited, got %d", n)
+	}
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/admin/approvals/"+approval.ID.String()+"/approve", nil, approver.Token),
+		http.StatusConflict, errors.CodeApprovalNotPending)
+
+	// Once decided, the change may be requested again
+	requestPromotion(t, ta, requester, user)
+}
+
+// TestRoleChangeApprovalExpiry tests that approvals nobody decides in time
+// expire and can no longer be approved
+func TestRoleChangeApprovalExpiry(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	requester := ta.CreateUser(models.RoleAdmin)
+	approver := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	approval := requestPromotion(t, ta, requester, user)
+	if !approval.ExpiresAt.After(time.Now().Add(71 * time.Hour)) {
+		t.Errorf("expected the approval to expire after the configured TTL, got %s", approval.ExpiresAt)
+	}
+	if err := ta.DB().Model(&models.Approval{}).Where("id = ?", approval.ID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/admin/approvals/"+approval.ID.String()+"/approve", nil, approver.Token),
+		http.StatusConflict, errors.CodeApprovalExpired)
+	if got := userRole(t, ta, user); got != models.RoleUser {
+		t.Errorf("expected the role to be unchanged, got %s", got)
+	}
+
+	resp := ta.Request(http.MethodGet, "/api/v1/admin/approvals/"+approval.ID.String(), nil, approver.Token)
+	var body approvalEnvelope
+	resp.Decode(t, &body)
+	if body.Data.Status != models.ApprovalStatusExpired {
+		t.Errorf("expected the approval to have expired, got %s", body.Data.Status)
+	}
+}
+
+// TestSelfApprovalGuard tests that requesters can neither approve nor reject
+// their own changes, and that only they can cancel them
+func TestSelfApprovalGuard(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	requester := ta.CreateUser(models.RoleAdmin)
+	other := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	approval := requestPromotion(t, ta, requester, user)
+	path := "/api/v1/admin/approvals/" + approval.ID.String()
+	expectErrorCode(t, ta.Request(http.MethodPost, path+"/approve", nil, requester.Token), http.StatusForbidden, errors.CodeSelfApproval)
+	expectErrorCode(t, ta.Request(http.MethodPost, path+"/reject", nil, requester.Token), http.StatusForbidden, errors.CodeSelfApproval)
+	if got := userRole(t, ta, user); got != models.RoleUser {
+		t.Errorf("expected the role to be unchanged, got %s", got)
+	}
+
+	expectErrorCode(t, ta.Request(http.MethodDelete, path, nil, other.Token), http.StatusForbidden, errors.CodeNotApprovalRequester)
+	if resp := ta.Request(http.MethodDelete, path, nil, requester.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the requester to cancel the approval, got %d %s", resp.StatusCode, resp.Body)
+	}
+	expectErrorCode(t, ta.Request(http.MethodPost, path+"/approve", nil, other.Token), http.StatusConflict, errors.CodeApprovalNotPending)
+	expectErrorCode(t, ta.Request(http.MethodGet, "/api/v1/admin/approvals/"+user.ID.String(), nil, other.Token), http.StatusNotFound, errors.CodeApprovalNotFound)
+}
+
+// TestUnprotectedRoleChange tests that role changes outside the protected
+// transitions apply at once, and that the last admin keeps the role
+func TestUnprotectedRoleChange(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	resp := ta.Request(http.MethodPut, "/api/v1/users/"+user.ID.String()+"/role", map[string]string{"role": "guest"}, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the role to change at once, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if got := userRole(t, ta, user); got != models.RoleGuest {
+		t.Errorf("expected the user to be a guest, got %s", got)
+	}
+	if n := auditCount(t, ta, models.AuditActionUserRoleChanged, admin, user.ID.String()); n != 1 {
+		t.Errorf("expected the role change to be audited, got %d", n)
+	}
+
+	expectErrorCode(t, ta.Request(http.MethodPut, "/api/v1/users/"+user.ID.String()+"/role", map[string]string{"role": "owner"}, admin.Token),
+		http.StatusUnprocessableEntity, errors.CodeInvalidRole)
+	expectErrorCode(t, ta.Request(http.MethodPut, "/api/v1/users/"+admin.ID.String()+"/role", map[string]string{"role": "user"}, admin.Token),
+		http.StatusConflict, errors.CodeLastAdminDemotion)
+}
+
+// TestApprovedDemotionKeepsLastAdmin tests that approving demotions
+// requested while other admins existed still leaves an active admin
+func TestApprovedDemotionKeepsLastAdmin(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Approvals.ProtectedRoleChanges = []string{"admin->user"}
+	})
+	ann := ta.CreateUser(models.RoleAdmin)
+	bob := ta.CreateUser(models.RoleAdmin)
+
+	demote := func(requester, user *apptest.User) string {
+		resp := ta.Request(http.MethodPut, "/api/v1/users/"+user.ID.String()+"/role", map[string]string{"role": "user"}, requester.Token)
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected the demotion to await approval, got %d %s", resp.StatusCode, resp.Body)
+		}
+		var body approvalEnvelope
+		resp.Decode(t, &body)
+		return "/api/v1/admin/approvals/" + body.Data.ID.String()
+	}
+	demoteBob := demote(ann, bob)
+	demoteAnn := demote(bob, ann)
+
+	if resp := ta.Request(http.MethodPost, demoteBob+"/approve", nil, bob.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the first demotion approved, got %d %s", resp.StatusCode, resp.Body)
+	}
+	expectErrorCode(t, ta.Request(http.MethodPost, demoteAnn+"/approve", nil, ann.Token), http.StatusConflict, errors.CodeLastAdminDemotion)
+	if got := userRole(t, ta, ann); got != models.RoleAdmin {
+		t.Errorf("expected the last admin kept, got %s", got)
+	}
+
+	resp := ta.Request(http.MethodGet, demoteAnn, nil, ann.Token)
+	var body approvalEnvelope
+	resp.Decode(t, &body)
+	if body.Data.Status != models.ApprovalStatusPending {
+		t.Errorf("expected the refused approval still pending, got %s", body.Data.Status)
+	}
+}
+
+// TestRoleChangeClock tests that role changes are stamped by the service's clock
+func TestRoleChangeClock(t *testing.T) {
+	ctx := context.Background()
+	db, driver := databasetest.NewManager(t, databasetest.WithSQLite(&models.User{}))
+	store := cache.NewMemoryStore()
+	clk := clock.NewFake(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))
+	users := services.NewUserService(db, store, services.NewTokenRevoker(store, time.Hour), nil, nil, logger.NewSimpleLogger(), services.WithUserClock(clk))
+
+	user, err := users.CreateUser(ctx, &services.CreateUserRequest{Email: "ann@example.com"}, "")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, _, err := users.ChangeRole(ctx, user.ID.String(), models.RoleGuest, ""); err != nil {
+		t.Fatalf("change role: %v", err)
+	}
+
+	var stored models.User
+	if err := driver.GormDB().Where("id = ?", user.ID).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Role != models.RoleGuest || !stored.UpdatedAt.Equal(clk.Now()) {
+		t.Errorf("expected the guest role stamped %s, got %s at %s", clk.Now(), stored.Role, stored.UpdatedAt)
+	}
+}
+
+// TestParseRoleTransitions tests parsing the protected transitions setting
+func TestParseRoleTransitions(t *testing.T) {
+	transitions, err := services.ParseRoleTransitions([]string{"*->admin", " guest -> user "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 2 || !transitions[0].Matches(models.RoleGuest, models.RoleAdmin) || transitions[0].Matches(models.RoleAdmin, models.RoleUser) ||
+		!transitions[1].Matches(models.RoleGuest, models.RoleUser) || transitions[1].Matches(models.RoleUser, models.RoleUser) {
+		t.Errorf("unexpected transitions %+v", transitions)
+	}
+	for _, spec := range []string{"admin", "owner->admin", "user->"} {
+		if _, err := services.ParseRoleTransitions([]string{spec}); err == nil {
+			t.Errorf("expected %q to be refused", spec)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	apperrors "BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// auditChainFixture is a test app with an admin and the audit service
+// writing to its database
+type auditChainFixture struct {
+	ta    *apptest.TestApp
+	admin *apptest.User
+	audit *services.AuditService
+}
+
+// newAuditChainFixture starts an app and records n audit entries
+func newAuditChainFixture(t *testing.T, n int) *auditChainFixture {
+	t.Helper()
+	ta := apptest.NewTestApp(t)
+	f := &auditChainFixture{
+		ta:    ta,
+		admin: ta.CreateUser(models.RoleAdmin),
+		audit: services.NewAuditService(ta.App.GetDBManager(), logger.NewNopLogger()),
+	}
+	f.record(t, n)
+	return f
+}
+
+// record records n setting changes by the admin
+func (f *auditChainFixture) record(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		metadata := map[string]interface{}{"old": i, "new": i + 1, "note": "<b>&</b>"}
+		if err := f.audit.Record(context.Background(), f.admin.ID.String(), models.AuditActionSettingUpdated, "setting", "items_per_page", metadata); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+}
+
+// entries returns the chained audit entries by sequence
+func (f *auditChainFixture) entries(t *testing.T) []*models.AuditLog {
+	t.Helper()
+	var entries []*models.AuditLog
+	if err := f.ta.DB().Where("sequence IS NOT NULL").Order("sequence ASC").Find(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+// verify verifies the chain over body's range
+func (f *auditChainFixture) verify(t *testing.T, body interface{}) services.AuditVerification {
+	t.Helper()
+	resp := f.ta.Request(http.MethodPost, "/api/v1/admin/audit-logs/verify", body, f.admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("verify: expected 200, got %d %s", resp.StatusCode, resp.Body)
+	}
+	var result struct {
+		Data services.AuditVerification `json:"data"`
+	}
+	resp.Decode(t, &result)
+	return result.Data
+}
+
+// expectBreak fails unless result breaks at sequence for reason
+func expectBreak(t *testing.T, result services.AuditVerification, sequence int64, reason string) {
+	t.Helper()
+	if result.Valid || result.Break == nil {
+		t.Fatalf("expected a break at %d, got %+v", sequence, result)
+	}
+	if result.Break.Sequence != sequence || result.Break.Reason != reason {
+		t.Errorf("expected %s at %d, got %+v", reason, sequence, *result.Break)
+	}
+}
+
+// TestAuditEntryHashIsCanonical tests that an entry hashes the same
+// whatever the order of its metadata keys and the zone of its time
+func TestAuditEntryHashIsCanonical(t *testing.T) {
+	sequence := int64(7)
+	created := time.Date(2024, 3, 1, 12, 30, 0, 123000000, time.UTC)
+	entry := &models.AuditLog{
+		ID:         uuid.MustParse("6f1c2b4e-8a42-4f0e-9d1a-3c5b7e9f0a12"),
+		Action:     models.AuditActionSettingUpdated,
+		EntityType: "setting",
+		EntityID:   "items_per_page",
+		Metadata:   models.JSON(`{"old": 20, "new": {"b": 1.50, "a": "x"}}`),
+		CreatedAt:  created,
+		Sequence:   &sequence,
+		PrevHash:   "abc",
+	}
+	hash, err := services.AuditEntryHash(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reordered := *entry
+	reordered.Metadata = models.JSON(`{"new":{"a":"x","b":1.50},"old":20}`)
+	reordered.CreatedAt = created.In(time.FixedZone("CET", 3600))
+	if again, _ := services.AuditEntryHash(&reordered); again != hash {
+		t.Errorf("expected the same hash, got %s and %s", hash, again)
+	}
+
+	for name, change := range map[string]func(e *models.AuditLog){
+		"metadata":  func(e *models.AuditLog) { e.Metadata = models.JSON(`{"old": 20, "new": {"b": 1.5, "a": "x"}}`) },
+		"prev hash": func(e *models.AuditLog) { e.PrevHash = "abd" },
+		"sequence":  func(e *models.AuditLog) { other := int64(8); e.Sequence = &other },
+		"time":      func(e *models.AuditLog) { e.CreatedAt = created.Add(time.Millisecond) },
+	} {
+		changed := *entry
+		change(&changed)
+		if other, _ := services.AuditEntryHash(&changed); other == hash {
+			t.Errorf("%s: expected the hash to change", name)
+		}
+	}
+}
+
+// TestAuditChainRecords tests that entries are chained as they are
+// recorded, also when recorded concurrently
+func TestAuditChainRecords(t *testing.T) {
+	f := newAuditChainFixture(t, 3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f.audit.Record(context.Background(), "", models.AuditActionUserUpdated, "user", uuid.NewString(), nil); err != nil {
+				t.Errorf("record: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries := f.entries(t)
+	if len(entries) < 23 {
+		t.Fatalf("expected at least 23 chained entries, got %d", len(entries))
+	}
+	prev := ""
+	for i, entry := range entries {
+		if *entry.Sequence != int64(i+1) || entry.PrevHash != prev {
+			t.Fatalf("entry %d: expected sequence %d after %q, got %d after %q", i, i+1, prev, *entry.Sequence, entry.PrevHash)
+		}
+		prev = entry.EntryHash
+	}
+
+	var head models.AuditChain
+	if err := f.ta.DB().Where("id = ?", models.AuditChainLogs).First(&head).Error; err != nil {
+		t.Fatal(err)
+	}
+	if head.Sequence != int64(len(entries)) || head.Hash != prev {
+		t.Errorf("expected the head at the latest entry, got %+v", head)
+	}
+
+	result := f.verify(t, nil)
+	if !result.Valid || result.Checked != int64(len(entries)) || result.FirstSequence != 1 || result.LastSequence != int64(len(entries)) {
+		t.Errorf("expected every entry verified, got %+v", result)
+	}
+}
+
+// TestAuditChainPinpointsTampering tests that verification names the
+// first entry that was changed or removed
+func TestAuditChainPinpointsTampering(t *testing.T) {
+	t.Run("changed entry", func(t *testing.T) {
+		f := newAuditChainFixture(t, 5)
+		target := f.entries(t)[3]
+		if err := f.ta.DB().Model(&models.AuditLog{}).Where("id = ?", target.ID).
+			Update("metadata", `{"old": 3, "new": 100}`).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		result := f.verify(t, nil)
+		expectBreak(t, result, *target.Sequence, services.AuditBreakEntryHash)
+		if result.Break.EntryID != target.ID.String() || result.Checked != *target.Sequence-1 {
+			t.Errorf("expected the change found at %s after %d entries, got %+v", target.ID, *target.Sequence-1, result)
+		}
+	})
+
+	t.Run("rehashed entry", func(t *testing.T) {
+		f := newAuditChainFixture(t, 5)
+		entries := f.entries(t)
+		target := entries[2]
+		target.EntityID = "banner_message"
+		hash, err := services.AuditEntryHash(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.ta.DB().Model(&models.AuditLog{}).Where("id = ?", target.ID).
+			Updates(map[string]interface{}{"entity_id": target.EntityID, "entry_hash": hash}).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		// The entry hashes again, but the next one no longer links to it
+		expectBreak(t, f.verify(t, nil), *entries[3].Sequence, services.AuditBreakPrevHash)
+	})
+
+	t.Run("changed actor", func(t *testing.T) {
+		f := newAuditChainFixture(t, 3)
+		target := f.entries(t)[1]
+		if err := f.ta.DB().Model(&models.AuditLog{}).Where("id = ?", target.ID).Update("actor_id", uuid.New()).Error; err != nil {
+			t.Fatal(err)
+		}
+		expectBreak(t, f.verify(t, nil), *target.Sequence, services.AuditBreakActor)
+	})
+
+	t.Run("removed entry", func(t *testing.T) {
+		f := newAuditChainFixture(t, 5)
+		target := f.entries(t)[2]
+		if err := f.ta.DB().Delete(&models.AuditLog{}, "id = ?", target.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		expectBreak(t, f.verify(t, nil), *target.Sequence, services.AuditBreakMissingEntry)
+	})
+
+	t.Run("removed latest entry", func(t *testing.T) {
+		f := newAuditChainFixture(t, 3)
+		entries := f.entries(t)
+		latest := entries[len(entries)-1]
+		if err := f.ta.DB().Delete(&models.AuditLog{}, "id = ?", latest.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		expectBreak(t, f.verify(t, nil), *latest.Sequence, services.AuditBreakMissingEntry)
+	})
+
+	t.Run("range before the change", func(t *testing.T) {
+		f := newAuditChainFixture(t, 3)
+		before := time.Now()
+		time.Sleep(5 * time.Millisecond)
+		f.record(t, 2)
+		entries := f.entries(t)
+		if err := f.ta.DB().Model(&models.AuditLog{}).Where("id = ?", entries[len(entries)-1].ID).
+			Update("entity_id", "support_email").Error; err != nil {
+			t.Fatal(err)
+		}
+
+		if result := f.verify(t, map[string]string{"to": before.Format(time.RFC3339Nano)}); !result.Valid {
+			t.Errorf("expected the entries before the change valid, got %+v", result)
+		}
+		expectBreak(t, f.verify(t, map[string]string{"from": before.Format(time.RFC3339Nano)}), *entries[len(entries)-1].Sequence, services.AuditBreakEntryHash)
+	})
+}
+
+// TestAuditChainSurvivesPurges tests that purging users and old entries
+// leaves the rest of the chain verifiable
+func TestAuditChainSurvivesPurges(t *testing.T) {
+	f := newAuditChainFixture(t, 3)
+	cutoff := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	f.record(t, 2)
+
+	// Purged users are removed from the entries they made
+	if err := f.ta.DB().Model(&models.AuditLog{}).Where("actor_id = ?", f.admin.ID).Update("actor_id", nil).Error; err != nil {
+		t.Fatal(err)
+	}
+	if result := f.verify(t, nil); !result.Valid {
+		t.Fatalf("expected the chain valid without actors, got %+v", result)
+	}
+
+	purged, err := f.audit.PurgeBefore(context.Background(), cutoff)
+	if err != nil || purged < 3 {
+		t.Fatalf("expected the first entries purged, got %d %v", purged, err)
+	}
+	result := f.verify(t, nil)
+	if !result.Valid || result.Checked != 2 {
+		t.Errorf("expected the remaining entries valid, got %+v", result)
+	}
+}
+
+// TestAuditLogExport tests exporting the audit log as NDJSON
+func TestAuditLogExport(t *testing.T) {
+	f := newAuditChainFixture(t, 3)
+	// An entry recorded before entries were chained
+	legacy := &models.AuditLog{ID: uuid.New(), Action: models.AuditActionUserUpdated, EntityType: "user", EntityID: "legacy", CreatedAt: time.Now().Add(-time.Hour)}
+	if err := f.ta.DB().Create(legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+	chained := f.entries(t)
+
+	resp := f.ta.Request(http.MethodGet, "/api/v1/admin/audit-logs/export", nil, f.admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("export: expected 200, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected NDJSON, got %s", ct)
+	}
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(resp.Body))
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %d: %v", len(lines)+1, err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != len(chained)+1 {
+		t.Fatalf("expected %d lines, got %d", len(chained)+1, len(lines))
+	}
+	if lines[0]["id"] != legacy.ID.String() || lines[0]["entry_hash"] != nil {
+		t.Errorf("expected the unchained entry first, got %v", lines[0])
+	}
+	for i, entry := range chained {
+		line := lines[i+1]
+		if line["id"] != entry.ID.String() || line["sequence"] != float64(*entry.Sequence) || line["entry_hash"] != entry.EntryHash {
+			t.Errorf("line %d: expected entry %d, got %v", i+2, *entry.Sequence, line)
+		}
+	}
+
+	// The export is audited, and chained
+	var exported models.AuditLog
+	if err := f.ta.DB().Where("action = ?", models.AuditActionAuditExported).First(&exported).Error; err != nil {
+		t.Fatalf("expected the export audited: %v", err)
+	}
+	if exported.Sequence == nil || *exported.Sequence != *chained[len(chained)-1].Sequence+1 {
+		t.Errorf("expected the export chained after the entries, got %v", exported.Sequence)
+	}
+
+	t.Run("ranges", func(t *testing.T) {
+		resp := f.ta.Request(http.MethodGet, "/api/v1/admin/audit-logs/export?from=2024-02-01&to=2024-01-31", nil, f.admin.Token)
+		expectErrorCode(t, resp, http.StatusBadRequest, apperrors.CodeInvalidFilter)
+		resp = f.ta.Request(http.MethodGet, "/api/v1/admin/audit-logs/export?from=yesterday", nil, f.admin.Token)
+		expectErrorCode(t, resp, http.StatusBadRequest, apperrors.CodeInvalidFilter)
+
+		resp = f.ta.Request(http.MethodGet, "/api/v1/admin/audit-logs/export?to=2000-01-01", nil, f.admin.Token)
+		if resp.StatusCode != http.StatusOK || len(resp.Body) != 0 {
+			t.Errorf("expected an empty export, got %d %s", resp.StatusCode, resp.Body)
+		}
+	})
+
+	t.Run("needs audit.view", func(t *testing.T) {
+		user := f.ta.CreateUser(models.RoleUser)
+		resp := f.ta.Request(http.MethodGet, "/api/v1/admin/audit-logs/export", nil, user.Token)
+		expectErrorCode(t, resp, http.StatusForbidden, apperrors.CodeInsufficientPermissions)
+		resp = f.ta.Request(http.MethodPost, "/api/v1/admin/audit-logs/verify", nil, user.Token)
+		expectErrorCode(t, resp, http.StatusForbidden, apperrors.CodeInsufficientPermissions)
+	})
+}
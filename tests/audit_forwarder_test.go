@@ -0,0 +1,271 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/pkg/audit"
+	"BackofficeGoService/internal/pkg/httpclient"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// auditEntry returns an entry of the user.updated action
+func auditEntry(id string) audit.Entry {
+	return audit.Entry{
+		ID:         id,
+		Action:     "user.updated",
+		ActorID:    "actor-1",
+		EntityType: "user",
+		EntityID:   `user "1"`,
+		Metadata:   json.RawMessage(`{"field":"name"}`),
+		CreatedAt:  time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// readSyslogFrame reads one octet-counted syslog message
+func readSyslogFrame(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	length, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatalf("read frame length: %v", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		t.Fatalf("frame length %q: %v", length, err)
+	}
+	message := make([]byte, n)
+	if _, err := io.ReadFull(r, message); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	return string(message)
+}
+
+// TestSyslogForwarder tests the RFC 5424 messages sent over TCP and TLS
+func TestSyslogForwarder(t *testing.T) {
+	// The httptest certificate is valid for 127.0.0.1
+	certs := httptest.NewUnstartedServer(nil)
+	certs.StartTLS()
+	defer certs.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(certs.Certificate())
+
+	for name, tc := range map[string]struct {
+		listen func(l net.Listener) net.Listener
+		tls    *tls.Config
+	}{
+		"tcp": {listen: func(l net.Listener) net.Listener { return l }},
+		"tls": {
+			listen: func(l net.Listener) net.Listener {
+				return tls.NewListener(l, &tls.Config{Certificates: certs.TLS.Certificates})
+			},
+			tls: &tls.Config{RootCAs: roots},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			listener := tc.listen(l)
+			defer listener.Close()
+
+			messages := make(chan string, 2)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for i := 0; i < 2; i++ {
+					messages <- readSyslogFrame(t, r)
+				}
+			}()
+
+			forwarder := audit.NewSyslogForwarder(audit.SyslogConfig{Address: l.Addr().String(), TLS: tc.tls, AppName: "Backoffice Service", Hostname: "api-1"})
+			defer forwarder.Close()
+			for _, id := range []string{"a1", "a2"} {
+				if err := forwarder.Forward(context.Background(), auditEntry(id)); err != nil {
+					t.Fatalf("forward: %v", err)
+				}
+			}
+
+			for _, id := range []string{"a1", "a2"} {
+				select {
+				case message := <-messages:
+					header := `<109>1 2024-06-01T12:00:00Z api-1 Backoffice-Service - user.updated [audit@32473 id="` + id + `" actor_id="actor-1" entity_type="user" entity_id="user \"1\""] `
+					if !strings.HasPrefix(message, header) {
+						t.Fatalf("unexpected message %q", message)
+					}
+					var entry audit.Entry
+					if err := json.Unmarshal([]byte(strings.TrimPrefix(message, header)), &entry); err != nil || entry.ID != id || string(entry.Metadata) != `{"field":"name"}` {
+						t.Errorf("expected the entry as JSON, got %+v (%v)", entry, err)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("no message received")
+				}
+			}
+		})
+	}
+}
+
+// flakyCollector is an HTTP audit collector that fails while down
+type flakyCollector struct {
+	down     atomic.Bool
+	failures atomic.Int32
+
+	mu   sync.Mutex
+	auth []string
+	ids  []string
+}
+
+func (c *flakyCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c.down.Load() || c.failures.Add(-1) >= 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var entry audit.Entry
+	_ = json.NewDecoder(r.Body).Decode(&entry)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.auth = append(c.auth, r.Header.Get("Authorization"))
+	c.ids = append(c.ids, entry.ID)
+}
+
+// received returns the IDs of the entries accepted so far
+func (c *flakyCollector) received() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.ids...)
+}
+
+// waitFor polls cond until it holds or a few seconds pass
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// spilledLines counts the entries in a spill file
+func spilledLines(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	return strings.Count(string(data), "\n")
+}
+
+// TestAuditQueueRetries tests that failed forwards are retried with backoff
+// and counted once they succeed
+func TestAuditQueueRetries(t *testing.T) {
+	collector := &flakyCollector{}
+	collector.failures.Store(2)
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	m := metrics.New()
+	forwarder := audit.NewHTTPForwarder(httpclient.New(httpclient.Config{}), server.URL, "siem-token")
+	queue := audit.NewQueue(forwarder, audit.QueueConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, SpillPath: filepath.Join(t.TempDir(), "spill.jsonl")},
+		logger.NewNopLogger(), audit.WithMetrics(m.Business))
+	queue.Start()
+	defer queue.Stop(context.Background())
+
+	queue.Enqueue(auditEntry("a1"))
+	waitFor(t, "the entry", func() bool { return len(collector.received()) == 1 })
+
+	if collector.auth[0] != "Bearer siem-token" {
+		t.Errorf("expected the bearer token, got %q", collector.auth[0])
+	}
+	if n := testutil.ToFloat64(m.Business.AuditForwards.WithLabelValues("success")); n != 1 {
+		t.Errorf("expected one forwarded entry, got %v", n)
+	}
+	if n := testutil.ToFloat64(m.Business.AuditForwards.WithLabelValues("failure")); n != 0 {
+		t.Errorf("expected no failed entry, got %v", n)
+	}
+}
+
+// TestAuditQueueSpillReplay tests that entries are spilled to disk while the
+// SIEM is down, replayed once it is back, and kept across a restart
+func TestAuditQueueSpillReplay(t *testing.T) {
+	collector := &flakyCollector{}
+	collector.down.Store(true)
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	spill := filepath.Join(t.TempDir(), "audit", "spill.jsonl")
+	m := metrics.New()
+	newQueue := func() *audit.Queue {
+		forwarder := audit.NewHTTPForwarder(httpclient.New(httpclient.Config{}), server.URL, "")
+		return audit.NewQueue(forwarder, audit.QueueConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond, SpillPath: spill, ReplayInterval: 20 * time.Millisecond},
+			logger.NewNopLogger(), audit.WithMetrics(m.Business))
+	}
+
+	queue := newQueue()
+	queue.Start()
+	for _, id := range []string{"a1", "a2", "a3"} {
+		queue.Enqueue(auditEntry(id))
+	}
+	waitFor(t, "the entries spilled", func() bool { return spilledLines(spill) == 3 })
+	if n := testutil.ToFloat64(m.Business.AuditForwards.WithLabelValues("failure")); n < 3 {
+		t.Errorf("expected the failed entries counted, got %v", n)
+	}
+
+	// Stopping spills what is left, and later entries go straight to disk
+	if err := queue.Stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	queue.Enqueue(auditEntry("a4"))
+	if n := spilledLines(spill); n != 4 {
+		t.Fatalf("expected 4 spilled entries after stopping, got %d", n)
+	}
+
+	// A new queue replays the file once the SIEM is back
+	collector.down.Store(false)
+	queue = newQueue()
+	queue.Start()
+	defer queue.Stop(context.Background())
+	waitFor(t, "the spilled entries replayed", func() bool { return len(collector.received()) == 4 })
+
+	if got := strings.Join(collector.received(), ","); got != "a1,a2,a3,a4" {
+		t.Errorf("expected the spilled entries in order, got %s", got)
+	}
+	for _, path := range []string{spill, spill + ".replay"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s removed after the replay, got %v", filepath.Base(path), err)
+		}
+	}
+
+	// Entries failing once the SIEM is down again are replayed when it is back
+	collector.down.Store(true)
+	queue.Enqueue(auditEntry("a5"))
+	waitFor(t, "the entry spilled", func() bool { return spilledLines(spill) == 1 })
+	collector.down.Store(false)
+	waitFor(t, "the entry replayed", func() bool { return len(collector.received()) == 5 })
+	if n := testutil.ToFloat64(m.Business.AuditForwards.WithLabelValues("success")); n != 5 {
+		t.Errorf("expected 5 forwarded entries, got %v", n)
+	}
+}
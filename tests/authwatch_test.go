@@ -0,0 +1,415 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/geoip"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/ratelimit"
+	"BackofficeGoService/internal/services/authwatch"
+
+	"github.com/google/uuid"
+)
+
+// fakeAlertRepository keeps alerts in memory
+type fakeAlertRepository struct {
+	mu     sync.Mutex
+	alerts []*models.Alert
+	admins []uuid.UUID
+}
+
+func (r *fakeAlertRepository) Create(ctx context.Context, alert *models.Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *fakeAlertRepository) List(ctx context.Context, alertType string, limit, offset int) ([]*models.Alert, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.alerts, int64(len(r.alerts)), nil
+}
+
+func (r *fakeAlertRepository) Admins(ctx context.Context) ([]uuid.UUID, error) {
+	return r.admins, nil
+}
+
+// fakeNotifier collects the notifications sent, by user
+type fakeNotifier struct {
+	mu   sync.Mutex
+	sent map[string][]*models.Notification
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, userID string, notification *models.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.sent == nil {
+		n.sent = make(map[string][]*models.Notification)
+	}
+	n.sent[userID] = append(n.sent[userID], notification)
+	return nil
+}
+
+// fakePasswordResetter collects the users made to change their password
+type fakePasswordResetter struct {
+	reset []string
+}
+
+func (r *fakePasswordResetter) RequirePasswordChange(ctx context.Context, id string, actorID string) (*models.User, error) {
+	r.reset = append(r.reset, id)
+	return &models.User{}, nil
+}
+
+// watchFixture is a watch on fakes, fed logins one at a time
+type watchFixture struct {
+	t       *testing.T
+	watch   *authwatch.Watch
+	repo    *fakeAlertRepository
+	notes   *fakeNotifier
+	users   *fakePasswordResetter
+	audit   *fakeAuditRecorder
+	limiter *ratelimit.Limiter
+}
+
+// Networks the fixture's locator places: Berlin, Potsdam and New York
+const (
+	berlinIP  = "203.0.113.10"
+	potsdamIP = "192.0.2.10"
+	newYorkIP = "198.51.100.10"
+)
+
+func newWatchFixture(t *testing.T, cfg config.AuthWatchConfig) *watchFixture {
+	t.Helper()
+	locator, err := authwatch.NewNetworkLocator(map[string][]string{
+		"203.0.113.0/24":  {"52.52", "13.40"},
+		"192.0.2.0/24":    {"52.39", "13.06"},
+		"198.51.100.0/24": {"40.71", "-74.01"},
+	})
+	if err != nil {
+		t.Fatalf("locator: %v", err)
+	}
+	f := &watchFixture{
+		t:       t,
+		repo:    &fakeAlertRepository{admins: []uuid.UUID{uuid.New(), uuid.New()}},
+		notes:   &fakeNotifier{},
+		users:   &fakePasswordResetter{},
+		audit:   &fakeAuditRecorder{},
+		limiter: ratelimit.New(cache.NewMemoryStore()),
+	}
+	f.watch, err = authwatch.NewWatch(cfg, f.repo, cache.NewMemoryStore(), f.limiter, f.users, f.notes, f.audit, logger.NewNopLogger(), authwatch.WithLocator(locator))
+	if err != nil {
+		t.Fatalf("new watch: %v", err)
+	}
+	return f
+}
+
+// feed checks login at at and returns the types of the alerts raised
+func (f *watchFixture) feed(login events.Login, at time.Time) []string {
+	f.t.Helper()
+	alerts, err := f.watch.Check(context.Background(), login, at)
+	if err != nil {
+		f.t.Fatalf("check: %v", err)
+	}
+	var types []string
+	for _, alert := range alerts {
+		types = append(types, alert.Type)
+	}
+	return types
+}
+
+// watchStart is when the synthetic event streams start
+var watchStart = time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+// TestAuthWatchCredentialStuffing tests that one address failing as many
+// different emails fires once at the threshold and blocks the address
+func TestAuthWatchCredentialStuffing(t *testing.T) {
+	f := newWatchFixture(t, config.AuthWatchConfig{
+		CredentialStuffing: config.AuthWatchRule{Enabled: true, Threshold: 3, Window: 10 * time.Minute, Mitigations: []string{models.MitigationBlockIP}},
+		BlockDuration:      time.Hour,
+	})
+	fail := func(email, ip string, after time.Duration) []string {
+		return f.feed(events.Login{Email: email, IPAddress: ip, Reason: "unknown_email"}, watchStart.Add(after))
+	}
+
+	stream := []struct {
+		email string
+		ip    string
+		after time.Duration
+		fires bool
+	}{
+		{"a@example.com", "10.0.0.1", 0, false},
+		{"b@example.com", "10.0.0.1", time.Minute, false},
+		// The same email again, in another case, is not a new one
+		{"A@example.com", "10.0.0.1", 2 * time.Minute, false},
+		// Other addresses count on their own
+		{"c@example.com", "10.0.0.2", 2 * time.Minute, false},
+		{"c@example.com", "10.0.0.1", 3 * time.Minute, true},
+		{"d@example.com", "10.0.0.1", 4 * time.Minute, false},
+	}
+	for i, event := range stream {
+		got := fail(event.email, event.ip, event.after)
+		if fires := slices.Equal(got, []string{models.AlertTypeCredentialStuffing}); fires != event.fires || (!fires && len(got) != 0) {
+			t.Fatalf("event %d (%s from %s): expected firing %v, got %v", i, event.email, event.ip, event.fires, got)
+		}
+	}
+
+	if len(f.repo.alerts) != 1 {
+		t.Fatalf("expected one alert stored, got %d", len(f.repo.alerts))
+	}
+	alert := f.repo.alerts[0]
+	if alert.IPAddress != "10.0.0.1" || alert.UserID != nil || !slices.Equal(alert.Mitigations, []string{models.MitigationBlockIP}) {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+	if until, err := f.limiter.Blocked(context.Background(), "10.0.0.1"); err != nil || until.IsZero() {
+		t.Errorf("expected the address blocked, got %v, %v", until, err)
+	}
+	if until, _ := f.limiter.Blocked(context.Background(), "10.0.0.2"); !until.IsZero() {
+		t.Errorf("expected the other address not blocked, got %v", until)
+	}
+	if !slices.Equal(f.audit.entries, []string{models.AuditActionIPBlocked + ":10.0.0.1", models.AuditActionAlertRaised + ":" + alert.ID.String()}) {
+		t.Errorf("expected the block and the alert audited, got %v", f.audit.entries)
+	}
+	for _, admin := range f.repo.admins {
+		sent := f.notes.sent[admin.String()]
+		if len(sent) != 1 || sent[0].Type != models.NotificationTypeSecurityAlert || !strings.Contains(sent[0].Body, "10.0.0.1") {
+			t.Errorf("expected admin %s notified of the alert, got %+v", admin, sent)
+		}
+	}
+}
+
+// TestAuthWatchFailureSpike tests that failures to one account fire when
+// enough fall in the window, and that only a known user's password is reset
+func TestAuthWatchFailureSpike(t *testing.T) {
+	f := newWatchFixture(t, config.AuthWatchConfig{
+		FailureSpike: config.AuthWatchRule{Enabled: true, Threshold: 3, Window: 10 * time.Minute, Mitigations: []string{models.MitigationForcePasswordReset}},
+	})
+	userID := uuid.New().String()
+	login := events.Login{UserID: userID, Email: "victim@example.com", IPAddress: "10.0.0.1", Reason: "invalid_password"}
+	success := login
+	success.Success, success.Reason = true, ""
+
+	stream := []struct {
+		login events.Login
+		after time.Duration
+		fires bool
+	}{
+		{login, 0, false},
+		{login, 5 * time.Minute, false},
+		// Successful logins do not count
+		{success, 6 * time.Minute, false},
+		// The first failure left the window
+		{login, 11 * time.Minute, false},
+		{login, 12 * time.Minute, true},
+		// Over the threshold, the rule does not fire again
+		{login, 13 * time.Minute, false},
+	}
+	for i, event := range stream {
+		got := f.feed(event.login, watchStart.Add(event.after))
+		if fires := slices.Equal(got, []string{models.AlertTypeFailureSpike}); fires != event.fires || (!fires && len(got) != 0) {
+			t.Fatalf("event %d at %s: expected firing %v, got %v", i, event.after, event.fires, got)
+		}
+	}
+	if len(f.repo.alerts) != 1 || f.repo.alerts[0].UserID == nil || f.repo.alerts[0].UserID.String() != userID ||
+		!slices.Equal(f.repo.alerts[0].Mitigations, []string{models.MitigationForcePasswordReset}) {
+		t.Fatalf("expected an alert on the user with their password reset, got %+v", f.repo.alerts)
+	}
+	if !slices.Equal(f.users.reset, []string{userID}) {
+		t.Errorf("expected the user's password reset, got %v", f.users.reset)
+	}
+
+	// An email matching no user is alerted on, but has no password to reset
+	for i := 0; i < 3; i++ {
+		f.feed(events.Login{Email: "nobody@example.com", IPAddress: "10.0.0.3", Reason: "unknown_email"}, watchStart.Add(time.Duration(i)*time.Second))
+	}
+	if len(f.repo.alerts) != 2 || len(f.repo.alerts[1].Mitigations) != 0 || len(f.users.reset) != 1 {
+		t.Errorf("expected an unmitigated alert on the unknown email, got %+v and resets %v", f.repo.alerts[1:], f.users.reset)
+	}
+}
+
+// TestAuthWatchImpossibleTravel tests that successful logins are compared
+// with the user's previous located one by distance and speed
+func TestAuthWatchImpossibleTravel(t *testing.T) {
+	f := newWatchFixture(t, config.AuthWatchConfig{
+		ImpossibleTravel: config.AuthWatchTravelRule{Enabled: true, MaxSpeed: 900, MinDistance: 500, Window: 24 * time.Hour, Mitigations: []string{models.MitigationForcePasswordReset, models.MitigationBlockIP}},
+		BlockDuration:    time.Hour,
+	})
+	userID := uuid.New().String()
+	login := func(ip string, success bool) events.Login {
+		return events.Login{UserID: userID, Email: "traveller@example.com", IPAddress: ip, Success: success}
+	}
+
+	stream := []struct {
+		login events.Login
+		after time.Duration
+		fires bool
+	}{
+		{login(berlinIP, true), 0, false},
+		// Around 30 km in 5 minutes is fast, but not far enough to tell
+		{login(potsdamIP, true), 5 * time.Minute, false},
+		// Unlocated addresses and failed logins are not compared
+		{login("10.0.0.1", true), 10 * time.Minute, false},
+		{login(newYorkIP, false), 15 * time.Minute, false},
+		// Over 6000 km in an hour
+		{login(newYorkIP, true), time.Hour + 5*time.Minute, true},
+		// Back over 6000 km in 19 hours is a flight
+		{login(berlinIP, true), 20 * time.Hour, false},
+		// Logins further apart than the window are not compared
+		{login(newYorkIP, true), 48 * time.Hour, false},
+	}
+	for i, event := range stream {
+		got := f.feed(event.login, watchStart.Add(event.after))
+		if fires := slices.Equal(got, []string{models.AlertTypeImpossibleTravel}); fires != event.fires || (!fires && len(got) != 0) {
+			t.Fatalf("event %d from %s at %s: expected firing %v, got %v", i, event.login.IPAddress, event.after, event.fires, got)
+		}
+	}
+
+	if len(f.repo.alerts) != 1 {
+		t.Fatalf("expected one alert, got %d", len(f.repo.alerts))
+	}
+	alert := f.repo.alerts[0]
+	if alert.IPAddress != newYorkIP || !strings.Contains(string(alert.Details), `"previous_ip_address":"`+potsdamIP+`"`) {
+		t.Errorf("expected the alert to name both addresses, got %+v %s", alert, alert.Details)
+	}
+	if !slices.Equal(alert.Mitigations, []string{models.MitigationForcePasswordReset, models.MitigationBlockIP}) || !slices.Equal(f.users.reset, []string{userID}) {
+		t.Errorf("expected both mitigations applied, got %v and resets %v", alert.Mitigations, f.users.reset)
+	}
+	if until, _ := f.limiter.Blocked(context.Background(), newYorkIP); until.IsZero() {
+		t.Error("expected the new address blocked")
+	}
+}
+
+// TestAuthWatchTravelGeoIP tests that logins outside the configured
+// networks are compared where GeoIP placed them
+func TestAuthWatchTravelGeoIP(t *testing.T) {
+	f := newWatchFixture(t, config.AuthWatchConfig{
+		ImpossibleTravel: config.AuthWatchTravelRule{Enabled: true, MaxSpeed: 900, MinDistance: 500, Window: 24 * time.Hour},
+	})
+	userID := uuid.New().String()
+	paris := &geoip.Location{Country: "FR", City: "Paris", Lat: 48.86, Lon: 2.35}
+
+	if got := f.feed(events.Login{UserID: userID, IPAddress: "10.0.0.1", Location: paris, Success: true}, watchStart); len(got) != 0 {
+		t.Fatalf("expected the first login to raise nothing, got %v", got)
+	}
+	// A configured network is used rather than GeoIP's answer, which
+	// would put this login next to the last
+	got := f.feed(events.Login{UserID: userID, IPAddress: newYorkIP, Location: paris, Success: true}, watchStart.Add(time.Hour))
+	if !slices.Equal(got, []string{models.AlertTypeImpossibleTravel}) {
+		t.Fatalf("expected Paris to New York in an hour to fire, got %v", got)
+	}
+	// Country-only answers have nothing to compare
+	if got := f.feed(events.Login{UserID: userID, IPAddress: "10.0.0.2", Location: &geoip.Location{Country: "JP"}, Success: true}, watchStart.Add(2*time.Hour)); len(got) != 0 {
+		t.Errorf("expected a login without coordinates to raise nothing, got %v", got)
+	}
+}
+
+// TestAuthWatchRulesToggle tests that disabled rules never fire and that
+// rules are checked at creation
+func TestAuthWatchRulesToggle(t *testing.T) {
+	f := newWatchFixture(t, config.AuthWatchConfig{
+		CredentialStuffing: config.AuthWatchRule{Threshold: 1, Window: time.Minute},
+		FailureSpike:       config.AuthWatchRule{Threshold: 1, Window: time.Minute},
+		ImpossibleTravel:   config.AuthWatchTravelRule{MaxSpeed: 1, Window: time.Hour},
+	})
+	f.feed(events.Login{UserID: uuid.New().String(), Email: "a@example.com", IPAddress: berlinIP, Success: true}, watchStart)
+	for i := 0; i < 5; i++ {
+		if got := f.feed(events.Login{Email: "a@example.com", IPAddress: newYorkIP}, watchStart.Add(time.Second)); len(got) != 0 {
+			t.Fatalf("expected disabled rules to stay quiet, got %v", got)
+		}
+	}
+
+	invalid := []struct {
+		name    string
+		cfg     config.AuthWatchConfig
+		limiter *ratelimit.Limiter
+	}{
+		{"unknown mitigation", config.AuthWatchConfig{FailureSpike: config.AuthWatchRule{Mitigations: []string{"lock_account"}}}, ratelimit.New(cache.NewMemoryStore())},
+		{"block without a limiter", config.AuthWatchConfig{CredentialStuffing: config.AuthWatchRule{Mitigations: []string{models.MitigationBlockIP}}}, nil},
+		{"no threshold", config.AuthWatchConfig{FailureSpike: config.AuthWatchRule{Enabled: true, Window: time.Minute}}, nil},
+	}
+	for _, tt := range invalid {
+		if _, err := authwatch.NewWatch(tt.cfg, &fakeAlertRepository{}, cache.NewMemoryStore(), tt.limiter, &fakePasswordResetter{}, nil, nil, logger.NewNopLogger()); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+	if _, err := authwatch.NewNetworkLocator(map[string][]string{"203.0.113.0/24": {"95", "13"}}); err == nil {
+		t.Error("expected an invalid latitude to be rejected")
+	}
+}
+
+// TestAuthWatchLoginEvents tests the watch end to end: failed logins
+// through the API raise alerts admins can list, reset the password of the
+// account and block the address from logging in
+func TestAuthWatchLoginEvents(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.LoginRateLimit = 100
+		cfg.AuthWatch = config.AuthWatchConfig{
+			FailureSpike:       config.AuthWatchRule{Enabled: true, Threshold: 3, Window: time.Minute, Mitigations: []string{models.MitigationForcePasswordReset}},
+			CredentialStuffing: config.AuthWatchRule{Enabled: true, Threshold: 2, Window: time.Minute, Mitigations: []string{models.MitigationBlockIP}},
+			BlockDuration:      time.Minute,
+		}
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	failLogin := func(email string) {
+		t.Helper()
+		resp := ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": email, "password": "wrong-password"}, "")
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("login: expected 401, got %d: %s", resp.StatusCode, resp.Body)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := ta.App.GetAuthWatch().Stop(ctx); err != nil {
+			t.Fatalf("wait for the login check: %v", err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		failLogin(user.Email)
+	}
+	if n := countRows(t, ta.DB(), &models.Alert{}, "type = ? AND user_id = ?", models.AlertTypeFailureSpike, user.ID); n != 1 {
+		t.Fatalf("expected a failure spike alert on the user, got %d", n)
+	}
+	if n := countRows(t, ta.DB(), &models.User{}, "id = ? AND must_change_password = ?", user.ID, true); n != 1 {
+		t.Error("expected the user made to change their password")
+	}
+	if n := countRows(t, ta.DB(), &models.Notification{}, "user_id = ? AND type = ?", admin.ID, models.NotificationTypeSecurityAlert); n != 1 {
+		t.Errorf("expected the admin notified, got %d notifications", n)
+	}
+
+	// A second email failing from the same address is credential stuffing
+	failLogin("someone-else@example.com")
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": admin.Email, "password": admin.Password}, "")
+	expectErrorCode(t, resp, http.StatusTooManyRequests, errors.CodeRateLimited)
+
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/alerts?type="+models.AlertTypeCredentialStuffing, nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("list alerts: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var listed struct {
+		Data []models.Alert `json:"data"`
+	}
+	resp.Decode(t, &listed)
+	if len(listed.Data) != 1 || !slices.Equal(listed.Data[0].Mitigations, []string{models.MitigationBlockIP}) {
+		t.Errorf("expected the blocking stuffing alert listed, got %+v", listed.Data)
+	}
+
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/alerts?type=phishing", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusBadRequest, errors.CodeInvalidFilter)
+	if resp := ta.Request(http.MethodGet, "/api/v1/admin/alerts", nil, user.Token); resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected non-admins to be refused the alerts, got %d", resp.StatusCode)
+	}
+}
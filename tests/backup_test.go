@@ -0,0 +1,212 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/cli"
+	"BackofficeGoService/internal/infrastructure/storage"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// countCLIUsers counts the users in the SQLite file commands run against,
+// through a connection of its own
+func countCLIUsers(t *testing.T, cfg *config.Config) int64 {
+	t.Helper()
+	db := openCLIDatabase(t, cfg)
+	var count int64
+	if err := db.Model(&models.User{}).Count(&count).Error; err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	return count
+}
+
+// TestCLIBackupSQLite tests backing a SQLite database up, listing the
+// backup and restoring it, into a fresh database and over the original
+func TestCLIBackupSQLite(t *testing.T) {
+	cfg := newCLIConfig(t)
+	if res := runCLI(t, cfg, "", "migrate", "up"); res.code != cli.ExitOK {
+		t.Fatalf("migrate: %d %s", res.code, res.stderr)
+	}
+	db := openCLIDatabase(t, cfg)
+	if err := db.Create(&models.User{ID: uuid.New(), Email: "kept@example.com", Role: models.RoleUser, Active: true}).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	res := runCLI(t, cfg, "", "backup", "create")
+	if res.code != cli.ExitOK || !strings.HasPrefix(res.stdout, "Backed up primary to backups/primary/") {
+		t.Fatalf("expected a backup, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+	key := strings.Fields(strings.TrimPrefix(res.stdout, "Backed up primary to "))[0]
+	if !strings.HasSuffix(key, ".sqlite.gz") {
+		t.Errorf("expected a compressed SQLite backup, got %s", key)
+	}
+
+	res = runCLI(t, cfg, "", "backup", "list")
+	if res.code != cli.ExitOK || !strings.Contains(res.stdout, key) || !strings.Contains(res.stdout, "sqlite") {
+		t.Fatalf("expected the backup listed, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+
+	// Restoring over a database with tables takes --force
+	res = runCLI(t, cfg, "", "backup", "restore", key)
+	if res.code != cli.ExitError || !strings.Contains(res.stderr, "--force") {
+		t.Fatalf("expected the restore refused, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+	if res := runCLI(t, cfg, "", "backup", "restore"); res.code != cli.ExitUsage {
+		t.Errorf("expected a missing key to be a usage error, got %d", res.code)
+	}
+
+	fresh := *cfg
+	fresh.Database.Primary.DBName = filepath.Join(t.TempDir(), "fresh.db")
+	res = runCLI(t, &fresh, "", "backup", "restore", key)
+	if res.code != cli.ExitOK {
+		t.Fatalf("expected a restore into an empty database, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+	if count := countCLIUsers(t, &fresh); count != 1 {
+		t.Errorf("expected the restored database to hold the user, got %d users", count)
+	}
+
+	if err := db.Where("email = ?", "kept@example.com").Delete(&models.User{}).Error; err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	res = runCLI(t, cfg, "", "backup", "restore", key, "--force")
+	if res.code != cli.ExitOK {
+		t.Fatalf("expected a forced restore, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+	if count := countCLIUsers(t, cfg); count != 1 {
+		t.Errorf("expected the forced restore to bring the user back, got %d users", count)
+	}
+
+	if res := runCLI(t, cfg, "", "backup", "restore", "backups/primary/missing.sqlite.gz", "--force"); res.code != cli.ExitError {
+		t.Errorf("expected a missing backup to fail, got %d", res.code)
+	}
+}
+
+// TestBackupServiceAudit tests that backups are keyed by database and
+// time, audited, and not restored into another kind of database
+func TestBackupServiceAudit(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	audit := &fakeAuditRecorder{}
+	now := time.Date(2024, 6, 1, 2, 0, 0, 0, time.UTC)
+	backups := services.NewBackupService(config.BackupConfig{Prefix: "backups"}, store, audit, logger.NewNopLogger(), services.WithBackupClock(clock.NewFake(now)))
+
+	mock := databasetest.NewMockDriver(t, databasetest.WithSQLite(&models.User{}))
+	db, err := mock.GormDB().DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	target := services.BackupTarget{Name: "reports", Config: config.DatabaseConnectionConfig{Driver: "sqlite"}, DB: db}
+	backup, err := backups.Create(context.Background(), "admin-1", target)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if backup.Key != "backups/reports/20240601T020000.000Z.sqlite" || backup.Compressed || backup.Size == 0 {
+		t.Errorf("unexpected backup %+v", backup)
+	}
+	if !slices.Equal(audit.entries, []string{models.AuditActionBackupCreated + ":" + backup.Key}) {
+		t.Errorf("expected the backup audited, got %v", audit.entries)
+	}
+
+	listed, err := backups.List(context.Background())
+	if err != nil || len(listed) != 1 || listed[0].Database != "reports" || !listed[0].CreatedAt.Equal(now) || listed[0].Size != backup.Size {
+		t.Fatalf("expected the backup listed, got %+v, %v", listed, err)
+	}
+
+	target.Config.Driver = "postgresql"
+	if _, err := backups.Restore(context.Background(), "admin-1", target, backup.Key, true); !errors.Is(err, services.ErrBackupDriverMismatch) {
+		t.Errorf("expected ErrBackupDriverMismatch, got %v", err)
+	}
+	target.Config.Driver = "sqlite"
+	if _, err := backups.Restore(context.Background(), "admin-1", target, backup.Key, false); !errors.Is(err, services.ErrBackupTargetNotEmpty) {
+		t.Errorf("expected ErrBackupTargetNotEmpty, got %v", err)
+	}
+}
+
+// TestBackupCommands tests the dump and restore programs run for
+// PostgreSQL and MySQL, which get their password from the environment
+func TestBackupCommands(t *testing.T) {
+	backups := services.NewBackupService(config.BackupConfig{
+		PgDumpPath:    "/opt/pg/bin/pg_dump",
+		PsqlPath:      "/opt/pg/bin/psql",
+		MySQLDumpPath: "mysqldump",
+		MySQLPath:     "mysql",
+	}, nil, nil, logger.NewNopLogger())
+	ctx := context.Background()
+
+	postgres := config.DatabaseConnectionConfig{Driver: "postgresql", Host: "db", Port: "5433", User: "app", Password: "s3cret", DBName: "backoffice", SSLMode: "require"}
+	mysql := config.DatabaseConnectionConfig{Driver: "mysql", Host: "db", Port: "3306", User: "app", Password: "s3cret", DBName: "backoffice", Charset: "utf8mb4"}
+
+	tests := []struct {
+		name    string
+		restore bool
+		dbc     config.DatabaseConnectionConfig
+		path    string
+		args    []string
+		env     []string
+	}{
+		{
+			name: "pg_dump", dbc: postgres, path: "/opt/pg/bin/pg_dump",
+			args: []string{"--host=db", "--port=5433", "--username=app", "--dbname=backoffice", "--format=plain", "--no-owner", "--no-privileges", "--clean", "--if-exists"},
+			env:  []string{"PGPASSWORD=s3cret", "PGSSLMODE=require"},
+		},
+		{
+			name: "psql", restore: true, dbc: postgres, path: "/opt/pg/bin/psql",
+			args: []string{"--host=db", "--port=5433", "--username=app", "--dbname=backoffice", "--no-psqlrc", "--quiet", "--single-transaction", "--set=ON_ERROR_STOP=1"},
+			env:  []string{"PGPASSWORD=s3cret", "PGSSLMODE=require"},
+		},
+		{
+			name: "mysqldump", dbc: mysql, path: "mysqldump",
+			args: []string{"--host=db", "--port=3306", "--user=app", "--default-character-set=utf8mb4", "--single-transaction", "--routines", "--triggers", "--no-tablespaces", "backoffice"},
+			env:  []string{"MYSQL_PWD=s3cret"},
+		},
+		{
+			name: "mysql", restore: true, dbc: mysql, path: "mysql",
+			args: []string{"--host=db", "--port=3306", "--user=app", "--default-character-set=utf8mb4", "backoffice"},
+			env:  []string{"MYSQL_PWD=s3cret"},
+		},
+	}
+	for _, tt := range tests {
+		build := backups.DumpCommand
+		if tt.restore {
+			build = backups.RestoreCommand
+		}
+		cmd, err := build(ctx, tt.dbc)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if cmd.Args[0] != tt.path || !slices.Equal(cmd.Args[1:], tt.args) {
+			t.Errorf("%s: expected %s %v, got %v", tt.name, tt.path, tt.args, cmd.Args)
+		}
+		for _, env := range tt.env {
+			if !slices.Contains(cmd.Env, env) {
+				t.Errorf("%s: expected %s in the environment", tt.name, env)
+			}
+		}
+		for _, arg := range cmd.Args {
+			if strings.Contains(arg, "s3cret") {
+				t.Errorf("%s: expected the password kept off the command line, got %q", tt.name, arg)
+			}
+		}
+	}
+
+	if _, err := backups.DumpCommand(ctx, config.DatabaseConnectionConfig{Driver: "mongodb"}); !errors.Is(err, services.ErrBackupUnsupported) {
+		t.Errorf("expected ErrBackupUnsupported, got %v", err)
+	}
+}
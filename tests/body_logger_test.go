@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newBodyLogRouter echoes JSON and form requests and records what handlers received
+func newBodyLogRouter(logs *logger.CaptureLogger, limit int, received *string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.BodyLogger(logs, limit))
+	router.POST("/echo", func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		*received = string(body)
+		c.Data(http.StatusOK, c.ContentType(), body)
+	})
+	router.POST("/login", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"token": "issued-jwt", "user": gin.H{"email": "ann@example.com"}})
+	})
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Writer.WriteString("data: one\n\n")
+		c.Writer.Flush()
+		c.Writer.WriteString("data: two\n\n")
+	})
+	return router
+}
+
+// bodyLog returns the request and response bodies of the only body log entry
+func bodyLog(t *testing.T, logs *logger.CaptureLogger) (string, string) {
+	t.Helper()
+	entries := logs.FilterByLevel(logger.LevelDebug)
+	if len(entries) != 1 {
+		t.Fatalf("expected one body log entry, got %d", len(entries))
+	}
+	request, _ := entries[0].Field("request_body")
+	response, _ := entries[0].Field("response_body")
+	return request.(string), response.(string)
+}
+
+// TestBodyLoggerRedactsJSON tests that credentials are hidden at any depth in both directions
+func TestBodyLoggerRedactsJSON(t *testing.T) {
+	logs := logger.NewCaptureLogger()
+	var received string
+	router := newBodyLogRouter(logs, middleware.DefaultBodyLogLimit, &received)
+
+	body := `{"email":"ann@example.com","password":"hunter2","profile":{"api_token":"abc\"def","name":"Ann"}}`
+	req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if received != body || w.Body.String() != body {
+		t.Fatalf("handler or client saw a modified body: %q / %q", received, w.Body.String())
+	}
+	request, response := bodyLog(t, logs)
+	want := `{"email":"ann@example.com","password":"REDACTED","profile":{"api_token":"REDACTED","name":"Ann"}}`
+	if request != want || response != want {
+		t.Errorf("expected %s, got request %s and response %s", want, request, response)
+	}
+
+	logs = logger.NewCaptureLogger()
+	router = newBodyLogRouter(logs, middleware.DefaultBodyLogLimit, &received)
+	req = httptest.NewRequest(http.MethodPost, "/login", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if _, response := bodyLog(t, logs); strings.Contains(response, "issued-jwt") || !strings.Contains(response, "ann@example.com") {
+		t.Errorf("expected the token redacted from the response, got %s", response)
+	}
+}
+
+// TestBodyLoggerTruncates tests the size limit and that handlers still get the full body
+func TestBodyLoggerTruncates(t *testing.T) {
+	logs := logger.NewCaptureLogger()
+	var received string
+	router := newBodyLogRouter(logs, 32, &received)
+
+	body := `{"name":"` + strings.Repeat("a", 40) + `","password":"hunter2"}`
+	req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if received != body || w.Body.String() != body {
+		t.Fatal("truncation changed the body seen by the handler or client")
+	}
+	request, response := bodyLog(t, logs)
+	want := body[:32] + middleware.TruncatedMarker
+	if request != want || response != want {
+		t.Errorf("expected %q, got request %q and response %q", want, request, response)
+	}
+
+	// A password cut off mid-value is still hidden
+	if got := middleware.RedactJSON(`{"password":"hunt`); got != `{"password":"REDACTED"` {
+		t.Errorf("unexpected redaction of a truncated value: %s", got)
+	}
+}
+
+// TestBodyLoggerContentTypes tests that only JSON and form bodies are logged
+func TestBodyLoggerContentTypes(t *testing.T) {
+	t.Run("form", func(t *testing.T) {
+		logs := logger.NewCaptureLogger()
+		var received string
+		router := newBodyLogRouter(logs, middleware.DefaultBodyLogLimit, &received)
+
+		req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("user=ann&password=hunter2"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		if request, _ := bodyLog(t, logs); request != "password=REDACTED&user=ann" {
+			t.Errorf("unexpected form log %q", request)
+		}
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		logs := logger.NewCaptureLogger()
+		var received string
+		router := newBodyLogRouter(logs, middleware.DefaultBodyLogLimit, &received)
+
+		var buf bytes.Buffer
+		form := multipart.NewWriter(&buf)
+		part, _ := form.CreateFormFile("file", "secret.txt")
+		part.Write([]byte("file contents"))
+		form.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/echo", &buf)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		if !strings.Contains(received, "file contents") {
+			t.Error("handler did not receive the upload")
+		}
+		if logs.Contains("HTTP bodies") {
+			t.Error("expected multipart bodies not to be logged")
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		logs := logger.NewCaptureLogger()
+		var received string
+		router := newBodyLogRouter(logs, middleware.DefaultBodyLogLimit, &received)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+
+		if !w.Flushed || w.Body.String() != "data: one\n\ndata: two\n\n" {
+			t.Errorf("stream was not passed through: flushed=%v body=%q", w.Flushed, w.Body.String())
+		}
+		if logs.Contains("HTTP bodies") {
+			t.Error("expected event streams not to be logged")
+		}
+	})
+}
+
+// TestBodyLoggerDisabledInProduction tests the LOG_HTTP_BODIES wiring
+func TestBodyLoggerDisabledInProduction(t *testing.T) {
+	for _, env := range []string{"development", "production"} {
+		t.Run(env, func(t *testing.T) {
+			ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+				cfg.App.Environment = env
+				cfg.Logging.HTTPBodies = true
+			})
+			ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": "x@example.com", "password": "hunter2"}, "")
+
+			if logged := ta.Logs.Contains("HTTP bodies"); logged != (env != "production") {
+				t.Errorf("body logged = %v in %s", logged, env)
+			}
+			for _, entry := range ta.Logs.Entries() {
+				for _, field := range entry.Fields {
+					if s, ok := field.Value.(string); ok && strings.Contains(s, "hunter2") {
+						t.Errorf("password leaked in %q", entry.Message)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/pkg/buildinfo"
+)
+
+// vcsBuildInfo returns build info as the toolchain embeds it in a VCS checkout
+func vcsBuildInfo(version string, modified bool) *debug.BuildInfo {
+	dirty := "false"
+	if modified {
+		dirty = "true"
+	}
+	return &debug.BuildInfo{
+		GoVersion: "go1.24.0",
+		Main:      debug.Module{Path: "BackofficeGoService", Version: version},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs", Value: "git"},
+			{Key: "vcs.revision", Value: "0123abcd"},
+			{Key: "vcs.time", Value: "2024-05-01T10:00:00Z"},
+			{Key: "vcs.modified", Value: dirty},
+		},
+	}
+}
+
+// TestBuildInfoFallbackChain tests that -ldflags values win over the
+// embedded VCS data, which wins over the defaults
+func TestBuildInfoFallbackChain(t *testing.T) {
+	tests := []struct {
+		name string
+		set  buildinfo.Info
+		bi   *debug.BuildInfo
+		want buildinfo.Info
+	}{
+		{
+			name: "ldflags",
+			set:  buildinfo.Info{Version: "v1.4.0", Commit: "feedbeef", BuildDate: "2024-06-01T00:00:00Z"},
+			bi:   vcsBuildInfo("v0.0.0-20240501100000-0123abcd", true),
+			want: buildinfo.Info{Version: "v1.4.0", Commit: "feedbeef", BuildDate: "2024-06-01T00:00:00Z", GoVersion: "go1.24.0"},
+		},
+		{
+			name: "vcs",
+			bi:   vcsBuildInfo("v0.0.0-20240501100000-0123abcd", true),
+			want: buildinfo.Info{Version: "v0.0.0-20240501100000-0123abcd", Commit: "0123abcd", BuildDate: "2024-05-01T10:00:00Z", GoVersion: "go1.24.0", Modified: true},
+		},
+		{
+			name: "partial ldflags",
+			set:  buildinfo.Info{Version: "v1.4.0"},
+			bi:   vcsBuildInfo("(devel)", false),
+			want: buildinfo.Info{Version: "v1.4.0", Commit: "0123abcd", BuildDate: "2024-05-01T10:00:00Z", GoVersion: "go1.24.0"},
+		},
+		{
+			name: "devel build",
+			bi:   vcsBuildInfo("(devel)", false),
+			want: buildinfo.Info{Version: buildinfo.DefaultVersion, Commit: "0123abcd", BuildDate: "2024-05-01T10:00:00Z", GoVersion: "go1.24.0"},
+		},
+		{
+			name: "defaults",
+			want: buildinfo.Info{Version: buildinfo.DefaultVersion, Commit: buildinfo.Unknown, BuildDate: buildinfo.Unknown, GoVersion: runtime.Version()},
+		},
+	}
+	for _, tt := range tests {
+		if got := buildinfo.Resolve(tt.set, tt.bi); got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+
+	info := buildinfo.Info{Version: "v1.4.0", Commit: "0123abcd", BuildDate: "2024-05-01", GoVersion: "go1.24.0", Modified: true}
+	if got := info.String(); got != "v1.4.0 (commit 0123abcd-dirty, built 2024-05-01, go1.24.0)" {
+		t.Errorf("unexpected --version output %q", got)
+	}
+}
+
+// TestVersionEndpoints tests that the version endpoint, health check and
+// build_info metric report the same build
+func TestVersionEndpoints(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+
+	resp := ta.Request(http.MethodGet, "/api/v1/version", nil, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var version buildinfo.Info
+	resp.Decode(t, &version)
+	// APP_VERSION takes the place of the build's version
+	if version.Version != ta.Config.App.Version || version.Commit == "" || version.BuildDate == "" || version.GoVersion == "" {
+		t.Errorf("unexpected version %+v", version)
+	}
+
+	var health struct {
+		Version   string `json:"version"`
+		Commit    string `json:"commit"`
+		BuildDate string `json:"build_date"`
+	}
+	ta.Request(http.MethodGet, "/health", nil, "").Decode(t, &health)
+	if health.Version != version.Version || health.Commit != version.Commit || health.BuildDate != version.BuildDate {
+		t.Errorf("expected health to match %+v, got %+v", version, health)
+	}
+
+	metrics := string(ta.Request(http.MethodGet, "/metrics", nil, "").Body)
+	want := `build_info{build_date="` + version.BuildDate + `",commit="` + version.Commit + `",go_version="` + version.GoVersion + `",version="` + version.Version + `"} 1`
+	if !strings.Contains(metrics, want) {
+		t.Errorf("expected %s in the metrics", want)
+	}
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+	"BackofficeGoService/internal/services"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// expectCount checks the value of a counter
+func expectCount(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if got != want {
+		t.Errorf("%s: expected %v, got %v", name, want, got)
+	}
+}
+
+// TestAuthBusinessMetrics tests that registrations, logins, password
+// changes and user events are counted
+func TestAuthBusinessMetrics(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	m := ta.App.GetMetrics().Business
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"email":      "metrics@example.com",
+		"password":   "correct-horse",
+		"first_name": "Metric",
+		"last_name":  "Counter",
+		"username":   "metrics",
+	}, "")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	expectCount(t, "registrations", testutil.ToFloat64(m.Registrations), 1)
+
+	// CreateUser logged both users in
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": user.Email, "password": "wrong-password"}, "")
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("login: expected 401, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	expectCount(t, "successful logins", testutil.ToFloat64(m.Logins.WithLabelValues(string(metrics.LoginSuccess))), 2)
+	expectCount(t, "failed logins", testutil.ToFloat64(m.Logins.WithLabelValues(string(metrics.LoginFailure))), 1)
+
+	// A forced change counts as a reset, and completing it as a required change
+	resp = ta.Request(http.MethodPost, "/api/v1/users/"+user.ID.String()+"/require-password-change", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("require password change: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	expectCount(t, "password resets", testutil.ToFloat64(m.PasswordResets), 1)
+	expectCount(t, "user.updated events", testutil.ToFloat64(m.EventsPublished.WithLabelValues(events.UserUpdated)), 1)
+
+	restricted := loginWithPassword(t, ta, user.Email, user.Password)
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/change-password", map[string]string{
+		"current_password": user.Password,
+		"new_password":     "new-password-1",
+	}, restricted.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("change password: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/change-password", map[string]string{
+		"current_password": admin.Password,
+		"new_password":     "new-password-1",
+	}, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("change password: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	expectCount(t, "required password changes", testutil.ToFloat64(m.PasswordChanges.WithLabelValues(string(metrics.PasswordChangeRequired))), 1)
+	expectCount(t, "voluntary password changes", testutil.ToFloat64(m.PasswordChanges.WithLabelValues(string(metrics.PasswordChangeVoluntary))), 1)
+
+	ta.DB().Model(&models.User{}).Where("id = ?", user.ID).Update("active", false)
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": user.Email, "password": "new-password-1"}, "")
+	if resp.StatusCode == http.StatusOK {
+		t.Fatal("expected a deactivated user's login to fail")
+	}
+	expectCount(t, "deactivated logins", testutil.ToFloat64(m.Logins.WithLabelValues(string(metrics.LoginDeactivated))), 1)
+}
+
+// TestEmailMetrics tests that emails are counted by result
+func TestEmailMetrics(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	m := ta.App.GetMetrics().Business
+	user := ta.CreateUser(models.RoleUser)
+
+	resp := requestEmailChange(ta, user.Token, user.Password, "renamed-"+user.Email)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("email change: expected 202, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	// The confirmation and the notice to the current address
+	expectCount(t, "sent emails", testutil.ToFloat64(m.Emails.WithLabelValues("success")), 2)
+
+	m.EmailSent(stderrors.New("connection refused"))
+	expectCount(t, "failed emails", testutil.ToFloat64(m.Emails.WithLabelValues("failure")), 1)
+}
+
+// TestWebhookMetrics tests that deliveries are counted once and every
+// attempt is timed
+func TestWebhookMetrics(t *testing.T) {
+	repo := newFakeWebhookRepository()
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	m := metrics.New()
+	webhook := createTestWebhook(t, repo, receiver.URL)
+	dispatcher := services.NewWebhookDispatcher(repo, webhookTestConfig(), logger.NewSimpleLogger(), services.WithWebhookMetrics(m.Business))
+	if _, err := dispatcher.Deliver(context.Background(), webhook, events.New(events.UserUpdated, nil)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectCount(t, "successful deliveries", testutil.ToFloat64(m.Business.WebhookDeliveries.WithLabelValues("success")), 1)
+	expectCount(t, "failed deliveries", testutil.ToFloat64(m.Business.WebhookDeliveries.WithLabelValues("failure")), 0)
+	families, err := m.Registry.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == "webhook_delivery_duration_seconds" {
+			if count := family.GetMetric()[0].GetHistogram().GetSampleCount(); count != 2 {
+				t.Errorf("expected 2 timed attempts, got %d", count)
+			}
+			return
+		}
+	}
+	t.Error("webhook_delivery_duration_seconds not registered")
+}
+
+// TestBusinessMetricsNil tests that a nil *Business records nothing and
+// unknown event types share one series
+func TestBusinessMetricsNil(t *testing.T) {
+	var disabled *metrics.Business
+	disabled.Registered()
+	disabled.Login(metrics.LoginSuccess)
+	disabled.EmailSent(nil)
+	disabled.WebhookDelivered(true)
+	disabled.EventPublished(events.UserCreated)
+
+	m := metrics.New().Business
+	m.EventPublished("custom.one")
+	m.EventPublished("custom.two")
+	expectCount(t, "other events", testutil.ToFloat64(m.EventsPublished.WithLabelValues("other")), 2)
+}
@@ -0,0 +1,258 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+
+	"gorm.io/gorm"
+)
+
+var errConnRefused = &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+// flakyDriver is a SQLite-backed driver whose health checks and GORM
+// statements fail with a connection error while down is set
+type flakyDriver struct {
+	*databasetest.MockDriver
+	down  atomic.Bool
+	calls atomic.Int32
+}
+
+func newFlakyDriver(t *testing.T) *flakyDriver {
+	d := &flakyDriver{MockDriver: databasetest.NewMockDriver(t, databasetest.WithSQLite(&models.User{}))}
+	d.GormDB().Callback().Query().Before("gorm:query").Register("flaky", func(tx *gorm.DB) {
+		// Like GORM's own callbacks, skip statements that already failed
+		if tx.Error != nil {
+			return
+		}
+		d.calls.Add(1)
+		if d.down.Load() {
+			tx.AddError(driver.ErrBadConn)
+		}
+	})
+	return d
+}
+
+func (d *flakyDriver) Health(ctx context.Context) error {
+	d.calls.Add(1)
+	if d.down.Load() {
+		return errConnRefused
+	}
+	return nil
+}
+
+func newBreaker(fake *clock.Fake, transitions *[]string) *database.CircuitBreaker {
+	var mu sync.Mutex
+	return database.NewCircuitBreaker(database.CircuitBreakerConfig{
+		FailureThreshold: 3,
+		CoolDown:         10 * time.Second,
+		Clock:            fake,
+		OnStateChange: func(from, to database.CircuitState) {
+			mu.Lock()
+			defer mu.Unlock()
+			*transitions = append(*transitions, from.String()+"->"+to.String())
+		},
+	})
+}
+
+// TestCircuitBreakerStates tests opening, fail-fast, half-open probes and recovery
+func TestCircuitBreakerStates(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	var transitions []string
+	b := newBreaker(fake, &transitions)
+
+	// Errors from a database that answered do not count
+	for i := 0; i < 5; i++ {
+		b.Allow()
+		b.Record(gorm.ErrRecordNotFound)
+	}
+	if b.State() != database.CircuitClosed {
+		t.Fatalf("expected closed after query errors, got %s", b.State())
+	}
+
+	// A success resets the consecutive failure count
+	b.Record(errConnRefused)
+	b.Record(errConnRefused)
+	b.Record(nil)
+	b.Record(errConnRefused)
+	b.Record(errConnRefused)
+	if b.State() != database.CircuitClosed {
+		t.Fatalf("expected closed below the threshold, got %s", b.State())
+	}
+	b.Record(context.DeadlineExceeded)
+	if b.State() != database.CircuitOpen {
+		t.Fatalf("expected open after 3 consecutive failures, got %s", b.State())
+	}
+	if err := b.Allow(); !errors.Is(err, database.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if got := b.RetryAfter(); got != 10*time.Second {
+		t.Errorf("expected 10s until retry, got %v", got)
+	}
+
+	// A failed probe opens the breaker for another cool-down
+	fake.Advance(10 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("expected a probe after the cool-down, got %v", err)
+	}
+	b.Record(driver.ErrBadConn)
+	if b.State() != database.CircuitOpen || b.RetryAfter() != 10*time.Second {
+		t.Fatalf("expected open again after a failed probe, got %s", b.State())
+	}
+
+	// A successful probe closes it
+	fake.Advance(10 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatal(err)
+	}
+	b.Record(nil)
+	if b.State() != database.CircuitClosed || b.RetryAfter() != 0 {
+		t.Fatalf("expected closed after a successful probe, got %s", b.State())
+	}
+
+	want := "closed->open open->half-open half-open->open open->half-open half-open->closed"
+	if got := strings.Join(transitions, " "); got != want {
+		t.Errorf("expected transitions %q, got %q", want, got)
+	}
+}
+
+// TestCircuitBreakerConcurrentCallers tests that open breakers reject every caller and half-open ones admit one probe
+func TestCircuitBreakerConcurrentCallers(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	var transitions []string
+	b := newBreaker(fake, &transitions)
+	for i := 0; i < 3; i++ {
+		b.Record(errConnRefused)
+	}
+
+	allowed := func() int32 {
+		var n atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if b.Allow() == nil {
+					n.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		return n.Load()
+	}
+
+	if n := allowed(); n != 0 {
+		t.Errorf("expected every caller rejected while open, %d got through", n)
+	}
+	fake.Advance(10 * time.Second)
+	if n := allowed(); n != 1 {
+		t.Errorf("expected exactly one probe while half-open, got %d", n)
+	}
+	b.Record(nil)
+	if n := allowed(); n != 50 {
+		t.Errorf("expected every caller allowed once closed, got %d", n)
+	}
+}
+
+// TestManagerCircuitBreaker tests the breaker wired into a manager with a flaky driver
+func TestManagerCircuitBreaker(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	var transitions []string
+	flaky := newFlakyDriver(t)
+
+	manager := database.NewManager()
+	t.Cleanup(func() { manager.CloseAll() })
+	manager.SetCircuitBreaker("primary", newBreaker(fake, &transitions))
+	if err := manager.AddDriver("primary", flaky); err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.OpenGorm(flaky)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Failing GORM statements and health checks both count
+	flaky.down.Store(true)
+	var user models.User
+	db.WithContext(ctx).First(&user)
+	db.WithContext(ctx).First(&user)
+	if status, _ := manager.Readiness(ctx); status != database.StatusNotReady {
+		t.Fatalf("expected not ready, got %s", status)
+	}
+
+	_, databases := manager.Readiness(ctx)
+	if databases["primary"].Circuit != "open" {
+		t.Fatalf("expected the circuit reported open, got %+v", databases["primary"])
+	}
+
+	// While open nothing reaches the database
+	calls := flaky.calls.Load()
+	if _, err := manager.GetDriver("primary"); !errors.Is(err, database.ErrCircuitOpen) {
+		t.Errorf("expected GetDriver to fail fast, got %v", err)
+	}
+	if err := db.WithContext(ctx).First(&user).Error; !errors.Is(err, database.ErrCircuitOpen) {
+		t.Errorf("expected GORM to fail fast, got %v", err)
+	}
+	manager.Readiness(ctx)
+	if flaky.calls.Load() != calls {
+		t.Error("expected no calls to the database while the circuit is open")
+	}
+
+	// After the cool-down a health check probes the recovered database
+	flaky.down.Store(false)
+	fake.Advance(10 * time.Second)
+	if status, databases := manager.Readiness(ctx); status != database.StatusReady || databases["primary"].Circuit != "closed" {
+		t.Fatalf("expected ready and closed after recovery, got %s %+v", status, databases["primary"])
+	}
+	if err := db.WithContext(ctx).First(&user).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected queries to reach the database again, got %v", err)
+	}
+}
+
+// TestCircuitOpenResponses tests the 503, Retry-After and metric while the primary circuit is open
+func TestCircuitOpenResponses(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Database.Primary.BreakerFailures = 2
+		cfg.Database.Primary.BreakerCoolDown = time.Minute
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	breaker := ta.App.GetDBManager().CircuitBreaker("primary")
+	if breaker == nil {
+		t.Fatal("expected a breaker on the primary database")
+	}
+	breaker.Record(errConnRefused)
+	breaker.Record(errConnRefused)
+
+	resp := ta.Request(http.MethodGet, "/api/v1/users", nil, admin.Token)
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "60" {
+		t.Errorf("expected 503 with Retry-After 60, got %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if resp := ta.Request(http.MethodGet, "/health", nil, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected /health to stay up, got %d", resp.StatusCode)
+	}
+
+	resp = ta.Request(http.MethodGet, "/metrics", nil, "")
+	if !strings.Contains(string(resp.Body), `database_circuit_breaker_state{database="primary"} 2`) {
+		t.Errorf("expected the open state in the metrics:\n%s", resp.Body)
+	}
+	if !ta.Logs.Contains("Database circuit breaker opened") {
+		t.Error("expected the transition to be logged")
+	}
+}
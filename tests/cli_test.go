@@ -0,0 +1,383 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	approute "BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/cli"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/utils"
+	"BackofficeGoService/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// cliResult is the outcome of one command line
+type cliResult struct {
+	code   int
+	stdout string
+	stderr string
+}
+
+// newCLIConfig returns a configuration whose primary database is a SQLite
+// file, so state persists from one command to the next
+func newCLIConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	cfg := apptest.Config()
+	cfg.Storage.Path = t.TempDir()
+	cfg.Database.Primary.DBName = filepath.Join(t.TempDir(), "cli.db")
+	return cfg
+}
+
+// runCLI runs the command line args against cfg with stdin as input
+func runCLI(t *testing.T, cfg *config.Config, stdin string, args ...string) cliResult {
+	t.Helper()
+
+	root := cli.NewRootCommand(cli.WithConfigLoader(func() (*config.Config, error) {
+		return cfg, nil
+	}))
+	var stdout, stderr bytes.Buffer
+	root.SetArgs(args)
+	root.SetIn(strings.NewReader(stdin))
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+
+	code := cli.Execute(root)
+	return cliResult{code: code, stdout: stdout.String(), stderr: stderr.String()}
+}
+
+// openCLIDatabase opens the SQLite file commands run against
+func openCLIDatabase(t *testing.T, cfg *config.Config) *gorm.DB {
+	t.Helper()
+
+	driver, err := app.OpenDatabase(context.Background(), cfg.Database.Primary)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	return db
+}
+
+// TestCLIUsageErrors tests that invocation mistakes exit with the usage code
+func TestCLIUsageErrors(t *testing.T) {
+	cfg := newCLIConfig(t)
+
+	tests := [][]string{
+		{"bogus"},
+		{"--bogus"},
+		{"migrate"},
+		{"migrate", "sideways"},
+		{"migrate", "down", "--steps", "0"},
+		{"migrate", "status", "--database", "missing"},
+		{"selftest", "--timeout", "0s"},
+		{"user", "create", "--password-stdin"},
+		{"user", "create", "--email", "a@example.com", "--role", "root", "--password-stdin"},
+	}
+	for _, args := range tests {
+		res := runCLI(t, cfg, "secret-password\n", args...)
+		if res.code != cli.ExitUsage {
+			t.Errorf("%v: expected exit code %d, got %d: %s", args, cli.ExitUsage, res.code, res.stderr)
+		}
+		if !strings.Contains(res.stderr, "Error:") || !strings.Contains(res.stderr, "--help") {
+			t.Errorf("%v: expected the error and a usage hint on stderr, got %q", args, res.stderr)
+		}
+	}
+}
+
+// TestCLIMigrate tests applying, listing and rolling back migrations
+func TestCLIMigrate(t *testing.T) {
+	cfg := newCLIConfig(t)
+
+	res := runCLI(t, cfg, "", "migrate", "status")
+	if res.code != cli.ExitOK || !strings.Contains(res.stdout, "0001_create_users") || strings.Contains(res.stdout, "applied") {
+		t.Fatalf("expected every migration pending, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+
+	res = runCLI(t, cfg, "", "migrate", "up")
+	if res.code != cli.ExitOK || !strings.Contains(res.stdout, "Applied 0001_create_users") {
+		t.Fatalf("expected migrations applied, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+	if res = runCLI(t, cfg, "", "migrate", "up"); !strings.Contains(res.stdout, "Nothing to migrate") {
+		t.Errorf("expected nothing left to migrate, got %s", res.stdout)
+	}
+
+	res = runCLI(t, cfg, "", "migrate", "down", "--steps", "2")
+	if res.code != cli.ExitOK || strings.Count(res.stdout, "Rolled back") != 2 {
+		t.Fatalf("expected two migrations rolled back, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+
+	res = runCLI(t, cfg, "", "migrate", "status")
+	if strings.Count(res.stdout, " pending ") != 2 {
+		t.Errorf("expected two pending migrations, got %s", res.stdout)
+	}
+}
+
+// TestCLIUserCreate tests creating a user with a password piped to stdin
+func TestCLIUserCreate(t *testing.T) {
+	cfg := newCLIConfig(t)
+	if res := runCLI(t, cfg, "", "migrate", "up"); res.code != cli.ExitOK {
+		t.Fatalf("migrate: %s", res.stderr)
+	}
+
+	const password = "s3cret-admin-pass"
+	res := runCLI(t, cfg, password+"\n", "user", "create", "--email", "root@example.com", "--role", "admin", "--password-stdin")
+	if res.code != cli.ExitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", res.code, res.stderr)
+	}
+	if !strings.Contains(res.stdout, "root@example.com") {
+		t.Errorf("expected the created user in the output, got %q", res.stdout)
+	}
+	if strings.Contains(res.stdout+res.stderr, password) {
+		t.Error("the password must never be printed")
+	}
+
+	db := openCLIDatabase(t, cfg)
+	var user models.User
+	if err := db.Where("email = ?", "root@example.com").First(&user).Error; err != nil {
+		t.Fatalf("expected the user to exist: %v", err)
+	}
+	if user.Role != models.RoleAdmin || !user.Active {
+		t.Errorf("expected an active admin, got %s active=%v", user.Role, user.Active)
+	}
+	if !utils.CheckPasswordHash(password, user.Password) {
+		t.Error("expected the piped password to be set")
+	}
+
+	var audits int64
+	db.Model(&models.AuditLog{}).Where("entity_id = ? AND action = ?", user.ID.String(), models.AuditActionUserCreated).Count(&audits)
+	if audits != 1 {
+		t.Errorf("expected the creation to be audited, got %d entries", audits)
+	}
+
+	res = runCLI(t, cfg, password+"\n", "user", "create", "--email", "root@example.com", "--password-stdin")
+	if res.code != cli.ExitError || !strings.Contains(res.stderr, "already exists") {
+		t.Errorf("expected a duplicate email to fail, got %d: %s", res.code, res.stderr)
+	}
+
+	res = runCLI(t, cfg, "short\n", "user", "create", "--email", "other@example.com", "--password-stdin")
+	if res.code != cli.ExitError || strings.Contains(res.stderr, "short") {
+		t.Errorf("expected a short password to fail without echoing it, got %d: %s", res.code, res.stderr)
+	}
+
+	// Without --password-stdin the password is prompted for, which needs a terminal
+	res = runCLI(t, cfg, password+"\n", "user", "create", "--email", "other@example.com")
+	if res.code != cli.ExitUsage {
+		t.Errorf("expected a usage error without a terminal, got %d: %s", res.code, res.stderr)
+	}
+}
+
+// TestCLIUserResetPassword tests replacing a user's password
+func TestCLIUserResetPassword(t *testing.T) {
+	cfg := newCLIConfig(t)
+	runCLI(t, cfg, "", "migrate", "up")
+	runCLI(t, cfg, "first-password\n", "user", "create", "--email", "reset@example.com", "--password-stdin")
+
+	res := runCLI(t, cfg, "second-password\n", "user", "reset-password", "--email", "reset@example.com", "--password-stdin")
+	if res.code != cli.ExitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", res.code, res.stderr)
+	}
+
+	var user models.User
+	openCLIDatabase(t, cfg).Where("email = ?", "reset@example.com").First(&user)
+	if !utils.CheckPasswordHash("second-password", user.Password) {
+		t.Error("expected the new password to be set")
+	}
+
+	res = runCLI(t, cfg, "second-password\n", "user", "reset-password", "--email", "nobody@example.com", "--password-stdin")
+	if res.code != cli.ExitError || !strings.Contains(res.stderr, "no user") {
+		t.Errorf("expected an unknown email to fail, got %d: %s", res.code, res.stderr)
+	}
+}
+
+// TestCLIUserIssueToken tests that issue-token prints an API token carrying
+// its scope, and refuses scopes that restrict tokens
+func TestCLIUserIssueToken(t *testing.T) {
+	cfg := newCLIConfig(t)
+	runCLI(t, cfg, "", "migrate", "up")
+	runCLI(t, cfg, "ci-password\n", "user", "create", "--email", "ci@example.com", "--password-stdin")
+
+	res := runCLI(t, cfg, "", "user", "issue-token", "--email", "ci@example.com", "--scope", "automation", "--ttl", "1h")
+	if res.code != cli.ExitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", res.code, res.stderr)
+	}
+	claims, err := services.NewTokenManager(cfg.JWT, time.Now).VerifyFor("", strings.TrimSpace(res.stdout))
+	if err != nil || claims["api_scope"] != "automation" || claims["sid"] != nil {
+		t.Fatalf("expected a sessionless token with the API scope, got %v %v", claims, err)
+	}
+
+	if res := runCLI(t, cfg, "", "user", "issue-token", "--email", "ci@example.com", "--scope", services.ScopePasswordChange); res.code != cli.ExitUsage {
+		t.Errorf("expected a restricting scope refused, got %d: %s", res.code, res.stderr)
+	}
+	if res := runCLI(t, cfg, "", "user", "issue-token", "--email", "nobody@example.com", "--scope", "automation"); res.code != cli.ExitError || !strings.Contains(res.stderr, "no user") {
+		t.Errorf("expected an unknown email to fail, got %d: %s", res.code, res.stderr)
+	}
+}
+
+// TestCLISeed tests that seeding is repeatable and refused in production
+func TestCLISeed(t *testing.T) {
+	cfg := newCLIConfig(t)
+	runCLI(t, cfg, "", "migrate", "up")
+
+	if res := runCLI(t, cfg, "", "seed", "--users", "3"); res.code != cli.ExitOK || !strings.Contains(res.stdout, "Seeded 4 users") {
+		t.Fatalf("expected four users seeded, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+	if res := runCLI(t, cfg, "", "seed", "--users", "3"); !strings.Contains(res.stdout, "Seeded 0 users (4 already existed)") {
+		t.Errorf("expected reseeding to skip existing users, got %s", res.stdout)
+	}
+
+	cfg.App.Environment = "production"
+	if res := runCLI(t, cfg, "", "seed"); res.code != cli.ExitError || !strings.Contains(res.stderr, "production") {
+		t.Errorf("expected seeding production to be refused, got %d: %s", res.code, res.stderr)
+	}
+}
+
+// TestCLIRoutes tests that the route table is printed without a database
+func TestCLIRoutes(t *testing.T) {
+	cfg := newCLIConfig(t)
+	// Nothing may be connected, so an unreachable database must not matter
+	cfg.Database.Primary.Driver = "postgres"
+	cfg.Database.Primary.Host = "unreachable.invalid"
+
+	res := runCLI(t, cfg, "", "routes")
+	if res.code != cli.ExitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", res.code, res.stderr)
+	}
+	routes := make(map[string]string)
+	for _, line := range strings.Split(res.stdout, "\n") {
+		if fields := strings.Fields(line); len(fields) == 3 {
+			routes[fields[0]+" "+fields[1]] = fields[2]
+		}
+	}
+	if handler := routes["POST /api/v1/auth/login"]; handler != "internal/app/controllers/auth.(*AuthController).Login" {
+		t.Errorf("expected the login route, got %q:\n%s", handler, res.stdout)
+	}
+	if _, ok := routes["GET /health"]; !ok {
+		t.Errorf("expected the health check in the route table:\n%s", res.stdout)
+	}
+}
+
+// TestCLIRoutesFormats tests printing the route table with its policies as
+// JSON and Markdown
+func TestCLIRoutesFormats(t *testing.T) {
+	cfg := newCLIConfig(t)
+
+	res := runCLI(t, cfg, "", "routes", "--format", "json")
+	if res.code != cli.ExitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", res.code, res.stderr)
+	}
+	var table approute.Table
+	if err := json.Unmarshal([]byte(res.stdout), &table); err != nil {
+		t.Fatalf("decode %s: %v", res.stdout, err)
+	}
+	i := slices.IndexFunc(table, func(info approute.Info) bool {
+		return info.Method == "PUT" && info.Path == "/api/v1/users/:id"
+	})
+	if i < 0 || table[i].Permission != models.PermissionUsersUpdate || table[i].Auth != "authenticated" {
+		t.Errorf("expected the user update route with its permission in %s", res.stdout)
+	}
+
+	res = runCLI(t, cfg, "", "routes", "--format", "markdown")
+	if res.code != cli.ExitOK || !strings.Contains(res.stdout, "| PUT | `/api/v1/users/:id` | authenticated |  | users.update |") {
+		t.Errorf("unexpected markdown %d:\n%s", res.code, res.stdout)
+	}
+
+	if res := runCLI(t, cfg, "", "routes", "--format", "csv"); res.code != cli.ExitUsage {
+		t.Errorf("expected exit code %d for an unknown format, got %d: %s", cli.ExitUsage, res.code, res.stderr)
+	}
+}
+
+// selfTestRows parses the report of selftest into the result, required and
+// error columns of each check
+func selfTestRows(t *testing.T, stdout string) map[string][]string {
+	t.Helper()
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if header := strings.Fields(lines[0]); !slices.Equal(header, []string{"CHECK", "RESULT", "REQUIRED", "DURATION", "ERROR"}) {
+		t.Fatalf("unexpected report header %q", lines[0])
+	}
+	rows := make(map[string][]string)
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			t.Fatalf("unexpected report line %q", line)
+		}
+		rows[fields[0]] = []string{fields[1], fields[2], strings.Join(fields[4:], " ")}
+	}
+	return rows
+}
+
+// TestCLISelfTest tests that the self-test passes against a working setup
+// and leaves no probe file behind
+func TestCLISelfTest(t *testing.T) {
+	cfg := newCLIConfig(t)
+
+	res := runCLI(t, cfg, "", "selftest")
+	if res.code != cli.ExitOK {
+		t.Fatalf("expected exit code %d, got %d: %s%s", cli.ExitOK, res.code, res.stdout, res.stderr)
+	}
+	rows := selfTestRows(t, res.stdout)
+	for _, name := range []string{"database:primary", "migrations", "storage", "jwt"} {
+		if row := rows[name]; !slices.Equal(row, []string{"pass", "yes", "-"}) {
+			t.Errorf("%s: expected a required pass, got %v", name, row)
+		}
+	}
+	if len(rows) != 4 {
+		t.Errorf("expected only the configured dependencies to be checked, got %v", rows)
+	}
+	if entries, _ := filepath.Glob(filepath.Join(cfg.Storage.Path, ".probe", "*")); len(entries) != 0 {
+		t.Errorf("expected the probe to be deleted, found %v", entries)
+	}
+}
+
+// TestCLISelfTestFailures tests that failed required checks exit non-zero
+// while failed optional ones are only reported
+func TestCLISelfTestFailures(t *testing.T) {
+	cfg := newCLIConfig(t)
+	cfg.Database.AutoMigrate = false
+	cfg.Database.Databases = map[string]config.DatabaseConnectionConfig{
+		"reports": {Driver: string(database.DriverSQLite), DBName: filepath.Join(t.TempDir(), "missing", "reports.db"), UseGorm: true},
+	}
+
+	res := runCLI(t, cfg, "", "selftest")
+	if res.code != cli.ExitError || !strings.Contains(res.stderr, "1 required checks failed") {
+		t.Fatalf("expected the pending migrations to fail the self-test, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+	rows := selfTestRows(t, res.stdout)
+	if row := rows["migrations"]; row[0] != "fail" || !strings.Contains(row[2], "pending migrations") {
+		t.Errorf("expected pending migrations to be reported, got %v", row)
+	}
+	if row := rows["database:reports"]; row[0] != "fail" || row[1] != "no" {
+		t.Errorf("expected the optional database to fail, got %v", row)
+	}
+
+	if res := runCLI(t, cfg, "", "migrate", "up"); res.code != cli.ExitOK {
+		t.Fatalf("migrate up: %d %s", res.code, res.stderr)
+	}
+	res = runCLI(t, cfg, "", "selftest")
+	if res.code != cli.ExitOK {
+		t.Errorf("expected optional failures not to fail the self-test, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+
+	cfg.JWT.Secret = "short"
+	res = runCLI(t, cfg, "", "selftest")
+	if row := selfTestRows(t, res.stdout)["jwt"]; res.code != cli.ExitError || row[0] != "fail" || !strings.Contains(row[2], "at least 32") {
+		t.Errorf("expected the short secret to fail the self-test, got %d %v", res.code, row)
+	}
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+)
+
+func TestFakeClockTimers(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	after := fake.After(time.Minute)
+	ticker := fake.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	fake.Advance(30 * time.Second)
+	select {
+	case <-after:
+		t.Fatal("After fired before its deadline")
+	default:
+	}
+	select {
+	case <-ticker.C():
+	default:
+		t.Fatal("expected a tick after 30s")
+	}
+
+	fake.Advance(30 * time.Second)
+	select {
+	case fired := <-after:
+		if !fired.Equal(fake.Now()) {
+			t.Errorf("expected After to deliver the current fake time, got %v", fired)
+		}
+	default:
+		t.Fatal("After did not fire at its deadline")
+	}
+}
+
+// TestTokenExpiresWithFakeClock tests that a token is rejected once the clock passes its expiry
+func TestTokenExpiresWithFakeClock(t *testing.T) {
+	db, _ := databasetest.NewManager(t, databasetest.WithSQLite(&models.User{}, &models.LoginEvent{}))
+	fake := clock.NewFake(time.Now())
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "clock-test-secret", Expiration: time.Hour, Issuer: "clock-test"}}
+	store := cache.NewMemoryStore()
+	revoker := services.NewTokenRevoker(store, time.Hour, services.WithRevokerClock(fake))
+	log := logger.NewNopLogger()
+	auth := services.NewAuthService(db, cfg, store, revoker, services.NewAuditService(db, log), nil, log, services.WithAuthClock(fake))
+	ctx := context.Background()
+
+	if _, err := auth.Register(ctx, &services.CreateUserRequest{Email: "ann@example.com", Password: "secret123"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	result, err := auth.Login(ctx, "ann@example.com", "secret123", services.ClientInfo{})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	token := result.Token
+
+	fake.Advance(59 * time.Minute)
+	claims, err := auth.ValidateToken(ctx, token)
+	if err != nil {
+		t.Fatalf("token should still be valid: %v", err)
+	}
+	if got := claims.ExpiresAt.Sub(claims.IssuedAt); got != time.Hour {
+		t.Errorf("expected a one hour lifetime, got %v", got)
+	}
+
+	fake.Advance(2 * time.Minute)
+	if _, err := auth.ValidateToken(ctx, token); !errors.Is(err, services.ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken after expiry, got %v", err)
+	}
+}
+
+// TestFileLoggerRotatesAtMidnight tests that entries after midnight go to the next day's file
+func TestFileLoggerRotatesAtMidnight(t *testing.T) {
+	dir := t.TempDir()
+	fake := clock.NewFake(time.Date(2024, 1, 15, 23, 59, 0, 0, time.Local))
+	log, err := logger.NewFileLogger(logger.FileLoggerConfig{
+		LogPath:     dir,
+		LogFileName: "app",
+		MaxSize:     1,
+		DailyRotate: true,
+	}, logger.WithFileLoggerClock(fake))
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+
+	log.Info("before midnight")
+	fake.Advance(2 * time.Minute)
+	log.Info("after midnight")
+	if err := log.(*logger.FileLogger).Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return string(data)
+	}
+	first, second := read("app-2024-01-15.log"), read("app-2024-01-16.log")
+	if !strings.Contains(first, "before midnight") || strings.Contains(first, "after midnight") {
+		t.Errorf("unexpected first day log %q", first)
+	}
+	if !strings.Contains(second, "after midnight") || strings.Contains(second, "before midnight") {
+		t.Errorf("unexpected second day log %q", second)
+	}
+}
@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// blockingRouter serves handlers that signal entered and then hold their
+// slot until release is sent to
+type blockingRouter struct {
+	*gin.Engine
+	entered chan string
+	release chan struct{}
+}
+
+func newBlockingRouter(cfg middleware.ConcurrencyConfig, m *metrics.Metrics) *blockingRouter {
+	gin.SetMode(gin.TestMode)
+	r := &blockingRouter{
+		Engine:  gin.New(),
+		entered: make(chan string, 16),
+		release: make(chan struct{}),
+	}
+	r.Use(middleware.Locale())
+	r.Use(middleware.ConcurrencyLimit(cfg, middleware.ConcurrencyMetrics{InFlight: m.InFlight, Shed: m.Shed}))
+	block := func(c *gin.Context) {
+		r.entered <- c.Request.URL.Path
+		<-r.release
+		c.Status(http.StatusOK)
+	}
+	r.GET("/users/:id", block)
+	r.GET("/users/:id/export", block)
+	r.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+// get serves a request synchronously
+func (r *blockingRouter) get(path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+// start serves a request in the background, sending its recorder to done
+func (r *blockingRouter) start(wg *sync.WaitGroup, path string, done chan<- *httptest.ResponseRecorder) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		done <- r.get(path)
+	}()
+}
+
+// awaitEntered waits for a handler to start, failing after a second
+func (r *blockingRouter) awaitEntered(t *testing.T) string {
+	t.Helper()
+	select {
+	case path := <-r.entered:
+		return path
+	case <-time.After(time.Second):
+		t.Fatal("no handler started")
+		return ""
+	}
+}
+
+// expectShed checks a 503 SERVER_BUSY response with a Retry-After header
+func expectShed(t *testing.T, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected Retry-After 1, got %q", rec.Header().Get("Retry-After"))
+	}
+	var envelope errorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decode envelope: %v", err)
+	}
+	if envelope.Error.Code != string(errors.CodeServerBusy) {
+		t.Errorf("expected %s, got %+v", errors.CodeServerBusy, envelope.Error)
+	}
+}
+
+// TestConcurrencyLimit tests the cap, the wait queue, its timeout and the
+// exemptions under concurrent blocking requests
+func TestConcurrencyLimit(t *testing.T) {
+	m := metrics.New()
+	r := newBlockingRouter(middleware.ConcurrencyConfig{
+		Limit:     2,
+		Overrides: map[string]int{"/users/:id/export": 1},
+		Queue:     1,
+		Wait:      200 * time.Millisecond,
+		Exempt:    []string{"/health"},
+	}, m)
+
+	var wg sync.WaitGroup
+	done := make(chan *httptest.ResponseRecorder, 16)
+	defer func() {
+		close(r.release)
+		wg.Wait()
+	}()
+
+	// Two requests fill the global limit
+	r.start(&wg, "/users/1", done)
+	r.start(&wg, "/users/2", done)
+	r.awaitEntered(t)
+	r.awaitEntered(t)
+	if got := testutil.ToFloat64(m.InFlight.WithLabelValues("default")); got != 2 {
+		t.Errorf("expected 2 requests in flight, got %v", got)
+	}
+
+	// A third waits in the queue, so a fourth is shed at once
+	r.start(&wg, "/users/3", done)
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	expectShed(t, r.get("/users/4"))
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("expected a full queue to shed at once, took %v", elapsed)
+	}
+
+	// Freeing a slot admits the queued request
+	r.release <- struct{}{}
+	if path := r.awaitEntered(t); path != "/users/3" {
+		t.Fatalf("expected the queued request to start, got %s", path)
+	}
+	if rec := <-done; rec.Code != http.StatusOK {
+		t.Errorf("expected the released request to succeed, got %d", rec.Code)
+	}
+
+	// A queued request that waits too long is shed
+	start = time.Now()
+	expectShed(t, r.get("/users/5"))
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected the request to wait for its slot, shed after %v", elapsed)
+	}
+
+	// Exempt routes and overridden groups have their own room
+	if rec := r.get("/health"); rec.Code != http.StatusOK {
+		t.Errorf("expected /health to be exempt, got %d", rec.Code)
+	}
+	r.start(&wg, "/users/1/export", done)
+	if path := r.awaitEntered(t); path != "/users/1/export" {
+		t.Fatalf("expected the export to start beside the full default group, got %s", path)
+	}
+	expectShed(t, r.get("/users/2/export"))
+	if got := testutil.ToFloat64(m.InFlight.WithLabelValues("/users/:id/export")); got != 1 {
+		t.Errorf("expected 1 export in flight, got %v", got)
+	}
+
+	for reason, want := range map[string]float64{"queue_full": 1, "timeout": 1} {
+		if got := testutil.ToFloat64(m.Shed.WithLabelValues("default", reason)); got != want {
+			t.Errorf("expected %v %s sheds, got %v", want, reason, got)
+		}
+	}
+	if got := testutil.ToFloat64(m.Shed.WithLabelValues("/users/:id/export", "timeout")); got != 1 {
+		t.Errorf("expected the export to be shed on its own limit, got %v", got)
+	}
+}
+
+// TestConcurrencyLimitDisabled tests that a zero limit admits every request
+func TestConcurrencyLimitDisabled(t *testing.T) {
+	m := metrics.New()
+	r := newBlockingRouter(middleware.ConcurrencyConfig{Wait: time.Millisecond}, m)
+
+	var wg sync.WaitGroup
+	done := make(chan *httptest.ResponseRecorder, 16)
+	for i := 0; i < 5; i++ {
+		r.start(&wg, "/users/1", done)
+	}
+	for i := 0; i < 5; i++ {
+		r.awaitEntered(t)
+	}
+	close(r.release)
+	wg.Wait()
+	close(done)
+	for rec := range done {
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200 without a limit, got %d", rec.Code)
+		}
+	}
+}
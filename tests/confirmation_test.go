@@ -0,0 +1,294 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// challengeEnvelope is the answer to an unconfirmed destructive request
+type challengeEnvelope struct {
+	Data services.ConfirmationChallenge `json:"data"`
+}
+
+// TestConfirmationTokens tests that tokens confirm only the request and
+// user they were issued for, once, until they expire
+func TestConfirmationTokens(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	store := cache.NewMemoryStore(cache.WithMemoryClock(clk))
+	confirmations := services.NewConfirmations(store, time.Minute, nil, services.WithConfirmationClock(clk))
+	digest := services.ConfirmationDigest(http.MethodDelete, "/api/v1/users/1", "", nil)
+
+	challenge, err := confirmations.Issue(ctx, "ann", digest, &services.ConfirmationSummary{Affected: map[string]int64{"users": 1}})
+	if err != nil || challenge.Token == "" || !challenge.ExpiresAt.Equal(clk.Now().Add(time.Minute)) {
+		t.Fatalf("unexpected challenge %+v %v", challenge, err)
+	}
+
+	other := services.ConfirmationDigest(http.MethodDelete, "/api/v1/users/2", "", nil)
+	if err := confirmations.Redeem(ctx, challenge.Token, "ann", other); !stderrors.Is(err, services.ErrConfirmationMismatch) {
+		t.Errorf("expected another request refused, got %v", err)
+	}
+	if err := confirmations.Redeem(ctx, challenge.Token, "bob", digest); !stderrors.Is(err, services.ErrConfirmationMismatch) {
+		t.Errorf("expected another user refused, got %v", err)
+	}
+	if err := confirmations.Redeem(ctx, challenge.Token, "ann", digest); err != nil {
+		t.Fatalf("expected the token to confirm its request, got %v", err)
+	}
+	if err := confirmations.Redeem(ctx, challenge.Token, "ann", digest); !stderrors.Is(err, services.ErrConfirmationInvalid) {
+		t.Errorf("expected a used token refused, got %v", err)
+	}
+	if err := confirmations.Redeem(ctx, "unknown", "ann", digest); !stderrors.Is(err, services.ErrConfirmationInvalid) {
+		t.Errorf("expected an unknown token refused, got %v", err)
+	}
+
+	challenge, err = confirmations.Issue(ctx, "ann", digest, nil)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	clk.Advance(time.Minute + time.Second)
+	if err := confirmations.Redeem(ctx, challenge.Token, "ann", digest); !stderrors.Is(err, services.ErrConfirmationInvalid) {
+		t.Errorf("expected an expired token refused, got %v", err)
+	}
+
+	exempt := services.NewConfirmations(store, time.Minute, []string{"automation"})
+	if !exempt.Exempt("automation") || exempt.Exempt("") || exempt.Exempt(services.ScopePasswordChange) || confirmations.Exempt("automation") {
+		t.Error("expected only the listed scope exempt")
+	}
+}
+
+// confirmRouter serves a destructive route for the user named by X-User,
+// whose API token has the scope in X-Scope, counting the requests that ran
+func confirmRouter(confirmations *services.Confirmations, ran *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.ClaimsKey, &services.TokenClaims{UserID: c.GetHeader("X-User"), Role: string(models.RoleAdmin), APIScope: c.GetHeader("X-Scope")})
+	})
+	rule := middleware.ConfirmRule{Summarize: func(c *gin.Context) (*services.ConfirmationSummary, *errors.AppError) {
+		return &services.ConfirmationSummary{Affected: map[string]int64{"users": 2}, SampleIDs: []string{"a", "b"}}, nil
+	}}
+	router.POST("/purge", middleware.Confirm(confirmations, rule), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		*ran++
+		c.String(http.StatusOK, string(body))
+	})
+	return router
+}
+
+// TestConfirmMiddleware tests the two steps of destructive requests: the
+// summary with a token, then the same request with the token
+func TestConfirmMiddleware(t *testing.T) {
+	var ran int
+	router := confirmRouter(services.NewConfirmations(cache.NewMemoryStore(), time.Minute, []string{"automation"}), &ran)
+	serve := func(user, scope, body, token string) *apptest.Response {
+		req := httptest.NewRequest(http.MethodPost, "/purge", bytes.NewBufferString(body))
+		req.Header.Set("X-User", user)
+		req.Header.Set("X-Scope", scope)
+		if token != "" {
+			req.Header.Set(middleware.ConfirmTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return &apptest.Response{StatusCode: w.Code, Header: w.Header(), Body: w.Body.Bytes()}
+	}
+
+	resp := serve("ann", "", `{"filter":"inactive"}`, "")
+	var challenge challengeEnvelope
+	resp.Decode(t, &challenge)
+	if resp.StatusCode != http.StatusAccepted || challenge.Data.Token == "" || ran != 0 {
+		t.Fatalf("expected a challenge without running, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if summary := challenge.Data.Summary; summary == nil || summary.Affected["users"] != 2 || !slices.Equal(summary.SampleIDs, []string{"a", "b"}) {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	token := challenge.Data.Token
+	expectErrorCode(t, serve("ann", "", `{"filter":"all"}`, token), http.StatusConflict, errors.CodeConfirmationMismatch)
+	expectErrorCode(t, serve("bob", "", `{"filter":"inactive"}`, token), http.StatusConflict, errors.CodeConfirmationMismatch)
+	if ran != 0 {
+		t.Fatal("expected mismatched requests not to run")
+	}
+
+	// The handler still reads the body the digest was taken of
+	if resp := serve("ann", "", `{"filter":"inactive"}`, token); resp.StatusCode != http.StatusOK || string(resp.Body) != `{"filter":"inactive"}` || ran != 1 {
+		t.Fatalf("expected the confirmed request to run once, got %d %s", resp.StatusCode, resp.Body)
+	}
+	expectErrorCode(t, serve("ann", "", `{"filter":"inactive"}`, token), http.StatusConflict, errors.CodeConfirmationInvalid)
+
+	if resp := serve("ann", "automation", `{"filter":"inactive"}`, ""); resp.StatusCode != http.StatusOK || ran != 2 {
+		t.Errorf("expected an exempt scope to run at once, got %d", resp.StatusCode)
+	}
+	if resp := serve("ann", "reports", `{"filter":"inactive"}`, ""); resp.StatusCode != http.StatusAccepted || ran != 2 {
+		t.Errorf("expected a scope not listed to be confirmed, got %d", resp.StatusCode)
+	}
+
+	// Without confirmations, requests run at once
+	ran = 0
+	router = confirmRouter(nil, &ran)
+	if resp := serve("ann", "", `{}`, ""); resp.StatusCode != http.StatusOK || ran != 1 {
+		t.Errorf("expected confirmation off, got %d", resp.StatusCode)
+	}
+}
+
+// TestConfirmUserDeletion tests that deleting a user runs only once
+// confirmed, after a summary naming the user
+func TestConfirmUserDeletion(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.API.Confirmation = config.ConfirmationConfig{Enabled: true, TTL: time.Minute}
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+	path := "/api/v1/users/" + user.ID.String()
+
+	resp := ta.Request(http.MethodDelete, path, nil, admin.Token)
+	var challenge challengeEnvelope
+	resp.Decode(t, &challenge)
+	if resp.StatusCode != http.StatusAccepted || challenge.Data.Summary == nil ||
+		!slices.Equal(challenge.Data.Summary.SampleIDs, []string{user.ID.String()}) {
+		t.Fatalf("expected a challenge naming the user, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if resp := ta.Request(http.MethodGet, path, nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the user kept until confirmed, got %d", resp.StatusCode)
+	}
+
+	confirmed := func(token string) *apptest.Response {
+		req, _ := http.NewRequest(http.MethodDelete, ta.Server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		req.Header.Set(middleware.ConfirmTokenHeader, token)
+		res, err := ta.Server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return &apptest.Response{StatusCode: res.StatusCode, Header: res.Header, Body: body}
+	}
+	if resp := confirmed(challenge.Data.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the confirmed deletion to run, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if resp := ta.Request(http.MethodGet, path, nil, admin.Token); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected the user deleted, got %d", resp.StatusCode)
+	}
+	expectErrorCode(t, confirmed(challenge.Data.Token), http.StatusConflict, errors.CodeConfirmationInvalid)
+
+	resp = ta.Request(http.MethodPost, "/api/v1/users/"+admin.ID.String()+"x/anonymize", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusNotFound, errors.CodeUserNotFound)
+}
+
+// TestConfirmScopedToken tests that a signed token restricted to a scope
+// can neither skip the confirmation nor reach destructive routes
+func TestConfirmScopedToken(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.API.Confirmation = config.ConfirmationConfig{Enabled: true, TTL: time.Minute, ExemptScopes: []string{services.ScopePasswordChange}}
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+	path := "/api/v1/users/" + user.ID.String()
+
+	ta.DB().Model(&models.User{}).Where("id = ?", admin.ID).Update("must_change_password", true)
+	restricted := login(t, ta, admin, nil)
+
+	resp := ta.Request(http.MethodDelete, path, nil, restricted.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodePasswordChangeRequired)
+	if n := countRows(t, ta.DB(), &models.User{}, "id = ?", user.ID); n != 1 {
+		t.Error("expected the user kept")
+	}
+}
+
+// TestConfirmAPIToken tests that API tokens with an exempt scope pass Auth
+// and delete at once, while other tokens are still asked to confirm
+func TestConfirmAPIToken(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.API.Confirmation = config.ConfirmationConfig{Enabled: true, TTL: time.Minute, ExemptScopes: []string{"automation"}}
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+	log := logger.NewNopLogger()
+	db := ta.App.GetDBManager()
+	store := cache.NewMemoryStore()
+	auth := services.NewAuthService(db, ta.Config, store, services.NewTokenRevoker(store, ta.Config.JWT.Expiration), services.NewAuditService(db, log), nil, log)
+	issue := func(scope string) string {
+		t.Helper()
+		result, err := auth.IssueAPIToken(context.Background(), admin.ID.String(), scope, time.Hour)
+		if err != nil {
+			t.Fatalf("issue API token: %v", err)
+		}
+		return result.Token
+	}
+
+	for name, token := range map[string]string{"login": admin.Token, "unlisted scope": issue("reports")} {
+		user := ta.CreateUser(models.RoleUser)
+		if resp := ta.Request(http.MethodDelete, "/api/v1/users/"+user.ID.String(), nil, token); resp.StatusCode != http.StatusAccepted {
+			t.Errorf("expected the %s token to be confirmed, got %d %s", name, resp.StatusCode, resp.Body)
+		}
+	}
+
+	user := ta.CreateUser(models.RoleUser)
+	if resp := ta.Request(http.MethodDelete, "/api/v1/users/"+user.ID.String(), nil, issue("automation")); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the exempt API token to delete at once, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if n := countRows(t, ta.DB(), &models.User{}, "id = ?", user.ID); n != 0 {
+		t.Error("expected the user deleted")
+	}
+	if n := countRows(t, ta.DB(), &models.AuditLog{}, "action = ? AND entity_id = ?", models.AuditActionUserAPITokenIssued, admin.ID.String()); n != 2 {
+		t.Errorf("expected both API tokens audited, got %d", n)
+	}
+
+	// API tokens end when they expire
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": issue("automation")}, "")
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected refreshing an API token refused, got %d %s", resp.StatusCode, resp.Body)
+	}
+
+	for _, scope := range []string{"", services.ScopePasswordChange} {
+		if _, err := auth.IssueAPIToken(context.Background(), admin.ID.String(), scope, time.Hour); !stderrors.Is(err, services.ErrInvalidAPIScope) {
+			t.Errorf("expected scope %q refused, got %v", scope, err)
+		}
+	}
+	if _, err := auth.IssueAPIToken(context.Background(), admin.ID.String(), "automation", services.MaxTokenLifetime(ta.Config)+time.Hour); !stderrors.Is(err, services.ErrAPITokenLifetime) {
+		t.Errorf("expected a lifetime past the longest token refused, got %v", err)
+	}
+}
+
+// TestPreviewUserPurge tests the summary of running the purge job by hand
+func TestPreviewUserPurge(t *testing.T) {
+	svc, db := newPurgeFixture(t)
+	ctx := context.Background()
+	deleted := time.Now().Add(-48 * time.Hour)
+	id := seedPurgeUser(t, db, "old@example.com", &deleted)
+
+	summary, err := services.PreviewUserPurge(ctx, svc, 24*time.Hour, services.UserPurgeOptions{})
+	if err != nil || summary == nil {
+		t.Fatalf("preview: %+v %v", summary, err)
+	}
+	if summary.Affected["users"] != 1 || summary.Affected["login_events"] != 1 || !slices.Equal(summary.SampleIDs, []string{id.String()}) {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if n := countRows(t, db, &models.User{}, "id = ?", id); n != 1 {
+		t.Error("expected the preview to remove nothing")
+	}
+
+	for name, after := range map[string]time.Duration{"disabled": 0, "nothing due": 72 * time.Hour} {
+		if summary, err := services.PreviewUserPurge(ctx, svc, after, services.UserPurgeOptions{}); err != nil || summary != nil {
+			t.Errorf("%s: expected no summary, got %+v %v", name, summary, err)
+		}
+	}
+}
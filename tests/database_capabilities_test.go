@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	apperrors "BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/jobs"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// newCapabilitylessManager registers a connected driver without SQL or GORM
+// as the primary database
+func newCapabilitylessManager(t *testing.T) *database.Manager {
+	t.Helper()
+
+	driver := &fakeDriver{}
+	driver.Connect(context.Background())
+	manager := database.NewManager()
+	if err := manager.AddDriver(database.PrimaryDriver, driver); err != nil {
+		t.Fatalf("add driver: %v", err)
+	}
+	t.Cleanup(func() { manager.CloseAll() })
+	return manager
+}
+
+// TestCapabilitylessDriver tests that code needing SQL fails cleanly on a
+// driver without it
+func TestCapabilitylessDriver(t *testing.T) {
+	manager := newCapabilitylessManager(t)
+	driver, _ := manager.GetDriver(database.PrimaryDriver)
+
+	if _, err := database.SQLDB(driver); !errors.Is(err, database.ErrOperationNotSupported) {
+		t.Errorf("SQLDB: expected ErrOperationNotSupported, got %v", err)
+	}
+	if db := database.NativeGorm(driver); db != nil {
+		t.Errorf("NativeGorm: expected nil, got %v", db)
+	}
+	if _, err := database.OpenGorm(driver); !errors.Is(err, database.ErrOperationNotSupported) {
+		t.Errorf("OpenGorm: expected ErrOperationNotSupported, got %v", err)
+	}
+	if _, err := jobs.NewDBLocker(driver); !errors.Is(err, database.ErrOperationNotSupported) {
+		t.Errorf("NewDBLocker: expected ErrOperationNotSupported, got %v", err)
+	}
+
+	users := services.NewUserService(manager, cache.NewMemoryStore(), nil, nil, nil, logger.NewSimpleLogger())
+	ctx := context.Background()
+	if _, err := users.GetUser(ctx, uuid.NewString()); !errors.Is(err, database.ErrOperationNotSupported) {
+		t.Errorf("GetUser: expected ErrOperationNotSupported, got %v", err)
+	}
+	if _, err := users.ListUsers(ctx, services.ListUsersFilter{}, 10, 0); !errors.Is(err, database.ErrOperationNotSupported) {
+		t.Errorf("ListUsers: expected ErrOperationNotSupported, got %v", err)
+	}
+	if err := users.DeleteUser(ctx, uuid.NewString()); !errors.Is(err, database.ErrOperationNotSupported) {
+		t.Errorf("DeleteUser: expected ErrOperationNotSupported, got %v", err)
+	}
+
+	details := manager.HealthChecks()[0].(interface{ Details() map[string]string }).Details()
+	if details["capabilities"] != "none" {
+		t.Errorf("expected the health details to report no capabilities, got %v", details)
+	}
+}
+
+// TestDriverCapabilities tests the capabilities the drivers report
+func TestDriverCapabilities(t *testing.T) {
+	plain := database.NewPostgresDriver(&database.PostgresConfig{})
+	if caps := plain.Capabilities(); !caps.SQL || caps.Gorm || !caps.Transactions {
+		t.Errorf("unexpected postgres capabilities %s", caps)
+	}
+	// Without GORM the handle is a nil *gorm.DB, not a non-nil interface
+	if plain.GetGormDB() != nil || database.NativeGorm(plain) != nil {
+		t.Error("expected no GORM handle")
+	}
+	if _, err := database.SQLDB(plain); !errors.Is(err, database.ErrNotConnected) {
+		t.Errorf("expected an unconnected driver to fail with ErrNotConnected, got %v", err)
+	}
+
+	withGorm := database.NewSQLiteDriver(&database.SQLiteConfig{UseGorm: true})
+	if caps := withGorm.Capabilities(); caps.String() != "sql,gorm,transactions" {
+		t.Errorf("unexpected sqlite capabilities %s", caps)
+	}
+
+	_, mock := databasetest.NewManager(t, databasetest.WithSQLite(&models.User{}))
+	if !mock.Capabilities().Gorm || database.NativeGorm(mock) == nil {
+		t.Error("expected the mock driver with SQLite to offer GORM")
+	}
+}
+
+// TestUnsupportedOperationResponse tests that handlers answer 501 for
+// operations the driver cannot perform, whatever error they wrapped it in
+func TestUnsupportedOperationResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Locale())
+	router.GET("/users/:id", func(c *gin.Context) {
+		err := errors.Join(errors.New("database error"), database.ErrOperationNotSupported)
+		middleware.RespondError(c, apperrors.NewNotFoundError("", err).WithCode(apperrors.CodeUserNotFound))
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d: %s", rec.Code, rec.Body)
+	}
+	var envelope errorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decode envelope: %v", err)
+	}
+	if envelope.Error.Code != string(apperrors.CodeNotSupported) || envelope.Error.Message != "This operation is not supported by the configured database" {
+		t.Errorf("unexpected error %+v", envelope.Error)
+	}
+}
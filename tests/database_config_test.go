@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/pkg/database"
+)
+
+// TestConnectionConfigTranslation tests that every supported driver gets its own config type
+func TestConnectionConfigTranslation(t *testing.T) {
+	base := database.ConnectionConfig{
+		Host:            "db.internal",
+		Port:            "5432",
+		User:            "backoffice",
+		Password:        "secret",
+		DBName:          "backoffice",
+		SSLMode:         "require",
+		Charset:         "utf8mb4",
+		MaxOpenConns:    20,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: time.Hour,
+		ConnMaxIdleTime: time.Minute,
+		UseGorm:         true,
+	}
+
+	cases := []struct {
+		driver database.DriverType
+		check  func(t *testing.T, cfg interface{})
+	}{
+		{database.DriverPostgreSQL, func(t *testing.T, cfg interface{}) {
+			pg, ok := cfg.(*database.PostgresConfig)
+			if !ok {
+				t.Fatalf("expected *PostgresConfig, got %T", cfg)
+			}
+			if pg.Host != "db.internal" || pg.SSLMode != "require" || pg.MaxOpenConns != 20 || pg.ConnMaxLifetime != time.Hour || !pg.UseGorm {
+				t.Errorf("unexpected postgres config %+v", pg)
+			}
+		}},
+		{database.DriverMySQL, func(t *testing.T, cfg interface{}) {
+			my, ok := cfg.(*database.MySQLConfig)
+			if !ok {
+				t.Fatalf("expected *MySQLConfig, got %T", cfg)
+			}
+			if my.Charset != "utf8mb4" || !my.ParseTime || my.Loc != "Local" || my.MaxIdleConns != 5 || my.ConnMaxIdleTime != time.Minute {
+				t.Errorf("unexpected mysql config %+v", my)
+			}
+		}},
+		{database.DriverSQLite, func(t *testing.T, cfg interface{}) {
+			lite, ok := cfg.(*database.SQLiteConfig)
+			if !ok {
+				t.Fatalf("expected *SQLiteConfig, got %T", cfg)
+			}
+			if lite.Path != "backoffice" || lite.MaxOpenConns != 20 || !lite.UseGorm {
+				t.Errorf("unexpected sqlite config %+v", lite)
+			}
+		}},
+	}
+
+	factory := database.NewFactory()
+	for _, tc := range cases {
+		t.Run(string(tc.driver), func(t *testing.T) {
+			cfg := base
+			cfg.Driver = tc.driver
+
+			driverConfig, err := database.DriverConfig(cfg)
+			if err != nil {
+				t.Fatalf("translate: %v", err)
+			}
+			tc.check(t, driverConfig)
+
+			driver, err := factory.CreateFromConnectionConfig(cfg)
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			if driver.Type() != tc.driver {
+				t.Errorf("expected a %s driver, got %s", tc.driver, driver.Type())
+			}
+		})
+	}
+}
+
+// TestConnectionConfigUnsupportedDriver tests that drivers without a builder are rejected
+func TestConnectionConfigUnsupportedDriver(t *testing.T) {
+	for _, driver := range []database.DriverType{database.DriverMongoDB, "oracle", ""} {
+		_, err := database.NewFactory().CreateFromConnectionConfig(database.ConnectionConfig{Driver: driver})
+		if !errors.Is(err, database.ErrUnsupportedDriver) {
+			t.Errorf("%q: expected ErrUnsupportedDriver, got %v", driver, err)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var errDatabaseDown = errors.New("connection refused")
+
+// fakeDriver fails to connect until it has been attempted failures times
+type fakeDriver struct {
+	mu        sync.Mutex
+	failures  int
+	attempts  int
+	connected bool
+	closes    int
+}
+
+func (d *fakeDriver) Connect(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.attempts++
+	if d.attempts <= d.failures {
+		return errDatabaseDown
+	}
+	d.connected = true
+	return nil
+}
+
+func (d *fakeDriver) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closes++
+	d.connected = false
+	return nil
+}
+
+func (d *fakeDriver) Ping(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.connected {
+		return errDatabaseDown
+	}
+	return nil
+}
+
+func (d *fakeDriver) Health(ctx context.Context) error { return d.Ping(ctx) }
+func (d *fakeDriver) GetDB() interface{}               { return nil }
+func (d *fakeDriver) GetSQLDB() *sql.DB                { return nil }
+func (d *fakeDriver) GetGormDB() *gorm.DB              { return nil }
+func (d *fakeDriver) Type() database.DriverType        { return database.DriverPostgreSQL }
+
+// Capabilities reports nothing, like a driver without SQL or GORM
+func (d *fakeDriver) Capabilities() database.DriverCapabilities {
+	return database.DriverCapabilities{}
+}
+
+func (d *fakeDriver) setConnected(connected bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.connected = connected
+}
+
+func TestConnectDriverPolicies(t *testing.T) {
+	cases := []struct {
+		name       string
+		required   bool
+		failures   int
+		wantErr    bool
+		registered bool
+		status     string
+	}{
+		{"required up", true, 0, false, true, database.StatusReady},
+		{"required down", true, 1000, true, false, database.StatusReady},
+		{"optional up", false, 0, false, true, database.StatusReady},
+		{"optional down", false, 1000, false, false, database.StatusDegraded},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := database.NewManager()
+			defer manager.CloseAll()
+
+			driver := &fakeDriver{failures: tc.failures}
+			err := manager.ConnectDriver(context.Background(), "analytics", driver, database.ConnectPolicy{
+				Required:      tc.required,
+				RetryInterval: time.Hour,
+			})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error=%v, got %v", tc.wantErr, err)
+			}
+
+			if _, err := manager.GetDriver("analytics"); (err == nil) != tc.registered {
+				t.Errorf("expected registered=%v, got GetDriver error %v", tc.registered, err)
+			}
+			if tc.failures > 0 && driver.closes == 0 {
+				t.Error("expected the failed connection to be closed")
+			}
+
+			status, databases := manager.Readiness(context.Background())
+			if status != tc.status {
+				t.Errorf("expected readiness %q, got %q (%+v)", tc.status, status, databases)
+			}
+		})
+	}
+}
+
+func TestReadinessRequiredDownIsNotReady(t *testing.T) {
+	manager := database.NewManager()
+	defer manager.CloseAll()
+
+	primary := &fakeDriver{}
+	optional := &fakeDriver{failures: 1000}
+	if err := manager.ConnectDriver(context.Background(), "primary", primary, database.ConnectPolicy{Required: true}); err != nil {
+		t.Fatalf("connect primary: %v", err)
+	}
+	if err := manager.ConnectDriver(context.Background(), "analytics", optional, database.ConnectPolicy{RetryInterval: time.Hour}); err != nil {
+		t.Fatalf("connect optional: %v", err)
+	}
+
+	status, databases := manager.Readiness(context.Background())
+	if status != database.StatusDegraded {
+		t.Errorf("expected degraded with the optional database down, got %q", status)
+	}
+	if db := databases["analytics"]; db.Status != "down" || db.Required || db.Error == "" {
+		t.Errorf("unexpected optional database health %+v", db)
+	}
+
+	// A required database going down after startup outweighs a degraded optional one
+	primary.setConnected(false)
+	status, databases = manager.Readiness(context.Background())
+	if status != database.StatusNotReady {
+		t.Errorf("expected not ready with the primary down, got %q", status)
+	}
+	if db := databases["primary"]; db.Status != "down" || !db.Required {
+		t.Errorf("unexpected primary health %+v", db)
+	}
+}
+
+func TestOptionalDatabaseConnectsInBackground(t *testing.T) {
+	manager := database.NewManager()
+	defer manager.CloseAll()
+
+	connected := make(chan struct{})
+	var retries []error
+	driver := &fakeDriver{failures: 2}
+	err := manager.ConnectDriver(context.Background(), "analytics", driver, database.ConnectPolicy{
+		RetryInterval: 10 * time.Millisecond,
+		OnRetry: func(name string, err error) {
+			retries = append(retries, err)
+			if err == nil {
+				close(connected)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	select {
+	case <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("optional database was never retried")
+	}
+
+	if len(retries) != 2 || retries[0] == nil || retries[1] != nil {
+		t.Errorf("expected one failed and one successful retry, got %v", retries)
+	}
+	if _, err := manager.GetDriver("analytics"); err != nil {
+		t.Errorf("expected driver registered after reconnecting: %v", err)
+	}
+	if status, _ := manager.Readiness(context.Background()); status != database.StatusReady {
+		t.Errorf("expected ready after reconnecting, got %q", status)
+	}
+}
+
+func TestCloseAllStopsRetries(t *testing.T) {
+	manager := database.NewManager()
+
+	driver := &fakeDriver{failures: 1000}
+	if err := manager.ConnectDriver(context.Background(), "analytics", driver, database.ConnectPolicy{RetryInterval: 5 * time.Millisecond}); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if err := manager.CloseAll(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	driver.mu.Lock()
+	attempts := driver.attempts
+	driver.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+	driver.mu.Lock()
+	defer driver.mu.Unlock()
+	if driver.attempts != attempts {
+		t.Errorf("retries continued after CloseAll: %d -> %d attempts", attempts, driver.attempts)
+	}
+}
@@ -0,0 +1,383 @@
+package tests
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+)
+
+// newDatabaseRegistrationApp serves /admin/databases
+func newDatabaseRegistrationApp(t *testing.T) *apptest.TestApp {
+	return apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Database.RuntimeRegistration = true
+		cfg.Database.RegistrationTimeout = 2 * time.Second
+	})
+}
+
+// sqliteDatabase is the body registering a SQLite file database
+func sqliteDatabase(t *testing.T, name string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":     name,
+		"driver":   string(database.DriverSQLite),
+		"dbname":   filepath.Join(t.TempDir(), name+".db"),
+		"password": "s3cret-password",
+		"use_gorm": true,
+	}
+}
+
+// openSQLiteDriver connects a SQLite file database through the factory
+func openSQLiteDriver(t *testing.T, name string) database.Driver {
+	t.Helper()
+
+	driver, err := database.NewFactory().CreateFromConnectionConfig(database.ConnectionConfig{
+		Driver:       database.DriverSQLite,
+		DBName:       filepath.Join(t.TempDir(), name+".db"),
+		MaxOpenConns: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return driver
+}
+
+// TestDatabaseRegistration tests registering, listing and removing a
+// database through the admin API
+func TestDatabaseRegistration(t *testing.T) {
+	ta := newDatabaseRegistrationApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/admin/databases", sqliteDatabase(t, "reports"), admin.Token)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var created struct {
+		Data services.DatabaseInfo `json:"data"`
+	}
+	resp.Decode(t, &created)
+	if created.Data.Name != "reports" || created.Data.Driver != string(database.DriverSQLite) || created.Data.Status != "up" || created.Data.Required {
+		t.Errorf("unexpected registration %+v", created.Data)
+	}
+	if strings.Contains(string(resp.Body), "s3cret-password") {
+		t.Errorf("the password was echoed back: %s", resp.Body)
+	}
+
+	if _, err := ta.App.GetDBManager().GetDriver("reports"); err != nil {
+		t.Fatalf("expected the database registered: %v", err)
+	}
+	checked := false
+	for _, check := range ta.App.GetDBManager().HealthChecks() {
+		checked = checked || (check.Name() == "reports" && check.Check(context.Background()) == nil && !check.Critical())
+	}
+	if !checked {
+		t.Error("expected an informational readiness check of the new database")
+	}
+
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/databases", nil, admin.Token)
+	var list struct {
+		Data []services.DatabaseInfo `json:"data"`
+	}
+	resp.Decode(t, &list)
+	if len(list.Data) != 2 || list.Data[0].Name != "primary" || list.Data[1].Name != "reports" || list.Data[1].Status != "up" {
+		t.Errorf("expected primary and reports, got %+v", list.Data)
+	}
+	if strings.Contains(string(resp.Body), "s3cret-password") {
+		t.Errorf("the password was listed: %s", resp.Body)
+	}
+
+	resp = ta.Request(http.MethodDelete, "/api/v1/admin/databases/reports", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("remove: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	if _, err := ta.App.GetDBManager().GetDriver("reports"); !stderrors.Is(err, database.ErrDriverNotFound) {
+		t.Errorf("expected the database removed, got %v", err)
+	}
+	expectErrorCode(t, ta.Request(http.MethodDelete, "/api/v1/admin/databases/reports", nil, admin.Token), http.StatusNotFound, errors.CodeDatabaseNotFound)
+
+	// The name is free again
+	if resp := ta.Request(http.MethodPost, "/api/v1/admin/databases", sqliteDatabase(t, "reports"), admin.Token); resp.StatusCode != http.StatusCreated {
+		t.Errorf("register again: expected 201, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	for action, want := range map[string]int64{models.AuditActionDatabaseRegistered: 2, models.AuditActionDatabaseRemoved: 1} {
+		if got := auditCount(t, ta, action, admin, "reports"); got != want {
+			t.Errorf("%s: expected %d audit entries, got %d", action, want, got)
+		}
+	}
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/admin/databases"},
+		{http.MethodPost, "/api/v1/admin/databases"},
+		{http.MethodDelete, "/api/v1/admin/databases/reports"},
+	} {
+		if resp := ta.Request(req.method, req.path, sqliteDatabase(t, "other"), user.Token); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("user %s %s: expected 403, got %d", req.method, req.path, resp.StatusCode)
+		}
+	}
+}
+
+// TestDatabaseRegistrationErrors tests what cannot be registered or removed
+func TestDatabaseRegistrationErrors(t *testing.T) {
+	ta := newDatabaseRegistrationApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	unreachable := map[string]interface{}{
+		"name":     "warehouse",
+		"driver":   string(database.DriverPostgreSQL),
+		"host":     "127.0.0.1",
+		"port":     "1",
+		"user":     "reporter",
+		"password": "s3cret-password",
+		"dbname":   "warehouse",
+	}
+	resp := ta.Request(http.MethodPost, "/api/v1/admin/databases", unreachable, admin.Token)
+	expectErrorCode(t, resp, http.StatusBadGateway, errors.CodeDatabaseConnectionFailed)
+	if strings.Contains(string(resp.Body), "s3cret-password") {
+		t.Errorf("the password was echoed back: %s", resp.Body)
+	}
+	if _, err := ta.App.GetDBManager().GetDriver("warehouse"); err == nil {
+		t.Error("expected nothing registered for an unreachable database")
+	}
+
+	invalid := map[string]map[string]interface{}{
+		"name":   {"name": "Reports!", "driver": "sqlite", "dbname": ":memory:"},
+		"driver": {"name": "reports", "driver": "oracle", "dbname": "reports"},
+		"dbname": {"name": "reports", "driver": "sqlite"},
+	}
+	for field, body := range invalid {
+		t.Run(field, func(t *testing.T) {
+			expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/admin/databases", body, admin.Token), http.StatusBadRequest, errors.CodeInvalidDatabase)
+		})
+	}
+
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/admin/databases", sqliteDatabase(t, "primary"), admin.Token), http.StatusConflict, errors.CodeDatabaseExists)
+	expectErrorCode(t, ta.Request(http.MethodDelete, "/api/v1/admin/databases/primary", nil, admin.Token), http.StatusConflict, errors.CodeDatabaseInUse)
+	if _, err := ta.App.GetDBManager().GetDriver("primary"); err != nil {
+		t.Errorf("expected the primary database kept: %v", err)
+	}
+}
+
+// TestDatabaseRegistrationDisabled tests that the endpoints are not served
+// unless DB_RUNTIME_REGISTRATION is set
+func TestDatabaseRegistrationDisabled(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	if resp := ta.Request(http.MethodPost, "/api/v1/admin/databases", sqliteDatabase(t, "reports"), admin.Token); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
+	}
+	if _, err := ta.App.GetDBManager().GetDriver("reports"); err == nil {
+		t.Error("expected nothing registered")
+	}
+}
+
+// TestDatabaseRegistrationRace tests that one of several concurrent
+// registrations under a name wins and the others leave nothing behind
+func TestDatabaseRegistrationRace(t *testing.T) {
+	ta := newDatabaseRegistrationApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	const attempts = 8
+	statuses := make(chan int, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- ta.Request(http.MethodPost, "/api/v1/admin/databases", sqliteDatabase(t, "reports"), admin.Token).StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := make(map[int]int)
+	for status := range statuses {
+		counts[status]++
+	}
+	if counts[http.StatusCreated] != 1 || counts[http.StatusConflict] != attempts-1 {
+		t.Errorf("expected one 201 and %d 409, got %v", attempts-1, counts)
+	}
+	if got := auditCount(t, ta, models.AuditActionDatabaseRegistered, admin, "reports"); got != 1 {
+		t.Errorf("expected one audited registration, got %d", got)
+	}
+}
+
+// TestRemoveDriverInFlight tests that removing a database lets a query in
+// progress finish while new lookups fail at once
+func TestRemoveDriverInFlight(t *testing.T) {
+	manager := database.NewManager()
+	defer manager.CloseAll()
+
+	driver := openSQLiteDriver(t, "reports")
+	if err := manager.AddDriver("reports", driver); err != nil {
+		t.Fatal(err)
+	}
+
+	held, err := manager.GetDriver("reports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := held.GetSQLDB().QueryContext(context.Background(),
+		"WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000) SELECT i FROM n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.RemoveDriver("reports"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, err := manager.GetDriver("reports"); !stderrors.Is(err, database.ErrDriverNotFound) {
+		t.Errorf("expected ErrDriverNotFound after removal, got %v", err)
+	}
+
+	// The query started before the removal reads to the end
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	if err := rows.Err(); err != nil || count != 1000 {
+		t.Errorf("expected 1000 rows, got %d, %v", count, err)
+	}
+	rows.Close()
+
+	// Statements started after it fail
+	if _, err := held.GetSQLDB().ExecContext(context.Background(), "SELECT 1"); err == nil {
+		t.Error("expected the removed database closed")
+	}
+}
+
+// TestRemoveDriverUnderLoad tests registering and removing a database while
+// requests keep looking it up and querying it
+func TestRemoveDriverUnderLoad(t *testing.T) {
+	manager := database.NewManager()
+	defer manager.CloseAll()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var unexpected []error
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				driver, err := manager.GetDriver("reports")
+				if err == nil {
+					_, err = driver.GetSQLDB().ExecContext(ctx, "SELECT 1")
+				}
+				if err != nil && !stderrors.Is(err, database.ErrDriverNotFound) && !isClosedError(err) && ctx.Err() == nil {
+					mu.Lock()
+					unexpected = append(unexpected, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		if err := manager.ConnectDriver(context.Background(), "reports", openSQLiteDriver(t, "reports"), database.ConnectPolicy{Required: true}); err != nil {
+			t.Fatalf("register %d: %v", i, err)
+		}
+		time.Sleep(time.Millisecond)
+		if err := manager.RemoveDriver("reports"); err != nil {
+			t.Fatalf("remove %d: %v", i, err)
+		}
+	}
+	cancel()
+	wg.Wait()
+
+	if len(unexpected) > 0 {
+		t.Errorf("unexpected errors while the database came and went: %v", unexpected[0])
+	}
+}
+
+// isClosedError reports whether err comes from a database closed under a request
+func isClosedError(err error) bool {
+	return strings.Contains(err.Error(), "database is closed")
+}
+
+// TestRemoveDriverRules tests which databases can be removed
+func TestRemoveDriverRules(t *testing.T) {
+	manager := database.NewManager()
+	defer manager.CloseAll()
+
+	for _, name := range []string{database.PrimaryDriver, "acme_db"} {
+		if err := manager.AddDriver(name, &fakeDriver{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := manager.RegisterTenant("acme", "acme_db"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{database.PrimaryDriver, "acme_db"} {
+		if err := manager.RemoveDriver(name); !stderrors.Is(err, database.ErrDriverInUse) {
+			t.Errorf("%s: expected ErrDriverInUse, got %v", name, err)
+		}
+	}
+	if err := manager.RemoveDriver("missing"); !stderrors.Is(err, database.ErrDriverNotFound) {
+		t.Errorf("expected ErrDriverNotFound, got %v", err)
+	}
+
+	// Removing a database still retrying stops the retries
+	driver := &fakeDriver{failures: 1000}
+	if err := manager.ConnectDriver(context.Background(), "analytics", driver, database.ConnectPolicy{RetryInterval: 5 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := manager.RemoveDriver("analytics"); err != nil {
+		t.Fatalf("remove retrying database: %v", err)
+	}
+	if _, databases := manager.Readiness(context.Background()); len(databases) != 2 {
+		t.Errorf("expected the removed database gone from readiness, got %v", databases)
+	}
+	time.Sleep(10 * time.Millisecond)
+	driver.mu.Lock()
+	attempts := driver.attempts
+	driver.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	driver.mu.Lock()
+	defer driver.mu.Unlock()
+	if driver.attempts != attempts {
+		t.Errorf("retries continued after removal: %d -> %d attempts", attempts, driver.attempts)
+	}
+}
+
+// TestConnectDriverNoRetry tests that NoRetry reports an optional
+// database's failure instead of retrying it
+func TestConnectDriverNoRetry(t *testing.T) {
+	manager := database.NewManager()
+	defer manager.CloseAll()
+
+	driver := &fakeDriver{failures: 1}
+	err := manager.ConnectDriver(context.Background(), "analytics", driver, database.ConnectPolicy{NoRetry: true, RetryInterval: time.Millisecond})
+	if !stderrors.Is(err, errDatabaseDown) {
+		t.Fatalf("expected the connection error, got %v", err)
+	}
+	if _, databases := manager.Readiness(context.Background()); len(databases) != 0 {
+		t.Errorf("expected nothing registered, got %v", databases)
+	}
+
+	// The name is free for another attempt
+	if err := manager.ConnectDriver(context.Background(), "analytics", driver, database.ConnectPolicy{NoRetry: true}); err != nil {
+		t.Fatal(err)
+	}
+	if required := manager.Required("analytics"); required {
+		t.Error("expected the database optional")
+	}
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/services"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+)
+
+// withQueryRetries runs operations up to three times without a real backoff
+func withQueryRetries(cfg *config.Config) {
+	cfg.Database.QueryRetryAttempts = 3
+	cfg.Database.QueryRetryBaseDelay = time.Millisecond
+	cfg.Database.QueryRetryMaxDelay = time.Millisecond
+}
+
+// injectFailures makes the next n statements of kind, "query" or "create",
+// on table fail with err. It returns a count of the statements attempted.
+func injectFailures(t *testing.T, ta *apptest.TestApp, kind, table string, n int, err error) *atomic.Int32 {
+	t.Helper()
+
+	drv, dbErr := ta.App.GetDBManager().GetDriver(database.PrimaryDriver)
+	if dbErr != nil {
+		t.Fatal(dbErr)
+	}
+	db := database.NativeGorm(drv)
+
+	var attempts atomic.Int32
+	remaining := int32(n)
+	inject := func(tx *gorm.DB) {
+		if tx.Statement.Table != table {
+			return
+		}
+		attempts.Add(1)
+		if atomic.AddInt32(&remaining, -1) >= 0 {
+			tx.AddError(err)
+		}
+	}
+	name := fmt.Sprintf("test:inject_%s_%s", kind, table)
+	switch kind {
+	case "query":
+		dbErr = db.Callback().Query().Before("gorm:query").Register(name, inject)
+	case "create":
+		dbErr = db.Callback().Create().Before("gorm:create").Register(name, inject)
+	}
+	if dbErr != nil {
+		t.Fatal(dbErr)
+	}
+	return &attempts
+}
+
+// TestTransientReason tests which driver errors are worth a retry
+func TestTransientReason(t *testing.T) {
+	tests := []struct {
+		err    error
+		reason string
+	}{
+		{&pq.Error{Code: "40001"}, database.RetrySerialization},
+		{fmt.Errorf("query: %w", &pq.Error{Code: "40P01"}), database.RetryDeadlock},
+		{&pq.Error{Code: "57P03", Message: "the database system is starting up"}, database.RetryStartingUp},
+		{&pq.Error{Code: "08006"}, database.RetryConnection},
+		{&pq.Error{Code: "23505"}, ""},
+		{&mysql.MySQLError{Number: 1213}, database.RetryDeadlock},
+		{&mysql.MySQLError{Number: 1062}, ""},
+		{fmt.Errorf("query: %w", driver.ErrBadConn), database.RetryConnection},
+		{database.ErrCircuitOpen, ""},
+		{context.DeadlineExceeded, ""},
+		{gorm.ErrRecordNotFound, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		reason, ok := database.TransientReason(tt.err)
+		if reason != tt.reason || ok != (tt.reason != "") {
+			t.Errorf("%v: expected %q, got %q %v", tt.err, tt.reason, reason, ok)
+		}
+	}
+}
+
+// TestQueryRetries tests that reads are retried after transient errors and
+// that the retries are counted
+func TestQueryRetries(t *testing.T) {
+	ta := apptest.NewTestApp(t, withQueryRetries)
+	user := ta.CreateUser(models.RoleUser)
+	retries := ta.App.GetMetrics().QueryRetries.WithLabelValues(database.RetryDeadlock)
+
+	attempts := injectFailures(t, ta, "query", "notifications", 2, &pq.Error{Code: "40P01"})
+	resp := ta.Request(http.MethodGet, "/api/v1/me/notifications", nil, user.Token)
+	if resp.StatusCode != http.StatusOK || attempts.Load() < 3 {
+		t.Fatalf("expected the retries to absorb two deadlocks, got %d after %d statements: %s", resp.StatusCode, attempts.Load(), resp.Body)
+	}
+	if got := testutil.ToFloat64(retries); got != 2 {
+		t.Errorf("expected two retries counted, got %v", got)
+	}
+}
+
+// TestQueryRetriesExhausted tests that the last error is returned once the
+// attempts run out, and that other errors are never retried
+func TestQueryRetriesExhausted(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	manager := ta.App.GetDBManager()
+	manager.SetRetryPolicy(database.RetryPolicy{MaxAttempts: 3})
+	repo := services.NewWebhookRepository(manager)
+
+	attempts := injectFailures(t, ta, "query", "webhooks", 3, &mysql.MySQLError{Number: 1213})
+	_, err := repo.List(context.Background())
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || attempts.Load() != 3 {
+		t.Fatalf("expected the deadlock after three attempts, got %v after %d", err, attempts.Load())
+	}
+	if _, err := repo.Get(context.Background(), uuid.New()); !errors.Is(err, services.ErrWebhookNotFound) || attempts.Load() != 4 {
+		t.Errorf("expected not found at once, got %v after %d", err, attempts.Load())
+	}
+
+	// A query the database rejected is not retried
+	attempts = injectFailures(t, ta, "query", "tasks", 5, &pq.Error{Code: "42P01"})
+	if _, err := services.NewTaskRepository(manager).Get(context.Background(), uuid.New()); err == nil || attempts.Load() != 1 {
+		t.Errorf("expected one attempt, got %v after %d", err, attempts.Load())
+	}
+}
+
+// TestQueryRetriesNonIdempotent tests that inserts are never retried
+func TestQueryRetriesNonIdempotent(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	manager := ta.App.GetDBManager()
+	manager.SetRetryPolicy(database.RetryPolicy{MaxAttempts: 3})
+	user := ta.CreateUser(models.RoleUser)
+
+	attempts := injectFailures(t, ta, "create", "sessions", 1, driver.ErrBadConn)
+	session := &models.Session{ID: uuid.New(), UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := services.NewSessionRepository(manager).Create(context.Background(), session); err == nil || attempts.Load() != 1 {
+		t.Errorf("expected the insert to fail once, got %v after %d", err, attempts.Load())
+	}
+}
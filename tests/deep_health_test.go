@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/health"
+)
+
+// connectSQLite registers the SQLite database at dsn as the manager's primary
+func connectSQLite(t *testing.T, dsn string) *database.Manager {
+	t.Helper()
+
+	manager := database.NewManager()
+	t.Cleanup(func() { manager.CloseAll() })
+	driver := database.NewSQLiteDriver(&database.SQLiteConfig{Path: dsn, UseGorm: true})
+	if err := manager.ConnectDriver(context.Background(), database.PrimaryDriver, driver, database.ConnectPolicy{Required: true}); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	return manager
+}
+
+// TestDeepHealthCheckReadOnly tests that the write probe flags a database
+// that answers pings but refuses writes, only after repeated failures
+func TestDeepHealthCheckReadOnly(t *testing.T) {
+	cfg := newCLIConfig(t)
+	if res := runCLI(t, cfg, "", "migrate", "up"); res.code != 0 {
+		t.Fatalf("migrate: %s", res.stderr)
+	}
+
+	manager := connectSQLite(t, "file:"+cfg.Database.Primary.DBName+"?mode=ro")
+	registry := health.NewRegistry(0)
+	registry.RegisterSource(manager.HealthChecks)
+	registry.Register(manager.WriteProbe(database.PrimaryDriver, database.WriteProbeConfig{InstanceID: "node-1", Failures: 3}))
+	ctx := context.Background()
+
+	for i := 1; i < 3; i++ {
+		report := registry.Run(ctx)
+		if report.Status != health.StatusReady {
+			t.Fatalf("run %d: expected ready below the threshold, got %+v", i, report)
+		}
+		if r := report.Checks["primary:write"]; r.Details["consecutive_failures"] != strconv.Itoa(i) || r.Details["last_error"] == "" {
+			t.Errorf("run %d: unexpected details %+v", i, r.Details)
+		}
+	}
+
+	report := registry.Run(ctx)
+	if report.Status != health.StatusDegraded {
+		t.Fatalf("expected degraded after three failed writes, got %+v", report)
+	}
+	if r := report.Checks["primary"]; r.Status != health.StatusUp {
+		t.Errorf("expected the ping to succeed, got %+v", r)
+	}
+	if r := report.Checks["primary:write"]; r.Status != health.StatusDown || r.Critical || r.Error == "" || r.Details["last_write"] != "" {
+		t.Errorf("unexpected write result %+v", r)
+	}
+}
+
+// TestDeepHealthCheckWrites tests that the probe keeps one row per instance
+func TestDeepHealthCheckWrites(t *testing.T) {
+	cfg := newCLIConfig(t)
+	if res := runCLI(t, cfg, "", "migrate", "up"); res.code != 0 {
+		t.Fatalf("migrate: %s", res.stderr)
+	}
+
+	manager := connectSQLite(t, cfg.Database.Primary.DBName)
+	probe := manager.WriteProbe(database.PrimaryDriver, database.WriteProbeConfig{InstanceID: "node-1", Failures: 1})
+	for range 2 {
+		if err := probe.Check(context.Background()); err != nil {
+			t.Fatalf("check: %v", err)
+		}
+	}
+	if details := probe.(health.Detailer).Details(); details["last_write"] == "" || details["consecutive_failures"] != "0" {
+		t.Errorf("unexpected details %+v", details)
+	}
+
+	var rows []models.HealthCheck
+	if err := openCLIDatabase(t, cfg).Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].InstanceID != "node-1" || rows[0].CheckedAt.IsZero() {
+		t.Errorf("expected one row for the instance, got %+v", rows)
+	}
+}
@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// loginResult is the body of a login response
+type loginResult struct {
+	Token         string `json:"token"`
+	TrustedDevice bool   `json:"trusted_device"`
+	DeviceID      string `json:"device_id"`
+}
+
+// login logs in with the given extra fields, failing on anything but 200
+func login(t *testing.T, ta *apptest.TestApp, user *apptest.User, fields map[string]interface{}) loginResult {
+	t.Helper()
+	body := map[string]interface{}{"email": user.Email, "password": user.Password}
+	for key, value := range fields {
+		body[key] = value
+	}
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/login", body, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var result loginResult
+	resp.Decode(t, &result)
+	return result
+}
+
+// deviceList is the body of a trusted device listing
+type deviceList struct {
+	Data []struct {
+		ID      string `json:"id"`
+		Current bool   `json:"current"`
+	} `json:"data"`
+}
+
+// listDevices lists the trusted devices of token's user
+func listDevices(t *testing.T, ta *apptest.TestApp, token string) deviceList {
+	t.Helper()
+	resp := ta.Request(http.MethodGet, "/api/v1/me/devices", nil, token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("list devices: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var body deviceList
+	resp.Decode(t, &body)
+	return body
+}
+
+// TestRememberMeTrustsDevice tests that only logins presenting the
+// identifier of a device the user trusted are reported as trusted
+func TestRememberMeTrustsDevice(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+	other := ta.CreateUser(models.RoleUser)
+
+	remembered := login(t, ta, user, map[string]interface{}{"remember_me": true})
+	if remembered.TrustedDevice || len(remembered.DeviceID) != 64 {
+		t.Fatalf("expected a new device with a generated identifier, got %+v", remembered)
+	}
+
+	// Remembered sessions last JWT_REMEMBER_EXPIRATION instead of JWT_EXPIRATION
+	list := listSessions(t, ta, "/api/v1/me/sessions", remembered.Token)
+	for _, session := range list.Data {
+		if session.Current && time.Until(session.ExpiresAt) < 2*time.Hour {
+			t.Errorf("expected the remembered session to outlast JWT_EXPIRATION, expires at %v", session.ExpiresAt)
+		}
+	}
+
+	again := login(t, ta, user, map[string]interface{}{"device_id": remembered.DeviceID})
+	if !again.TrustedDevice || again.DeviceID != "" {
+		t.Errorf("expected the remembered device to be trusted, got %+v", again)
+	}
+
+	cases := []struct {
+		name   string
+		user   *apptest.User
+		fields map[string]interface{}
+	}{
+		{"no device", user, nil},
+		{"unknown device", user, map[string]interface{}{"device_id": uuid.NewString()}},
+		{"another user's device", other, map[string]interface{}{"device_id": remembered.DeviceID}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := login(t, ta, tc.user, tc.fields); result.TrustedDevice {
+				t.Errorf("expected an untrusted login, got %+v", result)
+			}
+		})
+	}
+
+	// A client-supplied identifier is kept and not echoed back
+	supplied := uuid.NewString()
+	first := login(t, ta, other, map[string]interface{}{"remember_me": true, "device_id": supplied})
+	if first.TrustedDevice || first.DeviceID != supplied {
+		t.Errorf("expected the supplied identifier to be trusted, got %+v", first)
+	}
+	if second := login(t, ta, other, map[string]interface{}{"device_id": supplied}); !second.TrustedDevice {
+		t.Errorf("expected the supplied identifier to be recognized, got %+v", second)
+	}
+
+	// Restricted logins never count as trusted
+	ta.DB().Model(&models.User{}).Where("id = ?", user.ID).Update("must_change_password", true)
+	restricted := login(t, ta, user, map[string]interface{}{"device_id": remembered.DeviceID, "remember_me": true})
+	if restricted.TrustedDevice || restricted.DeviceID != "" {
+		t.Errorf("expected a restricted login to skip devices, got %+v", restricted)
+	}
+}
+
+// TestRevokeDevice tests that revoking a trusted device ends its sessions,
+// stops its tokens from refreshing and forgets its identifier
+func TestRevokeDevice(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+	remembered := login(t, ta, user, map[string]interface{}{"remember_me": true})
+
+	devices := listDevices(t, ta, remembered.Token)
+	if len(devices.Data) != 1 || !devices.Data[0].Current {
+		t.Fatalf("expected the remembered device marked current, got %+v", devices.Data)
+	}
+	if others := listDevices(t, ta, user.Token); others.Data[0].Current {
+		t.Error("expected the device not to be current for a login without it")
+	}
+
+	// Only the owner can revoke a device
+	stranger := ta.CreateUser(models.RoleUser)
+	resp := ta.Request(http.MethodDelete, "/api/v1/me/devices/"+devices.Data[0].ID, nil, stranger.Token)
+	expectErrorCode(t, resp, http.StatusNotFound, errors.CodeDeviceNotFound)
+
+	resp = ta.Request(http.MethodDelete, "/api/v1/me/devices/"+devices.Data[0].ID, nil, user.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	expectRevoked(t, ta, remembered.Token)
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": remembered.Token}, "")
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("refresh: expected 401 for a revoked device, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	// Sessions without the device carry on
+	if resp := ta.Request(http.MethodGet, "/api/v1/me/features", nil, user.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the other session to keep working, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	if len(listDevices(t, ta, user.Token).Data) != 0 {
+		t.Error("expected the device to be forgotten")
+	}
+	if result := login(t, ta, user, map[string]interface{}{"device_id": remembered.DeviceID}); result.TrustedDevice {
+		t.Error("expected the revoked device to be untrusted")
+	}
+
+	resp = ta.Request(http.MethodDelete, "/api/v1/me/devices/"+devices.Data[0].ID, nil, user.Token)
+	expectErrorCode(t, resp, http.StatusNotFound, errors.CodeDeviceNotFound)
+}
+
+// TestRememberedRefresh tests that refreshing a remembered token keeps its
+// device and lifetime
+func TestRememberedRefresh(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+	remembered := login(t, ta, user, map[string]interface{}{"remember_me": true})
+
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": remembered.Token}, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("refresh: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var refreshed struct {
+		Token string `json:"token"`
+	}
+	resp.Decode(t, &refreshed)
+
+	list := listSessions(t, ta, "/api/v1/me/sessions", refreshed.Token)
+	for _, session := range list.Data {
+		if session.Current && time.Until(session.ExpiresAt) < 2*time.Hour {
+			t.Errorf("expected the refresh to keep the remembered lifetime, expires at %v", session.ExpiresAt)
+		}
+	}
+	if devices := listDevices(t, ta, refreshed.Token); len(devices.Data) != 1 || !devices.Data[0].Current {
+		t.Errorf("expected the refreshed token on the remembered device, got %+v", devices.Data)
+	}
+}
+
+// TestTrustedDevicePurge tests that the cleanup forgets devices unused
+// since before the cutoff
+func TestTrustedDevicePurge(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+	svc := services.NewTrustedDeviceService(
+		services.NewTrustedDeviceRepository(ta.App.GetDBManager()),
+		nil,
+		services.NewAuditService(ta.App.GetDBManager(), logger.NewSimpleLogger()),
+		logger.NewSimpleLogger(),
+	)
+
+	now := time.Now()
+	for _, lastUsed := range []time.Time{now.Add(-100 * 24 * time.Hour), now.Add(-time.Hour)} {
+		device := &models.TrustedDevice{
+			ID:         uuid.New(),
+			UserID:     user.ID,
+			TokenHash:  uuid.NewString(),
+			CreatedAt:  lastUsed,
+			LastUsedAt: lastUsed,
+		}
+		if err := ta.DB().Create(device).Error; err != nil {
+			t.Fatalf("create device: %v", err)
+		}
+	}
+
+	purged, err := svc.PurgeUnusedBefore(context.Background(), now.Add(-90*24*time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("expected one purged device, got %d (err %v)", purged, err)
+	}
+	if devices, err := svc.List(context.Background(), user.ID.String(), ""); err != nil || len(devices) != 1 {
+		t.Errorf("expected the recently used device to remain, got %d (err %v)", len(devices), err)
+	}
+}
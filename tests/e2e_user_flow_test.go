@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+)
+
+type userEnvelope struct {
+	Data models.User `json:"data"`
+}
+
+// TestE2EUserLifecycle walks a user through register, login, lookup, update and delete
+func TestE2EUserLifecycle(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	// Register
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"email":      "jane@example.com",
+		"password":   "correct-horse",
+		"first_name": "Jane",
+		"last_name":  "Doe",
+		"username":   "jane",
+	}, "")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register: %d %s", resp.StatusCode, resp.Body)
+	}
+	var registered struct {
+		User models.User `json:"user"`
+	}
+	resp.Decode(t, &registered)
+	if registered.User.Email != "jane@example.com" || registered.User.Role != models.RoleUser {
+		t.Fatalf("unexpected registered user %+v", registered.User)
+	}
+	userPath := "/api/v1/users/" + registered.User.ID.String()
+
+	// Login
+	if resp := ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{
+		"email": "jane@example.com", "password": "wrong-password",
+	}, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("login with a wrong password: expected 401, got %d", resp.StatusCode)
+	}
+	token := ta.Login("jane@example.com", "correct-horse")
+
+	// Get me
+	resp = ta.Request(http.MethodGet, userPath, nil, token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("get me: %d %s", resp.StatusCode, resp.Body)
+	}
+	var me userEnvelope
+	resp.Decode(t, &me)
+	if me.Data.Username != "jane" || me.Data.Password != "" {
+		t.Errorf("unexpected user %+v", me.Data)
+	}
+
+	// Update needs users.update, which plain users lack
+	update := map[string]string{"first_name": "Janet"}
+	if resp := ta.Request(http.MethodPut, userPath, update, token); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("update as user: expected 403, got %d", resp.StatusCode)
+	}
+	resp = ta.Request(http.MethodPut, userPath, update, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update: %d %s", resp.StatusCode, resp.Body)
+	}
+	resp = ta.Request(http.MethodGet, userPath, nil, token)
+	resp.Decode(t, &me)
+	if me.Data.FirstName != "Janet" {
+		t.Errorf("expected updated first name, got %q", me.Data.FirstName)
+	}
+
+	// Delete
+	if resp := ta.Request(http.MethodDelete, userPath, nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete: %d %s", resp.StatusCode, resp.Body)
+	}
+	if resp := ta.Request(http.MethodGet, userPath, nil, admin.Token); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("get after delete: expected 404, got %d", resp.StatusCode)
+	}
+
+	// The deleted user's token stops working
+	if resp := ta.Request(http.MethodGet, userPath, nil, token); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("deleted user's token: expected 401, got %d", resp.StatusCode)
+	}
+}
+
+// TestE2ERequiresAuthentication tests that protected routes reject anonymous requests
+func TestE2ERequiresAuthentication(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+
+	if resp := ta.Request(http.MethodGet, "/api/v1/users", nil, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", resp.StatusCode)
+	}
+	if resp := ta.Request(http.MethodGet, "/api/v1/users", nil, "not-a-jwt"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 with a bad token, got %d", resp.StatusCode)
+	}
+	if resp := ta.Request(http.MethodGet, "/ready", nil, ""); resp.StatusCode != http.StatusServiceUnavailable {
+		// Start is never called, so the application never reports ready
+		t.Errorf("expected 503 from /ready before Start, got %d", resp.StatusCode)
+	}
+	if !ta.Logs.Contains("Primary database connected") {
+		t.Error("expected startup to be logged")
+	}
+}
@@ -0,0 +1,203 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	apperrors "BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+)
+
+var emailChangeToken = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// requestEmailChange asks to change the email of the token's user and returns the response
+func requestEmailChange(ta *apptest.TestApp, token, password, newEmail string) *apptest.Response {
+	return ta.Request(http.MethodPost, "/api/v1/me/email-change", map[string]string{"password": password, "new_email": newEmail}, token)
+}
+
+// confirmEmailChange sends a confirmation token and returns the response
+func confirmEmailChange(ta *apptest.TestApp, token string) *apptest.Response {
+	return ta.Request(http.MethodPost, "/api/v1/auth/confirm-email-change", map[string]string{"token": token}, "")
+}
+
+// mailedToken returns the confirmation token of the last message sent to address
+func mailedToken(t *testing.T, ta *apptest.TestApp, address string) string {
+	t.Helper()
+	messages := ta.Mail.Messages(address)
+	if len(messages) == 0 {
+		t.Fatalf("no mail sent to %s", address)
+	}
+	token := emailChangeToken.FindString(messages[len(messages)-1].Body)
+	if token == "" {
+		t.Fatalf("no token in %q", messages[len(messages)-1].Body)
+	}
+	return token
+}
+
+// TestEmailChangeFlow tests requesting and confirming an email change
+func TestEmailChangeFlow(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+	other := ta.CreateUser(models.RoleUser)
+	newEmail := "renamed-" + user.Email
+
+	expectErrorCode(t, requestEmailChange(ta, user.Token, "wrong-password", newEmail), http.StatusBadRequest, apperrors.CodeInvalidCurrentPassword)
+	expectErrorCode(t, requestEmailChange(ta, user.Token, user.Password, other.Email), http.StatusConflict, apperrors.CodeEmailAlreadyExists)
+	expectErrorCode(t, requestEmailChange(ta, user.Token, user.Password, user.Email), http.StatusBadRequest, apperrors.CodeEmailUnchanged)
+	if len(ta.Mail.Messages(other.Email)) != 0 {
+		t.Fatal("expected no mail for refused requests")
+	}
+
+	resp := requestEmailChange(ta, user.Token, user.Password, newEmail)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("request: expected 202, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	token := mailedToken(t, ta, newEmail)
+	if notices := ta.Mail.Messages(user.Email); len(notices) != 1 || emailChangeToken.MatchString(notices[0].Body) {
+		t.Errorf("expected a notice without the token at the old address, got %+v", notices)
+	}
+
+	// Nothing changes until the new address confirms
+	second := ta.Login(user.Email, user.Password)
+
+	resp = confirmEmailChange(ta, token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("confirm: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var confirmed struct {
+		Data models.User `json:"data"`
+	}
+	resp.Decode(t, &confirmed)
+	if confirmed.Data.Email != newEmail {
+		t.Errorf("expected the new email, got %q", confirmed.Data.Email)
+	}
+
+	// Every token and session of the user ends with the change
+	expectRevoked(t, ta, user.Token)
+	expectRevoked(t, ta, second)
+	if resp := ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": user.Email, "password": user.Password}, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the old email to stop logging in, got %d", resp.StatusCode)
+	}
+
+	// Revocations cover tokens issued up to the second they were made in
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	fresh := ta.Login(newEmail, user.Password)
+
+	resp = ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String(), nil, fresh)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("get user: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var fetched struct {
+		Data models.User `json:"data"`
+	}
+	resp.Decode(t, &fetched)
+	if fetched.Data.Email != newEmail {
+		t.Errorf("expected the cached user to have the new email, got %q", fetched.Data.Email)
+	}
+
+	var audited int64
+	ta.DB().Model(&models.AuditLog{}).Where("action = ? AND entity_id = ?", models.AuditActionUserEmailChanged, user.ID.String()).Count(&audited)
+	if audited != 1 {
+		t.Errorf("expected the change to be audited once, got %d", audited)
+	}
+
+	// A token works once
+	expectErrorCode(t, confirmEmailChange(ta, token), http.StatusBadRequest, apperrors.CodeEmailChangeTokenInvalid)
+}
+
+// TestEmailChangeSupersedes tests that a newer request invalidates the pending one
+func TestEmailChangeSupersedes(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+
+	requestEmailChange(ta, user.Token, user.Password, "first-"+user.Email)
+	first := mailedToken(t, ta, "first-"+user.Email)
+	requestEmailChange(ta, user.Token, user.Password, "second-"+user.Email)
+	second := mailedToken(t, ta, "second-"+user.Email)
+
+	expectErrorCode(t, confirmEmailChange(ta, first), http.StatusBadRequest, apperrors.CodeEmailChangeTokenInvalid)
+	if resp := confirmEmailChange(ta, second); resp.StatusCode != http.StatusOK {
+		t.Fatalf("confirm: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	ta.Login("second-"+user.Email, user.Password)
+}
+
+// TestEmailChangeExpires tests that tokens stop working after AUTH_EMAIL_CHANGE_EXPIRATION
+func TestEmailChangeExpires(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.EmailChangeExpiration = time.Millisecond
+	})
+	user := ta.CreateUser(models.RoleUser)
+
+	requestEmailChange(ta, user.Token, user.Password, "late-"+user.Email)
+	token := mailedToken(t, ta, "late-"+user.Email)
+	time.Sleep(5 * time.Millisecond)
+
+	expectErrorCode(t, confirmEmailChange(ta, token), http.StatusBadRequest, apperrors.CodeEmailChangeTokenExpired)
+	ta.Login(user.Email, user.Password)
+}
+
+// TestEmailChangeRace tests that an address registered between the request
+// and the confirmation is not taken over
+func TestEmailChangeRace(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+	contested := "contested-" + user.Email
+
+	requestEmailChange(ta, user.Token, user.Password, contested)
+	token := mailedToken(t, ta, contested)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"email": contested, "password": "secret123", "first_name": "Other", "last_name": "User", "username": "other",
+	}, "")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	expectErrorCode(t, confirmEmailChange(ta, token), http.StatusConflict, apperrors.CodeEmailAlreadyExists)
+
+	var owners int64
+	ta.DB().Model(&models.User{}).Where("email = ?", contested).Count(&owners)
+	if owners != 1 {
+		t.Errorf("expected the address to keep one owner, got %d", owners)
+	}
+	if resp := ta.Request(http.MethodGet, "/api/v1/me/features", nil, user.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the failed change to leave the user's tokens alone, got %d", resp.StatusCode)
+	}
+	ta.Login(user.Email, user.Password)
+}
+
+// TestEmailChangeRestrictions tests impersonators, the generic update and
+// servers without email
+func TestEmailChangeRestrictions(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	resp := ta.Request(http.MethodPut, "/api/v1/users/"+user.ID.String(), map[string]string{"email": "x-" + user.Email}, admin.Token)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected the generic update to refuse the email, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	if resp := requestEmailChange(ta, impersonate(t, ta, admin, user), user.Password, "x-"+user.Email); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected impersonators to be refused, got %d", resp.StatusCode)
+	}
+
+	svc := services.NewEmailChangeService(
+		services.NewEmailChangeRepository(ta.App.GetDBManager()), nil, cache.NewMemoryStore(),
+		services.NewTokenRevoker(cache.NewMemoryStore(), time.Hour), nil, nil, time.Hour, logger.NewSimpleLogger(),
+	)
+	claims := &services.TokenClaims{UserID: user.ID.String()}
+	if _, err := svc.Request(context.Background(), claims, user.Password, "x-"+user.Email); !errors.Is(err, services.ErrEmailUnavailable) {
+		t.Errorf("expected ErrEmailUnavailable without a mailer, got %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/cli"
+	"BackofficeGoService/internal/database/migrations"
+	"BackofficeGoService/internal/pkg/emailaddr"
+	"BackofficeGoService/internal/pkg/errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// registerEmail registers a user with email and returns the response
+func registerEmail(ta *apptest.TestApp, email string) *apptest.Response {
+	return ta.Request(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"email": email, "password": "secret123", "first_name": "Mixed", "last_name": "Case", "username": "user-" + uuid.NewString()[:8],
+	}, "")
+}
+
+// storedEmails returns the email addresses stored in db, sorted
+func storedEmails(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var emails []string
+	if err := db.Model(&models.User{}).Order("email").Pluck("email", &emails).Error; err != nil {
+		t.Fatalf("load emails: %v", err)
+	}
+	return emails
+}
+
+// TestEmailNormalize tests the normalization rules
+func TestEmailNormalize(t *testing.T) {
+	tests := []struct {
+		n    emailaddr.Normalization
+		in   string
+		want string
+	}{
+		{emailaddr.Normalization{}, " User@Example.COM ", "user@example.com"},
+		{emailaddr.Normalization{}, "John.Smith+news@gmail.com", "john.smith+news@gmail.com"},
+		{emailaddr.Normalization{PreserveLocalCase: true}, "User@Example.COM", "User@example.com"},
+		{emailaddr.Normalization{Gmail: true}, "John.Smith+news@GoogleMail.com", "johnsmith@gmail.com"},
+		{emailaddr.Normalization{Gmail: true, PreserveLocalCase: true}, "John.Smith@gmail.com", "johnsmith@gmail.com"},
+		{emailaddr.Normalization{Gmail: true}, "John.Smith+news@example.com", "john.smith+news@example.com"},
+		{emailaddr.Normalization{}, " NoDomain ", "nodomain"},
+	}
+	for _, tt := range tests {
+		if got := tt.n.Normalize(tt.in); got != tt.want {
+			t.Errorf("%+v.Normalize(%q) = %q, want %q", tt.n, tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestEmailNormalizedRegistration tests that an address registered in one
+// case logs in and conflicts in any other
+func TestEmailNormalizedRegistration(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	resp := registerEmail(ta, "Mixed.Case@Example.COM")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var owners int64
+	ta.DB().Model(&models.User{}).Where("email = ?", "mixed.case@example.com").Count(&owners)
+	if owners != 1 {
+		t.Fatalf("expected the address stored normalized, got %d matches", owners)
+	}
+
+	loginWithPassword(t, ta, "MIXED.case@example.com", "secret123")
+
+	expectErrorCode(t, registerEmail(ta, "mixed.CASE@example.com"), http.StatusConflict, errors.CodeEmailAlreadyExists)
+	resp = ta.Request(http.MethodPost, "/api/v1/users", map[string]string{
+		"email": "Mixed.Case@example.com", "password": "secret123", "first_name": "Other", "last_name": "User", "username": "other", "role": string(models.RoleUser),
+	}, admin.Token)
+	expectErrorCode(t, resp, http.StatusConflict, errors.CodeEmailAlreadyExists)
+
+	user := ta.CreateUser(models.RoleUser)
+	expectErrorCode(t, requestEmailChange(ta, user.Token, user.Password, "MIXED.CASE@EXAMPLE.COM"), http.StatusConflict, errors.CodeEmailAlreadyExists)
+}
+
+// TestEmailNormalizationOptions tests keeping the case of the local part and
+// folding Gmail addresses
+func TestEmailNormalizationOptions(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.EmailPreserveLocalCase = true
+		cfg.Auth.EmailGmailNormalization = true
+	})
+
+	if resp := registerEmail(ta, "Mixed@Example.COM"); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	loginWithPassword(t, ta, "Mixed@example.com", "secret123")
+	// The local part now tells accounts apart
+	if resp := registerEmail(ta, "mixed@example.com"); resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected another local case to register, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	if resp := registerEmail(ta, "John.Smith+news@GoogleMail.com"); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	expectErrorCode(t, registerEmail(ta, "johnsmith@gmail.com"), http.StatusConflict, errors.CodeEmailAlreadyExists)
+	loginWithPassword(t, ta, "j.o.h.n.smith@gmail.com", "secret123")
+
+	want := []string{"Mixed@example.com", "johnsmith@gmail.com", "mixed@example.com"}
+	if got := storedEmails(t, ta.DB()); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v stored, got %v", want, got)
+	}
+}
+
+// TestEmailDuplicates tests that the unique index migration refuses
+// conflicting addresses, that the duplicates command reports them and that
+// the addresses are normalized once they are resolved
+func TestEmailDuplicates(t *testing.T) {
+	cfg := newCLIConfig(t)
+	if res := runCLI(t, cfg, "", "migrate", "up"); res.code != cli.ExitOK {
+		t.Fatalf("migrate: %s", res.stderr)
+	}
+	// Roll back to before the unique index, whatever came after it
+	steps := 0
+	for i, migration := range migrations.All() {
+		if migration.ID == "0021_add_users_email_unique" {
+			steps = len(migrations.All()) - i
+		}
+	}
+	if res := runCLI(t, cfg, "", "migrate", "down", "--steps", strconv.Itoa(steps)); res.code != cli.ExitOK {
+		t.Fatalf("migrate down: %s", res.stderr)
+	}
+
+	db := openCLIDatabase(t, cfg)
+	first := &models.User{ID: uuid.New(), Email: "dup@example.com", Username: "first", Password: "x", Role: models.RoleUser, Active: true}
+	second := &models.User{ID: uuid.New(), Email: " Dup@Example.com", Username: "second", Password: "x", Role: models.RoleUser, Active: true}
+	third := &models.User{ID: uuid.New(), Email: "Solo@Example.com", Username: "third", Password: "x", Role: models.RoleUser, Active: true}
+	for _, user := range []*models.User{first, second, third} {
+		// Columns added after the index are rolled back too
+		if err := db.Omit("APIQuota", "PartnerID", "ExternalID", "TenantID").Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	res := runCLI(t, cfg, "", "migrate", "up")
+	if res.code != cli.ExitError || !strings.Contains(res.stderr, "user duplicates") {
+		t.Fatalf("expected the migration refused, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+
+	res = runCLI(t, cfg, "", "user", "duplicates")
+	if res.code != cli.ExitError {
+		t.Fatalf("expected duplicates to fail, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+	for _, want := range []string{"dup@example.com", first.ID.String(), second.ID.String()} {
+		if !strings.Contains(res.stdout, want) {
+			t.Errorf("expected %q in the report, got %s", want, res.stdout)
+		}
+	}
+	if strings.Contains(res.stdout, third.ID.String()) {
+		t.Errorf("expected only conflicting users reported, got %s", res.stdout)
+	}
+	if res = runCLI(t, cfg, "", "user", "normalize-emails"); res.code != cli.ExitError {
+		t.Errorf("expected normalize-emails to refuse duplicates, got %d: %s", res.code, res.stdout)
+	}
+
+	db.Delete(&models.User{}, "id = ?", second.ID)
+	if res = runCLI(t, cfg, "", "user", "duplicates"); res.code != cli.ExitOK || !strings.Contains(res.stdout, "No duplicate") {
+		t.Fatalf("expected no duplicates left, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+	if res = runCLI(t, cfg, "", "migrate", "up"); res.code != cli.ExitOK {
+		t.Fatalf("migrate: %s", res.stderr)
+	}
+	want := []string{"dup@example.com", "solo@example.com"}
+	if got := storedEmails(t, db); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v stored, got %v", want, got)
+	}
+
+	// The index now refuses a second owner of an address
+	if err := db.Create(&models.User{ID: uuid.New(), Email: "solo@example.com", Username: "fourth", Password: "x", Role: models.RoleUser}).Error; err == nil {
+		t.Error("expected the unique index to refuse a second owner")
+	}
+	if res = runCLI(t, cfg, "", "user", "normalize-emails"); res.code != cli.ExitOK || !strings.Contains(res.stdout, "Normalized 0") {
+		t.Errorf("expected nothing left to normalize, got %d: %s%s", res.code, res.stdout, res.stderr)
+	}
+}
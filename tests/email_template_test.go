@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/controllers/admin"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/infrastructure/email"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TestEmailTemplatesRender tests that every built-in template renders in
+// each of its formats with its sample data
+func TestEmailTemplatesRender(t *testing.T) {
+	templates := services.NewEmailTemplates()
+	infos := templates.Templates()
+	if len(infos) == 0 {
+		t.Fatal("expected built-in templates")
+	}
+	for _, info := range infos {
+		for _, format := range info.Formats {
+			message, err := templates.Render(info.Name, format, info.Sample)
+			if err != nil {
+				t.Errorf("%s as %s: %v", info.Name, format, err)
+				continue
+			}
+			if message.Subject == "" || message.Body == "" {
+				t.Errorf("%s as %s rendered empty: %+v", info.Name, format, message)
+			}
+		}
+	}
+}
+
+// emailRouter serves the email routes of a controller over templates,
+// sending through the log-only driver, as an authenticated admin
+func emailRouter(templates *email.Registry, logs *logger.CaptureLogger) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	controller := admin.NewEmailController(services.NewEmailTemplateService(templates, email.NewLogClient(logs), nil, logs))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.ClaimsKey, &services.TokenClaims{UserID: uuid.NewString(), Role: string(models.RoleAdmin)})
+	})
+	router.GET("/emails/templates", controller.ListTemplates)
+	router.GET("/emails/templates/:name/preview", controller.PreviewTemplate)
+	router.POST("/emails/templates/:name/test-send", controller.TestSendTemplate)
+	return router
+}
+
+// TestEmailTemplateHandlers tests previewing and test-sending templates
+// through the log-only email driver
+func TestEmailTemplateHandlers(t *testing.T) {
+	templates := email.NewRegistry()
+	templates.MustRegister(email.Template{
+		Name:    "invitation",
+		Subject: "Join {{.Team}}",
+		Text:    "{{.Inviter}} invited you to {{.Team}}.",
+		HTML:    "<p>{{.Inviter}} invited you to <b>{{.Team}}</b>.</p>",
+		Sample:  map[string]string{"Inviter": "Ann <ann@example.com>", "Team": "Support"},
+	})
+	templates.MustRegister(email.Template{
+		Name:    "broken",
+		Subject: "Hello",
+		Text:    "Hello {{.Missing}}",
+		Sample:  map[string]string{"Name": "Ann"},
+	})
+	logs := logger.NewCaptureLogger()
+	router := emailRouter(templates, logs)
+	serve := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder, v interface{}) {
+		t.Helper()
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("decode %s: %v", w.Body, err)
+		}
+	}
+
+	var list struct {
+		Data []email.TemplateInfo `json:"data"`
+	}
+	w := serve(http.MethodGet, "/emails/templates", nil)
+	decode(w, &list)
+	if w.Code != http.StatusOK || len(list.Data) != 2 || list.Data[0].Name != "broken" || len(list.Data[1].Formats) != 2 || list.Data[1].Sample == nil {
+		t.Errorf("unexpected list %d %s", w.Code, w.Body)
+	}
+
+	var preview struct {
+		Data email.Message `json:"data"`
+	}
+	w = serve(http.MethodGet, "/emails/templates/invitation/preview?format=html", nil)
+	decode(w, &preview)
+	if w.Code != http.StatusOK || preview.Data.Subject != "Join Support" ||
+		preview.Data.Body != "<p>Ann &lt;ann@example.com&gt; invited you to <b>Support</b>.</p>" {
+		t.Errorf("unexpected html preview %d %s", w.Code, w.Body)
+	}
+	w = serve(http.MethodGet, "/emails/templates/invitation/preview", nil)
+	decode(w, &preview)
+	if w.Code != http.StatusOK || preview.Data.Format != email.FormatText || preview.Data.Body != "Ann <ann@example.com> invited you to Support." {
+		t.Errorf("unexpected text preview %d %s", w.Code, w.Body)
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		status int
+		code   errors.Code
+	}{
+		{"render error", http.MethodGet, "/emails/templates/broken/preview", nil, http.StatusUnprocessableEntity, errors.CodeEmailTemplateRenderFailed},
+		{"render error on send", http.MethodPost, "/emails/templates/broken/test-send", map[string]string{"to": "ops@example.com"}, http.StatusUnprocessableEntity, errors.CodeEmailTemplateRenderFailed},
+		{"unknown template", http.MethodGet, "/emails/templates/welcome/preview", nil, http.StatusNotFound, errors.CodeEmailTemplateNotFound},
+		{"no html body", http.MethodGet, "/emails/templates/broken/preview?format=html", nil, http.StatusBadRequest, errors.CodeEmailFormatUnsupported},
+		{"unknown format", http.MethodGet, "/emails/templates/invitation/preview?format=pdf", nil, http.StatusBadRequest, errors.CodeEmailFormatUnsupported},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serve(tc.method, tc.path, tc.body)
+			var body errorEnvelope
+			decode(w, &body)
+			if w.Code != tc.status || body.Error.Code != string(tc.code) {
+				t.Errorf("expected %d %s, got %d %s", tc.status, tc.code, w.Code, w.Body)
+			}
+		})
+	}
+
+	// The template error names the missing key
+	w = serve(http.MethodGet, "/emails/templates/broken/preview", nil)
+	if !strings.Contains(w.Body.String(), "Missing") {
+		t.Errorf("expected the template error in %s", w.Body)
+	}
+
+	if w := serve(http.MethodPost, "/emails/templates/invitation/test-send", map[string]string{"to": "not-an-email"}); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for an invalid recipient, got %d", w.Code)
+	}
+	w = serve(http.MethodPost, "/emails/templates/invitation/test-send", map[string]string{"to": "ops@example.com"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("test send: %d %s", w.Code, w.Body)
+	}
+	var sent []logger.Entry
+	for _, entry := range logs.Entries() {
+		if to, _ := entry.Field("to"); to == "ops@example.com" {
+			sent = append(sent, entry)
+		}
+	}
+	if len(sent) != 1 {
+		t.Fatalf("expected one logged email, got %d", len(sent))
+	}
+	if subject, _ := sent[0].Field("subject"); subject != "[Test] Join Support" {
+		t.Errorf("unexpected subject %v", subject)
+	}
+	if body, _ := sent[0].Field("body"); body != "Ann <ann@example.com> invited you to Support." {
+		t.Errorf("unexpected body %v", body)
+	}
+}
+
+// TestEmailTemplateRoutes tests that the email routes are admin-only and
+// audited, and that test sends reach the email client
+func TestEmailTemplateRoutes(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	operator := ta.CreateUser(models.RoleAdmin)
+	member := ta.CreateUser(models.RoleUser)
+
+	for _, path := range []string{"/api/v1/admin/emails/templates", "/api/v1/admin/emails/templates/email_change_notice/preview"} {
+		if resp := ta.Request(http.MethodGet, path, nil, member.Token); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected 403 for a user on %s, got %d", path, resp.StatusCode)
+		}
+	}
+	resp := ta.Request(http.MethodPost, "/api/v1/admin/emails/templates/email_change_notice/test-send", map[string]string{"to": member.Email}, member.Token)
+	if resp.StatusCode != http.StatusForbidden || len(ta.Mail.Messages(member.Email)) != 0 {
+		t.Errorf("expected 403 and nothing sent for a user, got %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Data []email.TemplateInfo `json:"data"`
+	}
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/emails/templates", nil, operator.Token)
+	resp.Decode(t, &list)
+	if resp.StatusCode != http.StatusOK || len(list.Data) != 3 {
+		t.Errorf("unexpected templates %d %s", resp.StatusCode, resp.Body)
+	}
+
+	var preview struct {
+		Data email.Message `json:"data"`
+	}
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/emails/templates/email_change_confirm/preview?format=html", nil, operator.Token)
+	resp.Decode(t, &preview)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(preview.Data.Body, "<code>") || !strings.Contains(preview.Data.Body, "Sat, 01 Jun 2024 12:00:00 UTC") {
+		t.Errorf("unexpected preview %d %s", resp.StatusCode, resp.Body)
+	}
+	if n := auditCount(t, ta, models.AuditActionEmailPreviewed, operator, services.EmailTemplateEmailChangeConfirm); n != 1 {
+		t.Errorf("expected the preview audited once, got %d", n)
+	}
+
+	resp = ta.Request(http.MethodPost, "/api/v1/admin/emails/templates/email_change_notice/test-send", map[string]string{"to": operator.Email}, operator.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("test send: %d %s", resp.StatusCode, resp.Body)
+	}
+	messages := ta.Mail.Messages(operator.Email)
+	if len(messages) != 1 || messages[0].Subject != "[Test] Your email address is being changed" || !strings.Contains(messages[0].Body, "new.address@example.com") {
+		t.Errorf("unexpected messages %+v", messages)
+	}
+	if n := auditCount(t, ta, models.AuditActionEmailTestSent, operator, services.EmailTemplateEmailChangeNotice); n != 1 {
+		t.Errorf("expected the test send audited once, got %d", n)
+	}
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TestRegisterRejectsDuplicates tests that a code cannot be registered twice
+func TestRegisterRejectsDuplicates(t *testing.T) {
+	for _, code := range []string{string(errors.CodeUserNotFound), ""} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected Register(%q) to panic", code)
+				}
+			}()
+			errors.Register(code, "duplicate")
+		}()
+	}
+}
+
+// TestListErrorCodes tests that the endpoint lists the whole catalogue
+func TestListErrorCodes(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+
+	resp := ta.Request(http.MethodGet, "/api/v1/error-codes", nil, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Data []errors.CodeInfo `json:"data"`
+	}
+	resp.Decode(t, &body)
+
+	want := errors.Codes()
+	if len(body.Data) != len(want) {
+		t.Fatalf("expected %d codes, got %d", len(want), len(body.Data))
+	}
+	for i, info := range body.Data {
+		if info != want[i] || info.Description == "" {
+			t.Errorf("unexpected code %+v, want %+v", info, want[i])
+		}
+	}
+}
+
+// TestTokenExpiredCode tests that expired tokens are told apart from invalid ones
+func TestTokenExpiredCode(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+
+	now := time.Now()
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID.String(),
+		"email":   user.Email,
+		"role":    string(user.Role),
+		"exp":     now.Add(-time.Minute).Unix(),
+		"iat":     now.Add(-time.Hour).Unix(),
+		"iss":     ta.Config.JWT.Issuer,
+	}).SignedString([]byte(ta.Config.JWT.Secret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		token string
+		want  errors.Code
+	}{
+		{"expired", expired, errors.CodeTokenExpired},
+		{"malformed", "not-a-token", errors.CodeTokenInvalid},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, envelope := localizedRequest(t, ta, http.MethodGet, "/api/v1/users", "", nil, tc.token)
+			if resp.StatusCode != http.StatusUnauthorized || envelope.Error.Code != string(tc.want) {
+				t.Errorf("expected 401 %s, got %d %+v", tc.want, resp.StatusCode, envelope.Error)
+			}
+		})
+	}
+}
+
+// TestConvertedHandlersSetCodes tests that every client error raised by the
+// converted handlers carries a specific code rather than the generic one for
+// its status. Internal errors keep INTERNAL_ERROR.
+func TestConvertedHandlersSetCodes(t *testing.T) {
+	files := []string{
+		"../internal/app/controllers/auth/auth_controller.go",
+		"../internal/app/controllers/user/user_controller.go",
+		"../internal/app/middleware/auth.go",
+		"../internal/app/middleware/permission.go",
+	}
+
+	for _, path := range files {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+
+		// Constructor calls that are the receiver of a WithCode call
+		coded := map[*ast.CallExpr]bool{}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "WithCode" {
+					if inner, ok := sel.X.(*ast.CallExpr); ok {
+						coded[inner] = true
+					}
+				}
+			}
+			return true
+		})
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			pkg, ok := sel.X.(*ast.Ident)
+			if !ok || pkg.Name != "errors" || !strings.HasPrefix(sel.Sel.Name, "New") || !strings.HasSuffix(sel.Sel.Name, "Error") {
+				return true
+			}
+			if sel.Sel.Name != "NewInternalServerError" && !coded[call] {
+				t.Errorf("%s: errors.%s without WithCode", fset.Position(call.Pos()), sel.Sel.Name)
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/export"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// newExportApp returns an app exporting two users per query, so exports
+// page across batches, with three users created a day apart
+func newExportApp(t *testing.T) (*apptest.TestApp, *apptest.User, []*apptest.User) {
+	t.Helper()
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Tasks.ExportBatchSize = 2
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	base := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	users := make([]*apptest.User, 3)
+	for i := range users {
+		users[i] = ta.CreateUser(models.RoleUser)
+		updates := map[string]interface{}{"created_at": base.AddDate(0, 0, i), "active": i != 1}
+		if err := ta.DB().Model(&models.User{}).Where("id = ?", users[i].ID).Updates(updates).Error; err != nil {
+			t.Fatalf("update user: %v", err)
+		}
+	}
+	// The admin sorts last by creation time
+	if err := ta.DB().Model(&models.User{}).Where("id = ?", admin.ID).Update("created_at", base.AddDate(0, 0, 10)).Error; err != nil {
+		t.Fatalf("update admin: %v", err)
+	}
+	return ta, admin, users
+}
+
+// TestUserExportXLSX tests that the XLSX export has the selected columns in
+// order, typed cells and a frozen header row
+func TestUserExportXLSX(t *testing.T) {
+	ta, admin, users := newExportApp(t)
+
+	resp := ta.Request(http.MethodGet, "/api/v1/users/export?format=xlsx&columns=email,created_at,active&sort=-created_at", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	if got := resp.Header.Get("Content-Type"); got != export.FormatXLSX.ContentType() {
+		t.Errorf("unexpected content type %q", got)
+	}
+	if got := resp.Header.Get("Content-Disposition"); !strings.Contains(got, `filename="users.xlsx"`) {
+		t.Errorf("unexpected content disposition %q", got)
+	}
+
+	file, err := excelize.OpenReader(bytes.NewReader(resp.Body))
+	if err != nil {
+		t.Fatalf("open workbook: %v", err)
+	}
+	defer file.Close()
+
+	rows, err := file.GetRows(export.XLSXSheet)
+	if err != nil {
+		t.Fatalf("read rows: %v", err)
+	}
+	if len(rows) != 5 || strings.Join(rows[0], ",") != "email,created_at,active" {
+		t.Fatalf("expected the header and four users, got %v", rows)
+	}
+	// Newest first, read across several batches
+	for i, want := range []string{admin.Email, users[2].Email, users[1].Email, users[0].Email} {
+		if rows[i+1][0] != want {
+			t.Errorf("row %d: expected %s, got %s", i+1, want, rows[i+1][0])
+		}
+	}
+
+	cellType := func(cell string) excelize.CellType {
+		t.Helper()
+		typ, err := file.GetCellType(export.XLSXSheet, cell)
+		if err != nil {
+			t.Fatalf("cell type %s: %v", cell, err)
+		}
+		return typ
+	}
+	if typ := cellType("A2"); typ != excelize.CellTypeInlineString && typ != excelize.CellTypeSharedString {
+		t.Errorf("expected a string email cell, got %v", typ)
+	}
+	if typ := cellType("C3"); typ != excelize.CellTypeBool {
+		t.Errorf("expected a boolean active cell, got %v", typ)
+	}
+	if rows[3][2] != "FALSE" || rows[2][2] != "TRUE" {
+		t.Errorf("expected the inactive user's flag to be false, got %v and %v", rows[2], rows[3])
+	}
+
+	// created_at is a number formatted as a date, not text
+	if typ := cellType("B3"); typ != excelize.CellTypeNumber && typ != excelize.CellTypeUnset {
+		t.Errorf("expected a numeric date cell, got %v", typ)
+	}
+	styleID, err := file.GetCellStyle(export.XLSXSheet, "B3")
+	if err != nil {
+		t.Fatalf("cell style: %v", err)
+	}
+	style, err := file.GetStyle(styleID)
+	if err != nil {
+		t.Fatalf("style: %v", err)
+	}
+	if style.CustomNumFmt == nil || *style.CustomNumFmt != "yyyy-mm-dd hh:mm:ss" {
+		t.Errorf("expected a date number format, got %+v", style)
+	}
+	if rows[2][1] != "2024-03-03 09:30:00" {
+		t.Errorf("expected the formatted creation time, got %q", rows[2][1])
+	}
+
+	panes, err := file.GetPanes(export.XLSXSheet)
+	if err != nil {
+		t.Fatalf("panes: %v", err)
+	}
+	if !panes.Freeze || panes.YSplit != 1 || panes.TopLeftCell != "A2" {
+		t.Errorf("expected the header row frozen, got %+v", panes)
+	}
+}
+
+// TestUserExportCSVOptions tests that CSV exports share the column, filter
+// and sort parsing
+func TestUserExportCSVOptions(t *testing.T) {
+	ta, admin, users := newExportApp(t)
+
+	resp := ta.Request(http.MethodGet, "/api/v1/users/export?columns=id,%20email&active=true&sort=created_at", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+		t.Fatalf("expected a CSV, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	records, err := csv.NewReader(bytes.NewReader(resp.Body)).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	want := [][]string{
+		{"id", "email"},
+		{users[0].ID.String(), users[0].Email},
+		{users[2].ID.String(), users[2].Email},
+		{admin.ID.String(), admin.Email},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("expected %v, got %v", want, records)
+	}
+	for i := range want {
+		if strings.Join(records[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("row %d: expected %v, got %v", i, want[i], records[i])
+		}
+	}
+}
+
+// TestUserExportRejectsInvalidOptions tests that format, columns and sort
+// are checked against what the export supports
+func TestUserExportRejectsInvalidOptions(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	for query, code := range map[string]errors.Code{
+		"format=pdf":              errors.CodeInvalidExportFormat,
+		"columns=email,password":  errors.CodeInvalidExportColumn,
+		"columns=":                "",
+		"sort=-password":          errors.CodeInvalidExportSort,
+		"active=maybe":            errors.CodeInvalidFilter,
+		"format=XLSX&sort=-email": "",
+	} {
+		resp := ta.Request(http.MethodGet, "/api/v1/users/export?"+query, nil, admin.Token)
+		if code == "" {
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("%s: expected 200, got %d: %s", query, resp.StatusCode, resp.Body)
+			}
+			continue
+		}
+		expectErrorCode(t, resp, http.StatusBadRequest, code)
+	}
+}
@@ -0,0 +1,264 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+	"BackofficeGoService/internal/services/featureflags"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeFlagRepository is an in-memory featureflags.Repository
+type fakeFlagRepository struct {
+	flags map[string]*models.FeatureFlag
+	loads int
+}
+
+func newFakeFlagRepository() *fakeFlagRepository {
+	return &fakeFlagRepository{flags: make(map[string]*models.FeatureFlag)}
+}
+
+func (r *fakeFlagRepository) List(ctx context.Context) ([]*models.FeatureFlag, error) {
+	r.loads++
+	var flags []*models.FeatureFlag
+	for _, flag := range r.flags {
+		copied := *flag
+		flags = append(flags, &copied)
+	}
+	return flags, nil
+}
+
+func (r *fakeFlagRepository) Get(ctx context.Context, key string) (*models.FeatureFlag, error) {
+	flag, ok := r.flags[key]
+	if !ok {
+		return nil, featureflags.ErrFlagNotFound
+	}
+	copied := *flag
+	return &copied, nil
+}
+
+func (r *fakeFlagRepository) Create(ctx context.Context, flag *models.FeatureFlag) error {
+	r.flags[flag.Key] = flag
+	return nil
+}
+
+func (r *fakeFlagRepository) Update(ctx context.Context, flag *models.FeatureFlag) error {
+	r.flags[flag.Key] = flag
+	return nil
+}
+
+func (r *fakeFlagRepository) Delete(ctx context.Context, key string) error {
+	if _, ok := r.flags[key]; !ok {
+		return featureflags.ErrFlagNotFound
+	}
+	delete(r.flags, key)
+	return nil
+}
+
+func newFeatureFlagFixture() (*featureflags.Service, *fakeFlagRepository) {
+	repo := newFakeFlagRepository()
+	return featureflags.NewService(repo, cache.NewMemoryStore(), logger.NewSimpleLogger()), repo
+}
+
+// TestFeatureFlagBucketing tests that rollout buckets are stable and evenly spread
+func TestFeatureFlagBucketing(t *testing.T) {
+	const users = 10000
+
+	counts := make([]int, 10)
+	for i := 0; i < users; i++ {
+		id := fmt.Sprintf("user-%d", i)
+		bucket := featureflags.Bucket("new-dashboard", id)
+		if bucket < 0 || bucket >= 100 {
+			t.Fatalf("bucket %d out of range", bucket)
+		}
+		if again := featureflags.Bucket("new-dashboard", id); again != bucket {
+			t.Fatalf("bucket for %s changed from %d to %d", id, bucket, again)
+		}
+		counts[bucket/10]++
+	}
+
+	// Each decile should hold roughly a tenth of the users
+	for decile, n := range counts {
+		if n < users/10*8/10 || n > users/10*12/10 {
+			t.Errorf("decile %d holds %d users, expected about %d", decile, n, users/10)
+		}
+	}
+}
+
+// TestFeatureFlagRolloutIsMonotonic tests that raising the percentage never turns a user off
+func TestFeatureFlagRolloutIsMonotonic(t *testing.T) {
+	flag := &models.FeatureFlag{Key: "beta-export", Enabled: true}
+
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		user := &featureflags.User{ID: fmt.Sprintf("user-%d", i), Role: string(models.RoleUser)}
+
+		flag.RolloutPercentage = 25
+		atQuarter := featureflags.Evaluate(flag, user)
+		flag.RolloutPercentage = 50
+		atHalf := featureflags.Evaluate(flag, user)
+
+		if atQuarter && !atHalf {
+			t.Fatalf("user %s lost the feature when the rollout grew", user.ID)
+		}
+		if atQuarter {
+			enabled++
+		}
+	}
+
+	if enabled < 200 || enabled > 300 {
+		t.Fatalf("expected about 250 of 1000 users at 25%%, got %d", enabled)
+	}
+}
+
+// TestFeatureFlagTargeting tests role and user targeting
+func TestFeatureFlagTargeting(t *testing.T) {
+	flag := &models.FeatureFlag{
+		Key:          "audit-viewer",
+		Enabled:      true,
+		AllowedRoles: models.StringList{string(models.RoleAdmin)},
+		AllowedUsers: models.StringList{"u-beta"},
+	}
+
+	cases := []struct {
+		name string
+		user *featureflags.User
+		want bool
+	}{
+		{"anonymous", nil, false},
+		{"role outside targeting", &featureflags.User{ID: "u1", Role: string(models.RoleUser)}, false},
+		{"allowed user with other role", &featureflags.User{ID: "u-beta", Role: string(models.RoleGuest)}, true},
+		{"allowed role at 0%", &featureflags.User{ID: "u2", Role: string(models.RoleAdmin)}, false},
+	}
+	for _, tc := range cases {
+		if got := featureflags.Evaluate(flag, tc.user); got != tc.want {
+			t.Errorf("%s: Evaluate = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	flag.RolloutPercentage = 100
+	if !featureflags.Evaluate(flag, &featureflags.User{ID: "u2", Role: string(models.RoleAdmin)}) {
+		t.Error("expected allowed role to be enabled at 100%")
+	}
+
+	flag.Enabled = false
+	if featureflags.Evaluate(flag, &featureflags.User{ID: "u-beta"}) {
+		t.Error("expected disabled flag to be off for allowed users")
+	}
+}
+
+// TestFeatureFlagCacheInvalidation tests that definitions are cached and edits apply immediately
+func TestFeatureFlagCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newFeatureFlagFixture()
+	user := &featureflags.User{ID: "u1", Role: string(models.RoleUser)}
+
+	if _, err := svc.CreateFlag(ctx, &featureflags.CreateFlagRequest{Key: "reports.v2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if svc.IsEnabled(ctx, "reports.v2", user) {
+			t.Fatal("expected flag to be off")
+		}
+	}
+	if repo.loads != 1 {
+		t.Fatalf("expected flags to be loaded once, got %d", repo.loads)
+	}
+
+	enabled, full := true, 100
+	if _, err := svc.UpdateFlag(ctx, "reports.v2", &featureflags.UpdateFlagRequest{Enabled: &enabled, RolloutPercentage: &full}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !svc.IsEnabled(ctx, "reports.v2", user) {
+		t.Fatal("expected update to apply immediately")
+	}
+	if svc.IsEnabled(ctx, "missing", user) {
+		t.Fatal("expected unknown flag to be off")
+	}
+}
+
+// TestFeatureFlagValidation tests key, percentage and duplicate validation
+func TestFeatureFlagValidation(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newFeatureFlagFixture()
+
+	if _, err := svc.CreateFlag(ctx, &featureflags.CreateFlagRequest{Key: "Bad Key"}); !errors.Is(err, featureflags.ErrInvalidFlagKey) {
+		t.Fatalf("expected ErrInvalidFlagKey, got %v", err)
+	}
+	if _, err := svc.CreateFlag(ctx, &featureflags.CreateFlagRequest{Key: "ok", RolloutPercentage: 101}); !errors.Is(err, featureflags.ErrInvalidPercentage) {
+		t.Fatalf("expected ErrInvalidPercentage, got %v", err)
+	}
+	if _, err := svc.CreateFlag(ctx, &featureflags.CreateFlagRequest{Key: "ok"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.CreateFlag(ctx, &featureflags.CreateFlagRequest{Key: "ok"}); !errors.Is(err, featureflags.ErrFlagExists) {
+		t.Fatalf("expected ErrFlagExists, got %v", err)
+	}
+}
+
+// TestRequireFeature tests that guarded routes 404 while the flag is off
+func TestRequireFeature(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newFeatureFlagFixture()
+	if _, err := svc.CreateFlag(ctx, &featureflags.CreateFlagRequest{
+		Key:          "bulk-import",
+		Enabled:      true,
+		AllowedUsers: []string{"u-beta"},
+		Payload:      models.JSON(`{"max_rows":500}`),
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	newRouter := func(userID string) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set(middleware.ClaimsKey, &services.TokenClaims{UserID: userID, Role: string(models.RoleUser)})
+			c.Next()
+		})
+		router.POST("/import", middleware.RequireFeature(svc, "bulk-import"), func(c *gin.Context) {
+			c.Status(http.StatusAccepted)
+		})
+		router.GET("/me/features", func(c *gin.Context) {
+			evaluations, _ := svc.EvaluateAll(c.Request.Context(), middleware.FeatureUser(c))
+			c.JSON(http.StatusOK, gin.H{"data": evaluations})
+		})
+		return router
+	}
+
+	w := httptest.NewRecorder()
+	newRouter("u1").ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/import", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 while flag is off, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	newRouter("u-beta").ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/import", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 for targeted user, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	newRouter("u-beta").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me/features", nil))
+	var body struct {
+		Data map[string]featureflags.Evaluation `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if eval := body.Data["bulk-import"]; !eval.Enabled || string(eval.Payload) != `{"max_rows":500}` {
+		t.Fatalf("expected enabled flag with payload, got %+v", eval)
+	}
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/infrastructure/storage"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/filetoken"
+)
+
+// newFileSigner returns a signer of links under /files on a fake clock
+func newFileSigner(t *testing.T, secret string, opts ...filetoken.Option) (*filetoken.Signer, *clock.Fake) {
+	t.Helper()
+
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	signer, err := filetoken.NewSigner("/files", secret, append([]filetoken.Option{filetoken.WithClock(fake)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, fake
+}
+
+// TestFileTokens tests that tokens carry their claims and are refused once
+// tampered with, expired or signed with another key
+func TestFileTokens(t *testing.T) {
+	signer, fake := newFileSigner(t, "current-secret")
+
+	token, err := signer.Mint("exports/users.csv", "user-1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := signer.Verify(token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if claims.Key != "exports/users.csv" || claims.UserID != "user-1" || !claims.ExpiresAt.Equal(fake.Now().Add(time.Minute)) {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if !claims.Allows("user-1") || claims.Allows("user-2") || claims.Allows("") {
+		t.Error("expected the token to only allow user-1")
+	}
+
+	payload, signature, _ := strings.Cut(token, ".")
+	other, _ := signer.Mint("exports/other.csv", filetoken.Anyone, time.Minute)
+	otherPayload, _, _ := strings.Cut(other, ".")
+	for name, tampered := range map[string]string{
+		"swapped payload":   otherPayload + "." + signature,
+		"changed signature": payload + "." + strings.Repeat("A", len(signature)),
+		"no signature":      payload,
+		"garbage":           "not-a-token",
+	} {
+		if _, err := signer.Verify(tampered); !stderrors.Is(err, filetoken.ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+
+	foreign, _ := newFileSigner(t, "another-secret")
+	if _, err := foreign.Verify(token); !stderrors.Is(err, filetoken.ErrInvalidToken) {
+		t.Errorf("expected a token of another key to be refused, got %v", err)
+	}
+
+	fake.Advance(time.Minute)
+	if _, err := signer.Verify(token); !stderrors.Is(err, filetoken.ErrExpired) {
+		t.Errorf("expected the token to have expired, got %v", err)
+	}
+
+	anyone, _ := signer.Mint("exports/users.csv", filetoken.Anyone, time.Minute)
+	if claims, err := signer.Verify(anyone); err != nil || !claims.Allows("") || !claims.Allows("user-2") {
+		t.Errorf("expected a token for anyone, got %+v %v", claims, err)
+	}
+}
+
+// TestFileTokenRotation tests that tokens of the previous key are accepted
+// until its grace period ends
+func TestFileTokenRotation(t *testing.T) {
+	old, _ := newFileSigner(t, "old-secret")
+	token, err := old.Mint("exports/users.csv", filetoken.Anyone, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	rotated, fake := newFileSigner(t, "new-secret", filetoken.WithPreviousSecret("old-secret", start.Add(time.Hour)))
+	if _, err := rotated.Verify(token); err != nil {
+		t.Errorf("expected the old key to be accepted during the grace period, got %v", err)
+	}
+	fake.Advance(time.Hour)
+	if _, err := rotated.Verify(token); !stderrors.Is(err, filetoken.ErrInvalidToken) {
+		t.Errorf("expected the old key to be refused after the grace period, got %v", err)
+	}
+
+	fresh, _ := rotated.Mint("exports/users.csv", filetoken.Anyone, time.Minute)
+	if _, err := old.Verify(fresh); !stderrors.Is(err, filetoken.ErrInvalidToken) {
+		t.Errorf("expected new tokens to be signed with the new key only, got %v", err)
+	}
+}
+
+// TestFileDownloadLinks tests downloading through links for anyone and for
+// one user, and that expired, tampered and rotated-out links are refused
+func TestFileDownloadLinks(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Storage.URLSecret = "current-url-secret"
+		cfg.Storage.PreviousURLSecret = "previous-url-secret"
+		cfg.Storage.PreviousURLSecretGrace = time.Hour
+	})
+	owner := ta.CreateUser(models.RoleUser)
+	other := ta.CreateUser(models.RoleUser)
+
+	store, err := storage.NewLocalStore(ta.Config.Storage.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, body := range map[string]string{"exports/report.xlsx": "workbook", "avatars/face.png": "png"} {
+		if err := store.Put(context.Background(), key, strings.NewReader(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := func(secret, key, userID string, ttl time.Duration) string {
+		t.Helper()
+		signer, err := filetoken.NewSigner("/api/v1/files", secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		url, err := signer.URL(key, userID, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return url
+	}
+
+	public := ta.Request(http.MethodGet, link("current-url-secret", "avatars/face.png", filetoken.Anyone, time.Minute), nil, "")
+	if public.StatusCode != http.StatusOK || string(public.Body) != "png" || public.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("expected the image without a token, got %d %s %q", public.StatusCode, public.Header.Get("Content-Type"), public.Body)
+	}
+
+	owned := link("current-url-secret", "exports/report.xlsx", owner.ID.String(), time.Minute)
+	resp := ta.Request(http.MethodGet, owned, nil, owner.Token)
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != "workbook" {
+		t.Fatalf("expected the owner to download the file, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet") {
+		t.Errorf("unexpected Content-Type %q", got)
+	}
+	if got := resp.Header.Get("Content-Disposition"); got != "attachment; filename=report.xlsx" {
+		t.Errorf("unexpected Content-Disposition %q", got)
+	}
+	if resp := ta.Request(http.MethodGet, owned+"?access_token="+owner.Token, nil, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the access token to be read from the query, got %d", resp.StatusCode)
+	}
+	expectErrorCode(t, ta.Request(http.MethodGet, owned, nil, other.Token), http.StatusForbidden, errors.CodeDownloadLinkForbidden)
+	expectErrorCode(t, ta.Request(http.MethodGet, owned, nil, ""), http.StatusUnauthorized, errors.CodeTokenRequired)
+
+	expired := link("current-url-secret", "exports/report.xlsx", filetoken.Anyone, -time.Second)
+	expectErrorCode(t, ta.Request(http.MethodGet, expired, nil, ""), http.StatusForbidden, errors.CodeDownloadLinkExpired)
+	tampered := link("current-url-secret", "exports/report.xlsx", filetoken.Anyone, time.Minute) + "A"
+	expectErrorCode(t, ta.Request(http.MethodGet, tampered, nil, ""), http.StatusForbidden, errors.CodeDownloadLinkInvalid)
+	forged := link("guessed-secret", "exports/report.xlsx", filetoken.Anyone, time.Minute)
+	expectErrorCode(t, ta.Request(http.MethodGet, forged, nil, ""), http.StatusForbidden, errors.CodeDownloadLinkInvalid)
+	missing := link("current-url-secret", "exports/gone.csv", filetoken.Anyone, time.Minute)
+	expectErrorCode(t, ta.Request(http.MethodGet, missing, nil, ""), http.StatusNotFound, errors.CodeFileNotFound)
+
+	// Links minted before the rotation still work during the grace period
+	if resp := ta.Request(http.MethodGet, link("previous-url-secret", "avatars/face.png", filetoken.Anyone, time.Minute), nil, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected a link of the previous key to work, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// forceLogout signs user out everywhere as admin, failing on anything but 200
+func forceLogout(t *testing.T, ta *apptest.TestApp, admin, user *apptest.User) {
+	t.Helper()
+	resp := ta.Request(http.MethodPost, "/api/v1/users/"+user.ID.String()+"/force-logout", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("force logout: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+}
+
+// TestForceLogout tests that a forced logout refuses every token issued
+// before it while new logins work straight away
+func TestForceLogout(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+	other := ta.CreateUser(models.RoleUser)
+
+	second := login(t, ta, user, nil).Token
+	if resp := ta.Request(http.MethodGet, "/api/v1/users", nil, second); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 before the forced logout, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	forceLogout(t, ta, admin, user)
+
+	for _, token := range []string{user.Token, second} {
+		resp := ta.Request(http.MethodGet, "/api/v1/users", nil, token)
+		expectErrorCode(t, resp, http.StatusUnauthorized, errors.CodeTokenRevoked)
+		resp = ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": token}, "")
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("refresh: expected 401 for a token issued before the forced logout, got %d: %s", resp.StatusCode, resp.Body)
+		}
+	}
+	var active int64
+	ta.DB().Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&active)
+	if active != 0 {
+		t.Errorf("expected every session ended, %d remain", active)
+	}
+
+	// Logging in again works at once, and so does refreshing the new token
+	fresh := login(t, ta, user, nil).Token
+	if resp := ta.Request(http.MethodGet, "/api/v1/users", nil, fresh); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected a new login to work, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	if resp := ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": fresh}, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the new token to refresh, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	// Other users are unaffected
+	if resp := ta.Request(http.MethodGet, "/api/v1/users", nil, other.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected other users' tokens to work, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	if n := auditCount(t, ta, models.AuditActionUserForcedLogout, admin, user.ID.String()); n != 1 {
+		t.Errorf("expected 1 audited forced logout, got %d", n)
+	}
+
+	resp := ta.Request(http.MethodPost, "/api/v1/users/"+admin.ID.String()+"/force-logout", nil, other.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodeInsufficientPermissions)
+	resp = ta.Request(http.MethodPost, "/api/v1/users/"+uuid.NewString()+"/force-logout", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusNotFound, errors.CodeUserNotFound)
+}
+
+// TestForceLogoutEndsImpersonations tests that signing out either side of
+// an impersonation ends it
+func TestForceLogoutEndsImpersonations(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	impersonator := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	ofUser := impersonate(t, ta, impersonator, user)
+	forceLogout(t, ta, admin, user)
+	expectErrorCode(t, ta.Request(http.MethodGet, "/api/v1/users", nil, ofUser), http.StatusUnauthorized, errors.CodeTokenRevoked)
+
+	byImpersonator := impersonate(t, ta, impersonator, user)
+	forceLogout(t, ta, admin, impersonator)
+	expectErrorCode(t, ta.Request(http.MethodGet, "/api/v1/users", nil, byImpersonator), http.StatusUnauthorized, errors.CodeTokenRevoked)
+}
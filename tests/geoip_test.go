@@ -0,0 +1,286 @@
+package tests
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/geoip"
+	"BackofficeGoService/internal/pkg/httpclient"
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+// The test databases, written by testdata/geoip/gen.go, place
+// 203.0.113.0/24 in Berlin (Hamburg in v2), 198.51.100.0/24 in New York
+// and 192.0.2.0/24 in France without a city
+const (
+	geoIPDatabase   = "testdata/geoip/city-v1.mmdb"
+	geoIPDatabaseV2 = "testdata/geoip/city-v2.mmdb"
+)
+
+// TestMaxMindResolver tests lookups in a City database
+func TestMaxMindResolver(t *testing.T) {
+	db, err := geoip.OpenMaxMind(geoIPDatabase, logger.NewNopLogger())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	ctx := context.Background()
+
+	berlin, err := db.Resolve(ctx, "203.0.113.9")
+	if err != nil || berlin.String() != "Berlin, DE" || berlin.Lat != 52.52 || berlin.Lon != 13.405 {
+		t.Errorf("expected Berlin with coordinates, got %+v, %v", berlin, err)
+	}
+	if loc, _ := db.Resolve(ctx, "192.0.2.3"); loc.String() != "FR" || loc.HasCoordinates() {
+		t.Errorf("expected the country alone, got %+v", loc)
+	}
+	if loc, err := db.Resolve(ctx, "8.8.8.8"); err != nil || loc.Known() {
+		t.Errorf("expected an unlisted address unknown, got %+v, %v", loc, err)
+	}
+	if _, err := db.Resolve(ctx, "not-an-ip"); !stderrors.Is(err, geoip.ErrInvalidIP) {
+		t.Errorf("expected ErrInvalidIP, got %v", err)
+	}
+	if _, err := geoip.OpenMaxMind(filepath.Join(t.TempDir(), "missing.mmdb"), logger.NewNopLogger()); err == nil {
+		t.Error("expected a missing database to fail")
+	}
+}
+
+// TestMaxMindReload tests that a database replaced on disk is picked up,
+// and that an unreadable replacement keeps the last one
+func TestMaxMindReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+	copyFile := func(src string, mtime time.Time) {
+		t.Helper()
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// Stamp the file so the change shows on coarse filesystem clocks
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	copyFile(geoIPDatabase, start)
+
+	logs := logger.NewCaptureLogger()
+	db, err := geoip.OpenMaxMind(path, logs)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	db.Start(10 * time.Millisecond)
+	defer db.Stop(context.Background())
+
+	city := func() string {
+		loc, _ := db.Resolve(context.Background(), "203.0.113.9")
+		return loc.City
+	}
+	if got := city(); got != "Berlin" {
+		t.Fatalf("expected Berlin before the update, got %q", got)
+	}
+
+	copyFile(geoIPDatabaseV2, start.Add(time.Minute))
+	deadline := time.Now().Add(5 * time.Second)
+	for city() != "Hamburg" {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the updated database picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := os.WriteFile(path, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Reload(); err == nil {
+		t.Error("expected a corrupt database to fail to load")
+	}
+	if got := city(); got != "Hamburg" {
+		t.Errorf("expected the last good database kept, got %q", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := db.Stop(ctx); err != nil {
+		t.Errorf("stop: %v", err)
+	}
+}
+
+// geoIPAPI is an ipapi.co style provider counting the addresses asked about
+type geoIPAPI struct {
+	mu    sync.Mutex
+	asked []string
+	down  atomic.Bool
+}
+
+func (a *geoIPAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ip := strings.Trim(strings.TrimSuffix(r.URL.Path, "/json/"), "/")
+	a.mu.Lock()
+	a.asked = append(a.asked, ip)
+	a.mu.Unlock()
+
+	if a.down.Load() {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch ip {
+	case "203.0.113.9":
+		w.Write([]byte(`{"ip":"203.0.113.9","city":"Berlin","country_code":"DE","latitude":52.52,"longitude":13.405}`))
+	default:
+		w.Write([]byte(`{"ip":"` + ip + `","error":true,"reason":"Reserved IP Address"}`))
+	}
+}
+
+// count returns how often ip was asked about
+func (a *geoIPAPI) count(ip string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := 0
+	for _, asked := range a.asked {
+		if asked == ip {
+			n++
+		}
+	}
+	return n
+}
+
+// TestHTTPResolver tests the remote provider and its cache
+func TestHTTPResolver(t *testing.T) {
+	api := &geoIPAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	client := httpclient.New(httpclient.Config{Timeout: time.Second})
+	resolver := geoip.NewHTTPResolver(client, server.URL+"/{ip}/json/", cache.NewMemoryStore(), time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		loc, err := resolver.Resolve(ctx, "203.0.113.9")
+		if err != nil || loc.String() != "Berlin, DE" || loc.Lat != 52.52 {
+			t.Fatalf("lookup %d: expected Berlin, got %+v, %v", i, loc, err)
+		}
+	}
+	if n := api.count("203.0.113.9"); n != 1 {
+		t.Errorf("expected the second lookup cached, got %d requests", n)
+	}
+
+	// Addresses the provider does not know are cached as unknown
+	for i := 0; i < 2; i++ {
+		if loc, err := resolver.Resolve(ctx, "198.51.100.1"); err != nil || loc.Known() {
+			t.Fatalf("expected an unknown address, got %+v, %v", loc, err)
+		}
+	}
+	if n := api.count("198.51.100.1"); n != 1 {
+		t.Errorf("expected unknown answers cached, got %d requests", n)
+	}
+
+	// Private addresses are never sent
+	if loc, err := resolver.Resolve(ctx, "10.1.2.3"); err != nil || loc.Known() || api.count("10.1.2.3") != 0 {
+		t.Errorf("expected a private address unknown without a request, got %+v, %v", loc, err)
+	}
+
+	// Failures are not cached
+	api.down.Store(true)
+	if _, err := resolver.Resolve(ctx, "192.0.2.7"); err == nil {
+		t.Error("expected an unavailable provider to fail")
+	}
+	api.down.Store(false)
+	if _, err := resolver.Resolve(ctx, "192.0.2.7"); err != nil || api.count("192.0.2.7") != 2 {
+		t.Errorf("expected the failed lookup asked again, got %v after %d requests", err, api.count("192.0.2.7"))
+	}
+}
+
+// slowResolver answers after delay unless its context ends first
+type slowResolver struct {
+	delay time.Duration
+	err   error
+}
+
+func (r slowResolver) Resolve(ctx context.Context, ip string) (geoip.Location, error) {
+	select {
+	case <-time.After(r.delay):
+		return geoip.Location{Country: "DE"}, r.err
+	case <-ctx.Done():
+		return geoip.Location{}, ctx.Err()
+	}
+}
+
+// TestResolveWithin tests that slow and failing resolvers leave the
+// location unknown
+func TestResolveWithin(t *testing.T) {
+	ctx := context.Background()
+	if loc := geoip.ResolveWithin(ctx, slowResolver{}, "203.0.113.9", time.Second); loc.Country != "DE" {
+		t.Errorf("expected a quick answer used, got %+v", loc)
+	}
+
+	start := time.Now()
+	if loc := geoip.ResolveWithin(ctx, slowResolver{delay: time.Minute}, "203.0.113.9", 20*time.Millisecond); loc.Known() {
+		t.Errorf("expected a slow lookup abandoned, got %+v", loc)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the lookup abandoned at the timeout, waited %s", elapsed)
+	}
+
+	if loc := geoip.ResolveWithin(ctx, slowResolver{err: stderrors.New("boom")}, "203.0.113.9", time.Second); loc.Known() {
+		t.Errorf("expected a failed lookup unknown, got %+v", loc)
+	}
+	if loc := geoip.ResolveWithin(ctx, nil, "203.0.113.9", time.Second); loc.Known() {
+		t.Errorf("expected no resolver to locate nothing, got %+v", loc)
+	}
+}
+
+// TestLoginLocation tests that sessions show where their login came from
+func TestLoginLocation(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.GeoIP = config.GeoIPConfig{Driver: "maxmind", MaxMindPath: geoIPDatabase, Timeout: time.Second}
+	})
+	user := ta.CreateUser(models.RoleUser)
+
+	loginFrom := func(ip string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ta.Server.URL+"/api/v1/auth/login",
+			strings.NewReader(`{"email":"`+user.Email+`","password":"`+user.Password+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", ip)
+		res, err := ta.Server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("login: %v", err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("login: expected 200, got %d", res.StatusCode)
+		}
+	}
+	loginFrom("203.0.113.9")
+	loginFrom("8.8.8.8")
+
+	var sessions []models.Session
+	if err := ta.DB().Where("user_id = ?", user.ID).Find(&sessions).Error; err != nil {
+		t.Fatal(err)
+	}
+	locations := map[string]string{}
+	for _, session := range sessions {
+		locations[session.IPAddress] = session.Location
+	}
+	if locations["203.0.113.9"] != "Berlin, DE" || locations["8.8.8.8"] != "" {
+		t.Fatalf("expected only the Berlin session located, got %v", locations)
+	}
+
+	resp := ta.Request(http.MethodGet, "/api/v1/me/sessions", nil, user.Token)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(resp.Body), `"location":"Berlin, DE"`) {
+		t.Errorf("expected the listing to show the location, got %d: %s", resp.StatusCode, resp.Body)
+	}
+}
@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	userv1 "BackofficeGoService/api/gen/backoffice/user/v1"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/grpcserver"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const grpcTestToken = "service-token"
+
+// fakeUserLookup serves users from memory
+type fakeUserLookup map[uuid.UUID]*models.User
+
+func (f fakeUserLookup) GetUser(ctx context.Context, id string) (*models.User, error) {
+	if id == "00000000-0000-0000-0000-00000000dead" {
+		panic("lookup exploded")
+	}
+	user, ok := f[uuid.MustParse(id)]
+	if !ok {
+		return nil, services.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (f fakeUserLookup) GetUsersByIDs(ctx context.Context, ids []string) ([]*models.User, error) {
+	var users []*models.User
+	for _, id := range ids {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		if user, ok := f[parsed]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// startGRPC serves the internal API over an in-memory listener
+func startGRPC(t *testing.T, users fakeUserLookup, tokens staticTokenValidator) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpcserver.NewServer(users, tokens, grpcTestToken, logger.NewSimpleLogger())
+	go server.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := server.Stop(ctx); err != nil {
+			t.Errorf("stop: %v", err)
+		}
+	})
+	return conn
+}
+
+func authorized(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+grpcTestToken)
+}
+
+func newGRPCUser(email string) *models.User {
+	return &models.User{ID: uuid.New(), Email: email, Username: strings.Split(email, "@")[0], Role: models.RoleUser, Active: true}
+}
+
+func TestGRPCRequiresServiceToken(t *testing.T) {
+	user := newGRPCUser("ann@example.com")
+	client := userv1.NewUserServiceClient(startGRPC(t, fakeUserLookup{user.ID: user}, nil))
+
+	req := &userv1.GetUserRequest{Id: user.ID.String()}
+	if _, err := client.GetUser(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without token, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	if _, err := client.GetUser(ctx, req); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated with wrong token, got %v", err)
+	}
+
+	resp, err := client.GetUser(authorized(context.Background()), req)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if resp.GetUser().GetEmail() != "ann@example.com" || resp.GetUser().GetRole() != string(models.RoleUser) {
+		t.Errorf("unexpected user %+v", resp.GetUser())
+	}
+}
+
+func TestGRPCGetUserErrors(t *testing.T) {
+	client := userv1.NewUserServiceClient(startGRPC(t, fakeUserLookup{}, nil))
+	ctx := authorized(context.Background())
+
+	cases := []struct {
+		id   string
+		code codes.Code
+	}{
+		{"not-a-uuid", codes.InvalidArgument},
+		{uuid.NewString(), codes.NotFound},
+		// A panicking handler is recovered and must not take down the server
+		{"00000000-0000-0000-0000-00000000dead", codes.Internal},
+	}
+	for _, tc := range cases {
+		if _, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: tc.id}); status.Code(err) != tc.code {
+			t.Errorf("GetUser(%q): expected %v, got %v", tc.id, tc.code, err)
+		}
+	}
+
+	if _, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: uuid.NewString()}); status.Code(err) != codes.NotFound {
+		t.Errorf("server unusable after panic: %v", err)
+	}
+}
+
+func TestGRPCGetUsersByIDs(t *testing.T) {
+	ann := newGRPCUser("ann@example.com")
+	bob := newGRPCUser("bob@example.com")
+	client := userv1.NewUserServiceClient(startGRPC(t, fakeUserLookup{ann.ID: ann, bob.ID: bob}, nil))
+	ctx := authorized(context.Background())
+
+	unknown := uuid.NewString()
+	resp, err := client.GetUsersByIDs(ctx, &userv1.GetUsersByIDsRequest{
+		Ids: []string{ann.ID.String(), strings.ToUpper(bob.ID.String()), unknown, "garbage"},
+	})
+	if err != nil {
+		t.Fatalf("GetUsersByIDs: %v", err)
+	}
+	if len(resp.GetUsers()) != 2 {
+		t.Errorf("expected 2 users, got %d", len(resp.GetUsers()))
+	}
+	missing := resp.GetMissingIds()
+	if len(missing) != 2 || missing[0] != unknown || missing[1] != "garbage" {
+		t.Errorf("unexpected missing ids %v", missing)
+	}
+
+	tooMany := make([]string, 101)
+	for i := range tooMany {
+		tooMany[i] = uuid.NewString()
+	}
+	if _, err := client.GetUsersByIDs(ctx, &userv1.GetUsersByIDsRequest{Ids: tooMany}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument over the batch limit, got %v", err)
+	}
+}
+
+func TestGRPCValidateToken(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	tokens := staticTokenValidator{
+		"good": {UserID: "u1", Email: "ann@example.com", Role: "admin", OrgIDs: []string{"o1"}, ExpiresAt: expires},
+	}
+	client := userv1.NewUserServiceClient(startGRPC(t, fakeUserLookup{}, tokens))
+	ctx := authorized(context.Background())
+
+	resp, err := client.ValidateToken(ctx, &userv1.ValidateTokenRequest{Token: "good"})
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if resp.GetUserId() != "u1" || resp.GetRole() != "admin" || len(resp.GetOrgIds()) != 1 {
+		t.Errorf("unexpected claims %+v", resp)
+	}
+	if !resp.GetExpiresAt().AsTime().Equal(expires) {
+		t.Errorf("expected expiry %v, got %v", expires, resp.GetExpiresAt().AsTime())
+	}
+
+	if _, err := client.ValidateToken(ctx, &userv1.ValidateTokenRequest{Token: "bad"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated for bad token, got %v", err)
+	}
+	if _, err := client.ValidateToken(ctx, &userv1.ValidateTokenRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for empty token, got %v", err)
+	}
+}
+
+func TestGRPCHealthIsPublic(t *testing.T) {
+	health := healthpb.NewHealthClient(startGRPC(t, fakeUserLookup{}, nil))
+
+	resp, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: userv1.UserService_ServiceDesc.ServiceName,
+	})
+	if err != nil {
+		t.Fatalf("health check: %v", err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected SERVING, got %v", resp.GetStatus())
+	}
+}
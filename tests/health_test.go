@@ -0,0 +1,289 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/infrastructure/email"
+	"BackofficeGoService/internal/infrastructure/redis"
+	"BackofficeGoService/internal/infrastructure/storage"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/health"
+)
+
+// countingCheck returns a check that fails while *failing is set and counts its runs
+func countingCheck(name string, critical bool, runs *atomic.Int32, failing *atomic.Bool) health.Checker {
+	return health.NewCheck(name, critical, func(ctx context.Context) error {
+		runs.Add(1)
+		if failing.Load() {
+			return errors.New(name + " unreachable")
+		}
+		return nil
+	})
+}
+
+// TestHealthCheckSeverity tests that only critical failures make the service not ready
+func TestHealthCheckSeverity(t *testing.T) {
+	var runs atomic.Int32
+	var primaryDown, reportingDown atomic.Bool
+	registry := health.NewRegistry(0)
+	registry.Register(
+		countingCheck("primary", true, &runs, &primaryDown),
+		countingCheck("reporting", false, &runs, &reportingDown),
+	)
+	ctx := context.Background()
+
+	if report := registry.Run(ctx); report.Status != health.StatusReady || len(report.Checks) != 2 {
+		t.Fatalf("expected ready with two checks, got %+v", report)
+	}
+
+	reportingDown.Store(true)
+	report := registry.Run(ctx)
+	if report.Status != health.StatusDegraded {
+		t.Errorf("expected degraded with an informational check down, got %q", report.Status)
+	}
+	if r := report.Checks["reporting"]; r.Status != health.StatusDown || r.Critical || r.Error != "reporting unreachable" || r.LastSuccess == nil {
+		t.Errorf("unexpected reporting result %+v", r)
+	}
+
+	// Excluding the informational check hides its failure
+	if report := registry.Run(ctx, "reporting"); report.Status != health.StatusReady || len(report.Checks) != 1 {
+		t.Errorf("expected ready without reporting, got %+v", report)
+	}
+
+	// Critical checks cannot be excluded
+	primaryDown.Store(true)
+	report = registry.Run(ctx, "primary", "reporting")
+	if report.Status != health.StatusNotReady {
+		t.Errorf("expected not ready with the primary down, got %q", report.Status)
+	}
+	if _, ok := report.Checks["primary"]; !ok {
+		t.Error("expected the critical check to run despite exclude")
+	}
+}
+
+// TestHealthCheckCaching tests that reports are reused until the ttl passes
+func TestHealthCheckCaching(t *testing.T) {
+	var runs atomic.Int32
+	var down atomic.Bool
+	fake := clock.NewFake(time.Now())
+	registry := health.NewRegistry(2*time.Second, health.WithClock(fake))
+	registry.Register(countingCheck("primary", true, &runs, &down))
+	ctx := context.Background()
+
+	if report := registry.Run(ctx); report.Cached {
+		t.Error("expected the first report to be fresh")
+	}
+	down.Store(true)
+	fake.Advance(time.Second)
+	report := registry.Run(ctx)
+	if !report.Cached || report.Status != health.StatusReady || runs.Load() != 1 {
+		t.Errorf("expected the cached ready report after one run, got %+v after %d runs", report, runs.Load())
+	}
+
+	// A different exclude list is cached separately; order does not matter
+	registry.Run(ctx, "b", "a")
+	if report := registry.Run(ctx, "a", "b"); !report.Cached || runs.Load() != 2 {
+		t.Errorf("expected one run per exclude list, got %d", runs.Load())
+	}
+
+	fake.Advance(time.Second)
+	report = registry.Run(ctx)
+	if report.Cached || report.Status != health.StatusNotReady || runs.Load() != 3 {
+		t.Errorf("expected a fresh not ready report after the ttl, got %+v after %d runs", report, runs.Load())
+	}
+}
+
+// TestDatabaseHealthChecks tests that required databases are critical and optional ones informational
+func TestDatabaseHealthChecks(t *testing.T) {
+	manager := database.NewManager()
+	defer manager.CloseAll()
+
+	ctx := context.Background()
+	if err := manager.ConnectDriver(ctx, "primary", &fakeDriver{}, database.ConnectPolicy{Required: true}); err != nil {
+		t.Fatalf("connect primary: %v", err)
+	}
+	if err := manager.ConnectDriver(ctx, "reporting", &fakeDriver{failures: 1000}, database.ConnectPolicy{RetryInterval: time.Hour}); err != nil {
+		t.Fatalf("connect reporting: %v", err)
+	}
+
+	registry := health.NewRegistry(0)
+	registry.RegisterSource(manager.HealthChecks)
+
+	report := registry.Run(ctx)
+	if report.Status != health.StatusDegraded {
+		t.Errorf("expected degraded, got %+v", report)
+	}
+	if r := report.Checks["primary"]; r.Status != health.StatusUp || !r.Critical {
+		t.Errorf("unexpected primary result %+v", r)
+	}
+	if r := report.Checks["reporting"]; r.Status != health.StatusDown || r.Critical || r.Error == "" {
+		t.Errorf("unexpected reporting result %+v", r)
+	}
+
+	if report := registry.Run(ctx, "reporting"); report.Status != health.StatusReady {
+		t.Errorf("expected ready without reporting, got %+v", report)
+	}
+}
+
+// detailedCheck is a checker that reports details and waits for its peers
+type detailedCheck struct {
+	health.Checker
+	started *sync.WaitGroup
+}
+
+func (c detailedCheck) Check(ctx context.Context) error {
+	// Every check must be running before any returns, so a sequential
+	// registry would time out here
+	c.started.Done()
+	c.started.Wait()
+	return c.Checker.Check(ctx)
+}
+
+func (c detailedCheck) Details() map[string]string {
+	return map[string]string{"name": c.Name()}
+}
+
+// TestHealthCheckAggregation tests that checkers run concurrently and that
+// every result, with its details, lands in one report
+func TestHealthCheckAggregation(t *testing.T) {
+	var runs atomic.Int32
+	var up, down atomic.Bool
+	down.Store(true)
+
+	var started sync.WaitGroup
+	started.Add(3)
+	registry := health.NewRegistry(0, health.WithTimeout(2*time.Second))
+	registry.Register(
+		detailedCheck{countingCheck("primary", true, &runs, &up), &started},
+		detailedCheck{countingCheck("cache", false, &runs, &down), &started},
+	)
+	registry.RegisterSource(func() []health.Checker {
+		return []health.Checker{detailedCheck{countingCheck("reporting", false, &runs, &up), &started}}
+	})
+
+	report := registry.Run(context.Background())
+	if report.Status != health.StatusDegraded || len(report.Checks) != 3 || runs.Load() != 3 {
+		t.Fatalf("expected a degraded report of three checks, got %+v", report)
+	}
+	for name, result := range report.Checks {
+		if result.Details["name"] != name {
+			t.Errorf("expected the details of %s, got %v", name, result.Details)
+		}
+		if want := name == "cache"; (result.Status == health.StatusDown) != want {
+			t.Errorf("unexpected %s result %+v", name, result)
+		}
+	}
+	if r := report.Checks["cache"]; r.LastSuccess != nil || r.Error != "cache unreachable" {
+		t.Errorf("expected a check that never passed to have no last success, got %+v", r)
+	}
+}
+
+// TestHealthCheckTimeout tests that a hung check is reported down at the
+// timeout without holding up the report
+func TestHealthCheckTimeout(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+
+	registry := health.NewRegistry(0, health.WithTimeout(50*time.Millisecond))
+	registry.Register(
+		// Ignores its context entirely
+		health.NewCheck("smtp", false, func(ctx context.Context) error {
+			<-hang
+			return nil
+		}),
+		health.NewCheck("primary", true, func(ctx context.Context) error {
+			return nil
+		}),
+	)
+
+	start := time.Now()
+	report := registry.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the report at the timeout, took %s", elapsed)
+	}
+	if report.Status != health.StatusDegraded {
+		t.Errorf("expected an informational timeout to degrade, got %q", report.Status)
+	}
+	if r := report.Checks["smtp"]; r.Status != health.StatusDown || r.Error != "timed out after 50ms" {
+		t.Errorf("unexpected smtp result %+v", r)
+	}
+	if r := report.Checks["primary"]; r.Status != health.StatusUp {
+		t.Errorf("expected the fast check up, got %+v", r)
+	}
+
+	// A critical check that times out makes the service not ready
+	registry.Register(health.NewCheck("broker", true, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	if report := registry.Run(context.Background()); report.Status != health.StatusNotReady || report.Checks["broker"].Error != "timed out after 50ms" {
+		t.Errorf("expected not ready after a critical timeout, got %+v", report)
+	}
+}
+
+// fakeRedis is a redis client whose ping fails with err
+type fakeRedis struct {
+	redis.Client
+	err error
+}
+
+func (f fakeRedis) Ping(ctx context.Context) error { return f.err }
+
+// fakeMailer is an email client whose ping fails with err
+type fakeMailer struct{ err error }
+
+func (f fakeMailer) Send(to, subject, body string) error { return nil }
+func (f fakeMailer) Ping(ctx context.Context) error      { return f.err }
+
+// TestInfrastructureHealthChecks tests the adapters of the redis, storage and
+// email clients, and that the critical flag decides the readiness
+func TestInfrastructureHealthChecks(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "files")
+	store, err := storage.NewLocalStore(root)
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+
+	var mailer email.EmailClient = fakeMailer{err: errors.New("421 service not available")}
+	pinger, ok := mailer.(email.Pinger)
+	if !ok {
+		t.Fatal("expected the mailer to be a Pinger")
+	}
+
+	registry := health.NewRegistry(0)
+	registry.Register(
+		redis.NewHealthCheck(fakeRedis{}, false),
+		storage.NewHealthCheck(store, true),
+		email.NewHealthCheck(pinger, false),
+	)
+
+	ctx := context.Background()
+	report := registry.Run(ctx)
+	if report.Status != health.StatusDegraded {
+		t.Errorf("expected degraded with email down, got %+v", report)
+	}
+	if r := report.Checks["redis"]; r.Status != health.StatusUp || r.Critical {
+		t.Errorf("unexpected redis result %+v", r)
+	}
+	if r := report.Checks["email"]; r.Status != health.StatusDown || r.Error != "421 service not available" {
+		t.Errorf("unexpected email result %+v", r)
+	}
+	if r := report.Checks["storage"]; r.Status != health.StatusUp || !r.Critical {
+		t.Errorf("unexpected storage result %+v", r)
+	}
+
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatalf("remove root: %v", err)
+	}
+	if report := registry.Run(ctx, "email"); report.Status != health.StatusNotReady || report.Checks["storage"].Status != health.StatusDown {
+		t.Errorf("expected not ready with the storage root gone, got %+v", report)
+	}
+}
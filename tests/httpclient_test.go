@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/infrastructure/email"
+	"BackofficeGoService/internal/pkg/httpclient"
+	"BackofficeGoService/internal/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// TestHTTPClientTimeout tests that a slow server fails the request once
+// the response header timeout passes
+func TestHTTPClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := httpclient.New(httpclient.Config{ResponseHeaderTimeout: 50 * time.Millisecond})
+	start := time.Now()
+	resp, err := client.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the request to time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the timeout enforced, took %v", elapsed)
+	}
+
+	client = httpclient.New(httpclient.Config{Timeout: 50 * time.Millisecond})
+	if resp, err := client.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the overall timeout enforced")
+	}
+}
+
+// TestHTTPClientRetries tests that idempotent requests are retried with
+// their body after transient failures, and others are sent once
+func TestHTTPClientRetries(t *testing.T) {
+	var calls atomic.Int32
+	var lastBody atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lastBody.Store(string(body))
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := httpclient.New(httpclient.Config{MaxRetries: 3, RetryBackoff: time.Millisecond})
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Errorf("expected success on the third attempt, got %d after %d", resp.StatusCode, calls.Load())
+	}
+
+	calls.Store(0)
+	resp, err = client.Post(server.URL, "application/json", strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Errorf("expected a POST sent once, got %d after %d", resp.StatusCode, calls.Load())
+	}
+
+	calls.Store(0)
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"a":1}`))
+	req.Header.Set("Idempotency-Key", "k1")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("post with key: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 || lastBody.Load() != `{"a":1}` {
+		t.Errorf("expected a keyed POST retried with its body, got %d after %d, body %v", resp.StatusCode, calls.Load(), lastBody.Load())
+	}
+
+	calls.Store(0)
+	once := httpclient.New(httpclient.Config{MaxRetries: 1, RetryBackoff: time.Millisecond})
+	resp, err = once.Get(server.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 2 {
+		t.Errorf("expected the last failure after the retries, got %d after %d", resp.StatusCode, calls.Load())
+	}
+}
+
+// TestHTTPClientHooks tests that hooks see every attempt, and that
+// requests name the service and carry the caller's trace
+func TestHTTPClientHooks(t *testing.T) {
+	var calls atomic.Int32
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	type observation struct {
+		host   string
+		status int
+	}
+	var seen []observation
+	m := metrics.New()
+	observe := func(host string, status int, elapsed time.Duration) {
+		seen = append(seen, observation{host, status})
+		m.ObserveOutbound(host, status, elapsed)
+	}
+	client := httpclient.New(httpclient.Config{MaxRetries: 1, RetryBackoff: time.Millisecond, UserAgent: httpclient.UserAgent("Backoffice Service")},
+		httpclient.Latency(observe))
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "call")
+	defer span.End()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	if len(seen) != 2 || seen[0] != (observation{host, http.StatusBadGateway}) || seen[1] != (observation{host, http.StatusNoContent}) {
+		t.Errorf("expected both attempts observed, got %+v", seen)
+	}
+	if n := testutil.CollectAndCount(m.Outbound); n != 2 {
+		t.Errorf("expected a series per status class, got %d", n)
+	}
+
+	if ua := header.Get("User-Agent"); !strings.HasPrefix(ua, "Backoffice-Service/") {
+		t.Errorf("expected the service in User-Agent, got %q", ua)
+	}
+	if traceparent := header.Get("Traceparent"); !strings.Contains(traceparent, span.SpanContext().TraceID().String()) {
+		t.Errorf("expected the trace propagated, got %q", traceparent)
+	}
+
+	// Unreachable hosts are observed without a status
+	seen = nil
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	if resp, err := client.Get(closed.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected a closed server to fail")
+	}
+	if len(seen) != 2 || seen[0].status != 0 {
+		t.Errorf("expected failed attempts observed with status 0, got %+v", seen)
+	}
+}
+
+// TestSendGridClient tests the mail send request and its error handling
+func TestSendGridClient(t *testing.T) {
+	var auth string
+	var payload struct {
+		Personalizations []struct {
+			To []struct {
+				Email string `json:"email"`
+			} `json:"to"`
+		} `json:"personalizations"`
+		From struct {
+			Email string `json:"email"`
+		} `json:"from"`
+		Subject string `json:"subject"`
+	}
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"errors":[{"message":"bad"}]}`))
+	}))
+	defer server.Close()
+
+	client := email.NewSendGridClient(httpclient.New(httpclient.Config{}), server.URL, "key", "no-reply@example.com")
+	if err := client.Send("ann@example.com", "Hello", "Hi Ann"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if auth != "Bearer key" || payload.From.Email != "no-reply@example.com" || payload.Subject != "Hello" ||
+		len(payload.Personalizations) != 1 || payload.Personalizations[0].To[0].Email != "ann@example.com" {
+		t.Errorf("unexpected request %q %+v", auth, payload)
+	}
+
+	status = http.StatusUnauthorized
+	if err := client.Send("ann@example.com", "Hello", "Hi Ann"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected the rejection reported, got %v", err)
+	}
+}
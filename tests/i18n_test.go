@@ -0,0 +1,231 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+)
+
+// messageKeys returns every string constant declared in the i18n keys file
+func messageKeys(t *testing.T) []string {
+	t.Helper()
+
+	file, err := parser.ParseFile(token.NewFileSet(), "../internal/pkg/i18n/keys.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parse keys: %v", err)
+	}
+
+	var keys []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		for _, value := range spec.Values {
+			if lit, ok := value.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				key, _ := strconv.Unquote(lit.Value)
+				keys = append(keys, key)
+			}
+		}
+		return true
+	})
+	if len(keys) == 0 {
+		t.Fatal("no message keys found")
+	}
+	return keys
+}
+
+var placeholderPattern = regexp.MustCompile(`\{\w+\}`)
+
+func placeholders(text string) []string {
+	found := placeholderPattern.FindAllString(text, -1)
+	sort.Strings(found)
+	return found
+}
+
+// TestCatalogueCoverage tests that every key has English text and that
+// translations only use known keys and the English placeholders
+func TestCatalogueCoverage(t *testing.T) {
+	for _, key := range messageKeys(t) {
+		if !i18n.Has(i18n.DefaultLocale, key) {
+			t.Errorf("key %q has no English entry", key)
+		}
+	}
+
+	for _, locale := range i18n.Locales() {
+		for _, key := range i18n.Keys(locale) {
+			if !i18n.Has(i18n.DefaultLocale, key) {
+				t.Errorf("%s: key %q is not in the English catalogue", locale, key)
+				continue
+			}
+			want := placeholders(i18n.Translate(i18n.DefaultLocale, key, nil))
+			if got := placeholders(i18n.Translate(locale, key, nil)); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: key %q has placeholders %v, English has %v", locale, key, got, want)
+			}
+		}
+	}
+}
+
+// TestLocaleMatch tests picking a supported locale from Accept-Language
+func TestLocaleMatch(t *testing.T) {
+	cases := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"de-DE,de;q=0.9,en;q=0.8", "de"},
+		{"fr-CH, fr;q=0.9", "fr"},
+		{"es, de;q=0.5", "de"},
+		{"es", "en"},
+		{"en-GB", "en"},
+		{"not a language header;;", "en"},
+	}
+	for _, tc := range cases {
+		if got := i18n.Match(tc.header); got != tc.want {
+			t.Errorf("Match(%q) = %q, want %q", tc.header, got, tc.want)
+		}
+	}
+}
+
+// TestTranslateFallback tests placeholder filling and the English and key fallbacks
+func TestTranslateFallback(t *testing.T) {
+	params := map[string]string{"field": "email"}
+	if got := i18n.Translate("de", i18n.ValidationRequired, params); got != "email ist erforderlich" {
+		t.Errorf("unexpected German text %q", got)
+	}
+	if got := i18n.Translate("xx", i18n.ValidationRequired, params); got != "email is required" {
+		t.Errorf("expected the English fallback, got %q", got)
+	}
+	if got := i18n.Translate("de", "Some literal text", nil); got != "Some literal text" {
+		t.Errorf("expected unknown keys unchanged, got %q", got)
+	}
+}
+
+type errorEnvelope struct {
+	Error middleware.ErrorBody `json:"error"`
+}
+
+// localizedRequest sends a request with an Accept-Language header and decodes the error envelope
+func localizedRequest(t *testing.T, ta *apptest.TestApp, method, path, lang string, body interface{}, token string) (*http.Response, errorEnvelope) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, ta.Server.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", lang)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := ta.Server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var envelope errorEnvelope
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		t.Fatalf("decode error envelope: %v", err)
+	}
+	return resp, envelope
+}
+
+// TestLocalizedErrors tests that API errors are translated while codes stay stable
+func TestLocalizedErrors(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	cases := []struct {
+		name        string
+		path        string
+		lang        string
+		wantLocale  string
+		wantMessage string
+	}{
+		{"german", "/api/v1/auth/login", "de-DE,de;q=0.9", "de", "Ungültige Anmeldedaten"},
+		{"french", "/api/v1/auth/login", "fr", "fr", "Identifiants invalides"},
+		{"query override", "/api/v1/auth/login?lang=fr", "de", "fr", "Identifiants invalides"},
+		{"unsupported", "/api/v1/auth/login", "es", "en", "Invalid credentials"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, envelope := localizedRequest(t, ta, http.MethodPost, tc.path, tc.lang, map[string]string{"email": admin.Email, "password": "wrong-password"}, "")
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("expected 401, got %d", resp.StatusCode)
+			}
+			if envelope.Error.Code != string(errors.CodeInvalidCredentials) || envelope.Error.Message != tc.wantMessage {
+				t.Errorf("unexpected error %+v", envelope.Error)
+			}
+			if got := resp.Header.Get("Content-Language"); got != tc.wantLocale {
+				t.Errorf("expected Content-Language %s, got %q", tc.wantLocale, got)
+			}
+		})
+	}
+
+	t.Run("validation details", func(t *testing.T) {
+		resp, envelope := localizedRequest(t, ta, http.MethodPost, "/api/v1/auth/register", "de", map[string]string{"email": "not-an-email", "password": "123"}, "")
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("expected 422, got %d", resp.StatusCode)
+		}
+		if envelope.Error.Code != string(errors.CodeValidationFailed) || envelope.Error.Message != "Ungültige Anfragedaten" {
+			t.Errorf("unexpected error %+v", envelope.Error)
+		}
+
+		details := map[string]middleware.ErrorDetail{}
+		for _, detail := range envelope.Error.Details {
+			details[detail.Field] = detail
+		}
+		if d := details["email"]; d.Code != i18n.ValidationEmail || d.Message != "email muss eine gültige E-Mail-Adresse sein" {
+			t.Errorf("unexpected email detail %+v", d)
+		}
+		if d := details["password"]; d.Code != i18n.ValidationMin || d.Message != "password muss mindestens 6 Zeichen lang sein" {
+			t.Errorf("unexpected password detail %+v", d)
+		}
+		if d := details["first_name"]; d.Code != i18n.ValidationRequired {
+			t.Errorf("unexpected first_name detail %+v", d)
+		}
+	})
+
+	t.Run("user update details", func(t *testing.T) {
+		resp, envelope := localizedRequest(t, ta, http.MethodPut, "/api/v1/users/"+admin.ID.String(), "fr", map[string]string{"nickname": "x"}, admin.Token)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", resp.StatusCode)
+		}
+		if envelope.Error.Code != string(errors.CodeUnknownFields) {
+			t.Errorf("expected %s, got %s", errors.CodeUnknownFields, envelope.Error.Code)
+		}
+		if len(envelope.Error.Details) != 1 || envelope.Error.Details[0].Message != "nickname n'est pas un champ connu" {
+			t.Errorf("unexpected details %+v", envelope.Error.Details)
+		}
+	})
+
+	t.Run("middleware errors", func(t *testing.T) {
+		resp, envelope := localizedRequest(t, ta, http.MethodGet, "/api/v1/users", "de", nil, "")
+		if resp.StatusCode != http.StatusUnauthorized || envelope.Error.Code != string(errors.CodeTokenRequired) || envelope.Error.Message != "Autorisierungstoken erforderlich" {
+			t.Errorf("unexpected response %d %+v", resp.StatusCode, envelope.Error)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/identifier"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// TestIdentifierRoundTrip tests that IDs of every format parse back from
+// both of their written forms
+func TestIdentifierRoundTrip(t *testing.T) {
+	for _, format := range identifier.Formats {
+		t.Run(string(format), func(t *testing.T) {
+			ids, err := identifier.NewGenerator(format)
+			if err != nil {
+				t.Fatalf("generator: %v", err)
+			}
+			for i := 0; i < 100; i++ {
+				id := ids.New()
+				for _, written := range []string{id.String(), identifier.Encode(id, identifier.FormatULID), identifier.Encode(id, format)} {
+					parsed, err := identifier.Parse(written)
+					if err != nil {
+						t.Fatalf("parse %q: %v", written, err)
+					}
+					if parsed != id {
+						t.Fatalf("parse %q = %s, want %s", written, parsed, id)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestIdentifierVersions tests the version of generated UUIDs
+func TestIdentifierVersions(t *testing.T) {
+	cases := map[identifier.Format]uuid.Version{
+		"":                      4,
+		identifier.FormatUUIDv4: 4,
+		identifier.FormatUUIDv7: 7,
+	}
+	for format, want := range cases {
+		ids, err := identifier.NewGenerator(format)
+		if err != nil {
+			t.Fatalf("generator %q: %v", format, err)
+		}
+		if got := ids.New().Version(); got != want {
+			t.Errorf("format %q generated version %d, want %d", format, got, want)
+		}
+	}
+
+	if _, err := identifier.NewGenerator("snowflake"); !errors.Is(err, identifier.ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+// TestParseULID tests the Crockford base32 form against known values and malformed input
+func TestParseULID(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"00000000000000000000000000", "00000000-0000-0000-0000-000000000000"},
+		{"7ZZZZZZZZZZZZZZZZZZZZZZZZZ", "ffffffff-ffff-ffff-ffff-ffffffffffff"},
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "01563e3a-b5d3-d676-4c61-efb99302bd5b"},
+		{"01arz3ndektsv4rrffq69g5fav", "01563e3a-b5d3-d676-4c61-efb99302bd5b"},
+	}
+	for _, tc := range cases {
+		id, err := identifier.Parse(tc.in)
+		if err != nil {
+			t.Errorf("parse %q: %v", tc.in, err)
+			continue
+		}
+		if id.String() != tc.want {
+			t.Errorf("parse %q = %s, want %s", tc.in, id, tc.want)
+		}
+		if got := identifier.Encode(id, identifier.FormatULID); got != strings.ToUpper(tc.in) {
+			t.Errorf("encode %s = %s, want %s", id, got, strings.ToUpper(tc.in))
+		}
+	}
+
+	for _, in := range []string{
+		"80000000000000000000000000", // more than 128 bits
+		"0000000000000000000000000U", // U is not in the alphabet
+		"0000000000000000000000000!",
+		"0000000000000000000000000",
+		"not-an-id",
+		"",
+	} {
+		if _, err := identifier.Parse(in); !errors.Is(err, identifier.ErrInvalid) {
+			t.Errorf("parse %q: expected ErrInvalid, got %v", in, err)
+		}
+	}
+}
+
+// TestULIDGeneratorMonotonic tests that ULIDs increase within a millisecond
+// and when the clock goes back
+func TestULIDGeneratorMonotonic(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	ids := identifier.NewULIDGenerator(clk)
+
+	prev := ids.New()
+	if got := identifier.Encode(prev, identifier.FormatULID)[:10]; got != "01HZ9TQGG0" {
+		t.Errorf("timestamp of %s = %s, want 01HZ9TQGG0", identifier.Encode(prev, identifier.FormatULID), got)
+	}
+	next := func() {
+		t.Helper()
+		id := ids.New()
+		if bytes.Compare(id[:], prev[:]) <= 0 {
+			t.Fatalf("%s does not sort after %s", id, prev)
+		}
+		prev = id
+	}
+
+	for i := 0; i < 1000; i++ {
+		next()
+	}
+	clk.Advance(time.Millisecond)
+	next()
+	clk.Set(start)
+	next()
+}
+
+// TestUserIDFormats tests that users get IDs of the configured format and
+// can be looked up by any written form of their ID
+func TestUserIDFormats(t *testing.T) {
+	for _, format := range identifier.Formats {
+		t.Run(string(format), func(t *testing.T) {
+			ids, err := identifier.NewGenerator(format)
+			if err != nil {
+				t.Fatalf("generator: %v", err)
+			}
+			db, _ := databasetest.NewManager(t, databasetest.WithSQLite(&models.User{}))
+			users := services.NewUserService(db, cache.NewMemoryStore(), nil, nil, nil, logger.NewSimpleLogger(), services.WithUserIDGenerator(ids))
+			ctx := context.Background()
+
+			first, err := users.CreateUser(ctx, &services.CreateUserRequest{Email: "ann@example.com"}, "")
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			second, err := users.CreateUser(ctx, &services.CreateUserRequest{Email: "bob@example.com"}, "")
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			if format == identifier.FormatUUIDv7 && first.ID.Version() != 7 {
+				t.Errorf("expected a UUIDv7, got version %d", first.ID.Version())
+			}
+			if format != identifier.FormatUUIDv4 && bytes.Compare(first.ID[:], second.ID[:]) >= 0 {
+				t.Errorf("%s was created before %s but does not sort first", first.ID, second.ID)
+			}
+
+			for _, written := range []string{first.ID.String(), identifier.Encode(first.ID, identifier.FormatULID)} {
+				fetched, err := users.GetUser(ctx, written)
+				if err != nil {
+					t.Fatalf("get %s: %v", written, err)
+				}
+				if fetched.ID != first.ID {
+					t.Errorf("get %s returned %s", written, fetched.ID)
+				}
+			}
+		})
+	}
+}
+
+// TestUserIDFormatAPI tests that a v4 user keeps working when new users get
+// UUIDv7s, including self-access through the ULID form of its ID
+func TestUserIDFormatAPI(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.App.IDFormat = string(identifier.FormatUUIDv7)
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+	member := ta.CreateUser(models.RoleUser)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/users", map[string]string{
+		"email": "ann@example.com", "password": "secret123", "first_name": "Ann",
+	}, admin.Token)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: %d %s", resp.StatusCode, resp.Body)
+	}
+	var created struct {
+		Data models.User `json:"data"`
+	}
+	resp.Decode(t, &created)
+	if created.Data.ID.Version() != 7 {
+		t.Errorf("expected a UUIDv7, got %s", created.Data.ID)
+	}
+
+	path := "/api/v1/users/" + identifier.Encode(member.ID, identifier.FormatULID)
+	if resp := ta.Request(http.MethodGet, path, nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("get by ULID: %d %s", resp.StatusCode, resp.Body)
+	}
+	if resp := ta.Request(http.MethodGet, path+"/export", nil, member.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("export own data by ULID: %d %s", resp.StatusCode, resp.Body)
+	}
+}
+
+func BenchmarkIdentifierGenerators(b *testing.B) {
+	for _, format := range identifier.Formats {
+		ids, err := identifier.NewGenerator(format)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(string(format), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ids.New()
+			}
+		})
+	}
+}
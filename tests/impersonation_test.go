@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+)
+
+// impersonate starts impersonating target as admin and returns the token
+func impersonate(t *testing.T, ta *apptest.TestApp, admin, target *apptest.User) string {
+	t.Helper()
+
+	resp := ta.Request(http.MethodPost, "/api/v1/admin/impersonate/"+target.ID.String(), nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("impersonate: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var result services.AuthResult
+	resp.Decode(t, &result)
+	if result.Token == "" || result.User == nil || result.User.ID != target.ID {
+		t.Fatalf("unexpected impersonation result %+v", result)
+	}
+	return result.Token
+}
+
+// expectErrorCode asserts a response's status and error code
+func expectErrorCode(t *testing.T, resp *apptest.Response, status int, code errors.Code) {
+	t.Helper()
+
+	var body errorEnvelope
+	resp.Decode(t, &body)
+	if resp.StatusCode != status || body.Error.Code != string(code) {
+		t.Errorf("expected %d %s, got %d %s", status, code, resp.StatusCode, body.Error.Code)
+	}
+}
+
+// auditCount counts audit entries of action by actor about entityID
+func auditCount(t *testing.T, ta *apptest.TestApp, action string, actor *apptest.User, entityID string) int64 {
+	t.Helper()
+
+	var n int64
+	if err := ta.DB().Model(&models.AuditLog{}).
+		Where("action = ? AND actor_id = ? AND entity_id = ?", action, actor.ID.String(), entityID).
+		Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// TestImpersonationRoundTrip tests acting as a user, the request log and returning to the admin
+func TestImpersonationRoundTrip(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	token := impersonate(t, ta, admin, user)
+	if n := auditCount(t, ta, models.AuditActionUserImpersonated, admin, user.ID.String()); n != 1 {
+		t.Errorf("expected one impersonation audit entry, got %d", n)
+	}
+
+	// The token acts as the user: admin routes are closed
+	if resp := ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String()+"/activity", nil, token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the user's own activity, got %d", resp.StatusCode)
+	}
+	if resp := ta.Request(http.MethodGet, "/api/v1/admin/jobs", nil, token); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected admin routes to be closed, got %d", resp.StatusCode)
+	}
+
+	logged := false
+	for _, entry := range ta.Logs.Entries() {
+		if entry.Message != "HTTP Request" {
+			continue
+		}
+		if path, _ := entry.Field("path"); path == "/api/v1/users/"+user.ID.String()+"/activity" {
+			impersonator, _ := entry.Field("impersonator_id")
+			logged = impersonator == admin.ID.String()
+		}
+	}
+	if !logged {
+		t.Error("expected the request log to name the impersonator")
+	}
+
+	// Stopping hands back a token of the admin
+	resp := ta.Request(http.MethodPost, "/api/v1/admin/impersonate/stop", nil, token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("stop: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var result services.AuthResult
+	resp.Decode(t, &result)
+	if result.User == nil || result.User.ID != admin.ID {
+		t.Fatalf("expected the admin back, got %+v", result.User)
+	}
+	if resp := ta.Request(http.MethodGet, "/api/v1/admin/jobs", nil, result.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the admin token to reach admin routes, got %d", resp.StatusCode)
+	}
+	if n := auditCount(t, ta, models.AuditActionImpersonationEnded, admin, user.ID.String()); n != 1 {
+		t.Errorf("expected one end of impersonation audit entry, got %d", n)
+	}
+
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/admin/impersonate/stop", nil, admin.Token), http.StatusBadRequest, errors.CodeNotImpersonating)
+}
+
+// TestImpersonationScope tests what an impersonation token may not do
+func TestImpersonationScope(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.JWT.AllowAdminImpersonation = true
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+	target := ta.CreateUser(models.RoleAdmin)
+	other := ta.CreateUser(models.RoleUser)
+
+	// Impersonating an admin gives the most permissive token to check the scope against
+	token := impersonate(t, ta, admin, target)
+	targetPath := "/api/v1/users/" + target.ID.String()
+
+	expectErrorCode(t, ta.Request(http.MethodPut, targetPath, map[string]string{"password": "new-secret"}, token), http.StatusForbidden, errors.CodeImpersonationForbidden)
+	expectErrorCode(t, ta.Request(http.MethodPut, "/api/v1/roles/user/permissions", map[string][]string{"permissions": {models.PermissionUsersView}}, token), http.StatusForbidden, errors.CodeImpersonationForbidden)
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/admin/impersonate/"+other.ID.String(), nil, token), http.StatusForbidden, errors.CodeImpersonationForbidden)
+
+	// Other changes are allowed
+	if resp := ta.Request(http.MethodPut, targetPath, map[string]string{"first_name": "Ann"}, token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected a name change to pass, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	// The token cannot be refreshed into a lasting one
+	if resp := ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": token}, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected refresh to be refused, got %d", resp.StatusCode)
+	}
+
+	if n := auditCount(t, ta, models.AuditActionUserImpersonated, admin, other.ID.String()); n != 0 {
+		t.Errorf("expected no audit entry for the refused impersonation, got %d", n)
+	}
+}
+
+// TestImpersonationRestrictions tests who may be impersonated and by whom
+func TestImpersonationRestrictions(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	otherAdmin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	path := "/api/v1/admin/impersonate/"
+	expectErrorCode(t, ta.Request(http.MethodPost, path+otherAdmin.ID.String(), nil, admin.Token), http.StatusForbidden, errors.CodeAdminImpersonationDisabled)
+	expectErrorCode(t, ta.Request(http.MethodPost, path+admin.ID.String(), nil, admin.Token), http.StatusBadRequest, errors.CodeSelfImpersonation)
+	expectErrorCode(t, ta.Request(http.MethodPost, path+"00000000-0000-0000-0000-000000000000", nil, admin.Token), http.StatusNotFound, errors.CodeUserNotFound)
+	expectErrorCode(t, ta.Request(http.MethodPost, path+admin.ID.String(), nil, user.Token), http.StatusForbidden, errors.CodeInsufficientPermissions)
+
+	// Deactivating the admin ends their impersonations
+	token := impersonate(t, ta, admin, user)
+	if resp := ta.Request(http.MethodPost, "/api/v1/users/"+admin.ID.String()+"/deactivate", nil, otherAdmin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("deactivate: %d %s", resp.StatusCode, resp.Body)
+	}
+	if resp := ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String()+"/activity", nil, token); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the impersonation token to stop working, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// moduleRoot is the repository root relative to the tests package
+const moduleRoot = ".."
+
+// modulePath reads the module path from go.mod
+func modulePath(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(moduleRoot, "go.mod"))
+	if err != nil {
+		t.Fatalf("read go.mod: %v", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.TrimSpace(rest)
+		}
+	}
+	t.Fatal("go.mod has no module directive")
+	return ""
+}
+
+// sourceImports maps every Go file in the module to its import paths
+func sourceImports(t *testing.T) map[string][]string {
+	t.Helper()
+	imports := make(map[string][]string)
+	fset := token.NewFileSet()
+
+	err := filepath.WalkDir(moduleRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != moduleRoot && (strings.HasPrefix(name, ".") || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(moduleRoot, path)
+		for _, spec := range file.Imports {
+			importPath, _ := strconv.Unquote(spec.Path.Value)
+			imports[filepath.ToSlash(rel)] = append(imports[filepath.ToSlash(rel)], importPath)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk source tree: %v", err)
+	}
+	return imports
+}
+
+// TestImportsUseModulePath tests that module packages are only imported through the go.mod module path
+func TestImportsUseModulePath(t *testing.T) {
+	module := modulePath(t)
+	imports := sourceImports(t)
+
+	// Directories holding Go files are the module's packages
+	packages := make(map[string]bool)
+	for file := range imports {
+		packages[filepath.ToSlash(filepath.Dir(file))] = true
+	}
+
+	for file, paths := range imports {
+		for _, importPath := range paths {
+			if importPath == module || strings.HasPrefix(importPath, module+"/") {
+				continue
+			}
+			for pkg := range packages {
+				if pkg != "." && strings.HasSuffix(importPath, "/"+pkg) {
+					t.Errorf("%s imports %q; use %q", file, importPath, module+"/"+pkg)
+				}
+			}
+		}
+	}
+}
+
+// TestConfigHasNoInternalImports tests that config stays a leaf package so
+// any internal package can depend on it without import cycles
+func TestConfigHasNoInternalImports(t *testing.T) {
+	module := modulePath(t)
+	for file, paths := range sourceImports(t) {
+		if !strings.HasPrefix(file, "config/") {
+			continue
+		}
+		for _, importPath := range paths {
+			if strings.HasPrefix(importPath, module+"/") {
+				t.Errorf("%s imports %q; config must not depend on module packages", file, importPath)
+			}
+		}
+	}
+}
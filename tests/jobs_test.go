@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/pkg/jobs"
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+// blockingJob registers a job that signals when it starts and runs until released
+func blockingJob(t *testing.T, s *jobs.Scheduler, name string) (started <-chan struct{}, release chan<- struct{}) {
+	t.Helper()
+
+	startedCh := make(chan struct{}, 1)
+	releaseCh := make(chan struct{})
+	job := jobs.NewFunc(name, "@every 1h", func(ctx context.Context) error {
+		startedCh <- struct{}{}
+		select {
+		case <-releaseCh:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+	if err := s.Register(job); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return startedCh, releaseCh
+}
+
+// waitStarted waits for a blocking job to start
+func waitStarted(t *testing.T, started <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job did not start")
+	}
+}
+
+// TestJobOverlapPrevented tests that a job never overlaps with itself
+func TestJobOverlapPrevented(t *testing.T) {
+	s := jobs.NewScheduler(jobs.NewMemoryLocker(), logger.NewSimpleLogger())
+	started, release := blockingJob(t, s, "cleanup")
+
+	if err := s.RunNow("cleanup"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitStarted(t, started)
+
+	if _, err := s.Run("cleanup"); !errors.Is(err, jobs.ErrJobRunning) {
+		t.Fatalf("expected ErrJobRunning, got %v", err)
+	}
+	if err := s.RunNow("cleanup"); !errors.Is(err, jobs.ErrJobRunning) {
+		t.Fatalf("expected ErrJobRunning from RunNow, got %v", err)
+	}
+
+	close(release)
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	statuses := s.Statuses()
+	if len(statuses) != 1 || statuses[0].LastOutcome != jobs.OutcomeSuccess || statuses[0].Running {
+		t.Fatalf("expected one successful run, got %+v", statuses)
+	}
+}
+
+// TestJobLockedByAnotherInstance tests that a shared lock prevents double runs across replicas
+func TestJobLockedByAnotherInstance(t *testing.T) {
+	locker := jobs.NewMemoryLocker()
+	replicaA := jobs.NewScheduler(locker, logger.NewSimpleLogger())
+	replicaB := jobs.NewScheduler(locker, logger.NewSimpleLogger())
+
+	started, release := blockingJob(t, replicaA, "cleanup")
+	ranOnB := false
+	if err := replicaB.Register(jobs.NewFunc("cleanup", "@every 1h", func(ctx context.Context) error {
+		ranOnB = true
+		return nil
+	})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := replicaA.RunNow("cleanup"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitStarted(t, started)
+
+	status, err := replicaB.Run("cleanup")
+	if !errors.Is(err, jobs.ErrJobLocked) {
+		t.Fatalf("expected ErrJobLocked, got %v", err)
+	}
+	if status.LastOutcome != jobs.OutcomeSkipped || ranOnB {
+		t.Fatalf("expected skipped run on replica B, got %+v (ran %v)", status, ranOnB)
+	}
+
+	close(release)
+	if err := replicaA.Stop(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Once released, the other replica can take the lock
+	if _, err := replicaB.Run("cleanup"); err != nil || !ranOnB {
+		t.Fatalf("expected replica B to run after release, got %v", err)
+	}
+}
+
+// TestJobSchedulerStopWaitsForInFlightJob tests the shutdown deadline handling
+func TestJobSchedulerStopWaitsForInFlightJob(t *testing.T) {
+	s := jobs.NewScheduler(jobs.NewMemoryLocker(), logger.NewSimpleLogger())
+
+	cancelled := make(chan struct{})
+	started := make(chan struct{})
+	if err := s.Register(jobs.NewFunc("slow", "@every 1h", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := s.RunNow("slow"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("in-flight job was not cancelled after the deadline")
+	}
+
+	if err := s.RunNow("slow"); !errors.Is(err, jobs.ErrSchedulerStopped) {
+		t.Fatalf("expected ErrSchedulerStopped, got %v", err)
+	}
+}
+
+// TestJobRegistration tests schedule validation and duplicate names
+func TestJobRegistration(t *testing.T) {
+	s := jobs.NewScheduler(jobs.NewMemoryLocker(), logger.NewSimpleLogger())
+	noop := func(ctx context.Context) error { return nil }
+
+	if err := s.Register(jobs.NewFunc("bad", "every day", noop)); err == nil {
+		t.Fatal("expected invalid schedule to be rejected")
+	}
+	if err := s.Register(jobs.NewFunc("nightly", "0 3 * * *", noop)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Register(jobs.NewFunc("nightly", "0 4 * * *", noop)); !errors.Is(err, jobs.ErrDuplicateJob) {
+		t.Fatalf("expected ErrDuplicateJob, got %v", err)
+	}
+	if _, err := s.Run("missing"); !errors.Is(err, jobs.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+
+	failing := errors.New("boom")
+	if err := s.Register(jobs.NewFunc("failing", "@hourly", func(ctx context.Context) error { return failing })); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status, err := s.Run("failing")
+	if !errors.Is(err, failing) || status.LastOutcome != jobs.OutcomeFailed || status.LastError != "boom" {
+		t.Fatalf("expected failed status, got %+v (err %v)", status, err)
+	}
+}
@@ -0,0 +1,244 @@
+package tests
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/jwtutil"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+	"BackofficeGoService/internal/services"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// rotationConfig returns JWT settings whose tokens live at most an hour,
+// with a key file in a temporary directory
+func rotationConfig(t *testing.T) *config.Config {
+	return &config.Config{
+		JWT: config.JWTConfig{
+			Secret:            "rotation-test-original-secret",
+			Expiration:        time.Hour,
+			Issuer:            "rotation-test",
+			SecretFile:        filepath.Join(t.TempDir(), "jwt.json"),
+			SecretFileRefresh: time.Minute,
+		},
+	}
+}
+
+// TestJWTSecretRotation tests that tokens issued before a rotation keep
+// verifying during the grace window, counted as previous secret
+// verifications, and fail after it
+func TestJWTSecretRotation(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	cfg := rotationConfig(t)
+	db, _ := databasetest.NewManager(t, databasetest.WithSQLite(&models.User{}, &models.LoginEvent{}, &models.AuditLog{}))
+	store := cache.NewMemoryStore(cache.WithMemoryClock(fake))
+	log := logger.NewNopLogger()
+	audit := services.NewAuditService(db, log)
+	m := metrics.New()
+	auth := services.NewAuthService(db, cfg, store, services.NewTokenRevoker(store, time.Hour, services.WithRevokerClock(fake)), audit, nil, log,
+		services.WithAuthClock(fake), services.WithAuthMetrics(m.Business))
+	secrets := services.NewJWTSecretService(auth.Tokens(), cfg, audit, log, services.WithJWTSecretClock(fake))
+	ctx := context.Background()
+
+	if _, err := auth.Register(ctx, &services.CreateUserRequest{Email: "ann@example.com", Password: "secret123"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	before, err := auth.Login(ctx, "ann@example.com", "secret123", services.ClientInfo{})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	// A token outliving the grace window shows when the previous secret is dropped
+	longLived, err := jwtutil.NewManager(jwtutil.Settings{Secret: cfg.JWT.Secret, Issuer: cfg.JWT.Issuer}, nil, fake.Now).
+		GenerateFor("", jwt.MapClaims{"user_id": "x", "iat": fake.Now().Unix(), "exp": fake.Now().Add(48 * time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotation, err := secrets.Rotate(ctx, "admin-1", false)
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if want := fake.Now().Add(time.Hour); !rotation.PreviousValidUntil.Equal(want) {
+		t.Errorf("expected the previous secret to be accepted until %v, got %v", want, rotation.PreviousValidUntil)
+	}
+	keys, err := jwtutil.LoadKeys(cfg.JWT.SecretFile)
+	if err != nil {
+		t.Fatalf("load key file: %v", err)
+	}
+	if keys.Previous != cfg.JWT.Secret || keys.Current == cfg.JWT.Secret || len(keys.Current) < 32 {
+		t.Errorf("expected the key file to hold a new secret and the original as previous, got %+v", keys)
+	}
+	if info, err := os.Stat(cfg.JWT.SecretFile); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the key file to be readable by its owner only, got %v %v", info.Mode(), err)
+	}
+
+	fake.Advance(30 * time.Minute)
+	if _, err := auth.ValidateToken(ctx, before.Token); err != nil {
+		t.Errorf("expected a token issued before the rotation to verify in the grace window, got %v", err)
+	}
+	if _, err := auth.RefreshToken(ctx, before.Token); err != nil {
+		t.Errorf("expected a token issued before the rotation to refresh, got %v", err)
+	}
+	if n := testutil.ToFloat64(m.Business.PreviousSecretVerifications); n != 2 {
+		t.Errorf("expected 2 verifications with the previous secret, got %v", n)
+	}
+	after, err := auth.Login(ctx, "ann@example.com", "secret123", services.ClientInfo{})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if _, err := jwtutil.NewManager(jwtutil.Settings{Secret: keys.Current, Issuer: cfg.JWT.Issuer}, nil, fake.Now).VerifyFor("", after.Token); err != nil {
+		t.Errorf("expected new tokens to be signed with the new secret, got %v", err)
+	}
+
+	if _, err := secrets.Rotate(ctx, "admin-1", false); !stderrors.Is(err, jwtutil.ErrRotationInProgress) {
+		t.Errorf("expected rotating again in the grace window to be refused, got %v", err)
+	}
+	if _, err := auth.Tokens().VerifyFor("", longLived); err != nil {
+		t.Errorf("expected the previous secret to be accepted in the grace window, got %v", err)
+	}
+
+	fake.Advance(30 * time.Minute)
+	if _, err := auth.Tokens().VerifyFor("", longLived); !stderrors.Is(err, jwtutil.ErrInvalidToken) {
+		t.Errorf("expected the previous secret to be rejected after the grace window, got %v", err)
+	}
+	if _, err := auth.ValidateToken(ctx, before.Token); err == nil {
+		t.Error("expected a token issued before the rotation to fail after the grace window")
+	}
+	if _, err := secrets.Rotate(ctx, "admin-1", false); err != nil {
+		t.Errorf("expected rotating after the grace window to work, got %v", err)
+	}
+}
+
+// TestJWTKeyFileFollowed tests that an instance verifies tokens signed by
+// another one right after it rotated the shared key file, and signs with
+// the new secret once it reads the file again
+func TestJWTKeyFileFollowed(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	cfg := rotationConfig(t)
+	first := services.NewTokenManager(cfg.JWT, fake.Now)
+	second := services.NewTokenManager(cfg.JWT, fake.Now)
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{"user_id": "x", "iat": fake.Now().Unix(), "exp": fake.Now().Add(time.Hour).Unix()}
+	}
+
+	keys, err := jwtutil.RotateFile(cfg.JWT.SecretFile, cfg.JWT.Secret, fake.Now(), time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.SetKeys(keys)
+	fake.Advance(2 * time.Second)
+
+	token, err := first.GenerateFor("", claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.VerifyFor("", token); err != nil {
+		t.Errorf("expected a token signed with the rotated secret to verify elsewhere at once, got %v", err)
+	}
+
+	fake.Advance(time.Minute)
+	token, err = second.GenerateFor("", claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwtutil.NewManager(jwtutil.Settings{Secret: keys.Current, Issuer: cfg.JWT.Issuer}, nil, fake.Now).VerifyFor("", token); err != nil {
+		t.Errorf("expected the other instance to sign with the new secret once it read the file, got %v", err)
+	}
+}
+
+// TestJWTSecretRotationEndpoint tests rotating the secret through the admin API
+func TestJWTSecretRotationEndpoint(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "jwt.json")
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.JWT.SecretFile = keyFile
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/admin/jwt/rotate-secret", nil, user.Token), http.StatusForbidden, errors.CodeInsufficientPermissions)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/admin/jwt/rotate-secret", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the secret to rotate, got %d %s", resp.StatusCode, resp.Body)
+	}
+	keys, err := jwtutil.LoadKeys(keyFile)
+	if err != nil {
+		t.Fatalf("load key file: %v", err)
+	}
+	if strings.Contains(string(resp.Body), keys.Current) || strings.Contains(string(resp.Body), keys.Previous) {
+		t.Errorf("expected the response not to reveal secrets, got %s", resp.Body)
+	}
+	if n := auditCount(t, ta, models.AuditActionJWTSecretRotated, admin, ""); n != 1 {
+		t.Errorf("expected the rotation to be audited, got %d", n)
+	}
+
+	// Both the tokens issued before and after keep working
+	if resp := ta.Request(http.MethodGet, "/api/v1/me", nil, user.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected a token issued before the rotation to keep working, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if resp := ta.Request(http.MethodGet, "/api/v1/me", nil, ta.Login(user.Email, user.Password)); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected a token issued after the rotation to work, got %d %s", resp.StatusCode, resp.Body)
+	}
+
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/admin/jwt/rotate-secret", nil, admin.Token), http.StatusConflict, errors.CodeJWTRotationInProgress)
+	if resp := ta.Request(http.MethodPost, "/api/v1/admin/jwt/rotate-secret?force=true", nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected a forced rotation to work, got %d %s", resp.StatusCode, resp.Body)
+	}
+}
+
+// TestJWTSecretRotationNeedsKeyFile tests that the secret is not rotated
+// in memory only, which a restart would undo
+func TestJWTSecretRotationNeedsKeyFile(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/admin/jwt/rotate-secret", nil, admin.Token), http.StatusConflict, errors.CodeJWTKeyFileUnset)
+}
+
+// TestCLIRotateJWTSecret tests the rotate-jwt-secret command
+func TestCLIRotateJWTSecret(t *testing.T) {
+	cfg := newCLIConfig(t)
+	if res := runCLI(t, cfg, "", "rotate-jwt-secret"); res.code != 2 || !strings.Contains(res.stderr, "JWT_SECRET_FILE") {
+		t.Errorf("expected a usage error without a key file, got %+v", res)
+	}
+
+	cfg.JWT.SecretFile = filepath.Join(t.TempDir(), "jwt.json")
+	res := runCLI(t, cfg, "", "rotate-jwt-secret")
+	if res.code != 0 || !strings.Contains(res.stdout, "accepted until") {
+		t.Fatalf("expected the secret to rotate, got %+v", res)
+	}
+	keys, err := jwtutil.LoadKeys(cfg.JWT.SecretFile)
+	if err != nil {
+		t.Fatalf("load key file: %v", err)
+	}
+	if keys.Previous != cfg.JWT.Secret || strings.Contains(res.stdout, keys.Current) {
+		t.Errorf("expected JWT_SECRET to become the previous secret and the new one not to be printed, got %+v %q", keys, res.stdout)
+	}
+
+	if res := runCLI(t, cfg, "", "rotate-jwt-secret"); res.code != 1 || !strings.Contains(res.stderr, "--force") {
+		t.Errorf("expected rotating again in the grace window to fail, got %+v", res)
+	}
+	if res := runCLI(t, cfg, "", "rotate-jwt-secret", "--force"); res.code != 0 {
+		t.Errorf("expected a forced rotation to work, got %+v", res)
+	}
+	forced, _ := jwtutil.LoadKeys(cfg.JWT.SecretFile)
+	if forced.Previous != keys.Current {
+		t.Errorf("expected the forced rotation to keep the secret it replaced, got %+v", forced)
+	}
+}
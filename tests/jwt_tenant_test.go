@@ -0,0 +1,218 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/jwtutil"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+)
+
+// userClaims are the claims of a token for userID issued now
+func userClaims(userID string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"user_id":       userID,
+		"exp":           now.Add(time.Hour).Unix(),
+		"iat":           now.Unix(),
+		"token_version": 0,
+	}
+}
+
+// TestTokenManager tests issuing and verifying tokens with tenant settings
+func TestTokenManager(t *testing.T) {
+	defaults := jwtutil.Settings{Secret: "default-secret", Issuer: "backoffice", Audience: []string{"backoffice-api", "backoffice-legacy"}}
+	tokens := jwtutil.NewManager(defaults, map[string]jwtutil.Settings{
+		"acme": {Secret: "acme-secret", Issuer: "acme-idp", Audience: []string{"acme-api"}},
+		// Inherits the default issuer and audiences
+		"globex": {Secret: "globex-secret"},
+	}, time.Now)
+
+	for _, tenant := range []string{"", "acme", "globex"} {
+		token, err := tokens.GenerateFor(tenant, userClaims("u1"))
+		if err != nil {
+			t.Fatalf("generate for %q: %v", tenant, err)
+		}
+		claims, err := tokens.VerifyFor(tenant, token)
+		if err != nil {
+			t.Fatalf("verify for %q: %v", tenant, err)
+		}
+		settings := tokens.SettingsFor(tenant)
+		if claims["iss"] != settings.Issuer || claims["aud"] != settings.Audience[0] {
+			t.Errorf("%q: expected issuer %s and audience %s, got %v and %v", tenant, settings.Issuer, settings.Audience[0], claims["iss"], claims["aud"])
+		}
+		if got, _ := claims[jwtutil.TenantClaim].(string); got != tenant {
+			t.Errorf("expected tenant claim %q, got %q", tenant, got)
+		}
+
+		// No other tenant accepts the token
+		for _, other := range []string{"", "acme", "globex"} {
+			if other == tenant {
+				continue
+			}
+			if _, err := tokens.VerifyFor(other, token); err == nil {
+				t.Errorf("expected a token for %q rejected for %q", tenant, other)
+			}
+		}
+	}
+
+	// Any accepted audience passes, others and a missing one fail
+	for aud, valid := range map[string]bool{"backoffice-legacy": true, "acme-api": false, "": false} {
+		claims := userClaims("u1")
+		claims["iss"] = "backoffice"
+		if aud != "" {
+			claims["aud"] = aud
+		}
+		token, _ := jwt.NewWithClaims(jwtutil.SigningMethod, claims).SignedString([]byte("default-secret"))
+		if _, err := tokens.VerifyFor("", token); (err == nil) != valid {
+			t.Errorf("audience %q: expected valid=%v, got %v", aud, valid, err)
+		}
+	}
+
+	// A tenant claim signed with another tenant's secret does not verify
+	forged, _ := jwtutil.NewManager(jwtutil.Settings{Secret: "globex-secret", Issuer: "acme-idp", Audience: []string{"acme-api"}}, nil, time.Now).
+		GenerateFor("acme", userClaims("u1"))
+	if _, err := tokens.VerifyFor("acme", forged); !errors.Is(err, jwtutil.ErrInvalidToken) {
+		t.Errorf("expected a forged token rejected, got %v", err)
+	}
+
+	// Tenants sharing the default secret still only accept their own tokens
+	shared := jwtutil.NewManager(jwtutil.Settings{Secret: "default-secret", Issuer: "backoffice"}, nil, time.Now)
+	token, _ := shared.GenerateFor("acme", userClaims("u1"))
+	if _, err := shared.VerifyFor("globex", token); !errors.Is(err, jwtutil.ErrTenantMismatch) {
+		t.Errorf("expected a tenant mismatch, got %v", err)
+	}
+
+	expired := userClaims("u1")
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	token, _ = tokens.GenerateFor("acme", expired)
+	if _, err := tokens.VerifyFor("acme", token); !errors.Is(err, jwtutil.ErrTokenExpired) {
+		t.Errorf("expected an expired token, got %v", err)
+	}
+}
+
+// hostRequest sends an authenticated GET for path to host
+func hostRequest(t *testing.T, ta *apptest.TestApp, host, tenant, path, token string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, ta.Server.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = host
+	if tenant != "" {
+		req.Header.Set(middleware.TenantHeader, tenant)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := ta.Server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// TestCrossTenantTokenReplay tests that a token issued in one tenant is
+// rejected wherever the request resolves to another
+func TestCrossTenantTokenReplay(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		tenantDB := config.DatabaseConnectionConfig{Driver: string(database.DriverSQLite), DBName: ":memory:", UseGorm: true, Required: true}
+		cfg.Database.Databases = map[string]config.DatabaseConnectionConfig{"acme_db": tenantDB, "globex_db": tenantDB}
+		cfg.Database.Tenants = map[string]string{"acme": "acme_db", "globex": "globex_db"}
+		cfg.Database.TenantHosts = map[string]string{"acme.example.com": "acme", "globex.example.com": "globex"}
+		cfg.JWT.Audience = []string{"backoffice-api"}
+		cfg.JWT.Tenants = map[string]config.JWTTenantConfig{
+			"acme": {Secret: "acme-secret-key-that-is-long-enough", Issuer: "acme-idp", Audience: []string{"acme-api"}},
+		}
+	})
+	acmeAdmin, acmeToken := seedTenantAdmin(t, ta, "acme", "admin@example.com")
+	globexAdmin, globexToken := seedTenantAdmin(t, ta, "globex", "admin@example.com")
+
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(acmeToken, claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["iss"] != "acme-idp" || claims["aud"] != "acme-api" {
+		t.Errorf("expected the acme issuer and audience, got %v and %v", claims["iss"], claims["aud"])
+	}
+
+	acmeUser := "/api/v1/users/" + acmeAdmin.ID.String()
+	globexUser := "/api/v1/users/" + globexAdmin.ID.String()
+	cases := []struct {
+		name   string
+		host   string
+		tenant string
+		path   string
+		token  string
+		want   int
+	}{
+		{"own host", "acme.example.com", "", acmeUser, acmeToken, http.StatusOK},
+		{"own header", "localhost", "globex", globexUser, globexToken, http.StatusOK},
+		{"acme token on the globex host", "globex.example.com", "", globexUser, acmeToken, http.StatusUnauthorized},
+		{"globex token on the acme host", "ACME.example.com:8080", "", acmeUser, globexToken, http.StatusUnauthorized},
+		{"acme token for the globex header", "localhost", "globex", globexUser, acmeToken, http.StatusUnauthorized},
+		{"globex token for the acme header", "localhost", "acme", acmeUser, globexToken, http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if status := hostRequest(t, ta, tc.host, tc.tenant, tc.path, tc.token); status != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, status)
+			}
+		})
+	}
+
+	// A token naming acme but signed with the shared secret, as globex's
+	// are, is refused by acme
+	forged, err := jwtutil.NewManager(jwtutil.Settings{Secret: "apptest-secret-key-that-is-long-enough", Issuer: "acme-idp", Audience: []string{"acme-api"}}, nil, time.Now).
+		GenerateFor("acme", userClaims(acmeAdmin.ID.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := hostRequest(t, ta, "acme.example.com", "", acmeUser, forged); status != http.StatusUnauthorized {
+		t.Errorf("expected a forged acme token rejected, got %d", status)
+	}
+}
+
+// TestJWTTenantConfig tests that tenant token settings need their own secret
+func TestJWTTenantConfig(t *testing.T) {
+	cfg := config.JWTConfig{Secret: "default", Tenants: map[string]config.JWTTenantConfig{
+		"acme": {Secret: "acme-secret"},
+	}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a valid config, got %v", err)
+	}
+	cfg.Tenants["globex"] = config.JWTTenantConfig{Issuer: "globex-idp"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "globex") {
+		t.Errorf("expected globex's missing secret reported, got %v", err)
+	}
+
+	t.Setenv("JWT_AUDIENCE", "backoffice-api, backoffice-legacy")
+	viper.Set("jwt.tenants", map[string]interface{}{"acme": map[string]interface{}{"issuer": "acme-idp"}})
+	t.Cleanup(func() { viper.Set("jwt.tenants", nil) })
+	if _, err := config.LoadConfig(); err == nil || !strings.Contains(err.Error(), "acme has no secret") {
+		t.Errorf("expected loading to fail without acme's secret, got %v", err)
+	}
+
+	viper.Set("jwt.tenants", map[string]interface{}{"acme": map[string]interface{}{"secret": "acme-secret", "audience": []string{"acme-api"}}})
+	loaded, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := strings.Join(loaded.JWT.Audience, ","); got != "backoffice-api,backoffice-legacy" {
+		t.Errorf("expected both audiences, got %s", got)
+	}
+	if acme := loaded.JWT.Tenants["acme"]; acme.Secret != "acme-secret" || len(acme.Audience) != 1 || acme.Audience[0] != "acme-api" {
+		t.Errorf("unexpected acme settings %+v", acme)
+	}
+	if redacted := loaded.Redacted().JWT.Tenants["acme"].Secret; redacted != config.RedactedValue {
+		t.Errorf("expected the tenant secret redacted, got %s", redacted)
+	}
+}
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/pkg/lifecycle"
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+// stopRecorder records the order and time components are stopped in
+type stopRecorder struct {
+	mu     sync.Mutex
+	names  []string
+	times  []time.Time
+	states []bool
+	life   *lifecycle.Lifecycle
+}
+
+func (r *stopRecorder) record(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = append(r.names, name)
+	r.times = append(r.times, time.Now())
+	r.states = append(r.states, r.life.Ready())
+}
+
+func (r *stopRecorder) stopper(name string, err error) lifecycle.Stopper {
+	return lifecycle.StopFunc(func(ctx context.Context) error {
+		r.record(name)
+		return err
+	})
+}
+
+func (r *stopRecorder) closer(name string) io.Closer {
+	return lifecycle.CloseFunc(func() error {
+		r.record(name)
+		return nil
+	})
+}
+
+func TestLifecycleShutdownOrder(t *testing.T) {
+	const drainDelay = 50 * time.Millisecond
+	life := lifecycle.New(drainDelay, logger.NewSimpleLogger())
+	rec := &stopRecorder{life: life}
+
+	life.AddCloser("logger", rec.closer("logger"))
+	life.AddCloser("databases", rec.closer("databases"))
+	life.AddServer("http", rec.stopper("http", nil))
+	life.AddServer("grpc", rec.stopper("grpc", nil))
+	life.AddWorker("webhooks", rec.stopper("webhooks", nil))
+	life.AddWorker("jobs", rec.stopper("jobs", nil))
+	life.SetReady()
+
+	start := time.Now()
+	if err := life.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	want := []string{"http", "grpc", "jobs", "webhooks", "databases", "logger"}
+	if len(rec.names) != len(want) {
+		t.Fatalf("expected %v, got %v", want, rec.names)
+	}
+	for i := range want {
+		if rec.names[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, rec.names)
+		}
+		if rec.states[i] {
+			t.Errorf("%s stopped while still reporting ready", rec.names[i])
+		}
+		if i > 0 && rec.times[i].Before(rec.times[i-1]) {
+			t.Errorf("%s stopped before %s", rec.names[i], rec.names[i-1])
+		}
+	}
+	if waited := rec.times[0].Sub(start); waited < drainDelay {
+		t.Errorf("servers shut after %v, before the %v drain delay", waited, drainDelay)
+	}
+
+	// A second shutdown is a no-op
+	if err := life.Shutdown(context.Background()); err != nil || len(rec.names) != len(want) {
+		t.Errorf("second shutdown should do nothing, got err=%v names=%v", err, rec.names)
+	}
+}
+
+func TestLifecycleShutdownContinuesAfterErrors(t *testing.T) {
+	logs := logger.NewCaptureLogger()
+	life := lifecycle.New(0, logs)
+	rec := &stopRecorder{life: life}
+	failure := errors.New("worker stuck")
+
+	life.AddCloser("databases", rec.closer("databases"))
+	life.AddServer("http", rec.stopper("http", nil))
+	life.AddWorker("jobs", rec.stopper("jobs", failure))
+
+	err := life.Shutdown(context.Background())
+	if !errors.Is(err, failure) {
+		t.Errorf("expected worker error, got %v", err)
+	}
+	if len(rec.names) != 3 || rec.names[2] != "databases" {
+		t.Errorf("expected every component to stop, got %v", rec.names)
+	}
+
+	errs := logs.FilterByLevel(logger.LevelError)
+	if len(errs) != 1 || errs[0].Message != "Error stopping component" {
+		t.Fatalf("expected one stop error to be logged, got %+v", errs)
+	}
+	if component, _ := errs[0].Field("component"); component != "jobs" {
+		t.Errorf("expected the failing component to be logged, got %v", component)
+	}
+}
+
+func TestLifecycleInFlightRequestCompletes(t *testing.T) {
+	life := lifecycle.New(100*time.Millisecond, logger.NewSimpleLogger())
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if !life.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &http.Server{Handler: mux}
+	life.AddServer("http", lifecycle.StopFunc(server.Shutdown))
+	go server.Serve(lis)
+	life.SetReady()
+
+	base := "http://" + lis.Addr().String()
+	slow := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			t.Errorf("in-flight request failed: %v", err)
+			close(slow)
+			return
+		}
+		slow <- resp
+	}()
+	<-entered
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr <- life.Shutdown(ctx)
+	}()
+
+	// During the drain delay the server still answers, but reports not ready
+	deadline := time.Now().Add(time.Second)
+	for life.Ready() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	resp, err := http.Get(base + "/ready")
+	if err != nil {
+		t.Fatalf("readiness probe during drain: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 from readiness during drain, got %d", resp.StatusCode)
+	}
+
+	close(release)
+	if resp := <-slow; resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected in-flight request to complete with 200, got %v", resp)
+	} else {
+		resp.Body.Close()
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+}
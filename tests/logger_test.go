@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/pkg/logger"
+)
+
+func TestCaptureLoggerConcurrent(t *testing.T) {
+	logs := logger.NewCaptureLogger()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			logs.Info("worker done", logger.Field{Key: "worker", Value: i})
+		}(i)
+	}
+	wg.Wait()
+
+	if got := len(logs.Entries()); got != 20 {
+		t.Errorf("expected 20 entries, got %d", got)
+	}
+}
+
+func TestCaptureLoggerLevels(t *testing.T) {
+	logs := logger.NewCaptureLogger()
+	logs.Debug("debugging")
+	logs.Info("started")
+	logs.Warn("slow query")
+	logs.Error("query failed")
+	logs.Fatal("giving up")
+
+	cases := []struct {
+		level logger.Level
+		msg   string
+	}{
+		{logger.LevelDebug, "debugging"},
+		{logger.LevelInfo, "started"},
+		{logger.LevelWarn, "slow query"},
+		{logger.LevelError, "query failed"},
+		{logger.LevelFatal, "giving up"},
+	}
+	for _, tc := range cases {
+		entries := logs.FilterByLevel(tc.level)
+		if len(entries) != 1 || entries[0].Message != tc.msg {
+			t.Errorf("%s: expected %q, got %+v", tc.level, tc.msg, entries)
+		}
+	}
+
+	if !logs.Contains("slow") || logs.Contains("missing") {
+		t.Error("Contains should match message substrings only")
+	}
+}
+
+func TestLoggerWith(t *testing.T) {
+	logs := logger.NewCaptureLogger()
+	request := logs.With(logger.Field{Key: "request_id", Value: "r-1"})
+	user := request.With(logger.Field{Key: "user_id", Value: "u-1"})
+
+	user.Info("loaded", logger.Field{Key: "count", Value: 3})
+	request.Warn("retrying")
+	logs.Info("unscoped")
+
+	entries := logs.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+
+	keys := func(e logger.Entry) string {
+		var names []string
+		for _, f := range e.Fields {
+			names = append(names, f.Key)
+		}
+		return strings.Join(names, ",")
+	}
+	if got := keys(entries[0]); got != "request_id,user_id,count" {
+		t.Errorf("expected scoped fields before call fields, got %s", got)
+	}
+	if got := keys(entries[1]); got != "request_id" {
+		t.Errorf("expected only the parent's fields, got %s", got)
+	}
+	if got := keys(entries[2]); got != "" {
+		t.Errorf("expected no fields on the root logger, got %s", got)
+	}
+}
+
+// TestRequestLogRedactsStreamToken tests that the access log never contains a query token
+func TestRequestLogRedactsStreamToken(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+
+	ta.Request(http.MethodGet, "/health?access_token=secret-token&verbose=1", nil, "")
+
+	for _, entry := range ta.Logs.Entries() {
+		if entry.Message != "HTTP Request" {
+			continue
+		}
+		path, _ := entry.Field("path")
+		logged := fmt.Sprint(path)
+		if strings.Contains(logged, "secret-token") || !strings.Contains(logged, "access_token=REDACTED") {
+			t.Errorf("expected the token to be redacted, got %q", logged)
+		}
+		return
+	}
+	t.Fatal("expected the request to be logged")
+}
+
+// TestOptionalDatabaseUnavailableLogsWarning tests that a missing optional database only warns
+func TestOptionalDatabaseUnavailableLogsWarning(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Database.Databases = map[string]config.DatabaseConnectionConfig{
+			"analytics": {Driver: "sqlite", DBName: t.TempDir() + "/missing/analytics.db", UseGorm: true},
+		}
+	})
+
+	for _, entry := range ta.Logs.FilterByLevel(logger.LevelWarn) {
+		if strings.HasPrefix(entry.Message, "Optional database unavailable") {
+			if name, _ := entry.Field("database"); name != "analytics" {
+				t.Errorf("expected the analytics database in the warning, got %v", name)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected an optional database warning, got %+v", ta.Logs.Entries())
+}
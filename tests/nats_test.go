@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	natsmessaging "BackofficeGoService/internal/infrastructure/messaging/nats"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/logger"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+)
+
+// runNATS starts an embedded JetStream server for the test
+func runNATS(t *testing.T) *server.Server {
+	t.Helper()
+
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	srv := natsserver.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+// natsConfig returns a config for srv with short timeouts
+func natsConfig(srv *server.Server) config.NATSConfig {
+	return config.NATSConfig{
+		URLs:            srv.ClientURL(),
+		Name:            "test",
+		ConnectTimeout:  5 * time.Second,
+		DrainTimeout:    5 * time.Second,
+		Stream:          "TEST_EVENTS",
+		SubjectPrefix:   "test.events",
+		MaxAge:          time.Hour,
+		DuplicateWindow: time.Minute,
+		Consumer:        "test",
+		MaxDeliver:      3,
+		AckWait:         5 * time.Second,
+	}
+}
+
+// connectNATS connects a client that is closed with the test
+func connectNATS(t *testing.T, cfg config.NATSConfig, log logger.Logger) *natsmessaging.Client {
+	t.Helper()
+
+	client, err := natsmessaging.Connect(context.Background(), cfg, log)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// startConsumer starts a consumer that is stopped with the test
+func startConsumer(t *testing.T, client *natsmessaging.Client, handler natsmessaging.Handler, log logger.Logger) *natsmessaging.Consumer {
+	t.Helper()
+
+	consumer, err := natsmessaging.NewConsumer(context.Background(), client, handler, log)
+	if err != nil {
+		t.Fatalf("consumer: %v", err)
+	}
+	if err := consumer.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() { _ = consumer.Stop(context.Background()) })
+	return consumer
+}
+
+// deliveryLog counts the deliveries of each event
+type deliveryLog struct {
+	mu       sync.Mutex
+	attempts map[string]int
+	received chan events.Event
+}
+
+func newDeliveryLog() *deliveryLog {
+	return &deliveryLog{attempts: make(map[string]int), received: make(chan events.Event, 16)}
+}
+
+func (d *deliveryLog) record(event events.Event) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.attempts[event.ID]++
+	return d.attempts[event.ID]
+}
+
+func (d *deliveryLog) count(id string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.attempts[id]
+}
+
+// waitForEvent returns the next handled event
+func waitForEvent(t *testing.T, received <-chan events.Event) events.Event {
+	t.Helper()
+
+	select {
+	case event := <-received:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received within 5s")
+		return events.Event{}
+	}
+}
+
+// TestNATSPublishConsume tests delivery and deduplication by event ID
+func TestNATSPublishConsume(t *testing.T) {
+	cfg := natsConfig(runNATS(t))
+	log := logger.NewNopLogger()
+	client := connectNATS(t, cfg, log)
+
+	deliveries := newDeliveryLog()
+	startConsumer(t, client, func(ctx context.Context, event events.Event) error {
+		deliveries.record(event)
+		deliveries.received <- event
+		return nil
+	}, log)
+
+	created := events.New(events.UserCreated, map[string]string{"id": "42"})
+	for i := 0; i < 2; i++ {
+		// A retried publish of the same event is stored once
+		if err := client.Publish(context.Background(), created); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	deleted := events.New(events.UserDeleted, map[string]string{"id": "42"})
+	if err := client.Publish(context.Background(), deleted); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	first := waitForEvent(t, deliveries.received)
+	if first.ID != created.ID || first.Type != events.UserCreated {
+		t.Errorf("expected %s first, got %+v", created.ID, first)
+	}
+	if data, ok := first.Data.(map[string]interface{}); !ok || data["id"] != "42" {
+		t.Errorf("expected the event data to survive, got %#v", first.Data)
+	}
+	if second := waitForEvent(t, deliveries.received); second.ID != deleted.ID {
+		t.Errorf("expected %s second, got %s", deleted.ID, second.ID)
+	}
+
+	select {
+	case event := <-deliveries.received:
+		t.Errorf("unexpected delivery of %s", event.ID)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if err := client.Health(context.Background()); err != nil {
+		t.Errorf("expected a healthy connection, got %v", err)
+	}
+}
+
+// TestNATSRedelivery tests that failed events are redelivered up to MaxDeliver times
+func TestNATSRedelivery(t *testing.T) {
+	cfg := natsConfig(runNATS(t))
+	logs := logger.NewCaptureLogger()
+	client := connectNATS(t, cfg, logs)
+
+	flaky := events.New(events.UserUpdated, nil)
+	broken := events.New(events.UserDeactivated, nil)
+	deliveries := newDeliveryLog()
+	startConsumer(t, client, func(ctx context.Context, event events.Event) error {
+		attempt := deliveries.record(event)
+		if event.ID == broken.ID || attempt == 1 {
+			deliveries.received <- event
+			return errors.New("temporary failure")
+		}
+		deliveries.received <- event
+		return nil
+	}, logs)
+
+	for _, event := range []events.Event{flaky, broken} {
+		if err := client.Publish(context.Background(), event); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	// One failed and one successful delivery of flaky, MaxDeliver deliveries of broken
+	for i := 0; i < 2+cfg.MaxDeliver; i++ {
+		waitForEvent(t, deliveries.received)
+	}
+	select {
+	case event := <-deliveries.received:
+		t.Errorf("unexpected extra delivery of %s", event.ID)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	if n := deliveries.count(flaky.ID); n != 2 {
+		t.Errorf("expected the flaky event to be handled twice, got %d", n)
+	}
+	if n := deliveries.count(broken.ID); n != cfg.MaxDeliver {
+		t.Errorf("expected the broken event to be delivered %d times, got %d", cfg.MaxDeliver, n)
+	}
+
+	gaveUp := false
+	for _, entry := range logs.Entries() {
+		if id, _ := entry.Field("event_id"); entry.Message == "Giving up on NATS event" && id == broken.ID {
+			gaveUp = true
+		}
+	}
+	if !gaveUp {
+		t.Error("expected giving up on the broken event to be logged")
+	}
+}
+
+// TestNATSDrain tests that stopping waits for the event being handled and
+// that closing drains the connection
+func TestNATSDrain(t *testing.T) {
+	cfg := natsConfig(runNATS(t))
+	log := logger.NewNopLogger()
+	client := connectNATS(t, cfg, log)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	deliveries := newDeliveryLog()
+	consumer := startConsumer(t, client, func(ctx context.Context, event events.Event) error {
+		if deliveries.record(event) == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	}, log)
+
+	event := events.New(events.UserCreated, nil)
+	if err := client.Publish(context.Background(), event); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- consumer.Stop(context.Background()) }()
+	select {
+	case err := <-stopped:
+		t.Fatalf("stop returned before the handler finished: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("stop: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop did not return after the handler finished")
+	}
+
+	// The event was acknowledged, so a new consumer on the durable gets nothing
+	again := newDeliveryLog()
+	startConsumer(t, client, func(ctx context.Context, event events.Event) error {
+		again.received <- event
+		return nil
+	}, log)
+	select {
+	case event := <-again.received:
+		t.Errorf("expected no redelivery after the drain, got %s", event.ID)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := client.Publish(context.Background(), events.New(events.UserUpdated, nil)); err == nil {
+		t.Error("expected publishing on a drained connection to fail")
+	}
+	if err := client.Health(context.Background()); err == nil {
+		t.Error("expected a closed connection to be unhealthy")
+	}
+}
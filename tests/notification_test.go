@@ -0,0 +1,298 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/controllers/admin"
+	"BackofficeGoService/internal/app/controllers/notification"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/jobs"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// fakeNotificationRepository is an in-memory NotificationRepository
+type fakeNotificationRepository struct {
+	mu            sync.Mutex
+	notifications map[uuid.UUID]*models.Notification
+}
+
+func newFakeNotificationRepository() *fakeNotificationRepository {
+	return &fakeNotificationRepository{notifications: make(map[uuid.UUID]*models.Notification)}
+}
+
+func (r *fakeNotificationRepository) Create(ctx context.Context, n *models.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifications[n.ID] = n
+	return nil
+}
+
+func (r *fakeNotificationRepository) List(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]*models.Notification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var list []*models.Notification
+	for _, n := range r.notifications {
+		if n.UserID == userID && (!unreadOnly || n.ReadAt == nil) {
+			list = append(list, n)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+
+	if offset >= len(list) {
+		return nil, nil
+	}
+	list = list[offset:]
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func (r *fakeNotificationRepository) Count(ctx context.Context, userID uuid.UUID, unreadOnly bool) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, n := range r.notifications {
+		if n.UserID == userID && (!unreadOnly || n.ReadAt == nil) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeNotificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, n := range r.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeNotificationRepository) MarkRead(ctx context.Context, userID, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.notifications[id]
+	if !ok || n.UserID != userID {
+		return services.ErrNotificationNotFound
+	}
+	if n.ReadAt == nil {
+		n.ReadAt = &at
+	}
+	return nil
+}
+
+func (r *fakeNotificationRepository) MarkAllRead(ctx context.Context, userID uuid.UUID, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var marked int64
+	for _, n := range r.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			n.ReadAt = &at
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (r *fakeNotificationRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, n := range r.notifications {
+		if n.CreatedAt.Before(cutoff) {
+			delete(r.notifications, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func newNotificationFixture() (*services.NotificationService, *fakeNotificationRepository) {
+	repo := newFakeNotificationRepository()
+	return services.NewNotificationService(repo, nil, logger.NewSimpleLogger()), repo
+}
+
+// notifyN sends n notifications to userID
+func notifyN(t *testing.T, svc *services.NotificationService, userID string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := svc.Notify(context.Background(), userID, &models.Notification{Type: "import.finished", Title: "Import finished"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+// TestNotificationReadTracking tests unread counts and marking notifications read
+func TestNotificationReadTracking(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newNotificationFixture()
+	userID, otherID := uuid.New().String(), uuid.New().String()
+
+	notifyN(t, svc, userID, 3)
+	notifyN(t, svc, otherID, 1)
+
+	list, unread, err := svc.ListNotifications(ctx, userID, false, 10, 0)
+	if err != nil || len(list.Items) != 3 || list.Total != 3 || unread != 3 {
+		t.Fatalf("expected 3 unread notifications, got %d/%d (err %v)", len(list.Items), unread, err)
+	}
+
+	// Users cannot touch each other's notifications
+	if err := svc.MarkRead(ctx, otherID, list.Items[0].ID.String()); !errors.Is(err, services.ErrNotificationNotFound) {
+		t.Fatalf("expected ErrNotificationNotFound for another user, got %v", err)
+	}
+	if err := svc.MarkRead(ctx, userID, list.Items[0].ID.String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	list, unread, _ = svc.ListNotifications(ctx, userID, true, 10, 0)
+	if len(list.Items) != 2 || list.Total != 2 || unread != 2 {
+		t.Fatalf("expected 2 unread after marking one, got %d/%d", len(list.Items), unread)
+	}
+
+	marked, err := svc.MarkAllRead(ctx, userID)
+	if err != nil || marked != 2 {
+		t.Fatalf("expected 2 marked, got %d (err %v)", marked, err)
+	}
+	if _, unread, _ := svc.ListNotifications(ctx, otherID, false, 10, 0); unread != 1 {
+		t.Fatalf("expected other user's notification to stay unread, got %d", unread)
+	}
+
+	if err := svc.Notify(ctx, userID, &models.Notification{Type: "import.finished"}); !errors.Is(err, services.ErrInvalidNotification) {
+		t.Fatalf("expected ErrInvalidNotification, got %v", err)
+	}
+}
+
+// TestNotificationPurge tests the retention cleanup
+func TestNotificationPurge(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newNotificationFixture()
+	userID := uuid.New().String()
+
+	notifyN(t, svc, userID, 2)
+	for _, n := range repo.notifications {
+		n.CreatedAt = time.Now().Add(-100 * 24 * time.Hour)
+		break
+	}
+
+	purged, err := svc.PurgeBefore(ctx, time.Now().Add(-90*24*time.Hour))
+	if err != nil || purged != 1 || len(repo.notifications) != 1 {
+		t.Fatalf("expected one purged notification, got %d (err %v)", purged, err)
+	}
+}
+
+// newNotificationRouter registers the notification routes behind fixed claims
+func newNotificationRouter(svc *services.NotificationService, userID string) *gin.Engine {
+	claims := &services.TokenClaims{UserID: userID, Role: string(models.RoleAdmin)}
+	return newClaimsRouter(claims, notification.NewNotificationController(svc, pagination.DefaultConfig).Routes())
+}
+
+// TestNotificationRoutes tests the list, read and read-all handlers
+func TestNotificationRoutes(t *testing.T) {
+	svc, _ := newNotificationFixture()
+	userID := uuid.New().String()
+	notifyN(t, svc, userID, 3)
+	router := newNotificationRouter(svc, userID)
+
+	var body struct {
+		Data        []models.Notification `json:"data"`
+		UnreadCount int64                 `json:"unread_count"`
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/me/notifications?limit=2", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(body.Data) != 2 || body.UnreadCount != 3 {
+		t.Fatalf("expected a page of 2 with 3 unread, got %d/%d", len(body.Data), body.UnreadCount)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/me/notifications/"+body.Data[0].ID.String()+"/read", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 marking read, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/me/notifications/"+uuid.New().String()+"/read", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown notification, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/me/notifications/read-all", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 marking all read, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/me/notifications?unread=true", nil))
+	body.Data = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Data) != 0 || body.UnreadCount != 0 {
+		t.Fatalf("expected no unread notifications, got %s", w.Body.String())
+	}
+}
+
+// TestJobRunNotifiesCaller tests that a manually started job notifies the admin who started it
+func TestJobRunNotifiesCaller(t *testing.T) {
+	svc, _ := newNotificationFixture()
+	scheduler := jobs.NewScheduler(jobs.NewMemoryLocker(), logger.NewSimpleLogger())
+	started := make(chan struct{})
+	if err := scheduler.Register(jobs.NewFunc("export", "@daily", func(ctx context.Context) error {
+		close(started)
+		return errors.New("disk full")
+	})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	adminID := uuid.New().String()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/jobs/:name/run", func(c *gin.Context) {
+		c.Set(middleware.ClaimsKey, &services.TokenClaims{UserID: adminID, Role: string(models.RoleAdmin)})
+		c.Next()
+	}, admin.NewJobsController(scheduler, svc).RunJob)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jobs/export/run", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", w.Code)
+	}
+
+	// Stop waits for the run and its completion callback
+	<-started
+	if err := scheduler.Stop(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	list, _, err := svc.ListNotifications(context.Background(), adminID, true, 10, 0)
+	if err != nil || len(list.Items) != 1 {
+		t.Fatalf("expected one notification, got %d (err %v)", len(list.Items), err)
+	}
+	if n := list.Items[0]; n.Type != models.NotificationTypeJobFinished || n.Title != "Job export failed" || n.Body != "disk full" {
+		t.Fatalf("unexpected notification %+v", n)
+	}
+}
@@ -0,0 +1,425 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcClientID     = "backoffice"
+	oidcClientSecret = "oidc-client-secret"
+	oidcRedirectURL  = "https://backoffice.example.com/auth/callback"
+)
+
+// mockOIDC is an OpenID Connect provider issuing ID tokens with the claims
+// each test asks for. Its token endpoint checks the client's secret and
+// PKCE verifier.
+type mockOIDC struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockCode
+}
+
+// mockCode is an authorization code and what redeeming it returns
+type mockCode struct {
+	challenge string
+	idToken   string
+}
+
+func newMockOIDC(t *testing.T) *mockOIDC {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockOIDC{t: t, key: key, codes: map[string]mockCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", m.token)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// token redeems a code once, for the client holding its PKCE verifier
+func (m *mockOIDC) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, _ := r.BasicAuth()
+	if id != oidcClientID || secret != oidcClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJSON(w, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.mu.Lock()
+	code, ok := m.codes[r.PostFormValue("code")]
+	delete(m.codes, r.PostFormValue("code"))
+	m.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge || r.PostFormValue("redirect_uri") != oidcRedirectURL {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	writeJSON(w, map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": code.idToken})
+}
+
+// provider returns the configuration of a provider backed by the mock
+func (m *mockOIDC) provider(adjust func(p *config.OIDCProviderConfig)) config.OIDCProviderConfig {
+	p := config.OIDCProviderConfig{
+		Issuer:       m.server.URL,
+		ClientID:     oidcClientID,
+		ClientSecret: oidcClientSecret,
+		RedirectURL:  oidcRedirectURL,
+	}
+	if adjust != nil {
+		adjust(&p)
+	}
+	return p
+}
+
+// claims returns valid ID token claims for subject, signed in with nonce
+func (m *mockOIDC) claims(subject, email, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            oidcClientID,
+		"sub":            subject,
+		"email":          email,
+		"email_verified": true,
+		"nonce":          nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+// authorize issues a code for the sign-in whose PKCE challenge is given,
+// redeemed for an ID token with claims signed by key
+func (m *mockOIDC) authorize(challenge string, claims jwt.MapClaims, key *rsa.PrivateKey) string {
+	m.t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	idToken, err := token.SignedString(key)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		m.t.Fatal(err)
+	}
+	code := hex.EncodeToString(buf)
+	m.mu.Lock()
+	m.codes[code] = mockCode{challenge: challenge, idToken: idToken}
+	m.mu.Unlock()
+	return code
+}
+
+// oidcSignIn is a sign-in started with a provider
+type oidcSignIn struct {
+	state, nonce, challenge string
+}
+
+// startOIDCSignIn requests the login redirect of provider and reads the
+// sign-in from it
+func startOIDCSignIn(t *testing.T, ta *apptest.TestApp, provider string) oidcSignIn {
+	t.Helper()
+
+	client := *ta.Server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(ta.Server.URL + "/api/v1/auth/oidc/" + provider + "/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("login: expected 302, got %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := location.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != oidcClientID || query.Get("redirect_uri") != oidcRedirectURL {
+		t.Fatalf("unexpected authorization request %s", location)
+	}
+	return oidcSignIn{state: query.Get("state"), nonce: query.Get("nonce"), challenge: query.Get("code_challenge")}
+}
+
+// completeOIDCSignIn sends the provider's callback with code and state
+func completeOIDCSignIn(ta *apptest.TestApp, provider, code, state string) *apptest.Response {
+	query := url.Values{"code": {code}, "state": {state}}
+	return ta.Request(http.MethodGet, "/api/v1/auth/oidc/"+provider+"/callback?"+query.Encode(), nil, "")
+}
+
+// oidcSignInAs signs in with provider as the holder of claims, which may
+// adjust the valid claims of subject before they are signed
+func oidcSignInAs(t *testing.T, ta *apptest.TestApp, m *mockOIDC, provider, subject, email string, adjust func(jwt.MapClaims)) *apptest.Response {
+	t.Helper()
+
+	signIn := startOIDCSignIn(t, ta, provider)
+	claims := m.claims(subject, email, signIn.nonce)
+	if adjust != nil {
+		adjust(claims)
+	}
+	return completeOIDCSignIn(ta, provider, m.authorize(signIn.challenge, claims, m.key), signIn.state)
+}
+
+// withOIDCProviders configures the providers of the test app
+func withOIDCProviders(providers map[string]config.OIDCProviderConfig) apptest.Option {
+	return func(cfg *config.Config) {
+		cfg.OIDC.Providers = providers
+	}
+}
+
+// decodeLogin decodes a successful login response
+func decodeLogin(t *testing.T, resp *apptest.Response) services.AuthResult {
+	t.Helper()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var result services.AuthResult
+	resp.Decode(t, &result)
+	if result.Token == "" || result.User == nil {
+		t.Fatalf("expected a token and user: %s", resp.Body)
+	}
+	return result
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// TestOIDCProvisionsUsers tests that a first sign-in creates the user with
+// the role of their groups, and later ones reuse it without syncing roles
+func TestOIDCProvisionsUsers(t *testing.T) {
+	m := newMockOIDC(t)
+	ta := apptest.NewTestApp(t, withOIDCProviders(map[string]config.OIDCProviderConfig{
+		"corp": m.provider(func(p *config.OIDCProviderConfig) {
+			p.Provision = true
+			p.GroupRoles = []config.OIDCGroupRole{{Group: "backoffice-admins", Role: "admin"}}
+			p.DefaultRole = "guest"
+		}),
+	}))
+
+	first := decodeLogin(t, oidcSignInAs(t, ta, m, "corp", "sub-1", "Ada@Example.com", func(c jwt.MapClaims) {
+		c["given_name"], c["family_name"] = "Ada", "Lovelace"
+		c["groups"] = []string{"staff", "backoffice-admins"}
+	}))
+	if first.User.Role != models.RoleAdmin || first.User.Email != "ada@example.com" || first.User.FirstName != "Ada" || first.User.LastName != "Lovelace" {
+		t.Fatalf("unexpected provisioned user %+v", first.User)
+	}
+	if resp := ta.Request(http.MethodGet, "/api/v1/me", nil, first.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("the issued token was refused: %d", resp.StatusCode)
+	}
+
+	again := decodeLogin(t, oidcSignInAs(t, ta, m, "corp", "sub-1", "ada@example.com", nil))
+	if again.User.ID != first.User.ID || again.User.Role != models.RoleAdmin {
+		t.Errorf("expected the provisioned admin again, got %+v", again.User)
+	}
+	if n := countRows(t, ta.DB(), &models.UserIdentity{}, "provider = ? AND subject = ?", "corp", "sub-1"); n != 1 {
+		t.Errorf("expected 1 identity, got %d", n)
+	}
+
+	other := decodeLogin(t, oidcSignInAs(t, ta, m, "corp", "sub-2", "grace@example.com", nil))
+	if other.User.Role != models.RoleGuest {
+		t.Errorf("expected the default role guest, got %s", other.User.Role)
+	}
+}
+
+// TestOIDCLinksExistingUsers tests which sign-ins are linked to existing users
+func TestOIDCLinksExistingUsers(t *testing.T) {
+	const mapped = "mapped@example.com"
+	m := newMockOIDC(t)
+	ta := apptest.NewTestApp(t, withOIDCProviders(map[string]config.OIDCProviderConfig{
+		"linking": m.provider(func(p *config.OIDCProviderConfig) { p.LinkByEmail = true }),
+		"strict": m.provider(func(p *config.OIDCProviderConfig) {
+			p.Subjects = []config.OIDCSubject{{Subject: "mapped-sub", Email: mapped}}
+		}),
+	}))
+	user := ta.CreateUser(models.RoleUser)
+	if err := ta.DB().Model(&models.User{}).Where("id = ?", user.ID).Update("email", mapped).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unverified email", func(t *testing.T) {
+		resp := oidcSignInAs(t, ta, m, "linking", "sub-a", mapped, func(c jwt.MapClaims) { c["email_verified"] = false })
+		expectErrorCode(t, resp, http.StatusConflict, errors.CodeOIDCAccountExists)
+	})
+
+	t.Run("email without linking", func(t *testing.T) {
+		resp := oidcSignInAs(t, ta, m, "strict", "sub-b", mapped, nil)
+		expectErrorCode(t, resp, http.StatusConflict, errors.CodeOIDCAccountExists)
+	})
+
+	t.Run("not provisioned", func(t *testing.T) {
+		resp := oidcSignInAs(t, ta, m, "strict", "sub-c", "stranger@example.com", nil)
+		expectErrorCode(t, resp, http.StatusForbidden, errors.CodeOIDCNotProvisioned)
+	})
+
+	t.Run("verified email", func(t *testing.T) {
+		result := decodeLogin(t, oidcSignInAs(t, ta, m, "linking", "sub-a", mapped, nil))
+		if result.User.ID != user.ID {
+			t.Errorf("expected user %s, got %s", user.ID, result.User.ID)
+		}
+		if n := auditCount(t, ta, models.AuditActionUserIdentityLinked, user, user.ID.String()); n != 1 {
+			t.Errorf("expected 1 identity link audited, got %d", n)
+		}
+	})
+
+	t.Run("configured subject", func(t *testing.T) {
+		result := decodeLogin(t, oidcSignInAs(t, ta, m, "strict", "mapped-sub", "someone-else@example.com", func(c jwt.MapClaims) { c["email_verified"] = false }))
+		if result.User.ID != user.ID {
+			t.Errorf("expected user %s, got %s", user.ID, result.User.ID)
+		}
+	})
+
+	if n := countRows(t, ta.DB(), &models.User{}, "1 = 1"); n != 1 {
+		t.Errorf("expected no user created, got %d users", n)
+	}
+}
+
+// TestOIDCRejectsInvalidIDTokens tests that ID tokens are checked
+func TestOIDCRejectsInvalidIDTokens(t *testing.T) {
+	m := newMockOIDC(t)
+	ta := apptest.NewTestApp(t, withOIDCProviders(map[string]config.OIDCProviderConfig{
+		"corp": m.provider(func(p *config.OIDCProviderConfig) { p.Provision = true }),
+	}))
+	forged, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		key    *rsa.PrivateKey
+		adjust func(c jwt.MapClaims)
+	}{
+		{"bad signature", forged, nil},
+		{"wrong audience", m.key, func(c jwt.MapClaims) { c["aud"] = "another-client" }},
+		{"wrong issuer", m.key, func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{"wrong nonce", m.key, func(c jwt.MapClaims) { c["nonce"] = "replayed" }},
+		{"expired beyond the clock skew", m.key, func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() }},
+		{"issued beyond the clock skew", m.key, func(c jwt.MapClaims) { c["iat"] = time.Now().Add(2 * time.Minute).Unix() }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			signIn := startOIDCSignIn(t, ta, "corp")
+			claims := m.claims("sub-1", "ada@example.com", signIn.nonce)
+			if tc.adjust != nil {
+				tc.adjust(claims)
+			}
+			resp := completeOIDCSignIn(ta, "corp", m.authorize(signIn.challenge, claims, tc.key), signIn.state)
+			expectErrorCode(t, resp, http.StatusUnauthorized, errors.CodeOIDCLoginFailed)
+		})
+	}
+
+	// Clocks a little apart are tolerated
+	decodeLogin(t, oidcSignInAs(t, ta, m, "corp", "sub-1", "ada@example.com", func(c jwt.MapClaims) {
+		c["exp"] = time.Now().Add(-30 * time.Second).Unix()
+		c["iat"] = time.Now().Add(30 * time.Second).Unix()
+	}))
+	if n := countRows(t, ta.DB(), &models.User{}, "1 = 1"); n != 1 {
+		t.Errorf("expected only the valid sign-in to create a user, got %d users", n)
+	}
+}
+
+// TestOIDCStateIsSingleUse tests that a sign-in completes once, with the
+// provider it was started with
+func TestOIDCStateIsSingleUse(t *testing.T) {
+	m := newMockOIDC(t)
+	ta := apptest.NewTestApp(t, withOIDCProviders(map[string]config.OIDCProviderConfig{
+		"corp":    m.provider(func(p *config.OIDCProviderConfig) { p.Provision = true }),
+		"partner": m.provider(func(p *config.OIDCProviderConfig) { p.Provision = true }),
+	}))
+
+	signIn := startOIDCSignIn(t, ta, "corp")
+	claims := m.claims("sub-1", "ada@example.com", signIn.nonce)
+	decodeLogin(t, completeOIDCSignIn(ta, "corp", m.authorize(signIn.challenge, claims, m.key), signIn.state))
+
+	resp := completeOIDCSignIn(ta, "corp", m.authorize(signIn.challenge, claims, m.key), signIn.state)
+	expectErrorCode(t, resp, http.StatusBadRequest, errors.CodeOIDCStateInvalid)
+
+	signIn = startOIDCSignIn(t, ta, "corp")
+	claims = m.claims("sub-1", "ada@example.com", signIn.nonce)
+	resp = completeOIDCSignIn(ta, "partner", m.authorize(signIn.challenge, claims, m.key), signIn.state)
+	expectErrorCode(t, resp, http.StatusBadRequest, errors.CodeOIDCStateInvalid)
+
+	resp = completeOIDCSignIn(ta, "corp", "code", "unknown-state")
+	expectErrorCode(t, resp, http.StatusBadRequest, errors.CodeOIDCStateInvalid)
+
+	// A code redeemed with another sign-in's verifier is refused by the provider
+	first, second := startOIDCSignIn(t, ta, "corp"), startOIDCSignIn(t, ta, "corp")
+	code := m.authorize(first.challenge, m.claims("sub-1", "ada@example.com", second.nonce), m.key)
+	expectErrorCode(t, completeOIDCSignIn(ta, "corp", code, second.state), http.StatusUnauthorized, errors.CodeOIDCLoginFailed)
+}
+
+// TestOIDCProviders tests listing providers, unknown ones and unreachable ones
+func TestOIDCProviders(t *testing.T) {
+	m := newMockOIDC(t)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	ta := apptest.NewTestApp(t, withOIDCProviders(map[string]config.OIDCProviderConfig{
+		"corp": m.provider(func(p *config.OIDCProviderConfig) { p.Name = "Corporate SSO" }),
+		"down": m.provider(func(p *config.OIDCProviderConfig) { p.Issuer = down.URL }),
+	}))
+
+	resp := ta.Request(http.MethodGet, "/api/v1/auth/oidc/providers", nil, "")
+	var body struct {
+		Data []services.OIDCProvider `json:"data"`
+	}
+	resp.Decode(t, &body)
+	want := []services.OIDCProvider{{Slug: "corp", Name: "Corporate SSO"}, {Slug: "down", Name: "down"}}
+	if len(body.Data) != len(want) || body.Data[0] != want[0] || body.Data[1] != want[1] {
+		t.Errorf("expected providers %v, got %v", want, body.Data)
+	}
+
+	expectErrorCode(t, ta.Request(http.MethodGet, "/api/v1/auth/oidc/unknown/login", nil, ""), http.StatusNotFound, errors.CodeOIDCProviderNotFound)
+	expectErrorCode(t, completeOIDCSignIn(ta, "unknown", "code", "state"), http.StatusNotFound, errors.CodeOIDCProviderNotFound)
+	expectErrorCode(t, ta.Request(http.MethodGet, "/api/v1/auth/oidc/down/login", nil, ""), http.StatusServiceUnavailable, errors.CodeOIDCProviderUnavailable)
+
+	resp = ta.Request(http.MethodGet, "/api/v1/auth/oidc/corp/callback?error=access_denied", nil, "")
+	expectErrorCode(t, resp, http.StatusUnauthorized, errors.CodeOIDCLoginFailed)
+}
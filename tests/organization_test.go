@@ -0,0 +1,286 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"BackofficeGoService/internal/app/controllers/organization"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// fakeOrganizationRepository is an in-memory OrganizationRepository
+type fakeOrganizationRepository struct {
+	orgs    map[uuid.UUID]*models.Organization
+	members map[uuid.UUID]map[uuid.UUID]*models.OrganizationMember
+	users   map[uuid.UUID]bool
+}
+
+func newFakeOrganizationRepository() *fakeOrganizationRepository {
+	return &fakeOrganizationRepository{
+		orgs:    make(map[uuid.UUID]*models.Organization),
+		members: make(map[uuid.UUID]map[uuid.UUID]*models.OrganizationMember),
+		users:   make(map[uuid.UUID]bool),
+	}
+}
+
+func (r *fakeOrganizationRepository) Create(ctx context.Context, org *models.Organization) error {
+	r.orgs[org.ID] = org
+	return nil
+}
+
+func (r *fakeOrganizationRepository) Get(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	org, ok := r.orgs[id]
+	if !ok {
+		return nil, services.ErrOrganizationNotFound
+	}
+	return org, nil
+}
+
+func (r *fakeOrganizationRepository) List(ctx context.Context, limit, offset int) ([]*models.Organization, error) {
+	var orgs []*models.Organization
+	for _, org := range r.orgs {
+		orgs = append(orgs, org)
+	}
+	return orgs, nil
+}
+
+func (r *fakeOrganizationRepository) Count(ctx context.Context) (int64, error) {
+	return int64(len(r.orgs)), nil
+}
+
+func (r *fakeOrganizationRepository) Update(ctx context.Context, org *models.Organization) error {
+	r.orgs[org.ID] = org
+	return nil
+}
+
+func (r *fakeOrganizationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	delete(r.members, id)
+	delete(r.orgs, id)
+	return nil
+}
+
+func (r *fakeOrganizationRepository) CountMembers(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	return int64(len(r.members[orgID])), nil
+}
+
+func (r *fakeOrganizationRepository) GetMember(ctx context.Context, orgID, userID uuid.UUID) (*models.OrganizationMember, error) {
+	member, ok := r.members[orgID][userID]
+	if !ok {
+		return nil, services.ErrMembershipNotFound
+	}
+	return member, nil
+}
+
+func (r *fakeOrganizationRepository) AddMember(ctx context.Context, member *models.OrganizationMember) error {
+	if r.members[member.OrganizationID] == nil {
+		r.members[member.OrganizationID] = make(map[uuid.UUID]*models.OrganizationMember)
+	}
+	r.members[member.OrganizationID][member.UserID] = member
+	return nil
+}
+
+func (r *fakeOrganizationRepository) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	if _, ok := r.members[orgID][userID]; !ok {
+		return services.ErrMembershipNotFound
+	}
+	delete(r.members[orgID], userID)
+	return nil
+}
+
+func (r *fakeOrganizationRepository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]*models.OrganizationMember, error) {
+	var members []*models.OrganizationMember
+	for _, m := range r.members[orgID] {
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func (r *fakeOrganizationRepository) ListUserOrganizationIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for orgID, members := range r.members {
+		if _, ok := members[userID]; ok {
+			ids = append(ids, orgID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeOrganizationRepository) UserExists(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return r.users[userID], nil
+}
+
+// newOrganizationFixture creates a service over a fake repository with one organization
+func newOrganizationFixture(t *testing.T) (*services.OrganizationService, *fakeOrganizationRepository, *models.Organization) {
+	t.Helper()
+
+	repo := newFakeOrganizationRepository()
+	svc := services.NewOrganizationService(repo, logger.NewSimpleLogger())
+
+	org, err := svc.CreateOrganization(context.Background(), &services.CreateOrganizationRequest{Name: " Finance "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return svc, repo, org
+}
+
+// TestOrganizationMembership tests adding, listing and removing members
+func TestOrganizationMembership(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, org := newOrganizationFixture(t)
+
+	if org.Name != "Finance" {
+		t.Fatalf("expected trimmed name, got %q", org.Name)
+	}
+
+	userID := uuid.New()
+	repo.users[userID] = true
+
+	member, err := svc.AddMember(ctx, org.ID.String(), &services.AddMemberRequest{UserID: userID.String()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if member.Role != models.OrgRoleMember {
+		t.Fatalf("expected default role member, got %q", member.Role)
+	}
+
+	_, err = svc.AddMember(ctx, org.ID.String(), &services.AddMemberRequest{UserID: userID.String()})
+	if !errors.Is(err, services.ErrMembershipExists) {
+		t.Fatalf("expected ErrMembershipExists, got %v", err)
+	}
+
+	ids, err := svc.ListUserOrganizationIDs(ctx, userID.String())
+	if err != nil || len(ids) != 1 || ids[0] != org.ID.String() {
+		t.Fatalf("expected membership in %s, got %v (err %v)", org.ID, ids, err)
+	}
+
+	if err := svc.RemoveMember(ctx, org.ID.String(), userID.String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.RemoveMember(ctx, org.ID.String(), userID.String()); !errors.Is(err, services.ErrMembershipNotFound) {
+		t.Fatalf("expected ErrMembershipNotFound, got %v", err)
+	}
+}
+
+// TestOrganizationAddMemberValidation tests role and user validation when adding members
+func TestOrganizationAddMemberValidation(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, org := newOrganizationFixture(t)
+
+	_, err := svc.AddMember(ctx, org.ID.String(), &services.AddMemberRequest{UserID: uuid.New().String()})
+	if !errors.Is(err, services.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+
+	userID := uuid.New()
+	repo.users[userID] = true
+	_, err = svc.AddMember(ctx, org.ID.String(), &services.AddMemberRequest{UserID: userID.String(), Role: "superuser"})
+	if !errors.Is(err, services.ErrInvalidOrgRole) {
+		t.Fatalf("expected ErrInvalidOrgRole, got %v", err)
+	}
+
+	_, err = svc.AddMember(ctx, uuid.New().String(), &services.AddMemberRequest{UserID: userID.String()})
+	if !errors.Is(err, services.ErrOrganizationNotFound) {
+		t.Fatalf("expected ErrOrganizationNotFound, got %v", err)
+	}
+}
+
+// TestOrganizationDeleteRequiresForce tests that organizations with members need force to delete
+func TestOrganizationDeleteRequiresForce(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, org := newOrganizationFixture(t)
+
+	userID := uuid.New()
+	repo.users[userID] = true
+	if _, err := svc.AddMember(ctx, org.ID.String(), &services.AddMemberRequest{UserID: userID.String(), Role: models.OrgRoleOwner}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := svc.DeleteOrganization(ctx, org.ID.String(), false); !errors.Is(err, services.ErrOrganizationHasMembers) {
+		t.Fatalf("expected ErrOrganizationHasMembers, got %v", err)
+	}
+
+	if err := svc.DeleteOrganization(ctx, org.ID.String(), true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := repo.orgs[org.ID]; ok {
+		t.Fatal("organization should be deleted")
+	}
+	if len(repo.members[org.ID]) != 0 {
+		t.Fatal("memberships should be deleted with the organization")
+	}
+}
+
+// newOrganizationRouter registers the organization routes behind fixed claims
+func newOrganizationRouter(svc *services.OrganizationService, claims *services.TokenClaims) *gin.Engine {
+	return newClaimsRouter(claims, organization.NewOrganizationController(svc, pagination.DefaultConfig).Routes())
+}
+
+// TestOrganizationMembershipRoutes tests the membership endpoints and their guards
+func TestOrganizationMembershipRoutes(t *testing.T) {
+	svc, repo, org := newOrganizationFixture(t)
+	userID := uuid.New()
+	repo.users[userID] = true
+
+	admin := &services.TokenClaims{UserID: uuid.New().String(), Role: string(models.RoleAdmin)}
+	adminRouter := newOrganizationRouter(svc, admin)
+	membersURL := "/api/v1/organizations/" + org.ID.String() + "/members"
+
+	body, _ := json.Marshal(map[string]string{"user_id": userID.String(), "role": "admin"})
+	w := httptest.NewRecorder()
+	adminRouter.ServeHTTP(w, httptest.NewRequest(http.MethodPost, membersURL, bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	adminRouter.ServeHTTP(w, httptest.NewRequest(http.MethodPost, membersURL, bytes.NewReader(body)))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate membership, got %d", w.Code)
+	}
+
+	// Members may list the organization's members but not manage them
+	member := &services.TokenClaims{UserID: userID.String(), Role: string(models.RoleUser), OrgIDs: []string{org.ID.String()}}
+	memberRouter := newOrganizationRouter(svc, member)
+
+	w = httptest.NewRecorder()
+	memberRouter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, membersURL, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for member, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	memberRouter.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, membersURL+"/"+userID.String(), nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-admin removal, got %d", w.Code)
+	}
+
+	outsider := &services.TokenClaims{UserID: uuid.New().String(), Role: string(models.RoleUser)}
+	w = httptest.NewRecorder()
+	newOrganizationRouter(svc, outsider).ServeHTTP(w, httptest.NewRequest(http.MethodGet, membersURL, nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-member, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	adminRouter.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/organizations/"+org.ID.String(), nil))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 deleting organization with members, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	adminRouter.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, membersURL+"/"+userID.String(), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 removing member, got %d", w.Code)
+	}
+}
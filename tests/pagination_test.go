@@ -0,0 +1,357 @@
+package tests
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/pagination"
+
+	"github.com/google/uuid"
+)
+
+// mustURL parses a request URI the way the server sees it
+func mustURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.ParseRequestURI(raw)
+	if err != nil {
+		t.Fatalf("parse %q: %v", raw, err)
+	}
+	return u
+}
+
+// TestPaginationParams tests defaults, clamping and rejection of page and limit
+func TestPaginationParams(t *testing.T) {
+	cfg := pagination.Config{DefaultLimit: 20, MaxLimit: 50}
+	cases := []struct {
+		query string
+		want  pagination.Params
+	}{
+		{"", pagination.Params{Page: 1, Limit: 20}},
+		{"page=3&limit=5", pagination.Params{Page: 3, Limit: 5}},
+		{"limit=1000", pagination.Params{Page: 1, Limit: 50}},
+	}
+	for _, tc := range cases {
+		query, _ := url.ParseQuery(tc.query)
+		if got, err := pagination.Parse(query, cfg); err != nil || got != tc.want {
+			t.Errorf("%q: expected %+v, got %+v (err %v)", tc.query, tc.want, got, err)
+		}
+	}
+
+	for _, query := range []string{"page=abc", "page=0", "limit=-1", "limit=2.5", "limit=0"} {
+		values, _ := url.ParseQuery(query)
+		if _, err := pagination.Parse(values, cfg); !stderrors.Is(err, pagination.ErrInvalidParam) {
+			t.Errorf("%q: expected ErrInvalidParam, got %v", query, err)
+		}
+	}
+
+	// Unset sizes fall back to DefaultConfig, and overrides replace one size
+	values, _ := url.ParseQuery("limit=1000")
+	if got, _ := pagination.Parse(values, pagination.Config{}); got.Limit != pagination.DefaultConfig.MaxLimit {
+		t.Errorf("expected the zero config to cap at %d, got %d", pagination.DefaultConfig.MaxLimit, got.Limit)
+	}
+	if got, _ := pagination.Parse(values, cfg.WithMax(500)); got.Limit != 500 {
+		t.Errorf("expected an override to allow 500, got %d", got.Limit)
+	}
+	if got, _ := pagination.Parse(nil, cfg.WithDefault(100)); got.Limit != 50 {
+		t.Errorf("expected a default over the cap to be capped, got %d", got.Limit)
+	}
+
+	if offset := (pagination.Params{Page: 3, Limit: 5}).Offset(); offset != 10 {
+		t.Errorf("expected offset 10, got %d", offset)
+	}
+	for total, pages := range map[int64]int{0: 0, 1: 1, 10: 1, 11: 2, 25: 3} {
+		if meta := pagination.NewMeta(pagination.Params{Page: 1, Limit: 10}, total); meta.TotalPages != pages {
+			t.Errorf("%d items: expected %d pages, got %d", total, pages, meta.TotalPages)
+		}
+	}
+}
+
+// TestPaginationLinks tests the rels emitted on each page
+func TestPaginationLinks(t *testing.T) {
+	u := mustURL(t, "/api/v1/users?limit=10&page=2")
+	cases := []struct {
+		page  int
+		total int64
+		want  string
+	}{
+		{1, 0, `</api/v1/users?limit=10&page=1>; rel="first", </api/v1/users?limit=10&page=1>; rel="last"`},
+		{1, 25, `</api/v1/users?limit=10&page=1>; rel="first", </api/v1/users?limit=10&page=2>; rel="next", </api/v1/users?limit=10&page=3>; rel="last"`},
+		{2, 25, `</api/v1/users?limit=10&page=1>; rel="first", </api/v1/users?limit=10&page=1>; rel="prev", </api/v1/users?limit=10&page=3>; rel="next", </api/v1/users?limit=10&page=3>; rel="last"`},
+		{3, 25, `</api/v1/users?limit=10&page=1>; rel="first", </api/v1/users?limit=10&page=2>; rel="prev", </api/v1/users?limit=10&page=3>; rel="last"`},
+		// Past the end, prev goes back to the last page
+		{9, 25, `</api/v1/users?limit=10&page=1>; rel="first", </api/v1/users?limit=10&page=3>; rel="prev", </api/v1/users?limit=10&page=3>; rel="last"`},
+	}
+	for _, tc := range cases {
+		meta := pagination.NewMeta(pagination.Params{Page: tc.page, Limit: 10}, tc.total)
+		if got := meta.Links(u); got != tc.want {
+			t.Errorf("page %d of %d items:\nexpected %s\ngot      %s", tc.page, tc.total, tc.want, got)
+		}
+	}
+}
+
+// TestPaginationLinksPreserveQuery tests that filters survive, escaped, in every link
+func TestPaginationLinksPreserveQuery(t *testing.T) {
+	cases := []struct {
+		name string
+		uri  string
+		want string
+	}{
+		{
+			name: "filters and sort",
+			uri:  "/api/v1/users/search?q=jane+doe&sort=-created_at&active=true&page=2",
+			want: "/api/v1/users/search?active=true&limit=10&page=3&q=jane+doe&sort=-created_at",
+		},
+		{
+			name: "repeated parameters",
+			uri:  "/api/v1/users?role=admin&role=user",
+			want: "/api/v1/users?limit=10&page=2&role=admin&role=user",
+		},
+		{
+			// Characters that would end the URI or the header entry are escaped
+			name: "header delimiters",
+			uri:  "/api/v1/users?q=%3Cb%3E%2C%20x%3By%22&tag=a%26b%3Dc",
+			want: "/api/v1/users?limit=10&page=2&q=%3Cb%3E%2C+x%3By%22&tag=a%26b%3Dc",
+		},
+		{
+			name: "unicode and percent",
+			uri:  "/api/v1/users?q=Ren%C3%A9e&discount=100%25",
+			want: "/api/v1/users?discount=100%25&limit=10&page=2&q=Ren%C3%A9e",
+		},
+		{
+			name: "empty value",
+			uri:  "/api/v1/users?q=&page=1",
+			want: "/api/v1/users?limit=10&page=2&q=",
+		},
+		{
+			name: "escaped path",
+			uri:  "/api/v1/users/a%2Fb/activity?page=1",
+			want: "/api/v1/users/a%2Fb/activity?limit=10&page=2",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := mustURL(t, tc.uri)
+			params, err := pagination.Parse(u.Query(), pagination.DefaultConfig)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			meta := pagination.NewMeta(params, 100)
+			links := meta.Links(u)
+
+			want := "<" + tc.want + `>; rel="next"`
+			if !strings.Contains(links, want) {
+				t.Fatalf("expected %s in\n%s", want, links)
+			}
+			for _, entry := range strings.Split(links, ", ") {
+				target := entry[1:strings.Index(entry, ">")]
+				if strings.ContainsAny(target, " <>\",;") {
+					t.Errorf("unescaped delimiter in %q", target)
+				}
+				// Following a link gives back the original filters
+				parsed := mustURL(t, target).Query()
+				for key, values := range u.Query() {
+					if key == pagination.PageParam || key == pagination.LimitParam {
+						continue
+					}
+					if strings.Join(parsed[key], "|") != strings.Join(values, "|") {
+						t.Errorf("%s: expected %q, got %q", key, values, parsed[key])
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestPaginationCursor tests cursor encoding and next-only links
+func TestPaginationCursor(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.FixedZone("CET", 3600))
+	cursor := pagination.Cursor{Time: at, ID: uuid.NewString()}
+	encoded := cursor.Encode()
+	if strings.ContainsAny(encoded, "+/=") {
+		t.Errorf("expected a URL-safe cursor, got %q", encoded)
+	}
+	decoded, err := pagination.ParseCursor(encoded)
+	if err != nil || !decoded.Time.Equal(at) || decoded.ID != cursor.ID {
+		t.Fatalf("expected %+v back, got %+v (err %v)", cursor, decoded, err)
+	}
+	for _, bad := range []string{"not base64!", "bm8tc2VwYXJhdG9y", "eHx5"} {
+		if _, err := pagination.ParseCursor(bad); err != pagination.ErrInvalidCursor {
+			t.Errorf("%q: expected ErrInvalidCursor, got %v", bad, err)
+		}
+	}
+
+	u := mustURL(t, "/api/v1/webhooks/1/deliveries?cursor=old&success=false")
+	meta := pagination.CursorMeta{Limit: 50, NextCursor: encoded}
+	want := "</api/v1/webhooks/1/deliveries?cursor=" + encoded + `&limit=50&success=false>; rel="next"`
+	if got := meta.Links(u); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if got := (pagination.CursorMeta{Limit: 50}).Links(u); got != "" {
+		t.Errorf("expected no links on the last page, got %s", got)
+	}
+}
+
+// TestListEndpointsPaginate tests the meta block and Link header on the API
+func TestListEndpointsPaginate(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	for i := 0; i < 4; i++ {
+		ta.CreateUser(models.RoleUser)
+	}
+
+	resp := ta.Request(http.MethodGet, "/api/v1/users?limit=2&page=2&include_anonymized=false", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("list users: %d %s", resp.StatusCode, resp.Body)
+	}
+	var body struct {
+		Data []models.User   `json:"data"`
+		Meta pagination.Meta `json:"meta"`
+	}
+	resp.Decode(t, &body)
+	if want := (pagination.Meta{Page: 2, Limit: 2, Total: 5, TotalPages: 3}); body.Meta != want || len(body.Data) != 2 {
+		t.Errorf("expected %+v with 2 users, got %+v with %d", want, body.Meta, len(body.Data))
+	}
+	link := resp.Header.Get("Link")
+	for _, want := range []string{
+		`</api/v1/users?include_anonymized=false&limit=2&page=1>; rel="first"`,
+		`</api/v1/users?include_anonymized=false&limit=2&page=1>; rel="prev"`,
+		`</api/v1/users?include_anonymized=false&limit=2&page=3>; rel="next"`,
+		`</api/v1/users?include_anonymized=false&limit=2&page=3>; rel="last"`,
+	} {
+		if !strings.Contains(link, want) {
+			t.Errorf("expected %s in Link %s", want, link)
+		}
+	}
+
+	resp = ta.Request(http.MethodGet, "/api/v1/me/notifications", nil, admin.Token)
+	var notifications struct {
+		Meta pagination.Meta `json:"meta"`
+	}
+	resp.Decode(t, &notifications)
+	if notifications.Meta.Limit != 20 || notifications.Meta.Total != 0 || resp.Header.Get("Link") == "" {
+		t.Errorf("expected an empty first page of 20, got %+v (Link %q)", notifications.Meta, resp.Header.Get("Link"))
+	}
+}
+
+// TestListEndpointsPageSizes tests the configured page sizes, the larger
+// cap of audit trails and the rejection of invalid values
+func TestListEndpointsPageSizes(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.API.DefaultPageSize = 3
+		cfg.API.MaxPageSize = 4
+		cfg.API.AuditMaxPageSize = 500
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	cases := []struct {
+		path  string
+		limit int
+	}{
+		{"/api/v1/users", 3},
+		{"/api/v1/users?limit=1000", 4},
+		{"/api/v1/organizations?limit=1000", 4},
+		{"/api/v1/users/" + admin.ID.String() + "/activity?limit=1000", 500},
+	}
+	for _, tc := range cases {
+		resp := ta.Request(http.MethodGet, tc.path, nil, admin.Token)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tc.path, resp.StatusCode, resp.Body)
+		}
+		var body struct {
+			Meta pagination.Meta `json:"meta"`
+		}
+		resp.Decode(t, &body)
+		if body.Meta.Limit != tc.limit {
+			t.Errorf("%s: expected limit %d, got %d", tc.path, tc.limit, body.Meta.Limit)
+		}
+	}
+
+	for _, path := range []string{
+		"/api/v1/users?page=abc",
+		"/api/v1/users/search?q=a&limit=ten",
+		"/api/v1/me/notifications?page=0",
+		"/api/v1/organizations?limit=-5",
+		"/api/v1/users/" + admin.ID.String() + "/activity?limit=1.5",
+	} {
+		resp := ta.Request(http.MethodGet, path, nil, admin.Token)
+		expectErrorCode(t, resp, http.StatusBadRequest, errors.CodeInvalidPagination)
+	}
+
+	resp := ta.Request(http.MethodGet, "/api/v1/users?page=abc", nil, admin.Token)
+	var envelope errorEnvelope
+	resp.Decode(t, &envelope)
+	if envelope.Error.Message != "page must be a positive integer" {
+		t.Errorf("expected the message to name the parameter, got %q", envelope.Error.Message)
+	}
+}
+
+// TestWebhookDeliveriesCursor tests walking the delivery log by cursor
+func TestWebhookDeliveriesCursor(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	db := ta.DB()
+
+	webhook := &models.Webhook{ID: uuid.New(), URL: "https://example.com/hook", Secret: "secret", EventTypes: models.StringList{"user.created"}, Enabled: true}
+	if err := db.Create(webhook).Error; err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	// Two attempts share a timestamp, so the ID breaks the tie
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	created := []time.Time{base, base.Add(time.Minute), base.Add(time.Minute), base.Add(2 * time.Minute), base.Add(3 * time.Minute)}
+	for i, at := range created {
+		delivery := &models.WebhookDelivery{ID: uuid.New(), WebhookID: webhook.ID, EventID: uuid.NewString(), EventType: "user.created", Attempt: i + 1, CreatedAt: at}
+		if err := db.Create(delivery).Error; err != nil {
+			t.Fatalf("create delivery: %v", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	path := "/api/v1/webhooks/" + webhook.ID.String() + "/deliveries?limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("cursor did not reach the end")
+		}
+		resp := ta.Request(http.MethodGet, path, nil, admin.Token)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("list deliveries: %d %s", resp.StatusCode, resp.Body)
+		}
+		var body struct {
+			Data []models.WebhookDelivery `json:"data"`
+			Meta pagination.CursorMeta    `json:"meta"`
+		}
+		resp.Decode(t, &body)
+		for _, d := range body.Data {
+			if seen[d.ID.String()] {
+				t.Errorf("delivery %s returned twice", d.ID)
+			}
+			seen[d.ID.String()] = true
+		}
+
+		link := resp.Header.Get("Link")
+		if body.Meta.NextCursor == "" {
+			if link != "" {
+				t.Errorf("expected no Link on the last page, got %s", link)
+			}
+			path = ""
+			continue
+		}
+		if strings.Contains(link, `rel="prev"`) || strings.Contains(link, `rel="first"`) || !strings.HasSuffix(link, `>; rel="next"`) {
+			t.Errorf("expected only a next link, got %s", link)
+		}
+		path = link[1:strings.Index(link, ">")]
+	}
+	if len(seen) != len(created) {
+		t.Errorf("expected all %d deliveries, got %d", len(created), len(seen))
+	}
+
+	resp := ta.Request(http.MethodGet, "/api/v1/webhooks/"+webhook.ID.String()+"/deliveries?cursor=bogus", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusBadRequest, errors.CodeInvalidCursor)
+}
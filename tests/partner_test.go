@@ -0,0 +1,298 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+)
+
+// provisionPath is where partners provision users
+const provisionPath = "/api/v1/partners/provision-user"
+
+// createPartner registers a partner through the admin API and returns it with its secret
+func createPartner(t *testing.T, ta *apptest.TestApp, admin *apptest.User, name string) (*models.Partner, string) {
+	t.Helper()
+
+	resp := ta.Request(http.MethodPost, "/api/v1/admin/partners", map[string]string{"name": name}, admin.Token)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create partner: %d %s", resp.StatusCode, resp.Body)
+	}
+	var body struct {
+		Data   models.Partner `json:"data"`
+		Secret string         `json:"secret"`
+	}
+	resp.Decode(t, &body)
+	if body.Secret == "" {
+		t.Fatalf("create partner returned no secret: %s", resp.Body)
+	}
+	return &body.Data, body.Secret
+}
+
+// signedRequest POSTs body to path with the given partner headers; empty
+// headers are left out
+func signedRequest(t *testing.T, ta *apptest.TestApp, path, partnerID, timestamp, signature string, body []byte) *apptest.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, ta.Server.URL+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for header, value := range map[string]string{
+		middleware.PartnerIDHeader:          partnerID,
+		middleware.SignatureTimestampHeader: timestamp,
+		middleware.SignatureHeader:          signature,
+	} {
+		if value != "" {
+			req.Header.Set(header, value)
+		}
+	}
+
+	resp, err := ta.Server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &apptest.Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: data}
+}
+
+// provision signs payload as partner at the current time and sends it
+func provision(t *testing.T, ta *apptest.TestApp, partner *models.Partner, secret string, payload interface{}) *apptest.Response {
+	t.Helper()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	return signedRequest(t, ta, provisionPath, partner.ID.String(), timestamp, services.SignPartnerRequest(secret, timestamp, body), body)
+}
+
+// TestPartnerProvisioning tests that provisioning creates a user once and
+// updates it on every later request for the same external ID
+func TestPartnerProvisioning(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	partner, secret := createPartner(t, ta, admin, "crm")
+	if n := auditCount(t, ta, models.AuditActionPartnerCreated, admin, partner.ID.String()); n != 1 {
+		t.Errorf("expected one partner.created audit entry, got %d", n)
+	}
+
+	resp := provision(t, ta, partner, secret, map[string]interface{}{
+		"external_id": "crm-42", "email": "ann@example.com", "first_name": "Ann", "last_name": "Lee",
+	})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("first provision: %d %s", resp.StatusCode, resp.Body)
+	}
+	var created struct {
+		Data models.User `json:"data"`
+	}
+	resp.Decode(t, &created)
+	if created.Data.PartnerID == nil || *created.Data.PartnerID != partner.ID || created.Data.ExternalID == nil || *created.Data.ExternalID != "crm-42" {
+		t.Errorf("user is not linked to the partner: %s", resp.Body)
+	}
+
+	// Only the given fields change, and the email is never taken over
+	resp = provision(t, ta, partner, secret, map[string]interface{}{
+		"external_id": "crm-42", "email": "other@example.com", "first_name": "Anna", "active": false,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("second provision: %d %s", resp.StatusCode, resp.Body)
+	}
+	var updated struct {
+		Data models.User `json:"data"`
+	}
+	resp.Decode(t, &updated)
+	if updated.Data.ID != created.Data.ID {
+		t.Fatalf("second provision created user %s, want %s", updated.Data.ID, created.Data.ID)
+	}
+	if updated.Data.FirstName != "Anna" || updated.Data.LastName != "Lee" || updated.Data.Email != "ann@example.com" || updated.Data.Active {
+		t.Errorf("unexpected update %+v", updated.Data)
+	}
+
+	var users int64
+	if err := ta.DB().Model(&models.User{}).Where("external_id = ?", "crm-42").Count(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if users != 1 {
+		t.Errorf("expected one provisioned user, got %d", users)
+	}
+
+	// Another partner's external IDs are its own
+	other, otherSecret := createPartner(t, ta, admin, "erp")
+	resp = provision(t, ta, other, otherSecret, map[string]interface{}{"external_id": "crm-42", "email": "bob@example.com"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("other partner: %d %s", resp.StatusCode, resp.Body)
+	}
+
+	// Anonymizing unlinks the user, so the partner gets a new one
+	if resp := ta.Request(http.MethodPost, "/api/v1/users/"+created.Data.ID.String()+"/anonymize", nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("anonymize: %d %s", resp.StatusCode, resp.Body)
+	}
+	resp = provision(t, ta, partner, secret, map[string]interface{}{"external_id": "crm-42", "email": "ann@example.com"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("provision after anonymizing: %d %s", resp.StatusCode, resp.Body)
+	}
+}
+
+// TestPartnerProvisionEmailTaken tests that provisioning never takes over an existing account
+func TestPartnerProvisionEmailTaken(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	partner, secret := createPartner(t, ta, admin, "crm")
+
+	resp := provision(t, ta, partner, secret, map[string]interface{}{"external_id": "crm-1", "email": admin.Email})
+	expectErrorCode(t, resp, http.StatusConflict, errors.CodeEmailAlreadyExists)
+
+	resp = provision(t, ta, partner, secret, map[string]interface{}{"external_id": "crm-1", "email": "not-an-email"})
+	expectErrorCode(t, resp, http.StatusUnprocessableEntity, errors.CodeValidationFailed)
+}
+
+// TestPartnerSignatureRejected tests the requests VerifySignature refuses
+func TestPartnerSignatureRejected(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	partner, secret := createPartner(t, ta, admin, "crm")
+	disabled, disabledSecret := createPartner(t, ta, admin, "legacy")
+	if resp := ta.Request(http.MethodPut, "/api/v1/admin/partners/"+disabled.ID.String(), map[string]bool{"enabled": false}, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("disable partner: %d %s", resp.StatusCode, resp.Body)
+	}
+
+	body := []byte(`{"external_id":"crm-1","email":"ann@example.com"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(10*time.Minute).Unix(), 10)
+	sign := func(secret, timestamp string, body []byte) string {
+		return services.SignPartnerRequest(secret, timestamp, body)
+	}
+
+	cases := []struct {
+		name      string
+		partnerID string
+		timestamp string
+		signature string
+		body      []byte
+		code      errors.Code
+	}{
+		{"unsigned", partner.ID.String(), now, "", body, errors.CodeSignatureInvalid},
+		{"no partner", "", now, sign(secret, now, body), body, errors.CodeSignatureInvalid},
+		{"no timestamp", partner.ID.String(), "", sign(secret, now, body), body, errors.CodeSignatureInvalid},
+		{"wrong secret", partner.ID.String(), now, sign("not-the-partner-secret", now, body), body, errors.CodeSignatureInvalid},
+		{"tampered body", partner.ID.String(), now, sign(secret, now, body), []byte(`{"external_id":"crm-1","email":"eve@example.com"}`), errors.CodeSignatureInvalid},
+		{"tampered timestamp", partner.ID.String(), future, sign(secret, now, body), body, errors.CodeSignatureInvalid},
+		{"unknown partner", "2b9c4d1e-0000-4000-8000-000000000000", now, sign(secret, now, body), body, errors.CodeSignatureInvalid},
+		{"malformed partner", "crm", now, sign(secret, now, body), body, errors.CodeSignatureInvalid},
+		{"disabled partner", disabled.ID.String(), now, sign(disabledSecret, now, body), body, errors.CodeSignatureInvalid},
+		{"malformed timestamp", partner.ID.String(), "yesterday", sign(secret, "yesterday", body), body, errors.CodeSignatureInvalid},
+		{"stale", partner.ID.String(), stale, sign(secret, stale, body), body, errors.CodeSignatureExpired},
+		{"future", partner.ID.String(), future, sign(secret, future, body), body, errors.CodeSignatureExpired},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := signedRequest(t, ta, provisionPath, tc.partnerID, tc.timestamp, tc.signature, tc.body)
+			expectErrorCode(t, resp, http.StatusUnauthorized, tc.code)
+		})
+	}
+
+	var users int64
+	if err := ta.DB().Model(&models.User{}).Where("email = ?", "ann@example.com").Count(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if users != 0 {
+		t.Errorf("a refused request provisioned a user")
+	}
+
+	t.Run("replayed", func(t *testing.T) {
+		signature := sign(secret, now, body)
+		if resp := signedRequest(t, ta, provisionPath, partner.ID.String(), now, signature, body); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("first request: %d %s", resp.StatusCode, resp.Body)
+		}
+		resp := signedRequest(t, ta, provisionPath, partner.ID.String(), now, signature, body)
+		expectErrorCode(t, resp, http.StatusUnauthorized, errors.CodeSignatureReplayed)
+	})
+}
+
+// TestPartnerAdministration tests managing partners and their secrets
+func TestPartnerAdministration(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	member := ta.CreateUser(models.RoleUser)
+
+	if resp := ta.Request(http.MethodGet, "/api/v1/admin/partners", nil, member.Token); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 without partners.manage, got %d", resp.StatusCode)
+	}
+
+	partner, secret := createPartner(t, ta, admin, "crm")
+	resp := ta.Request(http.MethodPost, "/api/v1/admin/partners", map[string]string{"name": "crm"}, admin.Token)
+	expectErrorCode(t, resp, http.StatusConflict, errors.CodePartnerExists)
+
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/partners", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("list: %d %s", resp.StatusCode, resp.Body)
+	}
+	if bytes.Contains(resp.Body, []byte(secret)) {
+		t.Errorf("list leaks the secret: %s", resp.Body)
+	}
+
+	// Requests signed with a replaced secret are refused
+	newSecret := "a-new-partner-secret-of-enough-length"
+	path := "/api/v1/admin/partners/" + partner.ID.String()
+	if resp := ta.Request(http.MethodPut, path, map[string]string{"secret": newSecret}, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("rotate secret: %d %s", resp.StatusCode, resp.Body)
+	}
+	resp = provision(t, ta, partner, secret, map[string]interface{}{"external_id": "crm-1", "email": "ann@example.com"})
+	expectErrorCode(t, resp, http.StatusUnauthorized, errors.CodeSignatureInvalid)
+	resp = provision(t, ta, partner, newSecret, map[string]interface{}{"external_id": "crm-1", "email": "ann@example.com"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("provision with the new secret: %d %s", resp.StatusCode, resp.Body)
+	}
+	if n := auditCount(t, ta, models.AuditActionPartnerUpdated, admin, partner.ID.String()); n != 1 {
+		t.Errorf("expected one partner.updated audit entry, got %d", n)
+	}
+
+	// Deleting keeps the users but unlinks them
+	if resp := ta.Request(http.MethodDelete, path, nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete: %d %s", resp.StatusCode, resp.Body)
+	}
+	var user models.User
+	if err := ta.DB().Where("email = ?", "ann@example.com").First(&user).Error; err != nil {
+		t.Fatalf("provisioned user was deleted: %v", err)
+	}
+	if user.PartnerID != nil || user.ExternalID != nil {
+		t.Errorf("user is still linked: %+v", user)
+	}
+	resp = ta.Request(http.MethodGet, path, nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusNotFound, errors.CodePartnerNotFound)
+}
+
+// TestVerifyPartnerSignature tests the signature against a value computed
+// independently, and that altered signatures do not verify
+func TestVerifyPartnerSignature(t *testing.T) {
+	const want = "sha256=f3a15680551495e7d27302c4d19e4cbedc650f19fa5b40373c00cbea52a64406"
+	body := []byte(`{"a":1}`)
+	if got := services.SignPartnerRequest("secret", "1700000000", body); got != want {
+		t.Fatalf("signature %s, want %s", got, want)
+	}
+	if !services.VerifyPartnerSignature("secret", "1700000000", body, want) {
+		t.Error("valid signature does not verify")
+	}
+	for _, signature := range []string{"", want[:len(want)-1], want[:len(want)-1] + "7", want[len("sha256="):]} {
+		if services.VerifyPartnerSignature("secret", "1700000000", body, signature) {
+			t.Errorf("signature %q verified", signature)
+		}
+	}
+}
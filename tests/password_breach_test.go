@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	apperrors "BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/httpclient"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+)
+
+// breachedPassword is in the stub corpus 42 times
+const breachedPassword = "correct-horse-battery"
+
+// rangeAPI stubs the Have I Been Pwned range API with breachedPassword in
+// its corpus, and records the paths it is asked for
+type rangeAPI struct {
+	down atomic.Bool
+
+	mu    sync.Mutex
+	paths []string
+}
+
+func (a *rangeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	a.paths = append(a.paths, r.URL.Path)
+	a.mu.Unlock()
+	if a.down.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	sum := sha1.Sum([]byte(breachedPassword))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:0\r\n")
+	if r.Header.Get("Add-Padding") == "true" && r.URL.Path == "/range/"+hash[:5] {
+		fmt.Fprintf(w, "%s:42\r\n", hash[5:])
+	}
+	fmt.Fprintf(w, "00D4F6E8FA6EECAD2A3AA415EEC418D38EC:3\r\n")
+}
+
+// requested returns the paths the stub was asked for
+func (a *rangeAPI) requested() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.paths...)
+}
+
+// TestPwnedPasswords tests the range lookup, the threshold and both
+// policies for an unreachable API
+func TestPwnedPasswords(t *testing.T) {
+	api := &rangeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	client := httpclient.New(httpclient.Config{Timeout: time.Second})
+	ctx := context.Background()
+
+	policy := services.NewPwnedPasswords(client, server.URL+"/", 0, false, logger.NewNopLogger())
+	if err := policy.Check(ctx, breachedPassword); !errors.Is(err, services.ErrPasswordBreached) {
+		t.Errorf("expected a breached password rejected, got %v", err)
+	}
+	if err := policy.Check(ctx, "a-password-nobody-used"); err != nil {
+		t.Errorf("expected an unseen password accepted, got %v", err)
+	}
+	if err := services.NewPwnedPasswords(client, server.URL, 42, false, logger.NewNopLogger()).Check(ctx, breachedPassword); err != nil {
+		t.Errorf("expected a password seen no more than the threshold accepted, got %v", err)
+	}
+
+	// Only the hash prefix leaves the service
+	sum := sha1.Sum([]byte(breachedPassword))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	for _, path := range api.requested() {
+		if len(path) != len("/range/")+5 || strings.Contains(path, hash[5:]) {
+			t.Errorf("expected only a 5 character prefix requested, got %s", path)
+		}
+	}
+
+	api.down.Store(true)
+	logs := logger.NewCaptureLogger()
+	if err := services.NewPwnedPasswords(client, server.URL, 0, false, logs).Check(ctx, breachedPassword); err != nil {
+		t.Errorf("expected fail-open to accept the password, got %v", err)
+	}
+	if !logs.Contains("Password breach check unavailable, accepting the password") {
+		t.Error("expected fail-open to warn")
+	}
+	for _, entry := range logs.Entries() {
+		line := fmt.Sprint(entry.Message, entry.Fields)
+		if strings.Contains(line, breachedPassword) || strings.Contains(strings.ToUpper(line), hash) {
+			t.Errorf("expected neither the password nor its hash logged, got %s", line)
+		}
+	}
+	if err := services.NewPwnedPasswords(client, server.URL, 0, true, logs).Check(ctx, breachedPassword); !errors.Is(err, services.ErrPasswordCheckUnavailable) {
+		t.Errorf("expected fail-closed to refuse the password, got %v", err)
+	}
+}
+
+// TestPasswordBreachCheckRoutes tests that registration, password changes
+// and admins setting passwords are screened
+func TestPasswordBreachCheckRoutes(t *testing.T) {
+	api := &rangeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.PasswordBreachCheck = true
+		cfg.Auth.PasswordBreachURL = server.URL
+		cfg.Auth.PasswordBreachTimeout = time.Second
+		cfg.Auth.PasswordBreachFailClosed = true
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"email": "breached@example.com", "username": "breached", "first_name": "Ann", "last_name": "Lee", "password": breachedPassword,
+	}, "")
+	expectErrorCode(t, resp, http.StatusUnprocessableEntity, apperrors.CodePasswordBreached)
+
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/change-password", map[string]string{
+		"current_password": user.Password, "new_password": breachedPassword,
+	}, user.Token)
+	expectErrorCode(t, resp, http.StatusUnprocessableEntity, apperrors.CodePasswordBreached)
+
+	resp = ta.Request(http.MethodPut, "/api/v1/users/"+user.ID.String(), map[string]string{"password": breachedPassword}, admin.Token)
+	expectErrorCode(t, resp, http.StatusUnprocessableEntity, apperrors.CodePasswordBreached)
+
+	resp = ta.Request(http.MethodPost, "/api/v1/users", map[string]string{
+		"email": "created@example.com", "password": breachedPassword,
+	}, admin.Token)
+	expectErrorCode(t, resp, http.StatusUnprocessableEntity, apperrors.CodePasswordBreached)
+
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"email": "fresh@example.com", "username": "fresh", "first_name": "Ann", "last_name": "Lee", "password": "a-password-nobody-used",
+	}, "")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	api.down.Store(true)
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"email": "later@example.com", "username": "later", "first_name": "Ann", "last_name": "Lee", "password": "another-password-nobody-used",
+	}, "")
+	expectErrorCode(t, resp, http.StatusServiceUnavailable, apperrors.CodePasswordCheckUnavailable)
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// passwordLogin is the body of a login or change-password response
+type passwordLogin struct {
+	Token           string      `json:"token"`
+	PasswordExpired bool        `json:"password_expired"`
+	User            models.User `json:"user"`
+}
+
+// loginWithPassword logs in and decodes the response, failing on anything but 200
+func loginWithPassword(t *testing.T, ta *apptest.TestApp, email, password string) passwordLogin {
+	t.Helper()
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": email, "password": password}, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var body passwordLogin
+	resp.Decode(t, &body)
+	return body
+}
+
+// TestPasswordExpired tests the expiry boundary: a password expires exactly
+// maxAge after it was changed
+func TestPasswordExpired(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	changed := created.AddDate(0, 1, 0)
+	maxAge := 90 * 24 * time.Hour
+
+	withChange := &models.User{CreatedAt: created, PasswordChangedAt: &changed}
+	neverChanged := &models.User{CreatedAt: created}
+
+	tests := []struct {
+		name   string
+		user   *models.User
+		maxAge time.Duration
+		now    time.Time
+		want   bool
+	}{
+		{"just before max age", withChange, maxAge, changed.Add(maxAge - time.Second), false},
+		{"exactly at max age", withChange, maxAge, changed.Add(maxAge), true},
+		{"after max age", withChange, maxAge, changed.Add(maxAge + time.Second), true},
+		{"zero max age disables expiry", withChange, 0, changed.AddDate(10, 0, 0), false},
+		{"never changed counts from creation", neverChanged, maxAge, created.Add(maxAge), true},
+		{"never changed before max age", neverChanged, maxAge, created.Add(maxAge - time.Second), false},
+	}
+	for _, tt := range tests {
+		if got := services.PasswordExpired(tt.user, tt.maxAge, tt.now); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+// TestExpiredPasswordLogin tests that an expired password still logs in but
+// only buys a token for changing it, and that changing it lifts the restriction
+func TestExpiredPasswordLogin(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.PasswordMaxAge = 24 * time.Hour
+	})
+	user := ta.CreateUser(models.RoleUser)
+
+	fresh := loginWithPassword(t, ta, user.Email, user.Password)
+	if fresh.PasswordExpired {
+		t.Fatal("a new password should not be expired")
+	}
+
+	changedAt := time.Now().Add(-25 * time.Hour)
+	if err := ta.DB().Model(&models.User{}).Where("id = ?", user.ID).Update("password_changed_at", changedAt).Error; err != nil {
+		t.Fatalf("backdate password: %v", err)
+	}
+
+	expired := loginWithPassword(t, ta, user.Email, user.Password)
+	if !expired.PasswordExpired || expired.Token == "" {
+		t.Fatalf("expected a restricted token for an expired password, got %+v", expired)
+	}
+
+	// The restricted token is refused everywhere but change-password
+	resp := ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String(), nil, expired.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodePasswordChangeRequired)
+	resp = ta.Request(http.MethodGet, "/api/v1/me/features", nil, expired.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodePasswordChangeRequired)
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": expired.Token}, "")
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodePasswordChangeRequired)
+
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/change-password", map[string]string{
+		"current_password": "wrong-password",
+		"new_password":     "new-password-1",
+	}, expired.Token)
+	expectErrorCode(t, resp, http.StatusBadRequest, errors.CodeInvalidCurrentPassword)
+
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/change-password", map[string]string{
+		"current_password": user.Password,
+		"new_password":     user.Password,
+	}, expired.Token)
+	expectErrorCode(t, resp, http.StatusBadRequest, errors.CodePasswordReused)
+
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/change-password", map[string]string{
+		"current_password": user.Password,
+		"new_password":     "new-password-1",
+	}, expired.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("change password: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var changed passwordLogin
+	resp.Decode(t, &changed)
+	if changed.PasswordExpired || changed.User.PasswordChangedAt == nil || !changed.User.PasswordChangedAt.After(changedAt) {
+		t.Fatalf("expected a fresh password change, got %+v", changed)
+	}
+
+	if resp := ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String(), nil, changed.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the new token to be unrestricted, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	if again := loginWithPassword(t, ta, user.Email, "new-password-1"); again.PasswordExpired {
+		t.Error("expected the new password to log in normally")
+	}
+}
+
+// TestRequirePasswordChange tests that admins can force a password change,
+// which revokes the user's tokens and restricts their next login
+func TestRequirePasswordChange(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	path := "/api/v1/users/" + user.ID.String() + "/require-password-change"
+	if resp := ta.Request(http.MethodPost, path, nil, user.Token); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected non-admins to be refused, got %d", resp.StatusCode)
+	}
+
+	resp := ta.Request(http.MethodPost, path, nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var body struct {
+		Data models.User `json:"data"`
+	}
+	resp.Decode(t, &body)
+	if !body.Data.MustChangePassword {
+		t.Errorf("expected the user to be flagged, got %+v", body.Data)
+	}
+
+	// Existing sessions end
+	resp = ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String(), nil, user.Token)
+	expectErrorCode(t, resp, http.StatusUnauthorized, errors.CodeTokenRevoked)
+
+	restricted := loginWithPassword(t, ta, user.Email, user.Password)
+	if !restricted.PasswordExpired {
+		t.Fatalf("expected a restricted token for a flagged user, got %+v", restricted)
+	}
+	resp = ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String(), nil, restricted.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodePasswordChangeRequired)
+
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/change-password", map[string]string{
+		"current_password": user.Password,
+		"new_password":     "new-password-1",
+	}, restricted.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("change password: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	var stored models.User
+	if err := ta.DB().Where("id = ?", user.ID).First(&stored).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	if stored.MustChangePassword {
+		t.Error("expected changing the password to clear the flag")
+	}
+	if again := loginWithPassword(t, ta, user.Email, "new-password-1"); again.PasswordExpired {
+		t.Error("expected the new password to log in normally")
+	}
+
+	resp = ta.Request(http.MethodPost, "/api/v1/users/"+uuid.NewString()+"/require-password-change", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusNotFound, errors.CodeUserNotFound)
+}
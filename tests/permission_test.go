@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakePermissionRepository is an in-memory PermissionRepository seeded with the defaults
+type fakePermissionRepository struct {
+	grants map[models.UserRole][]string
+	loads  int
+}
+
+func newFakePermissionRepository() *fakePermissionRepository {
+	grants := make(map[models.UserRole][]string)
+	for role, names := range models.DefaultRolePermissions {
+		grants[role] = append([]string(nil), names...)
+	}
+	return &fakePermissionRepository{grants: grants}
+}
+
+func (r *fakePermissionRepository) ListPermissions(ctx context.Context) ([]*models.Permission, error) {
+	permissions := make([]*models.Permission, len(models.DefaultPermissions))
+	for i := range models.DefaultPermissions {
+		permissions[i] = &models.DefaultPermissions[i]
+	}
+	return permissions, nil
+}
+
+func (r *fakePermissionRepository) ListRolePermissions(ctx context.Context, role models.UserRole) ([]string, error) {
+	r.loads++
+	return r.grants[role], nil
+}
+
+func (r *fakePermissionRepository) SetRolePermissions(ctx context.Context, role models.UserRole, permissions []string) error {
+	r.grants[role] = permissions
+	return nil
+}
+
+func newPermissionFixture() (*services.PermissionService, *fakePermissionRepository) {
+	repo := newFakePermissionRepository()
+	return services.NewPermissionService(repo, cache.NewMemoryStore(), logger.NewSimpleLogger()), repo
+}
+
+// TestPermissionDefaults tests the seeded role permissions
+func TestPermissionDefaults(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newPermissionFixture()
+
+	cases := []struct {
+		role       models.UserRole
+		permission string
+		want       bool
+	}{
+		{models.RoleAdmin, models.PermissionUsersDelete, true},
+		{models.RoleUser, models.PermissionUsersView, true},
+		{models.RoleUser, models.PermissionUsersDelete, false},
+		{models.RoleGuest, models.PermissionUsersView, false},
+	}
+
+	for _, tc := range cases {
+		got, err := svc.HasPermission(ctx, string(tc.role), tc.permission)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("HasPermission(%s, %s) = %v, want %v", tc.role, tc.permission, got, tc.want)
+		}
+	}
+}
+
+// TestUnknownPermissionFailsClosed tests that unknown names and roles are never granted
+func TestUnknownPermissionFailsClosed(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newPermissionFixture()
+
+	if ok, err := svc.HasPermission(ctx, string(models.RoleAdmin), "users.delte"); ok || err != nil {
+		t.Fatalf("expected unknown permission to be denied, got %v (err %v)", ok, err)
+	}
+	if ok, err := svc.HasPermission(ctx, "superadmin", models.PermissionUsersView); ok || err != nil {
+		t.Fatalf("expected unknown role to be denied, got %v (err %v)", ok, err)
+	}
+
+	_, err := svc.SetRolePermissions(ctx, models.RoleUser, []string{"users.view", "users.fly"})
+	if !errors.Is(err, services.ErrUnknownPermission) {
+		t.Fatalf("expected ErrUnknownPermission, got %v", err)
+	}
+}
+
+// TestPermissionCacheInvalidation tests that role permissions are cached and invalidated on change
+func TestPermissionCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newPermissionFixture()
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.HasPermission(ctx, string(models.RoleUser), models.PermissionUsersDelete); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if repo.loads != 1 {
+		t.Fatalf("expected role permissions to be loaded once, got %d", repo.loads)
+	}
+
+	if _, err := svc.SetRolePermissions(ctx, models.RoleUser, []string{models.PermissionUsersView, models.PermissionUsersDelete}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ok, err := svc.HasPermission(ctx, string(models.RoleUser), models.PermissionUsersDelete)
+	if err != nil || !ok {
+		t.Fatalf("expected granted permission to apply immediately, got %v (err %v)", ok, err)
+	}
+	if repo.loads != 2 {
+		t.Fatalf("expected cache to be invalidated, got %d loads", repo.loads)
+	}
+}
+
+// TestRequirePermissionDenies tests the permission middleware on a user route
+func TestRequirePermissionDenies(t *testing.T) {
+	svc, _ := newPermissionFixture()
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(role models.UserRole) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set(middleware.ClaimsKey, &services.TokenClaims{UserID: "u1", Role: string(role)})
+			c.Next()
+		})
+		router.DELETE("/users/:id", middleware.RequirePermission(svc, models.PermissionUsersDelete), func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+		return router
+	}
+
+	w := httptest.NewRecorder()
+	newRouter(models.RoleUser).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for user, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	newRouter(models.RoleAdmin).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for admin, got %d", w.Code)
+	}
+}
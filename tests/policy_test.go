@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+)
+
+// setPolicyVersions stores the current policy versions as admin
+func setPolicyVersions(t *testing.T, ta *apptest.TestApp, admin *apptest.User, versions map[string]string) {
+	t.Helper()
+	body := map[string]interface{}{"settings": map[string]interface{}{models.SettingPolicyVersions: versions}}
+	if resp := ta.Request(http.MethodPut, "/api/v1/admin/settings", body, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("set policy versions: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+}
+
+// pendingPolicies fetches the policies /me reports token's user has yet to accept
+func pendingPolicies(t *testing.T, ta *apptest.TestApp, token string) []services.PendingPolicy {
+	t.Helper()
+	resp := ta.Request(http.MethodGet, "/api/v1/me", nil, token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("get me: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var body struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+		PendingPolicies []services.PendingPolicy `json:"pending_policies"`
+	}
+	resp.Decode(t, &body)
+	if body.Data.ID == "" || body.PendingPolicies == nil {
+		t.Fatalf("expected the user and their pending policies, got %s", resp.Body)
+	}
+	return body.PendingPolicies
+}
+
+// acceptPolicy accepts version of policy, failing on anything but 200
+func acceptPolicy(t *testing.T, ta *apptest.TestApp, token, policy, version string) string {
+	t.Helper()
+	resp := ta.Request(http.MethodPost, "/api/v1/me/accept-policy", map[string]string{"policy": policy, "version": version}, token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("accept %s %s: expected 200, got %d: %s", policy, version, resp.StatusCode, resp.Body)
+	}
+	var body struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	resp.Decode(t, &body)
+	return body.Data.ID
+}
+
+// TestPolicyVersionBump tests that users accept the current version of each
+// policy and are asked again once a version is bumped
+func TestPolicyVersionBump(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	if pending := pendingPolicies(t, ta, user.Token); len(pending) != 0 {
+		t.Fatalf("expected nothing to accept before policies are configured, got %v", pending)
+	}
+
+	setPolicyVersions(t, ta, admin, map[string]string{"tos": "2024-01", "privacy": "2024-01"})
+	want := []services.PendingPolicy{{Policy: "privacy", Version: "2024-01"}, {Policy: "tos", Version: "2024-01"}}
+	if pending := pendingPolicies(t, ta, user.Token); !reflect.DeepEqual(pending, want) {
+		t.Fatalf("expected %v pending, got %v", want, pending)
+	}
+
+	// Logins list the pending policies too
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": user.Email, "password": user.Password}, "")
+	var loginBody struct {
+		PendingPolicies []services.PendingPolicy `json:"pending_policies"`
+	}
+	resp.Decode(t, &loginBody)
+	if !reflect.DeepEqual(loginBody.PendingPolicies, want) {
+		t.Errorf("expected the login to list %v, got %s", want, resp.Body)
+	}
+
+	// Only the current version of a known policy can be accepted
+	resp = ta.Request(http.MethodPost, "/api/v1/me/accept-policy", map[string]string{"policy": "tos", "version": "2023-01"}, user.Token)
+	expectErrorCode(t, resp, http.StatusConflict, errors.CodePolicyVersionOutdated)
+	resp = ta.Request(http.MethodPost, "/api/v1/me/accept-policy", map[string]string{"policy": "cookies", "version": "2024-01"}, user.Token)
+	expectErrorCode(t, resp, http.StatusBadRequest, errors.CodeUnknownPolicy)
+
+	first := acceptPolicy(t, ta, user.Token, "tos", "2024-01")
+	if again := acceptPolicy(t, ta, user.Token, "tos", "2024-01"); again != first {
+		t.Errorf("expected accepting twice to return the first acceptance %s, got %s", first, again)
+	}
+	acceptPolicy(t, ta, user.Token, "privacy", "2024-01")
+	if pending := pendingPolicies(t, ta, user.Token); len(pending) != 0 {
+		t.Fatalf("expected every policy accepted, got %v", pending)
+	}
+
+	var stored models.PolicyAcceptance
+	if err := ta.DB().Where("id = ?", first).First(&stored).Error; err != nil {
+		t.Fatalf("load acceptance: %v", err)
+	}
+	if stored.UserID != user.ID || stored.IPAddress == "" || stored.AcceptedAt.IsZero() {
+		t.Errorf("expected the acceptance with its user, IP and time, got %+v", stored)
+	}
+	if n := auditCount(t, ta, models.AuditActionUserPolicyAccepted, user, user.ID.String()); n != 2 {
+		t.Errorf("expected 2 audited acceptances, got %d", n)
+	}
+
+	// Bumping the version asks for acceptance again
+	setPolicyVersions(t, ta, admin, map[string]string{"tos": "2024-06", "privacy": "2024-01"})
+	want = []services.PendingPolicy{{Policy: "tos", Version: "2024-06"}}
+	if pending := pendingPolicies(t, ta, user.Token); !reflect.DeepEqual(pending, want) {
+		t.Fatalf("expected %v pending after the bump, got %v", want, pending)
+	}
+	resp = ta.Request(http.MethodPost, "/api/v1/me/accept-policy", map[string]string{"policy": "tos", "version": "2024-01"}, user.Token)
+	expectErrorCode(t, resp, http.StatusConflict, errors.CodePolicyVersionOutdated)
+	acceptPolicy(t, ta, user.Token, "tos", "2024-06")
+
+	// The report counts the admin as a user with everything pending
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/policies", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("report: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var report struct {
+		Data services.PolicyReport `json:"data"`
+	}
+	resp.Decode(t, &report)
+	if report.Data.Users != 2 || len(report.Data.Policies) != 2 {
+		t.Fatalf("unexpected report %s", resp.Body)
+	}
+	tos := report.Data.Policies[1]
+	if tos.Policy != "tos" || tos.CurrentVersion != "2024-06" || tos.Accepted != 1 || tos.Pending != 1 {
+		t.Errorf("unexpected tos status %+v", tos)
+	}
+	wantVersions := []services.PolicyVersionCount{{Version: "2024-01", Users: 1}, {Version: "2024-06", Users: 1}}
+	if !reflect.DeepEqual(tos.Versions, wantVersions) {
+		t.Errorf("expected tos versions %v, got %v", wantVersions, tos.Versions)
+	}
+
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/policies", nil, user.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodeInsufficientPermissions)
+}
+
+// TestPolicyAcceptanceGate tests that AUTH_REQUIRE_POLICY_ACCEPTANCE refuses
+// requests outside /auth and /me until the current policies are accepted
+func TestPolicyAcceptanceGate(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.RequirePolicyAcceptance = true
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	if resp := ta.Request(http.MethodGet, "/api/v1/users", nil, user.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 before policies are configured, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	setPolicyVersions(t, ta, admin, map[string]string{"tos": "2024-06"})
+	resp := ta.Request(http.MethodGet, "/api/v1/users", nil, user.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodePolicyAcceptanceRequired)
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/settings", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodePolicyAcceptanceRequired)
+
+	// The user can still see and accept what is pending
+	if pending := pendingPolicies(t, ta, user.Token); len(pending) != 1 {
+		t.Fatalf("expected tos pending, got %v", pending)
+	}
+	acceptPolicy(t, ta, user.Token, "tos", "2024-06")
+	if resp := ta.Request(http.MethodGet, "/api/v1/users", nil, user.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 once accepted, got %d: %s", resp.StatusCode, resp.Body)
+	}
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+)
+
+// negotiatedRequest sends a request with an Accept header and returns the
+// response
+func negotiatedRequest(t *testing.T, ta *apptest.TestApp, method, path, accept string, body interface{}, token string) *apptest.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, ta.Server.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "de")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := ta.Server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	return &apptest.Response{StatusCode: res.StatusCode, Header: res.Header, Body: data}
+}
+
+// expectProblemMatches checks that problem carries what envelope does
+func expectProblemMatches(t *testing.T, resp *apptest.Response, envelope errorEnvelope, path string) middleware.Problem {
+	t.Helper()
+
+	if got := resp.Header.Get("Content-Type"); got != middleware.ProblemContentType {
+		t.Errorf("expected %s, got %q", middleware.ProblemContentType, got)
+	}
+	var problem middleware.Problem
+	resp.Decode(t, &problem)
+
+	code := errors.Code(envelope.Error.Code)
+	if problem.Code != envelope.Error.Code || problem.Detail != envelope.Error.Message || problem.Status != resp.StatusCode {
+		t.Errorf("expected code %s, detail %q and status %d, got %+v", code, envelope.Error.Message, resp.StatusCode, problem)
+	}
+	if problem.Type != middleware.DefaultProblemTypeBase+envelope.Error.Code || problem.Title != errors.Describe(code) {
+		t.Errorf("expected the catalogue's type and title for %s, got %q %q", code, problem.Type, problem.Title)
+	}
+	if problem.Instance != path {
+		t.Errorf("expected instance %s, got %q", path, problem.Instance)
+	}
+	if problem.RequestID == "" || problem.RequestID != resp.Header.Get(middleware.RequestIDHeader) {
+		t.Errorf("expected the request ID %q, got %q", resp.Header.Get(middleware.RequestIDHeader), problem.RequestID)
+	}
+	if !reflect.DeepEqual(problem.Details, envelope.Error.Details) {
+		t.Errorf("expected details %+v, got %+v", envelope.Error.Details, problem.Details)
+	}
+	return problem
+}
+
+// TestProblemDocuments tests that failing endpoints answer equivalent
+// problem documents to clients that prefer them
+func TestProblemDocuments(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		token  string
+		status int
+	}{
+		{"validation", http.MethodPost, "/api/v1/auth/register", map[string]string{"email": "not-an-email", "password": "123"}, "", http.StatusUnprocessableEntity},
+		{"middleware", http.MethodGet, "/api/v1/me/features", nil, "not-a-token", http.StatusUnauthorized},
+		{"forbidden", http.MethodGet, "/api/v1/admin/request-logs", nil, user.Token, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := negotiatedRequest(t, ta, tc.method, tc.path, "", tc.body, tc.token)
+			if resp.StatusCode != tc.status || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+				t.Fatalf("expected a %d JSON envelope, got %d %s", tc.status, resp.StatusCode, resp.Header.Get("Content-Type"))
+			}
+			var envelope errorEnvelope
+			resp.Decode(t, &envelope)
+
+			resp = negotiatedRequest(t, ta, tc.method, tc.path, middleware.ProblemContentType, tc.body, tc.token)
+			if resp.StatusCode != tc.status {
+				t.Fatalf("expected %d, got %d", tc.status, resp.StatusCode)
+			}
+			problem := expectProblemMatches(t, resp, envelope, tc.path)
+			if tc.name == "validation" && len(problem.Details) == 0 {
+				t.Error("expected the validation details")
+			}
+		})
+	}
+}
+
+// TestProblemNegotiation tests which Accept headers get problem documents
+func TestProblemNegotiation(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+
+	for _, tc := range []struct {
+		accept  string
+		problem bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"application/problem+json", true},
+		{"application/problem+json, */*", true},
+		{"application/json, application/problem+json", false},
+		{"application/json;q=0.5, application/problem+json", true},
+		{"application/problem+json;q=0.5, application/json", false},
+		{"application/problem+json;q=0, */*", false},
+		{"application/*, application/problem+json;q=0.9", false},
+	} {
+		resp := negotiatedRequest(t, ta, http.MethodGet, "/api/v1/me/features", tc.accept, nil, "")
+		got := resp.Header.Get("Content-Type") == middleware.ProblemContentType
+		if got != tc.problem {
+			t.Errorf("Accept %q: expected a problem document %v, got %s", tc.accept, tc.problem, resp.Body)
+		}
+		if resp.StatusCode != http.StatusUnauthorized || !strings.Contains(resp.Header.Get("Vary"), "Accept") {
+			t.Errorf("Accept %q: expected a 401 varying on Accept, got %d %v", tc.accept, resp.StatusCode, resp.Header["Vary"])
+		}
+	}
+}
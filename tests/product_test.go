@@ -0,0 +1,246 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/crud"
+	"BackofficeGoService/internal/app/models"
+	apperrors "BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+// productsPath is where products are served
+const productsPath = "/api/v1/products"
+
+// createProduct creates a product through the API and returns it
+func createProduct(t *testing.T, ta *apptest.TestApp, admin *apptest.User, body map[string]interface{}) *models.Product {
+	t.Helper()
+
+	resp := ta.Request(http.MethodPost, productsPath, body, admin.Token)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create product: %d %s", resp.StatusCode, resp.Body)
+	}
+	var created struct {
+		Data models.Product `json:"data"`
+	}
+	resp.Decode(t, &created)
+	return &created.Data
+}
+
+// TestProductCRUD tests creating, reading, updating and deleting a product
+// and the audit entries of the changes
+func TestProductCRUD(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	created := createProduct(t, ta, admin, map[string]interface{}{"name": "Desk lamp", "sku": "LAMP-1", "price": 0})
+	if !created.Active || created.Price != 0 || created.SKU != "LAMP-1" {
+		t.Errorf("expected an active free LAMP-1, got %+v", created)
+	}
+	id := created.ID.String()
+	if n := auditCount(t, ta, "product.created", admin, id); n != 1 {
+		t.Errorf("expected one product.created entry, got %d", n)
+	}
+
+	resp := ta.Request(http.MethodPost, productsPath, map[string]interface{}{"name": "Other lamp", "sku": "LAMP-1", "price": 100}, admin.Token)
+	expectErrorCode(t, resp, http.StatusConflict, apperrors.CodeProductSKUTaken)
+	resp = ta.Request(http.MethodPost, productsPath, map[string]interface{}{"name": "No price", "sku": "LAMP-2"}, admin.Token)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 without a price, got %d %s", resp.StatusCode, resp.Body)
+	}
+
+	resp = ta.Request(http.MethodPut, productsPath+"/"+id, map[string]interface{}{"price": 2500, "name": "Desk lamp"}, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update product: %d %s", resp.StatusCode, resp.Body)
+	}
+	var entry models.AuditLog
+	if err := ta.DB().Where("action = ? AND entity_id = ?", "product.updated", id).First(&entry).Error; err != nil {
+		t.Fatalf("expected a product.updated entry: %v", err)
+	}
+	if got := string(entry.Metadata); got != `{"fields":["price"]}` {
+		t.Errorf("expected only price to change, got %s", got)
+	}
+
+	other := createProduct(t, ta, admin, map[string]interface{}{"name": "Desk", "sku": "DESK-1", "price": 9900})
+	resp = ta.Request(http.MethodPut, productsPath+"/"+other.ID.String(), map[string]interface{}{"sku": "LAMP-1"}, admin.Token)
+	expectErrorCode(t, resp, http.StatusConflict, apperrors.CodeProductSKUTaken)
+
+	resp = ta.Request(http.MethodGet, productsPath+"/"+id, nil, admin.Token)
+	var got struct {
+		Data models.Product `json:"data"`
+	}
+	resp.Decode(t, &got)
+	if resp.StatusCode != http.StatusOK || got.Data.Price != 2500 {
+		t.Errorf("expected the updated price, got %d %s", resp.StatusCode, resp.Body)
+	}
+
+	resp = ta.Request(http.MethodDelete, productsPath+"/"+id, nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete product: %d %s", resp.StatusCode, resp.Body)
+	}
+	if n := auditCount(t, ta, "product.deleted", admin, id); n != 1 {
+		t.Errorf("expected one product.deleted entry, got %d", n)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		resp = ta.Request(method, productsPath+"/"+id, nil, admin.Token)
+		expectErrorCode(t, resp, http.StatusNotFound, apperrors.CodeProductNotFound)
+	}
+	resp = ta.Request(http.MethodGet, productsPath+"/not-a-uuid", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusNotFound, apperrors.CodeProductNotFound)
+}
+
+// TestProductPermissions tests that users may view but not change products
+func TestProductPermissions(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+	guest := ta.CreateUser(models.RoleGuest)
+	p := createProduct(t, ta, admin, map[string]interface{}{"name": "Chair", "sku": "CHAIR-1", "price": 4900})
+
+	if resp := ta.Request(http.MethodGet, productsPath+"/"+p.ID.String(), nil, user.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected users to view products, got %d", resp.StatusCode)
+	}
+	for _, req := range []struct {
+		method, path string
+		token        string
+	}{
+		{http.MethodPost, productsPath, user.Token},
+		{http.MethodPut, productsPath + "/" + p.ID.String(), user.Token},
+		{http.MethodDelete, productsPath + "/" + p.ID.String(), user.Token},
+		{http.MethodGet, productsPath, guest.Token},
+	} {
+		resp := ta.Request(req.method, req.path, map[string]interface{}{"name": "Stool", "sku": "STOOL-1", "price": 1}, req.token)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s %s: expected 403, got %d", req.method, req.path, resp.StatusCode)
+		}
+	}
+	if resp := ta.Request(http.MethodGet, productsPath, nil, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", resp.StatusCode)
+	}
+}
+
+// TestProductList tests filtering, sorting and paginating products
+func TestProductList(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	for _, p := range []map[string]interface{}{
+		{"name": "Oak desk", "sku": "DESK-OAK", "price": 30000},
+		{"name": "Pine desk", "sku": "DESK-PINE", "price": 15000, "active": false},
+		{"name": "Office chair", "sku": "CHAIR-1", "price": 8000},
+	} {
+		createProduct(t, ta, admin, p)
+	}
+
+	list := func(query string) ([]string, int64) {
+		t.Helper()
+		resp := ta.Request(http.MethodGet, productsPath+query, nil, admin.Token)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("list %q: %d %s", query, resp.StatusCode, resp.Body)
+		}
+		var body struct {
+			Data []models.Product `json:"data"`
+			Meta pagination.Meta  `json:"meta"`
+		}
+		resp.Decode(t, &body)
+		skus := make([]string, len(body.Data))
+		for i, p := range body.Data {
+			skus[i] = p.SKU
+		}
+		return skus, body.Meta.Total
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  string
+		total int64
+	}{
+		{"", "DESK-OAK,CHAIR-1,DESK-PINE", 3},
+		{"?sort=-price", "DESK-OAK,DESK-PINE,CHAIR-1", 3},
+		{"?active=true&sort=price", "CHAIR-1,DESK-OAK", 2},
+		{"?name=DESK&sort=sku", "DESK-OAK,DESK-PINE", 2},
+		{"?name=pine%25", "DESK-PINE", 1},
+		{"?sku=CHAIR-1", "CHAIR-1", 1},
+		{"?sort=price&limit=2&page=2", "DESK-OAK", 3},
+		{"?unknown=1&sku=CHAIR-1", "CHAIR-1", 1},
+	} {
+		skus, total := list(tc.query)
+		if got := strings.Join(skus, ","); got != tc.want || total != tc.total {
+			t.Errorf("%q: expected %s of %d, got %s of %d", tc.query, tc.want, tc.total, got, total)
+		}
+	}
+
+	resp := ta.Request(http.MethodGet, productsPath+"?sort=secret", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusBadRequest, apperrors.CodeInvalidSort)
+	resp = ta.Request(http.MethodGet, productsPath+"?active=maybe", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusBadRequest, apperrors.CodeInvalidFilter)
+}
+
+// TestProductListCache tests that changes purge cached product lists
+func TestProductListCache(t *testing.T) {
+	ta := apptest.NewTestApp(t, withResponseCache)
+	admin := ta.CreateUser(models.RoleAdmin)
+	createProduct(t, ta, admin, map[string]interface{}{"name": "Lamp", "sku": "LAMP-1", "price": 100})
+
+	if resp := ta.Request(http.MethodGet, productsPath, nil, admin.Token); resp.Header.Get("X-Cache") != "MISS" {
+		t.Fatalf("expected a cache miss, got %q", resp.Header.Get("X-Cache"))
+	}
+	if resp := ta.Request(http.MethodGet, productsPath, nil, admin.Token); resp.Header.Get("X-Cache") != "HIT" {
+		t.Fatalf("expected a cache hit, got %q", resp.Header.Get("X-Cache"))
+	}
+	createProduct(t, ta, admin, map[string]interface{}{"name": "Desk", "sku": "DESK-1", "price": 100})
+	resp := ta.Request(http.MethodGet, productsPath, nil, admin.Token)
+	var body struct {
+		Data []models.Product `json:"data"`
+	}
+	resp.Decode(t, &body)
+	if resp.Header.Get("X-Cache") != "MISS" || len(body.Data) != 2 {
+		t.Errorf("expected the new product after a miss, got %q with %d products", resp.Header.Get("X-Cache"), len(body.Data))
+	}
+}
+
+// TestCRUDCustomHandlers tests that a custom handler replaces the generic
+// one of its operation while the others stay
+func TestCRUDCustomHandlers(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	products := crud.New(ta.App.GetDBManager(), crud.Config[models.Product]{
+		Path:       "/products",
+		EntityType: "product",
+		Permission: "products",
+		Handlers: map[crud.Operation]gin.HandlerFunc{
+			crud.Delete: func(c *gin.Context) {
+				c.JSON(http.StatusMethodNotAllowed, gin.H{"message": "products are archived, not deleted"})
+			},
+		},
+	})
+
+	permissions := map[string]string{
+		http.MethodGet:    models.PermissionProductsView,
+		http.MethodPost:   models.PermissionProductsCreate,
+		http.MethodPut:    models.PermissionProductsUpdate,
+		http.MethodDelete: models.PermissionProductsDelete,
+	}
+	router := gin.New()
+	for _, def := range products.Routes() {
+		if def.Policy.Permission != permissions[def.Method] {
+			t.Errorf("%s %s: expected %s, got %s", def.Method, def.Path, permissions[def.Method], def.Policy.Permission)
+		}
+		router.Handle(def.Method, def.Path, def.Handler)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/products/any", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected the custom delete handler, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"data":[]`) {
+		t.Errorf("expected the generic list handler, got %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+)
+
+// profileLogin is the body of a login or profile update response
+type profileLogin struct {
+	Token                     string   `json:"token"`
+	ProfileComplete           bool     `json:"profile_complete"`
+	MissingProfileFields      []string `json:"missing_profile_fields"`
+	ProfileCompletionRequired bool     `json:"profile_completion_required"`
+}
+
+// blankProfile clears the names of user, leaving their profile incomplete
+func blankProfile(t *testing.T, ta *apptest.TestApp, user *apptest.User) {
+	t.Helper()
+	if err := ta.DB().Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{"first_name": "", "last_name": "  "}).Error; err != nil {
+		t.Fatalf("blank profile: %v", err)
+	}
+}
+
+// loginProfile logs in and decodes the response, failing on anything but 200
+func loginProfile(t *testing.T, ta *apptest.TestApp, user *apptest.User) profileLogin {
+	t.Helper()
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": user.Email, "password": user.Password}, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var body profileLogin
+	resp.Decode(t, &body)
+	return body
+}
+
+// TestNewProfileServiceRejectsUnknownFields tests that required fields are
+// validated against roles and profile fields
+func TestNewProfileServiceRejectsUnknownFields(t *testing.T) {
+	if _, err := services.NewProfileService(nil, map[string][]string{"*": {"email"}}); err == nil {
+		t.Error("expected an unknown field to be rejected")
+	}
+	if _, err := services.NewProfileService(nil, map[string][]string{"owner": {"first_name"}}); err == nil {
+		t.Error("expected an unknown role to be rejected")
+	}
+
+	profiles, err := services.NewProfileService(nil, map[string][]string{
+		"*":     {"last_name", "first_name"},
+		"admin": {"username"},
+	})
+	if err != nil {
+		t.Fatalf("new profile service: %v", err)
+	}
+	if got := profiles.RequiredFields(models.RoleUser); !slices.Equal(got, []string{"first_name", "last_name"}) {
+		t.Errorf("expected the default fields in profile order, got %v", got)
+	}
+	status := profiles.Evaluate(&models.User{Role: models.RoleAdmin, FirstName: "", Username: " "})
+	if status.Complete || !slices.Equal(status.Missing, []string{"username"}) {
+		t.Errorf("expected admins to need only a username, got %+v", status)
+	}
+}
+
+// TestProfileCompletionEnforced tests that an incomplete profile only buys
+// a token for completing it, and that completing it lifts the restriction
+// on the very next request
+func TestProfileCompletionEnforced(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.ProfileRequiredFields = map[string][]string{"*": {"first_name", "last_name"}}
+		cfg.Auth.ProfileEnforce = true
+	})
+	user := ta.CreateUser(models.RoleUser)
+	blankProfile(t, ta, user)
+
+	login := loginProfile(t, ta, user)
+	if !login.ProfileCompletionRequired || login.ProfileComplete || !slices.Equal(login.MissingProfileFields, []string{"first_name", "last_name"}) {
+		t.Fatalf("expected a restricted login listing the missing fields, got %+v", login)
+	}
+
+	// The restricted token is refused everywhere but showing and updating the profile
+	resp := ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String(), nil, login.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodeProfileIncomplete)
+	resp = ta.Request(http.MethodGet, "/api/v1/me/features", nil, login.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodeProfileIncomplete)
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": login.Token}, "")
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodeProfileIncomplete)
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/change-password", map[string]string{
+		"current_password": user.Password,
+		"new_password":     "new-password-1",
+	}, login.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodeProfileIncomplete)
+
+	resp = ta.Request(http.MethodGet, "/api/v1/me", nil, login.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("get me: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var me profileLogin
+	resp.Decode(t, &me)
+	if me.ProfileComplete || !slices.Equal(me.MissingProfileFields, []string{"first_name", "last_name"}) {
+		t.Errorf("expected /me to list the missing fields, got %+v", me)
+	}
+
+	// Only profile fields can be changed through /me
+	resp = ta.Request(http.MethodPut, "/api/v1/me", map[string]interface{}{"first_name": "Ada", "active": false}, login.Token)
+	expectErrorCode(t, resp, http.StatusBadRequest, errors.CodeUnknownFields)
+
+	resp = ta.Request(http.MethodPut, "/api/v1/me", map[string]string{"first_name": "Ada"}, login.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update me: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var partial profileLogin
+	resp.Decode(t, &partial)
+	if partial.Token != "" || !partial.ProfileCompletionRequired || !slices.Equal(partial.MissingProfileFields, []string{"last_name"}) {
+		t.Fatalf("expected the profile to stay incomplete, got %+v", partial)
+	}
+
+	resp = ta.Request(http.MethodPut, "/api/v1/me", map[string]string{"last_name": "Lovelace"}, login.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update me: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var completed profileLogin
+	resp.Decode(t, &completed)
+	if completed.Token == "" || !completed.ProfileComplete || completed.ProfileCompletionRequired || len(completed.MissingProfileFields) != 0 {
+		t.Fatalf("expected an unrestricted token for the completed profile, got %+v", completed)
+	}
+
+	if resp := ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String(), nil, completed.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the new token to be unrestricted, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	resp = ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String(), nil, login.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodeProfileIncomplete)
+
+	if again := loginProfile(t, ta, user); again.ProfileCompletionRequired || !again.ProfileComplete {
+		t.Errorf("expected the completed profile to log in normally, got %+v", again)
+	}
+}
+
+// TestProfileCompletionReported tests that without enforcement incomplete
+// profiles are reported but their tokens are not restricted
+func TestProfileCompletionReported(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.ProfileRequiredFields = map[string][]string{"*": {"first_name"}, "admin": {}}
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+	ta.CreateUser(models.RoleUser)
+	blankProfile(t, ta, user)
+
+	login := loginProfile(t, ta, user)
+	if login.ProfileCompletionRequired || login.ProfileComplete || !slices.Equal(login.MissingProfileFields, []string{"first_name"}) {
+		t.Fatalf("expected an unrestricted login listing the missing field, got %+v", login)
+	}
+	if resp := ta.Request(http.MethodGet, "/api/v1/me/features", nil, login.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the token to be unrestricted, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	resp := ta.Request(http.MethodGet, "/api/v1/admin/profiles", nil, user.Token)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected non-admins to be refused the report, got %d", resp.StatusCode)
+	}
+
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/profiles", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("profile report: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var report struct {
+		Data services.ProfileReport `json:"data"`
+	}
+	resp.Decode(t, &report)
+	if report.Data.Users != 3 || report.Data.Complete != 2 {
+		t.Errorf("expected 2 of 3 users complete, got %+v", report.Data)
+	}
+	for _, role := range report.Data.Roles {
+		switch role.Role {
+		case models.RoleAdmin:
+			if len(role.RequiredFields) != 0 || role.Complete != 1 || role.CompletionRate != 1 {
+				t.Errorf("expected admins to need nothing, got %+v", role)
+			}
+		case models.RoleUser:
+			if role.Users != 2 || role.Complete != 1 || role.Missing["first_name"] != 1 || role.CompletionRate != 0.5 {
+				t.Errorf("expected one of two users to miss a first name, got %+v", role)
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/requestctx"
+
+	"gorm.io/gorm"
+)
+
+// sqlRecorder keeps the SQL text GORM statements reached the connection with
+type sqlRecorder struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (r *sqlRecorder) record(query string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, query)
+}
+
+func (r *sqlRecorder) all() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.statements...)
+}
+
+// recordingPool records statements before passing them on to the connection
+type recordingPool struct {
+	gorm.ConnPool
+	recorder *sqlRecorder
+}
+
+func (p recordingPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	p.recorder.record(query)
+	return p.ConnPool.PrepareContext(ctx, query)
+}
+
+func (p recordingPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	p.recorder.record(query)
+	return p.ConnPool.ExecContext(ctx, query, args...)
+}
+
+func (p recordingPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	p.recorder.record(query)
+	return p.ConnPool.QueryContext(ctx, query, args...)
+}
+
+func (p recordingPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	p.recorder.record(query)
+	return p.ConnPool.QueryRowContext(ctx, query, args...)
+}
+
+// recordSQL records the SQL of db's queries as sent to the connection, so
+// after anything the manager's callbacks add to it
+func recordSQL(t *testing.T, db *gorm.DB) *sqlRecorder {
+	t.Helper()
+	recorder := &sqlRecorder{}
+	wrap := func(tx *gorm.DB) {
+		tx.Statement.ConnPool = recordingPool{ConnPool: tx.Statement.ConnPool, recorder: recorder}
+	}
+	callbacks := db.Callback()
+	for _, processor := range []interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}{
+		callbacks.Query().Before("*"),
+		callbacks.Row().Before("*"),
+		callbacks.Raw().Before("*"),
+	} {
+		if err := processor.Register("test:record_sql", wrap); err != nil {
+			t.Fatalf("register callback: %v", err)
+		}
+	}
+	return recorder
+}
+
+// newAnnotatedManager registers a SQLite driver on a manager with query
+// annotations set to enabled
+func newAnnotatedManager(t *testing.T, enabled bool) (*database.Manager, *gorm.DB) {
+	t.Helper()
+	manager := database.NewManager()
+	manager.SetQueryAnnotations(enabled)
+	mock := databasetest.NewMockDriver(t, databasetest.WithSQLite(&models.User{}))
+	if err := manager.AddDriver("primary", mock); err != nil {
+		t.Fatalf("add driver: %v", err)
+	}
+	return manager, mock.GormDB()
+}
+
+// hostileContext is a request context whose route and request ID try to
+// close the annotation and smuggle in SQL and bind parameters
+func hostileContext() context.Context {
+	ctx := requestctx.WithRoute(context.Background(), requestctx.Route{
+		Method:   "GET",
+		Template: "/api/v1/users/*/ DROP TABLE users; -- '$1' ?" + strings.Repeat("x", 500),
+	})
+	return requestctx.WithRequestID(ctx, "req-1 */ DELETE FROM users /*")
+}
+
+// expectSanitizedAnnotation fails unless query starts with a single, closed
+// annotation of bounded length that carries none of the hostile characters
+func expectSanitizedAnnotation(t *testing.T, query string) {
+	t.Helper()
+	if !strings.HasPrefix(query, "/* route=/api/v1/users/") {
+		t.Fatalf("expected the query to start with its annotation, got %q", query)
+	}
+	end := strings.Index(query, "*/")
+	if end < 0 {
+		t.Fatalf("expected the annotation to be closed, got %q", query)
+	}
+	comment := query[:end+2]
+	if strings.Contains(query[end+2:], "*/") || strings.Count(query, "/*") != 1 {
+		t.Errorf("expected a single annotation, got %q", query)
+	}
+	for _, hostile := range []string{"'", "$", "?", ";", "DROP TABLE", "DELETE FROM"} {
+		if strings.Contains(comment, hostile) {
+			t.Errorf("expected %q to be sanitized from the annotation, got %q", hostile, comment)
+		}
+	}
+	if len(comment) > 300 {
+		t.Errorf("expected the annotation to be capped, got %d bytes", len(comment))
+	}
+	if !strings.Contains(comment, " method=GET req=req-1_") {
+		t.Errorf("expected the method and request ID in the annotation, got %q", comment)
+	}
+}
+
+// TestQueryAnnotationsSanitizeHostileValues tests that GORM statements and
+// raw SQL both carry the annotation, with hostile route templates and
+// request IDs neutralized, and that the annotated SQL still runs
+func TestQueryAnnotationsSanitizeHostileValues(t *testing.T) {
+	manager, db := newAnnotatedManager(t, true)
+	recorder := recordSQL(t, db)
+	ctx := hostileContext()
+
+	var count int64
+	if err := db.WithContext(ctx).Model(&models.User{}).Count(&count).Error; err != nil {
+		t.Fatalf("gorm query: %v", err)
+	}
+	statements := recorder.all()
+	if len(statements) != 1 {
+		t.Fatalf("expected one recorded statement, got %q", statements)
+	}
+	expectSanitizedAnnotation(t, statements[0])
+
+	query := manager.Annotate(ctx, database.Rebind(database.DriverSQLite, "SELECT COUNT(*) FROM users WHERE email = ?"))
+	expectSanitizedAnnotation(t, query)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	if err := sqlDB.QueryRowContext(ctx, query, "nobody@example.com").Scan(&count); err != nil {
+		t.Fatalf("expected the annotated raw query to run: %v", err)
+	}
+}
+
+// TestQueryAnnotationsDisabled tests that statements are sent as written
+// while annotations are off, and outside a request when they are on
+func TestQueryAnnotationsDisabled(t *testing.T) {
+	manager, db := newAnnotatedManager(t, false)
+	recorder := recordSQL(t, db)
+
+	var count int64
+	if err := db.WithContext(hostileContext()).Model(&models.User{}).Count(&count).Error; err != nil {
+		t.Fatalf("gorm query: %v", err)
+	}
+	for _, statement := range recorder.all() {
+		if strings.Contains(statement, "/*") {
+			t.Errorf("expected no annotation while disabled, got %q", statement)
+		}
+	}
+	if query := manager.Annotate(hostileContext(), "SELECT 1"); query != "SELECT 1" {
+		t.Errorf("expected raw SQL to be left alone while disabled, got %q", query)
+	}
+
+	manager.SetQueryAnnotations(true)
+	if query := manager.Annotate(context.Background(), "SELECT 1"); query != "SELECT 1" {
+		t.Errorf("expected SQL outside a request to be left alone, got %q", query)
+	}
+}
+
+// TestQueryAnnotationsNameTheRoute tests that statements made for a request
+// name its route template, not the path requested, and its request ID
+func TestQueryAnnotationsNameTheRoute(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Database.QueryAnnotations = true
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+	token := admin.Token
+	recorder := recordSQL(t, ta.DB())
+
+	req, err := http.NewRequest(http.MethodGet, ta.Server.URL+"/api/v1/users/"+admin.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Request-ID", "trace-42 */ DROP TABLE users; /*")
+	resp, err := ta.Server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	want := "/* route=/api/v1/users/:id method=GET req=trace-42__/_DROP_TABLE_users__/_ */ "
+	var annotated int
+	for _, statement := range recorder.all() {
+		if strings.HasPrefix(statement, want) {
+			annotated++
+		} else if strings.Contains(statement, "/*") {
+			t.Errorf("expected only the request's annotation, got %q", statement)
+		}
+	}
+	if annotated == 0 {
+		t.Errorf("expected the request's statements to be annotated with %q, got %q", want, recorder.all())
+	}
+}
@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestRebind tests rebinding queries written with either placeholder style
+func TestRebind(t *testing.T) {
+	cases := []struct {
+		name     string
+		query    string
+		postgres string
+		mysql    string
+	}{
+		{"question marks", "SELECT * FROM users WHERE id = ? AND role = ?",
+			"SELECT * FROM users WHERE id = $1 AND role = $2", "SELECT * FROM users WHERE id = ? AND role = ?"},
+		{"numbered", "UPDATE users SET active = $1 WHERE id = $2",
+			"UPDATE users SET active = $1 WHERE id = $2", "UPDATE users SET active = ? WHERE id = ?"},
+		{"mixed", "SELECT * FROM users WHERE email = ? AND role = $2 LIMIT ? OFFSET $4",
+			"SELECT * FROM users WHERE email = $1 AND role = $2 LIMIT $3 OFFSET $4", "SELECT * FROM users WHERE email = ? AND role = ? LIMIT ? OFFSET ?"},
+		{"literals", `SELECT '?', 'it''s $1', "a?b", ` + "`c?`" + ` FROM users WHERE id = ?`,
+			`SELECT '?', 'it''s $1', "a?b", ` + "`c?`" + ` FROM users WHERE id = $1`, `SELECT '?', 'it''s $1', "a?b", ` + "`c?`" + ` FROM users WHERE id = ?`},
+		{"no placeholders", "SELECT COUNT(*) FROM users", "SELECT COUNT(*) FROM users", "SELECT COUNT(*) FROM users"},
+		{"dollar without number", "SELECT '$' || ?, price$ FROM t", "SELECT '$' || $1, price$ FROM t", "SELECT '$' || ?, price$ FROM t"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := database.Rebind(database.DriverPostgreSQL, tc.query); got != tc.postgres {
+				t.Errorf("postgres: got %q, want %q", got, tc.postgres)
+			}
+			for _, driverType := range []database.DriverType{database.DriverMySQL, database.DriverSQLite} {
+				if got := database.Rebind(driverType, tc.query); got != tc.mysql {
+					t.Errorf("%s: got %q, want %q", driverType, got, tc.mysql)
+				}
+			}
+		})
+	}
+
+	t.Run("out of position", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic for $1 as the second placeholder")
+			}
+		}()
+		database.Rebind(database.DriverMySQL, "SELECT * FROM users WHERE id = ? OR parent_id = $1")
+	})
+}
+
+// TestWhereBuilder tests the clauses and arguments built from filters
+func TestWhereBuilder(t *testing.T) {
+	where := database.NewWhereBuilder("created_at", "email")
+	if clause, args := where.Clause(); clause != "" || args != nil || where.Order() != "" {
+		t.Errorf("expected empty clauses, got %q %v %q", clause, args, where.Order())
+	}
+
+	where.Where("anonymized_at IS NULL").
+		Where("role = ? OR email = ?", models.RoleAdmin, "ann@example.com")
+	if err := where.Sort("email", false); err != nil {
+		t.Fatalf("sort: %v", err)
+	}
+	if err := where.Sort("created_at", true); err != nil {
+		t.Fatalf("sort: %v", err)
+	}
+	if err := where.Sort("email; DROP TABLE users", false); !errors.Is(err, database.ErrUnsortable) {
+		t.Errorf("expected ErrUnsortable, got %v", err)
+	}
+
+	clause, args := where.Clause()
+	if want := "WHERE (anonymized_at IS NULL) AND (role = ? OR email = ?)"; clause != want {
+		t.Errorf("got %q, want %q", clause, want)
+	}
+	if len(args) != 2 || args[0] != models.RoleAdmin || args[1] != "ann@example.com" {
+		t.Errorf("unexpected args %v", args)
+	}
+	if want := "ORDER BY email ASC, created_at DESC"; where.Order() != want {
+		t.Errorf("got %q, want %q", where.Order(), want)
+	}
+	query := database.Rebind(database.DriverPostgreSQL, "SELECT * FROM users "+clause+" LIMIT ?")
+	if want := "SELECT * FROM users WHERE (anonymized_at IS NULL) AND (role = $1 OR email = $2) LIMIT $3"; query != want {
+		t.Errorf("got %q, want %q", query, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a missing argument")
+		}
+	}()
+	where.Where("username = ?")
+}
+
+// newRawSQLiteManager registers an in-memory SQLite database without GORM
+// as the primary, so services take their raw SQL path
+func newRawSQLiteManager(t *testing.T) *database.Manager {
+	t.Helper()
+
+	manager := database.NewManager()
+	t.Cleanup(func() { manager.CloseAll() })
+	driver := database.NewSQLiteDriver(&database.SQLiteConfig{})
+	if err := manager.ConnectDriver(context.Background(), database.PrimaryDriver, driver, database.ConnectPolicy{Required: true}); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	schema, err := gorm.Open(sqlite.Dialector{Conn: driver.GetSQLDB()}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open schema: %v", err)
+	}
+	if err := schema.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return manager
+}
+
+// TestUserRawSQLRoundTrip tests create, get, list, update and delete through
+// the raw SQL path on SQLite, which binds with "?"
+func TestUserRawSQLRoundTrip(t *testing.T) {
+	db := newRawSQLiteManager(t)
+	users := newTestUserService(db)
+	ctx := context.Background()
+
+	created, err := users.CreateUser(ctx, &services.CreateUserRequest{Email: "ann@example.com", FirstName: "Ann", Password: "secret123"}, "")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := users.CreateUser(ctx, &services.CreateUserRequest{Email: "ann@example.com"}, ""); !errors.Is(err, services.ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken, got %v", err)
+	}
+
+	fetched, err := users.GetUser(ctx, created.ID.String())
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if fetched.Email != "ann@example.com" || fetched.FirstName != "Ann" || !fetched.Active {
+		t.Errorf("unexpected user %+v", fetched)
+	}
+
+	if _, err := users.UpdateUser(ctx, created.ID.String(), &services.UpdateUserRequest{FirstName: strPtr("Anna"), LastName: strPtr("Lee")}, ""); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	stored, err := newTestUserService(db).GetUser(ctx, created.ID.String())
+	if err != nil {
+		t.Fatalf("get after update: %v", err)
+	}
+	if stored.FirstName != "Anna" || stored.LastName != "Lee" {
+		t.Errorf("update not stored: %+v", stored)
+	}
+
+	list, err := users.ListUsers(ctx, services.ListUsersFilter{}, 10, 0)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if list.Total != 1 || len(list.Items) != 1 || list.Items[0].ID != created.ID {
+		t.Errorf("unexpected list %+v", list)
+	}
+
+	if err := users.DeleteUser(ctx, created.ID.String()); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := users.GetUser(ctx, created.ID.String()); !errors.Is(err, services.ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound after delete, got %v", err)
+	}
+}
+
+// TestUserRawSQLBinding tests the placeholders the raw SQL path sends to
+// PostgreSQL and MySQL
+func TestUserRawSQLBinding(t *testing.T) {
+	cases := []struct {
+		driverType database.DriverType
+		count      string
+		list       string
+	}{
+		{database.DriverPostgreSQL, `SELECT COUNT\(\*\) FROM users WHERE \(anonymized_at IS NULL\)$`,
+			`FROM users WHERE \(anonymized_at IS NULL\) ORDER BY created_at DESC LIMIT \$1 OFFSET \$2`},
+		{database.DriverMySQL, `SELECT COUNT\(\*\) FROM users WHERE \(anonymized_at IS NULL\)$`,
+			`FROM users WHERE \(anonymized_at IS NULL\) ORDER BY created_at DESC LIMIT \? OFFSET \?`},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.driverType), func(t *testing.T) {
+			db, driver := databasetest.NewManager(t, databasetest.WithType(tc.driverType))
+			driver.Mock.ExpectQuery(tc.count).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			driver.Mock.ExpectQuery(tc.list).WithArgs(20, 40).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			if _, err := newTestUserService(db).ListUsers(context.Background(), services.ListUsersFilter{}, 20, 40); err != nil {
+				t.Fatalf("list: %v", err)
+			}
+
+			db, driver = databasetest.NewManager(t, databasetest.WithType(tc.driverType))
+			placeholder := `\?`
+			if tc.driverType == database.DriverPostgreSQL {
+				placeholder = `\$1`
+			}
+			driver.Mock.ExpectExec(`DELETE FROM users WHERE id = ` + placeholder).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			if err := newTestUserService(db).DeleteUser(context.Background(), "5b7a8f0e-1c2d-4e3f-9a8b-7c6d5e4f3a2b"); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+		})
+	}
+}
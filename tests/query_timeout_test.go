@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	apperrors "BackofficeGoService/internal/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// runawayQuery counts forever, so it only ends when it is interrupted. It is
+// run with Exec because SQLite only watches the context while executing, not
+// while rows are read.
+const runawayQuery = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c"
+
+// timedOutQuery records the queries a manager reported as timed out
+type timedOutQuery struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (r *timedOutQuery) record(database, query string, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries = append(r.queries, database+"/"+query)
+}
+
+func (r *timedOutQuery) all() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.queries...)
+}
+
+// newTimeoutManager registers a SQLite driver on a manager whose statements
+// time out after timeout
+func newTimeoutManager(t *testing.T, timeout time.Duration) (*gorm.DB, *timedOutQuery) {
+	t.Helper()
+	recorded := &timedOutQuery{}
+	manager := database.NewManager()
+	manager.SetQueryTimeout(database.QueryTimeoutConfig{Timeout: timeout, OnTimeout: recorded.record})
+	mock := databasetest.NewMockDriver(t, databasetest.WithSQLite(&models.User{}))
+	if err := manager.AddDriver("primary", mock); err != nil {
+		t.Fatalf("add driver: %v", err)
+	}
+	return mock.GormDB(), recorded
+}
+
+// runRunaway runs runawayQuery with ctx, failing if the caller is not freed
+// within a second
+func runRunaway(t *testing.T, db *gorm.DB, ctx context.Context) (error, time.Duration) {
+	t.Helper()
+	start := time.Now()
+	err := db.WithContext(ctx).Exec(runawayQuery).Error
+	elapsed := time.Since(start)
+	if elapsed > time.Second {
+		t.Fatalf("expected the query to be interrupted, it ran for %v", elapsed)
+	}
+	return err, elapsed
+}
+
+// TestQueryTimeout tests that runaway statements are interrupted by the
+// configured timeout or a sooner caller deadline, and that only deadlines
+// count as timeouts
+func TestQueryTimeout(t *testing.T) {
+	db, recorded := newTimeoutManager(t, 100*time.Millisecond)
+
+	err, elapsed := runRunaway(t, db, context.Background())
+	if !errors.Is(err, database.ErrQueryTimeout) {
+		t.Fatalf("expected ErrQueryTimeout, got %v", err)
+	}
+	if elapsed < 100*time.Millisecond {
+		t.Errorf("expected the query to run until the timeout, it stopped after %v", elapsed)
+	}
+	if got := recorded.all(); len(got) != 1 || got[0] != "primary/raw" {
+		t.Errorf("expected the timeout reported by query name, got %v", got)
+	}
+
+	// A sooner request deadline wins over the configured timeout
+	slow, _ := newTimeoutManager(t, time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err, _ := runRunaway(t, slow, ctx); !errors.Is(err, database.ErrQueryTimeout) {
+		t.Errorf("expected the request deadline to time the query out, got %v", err)
+	}
+
+	// Callers that give up are not told their query timed out
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err, _ := runRunaway(t, slow, ctx); err == nil || errors.Is(err, database.ErrQueryTimeout) {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+
+	// Statements that finish in time are unaffected and keep their context
+	var users []models.User
+	if err := db.WithContext(context.Background()).Where("email = ?", "nobody@example.com").Find(&users).Error; err != nil {
+		t.Errorf("expected a fast query to succeed, got %v", err)
+	}
+	if err := db.Create(&models.User{Email: "fast@example.com"}).Error; err != nil {
+		t.Errorf("expected a fast insert to succeed, got %v", err)
+	}
+}
+
+// TestQueryTimeoutResponse tests that handlers wrapping a timed out query
+// answer 504 QUERY_TIMEOUT
+func TestQueryTimeoutResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Locale())
+	router.GET("/slow", func(c *gin.Context) {
+		err := fmt.Errorf("failed to list users: %w", database.ErrQueryTimeout)
+		middleware.RespondError(c, apperrors.NewInternalServerError("", err))
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d: %s", rec.Code, rec.Body)
+	}
+	var envelope errorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decode envelope: %v", err)
+	}
+	if envelope.Error.Code != string(apperrors.CodeQueryTimeout) {
+		t.Errorf("unexpected error %+v", envelope.Error)
+	}
+}
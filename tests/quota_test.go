@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newQuotaService builds a quota service on the test app's database with
+// its own cache
+func newQuotaService(ta *apptest.TestApp, monthly int64, clk clock.Clock) *services.QuotaService {
+	manager := ta.App.GetDBManager()
+	return services.NewQuotaService(services.NewUsageRepository(manager), cache.NewMemoryStore(), services.NewAuditService(manager, logger.NewNopLogger()), monthly, logger.NewNopLogger(), services.WithQuotaClock(clk))
+}
+
+// apiToken signs an access token without a login session, as machine
+// clients hold
+func apiToken(t *testing.T, ta *apptest.TestApp, user *apptest.User) string {
+	t.Helper()
+
+	now := time.Now()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID.String(),
+		"email":   user.Email,
+		"role":    string(user.Role),
+		"exp":     now.Add(time.Hour).Unix(),
+		"iat":     now.Unix(),
+		"iss":     ta.Config.JWT.Issuer,
+	}).SignedString([]byte(ta.Config.JWT.Secret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+// TestQuotaWindowRollover tests that a spent quota is refused until the
+// month ends, and that each month is recorded apart
+func TestQuotaWindowRollover(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+	clk := clock.NewFake(time.Date(2024, 6, 30, 23, 58, 0, 0, time.UTC))
+	quotas := newQuotaService(ta, 2, clk)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := quotas.Consume(ctx, user.ID.String()); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	status, err := quotas.Consume(ctx, user.ID.String())
+	if !stderrors.Is(err, services.ErrQuotaExceeded) {
+		t.Fatalf("expected the quota to be spent, got %v", err)
+	}
+	if status.Used != 2 || *status.Remaining != 0 || !status.ResetAt.Equal(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	clk.Advance(2 * time.Minute)
+	status, err = quotas.Consume(ctx, user.ID.String())
+	if err != nil || status.Period != "2024-07" || status.Used != 1 || *status.Remaining != 1 {
+		t.Fatalf("expected a fresh quota in July, got %+v, %v", status, err)
+	}
+
+	for period, want := range map[string]int64{"2024-06": 2, "2024-07": 1} {
+		report, err := quotas.Report(ctx, period)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Total != want || len(report.Users) != 1 || report.Users[0].UserID != user.ID {
+			t.Errorf("%s: expected %d requests of the user, got %+v", period, want, report)
+		}
+	}
+
+	// Another instance whose cache lost the counter resumes from the record
+	status, err = newQuotaService(ta, 2, clk).Consume(ctx, user.ID.String())
+	if err != nil || status.Used != 2 {
+		t.Errorf("expected the recorded request to count, got %+v, %v", status, err)
+	}
+}
+
+// TestQuotaOverridePrecedence tests that a user's own quota beats the
+// default, that zero means no limit and that nil restores the default
+func TestQuotaOverridePrecedence(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	limited := ta.CreateUser(models.RoleUser)
+	unlimited := ta.CreateUser(models.RoleUser)
+	quotas := newQuotaService(ta, 3, clock.NewFake(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+	ctx := context.Background()
+
+	one, zero := int64(1), int64(0)
+	if err := quotas.SetQuota(ctx, admin.ID.String(), limited.ID.String(), &one); err != nil {
+		t.Fatal(err)
+	}
+	if err := quotas.SetQuota(ctx, admin.ID.String(), unlimited.ID.String(), &zero); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := quotas.Consume(ctx, limited.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := quotas.Consume(ctx, limited.ID.String()); !stderrors.Is(err, services.ErrQuotaExceeded) {
+		t.Errorf("expected the override of one to be spent, got %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		status, err := quotas.Consume(ctx, unlimited.ID.String())
+		if err != nil || status.Limit != nil || status.Remaining != nil {
+			t.Fatalf("expected no limit, got %+v, %v", status, err)
+		}
+	}
+
+	// Restoring the default applies at once on this instance
+	if err := quotas.SetQuota(ctx, admin.ID.String(), unlimited.ID.String(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if limit, err := quotas.Limit(ctx, unlimited.ID.String()); err != nil || limit != 3 {
+		t.Errorf("expected the default of 3, got %d, %v", limit, err)
+	}
+	if _, err := quotas.Consume(ctx, unlimited.ID.String()); !stderrors.Is(err, services.ErrQuotaExceeded) {
+		t.Errorf("expected the default to be spent by the earlier requests, got %v", err)
+	}
+
+	if got := auditCount(t, ta, models.AuditActionUserQuotaChanged, admin, unlimited.ID.String()); got != 2 {
+		t.Errorf("expected two audited changes, got %d", got)
+	}
+}
+
+// TestQuotaMiddleware tests that API clients are refused with 429 once their
+// quota is spent while login sessions and health checks are not
+func TestQuotaMiddleware(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.API.Quota.Monthly = 2
+	})
+	user := ta.CreateUser(models.RoleUser)
+	token := apiToken(t, ta, user)
+
+	for _, want := range []string{"1", "0"} {
+		resp := ta.Request(http.MethodGet, "/api/v1/me", nil, token)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Quota-Remaining") != want || resp.Header.Get("X-Quota-Reset") == "" {
+			t.Fatalf("expected 200 with %s remaining, got %d %v", want, resp.StatusCode, resp.Header)
+		}
+	}
+	resp := ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String(), nil, token)
+	expectErrorCode(t, resp, http.StatusTooManyRequests, errors.CodeQuotaExceeded)
+	if resp.Header.Get("Retry-After") == "" || resp.Header.Get("X-Quota-Remaining") != "0" {
+		t.Errorf("expected quota headers, got %v", resp.Header)
+	}
+
+	if resp := ta.Request(http.MethodGet, "/health", nil, token); resp.StatusCode != http.StatusOK {
+		t.Errorf("health: expected 200, got %d", resp.StatusCode)
+	}
+	for i := 0; i < 3; i++ {
+		resp := ta.Request(http.MethodGet, "/api/v1/me", nil, user.Token)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Quota-Remaining") != "" {
+			t.Fatalf("session: expected 200 without quota headers, got %d %v", resp.StatusCode, resp.Header)
+		}
+	}
+}
+
+// TestQuotaSessions tests that API_QUOTA_SESSIONS counts login sessions too
+func TestQuotaSessions(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.API.Quota.Monthly = 1
+		cfg.API.Quota.Sessions = true
+	})
+	user := ta.CreateUser(models.RoleUser)
+
+	if resp := ta.Request(http.MethodGet, "/api/v1/me", nil, user.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	resp := ta.Request(http.MethodGet, "/api/v1/me/sessions", nil, user.Token)
+	expectErrorCode(t, resp, http.StatusTooManyRequests, errors.CodeQuotaExceeded)
+}
+
+// TestUsageEndpoints tests the usage of the current user, the admin report
+// and setting a user's quota
+func TestUsageEndpoints(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+	token := apiToken(t, ta, user)
+
+	resp := ta.Request(http.MethodPut, "/api/v1/users/"+user.ID.String()+"/quota", map[string]interface{}{"quota": 10}, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set quota: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	if resp := ta.Request(http.MethodPut, "/api/v1/users/"+user.ID.String()+"/quota", map[string]interface{}{"quota": -1}, admin.Token); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("negative quota: expected 422, got %d", resp.StatusCode)
+	}
+	if resp := ta.Request(http.MethodPut, "/api/v1/users/"+admin.ID.String()+"/quota", map[string]interface{}{"quota": 1}, user.Token); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("user: expected 403, got %d", resp.StatusCode)
+	}
+
+	var usage struct {
+		Data services.QuotaStatus `json:"data"`
+	}
+	ta.Request(http.MethodGet, "/api/v1/me/usage", nil, token).Decode(t, &usage)
+	if usage.Data.Limit == nil || *usage.Data.Limit != 10 || usage.Data.Used != 1 || *usage.Data.Remaining != 9 {
+		t.Errorf("unexpected usage %+v", usage.Data)
+	}
+
+	var report struct {
+		Data services.UsageReport `json:"data"`
+	}
+	ta.Request(http.MethodGet, "/api/v1/admin/usage", nil, admin.Token).Decode(t, &report)
+	if report.Data.Period != services.UsagePeriod(time.Now()) || report.Data.Total != 1 || len(report.Data.Users) != 1 || report.Data.Users[0].UserID != user.ID {
+		t.Errorf("unexpected report %+v", report.Data)
+	}
+	expectErrorCode(t, ta.Request(http.MethodGet, "/api/v1/admin/usage?period=2024-6", nil, admin.Token), http.StatusBadRequest, errors.CodeInvalidPeriod)
+	if resp := ta.Request(http.MethodGet, "/api/v1/admin/usage?period=2024-06", nil, user.Token); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("user: expected 403, got %d", resp.StatusCode)
+	}
+}
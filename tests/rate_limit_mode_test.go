@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	approute "BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/apiversion"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/metrics"
+	"BackofficeGoService/internal/pkg/ratelimit"
+	"BackofficeGoService/internal/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestRateLimitModesShareCounters tests that observing and enforcing a
+// limit count in the same windows, so switching modes resets nothing
+func TestRateLimitModesShareCounters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := cache.NewMemoryStore()
+	m := metrics.New()
+
+	// The same route under either mode, as before and after a switch
+	routers := map[ratelimit.Mode]*gin.Engine{}
+	for _, mode := range []ratelimit.Mode{ratelimit.Observe, ratelimit.Enforce} {
+		router := gin.New()
+		registrar := routes.NewRegistrar(routes.Dependencies{RateLimits: store, RateLimitMode: mode, RateLimitWouldBlock: m.RateLimitWouldBlock})
+		err := registrar.Register(router.Group("/api/v1"), apiversion.V1, []approute.Definition{{
+			Method:  http.MethodGet,
+			Path:    "/limited",
+			Handler: func(c *gin.Context) { c.Status(http.StatusNoContent) },
+			Policy:  approute.Policy{RateLimit: approute.RateLimit{Requests: 2, Window: time.Hour}},
+		}})
+		if err != nil {
+			t.Fatalf("register %s: %v", mode, err)
+		}
+		routers[mode] = router
+	}
+	serve := func(mode ratelimit.Mode) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/limited", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		routers[mode].ServeHTTP(w, req)
+		return w
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		w := serve(ratelimit.Observe)
+		if w.Code != http.StatusNoContent || w.Header().Get(middleware.RateLimitRemainingHeader) != remaining || w.Header().Get(middleware.RateLimitWarningHeader) != "" {
+			t.Fatalf("request %d: expected 204 with %s remaining and no warning, got %d %v", i+1, remaining, w.Code, w.Header())
+		}
+		if w.Header().Get(middleware.RateLimitLimitHeader) != "2" || w.Header().Get(middleware.RateLimitResetHeader) == "" {
+			t.Errorf("request %d: expected the limit and reset headers, got %v", i+1, w.Header())
+		}
+	}
+
+	// Observed requests over the limit pass with a warning
+	w := serve(ratelimit.Observe)
+	if w.Code != http.StatusNoContent || w.Header().Get(middleware.RateLimitWarningHeader) != middleware.RateLimitWouldThrottle {
+		t.Fatalf("expected 204 with a would-throttle warning, got %d %v", w.Code, w.Header())
+	}
+	if n := testutil.ToFloat64(m.RateLimitWouldBlock.WithLabelValues("/api/v1/limited")); n != 1 {
+		t.Errorf("expected one would-block request, got %v", n)
+	}
+
+	// Enforcing picks up the observed count
+	w = serve(ratelimit.Enforce)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" || w.Header().Get(middleware.RateLimitRemainingHeader) != "0" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+	expectErrorCode(t, &apptest.Response{StatusCode: w.Code, Body: w.Body.Bytes()}, http.StatusTooManyRequests, errors.CodeRateLimited)
+	if n := testutil.ToFloat64(m.RateLimitWouldBlock.WithLabelValues("/api/v1/limited")); n != 1 {
+		t.Errorf("expected refused requests not counted as would-block, got %v", n)
+	}
+
+	// And observing again goes on from there
+	if w := serve(ratelimit.Observe); w.Code != http.StatusNoContent || w.Header().Get(middleware.RateLimitWarningHeader) != middleware.RateLimitWouldThrottle {
+		t.Errorf("expected 204 with a warning after switching back, got %d %v", w.Code, w.Header())
+	}
+
+	offenders, err := ratelimit.New(store).Offenders(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("offenders: %v", err)
+	}
+	if len(offenders) != 1 {
+		t.Fatalf("expected one offender, got %+v", offenders)
+	}
+	if o := offenders[0]; o.Route != "GET /api/v1/limited" || o.Client != "10.0.0.1" || o.Requests != 5 || o.OverLimit != 3 {
+		t.Errorf("unexpected offender %+v", o)
+	}
+}
+
+// TestRateLimitOffenders tests observing the login limit on the API and
+// listing its offenders
+func TestRateLimitOffenders(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.LoginRateLimit = 2
+		cfg.Auth.LoginRateLimitMode = string(ratelimit.Observe)
+	})
+	admin := ta.CreateUser(models.RoleAdmin) // logs in once
+
+	ta.Login(admin.Email, admin.Password)
+	for i := 0; i < 2; i++ {
+		resp := ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": admin.Email, "password": admin.Password}, "")
+		if resp.StatusCode != http.StatusOK || resp.Header.Get(middleware.RateLimitWarningHeader) != middleware.RateLimitWouldThrottle {
+			t.Fatalf("expected the login let through with a warning, got %d %v", resp.StatusCode, resp.Header)
+		}
+	}
+	if !ta.Logs.Contains("Request over rate limit let through") {
+		t.Error("expected the offender logged")
+	}
+
+	// The route table lists the mode
+	resp := ta.Request(http.MethodGet, "/api/v1/admin/routes", nil, admin.Token)
+	var routeTable struct {
+		Data approute.Table `json:"data"`
+	}
+	resp.Decode(t, &routeTable)
+	for _, info := range routeTable.Data {
+		if info.Path == "/api/v1/auth/login" && (info.RateLimit == nil || info.RateLimit.Mode != "observe") {
+			t.Errorf("expected the login limit listed as observed, got %+v", info.RateLimit)
+		}
+	}
+
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/ratelimit/offenders", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("offenders: expected 200, got %d %s", resp.StatusCode, resp.Body)
+	}
+	var list struct {
+		Data []ratelimit.Offender `json:"data"`
+	}
+	if err := json.Unmarshal(resp.Body, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Data) != 1 {
+		t.Fatalf("expected one offender, got %s", resp.Body)
+	}
+	if o := list.Data[0]; o.Route != "POST /api/v1/auth/login" || o.Mode != ratelimit.Observe || o.Limit != 2 || o.OverLimit != 2 || o.WindowEndsAt.IsZero() {
+		t.Errorf("unexpected offender %+v", o)
+	}
+
+	user := ta.CreateUser(models.RoleUser)
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/ratelimit/offenders", nil, user.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodeInsufficientPermissions)
+}
+
+// TestRateLimitModeConfig tests that unknown modes are refused
+func TestRateLimitModeConfig(t *testing.T) {
+	t.Setenv("AUTH_LOGIN_RATE_LIMIT_MODE", "observe")
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.API.RateLimitMode != "enforce" || cfg.Auth.LoginRateLimitMode != "observe" {
+		t.Errorf("unexpected modes %q and %q", cfg.API.RateLimitMode, cfg.Auth.LoginRateLimitMode)
+	}
+
+	t.Setenv("API_RATE_LIMIT_MODE", "warn")
+	if _, err := config.LoadConfig(); err == nil {
+		t.Error("expected an unknown mode refused")
+	}
+}
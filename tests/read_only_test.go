@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/readonly"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// setReadOnly turns read-only mode on or off on behalf of admin
+func setReadOnly(t *testing.T, ta *apptest.TestApp, admin *apptest.User, enabled bool) {
+	t.Helper()
+
+	resp := ta.Request(http.MethodPut, "/api/v1/admin/read-only", map[string]interface{}{"enabled": enabled, "reason": "incident 42"}, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected read-only mode to change, got %d %s", resp.StatusCode, resp.Body)
+	}
+}
+
+// TestReadOnlyMethodFiltering tests that read-only mode refuses writes to
+// business routes while reads, token refresh and the switch keep working
+func TestReadOnlyMethodFiltering(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	setReadOnly(t, ta, admin, true)
+
+	var health struct {
+		ReadOnly readonly.State `json:"read_only"`
+	}
+	ta.Request(http.MethodGet, "/health", nil, "").Decode(t, &health)
+	if !health.ReadOnly.Enabled || health.ReadOnly.Reason != "incident 42" || health.ReadOnly.ActorID != admin.ID.String() || health.ReadOnly.Since == nil {
+		t.Errorf("expected /health to show read-only mode, got %+v", health.ReadOnly)
+	}
+	if n := auditCount(t, ta, models.AuditActionReadOnlyEnabled, admin, ""); n != 1 {
+		t.Errorf("expected enabling read-only mode to be audited, got %d", n)
+	}
+
+	for _, read := range []string{"/api/v1/users", "/api/v1/users/" + user.ID.String(), "/api/v2/users", "/api/v1/admin/read-only"} {
+		if resp := ta.Request(http.MethodGet, read, nil, admin.Token); resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: expected reads to keep working, got %d %s", read, resp.StatusCode, resp.Body)
+		}
+	}
+	userPath := "/api/v1/users/" + user.ID.String()
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/users", map[string]string{"email": "new@example.com"}, admin.Token), http.StatusServiceUnavailable, errors.CodeServiceReadOnly)
+	expectErrorCode(t, ta.Request(http.MethodPut, userPath, map[string]string{"first_name": "Changed"}, admin.Token), http.StatusServiceUnavailable, errors.CodeServiceReadOnly)
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/me/notifications/read-all", nil, user.Token), http.StatusServiceUnavailable, errors.CodeServiceReadOnly)
+	expectErrorCode(t, ta.Request(http.MethodDelete, userPath, nil, admin.Token), http.StatusServiceUnavailable, errors.CodeServiceReadOnly)
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/auth/register", map[string]string{"email": "x@example.com", "password": "password123"}, ""), http.StatusServiceUnavailable, errors.CodeServiceReadOnly)
+
+	if resp := ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": user.Token}, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected token refresh to keep working, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if token := ta.Login(user.Email, user.Password); token == "" {
+		t.Error("expected logins to keep working")
+	}
+
+	setReadOnly(t, ta, admin, false)
+	if resp := ta.Request(http.MethodPut, userPath, map[string]string{"first_name": "Changed"}, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected writes to work again, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if n := auditCount(t, ta, models.AuditActionReadOnlyDisabled, admin, ""); n != 1 {
+		t.Errorf("expected disabling read-only mode to be audited, got %d", n)
+	}
+}
+
+// TestReadOnlyWriteGuard tests that the databases refuse writes made
+// outside the requests the API admitted, such as by background workers
+func TestReadOnlyWriteGuard(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	setReadOnly(t, ta, admin, true)
+
+	// Work started in the background carries no admitted request
+	ctx := context.Background()
+	notification := func() *models.Notification {
+		return &models.Notification{ID: uuid.New(), UserID: admin.ID, Type: "test", Title: "Guarded", CreatedAt: time.Now()}
+	}
+	if err := ta.DB().WithContext(ctx).Create(notification()).Error; !stderrors.Is(err, readonly.ErrReadOnly) {
+		t.Errorf("expected a background insert to be refused, got %v", err)
+	}
+	if err := ta.DB().WithContext(ctx).Model(&models.User{}).Where("id = ?", admin.ID).Update("first_name", "Changed").Error; !stderrors.Is(err, readonly.ErrReadOnly) {
+		t.Errorf("expected a background update to be refused, got %v", err)
+	}
+	if err := ta.DB().WithContext(ctx).Exec("DELETE FROM notifications").Error; !stderrors.Is(err, readonly.ErrReadOnly) {
+		t.Errorf("expected a background statement to be refused, got %v", err)
+	}
+	if n := countRows(t, ta.DB().WithContext(ctx), &models.User{}, "id = ? AND first_name = ?", admin.ID, "Test"); n != 1 {
+		t.Errorf("expected reads to keep working and nothing to be written, got %d", n)
+	}
+
+	if err := ta.DB().WithContext(readonly.Allow(ctx)).Create(notification()).Error; err != nil {
+		t.Errorf("expected an allowed write to go through, got %v", err)
+	}
+
+	setReadOnly(t, ta, admin, false)
+	if err := ta.DB().WithContext(ctx).Create(notification()).Error; err != nil {
+		t.Errorf("expected background writes to work again, got %v", err)
+	}
+}
+
+// TestReadOnlyPropagation tests that replicas sharing a cache store follow
+// the switch within their refresh interval
+func TestReadOnlyPropagation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	shared := cache.NewMemoryStore()
+	replica := func() (*readonly.Switch, *gin.Engine) {
+		sw := readonly.New(shared, readonly.WithRefreshInterval(2*time.Second), readonly.WithClock(fake))
+		router := gin.New()
+		router.Use(middleware.ReadOnly(sw, middleware.ReadOnlyConfig{}))
+		router.POST("/items", func(c *gin.Context) { c.Status(http.StatusCreated) })
+		return sw, router
+	}
+	first, _ := replica()
+	second, router := replica()
+	post := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/items", nil))
+		return w.Code
+	}
+
+	if code := post(); code != http.StatusCreated {
+		t.Fatalf("expected writes before read-only mode, got %d", code)
+	}
+	if _, err := first.Set(context.Background(), true, "incident", "admin-1"); err != nil {
+		t.Fatal(err)
+	}
+	if !first.Enabled(context.Background()) {
+		t.Error("expected the replica that turned it on to be read-only at once")
+	}
+	if code := post(); code != http.StatusCreated {
+		t.Errorf("expected the other replica to trust its last read within the interval, got %d", code)
+	}
+	fake.Advance(2 * time.Second)
+	if code := post(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected the other replica to follow after the interval, got %d", code)
+	}
+	if state := second.State(context.Background()); state.Reason != "incident" || state.ActorID != "admin-1" {
+		t.Errorf("expected the state to propagate, got %+v", state)
+	}
+
+	if _, err := second.Set(context.Background(), false, "", "admin-2"); err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(2 * time.Second)
+	if first.Enabled(context.Background()) {
+		t.Error("expected the first replica to follow the switch back off")
+	}
+}
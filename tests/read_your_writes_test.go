@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// newReplicatedManager returns a manager whose primary database has a read
+// replica; the two are separate SQLite databases, so reads show which one
+// served them
+func newReplicatedManager(t *testing.T) (*database.Manager, *databasetest.MockDriver, *databasetest.MockDriver) {
+	t.Helper()
+	manager, primary := databasetest.NewManager(t, databasetest.WithSQLite(&models.User{}))
+	replica := databasetest.NewMockDriver(t, databasetest.WithSQLite(&models.User{}))
+	if err := manager.AddDriver("replica", replica); err != nil {
+		t.Fatalf("add replica: %v", err)
+	}
+	if err := manager.SetReplica(database.PrimaryDriver, "replica"); err != nil {
+		t.Fatalf("set replica: %v", err)
+	}
+	return manager, primary, replica
+}
+
+// TestReaderForSession tests that reads go to the replica until the
+// session writes, and that a new session starts on the replica again
+func TestReaderForSession(t *testing.T) {
+	manager, primary, replica := newReplicatedManager(t)
+	reader := func(ctx context.Context) database.Driver {
+		t.Helper()
+		driver, err := manager.ReaderFor(ctx)
+		if err != nil {
+			t.Fatalf("reader: %v", err)
+		}
+		return driver
+	}
+
+	if reader(context.Background()) != primary {
+		t.Error("expected reads outside a request on the primary")
+	}
+
+	ctx := database.WithSession(context.Background(), database.NewSession(nil))
+	if reader(ctx) != replica {
+		t.Fatal("expected reads before a write on the replica")
+	}
+	user := models.User{ID: uuid.New(), Email: "ann@example.com", Username: "ann", Password: "x", Role: models.RoleUser}
+	if err := primary.GetGormDB().WithContext(ctx).Create(&user).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if reader(ctx) != primary {
+		t.Error("expected reads after a write on the primary")
+	}
+
+	if reader(database.WithSession(context.Background(), database.NewSession(nil))) != replica {
+		t.Error("expected a new session to read from the replica")
+	}
+	sticky := database.NewSession(func() bool { return true })
+	if reader(database.WithSession(context.Background(), sticky)) != primary || sticky.Written() {
+		t.Error("expected a sticky session to read from the primary without having written")
+	}
+
+	// Reads fall back to the primary while the replica is gone
+	if err := manager.RemoveDriver("replica"); !stderrors.Is(err, database.ErrDriverInUse) {
+		t.Errorf("expected the replica in use, got %v", err)
+	}
+	if err := manager.SetReplica(database.PrimaryDriver, "missing"); !stderrors.Is(err, database.ErrDriverNotFound) {
+		t.Errorf("expected an unknown replica rejected, got %v", err)
+	}
+	if err := manager.SetReplica(database.PrimaryDriver, database.PrimaryDriver); !stderrors.Is(err, database.ErrDriverInUse) {
+		t.Errorf("expected the primary rejected as its own replica, got %v", err)
+	}
+}
+
+// TestUserReadsFollowWrites tests that the user service reads back a user
+// it created within the same session only
+func TestUserReadsFollowWrites(t *testing.T) {
+	manager, _, _ := newReplicatedManager(t)
+	users := newTestUserService(manager)
+
+	ctx := database.WithSession(context.Background(), database.NewSession(nil))
+	if _, err := users.CreateUser(ctx, &services.CreateUserRequest{Email: "bob@example.com", Username: "bob", Password: "Password123!"}, ""); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	list, err := users.ListUsers(ctx, services.ListUsersFilter{}, 10, 0)
+	if err != nil || list.Total != 1 {
+		t.Fatalf("expected the created user in the same session, got %+v %v", list, err)
+	}
+
+	next := database.WithSession(context.Background(), database.NewSession(nil))
+	if list, err = users.ListUsers(next, services.ListUsersFilter{}, 10, 0); err != nil || list.Total != 0 {
+		t.Errorf("expected the next session to read the replica, got %+v %v", list, err)
+	}
+}
+
+// sessionRouter serves a write and a read route reporting which database
+// reads went to, as the user named by the X-User header
+func sessionRouter(t *testing.T, hints cache.Store, window time.Duration) *gin.Engine {
+	manager, primary, _ := newReplicatedManager(t)
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(
+		middleware.DatabaseSession(hints, window),
+		func(c *gin.Context) {
+			if user := c.GetHeader("X-User"); user != "" {
+				c.Set(middleware.ClaimsKey, &services.TokenClaims{UserID: user, Role: string(models.RoleUser)})
+			}
+		},
+	)
+	reader := func(c *gin.Context) {
+		driver, err := manager.ReaderFor(c.Request.Context())
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		if driver == primary {
+			c.String(http.StatusOK, database.PrimaryDriver)
+		} else {
+			c.String(http.StatusOK, "replica")
+		}
+	}
+	router.GET("/reader", reader)
+	router.POST("/write", func(c *gin.Context) {
+		user := models.User{ID: uuid.New(), Email: uuid.NewString() + "@example.com", Username: uuid.NewString(), Password: "x", Role: models.RoleUser}
+		if err := primary.GetGormDB().WithContext(c.Request.Context()).Create(&user).Error; err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		reader(c)
+	})
+	return router
+}
+
+// TestDatabaseSessionMiddleware tests that routing flips to the primary
+// after a write and resets on the next request, unless the user's write
+// is remembered for a window
+func TestDatabaseSessionMiddleware(t *testing.T) {
+	serve := func(router *gin.Engine, method, user string) string {
+		req := httptest.NewRequest(method, map[string]string{http.MethodGet: "/reader", http.MethodPost: "/write"}[method], nil)
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: %d", method, w.Code)
+		}
+		return w.Body.String()
+	}
+
+	router := sessionRouter(t, nil, 0)
+	if got := serve(router, http.MethodGet, ""); got != "replica" {
+		t.Errorf("expected a read on the replica, got %s", got)
+	}
+	if got := serve(router, http.MethodPost, ""); got != database.PrimaryDriver {
+		t.Errorf("expected a read after a write on the primary, got %s", got)
+	}
+	if got := serve(router, http.MethodGet, ""); got != "replica" {
+		t.Errorf("expected the next request on the replica, got %s", got)
+	}
+
+	router = sessionRouter(t, cache.NewMemoryStore(), time.Minute)
+	serve(router, http.MethodPost, "ann")
+	if got := serve(router, http.MethodGet, "ann"); got != database.PrimaryDriver {
+		t.Errorf("expected the writer's next request on the primary, got %s", got)
+	}
+	if got := serve(router, http.MethodGet, "bob"); got != "replica" {
+		t.Errorf("expected other users on the replica, got %s", got)
+	}
+	if got := serve(router, http.MethodGet, ""); got != "replica" {
+		t.Errorf("expected anonymous requests on the replica, got %s", got)
+	}
+}
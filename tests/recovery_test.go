@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newPanicRouter serves a route that panics with a plain value and one that
+// panics as if the client had hung up
+func newPanicRouter(logs logger.Logger, showStack bool, m *metrics.Metrics) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Recovery(logs, showStack, m.Panics))
+	router.GET("/panic/:id", func(c *gin.Context) {
+		panic("boom")
+	})
+	router.GET("/hangup", func(c *gin.Context) {
+		panic(&net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)})
+	})
+	return router
+}
+
+// TestRecoveryLogsPanics tests the log entry, metric and response of a panic
+func TestRecoveryLogsPanics(t *testing.T) {
+	logs := logger.NewCaptureLogger()
+	m := metrics.New()
+	router := newPanicRouter(logs, false, m)
+
+	req := httptest.NewRequest(http.MethodGet, "/panic/1", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	var envelope errorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	if envelope.Error.Code != string(errors.CodeInternal) || envelope.Error.Message == "" || envelope.Error.Stack != nil {
+		t.Errorf("unexpected error %+v", envelope.Error)
+	}
+	if strings.Contains(rec.Body.String(), "boom") {
+		t.Errorf("response leaks the panic: %s", rec.Body.String())
+	}
+
+	entries := logs.FilterByLevel(logger.LevelError)
+	if len(entries) != 1 || entries[0].Message != "Panic recovered" {
+		t.Fatalf("expected one panic entry, got %+v", entries)
+	}
+	for key, want := range map[string]string{"request_id": "req-42", "method": http.MethodGet, "path": "/panic/1", "error": "boom"} {
+		if got, _ := entries[0].Field(key); got != want {
+			t.Errorf("expected %s=%q, got %v", key, want, got)
+		}
+	}
+	if stack, _ := entries[0].Field("stack"); !strings.Contains(stack.(string), "recovery_test.go") {
+		t.Errorf("expected the stack to name the panicking handler, got %v", stack)
+	}
+
+	if got := testutil.ToFloat64(m.Panics.WithLabelValues("/panic/:id")); got != 1 {
+		t.Errorf("expected 1 panic counted, got %v", got)
+	}
+}
+
+// TestRecoveryDebugStack tests that debug mode adds the stack to the response
+func TestRecoveryDebugStack(t *testing.T) {
+	router := newPanicRouter(logger.NewNopLogger(), true, metrics.New())
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic/1", nil))
+
+	var envelope errorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if !strings.Contains(strings.Join(envelope.Error.Stack, "\n"), "recovery_test.go") {
+		t.Errorf("expected the stack in the response, got %v", envelope.Error.Stack)
+	}
+}
+
+// TestRecoveryBrokenPipe tests that a client hanging up is only logged
+func TestRecoveryBrokenPipe(t *testing.T) {
+	logs := logger.NewCaptureLogger()
+	m := metrics.New()
+	router := newPanicRouter(logs, true, m)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hangup", nil))
+
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected nothing written, got %q", rec.Body.String())
+	}
+	if n := len(logs.FilterByLevel(logger.LevelError)); n != 0 {
+		t.Errorf("expected no error entries, got %d", n)
+	}
+	if !logs.Contains("Client connection lost") {
+		t.Error("expected the lost connection to be logged")
+	}
+	if got := testutil.ToFloat64(m.Panics.WithLabelValues("/hangup")); got != 0 {
+		t.Errorf("expected no panic counted, got %v", got)
+	}
+}
+
+// TestApplicationRecovery tests that the application logs panics through its logger
+func TestApplicationRecovery(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.App.Debug = true
+	})
+	ta.App.GetRouter().GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	resp := ta.Request(http.MethodGet, "/panic", nil, "")
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", resp.StatusCode)
+	}
+	var envelope errorEnvelope
+	resp.Decode(t, &envelope)
+	if envelope.Error.Code != string(errors.CodeInternal) || len(envelope.Error.Stack) == 0 {
+		t.Errorf("unexpected error %+v", envelope.Error)
+	}
+	if !ta.Logs.Contains("Panic recovered") {
+		t.Error("expected the panic in the application log")
+	}
+}
@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	apperrors "BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// requestLogsPath is where admins search the request log
+const requestLogsPath = "/api/v1/admin/request-logs"
+
+// withRequestLog turns the request log on
+func withRequestLog(cfg *config.Config) {
+	cfg.RequestLog.Enabled = true
+}
+
+// loggedRequests writes the queued request log entries and returns every
+// entry, oldest first
+func loggedRequests(t *testing.T, ta *apptest.TestApp) []models.RequestLog {
+	t.Helper()
+
+	if err := ta.App.GetRequestLogWriter().Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var entries []models.RequestLog
+	if err := ta.DB().Order("created_at").Find(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+// TestRequestLogRecordsMutations tests that changes are logged with their
+// actor, route and status while reads are not
+func TestRequestLogRecordsMutations(t *testing.T) {
+	ta := apptest.NewTestApp(t, withRequestLog)
+	admin := ta.CreateUser(models.RoleAdmin)
+	before := len(loggedRequests(t, ta))
+
+	p := createProduct(t, ta, admin, map[string]interface{}{"name": "Lamp", "sku": "LAMP-1", "price": 100})
+	ta.Request(http.MethodGet, productsPath+"/"+p.ID.String(), nil, admin.Token)
+	ta.Request(http.MethodPost, productsPath, map[string]interface{}{"name": "Lamp", "sku": "LAMP-1", "price": 100}, admin.Token)
+
+	entries := loggedRequests(t, ta)[before:]
+	if len(entries) != 2 {
+		t.Fatalf("expected the two creates to be logged, got %+v", entries)
+	}
+	created, conflict := entries[0], entries[1]
+	if created.Method != http.MethodPost || created.Route != productsPath || created.Status != http.StatusCreated {
+		t.Errorf("expected POST %s 201, got %s %s %d", productsPath, created.Method, created.Route, created.Status)
+	}
+	if created.ActorID != admin.ID.String() || created.RequestID == "" || created.Path != productsPath {
+		t.Errorf("expected the admin's request, got %+v", created)
+	}
+	if !strings.Contains(created.RequestBody, `"sku":"LAMP-1"`) {
+		t.Errorf("expected the request body, got %q", created.RequestBody)
+	}
+	if conflict.Status != http.StatusConflict {
+		t.Errorf("expected the conflict to be logged with its status, got %d", conflict.Status)
+	}
+}
+
+// TestRequestLogRedactsBodies tests that credentials are redacted and long
+// bodies cut to the limit
+func TestRequestLogRedactsBodies(t *testing.T) {
+	ta := apptest.NewTestApp(t, withRequestLog, func(cfg *config.Config) {
+		cfg.RequestLog.BodyLimit = 64
+	})
+	user := ta.CreateUser(models.RoleUser)
+	before := len(loggedRequests(t, ta))
+
+	ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": user.Email, "password": "wrong-password"}, "")
+	ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": user.Email, "password": strings.Repeat("x", 100)}, "")
+
+	entries := loggedRequests(t, ta)[before:]
+	if len(entries) != 2 {
+		t.Fatalf("expected both logins to be logged, got %d", len(entries))
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.RequestBody, "wrong-password") || strings.Contains(entry.RequestBody, "xxx") {
+			t.Errorf("expected the password to be redacted, got %q", entry.RequestBody)
+		}
+		if entry.ActorID != "" || entry.Status != http.StatusUnauthorized {
+			t.Errorf("expected an anonymous 401, got %q %d", entry.ActorID, entry.Status)
+		}
+	}
+	if body := entries[0].RequestBody; !strings.Contains(body, `"password":"`+middleware.RedactedValue+`"`) {
+		t.Errorf("expected a redacted password, got %q", body)
+	}
+	if body := entries[1].RequestBody; !strings.HasSuffix(body, middleware.TruncatedMarker) {
+		t.Errorf("expected the long body to be truncated, got %q", body)
+	}
+}
+
+// TestRequestLogSampling tests that routes take the sample rate of their
+// longest matching prefix
+func TestRequestLogSampling(t *testing.T) {
+	ta := apptest.NewTestApp(t, withRequestLog, func(cfg *config.Config) {
+		cfg.RequestLog.SampleRate = 0
+		cfg.RequestLog.SampleRates = map[string]float64{"/api/v1/products": 1}
+	})
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": admin.Email, "password": "wrong-password"}, "")
+	createProduct(t, ta, admin, map[string]interface{}{"name": "Lamp", "sku": "LAMP-1", "price": 100})
+
+	entries := loggedRequests(t, ta)
+	if len(entries) != 1 || entries[0].Route != productsPath {
+		t.Errorf("expected only the product create to be logged, got %+v", entries)
+	}
+}
+
+// TestRequestLogBackpressure tests that entries arriving while the buffer is
+// full are dropped and counted instead of blocking the request
+func TestRequestLogBackpressure(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	m := metrics.New().Business
+	writer := services.NewRequestLogWriter(ta.App.GetDBManager(), services.RequestLogWriterConfig{BufferSize: 2}, logger.NewNopLogger(), services.WithRequestLogMetrics(m))
+
+	entry := func() *models.RequestLog {
+		return &models.RequestLog{ID: uuid.New(), Method: http.MethodPost, Route: "/api/v1/products", Path: "/api/v1/products", Status: http.StatusCreated, CreatedAt: time.Now()}
+	}
+	for i, want := range []bool{true, true, false} {
+		if got := writer.Enqueue(entry()); got != want {
+			t.Errorf("entry %d: expected enqueued %v, got %v", i, want, got)
+		}
+	}
+	expectCount(t, "dropped entries", testutil.ToFloat64(m.RequestLogs.WithLabelValues(string(metrics.RequestLogDropped))), 1)
+
+	if err := writer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectCount(t, "written entries", testutil.ToFloat64(m.RequestLogs.WithLabelValues(string(metrics.RequestLogWritten))), 2)
+	var n int64
+	ta.DB().Model(&models.RequestLog{}).Count(&n)
+	if n != 2 {
+		t.Errorf("expected 2 stored entries, got %d", n)
+	}
+
+	// The buffer has room again, until the writer stops
+	if !writer.Enqueue(entry()) {
+		t.Error("expected an entry to fit after the flush")
+	}
+	if err := writer.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if writer.Enqueue(entry()) {
+		t.Error("expected entries to be dropped after stopping")
+	}
+	expectCount(t, "written entries", testutil.ToFloat64(m.RequestLogs.WithLabelValues(string(metrics.RequestLogWritten))), 3)
+	expectCount(t, "dropped entries", testutil.ToFloat64(m.RequestLogs.WithLabelValues(string(metrics.RequestLogDropped))), 2)
+}
+
+// TestRequestLogSearch tests filtering the request log by actor, route,
+// status and time and that only admins may search it
+func TestRequestLogSearch(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	now := time.Now().UTC()
+	for _, entry := range []models.RequestLog{
+		{ActorID: user.ID.String(), Method: http.MethodPost, Route: productsPath, Status: http.StatusCreated, CreatedAt: now.Add(-time.Hour)},
+		{ActorID: user.ID.String(), Method: http.MethodDelete, Route: productsPath + "/:id", Status: http.StatusNotFound, CreatedAt: now.Add(-48 * time.Hour)},
+		{ActorID: admin.ID.String(), Method: http.MethodPost, Route: productsPath, Status: http.StatusCreated, CreatedAt: now},
+	} {
+		entry.ID, entry.Path = uuid.New(), entry.Route
+		if err := ta.DB().Create(&entry).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	search := func(query string) []models.RequestLog {
+		t.Helper()
+		resp := ta.Request(http.MethodGet, requestLogsPath+query, nil, admin.Token)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("search %q: %d %s", query, resp.StatusCode, resp.Body)
+		}
+		var body struct {
+			Data []models.RequestLog `json:"data"`
+			Meta pagination.Meta     `json:"meta"`
+		}
+		resp.Decode(t, &body)
+		if int(body.Meta.Total) != len(body.Data) {
+			t.Errorf("search %q: expected a total of %d, got %d", query, len(body.Data), body.Meta.Total)
+		}
+		return body.Data
+	}
+
+	if got := search(""); len(got) != 3 || got[0].ActorID != admin.ID.String() {
+		t.Errorf("expected every entry, newest first, got %+v", got)
+	}
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"?actor_id=" + user.ID.String(), 2},
+		{"?route=" + productsPath, 2},
+		{"?actor_id=" + user.ID.String() + "&method=delete", 1},
+		{"?status=404", 1},
+		{"?from=" + now.Add(-2*time.Hour).Format(time.RFC3339), 2},
+		{"?to=" + now.Add(-24*time.Hour).Format(time.RFC3339), 1},
+	} {
+		if got := search(tc.query); len(got) != tc.want {
+			t.Errorf("%q: expected %d entries, got %d", tc.query, tc.want, len(got))
+		}
+	}
+
+	resp := ta.Request(http.MethodGet, requestLogsPath+"?status=teapot", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusBadRequest, apperrors.CodeInvalidFilter)
+	resp = ta.Request(http.MethodGet, requestLogsPath+"?from=yesterday", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusBadRequest, apperrors.CodeInvalidFilter)
+	if resp := ta.Request(http.MethodGet, requestLogsPath, nil, user.Token); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected users to be forbidden, got %d", resp.StatusCode)
+	}
+}
+
+// TestRequestLogCleanupJob tests that the cleanup job purges entries older
+// than the retention
+func TestRequestLogCleanupJob(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	for _, age := range []time.Duration{time.Minute, 2 * time.Hour} {
+		entry := models.RequestLog{ID: uuid.New(), Method: http.MethodPost, Route: productsPath, Path: productsPath, Status: http.StatusCreated, CreatedAt: time.Now().Add(-age)}
+		if err := ta.DB().Create(&entry).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	requestLogs := services.NewRequestLogService(ta.App.GetDBManager(), logger.NewNopLogger())
+	job := services.NewRequestLogCleanupJob(requestLogs, "55 3 * * *", time.Hour, logger.NewNopLogger())
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	ta.DB().Model(&models.RequestLog{}).Count(&n)
+	if n != 1 {
+		t.Errorf("expected only the recent entry to remain, got %d", n)
+	}
+}
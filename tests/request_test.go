@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/request"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/i18n"
+	"BackofficeGoService/internal/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	playground "github.com/go-playground/validator/v10"
+)
+
+// bindListRequest reads from the path and the query string
+type bindListRequest struct {
+	OrgID  string   `uri:"org" binding:"required,uuid"`
+	Page   int      `form:"page" binding:"omitempty,gte=1"`
+	Tags   []string `form:"tag" binding:"max=3"`
+	Filter struct {
+		Active *bool `form:"active"`
+	}
+
+	// Only settable from a JSON body
+	Role string `json:"role"`
+}
+
+// bindAddress is nested in bindProfileRequest
+type bindAddress struct {
+	City    string `json:"city" binding:"required"`
+	Country string `json:"country" binding:"required,len=2"`
+}
+
+// bindProfileRequest is a JSON body with a nested struct
+type bindProfileRequest struct {
+	Name    string      `json:"name" binding:"required,notblank"`
+	Address bindAddress `json:"address"`
+}
+
+// serveBind binds the request with request.Bind[T] and reports what it got
+func serveBind[T any](t *testing.T, route string, req *http.Request) (*httptest.ResponseRecorder, T, bool) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var (
+		got    T
+		ok     bool
+		called bool
+	)
+	router := gin.New()
+	router.Use(middleware.Locale())
+	router.Handle(req.Method, route, func(c *gin.Context) {
+		got, ok = request.Bind[T](c)
+		if ok {
+			c.Status(http.StatusNoContent)
+		}
+		called = true
+	})
+
+	rec := httptest.NewRecorder()
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	router.ServeHTTP(rec, req)
+	if !called {
+		t.Fatalf("route %s was not matched", route)
+	}
+	return rec, got, ok
+}
+
+// bindDetails decodes a 422 envelope into its details by field
+func bindDetails(t *testing.T, rec *httptest.ResponseRecorder) map[string]middleware.ErrorDetail {
+	t.Helper()
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rec.Code, rec.Body)
+	}
+	var envelope errorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decode envelope: %v", err)
+	}
+	if envelope.Error.Code != string(errors.CodeValidationFailed) {
+		t.Errorf("expected %s, got %+v", errors.CodeValidationFailed, envelope.Error)
+	}
+
+	details := map[string]middleware.ErrorDetail{}
+	for _, detail := range envelope.Error.Details {
+		details[detail.Field] = detail
+	}
+	return details
+}
+
+// TestBindSharedValidator tests that gin's binding engine is the shared validator
+func TestBindSharedValidator(t *testing.T) {
+	if engine, ok := binding.Validator.Engine().(*playground.Validate); !ok || engine != validator.GetValidator() {
+		t.Fatalf("expected gin to bind with the shared validator, got %T", binding.Validator.Engine())
+	}
+}
+
+// TestBindQueryParams tests binding the path and query string
+func TestBindQueryParams(t *testing.T) {
+	const orgID = "0b1c52e8-6c2e-4a52-8b2a-3f7f3c2d7a10"
+
+	req := httptest.NewRequest(http.MethodGet, "/orgs/"+orgID+"/members?page=2&tag=a&tag=b&active=false&Role=admin", nil)
+	rec, got, ok := serveBind[bindListRequest](t, "/orgs/:org/members", req)
+	if !ok || rec.Code != http.StatusNoContent {
+		t.Fatalf("expected the request to bind, got %d: %s", rec.Code, rec.Body)
+	}
+	if got.OrgID != orgID || got.Page != 2 || strings.Join(got.Tags, ",") != "a,b" {
+		t.Errorf("unexpected binding %+v", got)
+	}
+	if got.Filter.Active == nil || *got.Filter.Active {
+		t.Errorf("expected the nested active filter to be false, got %v", got.Filter.Active)
+	}
+	if got.Role != "" {
+		t.Errorf("expected the untagged field to ignore the query string, got %q", got.Role)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/orgs/not-a-uuid/members?page=-1&tag=a&tag=b&tag=c&tag=d", nil)
+	rec, _, ok = serveBind[bindListRequest](t, "/orgs/:org/members", req)
+	if ok {
+		t.Fatal("expected invalid input to fail")
+	}
+	details := bindDetails(t, rec)
+	for field, code := range map[string]string{"org": i18n.ValidationUUID, "page": i18n.ValidationGTE, "tag": i18n.ValidationMax} {
+		if details[field].Code != code {
+			t.Errorf("expected %s on %s, got %+v", code, field, details)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/orgs/"+orgID+"/members?page=first", nil)
+	if rec, _, ok = serveBind[bindListRequest](t, "/orgs/:org/members", req); ok || rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected an unparsable page to fail with 422, got %d", rec.Code)
+	}
+}
+
+// TestBindNestedStructs tests that nested fields are reported by their JSON path
+func TestBindNestedStructs(t *testing.T) {
+	body := `{"name": "Ada", "address": {"city": "", "country": "GBR"}}`
+	rec, _, ok := serveBind[bindProfileRequest](t, "/profile", httptest.NewRequest(http.MethodPost, "/profile", strings.NewReader(body)))
+	if ok {
+		t.Fatal("expected the nested struct to fail validation")
+	}
+	details := bindDetails(t, rec)
+	if d := details["address.city"]; d.Code != i18n.ValidationRequired || d.Message != "address.city is required" {
+		t.Errorf("unexpected city detail %+v", d)
+	}
+	if d := details["address.country"]; d.Code != i18n.ValidationLen {
+		t.Errorf("unexpected country detail %+v", d)
+	}
+
+	body = `{"name": "Ada", "address": {"city": "London", "country": "GB"}}`
+	rec, got, ok := serveBind[bindProfileRequest](t, "/profile", httptest.NewRequest(http.MethodPost, "/profile", strings.NewReader(body)))
+	if !ok || got.Address.City != "London" {
+		t.Errorf("expected the body to bind, got %d %+v", rec.Code, got)
+	}
+
+	rec, _, _ = serveBind[bindProfileRequest](t, "/profile", httptest.NewRequest(http.MethodPost, "/profile", strings.NewReader(`{"name":`)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected malformed JSON to fail with 422, got %d", rec.Code)
+	}
+}
+
+// TestBindCustomRules tests that custom rules apply to Bind, gin's own
+// binding and Var alike
+func TestBindCustomRules(t *testing.T) {
+	body := `{"name": "   ", "address": {"city": "London", "country": "GB"}}`
+	rec, _, _ := serveBind[bindProfileRequest](t, "/profile", httptest.NewRequest(http.MethodPost, "/profile", strings.NewReader(body)))
+	if d := bindDetails(t, rec)["name"]; d.Code != i18n.ValidationNotBlank || d.Message != "name must not be blank" {
+		t.Errorf("unexpected name detail %+v", d)
+	}
+
+	err := validator.RegisterRule("bindtest_even", func(fl playground.FieldLevel) bool {
+		return fl.Field().Int()%2 == 0
+	}, i18n.ValidationInvalid)
+	if err != nil {
+		t.Fatalf("register rule: %v", err)
+	}
+
+	var req struct {
+		Count int `json:"count" binding:"bindtest_even"`
+	}
+	err = binding.JSON.BindBody([]byte(`{"count": 3}`), &req)
+	if failed, ok := validator.FieldErrors(err); !ok || len(failed) != 1 || failed[0].Rule != "bindtest_even" || failed[0].Field != "count" {
+		t.Errorf("expected gin's binding to apply the rule, got %v", err)
+	}
+	if failed := validator.Var("count", 5, "bindtest_even"); len(failed) != 1 {
+		t.Errorf("expected Var to apply the rule, got %v", failed)
+	}
+	if d := validator.Details(err); len(d) != 1 || d[0].Message != i18n.ValidationInvalid {
+		t.Errorf("expected the registered message, got %+v", d)
+	}
+}
+
+// TestRegisterRejectsBlankNames tests the migrated auth controller end to end
+func TestRegisterRejectsBlankNames(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"email":      "blank@example.com",
+		"password":   "secret-password",
+		"first_name": " ",
+		"last_name":  "Lovelace",
+		"username":   "ada",
+	}, "")
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var envelope errorEnvelope
+	resp.Decode(t, &envelope)
+	if len(envelope.Error.Details) != 1 || envelope.Error.Details[0].Field != "first_name" || envelope.Error.Details[0].Code != i18n.ValidationNotBlank {
+		t.Errorf("unexpected details %+v", envelope.Error.Details)
+	}
+}
@@ -0,0 +1,248 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/requestctx"
+
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestRequestContextDefaults tests the values getters return when nothing was set
+func TestRequestContextDefaults(t *testing.T) {
+	ctx := context.Background()
+
+	if user, ok := requestctx.UserFrom(ctx); ok || user != (requestctx.User{}) {
+		t.Errorf("expected no user, got %+v", user)
+	}
+	if id := requestctx.RequestIDFrom(ctx); id != "" {
+		t.Errorf("expected no request ID, got %q", id)
+	}
+	if tenant := requestctx.TenantFrom(ctx); tenant != "" {
+		t.Errorf("expected no tenant, got %q", tenant)
+	}
+
+	log := requestctx.LoggerFrom(ctx)
+	if log == nil {
+		t.Fatal("expected a no-op logger, got nil")
+	}
+	log.With(logger.Field{Key: "k", Value: "v"}).Info("discarded")
+
+	fallback := logger.NewCaptureLogger()
+	requestctx.LoggerOr(ctx, fallback).Info("kept")
+	if !fallback.Contains("kept") {
+		t.Error("expected LoggerOr to fall back")
+	}
+}
+
+// TestRequestContextPropagation tests that values survive derived contexts
+func TestRequestContextPropagation(t *testing.T) {
+	logs := logger.NewCaptureLogger()
+	ctx := requestctx.WithUser(context.Background(), requestctx.User{ID: "u-1", Role: "admin"})
+	ctx = requestctx.WithRequestID(ctx, "req-1")
+	ctx = database.WithTenant(ctx, "acme", "acme_db")
+	ctx = requestctx.WithLogger(ctx, logs.With(logger.Field{Key: "request_id", Value: "req-1"}))
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	if user, ok := requestctx.UserFrom(ctx); !ok || user.ID != "u-1" || user.Role != "admin" {
+		t.Errorf("unexpected user %+v", user)
+	}
+	if id := requestctx.RequestIDFrom(ctx); id != "req-1" {
+		t.Errorf("expected req-1, got %q", id)
+	}
+	if tenant := requestctx.TenantFrom(ctx); tenant != "acme" || database.TenantID(ctx) != "acme" {
+		t.Errorf("expected the tenant set by the database package, got %q", tenant)
+	}
+	if driver := database.DriverName(ctx); driver != "acme_db" {
+		t.Errorf("expected acme_db, got %q", driver)
+	}
+
+	requestctx.LoggerFrom(ctx).Info("hello")
+	entries := logs.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("expected one entry, got %+v", entries)
+	}
+	if id, _ := entries[0].Field("request_id"); id != "req-1" {
+		t.Errorf("expected the request logger, got %+v", entries[0])
+	}
+}
+
+// TestRequestContextGinAdapter tests that values stored from middleware are
+// read the same through the *gin.Context and the request's context
+func TestRequestContextGinAdapter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		requestctx.Update(c, func(ctx context.Context) context.Context {
+			return requestctx.WithRequestID(ctx, "req-7")
+		})
+		c.Next()
+	})
+
+	var fromGin, fromRequest string
+	router.GET("/", func(c *gin.Context) {
+		fromGin = requestctx.RequestIDFrom(c)
+		fromRequest = requestctx.RequestIDFrom(c.Request.Context())
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if fromGin != "req-7" || fromRequest != "req-7" {
+		t.Errorf("expected req-7 from both contexts, got %q and %q", fromGin, fromRequest)
+	}
+}
+
+// TestServiceLogsCarryRequestID tests that services log through the
+// request's logger, so their entries share the access log's request ID
+func TestServiceLogsCarryRequestID(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/users/"+user.ID.String()+"/deactivate", nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	requestID := resp.Header.Get("X-Request-ID")
+	if requestID == "" {
+		t.Fatal("expected a generated request ID in the response")
+	}
+
+	for _, entry := range ta.Logs.Entries() {
+		if entry.Message != "User active status changed" {
+			continue
+		}
+		if id, _ := entry.Field("request_id"); id != requestID {
+			t.Errorf("expected request_id %s, got %v", requestID, id)
+		}
+		if id, _ := entry.Field("user_id"); id != user.ID.String() {
+			t.Errorf("expected user_id %s, got %v", user.ID, id)
+		}
+		for _, access := range ta.Logs.Entries() {
+			if id, _ := access.Field("request_id"); access.Message == "HTTP Request" && id == requestID {
+				if actor, _ := access.Field("user_id"); actor != admin.ID.String() {
+					t.Errorf("expected the access log to name the admin, got %v", actor)
+				}
+				return
+			}
+		}
+		t.Fatal("expected the access log to share the request ID")
+	}
+	t.Fatal("expected the service to log the change")
+}
+
+// w3cTraceID and w3cSpanID match the IDs of a W3C traceparent header
+var (
+	w3cTraceID = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	w3cSpanID  = regexp.MustCompile(`^[0-9a-f]{16}$`)
+)
+
+// TestLoggerTraceIDs tests that context loggers name the active span, and
+// add nothing without one
+func TestLoggerTraceIDs(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	logs := logger.NewCaptureLogger()
+	ctx := requestctx.WithLogger(context.Background(), logs.With(logger.Field{Key: "request_id", Value: "req-1"}))
+
+	requestctx.LoggerFrom(ctx).Info("before")
+	spanCtx, span := tracer.Start(ctx, "operation")
+	requestctx.LoggerFrom(spanCtx).Info("during")
+	requestctx.LoggerOr(spanCtx, logs).With(logger.Field{Key: "k", Value: "v"}).Info("derived")
+	span.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("expected one recorded span, got %d", len(ended))
+	}
+	want := ended[0].SpanContext()
+
+	entries := logs.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("expected three entries, got %+v", entries)
+	}
+	for _, key := range []string{"trace_id", "span_id"} {
+		if _, ok := entries[0].Field(key); ok {
+			t.Errorf("expected no %s without a span, got %+v", key, entries[0])
+		}
+	}
+	for _, entry := range entries[1:] {
+		traceID, _ := entry.Field("trace_id")
+		spanID, _ := entry.Field("span_id")
+		if traceID != want.TraceID().String() || spanID != want.SpanID().String() {
+			t.Errorf("%s: expected trace %s span %s, got %+v", entry.Message, want.TraceID(), want.SpanID(), entry.Fields)
+		}
+		if !w3cTraceID.MatchString(traceID.(string)) || !w3cSpanID.MatchString(spanID.(string)) {
+			t.Errorf("%s: expected W3C hex IDs, got %v and %v", entry.Message, traceID, spanID)
+		}
+		if id, _ := entry.Field("request_id"); id != "req-1" {
+			t.Errorf("%s: expected the request logger, got %+v", entry.Message, entry.Fields)
+		}
+	}
+
+	// The fallback gets the span's IDs too
+	fallback := logger.NewCaptureLogger()
+	jobCtx, span := tracer.Start(context.Background(), "background")
+	requestctx.LoggerOr(jobCtx, fallback).Info("job")
+	span.End()
+	if id, _ := fallback.Entries()[0].Field("span_id"); id != span.SpanContext().SpanID().String() {
+		t.Errorf("expected the fallback to carry the span, got %+v", fallback.Entries())
+	}
+}
+
+// TestAccessLogTraceIDs tests that the access log names the span tracing
+// middleware started for the request
+func TestAccessLogTraceIDs(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	traced := func(c *gin.Context) {
+		ctx, span := tracer.Start(c.Request.Context(), c.FullPath())
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+
+	logs := logger.NewCaptureLogger()
+	application, err := app.New(apptest.Config(), logs, app.WithRouterMiddleware(traced))
+	if err != nil {
+		t.Fatalf("create application: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		application.Shutdown(ctx)
+	})
+
+	if w := serve(application, http.MethodGet, "/api/v1/version", "", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	ended := recorder.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("expected one recorded span, got %d", len(ended))
+	}
+	for _, entry := range logs.Entries() {
+		if entry.Message != "HTTP Request" {
+			continue
+		}
+		traceID, _ := entry.Field("trace_id")
+		spanID, _ := entry.Field("span_id")
+		if traceID != ended[0].SpanContext().TraceID().String() || spanID != ended[0].SpanContext().SpanID().String() {
+			t.Errorf("expected the request's span, got %+v", entry.Fields)
+		}
+		return
+	}
+	t.Fatal("expected an access log entry")
+}
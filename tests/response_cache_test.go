@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+)
+
+// withResponseCache caches responses of cacheable routes for a minute
+func withResponseCache(cfg *config.Config) {
+	cfg.Cache.ResponseTTL = time.Minute
+}
+
+// cachedGet sends a GET request, with Cache-Control: no-cache if noCache is
+// set, and returns the status, the X-Cache header and the body
+func cachedGet(t *testing.T, ta *apptest.TestApp, path, token string, noCache bool) (int, string, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, ta.Server.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if noCache {
+		req.Header.Set("Cache-Control", "no-cache")
+	}
+
+	resp, err := ta.Server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header.Get(middleware.CacheHeader), string(data)
+}
+
+// TestResponseCache tests that list responses are reused until users change
+func TestResponseCache(t *testing.T) {
+	ta := apptest.NewTestApp(t, withResponseCache)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	_, state, body := cachedGet(t, ta, "/api/v1/users", user.Token, false)
+	if state != "MISS" {
+		t.Fatalf("expected the first listing to miss, got %q", state)
+	}
+	status, state, hit := cachedGet(t, ta, "/api/v1/users", user.Token, false)
+	if status != http.StatusOK || state != "HIT" || hit != body {
+		t.Fatalf("expected the same listing from the cache, got %d %q: %s", status, state, hit)
+	}
+	if _, state, _ := cachedGet(t, ta, "/api/v1/users?page=2", user.Token, false); state != "MISS" {
+		t.Errorf("expected another page to miss, got %q", state)
+	}
+
+	// Creating a user purges the listings
+	resp := ta.Request(http.MethodPost, "/api/v1/users", map[string]string{
+		"email": "cached@example.com", "password": "secret123", "first_name": "Cached", "last_name": "User", "username": "cached", "role": string(models.RoleUser),
+	}, admin.Token)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create user: expected 201, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	_, state, fresh := cachedGet(t, ta, "/api/v1/users", user.Token, false)
+	if state != "MISS" || fresh == body {
+		t.Errorf("expected a fresh listing after a user was created, got %q: %s", state, fresh)
+	}
+
+	// Only 200 responses are cached, and only known parameters are
+	for range 2 {
+		status, state, _ := cachedGet(t, ta, "/api/v1/users?page=0", user.Token, false)
+		if status != http.StatusBadRequest || state != "MISS" {
+			t.Errorf("expected an invalid page to be refused and never cached, got %d %q", status, state)
+		}
+	}
+	for range 2 {
+		if _, state, _ := cachedGet(t, ta, "/api/v1/users?sort=email", user.Token, false); state != "" {
+			t.Errorf("expected unknown parameters to skip the cache, got %q", state)
+		}
+	}
+}
+
+// TestResponseCacheRoles tests that users of different roles never share
+// cached responses and that only admins can bypass the cache
+func TestResponseCacheRoles(t *testing.T) {
+	ta := apptest.NewTestApp(t, withResponseCache)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+	anonymized := ta.CreateUser(models.RoleUser)
+	if resp := ta.Request(http.MethodPost, "/api/v1/users/"+anonymized.ID.String()+"/anonymize", nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("anonymize: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	// Anonymized users are only listed to admins
+	path := "/api/v1/users?include_anonymized=true"
+	_, _, ofUser := cachedGet(t, ta, path, user.Token, false)
+	_, state, ofAdmin := cachedGet(t, ta, path, admin.Token, false)
+	if state != "MISS" || ofAdmin == ofUser {
+		t.Fatalf("expected admins not to get the users' listing, got %q: %s", state, ofAdmin)
+	}
+	if _, state, body := cachedGet(t, ta, path, user.Token, false); state != "HIT" || body != ofUser {
+		t.Errorf("expected users to get their own cached listing, got %q: %s", state, body)
+	}
+	if _, state, body := cachedGet(t, ta, path, admin.Token, false); state != "HIT" || body != ofAdmin {
+		t.Errorf("expected admins to get their own cached listing, got %q: %s", state, body)
+	}
+
+	if _, state, _ := cachedGet(t, ta, path, admin.Token, true); state != "BYPASS" {
+		t.Errorf("expected admins to bypass the cache, got %q", state)
+	}
+	if _, state, _ := cachedGet(t, ta, path, user.Token, true); state != "HIT" {
+		t.Errorf("expected no-cache from users to be ignored, got %q", state)
+	}
+}
+
+// TestResponseCachePolicyReport tests that the policy report is purged when
+// the current versions change and when users accept them
+func TestResponseCachePolicyReport(t *testing.T) {
+	ta := apptest.NewTestApp(t, withResponseCache)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	setPolicyVersions(t, ta, admin, map[string]string{"terms": "v1"})
+	cachedGet(t, ta, "/api/v1/admin/policies", admin.Token, false)
+	_, state, before := cachedGet(t, ta, "/api/v1/admin/policies", admin.Token, false)
+	if state != "HIT" {
+		t.Fatalf("expected the report cached, got %q", state)
+	}
+
+	resp := ta.Request(http.MethodPost, "/api/v1/me/accept-policy", map[string]string{"policy": "terms", "version": "v1"}, user.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("accept: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	_, state, accepted := cachedGet(t, ta, "/api/v1/admin/policies", admin.Token, false)
+	if state != "MISS" || accepted == before {
+		t.Errorf("expected a fresh report after an acceptance, got %q: %s", state, accepted)
+	}
+
+	setPolicyVersions(t, ta, admin, map[string]string{"terms": "v2"})
+	if _, state, _ := cachedGet(t, ta, "/api/v1/admin/policies", admin.Token, false); state != "MISS" {
+		t.Errorf("expected a fresh report after the versions changed, got %q", state)
+	}
+}
+
+// TestResponseCacheDisabled tests that nothing is cached without a TTL
+func TestResponseCacheDisabled(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+
+	for range 2 {
+		if _, state, _ := cachedGet(t, ta, "/api/v1/users", user.Token, false); state != "" {
+			t.Errorf("expected no cache header, got %q", state)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package tests
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/controllers/auth"
+	"BackofficeGoService/internal/app/controllers/user"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	approute "BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/apiversion"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/routes"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestDeclaredRouteChains dumps the effective middleware chain of every
+// route the auth and user controllers declare
+func TestDeclaredRouteChains(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	registrar := routes.NewRegistrar(routes.Dependencies{
+		Features: config.APIFeatures{config.FeatureUserDelete: false},
+	})
+	declared := append(
+		auth.NewAuthController(nil, auth.WithLoginRateLimit(approute.RateLimit{Requests: 10, Window: time.Minute})).Routes(),
+		user.NewUserController(nil, pagination.DefaultConfig).Routes()...,
+	)
+	for _, version := range apiversion.Versions {
+		if err := registrar.Register(router.Group(version.Prefix()), version, declared); err != nil {
+			t.Fatalf("register %s: %v", version, err)
+		}
+	}
+
+	want := []string{
+		"POST /api/v1/auth/login: rate_limit(10/1m0s) -> handler",
+		"POST /api/v1/auth/logout: auth -> quota -> policies -> handler",
+		"POST /api/v1/auth/refresh: handler",
+		"POST /api/v1/auth/register: rate_limit(10/1m0s) -> handler",
+		"POST /api/v1/auth/change-password: password_change_auth -> not_impersonating -> handler",
+		"GET /api/v1/users: auth -> quota -> policies -> cache(users) -> handler",
+		"GET /api/v1/users/search: auth -> quota -> policies -> cache(users) -> handler",
+		"GET /api/v1/users/:id: auth -> quota -> policies -> handler",
+		"PUT /api/v1/users/:id: auth -> quota -> policies -> permission(users.update) -> handler",
+		"POST /api/v1/users/:id/activate: auth -> quota -> policies -> permission(users.manage) -> handler",
+		"POST /api/v1/users/:id/deactivate: auth -> quota -> policies -> permission(users.manage) -> handler",
+		"PUT /api/v1/users/:id/role: auth -> quota -> policies -> permission(users.manage) -> not_impersonating -> handler",
+		"GET /api/v1/users/:id/export: auth -> quota -> policies -> handler",
+		"POST /api/v1/users/:id/anonymize: auth -> quota -> policies -> permission(users.manage) -> confirm -> handler",
+		"POST /api/v1/users: auth -> quota -> policies -> permission(users.create) -> handler",
+		"POST /api/v1/users/:id/require-password-change: auth -> quota -> policies -> permission(users.manage) -> handler",
+		"GET /api/v2/users: auth -> quota -> policies -> cache(users) -> handler",
+		"GET /api/v2/users/search: auth -> quota -> policies -> cache(users) -> handler",
+		"GET /api/v2/users/:id: auth -> quota -> policies -> handler",
+	}
+	var got []string
+	for _, chain := range registrar.Chains() {
+		t.Log(chain)
+		got = append(got, chain.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected chains:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(router.Routes()) != len(want) {
+		t.Errorf("expected %d routes registered, got %d", len(want), len(router.Routes()))
+	}
+}
+
+// TestRouteChainOrder tests that policies become middleware in the fixed
+// order, whatever order a definition lists them in
+func TestRouteChainOrder(t *testing.T) {
+	registrar := routes.NewRegistrar(routes.Dependencies{})
+	err := registrar.Register(gin.New().Group("/api/v1"), apiversion.V1, []approute.Definition{{
+		Method:      http.MethodGet,
+		Path:        "/reports",
+		Handler:     func(c *gin.Context) {},
+		Middlewares: []string{approute.NotImpersonating},
+		Policy: approute.Policy{
+			Cache:      &middleware.CacheRule{Groups: []string{"reports"}},
+			Permission: models.PermissionUsersView,
+			Roles:      []models.UserRole{models.RoleAdmin, models.RoleUser},
+			Auth:       approute.Authenticated,
+			Timeout:    5 * time.Second,
+			RateLimit:  approute.RateLimit{Requests: 30, Window: time.Second},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	want := "GET /api/v1/reports: rate_limit(30/1s) -> timeout(5s) -> auth -> quota -> policies -> roles(admin|user) -> permission(users.view) -> not_impersonating -> cache(reports) -> handler"
+	if chains := registrar.Chains(); len(chains) != 1 || chains[0].String() != want {
+		t.Errorf("expected %s, got %v", want, chains)
+	}
+}
+
+// confirmNothing summarizes requests that destroy nothing
+func confirmNothing(*gin.Context) (*services.ConfirmationSummary, *errors.AppError) {
+	return nil, nil
+}
+
+// TestInvalidRouteDefinitions tests that misconfigured definitions are
+// refused and register nothing
+func TestInvalidRouteDefinitions(t *testing.T) {
+	handler := func(c *gin.Context) {}
+	authenticated := approute.Policy{Auth: approute.Authenticated}
+	cases := map[string]approute.Definition{
+		"unknown permission": {Method: http.MethodGet, Path: "/x", Handler: handler,
+			Policy: approute.Policy{Auth: approute.Authenticated, Permission: "users.fly"}},
+		"cache on POST": {Method: http.MethodPost, Path: "/x", Handler: handler,
+			Policy: approute.Policy{Auth: approute.Authenticated, Cache: &middleware.CacheRule{}}},
+		"confirmation on GET": {Method: http.MethodGet, Path: "/x", Handler: handler,
+			Policy: approute.Policy{Auth: approute.Authenticated, Confirm: &middleware.ConfirmRule{Summarize: confirmNothing}}},
+		"confirmation on signed route": {Method: http.MethodPost, Path: "/x", Handler: handler,
+			Policy: approute.Policy{Auth: approute.Signed, Confirm: &middleware.ConfirmRule{Summarize: confirmNothing}}},
+		"confirmation without summary": {Method: http.MethodPost, Path: "/x", Handler: handler,
+			Policy: approute.Policy{Auth: approute.Authenticated, Confirm: &middleware.ConfirmRule{}}},
+		"permission on public route": {Method: http.MethodGet, Path: "/x", Handler: handler,
+			Policy: approute.Policy{Permission: models.PermissionUsersView}},
+		"unknown role": {Method: http.MethodGet, Path: "/x", Handler: handler,
+			Policy: approute.Policy{Auth: approute.Authenticated, Roles: []models.UserRole{"root"}}},
+		"unknown middleware": {Method: http.MethodGet, Path: "/x", Handler: handler, Policy: authenticated,
+			Middlewares: []string{"cors"}},
+		"rate limit without window": {Method: http.MethodGet, Path: "/x", Handler: handler,
+			Policy: approute.Policy{RateLimit: approute.RateLimit{Requests: 5}}},
+		"negative timeout": {Method: http.MethodGet, Path: "/x", Handler: handler,
+			Policy: approute.Policy{Timeout: -time.Second}},
+		"unknown feature": {Method: http.MethodGet, Path: "/x", Handler: handler, Feature: "teleport"},
+		"unknown version": {Method: http.MethodGet, Path: "/x", Handler: handler, Versions: []apiversion.Version{9}},
+		"unknown method":  {Method: "BREW", Path: "/x", Handler: handler},
+		"no handler":      {Method: http.MethodGet, Path: "/x"},
+	}
+	for name, def := range cases {
+		t.Run(name, func(t *testing.T) {
+			router := gin.New()
+			registrar := routes.NewRegistrar(routes.Dependencies{})
+			valid := approute.Definition{Method: http.MethodGet, Path: "/ok", Handler: handler}
+
+			err := registrar.Register(router.Group("/api/v1"), apiversion.V1, []approute.Definition{valid, def})
+			if !stderrors.Is(err, approute.ErrInvalid) {
+				t.Fatalf("expected ErrInvalid, got %v", err)
+			}
+			if len(router.Routes()) != 0 || len(registrar.Chains()) != 0 {
+				t.Errorf("expected nothing registered, got %v", router.Routes())
+			}
+		})
+	}
+}
+
+// TestRoutePolicyLimits tests the rate limit and timeout of a route policy
+func TestRoutePolicyLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	registrar := routes.NewRegistrar(routes.Dependencies{RateLimits: cache.NewMemoryStore()})
+	err := registrar.Register(router.Group("/api/v1"), apiversion.V1, []approute.Definition{
+		{
+			Method:  http.MethodGet,
+			Path:    "/limited",
+			Handler: func(c *gin.Context) { c.Status(http.StatusNoContent) },
+			Policy:  approute.Policy{RateLimit: approute.RateLimit{Requests: 2, Window: time.Hour}},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/slow",
+			Handler: func(c *gin.Context) {
+				<-c.Request.Context().Done()
+			},
+			Policy: approute.Policy{Timeout: 10 * time.Millisecond},
+		},
+	})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	serve := func(path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := serve("/api/v1/limited", "10.0.0.1"); w.Code != http.StatusNoContent {
+			t.Fatalf("request %d: expected 204, got %d", i+1, w.Code)
+		}
+	}
+	w := serve("/api/v1/limited", "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), string(errors.CodeRateLimited)) || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 429 RATE_LIMITED with Retry-After, got %d %s", w.Code, w.Body)
+	}
+	if w := serve("/api/v1/limited", "10.0.0.2"); w.Code != http.StatusNoContent {
+		t.Errorf("expected another client to pass, got %d", w.Code)
+	}
+
+	w = serve("/api/v1/slow", "10.0.0.1")
+	if w.Code != http.StatusGatewayTimeout || !strings.Contains(w.Body.String(), string(errors.CodeRequestTimeout)) {
+		t.Errorf("expected 504 REQUEST_TIMEOUT, got %d %s", w.Code, w.Body)
+	}
+}
+
+// TestLoginRateLimit tests AUTH_LOGIN_RATE_LIMIT on the API
+func TestLoginRateLimit(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.LoginRateLimit = 3
+	})
+	member := ta.CreateUser(models.RoleUser) // logs in once
+
+	ta.Login(member.Email, member.Password)
+	ta.Login(member.Email, member.Password)
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/login", map[string]string{
+		"email": member.Email, "password": member.Password,
+	}, "")
+	expectErrorCode(t, resp, http.StatusTooManyRequests, errors.CodeRateLimited)
+
+	// Other routes are not limited
+	if resp := ta.Request(http.MethodGet, "/api/v1/users/"+member.ID.String(), nil, member.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected other routes to pass, got %d %s", resp.StatusCode, resp.Body)
+	}
+}
+
+// fixedTokens authenticates every bearer token as the same claims
+type fixedTokens struct {
+	claims *services.TokenClaims
+}
+
+func (f fixedTokens) ValidateToken(ctx context.Context, token string) (*services.TokenClaims, error) {
+	return f.claims, nil
+}
+
+// newClaimsRouter registers defs under /api/v1 with their policies, sending
+// every request with a token authenticated as claims
+func newClaimsRouter(claims *services.TokenClaims, defs []approute.Definition) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request.Header.Set("Authorization", "Bearer test")
+	})
+	registrar := routes.NewRegistrar(routes.Dependencies{Tokens: fixedTokens{claims: claims}})
+	if err := registrar.Register(router.Group("/api/v1"), apiversion.V1, defs); err != nil {
+		panic(err)
+	}
+	return router
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	approute "BackofficeGoService/internal/app/route"
+	"BackofficeGoService/internal/pkg/errors"
+)
+
+// TestRouteTableMatchesRouter tests that the route table lists exactly the
+// routes gin serves, with the same handlers, so no route lacks metadata
+func TestRouteTableMatchesRouter(t *testing.T) {
+	configs := map[string]apptest.Option{
+		"default": func(cfg *config.Config) {},
+		"runtime databases, no webhooks": func(cfg *config.Config) {
+			cfg.Database.RuntimeRegistration = true
+			cfg.API.Features = config.APIFeatures{config.FeatureWebhooks: false}
+			cfg.Auth.LoginRateLimit = 5
+		},
+	}
+	for name, opt := range configs {
+		t.Run(name, func(t *testing.T) {
+			ta := apptest.NewTestApp(t, opt)
+			table := ta.App.RouteTable()
+
+			served := make(map[route]string)
+			for _, r := range ta.App.GetRouter().Routes() {
+				served[route{r.Method, r.Path}] = strings.TrimSuffix(strings.TrimPrefix(r.Handler, "BackofficeGoService/"), "-fm")
+			}
+			listed := make(map[route]bool)
+			for _, info := range table {
+				r := route{info.Method, info.Path}
+				if listed[r] {
+					t.Errorf("%s %s listed twice", r.method, r.path)
+				}
+				listed[r] = true
+				handler, ok := served[r]
+				if !ok {
+					t.Errorf("%s %s listed but not served", r.method, r.path)
+				} else if handler != info.Handler {
+					t.Errorf("%s %s: listed handler %s, served %s", r.method, r.path, info.Handler, handler)
+				}
+			}
+			for r := range served {
+				if !listed[r] {
+					t.Errorf("%s %s served without metadata", r.method, r.path)
+				}
+			}
+			if !slices.IsSortedFunc(table, func(a, b approute.Info) int {
+				return strings.Compare(a.Path+" "+a.Method, b.Path+" "+b.Method)
+			}) {
+				t.Error("expected the table ordered by path and method")
+			}
+		})
+	}
+}
+
+// TestRouteTableMetadata tests the policies the table reports for routes
+// of each kind
+func TestRouteTableMetadata(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.LoginRateLimit = 5
+	})
+	infos := make(map[string]approute.Info)
+	for _, info := range ta.App.RouteTable() {
+		infos[info.Method+" "+info.Path] = info
+	}
+
+	login := infos["POST /api/v1/auth/login"]
+	if login.Auth != "public" || login.RateLimit == nil || login.RateLimit.Requests != 5 || login.RateLimit.Window != "1m0s" {
+		t.Errorf("unexpected login route %+v", login)
+	}
+	if create := infos["POST /api/v1/users"]; create.Auth != "authenticated" || create.Permission != models.PermissionUsersCreate || create.Feature != config.FeatureUserCreate {
+		t.Errorf("unexpected user creation route %+v", create)
+	}
+	if org := infos["PUT /api/v1/organizations/:id"]; !slices.Equal(org.Roles, []string{string(models.RoleAdmin)}) || org.Feature != config.FeatureOrganizations {
+		t.Errorf("unexpected organization route %+v", org)
+	}
+	for path, auth := range map[string]string{
+		"GET /api/v1/me":                       "profile_completion",
+		"GET /api/v1/ws":                       "stream",
+		"POST /api/v1/partners/provision-user": "signed",
+		"POST /api/v1/auth/change-password":    "password_change",
+		"GET /health":                          "public",
+	} {
+		if got := infos[path].Auth; got != auth {
+			t.Errorf("%s: expected auth %s, got %q", path, auth, got)
+		}
+	}
+	for _, path := range []string{"DELETE /api/v1/users/:id", "POST /api/v1/users/:id/anonymize", "POST /api/v1/admin/jobs/:name/run"} {
+		if !infos[path].Confirm {
+			t.Errorf("expected %s to ask for confirmation", path)
+		}
+	}
+	if infos["PUT /api/v1/users/:id"].Confirm {
+		t.Error("expected updates to run unconfirmed")
+	}
+	if users := infos["GET /api/v2/users"]; users.Handler != "internal/app/controllers/user.(*UserController).ListUsers" {
+		t.Errorf("unexpected v2 users route %+v", users)
+	}
+}
+
+// TestRouteTableEndpoint tests serving the route table to operators as
+// JSON and Markdown
+func TestRouteTableEndpoint(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	operator := ta.CreateUser(models.RoleAdmin)
+	member := ta.CreateUser(models.RoleUser)
+
+	if resp := ta.Request(http.MethodGet, "/api/v1/admin/routes", nil, member.Token); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for a user, got %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Data []approute.Info `json:"data"`
+	}
+	resp := ta.Request(http.MethodGet, "/api/v1/admin/routes", nil, operator.Token)
+	resp.Decode(t, &list)
+	if resp.StatusCode != http.StatusOK || len(list.Data) != len(ta.App.RouteTable()) {
+		t.Fatalf("unexpected table %d with %d routes", resp.StatusCode, len(list.Data))
+	}
+	if i := slices.IndexFunc(list.Data, func(info approute.Info) bool { return info.Path == "/api/v1/admin/routes" }); i < 0 || list.Data[i].Permission != models.PermissionRoutesView {
+		t.Errorf("expected the route table itself listed with routes.view")
+	}
+
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/routes?format=markdown", nil, operator.Token)
+	body := string(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/markdown") ||
+		!strings.HasPrefix(body, "| Method | Path | Auth |") ||
+		!strings.Contains(body, "| GET | `/api/v1/admin/routes` | authenticated |  | routes.view |") {
+		t.Errorf("unexpected markdown %d:\n%s", resp.StatusCode, body)
+	}
+	if lines := strings.Count(body, "\n"); lines != len(list.Data)+2 {
+		t.Errorf("expected a header, a separator and %d rows, got %d lines", len(list.Data), lines)
+	}
+
+	resp = ta.Request(http.MethodGet, "/api/v1/admin/routes?format=yaml", nil, operator.Token)
+	expectErrorCode(t, resp, http.StatusBadRequest, errors.CodeInvalidRouteFormat)
+}
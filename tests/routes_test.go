@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"BackofficeGoService/internal/app/apptest"
+)
+
+type route struct {
+	method, path string
+}
+
+// expectedRoutes is every route the application serves
+var expectedRoutes = []route{
+	{"DELETE", "/api/v1/admin/features/:key"},
+	{"DELETE", "/api/v1/admin/partners/:id"},
+	{"DELETE", "/api/v1/me/devices/:id"},
+	{"DELETE", "/api/v1/me/filters/:id"},
+	{"DELETE", "/api/v1/me/sessions"},
+	{"DELETE", "/api/v1/me/sessions/:sid"},
+	{"DELETE", "/api/v1/organizations/:id"},
+	{"DELETE", "/api/v1/organizations/:id/members/:userId"},
+	{"DELETE", "/api/v1/admin/approvals/:id"},
+	{"DELETE", "/api/v1/products/:id"},
+	{"DELETE", "/api/v1/tasks/:id"},
+	{"DELETE", "/api/v1/users/:id"},
+	{"DELETE", "/api/v1/users/:id/sessions"},
+	{"DELETE", "/api/v1/users/:id/sessions/:sid"},
+	{"DELETE", "/api/v1/webhooks/:id"},
+	{"GET", "/api/v1/admin/alerts"},
+	{"GET", "/api/v1/admin/approvals"},
+	{"GET", "/api/v1/admin/approvals/:id"},
+	{"GET", "/api/v1/admin/audit-logs/export"},
+	{"GET", "/api/v1/admin/emails/templates"},
+	{"GET", "/api/v1/admin/emails/templates/:name/preview"},
+	{"GET", "/api/v1/admin/features"},
+	{"GET", "/api/v1/admin/features/:key"},
+	{"GET", "/api/v1/admin/jobs"},
+	{"GET", "/api/v1/admin/partners"},
+	{"GET", "/api/v1/admin/partners/:id"},
+	{"GET", "/api/v1/admin/policies"},
+	{"GET", "/api/v1/admin/profiles"},
+	{"GET", "/api/v1/admin/read-only"},
+	{"GET", "/api/v1/admin/request-logs"},
+	{"GET", "/api/v1/admin/routes"},
+	{"GET", "/api/v1/admin/ratelimit/offenders"},
+	{"GET", "/api/v1/admin/settings"},
+	{"GET", "/api/v1/admin/tenants"},
+	{"GET", "/api/v1/admin/usage"},
+	{"GET", "/api/v1/auth/oidc/:provider/callback"},
+	{"GET", "/api/v1/auth/oidc/:provider/login"},
+	{"GET", "/api/v1/auth/oidc/providers"},
+	{"GET", "/api/v1/error-codes"},
+	{"GET", "/api/v1/events/stream"},
+	{"GET", "/api/v1/files/:token"},
+	{"GET", "/api/v1/me"},
+	{"GET", "/api/v1/me/devices"},
+	{"GET", "/api/v1/me/features"},
+	{"GET", "/api/v1/me/filters"},
+	{"GET", "/api/v1/me/filters/shared"},
+	{"GET", "/api/v1/me/notifications"},
+	{"GET", "/api/v1/me/sessions"},
+	{"GET", "/api/v1/me/usage"},
+	{"GET", "/api/v1/organizations"},
+	{"GET", "/api/v1/organizations/:id"},
+	{"GET", "/api/v1/organizations/:id/members"},
+	{"GET", "/api/v1/permissions"},
+	{"GET", "/api/v1/products"},
+	{"GET", "/api/v1/products/:id"},
+	{"GET", "/api/v1/roles/:role/permissions"},
+	{"GET", "/api/v1/tasks/:id"},
+	{"GET", "/api/v1/users"},
+	{"GET", "/api/v1/users/:id"},
+	{"GET", "/api/v1/users/:id/activity"},
+	{"GET", "/api/v1/users/:id/export"},
+	{"GET", "/api/v1/users/:id/sessions"},
+	{"GET", "/api/v1/users/export"},
+	{"GET", "/api/v1/users/search"},
+	{"GET", "/api/v1/version"},
+	{"GET", "/api/v1/webhooks"},
+	{"GET", "/api/v1/webhooks/:id"},
+	{"GET", "/api/v1/webhooks/:id/deliveries"},
+	{"GET", "/api/v1/ws"},
+	{"GET", "/api/v2/users"},
+	{"GET", "/api/v2/users/:id"},
+	{"GET", "/api/v2/users/search"},
+	{"GET", "/health"},
+	{"GET", "/metrics"},
+	{"GET", "/ready"},
+	{"POST", "/api/v1/admin/approvals/:id/approve"},
+	{"POST", "/api/v1/admin/approvals/:id/reject"},
+	{"POST", "/api/v1/admin/audit-logs/verify"},
+	{"POST", "/api/v1/admin/emails/templates/:name/test-send"},
+	{"POST", "/api/v1/admin/features"},
+	{"POST", "/api/v1/admin/impersonate/:id"},
+	{"POST", "/api/v1/admin/impersonate/stop"},
+	{"POST", "/api/v1/admin/jobs/:name/run"},
+	{"POST", "/api/v1/admin/jwt/rotate-secret"},
+	{"POST", "/api/v1/admin/partners"},
+	{"POST", "/api/v1/admin/tenants"},
+	{"POST", "/api/v1/auth/change-password"},
+	{"POST", "/api/v1/auth/confirm-email-change"},
+	{"POST", "/api/v1/auth/login"},
+	{"POST", "/api/v1/auth/logout"},
+	{"POST", "/api/v1/auth/refresh"},
+	{"POST", "/api/v1/auth/register"},
+	{"POST", "/api/v1/auth/secure-account"},
+	{"POST", "/api/v1/me/accept-policy"},
+	{"POST", "/api/v1/me/email-change"},
+	{"POST", "/api/v1/me/filters"},
+	{"POST", "/api/v1/me/notifications/:id/read"},
+	{"POST", "/api/v1/me/notifications/read-all"},
+	{"POST", "/api/v1/organizations"},
+	{"POST", "/api/v1/organizations/:id/members"},
+	{"POST", "/api/v1/partners/provision-user"},
+	{"POST", "/api/v1/products"},
+	{"POST", "/api/v1/users"},
+	{"POST", "/api/v1/users/:id/activate"},
+	{"POST", "/api/v1/users/:id/anonymize"},
+	{"POST", "/api/v1/users/:id/deactivate"},
+	{"POST", "/api/v1/users/:id/force-logout"},
+	{"POST", "/api/v1/users/:id/require-password-change"},
+	{"POST", "/api/v1/users/export"},
+	{"POST", "/api/v1/webhooks"},
+	{"POST", "/api/v1/webhooks/:id/test"},
+	{"PUT", "/api/v1/admin/features/:key"},
+	{"PUT", "/api/v1/admin/partners/:id"},
+	{"PUT", "/api/v1/admin/read-only"},
+	{"PUT", "/api/v1/admin/settings"},
+	{"PUT", "/api/v1/me"},
+	{"PUT", "/api/v1/me/filters/:id"},
+	{"PUT", "/api/v1/organizations/:id"},
+	{"PUT", "/api/v1/products/:id"},
+	{"PUT", "/api/v1/roles/:role/permissions"},
+	{"PUT", "/api/v1/users/:id"},
+	{"PUT", "/api/v1/users/:id/quota"},
+	{"PUT", "/api/v1/users/:id/role"},
+	{"PUT", "/api/v1/webhooks/:id"},
+}
+
+// TestRegisteredRoutes tests that the router serves exactly the expected routes
+func TestRegisteredRoutes(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+
+	registered := make(map[route]bool)
+	for _, r := range ta.App.GetRouter().Routes() {
+		registered[route{r.Method, r.Path}] = true
+	}
+	for _, r := range expectedRoutes {
+		if !registered[r] {
+			t.Errorf("missing route %s %s", r.method, r.path)
+		}
+		delete(registered, r)
+	}
+	for r := range registered {
+		t.Errorf("unexpected route %s %s", r.method, r.path)
+	}
+}
+
+// publicRoutes are the API routes besides auth that need no token
+var publicRoutes = map[string]bool{
+	"/api/v1/error-codes": true,
+	"/api/v1/version":     true,
+	// Signed download links carry their own authorization
+	"/api/v1/files/:token": true,
+}
+
+// TestRoutesRequireAuthentication tests that only health, auth and public routes are public
+func TestRoutesRequireAuthentication(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+
+	for _, r := range expectedRoutes {
+		if !strings.HasPrefix(r.path, "/api/") || strings.HasPrefix(r.path, "/api/v1/auth/") || publicRoutes[r.path] {
+			continue
+		}
+		path := strings.NewReplacer(":id", "x", ":key", "x", ":userId", "x", ":name", "x", ":role", "x").Replace(r.path)
+		if resp := ta.Request(r.method, path, nil, ""); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without a token, got %d", r.method, r.path, resp.StatusCode)
+		}
+	}
+}
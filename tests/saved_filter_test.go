@@ -0,0 +1,305 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	apperrors "BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// TestFilterSchema tests decoding, upgrading and merging filters
+func TestFilterSchema(t *testing.T) {
+	schema := services.UserFilterSchema
+
+	values, err := schema.Decode([]byte(`{"role": "user", "active": false, "created_after": null}`), schema.Version)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(values) != 2 || values["role"] != "user" || values["active"] != "false" {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	var fieldErr *services.FilterFieldError
+	_, err = schema.Decode([]byte(`{"role": "user", "status": "locked"}`), schema.Version)
+	if !errors.As(err, &fieldErr) || !errors.Is(err, services.ErrUnknownFilterField) || fieldErr.Field != "status" {
+		t.Errorf("expected status refused as unknown, got %v", err)
+	}
+	if _, err := schema.Decode([]byte(`{"active": "maybe"}`), schema.Version); !errors.Is(err, services.ErrInvalidFilterValue) {
+		t.Errorf("expected an invalid active refused, got %v", err)
+	}
+	if _, err := schema.Decode([]byte(`{"role": ["user"]}`), schema.Version); !errors.Is(err, services.ErrInvalidFilterValue) {
+		t.Errorf("expected a list refused, got %v", err)
+	}
+	if _, err := schema.Decode([]byte(`{}`), schema.Version+1); !errors.Is(err, services.ErrFilterSchemaTooNew) {
+		t.Errorf("expected a newer schema refused, got %v", err)
+	}
+
+	// Filters saved before a field was renamed are upgraded when loaded
+	renamed := services.FilterSchema{
+		Version: 2,
+		Fields:  []string{"state"},
+		Upgrades: map[int]func(map[string]string) error{
+			1: func(values map[string]string) error {
+				if status, ok := values["status"]; ok {
+					values["state"] = status
+					delete(values, "status")
+				}
+				return nil
+			},
+		},
+	}
+	if values, err := renamed.Decode([]byte(`{"status": "locked"}`), 1); err != nil || values["state"] != "locked" || len(values) != 1 {
+		t.Errorf("expected status upgraded to state, got %v %v", values, err)
+	}
+	if _, err := renamed.Decode([]byte(`{"status": "locked"}`), 2); !errors.Is(err, services.ErrUnknownFilterField) {
+		t.Errorf("expected status refused at version 2, got %v", err)
+	}
+}
+
+// TestFilterMergePrecedence tests that request parameters win over the
+// saved filter, field by field
+func TestFilterMergePrecedence(t *testing.T) {
+	saved := map[string]string{"role": "user", "active": "false", "created_after": "2024-01-01"}
+	query := url.Values{
+		"active":         {"true"},
+		"created_after":  {""},
+		"created_before": {"2024-06-30"},
+		"status":         {"locked"},
+		"page":           {"2"},
+	}
+
+	merged := services.UserFilterSchema.Merge(saved, query)
+	want := map[string]string{"role": "user", "active": "true", "created_before": "2024-06-30"}
+	if len(merged) != len(want) {
+		t.Fatalf("expected %v, got %v", want, merged)
+	}
+	for name, value := range want {
+		if merged[name] != value {
+			t.Errorf("%s: expected %q, got %q", name, value, merged[name])
+		}
+	}
+	if saved["active"] != "false" {
+		t.Error("expected the saved values left alone")
+	}
+
+	filter, err := services.ParseUserFilter(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.Role != models.RoleUser || filter.Active == nil || !*filter.Active || filter.CreatedAfter != nil {
+		t.Errorf("unexpected filter %+v", filter)
+	}
+	if end := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC); filter.CreatedBefore == nil || !filter.CreatedBefore.Equal(end) {
+		t.Errorf("expected created_before to include June 30, got %v", filter.CreatedBefore)
+	}
+}
+
+// savedFilter is a filter as the API returns it
+type savedFilter struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	SchemaVersion int    `json:"schema_version"`
+	Shared        bool   `json:"shared"`
+}
+
+// listUserIDs lists users with query and returns their IDs
+func listUserIDs(t *testing.T, ta *apptest.TestApp, query, token string) []string {
+	t.Helper()
+	resp := ta.Request(http.MethodGet, "/api/v1/users?limit=100&"+query, nil, token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("list %s: expected 200, got %d %s", query, resp.StatusCode, resp.Body)
+	}
+	var body struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	resp.Decode(t, &body)
+	ids := make([]string, len(body.Data))
+	for i, user := range body.Data {
+		ids[i] = user.ID
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// sortedIDs returns the users' IDs, sorted
+func sortedIDs(users ...*apptest.User) []string {
+	ids := make([]string, len(users))
+	for i, user := range users {
+		ids[i] = user.ID.String()
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// TestSavedFilters tests saving, sharing and applying user filters
+func TestSavedFilters(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	agent := ta.CreateUser(models.RoleUser)
+	colleague := ta.CreateUser(models.RoleUser)
+	guest := ta.CreateUser(models.RoleGuest)
+	inactive := ta.CreateUser(models.RoleUser)
+	if err := ta.DB().Model(&models.User{}).Where("id = ?", inactive.ID).Update("active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	resp := ta.Request(http.MethodPost, "/api/v1/me/filters", map[string]interface{}{
+		"name":          "Inactive agents",
+		"resource_type": "users",
+		"filter":        map[string]interface{}{"role": "user", "active": false},
+		"shared":        true,
+	}, agent.Token)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d %s", resp.StatusCode, resp.Body)
+	}
+	var created struct {
+		Data savedFilter `json:"data"`
+	}
+	resp.Decode(t, &created)
+	shared := created.Data
+	if shared.SchemaVersion != services.UserFilterSchema.Version {
+		t.Errorf("expected schema version %d, got %d", services.UserFilterSchema.Version, shared.SchemaVersion)
+	}
+
+	t.Run("applies the saved filter", func(t *testing.T) {
+		if ids := listUserIDs(t, ta, "filter_id="+shared.ID, agent.Token); !slices.Equal(ids, sortedIDs(inactive)) {
+			t.Errorf("expected the inactive user, got %v", ids)
+		}
+	})
+
+	t.Run("request parameters win", func(t *testing.T) {
+		if ids := listUserIDs(t, ta, "filter_id="+shared.ID+"&active=true", agent.Token); !slices.Equal(ids, sortedIDs(agent, colleague)) {
+			t.Errorf("expected the active users, got %v", ids)
+		}
+		// Clearing the role lists inactive users of every role
+		if ids := listUserIDs(t, ta, "filter_id="+shared.ID+"&role=", admin.Token); !slices.Equal(ids, sortedIDs(inactive)) {
+			t.Errorf("expected the inactive user, got %v", ids)
+		}
+		resp := ta.Request(http.MethodGet, "/api/v1/users?filter_id="+shared.ID+"&role=owner", nil, agent.Token)
+		expectErrorCode(t, resp, http.StatusBadRequest, apperrors.CodeInvalidFilter)
+	})
+
+	t.Run("refuses unknown fields", func(t *testing.T) {
+		resp := ta.Request(http.MethodPost, "/api/v1/me/filters", map[string]interface{}{
+			"name":          "Locked",
+			"resource_type": "users",
+			"filter":        map[string]interface{}{"status": "locked"},
+		}, agent.Token)
+		expectErrorCode(t, resp, http.StatusUnprocessableEntity, apperrors.CodeUnknownFilterField)
+		if !strings.Contains(string(resp.Body), "status") || !strings.Contains(string(resp.Body), "created_after") {
+			t.Errorf("expected the field and the allowed ones named, got %s", resp.Body)
+		}
+
+		resp = ta.Request(http.MethodPost, "/api/v1/me/filters", map[string]interface{}{
+			"name":          "Orders",
+			"resource_type": "orders",
+			"filter":        map[string]interface{}{},
+		}, agent.Token)
+		expectErrorCode(t, resp, http.StatusUnprocessableEntity, apperrors.CodeUnknownFilterResource)
+	})
+
+	t.Run("shares with those who may list users", func(t *testing.T) {
+		var list struct {
+			Data []savedFilter `json:"data"`
+		}
+		ta.Request(http.MethodGet, "/api/v1/me/filters/shared", nil, colleague.Token).Decode(t, &list)
+		if len(list.Data) != 1 || list.Data[0].ID != shared.ID {
+			t.Errorf("expected the shared filter, got %+v", list.Data)
+		}
+		ta.Request(http.MethodGet, "/api/v1/me/filters/shared", nil, agent.Token).Decode(t, &list)
+		if len(list.Data) != 0 {
+			t.Errorf("expected the owner's own filters left out, got %+v", list.Data)
+		}
+		ta.Request(http.MethodGet, "/api/v1/me/filters/shared", nil, guest.Token).Decode(t, &list)
+		if len(list.Data) != 0 {
+			t.Errorf("expected nothing shared with guests, got %+v", list.Data)
+		}
+
+		if ids := listUserIDs(t, ta, "filter_id="+shared.ID, colleague.Token); !slices.Equal(ids, sortedIDs(inactive)) {
+			t.Errorf("expected the colleague to apply the filter, got %v", ids)
+		}
+		resp := ta.Request(http.MethodGet, "/api/v1/users?filter_id="+shared.ID, nil, guest.Token)
+		expectErrorCode(t, resp, http.StatusNotFound, apperrors.CodeSavedFilterNotFound)
+	})
+
+	t.Run("only the owner or an admin edits", func(t *testing.T) {
+		resp := ta.Request(http.MethodPut, "/api/v1/me/filters/"+shared.ID, map[string]interface{}{"name": "Mine now"}, colleague.Token)
+		expectErrorCode(t, resp, http.StatusForbidden, apperrors.CodeSavedFilterForbidden)
+		resp = ta.Request(http.MethodDelete, "/api/v1/me/filters/"+shared.ID, nil, colleague.Token)
+		expectErrorCode(t, resp, http.StatusForbidden, apperrors.CodeSavedFilterForbidden)
+
+		resp = ta.Request(http.MethodPut, "/api/v1/me/filters/"+shared.ID, map[string]interface{}{"name": "Inactive users"}, admin.Token)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("admin update: expected 200, got %d %s", resp.StatusCode, resp.Body)
+		}
+		resp = ta.Request(http.MethodPut, "/api/v1/me/filters/"+shared.ID, map[string]interface{}{"shared": false}, agent.Token)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("owner update: expected 200, got %d %s", resp.StatusCode, resp.Body)
+		}
+
+		// Private filters are not found by others, admins included
+		for _, other := range []*apptest.User{colleague, admin} {
+			resp = ta.Request(http.MethodPut, "/api/v1/me/filters/"+shared.ID, map[string]interface{}{"shared": true}, other.Token)
+			expectErrorCode(t, resp, http.StatusNotFound, apperrors.CodeSavedFilterNotFound)
+		}
+		resp = ta.Request(http.MethodGet, "/api/v1/users?filter_id="+shared.ID, nil, colleague.Token)
+		expectErrorCode(t, resp, http.StatusNotFound, apperrors.CodeSavedFilterNotFound)
+
+		var list struct {
+			Data []savedFilter `json:"data"`
+		}
+		ta.Request(http.MethodGet, "/api/v1/me/filters", nil, agent.Token).Decode(t, &list)
+		if len(list.Data) != 1 || list.Data[0].Name != "Inactive users" || list.Data[0].Shared {
+			t.Errorf("expected the renamed private filter, got %+v", list.Data)
+		}
+	})
+
+	t.Run("checks stored filters against the current schema", func(t *testing.T) {
+		stale := &models.SavedFilter{
+			ID:            uuid.New(),
+			OwnerID:       agent.ID,
+			Name:          "Stale",
+			ResourceType:  models.FilterResourceUsers,
+			Filter:        models.JSON(`{"status": "locked"}`),
+			SchemaVersion: services.UserFilterSchema.Version,
+		}
+		future := &models.SavedFilter{
+			ID:            uuid.New(),
+			OwnerID:       agent.ID,
+			Name:          "Future",
+			ResourceType:  models.FilterResourceUsers,
+			Filter:        models.JSON(`{}`),
+			SchemaVersion: services.UserFilterSchema.Version + 1,
+		}
+		for _, filter := range []*models.SavedFilter{stale, future} {
+			if err := ta.DB().Create(filter).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		resp := ta.Request(http.MethodGet, "/api/v1/users?filter_id="+stale.ID.String(), nil, agent.Token)
+		expectErrorCode(t, resp, http.StatusUnprocessableEntity, apperrors.CodeUnknownFilterField)
+		resp = ta.Request(http.MethodGet, "/api/v1/users?filter_id="+future.ID.String(), nil, agent.Token)
+		expectErrorCode(t, resp, http.StatusUnprocessableEntity, apperrors.CodeFilterSchemaUnsupported)
+	})
+
+	t.Run("owners delete", func(t *testing.T) {
+		if resp := ta.Request(http.MethodDelete, "/api/v1/me/filters/"+shared.ID, nil, agent.Token); resp.StatusCode != http.StatusOK {
+			t.Fatalf("delete: expected 200, got %d %s", resp.StatusCode, resp.Body)
+		}
+		resp := ta.Request(http.MethodGet, "/api/v1/users?filter_id="+shared.ID, nil, agent.Token)
+		expectErrorCode(t, resp, http.StatusNotFound, apperrors.CodeSavedFilterNotFound)
+	})
+}
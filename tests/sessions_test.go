@@ -0,0 +1,455 @@
+package tests
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/clock"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// sessionList is the body of a session listing
+type sessionList struct {
+	Data []struct {
+		ID         string    `json:"id"`
+		UserAgent  string    `json:"user_agent"`
+		IPAddress  string    `json:"ip_address"`
+		LastSeenAt time.Time `json:"last_seen_at"`
+		ExpiresAt  time.Time `json:"expires_at"`
+		Current    bool      `json:"current"`
+	} `json:"data"`
+}
+
+// listSessions lists the sessions at path, failing on anything but 200
+func listSessions(t *testing.T, ta *apptest.TestApp, path, token string) sessionList {
+	t.Helper()
+	resp := ta.Request(http.MethodGet, path, nil, token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("list sessions: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var body sessionList
+	resp.Decode(t, &body)
+	return body
+}
+
+// currentSession returns the ID of the session marked current
+func currentSession(t *testing.T, list sessionList) string {
+	t.Helper()
+	for _, session := range list.Data {
+		if session.Current {
+			return session.ID
+		}
+	}
+	t.Fatalf("no current session in %+v", list.Data)
+	return ""
+}
+
+// expectRevoked checks that token is refused as revoked
+func expectRevoked(t *testing.T, ta *apptest.TestApp, token string) {
+	t.Helper()
+	resp := ta.Request(http.MethodGet, "/api/v1/me/features", nil, token)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a revoked session, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var envelope errorEnvelope
+	resp.Decode(t, &envelope)
+	if envelope.Error.Code != string(errors.CodeTokenRevoked) {
+		t.Errorf("expected %s, got %+v", errors.CodeTokenRevoked, envelope.Error)
+	}
+}
+
+// TestSessionRevocation tests that revoking a session refuses its token on
+// the next request and its refresh, while other sessions keep working
+func TestSessionRevocation(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+	other := ta.Login(user.Email, user.Password)
+
+	list := listSessions(t, ta, "/api/v1/me/sessions", user.Token)
+	if len(list.Data) != 2 {
+		t.Fatalf("expected two sessions, got %+v", list.Data)
+	}
+	current := currentSession(t, list)
+	if list.Data[0].UserAgent == "" || list.Data[0].IPAddress == "" || list.Data[0].ExpiresAt.IsZero() {
+		t.Errorf("expected the client and expiry to be recorded, got %+v", list.Data[0])
+	}
+
+	otherSID := currentSession(t, listSessions(t, ta, "/api/v1/me/sessions", other))
+	if otherSID == current {
+		t.Fatal("expected each login to start its own session")
+	}
+
+	resp := ta.Request(http.MethodDelete, "/api/v1/me/sessions/"+otherSID, nil, user.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	expectRevoked(t, ta, other)
+	if resp := ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": other}, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the revoked session not to refresh, got %d", resp.StatusCode)
+	}
+
+	// The revoking session is unaffected and no longer lists the other one
+	list = listSessions(t, ta, "/api/v1/me/sessions", user.Token)
+	if len(list.Data) != 1 || list.Data[0].ID != current {
+		t.Errorf("expected only the current session, got %+v", list.Data)
+	}
+
+	// Revoking it again, or someone else's session, is not found
+	if resp := ta.Request(http.MethodDelete, "/api/v1/me/sessions/"+otherSID, nil, user.Token); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for a revoked session, got %d", resp.StatusCode)
+	}
+	stranger := ta.CreateUser(models.RoleUser)
+	strangerSID := currentSession(t, listSessions(t, ta, "/api/v1/me/sessions", stranger.Token))
+	if resp := ta.Request(http.MethodDelete, "/api/v1/me/sessions/"+strangerSID, nil, user.Token); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for another user's session, got %d", resp.StatusCode)
+	}
+	if resp := ta.Request(http.MethodGet, "/api/v1/me/features", nil, stranger.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the other user's session to keep working, got %d", resp.StatusCode)
+	}
+}
+
+// TestLogoutEndsSession tests that logging out ends the token's session
+// like revoking it does, leaving the user's other sessions alone
+func TestLogoutEndsSession(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+	other := ta.Login(user.Email, user.Password)
+	current := currentSession(t, listSessions(t, ta, "/api/v1/me/sessions", user.Token))
+
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/logout", nil, other)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("logout: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	expectRevoked(t, ta, other)
+	if resp := ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": other}, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the logged out session not to refresh, got %d", resp.StatusCode)
+	}
+	if list := listSessions(t, ta, "/api/v1/me/sessions", user.Token); len(list.Data) != 1 || list.Data[0].ID != current {
+		t.Errorf("expected only the other session listed, got %+v", list.Data)
+	}
+	if n := countRows(t, ta.DB(), &models.AuditLog{}, "action = ? AND entity_id = ?", models.AuditActionUserSessionsRevoked, user.ID.String()); n != 1 {
+		t.Errorf("expected the logout audited, got %d entries", n)
+	}
+
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/auth/logout", nil, other), http.StatusUnauthorized, errors.CodeTokenRevoked)
+	expectErrorCode(t, ta.Request(http.MethodPost, "/api/v1/auth/logout", nil, ""), http.StatusUnauthorized, errors.CodeTokenRequired)
+}
+
+// TestSessionRefreshKeepsSession tests that refreshed tokens stay in their
+// session and are revoked with it
+func TestSessionRefreshKeepsSession(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": user.Token}, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("refresh: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var refreshed struct {
+		Token string `json:"token"`
+	}
+	resp.Decode(t, &refreshed)
+
+	list := listSessions(t, ta, "/api/v1/me/sessions", refreshed.Token)
+	if len(list.Data) != 1 || currentSession(t, list) == "" {
+		t.Fatalf("expected the refreshed token in the login's session, got %+v", list.Data)
+	}
+
+	resp = ta.Request(http.MethodDelete, "/api/v1/me/sessions/"+list.Data[0].ID, nil, refreshed.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	expectRevoked(t, ta, user.Token)
+	expectRevoked(t, ta, refreshed.Token)
+}
+
+// TestRevokeOtherSessions tests signing out everywhere but the current session
+func TestRevokeOtherSessions(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+	others := []string{ta.Login(user.Email, user.Password), ta.Login(user.Email, user.Password)}
+
+	resp := ta.Request(http.MethodDelete, "/api/v1/me/sessions", nil, user.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke others: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var body struct {
+		Data struct {
+			Revoked int `json:"revoked"`
+		} `json:"data"`
+	}
+	resp.Decode(t, &body)
+	if body.Data.Revoked != 2 {
+		t.Errorf("expected two revoked sessions, got %d", body.Data.Revoked)
+	}
+
+	for _, token := range others {
+		expectRevoked(t, ta, token)
+	}
+	if list := listSessions(t, ta, "/api/v1/me/sessions", user.Token); len(list.Data) != 1 || !list.Data[0].Current {
+		t.Errorf("expected only the current session to remain, got %+v", list.Data)
+	}
+}
+
+// TestAdminSessionRoutes tests that admins list and end a user's sessions
+// and that users cannot reach the admin routes
+func TestAdminSessionRoutes(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+	second := ta.Login(user.Email, user.Password)
+	path := "/api/v1/users/" + user.ID.String() + "/sessions"
+
+	if resp := ta.Request(http.MethodGet, path, nil, user.Token); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected users to be refused the admin routes, got %d", resp.StatusCode)
+	}
+
+	list := listSessions(t, ta, path, admin.Token)
+	if len(list.Data) != 2 {
+		t.Fatalf("expected two sessions, got %+v", list.Data)
+	}
+	for _, session := range list.Data {
+		if session.Current {
+			t.Errorf("expected none of the user's sessions to be the admin's, got %+v", session)
+		}
+	}
+
+	resp := ta.Request(http.MethodDelete, path+"/"+list.Data[0].ID, nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	if resp := ta.Request(http.MethodDelete, path, nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke all: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	expectRevoked(t, ta, user.Token)
+	expectRevoked(t, ta, second)
+	if list := listSessions(t, ta, path, admin.Token); len(list.Data) != 0 {
+		t.Errorf("expected no active sessions, got %+v", list.Data)
+	}
+
+	// The revocations show on the user's timeline with the admin as actor
+	var timeline struct {
+		Data []services.ActivityEntry `json:"data"`
+	}
+	ta.Request(http.MethodGet, "/api/v1/users/"+user.ID.String()+"/activity", nil, admin.Token).Decode(t, &timeline)
+	var summaries []string
+	for _, entry := range timeline.Data {
+		if entry.Type == models.AuditActionUserSessionsRevoked {
+			summaries = append(summaries, entry.Summary)
+			if entry.Actor == nil || entry.Actor.ID != admin.ID.String() {
+				t.Errorf("expected the admin as actor, got %+v", entry.Actor)
+			}
+		}
+	}
+	if len(summaries) != 2 || !strings.HasPrefix(summaries[0], "Signed out of 1 session") || !strings.HasPrefix(summaries[1], "Signed out of 1 session") {
+		t.Errorf("unexpected revocation entries %v", summaries)
+	}
+}
+
+// TestImpersonationEndsWithAdminSession tests that impersonation tokens
+// belong to the admin's session
+func TestImpersonationEndsWithAdminSession(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	impersonation := impersonate(t, ta, admin, user)
+
+	// Impersonators may look at the user's sessions but not end them
+	if list := listSessions(t, ta, "/api/v1/me/sessions", impersonation); len(list.Data) != 1 || list.Data[0].Current {
+		t.Errorf("expected the user's session, not current, got %+v", list.Data)
+	}
+	if resp := ta.Request(http.MethodDelete, "/api/v1/me/sessions", nil, impersonation); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected impersonators not to end sessions, got %d", resp.StatusCode)
+	}
+
+	adminSID := currentSession(t, listSessions(t, ta, "/api/v1/me/sessions", admin.Token))
+	if resp := ta.Request(http.MethodDelete, "/api/v1/me/sessions/"+adminSID, nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke: expected 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	expectRevoked(t, ta, impersonation)
+}
+
+// TestSessionPurge tests that the cleanup purges sessions only once they
+// expired or were revoked before the cutoff
+func TestSessionPurge(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	user := ta.CreateUser(models.RoleUser)
+	svc := services.NewSessionService(
+		services.NewSessionRepository(ta.App.GetDBManager()),
+		services.NewTokenRevoker(cache.NewMemoryStore(), time.Hour),
+		cache.NewMemoryStore(),
+		services.NewAuditService(ta.App.GetDBManager(), logger.NewSimpleLogger()),
+		logger.NewSimpleLogger(),
+	)
+
+	now := time.Now()
+	longAgo := now.Add(-30 * 24 * time.Hour)
+	for _, session := range []*models.Session{
+		{ID: uuid.New(), UserID: user.ID, CreatedAt: longAgo, LastSeenAt: longAgo, ExpiresAt: longAgo},
+		{ID: uuid.New(), UserID: user.ID, CreatedAt: longAgo, LastSeenAt: longAgo, ExpiresAt: now.Add(time.Hour), RevokedAt: &longAgo},
+		{ID: uuid.New(), UserID: user.ID, CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(-time.Hour)},
+	} {
+		if err := ta.DB().Create(session).Error; err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+	}
+
+	purged, err := svc.PurgeBefore(context.Background(), now.Add(-7*24*time.Hour))
+	if err != nil || purged != 2 {
+		t.Fatalf("expected two purged sessions, got %d (err %v)", purged, err)
+	}
+
+	// The recently expired session and the login's remain
+	var remaining int64
+	ta.DB().Model(&models.Session{}).Where("user_id = ?", user.ID).Count(&remaining)
+	if remaining != 2 {
+		t.Errorf("expected two remaining sessions, got %d", remaining)
+	}
+}
+
+// sessionLimitsAuth returns an auth service whose sessions end after 30
+// minutes idle or 8 hours, with a minute of clock skew, all on fake
+func sessionLimitsAuth(t *testing.T, fake *clock.Fake) (*services.AuthService, *gorm.DB) {
+	t.Helper()
+	db, driver := databasetest.NewManager(t, databasetest.WithSQLite(&models.User{}, &models.LoginEvent{}, &models.Session{}))
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "session-limits-test-secret", Expiration: 24 * time.Hour, Issuer: "session-test"}}
+	store := cache.NewMemoryStore(cache.WithMemoryClock(fake))
+	revoker := services.NewTokenRevoker(store, time.Hour, services.WithRevokerClock(fake))
+	log := logger.NewNopLogger()
+	audit := services.NewAuditService(db, log)
+	sessions := services.NewSessionService(services.NewSessionRepository(db), revoker, store, audit, log,
+		services.WithSessionClock(fake), services.WithSessionLimits(30*time.Minute, 8*time.Hour, time.Minute))
+	auth := services.NewAuthService(db, cfg, store, revoker, audit, nil, log, services.WithAuthClock(fake), services.WithSessions(sessions))
+
+	if _, err := auth.Register(context.Background(), &services.CreateUserRequest{Email: "ann@example.com", Password: "secret123"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	return auth, driver.GormDB()
+}
+
+// useToken validates token and touches its session, as the auth middleware does
+func useToken(auth *services.AuthService, token string) error {
+	claims, err := auth.ValidateToken(context.Background(), token)
+	if err != nil {
+		return err
+	}
+	auth.TouchSession(context.Background(), claims)
+	return nil
+}
+
+// expectSessionExpired checks that the session of token was marked expired
+func expectSessionExpired(t *testing.T, db *gorm.DB, auth *services.AuthService, token string, at time.Time) {
+	t.Helper()
+	if err := useToken(auth, token); !stderrors.Is(err, services.ErrSessionExpired) {
+		t.Fatalf("expected ErrSessionExpired, got %v", err)
+	}
+	if _, err := auth.RefreshToken(context.Background(), token); !stderrors.Is(err, services.ErrSessionExpired) {
+		t.Errorf("expected the refresh to fail with ErrSessionExpired, got %v", err)
+	}
+	var session models.Session
+	if err := db.First(&session).Error; err != nil {
+		t.Fatal(err)
+	}
+	if session.Active(at) || session.RevokedAt != nil {
+		t.Errorf("expected the session to be marked expired at %v, got %+v", at, session)
+	}
+}
+
+// TestSessionIdleTimeout tests that a session ends once unused for longer
+// than the idle timeout and the skew, and that use keeps it alive
+func TestSessionIdleTimeout(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	auth, db := sessionLimitsAuth(t, fake)
+	result, err := auth.Login(context.Background(), "ann@example.com", "secret123", services.ClientInfo{})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// Each request starts a new idle window
+	for i := 0; i < 3; i++ {
+		fake.Advance(25 * time.Minute)
+		if err := useToken(auth, result.Token); err != nil {
+			t.Fatalf("request %d within the idle timeout: %v", i, err)
+		}
+	}
+
+	// Past the timeout but within the skew
+	fake.Advance(30*time.Minute + 30*time.Second)
+	if _, err := auth.ValidateToken(context.Background(), result.Token); err != nil {
+		t.Fatalf("expected the skew to be tolerated, got %v", err)
+	}
+
+	fake.Advance(time.Minute)
+	expectSessionExpired(t, db, auth, result.Token, fake.Now())
+}
+
+// TestSessionAbsoluteLifetime tests that a session in constant use ends
+// once it is older than the absolute lifetime and the skew, refreshed or not
+func TestSessionAbsoluteLifetime(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	auth, db := sessionLimitsAuth(t, fake)
+	result, err := auth.Login(context.Background(), "ann@example.com", "secret123", services.ClientInfo{})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	token := result.Token
+	for elapsed := time.Duration(0); elapsed < 8*time.Hour; elapsed += 20 * time.Minute {
+		fake.Advance(20 * time.Minute)
+		refreshed, err := auth.RefreshToken(context.Background(), token)
+		if err != nil {
+			t.Fatalf("refresh after %v: %v", elapsed+20*time.Minute, err)
+		}
+		token = refreshed.Token
+	}
+
+	// 8h within the skew, then past it
+	fake.Advance(30 * time.Second)
+	if err := useToken(auth, token); err != nil {
+		t.Fatalf("expected the skew to be tolerated, got %v", err)
+	}
+	fake.Advance(time.Minute)
+	expectSessionExpired(t, db, auth, token, fake.Now())
+}
+
+// TestSessionExpiredResponses tests that requests and refreshes of an idle
+// session answer SESSION_EXPIRED
+func TestSessionExpiredResponses(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.SessionIdleTimeout = 30 * time.Minute
+	})
+	user := ta.CreateUser(models.RoleUser)
+	idle := ta.CreateUser(models.RoleUser)
+
+	if resp := ta.Request(http.MethodGet, "/api/v1/me/features", nil, user.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected an active session to pass, got %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	longAgo := time.Now().Add(-time.Hour)
+	if err := ta.DB().Model(&models.Session{}).Where("user_id = ?", idle.ID).Update("last_seen_at", longAgo).Error; err != nil {
+		t.Fatal(err)
+	}
+	resp := ta.Request(http.MethodGet, "/api/v1/me/features", nil, idle.Token)
+	expectErrorCode(t, resp, http.StatusUnauthorized, errors.CodeSessionExpired)
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refresh_token": idle.Token}, "")
+	expectErrorCode(t, resp, http.StatusUnauthorized, errors.CodeSessionExpired)
+
+	if resp := ta.Request(http.MethodGet, "/api/v1/me/features", nil, user.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected other sessions to keep working, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// fakeSettingsRepository is an in-memory SettingsRepository
+type fakeSettingsRepository struct {
+	mu       sync.Mutex
+	settings map[string]models.Setting
+}
+
+func newFakeSettingsRepository() *fakeSettingsRepository {
+	return &fakeSettingsRepository{settings: make(map[string]models.Setting)}
+}
+
+func (r *fakeSettingsRepository) List(ctx context.Context) ([]*models.Setting, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	settings := make([]*models.Setting, 0, len(r.settings))
+	for _, setting := range r.settings {
+		copied := setting
+		settings = append(settings, &copied)
+	}
+	return settings, nil
+}
+
+func (r *fakeSettingsRepository) SaveAll(ctx context.Context, settings []*models.Setting) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, setting := range settings {
+		r.settings[setting.Key] = *setting
+	}
+	return nil
+}
+
+// fakeAuditRecorder collects audit entries in memory
+type fakeAuditRecorder struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (r *fakeAuditRecorder) Record(ctx context.Context, actorID, action, entityType, entityID string, metadata interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, action+":"+entityID)
+	return nil
+}
+
+func newSettingsFixture() (*services.SettingsService, *fakeSettingsRepository, *fakeAuditRecorder) {
+	repo := newFakeSettingsRepository()
+	audit := &fakeAuditRecorder{}
+	return services.NewSettingsService(repo, cache.NewMemoryStore(), audit, logger.NewSimpleLogger()), repo, audit
+}
+
+// TestSettingsDefaultsAndTypedAccess tests defaults and typed getters
+func TestSettingsDefaultsAndTypedAccess(t *testing.T) {
+	ctx := context.Background()
+	svc, _, audit := newSettingsFixture()
+
+	perPage, err := svc.GetInt(ctx, models.SettingItemsPerPage)
+	if err != nil || perPage != 20 {
+		t.Fatalf("expected default 20, got %d (err %v)", perPage, err)
+	}
+
+	if _, err := svc.GetBool(ctx, models.SettingItemsPerPage); !errors.Is(err, services.ErrSettingTypeMismatch) {
+		t.Fatalf("expected ErrSettingTypeMismatch, got %v", err)
+	}
+	if _, err := svc.GetString(ctx, "theme"); !errors.Is(err, services.ErrUnknownSetting) {
+		t.Fatalf("expected ErrUnknownSetting, got %v", err)
+	}
+
+	actor := uuid.New().String()
+	_, err = svc.UpdateSettings(ctx, actor, map[string]json.RawMessage{
+		models.SettingBannerMessage: json.RawMessage(`"Maintenance tonight"`),
+		models.SettingItemsPerPage:  json.RawMessage(`50`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	banner, err := svc.GetString(ctx, models.SettingBannerMessage)
+	if err != nil || banner != "Maintenance tonight" {
+		t.Fatalf("expected updated banner, got %q (err %v)", banner, err)
+	}
+	if perPage, _ := svc.GetInt(ctx, models.SettingItemsPerPage); perPage != 50 {
+		t.Fatalf("expected 50 after update, got %d", perPage)
+	}
+	if len(audit.entries) != 2 {
+		t.Fatalf("expected one audit entry per changed key, got %v", audit.entries)
+	}
+
+	// Writing the same value again is not a change
+	if _, err := svc.UpdateSettings(ctx, actor, map[string]json.RawMessage{models.SettingItemsPerPage: json.RawMessage(`50`)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(audit.entries) != 2 {
+		t.Fatalf("expected unchanged value not to be audited, got %v", audit.entries)
+	}
+}
+
+// TestSettingsSchemaValidation tests that unknown keys and type mismatches store nothing
+func TestSettingsSchemaValidation(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _ := newSettingsFixture()
+
+	cases := []struct {
+		name   string
+		values map[string]json.RawMessage
+		want   error
+	}{
+		{"unknown key", map[string]json.RawMessage{"theme": json.RawMessage(`"dark"`)}, services.ErrUnknownSetting},
+		{"string for int", map[string]json.RawMessage{models.SettingItemsPerPage: json.RawMessage(`"50"`)}, services.ErrSettingTypeMismatch},
+		{"fraction for int", map[string]json.RawMessage{models.SettingItemsPerPage: json.RawMessage(`2.5`)}, services.ErrSettingTypeMismatch},
+		{"null for string", map[string]json.RawMessage{models.SettingSupportEmail: json.RawMessage(`null`)}, services.ErrSettingTypeMismatch},
+		{"one bad key among good ones", map[string]json.RawMessage{
+			models.SettingBannerMessage: json.RawMessage(`"hello"`),
+			models.SettingItemsPerPage:  json.RawMessage(`true`),
+		}, services.ErrSettingTypeMismatch},
+	}
+
+	for _, tc := range cases {
+		if _, err := svc.UpdateSettings(ctx, "", tc.values); !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+
+	if len(repo.settings) != 0 {
+		t.Fatalf("expected rejected updates to store nothing, got %v", repo.settings)
+	}
+}
+
+// TestSettingsConcurrentReadsDuringWrite tests that readers see either the old or the new value
+func TestSettingsConcurrentReadsDuringWrite(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newSettingsFixture()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				v, err := svc.GetInt(ctx, models.SettingItemsPerPage)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if v != 20 && v != 100 {
+					errs <- errors.New("read a value that was never written")
+					return
+				}
+			}
+		}()
+	}
+
+	if _, err := svc.UpdateSettings(ctx, "", map[string]json.RawMessage{models.SettingItemsPerPage: json.RawMessage(`100`)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("concurrent read failed: %v", err)
+	}
+	if v, _ := svc.GetInt(ctx, models.SettingItemsPerPage); v != 100 {
+		t.Fatalf("expected write to be visible after it returns, got %d", v)
+	}
+}
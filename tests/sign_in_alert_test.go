@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+)
+
+// TestSignInNetwork tests the ranges logins are compared by
+func TestSignInNetwork(t *testing.T) {
+	for ip, want := range map[string]string{
+		"203.0.113.42":        "203.0.113.0/24",
+		"203.0.113.7":         "203.0.113.0/24",
+		"::ffff:198.51.100.9": "198.51.100.0/24",
+		"2001:db8:abcd:12::1": "2001:db8:abcd::/48",
+		"not an address":      "not an address",
+		"":                    "",
+	} {
+		if got := services.SignInNetwork(ip); got != want {
+			t.Errorf("%q: expected %q, got %q", ip, want, got)
+		}
+	}
+}
+
+// secureTokenPattern finds the token in the link of a new sign-in email
+var secureTokenPattern = regexp.MustCompile(`token=([0-9a-f]{64})`)
+
+// TestNewSignInAlerts tests that only logins from a new device are mailed
+// about, after the first, and that the emailed link signs the user out
+func TestNewSignInAlerts(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/admin/features", map[string]interface{}{
+		"key": services.SignInAlertsFlag, "enabled": true, "rollout_percentage": 100,
+	}, admin.Token)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create flag: %d %s", resp.StatusCode, resp.Body)
+	}
+
+	loginFrom := func(userAgent string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ta.Server.URL+"/api/v1/auth/login",
+			strings.NewReader(`{"email":"`+user.Email+`","password":"`+user.Password+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		res, err := ta.Server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("login: %v", err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("login: expected 200, got %d", res.StatusCode)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := ta.App.GetSignInAlerts().Stop(ctx); err != nil {
+			t.Fatalf("wait for the sign-in check: %v", err)
+		}
+	}
+
+	// The first login is the baseline, and the same device is known after it
+	loginFrom("Firefox/128.0")
+	loginFrom("Firefox/128.0")
+	if mail := ta.Mail.Messages(user.Email); len(mail) != 0 {
+		t.Fatalf("expected no email for the first and a repeated login, got %+v", mail)
+	}
+	var known int64
+	ta.DB().Model(&models.KnownSignIn{}).Where("user_id = ?", user.ID).Count(&known)
+	if known != 1 {
+		t.Errorf("expected one known sign-in, got %d", known)
+	}
+
+	loginFrom("curl/8.7.1")
+	mail := ta.Mail.Messages(user.Email)
+	if len(mail) != 1 || mail[0].Subject != "New sign-in to your account" || !strings.Contains(mail[0].Body, "curl/8.7.1") {
+		t.Fatalf("expected a new sign-in email naming the device, got %+v", mail)
+	}
+	match := secureTokenPattern.FindStringSubmatch(mail[0].Body)
+	if match == nil || !strings.Contains(mail[0].Body, "/secure-account?token=") {
+		t.Fatalf("expected a secure account link, got %q", mail[0].Body)
+	}
+
+	if resp := ta.Request(http.MethodPost, "/api/v1/auth/secure-account", map[string]string{"token": match[1]}, ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("secure account: %d %s", resp.StatusCode, resp.Body)
+	}
+	if resp := ta.Request(http.MethodGet, "/api/v1/me", nil, user.Token); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected earlier tokens revoked, got %d", resp.StatusCode)
+	}
+	resp = ta.Request(http.MethodPost, "/api/v1/auth/secure-account", map[string]string{"token": match[1]}, "")
+	expectErrorCode(t, resp, http.StatusBadRequest, errors.CodeSecureAccountTokenInvalid)
+}
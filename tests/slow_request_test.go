@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newSlowRouter serves handlers that sleep for the duration in the sleep query parameter
+func newSlowRouter(logs logger.Logger, cfg middleware.SlowRequestConfig, m *metrics.Metrics) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.SlowRequests(logs, cfg, m.SlowRequests))
+	sleep := func(c *gin.Context) {
+		d, _ := time.ParseDuration(c.Query("sleep"))
+		time.Sleep(d)
+		c.Status(http.StatusOK)
+	}
+	router.GET("/users/:id", sleep)
+	router.GET("/admin/users/:id/export", sleep)
+	return router
+}
+
+// TestSlowRequests tests the warn log and metric for requests over their threshold
+func TestSlowRequests(t *testing.T) {
+	logs := logger.NewCaptureLogger()
+	m := metrics.New()
+	router := newSlowRouter(logs, middleware.SlowRequestConfig{
+		Threshold: 20 * time.Millisecond,
+		Overrides: map[string]time.Duration{"/admin": time.Hour},
+	}, m)
+
+	get := func(path string, header ...string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	get("/users/1?sleep=0s")
+	get("/admin/users/1/export?sleep=30ms")
+	get("/missing")
+	if n := len(logs.FilterByLevel(logger.LevelWarn)); n != 0 {
+		t.Fatalf("expected no slow requests, got %d", n)
+	}
+
+	get("/users/1?sleep=30ms")
+	get("/users/2?sleep=30ms")
+
+	warns := logs.FilterByLevel(logger.LevelWarn)
+	if len(warns) != 2 {
+		t.Fatalf("expected 2 slow request logs, got %d", len(warns))
+	}
+	if slow, _ := warns[0].Field("slow"); slow != true {
+		t.Errorf("expected slow=true, got %v", slow)
+	}
+	if route, _ := warns[0].Field("route"); route != "/users/:id" {
+		t.Errorf("expected the route template, got %v", route)
+	}
+	if _, ok := warns[0].Field("queue_time"); ok {
+		t.Error("expected no queue time without a request start header")
+	}
+	if got := testutil.ToFloat64(m.SlowRequests.WithLabelValues("/users/:id")); got != 2 {
+		t.Errorf("expected the counter at 2, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.SlowRequests.WithLabelValues("/admin/users/:id/export")); got != 0 {
+		t.Errorf("expected the override to apply, got %v", got)
+	}
+
+	// Proxies report when they received the request
+	received := time.Now().Add(-50 * time.Millisecond)
+	get("/users/3?sleep=30ms", middleware.RequestStartHeader, fmt.Sprintf("t=%d", received.UnixMilli()))
+	warns = logs.FilterByLevel(logger.LevelWarn)
+	queue, ok := warns[len(warns)-1].Field("queue_time")
+	if !ok || queue.(time.Duration) < 40*time.Millisecond || queue.(time.Duration) > time.Second {
+		t.Errorf("expected a queue time of about 50ms, got %v", queue)
+	}
+}
+
+// TestSlowRequestsMetricsEndpoint tests that the counter is exported on /metrics
+func TestSlowRequestsMetricsEndpoint(t *testing.T) {
+	ta := apptest.NewTestApp(t, func(cfg *config.Config) {
+		cfg.Server.SlowRequestThreshold = time.Nanosecond
+	})
+
+	ta.Request(http.MethodGet, "/health", nil, "")
+	if !ta.Logs.Contains("Slow HTTP request") {
+		t.Error("expected the slow request to be logged")
+	}
+
+	resp := ta.Request(http.MethodGet, "/metrics", nil, "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(resp.Body), `http_slow_requests_total{route="/health"} 1`) {
+		t.Errorf("expected the slow request counter, got %d:\n%s", resp.StatusCode, resp.Body)
+	}
+}
+
+// TestSlowRequestOverridesConfig tests parsing SERVER_SLOW_REQUEST_OVERRIDES
+func TestSlowRequestOverridesConfig(t *testing.T) {
+	t.Setenv("SERVER_SLOW_REQUEST_OVERRIDES", "/api/v1/users/:id/export=30s, /api/v1/admin=5s")
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{"/api/v1/users/:id/export": 30 * time.Second, "/api/v1/admin": 5 * time.Second}
+	if len(cfg.Server.SlowRequestOverrides) != len(want) {
+		t.Fatalf("expected %v, got %v", want, cfg.Server.SlowRequestOverrides)
+	}
+	for route, d := range want {
+		if cfg.Server.SlowRequestOverrides[route] != d {
+			t.Errorf("%s: expected %v, got %v", route, d, cfg.Server.SlowRequestOverrides[route])
+		}
+	}
+	if cfg.Server.SlowRequestThreshold != time.Second {
+		t.Errorf("expected the 1s default threshold, got %v", cfg.Server.SlowRequestThreshold)
+	}
+
+	t.Setenv("SERVER_SLOW_REQUEST_OVERRIDES", "/api/v1/admin")
+	if _, err := config.LoadConfig(); err == nil {
+		t.Error("expected an error for an entry without a duration")
+	}
+}
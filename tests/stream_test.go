@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/controllers/stream"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/sse"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// staticTokenValidator accepts a fixed set of tokens
+type staticTokenValidator map[string]*services.TokenClaims
+
+func (v staticTokenValidator) ValidateToken(ctx context.Context, token string) (*services.TokenClaims, error) {
+	claims, ok := v[token]
+	if !ok {
+		return nil, services.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// newStreamServer serves the event stream on a real HTTP server whose
+// WriteTimeout is shorter than the test's streams
+func newStreamServer(t *testing.T, broker *sse.Broker, heartbeat time.Duration) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	validator := staticTokenValidator{
+		"alice-token": {UserID: "alice", Role: string(models.RoleUser)},
+	}
+	router := gin.New()
+	router.GET("/api/v1/events/stream", middleware.StreamAuth(validator), stream.NewStreamController(broker, heartbeat).Stream)
+
+	server := httptest.NewUnstartedServer(router)
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// openStream connects to the stream and returns a line reader
+func openStream(t *testing.T, ctx context.Context, url string) (*bufio.Reader, *http.Response) {
+	t.Helper()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return bufio.NewReader(resp.Body), resp
+}
+
+// readUntil reads lines until one contains want
+func readUntil(t *testing.T, r *bufio.Reader, want string) {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if strings.Contains(line, want) {
+			return
+		}
+		if err != nil {
+			t.Fatalf("stream ended before %q: %v", want, err)
+		}
+	}
+}
+
+// waitForClients polls until the broker has n open streams
+func waitForClients(t *testing.T, broker *sse.Broker, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for broker.ClientCount() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d stream clients, got %d", n, broker.ClientCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestStreamDeliversUserEvents tests query-token auth and per-user routing
+func TestStreamDeliversUserEvents(t *testing.T) {
+	broker := sse.NewBroker(8, logger.NewSimpleLogger())
+	server := newStreamServer(t, broker, time.Hour)
+
+	reader, resp := openStream(t, context.Background(), server.URL+"/api/v1/events/stream?access_token=alice-token")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	readUntil(t, reader, ": connected")
+	waitForClients(t, broker, 1)
+
+	broker.Publish("bob", "notification", map[string]string{"title": "not for alice"})
+	broker.Publish("alice", "notification", map[string]string{"title": "Import finished"})
+
+	readUntil(t, reader, "event: notification")
+	line, _ := reader.ReadString('\n')
+	if line != "data: {\"title\":\"Import finished\"}\n" {
+		t.Fatalf("expected alice's notification, got %q", line)
+	}
+}
+
+// TestStreamRequiresToken tests that streams need a valid token
+func TestStreamRequiresToken(t *testing.T) {
+	broker := sse.NewBroker(8, logger.NewSimpleLogger())
+	server := newStreamServer(t, broker, time.Hour)
+
+	for _, url := range []string{
+		server.URL + "/api/v1/events/stream",
+		server.URL + "/api/v1/events/stream?access_token=forged",
+	} {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for %s, got %d", url, resp.StatusCode)
+		}
+	}
+}
+
+// TestStreamRequiresClaims tests that the controller refuses requests that
+// reach it without an authenticated caller, with the API's error body
+func TestStreamRequiresClaims(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	broker := sse.NewBroker(8, logger.NewSimpleLogger())
+	router := gin.New()
+	router.GET("/api/v1/events/stream", stream.NewStreamController(broker, time.Hour).Stream)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/events/stream", nil))
+
+	resp := &apptest.Response{StatusCode: w.Code, Header: w.Header(), Body: w.Body.Bytes()}
+	expectErrorCode(t, resp, http.StatusUnauthorized, errors.CodeAuthenticationRequired)
+}
+
+// TestStreamOutlivesWriteTimeout tests heartbeats on a stream older than the server's WriteTimeout
+func TestStreamOutlivesWriteTimeout(t *testing.T) {
+	broker := sse.NewBroker(8, logger.NewSimpleLogger())
+	server := newStreamServer(t, broker, 20*time.Millisecond)
+
+	reader, _ := openStream(t, context.Background(), server.URL+"/api/v1/events/stream?access_token=alice-token")
+	time.Sleep(250 * time.Millisecond)
+
+	readUntil(t, reader, ": heartbeat")
+	broker.Publish("alice", "job.status", map[string]string{"name": "audit_cleanup"})
+	readUntil(t, reader, "event: job.status")
+}
+
+// TestStreamCleanup tests that disconnects and shutdown release stream clients
+func TestStreamCleanup(t *testing.T) {
+	broker := sse.NewBroker(8, logger.NewSimpleLogger())
+	server := newStreamServer(t, broker, time.Hour)
+	url := server.URL + "/api/v1/events/stream?access_token=alice-token"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, _ := openStream(t, ctx, url)
+	readUntil(t, reader, ": connected")
+	waitForClients(t, broker, 1)
+
+	cancel()
+	waitForClients(t, broker, 0)
+
+	reader, _ = openStream(t, context.Background(), url)
+	readUntil(t, reader, ": connected")
+	waitForClients(t, broker, 1)
+
+	// Shutdown ends the response; reading drains to EOF
+	broker.Close()
+	for {
+		if _, err := reader.ReadString('\n'); err != nil {
+			break
+		}
+	}
+	waitForClients(t, broker, 0)
+
+	if _, open := <-broker.Subscribe("alice", "").Messages(); open {
+		t.Fatal("expected subscriptions after Close to be closed")
+	}
+}
+
+// TestBrokerEvictsSlowClients tests that a full buffer drops only the slow client
+func TestBrokerEvictsSlowClients(t *testing.T) {
+	broker := sse.NewBroker(2, logger.NewSimpleLogger())
+	slow := broker.Subscribe("alice", "")
+	fast := broker.Subscribe("alice", "")
+
+	for i := 0; i < 3; i++ {
+		broker.Publish("alice", "notification", i)
+		<-fast.Messages()
+	}
+
+	if broker.ClientCount() != 1 {
+		t.Fatalf("expected the slow client to be evicted, got %d clients", broker.ClientCount())
+	}
+
+	received := 0
+	for range slow.Messages() {
+		received++
+	}
+	if received != 2 {
+		t.Fatalf("expected the slow client to keep its buffered messages, got %d", received)
+	}
+
+	broker.Unsubscribe(slow)
+	broker.Unsubscribe(fast)
+	broker.Unsubscribe(fast)
+	if broker.ClientCount() != 0 {
+		t.Fatalf("expected no clients, got %d", broker.ClientCount())
+	}
+}
@@ -0,0 +1,321 @@
+package tests
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/infrastructure/storage"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/filetoken"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// progressRecorder records every progress update a task saves
+type progressRecorder struct {
+	services.TaskRepository
+
+	mu       sync.Mutex
+	progress []int
+}
+
+func (r *progressRecorder) Update(ctx context.Context, id uuid.UUID, fields map[string]interface{}) (bool, error) {
+	if percent, ok := fields["progress"].(int); ok && fields["status"] == nil {
+		r.mu.Lock()
+		r.progress = append(r.progress, percent)
+		r.mu.Unlock()
+	}
+	return r.TaskRepository.Update(ctx, id, fields)
+}
+
+func (r *progressRecorder) updates() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.progress...)
+}
+
+type taskFixture struct {
+	tasks    *services.TaskService
+	users    *services.UserService
+	store    *storage.LocalStore
+	links    *filetoken.Signer
+	root     string
+	recorder *progressRecorder
+	db       *gorm.DB
+}
+
+// newTaskFixture returns a task service on SQLite with a local store
+func newTaskFixture(t *testing.T) *taskFixture {
+	t.Helper()
+
+	manager, driver := databasetest.NewManager(t, databasetest.WithSQLite(
+		&models.User{}, &models.Task{}, &models.Notification{},
+	))
+	root := t.TempDir()
+	store, err := storage.NewLocalStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links, err := filetoken.NewSigner("/files", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := logger.NewNopLogger()
+	recorder := &progressRecorder{TaskRepository: services.NewTaskRepository(manager)}
+	notifications := services.NewNotificationService(services.NewNotificationRepository(manager), nil, log)
+	tasks := services.NewTaskService(recorder, store, links, notifications, 1, time.Minute, log)
+	t.Cleanup(func() { _ = tasks.Stop(context.Background()) })
+
+	return &taskFixture{
+		tasks:    tasks,
+		users:    newTestUserService(manager),
+		store:    store,
+		links:    links,
+		root:     root,
+		recorder: recorder,
+		db:       driver.GormDB(),
+	}
+}
+
+// waitForTask polls the task until it has finished
+func waitForTask(t *testing.T, tasks *services.TaskService, id uuid.UUID) *models.Task {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		task, err := tasks.Get(context.Background(), id.String())
+		if err != nil {
+			t.Fatalf("get task: %v", err)
+		}
+		if task.Status.Finished() {
+			return task
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task still %s after 5s", task.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestUserExportTaskProgress tests an export read in several batches
+func TestUserExportTaskProgress(t *testing.T) {
+	f := newTaskFixture(t)
+	owner := uuid.New()
+	for i := 0; i < 25; i++ {
+		user := &models.User{ID: uuid.New(), Email: uuid.NewString() + "@example.com", Role: models.RoleUser, Active: true}
+		if err := f.db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	deleted := time.Now()
+	if err := f.db.Create(&models.User{ID: uuid.New(), Email: "gone@example.com", Role: models.RoleUser, DeletedAt: &deleted}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := f.tasks.Submit(context.Background(), owner.String(), models.TaskTypeUserExport, services.UserExportTask(f.users, f.store, services.UserExportOptions{}, 10))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if task.Status != models.TaskStatusPending {
+		t.Errorf("expected a pending task, got %s", task.Status)
+	}
+
+	done := waitForTask(t, f.tasks, task.ID)
+	if done.Status != models.TaskStatusSucceeded || done.Progress != 100 || done.FinishedAt == nil {
+		t.Fatalf("expected a finished export, got %+v", done)
+	}
+	if got, want := f.recorder.updates(), []int{40, 80, 100}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("expected progress %v, got %v", want, got)
+	}
+	// Without a caller the link is the owner's
+	claims, err := f.links.Verify(strings.TrimPrefix(done.ResultURL, "/files/"))
+	if err != nil {
+		t.Fatalf("unexpected result link %q: %v", done.ResultURL, err)
+	}
+	if claims.Key != "exports/users-"+task.ID.String()+".csv" || claims.UserID != owner.String() {
+		t.Errorf("unexpected result link claims %+v", claims)
+	}
+
+	file, err := f.store.Open(context.Background(), done.ResultKey)
+	if err != nil {
+		t.Fatalf("open result: %v", err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 26 || rows[0][1] != "email" {
+		t.Errorf("expected a header and 25 users, got %d rows", len(rows))
+	}
+
+	if n := countRows(t, f.db, &models.Notification{}, "user_id = ? AND type = ?", owner, models.NotificationTypeTaskFinished); n != 1 {
+		t.Errorf("expected the owner to be notified once, got %d", n)
+	}
+}
+
+// TestTaskCancelMidRun tests that cancelling reaches a running task
+func TestTaskCancelMidRun(t *testing.T) {
+	f := newTaskFixture(t)
+
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	task, err := f.tasks.Submit(context.Background(), uuid.NewString(), "test.block", func(ctx context.Context, run *services.TaskRun) (string, error) {
+		run.Progress(50)
+		close(started)
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return "", ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+
+	<-started
+	running, err := f.tasks.Get(context.Background(), task.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if running.Status != models.TaskStatusRunning || running.Progress != 50 {
+		t.Fatalf("expected a running task at 50%%, got %s at %d%%", running.Status, running.Progress)
+	}
+
+	if _, err := f.tasks.Cancel(context.Background(), task.ID.String()); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	select {
+	case err := <-stopped:
+		if err != context.Canceled {
+			t.Errorf("expected the task context to be cancelled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not see the cancellation")
+	}
+
+	done := waitForTask(t, f.tasks, task.ID)
+	if done.Status != models.TaskStatusCancelled || done.Error != "" {
+		t.Errorf("expected a cancelled task, got %s %q", done.Status, done.Error)
+	}
+	if _, err := f.tasks.Cancel(context.Background(), task.ID.String()); err != services.ErrTaskFinished {
+		t.Errorf("expected ErrTaskFinished, got %v", err)
+	}
+}
+
+// TestTaskCleanupJob tests that old tasks are purged with their results
+func TestTaskCleanupJob(t *testing.T) {
+	f := newTaskFixture(t)
+
+	task, err := f.tasks.Submit(context.Background(), uuid.NewString(), "test.file", func(ctx context.Context, run *services.TaskRun) (string, error) {
+		return "results/old.txt", f.store.Put(ctx, "results/old.txt", strings.NewReader("done"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForTask(t, f.tasks, task.ID)
+
+	recent := &models.Task{ID: uuid.New(), Type: "test.file", OwnerID: uuid.New(), Status: models.TaskStatusSucceeded, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := f.db.Create(recent).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := f.db.Model(&models.Task{}).Where("id = ?", task.ID).Update("updated_at", time.Now().Add(-48*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	job := services.NewTaskCleanupJob(f.tasks, "0 4 * * *", 24*time.Hour, logger.NewNopLogger())
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if n := countRows(t, f.db, &models.Task{}, "id = ?", task.ID); n != 0 {
+		t.Error("expected the old task to be purged")
+	}
+	if n := countRows(t, f.db, &models.Task{}, "id = ?", recent.ID); n != 1 {
+		t.Error("expected the recent task to be kept")
+	}
+	if _, err := os.Stat(filepath.Join(f.root, "results", "old.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected the result file to be deleted, got %v", err)
+	}
+}
+
+// TestUserExportAPI tests the sync and async exports, ownership and download links
+func TestUserExportAPI(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	otherAdmin := ta.CreateUser(models.RoleAdmin)
+	user := ta.CreateUser(models.RoleUser)
+
+	sync := ta.Request(http.MethodPost, "/api/v1/users/export", nil, admin.Token)
+	if sync.StatusCode != http.StatusOK || !strings.HasPrefix(sync.Header.Get("Content-Type"), "text/csv") {
+		t.Fatalf("expected a CSV, got %d %s", sync.StatusCode, sync.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(sync.Body), user.Email) {
+		t.Errorf("expected the export to list %s", user.Email)
+	}
+	if resp := ta.Request(http.MethodPost, "/api/v1/users/export", nil, user.Token); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected users without users.manage to be refused, got %d", resp.StatusCode)
+	}
+
+	resp := ta.Request(http.MethodPost, "/api/v1/users/export?async=true", nil, admin.Token)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", resp.StatusCode, resp.Body)
+	}
+	var submitted struct {
+		Data models.Task `json:"data"`
+	}
+	resp.Decode(t, &submitted)
+	path := "/api/v1/tasks/" + submitted.Data.ID.String()
+	if resp.Header.Get("Location") != path {
+		t.Errorf("expected Location %s, got %s", path, resp.Header.Get("Location"))
+	}
+
+	var polled struct {
+		Data models.Task `json:"data"`
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !polled.Data.Status.Finished() {
+		if time.Now().After(deadline) {
+			t.Fatalf("export still %s after 5s", polled.Data.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+		ta.Request(http.MethodGet, path, nil, admin.Token).Decode(t, &polled)
+	}
+	if polled.Data.Status != models.TaskStatusSucceeded || polled.Data.ResultURL == "" {
+		t.Fatalf("expected a result link, got %+v", polled.Data)
+	}
+
+	// Only the owner and admins see the task
+	expectErrorCode(t, ta.Request(http.MethodGet, path, nil, user.Token), http.StatusForbidden, errors.CodeInsufficientPermissions)
+	if resp := ta.Request(http.MethodGet, path, nil, otherAdmin.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected admins to see the task, got %d", resp.StatusCode)
+	}
+	expectErrorCode(t, ta.Request(http.MethodGet, "/api/v1/tasks/"+uuid.NewString(), nil, admin.Token), http.StatusNotFound, errors.CodeTaskNotFound)
+	expectErrorCode(t, ta.Request(http.MethodDelete, path, nil, admin.Token), http.StatusConflict, errors.CodeTaskFinished)
+
+	// The link is the admin's who polled the task
+	download := ta.Request(http.MethodGet, polled.Data.ResultURL, nil, admin.Token)
+	if download.StatusCode != http.StatusOK {
+		t.Fatalf("download: expected 200, got %d: %s", download.StatusCode, download.Body)
+	}
+	if body := string(download.Body); !strings.Contains(body, admin.Email) || !strings.Contains(body, user.Email) {
+		t.Errorf("expected the download to list the users, got %s", body)
+	}
+	if got := download.Header.Get("Content-Disposition"); got != `attachment; filename=users-`+polled.Data.ID.String()+`.csv` {
+		t.Errorf("unexpected Content-Disposition %q", got)
+	}
+	expectErrorCode(t, ta.Request(http.MethodGet, polled.Data.ResultURL, nil, otherAdmin.Token), http.StatusForbidden, errors.CodeDownloadLinkForbidden)
+	expectErrorCode(t, ta.Request(http.MethodGet, polled.Data.ResultURL, nil, ""), http.StatusUnauthorized, errors.CodeTokenRequired)
+}
@@ -0,0 +1,269 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+// newTenantApp serves an application with tenants acme and globex, each on
+// its own in-memory SQLite database
+func newTenantApp(t *testing.T) *apptest.TestApp {
+	return apptest.NewTestApp(t, func(cfg *config.Config) {
+		tenantDB := config.DatabaseConnectionConfig{Driver: string(database.DriverSQLite), DBName: ":memory:", UseGorm: true, Required: true}
+		cfg.Database.Databases = map[string]config.DatabaseConnectionConfig{
+			"acme_db":   tenantDB,
+			"globex_db": tenantDB,
+		}
+		cfg.Database.Tenants = map[string]string{
+			"acme":   "acme_db",
+			"globex": "globex_db",
+		}
+	})
+}
+
+// tenantRequest sends a request for tenant; an empty tenant sends no header
+func tenantRequest(t *testing.T, ta *apptest.TestApp, tenant, method, path string, body interface{}, token string) (int, []byte) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, ta.Server.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if tenant != "" {
+		req.Header.Set(middleware.TenantHeader, tenant)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := ta.Server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, data
+}
+
+// seedTenantAdmin stores an admin in the tenant's database and logs it in there
+func seedTenantAdmin(t *testing.T, ta *apptest.TestApp, tenant, email string) (*models.User, string) {
+	t.Helper()
+
+	name, ok := ta.App.GetDBManager().TenantDatabase(tenant)
+	if !ok {
+		t.Fatalf("tenant %s not registered", tenant)
+	}
+	driver, err := ta.App.GetDBManager().GetDriver(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.OpenGorm(driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, _ := utils.HashPassword("password123")
+	user := &models.User{ID: uuid.New(), Email: email, Username: tenant + "-admin", Password: hash, Role: models.RoleAdmin, Active: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("seed %s: %v", tenant, err)
+	}
+
+	status, body := tenantRequest(t, ta, tenant, http.MethodPost, "/api/v1/auth/login", map[string]string{"email": email, "password": "password123"}, "")
+	if status != http.StatusOK {
+		t.Fatalf("login to %s: %d %s", tenant, status, body)
+	}
+	var login struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(body, &login)
+	return user, login.Token
+}
+
+// TestTenantIsolation tests that two tenants on separate databases never see each other's data
+func TestTenantIsolation(t *testing.T) {
+	ta := newTenantApp(t)
+
+	// The same email can exist once per tenant
+	acmeAdmin, acmeToken := seedTenantAdmin(t, ta, "acme", "admin@example.com")
+	globexAdmin, globexToken := seedTenantAdmin(t, ta, "globex", "admin@example.com")
+
+	status, body := tenantRequest(t, ta, "acme", http.MethodPost, "/api/v1/users", map[string]string{"email": "wile@acme.test", "password": "password123"}, acmeToken)
+	if status != http.StatusCreated {
+		t.Fatalf("create acme user: %d %s", status, body)
+	}
+
+	t.Run("lists only own users", func(t *testing.T) {
+		for tenant, want := range map[string]int{"acme": 2, "globex": 1} {
+			token := acmeToken
+			if tenant == "globex" {
+				token = globexToken
+			}
+			status, body := tenantRequest(t, ta, tenant, http.MethodGet, "/api/v1/users", nil, token)
+			if status != http.StatusOK {
+				t.Fatalf("%s: %d %s", tenant, status, body)
+			}
+			var list struct {
+				Data []models.User `json:"data"`
+			}
+			json.Unmarshal(body, &list)
+			if len(list.Data) != want {
+				t.Errorf("%s: expected %d users, got %d", tenant, want, len(list.Data))
+			}
+		}
+	})
+
+	t.Run("other tenant's IDs are not found", func(t *testing.T) {
+		status, _ := tenantRequest(t, ta, "globex", http.MethodGet, "/api/v1/users/"+acmeAdmin.ID.String(), nil, globexToken)
+		if status != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", status)
+		}
+		status, _ = tenantRequest(t, ta, "acme", http.MethodGet, "/api/v1/users/"+acmeAdmin.ID.String(), nil, acmeToken)
+		if status != http.StatusOK {
+			t.Errorf("expected 200 for own user, got %d", status)
+		}
+	})
+
+	t.Run("tokens are bound to their tenant", func(t *testing.T) {
+		primaryAdmin := ta.CreateUser(models.RoleAdmin)
+		cases := []struct {
+			name   string
+			tenant string
+			token  string
+			want   int
+		}{
+			{"acme token for globex", "globex", acmeToken, http.StatusUnauthorized},
+			{"primary token for acme", "acme", primaryAdmin.Token, http.StatusUnauthorized},
+			{"tenant from the token claim", "", globexToken, http.StatusOK},
+			// The globex user does not exist in the primary database
+			{"primary token without tenant", "", primaryAdmin.Token, http.StatusNotFound},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				status, body := tenantRequest(t, ta, tc.tenant, http.MethodGet, "/api/v1/users/"+globexAdmin.ID.String(), nil, tc.token)
+				if status != tc.want {
+					t.Errorf("expected %d, got %d %s", tc.want, status, body)
+				}
+			})
+		}
+	})
+
+	t.Run("unknown tenant", func(t *testing.T) {
+		status, _ := tenantRequest(t, ta, "initech", http.MethodPost, "/api/v1/auth/login", map[string]string{"email": "admin@example.com", "password": "password123"}, "")
+		if status != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", status)
+		}
+	})
+}
+
+// TestTenantRegistration tests connecting a tenant's database at runtime
+func TestTenantRegistration(t *testing.T) {
+	ta := newTenantApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	_, acmeToken := seedTenantAdmin(t, ta, "acme", "admin@example.com")
+
+	create := func(body map[string]interface{}) int {
+		status, _ := tenantRequest(t, ta, "", http.MethodPost, "/api/v1/admin/tenants", body, admin.Token)
+		return status
+	}
+	sqlite := map[string]string{"driver": string(database.DriverSQLite), "dbname": ":memory:"}
+
+	if status := create(map[string]interface{}{"id": "initech", "connection": sqlite}); status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", status)
+	}
+
+	// The new database is migrated and isolated from the others
+	if _, token := seedTenantAdmin(t, ta, "initech", "admin@example.com"); token == "" {
+		t.Fatal("no token for the new tenant")
+	}
+	if name, _ := ta.App.GetDBManager().TenantDatabase("initech"); name != "tenant_initech" {
+		t.Errorf("expected database tenant_initech, got %q", name)
+	}
+
+	cases := []struct {
+		name string
+		body map[string]interface{}
+		want int
+	}{
+		{"duplicate", map[string]interface{}{"id": "initech", "connection": sqlite}, http.StatusConflict},
+		{"shared database", map[string]interface{}{"id": "acme-eu", "database": "acme_db"}, http.StatusCreated},
+		{"unknown database", map[string]interface{}{"id": "hooli", "database": "hooli_db"}, http.StatusBadRequest},
+		{"invalid id", map[string]interface{}{"id": "Bad ID", "database": "acme_db"}, http.StatusBadRequest},
+		{"both targets", map[string]interface{}{"id": "hooli", "database": "acme_db", "connection": sqlite}, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if status := create(tc.body); status != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, status)
+			}
+		})
+	}
+
+	// Tenant admins cannot manage tenants
+	status, _ := tenantRequest(t, ta, "acme", http.MethodGet, "/api/v1/admin/tenants", nil, acmeToken)
+	if status != http.StatusForbidden {
+		t.Errorf("expected 403 for a tenant admin, got %d", status)
+	}
+
+	status, body := tenantRequest(t, ta, "", http.MethodGet, "/api/v1/admin/tenants", nil, admin.Token)
+	var list struct {
+		Data []struct {
+			ID       string `json:"id"`
+			Database string `json:"database"`
+		} `json:"data"`
+	}
+	json.Unmarshal(body, &list)
+	if status != http.StatusOK || len(list.Data) != 4 {
+		t.Errorf("expected 4 tenants, got %d %s", status, body)
+	}
+}
+
+// TestManagerTenants tests routing contexts to tenant databases
+func TestManagerTenants(t *testing.T) {
+	db, primary := databasetest.NewManager(t)
+	acme := databasetest.NewMockDriver(t)
+	if err := db.AddDriver("acme_db", acme); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.RegisterTenant("acme", "missing_db"); !errors.Is(err, database.ErrDriverNotFound) {
+		t.Errorf("expected ErrDriverNotFound, got %v", err)
+	}
+	if err := db.RegisterTenant("acme", "acme_db"); err != nil {
+		t.Fatal(err)
+	}
+
+	if driver, _ := db.DriverFor(context.Background()); driver != primary {
+		t.Error("expected the primary driver without a tenant")
+	}
+	ctx := database.WithTenant(context.Background(), "acme", "acme_db")
+	if driver, _ := db.DriverFor(ctx); driver != acme {
+		t.Error("expected the tenant's driver")
+	}
+	if got := database.TenantID(ctx); got != "acme" {
+		t.Errorf("expected tenant acme, got %q", got)
+	}
+}
@@ -0,0 +1,173 @@
+//go:build ignore
+
+// gen writes the tiny MaxMind databases the GeoIP tests read:
+//
+//	go run gen.go
+//
+// city-v1.mmdb and city-v2.mmdb are IPv4 City databases that differ only
+// in 203.0.113.0/24, so the tests can tell which one was loaded.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"math"
+	"net"
+	"os"
+	"sort"
+)
+
+type network struct {
+	cidr   string
+	record map[string]any
+}
+
+func city(name, country string, lat, lon float64) map[string]any {
+	return map[string]any{
+		"city":     map[string]any{"names": map[string]any{"en": name}},
+		"country":  map[string]any{"iso_code": country},
+		"location": map[string]any{"latitude": lat, "longitude": lon},
+	}
+}
+
+func main() {
+	shared := []network{
+		{"198.51.100.0/24", city("New York", "US", 40.7128, -74.006)},
+		{"192.0.2.0/24", map[string]any{"country": map[string]any{"iso_code": "FR"}}},
+	}
+	write("city-v1.mmdb", append([]network{{"203.0.113.0/24", city("Berlin", "DE", 52.52, 13.405)}}, shared...))
+	write("city-v2.mmdb", append([]network{{"203.0.113.0/24", city("Hamburg", "DE", 53.5511, 9.9937)}}, shared...))
+}
+
+// node is a node of the search tree; each side leads to another node, to
+// data at an offset or, when neither, to nothing
+type node struct {
+	kids [2]*node
+	data [2]int
+}
+
+func write(path string, networks []network) {
+	root := &node{data: [2]int{-1, -1}}
+	var data bytes.Buffer
+	for _, n := range networks {
+		_, ipnet, err := net.ParseCIDR(n.cidr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		offset := data.Len()
+		encode(&data, n.record)
+
+		ip := ipnet.IP.To4()
+		bits, _ := ipnet.Mask.Size()
+		cur := root
+		for i := 0; i < bits; i++ {
+			bit := ip[i/8] >> (7 - i%8) & 1
+			if i == bits-1 {
+				cur.data[bit] = offset
+				break
+			}
+			if cur.kids[bit] == nil {
+				cur.kids[bit] = &node{data: [2]int{-1, -1}}
+			}
+			cur = cur.kids[bit]
+		}
+	}
+
+	var nodes []*node
+	index := map[*node]int{}
+	var walk func(n *node)
+	walk = func(n *node) {
+		index[n] = len(nodes)
+		nodes = append(nodes, n)
+		for _, kid := range n.kids {
+			if kid != nil {
+				walk(kid)
+			}
+		}
+	}
+	walk(root)
+
+	count := len(nodes)
+	var out bytes.Buffer
+	for _, n := range nodes {
+		for side := 0; side < 2; side++ {
+			record := count
+			switch {
+			case n.kids[side] != nil:
+				record = index[n.kids[side]]
+			case n.data[side] >= 0:
+				record = count + 16 + n.data[side]
+			}
+			out.Write([]byte{byte(record >> 16), byte(record >> 8), byte(record)})
+		}
+	}
+	out.Write(make([]byte, 16))
+	out.Write(data.Bytes())
+	out.WriteString("\xAB\xCD\xEFMaxMind.com")
+	encode(&out, map[string]any{
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(24),
+		"ip_version":                  uint16(4),
+		"database_type":               "GeoLite2-City",
+		"languages":                   []any{"en"},
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1700000000),
+		"description":                 map[string]any{"en": "GeoIP test database"},
+	})
+
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// control writes the control byte of a value of type typ and size
+func control(buf *bytes.Buffer, typ, size int) {
+	if size >= 29 {
+		log.Fatalf("size %d too large", size)
+	}
+	if typ > 7 {
+		buf.Write([]byte{byte(size), byte(typ - 7)})
+		return
+	}
+	buf.WriteByte(byte(typ<<5 | size))
+}
+
+func encode(buf *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case string:
+		control(buf, 2, len(v))
+		buf.WriteString(v)
+	case float64:
+		control(buf, 3, 8)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case uint16:
+		control(buf, 5, 2)
+		binary.Write(buf, binary.BigEndian, v)
+	case uint32:
+		control(buf, 6, 4)
+		binary.Write(buf, binary.BigEndian, v)
+	case uint64:
+		control(buf, 9, 8)
+		binary.Write(buf, binary.BigEndian, v)
+	case map[string]any:
+		control(buf, 7, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			encode(buf, key)
+			encode(buf, v[key])
+		}
+	case []any:
+		control(buf, 11, len(v))
+		for _, item := range v {
+			encode(buf, item)
+		}
+	default:
+		log.Fatalf("cannot encode %T", v)
+	}
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// TestSelfDeactivationRefused tests that an admin cannot deactivate their own account
+func TestSelfDeactivationRefused(t *testing.T) {
+	admin := &models.User{ID: uuid.New(), Role: models.RoleAdmin, Active: true}
+
+	err := services.CheckActiveChange(admin.ID.String(), admin, false, 5)
+	if !stderrors.Is(err, services.ErrSelfDeactivation) {
+		t.Fatalf("expected ErrSelfDeactivation, got %v", err)
+	}
+}
+
+// TestLastActiveAdminDeactivationRefused tests that the last active admin is protected
+func TestLastActiveAdminDeactivationRefused(t *testing.T) {
+	actorID := uuid.New().String()
+	admin := &models.User{ID: uuid.New(), Role: models.RoleAdmin, Active: true}
+
+	if err := services.CheckActiveChange(actorID, admin, false, 1); !stderrors.Is(err, services.ErrLastActiveAdmin) {
+		t.Fatalf("expected ErrLastActiveAdmin, got %v", err)
+	}
+	if err := services.CheckActiveChange(actorID, admin, false, 2); err != nil {
+		t.Fatalf("expected deactivation to be allowed with another admin, got %v", err)
+	}
+
+	regular := &models.User{ID: uuid.New(), Role: models.RoleUser, Active: true}
+	if err := services.CheckActiveChange(actorID, regular, false, 0); err != nil {
+		t.Fatalf("expected regular user deactivation to be allowed, got %v", err)
+	}
+}
+
+// TestActiveGuardsThroughAPI tests that the guards hold on every endpoint
+// that can deactivate a user, so the only admin cannot be locked out
+func TestActiveGuardsThroughAPI(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	operator := ta.CreateUser(models.RoleUser)
+	path := "/api/v1/users/" + admin.ID.String()
+
+	// The active flag only changes through activate and deactivate
+	resp := ta.Request(http.MethodPut, path, map[string]bool{"active": false}, admin.Token)
+	expectErrorCode(t, resp, http.StatusBadRequest, errors.CodeUnknownFields)
+
+	resp = ta.Request(http.MethodPost, path+"/deactivate", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodeSelfDeactivation)
+
+	grant := map[string][]string{"permissions": {models.PermissionUsersManage}}
+	if resp := ta.Request(http.MethodPut, "/api/v1/roles/user/permissions", grant, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("grant users.manage: %d %s", resp.StatusCode, resp.Body)
+	}
+	resp = ta.Request(http.MethodPost, path+"/deactivate", nil, operator.Token)
+	expectErrorCode(t, resp, http.StatusConflict, errors.CodeLastActiveAdmin)
+
+	if resp := ta.Request(http.MethodGet, "/api/v1/me", nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the admin still active, got %d", resp.StatusCode)
+	}
+}
+
+// TestTokenRevokerRevokesEarlierTokens tests that revocation only covers tokens issued before it
+func TestTokenRevokerRevokesEarlierTokens(t *testing.T) {
+	ctx := context.Background()
+	revoker := services.NewTokenRevoker(cache.NewMemoryStore(), time.Hour)
+	userID := uuid.New().String()
+
+	issued := time.Now().Add(-time.Minute)
+	if revoker.IsRevoked(ctx, userID, issued) {
+		t.Fatal("token should not be revoked before RevokeUserTokens")
+	}
+
+	if err := revoker.RevokeUserTokens(ctx, userID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !revoker.IsRevoked(ctx, userID, issued) {
+		t.Fatal("token issued before revocation should be revoked")
+	}
+	if revoker.IsRevoked(ctx, userID, time.Now().Add(2*time.Second)) {
+		t.Fatal("token issued after revocation should remain valid")
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"BackofficeGoService/internal/app/controllers/auth"
+	"BackofficeGoService/internal/app/controllers/user"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/pkg/service/servicetest"
+	"BackofficeGoService/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newUserRouter registers the user and auth routes on fake services
+func newUserRouter(users *servicetest.UserService, tokens *servicetest.AuthService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	authController := auth.NewAuthController(tokens)
+	router.POST("/api/v1/auth/login", authController.Login)
+
+	uc := user.NewUserController(users, pagination.DefaultConfig)
+	group := router.Group("/api/v1/users", middleware.Auth(tokens))
+	group.GET("", uc.ListUsers)
+	group.GET("/:id", uc.GetUser)
+	group.POST("", uc.CreateUser)
+	group.POST("/:id/deactivate", uc.DeactivateUser)
+	return router
+}
+
+func serveJSON(router *gin.Engine, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestUserControllerWithFakes tests the user endpoints against servicetest fakes
+func TestUserControllerWithFakes(t *testing.T) {
+	users := servicetest.NewUserService()
+	admin := users.Add(&models.User{Email: "admin@example.com", Role: models.RoleAdmin, Active: true})
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		users.Add(&models.User{Email: email, Role: models.RoleUser, Active: true})
+	}
+	tokens := servicetest.NewAuthService(users)
+	token := tokens.IssueToken(&services.TokenClaims{UserID: admin.ID.String(), Role: string(admin.Role)})
+	router := newUserRouter(users, tokens)
+
+	t.Run("list reports the total", func(t *testing.T) {
+		w := serveJSON(router, http.MethodGet, "/api/v1/users?limit=2", token, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var body struct {
+			Data []map[string]interface{} `json:"data"`
+			Meta struct {
+				Total int `json:"total"`
+			} `json:"meta"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if len(body.Data) != 2 || body.Meta.Total != 4 {
+			t.Errorf("expected a page of 2 out of 4, got %d of %d", len(body.Data), body.Meta.Total)
+		}
+	})
+
+	t.Run("duplicate email conflicts", func(t *testing.T) {
+		w := serveJSON(router, http.MethodPost, "/api/v1/users", token, map[string]string{"email": "a@example.com"})
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected 409, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("self deactivation is forbidden", func(t *testing.T) {
+		w := serveJSON(router, http.MethodPost, "/api/v1/users/"+admin.ID.String()+"/deactivate", token, nil)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("unknown token is rejected", func(t *testing.T) {
+		w := serveJSON(router, http.MethodGet, "/api/v1/users/"+admin.ID.String(), "forged", nil)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", w.Code)
+		}
+	})
+}
+
+// TestAuthControllerLoginWithFakes tests that a fake login token opens the user routes
+func TestAuthControllerLoginWithFakes(t *testing.T) {
+	users := servicetest.NewUserService()
+	ann := users.Add(&models.User{Email: "ann@example.com", Role: models.RoleUser, Active: true})
+	tokens := servicetest.NewAuthService(users)
+	tokens.SetPassword(ann.Email, "secret123")
+	router := newUserRouter(users, tokens)
+
+	w := serveJSON(router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": ann.Email, "password": "wrong-password"})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong password, got %d", w.Code)
+	}
+
+	w = serveJSON(router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": ann.Email, "password": "secret123"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result services.AuthResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+
+	w = serveJSON(router, http.MethodGet, "/api/v1/users/"+ann.ID.String(), result.Token, nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected the issued token to work, got %d", w.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// TestAnonymizeUserFieldsScrubsPII tests that every personal field is replaced
+func TestAnonymizeUserFieldsScrubsPII(t *testing.T) {
+	user := &models.User{
+		ID:        uuid.New(),
+		Email:     "jane@example.com",
+		Username:  "jane",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Password:  "hash",
+		Role:      models.RoleUser,
+		Active:    true,
+	}
+
+	services.AnonymizeUserFields(user, time.Now())
+
+	if strings.Contains(user.Email, "jane") || !strings.HasSuffix(user.Email, "@anonymized.invalid") {
+		t.Fatalf("email not replaced with tombstone: %s", user.Email)
+	}
+	if user.Username != "" || user.FirstName != "" || user.LastName != "" || user.Password != "" {
+		t.Fatalf("personal fields not blanked: %+v", user)
+	}
+	if user.Active || user.AnonymizedAt == nil {
+		t.Fatalf("anonymized user must be inactive and flagged: %+v", user)
+	}
+}
+
+// TestAnonymizeUserFieldsIdempotent tests that re-anonymizing yields identical values
+func TestAnonymizeUserFieldsIdempotent(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "john@example.com", FirstName: "John"}
+
+	first := services.AnonymizeUserFields(user, time.Now())
+	second := services.AnonymizeUserFields(user, time.Now().Add(time.Hour))
+
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("re-anonymization changed values:\nfirst:  %v\nsecond: %v", first, second)
+	}
+}
+
+// TestAnonymizeGuards tests that anonymizing, which deactivates the user for
+// good, cannot lock out the only admin, and that unknown users get 404
+func TestAnonymizeGuards(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	operator := ta.CreateUser(models.RoleUser)
+	path := "/api/v1/users/" + admin.ID.String() + "/anonymize"
+
+	resp := ta.Request(http.MethodPost, path, nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusForbidden, errors.CodeSelfDeactivation)
+
+	grant := map[string][]string{"permissions": {models.PermissionUsersManage}}
+	if resp := ta.Request(http.MethodPut, "/api/v1/roles/user/permissions", grant, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("grant users.manage: %d %s", resp.StatusCode, resp.Body)
+	}
+	resp = ta.Request(http.MethodPost, path, nil, operator.Token)
+	expectErrorCode(t, resp, http.StatusConflict, errors.CodeLastActiveAdmin)
+
+	if n := countRows(t, ta.DB(), &models.User{}, "id = ? AND active = ? AND anonymized_at IS NULL", admin.ID, true); n != 1 {
+		t.Error("expected the admin kept")
+	}
+
+	resp = ta.Request(http.MethodPost, "/api/v1/users/"+uuid.NewString()+"/anonymize", nil, admin.Token)
+	expectErrorCode(t, resp, http.StatusNotFound, errors.CodeUserNotFound)
+}
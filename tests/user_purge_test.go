@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/jobs"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+)
+
+// seedPurgeUser creates a user deleted at deletedAt (nil for a live user)
+// with a login event, a notification, a membership and an audit entry
+func seedPurgeUser(t *testing.T, db *gorm.DB, email string, deletedAt *time.Time) uuid.UUID {
+	t.Helper()
+
+	id := uuid.New()
+	rows := []interface{}{
+		&models.User{ID: id, Email: email, Role: models.RoleUser, Active: true, DeletedAt: deletedAt},
+		&models.LoginEvent{ID: uuid.New(), UserID: &id, Email: email, Success: true},
+		&models.Notification{ID: uuid.New(), UserID: id, Type: "test", Title: "hello"},
+		&models.OrganizationMember{OrganizationID: uuid.New(), UserID: id, Role: models.OrgRoleMember},
+		&models.AuditLog{ID: uuid.New(), ActorID: &id, Action: models.AuditActionUserUpdated, EntityType: "user", EntityID: id.String()},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("seed %T: %v", row, err)
+		}
+	}
+	return id
+}
+
+// newPurgeFixture returns a user service on SQLite with the tables a purge touches
+func newPurgeFixture(t *testing.T) (*services.UserService, *gorm.DB) {
+	t.Helper()
+
+	manager, driver := databasetest.NewManager(t, databasetest.WithSQLite(
+		&models.User{}, &models.LoginEvent{}, &models.Notification{}, &models.OrganizationMember{}, &models.AuditLog{},
+	))
+	return newTestUserService(manager), driver.GormDB()
+}
+
+// countRows counts the rows of model matching where
+func countRows(t *testing.T, db *gorm.DB, model interface{}, where string, args ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Model(model).Where(where, args...).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// TestPurgeDeletedUsersCutoff tests that only users deleted before the cutoff are purged
+func TestPurgeDeletedUsersCutoff(t *testing.T) {
+	svc, db := newPurgeFixture(t)
+	now := time.Now()
+	old, recent := now.Add(-100*24*time.Hour), now.Add(-time.Hour)
+
+	purged := seedPurgeUser(t, db, "old@example.com", &old)
+	kept := seedPurgeUser(t, db, "recent@example.com", &recent)
+	live := seedPurgeUser(t, db, "live@example.com", nil)
+
+	result, err := svc.PurgeDeleted(context.Background(), now.Add(-90*24*time.Hour), services.UserPurgeOptions{})
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	want := services.UserPurgeResult{Users: 1, LoginEvents: 1, Notifications: 1, Memberships: 1, AuditEntries: 1}
+	if *result != want {
+		t.Errorf("expected %+v, got %+v", want, *result)
+	}
+
+	if n := countRows(t, db, &models.User{}, "id = ?", purged); n != 0 {
+		t.Error("expected the long deleted user to be purged")
+	}
+	for _, id := range []uuid.UUID{kept, live} {
+		if n := countRows(t, db, &models.User{}, "id = ?", id); n != 1 {
+			t.Errorf("expected user %s to be kept", id)
+		}
+		if n := countRows(t, db, &models.LoginEvent{}, "user_id = ?", id); n != 1 {
+			t.Errorf("expected the login events of %s to be kept", id)
+		}
+	}
+	for _, model := range []interface{}{&models.LoginEvent{}, &models.Notification{}, &models.OrganizationMember{}} {
+		if n := countRows(t, db, model, "user_id = ?", purged); n != 0 {
+			t.Errorf("expected no %T of the purged user, got %d", model, n)
+		}
+	}
+
+	// The audit trail stays, without pointing at the purged user
+	if n := countRows(t, db, &models.AuditLog{}, "entity_id = ? AND actor_id IS NULL", purged.String()); n != 1 {
+		t.Errorf("expected the audit entry to be kept without actor, got %d", n)
+	}
+}
+
+// TestPurgeDeletedUsersBatches tests the per-run cap and dry runs
+func TestPurgeDeletedUsersBatches(t *testing.T) {
+	svc, db := newPurgeFixture(t)
+	deleted := time.Now().Add(-48 * time.Hour)
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		seedPurgeUser(t, db, email, &deleted)
+	}
+	ctx := context.Background()
+	cutoff := time.Now().Add(-24 * time.Hour)
+
+	result, err := svc.PurgeDeleted(ctx, cutoff, services.UserPurgeOptions{Limit: 4, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !result.DryRun || result.Users != 4 || result.LoginEvents != 4 {
+		t.Errorf("expected a dry run of 4 users, got %+v", result)
+	}
+	if n := countRows(t, db, &models.User{}, "1 = 1"); n != 5 {
+		t.Fatalf("expected a dry run to remove nothing, %d users left", n)
+	}
+
+	result, err = svc.PurgeDeleted(ctx, cutoff, services.UserPurgeOptions{BatchSize: 2, Limit: 3})
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if result.Users != 3 || result.Notifications != 3 {
+		t.Errorf("expected 3 users purged, got %+v", result)
+	}
+	if n := countRows(t, db, &models.User{}, "1 = 1"); n != 2 {
+		t.Errorf("expected 2 users left after the capped run, got %d", n)
+	}
+}
+
+// TestUserPurgeJob tests that the job counts what it purged and that a zero retention disables it
+func TestUserPurgeJob(t *testing.T) {
+	svc, db := newPurgeFixture(t)
+	deleted := time.Now().Add(-48 * time.Hour)
+	seedPurgeUser(t, db, "gone@example.com", &deleted)
+	m := metrics.New()
+
+	s := jobs.NewScheduler(jobs.NewMemoryLocker(), logger.NewNopLogger())
+	if err := s.Register(services.NewUserPurgeJob(svc, "@daily", 0, services.UserPurgeOptions{}, m.UserPurge, logger.NewNopLogger())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Run(services.JobUserPurge); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if n := countRows(t, db, &models.User{}, "1 = 1"); n != 1 {
+		t.Fatal("expected a zero retention to purge nothing")
+	}
+
+	s = jobs.NewScheduler(jobs.NewMemoryLocker(), logger.NewNopLogger())
+	if err := s.Register(services.NewUserPurgeJob(svc, "@daily", 24*time.Hour, services.UserPurgeOptions{}, m.UserPurge, logger.NewNopLogger())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Run(services.JobUserPurge); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got := testutil.ToFloat64(m.UserPurge.WithLabelValues("users")); got != 1 {
+		t.Errorf("expected 1 purged user counted, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.UserPurge.WithLabelValues("audit_logs")); got != 1 {
+		t.Errorf("expected 1 audit entry counted, got %v", got)
+	}
+}
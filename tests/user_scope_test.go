@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/requestctx"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// TestUserScopesPerRole tests the filters each kind of principal is held to
+func TestUserScopesPerRole(t *testing.T) {
+	tenant := "acme"
+	admin := &models.User{Role: models.RoleAdmin}
+	member := &models.User{Role: models.RoleUser}
+	acmeMember := &models.User{Role: models.RoleUser, TenantID: &tenant}
+	other := "globex"
+	globexMember := &models.User{Role: models.RoleUser, TenantID: &other}
+
+	cases := []struct {
+		name      string
+		principal services.Principal
+		where     string
+		sees      []*models.User
+		hidden    []*models.User
+	}{
+		{
+			name:      "admin",
+			principal: services.Principal{Role: models.RoleAdmin, Permissions: models.DefaultRolePermissions[models.RoleAdmin]},
+			sees:      []*models.User{admin, member, acmeMember, globexMember},
+		},
+		{
+			name:      "support agent",
+			principal: services.Principal{Role: models.RoleUser, Permissions: []string{models.PermissionUsersView}},
+			where:     "(role <> ?)",
+			sees:      []*models.User{member, acmeMember, globexMember},
+			hidden:    []*models.User{admin},
+		},
+		{
+			name:      "guest",
+			principal: services.Principal{Role: models.RoleGuest},
+			where:     "(role <> ?)",
+			sees:      []*models.User{member},
+			hidden:    []*models.User{admin},
+		},
+		{
+			name:      "user manager",
+			principal: services.Principal{Role: models.RoleUser, Permissions: []string{models.PermissionUsersManage}},
+			sees:      []*models.User{admin, member},
+		},
+		{
+			name:      "tenant admin",
+			principal: services.Principal{Role: models.RoleAdmin, TenantID: tenant, Permissions: models.DefaultRolePermissions[models.RoleAdmin]},
+			where:     "(tenant_id = ? OR tenant_id IS NULL)",
+			sees:      []*models.User{admin, member, acmeMember},
+			hidden:    []*models.User{globexMember},
+		},
+		{
+			name:      "tenant support agent",
+			principal: services.Principal{Role: models.RoleUser, TenantID: tenant, Permissions: []string{models.PermissionUsersView}},
+			where:     "(role <> ?) AND (tenant_id = ? OR tenant_id IS NULL)",
+			sees:      []*models.User{member, acmeMember},
+			hidden:    []*models.User{admin, globexMember},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope := services.ScopeFor(tc.principal, services.DefaultUserScopes...)
+			if where, _ := scope.Where(); where != tc.where {
+				t.Errorf("expected %q, got %q", tc.where, where)
+			}
+			for _, user := range tc.sees {
+				if !scope.Allows(user) {
+					t.Errorf("expected %+v seen", *user)
+				}
+			}
+			for _, user := range tc.hidden {
+				if scope.Allows(user) {
+					t.Errorf("expected %+v hidden", *user)
+				}
+			}
+		})
+	}
+
+	custom := func(p services.Principal) (services.UserFilter, bool) {
+		return services.UserFilter{Where: "active = ?", Args: []interface{}{true}, Match: func(u *models.User) bool { return u.Active }}, true
+	}
+	where, args := services.ScopeFor(services.Principal{Role: models.RoleGuest, TenantID: tenant}, services.OwnTenant(), custom).Where()
+	if where != "(tenant_id = ? OR tenant_id IS NULL) AND (active = ?)" || !slices.Equal(args, []interface{}{tenant, true}) {
+		t.Errorf("expected scopes composed in order, got %q %v", where, args)
+	}
+}
+
+// staticPermissions grants each role the default permissions
+type staticPermissions struct{}
+
+func (staticPermissions) RolePermissions(ctx context.Context, role models.UserRole) ([]string, error) {
+	return models.DefaultRolePermissions[role], nil
+}
+
+// TestTenantUserScope tests that tenants sharing a database list and fetch
+// only their own users and those created before users were tagged
+func TestTenantUserScope(t *testing.T) {
+	manager, driver := databasetest.NewManager(t, databasetest.WithSQLite(&models.User{}), databasetest.WithType(database.DriverSQLite))
+	users := services.NewUserService(manager, cache.NewMemoryStore(), nil, nil, nil, logger.NewNopLogger(),
+		services.WithUserScopes(staticPermissions{}, services.DefaultUserScopes...))
+
+	tenantCtx := func(tenant string) context.Context {
+		ctx := database.WithTenant(context.Background(), tenant, database.PrimaryDriver)
+		return requestctx.WithUser(ctx, requestctx.User{ID: uuid.NewString(), Role: string(models.RoleAdmin)})
+	}
+	create := func(ctx context.Context, name string) *models.User {
+		user, err := users.CreateUser(ctx, &services.CreateUserRequest{Email: name + "@example.com", Username: name, Password: "Password123!"}, "")
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		return user
+	}
+	legacy := create(context.Background(), "legacy")
+	ann := create(tenantCtx("acme"), "ann")
+	bob := create(tenantCtx("globex"), "bob")
+
+	var tagged string
+	if err := driver.GormDB().Model(&models.User{}).Where("id = ?", ann.ID).Pluck("tenant_id", &tagged).Error; err != nil || tagged != "acme" {
+		t.Fatalf("expected the user tagged with the tenant, got %q %v", tagged, err)
+	}
+
+	acme := tenantCtx("acme")
+	list, err := users.ListUsers(acme, services.ListUsersFilter{}, 10, 0)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var ids []uuid.UUID
+	for _, user := range list.Items {
+		ids = append(ids, user.ID)
+	}
+	if list.Total != 2 || !slices.Contains(ids, ann.ID) || !slices.Contains(ids, legacy.ID) {
+		t.Errorf("expected acme's and the untagged user, got %v", ids)
+	}
+	if _, err := users.GetUser(acme, bob.ID.String()); err != services.ErrUserNotFound {
+		t.Errorf("expected another tenant's user not found, got %v", err)
+	}
+	search, err := users.SearchUsers(acme, services.SearchUsersFilter{Query: "bob"}, 10, 0)
+	if err != nil || search.Total != 0 {
+		t.Errorf("expected another tenant's user left out of search, got %+v %v", search, err)
+	}
+
+	// Requests outside any tenant, and calls outside requests, see everyone
+	if list, err := users.ListUsers(context.Background(), services.ListUsersFilter{}, 10, 0); err != nil || list.Total != 3 {
+		t.Errorf("expected every user unscoped, got %+v %v", list, err)
+	}
+	if _, err := users.GetUser(context.Background(), bob.ID.String()); err != nil {
+		t.Errorf("expected unscoped lookups to find any user, got %v", err)
+	}
+}
+
+// TestSupportAgentCannotSeeAdmins tests that a user without users.manage
+// cannot fetch, list or search admins, and is told they do not exist
+func TestSupportAgentCannotSeeAdmins(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	agent := ta.CreateUser(models.RoleUser)
+	customer := ta.CreateUser(models.RoleUser)
+
+	resp := ta.Request(http.MethodGet, "/api/v1/users/"+admin.ID.String(), nil, agent.Token)
+	expectErrorCode(t, resp, http.StatusNotFound, errors.CodeUserNotFound)
+	unknown := ta.Request(http.MethodGet, "/api/v1/users/"+uuid.NewString(), nil, agent.Token)
+	if string(resp.Body) != string(unknown.Body) {
+		t.Errorf("expected an admin answered like an unknown user, got %s and %s", resp.Body, unknown.Body)
+	}
+	if resp := ta.Request(http.MethodGet, "/api/v1/users/"+customer.ID.String(), nil, agent.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the agent to see a customer, got %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Data []models.User `json:"data"`
+	}
+	ta.Request(http.MethodGet, "/api/v1/users?limit=100", nil, agent.Token).Decode(t, &list)
+	if slices.ContainsFunc(list.Data, func(u models.User) bool { return u.Role == models.RoleAdmin }) || len(list.Data) != 2 {
+		t.Errorf("expected the two non-admin users listed, got %+v", list.Data)
+	}
+	ta.Request(http.MethodGet, "/api/v1/users/search?q=example", nil, agent.Token).Decode(t, &list)
+	if slices.ContainsFunc(list.Data, func(u models.User) bool { return u.Role == models.RoleAdmin }) {
+		t.Errorf("expected no admin in search results, got %+v", list.Data)
+	}
+
+	// Admins bypass the scopes
+	if resp := ta.Request(http.MethodGet, "/api/v1/users/"+admin.ID.String(), nil, admin.Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected admins to see admins, got %d", resp.StatusCode)
+	}
+	ta.Request(http.MethodGet, "/api/v1/users?limit=100", nil, admin.Token).Decode(t, &list)
+	if len(list.Data) != 3 {
+		t.Errorf("expected admins to list everyone, got %d users", len(list.Data))
+	}
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+// TestSearchUsersLike tests the substring strategy used on SQLite and MySQL
+func TestSearchUsersLike(t *testing.T) {
+	db, driver := databasetest.NewManager(t,
+		databasetest.WithSQLite(&models.User{}),
+		databasetest.WithType(database.DriverSQLite),
+	)
+	anonymizedAt := time.Now()
+	for _, u := range []*models.User{
+		{Email: "ann.smith@example.com", Username: "ann", FirstName: "Ann", LastName: "Smith", Active: true},
+		{Email: "jo@example.com", Username: "joanna", FirstName: "Joanna", LastName: "Smithers", Active: true},
+		{Email: "annie@example.com", Username: "annie", FirstName: "Annie", LastName: "Jones", Active: false},
+		{Email: "gone@example.com", FirstName: "Anna", AnonymizedAt: &anonymizedAt, Active: true},
+		{Email: "bob@example.com", Username: "bob", FirstName: "Bob", Active: true},
+	} {
+		u.ID = uuid.New()
+		u.Role = models.RoleUser
+		if err := driver.GormDB().Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// GORM skips false on create, so store the inactive flag explicitly
+	driver.GormDB().Model(&models.User{}).Where("email = ?", "annie@example.com").Update("active", false)
+
+	users := newTestUserService(db)
+	ctx := context.Background()
+
+	cases := []struct {
+		name   string
+		filter services.SearchUsersFilter
+		want   []string
+	}{
+		// Equal scores fall back to newest first
+		{"prefix ranks first", services.SearchUsersFilter{Query: "ann"}, []string{"annie@example.com", "ann.smith@example.com", "jo@example.com"}},
+		{"every term must match", services.SearchUsersFilter{Query: "ANN  smith"}, []string{"ann.smith@example.com", "jo@example.com"}},
+		{"active only", services.SearchUsersFilter{Query: "ann", Active: boolPtr(true)}, []string{"ann.smith@example.com", "jo@example.com"}},
+		{"inactive only", services.SearchUsersFilter{Query: "ann", Active: boolPtr(false)}, []string{"annie@example.com"}},
+		{"anonymized included", services.SearchUsersFilter{Query: "anna", IncludeAnonymized: true}, []string{"gone@example.com", "jo@example.com"}},
+		{"wildcards are not patterns", services.SearchUsersFilter{Query: "%_"}, nil},
+		{"no match", services.SearchUsersFilter{Query: "zed"}, []string{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := users.SearchUsers(ctx, tc.filter, 10, 0)
+			if tc.want == nil {
+				if !errors.Is(err, services.ErrEmptySearchQuery) {
+					t.Fatalf("expected ErrEmptySearchQuery, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("search: %v", err)
+			}
+
+			got := make([]string, len(result.Items))
+			for i, item := range result.Items {
+				got[i] = item.Email
+				if item.Password != "" {
+					t.Error("search returned a password hash")
+				}
+				if item.Score <= 0 || item.Score > 1 {
+					t.Errorf("unexpected score %v for %s", item.Score, item.Email)
+				}
+			}
+			if len(got) != len(tc.want) || result.Total != int64(len(tc.want)) {
+				t.Fatalf("expected %v, got %v (total %d)", tc.want, got, result.Total)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("expected %v, got %v", tc.want, got)
+				}
+			}
+		})
+	}
+
+	t.Run("pagination", func(t *testing.T) {
+		result, err := users.SearchUsers(ctx, services.SearchUsersFilter{Query: "ann"}, 1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Total != 3 || len(result.Items) != 1 || result.Items[0].Email != "ann.smith@example.com" {
+			t.Errorf("unexpected page %+v", result)
+		}
+	})
+}
+
+// TestSearchUsersFullText tests that PostgreSQL searches the tsvector column
+func TestSearchUsersFullText(t *testing.T) {
+	db, driver := databasetest.NewManager(t)
+	driver.Mock.ExpectQuery(`SELECT count\(\*\) FROM "users" WHERE deleted_at IS NULL AND anonymized_at IS NULL AND search_vector @@ to_tsquery\('simple', \$1\)`).
+		WithArgs("ann:* & smi:*").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	driver.Mock.ExpectQuery(`SELECT users\.\*, ts_rank\(search_vector, to_tsquery\('simple', \$1\)\) AS score FROM "users" .* ORDER BY score DESC, created_at DESC`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "score"}).
+			AddRow(uuid.NewString(), "ann@example.com", "hash", 0.6))
+
+	result, err := newTestUserService(db).SearchUsers(context.Background(), services.SearchUsersFilter{Query: "Ann Smi"}, 10, 0)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if result.Total != 1 || len(result.Items) != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if item := result.Items[0]; item.Email != "ann@example.com" || item.Score != 0.6 || item.Password != "" {
+		t.Errorf("unexpected item %+v", item)
+	}
+}
+
+// TestSearchUsersEndpoint tests GET /api/v1/users/search
+func TestSearchUsersEndpoint(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+
+	resp := ta.Request(http.MethodGet, "/api/v1/users/search?q=test+"+string(models.RoleAdmin), nil, admin.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("search: %d %s", resp.StatusCode, resp.Body)
+	}
+	var body struct {
+		Data []services.UserSearchResult `json:"data"`
+		Meta struct {
+			Total int64 `json:"total"`
+		} `json:"meta"`
+	}
+	resp.Decode(t, &body)
+	if body.Meta.Total != 1 || len(body.Data) != 1 || body.Data[0].ID != admin.ID || body.Data[0].Score == 0 {
+		t.Errorf("unexpected response %s", resp.Body)
+	}
+
+	if resp := ta.Request(http.MethodGet, "/api/v1/users/search", nil, admin.Token); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("missing q: expected 400, got %d", resp.StatusCode)
+	}
+	if resp := ta.Request(http.MethodGet, "/api/v1/users/search?q=a&active=maybe", nil, admin.Token); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad active: expected 400, got %d", resp.StatusCode)
+	}
+}
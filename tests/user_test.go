@@ -1,12 +1,203 @@
 package tests
 
 import (
+	"context"
+	"errors"
 	"testing"
+
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/cache"
+	"BackofficeGoService/internal/pkg/database"
+	"BackofficeGoService/internal/pkg/database/databasetest"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
 )
 
-// TestUserGet tests getting a user
-func TestUserGet(t *testing.T) {
-	// TODO: Implement user tests
-	t.Skip("Not implemented yet")
+func newTestUserService(db *database.Manager) *services.UserService {
+	return services.NewUserService(db, cache.NewMemoryStore(), nil, nil, nil, logger.NewSimpleLogger())
 }
 
+func strPtr(s string) *string { return &s }
+
+// TestUserRoundTrip tests create, get, update and delete against SQLite
+func TestUserRoundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		create  *services.CreateUserRequest
+		update  *services.UpdateUserRequest
+		checkFn func(t *testing.T, user *models.User)
+	}{
+		{
+			name:   "rename",
+			create: &services.CreateUserRequest{Email: "ann@example.com", FirstName: "Ann", Password: "secret123"},
+			update: &services.UpdateUserRequest{FirstName: strPtr("Anna")},
+			checkFn: func(t *testing.T, user *models.User) {
+				if user.FirstName != "Anna" || user.Email != "ann@example.com" {
+					t.Errorf("unexpected user %+v", user)
+				}
+			},
+		},
+		{
+			name:   "change username",
+			create: &services.CreateUserRequest{Email: "bob@example.com", Username: "bob"},
+			update: &services.UpdateUserRequest{Username: strPtr("robert")},
+			checkFn: func(t *testing.T, user *models.User) {
+				if user.Username != "robert" || user.Email != "bob@example.com" {
+					t.Errorf("unexpected user %+v", user)
+				}
+			},
+		},
+		{
+			name:   "empty update",
+			create: &services.CreateUserRequest{Email: "cy@example.com", LastName: "Young"},
+			update: &services.UpdateUserRequest{},
+			checkFn: func(t *testing.T, user *models.User) {
+				if user.LastName != "Young" {
+					t.Errorf("unexpected user %+v", user)
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, _ := databasetest.NewManager(t, databasetest.WithSQLite(&models.User{}))
+			users := newTestUserService(db)
+			ctx := context.Background()
+
+			created, err := users.CreateUser(ctx, tc.create, "")
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			if created.Password != "" {
+				t.Error("create returned the password hash")
+			}
+
+			fetched, err := users.GetUser(ctx, created.ID.String())
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			if fetched.Email != created.Email || fetched.Role != models.RoleUser || !fetched.Active {
+				t.Errorf("fetched %+v, created %+v", fetched, created)
+			}
+
+			updated, err := users.UpdateUser(ctx, created.ID.String(), tc.update, "")
+			if err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			tc.checkFn(t, updated)
+
+			// Read back through a fresh cache so the stored row is checked
+			stored, err := newTestUserService(db).GetUser(ctx, created.ID.String())
+			if err != nil {
+				t.Fatalf("get after update: %v", err)
+			}
+			tc.checkFn(t, stored)
+
+			if err := users.DeleteUser(ctx, created.ID.String()); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+			if _, err := users.GetUser(ctx, created.ID.String()); !errors.Is(err, services.ErrUserNotFound) {
+				t.Errorf("expected ErrUserNotFound after delete, got %v", err)
+			}
+		})
+	}
+}
+
+// TestUserNotFound tests lookups and updates of missing or malformed IDs
+func TestUserNotFound(t *testing.T) {
+	db, _ := databasetest.NewManager(t, databasetest.WithSQLite(&models.User{}))
+	users := newTestUserService(db)
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"get unknown", func() error { _, err := users.GetUser(ctx, uuid.NewString()); return err }, services.ErrUserNotFound},
+		{"update unknown", func() error {
+			_, err := users.UpdateUser(ctx, uuid.NewString(), &services.UpdateUserRequest{FirstName: strPtr("x")}, "")
+			return err
+		}, services.ErrUserNotFound},
+		{"get malformed", func() error { _, err := users.GetUser(ctx, "not-a-uuid"); return err }, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+// TestUserDuplicateEmail tests that a second user with the same email is rejected
+func TestUserDuplicateEmail(t *testing.T) {
+	db, driver := databasetest.NewManager(t, databasetest.WithSQLite(&models.User{}))
+	users := newTestUserService(db)
+	ctx := context.Background()
+
+	if _, err := users.CreateUser(ctx, &services.CreateUserRequest{Email: "ann@example.com"}, ""); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := users.CreateUser(ctx, &services.CreateUserRequest{Email: "ann@example.com"}, ""); !errors.Is(err, services.ErrEmailTaken) {
+		t.Fatalf("expected ErrEmailTaken, got %v", err)
+	}
+
+	var count int64
+	driver.GormDB().Model(&models.User{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected 1 stored user, got %d", count)
+	}
+}
+
+// TestUserRawSQL tests the raw SQL paths, used when GORM is disabled, with sqlmock
+func TestUserRawSQL(t *testing.T) {
+	t.Run("duplicate email", func(t *testing.T) {
+		db, driver := databasetest.NewManager(t)
+		driver.Mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users WHERE email = \$1`).
+			WithArgs("ann@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		_, err := newTestUserService(db).CreateUser(context.Background(), &services.CreateUserRequest{Email: "ann@example.com"}, "")
+		if !errors.Is(err, services.ErrEmailTaken) {
+			t.Fatalf("expected ErrEmailTaken, got %v", err)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		db, driver := databasetest.NewManager(t)
+		driver.Mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		driver.Mock.ExpectExec(`INSERT INTO users`).
+			WithArgs(sqlmock.AnyArg(), "ann@example.com", "ann", "", "", "", models.RoleUser, true, nil, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		user, err := newTestUserService(db).CreateUser(context.Background(), &services.CreateUserRequest{Email: "ann@example.com", Username: "ann"}, "")
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if user.Email != "ann@example.com" {
+			t.Errorf("unexpected user %+v", user)
+		}
+	})
+
+	t.Run("get unknown", func(t *testing.T) {
+		db, driver := databasetest.NewManager(t)
+		driver.Mock.ExpectQuery(`SELECT .* FROM users WHERE id = \$1`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := newTestUserService(db).GetUser(context.Background(), uuid.NewString())
+		if !errors.Is(err, services.ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"BackofficeGoService/internal/services"
+)
+
+// TestUpdateUserRequestClearsField tests that an empty string is applied rather than ignored
+func TestUpdateUserRequestClearsField(t *testing.T) {
+	req, err := services.ParseUpdateUserRequest([]byte(`{"last_name": "", "first_name": ""}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]interface{}{"last_name": "", "first_name": ""}
+	if got := req.Changes(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes = %v, want %v", got, want)
+	}
+}
+
+// TestUpdateUserRequestNoop tests that omitted and null fields leave the user unchanged
+func TestUpdateUserRequestNoop(t *testing.T) {
+	for _, body := range []string{`{}`, `{"first_name": null}`} {
+		req, err := services.ParseUpdateUserRequest([]byte(body))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", body, err)
+		}
+		if !req.IsEmpty() {
+			t.Fatalf("%s: expected no-op update, got %v", body, req.Changes())
+		}
+	}
+}
+
+// TestUpdateUserRequestUnknownFields tests that unrecognised keys are rejected and listed
+func TestUpdateUserRequestUnknownFields(t *testing.T) {
+	_, err := services.ParseUpdateUserRequest([]byte(`{"firstname": "Jane", "emial": "x", "email": "jane@example.com", "active": false, "username": "jane"}`))
+
+	var unknownErr *services.UnknownFieldsError
+	if !errors.As(err, &unknownErr) {
+		t.Fatalf("expected UnknownFieldsError, got %v", err)
+	}
+	// The email only changes through the confirmed email change flow, and
+	// the active flag through activate and deactivate
+	if want := []string{"active", "email", "emial", "firstname"}; !reflect.DeepEqual(unknownErr.Fields, want) {
+		t.Fatalf("fields = %v, want %v", unknownErr.Fields, want)
+	}
+}
+
+// TestUpdateUserRequestValidation tests that provided values are validated
+func TestUpdateUserRequestValidation(t *testing.T) {
+	for _, body := range []string{`{"password": ""}`, `{"password": "123"}`} {
+		if _, err := services.ParseUpdateUserRequest([]byte(body)); err == nil {
+			t.Fatalf("%s: expected validation error", body)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"BackofficeGoService/config"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/pagination"
+	"BackofficeGoService/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// fakeWebhookRepository is an in-memory WebhookRepository
+type fakeWebhookRepository struct {
+	mu         sync.Mutex
+	webhooks   map[uuid.UUID]*models.Webhook
+	deliveries []*models.WebhookDelivery
+}
+
+func newFakeWebhookRepository() *fakeWebhookRepository {
+	return &fakeWebhookRepository{webhooks: make(map[uuid.UUID]*models.Webhook)}
+}
+
+func (r *fakeWebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *webhook
+	r.webhooks[webhook.ID] = &copied
+	return nil
+}
+
+func (r *fakeWebhookRepository) Get(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, services.ErrWebhookNotFound
+	}
+	copied := *webhook
+	return &copied, nil
+}
+
+func (r *fakeWebhookRepository) List(ctx context.Context) ([]*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var webhooks []*models.Webhook
+	for _, w := range r.webhooks {
+		copied := *w
+		webhooks = append(webhooks, &copied)
+	}
+	return webhooks, nil
+}
+
+func (r *fakeWebhookRepository) ListEnabled(ctx context.Context) ([]*models.Webhook, error) {
+	all, _ := r.List(ctx)
+	var enabled []*models.Webhook
+	for _, w := range all {
+		if w.Enabled {
+			enabled = append(enabled, w)
+		}
+	}
+	return enabled, nil
+}
+
+func (r *fakeWebhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	return r.Create(ctx, webhook)
+}
+
+func (r *fakeWebhookRepository) UpdateDeliveryState(ctx context.Context, webhook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.webhooks[webhook.ID]
+	stored.Enabled = webhook.Enabled
+	stored.ConsecutiveFailures = webhook.ConsecutiveFailures
+	stored.DisabledAt = webhook.DisabledAt
+	return nil
+}
+
+func (r *fakeWebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.webhooks, id)
+	return nil
+}
+
+func (r *fakeWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, delivery)
+	return nil
+}
+
+func (r *fakeWebhookRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, after *pagination.Cursor, limit int) ([]*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deliveries []*models.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.WebhookID == webhookID {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (r *fakeWebhookRepository) DeleteDeliveriesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.deliveries[:0]
+	for _, d := range r.deliveries {
+		if !d.CreatedAt.Before(cutoff) {
+			kept = append(kept, d)
+		}
+	}
+	purged := int64(len(r.deliveries) - len(kept))
+	r.deliveries = kept
+	return purged, nil
+}
+
+// webhookTestConfig retries quickly so tests do not sleep
+func webhookTestConfig() config.WebhookConfig {
+	return config.WebhookConfig{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		Timeout:        time.Second,
+		DisableAfter:   2,
+		QueueSize:      10,
+		Workers:        1,
+	}
+}
+
+// createTestWebhook registers a webhook for every event type pointing at url
+func createTestWebhook(t *testing.T, repo services.WebhookRepository, url string) *models.Webhook {
+	t.Helper()
+	svc := services.NewWebhookService(repo, logger.NewSimpleLogger())
+	webhook, err := svc.CreateWebhook(context.Background(), &services.CreateWebhookRequest{
+		URL:        url,
+		EventTypes: []string{models.WebhookEventAll},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return webhook
+}
+
+// TestWebhookSignature tests that deliveries are signed with the endpoint secret
+func TestWebhookSignature(t *testing.T) {
+	repo := newFakeWebhookRepository()
+
+	var verified, hasHeaders atomic.Bool
+	var secret string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verified.Store(services.VerifyWebhookSignature(secret, body, r.Header.Get(services.WebhookSignatureHeader)))
+
+		var event events.Event
+		_ = json.Unmarshal(body, &event)
+		hasHeaders.Store(r.Header.Get(services.WebhookEventIDHeader) == event.ID &&
+			r.Header.Get(services.WebhookEventTypeHeader) == events.UserCreated &&
+			r.Header.Get(services.WebhookTimestampHeader) != "")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	webhook := createTestWebhook(t, repo, receiver.URL)
+	secret = webhook.Secret
+
+	dispatcher := services.NewWebhookDispatcher(repo, webhookTestConfig(), logger.NewSimpleLogger())
+	delivery, err := dispatcher.Deliver(context.Background(), webhook, events.New(events.UserCreated, map[string]string{"id": "u1"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !delivery.Success || delivery.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected successful delivery, got %+v", delivery)
+	}
+	if !verified.Load() {
+		t.Fatal("receiver could not verify the signature")
+	}
+	if !hasHeaders.Load() {
+		t.Fatal("expected event id, event type and timestamp headers")
+	}
+
+	if services.VerifyWebhookSignature("wrong-secret", []byte(`{}`), services.SignWebhookPayload(secret, []byte(`{}`))) {
+		t.Fatal("signature must not verify with a different secret")
+	}
+}
+
+// TestWebhookRetriesServerErrors tests that 5xx responses are retried and every attempt recorded
+func TestWebhookRetriesServerErrors(t *testing.T) {
+	repo := newFakeWebhookRepository()
+
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	webhook := createTestWebhook(t, repo, receiver.URL)
+	dispatcher := services.NewWebhookDispatcher(repo, webhookTestConfig(), logger.NewSimpleLogger())
+
+	delivery, err := dispatcher.Deliver(context.Background(), webhook, events.New(events.UserUpdated, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if delivery.Attempt != 3 {
+		t.Fatalf("expected success on attempt 3, got %d", delivery.Attempt)
+	}
+
+	deliveries, _ := repo.ListDeliveries(context.Background(), webhook.ID, nil, 100)
+	if len(deliveries) != 3 {
+		t.Fatalf("expected 3 recorded attempts, got %d", len(deliveries))
+	}
+}
+
+// TestWebhookClientErrorsAreNotRetried tests that 4xx responses fail without retrying
+func TestWebhookClientErrorsAreNotRetried(t *testing.T) {
+	repo := newFakeWebhookRepository()
+
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer receiver.Close()
+
+	webhook := createTestWebhook(t, repo, receiver.URL)
+	dispatcher := services.NewWebhookDispatcher(repo, webhookTestConfig(), logger.NewSimpleLogger())
+
+	if _, err := dispatcher.Deliver(context.Background(), webhook, events.New(events.UserUpdated, nil)); err == nil {
+		t.Fatal("expected delivery to fail")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls.Load())
+	}
+}
+
+// TestWebhookDisabledAfterConsecutiveFailures tests auto-disable and re-enable
+func TestWebhookDisabledAfterConsecutiveFailures(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWebhookRepository()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	webhook := createTestWebhook(t, repo, receiver.URL)
+	dispatcher := services.NewWebhookDispatcher(repo, webhookTestConfig(), logger.NewSimpleLogger())
+
+	for i := 0; i < 2; i++ {
+		if _, err := dispatcher.Deliver(ctx, webhook, events.New(events.UserUpdated, nil)); err == nil {
+			t.Fatal("expected delivery to fail")
+		}
+	}
+
+	stored, _ := repo.Get(ctx, webhook.ID)
+	if stored.Enabled || stored.DisabledAt == nil {
+		t.Fatalf("expected webhook to be disabled after 2 failed deliveries, got %+v", stored)
+	}
+
+	svc := services.NewWebhookService(repo, logger.NewSimpleLogger())
+	enabled := true
+	updated, err := svc.UpdateWebhook(ctx, webhook.ID.String(), &services.UpdateWebhookRequest{Enabled: &enabled})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !updated.Enabled || updated.ConsecutiveFailures != 0 || updated.DisabledAt != nil {
+		t.Fatalf("expected re-enabling to clear the failure streak, got %+v", updated)
+	}
+}
+
+// TestWebhookDispatcherConsumesEvents tests delivery of events published on the bus
+func TestWebhookDispatcherConsumesEvents(t *testing.T) {
+	repo := newFakeWebhookRepository()
+
+	received := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(services.WebhookEventTypeHeader)
+	}))
+	defer receiver.Close()
+
+	createTestWebhook(t, repo, receiver.URL)
+
+	bus := events.NewBus()
+	dispatcher := services.NewWebhookDispatcher(repo, webhookTestConfig(), logger.NewSimpleLogger())
+	bus.Subscribe(dispatcher.HandleEvent)
+	dispatcher.Start()
+	defer dispatcher.Stop(context.Background())
+
+	_ = bus.Publish(context.Background(), events.New(events.UserDeactivated, nil))
+
+	select {
+	case eventType := <-received:
+		if eventType != events.UserDeactivated {
+			t.Fatalf("expected %s, got %s", events.UserDeactivated, eventType)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}
+
+// TestWebhookValidation tests URL and event type validation
+func TestWebhookValidation(t *testing.T) {
+	svc := services.NewWebhookService(newFakeWebhookRepository(), logger.NewSimpleLogger())
+	ctx := context.Background()
+
+	_, err := svc.CreateWebhook(ctx, &services.CreateWebhookRequest{URL: "ftp://example.com", EventTypes: []string{events.UserCreated}})
+	if !errors.Is(err, services.ErrInvalidWebhookURL) {
+		t.Fatalf("expected ErrInvalidWebhookURL, got %v", err)
+	}
+
+	_, err = svc.CreateWebhook(ctx, &services.CreateWebhookRequest{URL: "https://example.com/hook", EventTypes: []string{"user.exploded"}})
+	if !errors.Is(err, services.ErrUnknownEventType) {
+		t.Fatalf("expected ErrUnknownEventType, got %v", err)
+	}
+}
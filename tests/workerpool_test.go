@@ -0,0 +1,241 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/pkg/httpclient"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/metrics"
+	"BackofficeGoService/internal/pkg/workerpool"
+	"BackofficeGoService/internal/services"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newTestPool starts a pool reporting to fresh metrics, stopped when the
+// test ends
+func newTestPool(t *testing.T, cfg workerpool.Config) (*workerpool.Pool, *metrics.Metrics) {
+	t.Helper()
+	m := metrics.New()
+	pool := workerpool.New("test", cfg, logger.NewNopLogger(), workerpool.WithMetrics(workerpool.Metrics{
+		Queued:   m.WorkerPoolQueued,
+		InFlight: m.WorkerPoolInFlight,
+		Rejected: m.WorkerPoolRejected,
+	}))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = pool.Stop(ctx)
+	})
+	return pool, m
+}
+
+// occupy submits a task that holds a worker until release is closed and
+// waits until it runs
+func occupy(t *testing.T, pool *workerpool.Pool, release <-chan struct{}) *workerpool.Future[struct{}] {
+	t.Helper()
+	started := make(chan struct{})
+	future, err := workerpool.Submit(context.Background(), pool, func(ctx context.Context) (struct{}, error) {
+		close(started)
+		<-release
+		return struct{}{}, nil
+	})
+	if err != nil {
+		t.Fatalf("occupy: %v", err)
+	}
+	<-started
+	return future
+}
+
+// noop is a task returning n
+func noop(n int) func(context.Context) (int, error) {
+	return func(context.Context) (int, error) { return n, nil }
+}
+
+// TestWorkerPoolRejectsWhenSaturated tests that a full queue refuses tasks
+// in reject mode and counts them
+func TestWorkerPoolRejectsWhenSaturated(t *testing.T) {
+	pool, m := newTestPool(t, workerpool.Config{Size: 1, QueueDepth: 1, Mode: workerpool.ModeReject})
+	release := make(chan struct{})
+	occupy(t, pool, release)
+
+	queued, err := workerpool.Submit(context.Background(), pool, noop(1))
+	if err != nil {
+		t.Fatalf("expected the queue to take one task, got %v", err)
+	}
+	if _, err := workerpool.Submit(context.Background(), pool, noop(2)); !errors.Is(err, workerpool.ErrSaturated) {
+		t.Fatalf("expected ErrSaturated, got %v", err)
+	}
+	expectCount(t, "rejected tasks", testutil.ToFloat64(m.WorkerPoolRejected.WithLabelValues("test")), 1)
+	expectCount(t, "queued tasks", testutil.ToFloat64(m.WorkerPoolQueued.WithLabelValues("test")), 1)
+	expectCount(t, "running tasks", testutil.ToFloat64(m.WorkerPoolInFlight.WithLabelValues("test")), 1)
+
+	close(release)
+	if n, err := queued.Wait(context.Background()); err != nil || n != 1 {
+		t.Errorf("expected the queued task to run, got %d %v", n, err)
+	}
+}
+
+// TestWorkerPoolBlocksWhenSaturated tests that a full queue makes Submit
+// wait for room in block mode, until its context ends
+func TestWorkerPoolBlocksWhenSaturated(t *testing.T) {
+	pool, m := newTestPool(t, workerpool.Config{Size: 1, QueueDepth: 1, Mode: workerpool.ModeBlock})
+	release := make(chan struct{})
+	occupy(t, pool, release)
+	if _, err := workerpool.Submit(context.Background(), pool, noop(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := workerpool.Submit(ctx, pool, noop(2)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the submission to time out, got %v", err)
+	}
+
+	submitted := make(chan *workerpool.Future[int])
+	go func() {
+		future, err := workerpool.Submit(context.Background(), pool, noop(3))
+		if err != nil {
+			t.Errorf("expected the blocked submission to succeed, got %v", err)
+		}
+		submitted <- future
+	}()
+	select {
+	case <-submitted:
+		t.Fatal("expected Submit to block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	future := <-submitted
+	if n, err := future.Wait(context.Background()); err != nil || n != 3 {
+		t.Errorf("expected the blocked task to run, got %d %v", n, err)
+	}
+	expectCount(t, "rejected tasks", testutil.ToFloat64(m.WorkerPoolRejected.WithLabelValues("test")), 0)
+}
+
+// TestWorkerPoolContainsPanics tests that a panicking task fails with a
+// PanicError while the worker carries on
+func TestWorkerPoolContainsPanics(t *testing.T) {
+	pool, _ := newTestPool(t, workerpool.Config{Size: 1, QueueDepth: 2})
+
+	panicked, err := workerpool.Submit(context.Background(), pool, func(context.Context) (int, error) {
+		panic("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := workerpool.Submit(context.Background(), pool, noop(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var panicErr *workerpool.PanicError
+	if _, err := panicked.Wait(context.Background()); !errors.As(err, &panicErr) || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Errorf("expected a PanicError with its stack, got %v", err)
+	}
+	if n, err := next.Wait(context.Background()); err != nil || n != 7 {
+		t.Errorf("expected the worker to survive the panic, got %d %v", n, err)
+	}
+}
+
+// TestWorkerPoolDrain tests that Stop refuses new tasks, runs the queued
+// ones in submission order and waits for them
+func TestWorkerPoolDrain(t *testing.T) {
+	pool, _ := newTestPool(t, workerpool.Config{Size: 1, QueueDepth: 3})
+	release := make(chan struct{})
+	first := occupy(t, pool, release)
+
+	var mu sync.Mutex
+	var order []int
+	for i := 1; i <= 3; i++ {
+		if _, err := workerpool.Submit(context.Background(), pool, func(context.Context) (int, error) {
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			return i, nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A deadline passing while a task runs fails the stop, not the tasks
+	short, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Stop(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the stop to time out while a task runs, got %v", err)
+	}
+	if _, err := workerpool.Submit(context.Background(), pool, noop(4)); !errors.Is(err, workerpool.ErrStopped) {
+		t.Errorf("expected ErrStopped once stopping, got %v", err)
+	}
+
+	close(release)
+	if err := pool.Stop(context.Background()); err != nil {
+		t.Fatalf("expected the pool to drain, got %v", err)
+	}
+	select {
+	case <-first.Done():
+	default:
+		t.Error("expected the running task finished")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 3 || order[0] != 1 || order[1] != 2 || order[2] != 3 {
+		t.Errorf("expected the queued tasks run in order, got %v", order)
+	}
+	if pool.Queued() != 0 || pool.InFlight() != 0 {
+		t.Errorf("expected an idle pool, got %d queued and %d running", pool.Queued(), pool.InFlight())
+	}
+}
+
+// TestWorkerPoolSkipsAbandonedTasks tests that a task whose submitter went
+// away while it was queued does not run
+func TestWorkerPoolSkipsAbandonedTasks(t *testing.T) {
+	pool, _ := newTestPool(t, workerpool.Config{Size: 1, QueueDepth: 1})
+	release := make(chan struct{})
+	occupy(t, pool, release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := false
+	future, err := workerpool.Submit(ctx, pool, func(context.Context) (int, error) {
+		ran = true
+		return 0, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	close(release)
+
+	<-future.Done()
+	if _, err := future.Wait(context.Background()); !errors.Is(err, context.Canceled) || ran {
+		t.Errorf("expected the abandoned task skipped, got %v (ran %v)", err, ran)
+	}
+}
+
+// TestBreachCheckOnSaturatedPool tests that breach checks the pool refuses
+// are handled like an unreachable range API
+func TestBreachCheckOnSaturatedPool(t *testing.T) {
+	server := httptest.NewServer(&rangeAPI{})
+	defer server.Close()
+	client := httpclient.New(httpclient.Config{Timeout: time.Second})
+	pool, _ := newTestPool(t, workerpool.Config{Size: 1, QueueDepth: 0})
+	ctx := context.Background()
+
+	policy := services.NewPwnedPasswords(client, server.URL, 0, true, logger.NewNopLogger(), services.WithBreachCheckPool(pool))
+	if err := policy.Check(ctx, breachedPassword); !errors.Is(err, services.ErrPasswordBreached) {
+		t.Fatalf("expected the pooled check to find the breach, got %v", err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	occupy(t, pool, release)
+	if err := policy.Check(ctx, breachedPassword); !errors.Is(err, services.ErrPasswordCheckUnavailable) {
+		t.Errorf("expected fail-closed to refuse while the pool is saturated, got %v", err)
+	}
+}
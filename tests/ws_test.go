@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"BackofficeGoService/internal/app/apptest"
+	"BackofficeGoService/internal/app/controllers/stream"
+	"BackofficeGoService/internal/app/middleware"
+	"BackofficeGoService/internal/app/models"
+	"BackofficeGoService/internal/pkg/errors"
+	"BackofficeGoService/internal/pkg/events"
+	"BackofficeGoService/internal/pkg/logger"
+	"BackofficeGoService/internal/pkg/ws"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// dialSocket opens a socket to serverURL's /api/v1/ws with token as the
+// access_token query parameter
+func dialSocket(t *testing.T, serverURL, token string) *websocket.Conn {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(serverURL, "http") + "/api/v1/ws?access_token=" + token
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("dial: %v (status %d)", err, status)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readSocket reads the next protocol message
+func readSocket(t *testing.T, conn *websocket.Conn) ws.Message {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg ws.Message
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return msg
+}
+
+// subscribeSocket subscribes to topics and waits for the acknowledgement
+func subscribeSocket(t *testing.T, conn *websocket.Conn, topics ...string) {
+	t.Helper()
+
+	if err := conn.WriteJSON(ws.Message{Type: ws.TypeSubscribe, Topics: topics}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if msg := readSocket(t, conn); msg.Type != ws.TypeSubscribed || len(msg.Topics) != len(topics) {
+		t.Fatalf("expected %v to be acknowledged, got %+v", topics, msg)
+	}
+}
+
+// readCloseCode reads until the server closes the socket and returns its close code
+func readCloseCode(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			closeErr, ok := err.(*websocket.CloseError)
+			if !ok {
+				t.Fatalf("expected a close frame, got %v", err)
+			}
+			return closeErr.Code
+		}
+	}
+}
+
+// TestSocketReceivesUserEvents tests that a subscribed admin receives
+// user.created live and that topics are authorized
+func TestSocketReceivesUserEvents(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	member := ta.CreateUser(models.RoleUser)
+
+	conn := dialSocket(t, ta.Server.URL, admin.Token)
+	subscribeSocket(t, conn, events.UserCreated)
+
+	resp := ta.Request(http.MethodPost, "/api/v1/users", map[string]string{
+		"email":      "live@example.com",
+		"username":   "live",
+		"first_name": "Live",
+		"last_name":  "Update",
+		"password":   "correct-horse",
+	}, admin.Token)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create user: %d %s", resp.StatusCode, resp.Body)
+	}
+
+	msg := readSocket(t, conn)
+	if msg.Type != ws.TypeEvent || msg.Event != events.UserCreated {
+		t.Fatalf("expected a user.created event, got %+v", msg)
+	}
+	var event struct {
+		Type string `json:"type"`
+		Data struct {
+			Email string `json:"email"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		t.Fatalf("decode event: %v", err)
+	}
+	if event.Type != events.UserCreated || event.Data.Email != "live@example.com" {
+		t.Errorf("expected the created user in the event, got %s", msg.Data)
+	}
+
+	// Users without users.manage cannot follow user events
+	other := dialSocket(t, ta.Server.URL, member.Token)
+	for topic, code := range map[string]errors.Code{
+		events.UserCreated: errors.CodeSocketTopicForbidden,
+		"no.such.topic":    errors.CodeSocketTopicUnknown,
+	} {
+		if err := other.WriteJSON(ws.Message{Type: ws.TypeSubscribe, Topics: []string{topic}}); err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+		if msg := readSocket(t, other); msg.Type != ws.TypeError || msg.Code != code {
+			t.Errorf("expected %s for %s, got %+v", code, topic, msg)
+		}
+	}
+	if err := other.WriteMessage(websocket.TextMessage, []byte("{")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if msg := readSocket(t, other); msg.Code != errors.CodeSocketMessageInvalid {
+		t.Errorf("expected %s for malformed JSON, got %+v", errors.CodeSocketMessageInvalid, msg)
+	}
+	subscribeSocket(t, other, "notification")
+}
+
+// TestSocketRequiresToken tests that the upgrade needs a valid token
+func TestSocketRequiresToken(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+
+	url := "ws" + strings.TrimPrefix(ta.Server.URL, "http") + "/api/v1/ws"
+	for _, target := range []string{url, url + "?access_token=forged"} {
+		_, resp, err := websocket.DefaultDialer.Dial(target, nil)
+		if err == nil {
+			t.Fatalf("expected the upgrade to %s to fail", target)
+		}
+		if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401 for %s, got %v", target, resp)
+		}
+	}
+}
+
+// TestSocketRequiresClaims tests that the controller refuses requests that
+// reach it without an authenticated caller, with the API's error body
+func TestSocketRequiresClaims(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := ws.NewHub(ws.Options{}, nil, logger.NewCaptureLogger())
+	router := gin.New()
+	router.GET("/api/v1/ws", stream.NewSocketController(hub, nil).Connect)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/ws", nil))
+
+	resp := &apptest.Response{StatusCode: w.Code, Header: w.Header(), Body: w.Body.Bytes()}
+	expectErrorCode(t, resp, http.StatusUnauthorized, errors.CodeAuthenticationRequired)
+}
+
+// TestSocketShutdownGoingAway tests that Shutdown closes open sockets with a
+// going-away frame
+func TestSocketShutdownGoingAway(t *testing.T) {
+	ta := apptest.NewTestApp(t)
+	admin := ta.CreateUser(models.RoleAdmin)
+	conn := dialSocket(t, ta.Server.URL, admin.Token)
+	subscribeSocket(t, conn, events.UserCreated)
+
+	closed := make(chan int, 1)
+	go func() { closed <- readCloseCode(t, conn) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := ta.App.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	select {
+	case code := <-closed:
+		if code != websocket.CloseGoingAway {
+			t.Errorf("expected close code %d, got %d", websocket.CloseGoingAway, code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("socket was not closed by Shutdown")
+	}
+}
+
+// TestHubEvictsSlowConsumers tests that a client that stops reading is
+// disconnected instead of holding up delivery
+func TestHubEvictsSlowConsumers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs := logger.NewCaptureLogger()
+	hub := ws.NewHub(ws.Options{SendQueue: 1, PingInterval: time.Minute, WriteTimeout: 5 * time.Second}, nil, logs)
+
+	validator := staticTokenValidator{
+		"alice-token": {UserID: "alice", Role: string(models.RoleAdmin)},
+		"bob-token":   {UserID: "bob", Role: string(models.RoleAdmin)},
+	}
+	router := gin.New()
+	router.GET("/api/v1/ws", middleware.StreamAuth(validator), stream.NewSocketController(hub, nil).Connect)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { _ = hub.Stop(context.Background()) })
+
+	slow := dialSocket(t, server.URL, "alice-token")
+	subscribeSocket(t, slow, events.UserCreated)
+	fast := dialSocket(t, server.URL, "bob-token")
+	subscribeSocket(t, fast, events.UserCreated)
+
+	// Large events fill the socket buffers of the client that does not read
+	payload := strings.Repeat("x", 64*1024)
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.ClientCount() == 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("slow client was not evicted")
+		}
+		hub.Publish("alice", events.UserCreated, payload)
+	}
+
+	if code := readCloseCode(t, slow); code != ws.CloseSlowConsumer {
+		t.Errorf("expected close code %d, got %d", ws.CloseSlowConsumer, code)
+	}
+	if !logs.Contains("Evicting slow socket client") {
+		t.Error("expected the eviction to be logged")
+	}
+
+	// The other client is unaffected
+	hub.Publish("bob", events.UserCreated, "hello")
+	if msg := readSocket(t, fast); msg.Event != events.UserCreated || string(msg.Data) != `"hello"` {
+		t.Errorf("expected bob's event, got %+v", msg)
+	}
+}